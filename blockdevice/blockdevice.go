@@ -0,0 +1,162 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package blockdevice
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// BLOCK DEVICES
+//
+//		The platform neutral view of the host's block devices:  their size, serial number and
+//		read-only state, and the rescan of their capacity.  Each kind of block device is handled by
+//		a Backend, selected by the OS device name:
+//
+//		Linux		"scsi" (sdX), "dm" (dm-N, multipath devices), "nvme" (nvmeXnY) and "virtio"
+//					(vdX), read from sysfs
+//		Windows		"disk" (DiskN, the disk number), read from the MSFT_Disk WMI class
+//
+//		A new kind of block device is supported by registering its Backend (RegisterBackend);
+//		the higher layers only use GetDevice, GetDevices and Rescan.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+var (
+	// ErrNotFound is returned if the block device doesn't exist or no backend handles it
+	ErrNotFound = errors.New("block device not found")
+)
+
+// Device is a block device
+type Device struct {
+	Name         string   // OS device name (e.g. "sdb", "dm-3" or "Disk3")
+	Path         string   // Device path (e.g. "/dev/dm-3" or the MSFT_Disk path)
+	Backend      string   // Name of the backend handling the device
+	SerialNumber string   // Serial number, as reported by the array, if known
+	Size         uint64   // Size in bytes
+	ReadOnly     bool     // True if the device is read-only
+	Slaves       []string // Names of the underlying block devices (e.g. the paths of a dm device)
+}
+
+// Backend handles a kind of block device
+type Backend interface {
+	// Name returns the backend name (e.g. "scsi")
+	Name() string
+
+	// Handles returns true if the backend handles the given device name
+	Handles(name string) bool
+
+	// GetDevice returns the given device; ErrNotFound if it doesn't exist
+	GetDevice(name string) (*Device, error)
+
+	// Rescan rescans the given device's capacity, or every device handled by the backend if the
+	// name is empty
+	Rescan(name string) error
+}
+
+var (
+	backendLock sync.RWMutex
+	backends    []Backend // Registered backends, in lookup order
+)
+
+// RegisterBackend registers the given backend.  It is looked up before the backends already
+// registered, so that it can handle devices they also claim.
+func RegisterBackend(backend Backend) {
+	backendLock.Lock()
+	defer backendLock.Unlock()
+	backends = append([]Backend{backend}, backends...)
+}
+
+// getBackends returns the registered backends, in lookup order
+func getBackends() []Backend {
+	backendLock.RLock()
+	defer backendLock.RUnlock()
+	return append([]Backend{}, backends...)
+}
+
+// getBackend returns the backend handling the given device name, nil if none does
+func getBackend(name string) Backend {
+	for _, backend := range getBackends() {
+		if backend.Handles(name) {
+			return backend
+		}
+	}
+	return nil
+}
+
+// GetDevice returns the given block device; ErrNotFound if it doesn't exist
+func GetDevice(name string) (*Device, error) {
+	log.Tracef(">>>>> GetDevice, name=%v", name)
+	defer log.Trace("<<<<< GetDevice")
+
+	backend := getBackend(name)
+	if backend == nil {
+		return nil, fmt.Errorf("%v: %w", name, ErrNotFound)
+	}
+	device, err := backend.GetDevice(name)
+	if err != nil {
+		return nil, err
+	}
+	device.Backend = backend.Name()
+	return device, nil
+}
+
+// GetDevices returns the host's block devices handled by a backend
+func GetDevices() ([]*Device, error) {
+	log.Trace(">>>>> GetDevices")
+	defer log.Trace("<<<<< GetDevices")
+
+	names, err := listDeviceNames()
+	if err != nil {
+		return nil, err
+	}
+	var devices []*Device
+	for _, name := range names {
+		if getBackend(name) == nil {
+			continue
+		}
+		device, err := GetDevice(name)
+		if err != nil {
+			// The device may have been removed since it was listed
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// RescanAll rescans the capacity of every device
+func RescanAll() error {
+	return Rescan("")
+}
+
+// Rescan rescans the given device's capacity, or every device if the name is empty
+func Rescan(name string) error {
+	log.Tracef(">>>>> Rescan, name=%v", name)
+	defer log.Trace("<<<<< Rescan")
+
+	if name != "" {
+		backend := getBackend(name)
+		if backend == nil {
+			return fmt.Errorf("%v: %w", name, ErrNotFound)
+		}
+		return backend.Rescan(name)
+	}
+
+	var lastErr error
+	for _, backend := range getBackends() {
+		if err := backend.Rescan(""); err != nil {
+			log.Errorf("Unable to rescan the %v devices, err=%v", backend.Name(), err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,303 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package blockdevice
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/util"
+)
+
+const (
+	sectorSize    = 512    // Unit of the sysfs size attribute
+	devPath       = "/dev" // Block device nodes
+	dmUUIDPrefix  = "mpath-"
+	wwidSeparator = "."
+)
+
+var (
+	// sysBlockPath is the sysfs block device directory; a variable so that tests can replace it
+	sysBlockPath = "/sys/block"
+
+	// resizeMultipathMap reloads the given multipath map to apply its paths' new size; a variable
+	// so that tests can replace it
+	resizeMultipathMap = func(mapName string) error {
+		out, _, err := util.ExecCommandOutput("multipathd", []string{"resize", "map", mapName})
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(out, "ok") {
+			return fmt.Errorf("unable to resize multipath map %v, out=%v", mapName, out)
+		}
+		return nil
+	}
+
+	scsiDeviceName   = regexp.MustCompile(`^sd[a-z]+$`)
+	dmDeviceName     = regexp.MustCompile(`^dm-[0-9]+$`)
+	nvmeDeviceName   = regexp.MustCompile(`^nvme[0-9]+n[0-9]+$`)
+	virtioDeviceName = regexp.MustCompile(`^vd[a-z]+$`)
+)
+
+func init() {
+	// Multipath devices are rescanned after their SCSI paths (see Rescan)
+	RegisterBackend(&virtioBackend{})
+	RegisterBackend(&nvmeBackend{})
+	RegisterBackend(&dmBackend{})
+	RegisterBackend(&scsiBackend{})
+}
+
+// listDeviceNames returns the names of the host's block devices
+func listDeviceNames() ([]string, error) {
+	entries, err := ioutil.ReadDir(sysBlockPath)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+// listBackendDevices returns the names of the host's block devices handled by the given backend
+func listBackendDevices(backend Backend) ([]string, error) {
+	names, err := listDeviceNames()
+	if err != nil {
+		return nil, err
+	}
+	var handled []string
+	for _, name := range names {
+		if backend.Handles(name) {
+			handled = append(handled, name)
+		}
+	}
+	return handled, nil
+}
+
+// readAttribute returns the trimmed value of the given block device's sysfs attribute (e.g.
+// "size" or "device/wwid"); ErrNotFound if the device doesn't exist
+func readAttribute(name string, attribute string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(sysBlockPath, name, attribute))
+	if err != nil {
+		if os.IsNotExist(err) {
+			if _, statErr := os.Stat(filepath.Join(sysBlockPath, name)); os.IsNotExist(statErr) {
+				return "", fmt.Errorf("%v: %w", name, ErrNotFound)
+			}
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// writeAttribute writes the given block device's sysfs attribute
+func writeAttribute(name string, attribute string, value string) error {
+	return ioutil.WriteFile(filepath.Join(sysBlockPath, name, attribute), []byte(value), 0200)
+}
+
+// getSysfsDevice returns the size, read-only state and slaves of the given block device
+func getSysfsDevice(name string) (*Device, error) {
+	size, err := readAttribute(name, "size")
+	if err != nil {
+		return nil, err
+	}
+	sectors, err := strconv.ParseUint(size, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the size of %v, err=%v", name, err)
+	}
+	readOnly, err := readAttribute(name, "ro")
+	if err != nil {
+		return nil, err
+	}
+
+	device := &Device{
+		Name:     name,
+		Path:     filepath.Join(devPath, name),
+		Size:     sectors * sectorSize,
+		ReadOnly: readOnly == "1",
+	}
+	entries, err := ioutil.ReadDir(filepath.Join(sysBlockPath, name, "slaves"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		device.Slaves = append(device.Slaves, entry.Name())
+	}
+	return device, nil
+}
+
+// trimWWIDType returns the given sysfs WWID (e.g. "naa.624a9370...") without its type prefix
+func trimWWIDType(wwid string) string {
+	if i := strings.Index(wwid, wwidSeparator); i >= 0 {
+		return wwid[i+1:]
+	}
+	return wwid
+}
+
+// scsiBackend handles the SCSI disks (sdX)
+type scsiBackend struct{}
+
+func (backend *scsiBackend) Name() string { return "scsi" }
+
+func (backend *scsiBackend) Handles(name string) bool { return scsiDeviceName.MatchString(name) }
+
+// GetDevice returns the given SCSI disk; its serial number is its WWID without the type prefix
+func (backend *scsiBackend) GetDevice(name string) (*Device, error) {
+	device, err := getSysfsDevice(name)
+	if err != nil {
+		return nil, err
+	}
+	if wwid, err := readAttribute(name, "device/wwid"); err == nil {
+		device.SerialNumber = strings.ToLower(trimWWIDType(wwid))
+	}
+	return device, nil
+}
+
+// Rescan rescans the given SCSI disk, or every SCSI disk
+func (backend *scsiBackend) Rescan(name string) error {
+	names := []string{name}
+	if name == "" {
+		var err error
+		if names, err = listBackendDevices(backend); err != nil {
+			return err
+		}
+	}
+	var lastErr error
+	for _, name := range names {
+		if err := writeAttribute(name, "device/rescan", "1"); err != nil {
+			log.Errorf("Unable to rescan %v, err=%v", name, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// dmBackend handles the device mapper devices (dm-N), e.g. multipath devices
+type dmBackend struct{}
+
+func (backend *dmBackend) Name() string { return "dm" }
+
+func (backend *dmBackend) Handles(name string) bool { return dmDeviceName.MatchString(name) }
+
+// GetDevice returns the given dm device; a multipath device's serial number is its WWID without
+// the SCSI designator type
+func (backend *dmBackend) GetDevice(name string) (*Device, error) {
+	device, err := getSysfsDevice(name)
+	if err != nil {
+		return nil, err
+	}
+	if uuid, err := readAttribute(name, "dm/uuid"); err == nil && strings.HasPrefix(uuid, dmUUIDPrefix) && len(uuid) > len(dmUUIDPrefix) {
+		device.SerialNumber = strings.ToLower(uuid[len(dmUUIDPrefix)+1:])
+	}
+	return device, nil
+}
+
+// Rescan rescans the paths of the given multipath device, or of none if the name is empty (the
+// SCSI backend rescans them first), and resizes the multipath map
+func (backend *dmBackend) Rescan(name string) error {
+	names := []string{name}
+	if name == "" {
+		var err error
+		if names, err = listBackendDevices(backend); err != nil {
+			return err
+		}
+	}
+	var lastErr error
+	for _, dmName := range names {
+		uuid, err := readAttribute(dmName, "dm/uuid")
+		if err != nil || !strings.HasPrefix(uuid, dmUUIDPrefix) {
+			// Not a multipath device
+			continue
+		}
+		if name != "" {
+			device, err := getSysfsDevice(dmName)
+			if err != nil {
+				return err
+			}
+			for _, slave := range device.Slaves {
+				if err = Rescan(slave); err != nil {
+					lastErr = err
+				}
+			}
+		}
+		mapName, err := readAttribute(dmName, "dm/name")
+		if err == nil {
+			err = resizeMultipathMap(mapName)
+		}
+		if err != nil {
+			log.Errorf("Unable to resize multipath device %v, err=%v", dmName, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// nvmeBackend handles the NVMe namespaces (nvmeXnY)
+type nvmeBackend struct{}
+
+func (backend *nvmeBackend) Name() string { return "nvme" }
+
+func (backend *nvmeBackend) Handles(name string) bool { return nvmeDeviceName.MatchString(name) }
+
+// GetDevice returns the given NVMe namespace; its serial number is its WWID (EUI-64, NGUID or
+// UUID) without the type prefix
+func (backend *nvmeBackend) GetDevice(name string) (*Device, error) {
+	device, err := getSysfsDevice(name)
+	if err != nil {
+		return nil, err
+	}
+	if wwid, err := readAttribute(name, "wwid"); err == nil {
+		device.SerialNumber = strings.ToLower(trimWWIDType(wwid))
+	}
+	return device, nil
+}
+
+// Rescan rescans the controller of the given NVMe namespace, or of every NVMe namespace
+func (backend *nvmeBackend) Rescan(name string) error {
+	names := []string{name}
+	if name == "" {
+		var err error
+		if names, err = listBackendDevices(backend); err != nil {
+			return err
+		}
+	}
+	var lastErr error
+	for _, name := range names {
+		if err := writeAttribute(name, "device/rescan_controller", "1"); err != nil {
+			log.Errorf("Unable to rescan the controller of %v, err=%v", name, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// virtioBackend handles the virtio disks (vdX)
+type virtioBackend struct{}
+
+func (backend *virtioBackend) Name() string { return "virtio" }
+
+func (backend *virtioBackend) Handles(name string) bool { return virtioDeviceName.MatchString(name) }
+
+// GetDevice returns the given virtio disk; its serial number is the one set by the hypervisor
+func (backend *virtioBackend) GetDevice(name string) (*Device, error) {
+	device, err := getSysfsDevice(name)
+	if err != nil {
+		return nil, err
+	}
+	if serial, err := readAttribute(name, "serial"); err == nil {
+		device.SerialNumber = strings.ToLower(serial)
+	}
+	return device, nil
+}
+
+// Rescan does nothing; the virtio driver applies a capacity change as soon as the hypervisor
+// reports it
+func (backend *virtioBackend) Rescan(name string) error {
+	return nil
+}
@@ -0,0 +1,116 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package blockdevice
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeAttributes creates the given sysfs attributes of a block device
+func fakeAttributes(t *testing.T, root string, name string, attributes map[string]string) {
+	for attribute, value := range attributes {
+		path := filepath.Join(root, name, attribute)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetDevices(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "blockdevice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	savedSysBlockPath := sysBlockPath
+	sysBlockPath = tempDir
+	defer func() { sysBlockPath = savedSysBlockPath }()
+
+	const serialNumber = "6d70f5a2c0d7a8bd6c9ce900e3ba4f6c"
+	fakeAttributes(t, tempDir, "sdb", map[string]string{"size": "2097152", "ro": "0", "device/wwid": "eui." + serialNumber, "device/rescan": ""})
+	fakeAttributes(t, tempDir, "sdc", map[string]string{"size": "2097152", "ro": "0", "device/wwid": "eui." + serialNumber, "device/rescan": ""})
+	fakeAttributes(t, tempDir, "dm-3", map[string]string{"size": "2097152", "ro": "1", "dm/uuid": "mpath-2" + serialNumber, "dm/name": "mpatha", "slaves/sdb": "", "slaves/sdc": ""})
+	fakeAttributes(t, tempDir, "nvme0n1", map[string]string{"size": "4194304", "ro": "0", "wwid": "eui.0025388B71B0A1E3"})
+	fakeAttributes(t, tempDir, "vda", map[string]string{"size": "1024", "ro": "0", "serial": "VOL1"})
+	fakeAttributes(t, tempDir, "loop0", map[string]string{"size": "0", "ro": "0"})
+
+	devices, err := GetDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*Device{
+		{Name: "dm-3", Path: "/dev/dm-3", Backend: "dm", SerialNumber: serialNumber, Size: 1 << 30, ReadOnly: true, Slaves: []string{"sdb", "sdc"}},
+		{Name: "nvme0n1", Path: "/dev/nvme0n1", Backend: "nvme", SerialNumber: "0025388b71b0a1e3", Size: 2 << 30},
+		{Name: "sdb", Path: "/dev/sdb", Backend: "scsi", SerialNumber: serialNumber, Size: 1 << 30},
+		{Name: "sdc", Path: "/dev/sdc", Backend: "scsi", SerialNumber: serialNumber, Size: 1 << 30},
+		{Name: "vda", Path: "/dev/vda", Backend: "virtio", SerialNumber: "vol1", Size: 512 * 1024},
+	}
+	if !reflect.DeepEqual(devices, expected) {
+		for _, device := range devices {
+			t.Logf("%+v", device)
+		}
+		t.Error("unexpected devices")
+	}
+
+	for _, name := range []string{"sdz", "loop0"} {
+		if _, err = GetDevice(name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%v: unexpected error %v", name, err)
+		}
+	}
+}
+
+func TestRescanMultipathDevice(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "blockdevice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	savedSysBlockPath := sysBlockPath
+	sysBlockPath = tempDir
+	defer func() { sysBlockPath = savedSysBlockPath }()
+
+	var resizedMaps []string
+	savedResizeMultipathMap := resizeMultipathMap
+	resizeMultipathMap = func(mapName string) error {
+		resizedMaps = append(resizedMaps, mapName)
+		return nil
+	}
+	defer func() { resizeMultipathMap = savedResizeMultipathMap }()
+
+	fakeAttributes(t, tempDir, "sdb", map[string]string{"size": "2097152", "ro": "0", "device/rescan": ""})
+	fakeAttributes(t, tempDir, "sdc", map[string]string{"size": "2097152", "ro": "0", "device/rescan": ""})
+	fakeAttributes(t, tempDir, "dm-3", map[string]string{"size": "2097152", "ro": "0", "dm/uuid": "mpath-2abc", "dm/name": "mpatha", "slaves/sdb": "", "slaves/sdc": ""})
+	fakeAttributes(t, tempDir, "dm-4", map[string]string{"size": "2097152", "ro": "0", "dm/uuid": "LVM-abc", "dm/name": "vg-lv"})
+
+	// The paths are rescanned before the map is resized
+	if err = Rescan("dm-3"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"sdb", "sdc"} {
+		if value, _ := readAttribute(name, "device/rescan"); value != "1" {
+			t.Errorf("%v not rescanned, rescan=%q", name, value)
+		}
+	}
+	if !reflect.DeepEqual(resizedMaps, []string{"mpatha"}) {
+		t.Errorf("unexpected resized maps %v", resizedMaps)
+	}
+
+	// Rescanning every device only resizes the multipath maps
+	resizedMaps = nil
+	if err = RescanAll(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resizedMaps, []string{"mpatha"}) {
+		t.Errorf("unexpected resized maps %v", resizedMaps)
+	}
+}
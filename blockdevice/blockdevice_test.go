@@ -0,0 +1,67 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package blockdevice
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeBackend handles the device names starting with its name
+type fakeBackend struct {
+	name     string
+	rescans  []string
+	rescanFn func(name string) error
+}
+
+func (backend *fakeBackend) Name() string { return backend.name }
+
+func (backend *fakeBackend) Handles(name string) bool {
+	return len(name) > len(backend.name) && name[:len(backend.name)] == backend.name
+}
+
+func (backend *fakeBackend) GetDevice(name string) (*Device, error) {
+	return &Device{Name: name, Size: 512}, nil
+}
+
+func (backend *fakeBackend) Rescan(name string) error {
+	backend.rescans = append(backend.rescans, name)
+	if backend.rescanFn != nil {
+		return backend.rescanFn(name)
+	}
+	return nil
+}
+
+func TestRegisterBackend(t *testing.T) {
+	savedBackends := getBackends()
+	defer func() { backends = savedBackends }()
+	backends = nil
+
+	// A backend registered later is looked up first
+	fake := &fakeBackend{name: "fake"}
+	fakeDisk := &fakeBackend{name: "fakedisk", rescanFn: func(name string) error { return errors.New("rescan failed") }}
+	RegisterBackend(fake)
+	RegisterBackend(fakeDisk)
+
+	device, err := GetDevice("fakedisk0")
+	if err != nil || device.Backend != "fakedisk" || device.Name != "fakedisk0" {
+		t.Errorf("unexpected device %+v, err=%v", device, err)
+	}
+	if device, err = GetDevice("fake0"); err != nil || device.Backend != "fake" {
+		t.Errorf("unexpected device %+v, err=%v", device, err)
+	}
+	if _, err = GetDevice("other0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Rescanning every device rescans every backend, even if one fails
+	if err = RescanAll(); err == nil {
+		t.Error("expected rescan error")
+	}
+	if len(fake.rescans) != 1 || len(fakeDisk.rescans) != 1 {
+		t.Errorf("unexpected rescans %v, %v", fake.rescans, fakeDisk.rescans)
+	}
+	if err = Rescan("fake0"); err != nil || len(fake.rescans) != 2 || fake.rescans[1] != "fake0" {
+		t.Errorf("unexpected rescans %v, err=%v", fake.rescans, err)
+	}
+}
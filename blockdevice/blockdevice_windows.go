@@ -0,0 +1,88 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package blockdevice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/windows/wmi"
+)
+
+const (
+	diskNamePrefix = "Disk" // Disk names are "Disk" followed by the disk number (e.g. "Disk3")
+)
+
+var (
+	// Disk routines; variables so that tests can replace them
+	getMSFTDisk = wmi.GetMSFTDisk
+	rescanDisks = wmi.RescanDisks
+)
+
+func init() {
+	RegisterBackend(&diskBackend{})
+}
+
+// listDeviceNames returns the names of the host's disks
+func listDeviceNames() ([]string, error) {
+	disks, err := getMSFTDisk("")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, disk := range disks {
+		names = append(names, fmt.Sprintf("%v%v", diskNamePrefix, disk.Number))
+	}
+	return names, nil
+}
+
+// diskBackend handles the Windows disks (DiskN)
+type diskBackend struct{}
+
+func (backend *diskBackend) Name() string { return "disk" }
+
+func (backend *diskBackend) Handles(name string) bool {
+	_, err := getDiskNumber(name)
+	return err == nil
+}
+
+// GetDevice returns the given disk
+func (backend *diskBackend) GetDevice(name string) (*Device, error) {
+	number, err := getDiskNumber(name)
+	if err != nil {
+		return nil, err
+	}
+	disks, err := getMSFTDisk(fmt.Sprintf("Number=%v", number))
+	if err != nil {
+		return nil, err
+	}
+	if len(disks) == 0 {
+		return nil, fmt.Errorf("%v: %w", name, ErrNotFound)
+	}
+	disk := disks[0]
+	return &Device{
+		Name:         name,
+		Path:         disk.Path,
+		SerialNumber: strings.ToLower(strings.TrimSpace(disk.SerialNumber)),
+		Size:         disk.Size,
+		ReadOnly:     disk.IsReadOnly,
+	}, nil
+}
+
+// Rescan rescans every disk; Windows cannot rescan a single disk
+func (backend *diskBackend) Rescan(name string) error {
+	return rescanDisks()
+}
+
+// getDiskNumber returns the disk number of the given disk name
+func getDiskNumber(name string) (uint32, error) {
+	if !strings.HasPrefix(name, diskNamePrefix) {
+		return 0, fmt.Errorf("%v: %w", name, ErrNotFound)
+	}
+	number, err := strconv.ParseUint(strings.TrimPrefix(name, diskNamePrefix), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %w", name, ErrNotFound)
+	}
+	return uint32(number), nil
+}
@@ -141,6 +141,11 @@ func NewRouter() *mux.Router {
 			HandlerFunc: getChapInfo,
 		},
 	}
+	// Cap the request bodies; the limits are read once (see connectivity.GetRequestLimits)
+	requestLimits = connectivity.GetRequestLimits()
+	for i := range routes {
+		routes[i].HandlerFunc = connectivity.LimitRequestBody(routes[i].HandlerFunc, requestLimits.MaxBytes)
+	}
 	router := mux.NewRouter().StrictSlash(true)
 	util.InitializeRouter(router, routes)
 	return router
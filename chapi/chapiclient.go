@@ -1,6 +1,7 @@
 package chapi
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -54,6 +55,8 @@ type Client struct {
 	hostID string
 	// HTTP headers
 	header map[string]string
+	// context of the request being served, whose request correlation ID is forwarded to chapid
+	ctx context.Context
 }
 
 // AccessKeyPath struct
@@ -117,6 +120,12 @@ func (chapiClient *Client) AddHeader(header map[string]string) error {
 	return nil
 }
 
+// SetContext sets the context of the request being served; its request correlation ID is
+// forwarded to chapid
+func (chapiClient *Client) SetContext(ctx context.Context) {
+	chapiClient.ctx = ctx
+}
+
 // get host ID and cache it with chapi client
 func (chapiClient *Client) cacheHostID() (err error) {
 	log.Tracef("cacheHostID called")
@@ -138,7 +147,7 @@ func (chapiClient *Client) GetHostID() (string, error) {
 	var chapiResp Response
 	chapiResp.Data = &hosts
 	chapiResp.Err = &errResp
-	_, err := chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: "/hosts", Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err := chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: "/hosts", Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Errorf(errResp.Info)
@@ -173,7 +182,7 @@ func (chapiClient *Client) GetInitiators() (initiators []*model.Initiator, err e
 	var errResp *ErrorResponse
 	chapiResp.Err = &errResp
 	initiatorsURI := fmt.Sprintf(InitiatorsURIfmt, fmt.Sprintf(HostURIfmt, chapiClient.hostID))
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: initiatorsURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: initiatorsURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -201,7 +210,7 @@ func (chapiClient *Client) GetChapInfo() (chapInfo *model.ChapInfo, err error) {
 	var chapiResp Response
 	chapiResp.Err = &errResp
 	chapiResp.Data = &chapInfo
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: chapURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: chapURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -229,7 +238,7 @@ func (chapiClient *Client) GetNetworks() (networks []*model.NetworkInterface, er
 	chapiResp.Data = &networks
 	chapiResp.Err = &errResp
 	networksURI := fmt.Sprintf(NetworksURIfmt, fmt.Sprintf(HostURIfmt, chapiClient.hostID))
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: networksURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: networksURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -262,7 +271,7 @@ func (chapiClient *Client) AttachDevice(volumes []*model.Volume) (devices []*mod
 	var chapiResp Response
 	chapiResp.Data = &devices
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "POST", Path: devicesURI, Header: chapiClient.header, Payload: &volumes, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "POST", Path: devicesURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: &volumes, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Errorf("AttachDevice: %s for volume(%s)", errResp.Info, volumes[0].Name)
@@ -281,7 +290,7 @@ func (chapiClient *Client) AttachDevice(volumes []*model.Volume) (devices []*mod
 
 // AttachAndMountDevice will attach the given os device for given volume and mounts the filesystem on the host
 func (chapiClient *Client) AttachAndMountDevice(volume *model.Volume, mountPath string) (err error) {
-	log.Tracef(">>>>> AttachAndMountDevice on volume %#v to mount path %s", volume, mountPath)
+	log.Tracef(">>>>> AttachAndMountDevice on volume %#v to mount path %s", volume.Redacted(), mountPath)
 	defer log.Trace("<<<<< AttachAndMountDevice")
 
 	var vols []*model.Volume
@@ -312,7 +321,7 @@ func (chapiClient *Client) AttachAndMountDevice(volume *model.Volume, mountPath
 }
 
 func (chapiClient *Client) retryMountFileSystem(volume *model.Volume, mountPath string) (err error) {
-	log.Tracef("retryMountFileSystem called with %#v and mountPoint %s", volume, mountPath)
+	log.Tracef("retryMountFileSystem called with %#v and mountPoint %s", volume.Redacted(), mountPath)
 	maxTries := 3
 	try := 0
 	for {
@@ -349,7 +358,7 @@ func (chapiClient *Client) CreateFilesystem(device *model.Device, vol *model.Vol
 	var chapiResp Response
 	chapiResp.Data = &dev
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "PUT", Path: createFSURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "PUT", Path: createFSURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -376,7 +385,7 @@ func (chapiClient *Client) Mount(reqMount *model.Mount, respMount *model.Mount)
 	var chapiResp Response
 	chapiResp.Data = &respMount
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "POST", Path: mountURI, Header: chapiClient.header, Payload: &reqMount, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "POST", Path: mountURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: &reqMount, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -408,7 +417,7 @@ func (chapiClient *Client) GetDevices() (devices []*model.Device, err error) {
 	devicesURI := fmt.Sprintf(DevicesURIfmt, fmt.Sprintf(HostURIfmt, chapiClient.hostID))
 	chapiResp.Data = &devices
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: devicesURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: devicesURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error("GetDevices: error response, ", errResp.Info)
@@ -437,7 +446,7 @@ func (chapiClient *Client) GetDeviceFromVolume(volume *model.Volume) (device *mo
 	var chapiResp Response
 	chapiResp.Data = &device
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: devicesURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: devicesURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -451,7 +460,7 @@ func (chapiClient *Client) GetDeviceFromVolume(volume *model.Volume) (device *mo
 		return nil, fmt.Errorf("no matching device found for volume %s", volume.Name)
 	}
 
-	device.TargetScope = volume.TargetScope
+	volume.UpdateDevice(device)
 	return device, nil
 }
 
@@ -471,7 +480,7 @@ func (chapiClient *Client) GetMounts(respMount *[]*model.Mount, serialNumber str
 	var chapiResp Response
 	chapiResp.Data = &respMount
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: mountsURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: mountsURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -540,6 +549,7 @@ func (chapiClient *Client) Unmount(reqMount *model.Mount, respMount *model.Mount
 			Action:        "DELETE",
 			Path:          unMountURI,
 			Header:        chapiClient.header,
+			Context:       chapiClient.ctx,
 			Payload:       &reqMount,
 			Response:      &chapiResp,
 			ResponseError: &chapiResp,
@@ -575,7 +585,7 @@ func (chapiClient *Client) OfflineDevice(device *model.Device) (err error) {
 	var chapiResp Response
 	chapiResp.Data = &deviceResp
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "PUT", Path: deviceOfflineURI, Header: chapiClient.header, Payload: device, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "PUT", Path: deviceOfflineURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: device, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Errorf("OfflineDevice Err info :%s", errResp.Info)
@@ -607,7 +617,7 @@ func (chapiClient *Client) DeleteDevice(device *model.Device) (err error) {
 	var chapiResp Response
 	chapiResp.Data = &deviceResp
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "DELETE", Path: deviceURI, Header: chapiClient.header, Payload: device, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "DELETE", Path: deviceURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: device, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Errorf("DeleteDevice Err info %s", errResp.Info)
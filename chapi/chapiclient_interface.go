@@ -0,0 +1,32 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi
+
+import (
+	"github.com/hpe-storage/common-host-libs/model"
+)
+
+// ClientInterface lists the CHAPI1 client methods consumed by the docker plugin and other CHAPI1
+// callers.  It is satisfied by the CHAPI1 Client object as well as by the chapi2/compat package,
+// which implements the same methods on top of the CHAPI2 endpoints.  Callers should depend on this
+// interface, rather than *Client, so that they can migrate to CHAPI2 incrementally.
+type ClientInterface interface {
+	GetHostID() (string, error)
+	GetHostName() (*model.Host, error)
+	GetInitiators() ([]*model.Initiator, error)
+	GetChapInfo() (*model.ChapInfo, error)
+	GetNetworks() ([]*model.NetworkInterface, error)
+	AttachDevice(volumes []*model.Volume) ([]*model.Device, error)
+	AttachAndMountDevice(volume *model.Volume, mountPath string) error
+	CreateFilesystem(device *model.Device, vol *model.Volume, filesystem string) error
+	SetupFilesystemAndPermissions(device *model.Device, vol *model.Volume, filesystem string) error
+	Mount(reqMount *model.Mount, respMount *model.Mount) error
+	MountFilesystem(volume *model.Volume, mountPoint string) error
+	GetDevices() ([]*model.Device, error)
+	GetDeviceFromVolume(volume *model.Volume) (*model.Device, error)
+	GetMounts(respMount *[]*model.Mount, serialNumber string) error
+	UnmountDevice(volume *model.Volume) error
+	Unmount(reqMount *model.Mount, respMount *model.Mount) error
+	OfflineDevice(device *model.Device) error
+	DeleteDevice(device *model.Device) error
+}
@@ -16,6 +16,11 @@ import (
 	"github.com/hpe-storage/common-host-libs/model"
 )
 
+var (
+	// Compilation fails if the Linux CHAPI client stops satisfying ClientInterface
+	_ ClientInterface = &Client{}
+)
+
 // GetSocketName returns unix socket name (per process)
 func GetSocketName() string {
 	var socketName string
@@ -99,7 +104,7 @@ func (chapiClient *Client) GetHostName() (host *model.Host, err error) {
 	hostnameURI = fmt.Sprintf(HostnameURIfmt, fmt.Sprintf(HostURIfmt, chapiClient.hostID))
 
 	log.Tracef("GetHostName called with URI %s", hostnameURI)
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: hostnameURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: hostnameURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
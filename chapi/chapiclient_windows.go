@@ -21,6 +21,9 @@ import (
 
 var (
 	chapiPortFilePath = "c:\\ProgramData\\Nimble Storage\\CHAPI\\CHAPIPort.txt"
+
+	// Compilation fails if the Windows CHAPI client stops satisfying ClientInterface
+	_ ClientInterface = &Client{}
 )
 
 func init() {
@@ -91,7 +94,7 @@ func (chapiClient *Client) GetAccessKey() (string, error) {
 	var chapiResp Response
 	chapiResp.Data = &accessKeyPath
 	chapiResp.Err = &errResp
-	_, err := chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: "/keyfile", Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err := chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: "/keyfile", Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if errResp != nil {
 		log.Trace(errResp.Info)
 		return "", errors.New(errResp.Info)
@@ -151,7 +154,7 @@ func (chapiClient *Client) GetHostName() (host *model.Host, err error) {
 	hostnameURI = "/hosts"
 
 	log.Trace("GetHostName called with URI %s", hostnameURI)
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: hostnameURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "GET", Path: hostnameURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -274,7 +277,7 @@ func (chapiClient *Client) DetachDevice(device *model.Device) (err error) {
 	var chapiResp Response
 	chapiResp.Data = &deviceResp
 	chapiResp.Err = &errResp
-	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "DELETE", Path: deviceURI, Header: chapiClient.header, Payload: device, Response: &chapiResp, ResponseError: &chapiResp})
+	_, err = chapiClient.client.DoJSON(&connectivity.Request{Action: "DELETE", Path: deviceURI, Header: chapiClient.header, Context: chapiClient.ctx, Payload: device, Response: &chapiResp, ResponseError: &chapiResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
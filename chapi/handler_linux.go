@@ -12,6 +12,7 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/hpe-storage/common-host-libs/connectivity"
 	"github.com/hpe-storage/common-host-libs/linux"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/model"
@@ -27,6 +28,8 @@ var (
 	//ChapidSocketName : chapid socket name
 	ChapidSocketName = "chapid"
 	driver           Driver
+	// requestLimits are the limits applied to the request bodies, set by NewRouter
+	requestLimits = connectivity.DefaultRequestLimits()
 )
 
 // initialize the host gob file only once
@@ -166,8 +169,7 @@ func createDevices(w http.ResponseWriter, r *http.Request) {
 	createDeviceLock.Lock()
 	defer createDeviceLock.Unlock()
 	var vols []*model.Volume
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&vols)
+	err = connectivity.DecodeJSON(r.Body, &vols, requestLimits)
 	defer r.Body.Close()
 
 	if err != nil {
@@ -203,8 +205,7 @@ func offlineDevice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var device *model.Device
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&device)
+	err = connectivity.DecodeJSON(r.Body, &device, requestLimits)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
 		return
@@ -244,8 +245,7 @@ func deleteDevice(w http.ResponseWriter, r *http.Request) {
 	defer removeDeviceLock.Unlock()
 
 	var device *model.Device
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&device)
+	err = connectivity.DecodeJSON(r.Body, &device, requestLimits)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
 		return
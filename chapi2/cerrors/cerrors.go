@@ -5,6 +5,7 @@ package cerrors
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
@@ -12,22 +13,25 @@ import (
 type ChapiErrorCode uint32
 
 const (
-	OK                ChapiErrorCode = 0
-	Canceled          ChapiErrorCode = 1
-	Unknown           ChapiErrorCode = 2
-	InvalidArgument   ChapiErrorCode = 3
-	NotFound          ChapiErrorCode = 4
-	AlreadyExists     ChapiErrorCode = 5
-	PermissionDenied  ChapiErrorCode = 6
-	ResourceExhausted ChapiErrorCode = 7
-	Aborted           ChapiErrorCode = 8
-	Unimplemented     ChapiErrorCode = 9
-	Internal          ChapiErrorCode = 10
-	DataLoss          ChapiErrorCode = 11
-	Unauthenticated   ChapiErrorCode = 12
-	Timeout           ChapiErrorCode = 13
-	ConnectionFailed  ChapiErrorCode = 14
-	_maxCode          ChapiErrorCode = 15
+	OK                  ChapiErrorCode = 0
+	Canceled            ChapiErrorCode = 1
+	Unknown             ChapiErrorCode = 2
+	InvalidArgument     ChapiErrorCode = 3
+	NotFound            ChapiErrorCode = 4
+	AlreadyExists       ChapiErrorCode = 5
+	PermissionDenied    ChapiErrorCode = 6
+	ResourceExhausted   ChapiErrorCode = 7
+	Aborted             ChapiErrorCode = 8
+	Unimplemented       ChapiErrorCode = 9
+	Internal            ChapiErrorCode = 10
+	DataLoss            ChapiErrorCode = 11
+	Unauthenticated     ChapiErrorCode = 12
+	Timeout             ChapiErrorCode = 13
+	ConnectionFailed    ChapiErrorCode = 14
+	Busy                ChapiErrorCode = 15
+	ReadOnly            ChapiErrorCode = 16
+	ReservationConflict ChapiErrorCode = 17
+	_maxCode            ChapiErrorCode = 18
 )
 
 const (
@@ -35,16 +39,19 @@ const (
 )
 
 type ChapiError struct {
-	Code ChapiErrorCode `json:"code"`
-	Text string         `json:"text,omitempty"`
+	Code    ChapiErrorCode `json:"code"`
+	Text    string         `json:"text,omitempty"`
+	Details interface{}    `json:"details,omitempty"` // Optional structured details (e.g. processes blocking an unmount)
 }
 
 // NewChapiError takes an array of objects and returns a pointer to a ChapiError object.  The
 // following input parameters, in any order, are supported:
-//     ChapiError     - ChapiError object
-//     error          - All other error objects
-//     ChapiErrorCode - CHAPI error code
-//     string         - CHAPI error text
+//
+//	ChapiError     - ChapiError object
+//	error          - All other error objects
+//	ChapiErrorCode - CHAPI error code
+//	string         - CHAPI error text
+//
 // This routine parses the input data to create and return a new ChapiError object
 func NewChapiError(args ...interface{}) *ChapiError {
 
@@ -111,6 +118,12 @@ func NewChapiErrorf(c ChapiErrorCode, format string, a ...interface{}) *ChapiErr
 	return &ChapiError{Code: c, Text: fmt.Sprintf(format, a...)}
 }
 
+// WithDetails attaches structured details to the ChapiError and returns the same object
+func (e *ChapiError) WithDetails(details interface{}) *ChapiError {
+	e.Details = details
+	return e
+}
+
 func (e *ChapiError) Error() string {
 	return fmt.Sprintf("status: %d msg: %s", e.Code, e.Text)
 }
@@ -136,6 +149,17 @@ func (e *ChapiError) ErrorText() string {
 	return e.Text
 }
 
+// ParseChapiErrorCode returns the CHAPI error code with the given name (e.g. "Timeout").  The name
+// is case insensitive.  false is returned if the name is not a known error code.
+func ParseChapiErrorCode(name string) (ChapiErrorCode, bool) {
+	for c := OK; c < _maxCode; c++ {
+		if strings.EqualFold(c.String(), name) {
+			return c, true
+		}
+	}
+	return _maxCode, false
+}
+
 func (c ChapiErrorCode) String() string {
 	switch c {
 	case OK:
@@ -168,6 +192,12 @@ func (c ChapiErrorCode) String() string {
 		return "Timeout"
 	case ConnectionFailed:
 		return "ConnectionFailed"
+	case Busy:
+		return "Busy"
+	case ReadOnly:
+		return "ReadOnly"
+	case ReservationConflict:
+		return "ReservationConflict"
 	default:
 		return "Code(" + strconv.FormatInt(int64(c), 10) + ")"
 	}
@@ -0,0 +1,33 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package cerrors
+
+import (
+	"github.com/hpe-storage/common-host-libs/sgio"
+)
+
+// ScsiErrToCerrors takes a failed SCSI command error (sgio.ScsiError) and converts it to a cerrors
+// object.  The error text includes the decoded sense data and the details hold the SCSI status and
+// sense data (e.g. sense key, ASC/ASCQ and reason).  All other errors are returned as is.
+func ScsiErrToCerrors(err error) error {
+	scsiErr, ok := err.(*sgio.ScsiError)
+	if !ok {
+		return err
+	}
+
+	code := Internal
+	switch {
+	case scsiErr.IsReservationConflict():
+		code = ReservationConflict
+	case scsiErr.Sense != nil && scsiErr.Sense.Asc == sgio.AscLogicalUnitNotSupported:
+		code = NotFound
+	case scsiErr.Sense != nil && scsiErr.Sense.SenseKey == sgio.SenseKeyNotReady,
+		scsiErr.Status == sgio.ScsiStatusBusy,
+		scsiErr.Status == sgio.ScsiStatusTaskSetFull:
+		code = Busy
+	case scsiErr.Sense != nil && scsiErr.Sense.SenseKey == sgio.SenseKeyUnitAttention,
+		scsiErr.Status == sgio.ScsiStatusTaskAborted:
+		code = Aborted
+	}
+	return NewChapiError(code, scsiErr.Error()).WithDetails(scsiErr)
+}
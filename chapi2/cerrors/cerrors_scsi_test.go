@@ -0,0 +1,49 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package cerrors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/sgio"
+)
+
+func TestScsiErrToCerrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   *sgio.ScsiError
+		code  ChapiErrorCode
+		sense string
+	}{
+		{"lun not supported", sgio.NewScsiError(sgio.ScsiStatusCheckCondition, []byte{0x70, 0, 0x05, 0, 0, 0, 0, 10, 0, 0, 0, 0, 0x25, 0x00}), NotFound, "LOGICAL UNIT NOT SUPPORTED"},
+		{"not ready", sgio.NewScsiError(sgio.ScsiStatusCheckCondition, []byte{0x72, 0x02, 0x04, 0x01}), Busy, "LOGICAL UNIT IS IN PROCESS OF BECOMING READY"},
+		{"unit attention", sgio.NewScsiError(sgio.ScsiStatusCheckCondition, []byte{0x72, 0x06, 0x29, 0x00}), Aborted, "POWER ON, RESET, OR BUS DEVICE RESET OCCURRED"},
+		{"busy", sgio.NewScsiError(sgio.ScsiStatusBusy, nil), Busy, ""},
+		{"reservation conflict", sgio.NewScsiError(sgio.ScsiStatusReservationConflict, nil), ReservationConflict, ""},
+		{"medium error", sgio.NewScsiError(sgio.ScsiStatusCheckCondition, []byte{0x72, 0x03, 0x11, 0x00}), Internal, "UNRECOVERED READ ERROR"},
+	}
+	for _, tc := range tests {
+		err, ok := ScsiErrToCerrors(tc.err).(*ChapiError)
+		if !ok {
+			t.Errorf("%v: expected a ChapiError", tc.name)
+			continue
+		}
+		if err.Code != tc.code || err.Text != tc.err.Error() {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		}
+		details, ok := err.Details.(*sgio.ScsiError)
+		if !ok {
+			t.Errorf("%v: unexpected details %v", tc.name, err.Details)
+			continue
+		}
+		if tc.sense != "" && (details.Sense == nil || details.Sense.Reason != tc.sense) {
+			t.Errorf("%v: unexpected sense %+v, expected %v", tc.name, details.Sense, tc.sense)
+		}
+	}
+
+	otherErr := errors.New("other error")
+	if err := ScsiErrToCerrors(otherErr); err != otherErr {
+		t.Errorf("expected other errors to be returned as is, got %v", err)
+	}
+}
@@ -4,16 +4,252 @@ package chapi2
 
 import (
 	"github.com/gorilla/mux"
+	"github.com/hpe-storage/common-host-libs/chapi2/driver"
 	"github.com/hpe-storage/common-host-libs/chapi2/handler"
+	"github.com/hpe-storage/common-host-libs/connectivity"
+	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/util"
 )
 
+// diagnosticsEndpoints are the read-only endpoints also served on the diagnostics listener
+var diagnosticsEndpoints = map[string]bool{
+	"/api/v1/health":              true,
+	"/api/v1/latency":             true,
+	"/api/v1/openapi.json":        true,
+	"/api/v1/hosts":               true,
+	"/api/v1/networks":            true,
+	"/api/v1/initiators":          true,
+	"/api/v1/targets/unconnected": true,
+	"/api/v1/targets/fc":          true,
+	"/api/v1/readiness":           true,
+	"/api/v1/capabilities":        true,
+	"/api/v1/devices":             true,
+	"/api/v1/devices/details":     true,
+	"/api/v1/mounts":              true,
+	"/api/v1/mounts/details":      true,
+	"/api/v1/state":               true,
+}
+
 // NewRouter creates a new mux.Router
 func NewRouter() *mux.Router {
+	// Fault injection is opt-in (see FAULT INJECTION in chapi_faults.go)
+	loadFaultInjectionConfigFromEnv()
+	// API token authorization is opt-in (see API TOKENS in chapi_auth.go)
+	loadAPITokensConfigFromEnv()
+	handler.SetOpenAPIDocument(newOpenAPIDocument())
+	routes := getRoutes()
+	// Request limits are read once (see connectivity.GetRequestLimits)
+	requestLimits := connectivity.GetRequestLimits()
+	handler.SetRequestLimits(requestLimits)
+	for i := range routes {
+		routes[i].HandlerFunc = latencyHandler(routes[i].Name, faultInjectionHandler(routes[i].Name, routes[i].HandlerFunc))
+		routes[i].HandlerFunc = apiTokenHandler(routes[i].Name, routes[i].Method, routes[i].HandlerFunc)
+		routes[i].HandlerFunc = connectivity.LimitRequestBody(routes[i].HandlerFunc, requestLimits.MaxBytes)
+		if routes[i].Method == "GET" {
+			routes[i].HandlerFunc = handler.ContentNegotiationHandler(routes[i].HandlerFunc)
+		}
+	}
+
+	router := mux.NewRouter().StrictSlash(true)
+	util.InitializeRouter(router, routes)
+	return router
+}
+
+// checkIscsiInitiatorName checks, before any volume is attached, that the iSCSI initiator name is
+// not shared with a cloned host (see driver.CheckIscsiInitiatorName).  Failures are logged but not
+// fatal.
+func checkIscsiInitiatorName() {
+	if err := (&driver.ChapiServer{}).CheckIscsiInitiatorName(); err != nil {
+		log.Errorf("Unable to check the iSCSI initiator name, err=%v", err)
+	}
+}
+
+// NewDiagnosticsRouter creates a new mux.Router that only serves the read-only diagnostics
+// endpoints, without request header validation
+func NewDiagnosticsRouter() *mux.Router {
+	handler.SetOpenAPIDocument(newOpenAPIDocument())
+	var routes []util.Route
+	for _, route := range getRoutes() {
+		if route.Method == "GET" && diagnosticsEndpoints[route.Pattern] {
+			route.HandlerFunc = handler.ContentNegotiationHandler(handler.DiagnosticsHandler(route.HandlerFunc))
+			routes = append(routes, route)
+		}
+	}
+	router := mux.NewRouter().StrictSlash(true)
+	util.InitializeRouter(router, routes)
+	return router
+}
+
+// getRoutes returns all the CHAPI endpoints
+func getRoutes() []util.Route {
 	routes := []util.Route{
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/health
+		// Description: 	Reports whether CHAPI is able to respond, along with its uptime and, if
+		//					logging to a file, the log disk usage (see logger.GetLogDiskUsage).
+		//					Also served on the read-only diagnostics listener (see RunDiagnostics).
+		// Input Object:	None
+		// Output Object:	chapi2.Health object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "status": "ok",
+		//         "uptime_seconds": 3600,
+		//         "logs": {
+		//             "directory": "/var/log",
+		//             "log_bytes": 52428800,
+		//             "rotated_files": 4,
+		//             "pruned_files": 0,
+		//             "free_bytes": 2147483648,
+		//             "min_free_bytes": 67108864,
+		//             "degraded": false
+		//         }
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "Health",
+			Method:      "GET",
+			Pattern:     "/api/v1/health",
+			HandlerFunc: handler.GetHealth,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/latency
+		// Description: 	Reports the request latency histogram of each endpoint along with the
+		//					slow request threshold.  Requests exceeding the threshold (see
+		//					CHAPI_SLOW_REQUEST_THRESHOLD_MS) are logged with their stage timings
+		//					(e.g. login, rescan, mkfs and mount).
+		// Input Object:	None
+		// Output Object:	chapi2.LatencyReport object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "slow_threshold_ms": 30000,
+		//         "endpoints": [
+		//             {
+		//                 "endpoint": "CreateDevice",
+		//                 "count": 2,
+		//                 "slow_count": 1,
+		//                 "total_ms": 47250,
+		//                 "max_ms": 42100,
+		//                 "buckets": [
+		//                     {"le_ms": 10, "count": 0},
+		//                     ...
+		//                     {"le_ms": 5000, "count": 1},
+		//                     ...
+		//                     {"le_ms": 60000, "count": 1},
+		//                     {"le_ms": 120000, "count": 0},
+		//                     {"le_ms": -1, "count": 0}
+		//                 ]
+		//             }
+		//         ]
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "Latency",
+			Method:      "GET",
+			Pattern:     "/api/v1/latency",
+			HandlerFunc: handler.GetLatency,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/logging
+		// Description: 	Reports the active log level and format along with the log level
+		//					overrides of individual components (source directories).
+		// Input Object:	None
+		// Output Object:	logger.LogConfig object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "level": "info",
+		//         "format": "text",
+		//         "components": {
+		//             "chapi2/iscsi": "trace"
+		//         }
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetLogging",
+			Method:      "GET",
+			Pattern:     "/api/v1/logging",
+			HandlerFunc: handler.GetLogging,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/logging
+		// Description: 	Changes the active log level and format without restarting CHAPI (e.g.
+		//					to enable trace logging during a live incident and revert it
+		//					afterwards).  Settings omitted from the input object are left
+		//					unchanged.  If present, "components" replaces the component level
+		//					overrides; an empty object removes them.  Nothing is changed if any
+		//					setting is invalid.
+		// Input Object:	logger.LogConfig object
+		// Output Object:	logger.LogConfig object (configuration in use after the update)
+		// Sample Input:    {
+		//                      "level": "debug",
+		//                      "components": {
+		//                          "chapi2/iscsi": "trace"
+		//                      }
+		//                  }
+		// Sample Output:	See "GET /api/v1/logging" endpoint
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "SetLogging",
+			Method:      "PUT",
+			Pattern:     "/api/v1/logging",
+			HandlerFunc: handler.SetLogging,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/openapi.json
+		// Description: 	Returns the OpenAPI 3 document describing the CHAPI endpoints, generated
+		//					from the route table and model objects (see chapi_openapi.go), so that
+		//					clients can be generated in other languages.  The document is returned
+		//					as is, not in the "data" envelope.  Also served on the read-only
+		//					diagnostics listener.
+		// Input Object:	None
+		// Output Object:	OpenAPI 3 document
+		// Sample Output:
+		// {
+		//     "openapi": "3.0.3",
+		//     "info": {
+		//         "title": "CHAPI",
+		//         "version": "1.0.0"
+		//     },
+		//     "paths": {
+		//         "/api/v1/devices/{serialNumber}": {
+		//             "delete": {
+		//                 "operationId": "DeleteDevice",
+		//                 ...
+		//             }
+		//         },
+		//         ...
+		//     },
+		//     "components": {
+		//         "schemas": {
+		//             "Device": {
+		//                 ...
+		//             },
+		//             ...
+		//         }
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "OpenAPI",
+			Method:      "GET",
+			Pattern:     "/api/v1/openapi.json",
+			HandlerFunc: handler.GetOpenAPI,
+		},
+
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		GET /hosts
-		// Description: 	This endpoint returns host information.
+		// Description: 	This endpoint returns host information, including the kernel (Windows)
+		//					version and the versions of the kernel modules (drivers) affecting
+		//					storage.
 		// Input Object:	None
 		// Output Object:	chapi2.Host object
 		// Sample Output:
@@ -23,6 +259,15 @@ func NewRouter() *mux.Router {
 		//         "id": "827c1723-5742-4661-be56-121edb7e263c",            "id":  "4ba7f223-f4ce-4aca-99ff-a150b6df50be",
 		//         "name": "localhost.localdomain",                         "name":  "HITDEV-WIN011",
 		//         "domain":  "americas.domain.net",                       "domain":  "americas.domain.net",
+		//         "kernel_version": "4.18.0-80.el8.x86_64",                "kernel_version":  "10.0.17763",
+		//         "modules": [                                             "modules":  [
+		//             {                                                        {
+		//                 "name": "dm_multipath",                                  "name":  "msdsm",
+		//                 "version": "4.18.0-80.el8.x86_64",                       "version":  "10.0.17763.1",
+		//                 "loaded": true                                           "loaded":  true
+		//             },                                                       },
+		//             ...                                                      ...
+		//         ]                                                        ]
 		//     }                                                        }
 		// }                                                        }
 		///////////////////////////////////////////////////////////////////////////////////////////
@@ -100,9 +345,241 @@ func NewRouter() *mux.Router {
 			HandlerFunc: handler.GetHostInitiators,
 		},
 
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/initiators/actions/rotate-credentials
+		// Description: 	Applies a new iSCSI initiator name and/or CHAP credentials, already
+		//					accepted by the array, and logs in again the logged in targets one
+		//					connection at a time so that the devices never lose all of their
+		//					paths.  Persistent logins (Linux node records, Windows persistent
+		//					logins) are updated.  Targets with a single connection are skipped
+		//					unless allow_single_path is set.  The first failed re-login stops the
+		//					rotation; the error "details" lists the outcome of each target.
+		//					Windows cannot report the current CHAP credentials, so they must be
+		//					provided again when only the initiator name is rotated.
+		// Input Object:	chapi2.CredentialRotation object
+		//                          rotation.InitiatorName (optional)
+		//                          rotation.ChapUser and rotation.ChapPassword (optional)
+		//                          rotation.Targets (optional, all logged in targets if empty)
+		//                          rotation.AllowSinglePath (optional)
+		// Output Object:	Array of chapi2.ReloginResult objects
+		// Sample Output:
+		// {
+		//     "data": [
+		//         {
+		//             "target_name": "iqn.2007-11.com.nimblestorage:vol1-v3b2a4c7f1e8d9c60.0000001a.6a8e1f0c",
+		//             "connections": 4,
+		//             "relogged": 4
+		//         }
+		//     ]
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "RotateCredentials",
+			Method:      "PUT",
+			Pattern:     "/api/v1/initiators/actions/rotate-credentials",
+			HandlerFunc: handler.RotateIscsiCredentials,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/targets/unconnected
+		// Description: 	This endpoint returns the iSCSI targets that are configured on the host
+		//					(Windows persistent logins, Linux node records) but have no active
+		//					session.  The last login failure reason is included where obtainable.
+		// Input Object:	None
+		// Output Object:	Array of chapi2.UnconnectedTarget objects
+		// Sample Output:
+		// LINUX                                                  WINDOWS
+		// {                                                      {
+		//     "data":  [                                             "data":  [
+		//         {                                                      {
+		//             "name":  "iqn.2007-11.com.nimblestorage:v1",           "name":  "iqn.2007-11.com.nimblestorage:v1",
+		//             "target_portals":  [                                   "target_portals":  [
+		//                 {                                                      {
+		//                     "address":  "xxx.xxx.xxx.xxx",                         "address":  "xxx.xxx.xxx.xxx",
+		//                     "port":  "3260",                                       "port":  "3260"
+		//                     "tag":  "2460"                                     }
+		//                 }                                                  ],
+		//             ],                                                     "source":  "persistent_login",
+		//             "source":  "node_record",                              "last_error":  "target not found"
+		//             "startup":  "automatic"                            }
+		//         }                                                  ]
+		//     ]                                                  }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "UnconnectedTargets",
+			Method:      "GET",
+			Pattern:     "/api/v1/targets/unconnected",
+			HandlerFunc: handler.GetUnconnectedTargets,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/targets/fc
+		// Description: 	This endpoint returns the remote FC target ports discovered by the
+		//					host ports along with their login state.  The discovering host port is
+		//					only reported on Linux.
+		// Input Object:	None
+		// Output Object:	Array of chapi2.FcTargetPort objects
+		// Sample Output:
+		// LINUX                                                  WINDOWS
+		// {                                                      {
+		//     "data":  [                                             "data":  [
+		//         {                                                      {
+		//             "host_port_wwn":  "10000090fa736eca",                  "port_wwn":  "56:C9:CE:90:7F:F1:E2:01",
+		//             "port_wwn":  "56c9ce907ff1e201",                       "node_wwn":  "56:C9:CE:90:7F:F1:E2:00",
+		//             "node_wwn":  "56c9ce907ff1e200",                       "port_state":  "Online"
+		//             "port_state":  "Online"                            }
+		//         }                                                  ]
+		//     ]                                                  }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "FcTargetPorts",
+			Method:      "GET",
+			Pattern:     "/api/v1/targets/fc",
+			HandlerFunc: handler.GetFcTargetPorts,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/targets/actions/collect-stale-sessions
+		// Description: 	Logs out the iSCSI targets whose sessions have reported no LUN for at
+		//					least min_age seconds (CHAPI_ISCSI_STALE_SESSION_AGE, default 600),
+		//					e.g. after the volume's access was removed on the array, and removes
+		//					their persistent logins (Windows) or node records (Linux).  A target's
+		//					age starts when CHAPI first sees it without any LUN.  Every target
+		//					without a LUN is returned; dry_run only reports them.
+		// Input Object:	chapi2.StaleSessionCollection object
+		//                          collection.Targets (optional, all logged in targets if empty)
+		//                          collection.MinAge (optional)
+		//                          collection.DryRun (optional)
+		// Output Object:	Array of chapi2.StaleTarget objects
+		// Sample Output:
+		// {
+		//     "data": [
+		//         {
+		//             "target_name": "iqn.2007-11.com.nimblestorage:vol1-v3b2a4c7f1e8d9c60.0000001a.6a8e1f0c",
+		//             "sessions": 2,
+		//             "empty_seconds": 1260,
+		//             "logged_out": true
+		//         }
+		//     ]
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "CollectStaleSessions",
+			Method:      "PUT",
+			Pattern:     "/api/v1/targets/actions/collect-stale-sessions",
+			HandlerFunc: handler.CollectStaleSessions,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/targets/actions/invalidate-lun-maps
+		// Description: 	Drops the cached LUN maps of the given iSCSI targets, or every cached
+		//					LUN map, e.g. after LUNs were remapped on the array without a rescan.
+		//					A LUN map resolves the serial numbers of a group scoped target's LUNs
+		//					to their devices until the next rescan (see GROUP SCOPED TARGET LUN
+		//					MAP in the multipath package).  The targets whose map was dropped are
+		//					returned.
+		// Input Object:	chapi2.LunMapInvalidation object
+		//                          invalidation.Targets (optional, every cached LUN map if empty)
+		// Output Object:	Array of target names
+		// Sample Output:
+		// {
+		//     "data": [
+		//         "iqn.2007-11.com.nimblestorage:group-g1a2b3c4d5e6f7a8"
+		//     ]
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "InvalidateLunMaps",
+			Method:      "PUT",
+			Pattern:     "/api/v1/targets/actions/invalidate-lun-maps",
+			HandlerFunc: handler.InvalidateLunMaps,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/readiness
+		// Description: 	This endpoint reports how ready the host is to use HPE storage.  Host
+		//					recommendations, services, multipath, kernel modules (or Windows
+		//					drivers) and connectivity are each scored pass (100), warn (50) or
+		//					fail (0); see NODE READINESS in driver_readiness.go.  If
+		//					CHAPI_PATH_MTU_CHECK is "true", connectivity includes a "path_mtu"
+		//					check of the logged in target portals.
+		// Input Object:	None
+		// Output Object:	chapi2.Readiness object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "status": "warn",
+		//         "score": 90,
+		//         "categories": [
+		//             {
+		//                 "name": "services",
+		//                 "status": "warn",
+		//                 "score": 50,
+		//                 "checks": [
+		//                     {
+		//                         "name": "iscsid",
+		//                         "status": "warn",
+		//                         "message": "service is inactive, iSCSI volumes cannot be attached"
+		//                     }
+		//                 ]
+		//             },
+		//             ...
+		//         ]
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "Readiness",
+			Method:      "GET",
+			Pattern:     "/api/v1/readiness",
+			HandlerFunc: handler.GetReadiness,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/capabilities
+		// Description: 	This endpoint lists the features supported on the host's platform (nvme,
+		//					fc, bind_mounts, expand, encryption and raw_block), with the reason each
+		//					unsupported feature is not supported; see CAPABILITIES in
+		//					driver_capabilities.go.
+		// Input Object:	None
+		// Output Object:	chapi2.Capabilities object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "os": "linux",
+		//         "features": [
+		//             {
+		//                 "name": "bind_mounts",
+		//                 "supported": true
+		//             },
+		//             {
+		//                 "name": "encryption",
+		//                 "supported": false,
+		//                 "reason": "host based encryption is not supported"
+		//             },
+		//             ...
+		//         ]
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "Capabilities",
+			Method:      "GET",
+			Pattern:     "/api/v1/capabilities",
+			HandlerFunc: handler.GetCapabilities,
+		},
+
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		GET /api/v1/devices
-		// Description: 	This endpoint returns all the Nimble volumes attached to the host
+		// Description: 	This endpoint returns all the Nimble volumes attached to the host, ordered
+		//					by serial number.  The optional "limit" and "offset" queries (e.g.
+		//					?limit=100&offset=200) return a single page of the devices, described
+		//					by the response's "page" property.  Ignored devices and devices whose
+		//					serial number prefix or inquiry product is listed in
+		//					CHAPI_SKIP_DEVICE_SERIAL_PREFIXES or CHAPI_SKIP_DEVICE_PRODUCTS (e.g.
+		//					array file service witness LUNs) are not returned.
 		// Input Object:	None
 		// Output Object:	Array of chapi2.Device objects with basic details
 		// Sample Output:
@@ -124,7 +601,13 @@ func NewRouter() *mux.Router {
 
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		GET /api/v1/devices/details
-		// Description: 	This endpoint returns all the Nimble volumes attached to the host
+		// Description: 	This endpoint returns all the Nimble volumes attached to the host.  The
+		//					response carries an ETag; polls with a matching If-None-Match header
+		//					return HTTP 304.  Responses are gzip encoded if the client accepts it.
+		//					Supports the "limit" and "offset" queries like "GET /api/v1/devices".
+		//					The optional "fields" query (e.g. ?fields=serial_number,size) only
+		//					returns the listed properties of each device; under Windows the iSCSI
+		//					target is only enumerated if "iscsi_target" is listed.
 		// Input Object:	None
 		// Output Object:	Array of chapi2.Device objects with detailed information
 		// Sample Output:
@@ -162,6 +645,60 @@ func NewRouter() *mux.Router {
 			HandlerFunc: handler.GetAllDeviceDetails,
 		},
 
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/devices/ignored
+		// Description: 	Returns the device WWIDs hidden from device enumeration.  Ignored devices
+		//					are not reported by the "GET /api/v1/devices" endpoints.
+		// Input Object:	None
+		// Output Object:	Array of chapi2.IgnoredDevice objects
+		// Sample Output:	{
+		//                      "data": [
+		//                          {
+		//                              "wwid": "28174883c7719ac236c9ce900584f2795"
+		//                          }
+		//                      ]
+		//                  }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetIgnoredDevices",
+			Method:      "GET",
+			Pattern:     "/api/v1/devices/ignored",
+			HandlerFunc: handler.GetIgnoredDevices,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		POST /api/v1/devices/ignored
+		// Description: 	Hides the specified device WWID from device enumeration, e.g. so that a
+		//					deleted volume rediscovered through a lingering session is not reported.
+		//					Under Linux, the WWID is also added to a CHAPI managed multipath
+		//					blacklist (/etc/multipath/conf.d) and multipathd is reconfigured.
+		// Input Object:	chapi2.IgnoredDevice object
+		// Output Object:	chapi2.IgnoredDevice object
+		// Sample Input:    {
+		//                      "wwid": "28174883c7719ac236c9ce900584f2795"
+		//                  }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "AddIgnoredDevice",
+			Method:      "POST",
+			Pattern:     "/api/v1/devices/ignored",
+			HandlerFunc: handler.AddIgnoredDevice,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		DELETE /api/v1/devices/ignored/{wwid}
+		// Description: 	Stops hiding the specified device WWID (and removes it from the Linux
+		//					multipath blacklist).  HTTP 404 is returned if the WWID is not ignored.
+		// Input Object:	None
+		// Output Object:	None (only Error details if request fails)
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "RemoveIgnoredDevice",
+			Method:      "DELETE",
+			Pattern:     "/api/v1/devices/ignored/{wwid}",
+			HandlerFunc: handler.RemoveIgnoredDevice,
+		},
+
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		GET /api/v1/devices/{serialNumber}/partitions
 		// Description: 	This endpoint returns partition information for the specified volume.
@@ -188,7 +725,17 @@ func NewRouter() *mux.Router {
 
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		POST /api/v1/devices
-		// Description: 	Connect to the specified Nimble volume.
+		// Description: 	Connect to the specified Nimble volume.  Invalid publish info fails with
+		//					HTTP 400; the error details list each invalid property, e.g.
+		//					{"field": "block_device.target_name", "error": "required"}.  If
+		//					"discovery_ips" are provided, each discovery IP is tried in order until
+		//					the target is discovered.  For a pre-zoned FC LUN, "fc_access_info"
+		//					"target_wwpns" and "lun_id" scan only that LUN on those target ports.
+		//					The attached device must serve a direct read of its first block within
+		//					CHAPI_DEVICE_PROBE_TIMEOUT seconds (default 5, 0 to disable).  A
+		//					volume persistently reserved by another host fails with HTTP 409 and a
+		//					ReservationConflict error whose details hold the reservation "type_name",
+		//					"holder_key" and "registered_keys" (Linux only).
 		// Input Object:	Array of chapi2.Volume objects
 		// Output Object:	Array of chapi2.Device objects
 		// Sample Input:    [
@@ -208,6 +755,50 @@ func NewRouter() *mux.Router {
 			HandlerFunc: handler.CreateDevice,
 		},
 
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		POST /api/v1/devices/actions/plan
+		// Description: 	Reports how "POST /api/v1/devices" would connect to the specified Nimble
+		//					volume without discovering or logging into the target: the connection
+		//					type and initiator/target port pairs that would be used, the expected
+		//					connection count and the preconditions that would fail the request (e.g.
+		//					invalid publish info, CHAP user without a password or a target that
+		//					cannot be discovered).  Invalid publish info is reported as blockers
+		//					rather than failing with HTTP 400.  If CHAPI_PATH_MTU_CHECK is "true",
+		//					"path_mtu" reports the path MTU of each connection, validated against
+		//					the NIC MTU; a smaller path MTU is reported as a warning.
+		// Input Object:	chapi2.PublishInfo object
+		// Output Object:	chapi2.AttachPlan object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "serial_number": "28174883c7719ac236c9ce900584f2795",
+		//         "access_protocol": "iscsi",
+		//         "target_name": "iqn.2007-11.com.nimblestorage:group-c32-array3-g5a2cdea9cf0b91f1",
+		//         "discovery_ip": "192.168.10.20",
+		//         "connect_type": "subnet",
+		//         "connections": [
+		//             {
+		//                 "initiator_address": "192.168.10.11",
+		//                 "target_address": "192.168.10.21",
+		//                 "target_port": "3260"
+		//             },
+		//             {
+		//                 "initiator_address": "192.168.10.11",
+		//                 "target_address": "192.168.10.22",
+		//                 "target_port": "3260"
+		//             }
+		//         ],
+		//         "connection_count": 2
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "PlanCreateDevice",
+			Method:      "POST",
+			Pattern:     "/api/v1/devices/actions/plan",
+			HandlerFunc: handler.PlanCreateDevice,
+		},
+
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		DELETE /api/v1/devices/{serialNumber}
 		// Description: 	Disconnects the specified Nimble serial number.  If it's an iSCSI GST
@@ -236,10 +827,267 @@ func NewRouter() *mux.Router {
 			HandlerFunc: handler.OfflineDevice,
 		},
 
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/devices/{serialNumber}/actions/extend-partition
+		// Description: 	Extends the last partition on the specified volume to fill the volume
+		//					(e.g. after the volume was expanded on the array).  Under Windows, the
+		//					host's storage cache is first updated (Update-HostStorageCache) and the
+		//					partition is then resized to its maximum supported size.  Not yet
+		//					supported on Linux.
+		// Input Object:	None
+		// Output Object:	Array of chapi2.DevicePartition objects (partitions after the update)
+		// Sample Output:	See "GET /api/v1/devices/{serialNumber}/partitions" endpoint
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "ExtendPartition",
+			Method:      "PUT",
+			Pattern:     "/api/v1/devices/{serialNumber}/actions/extend-partition",
+			HandlerFunc: handler.ExtendPartition,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		POST /api/v1/devices/{serialNumber}/actions/benchmark
+		// Description: 	Runs a short read-only latency probe against the specified volume, e.g.
+		//					to validate fabric health after attach and before the application
+		//					starts.  Reads of block_size bytes are issued one at a time at random
+		//					offsets using direct (unbuffered) I/O for duration_seconds (default 5,
+		//					at most 10).  Fails with HTTP 409 if the volume is mounted read-write.
+		// Input Object:	Optional chapi2.BenchmarkRequest object
+		// Output Object:	chapi2.BenchmarkResult object
+		// Sample Input:    {
+		//                      "duration_seconds": 5,
+		//                      "block_size": 4096
+		//                  }
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "serial_number": "28174883c7719ac236c9ce900584f2795",
+		//         "duration_ms": 5000,
+		//         "block_size": 4096,
+		//         "reads": 21837,
+		//         "iops": 4367.4,
+		//         "throughput_mb_per_sec": 17.06,
+		//         "latency_us": {
+		//             "min": 142,
+		//             "avg": 228,
+		//             "p50": 211,
+		//             "p90": 297,
+		//             "p99": 512,
+		//             "p99_9": 1874,
+		//             "max": 4210
+		//         }
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "BenchmarkDevice",
+			Method:      "POST",
+			Pattern:     "/api/v1/devices/{serialNumber}/actions/benchmark",
+			HandlerFunc: handler.BenchmarkDevice,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/devices/{serialNumber}/tuning
+		// Description: 	Returns the I/O queue settings currently in use by the specified volume.
+		//					On Windows only the queue depth is reported; it is the lowest outstanding
+		//					I/O limit (MaxNumberOfIO) of the StorPort adapters the volume is reached
+		//					through, and is omitted if none is configured.
+		// Input Object:	None
+		// Output Object:	chapi2.DeviceTuning object
+		// Sample Output:
+		// LINUX                                    WINDOWS
+		// {                                        {
+		//     "data": {                                "data": {
+		//         "nr_requests": 128,                      "queue_depth": 256
+		//         "queue_depth": 64,                   }
+		//         "read_ahead_kb": 4096,           }
+		//         "rq_affinity": 1,
+		//         "scheduler": "none"
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetDeviceTuning",
+			Method:      "GET",
+			Pattern:     "/api/v1/devices/{serialNumber}/tuning",
+			HandlerFunc: handler.GetDeviceTuning,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/devices/{serialNumber}/paths
+		// Description: 	Returns the paths to the specified volume grouped by their ALUA access
+		//					state, with the active/optimized paths first.  On Linux the access state
+		//					is read from sysfs (scsi_dh_alua) or queried from the array with REPORT
+		//					TARGET PORT GROUPS.  On Windows the paths are reported by MPIO
+		//					(MPIO_GET_DESCRIPTOR), named after their SCSI address, and their access
+		//					state by the Microsoft DSM (DSM_QueryLBPolicy_V2).
+		// Input Object:	None
+		// Output Object:	Array of chapi2.DevicePathGroup objects
+		// Sample Output:
+		// LINUX                                                    WINDOWS
+		// {                                                        {
+		//     "data": [                                                "data": [
+		//         {                                                        {
+		//             "access_state": "active/optimized",                      "access_state": "active/optimized",
+		//             "paths": [                                               "paths": [
+		//                 {                                                        {
+		//                     "name": "sdb",                                           "name": "2:0:0:1",
+		//                     "state": "running",                                      "state": "running",
+		//                     "access_state": "active/optimized",                      "access_state": "active/optimized",
+		//                     "target_port_group": 1,                                  "target_port_group": 1,
+		//                     "preferred": true                                        "preferred": true
+		//                 }                                                        }
+		//             ]                                                        ]
+		//         },                                                       },
+		//         {                                                        {
+		//             "access_state": "standby",                               "access_state": "standby",
+		//             "paths": [                                               "paths": [
+		//                 {                                                        {
+		//                     "name": "sdc",                                           "name": "2:0:1:1",
+		//                     "state": "running",                                      "state": "running",
+		//                     "access_state": "standby",                               "access_state": "standby",
+		//                     "target_port_group": 2                                   "target_port_group": 2
+		//                 }                                                        }
+		//             ]                                                        ]
+		//         }                                                        }
+		//     ]                                                        ]
+		// }                                                        }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetDevicePaths",
+			Method:      "GET",
+			Pattern:     "/api/v1/devices/{serialNumber}/paths",
+			HandlerFunc: handler.GetDevicePaths,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/devices/{serialNumber}/actions/recover-paths
+		// Description: 	Probes the failed paths of the specified volume with TEST UNIT READY and
+		//					reinstates the paths that respond, e.g. paths left failed by multipathd
+		//					after an array controller failover.  Failed paths are also recovered
+		//					periodically when CHAPI_PATH_RECOVERY_INTERVAL (seconds) is set.  Not
+		//					yet supported on Windows, where MPIO restores the paths itself.
+		// Input Object:	None
+		// Output Object:	chapi2.PathRecoveryReport object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "serial_number": "c5a28c28a2487d3d6c9ce900584f2795",
+		//         "paths": [
+		//             {
+		//                 "name": "sdc",
+		//                 "status": "reinstated"
+		//             },
+		//             {
+		//                 "name": "sde",
+		//                 "status": "unreachable",
+		//                 "error": "unable to open the device /dev/sde"
+		//             }
+		//         ],
+		//         "recovered": 1,
+		//         "failed": 1
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "RecoverDevicePaths",
+			Method:      "PUT",
+			Pattern:     "/api/v1/devices/{serialNumber}/actions/recover-paths",
+			HandlerFunc: handler.RecoverDevicePaths,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/devices/{serialNumber}/tuning
+		// Description: 	Applies I/O queue settings to the specified volume.  On Linux the
+		//					read_ahead_kb setting is applied to the dm device and its paths while
+		//					the remaining settings are applied to each path.  On Windows only the
+		//					queue depth can be set; it is set on every adapter of the miniport drivers
+		//					the volume is reached through, and applied when the adapters restart
+		//					(e.g. on reboot).  Settings omitted from the input object are left
+		//					unchanged.  Must be registered before the
+		//					"PUT /api/v1/devices/{serialNumber}/{fileSystem}" endpoint.
+		// Input Object:	chapi2.DeviceTuning object
+		// Output Object:	chapi2.DeviceTuning object (settings in use after the update)
+		// Sample Input:    {
+		//                      "read_ahead_kb": 4096,
+		//                      "scheduler": "none"
+		//                  }
+		// Sample Output:	See "GET /api/v1/devices/{serialNumber}/tuning" endpoint
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "SetDeviceTuning",
+			Method:      "PUT",
+			Pattern:     "/api/v1/devices/{serialNumber}/tuning",
+			HandlerFunc: handler.SetDeviceTuning,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/devices/{serialNumber}/claim
+		// Description: 	Returns the active ownership claim on the specified volume (see DEVICE
+		//					OWNERSHIP in driver_claim.go).  Fails with HTTP 404 if the volume is not
+		//					claimed or the claim has expired.
+		// Input Object:	None
+		// Output Object:	chapi2.DeviceClaim object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "serial_number": "6d70f5a2c0d7a8bd6c9ce900e3ba4f6c",
+		//         "owner": "csi-node",
+		//         "claimed": "2020-06-01T10:20:30Z",
+		//         "expires": "2020-06-01T10:25:30Z"
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetDeviceClaim",
+			Method:      "GET",
+			Pattern:     "/api/v1/devices/{serialNumber}/claim",
+			HandlerFunc: handler.GetDeviceClaim,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/devices/{serialNumber}/claim
+		// Description: 	Claims the specified volume for the given owner, or renews the owner's
+		//					claim, for ttl_seconds (default 300).  While the claim is active, the
+		//					requests that change the volume or its mount points fail with HTTP 403
+		//					unless they carry the owner in the X-Chapi-Owner header (or
+		//					X-Chapi-Claim-Override is "true").  A volume claimed by another owner
+		//					fails with HTTP 409 unless override is true.  Must be registered before
+		//					the "PUT /api/v1/devices/{serialNumber}/{fileSystem}" endpoint.
+		// Input Object:	chapi2.ClaimRequest object
+		// Output Object:	chapi2.DeviceClaim object
+		// Sample Input:    {
+		//                      "owner": "csi-node",
+		//                      "ttl_seconds": 300
+		//                  }
+		// Sample Output:	See "GET /api/v1/devices/{serialNumber}/claim" endpoint
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "ClaimDevice",
+			Method:      "PUT",
+			Pattern:     "/api/v1/devices/{serialNumber}/claim",
+			HandlerFunc: handler.ClaimDevice,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		DELETE /api/v1/devices/{serialNumber}/claim
+		// Description: 	Releases the owner's claim on the specified volume.  Releasing a volume
+		//					that is not claimed succeeds.  A volume claimed by another owner fails
+		//					with HTTP 403 unless override is true.
+		// Input Object:	chapi2.ClaimRequest object (ttl_seconds is ignored)
+		// Output Object:	None
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "ReleaseDevice",
+			Method:      "DELETE",
+			Pattern:     "/api/v1/devices/{serialNumber}/claim",
+			HandlerFunc: handler.ReleaseDevice,
+		},
+
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		PUT /api/v1/devices/{serialNumber}/{fileSystem}
 		// Description: 	Formats the specified volume with the specified file system.
-		// Input Object:	None
+		// Input Object:	Optional chapi2.FileSystemOptions object (only NoDiscard and FullFormat apply)
 		// Output Object:	None
 		// Sample Output:	See "GET /hosts/{id}/devices" endpoint
 		///////////////////////////////////////////////////////////////////////////////////////////
@@ -250,9 +1098,41 @@ func NewRouter() *mux.Router {
 			HandlerFunc: handler.CreateFileSystem,
 		},
 
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/devices/{serialNumber}/format
+		// Description: 	Reports the progress of the file system creation in progress on the
+		//					specified volume (see "PUT /api/v1/devices/{serialNumber}/{fileSystem}").
+		//					The percent complete is only reported for Windows full formats, polled
+		//					from the format's storage job.  Fails with a NotFound error if no file
+		//					system creation is in progress.
+		// Input Object:	None
+		// Output Object:	chapi2.FormatStatus object
+		// Sample Output:
+		// LINUX         WINDOWS
+		// TODO          {
+		//                   "data":  {
+		//                       "serial_number":  "2ab8d1c0d4b4a6d46c9ce9003bc8aa27",
+		//                       "file_system":  "NTFS",
+		//                       "full_format":  true,
+		//                       "percent_complete":  42,
+		//                       "elapsed_ms":  1834512
+		//                   }
+		//               }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetFormatStatus",
+			Method:      "GET",
+			Pattern:     "/api/v1/devices/{serialNumber}/format",
+			HandlerFunc: handler.GetFormatStatus,
+		},
+
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		GET /api/v1/mounts
-		// Description: 	Enumerates all mount points on the host, optionally with given serial number
+		// Description: 	Enumerates all mount points on the host, optionally with given serial number.
+		//					The optional "mountPointPrefix" query (e.g. ?mountPointPrefix=/var/lib/kubelet)
+		//					only reports the mount points at or beneath the given path.  Mount
+		//					points are ordered by path and support the "limit" and "offset"
+		//					queries like "GET /api/v1/devices".
 		// Input Object:	None
 		// Output Object:	Array of chapi2.Mount objects
 		// Sample Output:
@@ -277,7 +1157,10 @@ func NewRouter() *mux.Router {
 
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		GET /api/v1/mounts/details
-		// Description: 	Enumerates all mount points on the host with detailed information, optionally with given serial number
+		// Description: 	Enumerates all mount points on the host with detailed information, optionally with given serial number.
+		//					Supports ETag/If-None-Match (HTTP 304) and gzip like "GET /api/v1/devices/details".
+		//					Supports the "mountPointPrefix", "limit" and "offset" queries like "GET /api/v1/mounts".
+		//					Supports the "fields" query like "GET /api/v1/devices/details".
 		// Input Object:	None
 		// Output Object:	Array of chapi2.Mount objects
 		// Sample Output:
@@ -304,13 +1187,52 @@ func NewRouter() *mux.Router {
 			HandlerFunc: handler.GetAllMountDetails,
 		},
 
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/mounts/driveletters
+		// Description: 	Windows only - returns the free drive letters in the drive letter pool, in
+		//					the order they are assigned to "*" mount points.  The pool defaults to
+		//					D through Z and is configured with the CHAPI_DRIVE_LETTER_POOL
+		//					environment variable (e.g. "E,F,X-Z").
+		// Input Object:	None
+		// Output Object:	Array of drive letter paths
+		// Sample Output:
+		// {
+		//     "data":  [
+		//                  "E:\\",
+		//                  "F:\\",
+		//                  "X:\\"
+		//              ]
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetFreeDriveLetters",
+			Method:      "GET",
+			Pattern:     "/api/v1/mounts/driveletters",
+			HandlerFunc: handler.GetFreeDriveLetters,
+		},
+
 		///////////////////////////////////////////////////////////////////////////////////////////
 		// Endpoint:  		POST /api/v1/mounts
-		// Description: 	Mount a Nimble volume to the specified mount point location.
+		// Description: 	Mount a Nimble volume to the specified mount point location.  Under
+		//					Linux, if the volume is already mounted elsewhere, the existing (primary)
+		//					mount point is bind mounted to the requested location and a new mount
+		//					point ID is returned for the bind mount.
+		//					Under Windows, a mount point of "*" mounts the volume to the next free
+		//					drive letter in the drive letter pool (see
+		//					"GET /api/v1/mounts/driveletters") and returns the assigned drive letter.
+		//					If no drive letter is free, or if the volume's GUID path (e.g.
+		//					"\\?\Volume{GUID}\") is requested, the volume is only exposed through
+		//					its volume GUID path and any directory mount points.
 		// Input Object:	chapi2.Mount object - utilized input parameters listed below
 		//                          mount.SerialNumber (required)
-		//                          mount.MountPoint (required)
-		//                          mount.FsOpts (optional)
+		//                          mount.MountPoint (required, "*" for the next free drive letter)
+		//                          mount.FsOpts (optional, Linux supports selinux_context and
+		//                                        selinux_relabel to label the file system, and
+		//                                        fs_uuid and fs_label to verify the mounted file
+		//                                        system; a mismatch unmounts it and fails with
+		//                                        an Aborted error.  verify_writable writes a probe
+		//                                        file, failing with a ReadOnly error if the file
+		//                                        system does not accept writes)
 		// Output Object:	chapi2.Mount object
 		// Sample Output:	See "GET /api/v1/mounts/details" endpoint
 		///////////////////////////////////////////////////////////////////////////////////////////
@@ -322,10 +1244,212 @@ func NewRouter() *mux.Router {
 		},
 
 		///////////////////////////////////////////////////////////////////////////////////////////
-		// Endpoint:  		Delete /api/v1/mounts/{mountId}
-		// Description: 	Unmount a device from the specified mount point location.
+		// Endpoint:  		GET /api/v1/mounts/orphans?root={root1}&root={root2}
+		// Description: 	Enumerates the orphaned mount points directly beneath the given mount
+		//					roots (e.g. the docker plugin mount directory).  A mount point is
+		//					orphaned if it is an empty, unmounted directory, or if it is still
+		//					mounted (Linux) or an access path (Windows) to a device that no longer
+		//					exists.  At least one absolute mount root is required.
+		// Input Object:	None
+		// Output Object:	Array of chapi2.OrphanedMount objects
+		// Sample Output:
+		// LINUX                                   WINDOWS
+		// {                                       {
+		//     "data": [                               "data":  [
+		//         {                                       {
+		//             "path": "/mnt/vol1",                    "path":  "C:\\Mounts\\vol1",
+		//             "reason": "missing_device",             "reason":  "missing_device",
+		//             "removed": false                        "removed":  false
+		//         }                                       }
+		//     ]                                       ]
+		// }                                       }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetOrphanedMounts",
+			Method:      "GET",
+			Pattern:     "/api/v1/mounts/orphans",
+			HandlerFunc: handler.GetOrphanedMounts,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		DELETE /api/v1/mounts/orphans?root={root1}&root={root2}
+		// Description: 	Removes the orphaned mount points directly beneath the given mount
+		//					roots.  Stale Linux mounts are lazily unmounted and stale Windows
+		//					access paths are deleted before the (empty) directory is removed.
+		//					Directories that are not empty are never removed.
+		// Input Object:	None
+		// Output Object:	Array of chapi2.OrphanedMount objects with "removed" set, or "error"
+		//					set if the orphaned mount point could not be removed
+		// Sample Output:	See "GET /api/v1/mounts/orphans" endpoint
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "DeleteOrphanedMounts",
+			Method:      "DELETE",
+			Pattern:     "/api/v1/mounts/orphans",
+			HandlerFunc: handler.DeleteOrphanedMounts,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/mounts/actions/freeze
+		// Description: 	Quiesces a mounted volume so that an array side snapshot is consistent.
+		//					Linux freezes the file system (fsfreeze) and blocks writes until it is
+		//					thawed.  Windows only flushes the volume's file system buffers; writes
+		//					are not blocked.  The mount point is automatically thawed once the
+		//					timeout (default 60, maximum 300 seconds) expires.
+		// Input Object:	chapi2.QuiesceRequest object
+		//                          request.SerialNumber (required if MountID not provided)
+		//                          request.MountID (required if the volume has multiple mount points)
+		//                          request.TimeoutSeconds (optional)
+		// Output Object:	chapi2.QuiescedMount object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "mount_id": "3c2a3e7e3b4f5a61",
+		//         "mount_point": "/mnt/vol1",
+		//         "serial_number": "fc96d9c5dbd7e1a26c9ce900d5ed3a63",
+		//         "method": "fsfreeze",
+		//         "thaw_deadline": "2019-10-01T17:30:00Z"
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "FreezeMount",
+			Method:      "PUT",
+			Pattern:     "/api/v1/mounts/actions/freeze",
+			HandlerFunc: handler.FreezeMount,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/mounts/actions/thaw
+		// Description: 	Thaws a mount point frozen by "PUT /api/v1/mounts/actions/freeze"
+		// Input Object:	chapi2.QuiesceRequest object (TimeoutSeconds is ignored)
+		// Output Object:	None
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "ThawMount",
+			Method:      "PUT",
+			Pattern:     "/api/v1/mounts/actions/thaw",
+			HandlerFunc: handler.ThawMount,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/mounts/actions/stage
+		// Description: 	Mounts a volume at its staging path, once per volume, for a CSI node
+		//					driver's NodeStageVolume (see STAGED AND PUBLISHED MOUNTS in the mount
+		//					package).  Staging again at the same path returns the staged mount
+		//					point; a volume staged at another path fails with HTTP 409.  Linux only.
+		// Input Object:	chapi2.StagedMountRequest object
+		//                          request.SerialNumber (required)
+		//                          request.StagingPath (required)
+		//                          request.FsOpts (optional, as for "POST /api/v1/mounts")
+		// Output Object:	chapi2.Mount object, bind_mounts lists the target paths the volume is
+		//					published to
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "id": "3c2a3e7e3b4f5a61",
+		//         "mount_point": "/var/lib/kubelet/plugins/kubernetes.io/csi/pv/pvc-1/globalmount",
+		//         "serial_number": "fc96d9c5dbd7e1a26c9ce900d5ed3a63"
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "StageMount",
+			Method:      "PUT",
+			Pattern:     "/api/v1/mounts/actions/stage",
+			HandlerFunc: handler.StageMount,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/mounts/actions/publish
+		// Description: 	Bind mounts a volume's staging path to a target path, read-only if
+		//					requested, for a CSI node driver's NodePublishVolume.  Each target
+		//					path is a managed bind mount with its own mount point ID.  Publishing
+		//					again at the same target path returns its mount point; with a different
+		//					read_only setting it fails with HTTP 409.  A volume not staged at the
+		//					staging path fails with HTTP 404.  Linux only.
+		// Input Object:	chapi2.StagedMountRequest object
+		//                          request.SerialNumber (required)
+		//                          request.StagingPath (required)
+		//                          request.TargetPath (required)
+		//                          request.ReadOnly (optional)
+		// Output Object:	chapi2.Mount object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "id": "8b1e3f0c9d2a4e57-bind",
+		//         "mount_point": "/var/lib/kubelet/pods/0a1b2c3d/volumes/kubernetes.io~csi/pvc-1/mount",
+		//         "serial_number": "fc96d9c5dbd7e1a26c9ce900d5ed3a63"
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "PublishMount",
+			Method:      "PUT",
+			Pattern:     "/api/v1/mounts/actions/publish",
+			HandlerFunc: handler.PublishMount,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/mounts/actions/unpublish
+		// Description: 	Removes a volume's bind mount at a target path, for a CSI node
+		//					driver's NodeUnpublishVolume.  A target path the volume is not
+		//					published at is not an error.  Linux only.
+		// Input Object:	chapi2.StagedMountRequest object
+		//                          request.SerialNumber (required)
+		//                          request.TargetPath (required)
+		// Output Object:	None
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "UnpublishMount",
+			Method:      "PUT",
+			Pattern:     "/api/v1/mounts/actions/unpublish",
+			HandlerFunc: handler.UnpublishMount,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/mounts/actions/unstage
+		// Description: 	Unmounts a volume from its staging path, for a CSI node driver's
+		//					NodeUnstageVolume.  A staging path still published fails with HTTP 409,
+		//					the error "details" property listing its target paths.  A staging path
+		//					the volume is not staged at is not an error.  Linux only.
+		// Input Object:	chapi2.StagedMountRequest object
+		//                          request.SerialNumber (required)
+		//                          request.StagingPath (required)
+		// Output Object:	None
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "UnstageMount",
+			Method:      "PUT",
+			Pattern:     "/api/v1/mounts/actions/unstage",
+			HandlerFunc: handler.UnstageMount,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		Delete /api/v1/mounts/{mountId} or
+		//					Delete /api/v1/mounts/{mountId}?lazy=true
+		// Description: 	Unmount a device from the specified mount point location.  If the mount
+		//					point is busy, HTTP 409 is returned and the error "details" property
+		//					lists the processes using it.  With lazy=true (Linux only) the mount
+		//					point is detached immediately and released once it is no longer busy.
+		//					A primary mount point cannot be removed until all of its bind mounts
+		//					have been removed (HTTP 409, "details" lists the bind mounts).
 		// Input Object:	Nimble volume serial number (string only)
 		// Output Object:	None (only Error details if request fails)
+		// Sample Error:	{
+		//                      "errors": {
+		//                          "code": 15,
+		//                          "text": "mount point \"/mnt/vol1\" is busy, 1 process(es) using it",
+		//                          "details": [
+		//                              {
+		//                                  "pid": 4711,
+		//                                  "command": "bash",
+		//                                  "user": "root",
+		//                                  "path": "/mnt/vol1"
+		//                              }
+		//                          ]
+		//                      }
+		//                  }
 		///////////////////////////////////////////////////////////////////////////////////////////
 		util.Route{
 			Name:        "DeleteMount",
@@ -333,10 +1457,123 @@ func NewRouter() *mux.Router {
 			Pattern:     "/api/v1/mounts/{mountId}",
 			HandlerFunc: handler.DeleteMount,
 		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/mounts/{mountId}/move
+		// Description: 	Moves a mount point to a new location without unmounting the volume.
+		//					The new location is made available before the old one is removed
+		//					(Linux "mount --move", Windows adds the new partition access path
+		//					before removing the old one).  A directory must be empty or absent; a
+		//					drive letter must be free (HTTP 409 otherwise).  If the old mount point
+		//					is busy and cannot be released, HTTP 409 is returned, the error
+		//					"details" lists the processes using it and the volume stays mounted
+		//					at the old location.  A moved bind mount is returned with a new ID.
+		// Input Object:	chapi2.Mount object
+		//                          mount.SerialNumber (required)
+		//                          mount.MountPoint (required - new mount point)
+		// Output Object:	chapi2.Mount object
+		// Sample Output:	See "GET /api/v1/mounts/details" endpoint
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "MoveMount",
+			Method:      "PUT",
+			Pattern:     "/api/v1/mounts/{mountId}/move",
+			HandlerFunc: handler.MoveMount,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		POST /api/v1/node/drain
+		// Description: 	Tears down the storage of a node being drained.  The mount points of the
+		//					given volumes are removed in dependency order (bind mounts and nested
+		//					mount points first), then each volume's device is flushed and detached.
+		//					Up to "parallelism" volumes (default 4, maximum 16) are processed
+		//					concurrently.  A volume is only detached if all its mount points were
+		//					removed.  Every volume is reported; a failed volume has "error" set.
+		// Input Object:	chapi2.DrainRequest object
+		//                          request.SerialNumbers (required if AllManaged not set)
+		//                          request.AllManaged (optional, drain every device attached
+		//                                              through CHAPI)
+		//                          request.Parallelism (optional)
+		//                          request.Lazy (optional, Linux only)
+		// Output Object:	Array of chapi2.DrainResult objects
+		// Sample Output:
+		// {
+		//     "data": [
+		//         {
+		//             "serial_number": "c5a28c28a2487d3d6c9ce900584f2795",
+		//             "unmounted": ["/mnt/vol1-bind", "/mnt/vol1"],
+		//             "flushed": true,
+		//             "detached": true
+		//         },
+		//         {
+		//             "serial_number": "f4c97c5c1cd391756c9ce900584f2795",
+		//             "flushed": false,
+		//             "detached": false,
+		//             "error": "mount point \"/mnt/vol2\" is busy, 1 process(es) using it"
+		//         }
+		//     ]
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "DrainNode",
+			Method:      "POST",
+			Pattern:     "/api/v1/node/drain",
+			HandlerFunc: handler.DrainNode,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		GET /api/v1/state
+		// Description: 	Returns the devices and mount points created through CHAPI, as recorded
+		//					in the local state store, and whether each is still present on the host.
+		// Input Object:	None
+		// Output Object:	chapi2.ManagedState object
+		// Sample Output:
+		// {
+		//     "data": {
+		//         "schema_version": 1,
+		//         "devices": [
+		//             {
+		//                 "serial_number": "c5a28c28a2487d3d6c9ce900584f2795",
+		//                 "access_protocol": "iscsi",
+		//                 "target_name": "iqn.2007-11.com.nimblestorage:vol1-v1",
+		//                 "created": "2019-06-04T17:32:10Z",
+		//                 "status": "present"
+		//             }
+		//         ],
+		//         "mounts": [
+		//             {
+		//                 "id": "227bab86e6c96b83-1-1",
+		//                 "mount_point": "/mnt/vol1",
+		//                 "serial_number": "c5a28c28a2487d3d6c9ce900584f2795",
+		//                 "created": "2019-06-04T17:32:14Z",
+		//                 "status": "missing"
+		//             }
+		//         ]
+		//     }
+		// }
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "GetManagedState",
+			Method:      "GET",
+			Pattern:     "/api/v1/state",
+			HandlerFunc: handler.GetManagedState,
+		},
+
+		///////////////////////////////////////////////////////////////////////////////////////////
+		// Endpoint:  		PUT /api/v1/state/actions/reconcile
+		// Description: 	Removes the state store records of the devices and mount points that are
+		//					no longer present on the host (e.g. removed outside of CHAPI).
+		// Input Object:	None
+		// Output Object:	chapi2.ManagedState object, removed records have the "pruned" status
+		// Sample Output:	See "GET /api/v1/state" endpoint
+		///////////////////////////////////////////////////////////////////////////////////////////
+		util.Route{
+			Name:        "ReconcileManagedState",
+			Method:      "PUT",
+			Pattern:     "/api/v1/state/actions/reconcile",
+			HandlerFunc: handler.ReconcileManagedState,
+		},
 	}
 
-	routes = append(routes, platformSpecificEndpoints...)
-	router := mux.NewRouter().StrictSlash(true)
-	util.InitializeRouter(router, routes)
-	return router
+	return append(routes, platformSpecificEndpoints...)
 }
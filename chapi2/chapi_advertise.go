@@ -0,0 +1,49 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"runtime"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/discovery"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// AdvertiseEnv enables the service advertisement of the CHAPI for Windows TCP listener when
+	// set to "true" (see SERVICE DISCOVERY in discovery.go).  CHAPI for Linux only listens on a
+	// unix socket so nothing is advertised unless StartAdvertisement is called (e.g. for a TCP
+	// diagnostics listener).
+	AdvertiseEnv = config.AdvertiseEnv
+
+	// Advertised API version and path
+	advertisedAPIVersion = "v1"
+	advertisedAPIPath    = "/api/v1"
+)
+
+// StartAdvertisement advertises the CHAPI server listening on the given TCP port, using the
+// discovery package's Advertiser, so that management tooling can find it without knowing its port
+func StartAdvertisement(port int) error {
+	service := &discovery.Service{
+		Port:       port,
+		APIVersion: advertisedAPIVersion,
+		Path:       advertisedAPIPath,
+		Platform:   runtime.GOOS,
+	}
+	if err := discovery.GetAdvertiser().Advertise(service); err != nil {
+		log.Errorf("Unable to advertise CHAPI server on port %v, err=%v", port, err)
+		return err
+	}
+	return nil
+}
+
+// StopAdvertisement withdraws the CHAPI server advertisement, if any
+func StopAdvertisement() error {
+	return discovery.GetAdvertiser().Stop()
+}
+
+// isAdvertisementEnabled returns true if AdvertiseEnv enables the service advertisement
+func isAdvertisementEnabled() bool {
+	return config.Enabled(AdvertiseEnv)
+}
@@ -0,0 +1,235 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// API TOKENS
+//
+//		Several local agents (e.g. a CSI driver, a monitoring agent and a backup agent) can share
+//		a CHAPI server.  To give each agent only the access it needs, API tokens can be issued,
+//		each with a role:
+//
+//		- read-only		GET endpoints only, except the admin endpoints
+//		- operator		Every endpoint except the admin endpoints
+//		- admin			Every endpoint, including the admin endpoints (adminEndpoints, e.g. log
+//						configuration, credential rotation, node drain and the endpoints
+//						returning secrets) and the override of another owner's device claim
+//						(model.ClaimOverrideHeader)
+//
+//		A client presents its token in the "Authorization: Bearer <token>" request header.  API
+//		tokens are disabled unless set through SetAPITokens or a JSON token file is named by the
+//		CHAPI_API_TOKENS_CONFIG environment variable.  Once enabled, every request to the primary
+//		listener must present a valid token, except that CHAPI for Windows still accepts its
+//		local access key (CHAPILocalAccessKey) with the admin role.  If the token file named by
+//		CHAPI_API_TOKENS_CONFIG cannot be loaded (missing, malformed or with an invalid token),
+//		every request is rejected until valid tokens are set.  A sample token file:
+//
+//		{
+//		    "tokens": [
+//		        {"name": "monitoring", "token": "c2b7...", "role": "read-only"},
+//		        {"name": "csi-driver", "token": "9f31...", "role": "operator"}
+//		    ]
+//		}
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/handler"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// APITokensConfigEnv names the JSON token file loaded when the router is created
+	APITokensConfigEnv = config.APITokensConfigEnv
+
+	// API token roles
+	APIRoleReadOnly = "read-only"
+	APIRoleOperator = "operator"
+	APIRoleAdmin    = "admin"
+
+	// Authorization header scheme of the API tokens
+	apiTokenScheme = "Bearer "
+
+	// localAccessKeyHeader is the CHAPI for Windows local access key header
+	localAccessKeyHeader = "CHAPILocalAccessKey"
+
+	errorMessageAPITokenNotSupplied = "API token not supplied"
+	errorMessageInvalidAPIToken     = "invalid API token"
+	errorMessageAPIRoleDenied       = "API token %v (%v) is not allowed to call %v"
+	errorMessageAPITokensConfig     = "API tokens config named by %v could not be loaded, every request is rejected"
+)
+
+// adminEndpoints are the route names of the endpoints only allowed to the admin role
+var adminEndpoints = map[string]bool{
+	"SetLogging":            true,
+	"RotateCredentials":     true,
+	"AddIgnoredDevice":      true,
+	"RemoveIgnoredDevice":   true,
+	"DrainNode":             true,
+	"ReconcileManagedState": true,
+	"ChapInfo":              true, // Returns the CHAP password
+	"Keyfile":               true, // Returns the location of the CHAPI for Windows access key
+}
+
+// APIToken is an API token and its role
+type APIToken struct {
+	Name  string `json:"name"`  // Agent the token was issued to, for logging
+	Token string `json:"token"` // Token presented in the Authorization header
+	Role  string `json:"role"`  // "read-only", "operator" or "admin"
+}
+
+// APITokensConfig is the token file format
+type APITokensConfig struct {
+	Tokens []*APIToken `json:"tokens"`
+}
+
+var (
+	apiTokensLock  sync.RWMutex
+	apiTokens      []*APIToken
+	apiTokensOnce  sync.Once
+	apiTokensErr   error // Set if the token file named by APITokensConfigEnv could not be loaded
+	apiRoleOrdinal = map[string]int{APIRoleReadOnly: 1, APIRoleOperator: 2, APIRoleAdmin: 3}
+)
+
+// SetAPITokens validates and enables the given API tokens, replacing any tokens already enabled.
+// Passing no tokens disables API token authorization.
+func SetAPITokens(tokens []*APIToken) error {
+	names := make(map[string]bool)
+	for _, token := range tokens {
+		if token.Name == "" || token.Token == "" {
+			return fmt.Errorf("API token name or token not provided")
+		}
+		if names[token.Name] {
+			return fmt.Errorf("API token %v provided more than once", token.Name)
+		}
+		names[token.Name] = true
+		if apiRoleOrdinal[token.Role] == 0 {
+			return fmt.Errorf("API token %v role %q is not valid", token.Name, token.Role)
+		}
+	}
+
+	apiTokensLock.Lock()
+	defer apiTokensLock.Unlock()
+	apiTokens = tokens
+	apiTokensErr = nil
+	if len(tokens) != 0 {
+		log.Infof("CHAPI API token authorization enabled with %v token(s)", len(tokens))
+	}
+	return nil
+}
+
+// LoadAPITokensConfig enables the API tokens in the given JSON file
+func LoadAPITokensConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var config APITokensConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid API tokens config %v, err=%v", path, err)
+	}
+	return SetAPITokens(config.Tokens)
+}
+
+// loadAPITokensConfigFromEnv loads the token file named by APITokensConfigEnv, if any.  The file
+// is only loaded once per process.
+func loadAPITokensConfigFromEnv() {
+	apiTokensOnce.Do(applyAPITokensConfigEnv)
+}
+
+// applyAPITokensConfigEnv loads the token file named by APITokensConfigEnv, if any.  If the file
+// cannot be loaded, authorization fails closed: every request is rejected.
+func applyAPITokensConfigEnv() {
+	path := config.String(APITokensConfigEnv)
+	if path == "" {
+		return
+	}
+	if err := LoadAPITokensConfig(path); err != nil {
+		log.Errorf("Unable to load API tokens config, every request will be rejected, err=%v", err)
+		apiTokensLock.Lock()
+		defer apiTokensLock.Unlock()
+		apiTokensErr = err
+	}
+}
+
+// apiTokenHandler only passes the requests whose API token role allows the given route
+func apiTokenHandler(route string, method string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := authorizeRequest(route, method, r); err != nil {
+			log.Error(err)
+			statusCode := http.StatusForbidden
+			if err.ErrorCode() == cerrors.Unauthenticated {
+				statusCode = http.StatusUnauthorized
+			}
+			w.WriteHeader(statusCode)
+			json.NewEncoder(w).Encode(handler.Response{Err: err})
+			return
+		}
+		handlerFunc(w, r)
+	}
+}
+
+// authorizeRequest returns an error if the request's API token is missing or not allowed to call
+// the given route; nil is returned if API tokens are disabled
+func authorizeRequest(route string, method string, r *http.Request) *cerrors.ChapiError {
+	apiTokensLock.RLock()
+	tokens, tokensErr := apiTokens, apiTokensErr
+	apiTokensLock.RUnlock()
+	if tokensErr != nil {
+		return cerrors.NewChapiErrorf(cerrors.Unauthenticated, errorMessageAPITokensConfig, APITokensConfigEnv)
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, apiTokenScheme) {
+		// The CHAPI for Windows local access key, validated by the handler, grants the admin role
+		if runtime.GOOS == "windows" && r.Header.Get(localAccessKeyHeader) != "" {
+			return nil
+		}
+		return cerrors.NewChapiError(cerrors.Unauthenticated, errorMessageAPITokenNotSupplied)
+	}
+	token := findAPIToken(tokens, strings.TrimPrefix(authorization, apiTokenScheme))
+	if token == nil {
+		return cerrors.NewChapiError(cerrors.Unauthenticated, errorMessageInvalidAPIToken)
+	}
+
+	required := APIRoleReadOnly
+	switch {
+	case adminEndpoints[route] || strings.EqualFold(r.Header.Get(model.ClaimOverrideHeader), "true"):
+		required = APIRoleAdmin
+	case method != "GET":
+		required = APIRoleOperator
+	}
+	if apiRoleOrdinal[token.Role] < apiRoleOrdinal[required] {
+		return cerrors.NewChapiErrorf(cerrors.PermissionDenied, errorMessageAPIRoleDenied, token.Name, token.Role, route)
+	}
+	log.Tracef("API token %v (%v) authorized for %v", token.Name, token.Role, route)
+	return nil
+}
+
+// findAPIToken returns the API token matching the presented token, else nil.  Every token is
+// compared in constant time so that the comparison doesn't reveal the tokens.
+func findAPIToken(tokens []*APIToken, presented string) *APIToken {
+	var match *APIToken
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Token), []byte(presented)) == 1 {
+			match = token
+		}
+	}
+	return match
+}
@@ -0,0 +1,145 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestAPITokens(t *testing.T) {
+	router := NewRouter()
+	defer SetAPITokens(nil)
+
+	// Invalid tokens are rejected
+	invalidTokens := [][]*APIToken{
+		{{Name: "monitoring", Role: APIRoleReadOnly}},
+		{{Name: "monitoring", Token: "secret", Role: "superuser"}},
+		{{Name: "monitoring", Token: "secret1", Role: APIRoleReadOnly}, {Name: "monitoring", Token: "secret2", Role: APIRoleAdmin}},
+	}
+	for _, tokens := range invalidTokens {
+		if err := SetAPITokens(tokens); err == nil {
+			t.Errorf("expected tokens %+v to be rejected", tokens[0])
+		}
+	}
+
+	err := SetAPITokens([]*APIToken{
+		{Name: "monitoring", Token: "read-secret", Role: APIRoleReadOnly},
+		{Name: "csi", Token: "operator-secret", Role: APIRoleOperator},
+		{Name: "admin", Token: "admin-secret", Role: APIRoleAdmin},
+	})
+	if err != nil {
+		t.Fatalf("unable to set API tokens, err=%v", err)
+	}
+
+	tests := []struct {
+		method   string
+		path     string
+		token    string
+		override bool
+		expected int
+	}{
+		{"GET", "/api/v1/health", "", false, http.StatusUnauthorized},
+		{"GET", "/api/v1/health", "wrong-secret", false, http.StatusUnauthorized},
+		{"GET", "/api/v1/health", "read-secret", false, http.StatusOK},
+		{"PUT", "/api/v1/devices/abc/actions/offline", "read-secret", false, http.StatusForbidden},
+		{"PUT", "/api/v1/logging", "operator-secret", false, http.StatusForbidden},
+		{"PUT", "/api/v1/devices/abc/actions/offline", "operator-secret", true, http.StatusForbidden},
+		{"GET", "/api/v1/logging", "admin-secret", false, http.StatusOK},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.path, strings.NewReader("{}"))
+		if test.token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		if test.override {
+			request.Header.Set(model.ClaimOverrideHeader, "true")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		if w.Code != test.expected {
+			t.Errorf("%v %v with token %q: expected %v, got %v %v", test.method, test.path, test.token, test.expected, w.Code, w.Body.String())
+		}
+	}
+
+	// Requests aren't authorized once the tokens are disabled
+	SetAPITokens(nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %v once API tokens are disabled, got %v", http.StatusOK, w.Code)
+	}
+}
+
+func TestAPITokensSecretEndpoints(t *testing.T) {
+	defer SetAPITokens(nil)
+	err := SetAPITokens([]*APIToken{
+		{Name: "monitoring", Token: "read-secret", Role: APIRoleReadOnly},
+		{Name: "csi", Token: "operator-secret", Role: APIRoleOperator},
+		{Name: "admin", Token: "admin-secret", Role: APIRoleAdmin},
+	})
+	if err != nil {
+		t.Fatalf("unable to set API tokens, err=%v", err)
+	}
+
+	// Only the admin role may read the CHAP password or the key file location
+	for _, route := range []string{"ChapInfo", "Keyfile"} {
+		for token, allowed := range map[string]bool{"read-secret": false, "operator-secret": false, "admin-secret": true} {
+			request := httptest.NewRequest("GET", "/", nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			err := authorizeRequest(route, "GET", request)
+			if allowed && err != nil {
+				t.Errorf("%v with token %q: unexpected error %v", route, token, err)
+			}
+			if !allowed && (err == nil || err.Code != cerrors.PermissionDenied) {
+				t.Errorf("%v with token %q: expected PermissionDenied, got %v", route, token, err)
+			}
+		}
+	}
+}
+
+func TestAPITokensConfigFailsClosed(t *testing.T) {
+	router := NewRouter()
+	defer SetAPITokens(nil)
+	defer os.Unsetenv(APITokensConfigEnv)
+
+	dir, err := ioutil.TempDir("", "chapi-tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	malformed := filepath.Join(dir, "malformed.json")
+	invalidRole := filepath.Join(dir, "role.json")
+	ioutil.WriteFile(malformed, []byte("{"), 0600)
+	ioutil.WriteFile(invalidRole, []byte(`{"tokens": [{"name": "csi", "token": "secret", "role": "superuser"}]}`), 0600)
+
+	for _, path := range []string{filepath.Join(dir, "missing.json"), malformed, invalidRole} {
+		os.Setenv(APITokensConfigEnv, path)
+		applyAPITokensConfigEnv()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%v: expected %v, got %v", filepath.Base(path), http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	// Setting valid tokens recovers
+	if err := SetAPITokens([]*APIToken{{Name: "csi", Token: "secret", Role: APIRoleOperator}}); err != nil {
+		t.Fatalf("unable to set API tokens, err=%v", err)
+	}
+	request := httptest.NewRequest("GET", "/api/v1/health", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %v once valid tokens are set, got %v", http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,95 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// DIAGNOSTICS LISTENER
+//
+//		Monitoring agents only need to read the host state.  Rather than distributing the CHAPI
+//		credentials (or root access to the CHAPI socket) to them, an optional second listener can
+//		be started that serves only the GET endpoints listed in diagnosticsEndpoints (health,
+//		hosts, devices, mounts, etc.), without authentication.  Mutating endpoints, and read
+//		endpoints returning secrets (e.g. CHAP credentials), remain on the primary listener only.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+var (
+	diagnosticsLock     sync.Mutex
+	diagnosticsListener net.Listener
+)
+
+// RunDiagnostics starts the read-only diagnostics listener on the given network ("unix" or "tcp")
+// and address.  A unix socket is made accessible to all local users.  Nothing is done if the
+// diagnostics listener is already running.
+func RunDiagnostics(network string, address string) error {
+	log.Infof(">>>>> RunDiagnostics, network=%v, address=%v", network, address)
+	defer log.Info("<<<<< RunDiagnostics")
+
+	diagnosticsLock.Lock()
+	defer diagnosticsLock.Unlock()
+
+	if diagnosticsListener != nil {
+		return nil
+	}
+
+	// Remove any stale socket left behind by a previous instance
+	if network == "unix" {
+		if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Unable to remove existing diagnostics socket %v, err=%v", address, err)
+			return err
+		}
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		log.Error("listen error, Unable to create diagnostics listener ", err.Error())
+		return err
+	}
+	if network == "unix" {
+		if err = os.Chmod(address, 0666); err != nil {
+			log.Errorf("Unable to set diagnostics socket permissions, err=%v", err)
+			listener.Close()
+			return err
+		}
+	}
+	diagnosticsListener = listener
+
+	router := NewDiagnosticsRouter()
+	go func() {
+		err := http.Serve(listener, router)
+		log.Infof("exiting chapid diagnostics server, %v", err)
+	}()
+	return nil
+}
+
+// StopDiagnostics stops the diagnostics listener, if running
+func StopDiagnostics() error {
+	log.Info(">>>>> StopDiagnostics")
+	defer log.Info("<<<<< StopDiagnostics")
+
+	diagnosticsLock.Lock()
+	defer diagnosticsLock.Unlock()
+
+	if diagnosticsListener == nil {
+		return nil
+	}
+	err := diagnosticsListener.Close()
+	if err != nil {
+		log.Error("Unable to close diagnostics listener " + diagnosticsListener.Addr().String())
+	}
+	if diagnosticsListener.Addr().Network() == "unix" {
+		os.RemoveAll(diagnosticsListener.Addr().String())
+	}
+	diagnosticsListener = nil
+	return err
+}
@@ -0,0 +1,40 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticsRouter(t *testing.T) {
+	router := NewDiagnosticsRouter()
+
+	// Only the read-only diagnostics endpoints are routed
+	tests := []struct {
+		method   string
+		path     string
+		expected int
+	}{
+		{"GET", "/api/v1/health", http.StatusOK},
+		{"POST", "/api/v1/devices", http.StatusMethodNotAllowed},
+		{"DELETE", "/api/v1/mounts/1234", http.StatusNotFound},
+		{"GET", "/api/v1/chapinfo", http.StatusNotFound},
+		{"GET", "/api/v1/keyfile", http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.expected {
+			t.Errorf("%v %v returned %v, expected %v", tc.method, tc.path, w.Code, tc.expected)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+	if !strings.Contains(w.Body.String(), `"status":"ok"`) {
+		t.Errorf("unexpected health response %v", w.Body.String())
+	}
+}
@@ -0,0 +1,249 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// FAULT INJECTION
+//
+//		For testing only.  CHAPI consumers need to exercise their retry and cleanup paths against
+//		realistic CHAPI failures.  When enabled, fault rules are matched against each request's
+//		route name (e.g. "CreateDevice") and can:
+//
+//		- delay		Delay the request by DelayMs before it is handled
+//		- error		Fail the request with the given CHAPI error code and HTTP status
+//		- drop		Close the connection without sending a response
+//
+//		Each rule is applied with the given probability (e.g. 0.2 to fail 20% of requests, 0 to
+//		disable the rule); a rule without a probability is always applied.  Fault
+//		injection is disabled unless a rule set is enabled through EnableFaultInjection or a JSON
+//		rule file is named by the CHAPI_FAULT_INJECTION_CONFIG environment variable.  A sample
+//		rule file:
+//
+//		{
+//		    "rules": [
+//		        {"endpoint": "CreateDevice", "action": "error", "probability": 0.2, "error_code": "Timeout"},
+//		        {"endpoint": "*", "action": "delay", "delay_ms": 500}
+//		    ]
+//		}
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/handler"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// FaultInjectionConfigEnv names the JSON fault rule file loaded when the router is created
+	FaultInjectionConfigEnv = config.FaultInjectionConfigEnv
+
+	// Fault actions
+	FaultActionDelay = "delay"
+	FaultActionError = "error"
+	FaultActionDrop  = "drop"
+
+	// faultEndpointAll matches every endpoint
+	faultEndpointAll = "*"
+
+	// Injected error defaults
+	faultDefaultErrorCode = cerrors.Internal
+	faultDefaultMessage   = "injected fault"
+)
+
+// FaultRule describes a fault to inject into the matching endpoint
+type FaultRule struct {
+	Endpoint    string   `json:"endpoint"`              // Route name (e.g. "CreateDevice") or "*" for all endpoints
+	Action      string   `json:"action"`                // "delay", "error" or "drop"
+	Probability *float64 `json:"probability,omitempty"` // Chance (0.0-1.0) that the fault is injected; nil means always
+	DelayMs     int      `json:"delay_ms,omitempty"`    // Delay before the request is handled, failed or dropped
+	StatusCode  int      `json:"status_code,omitempty"` // error action only - HTTP status (default derived from error_code)
+	ErrorCode   string   `json:"error_code,omitempty"`  // error action only - CHAPI error code name (default "Internal")
+	Message     string   `json:"message,omitempty"`     // error action only - error text (default "injected fault")
+
+	errorCode cerrors.ChapiErrorCode
+}
+
+// FaultInjectionConfig is the fault rule file format
+type FaultInjectionConfig struct {
+	Rules []*FaultRule `json:"rules"`
+}
+
+var (
+	faultRulesLock sync.RWMutex
+	faultRules     []*FaultRule
+	faultEnvOnce   sync.Once
+
+	// faultRandFloat64 returns a random number in [0.0,1.0); safe for concurrent use and a variable
+	// so that tests can replace it
+	faultRandFloat64 = rand.Float64
+)
+
+// EnableFaultInjection validates and enables the given fault rules, replacing any rules already
+// enabled.  Passing no rules disables fault injection.
+func EnableFaultInjection(rules []*FaultRule) error {
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+
+	faultRulesLock.Lock()
+	defer faultRulesLock.Unlock()
+	faultRules = rules
+	if len(rules) != 0 {
+		log.Warnf("CHAPI fault injection enabled with %v rule(s)", len(rules))
+	}
+	return nil
+}
+
+// DisableFaultInjection disables fault injection
+func DisableFaultInjection() {
+	EnableFaultInjection(nil)
+}
+
+// LoadFaultInjectionConfig enables the fault rules in the given JSON file
+func LoadFaultInjectionConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var config FaultInjectionConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid fault injection config %v, err=%v", path, err)
+	}
+	return EnableFaultInjection(config.Rules)
+}
+
+// loadFaultInjectionConfigFromEnv loads the fault rule file named by FaultInjectionConfigEnv, if
+// any.  The file is only loaded once per process.
+func loadFaultInjectionConfigFromEnv() {
+	faultEnvOnce.Do(func() {
+		if path := config.String(FaultInjectionConfigEnv); path != "" {
+			if err := LoadFaultInjectionConfig(path); err != nil {
+				log.Errorf("Unable to load fault injection config, err=%v", err)
+			}
+		}
+	})
+}
+
+// validate verifies the rule and resolves its error code
+func (rule *FaultRule) validate() error {
+	if rule.Endpoint == "" {
+		return fmt.Errorf("fault rule endpoint not provided")
+	}
+	if rule.Probability != nil && (*rule.Probability < 0 || *rule.Probability > 1) {
+		return fmt.Errorf("fault rule %v probability %v is not between 0 and 1", rule.Endpoint, *rule.Probability)
+	}
+	switch rule.Action {
+	case FaultActionDelay, FaultActionDrop:
+	case FaultActionError:
+		rule.errorCode = faultDefaultErrorCode
+		if rule.ErrorCode != "" {
+			errorCode, ok := cerrors.ParseChapiErrorCode(rule.ErrorCode)
+			if !ok {
+				return fmt.Errorf("fault rule %v error code %v is not valid", rule.Endpoint, rule.ErrorCode)
+			}
+			rule.errorCode = errorCode
+		}
+	default:
+		return fmt.Errorf("fault rule %v action %v is not valid", rule.Endpoint, rule.Action)
+	}
+	return nil
+}
+
+// getFaultRule returns the first enabled rule matching the endpoint, if the fault is to be injected
+// into this request, else nil
+func getFaultRule(endpoint string) *FaultRule {
+	faultRulesLock.RLock()
+	defer faultRulesLock.RUnlock()
+	for _, rule := range faultRules {
+		if rule.Endpoint != endpoint && rule.Endpoint != faultEndpointAll {
+			continue
+		}
+		if rule.Probability == nil || faultRandFloat64() < *rule.Probability {
+			return rule
+		}
+	}
+	return nil
+}
+
+// faultInjectionHandler wraps the endpoint handler with the enabled fault rules
+func faultInjectionHandler(endpoint string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rule := getFaultRule(endpoint)
+		if rule == nil {
+			handlerFunc(w, r)
+			return
+		}
+
+		log.Warnf("Injecting fault into %v, action=%v, delayMs=%v", endpoint, rule.Action, rule.DelayMs)
+		if rule.DelayMs > 0 {
+			time.Sleep(time.Duration(rule.DelayMs) * time.Millisecond)
+		}
+
+		switch rule.Action {
+		case FaultActionError:
+			writeInjectedError(w, rule)
+		case FaultActionDrop:
+			dropConnection(w)
+		default:
+			handlerFunc(w, r)
+		}
+	}
+}
+
+// writeInjectedError writes the rule's CHAPI error response
+func writeInjectedError(w http.ResponseWriter, rule *FaultRule) {
+	message := rule.Message
+	if message == "" {
+		message = faultDefaultMessage
+	}
+	statusCode := rule.StatusCode
+	if statusCode == 0 {
+		statusCode = faultStatusCode(rule.errorCode)
+	}
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(handler.Response{Err: cerrors.NewChapiError(rule.errorCode, message)})
+}
+
+// faultStatusCode returns the HTTP status code typically returned with the given CHAPI error code
+func faultStatusCode(errorCode cerrors.ChapiErrorCode) int {
+	switch errorCode {
+	case cerrors.InvalidArgument:
+		return http.StatusBadRequest
+	case cerrors.Unauthenticated, cerrors.PermissionDenied:
+		return http.StatusUnauthorized
+	case cerrors.NotFound:
+		return http.StatusNotFound
+	case cerrors.AlreadyExists, cerrors.Busy, cerrors.ReadOnly, cerrors.ReservationConflict:
+		return http.StatusConflict
+	case cerrors.Timeout:
+		return http.StatusGatewayTimeout
+	case cerrors.Unimplemented:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// dropConnection closes the client connection without a response
+func dropConnection(w http.ResponseWriter) {
+	if hijacker, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hijacker.Hijack(); err == nil {
+			conn.Close()
+			return
+		}
+	}
+	// The connection cannot be hijacked; abort the response instead
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,95 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultInjection(t *testing.T) {
+	router := NewRouter()
+	defer DisableFaultInjection()
+
+	// Invalid rules are rejected
+	invalidRules := []*FaultRule{
+		{Action: FaultActionError},
+		{Endpoint: "Health", Action: "explode"},
+		{Endpoint: "Health", Action: FaultActionError, ErrorCode: "NoSuchCode"},
+		{Endpoint: "Health", Action: FaultActionDelay, Probability: faultProbability(1.5)},
+	}
+	for _, rule := range invalidRules {
+		if err := EnableFaultInjection([]*FaultRule{rule}); err == nil {
+			t.Errorf("expected rule %+v to be rejected", rule)
+		}
+	}
+
+	// Injected error
+	err := EnableFaultInjection([]*FaultRule{{Endpoint: "Health", Action: FaultActionError, ErrorCode: "timeout"}})
+	if err != nil {
+		t.Fatalf("unable to enable fault injection, err=%v", err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), `"text":"injected fault"`) {
+		t.Errorf("unexpected injected error response %v %v", w.Code, w.Body.String())
+	}
+
+	// Injected delay, after which the request is handled
+	err = EnableFaultInjection([]*FaultRule{{Endpoint: "*", Action: FaultActionDelay, DelayMs: 50}})
+	if err != nil {
+		t.Fatalf("unable to enable fault injection, err=%v", err)
+	}
+	start := time.Now()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+	if w.Code != http.StatusOK || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("unexpected delayed response %v after %v", w.Code, time.Since(start))
+	}
+
+	// Rules only apply to their endpoint
+	err = EnableFaultInjection([]*FaultRule{{Endpoint: "CreateDevice", Action: FaultActionError}})
+	if err != nil {
+		t.Fatalf("unable to enable fault injection, err=%v", err)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected response %v for endpoint without faults", w.Code)
+	}
+
+	// A rule with a zero probability is disabled, one with probability 1 always applies
+	savedFaultRandFloat64 := faultRandFloat64
+	defer func() { faultRandFloat64 = savedFaultRandFloat64 }()
+	faultRandFloat64 = func() float64 { return 0 }
+	for _, probability := range []float64{0, 1} {
+		err = EnableFaultInjection([]*FaultRule{{Endpoint: "Health", Action: FaultActionError, Probability: faultProbability(probability)}})
+		if err != nil {
+			t.Fatalf("unable to enable fault injection, err=%v", err)
+		}
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/health", nil))
+		if injected := w.Code != http.StatusOK; injected != (probability == 1) {
+			t.Errorf("probability %v: unexpected response %v", probability, w.Code)
+		}
+	}
+
+	// Dropped connection
+	err = EnableFaultInjection([]*FaultRule{{Endpoint: "Health", Action: FaultActionDrop}})
+	if err != nil {
+		t.Fatalf("unable to enable fault injection, err=%v", err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+	if resp, err := http.Get(server.URL + "/api/v1/health"); err == nil {
+		resp.Body.Close()
+		t.Errorf("expected dropped connection, got %v", resp.StatusCode)
+	}
+}
+
+func faultProbability(probability float64) *float64 {
+	return &probability
+}
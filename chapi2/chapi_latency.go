@@ -0,0 +1,31 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/timing"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// latencyHandler wraps the endpoint handler to add each request's duration to the endpoint's
+// latency histogram.  Requests exceeding the slow request threshold are logged, with their request
+// correlation ID, along with their stage timings (see REQUEST TIMING in timing.go).
+func latencyHandler(endpoint string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timings := timing.NewTimings()
+		start := time.Now()
+		handlerFunc(w, r.WithContext(timing.NewContext(r.Context(), timings)))
+		duration := time.Since(start)
+
+		if timing.ObserveRequest(endpoint, duration) {
+			stages := timings.String()
+			if stages == "" {
+				stages = "none"
+			}
+			log.WithContext(r.Context()).Warnf("Slow request %v %v %v took %v (threshold %v), stages: %v", endpoint, r.Method, r.URL.Path, duration, timing.SlowRequestThreshold(), stages)
+		}
+	}
+}
@@ -11,9 +11,15 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/driver"
 	"github.com/hpe-storage/common-host-libs/chapi2/handler"
+	"github.com/hpe-storage/common-host-libs/chapi2/openapi"
 	"github.com/hpe-storage/common-host-libs/connectivity"
+	"github.com/hpe-storage/common-host-libs/linux"
 	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/model"
+	"github.com/hpe-storage/common-host-libs/tunelinux"
 	"github.com/hpe-storage/common-host-libs/util"
 )
 
@@ -51,6 +57,13 @@ var platformSpecificEndpoints = []util.Route{
 	},
 }
 
+// platformSpecificSchemas describes the objects returned by the platformSpecificEndpoints
+var platformSpecificSchemas = map[string]openapi.Endpoint{
+	"Recommendations": {Summary: "Returns the host's recommended settings", Response: []*tunelinux.Recommendation{}},
+	"DeletingDevices": {Summary: "Returns the devices being deleted", Response: &linux.DeletingDevices{}},
+	"ChapInfo":        {Summary: "Returns the iSCSI CHAP settings of the host", Response: model.ChapInfo{}},
+}
+
 // Run will invoke a new chapid listener with socket filename containing current process ID
 func Run() (err error) {
 	// check if chapid is already running listening on standard socket or per process socket
@@ -64,6 +77,9 @@ func Run() (err error) {
 	chapidLock.Lock()
 	defer chapidLock.Unlock()
 
+	// Log the CHAPI settings configured in the environment
+	config.LogSettings()
+
 	//first check if the directory exists
 	_, isdDir, _ := util.FileExists(ChapidSocketPath)
 	if !isdDir {
@@ -75,6 +91,18 @@ func Run() (err error) {
 		}
 	}
 
+	// Detect (and optionally replace) an iSCSI initiator name shared with a cloned host
+	checkIscsiInitiatorName()
+
+	// Periodically reinstate failed paths that are reachable again (see driver.PathRecoveryIntervalEnv)
+	driver.StartPathRecoveryMonitor()
+
+	// Periodically log out the iSCSI targets left without any LUN (see iscsi.StaleSessionIntervalEnv)
+	driver.StartStaleSessionCollector()
+
+	// Quiesce the managed volumes before the host shuts down or suspends (see PowerEventsEnv)
+	startPowerEventMonitor()
+
 	chapidResult := make(chan error)
 	// start chapid server
 	go startChapid(chapidResult)
@@ -0,0 +1,97 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/openapi"
+	"github.com/hpe-storage/common-host-libs/logger"
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// OPENAPI DOCUMENT
+//
+//		The OpenAPI document served at "GET /api/v1/openapi.json" is generated from the route
+//		table.  util.Route carries no type information, so routeSchemas names the objects each
+//		route accepts and returns, along with its optional query parameters.  Platform specific
+//		routes are described by platformSpecificSchemas.  Every route must have an entry (see
+//		TestRouteSchemas); a route without one is still documented, but without its objects.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+const (
+	openAPITitle   = "CHAPI"
+	openAPIVersion = "1.0.0"
+)
+
+// routeSchemas describes the objects accepted and returned by each route, by route name
+var routeSchemas = map[string]openapi.Endpoint{
+	"Health":                {Summary: "Reports whether CHAPI is able to respond", Response: model.Health{}},
+	"Latency":               {Summary: "Reports the request latency histogram of each endpoint", Response: model.LatencyReport{}},
+	"GetLogging":            {Summary: "Reports the active logging configuration", Response: logger.LogConfig{}},
+	"SetLogging":            {Summary: "Changes the active logging configuration", Request: logger.LogConfig{}, Response: logger.LogConfig{}},
+	"OpenAPI":               {Summary: "Returns the OpenAPI document describing the CHAPI endpoints", Unwrapped: true},
+	"Hosts":                 {Summary: "Returns host information", Response: model.Host{}},
+	"HostNetworks":          {Summary: "Returns the host's network interfaces", Response: []*model.Network{}},
+	"HostInitiators":        {Summary: "Returns the host's iSCSI and FC initiators", Response: []*model.Initiator{}},
+	"RotateCredentials":     {Summary: "Rotates the iSCSI initiator name and/or CHAP credentials, logging in again one connection at a time", Request: model.CredentialRotation{}, Response: []*model.ReloginResult{}},
+	"UnconnectedTargets":    {Summary: "Returns the discovered iSCSI targets without a session", Response: []*model.UnconnectedTarget{}, Paged: true},
+	"FcTargetPorts":         {Summary: "Returns the remote FC target ports discovered by the host ports", Response: []*model.FcTargetPort{}},
+	"CollectStaleSessions":  {Summary: "Logs out the iSCSI targets whose sessions have reported no LUN for longer than the stale session age", Request: model.StaleSessionCollection{}, Response: []*model.StaleTarget{}},
+	"InvalidateLunMaps":     {Summary: "Drops the cached LUN maps of the given iSCSI targets, or every cached LUN map", Request: model.LunMapInvalidation{}, Response: []string{}},
+	"Readiness":             {Summary: "Reports whether the host is ready to attach volumes", Response: model.Readiness{}},
+	"Capabilities":          {Summary: "Lists the features supported on the host's platform", Response: model.Capabilities{}},
+	"Devices":               {Summary: "Enumerates the devices on the host", Query: []string{"serial"}, Response: []*model.Device{}, Paged: true},
+	"AllDeviceDetails":      {Summary: "Enumerates the devices on the host with details", Query: []string{"serial", "fields"}, Response: []*model.Device{}, Paged: true},
+	"GetIgnoredDevices":     {Summary: "Returns the devices CHAPI ignores", Response: []*model.IgnoredDevice{}, Paged: true},
+	"AddIgnoredDevice":      {Summary: "Ignores a device", Request: model.IgnoredDevice{}, Response: model.IgnoredDevice{}},
+	"RemoveIgnoredDevice":   {Summary: "Stops ignoring a device"},
+	"PartitionsForDevice":   {Summary: "Returns the partitions of a device", Response: []*model.DevicePartition{}},
+	"CreateDevice":          {Summary: "Attaches a device", Request: model.PublishInfo{}, Response: model.Device{}},
+	"PlanCreateDevice":      {Summary: "Reports how a device would be attached, without attaching it", Request: model.PublishInfo{}, Response: model.AttachPlan{}},
+	"DeleteDevice":          {Summary: "Detaches a device", Response: model.Device{}},
+	"OfflineDevice":         {Summary: "Offlines a device", Response: model.Device{}},
+	"ExtendPartition":       {Summary: "Extends a device's partition to fill the device", Response: []*model.DevicePartition{}},
+	"BenchmarkDevice":       {Summary: "Measures the read latency of a device", Request: model.BenchmarkRequest{}, Response: model.BenchmarkResult{}},
+	"GetDeviceTuning":       {Summary: "Returns the queue settings of a device", Response: model.DeviceTuning{}},
+	"GetDevicePaths":        {Summary: "Returns the paths of a device", Response: []*model.DevicePathGroup{}},
+	"GetFormatStatus":       {Summary: "Returns the progress of a device's file system creation", Response: model.FormatStatus{}},
+	"RecoverDevicePaths":    {Summary: "Reinstates the failed paths of a device that are reachable again", Response: model.PathRecoveryReport{}},
+	"SetDeviceTuning":       {Summary: "Sets the queue settings of a device", Request: model.DeviceTuning{}, Response: model.DeviceTuning{}},
+	"GetDeviceClaim":        {Summary: "Returns the ownership claim on a device", Response: model.DeviceClaim{}},
+	"ClaimDevice":           {Summary: "Claims a device for an orchestrator, or renews its claim", Request: model.ClaimRequest{}, Response: model.DeviceClaim{}},
+	"ReleaseDevice":         {Summary: "Releases an orchestrator's claim on a device", Request: model.ClaimRequest{}},
+	"CreateFileSystem":      {Summary: "Creates a file system on a device", Request: model.FileSystemOptions{}},
+	"GetMounts":             {Summary: "Enumerates the mount points on the host", Query: []string{"serial", "mountPointPrefix"}, Response: []*model.Mount{}, Paged: true},
+	"GetAllMountDetails":    {Summary: "Enumerates the mount points on the host with details", Query: []string{"serial", "mountId", "mountPointPrefix", "fields"}, Response: []*model.Mount{}, Paged: true},
+	"GetFreeDriveLetters":   {Summary: "Returns the free drive letters (Windows only)", Response: []string{}},
+	"CreateMount":           {Summary: "Mounts a device", Request: model.Mount{}, Response: model.Mount{}},
+	"GetOrphanedMounts":     {Summary: "Returns the orphaned mount points beneath the given roots", Query: []string{"root"}, Response: []*model.OrphanedMount{}, Paged: true},
+	"DeleteOrphanedMounts":  {Summary: "Removes the orphaned mount points beneath the given roots", Query: []string{"root"}, Response: []*model.OrphanedMount{}},
+	"FreezeMount":           {Summary: "Freezes a mount point's file system", Request: model.QuiesceRequest{}, Response: model.QuiescedMount{}},
+	"ThawMount":             {Summary: "Thaws a mount point's file system", Request: model.QuiesceRequest{}},
+	"StageMount":            {Summary: "Mounts a volume once at its staging path", Request: model.StagedMountRequest{}, Response: model.Mount{}},
+	"PublishMount":          {Summary: "Bind mounts a volume's staging path to a target path", Request: model.StagedMountRequest{}, Response: model.Mount{}},
+	"UnpublishMount":        {Summary: "Removes a volume's bind mount at a target path", Request: model.StagedMountRequest{}},
+	"UnstageMount":          {Summary: "Unmounts a volume from its staging path once it is no longer published", Request: model.StagedMountRequest{}},
+	"DeleteMount":           {Summary: "Unmounts a mount point, the request body is the device serial number", Query: []string{"lazy"}, Request: "", Response: model.Mount{}},
+	"MoveMount":             {Summary: "Moves a mount point without unmounting the volume", Request: model.Mount{}, Response: model.Mount{}},
+	"DrainNode":             {Summary: "Unmounts, flushes and detaches the given volumes", Request: model.DrainRequest{}, Response: []*model.DrainResult{}},
+	"GetManagedState":       {Summary: "Returns the devices and mount points CHAPI manages", Response: model.ManagedState{}},
+	"ReconcileManagedState": {Summary: "Removes the managed state of devices and mount points no longer present", Response: model.ManagedState{}},
+}
+
+// newOpenAPIDocument returns the OpenAPI document describing the CHAPI endpoints
+func newOpenAPIDocument() *openapi.Document {
+	var endpoints []*openapi.Endpoint
+	for _, route := range getRoutes() {
+		endpoint, ok := routeSchemas[route.Name]
+		if !ok {
+			endpoint = platformSpecificSchemas[route.Name]
+		}
+		endpoint.Name, endpoint.Method, endpoint.Pattern = route.Name, route.Method, route.Pattern
+		endpoints = append(endpoints, &endpoint)
+	}
+	return openapi.NewDocument(openAPITitle, openAPIVersion, endpoints)
+}
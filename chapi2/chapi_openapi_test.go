@@ -0,0 +1,52 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteSchemas(t *testing.T) {
+	// Every route must be described so that the OpenAPI document does not drift from the routes
+	for _, route := range getRoutes() {
+		_, found := routeSchemas[route.Name]
+		_, platformFound := platformSpecificSchemas[route.Name]
+		if !found && !platformFound {
+			t.Errorf("route %v has no routeSchemas entry", route.Name)
+		}
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	router := NewDiagnosticsRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/openapi.json returned %v", w.Code)
+	}
+
+	var document struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if document.OpenAPI == "" {
+		t.Errorf("unexpected document %v", w.Body.String())
+	}
+	if _, ok := document.Paths["/api/v1/mounts/{mountId}"]["delete"]; !ok {
+		t.Error("DeleteMount operation not documented")
+	}
+	for _, name := range []string{"Device", "Mount", "PublishInfo", "ChapiError"} {
+		if _, ok := document.Components.Schemas[name]; !ok {
+			t.Errorf("%v schema not documented", name)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// POWER EVENT MONITOR
+//
+//		If PowerEventsEnv is set to "true", the CHAPI managed volumes are quiesced before the host
+//		shuts down and flushed before it suspends (see POWER EVENTS in driver_power.go):
+//
+//		Linux		CHAPI holds a systemd-logind delay inhibitor lock (systemd-inhibit) for
+//					shutdown and sleep, and monitors the logind PrepareForShutdown and
+//					PrepareForSleep signals (busctl, systemd 240 or later).  The lock is released
+//					once the volumes are quiesced, and taken again when the host resumes.  logind
+//					only waits InhibitDelayMaxSec (5 seconds by default, see logind.conf) for the
+//					lock to be released.
+//		Windows		The CHAPI service passes QuiesceForShutdown and FlushForSuspend as the
+//					winservice PreShutdown (SERVICE_CONTROL_PRESHUTDOWN) and Suspend
+//					(PBT_APMSUSPEND) handlers.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/driver"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// PowerEventsEnv enables, when set to "true", quiescing the managed volumes on shutdown and
+	// suspend
+	PowerEventsEnv = config.PowerEventsEnv
+)
+
+// isPowerEventsEnabled returns true if PowerEventsEnv is set to "true"
+func isPowerEventsEnabled() bool {
+	return config.Enabled(PowerEventsEnv)
+}
+
+// QuiesceForShutdown quiesces the CHAPI managed volumes before the host shuts down, if enabled by
+// PowerEventsEnv
+func QuiesceForShutdown() {
+	if !isPowerEventsEnabled() {
+		return
+	}
+	log.Info("Host shutting down, quiescing the managed volumes")
+	(&driver.ChapiServer{}).QuiesceForShutdown()
+}
+
+// FlushForSuspend flushes the CHAPI managed volumes before the host suspends, if enabled by
+// PowerEventsEnv
+func FlushForSuspend() {
+	if !isPowerEventsEnabled() {
+		return
+	}
+	log.Info("Host suspending, flushing the managed volumes")
+	(&driver.ChapiServer{}).FlushForSuspend()
+}
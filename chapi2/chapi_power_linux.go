@@ -0,0 +1,124 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os/exec"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	inhibitCommand = "systemd-inhibit"
+	busctlCommand  = "busctl"
+
+	// logind signals monitored for shutdown and sleep; their boolean argument is true before the
+	// host shuts down (or sleeps) and false once the shutdown is cancelled (or the host resumed)
+	logindManagerInterface   = "org.freedesktop.login1.Manager"
+	logindPrepareForShutdown = "PrepareForShutdown"
+	logindPrepareForSleep    = "PrepareForSleep"
+)
+
+// delayLock is a systemd-logind delay inhibitor lock, held as long as its systemd-inhibit process
+// runs.  The process runs cat on a pipe so that it exits, releasing the lock, once the pipe is
+// closed or CHAPI exits.
+type delayLock struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// startPowerEventMonitor starts the power event monitor if PowerEventsEnv is set
+func startPowerEventMonitor() {
+	if !isPowerEventsEnabled() {
+		return
+	}
+	go monitorPowerEvents()
+}
+
+// monitorPowerEvents holds a delay inhibitor lock and quiesces (or flushes) the managed volumes,
+// before releasing the lock, when logind announces a shutdown (or sleep)
+func monitorPowerEvents() {
+	cmd := exec.Command(busctlCommand, "--system", "--json=short", "monitor", "--match", "type='signal',interface='"+logindManagerInterface+"'")
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		log.Errorf("Unable to monitor the logind power events, err=%v", err)
+		return
+	}
+	log.Info("Monitoring the logind power events to quiesce the managed volumes")
+
+	lock := takeDelayLock()
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		member, active, ok := parseLogindSignal(scanner.Bytes())
+		if !ok {
+			continue
+		}
+		log.Tracef("logind %v(%v)", member, active)
+		switch {
+		case active && member == logindPrepareForShutdown:
+			QuiesceForShutdown()
+			lock = lock.release()
+		case active && member == logindPrepareForSleep:
+			FlushForSuspend()
+			lock = lock.release()
+		case !active && lock == nil:
+			// Shutdown cancelled or host resumed
+			lock = takeDelayLock()
+		}
+	}
+	err = cmd.Wait()
+	log.Errorf("Stopped monitoring the logind power events, err=%v", err)
+	lock.release()
+}
+
+// takeDelayLock takes a delay inhibitor lock for shutdown and sleep; nil is returned if the lock
+// cannot be taken
+func takeDelayLock() *delayLock {
+	cmd := exec.Command(inhibitCommand, "--what=shutdown:sleep", "--who=HPE CHAPI", "--why=Quiescing the HPE storage volumes", "--mode=delay", "cat")
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		log.Errorf("Unable to take a shutdown delay lock, err=%v", err)
+		return nil
+	}
+	return &delayLock{cmd: cmd, stdin: stdin}
+}
+
+// release releases the delay inhibitor lock, if held, and returns nil
+func (lock *delayLock) release() *delayLock {
+	if lock != nil {
+		lock.stdin.Close()
+		lock.cmd.Wait()
+	}
+	return nil
+}
+
+// parseLogindSignal parses a logind Manager signal from the JSON output of busctl monitor and
+// returns its name and boolean argument.  false is returned if the line is not a logind signal.
+func parseLogindSignal(line []byte) (member string, active bool, ok bool) {
+	var signal struct {
+		Type      string `json:"type"`
+		Interface string `json:"interface"`
+		Member    string `json:"member"`
+		Payload   struct {
+			Data []interface{} `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(line, &signal); err != nil {
+		return "", false, false
+	}
+	if signal.Type != "signal" || signal.Interface != logindManagerInterface || len(signal.Payload.Data) != 1 {
+		return "", false, false
+	}
+	active, ok = signal.Payload.Data[0].(bool)
+	return signal.Member, active, ok
+}
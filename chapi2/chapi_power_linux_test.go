@@ -0,0 +1,28 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapi2
+
+import (
+	"testing"
+)
+
+func TestParseLogindSignal(t *testing.T) {
+	tests := []struct {
+		line   string
+		member string
+		active bool
+		ok     bool
+	}{
+		{`{"type":"signal","interface":"org.freedesktop.login1.Manager","member":"PrepareForShutdown","payload":{"type":"b","data":[true]}}`, "PrepareForShutdown", true, true},
+		{`{"type":"signal","interface":"org.freedesktop.login1.Manager","member":"PrepareForSleep","payload":{"type":"b","data":[false]}}`, "PrepareForSleep", false, true},
+		{`{"type":"signal","interface":"org.freedesktop.login1.Manager","member":"SessionNew","payload":{"type":"so","data":["3","/org/freedesktop/login1/session/_33"]}}`, "", false, false},
+		{`{"type":"method_call","interface":"org.freedesktop.login1.Manager","member":"PrepareForShutdown","payload":{"type":"b","data":[true]}}`, "", false, false},
+		{`Monitoring bus message stream.`, "", false, false},
+	}
+	for _, tc := range tests {
+		member, active, ok := parseLogindSignal([]byte(tc.line))
+		if member != tc.member || active != tc.active || ok != tc.ok {
+			t.Errorf("parseLogindSignal(%v) = %v, %v, %v", tc.line, member, active, ok)
+		}
+	}
+}
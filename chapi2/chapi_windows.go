@@ -9,9 +9,23 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/driver"
 	"github.com/hpe-storage/common-host-libs/chapi2/handler"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/openapi"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/util"
+	"github.com/hpe-storage/common-host-libs/windows/wmi"
+)
+
+const (
+	// EventLogEnv disables writing the CHAPI errors to the Windows Application event log when set
+	// to "false"
+	EventLogEnv = config.EventLogEnv
+
+	// EventLogSource is the Windows event source of the CHAPI errors
+	EventLogSource = "HPE CHAPI"
 )
 
 var (
@@ -28,6 +42,18 @@ var platformSpecificEndpoints = []util.Route{
 		Pattern:     "/api/v1/keyfile",
 		HandlerFunc: handler.GetKeyfile,
 	},
+	util.Route{
+		Name:        "TargetMappings",
+		Method:      "GET",
+		Pattern:     "/api/v1/targets/mappings",
+		HandlerFunc: handler.GetTargetMappings,
+	},
+}
+
+// platformSpecificSchemas describes the objects returned by the platformSpecificEndpoints
+var platformSpecificSchemas = map[string]openapi.Endpoint{
+	"Keyfile":        {Summary: "Returns the location of the CHAPI authentication key file", Response: model.KeyFileInfo{}},
+	"TargetMappings": {Summary: "Returns the OS SCSI address mappings of the active iSCSI sessions", Query: []string{"target"}, Response: []*model.IscsiTargetMapping{}},
 }
 
 // Run will invoke a new chapid listener
@@ -43,6 +69,29 @@ func Run() (err error) {
 		return nil
 	}
 
+	// Log the CHAPI settings configured in the environment
+	config.LogSettings()
+
+	// Write the CHAPI errors to the Windows Application event log, where Windows administrators
+	// look for them, unless disabled
+	if !config.Disabled(EventLogEnv) {
+		if err := log.AddEventLogHook(EventLogSource); err != nil {
+			log.Warnf("Unable to write to the Windows event log, err=%v", err)
+		}
+	}
+
+	// Initialize WMI now that the process is set up; if unsuccessful, the initialization is
+	// retried on each WMI query
+	if err := wmi.InitWMI(); err != nil {
+		log.Errorf("Unable to initialize WMI, err=%v", err)
+	}
+
+	// Detect (and optionally replace) an iSCSI initiator name shared with a cloned host
+	checkIscsiInitiatorName()
+
+	// Periodically log out the iSCSI targets left without any LUN (see iscsi.StaleSessionIntervalEnv)
+	driver.StartStaleSessionCollector()
+
 	chapidResult := make(chan error)
 	// start chapid server
 	go startChapid(chapidResult)
@@ -75,12 +124,18 @@ func startChapid(result chan error) {
 			// Allocate our mux.Router object
 			router := NewRouter()
 
+			// Advertise the CHAPI port, if enabled; failures are logged but not fatal
+			advertised := isAdvertisementEnabled() && StartAdvertisement(port) == nil
+
 			// indicate on channel before we block on listener
 			result <- nil
 			err = http.Serve(chapidListener, router)
 			if err != nil {
 				log.Tracef("exiting chapid server, err=%v", err.Error())
 			}
+			if advertised {
+				StopAdvertisement()
+			}
 
 			// Remove our CHAPI port and key files now that CHAPI has exited
 			handler.RemoveChapiInstanceData()
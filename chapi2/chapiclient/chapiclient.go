@@ -3,7 +3,9 @@
 package chapiclient
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -19,33 +21,81 @@ const (
 	apiVersion = "api/v1"
 
 	// Host Endpoints
-	hostURI       = apiVersion + "/hosts"      // api/v1/hosts
-	initiatorsURI = apiVersion + "/initiators" // api/v1/initiators
-	networksURI   = apiVersion + "/networks"   // api/v1/networks
+	hostURI       = apiVersion + "/hosts"                         // api/v1/hosts
+	initiatorsURI = apiVersion + "/initiators"                    // api/v1/initiators
+	rotateURI     = initiatorsURI + "/actions/rotate-credentials" // api/v1/initiators/actions/rotate-credentials
+	networksURI   = apiVersion + "/networks"                      // api/v1/networks
+
+	// Target Endpoints
+	targetsURI            = apiVersion + "/targets"                        // api/v1/targets
+	targetsUnconnectedURI = targetsURI + "/unconnected"                    // api/v1/targets/unconnected
+	targetsFcURI          = targetsURI + "/fc"                             // api/v1/targets/fc
+	targetsCollectURI     = targetsURI + "/actions/collect-stale-sessions" // api/v1/targets/actions/collect-stale-sessions
+	targetsInvalidateURI  = targetsURI + "/actions/invalidate-lun-maps"    // api/v1/targets/actions/invalidate-lun-maps
+
+	// Readiness Endpoints
+	readinessURI = apiVersion + "/readiness" // api/v1/readiness
+
+	// Capabilities Endpoints
+	capabilitiesURI = apiVersion + "/capabilities" // api/v1/capabilities
+
+	// Latency Endpoints
+	latencyURI = apiVersion + "/latency" // api/v1/latency
 
 	// Device Endpoints
-	devicesURI           = apiVersion + "/devices"            // api/v1/devices
-	devicesDetailURI     = devicesURI + "/details"            // api/v1/devices/details
-	devicesPartitionsURI = devicesURI + "/%v/partitions"      // api/v1/devices/{serialnumber}/partitions
-	devicesOfflineURI    = devicesURI + "/%v/actions/offline" // api/v1/devices/{serialnumber}/actions/offline
-	devicesFileSystemURI = devicesURI + "/%v/%v"              // api/v1/devices/{serialnumber}/filesystem/{filesystem}
+	devicesURI           = apiVersion + "/devices"                     // api/v1/devices
+	devicesDetailURI     = devicesURI + "/details"                     // api/v1/devices/details
+	devicesPlanURI       = devicesURI + "/actions/plan"                // api/v1/devices/actions/plan
+	devicesPartitionsURI = devicesURI + "/%v/partitions"               // api/v1/devices/{serialnumber}/partitions
+	devicesOfflineURI    = devicesURI + "/%v/actions/offline"          // api/v1/devices/{serialnumber}/actions/offline
+	devicesExtendURI     = devicesURI + "/%v/actions/extend-partition" // api/v1/devices/{serialnumber}/actions/extend-partition
+	devicesFileSystemURI = devicesURI + "/%v/%v"                       // api/v1/devices/{serialnumber}/filesystem/{filesystem}
+	devicesTuningURI     = devicesURI + "/%v/tuning"                   // api/v1/devices/{serialnumber}/tuning
+	devicesPathsURI      = devicesURI + "/%v/paths"                    // api/v1/devices/{serialnumber}/paths
+	devicesFormatURI     = devicesURI + "/%v/format"                   // api/v1/devices/{serialnumber}/format
+	devicesRecoverURI    = devicesURI + "/%v/actions/recover-paths"    // api/v1/devices/{serialnumber}/actions/recover-paths
+	devicesBenchmarkURI  = devicesURI + "/%v/actions/benchmark"        // api/v1/devices/{serialnumber}/actions/benchmark
+	devicesClaimURI      = devicesURI + "/%v/claim"                    // api/v1/devices/{serialnumber}/claim
+	devicesIgnoredURI    = devicesURI + "/ignored"                     // api/v1/devices/ignored
+	devicesIgnoredIDURI  = devicesIgnoredURI + "/%v"                   // api/v1/devices/ignored/{wwid}
 
 	// Mount Endpoints
-	mountsURI       = apiVersion + "/mounts" // api/v1/mounts
-	mountsDetailURI = mountsURI + "/details" // api/v1/mounts/details
-	mountsDeleteURI = mountsURI + "/%v"      // api/v1/mounts/{mountId}
+	mountsURI             = apiVersion + "/mounts"           // api/v1/mounts
+	mountsDetailURI       = mountsURI + "/details"           // api/v1/mounts/details
+	mountsDeleteURI       = mountsURI + "/%v"                // api/v1/mounts/{mountId}
+	mountsMoveURI         = mountsURI + "/%v/move"           // api/v1/mounts/{mountId}/move
+	mountsOrphanURI       = mountsURI + "/orphans"           // api/v1/mounts/orphans
+	mountsFreezeURI       = mountsURI + "/actions/freeze"    // api/v1/mounts/actions/freeze
+	mountsThawURI         = mountsURI + "/actions/thaw"      // api/v1/mounts/actions/thaw
+	mountsStageURI        = mountsURI + "/actions/stage"     // api/v1/mounts/actions/stage
+	mountsPublishURI      = mountsURI + "/actions/publish"   // api/v1/mounts/actions/publish
+	mountsUnpublishURI    = mountsURI + "/actions/unpublish" // api/v1/mounts/actions/unpublish
+	mountsUnstageURI      = mountsURI + "/actions/unstage"   // api/v1/mounts/actions/unstage
+	mountsDriveLettersURI = mountsURI + "/driveletters"      // api/v1/mounts/driveletters
+
+	// Node Endpoints
+	nodeDrainURI = apiVersion + "/node/drain" // api/v1/node/drain
+
+	// State Endpoints
+	stateURI          = apiVersion + "/state"           // api/v1/state
+	stateReconcileURI = stateURI + "/actions/reconcile" // api/v1/state/actions/reconcile
 )
 
 const (
 	// Query Parameters
-	queryMountID      = "mountId" // e.g. api/v1/mounts/details?serial=1234&mountId=5678
-	querySerialNumber = "serial"  // e.g. api/v1/devices/details?serial=1234
+	queryFields           = "fields"           // e.g. api/v1/devices/details?fields=serial_number,size
+	queryLazy             = "lazy"             // e.g. api/v1/mounts/5678?lazy=true
+	queryMountID          = "mountId"          // e.g. api/v1/mounts/details?serial=1234&mountId=5678
+	queryMountPointPrefix = "mountPointPrefix" // e.g. api/v1/mounts/details?mountPointPrefix=%2Fvar%2Flib%2Fkubelet
+	queryRoot             = "root"             // e.g. api/v1/mounts/orphans?root=%2Fmnt%2Fvolumes
+	querySerialNumber     = "serial"           // e.g. api/v1/devices/details?serial=1234
 )
 
 // ClientBase defines platform independent properties and is embedded within the Client object
 type ClientBase struct {
 	client *connectivity.Client // HTTP client for connectivity to chapid server
 	header map[string]string    // HTTP headers
+	strict bool                 // True to validate the responses (see SetStrictMode)
 }
 
 var (
@@ -58,6 +108,7 @@ var (
 // Response object defines the data and/or error that are returned by a CHAPI endpoint
 type Response struct {
 	Data interface{}         `json:"data,omitempty"`
+	Page *model.Page         `json:"page,omitempty"` // Page of the list returned, if the limit or offset query parameter was given
 	Err  *cerrors.ChapiError `json:"errors,omitempty"`
 }
 
@@ -87,6 +138,53 @@ func (chapiClient *Client) addHeader(header map[string]string) {
 	chapiClient.header = header
 }
 
+// SetOwner sends the given owner, in the model.OwnerHeader header, with each CHAPI request so that
+// the requests changing a device claimed by the owner are allowed (see ClaimDevice)
+func (chapiClient *Client) SetOwner(owner string) {
+	chapiClient.setHeader(model.OwnerHeader, owner)
+}
+
+// SetClaimOverride enables (or disables) the administrative override of other owners' device
+// claims for each CHAPI request
+func (chapiClient *Client) SetClaimOverride(override bool) {
+	value := ""
+	if override {
+		value = "true"
+	}
+	chapiClient.setHeader(model.ClaimOverrideHeader, value)
+}
+
+// SetAPIToken sends the given API token, in the Authorization header, with each CHAPI request (see
+// API TOKENS in chapi_auth.go).  An empty token stops sending the header.
+func (chapiClient *Client) SetAPIToken(token string) {
+	value := ""
+	if token != "" {
+		value = "Bearer " + token
+	}
+	chapiClient.setHeader("Authorization", value)
+}
+
+// SetRequestContext sends the request correlation ID carried by the given context (see
+// logger.RequestIDFromContext) with each CHAPI request, so that they can be traced in the CHAPI
+// logs to the request being served
+func (chapiClient *Client) SetRequestContext(ctx context.Context) {
+	chapiClient.setHeader(log.RequestIDHeader, log.RequestIDFromContext(ctx))
+}
+
+// setHeader sets (or, if the value is empty, removes) the given HTTP header of each CHAPI request
+func (chapiClient *Client) setHeader(key string, value string) {
+	header := make(map[string]string)
+	for k, v := range chapiClient.header {
+		header[k] = v
+	}
+	if value == "" {
+		delete(header, key)
+	} else {
+		header[key] = value
+	}
+	chapiClient.header = header
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // Host Methods
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -117,6 +215,20 @@ func (chapiClient *Client) GetHostInitiators() (initiators []*model.Initiator, e
 	return initiators, nil
 }
 
+// RotateIscsiCredentials applies the new iSCSI initiator name and/or CHAP credentials and logs in
+// again the logged in targets, one connection at a time.  The outcome of each target is returned.
+func (chapiClient *Client) RotateIscsiCredentials(rotation *model.CredentialRotation) (results []*model.ReloginResult, err error) {
+	log.Tracef(">>>>> RotateIscsiCredentials called, initiatorName=%v, chapUser=%v, targets=%v", rotation.InitiatorName, rotation.ChapUser, rotation.Targets)
+	defer log.Trace("<<<<< RotateIscsiCredentials")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &results, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: rotateURI, Header: chapiClient.header, Payload: rotation, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 // GetHostNetworks reports the networks on this host
 func (chapiClient *Client) GetHostNetworks() (networks []*model.Network, err error) {
 	log.Trace(">>>>> GetHostNetworks called")
@@ -130,6 +242,99 @@ func (chapiClient *Client) GetHostNetworks() (networks []*model.Network, err err
 	return networks, nil
 }
 
+// GetUnconnectedTargets reports the iSCSI targets configured on this host without an active session
+func (chapiClient *Client) GetUnconnectedTargets() (targets []*model.UnconnectedTarget, err error) {
+	log.Trace(">>>>> GetUnconnectedTargets called")
+	defer log.Trace("<<<<< GetUnconnectedTargets")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &targets, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: targetsUnconnectedURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// GetFcTargetPorts reports the remote FC target ports discovered by the host ports
+func (chapiClient *Client) GetFcTargetPorts() (targetPorts []*model.FcTargetPort, err error) {
+	log.Trace(">>>>> GetFcTargetPorts called")
+	defer log.Trace("<<<<< GetFcTargetPorts")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &targetPorts, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: targetsFcURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return targetPorts, nil
+}
+
+// CollectStaleSessions logs out the iSCSI targets whose sessions have reported no LUN for longer
+// than the stale session age.  Every target without a LUN is returned.
+func (chapiClient *Client) CollectStaleSessions(collection *model.StaleSessionCollection) (staleTargets []*model.StaleTarget, err error) {
+	log.Tracef(">>>>> CollectStaleSessions called, targets=%v, dryRun=%v", collection.Targets, collection.DryRun)
+	defer log.Trace("<<<<< CollectStaleSessions")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &staleTargets, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: targetsCollectURI, Header: chapiClient.header, Payload: collection, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return staleTargets, nil
+}
+
+// InvalidateLunMaps drops the cached LUN maps of the given iSCSI targets, or every cached LUN map.
+// The targets whose map was dropped are returned.
+func (chapiClient *Client) InvalidateLunMaps(invalidation *model.LunMapInvalidation) (targetNames []string, err error) {
+	log.Tracef(">>>>> InvalidateLunMaps called, targets=%v", invalidation.Targets)
+	defer log.Trace("<<<<< InvalidateLunMaps")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &targetNames, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: targetsInvalidateURI, Header: chapiClient.header, Payload: invalidation, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return targetNames, nil
+}
+
+// GetReadiness reports how ready this host is to use HPE storage
+func (chapiClient *Client) GetReadiness() (readiness *model.Readiness, err error) {
+	log.Trace(">>>>> GetReadiness called")
+	defer log.Trace("<<<<< GetReadiness")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &readiness, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: readinessURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return readiness, nil
+}
+
+// GetCapabilities reports the features supported on the host's platform
+func (chapiClient *Client) GetCapabilities() (capabilities *model.Capabilities, err error) {
+	log.Trace(">>>>> GetCapabilities called")
+	defer log.Trace("<<<<< GetCapabilities")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &capabilities, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: capabilitiesURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return capabilities, nil
+}
+
+// GetLatency reports the request latency histogram of each CHAPI endpoint
+func (chapiClient *Client) GetLatency() (latency *model.LatencyReport, err error) {
+	log.Trace(">>>>> GetLatency called")
+	defer log.Trace("<<<<< GetLatency")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &latency, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: latencyURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return latency, nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // Device methods
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -164,6 +369,23 @@ func (chapiClient *Client) GetAllDeviceDetails(serialNumber string) (devices []*
 	return devices, nil
 }
 
+// GetDeviceDetailFields enumerates all the Nimble volumes with detailed information, only
+// populating the given Device properties (e.g. "serial_number"), or every property if none are
+// given.  If serialNumber is non-empty then only specified device is returned
+func (chapiClient *Client) GetDeviceDetailFields(serialNumber string, fields []string) (devices []*model.Device, err error) {
+	log.Tracef(">>>>> GetDeviceDetailFields called, serialNumber=%v, fields=%v", serialNumber, fields)
+	defer log.Trace("<<<<< GetDeviceDetailFields")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &devices, Err: nil}
+	devicesURIOut := chapiClient.appendQuerySerialNumber(devicesDetailURI, serialNumber)
+	devicesURIOut = chapiClient.appendQueryFields(devicesURIOut, fields)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: devicesURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
 // GetPartitionInfo reports the partitions on the provided device
 func (chapiClient *Client) GetPartitionInfo(serialNumber string) (partitions []*model.DevicePartition, err error) {
 	log.Tracef(">>>>> GetPartitionInfo called, serialNumber=%v", serialNumber)
@@ -180,7 +402,7 @@ func (chapiClient *Client) GetPartitionInfo(serialNumber string) (partitions []*
 
 // CreateDevice will attach device on this host based on the details provided
 func (chapiClient *Client) CreateDevice(publishInfo model.PublishInfo) (device *model.Device, err error) {
-	log.Tracef(">>>>> CreateDevice called, publishInfo=%v", publishInfo)
+	log.Tracef(">>>>> CreateDevice called, publishInfo=%v", publishInfo.Redacted())
 	defer log.Trace("<<<<< CreateDevice")
 
 	// Initialize CHAPI response object, submit request to specified endpoint, return status
@@ -191,6 +413,19 @@ func (chapiClient *Client) CreateDevice(publishInfo model.PublishInfo) (device *
 	return device, nil
 }
 
+// PlanCreateDevice reports how CreateDevice would attach the device, without attaching it
+func (chapiClient *Client) PlanCreateDevice(publishInfo model.PublishInfo) (plan *model.AttachPlan, err error) {
+	log.Tracef(">>>>> PlanCreateDevice called, publishInfo=%v", publishInfo.Redacted())
+	defer log.Trace("<<<<< PlanCreateDevice")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &plan, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "POST", Path: devicesPlanURI, Header: chapiClient.header, Payload: &publishInfo, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
 // DeleteDevice will delete the given device from the host
 func (chapiClient *Client) DeleteDevice(serialNumber string) (err error) {
 	log.Tracef(">>>>> DeleteDevice called, serialNumber=%v", serialNumber)
@@ -219,15 +454,208 @@ func (chapiClient *Client) OfflineDevice(serialNumber string) (err error) {
 	return nil
 }
 
+// ExtendPartition extends the last partition on the device with the given serial number to fill
+// the device and returns the updated partitions
+func (chapiClient *Client) ExtendPartition(serialNumber string) (partitions []*model.DevicePartition, err error) {
+	log.Tracef(">>>>> ExtendPartition called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< ExtendPartition")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &partitions, Err: nil}
+	deviceExtendURIOut := fmt.Sprintf(devicesExtendURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: deviceExtendURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return partitions, nil
+}
+
 // CreateFileSystem writes the given file system to the device with the given serial number
 func (chapiClient *Client) CreateFileSystem(serialNumber string, filesystem string) (err error) {
-	log.Tracef(">>>>> CreateFileSystem called, serialNumber=%v, filesystem=%v", serialNumber, filesystem)
-	defer log.Trace("<<<<< CreateFileSystem")
+	return chapiClient.CreateFileSystemWithOptions(serialNumber, filesystem, nil)
+}
+
+// CreateFileSystemWithOptions writes the given file system, created with the given file system
+// options (may be nil), to the device with the given serial number
+func (chapiClient *Client) CreateFileSystemWithOptions(serialNumber string, filesystem string, fsOptions *model.FileSystemOptions) (err error) {
+	log.Tracef(">>>>> CreateFileSystemWithOptions called, serialNumber=%v, filesystem=%v, fsOptions=%+v", serialNumber, filesystem, fsOptions)
+	defer log.Trace("<<<<< CreateFileSystemWithOptions")
 
 	// Initialize CHAPI response object, submit request to specified endpoint, return status
 	chapiResp := Response{Data: nil, Err: nil}
 	deviceFileSystemURIOut := fmt.Sprintf(devicesFileSystemURI, serialNumber, filesystem)
-	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: deviceFileSystemURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: deviceFileSystemURIOut, Header: chapiClient.header, Payload: fsOptions, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetDeviceTuning reports the current queue settings for the device with the given serial number
+func (chapiClient *Client) GetDeviceTuning(serialNumber string) (tuning *model.DeviceTuning, err error) {
+	log.Tracef(">>>>> GetDeviceTuning called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< GetDeviceTuning")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &tuning, Err: nil}
+	deviceTuningURIOut := fmt.Sprintf(devicesTuningURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: deviceTuningURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return tuning, nil
+}
+
+// GetFormatStatus reports the progress of the file system creation in progress on the device with
+// the given serial number
+func (chapiClient *Client) GetFormatStatus(serialNumber string) (status *model.FormatStatus, err error) {
+	log.Tracef(">>>>> GetFormatStatus called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< GetFormatStatus")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &status, Err: nil}
+	deviceFormatURIOut := fmt.Sprintf(devicesFormatURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: deviceFormatURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// GetDevicePaths reports the paths to the device with the given serial number grouped by their
+// ALUA access state
+func (chapiClient *Client) GetDevicePaths(serialNumber string) (pathGroups []*model.DevicePathGroup, err error) {
+	log.Tracef(">>>>> GetDevicePaths called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< GetDevicePaths")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &pathGroups, Err: nil}
+	devicePathsURIOut := fmt.Sprintf(devicesPathsURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: devicePathsURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return pathGroups, nil
+}
+
+// RecoverDevicePaths reinstates the failed paths, that are reachable again, of the device with the
+// given serial number
+func (chapiClient *Client) RecoverDevicePaths(serialNumber string) (report *model.PathRecoveryReport, err error) {
+	log.Tracef(">>>>> RecoverDevicePaths called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< RecoverDevicePaths")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &report, Err: nil}
+	deviceRecoverURIOut := fmt.Sprintf(devicesRecoverURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: deviceRecoverURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// BenchmarkDevice runs a short read-only latency probe against the device with the given serial
+// number.  The client's timeout must allow for the requested benchmark duration.
+func (chapiClient *Client) BenchmarkDevice(serialNumber string, request model.BenchmarkRequest) (result *model.BenchmarkResult, err error) {
+	log.Tracef(">>>>> BenchmarkDevice called, serialNumber=%v, request=%+v", serialNumber, request)
+	defer log.Trace("<<<<< BenchmarkDevice")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &result, Err: nil}
+	deviceBenchmarkURIOut := fmt.Sprintf(devicesBenchmarkURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "POST", Path: deviceBenchmarkURIOut, Header: chapiClient.header, Payload: &request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetDeviceTuning applies the given queue settings to the device with the given serial number and
+// returns the resulting settings
+func (chapiClient *Client) SetDeviceTuning(serialNumber string, tuning model.DeviceTuning) (currentTuning *model.DeviceTuning, err error) {
+	log.Tracef(">>>>> SetDeviceTuning called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< SetDeviceTuning")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &currentTuning, Err: nil}
+	deviceTuningURIOut := fmt.Sprintf(devicesTuningURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: deviceTuningURIOut, Header: chapiClient.header, Payload: &tuning, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return currentTuning, nil
+}
+
+// GetIgnoredDevices reports the device WWIDs that are hidden from device enumeration
+func (chapiClient *Client) GetIgnoredDevices() (ignoredDevices []*model.IgnoredDevice, err error) {
+	log.Trace(">>>>> GetIgnoredDevices called")
+	defer log.Trace("<<<<< GetIgnoredDevices")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &ignoredDevices, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: devicesIgnoredURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return ignoredDevices, nil
+}
+
+// AddIgnoredDevice hides the given device WWID from device enumeration and, under Linux, adds it to
+// the multipath blacklist
+func (chapiClient *Client) AddIgnoredDevice(wwid string) (ignoredDevice *model.IgnoredDevice, err error) {
+	log.Tracef(">>>>> AddIgnoredDevice called, wwid=%v", wwid)
+	defer log.Trace("<<<<< AddIgnoredDevice")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &ignoredDevice, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "POST", Path: devicesIgnoredURI, Header: chapiClient.header, Payload: &model.IgnoredDevice{WWID: wwid}, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return ignoredDevice, nil
+}
+
+// RemoveIgnoredDevice removes the given device WWID from the ignore list (and multipath blacklist)
+func (chapiClient *Client) RemoveIgnoredDevice(wwid string) (err error) {
+	log.Tracef(">>>>> RemoveIgnoredDevice called, wwid=%v", wwid)
+	defer log.Trace("<<<<< RemoveIgnoredDevice")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: nil, Err: nil}
+	devicesIgnoredIDURIOut := fmt.Sprintf(devicesIgnoredIDURI, url.PathEscape(wwid))
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "DELETE", Path: devicesIgnoredIDURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetDeviceClaim returns the active ownership claim on the given device
+func (chapiClient *Client) GetDeviceClaim(serialNumber string) (claim *model.DeviceClaim, err error) {
+	log.Tracef(">>>>> GetDeviceClaim called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< GetDeviceClaim")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &claim, Err: nil}
+	devicesClaimURIOut := fmt.Sprintf(devicesClaimURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: devicesClaimURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// ClaimDevice claims the given device for the request's owner, or renews the owner's claim
+func (chapiClient *Client) ClaimDevice(serialNumber string, request model.ClaimRequest) (claim *model.DeviceClaim, err error) {
+	log.Tracef(">>>>> ClaimDevice called, serialNumber=%v, owner=%v", serialNumber, request.Owner)
+	defer log.Trace("<<<<< ClaimDevice")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &claim, Err: nil}
+	devicesClaimURIOut := fmt.Sprintf(devicesClaimURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: devicesClaimURIOut, Header: chapiClient.header, Payload: &request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// ReleaseDevice releases the request owner's claim on the given device
+func (chapiClient *Client) ReleaseDevice(serialNumber string, request model.ClaimRequest) (err error) {
+	log.Tracef(">>>>> ReleaseDevice called, serialNumber=%v, owner=%v", serialNumber, request.Owner)
+	defer log.Trace("<<<<< ReleaseDevice")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: nil, Err: nil}
+	devicesClaimURIOut := fmt.Sprintf(devicesClaimURI, serialNumber)
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "DELETE", Path: devicesClaimURIOut, Header: chapiClient.header, Payload: &request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
 		return err
 	}
 	return nil
@@ -239,12 +667,19 @@ func (chapiClient *Client) CreateFileSystem(serialNumber string, filesystem stri
 
 // GetMounts reports all mounts on this host for the specified Nimble volume
 func (chapiClient *Client) GetMounts(serialNumber string) (mounts []*model.Mount, err error) {
-	log.Tracef(">>>>> GetMounts called, serialNumber=%v", serialNumber)
-	defer log.Trace("<<<<< GetMounts")
+	return chapiClient.GetMountsWithPrefix(serialNumber, "")
+}
+
+// GetMountsWithPrefix reports all mounts on this host for the specified Nimble volume, optionally
+// only those at or beneath the given mount point prefix
+func (chapiClient *Client) GetMountsWithPrefix(serialNumber, mountPointPrefix string) (mounts []*model.Mount, err error) {
+	log.Tracef(">>>>> GetMountsWithPrefix called, serialNumber=%v, mountPointPrefix=%v", serialNumber, mountPointPrefix)
+	defer log.Trace("<<<<< GetMountsWithPrefix")
 
 	// Initialize CHAPI response object, submit request to specified endpoint, return status
 	chapiResp := Response{Data: &mounts, Err: nil}
 	mountsURIOut := chapiClient.appendQuerySerialNumber(mountsURI, serialNumber)
+	mountsURIOut = chapiClient.appendQueryMountPointPrefix(mountsURIOut, mountPointPrefix)
 	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: mountsURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
 		return nil, err
 	}
@@ -253,19 +688,40 @@ func (chapiClient *Client) GetMounts(serialNumber string) (mounts []*model.Mount
 
 // GetAllMountDetails enumerates the specified mount point ID
 func (chapiClient *Client) GetAllMountDetails(serialNumber, mountPointID string) (mounts []*model.Mount, err error) {
-	log.Tracef(">>>>> GetAllMountDetails called, serialNumber=%v, mountPointID=%v", serialNumber, mountPointID)
-	defer log.Trace("<<<<< GetAllMountDetails")
+	return chapiClient.GetAllMountDetailsWithPrefix(serialNumber, mountPointID, "")
+}
+
+// GetAllMountDetailsWithPrefix enumerates the specified mount point ID, optionally only those at
+// or beneath the given mount point prefix
+func (chapiClient *Client) GetAllMountDetailsWithPrefix(serialNumber, mountPointID, mountPointPrefix string) (mounts []*model.Mount, err error) {
+	log.Tracef(">>>>> GetAllMountDetailsWithPrefix called, serialNumber=%v, mountPointID=%v, mountPointPrefix=%v", serialNumber, mountPointID, mountPointPrefix)
+	defer log.Trace("<<<<< GetAllMountDetailsWithPrefix")
 
 	// Initialize CHAPI response object, submit request to specified endpoint, return status
 	chapiResp := Response{Data: &mounts, Err: nil}
 	mountsURIOut := chapiClient.appendQuerySerialNumber(mountsDetailURI, serialNumber)
 	mountsURIOut = chapiClient.appendQueryMountPointID(mountsURIOut, mountPointID)
+	mountsURIOut = chapiClient.appendQueryMountPointPrefix(mountsURIOut, mountPointPrefix)
 	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: mountsURIOut, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
 		return nil, err
 	}
 	return mounts, nil
 }
 
+// GetFreeDriveLetters returns the free drive letters (Windows only) that CreateMount assigns to
+// model.MountPointAutoDriveLetter mount points
+func (chapiClient *Client) GetFreeDriveLetters() (driveLetters []string, err error) {
+	log.Trace(">>>>> GetFreeDriveLetters called")
+	defer log.Trace("<<<<< GetFreeDriveLetters")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &driveLetters, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: mountsDriveLettersURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return driveLetters, nil
+}
+
 // CreateMount mounts the given device to the given mount point
 func (chapiClient *Client) CreateMount(serialNumber string, mountPoint string, fsOptions *model.FileSystemOptions) (mount *model.Mount, err error) {
 	log.Tracef(">>>>> CreateMount called, serialNumber=%v, mountPoint=%v, fsOptions=%v", serialNumber, mountPoint, fsOptions)
@@ -286,20 +742,208 @@ func (chapiClient *Client) CreateMount(serialNumber string, mountPoint string, f
 	return mount, nil
 }
 
-// DeleteMount unmounts the given mount point, serialNumber can be optional in the body
+// DeleteMount unmounts the given mount point, serialNumber can be optional in the body.  If the
+// mount point is busy, the returned cerrors.Busy error lists the blocking processes in its details.
 func (chapiClient *Client) DeleteMount(serialNumber, mountPointID string) (err error) {
-	log.Tracef(">>>>> DeleteMount called, serialNumber=%v, mountPointID=%v", serialNumber, mountPointID)
-	defer log.Trace("<<<<< DeleteMount")
+	return chapiClient.DeleteMountWithOptions(serialNumber, mountPointID, nil)
+}
+
+// DeleteMountWithOptions unmounts the given mount point with the given options (may be nil).
+// Setting options.Lazy detaches the mount point immediately even if it is still in use.
+func (chapiClient *Client) DeleteMountWithOptions(serialNumber, mountPointID string, options *model.DeleteMountOptions) (err error) {
+	lazy := options != nil && options.Lazy
+	log.Tracef(">>>>> DeleteMountWithOptions called, serialNumber=%v, mountPointID=%v, lazy=%v", serialNumber, mountPointID, lazy)
+	defer log.Trace("<<<<< DeleteMountWithOptions")
 
 	// Initialize CHAPI response object, submit request to specified endpoint, return status
 	chapiResp := Response{Data: nil, Err: nil}
 	mountsDeleteURIOut := fmt.Sprintf(mountsDeleteURI, mountPointID)
+	if lazy {
+		mountsDeleteURIOut = chapiClient.appendQuery(mountsDeleteURIOut, queryLazy, "true")
+	}
 	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "DELETE", Path: mountsDeleteURIOut, Header: chapiClient.header, Payload: serialNumber, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
 		return err
 	}
 	return nil
 }
 
+// MoveMount moves the given mount point to the given mount point path without unmounting the
+// volume.  The moved mount point is returned; a moved bind mount has a new mount point ID.
+func (chapiClient *Client) MoveMount(serialNumber, mountPointID, mountPoint string) (mount *model.Mount, err error) {
+	log.Tracef(">>>>> MoveMount called, serialNumber=%v, mountPointID=%v, mountPoint=%v", serialNumber, mountPointID, mountPoint)
+	defer log.Trace("<<<<< MoveMount")
+
+	// Initialize model.Mount submission object
+	mountSubmission := model.Mount{
+		SerialNumber: serialNumber,
+		MountPoint:   mountPoint,
+	}
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &mount, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: fmt.Sprintf(mountsMoveURI, mountPointID), Header: chapiClient.header, Payload: &mountSubmission, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return mount, nil
+}
+
+// GetOrphanedMounts reports the mount point directories, beneath the given mount roots, that are
+// no longer backed by a device
+func (chapiClient *Client) GetOrphanedMounts(roots []string) (orphans []*model.OrphanedMount, err error) {
+	log.Tracef(">>>>> GetOrphanedMounts called, roots=%v", roots)
+	defer log.Trace("<<<<< GetOrphanedMounts")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &orphans, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: chapiClient.appendQueryRoots(mountsOrphanURI, roots), Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+// DeleteOrphanedMounts removes the mount point directories, beneath the given mount roots, that
+// are no longer backed by a device.  The orphaned mount points are returned with their removal
+// status.
+func (chapiClient *Client) DeleteOrphanedMounts(roots []string) (orphans []*model.OrphanedMount, err error) {
+	log.Tracef(">>>>> DeleteOrphanedMounts called, roots=%v", roots)
+	defer log.Trace("<<<<< DeleteOrphanedMounts")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &orphans, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "DELETE", Path: chapiClient.appendQueryRoots(mountsOrphanURI, roots), Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+// FreezeMount quiesces the given mount point until it is thawed or the freeze times out
+func (chapiClient *Client) FreezeMount(request *model.QuiesceRequest) (quiesced *model.QuiescedMount, err error) {
+	log.Tracef(">>>>> FreezeMount called, request=%+v", request)
+	defer log.Trace("<<<<< FreezeMount")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &quiesced, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: mountsFreezeURI, Header: chapiClient.header, Payload: request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return quiesced, nil
+}
+
+// ThawMount resumes I/O on a mount point frozen by FreezeMount
+func (chapiClient *Client) ThawMount(request *model.QuiesceRequest) (err error) {
+	log.Tracef(">>>>> ThawMount called, request=%+v", request)
+	defer log.Trace("<<<<< ThawMount")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: nil, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: mountsThawURI, Header: chapiClient.header, Payload: request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// StageMount mounts the given volume once at its staging path.  The staged mount point is
+// returned with the target paths it is published to as its bind mounts.
+func (chapiClient *Client) StageMount(request *model.StagedMountRequest) (stagedMount *model.Mount, err error) {
+	log.Tracef(">>>>> StageMount called, request=%+v", request)
+	defer log.Trace("<<<<< StageMount")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &stagedMount, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: mountsStageURI, Header: chapiClient.header, Payload: request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return stagedMount, nil
+}
+
+// PublishMount bind mounts the given volume's staging path to the given target path
+func (chapiClient *Client) PublishMount(request *model.StagedMountRequest) (publishedMount *model.Mount, err error) {
+	log.Tracef(">>>>> PublishMount called, request=%+v", request)
+	defer log.Trace("<<<<< PublishMount")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &publishedMount, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: mountsPublishURI, Header: chapiClient.header, Payload: request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return publishedMount, nil
+}
+
+// UnpublishMount removes the given volume's bind mount at the given target path
+func (chapiClient *Client) UnpublishMount(request *model.StagedMountRequest) (err error) {
+	log.Tracef(">>>>> UnpublishMount called, request=%+v", request)
+	defer log.Trace("<<<<< UnpublishMount")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: nil, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: mountsUnpublishURI, Header: chapiClient.header, Payload: request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnstageMount unmounts the given volume from its staging path once it is no longer published
+func (chapiClient *Client) UnstageMount(request *model.StagedMountRequest) (err error) {
+	log.Tracef(">>>>> UnstageMount called, request=%+v", request)
+	defer log.Trace("<<<<< UnstageMount")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: nil, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: mountsUnstageURI, Header: chapiClient.header, Payload: request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return err
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// Node methods
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// DrainNode unmounts, flushes and detaches the given volumes and returns the outcome of each volume
+func (chapiClient *Client) DrainNode(request *model.DrainRequest) (results []*model.DrainResult, err error) {
+	log.Tracef(">>>>> DrainNode called, request=%+v", request)
+	defer log.Trace("<<<<< DrainNode")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &results, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "POST", Path: nodeDrainURI, Header: chapiClient.header, Payload: request, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// State methods
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// GetManagedState reports the devices and mount points created through CHAPI, and whether each is
+// still present on the host
+func (chapiClient *Client) GetManagedState() (managedState *model.ManagedState, err error) {
+	log.Trace(">>>>> GetManagedState called")
+	defer log.Trace("<<<<< GetManagedState")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &managedState, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: stateURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return managedState, nil
+}
+
+// ReconcileManagedState removes the records of the devices and mount points that are no longer
+// present on the host
+func (chapiClient *Client) ReconcileManagedState() (managedState *model.ManagedState, err error) {
+	log.Trace(">>>>> ReconcileManagedState called")
+	defer log.Trace("<<<<< ReconcileManagedState")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &managedState, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "PUT", Path: stateReconcileURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return managedState, nil
+}
+
 // CreateBindMount creates the given bind mount
 func (chapiClient *Client) CreateBindMount(sourceMount string, targetMount string, bindType string) (mount *model.Mount, err error) {
 	log.Tracef(">>>>> CreateBindMount called, sourceMount=%s, targetMount=%s bindType=%s", sourceMount, targetMount, bindType)
@@ -314,8 +958,18 @@ func (chapiClient *Client) CreateBindMount(sourceMount string, targetMount strin
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 
 // chapiClientDoJSON wraps the call to chapiClient.client.DoJSON().  If the request fails, and an
-// error was returned by the CHAPI server, that error is returned instead.
+// error was returned by the CHAPI server, that error is returned instead.  In strict mode, the
+// response data is validated before it is decoded (see STRICT MODE in chapiclient_schema.go).
 func (chapiClient *Client) chapiClientDoJSON(r *connectivity.Request) (int, error) {
+	if chapiResp, ok := r.Response.(*Response); ok && (chapiResp.Data != nil) && chapiClient.isStrictMode() {
+		return chapiClient.chapiClientDoJSONStrict(r, chapiResp)
+	}
+	return chapiClient.submitJSON(r)
+}
+
+// submitJSON submits the request to the CHAPI endpoint, returning the CHAPI server error, if any,
+// instead of the connectivity error
+func (chapiClient *Client) submitJSON(r *connectivity.Request) (int, error) {
 
 	// Start by calling submitting the request to the CHAPI endpoint
 	statusCode, err := chapiClient.client.DoJSON(r)
@@ -341,11 +995,29 @@ func (chapiClient *Client) appendQuerySerialNumber(uri string, serialNumber stri
 	return chapiClient.appendQuery(uri, querySerialNumber, serialNumber)
 }
 
+// appendQueryFields appends a (comma separated) fields query to the given URI
+func (chapiClient *Client) appendQueryFields(uri string, fields []string) string {
+	return chapiClient.appendQuery(uri, queryFields, url.QueryEscape(strings.Join(fields, ",")))
+}
+
 // appendQueryMountPointID appends a mount ID query to the given URI
 func (chapiClient *Client) appendQueryMountPointID(uri string, mountPointID string) string {
 	return chapiClient.appendQuery(uri, queryMountID, mountPointID)
 }
 
+// appendQueryMountPointPrefix appends a (URL encoded) mount point prefix query to the given URI
+func (chapiClient *Client) appendQueryMountPointPrefix(uri string, mountPointPrefix string) string {
+	return chapiClient.appendQuery(uri, queryMountPointPrefix, url.QueryEscape(mountPointPrefix))
+}
+
+// appendQueryRoots appends a (URL encoded) mount root query, for each given root, to the given URI
+func (chapiClient *Client) appendQueryRoots(uri string, roots []string) string {
+	for _, root := range roots {
+		uri = chapiClient.appendQuery(uri, queryRoot, url.QueryEscape(root))
+	}
+	return uri
+}
+
 // appendQuery appends the key key/value query to the given URI
 func (chapiClient *Client) appendQuery(uri string, key string, value string) string {
 	// Don't append query if value is empty
@@ -0,0 +1,138 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapiclient
+
+// chapiclient_batch.go queries the host, initiator, network and device endpoints of a CHAPI server
+// concurrently, under a single deadline, so that callers needing the whole node state (e.g. on
+// every mount) wait for the slowest query rather than for the sum of the queries.
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// DefaultNodeStateDeadline is the GetNodeState deadline if none is given
+	DefaultNodeStateDeadline = 30 * time.Second
+
+	errorMessageNodeStateTimeout = "%v query did not complete within %v"
+	errorMessageNodeStateFailed  = "unable to query %v of %v node state endpoints: %v"
+)
+
+// nodeStateClient is the subset of the Client methods used by GetNodeState; an interface so that
+// tests can replace the CHAPI server
+type nodeStateClient interface {
+	GetHostInfo() (*model.Host, error)
+	GetHostInitiators() ([]*model.Initiator, error)
+	GetHostNetworks() ([]*model.Network, error)
+	GetAllDeviceDetails(serialNumber string) ([]*model.Device, error)
+}
+
+// NodeState is the state of the host reported by the CHAPI server.  The endpoints that failed, or
+// did not complete before the deadline, are listed in Errors, keyed by endpoint name.
+type NodeState struct {
+	Host       *model.Host        `json:"host,omitempty"`
+	Initiators []*model.Initiator `json:"initiators,omitempty"`
+	Networks   []*model.Network   `json:"networks,omitempty"`
+	Devices    []*model.Device    `json:"devices,omitempty"`
+	Errors     map[string]string  `json:"errors,omitempty"`
+}
+
+// nodeStateQuery is a single node state endpoint query; set stores the query's result in the
+// NodeState
+type nodeStateQuery struct {
+	name  string
+	query func() (interface{}, error)
+	set   func(state *NodeState, result interface{})
+}
+
+// nodeStateResult is the outcome of a nodeStateQuery
+type nodeStateResult struct {
+	index  int
+	result interface{}
+	err    error
+}
+
+// GetNodeState queries the host, initiator, network and device endpoints concurrently and returns
+// the state they report.  Queries that have not completed when the deadline expires (or
+// DefaultNodeStateDeadline if no deadline is given) are abandoned.  The state of the queries that
+// completed is always returned; if any query failed or was abandoned, a cerrors.ChapiError is
+// returned as well (cerrors.Timeout if a query was abandoned).
+func GetNodeState(chapiClient *Client, deadline time.Duration) (*NodeState, error) {
+	return getNodeState(chapiClient, deadline)
+}
+
+// getNodeState collects the NodeState through the given client
+func getNodeState(client nodeStateClient, deadline time.Duration) (*NodeState, error) {
+	log.Tracef(">>>>> GetNodeState called, deadline=%v", deadline)
+	defer log.Trace("<<<<< GetNodeState")
+
+	if deadline <= 0 {
+		deadline = DefaultNodeStateDeadline
+	}
+
+	queries := []nodeStateQuery{
+		{"host", func() (interface{}, error) { return client.GetHostInfo() },
+			func(state *NodeState, result interface{}) { state.Host = result.(*model.Host) }},
+		{"initiators", func() (interface{}, error) { return client.GetHostInitiators() },
+			func(state *NodeState, result interface{}) { state.Initiators = result.([]*model.Initiator) }},
+		{"networks", func() (interface{}, error) { return client.GetHostNetworks() },
+			func(state *NodeState, result interface{}) { state.Networks = result.([]*model.Network) }},
+		{"devices", func() (interface{}, error) { return client.GetAllDeviceDetails("") },
+			func(state *NodeState, result interface{}) { state.Devices = result.([]*model.Device) }},
+	}
+
+	// The results channel is buffered so that abandoned queries can complete without blocking
+	results := make(chan nodeStateResult, len(queries))
+	for index, query := range queries {
+		go func(index int, query nodeStateQuery) {
+			result, err := query.query()
+			results <- nodeStateResult{index: index, result: result, err: err}
+		}(index, query)
+	}
+
+	state := &NodeState{}
+	completed := make([]bool, len(queries))
+	var failures []string
+	code := cerrors.Internal
+	record := func(name string, err error) {
+		log.Errorf("Unable to query %v, err=%v", name, err)
+		if state.Errors == nil {
+			state.Errors = make(map[string]string)
+		}
+		state.Errors[name] = err.Error()
+		failures = append(failures, name)
+	}
+
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+	for pending := len(queries); pending > 0; pending-- {
+		select {
+		case result := <-results:
+			completed[result.index] = true
+			if result.err != nil {
+				record(queries[result.index].name, result.err)
+				continue
+			}
+			queries[result.index].set(state, result.result)
+		case <-timer.C:
+			for index, query := range queries {
+				if !completed[index] {
+					record(query.name, cerrors.NewChapiErrorf(cerrors.Timeout, errorMessageNodeStateTimeout, query.name, deadline))
+				}
+			}
+			code = cerrors.Timeout
+			pending = 0
+		}
+	}
+
+	if len(failures) != 0 {
+		sort.Strings(failures)
+		return state, cerrors.NewChapiErrorf(code, errorMessageNodeStateFailed, len(failures), len(queries), failures).WithDetails(state.Errors)
+	}
+	return state, nil
+}
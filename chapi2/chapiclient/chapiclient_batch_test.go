@@ -0,0 +1,73 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapiclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+type testNodeStateClient struct {
+	delay       time.Duration // Delay of each query
+	devicesWait chan struct{} // Blocks the devices query, if set
+	networksErr error
+}
+
+func (c *testNodeStateClient) GetHostInfo() (*model.Host, error) {
+	time.Sleep(c.delay)
+	return &model.Host{Name: "host1"}, nil
+}
+
+func (c *testNodeStateClient) GetHostInitiators() ([]*model.Initiator, error) {
+	time.Sleep(c.delay)
+	return []*model.Initiator{{AccessProtocol: model.AccessProtocolIscsi}}, nil
+}
+
+func (c *testNodeStateClient) GetHostNetworks() ([]*model.Network, error) {
+	time.Sleep(c.delay)
+	return []*model.Network{{Name: "eth0"}}, c.networksErr
+}
+
+func (c *testNodeStateClient) GetAllDeviceDetails(serialNumber string) ([]*model.Device, error) {
+	time.Sleep(c.delay)
+	if c.devicesWait != nil {
+		<-c.devicesWait
+	}
+	return []*model.Device{{SerialNumber: "sn1"}}, nil
+}
+
+func TestGetNodeState(t *testing.T) {
+	// The queries are performed concurrently, so the state is returned after a single delay
+	start := time.Now()
+	state, err := getNodeState(&testNodeStateClient{delay: 200 * time.Millisecond}, 0)
+	if err != nil || state.Host.Name != "host1" || len(state.Initiators) != 1 || len(state.Networks) != 1 || len(state.Devices) != 1 || state.Errors != nil {
+		t.Fatalf("unexpected node state %+v, err=%v", state, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 600*time.Millisecond {
+		t.Errorf("queries not performed concurrently, took %v", elapsed)
+	}
+
+	// A failed query is reported without discarding the other queries
+	state, err = getNodeState(&testNodeStateClient{networksErr: errors.New("networks unavailable")}, time.Second)
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.Internal {
+		t.Errorf("expected internal error, got %v", err)
+	}
+	if state.Host == nil || len(state.Devices) != 1 || state.Networks != nil || state.Errors["networks"] != "networks unavailable" {
+		t.Errorf("unexpected node state %+v", state)
+	}
+
+	// A query that does not complete before the deadline is abandoned
+	client := &testNodeStateClient{devicesWait: make(chan struct{})}
+	defer close(client.devicesWait)
+	state, err = getNodeState(client, 100*time.Millisecond)
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.Timeout {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if state.Host == nil || state.Devices != nil || len(state.Errors) != 1 || state.Errors["devices"] == "" {
+		t.Errorf("unexpected node state %+v", state)
+	}
+}
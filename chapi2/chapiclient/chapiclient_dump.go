@@ -0,0 +1,177 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapiclient
+
+// chapiclient_dump.go collects the host, device, mount and iSCSI session state reported by a CHAPI
+// server into a single JSON document so that support tooling can capture a host's storage view
+// without issuing (and formatting) each request itself.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// DumpEndpoint identifies a set of CHAPI endpoints collected by Dump
+type DumpEndpoint uint32
+
+const (
+	DumpHost     DumpEndpoint = 1 << iota // Host name, domain and initiators
+	DumpNetworks                          // Host network interfaces
+	DumpDevices                           // Devices with all their details
+	DumpMounts                            // Mount points with all their details
+	DumpSessions                          // iSCSI targets of the devices, and configured targets without sessions
+
+	// DumpAll collects all the endpoints
+	DumpAll = DumpHost | DumpNetworks | DumpDevices | DumpMounts | DumpSessions
+)
+
+// dumpEndpointNames are the names of the DumpEndpoint values (see ParseDumpEndpoints)
+var dumpEndpointNames = []string{"host", "networks", "devices", "mounts", "sessions"}
+
+// dumpClient is the subset of the Client methods used by Dump; an interface so that tests can
+// replace the CHAPI server
+type dumpClient interface {
+	GetHostInfo() (*model.Host, error)
+	GetHostInitiators() ([]*model.Initiator, error)
+	GetHostNetworks() ([]*model.Network, error)
+	GetAllDeviceDetails(serialNumber string) ([]*model.Device, error)
+	GetAllMountDetails(serialNumber, mountPointID string) ([]*model.Mount, error)
+	GetUnconnectedTargets() ([]*model.UnconnectedTarget, error)
+}
+
+// DumpSession is an iSCSI target logged in by the host along with the devices it provides
+type DumpSession struct {
+	Target  *model.IscsiTarget `json:"target"`
+	Devices []string           `json:"devices,omitempty"` // Serial numbers of the target's devices
+}
+
+// DumpReport is the state of the host reported by the CHAPI server.  The endpoints that could not
+// be queried are listed in Errors, keyed by endpoint name, rather than failing the whole dump.
+type DumpReport struct {
+	Time               time.Time                  `json:"time"`
+	Host               *model.Host                `json:"host,omitempty"`
+	Initiators         []*model.Initiator         `json:"initiators,omitempty"`
+	Networks           []*model.Network           `json:"networks,omitempty"`
+	Devices            []*model.Device            `json:"devices,omitempty"`
+	Mounts             []*model.Mount             `json:"mounts,omitempty"`
+	Sessions           []*DumpSession             `json:"sessions,omitempty"`
+	UnconnectedTargets []*model.UnconnectedTarget `json:"unconnected_targets,omitempty"`
+	Errors             map[string]string          `json:"errors,omitempty"`
+}
+
+// ParseDumpEndpoints converts a comma separated list of endpoint names (e.g. "host,devices", or
+// "all") into a DumpEndpoint set
+func ParseDumpEndpoints(names string) (DumpEndpoint, error) {
+	var endpoints DumpEndpoint
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			endpoints |= DumpAll
+			continue
+		}
+		found := false
+		for i, endpointName := range dumpEndpointNames {
+			if name == endpointName {
+				endpoints |= 1 << uint(i)
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown dump endpoint %q, expected one of %v or all", name, strings.Join(dumpEndpointNames, ", "))
+		}
+	}
+	return endpoints, nil
+}
+
+// Dump queries the given set of CHAPI endpoints and returns the state they report
+func Dump(chapiClient *Client, endpoints DumpEndpoint) *DumpReport {
+	return dump(chapiClient, endpoints)
+}
+
+// DumpJSON queries the given set of CHAPI endpoints and writes the state they report, as indented
+// JSON, to the given writer
+func DumpJSON(w io.Writer, chapiClient *Client, endpoints DumpEndpoint) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Dump(chapiClient, endpoints))
+}
+
+// dump collects the DumpReport of the given set of endpoints
+func dump(client dumpClient, endpoints DumpEndpoint) *DumpReport {
+	log.Tracef(">>>>> Dump called, endpoints=%#x", endpoints)
+	defer log.Trace("<<<<< Dump")
+
+	report := &DumpReport{Time: time.Now().UTC()}
+	record := func(name string, err error) {
+		if err != nil {
+			log.Errorf("Unable to dump %v, err=%v", name, err)
+			if report.Errors == nil {
+				report.Errors = make(map[string]string)
+			}
+			report.Errors[name] = err.Error()
+		}
+	}
+
+	var err error
+	if endpoints&DumpHost != 0 {
+		report.Host, err = client.GetHostInfo()
+		record("host", err)
+		report.Initiators, err = client.GetHostInitiators()
+		record("initiators", err)
+	}
+	if endpoints&DumpNetworks != 0 {
+		report.Networks, err = client.GetHostNetworks()
+		record("networks", err)
+	}
+
+	// The sessions are derived from the devices' iSCSI targets, so the devices are enumerated for
+	// either endpoint but only reported if requested
+	if endpoints&(DumpDevices|DumpSessions) != 0 {
+		var devices []*model.Device
+		devices, err = client.GetAllDeviceDetails("")
+		record("devices", err)
+		if endpoints&DumpDevices != 0 {
+			report.Devices = devices
+		}
+		if endpoints&DumpSessions != 0 {
+			report.Sessions = getDumpSessions(devices)
+		}
+	}
+	if endpoints&DumpMounts != 0 {
+		report.Mounts, err = client.GetAllMountDetails("", "")
+		record("mounts", err)
+	}
+	if endpoints&DumpSessions != 0 {
+		report.UnconnectedTargets, err = client.GetUnconnectedTargets()
+		record("unconnected_targets", err)
+	}
+	return report
+}
+
+// getDumpSessions groups the given devices by the iSCSI target they are provided by
+func getDumpSessions(devices []*model.Device) []*DumpSession {
+	var sessions []*DumpSession
+	targetSessions := make(map[string]*DumpSession)
+	for _, device := range devices {
+		if device.IscsiTarget == nil {
+			continue
+		}
+		session := targetSessions[device.IscsiTarget.Name]
+		if session == nil {
+			session = &DumpSession{Target: device.IscsiTarget}
+			targetSessions[device.IscsiTarget.Name] = session
+			sessions = append(sessions, session)
+		}
+		session.Devices = append(session.Devices, device.SerialNumber)
+	}
+	return sessions
+}
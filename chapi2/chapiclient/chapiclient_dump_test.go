@@ -0,0 +1,77 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapiclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+type testDumpClient struct {
+	calls []string
+}
+
+func (c *testDumpClient) GetHostInfo() (*model.Host, error) {
+	c.calls = append(c.calls, "host")
+	return &model.Host{Name: "host1"}, nil
+}
+
+func (c *testDumpClient) GetHostInitiators() ([]*model.Initiator, error) {
+	c.calls = append(c.calls, "initiators")
+	return nil, errors.New("initiators unavailable")
+}
+
+func (c *testDumpClient) GetHostNetworks() ([]*model.Network, error) {
+	c.calls = append(c.calls, "networks")
+	return nil, nil
+}
+
+func (c *testDumpClient) GetAllDeviceDetails(serialNumber string) ([]*model.Device, error) {
+	c.calls = append(c.calls, "devices")
+	return []*model.Device{
+		{SerialNumber: "sn1", IscsiTarget: &model.IscsiTarget{Name: "iqn.target1"}},
+		{SerialNumber: "sn2"},
+		{SerialNumber: "sn3", IscsiTarget: &model.IscsiTarget{Name: "iqn.target1"}},
+	}, nil
+}
+
+func (c *testDumpClient) GetAllMountDetails(serialNumber, mountPointID string) ([]*model.Mount, error) {
+	c.calls = append(c.calls, "mounts")
+	return nil, nil
+}
+
+func (c *testDumpClient) GetUnconnectedTargets() ([]*model.UnconnectedTarget, error) {
+	c.calls = append(c.calls, "unconnected")
+	return nil, nil
+}
+
+func TestParseDumpEndpoints(t *testing.T) {
+	if endpoints, err := ParseDumpEndpoints(" Host, mounts,"); err != nil || endpoints != DumpHost|DumpMounts {
+		t.Errorf("expected host and mounts, got %#x, err=%v", endpoints, err)
+	}
+	if endpoints, err := ParseDumpEndpoints("all"); err != nil || endpoints != DumpAll {
+		t.Errorf("expected all, got %#x, err=%v", endpoints, err)
+	}
+	if _, err := ParseDumpEndpoints("devices,disks"); err == nil {
+		t.Error("expected unknown endpoint error")
+	}
+}
+
+func TestDump(t *testing.T) {
+	client := &testDumpClient{}
+	report := dump(client, DumpHost|DumpSessions)
+	if len(client.calls) != 4 {
+		t.Errorf("unexpected calls %v", client.calls)
+	}
+	if report.Host == nil || report.Host.Name != "host1" || report.Devices != nil || report.Mounts != nil {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors["initiators"] == "" {
+		t.Errorf("expected initiators error, got %v", report.Errors)
+	}
+	if len(report.Sessions) != 1 || report.Sessions[0].Target.Name != "iqn.target1" || len(report.Sessions[0].Devices) != 2 {
+		t.Errorf("unexpected sessions %+v", report.Sessions)
+	}
+}
@@ -10,6 +10,12 @@ import (
 	"github.com/hpe-storage/common-host-libs/chapi2"
 	"github.com/hpe-storage/common-host-libs/connectivity"
 	log "github.com/hpe-storage/common-host-libs/logger"
+	v1 "github.com/hpe-storage/common-host-libs/model"
+)
+
+const (
+	// Host Endpoints only served by CHAPI for Linux
+	chapInfoURI = apiVersion + "/chapinfo" // api/v1/chapinfo
 )
 
 // Client contains the Linux specific Client properties
@@ -68,3 +74,16 @@ func (chapiClient *Client) print() {
 	log.Traceln("Socket : ", chapiClient.socket)
 	log.Traceln("Header : ", chapiClient.header)
 }
+
+// GetChapInfo reports the iSCSI CHAP credentials configured on this host
+func (chapiClient *Client) GetChapInfo() (chapInfo *v1.ChapInfo, err error) {
+	log.Trace(">>>>> GetChapInfo called")
+	defer log.Trace("<<<<< GetChapInfo")
+
+	// Initialize CHAPI response object, submit request to specified endpoint, return status
+	chapiResp := Response{Data: &chapInfo, Err: nil}
+	if _, err = chapiClient.chapiClientDoJSON(&connectivity.Request{Action: "GET", Path: chapInfoURI, Header: chapiClient.header, Payload: nil, Response: &chapiResp, ResponseError: &chapiResp}); err != nil {
+		return nil, err
+	}
+	return chapInfo, nil
+}
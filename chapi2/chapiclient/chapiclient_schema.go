@@ -0,0 +1,192 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapiclient
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// STRICT MODE
+//
+//		A client built against a different CHAPI version than the server it talks to silently
+//		drops the properties it does not know about and zeroes the ones the server no longer
+//		returns.  In strict mode (see SetStrictMode or StrictModeEnv), each response's data is
+//		validated against the model object expected by the client method before it is decoded.
+//		Unknown properties, and missing properties that are not optional (i.e. without
+//		omitempty), are logged as warnings so that version skew is caught early in mixed-version
+//		fleets.  Validation never fails the request.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/connectivity"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// StrictModeEnv enables, when set to "true", the strict mode of every CHAPI client
+	StrictModeEnv = config.StrictModeEnv
+)
+
+var (
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// schemaField is a JSON property of a model object
+type schemaField struct {
+	fieldType reflect.Type // Go type of the property
+	optional  bool         // True if the property is omitted when empty
+}
+
+// SetStrictMode enables (or disables) the validation of the responses received by this client
+func (chapiClient *Client) SetStrictMode(strict bool) {
+	chapiClient.strict = strict
+}
+
+// isStrictMode returns true if the responses received by this client are validated
+func (chapiClient *Client) isStrictMode() bool {
+	return chapiClient.strict || config.Enabled(StrictModeEnv)
+}
+
+// chapiClientDoJSONStrict submits the request but validates the response data against the
+// expected model object before decoding it
+func (chapiClient *Client) chapiClientDoJSONStrict(r *connectivity.Request, chapiResp *Response) (int, error) {
+	// Receive the raw response data, then decode it into the expected object once validated
+	data := chapiResp.Data
+	var rawData json.RawMessage
+	chapiResp.Data = &rawData
+	statusCode, err := chapiClient.submitJSON(r)
+	chapiResp.Data = data
+	if err != nil || len(rawData) == 0 {
+		return statusCode, err
+	}
+
+	for _, warning := range validateSchema(rawData, reflect.TypeOf(data)) {
+		log.Warnf("CHAPI response schema mismatch, path=%v, %v", r.Path, warning)
+	}
+	if err = json.Unmarshal(rawData, data); err != nil {
+		log.Error("Unable to decode CHAPI response : ", err)
+		return 0, err
+	}
+	return statusCode, nil
+}
+
+// validateSchema returns the unknown and missing properties of the JSON data compared with the
+// given type, sorted by property name
+func validateSchema(data json.RawMessage, dataType reflect.Type) []string {
+	var warnings []string
+	validateValue("data", data, dataType, &warnings)
+	return warnings
+}
+
+// validateValue appends, to warnings, the unknown and missing properties of the JSON value at the
+// given path compared with the given type
+func validateValue(path string, value json.RawMessage, valueType reflect.Type, warnings *[]string) {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	if string(value) == "null" || reflect.PtrTo(valueType).Implements(unmarshalerType) {
+		return
+	}
+
+	switch valueType.Kind() {
+	case reflect.Struct:
+		var properties map[string]json.RawMessage
+		if json.Unmarshal(value, &properties) != nil {
+			return
+		}
+		fields := getSchemaFields(valueType)
+		for _, name := range sortedPropertyNames(properties) {
+			field, ok := fields[name]
+			if !ok {
+				*warnings = append(*warnings, fmt.Sprintf("unknown field %v.%v", path, name))
+				continue
+			}
+			validateValue(path+"."+name, properties[name], field.fieldType, warnings)
+		}
+		var names []string
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, ok := properties[name]; !ok && !fields[name].optional {
+				*warnings = append(*warnings, fmt.Sprintf("missing field %v.%v", path, name))
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			return // []byte is encoded as a base64 string
+		}
+		var items []json.RawMessage
+		if json.Unmarshal(value, &items) != nil {
+			return
+		}
+		for i, item := range items {
+			validateValue(fmt.Sprintf("%v[%v]", path, i), item, valueType.Elem(), warnings)
+		}
+
+	case reflect.Map:
+		var items map[string]json.RawMessage
+		if json.Unmarshal(value, &items) != nil {
+			return
+		}
+		for _, key := range sortedPropertyNames(items) {
+			validateValue(fmt.Sprintf("%v[%v]", path, key), items[key], valueType.Elem(), warnings)
+		}
+	}
+}
+
+// getSchemaFields returns the JSON properties of the given struct type, including the properties
+// of its embedded structs
+func getSchemaFields(structType reflect.Type) map[string]schemaField {
+	fields := make(map[string]schemaField)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embeddedType := field.Type
+			if embeddedType.Kind() == reflect.Ptr {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() == reflect.Struct {
+				for embeddedName, embeddedField := range getSchemaFields(embeddedType) {
+					fields[embeddedName] = embeddedField
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		optional := false
+		for _, option := range tag[1:] {
+			optional = optional || option == "omitempty"
+		}
+		fields[name] = schemaField{fieldType: field.Type, optional: optional}
+	}
+	return fields
+}
+
+// sortedPropertyNames returns the property names of the given JSON object in order
+func sortedPropertyNames(properties map[string]json.RawMessage) []string {
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
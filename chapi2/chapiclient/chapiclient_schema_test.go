@@ -0,0 +1,54 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package chapiclient
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSchemaBase struct {
+	ID string `json:"id"`
+}
+
+type testSchemaPath struct {
+	Name  string `json:"name"`
+	State string `json:"state,omitempty"`
+}
+
+type testSchemaDevice struct {
+	testSchemaBase
+	SerialNumber string                     `json:"serial_number"`
+	Size         uint64                     `json:"size,omitempty"`
+	Paths        []*testSchemaPath          `json:"paths,omitempty"`
+	Labels       map[string]*testSchemaPath `json:"labels,omitempty"`
+	Created      time.Time                  `json:"created,omitempty"`
+	Ignored      string                     `json:"-"`
+	internal     string
+}
+
+func TestValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{"matching", `[{"id":"1","serial_number":"abc","size":1,"paths":[{"name":"sda"}],"created":"2020-06-01T10:20:30Z"}]`, nil},
+		{"optional missing", `[{"id":"1","serial_number":"abc"}]`, nil},
+		{"null", `null`, nil},
+		{"required missing", `[{"serial_number":"abc"},{"id":"2"}]`, []string{"missing field data[0].id", "missing field data[1].serial_number"}},
+		{"unknown", `[{"id":"1","serial_number":"abc","wwid":"x","paths":[{"name":"sda","hctl":"0:0:0:1"}]}]`, []string{"unknown field data[0].paths[0].hctl", "unknown field data[0].wwid"}},
+		{"map", `[{"id":"1","serial_number":"abc","labels":{"a":{"name":"sda","lun":1}}}]`, []string{"unknown field data[0].labels[a].lun"}},
+		{"ignored", `[{"id":"1","serial_number":"abc","Ignored":"x","internal":"y"}]`, []string{"unknown field data[0].Ignored", "unknown field data[0].internal"}},
+	}
+
+	for _, tc := range testCases {
+		var devices []*testSchemaDevice
+		warnings := validateSchema(json.RawMessage(tc.data), reflect.TypeOf(&devices))
+		if !reflect.DeepEqual(warnings, tc.expected) {
+			t.Errorf("%v: expected %v, got %v", tc.name, tc.expected, warnings)
+		}
+	}
+}
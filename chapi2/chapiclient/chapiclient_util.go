@@ -6,3 +6,27 @@ package chapiclient
 // functionality, that extends the endpoint support, should be placed in this module.  For example,
 // CHAPI1 has an AttachAndMountDevice() Client method that makes use of multiple CHAPI endpoints.
 // This type of extended functionality, if needed, would be placed in this module.
+
+import (
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/discovery"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// DiscoverChapiServers returns the CHAPI servers advertised on the local network segment that
+// respond within the given timeout (discovery.DefaultBrowseTimeout if zero).  Each returned
+// service reports the server's address, port and API path (see discovery.Service.URL).
+func DiscoverChapiServers(timeout time.Duration) ([]*discovery.Service, error) {
+	log.Tracef(">>>>> DiscoverChapiServers called, timeout=%v", timeout)
+	defer log.Trace("<<<<< DiscoverChapiServers")
+
+	services, err := discovery.Browse(timeout)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range services {
+		log.Tracef("Discovered CHAPI server %v at %v%v", service.Instance, service.URL(), service.Path)
+	}
+	return services, nil
+}
@@ -0,0 +1,302 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// Package compat implements the CHAPI1 client interface on top of the CHAPI2 client so that CHAPI1
+// consumers (e.g. the docker plugin) can migrate to CHAPI2 incrementally.  All device and mount
+// logic is delegated to the CHAPI2 server; this package only translates between the two models.
+package compat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi"
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/chapiclient"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/multipath"
+	log "github.com/hpe-storage/common-host-libs/logger"
+	v1 "github.com/hpe-storage/common-host-libs/model"
+)
+
+const (
+	errorMessageNoDeviceAttached  = "no device attached for volume %s"
+	errorMessageNoDeviceFound     = "no matching device found for volume %s"
+	errorMessageNoDeviceProvided  = "no device provided for mount %s"
+	errorMessageNoMountCreated    = "no valid Mount Point created"
+	errorMessageNoVolumesProvided = "no volumes provided"
+)
+
+var (
+	// Ensure the compat Client supports every CHAPI1 client method; a compilation error will
+	// occur if any are missing.
+	_ chapi.ClientInterface = &Client{}
+)
+
+// Client implements chapi.ClientInterface using a CHAPI2 client
+type Client struct {
+	chapi2 *chapiclient.Client
+}
+
+// NewClient returns a CHAPI1 compatible client wrapping the given CHAPI2 client
+func NewClient(client *chapiclient.Client) *Client {
+	return &Client{chapi2: client}
+}
+
+// NewChapiClient returns a CHAPI1 compatible client that communicates with the CHAPI2 server
+func NewChapiClient() (*Client, error) {
+	client, err := chapiclient.NewChapiClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(client), nil
+}
+
+// NewChapiClientWithTimeout returns a CHAPI1 compatible client that communicates with the CHAPI2
+// server using a custom timeout value
+func NewChapiClientWithTimeout(timeout time.Duration) (*Client, error) {
+	client, err := chapiclient.NewChapiClientWithTimeout(timeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(client), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// Host Methods
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// GetHostID returns the host UUID
+func (c *Client) GetHostID() (string, error) {
+	host, err := c.chapi2.GetHostInfo()
+	if err != nil {
+		return "", err
+	}
+	return host.UUID, nil
+}
+
+// GetHostName returns the host name and domain
+func (c *Client) GetHostName() (*v1.Host, error) {
+	host, err := c.chapi2.GetHostInfo()
+	if err != nil {
+		return nil, err
+	}
+	return HostToV1(host), nil
+}
+
+// GetInitiators returns the host initiators
+func (c *Client) GetInitiators() ([]*v1.Initiator, error) {
+	initiators, err := c.chapi2.GetHostInitiators()
+	if err != nil {
+		return nil, err
+	}
+	return InitiatorsToV1(initiators), nil
+}
+
+// GetNetworks returns the host network interfaces
+func (c *Client) GetNetworks() ([]*v1.NetworkInterface, error) {
+	networks, err := c.chapi2.GetHostNetworks()
+	if err != nil {
+		return nil, err
+	}
+	return NetworksToV1(networks), nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// Device Methods
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// AttachDevice attaches the given volumes to this host and returns the created devices
+func (c *Client) AttachDevice(volumes []*v1.Volume) ([]*v1.Device, error) {
+	log.Tracef(">>>>> AttachDevice called, volumes=%d", len(volumes))
+	defer log.Trace("<<<<< AttachDevice")
+
+	if len(volumes) == 0 {
+		return nil, fmt.Errorf(errorMessageNoVolumesProvided)
+	}
+
+	var devices []*v1.Device
+	for _, volume := range volumes {
+		publishInfo, err := VolumeToPublishInfo(volume)
+		if err != nil {
+			return nil, err
+		}
+		device, err := c.chapi2.CreateDevice(*publishInfo)
+		if err != nil {
+			return nil, err
+		}
+		if device == nil {
+			return nil, fmt.Errorf(errorMessageNoDeviceAttached, volume.Name)
+		}
+		v1Device := DeviceToV1(device)
+		volume.UpdateDevice(v1Device)
+		devices = append(devices, v1Device)
+	}
+	return devices, nil
+}
+
+// AttachAndMountDevice attaches the given volume and mounts it on mountPath
+func (c *Client) AttachAndMountDevice(volume *v1.Volume, mountPath string) error {
+	log.Tracef(">>>>> AttachAndMountDevice called, volume=%v, mountPath=%v", volume.Name, mountPath)
+	defer log.Trace("<<<<< AttachAndMountDevice")
+
+	if _, err := c.AttachDevice([]*v1.Volume{volume}); err != nil {
+		return fmt.Errorf("unable to attach device %s", err.Error())
+	}
+	return c.MountFilesystem(volume, mountPath)
+}
+
+// GetDevices returns all the Nimble devices on this host
+func (c *Client) GetDevices() ([]*v1.Device, error) {
+	devices, err := c.chapi2.GetAllDeviceDetails("")
+	if err != nil {
+		return nil, err
+	}
+	return DevicesToV1(devices), nil
+}
+
+// GetDeviceFromVolume returns the device for the given volume.  The volume's serial number is
+// compared in its canonical form (see multipath.SerialNumbersMatch) so that a serial number in
+// another format (e.g. upper case or a multipath WWID) still finds the device.
+func (c *Client) GetDeviceFromVolume(volume *v1.Volume) (*v1.Device, error) {
+	serialNumber := volume.SerialNumber
+	if pitfalls := multipath.GetSerialNumberPitfalls(serialNumber); serialNumber != "" && len(pitfalls) != 0 {
+		log.Warnf("Volume %v serial number %q %v", volume.Name, serialNumber, strings.Join(pitfalls, ", "))
+		serialNumber = multipath.CanonicalSerialNumber(serialNumber)
+	}
+	devices, err := c.chapi2.GetAllDeviceDetails(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	var matched *model.Device
+	for _, device := range devices {
+		if device != nil && (serialNumber == "" || multipath.SerialNumbersMatch(device.SerialNumber, serialNumber)) {
+			matched = device
+			break
+		}
+	}
+	if matched == nil {
+		return nil, fmt.Errorf(errorMessageNoDeviceFound, volume.Name)
+	}
+	device := DeviceToV1(matched)
+	volume.UpdateDevice(device)
+	return device, nil
+}
+
+// OfflineDevice offlines the given device
+func (c *Client) OfflineDevice(device *v1.Device) error {
+	return c.chapi2.OfflineDevice(device.SerialNumber)
+}
+
+// DeleteDevice deletes the given device from this host
+func (c *Client) DeleteDevice(device *v1.Device) error {
+	return c.chapi2.DeleteDevice(device.SerialNumber)
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// File System and Mount Methods
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// CreateFilesystem creates a filesystem on the given device
+func (c *Client) CreateFilesystem(device *v1.Device, vol *v1.Volume, filesystem string) error {
+	return c.chapi2.CreateFileSystem(device.SerialNumber, filesystem)
+}
+
+// SetupFilesystemAndPermissions creates a filesystem on the given device, mounts it on the volume
+// mount point and applies the filesystem mode and owner from the volume status
+func (c *Client) SetupFilesystemAndPermissions(device *v1.Device, vol *v1.Volume, filesystem string) error {
+	log.Tracef(">>>>> SetupFilesystemAndPermissions called, volume=%v, mountPoint=%v", vol.Name, vol.MountPoint)
+	defer log.Trace("<<<<< SetupFilesystemAndPermissions")
+
+	if err := c.CreateFilesystem(device, vol, filesystem); err != nil {
+		return fmt.Errorf("unable to create filesystem %s", err.Error())
+	}
+	_, err := c.chapi2.CreateMount(device.SerialNumber, vol.MountPoint, FileSystemOptionsFromVolume(vol, filesystem))
+	return err
+}
+
+// Mount mounts the device in reqMount and returns the new mount in respMount
+func (c *Client) Mount(reqMount *v1.Mount, respMount *v1.Mount) error {
+	if reqMount.Device == nil {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageNoDeviceProvided, reqMount.Mountpoint)
+	}
+	var fsOpts *model.FileSystemOptions
+	if len(reqMount.Options) != 0 {
+		fsOpts = &model.FileSystemOptions{MountOpts: reqMount.Options}
+	}
+	mount, err := c.chapi2.CreateMount(reqMount.Device.SerialNumber, reqMount.Mountpoint, fsOpts)
+	if err != nil {
+		return err
+	}
+	if mount == nil {
+		return fmt.Errorf(errorMessageNoMountCreated)
+	}
+	if respMount != nil {
+		*respMount = *MountToV1(mount, reqMount.Device)
+	}
+	return nil
+}
+
+// MountFilesystem mounts the device for the given volume on mountPoint
+func (c *Client) MountFilesystem(volume *v1.Volume, mountPoint string) error {
+	device, err := c.GetDeviceFromVolume(volume)
+	if err != nil {
+		return err
+	}
+	_, err = c.chapi2.CreateMount(device.SerialNumber, mountPoint, nil)
+	return err
+}
+
+// GetMounts returns all the mount points for the volume with the given serial number
+func (c *Client) GetMounts(respMount *[]*v1.Mount, serialNumber string) error {
+	mounts, err := c.chapi2.GetAllMountDetails(serialNumber, "")
+	if err != nil {
+		return err
+	}
+	var v1Mounts []*v1.Mount
+	for _, mount := range mounts {
+		if mount == nil {
+			continue
+		}
+		v1Mounts = append(v1Mounts, MountToV1(mount, nil))
+	}
+	*respMount = v1Mounts
+	return nil
+}
+
+// UnmountDevice unmounts all the mount points of the given volume
+func (c *Client) UnmountDevice(volume *v1.Volume) error {
+	log.Tracef(">>>>> UnmountDevice called, volume=%v", volume.Name)
+	defer log.Trace("<<<<< UnmountDevice")
+
+	mounts, err := c.chapi2.GetMounts(volume.SerialNumber)
+	if err != nil {
+		if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.NotFound {
+			return nil
+		}
+		return err
+	}
+	for _, mount := range mounts {
+		if mount == nil {
+			continue
+		}
+		if err = c.chapi2.DeleteMount(volume.SerialNumber, mount.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unmount unmounts the mount point in reqMount
+func (c *Client) Unmount(reqMount *v1.Mount, respMount *v1.Mount) error {
+	if reqMount.Device == nil {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageNoDeviceProvided, reqMount.Mountpoint)
+	}
+	if err := c.chapi2.DeleteMount(reqMount.Device.SerialNumber, reqMount.ID); err != nil {
+		return err
+	}
+	if respMount != nil {
+		*respMount = *reqMount
+	}
+	return nil
+}
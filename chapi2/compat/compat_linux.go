@@ -0,0 +1,12 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package compat
+
+import (
+	v1 "github.com/hpe-storage/common-host-libs/model"
+)
+
+// GetChapInfo returns the host's iSCSI CHAP credentials
+func (c *Client) GetChapInfo() (*v1.ChapInfo, error) {
+	return c.chapi2.GetChapInfo()
+}
@@ -0,0 +1,25 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package compat
+
+import (
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	v1 "github.com/hpe-storage/common-host-libs/model"
+)
+
+func TestMountWithoutDevice(t *testing.T) {
+	// The request is rejected before the CHAPI2 server is contacted
+	client := NewClient(nil)
+	reqMount := &v1.Mount{Mountpoint: "/mnt/vol1"}
+
+	err := client.Mount(reqMount, &v1.Mount{})
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.InvalidArgument {
+		t.Errorf("Mount without a device returned %v, expected InvalidArgument", err)
+	}
+	err = client.Unmount(reqMount, &v1.Mount{})
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.InvalidArgument {
+		t.Errorf("Unmount without a device returned %v, expected InvalidArgument", err)
+	}
+}
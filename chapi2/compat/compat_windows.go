@@ -0,0 +1,17 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package compat
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	v1 "github.com/hpe-storage/common-host-libs/model"
+)
+
+const (
+	errorMessageChapInfoUnsupported = "host CHAP info is not supported by CHAPI2 for Windows, CHAP credentials are provided per volume"
+)
+
+// GetChapInfo is not supported by CHAPI2 for Windows
+func (c *Client) GetChapInfo() (*v1.ChapInfo, error) {
+	return nil, cerrors.NewChapiError(cerrors.Unimplemented, errorMessageChapInfoUnsupported)
+}
@@ -0,0 +1,272 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package compat
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	v1 "github.com/hpe-storage/common-host-libs/model"
+)
+
+const (
+	bytesPerMiB     = 1024 * 1024
+	devMapperPrefix = "/dev/mapper/"
+)
+
+// HostToV1 converts a CHAPI2 Host object into its CHAPI1 equivalent
+func HostToV1(host *model.Host) *v1.Host {
+	if host == nil {
+		return nil
+	}
+	return &v1.Host{
+		UUID:   host.UUID,
+		Name:   host.Name,
+		Domain: host.Domain,
+	}
+}
+
+// InitiatorsToV1 converts CHAPI2 Initiator objects into their CHAPI1 equivalent
+func InitiatorsToV1(initiators []*model.Initiator) []*v1.Initiator {
+	var v1Initiators []*v1.Initiator
+	for _, initiator := range initiators {
+		if initiator == nil {
+			continue
+		}
+		v1Initiators = append(v1Initiators, &v1.Initiator{
+			Type: initiator.AccessProtocol,
+			Init: initiator.Init,
+		})
+	}
+	return v1Initiators
+}
+
+// NetworksToV1 converts CHAPI2 Network objects into CHAPI1 NetworkInterface objects.  CHAPI2 does
+// not return the CIDR network so it is computed from the IPv4 address and subnet mask.
+func NetworksToV1(networks []*model.Network) []*v1.NetworkInterface {
+	var nics []*v1.NetworkInterface
+	for _, network := range networks {
+		if network == nil {
+			continue
+		}
+		nics = append(nics, &v1.NetworkInterface{
+			Name:        network.Name,
+			AddressV4:   network.AddressV4,
+			MaskV4:      network.MaskV4,
+			Mac:         network.Mac,
+			Mtu:         network.Mtu,
+			Up:          network.Up,
+			CidrNetwork: cidrNetwork(network.AddressV4, network.MaskV4),
+		})
+	}
+	return nics
+}
+
+// DeviceToV1 converts a CHAPI2 Device object into its CHAPI1 equivalent.  CHAPI2 reports the
+// device size in bytes while CHAPI1 reports it in MiB.
+func DeviceToV1(device *model.Device) *v1.Device {
+	if device == nil {
+		return nil
+	}
+	v1Device := &v1.Device{
+		SerialNumber:    device.SerialNumber,
+		Pathname:        device.Pathname,
+		AltFullPathName: device.AltFullPathName,
+		Size:            int64(device.Size / bytesPerMiB),
+		State:           device.State,
+	}
+	if strings.HasPrefix(device.AltFullPathName, devMapperPrefix) {
+		v1Device.MpathName = path.Base(device.AltFullPathName)
+	}
+	if device.IscsiTarget != nil {
+		v1Device.TargetScope = device.IscsiTarget.TargetScope
+		for _, portal := range device.IscsiTarget.TargetPortals {
+			if portal == nil {
+				continue
+			}
+			v1Device.IscsiTargets = append(v1Device.IscsiTargets, &v1.IscsiTarget{
+				Name:    device.IscsiTarget.Name,
+				Address: portal.Address,
+				Port:    portal.Port,
+				Tag:     portal.Tag,
+				Scope:   device.IscsiTarget.TargetScope,
+			})
+		}
+	}
+	return v1Device
+}
+
+// DevicesToV1 converts CHAPI2 Device objects into their CHAPI1 equivalent
+func DevicesToV1(devices []*model.Device) []*v1.Device {
+	var v1Devices []*v1.Device
+	for _, device := range devices {
+		if device == nil {
+			continue
+		}
+		v1Devices = append(v1Devices, DeviceToV1(device))
+	}
+	return v1Devices
+}
+
+// DeviceFromV1 converts a CHAPI1 Device object into its CHAPI2 equivalent.  It's the inverse of
+// DeviceToV1; CHAPI1 only details that CHAPI2 does not report (e.g. Major/Minor) are not carried.
+func DeviceFromV1(v1Device *v1.Device) *model.Device {
+	if v1Device == nil {
+		return nil
+	}
+	device := &model.Device{
+		SerialNumber:    v1Device.SerialNumber,
+		Pathname:        v1Device.Pathname,
+		AltFullPathName: v1Device.AltFullPathName,
+		Size:            uint64(v1Device.Size) * bytesPerMiB,
+		State:           v1Device.State,
+	}
+	for _, v1Target := range v1Device.IscsiTargets {
+		if v1Target == nil {
+			continue
+		}
+		if device.IscsiTarget == nil {
+			device.IscsiTarget = &model.IscsiTarget{
+				Name:        v1Target.Name,
+				TargetScope: v1Device.TargetScope,
+			}
+			if device.IscsiTarget.TargetScope == "" {
+				device.IscsiTarget.TargetScope = v1Target.Scope
+			}
+		}
+		device.IscsiTarget.TargetPortals = append(device.IscsiTarget.TargetPortals, &model.TargetPortal{
+			Address: v1Target.Address,
+			Port:    v1Target.Port,
+			Tag:     v1Target.Tag,
+		})
+	}
+	return device
+}
+
+// MountToV1 converts a CHAPI2 Mount object into its CHAPI1 equivalent.  CHAPI2 mount objects only
+// carry the volume serial number so the caller may provide the CHAPI1 device to embed; if nil, a
+// device object with just the serial number is embedded.
+func MountToV1(mount *model.Mount, device *v1.Device) *v1.Mount {
+	if mount == nil {
+		return nil
+	}
+	if device == nil {
+		device = &v1.Device{SerialNumber: mount.SerialNumber}
+	}
+	v1Mount := &v1.Mount{
+		ID:         mount.ID,
+		Mountpoint: mount.MountPoint,
+		Device:     device,
+	}
+	if mount.FsOpts != nil {
+		v1Mount.Options = mount.FsOpts.MountOpts
+	}
+	return v1Mount
+}
+
+// VolumeToPublishInfo converts a CHAPI1 Volume object into the CHAPI2 PublishInfo object needed
+// to attach the volume to this host
+func VolumeToPublishInfo(volume *v1.Volume) (*model.PublishInfo, error) {
+	if volume == nil {
+		return nil, fmt.Errorf("no volume provided")
+	}
+	if volume.SerialNumber == "" {
+		return nil, fmt.Errorf("volume %s has no serial number", volume.Name)
+	}
+
+	blockDev := &model.BlockDeviceAccessInfo{
+		AccessProtocol: strings.ToLower(volume.AccessProtocol),
+		TargetScope:    volume.TargetScope,
+		LunID:          volume.LunID,
+	}
+	if targetNames := volume.TargetNames(); len(targetNames) != 0 {
+		blockDev.TargetName = targetNames[0]
+	}
+
+	if blockDev.AccessProtocol == model.AccessProtocolIscsi {
+		iscsiAccessInfo := &model.IscsiAccessInfo{
+			ConnectType:  volume.ConnectionMode,
+			DiscoveryIP:  volume.DiscoveryIP,
+			DiscoveryIPs: volume.DiscoveryIPs,
+		}
+		if iscsiAccessInfo.DiscoveryIP == "" && len(volume.DiscoveryIPs) != 0 {
+			iscsiAccessInfo.DiscoveryIP = volume.DiscoveryIPs[0]
+		}
+		if volume.Chap != nil {
+			iscsiAccessInfo.ChapUser = volume.Chap.Name
+			iscsiAccessInfo.ChapPassword = volume.Chap.Password
+		}
+		blockDev.IscsiAccessInfo = iscsiAccessInfo
+	}
+
+	return &model.PublishInfo{
+		SerialNumber: volume.SerialNumber,
+		BlockDev:     blockDev,
+	}, nil
+}
+
+// PublishInfoToVolume converts a CHAPI2 PublishInfo object into the CHAPI1 Volume object needed to
+// attach the volume through CHAPI1.  It's the inverse of VolumeToPublishInfo.
+func PublishInfoToVolume(publishInfo *model.PublishInfo) (*v1.Volume, error) {
+	if publishInfo == nil {
+		return nil, fmt.Errorf("no publish info provided")
+	}
+	if publishInfo.BlockDev == nil {
+		return nil, fmt.Errorf("publish info for serial number %s has no block device", publishInfo.SerialNumber)
+	}
+
+	blockDev := publishInfo.BlockDev
+	volume := &v1.Volume{
+		SerialNumber:   publishInfo.SerialNumber,
+		AccessProtocol: blockDev.AccessProtocol,
+		TargetScope:    blockDev.TargetScope,
+		LunID:          blockDev.LunID,
+	}
+	if blockDev.TargetName != "" {
+		volume.Iqns = []string{blockDev.TargetName}
+	}
+
+	if iscsiAccessInfo := blockDev.IscsiAccessInfo; iscsiAccessInfo != nil {
+		volume.ConnectionMode = iscsiAccessInfo.ConnectType
+		volume.DiscoveryIP = iscsiAccessInfo.DiscoveryIP
+		volume.DiscoveryIPs = iscsiAccessInfo.DiscoveryIPs
+		if iscsiAccessInfo.ChapUser != "" || iscsiAccessInfo.ChapPassword != "" {
+			volume.Chap = &v1.ChapInfo{
+				Name:     iscsiAccessInfo.ChapUser,
+				Password: iscsiAccessInfo.ChapPassword,
+			}
+		}
+	}
+	return volume, nil
+}
+
+// FileSystemOptionsFromVolume returns the CHAPI2 file system options for the given CHAPI1 volume.
+// The filesystem mode and owner are taken from the volume status, as they are in CHAPI1.
+func FileSystemOptionsFromVolume(volume *v1.Volume, filesystem string) *model.FileSystemOptions {
+	fsOpts := &model.FileSystemOptions{FsType: filesystem}
+	if volume == nil {
+		return fsOpts
+	}
+	if mode, ok := volume.Status[v1.FsModeOpt].(string); ok {
+		fsOpts.FsMode = mode
+	}
+	if owner, ok := volume.Status[v1.FsOwnerOpt].(string); ok {
+		fsOpts.FsOwner = owner
+	}
+	return fsOpts
+}
+
+// cidrNetwork returns the CIDR network (e.g. "10.0.0.0/24") for the given IPv4 address and mask,
+// or an empty string if either cannot be parsed
+func cidrNetwork(address, mask string) string {
+	ip := net.ParseIP(address).To4()
+	ipMask := net.ParseIP(mask).To4()
+	if ip == nil || ipMask == nil {
+		return ""
+	}
+	ipNet := net.IPNet{IP: ip.Mask(net.IPMask(ipMask)), Mask: net.IPMask(ipMask)}
+	return ipNet.String()
+}
@@ -0,0 +1,181 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package compat
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	v1 "github.com/hpe-storage/common-host-libs/model"
+)
+
+// Legacy volume payload, as returned by the container provider to the docker plugin
+const legacyVolumePayload = `{
+	"name": "vol1",
+	"serial_number": "28174883c7719ac236c9ce900584f2795",
+	"access_protocol": "iscsi",
+	"iqn": "iqn.2007-11.com.nimblestorage:group-c32-array3-g5a2cdea9cf0b91f1",
+	"discovery_ip": "10.1.1.10",
+	"discovery_ips": ["10.1.1.10", "10.1.2.10"],
+	"chap_info": {"chap_user": "chapuser", "chap_password": "chappassword"},
+	"connection_mode": "manual",
+	"lun_id": "0",
+	"target_scope": "group",
+	"volume_group_id": ""
+}`
+
+// Expected CHAPI2 payload for legacyVolumePayload
+const expectedPublishInfoPayload = `{"serial_number":"28174883c7719ac236c9ce900584f2795","block_device":{"access_protocol":"iscsi","target_name":"iqn.2007-11.com.nimblestorage:group-c32-array3-g5a2cdea9cf0b91f1","target_scope":"group","lun_id":"0","iscsi_access_info":{"connect_type":"manual","discovery_ip":"10.1.1.10","discovery_ips":["10.1.1.10","10.1.2.10"],"chap_user":"chapuser","chap_password":"chappassword"}}}`
+
+// Fields without a CHAPI1 equivalent
+var untranslatedFields = map[string]bool{
+	"PublishInfo.VirtualDev":             true, // CHAPI1 does not support virtual devices
+	"PublishInfo.FsUUID":                 true, // CHAPI1 does not verify the mounted file system
+	"BlockDeviceAccessInfo.FcAccessInfo": true, // CHAPI1 rescans every FC target port
+	"IscsiAccessInfo.InitiatorInstance":  true, // Windows only, CHAPI1 always logs in from any initiator
+	"IscsiAccessInfo.Persistent":         true, // CHAPI1 sets node.startup through connection_mode
+	"IscsiAccessInfo.PingSize":           true, // CHAPI1 does not use the ping connect type
+	"IscsiAccessInfo.PingDontFragment":   true, // CHAPI1 does not use the ping connect type
+	"IscsiTarget.DiscoveryIP":            true, // Reported by CHAPI2 only
+	"Device.Timings":                     true, // Reported by CHAPI2 only
+	"TargetPortal.Private":               true, // Internal to CHAPI2
+	"Device.Private":                     true, // Internal to CHAPI2
+}
+
+func testPublishInfo() *model.PublishInfo {
+	return &model.PublishInfo{
+		SerialNumber: "28174883c7719ac236c9ce900584f2795",
+		BlockDev: &model.BlockDeviceAccessInfo{
+			AccessProtocol: model.AccessProtocolIscsi,
+			TargetName:     "iqn.2007-11.com.nimblestorage:group-c32-array3-g5a2cdea9cf0b91f1",
+			TargetScope:    "volume",
+			LunID:          "3",
+			IscsiAccessInfo: &model.IscsiAccessInfo{
+				ConnectType:  "auto",
+				DiscoveryIP:  "10.1.1.10",
+				DiscoveryIPs: []string{"10.1.2.10", "10.1.3.10"},
+				ChapUser:     "chapuser",
+				ChapPassword: "chappassword",
+			},
+		},
+	}
+}
+
+func testDevice() *model.Device {
+	return &model.Device{
+		SerialNumber:    "28174883c7719ac236c9ce900584f2795",
+		Pathname:        "dm-3",
+		AltFullPathName: "/dev/mapper/mpathg",
+		Size:            10 * 1024 * bytesPerMiB,
+		State:           v1.ActiveState.String(),
+		IscsiTarget: &model.IscsiTarget{
+			Name:        "iqn.2007-11.com.nimblestorage:group-c32-array3-g5a2cdea9cf0b91f1",
+			TargetScope: "group",
+			TargetPortals: []*model.TargetPortal{
+				{Address: "10.1.1.11", Port: "3260", Tag: "2460"},
+				{Address: "10.1.2.11", Port: "3260", Tag: "2460"},
+			},
+		},
+	}
+}
+
+// checkAllFieldsSet fails the test if any exported field of the given object, other than the
+// untranslatedFields, is not set.  It ensures the test objects are updated, and therefore the
+// round trip tests cover the new field, whenever a field is added to the models.
+func checkAllFieldsSet(t *testing.T, value reflect.Value) {
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := value.Type().Name() + "." + field.Name
+		if field.PkgPath != "" || untranslatedFields[name] {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if reflect.DeepEqual(fieldValue.Interface(), reflect.Zero(field.Type).Interface()) {
+			t.Errorf("%v is not set in the test object", name)
+			continue
+		}
+		if fieldValue.Kind() == reflect.Ptr && fieldValue.Elem().Kind() == reflect.Struct {
+			checkAllFieldsSet(t, fieldValue)
+		}
+		if fieldValue.Kind() == reflect.Slice && fieldValue.Type().Elem().Kind() == reflect.Ptr {
+			checkAllFieldsSet(t, fieldValue.Index(0))
+		}
+	}
+}
+
+func TestPublishInfoRoundTrip(t *testing.T) {
+	publishInfo := testPublishInfo()
+	checkAllFieldsSet(t, reflect.ValueOf(publishInfo))
+
+	volume, err := PublishInfoToVolume(publishInfo)
+	if err != nil {
+		t.Fatalf("PublishInfoToVolume failed, err=%v", err)
+	}
+	if volume.TargetScope != "volume" || volume.LunID != "3" {
+		t.Errorf("TargetScope or LunID dropped, volume=%+v", volume)
+	}
+	roundTrip, err := VolumeToPublishInfo(volume)
+	if err != nil {
+		t.Fatalf("VolumeToPublishInfo failed, err=%v", err)
+	}
+	if !reflect.DeepEqual(roundTrip, publishInfo) {
+		t.Errorf("publish info round trip mismatch, expected %+v, got %+v", publishInfo.BlockDev, roundTrip.BlockDev)
+	}
+
+	// FC volumes have no iSCSI access info
+	publishInfo = &model.PublishInfo{
+		SerialNumber: "4b2c1e5a0b1c4dd46c9ce900584f2795",
+		BlockDev:     &model.BlockDeviceAccessInfo{AccessProtocol: model.AccessProtocolFC, LunID: "7"},
+	}
+	volume, _ = PublishInfoToVolume(publishInfo)
+	if roundTrip, _ = VolumeToPublishInfo(volume); !reflect.DeepEqual(roundTrip, publishInfo) {
+		t.Errorf("FC publish info round trip mismatch, expected %+v, got %+v", publishInfo.BlockDev, roundTrip.BlockDev)
+	}
+
+	if _, err = PublishInfoToVolume(&model.PublishInfo{SerialNumber: "123"}); err == nil {
+		t.Error("expected publish info without a block device to be rejected")
+	}
+}
+
+func TestDeviceRoundTrip(t *testing.T) {
+	device := testDevice()
+	checkAllFieldsSet(t, reflect.ValueOf(device))
+
+	v1Device := DeviceToV1(device)
+	if v1Device.MpathName != "mpathg" || v1Device.Size != 10*1024 || v1Device.TargetScope != "group" {
+		t.Errorf("unexpected CHAPI1 device %+v", v1Device)
+	}
+	if roundTrip := DeviceFromV1(v1Device); !reflect.DeepEqual(roundTrip, device) {
+		t.Errorf("device round trip mismatch, expected %+v, got %+v", device, roundTrip)
+	}
+}
+
+func TestLegacyVolumePayload(t *testing.T) {
+	var volume v1.Volume
+	if err := json.Unmarshal([]byte(legacyVolumePayload), &volume); err != nil {
+		t.Fatalf("unable to unmarshal legacy volume payload, err=%v", err)
+	}
+
+	publishInfo, err := VolumeToPublishInfo(&volume)
+	if err != nil {
+		t.Fatalf("VolumeToPublishInfo failed, err=%v", err)
+	}
+	payload, _ := json.Marshal(publishInfo)
+	if string(payload) != expectedPublishInfoPayload {
+		t.Errorf("unexpected CHAPI2 payload\nexpected %v\ngot      %v", expectedPublishInfoPayload, string(payload))
+	}
+
+	// The device passed back to CHAPI must keep the volume's target scope
+	device := DeviceToV1(testDevice())
+	device.TargetScope = ""
+	device.IscsiTargets[0].Scope = ""
+	volume.UpdateDevice(device)
+	if device.TargetScope != "group" || device.IscsiTargets[0].Scope != "group" {
+		t.Errorf("target scope not applied to device %+v", device)
+	}
+}
@@ -0,0 +1,245 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// Package config reads the CHAPI settings configured through CHAPI_* environment variables.  Every
+// setting is named and described here and parsed by the same accessors, so that the CHAPI packages
+// don't each parse and log their own environment variables.
+package config
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// CHAPI CONFIGURATION
+//
+//		CHAPI is configured through environment variables, each documented in settings below.
+//		The accessors read the environment on every call; callers that only load a setting once
+//		(e.g. a JSON file named by the setting) guard the call with a sync.Once.  An invalid
+//		value is logged and replaced by the setting's default, so a typo never prevents CHAPI
+//		from starting.  LogSettings logs the configured settings, and warns about the unknown
+//		CHAPI_* variables (e.g. misspelled ones), when CHAPI starts.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/connectivity"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// Prefix is the prefix of every CHAPI environment variable
+	Prefix = "CHAPI_"
+
+	// Settings
+	AdvertiseEnv                  = "CHAPI_ADVERTISE"
+	APITokensConfigEnv            = "CHAPI_API_TOKENS_CONFIG"
+	DeviceProbeTimeoutEnv         = "CHAPI_DEVICE_PROBE_TIMEOUT"
+	DriveLetterPoolEnv            = "CHAPI_DRIVE_LETTER_POOL"
+	EmulexLIPRescanEnv            = "CHAPI_FC_EMULEX_LIP_RESCAN"
+	EventHooksConfigEnv           = "CHAPI_EVENT_HOOKS_CONFIG"
+	EventLogEnv                   = "CHAPI_EVENT_LOG"
+	FaultInjectionConfigEnv       = "CHAPI_FAULT_INJECTION_CONFIG"
+	LogoutEmptyTargetEnv          = "CHAPI_LOGOUT_EMPTY_TARGET"
+	MountMaxWaitEnv               = "CHAPI_MOUNT_MAX_WAIT"
+	PathMtuCheckEnv               = "CHAPI_PATH_MTU_CHECK"
+	PathRecoveryIntervalEnv       = "CHAPI_PATH_RECOVERY_INTERVAL"
+	PortalAddressFamilyEnv        = "CHAPI_ISCSI_ADDRESS_FAMILY"
+	PowerEventsEnv                = "CHAPI_POWER_EVENTS"
+	RegenerateIqnEnv              = "CHAPI_REGENERATE_DUPLICATE_IQN"
+	SkipProductsEnv               = "CHAPI_SKIP_DEVICE_PRODUCTS"
+	SkipSerialPrefixesEnv         = "CHAPI_SKIP_DEVICE_SERIAL_PREFIXES"
+	SlowRequestThresholdEnv       = "CHAPI_SLOW_REQUEST_THRESHOLD_MS"
+	StaleSessionAgeEnv            = "CHAPI_ISCSI_STALE_SESSION_AGE"
+	StaleSessionIntervalEnv       = "CHAPI_ISCSI_STALE_SESSION_INTERVAL"
+	StrictModeEnv                 = "CHAPI_CLIENT_STRICT"
+	SubnetAvoidCIDRsEnv           = "CHAPI_ISCSI_AVOID_CIDRS"
+	SubnetMaxConnectionsPerNICEnv = "CHAPI_ISCSI_MAX_CONNECTIONS_PER_NIC"
+	SubnetPreferredCIDRsEnv       = "CHAPI_ISCSI_PREFERRED_CIDRS"
+	SuppressWindowEnv             = "CHAPI_RESCAN_SUPPRESS_WINDOW"
+
+	// Request limit settings, read by the connectivity package itself so that it doesn't depend
+	// on chapi2
+	DisallowUnknownFieldsEnv = connectivity.DisallowUnknownFieldsEnv
+	MaxJSONDepthEnv          = connectivity.MaxJSONDepthEnv
+	MaxRequestBytesEnv       = connectivity.MaxRequestBytesEnv
+)
+
+// Setting describes a CHAPI environment variable
+type Setting struct {
+	Name        string
+	Description string
+}
+
+// settings describes every CHAPI environment variable
+var settings = []Setting{
+	{AdvertiseEnv, `"true" advertises the CHAPI for Windows TCP listener`},
+	{APITokensConfigEnv, "JSON API token file loaded when the router is created"},
+	{DeviceProbeTimeoutEnv, "direct read probe timeout, in seconds (probes disabled unless set)"},
+	{DisallowUnknownFieldsEnv, `"true" rejects requests with properties unknown to the destination type`},
+	{DriveLetterPoolEnv, `drive letters assigned to the Windows mounts (e.g. "E,F,X-Z")`},
+	{EmulexLIPRescanEnv, `"true" issues a LIP on an lpfc host whose LUN scan missed the LUN`},
+	{EventHooksConfigEnv, "JSON event hook file loaded on the first event"},
+	{EventLogEnv, `"false" stops writing the CHAPI errors to the Windows Application event log`},
+	{FaultInjectionConfigEnv, "JSON fault rule file loaded when the router is created (testing only)"},
+	{LogoutEmptyTargetEnv, `"true" logs out a Group Scoped Target once its last device is deleted`},
+	{MaxJSONDepthEnv, "deepest JSON nesting of the requests accepted by servers (0 for unlimited)"},
+	{MaxRequestBytesEnv, "largest request body accepted by servers (0 for unlimited)"},
+	{MountMaxWaitEnv, "time, in seconds, a Linux mount may take, journal replay included"},
+	{PathMtuCheckEnv, `"true" validates the path MTU of the planned iSCSI connections`},
+	{PathRecoveryIntervalEnv, "path recovery monitor interval, in seconds (disabled if not set)"},
+	{PortalAddressFamilyEnv, "address family policy of the target portals (ipv4, ipv6, prefer-ipv6 or dual)"},
+	{PowerEventsEnv, `"true" quiesces the managed volumes on shutdown and suspend`},
+	{RegenerateIqnEnv, `"true" replaces an iSCSI initiator name shared with a cloned host`},
+	{SkipProductsEnv, "SCSI inquiry products of the devices skipped by device enumeration"},
+	{SkipSerialPrefixesEnv, "serial number prefixes of the devices skipped by device enumeration"},
+	{SlowRequestThresholdEnv, "slow request threshold, in milliseconds"},
+	{StaleSessionAgeEnv, "time, in seconds, a target must have no LUN before its sessions are logged out"},
+	{StaleSessionIntervalEnv, "stale iSCSI session collector interval, in seconds (disabled if not set)"},
+	{StrictModeEnv, `"true" enables the strict mode of every CHAPI client`},
+	{SubnetAvoidCIDRsEnv, "CIDRs of the target portals to avoid"},
+	{SubnetMaxConnectionsPerNICEnv, "maximum IT nexuses per physical NIC (0 for unlimited)"},
+	{SubnetPreferredCIDRsEnv, "CIDRs of the preferred target portals"},
+	{SuppressWindowEnv, "time, in seconds, a detached volume's devices are suppressed (0 to disable)"},
+}
+
+// lookupEnv and environ read the environment; variables so that tests can replace them
+var (
+	lookupEnv = os.LookupEnv
+	environ   = os.Environ
+)
+
+// Settings returns the description of every CHAPI environment variable, sorted by name
+func Settings() []Setting {
+	sorted := make([]Setting, len(settings))
+	copy(sorted, settings)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// isSetting returns true if name is a known CHAPI environment variable
+func isSetting(name string) bool {
+	for _, setting := range settings {
+		if setting.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// getConfiguredSettings returns the sorted names of the known and of the unknown CHAPI_*
+// environment variables
+func getConfiguredSettings() (known []string, unknown []string) {
+	for _, variable := range environ() {
+		name := strings.SplitN(variable, "=", 2)[0]
+		if !strings.HasPrefix(name, Prefix) {
+			continue
+		}
+		if isSetting(name) {
+			known = append(known, name)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(known)
+	sort.Strings(unknown)
+	return known, unknown
+}
+
+// LogSettings logs the configured CHAPI settings and warns about the unknown CHAPI_* environment
+// variables
+func LogSettings() {
+	known, unknown := getConfiguredSettings()
+	for _, name := range known {
+		log.Infof("CHAPI setting %v=%q", name, String(name))
+	}
+	for _, name := range unknown {
+		log.Warnf("Ignoring unknown CHAPI setting %v", name)
+	}
+}
+
+// String returns the trimmed value of the setting, or "" if not set
+func String(name string) string {
+	value, _ := lookupEnv(name)
+	return strings.TrimSpace(value)
+}
+
+// Enabled returns true if the setting is set to "true"
+func Enabled(name string) bool {
+	return strings.EqualFold(String(name), "true")
+}
+
+// Disabled returns true if the setting is set to "false"
+func Disabled(name string) bool {
+	return strings.EqualFold(String(name), "false")
+}
+
+// Choice returns the lower case value of the setting if it is one of the choices, otherwise the
+// default value
+func Choice(name string, defaultValue string, choices ...string) string {
+	value := strings.ToLower(String(name))
+	if value == "" {
+		return defaultValue
+	}
+	for _, choice := range choices {
+		if value == choice {
+			return value
+		}
+	}
+	logInvalid(name, value, defaultValue)
+	return defaultValue
+}
+
+// Int returns the integer value of the setting, or the default value if not set, not an integer
+// or lower than minimum
+func Int(name string, defaultValue int, minimum int) int {
+	return int(Int64(name, int64(defaultValue), int64(minimum)))
+}
+
+// Int64 returns the integer value of the setting, or the default value if not set, not an integer
+// or lower than minimum
+func Int64(name string, defaultValue int64, minimum int64) int64 {
+	value := String(name)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < minimum {
+		logInvalid(name, value, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+// Seconds returns the duration of a setting expressed in seconds, or the default value if not set,
+// not an integer or lower than minimum seconds
+func Seconds(name string, defaultValue time.Duration, minimum int64) time.Duration {
+	return duration(name, time.Second, defaultValue, minimum)
+}
+
+// Milliseconds returns the duration of a setting expressed in milliseconds, or the default value
+// if not set, not an integer or lower than minimum milliseconds
+func Milliseconds(name string, defaultValue time.Duration, minimum int64) time.Duration {
+	return duration(name, time.Millisecond, defaultValue, minimum)
+}
+
+// duration returns the duration of a setting expressed in the given unit
+func duration(name string, unit time.Duration, defaultValue time.Duration, minimum int64) time.Duration {
+	value := String(name)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < minimum {
+		logInvalid(name, value, defaultValue)
+		return defaultValue
+	}
+	return time.Duration(number) * unit
+}
+
+// logInvalid logs a setting's invalid value and the default value used instead
+func logInvalid(name string, value string, defaultValue interface{}) {
+	log.Errorf("Invalid %v %q, using %v", name, value, defaultValue)
+}
@@ -0,0 +1,114 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEnvironment replaces the environment with the given variables
+func fakeEnvironment(variables map[string]string) (restore func()) {
+	savedLookupEnv, savedEnviron := lookupEnv, environ
+	lookupEnv = func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+	environ = func() []string {
+		var env []string
+		for name, value := range variables {
+			env = append(env, name+"="+value)
+		}
+		return env
+	}
+	return func() { lookupEnv, environ = savedLookupEnv, savedEnviron }
+}
+
+func TestSettings(t *testing.T) {
+	names := make(map[string]bool)
+	previous := ""
+	for _, setting := range Settings() {
+		if !strings.HasPrefix(setting.Name, Prefix) || setting.Description == "" {
+			t.Errorf("invalid setting %+v", setting)
+		}
+		if names[setting.Name] {
+			t.Errorf("duplicate setting %v", setting.Name)
+		}
+		if setting.Name < previous {
+			t.Errorf("setting %v not sorted after %v", setting.Name, previous)
+		}
+		names[setting.Name] = true
+		previous = setting.Name
+	}
+	if !isSetting(PowerEventsEnv) || isSetting("CHAPI_POWER_EVENT") {
+		t.Error("unexpected known settings")
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	defer fakeEnvironment(map[string]string{
+		AdvertiseEnv:            " TRUE ",
+		EventLogEnv:             "False",
+		PowerEventsEnv:          "yes",
+		PortalAddressFamilyEnv:  "Dual",
+		MaxJSONDepthEnv:         "-1",
+		MaxRequestBytesEnv:      "1024",
+		MountMaxWaitEnv:         "0",
+		StaleSessionAgeEnv:      "0",
+		DeviceProbeTimeoutEnv:   "ten",
+		SlowRequestThresholdEnv: "250",
+		DriveLetterPoolEnv:      " E,F ",
+	})()
+
+	if !Enabled(AdvertiseEnv) || Enabled(PowerEventsEnv) || Enabled(RegenerateIqnEnv) {
+		t.Error("unexpected enabled settings")
+	}
+	if !Disabled(EventLogEnv) || Disabled(AdvertiseEnv) {
+		t.Error("unexpected disabled settings")
+	}
+	if value := String(DriveLetterPoolEnv); value != "E,F" {
+		t.Errorf("unexpected string %q", value)
+	}
+	if value := Choice(PortalAddressFamilyEnv, "ipv4", "ipv4", "dual"); value != "dual" {
+		t.Errorf("unexpected choice %v", value)
+	}
+	if value := Choice(PortalAddressFamilyEnv, "ipv4", "ipv4", "ipv6"); value != "ipv4" {
+		t.Errorf("invalid choice not defaulted, %v", value)
+	}
+	if value := Int(MaxJSONDepthEnv, 64, 0); value != 64 {
+		t.Errorf("value below the minimum not defaulted, %v", value)
+	}
+	if value := Int64(MaxRequestBytesEnv, 0, 0); value != 1024 {
+		t.Errorf("unexpected int64 %v", value)
+	}
+	if value := Int(SubnetMaxConnectionsPerNICEnv, 2, 0); value != 2 {
+		t.Errorf("unset setting not defaulted, %v", value)
+	}
+	if value := Seconds(MountMaxWaitEnv, time.Minute, 1); value != time.Minute {
+		t.Errorf("value below the minimum not defaulted, %v", value)
+	}
+	if value := Seconds(StaleSessionAgeEnv, time.Minute, 0); value != 0 {
+		t.Errorf("unexpected seconds %v", value)
+	}
+	if value := Seconds(DeviceProbeTimeoutEnv, time.Second, 0); value != time.Second {
+		t.Errorf("invalid seconds not defaulted, %v", value)
+	}
+	if value := Milliseconds(SlowRequestThresholdEnv, time.Second, 1); value != 250*time.Millisecond {
+		t.Errorf("unexpected milliseconds %v", value)
+	}
+}
+
+func TestLogSettings(t *testing.T) {
+	defer fakeEnvironment(map[string]string{
+		AdvertiseEnv:       "true",
+		"CHAPI_ADVERTISED": "true", // Unknown setting
+		"HOME":             "/root",
+	})()
+	known, unknown := getConfiguredSettings()
+	if !reflect.DeepEqual(known, []string{AdvertiseEnv}) || !reflect.DeepEqual(unknown, []string{"CHAPI_ADVERTISED"}) {
+		t.Errorf("unexpected settings, known=%v, unknown=%v", known, unknown)
+	}
+	LogSettings()
+}
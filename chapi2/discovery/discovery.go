@@ -0,0 +1,141 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package discovery
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// SERVICE DISCOVERY
+//
+//		Management tooling otherwise has to know which port (and API path) each CHAPI server is
+//		listening on.  A CHAPI server listening on TCP can optionally advertise itself as a
+//		DNS-SD service (ServiceType) and clients on the same network segment can browse for the
+//		advertised CHAPI servers.  Each advertisement carries the port, along with TXT records for
+//		the API version, API path and platform.
+//
+//		The default Advertiser and Browser use multicast DNS (see mdns.go).  Other implementations
+//		(e.g. one registering with an Avahi or Bonjour daemon already running on the host) can be
+//		plugged in through SetAdvertiser and SetBrowser.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ServiceType is the DNS-SD service type advertised by CHAPI servers
+	ServiceType = "_chapi._tcp"
+
+	// Domain is the DNS-SD domain CHAPI servers are advertised in
+	Domain = "local."
+
+	// DefaultBrowseTimeout is how long Browse waits for CHAPI servers to respond by default
+	DefaultBrowseTimeout = 2 * time.Second
+
+	// TXT record keys
+	txtAPIVersion = "api_version"
+	txtPath       = "path"
+	txtPlatform   = "platform"
+)
+
+// Service is an advertised CHAPI server
+type Service struct {
+	Instance   string   `json:"instance"`            // Service instance name (e.g. the host name)
+	HostName   string   `json:"host_name,omitempty"` // e.g. "host1.local."
+	Port       int      `json:"port"`                // TCP port the CHAPI server is listening on
+	APIVersion string   `json:"api_version"`         // e.g. "v1"
+	Path       string   `json:"path"`                // API base path (e.g. "/api/v1")
+	Platform   string   `json:"platform"`            // e.g. "windows"
+	IPs        []net.IP `json:"ips,omitempty"`       // Addresses the CHAPI server is reachable on
+}
+
+// Advertiser advertises a CHAPI server
+type Advertiser interface {
+	Advertise(service *Service) error // Starts (or updates) the advertisement
+	Stop() error                      // Withdraws the advertisement
+}
+
+// Browser finds the advertised CHAPI servers
+type Browser interface {
+	Browse(timeout time.Duration) ([]*Service, error)
+}
+
+var (
+	pluginLock sync.Mutex
+	advertiser Advertiser = NewMdnsAdvertiser()
+	browser    Browser    = NewMdnsBrowser()
+)
+
+// SetAdvertiser replaces the Advertiser returned by GetAdvertiser
+func SetAdvertiser(newAdvertiser Advertiser) {
+	pluginLock.Lock()
+	defer pluginLock.Unlock()
+	advertiser = newAdvertiser
+}
+
+// GetAdvertiser returns the Advertiser used to advertise the CHAPI server
+func GetAdvertiser() Advertiser {
+	pluginLock.Lock()
+	defer pluginLock.Unlock()
+	return advertiser
+}
+
+// SetBrowser replaces the Browser used by Browse
+func SetBrowser(newBrowser Browser) {
+	pluginLock.Lock()
+	defer pluginLock.Unlock()
+	browser = newBrowser
+}
+
+// Browse returns the CHAPI servers that respond within the given timeout (DefaultBrowseTimeout if
+// zero)
+func Browse(timeout time.Duration) ([]*Service, error) {
+	if timeout <= 0 {
+		timeout = DefaultBrowseTimeout
+	}
+	pluginLock.Lock()
+	currentBrowser := browser
+	pluginLock.Unlock()
+	return currentBrowser.Browse(timeout)
+}
+
+// URL returns the base URL of the advertised CHAPI server (e.g. "http://10.1.2.3:49152"), using the
+// first advertised address, or the host name if no address was advertised
+func (service *Service) URL() string {
+	host := service.HostName
+	if len(service.IPs) != 0 {
+		host = service.IPs[0].String()
+	}
+	return fmt.Sprintf("http://%v", net.JoinHostPort(host, strconv.Itoa(service.Port)))
+}
+
+// txtRecords returns the service's TXT record strings
+func (service *Service) txtRecords() []string {
+	return []string{
+		txtAPIVersion + "=" + service.APIVersion,
+		txtPath + "=" + service.Path,
+		txtPlatform + "=" + service.Platform,
+	}
+}
+
+// setTxtRecord sets the service property named by the TXT record string (e.g. "platform=linux")
+func (service *Service) setTxtRecord(record string) {
+	for i := 0; i < len(record); i++ {
+		if record[i] != '=' {
+			continue
+		}
+		switch value := record[i+1:]; record[:i] {
+		case txtAPIVersion:
+			service.APIVersion = value
+		case txtPath:
+			service.Path = value
+		case txtPlatform:
+			service.Platform = value
+		}
+		return
+	}
+}
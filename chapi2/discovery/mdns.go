@@ -0,0 +1,395 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package discovery
+
+import (
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	mdnsPort      = 5353
+	mdnsTTL       = 120  // Record TTL, in seconds
+	mdnsMaxPacket = 9000 // Largest mDNS message (RFC 6762 section 17)
+
+	// mdnsClassCacheFlush is the cache flush bit of the mDNS record class (RFC 6762 section 10.2)
+	mdnsClassCacheFlush = 0x8000
+	// mdnsMaxTxtLength is the longest character string of a TXT record
+	mdnsMaxTxtLength = 255
+)
+
+var (
+	// mdnsGroup is the IPv4 mDNS multicast group
+	mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: mdnsPort}
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// mDNS Advertiser
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// MdnsAdvertiser advertises a CHAPI server using multicast DNS
+type MdnsAdvertiser struct {
+	lock    sync.Mutex
+	conn    *net.UDPConn
+	service *Service
+	records []dnsmessage.Resource // Records advertised for the service
+}
+
+// NewMdnsAdvertiser returns an mDNS Advertiser
+func NewMdnsAdvertiser() *MdnsAdvertiser {
+	return &MdnsAdvertiser{}
+}
+
+// Advertise starts answering mDNS queries for the given service and announces it.  If the service
+// is already advertised, the advertisement is updated.  The service's host name and addresses
+// default to this host's name and non-loopback addresses.
+func (advertiser *MdnsAdvertiser) Advertise(service *Service) error {
+	log.Tracef(">>>>> Advertise, service=%+v", service)
+	defer log.Trace("<<<<< Advertise")
+
+	advertised := *service
+	if advertised.Instance == "" || advertised.HostName == "" {
+		hostName, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		hostName = strings.Split(hostName, ".")[0]
+		if advertised.Instance == "" {
+			advertised.Instance = hostName
+		}
+		if advertised.HostName == "" {
+			advertised.HostName = hostName + "." + Domain
+		}
+	}
+	if len(advertised.IPs) == 0 {
+		advertised.IPs = getLocalIPs()
+	}
+
+	advertiser.lock.Lock()
+	defer advertiser.lock.Unlock()
+
+	if advertiser.conn == nil {
+		conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+		if err != nil {
+			log.Errorf("Unable to listen for mDNS queries, err=%v", err)
+			return err
+		}
+		advertiser.conn = conn
+		go advertiser.serve(conn)
+	}
+	records, err := serviceRecords(&advertised, mdnsTTL)
+	if err != nil {
+		log.Errorf("Unable to advertise %v, err=%v", instanceName(advertised.Instance), err)
+		return err
+	}
+	advertiser.service = &advertised
+	advertiser.records = records
+
+	log.Infof("Advertising %v on port %v", instanceName(advertised.Instance), advertised.Port)
+	return advertiser.send(advertiser.records, mdnsGroup)
+}
+
+// Stop withdraws the advertisement, sending goodbye (zero TTL) records
+func (advertiser *MdnsAdvertiser) Stop() error {
+	log.Trace(">>>>> Stop")
+	defer log.Trace("<<<<< Stop")
+
+	advertiser.lock.Lock()
+	defer advertiser.lock.Unlock()
+
+	if advertiser.conn == nil {
+		return nil
+	}
+	if records, err := serviceRecords(advertiser.service, 0); err != nil {
+		log.Errorf("Unable to build mDNS goodbye, err=%v", err)
+	} else if err = advertiser.send(records, mdnsGroup); err != nil {
+		log.Errorf("Unable to send mDNS goodbye, err=%v", err)
+	}
+	err := advertiser.conn.Close()
+	advertiser.conn = nil
+	advertiser.service = nil
+	advertiser.records = nil
+	return err
+}
+
+// serve answers the mDNS queries received on the connection until it is closed
+func (advertiser *MdnsAdvertiser) serve(conn *net.UDPConn) {
+	buf := make([]byte, mdnsMaxPacket)
+	for {
+		n, source, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			log.Tracef("Exiting mDNS advertiser, %v", err)
+			return
+		}
+		query, err := parseDNSMessage(buf[:n])
+		if err != nil || query.Header.Response {
+			continue
+		}
+		advertiser.answer(query, source)
+	}
+}
+
+// answer responds to the query if it asks for any of the advertised records
+func (advertiser *MdnsAdvertiser) answer(query *dnsmessage.Message, source *net.UDPAddr) {
+	advertiser.lock.Lock()
+	defer advertiser.lock.Unlock()
+
+	if advertiser.conn == nil || !matchesRecords(query.Questions, advertiser.records) {
+		return
+	}
+
+	// Queries sent from a port other than 5353 are legacy unicast queries (e.g. from Browse),
+	// which are answered directly and must echo the query ID and questions (RFC 6762 section 6.7)
+	if source.Port != mdnsPort {
+		response := &dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.Header.ID, Response: true, Authoritative: true},
+			Questions: query.Questions,
+			Answers:   advertiser.records,
+		}
+		if err := advertiser.write(response, source); err != nil {
+			log.Errorf("Unable to answer mDNS query from %v, err=%v", source, err)
+		}
+		return
+	}
+	if err := advertiser.send(advertiser.records, mdnsGroup); err != nil {
+		log.Errorf("Unable to answer mDNS query, err=%v", err)
+	}
+}
+
+// send sends an mDNS response with the given records
+func (advertiser *MdnsAdvertiser) send(records []dnsmessage.Resource, address *net.UDPAddr) error {
+	response := &dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}, Answers: records}
+	return advertiser.write(response, address)
+}
+
+// write encodes and sends the DNS message to the address
+func (advertiser *MdnsAdvertiser) write(message *dnsmessage.Message, address *net.UDPAddr) error {
+	buf, err := message.Pack()
+	if err != nil {
+		return err
+	}
+	_, err = advertiser.conn.WriteToUDP(buf, address)
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// mDNS Browser
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// MdnsBrowser finds the CHAPI servers advertised using multicast DNS
+type MdnsBrowser struct {
+}
+
+// NewMdnsBrowser returns an mDNS Browser
+func NewMdnsBrowser() *MdnsBrowser {
+	return &MdnsBrowser{}
+}
+
+// Browse sends a legacy unicast query for the CHAPI service type and returns the services that
+// respond within the timeout
+func (browser *MdnsBrowser) Browse(timeout time.Duration) ([]*Service, error) {
+	log.Tracef(">>>>> Browse, timeout=%v", timeout)
+	defer log.Trace("<<<<< Browse")
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	query := &dnsmessage.Message{Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(serviceName()), Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}}}
+	buf, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.WriteToUDP(buf, mdnsGroup); err != nil {
+		log.Errorf("Unable to send mDNS query, err=%v", err)
+		return nil, err
+	}
+
+	// Collect the responses until the timeout expires
+	var records []dnsmessage.Resource
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	buf = make([]byte, mdnsMaxPacket)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		response, err := parseDNSMessage(buf[:n])
+		if err != nil || !response.Header.Response {
+			continue
+		}
+		records = append(records, response.Answers...)
+	}
+	return servicesFromRecords(records), nil
+}
+
+// servicesFromRecords returns the services described by the given PTR, SRV, TXT and address
+// records
+func servicesFromRecords(records []dnsmessage.Resource) []*Service {
+	var services []*Service
+	instances := make(map[string]*Service)
+	for _, record := range records {
+		if ptr, ok := record.Body.(*dnsmessage.PTRResource); ok && strings.EqualFold(record.Header.Name.String(), serviceName()) && record.Header.TTL != 0 {
+			target := ptr.PTR.String()
+			key := strings.ToLower(target)
+			if instances[key] == nil {
+				instances[key] = &Service{Instance: strings.TrimSuffix(target, "."+serviceName())}
+				services = append(services, instances[key])
+			}
+		}
+	}
+	for _, record := range records {
+		service := instances[strings.ToLower(record.Header.Name.String())]
+		if service == nil {
+			continue
+		}
+		switch body := record.Body.(type) {
+		case *dnsmessage.SRVResource:
+			service.HostName = body.Target.String()
+			service.Port = int(body.Port)
+		case *dnsmessage.TXTResource:
+			for _, txt := range body.TXT {
+				service.setTxtRecord(txt)
+			}
+		}
+	}
+	for _, service := range services {
+		for _, record := range records {
+			if !strings.EqualFold(record.Header.Name.String(), service.HostName) {
+				continue
+			}
+			var ip net.IP
+			switch body := record.Body.(type) {
+			case *dnsmessage.AResource:
+				ip = net.IP(body.A[:])
+			case *dnsmessage.AAAAResource:
+				ip = net.IP(body.AAAA[:])
+			}
+			if ip != nil && !containsIP(service.IPs, ip) {
+				service.IPs = append(service.IPs, ip)
+			}
+		}
+	}
+	return services
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// DNS Messages
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// serviceName returns the DNS-SD service name (e.g. "_chapi._tcp.local.")
+func serviceName() string {
+	return ServiceType + "." + Domain
+}
+
+// instanceName returns the DNS-SD name of the given service instance
+func instanceName(instance string) string {
+	return instance + "." + serviceName()
+}
+
+// serviceRecords returns the PTR, SRV, TXT and address records advertising the service
+func serviceRecords(service *Service, ttl uint32) ([]dnsmessage.Resource, error) {
+	serviceType, err := dnsmessage.NewName(serviceName())
+	if err != nil {
+		return nil, err
+	}
+	instance, err := dnsmessage.NewName(instanceName(service.Instance))
+	if err != nil {
+		return nil, err
+	}
+	hostName, err := dnsmessage.NewName(service.HostName)
+	if err != nil {
+		return nil, err
+	}
+	var txt []string
+	for _, record := range service.txtRecords() {
+		if len(record) > mdnsMaxTxtLength {
+			record = record[:mdnsMaxTxtLength]
+		}
+		txt = append(txt, record)
+	}
+
+	// The unique records set the cache flush bit; the shared PTR record does not
+	header := func(name dnsmessage.Name, rtype dnsmessage.Type, class dnsmessage.Class) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: rtype, Class: class, TTL: ttl}
+	}
+	uniqueClass := dnsmessage.ClassINET | mdnsClassCacheFlush
+	records := []dnsmessage.Resource{
+		{Header: header(serviceType, dnsmessage.TypePTR, dnsmessage.ClassINET), Body: &dnsmessage.PTRResource{PTR: instance}},
+		{Header: header(instance, dnsmessage.TypeSRV, uniqueClass), Body: &dnsmessage.SRVResource{Port: uint16(service.Port), Target: hostName}},
+		{Header: header(instance, dnsmessage.TypeTXT, uniqueClass), Body: &dnsmessage.TXTResource{TXT: txt}},
+	}
+	for _, ip := range service.IPs {
+		var record dnsmessage.Resource
+		if ip4 := ip.To4(); ip4 != nil {
+			body := &dnsmessage.AResource{}
+			copy(body.A[:], ip4)
+			record = dnsmessage.Resource{Header: header(hostName, dnsmessage.TypeA, uniqueClass), Body: body}
+		} else {
+			body := &dnsmessage.AAAAResource{}
+			copy(body.AAAA[:], ip.To16())
+			record = dnsmessage.Resource{Header: header(hostName, dnsmessage.TypeAAAA, uniqueClass), Body: body}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// matchesRecords returns true if any question asks for any of the given records
+func matchesRecords(questions []dnsmessage.Question, records []dnsmessage.Resource) bool {
+	for _, question := range questions {
+		for _, record := range records {
+			if strings.EqualFold(question.Name.String(), record.Header.Name.String()) &&
+				(question.Type == record.Header.Type || question.Type == dnsmessage.TypeALL) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseDNSMessage decodes the DNS message.  The authority and additional records are returned
+// with the answers.
+func parseDNSMessage(buf []byte) (*dnsmessage.Message, error) {
+	message := &dnsmessage.Message{}
+	if err := message.Unpack(buf); err != nil {
+		return nil, err
+	}
+	message.Answers = append(append(message.Answers, message.Authorities...), message.Additionals...)
+	message.Authorities, message.Additionals = nil, nil
+	return message, nil
+}
+
+// getLocalIPs returns this host's non-loopback unicast addresses
+func getLocalIPs() []net.IP {
+	var ips []net.IP
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Errorf("Unable to enumerate interface addresses, err=%v", err)
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return ips
+}
+
+// containsIP returns true if the address is in the list
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, listed := range ips {
+		if listed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
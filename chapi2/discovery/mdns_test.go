@@ -0,0 +1,123 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package discovery
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestServiceRecordsRoundTrip(t *testing.T) {
+	service := &Service{
+		Instance:   "host1",
+		HostName:   "host1.local.",
+		Port:       49152,
+		APIVersion: "v1",
+		Path:       "/api/v1",
+		Platform:   "windows",
+		IPs:        []net.IP{net.ParseIP("10.1.2.3"), net.ParseIP("fd00::1")},
+	}
+	records, err := serviceRecords(service, mdnsTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &dnsmessage.Message{Header: dnsmessage.Header{ID: 7, Response: true}, Answers: records}
+	parsed, err := parseDNSMessage(mustPack(t, message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header.ID != 7 || !parsed.Header.Response || len(parsed.Answers) != 5 {
+		t.Fatalf("unexpected message %+v", parsed)
+	}
+
+	services := servicesFromRecords(parsed.Answers)
+	if len(services) != 1 {
+		t.Fatalf("unexpected services %+v", services)
+	}
+	found := services[0]
+	if found.Instance != "host1" || found.HostName != "host1.local." || found.Port != 49152 || found.APIVersion != "v1" ||
+		found.Path != "/api/v1" || found.Platform != "windows" || len(found.IPs) != 2 {
+		t.Errorf("unexpected service %+v", found)
+	}
+	if found.URL() != "http://10.1.2.3:49152" {
+		t.Errorf("unexpected URL %v", found.URL())
+	}
+
+	// Goodbye records do not report the service
+	if message.Answers, err = serviceRecords(service, 0); err != nil {
+		t.Fatal(err)
+	}
+	if parsed, err = parseDNSMessage(mustPack(t, message)); err != nil || len(servicesFromRecords(parsed.Answers)) != 0 {
+		t.Errorf("goodbye records reported a service, err=%v", err)
+	}
+
+	// Names too long for DNS are rejected
+	service.HostName = strings.Repeat("host1.", 50) + "local."
+	if _, err = serviceRecords(service, mdnsTTL); err == nil {
+		t.Error("expected error for a host name too long")
+	}
+}
+
+// mustPack encodes the DNS message
+func mustPack(t *testing.T, message *dnsmessage.Message) []byte {
+	buf, err := message.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestParseCompressedName(t *testing.T) {
+	// A response with the additional records, compressed, reports the service
+	records, err := serviceRecords(&Service{Instance: "host1", HostName: "host1.local.", Port: 1}, mdnsTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+	builder.EnableCompression()
+	builder.StartAnswers()
+	builder.PTRResource(records[0].Header, *records[0].Body.(*dnsmessage.PTRResource))
+	builder.StartAdditionals()
+	builder.SRVResource(records[1].Header, *records[1].Body.(*dnsmessage.SRVResource))
+	buf, err := builder.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := parseDNSMessage(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if services := servicesFromRecords(message.Answers); len(services) != 1 || services[0].Instance != "host1" || services[0].HostName != "host1.local." {
+		t.Errorf("unexpected services %+v", services)
+	}
+
+	// A pointer loop is rejected
+	loop := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xc0, 12, 0, 12, 0, 1}
+	if _, err = parseDNSMessage(loop); err == nil {
+		t.Error("pointer loop not rejected")
+	}
+}
+
+func TestMatchesRecords(t *testing.T) {
+	records, err := serviceRecords(&Service{Instance: "host1", HostName: "host1.local.", Port: 1}, mdnsTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		question dnsmessage.Question
+		matches  bool
+	}{
+		{dnsmessage.Question{Name: dnsmessage.MustNewName("_CHAPI._tcp.local."), Type: dnsmessage.TypePTR}, true},
+		{dnsmessage.Question{Name: dnsmessage.MustNewName("host1._chapi._tcp.local."), Type: dnsmessage.TypeALL}, true},
+		{dnsmessage.Question{Name: dnsmessage.MustNewName("host1._chapi._tcp.local."), Type: dnsmessage.TypeA}, false},
+		{dnsmessage.Question{Name: dnsmessage.MustNewName("_http._tcp.local."), Type: dnsmessage.TypePTR}, false},
+	}
+	for _, testCase := range testCases {
+		if matchesRecords([]dnsmessage.Question{testCase.question}, records) != testCase.matches {
+			t.Errorf("question %+v, expected match=%v", testCase.question, testCase.matches)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package driver
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/fc"
@@ -18,17 +19,17 @@ import (
 
 const (
 	// Shared error messages
-	errorMessageEmptyIqnFound         = "empty iqn found"
-	errorMessageMultipleDevices       = "multiple (%v) devices enumerated"
-	errorMessageMultipleDeviceObjects = "multiple device access objects provided"
-	errorMessageNoDeviceObject        = "device access object not provided"
-	errorMessageNoDevicesOnHost       = "no devices found on host"
-	errorMessageNoInitiatorsFound     = "neither of iscsi or fc initiators are found on host"
-	errorMessageNoMountPointsFound    = "no mount points found"
-	errorMessageNoNetworkInterfaces   = "no network interfaces found on host"
-	errorMessageNoPartitionsOnVolume  = "no partitions found on volume"
-	errorMessageNotYetImplemented     = "not yet implemented"
-	errorMessageVolumeMounted         = "volume mounted"
+	errorMessageEmptyIqnFound        = "empty iqn found"
+	errorMessageMultipleDevices      = "multiple (%v) devices enumerated"
+	errorMessageNoDevicesOnHost      = "no devices found on host"
+	errorMessageNoInitiatorsFound    = "neither of iscsi or fc initiators are found on host"
+	errorMessageNoMountPointsFound   = "no mount points found"
+	errorMessageNoNetworkInterfaces  = "no network interfaces found on host"
+	errorMessageNoPartitionsOnVolume = "no partitions found on volume"
+	errorMessageNoTuningProvided     = "no device tuning settings provided"
+	errorMessageNotYetImplemented    = "not yet implemented"
+	errorMessageVolumeMounted        = "volume mounted"
+	errorMessageVolumeMountedRW      = "volume mounted read-write at %v"
 )
 
 // Driver provides a common interface for host related operations
@@ -41,6 +42,25 @@ type Driver interface {
 	GetHostInitiators() ([]*model.Initiator, error) // GET /api/v1/initiators
 	GetHostNetworks() ([]*model.Network, error)     // GET /api/v1/networks
 
+	// PUT /api/v1/initiators/actions/rotate-credentials
+	RotateIscsiCredentials(rotation *model.CredentialRotation) ([]*model.ReloginResult, error)
+
+	// GET /api/v1/targets/unconnected
+	GetUnconnectedTargets() ([]*model.UnconnectedTarget, error)
+
+	// GET /api/v1/targets/fc
+	GetFcTargetPorts() ([]*model.FcTargetPort, error)
+
+	// PUT /api/v1/targets/actions/collect-stale-sessions
+	CollectStaleSessions(collection *model.StaleSessionCollection) ([]*model.StaleTarget, error)
+
+	// PUT /api/v1/targets/actions/invalidate-lun-maps
+	InvalidateLunMaps(invalidation *model.LunMapInvalidation) ([]string, error)
+
+	GetReadiness() (*model.Readiness, error) // GET /api/v1/readiness
+
+	GetCapabilities() (*model.Capabilities, error) // GET /api/v1/capabilities
+
 	///////////////////////////////////////////////////////////////////////////////////////////
 	// Device Methods
 	///////////////////////////////////////////////////////////////////////////////////////////
@@ -53,21 +73,69 @@ type Driver interface {
 	// GET /api/v1/devices/details?serial=serial
 	GetAllDeviceDetails(serialNumber string) ([]*model.Device, error)
 
+	// GET /api/v1/devices/details?fields=serial_number,size
+	GetDeviceDetailFields(serialNumber string, fields []string) ([]*model.Device, error)
+
 	// GET /api/v1/devices/{serialnumber}/partitions
 	GetPartitionInfo(serialNumber string) ([]*model.DevicePartition, error)
 
 	// POST /api/v1/devices
 	CreateDevice(publishInfo model.PublishInfo) (*model.Device, error)
 
+	// POST /api/v1/devices/actions/plan
+	PlanCreateDevice(publishInfo model.PublishInfo) (*model.AttachPlan, error)
+
 	// DELETE /api/v1/devices/{serialnumber}
 	DeleteDevice(serialNumber string) error
 
 	// PUT /api/v1/devices/{serialnumber}/actions/offline
 	OfflineDevice(serialNumber string) error
 
+	// PUT /api/v1/devices/{serialnumber}/actions/extend-partition
+	ExtendPartition(serialNumber string) ([]*model.DevicePartition, error)
+
 	// PUT /api/v1/devices/{serialnumber}/filesystem/{filesystem}
 	CreateFileSystem(serialNumber string, filesystem string) error
 
+	// PUT /api/v1/devices/{serialnumber}/filesystem/{filesystem} with file system options
+	CreateFileSystemWithOptions(serialNumber string, filesystem string, fsOptions *model.FileSystemOptions) error
+
+	// GET /api/v1/devices/{serialnumber}/format
+	GetFormatStatus(serialNumber string) (*model.FormatStatus, error)
+
+	// GET /api/v1/devices/{serialnumber}/tuning
+	GetDeviceTuning(serialNumber string) (*model.DeviceTuning, error)
+
+	// PUT /api/v1/devices/{serialnumber}/tuning
+	SetDeviceTuning(serialNumber string, tuning model.DeviceTuning) (*model.DeviceTuning, error)
+
+	// GET /api/v1/devices/{serialnumber}/paths
+	GetDevicePaths(serialNumber string) ([]*model.DevicePathGroup, error)
+
+	// PUT /api/v1/devices/{serialnumber}/actions/recover-paths
+	RecoverDevicePaths(serialNumber string) (*model.PathRecoveryReport, error)
+
+	// POST /api/v1/devices/{serialnumber}/actions/benchmark
+	BenchmarkDevice(serialNumber string, request model.BenchmarkRequest) (*model.BenchmarkResult, error)
+
+	// GET /api/v1/devices/ignored
+	GetIgnoredDevices() ([]*model.IgnoredDevice, error)
+
+	// POST /api/v1/devices/ignored
+	AddIgnoredDevice(wwid string) (*model.IgnoredDevice, error)
+
+	// DELETE /api/v1/devices/ignored/{wwid}
+	RemoveIgnoredDevice(wwid string) error
+
+	// GET /api/v1/devices/{serialnumber}/claim
+	GetDeviceClaim(serialNumber string) (*model.DeviceClaim, error)
+
+	// PUT /api/v1/devices/{serialnumber}/claim
+	ClaimDevice(serialNumber string, request model.ClaimRequest) (*model.DeviceClaim, error)
+
+	// DELETE /api/v1/devices/{serialnumber}/claim
+	ReleaseDevice(serialNumber string, request model.ClaimRequest) error
+
 	///////////////////////////////////////////////////////////////////////////////////////////
 	// Mount Methods
 	///////////////////////////////////////////////////////////////////////////////////////////
@@ -76,31 +144,107 @@ type Driver interface {
 	// GET /api/v1/mounts?serial=serial
 	GetMounts(serialNumber string) ([]*model.Mount, error)
 
+	// GET /api/v1/mounts?serial=serial&mountPointPrefix=prefix
+	GetMountsWithPrefix(serialNumber, mountPointPrefix string) ([]*model.Mount, error)
+
 	// GET /api/v1/mounts/details  or filter by serial using
 	// GET /api/v1/mounts/details?serial=serial or filter by serial and specific mount using
 	// GET /api/v1/mounts/details?serial=serial,mountId=mount
 	GetAllMountDetails(serialNumber, mountPointID string) ([]*model.Mount, error)
 
+	// GET /api/v1/mounts/details?serial=serial,mountId=mount,mountPointPrefix=prefix
+	GetAllMountDetailsWithPrefix(serialNumber, mountPointID, mountPointPrefix string) ([]*model.Mount, error)
+
+	// GET /api/v1/mounts/driveletters
+	GetFreeDriveLetters() ([]string, error)
+
 	// POST /api/v1/mounts
 	CreateMount(serialNumber string, mountPoint string, fsOptions *model.FileSystemOptions) (*model.Mount, error)
 
 	// DELETE /api/v1/mounts/{mountId}
 	DeleteMount(serialNumber, mountPointID string) error
 
+	// DELETE /api/v1/mounts/{mountId}?lazy=true
+	DeleteMountWithOptions(serialNumber, mountPointID string, options *model.DeleteMountOptions) error
+
+	// PUT /api/v1/mounts/{mountId}/move
+	MoveMount(serialNumber, mountPointID, mountPoint string) (*model.Mount, error)
+
+	// GET /api/v1/mounts/orphans?root=root1&root=root2
+	GetOrphanedMounts(roots []string) ([]*model.OrphanedMount, error)
+
+	// DELETE /api/v1/mounts/orphans?root=root1&root=root2
+	DeleteOrphanedMounts(roots []string) ([]*model.OrphanedMount, error)
+
+	// PUT /api/v1/mounts/actions/freeze
+	FreezeMount(request *model.QuiesceRequest) (*model.QuiescedMount, error)
+
+	// PUT /api/v1/mounts/actions/thaw
+	ThawMount(request *model.QuiesceRequest) error
+
+	// PUT /api/v1/mounts/actions/stage
+	StageMount(request *model.StagedMountRequest) (*model.Mount, error)
+
+	// PUT /api/v1/mounts/actions/publish
+	PublishMount(request *model.StagedMountRequest) (*model.Mount, error)
+
+	// PUT /api/v1/mounts/actions/unpublish
+	UnpublishMount(request *model.StagedMountRequest) error
+
+	// PUT /api/v1/mounts/actions/unstage
+	UnstageMount(request *model.StagedMountRequest) error
+
 	// TODO: check with George/Suneeth on this
 	// POST /api/v1/mounts/bind
 	CreateBindMount(sourceMount string, targetMount string, bindType string) (*model.Mount, error)
+
+	///////////////////////////////////////////////////////////////////////////////////////////
+	// Node Methods
+	///////////////////////////////////////////////////////////////////////////////////////////
+
+	// POST /api/v1/node/drain
+	DrainNode(request *model.DrainRequest) ([]*model.DrainResult, error)
+
+	///////////////////////////////////////////////////////////////////////////////////////////
+	// State Methods
+	///////////////////////////////////////////////////////////////////////////////////////////
+
+	// GET /api/v1/state
+	GetManagedState() (*model.ManagedState, error)
+
+	// PUT /api/v1/state/actions/reconcile
+	ReconcileManagedState() (*model.ManagedState, error)
 }
 
 // ChapiServer ... Implements the "Driver" interfaces
 type ChapiServer struct {
 }
 
+var (
+	// cacheGeneration is incremented whenever a CHAPI request changes the host's devices or mounts
+	cacheGeneration uint64
+)
+
+// CacheGeneration returns the current cache generation.  Callers caching device or mount
+// enumeration results can compare generations to detect changes made through CHAPI.  Changes made
+// outside of CHAPI (e.g. a path failure) do not increment the generation.
+func CacheGeneration() uint64 {
+	return atomic.LoadUint64(&cacheGeneration)
+}
+
+// bumpCacheGeneration increments the cache generation unless the request failed; deferred by the
+// requests changing the host with their named error result
+func bumpCacheGeneration(err *error) {
+	if *err == nil {
+		atomic.AddUint64(&cacheGeneration, 1)
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // Host methods
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 
-// GetHostInfo returns host name, domain, and network interfaces
+// GetHostInfo returns host name, domain, and the versions of the kernel and storage modules
 func (driver *ChapiServer) GetHostInfo() (*model.Host, error) {
 	log.Trace(">>>>> GetHostInfo called")
 	defer log.Trace("<<<<< GetHostInfo")
@@ -126,7 +270,18 @@ func (driver *ChapiServer) GetHostInfo() (*model.Host, error) {
 	}
 	log.Infof("Domain Name - %v", domainName)
 
-	return &model.Host{UUID: id, Name: hostName, Domain: domainName}, nil
+	hostInfo := &model.Host{UUID: id, Name: hostName, Domain: domainName}
+
+	// The versions are informational, the host is reported without them if they are not available
+	if hostInfo.KernelVersion, err = hostPlugin.GetKernelVersion(); err != nil {
+		log.Errorf("Unable to determine the kernel version, err=%v", err)
+	}
+	if hostInfo.Modules, err = hostPlugin.GetStorageModules(); err != nil {
+		log.Errorf("Unable to determine the storage module versions, err=%v", err)
+	}
+	log.Infof("Kernel Version - %v", hostInfo.KernelVersion)
+
+	return hostInfo, nil
 }
 
 // GetHostNetworks reports the networks on this host
@@ -193,6 +348,47 @@ func (driver *ChapiServer) GetHostInitiators() ([]*model.Initiator, error) {
 	return inits, nil
 }
 
+// GetUnconnectedTargets reports the iSCSI targets configured on this host that have no active
+// session (e.g. stale node records or persistent logins that failed at boot)
+func (driver *ChapiServer) GetUnconnectedTargets() ([]*model.UnconnectedTarget, error) {
+	log.Trace(">>>>> GetUnconnectedTargets called")
+	defer log.Trace("<<<<< GetUnconnectedTargets")
+
+	log.Info("Get Unconnected Targets")
+
+	iscsiPlugin := iscsi.NewIscsiPlugin()
+	targets, err := iscsiPlugin.GetUnconnectedTargets()
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	// Log enumerated unconnected targets
+	for _, target := range targets {
+		log.Infof("Target=%v, Source=%v, Portals=%v, LastError=%v", target.Name, target.Source, len(target.TargetPortals), target.LastError)
+	}
+	return targets, nil
+}
+
+// GetFcTargetPorts reports the remote FC target ports discovered by the host ports
+func (driver *ChapiServer) GetFcTargetPorts() ([]*model.FcTargetPort, error) {
+	log.Trace(">>>>> GetFcTargetPorts called")
+	defer log.Trace("<<<<< GetFcTargetPorts")
+
+	log.Info("Get FC Target Ports")
+
+	fcPlugin := fc.NewFcPlugin()
+	targetPorts, err := fcPlugin.GetFcTargetPorts()
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	// Log enumerated FC target ports
+	for _, targetPort := range targetPorts {
+		log.Infof("TargetPort=%v, NodeWwn=%v, State=%v, HostPort=%v", targetPort.PortWwn, targetPort.NodeWwn, targetPort.PortState, targetPort.HostPortWwn)
+	}
+	return targetPorts, nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // Device methods
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -228,14 +424,21 @@ func (driver *ChapiServer) GetDevices(serialNumber string) ([]*model.Device, err
 // GetAllDeviceDetails enumerates all the Nimble volumes with detailed information.
 // If serialNumber is non-empty then only specified device is returned
 func (driver *ChapiServer) GetAllDeviceDetails(serialNumber string) ([]*model.Device, error) {
-	log.Tracef(">>>>> GetAllDeviceDetails called, serialNumber=%v", serialNumber)
-	defer log.Trace("<<<<< GetAllDeviceDetails")
+	return driver.GetDeviceDetailFields(serialNumber, nil)
+}
+
+// GetDeviceDetailFields enumerates all the Nimble volumes with detailed information, only
+// populating the given Device properties (e.g. "serial_number"), or every property if none are
+// given.  If serialNumber is non-empty then only specified device is returned
+func (driver *ChapiServer) GetDeviceDetailFields(serialNumber string, fields []string) ([]*model.Device, error) {
+	log.Tracef(">>>>> GetDeviceDetailFields called, serialNumber=%v, fields=%v", serialNumber, fields)
+	defer log.Trace("<<<<< GetDeviceDetailFields")
 	multipathPlugin := multipath.NewMultipathPlugin()
 
 	log.Infof("Get All Device Details, serialNumber=%v", serialNumber)
 
-	// Enumerate all the Nimble volumes on this host (full details)
-	devices, err := multipathPlugin.GetAllDeviceDetails(serialNumber)
+	// Enumerate all the Nimble volumes on this host (full details, or the requested fields)
+	devices, err := multipathPlugin.GetDeviceDetailFields(serialNumber, fields)
 	if err != nil {
 		return nil, cerrors.NewChapiError(err)
 	}
@@ -279,24 +482,21 @@ func (driver *ChapiServer) GetPartitionInfo(serialNumber string) ([]*model.Devic
 }
 
 // CreateDevice will attach device on this host based on the details provided
-func (driver *ChapiServer) CreateDevice(publishInfo model.PublishInfo) (*model.Device, error) {
-	log.Tracef(">>>>> CreateDevice called, publishInfo=%v", publishInfo)
+func (driver *ChapiServer) CreateDevice(publishInfo model.PublishInfo) (device *model.Device, err error) {
+	log.Tracef(">>>>> CreateDevice called, publishInfo=%v", publishInfo.Redacted())
 	defer log.Trace("<<<<< CreateDevice")
+	defer bumpCacheGeneration(&err)
+	defer func() { notifyEvent(EventCreateDevice, publishInfo.SerialNumber, "", device, err) }()
 
 	log.Info("Create Device")
 
-	// Invalid request if no device access object provided
-	if (publishInfo.BlockDev == nil) && (publishInfo.VirtualDev == nil) {
-		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageNoDeviceObject)
-		log.Error(err)
-		return nil, err
-	}
-
-	// Invalid request if multiple device access objects provided
-	if (publishInfo.BlockDev != nil) && (publishInfo.VirtualDev != nil) {
-		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMultipleDeviceObjects)
-		log.Error(err)
-		return nil, err
+	// Invalid request if the publish info is incomplete or malformed (e.g. no device access
+	// object, multiple device access objects or an invalid target iqn).  Every invalid property
+	// is returned in the error details.
+	if err := publishInfo.Validate(); err != nil {
+		chapiErr := cerrors.NewChapiError(cerrors.InvalidArgument, model.ValidationErrorMessage("publish info", err)).WithDetails(err)
+		log.Error(chapiErr)
+		return nil, chapiErr
 	}
 
 	// Attach the virtual device
@@ -308,19 +508,28 @@ func (driver *ChapiServer) CreateDevice(publishInfo model.PublishInfo) (*model.D
 
 	// Attach the block device
 	multipathPlugin := multipath.NewMultipathPlugin()
-	device, err := multipathPlugin.AttachDevice(publishInfo.SerialNumber, *publishInfo.BlockDev)
+	device, err = multipathPlugin.AttachDevice(publishInfo.SerialNumber, *publishInfo.BlockDev)
 	if err != nil {
 		return nil, err
 	}
 
+	// If the direct read probes are enabled, the attached device must serve I/O (e.g. not just
+	// answer SCSI commands) before it's returned
+	if err = multipathPlugin.ProbeDevice(*device); err != nil {
+		return nil, err
+	}
+	recordManagedDevice(publishInfo)
+
 	driver.logDeviceDetails(device)
 	return device, nil
 }
 
 // DeleteDevice will delete the given device from the host
-func (driver *ChapiServer) DeleteDevice(serialNumber string) error {
+func (driver *ChapiServer) DeleteDevice(serialNumber string) (err error) {
 	log.Tracef(">>>>> DeleteDevice called, serialNumber=%v", serialNumber)
 	defer log.Trace("<<<<< DeleteDevice")
+	defer bumpCacheGeneration(&err)
+	defer func() { notifyEvent(EventDeleteDevice, serialNumber, "", nil, err) }()
 	multipathPlugin := multipath.NewMultipathPlugin()
 
 	log.Infof("Delete Device, serialNumber=%v", serialNumber)
@@ -332,6 +541,7 @@ func (driver *ChapiServer) DeleteDevice(serialNumber string) error {
 	devices, err := multipathPlugin.GetAllDeviceDetails(serialNumber)
 	if len(devices) == 0 {
 		log.Infof("Serial number %v not present, returning success", serialNumber)
+		forgetManagedDevice(serialNumber)
 		return nil
 	} else if err != nil {
 		return err
@@ -350,6 +560,7 @@ func (driver *ChapiServer) DeleteDevice(serialNumber string) error {
 	if err := multipathPlugin.DetachDevice(*devices[0]); err != nil {
 		return err
 	}
+	forgetManagedDevice(serialNumber)
 
 	// Success!!!
 	log.Infof("Device Deleted, SerialNumber=%v", serialNumber)
@@ -357,9 +568,10 @@ func (driver *ChapiServer) DeleteDevice(serialNumber string) error {
 }
 
 // OfflineDevice will offline the given device from the host
-func (driver *ChapiServer) OfflineDevice(serialNumber string) error {
+func (driver *ChapiServer) OfflineDevice(serialNumber string) (err error) {
 	log.Tracef(">>>>> OfflineDevice called, serialNumber=%v", serialNumber)
 	defer log.Trace("<<<<< OfflineDevice")
+	defer bumpCacheGeneration(&err)
 	multipathPlugin := multipath.NewMultipathPlugin()
 
 	log.Infof("Offline Device, serialNumber=%v", serialNumber)
@@ -380,10 +592,45 @@ func (driver *ChapiServer) OfflineDevice(serialNumber string) error {
 	return nil
 }
 
+// ExtendPartition extends the last partition on the device with the given serial number to fill
+// the device (e.g. after the volume was expanded on the array) and returns the updated partitions
+func (driver *ChapiServer) ExtendPartition(serialNumber string) (partitions []*model.DevicePartition, err error) {
+	log.Tracef(">>>>> ExtendPartition called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< ExtendPartition")
+	defer bumpCacheGeneration(&err)
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Infof("Extend Partition, serialNumber=%v", serialNumber)
+
+	// Enumerate basic details for the serial number
+	device, err := driver.getSingleDeviceSummary(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extend the partition
+	driver.logDeviceDetails(device)
+	partitions, err = multipathPlugin.ExtendPartition(*device)
+	if err != nil {
+		return nil, err
+	}
+
+	// Success!!!
+	log.Infof("Partition Extended, SerialNumber=%v", serialNumber)
+	return partitions, nil
+}
+
 // CreateFileSystem writes the given file system to the device with the given serial number
 func (driver *ChapiServer) CreateFileSystem(serialNumber string, filesystem string) error {
-	log.Tracef(">>>>> CreateFileSystem called, serialNumber=%v, filesystem=%v", serialNumber, filesystem)
-	defer log.Trace("<<<<< CreateFileSystem")
+	return driver.CreateFileSystemWithOptions(serialNumber, filesystem, nil)
+}
+
+// CreateFileSystemWithOptions writes the given file system, created with the given file system
+// options (may be nil), to the device with the given serial number
+func (driver *ChapiServer) CreateFileSystemWithOptions(serialNumber string, filesystem string, fsOptions *model.FileSystemOptions) (err error) {
+	log.Tracef(">>>>> CreateFileSystemWithOptions called, serialNumber=%v, filesystem=%v, fsOptions=%+v", serialNumber, filesystem, fsOptions)
+	defer log.Trace("<<<<< CreateFileSystemWithOptions")
+	defer bumpCacheGeneration(&err)
 	multipathPlugin := multipath.NewMultipathPlugin()
 
 	log.Infof("Create File System, serialNumber=%v, filesystem=%v", serialNumber, filesystem)
@@ -396,7 +643,189 @@ func (driver *ChapiServer) CreateFileSystem(serialNumber string, filesystem stri
 
 	// Format the device
 	driver.logDeviceDetails(device)
-	return multipathPlugin.CreateFileSystem(*device, filesystem)
+	if err = multipathPlugin.CreateFileSystemWithOptions(*device, filesystem, fsOptions); err != nil {
+		return err
+	}
+
+	// Record the new file system's UUID so that CreateMount can verify the device it mounts
+	recordManagedFileSystem(multipathPlugin, *device)
+	return nil
+}
+
+// GetFormatStatus reports the progress of the file system creation in progress on the device with
+// the given serial number
+func (driver *ChapiServer) GetFormatStatus(serialNumber string) (*model.FormatStatus, error) {
+	log.Tracef(">>>>> GetFormatStatus called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< GetFormatStatus")
+	return multipath.NewMultipathPlugin().GetFormatStatus(serialNumber)
+}
+
+// GetDeviceTuning reports the current queue settings for the device with the given serial number
+func (driver *ChapiServer) GetDeviceTuning(serialNumber string) (*model.DeviceTuning, error) {
+	log.Tracef(">>>>> GetDeviceTuning called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< GetDeviceTuning")
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Infof("Get Device Tuning, serialNumber=%v", serialNumber)
+
+	// Enumerate full details for the serial number (tuning needs the device paths)
+	device, err := driver.getSingleDeviceDetails(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return multipathPlugin.GetDeviceTuning(*device)
+}
+
+// GetDevicePaths reports the paths to the device with the given serial number grouped by their
+// ALUA access state
+func (driver *ChapiServer) GetDevicePaths(serialNumber string) ([]*model.DevicePathGroup, error) {
+	log.Tracef(">>>>> GetDevicePaths called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< GetDevicePaths")
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Infof("Get Device Paths, serialNumber=%v", serialNumber)
+
+	// Enumerate full details for the serial number (the paths are found from the device)
+	device, err := driver.getSingleDeviceDetails(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return multipathPlugin.GetDevicePaths(*device)
+}
+
+// BenchmarkDevice runs a short read-only latency probe against the device with the given serial
+// number.  The device must not be mounted, or only mounted read-only.
+func (driver *ChapiServer) BenchmarkDevice(serialNumber string, request model.BenchmarkRequest) (*model.BenchmarkResult, error) {
+	log.Tracef(">>>>> BenchmarkDevice called, serialNumber=%v, request=%+v", serialNumber, request)
+	defer log.Trace("<<<<< BenchmarkDevice")
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Infof("Benchmark Device, serialNumber=%v", serialNumber)
+
+	// Fail request if the benchmark is too long or the block size is invalid
+	if err := request.Validate(); err != nil {
+		chapiErr := cerrors.NewChapiError(cerrors.InvalidArgument, model.ValidationErrorMessage("benchmark request", err)).WithDetails(err)
+		log.Error(chapiErr)
+		return nil, chapiErr
+	}
+	if request.DurationSeconds == 0 {
+		request.DurationSeconds = model.BenchmarkDefaultDurationSeconds
+	}
+	if request.BlockSize == 0 {
+		request.BlockSize = model.BenchmarkDefaultBlockSize
+	}
+
+	// Enumerate basic details for the serial number
+	device, err := driver.getSingleDeviceSummary(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail request if the device is mounted read-write.  Reads bypass the host's cache and
+	// would not reflect data still being written through a read-write mount.
+	mountPlugin := mount.NewMounter()
+	mounts, _ := mountPlugin.GetAllMountDetails(serialNumber, "")
+	for _, mountPoint := range mounts {
+		if !isReadOnlyMount(mountPoint) {
+			err = cerrors.NewChapiErrorf(cerrors.Aborted, errorMessageVolumeMountedRW, mountPoint.MountPoint)
+			log.Error(err)
+			return nil, err
+		}
+	}
+
+	// Run the benchmark
+	driver.logDeviceDetails(device)
+	result, err := multipathPlugin.BenchmarkDevice(*device, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Success!!!
+	log.Infof("Device Benchmarked, SerialNumber=%v, reads=%v, iops=%.0f, latency=%+v", serialNumber, result.Reads, result.IOPS, result.Latency)
+	return result, nil
+}
+
+// isReadOnlyMount returns true if the mount's options report it as mounted read-only
+func isReadOnlyMount(mountPoint *model.Mount) bool {
+	if mountPoint.FsOpts == nil {
+		return false
+	}
+	for _, option := range mountPoint.FsOpts.MountOpts {
+		if option == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDeviceTuning applies the given queue settings to the device with the given serial number and
+// returns the resulting settings
+func (driver *ChapiServer) SetDeviceTuning(serialNumber string, tuning model.DeviceTuning) (appliedTuning *model.DeviceTuning, err error) {
+	log.Tracef(">>>>> SetDeviceTuning called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< SetDeviceTuning")
+	defer bumpCacheGeneration(&err)
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Infof("Set Device Tuning, serialNumber=%v", serialNumber)
+
+	// Invalid request if there is nothing to apply
+	if (tuning.ReadAheadKB == nil) && (tuning.NrRequests == nil) && (tuning.Scheduler == "") &&
+		(tuning.RqAffinity == nil) && (tuning.QueueDepth == nil) {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageNoTuningProvided)
+		log.Error(err)
+		return nil, err
+	}
+
+	// Enumerate full details for the serial number (tuning needs the device paths)
+	device, err := driver.getSingleDeviceDetails(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the settings and report back what the device is now using
+	driver.logDeviceDetails(device)
+	if err = multipathPlugin.SetDeviceTuning(*device, tuning); err != nil {
+		return nil, err
+	}
+	return multipathPlugin.GetDeviceTuning(*device)
+}
+
+// GetIgnoredDevices reports the device WWIDs that are hidden from device enumeration
+func (driver *ChapiServer) GetIgnoredDevices() ([]*model.IgnoredDevice, error) {
+	log.Trace(">>>>> GetIgnoredDevices called")
+	defer log.Trace("<<<<< GetIgnoredDevices")
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Info("Get Ignored Devices")
+
+	return multipathPlugin.GetIgnoredDevices()
+}
+
+// AddIgnoredDevice hides the given device WWID from device enumeration and, under Linux, adds it to
+// the multipath blacklist so that the device is not reassembled if it is rediscovered
+func (driver *ChapiServer) AddIgnoredDevice(wwid string) (ignoredDevice *model.IgnoredDevice, err error) {
+	log.Tracef(">>>>> AddIgnoredDevice called, wwid=%v", wwid)
+	defer log.Trace("<<<<< AddIgnoredDevice")
+	defer bumpCacheGeneration(&err)
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Infof("Add Ignored Device, wwid=%v", wwid)
+
+	return multipathPlugin.AddIgnoredDevice(wwid)
+}
+
+// RemoveIgnoredDevice removes the given device WWID from the ignore list (and multipath blacklist)
+func (driver *ChapiServer) RemoveIgnoredDevice(wwid string) (err error) {
+	log.Tracef(">>>>> RemoveIgnoredDevice called, wwid=%v", wwid)
+	defer log.Trace("<<<<< RemoveIgnoredDevice")
+	defer bumpCacheGeneration(&err)
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Infof("Remove Ignored Device, wwid=%v", wwid)
+
+	return multipathPlugin.RemoveIgnoredDevice(wwid)
 }
 
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -405,14 +834,20 @@ func (driver *ChapiServer) CreateFileSystem(serialNumber string, filesystem stri
 
 // GetMounts reports all mounts on this host for the specified Nimble volume
 func (driver *ChapiServer) GetMounts(serialNumber string) ([]*model.Mount, error) {
-	log.Tracef(">>>>> GetMounts called, serialNumber=%v", serialNumber)
-	defer log.Trace("<<<<< GetMounts")
+	return driver.GetMountsWithPrefix(serialNumber, "")
+}
 
-	log.Infof("Get Mounts, serialNumber=%v", serialNumber)
+// GetMountsWithPrefix reports all mounts on this host for the specified Nimble volume, optionally
+// only those at or beneath the given mount point prefix
+func (driver *ChapiServer) GetMountsWithPrefix(serialNumber, mountPointPrefix string) ([]*model.Mount, error) {
+	log.Tracef(">>>>> GetMountsWithPrefix called, serialNumber=%v, mountPointPrefix=%v", serialNumber, mountPointPrefix)
+	defer log.Trace("<<<<< GetMountsWithPrefix")
+
+	log.Infof("Get Mounts, serialNumber=%v, mountPointPrefix=%v", serialNumber, mountPointPrefix)
 
 	// Route request to the mount package to get the mounts
 	mountPlugin := mount.NewMounter()
-	mounts, err := mountPlugin.GetMounts(serialNumber)
+	mounts, err := mountPlugin.GetMountsWithPrefix(serialNumber, mountPointPrefix)
 	if err != nil {
 		return nil, err
 	}
@@ -427,15 +862,21 @@ func (driver *ChapiServer) GetMounts(serialNumber string) ([]*model.Mount, error
 }
 
 // GetAllMountDetails enumerates the specified mount point ID
-func (driver *ChapiServer) GetAllMountDetails(serialNumber string, mountPointID string) ([]*model.Mount, error) {
-	log.Tracef(">>>>> GetAllMountDetails called, serialNumber=%v, mountPointID=%v", serialNumber, mountPointID)
-	defer log.Trace("<<<<< GetAllMountDetails")
+func (driver *ChapiServer) GetAllMountDetails(serialNumber, mountPointID string) ([]*model.Mount, error) {
+	return driver.GetAllMountDetailsWithPrefix(serialNumber, mountPointID, "")
+}
 
-	log.Infof("Get All Mount Details, serialNumber=%v, mountPointID=%v", serialNumber, mountPointID)
+// GetAllMountDetailsWithPrefix enumerates the specified mount point ID, optionally only those at
+// or beneath the given mount point prefix
+func (driver *ChapiServer) GetAllMountDetailsWithPrefix(serialNumber, mountPointID, mountPointPrefix string) ([]*model.Mount, error) {
+	log.Tracef(">>>>> GetAllMountDetailsWithPrefix called, serialNumber=%v, mountPointID=%v, mountPointPrefix=%v", serialNumber, mountPointID, mountPointPrefix)
+	defer log.Trace("<<<<< GetAllMountDetailsWithPrefix")
+
+	log.Infof("Get All Mount Details, serialNumber=%v, mountPointID=%v, mountPointPrefix=%v", serialNumber, mountPointID, mountPointPrefix)
 
 	// Route request to the mount package to get the mounts
 	mountPlugin := mount.NewMounter()
-	mounts, err := mountPlugin.GetAllMountDetails(serialNumber, mountPointID)
+	mounts, err := mountPlugin.GetAllMountDetailsWithPrefix(serialNumber, mountPointID, mountPointPrefix)
 	if err != nil {
 		return nil, err
 	}
@@ -445,46 +886,164 @@ func (driver *ChapiServer) GetAllMountDetails(serialNumber string, mountPointID
 		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageNoMountPointsFound)
 	}
 
+	// Flag the managed mount points whose mount options in effect have drifted
+	checkManagedMountOptions(mounts)
+
 	driver.logMountArray(mounts)
 	return mounts, nil
 }
 
+// GetFreeDriveLetters returns the free drive letters (Windows only) that CreateMount can
+// automatically assign, in the order they are assigned
+func (driver *ChapiServer) GetFreeDriveLetters() ([]string, error) {
+	log.Trace(">>>>> GetFreeDriveLetters called")
+	defer log.Trace("<<<<< GetFreeDriveLetters")
+
+	log.Info("Get Free Drive Letters")
+
+	// Route request to the mount package to enumerate the free drive letters
+	mountPlugin := mount.NewMounter()
+	return mountPlugin.GetFreeDriveLetters()
+}
+
 // CreateMount mounts the given device to the given mount point
-func (driver *ChapiServer) CreateMount(serialNumber string, mountPoint string, fsOptions *model.FileSystemOptions) (*model.Mount, error) {
+func (driver *ChapiServer) CreateMount(serialNumber string, mountPoint string, fsOptions *model.FileSystemOptions) (newMount *model.Mount, err error) {
 	log.Tracef(">>>>> CreateMount called, serialNumber=%v, mountPoint=%v, fsOptions=%v", serialNumber, mountPoint, fsOptions)
 	defer log.Trace("<<<<< CreateMount")
+	defer bumpCacheGeneration(&err)
+	defer func() { notifyEvent(EventCreateMount, serialNumber, "", newMount, err) }()
 
 	log.Infof("Create Mount, serialNumber=%v, mountPoint=%v", serialNumber, mountPoint)
 
+	// Verify the file system UUID recorded for the device unless the caller provided one
+	fsOptions = withManagedFileSystem(serialNumber, fsOptions)
+
 	// Route request to the mount package to create the mount point
 	mountPlugin := mount.NewMounter()
-	mount, err := mountPlugin.CreateMount(serialNumber, mountPoint, fsOptions)
+	newMount, err = mountPlugin.CreateMount(serialNumber, mountPoint, fsOptions)
 	if err != nil {
 		return nil, err
 	}
+	var mountOptions []string
+	if fsOptions != nil {
+		mountOptions = fsOptions.MountOpts
+	}
+	recordManagedMount(newMount, mountOptions)
 
-	driver.logMount(mount)
-	return mount, nil
+	driver.logMount(newMount)
+	return newMount, nil
 }
 
-// DeleteMount unmounts the given mount point, serialNumber can be optional in the body
+// DeleteMount unmounts the given mount point, serialNumber can be optional in the body.  If the
+// mount point is busy, a cerrors.Busy error is returned with the blocking processes as details.
 func (driver *ChapiServer) DeleteMount(serialNumber string, mountPointId string) error {
-	log.Tracef(">>>>> DeleteMount called, serialNumber=%v, mountPointID=%v", serialNumber, mountPointId)
-	defer log.Trace("<<<<< DeleteMount")
+	return driver.DeleteMountWithOptions(serialNumber, mountPointId, nil)
+}
+
+// DeleteMountWithOptions unmounts the given mount point with the given options (may be nil).
+// Setting options.Lazy detaches the mount point immediately even if it is still in use.
+func (driver *ChapiServer) DeleteMountWithOptions(serialNumber string, mountPointId string, options *model.DeleteMountOptions) (err error) {
+	lazy := options != nil && options.Lazy
+	log.Tracef(">>>>> DeleteMountWithOptions called, serialNumber=%v, mountPointID=%v, lazy=%v", serialNumber, mountPointId, lazy)
+	defer log.Trace("<<<<< DeleteMountWithOptions")
+	defer bumpCacheGeneration(&err)
+	defer func() { notifyEvent(EventDeleteMount, serialNumber, mountPointId, nil, err) }()
 
-	log.Infof("Delete Mount, serialNumber=%v, mountPointId=%v", serialNumber, mountPointId)
+	log.Infof("Delete Mount, serialNumber=%v, mountPointId=%v, lazy=%v", serialNumber, mountPointId, lazy)
 
 	// Route request to the mount package to delete the mount point
 	mountPlugin := mount.NewMounter()
-	if err := mountPlugin.DeleteMount(serialNumber, mountPointId); err != nil {
+	if err = mountPlugin.DeleteMountWithOptions(serialNumber, mountPointId, options); err != nil {
 		return err
 	}
+	forgetManagedMount(mountPointId)
 
 	// Success!!!
 	log.Infof("Mount Point ID %v successfully deleted", mountPointId)
 	return nil
 }
 
+// MoveMount moves the given mount point to the given mount point path without unmounting the
+// volume.  The moved mount point is returned; a moved bind mount has a new mount point ID.
+func (driver *ChapiServer) MoveMount(serialNumber, mountPointId, mountPoint string) (movedMount *model.Mount, err error) {
+	log.Tracef(">>>>> MoveMount called, serialNumber=%v, mountPointID=%v, mountPoint=%v", serialNumber, mountPointId, mountPoint)
+	defer log.Trace("<<<<< MoveMount")
+	defer bumpCacheGeneration(&err)
+	defer func() { notifyEvent(EventMoveMount, serialNumber, mountPointId, movedMount, err) }()
+
+	log.Infof("Move Mount, serialNumber=%v, mountPointId=%v, mountPoint=%v", serialNumber, mountPointId, mountPoint)
+
+	// Route request to the mount package to move the mount point
+	mountPlugin := mount.NewMounter()
+	movedMount, err = mountPlugin.MoveMount(serialNumber, mountPointId, mountPoint)
+	if err != nil {
+		return nil, err
+	}
+	mountOptions := getManagedMountOptions(mountPointId)
+	forgetManagedMount(mountPointId)
+	recordManagedMount(movedMount, mountOptions)
+
+	driver.logMount(movedMount)
+	return movedMount, nil
+}
+
+// GetOrphanedMounts reports the mount point directories, recorded by CHAPI beneath the given mount
+// roots, that are no longer backed by a device (e.g. left behind after an ungraceful reboot)
+func (driver *ChapiServer) GetOrphanedMounts(roots []string) ([]*model.OrphanedMount, error) {
+	log.Tracef(">>>>> GetOrphanedMounts called, roots=%v", roots)
+	defer log.Trace("<<<<< GetOrphanedMounts")
+
+	log.Infof("Get Orphaned Mounts, roots=%v", roots)
+
+	// Route request to the mount package to enumerate the orphaned mount points
+	mountPlugin := mount.NewMounter()
+	return mountPlugin.GetOrphanedMounts(roots, false)
+}
+
+// DeleteOrphanedMounts removes the mount point directories, recorded by CHAPI beneath the given
+// mount roots, that are no longer backed by a device.  The orphaned mount points are returned with their removal
+// status.
+func (driver *ChapiServer) DeleteOrphanedMounts(roots []string) (orphans []*model.OrphanedMount, err error) {
+	log.Tracef(">>>>> DeleteOrphanedMounts called, roots=%v", roots)
+	defer log.Trace("<<<<< DeleteOrphanedMounts")
+	defer bumpCacheGeneration(&err)
+
+	log.Infof("Delete Orphaned Mounts, roots=%v", roots)
+
+	// Route request to the mount package to remove the orphaned mount points
+	mountPlugin := mount.NewMounter()
+	orphans, err = mountPlugin.GetOrphanedMounts(roots, true)
+	if err != nil {
+		return nil, err
+	}
+	forgetRemovedOrphans(orphans)
+	return orphans, nil
+}
+
+// FreezeMount quiesces the given mount point until it is thawed or the freeze times out
+func (driver *ChapiServer) FreezeMount(request *model.QuiesceRequest) (*model.QuiescedMount, error) {
+	log.Tracef(">>>>> FreezeMount called, request=%+v", request)
+	defer log.Trace("<<<<< FreezeMount")
+
+	log.Infof("Freeze Mount, serialNumber=%v, mountId=%v, timeout=%v", request.SerialNumber, request.MountID, request.TimeoutSeconds)
+
+	// Route request to the mount package to freeze the mount point
+	mountPlugin := mount.NewMounter()
+	return mountPlugin.FreezeMount(request)
+}
+
+// ThawMount resumes I/O on a mount point frozen by FreezeMount
+func (driver *ChapiServer) ThawMount(request *model.QuiesceRequest) error {
+	log.Tracef(">>>>> ThawMount called, request=%+v", request)
+	defer log.Trace("<<<<< ThawMount")
+
+	log.Infof("Thaw Mount, serialNumber=%v, mountId=%v", request.SerialNumber, request.MountID)
+
+	// Route request to the mount package to thaw the mount point
+	mountPlugin := mount.NewMounter()
+	return mountPlugin.ThawMount(request)
+}
+
 // CreateBindMount creates the given bind mount
 func (driver *ChapiServer) CreateBindMount(sourceMount string, targetMount string, bindType string) (*model.Mount, error) {
 	log.Tracef(">>>>> CreateBindMount called, sourceMount=%s, targetMount=%s bindType=%s", sourceMount, targetMount, bindType)
@@ -530,6 +1089,34 @@ func (driver *ChapiServer) getSingleDeviceSummary(serialNumber string) (*model.D
 	return devices[0], nil
 }
 
+// getSingleDeviceDetails is the same as getSingleDeviceSummary() except that the full device
+// details are enumerated.
+func (driver *ChapiServer) getSingleDeviceDetails(serialNumber string) (*model.Device, error) {
+	log.Tracef(">>>>> getSingleDeviceDetails called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< getSingleDeviceDetails")
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	// Enumerate the device details for the provided serial number
+	devices, err := multipathPlugin.GetAllDeviceDetails(serialNumber)
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	// Fail request if no Nimble devices found on this host
+	if len(devices) == 0 {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageNoDevicesOnHost)
+	}
+
+	// Multiple devices with the same serial number indicates a misconfigured host
+	if len(devices) != 1 {
+		err = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageMultipleDevices, len(devices))
+		log.Errorf(err.Error())
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	return devices[0], nil
+}
+
 // logNetworks records the host NIC details, one line for NIC, to the information log
 func (driver *ChapiServer) logNetworks(networks []*model.Network) {
 	for _, network := range networks {
@@ -0,0 +1,51 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// CAPABILITIES
+//
+//		GetCapabilities advertises the features CHAPI supports on this host's platform, so that
+//		cross-platform callers (e.g. the CSI driver) can branch on the advertised capabilities
+//		rather than probing endpoints and interpreting cerrors.Unimplemented errors.  Every
+//		feature is always listed, along with the reason it's not supported:
+//
+//		- nvme				NVMe volumes
+//		- fc				Fibre Channel volumes
+//		- bind_mounts		Managed bind mounts of a mount point
+//		- expand			Growing a device's partition after a volume expansion
+//		- encryption		Host based encryption of the volume
+//		- raw_block			Consuming a device as a raw block device, without a file system
+//
+//		The capabilities describe the platform (see platformCapabilities) and not the host's
+//		current configuration; e.g. fc is supported even if the host has no FC HBA.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"runtime"
+	"sort"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// GetCapabilities reports the features CHAPI supports on this host's platform
+func (driver *ChapiServer) GetCapabilities() (*model.Capabilities, error) {
+	log.Trace(">>>>> GetCapabilities called")
+	defer log.Trace("<<<<< GetCapabilities")
+
+	return newCapabilities(runtime.GOOS, platformCapabilities), nil
+}
+
+// newCapabilities returns the capabilities of the given platform; unsupported maps each feature
+// name to the reason it's not supported, or to an empty string if the feature is supported
+func newCapabilities(os string, unsupported map[string]string) *model.Capabilities {
+	capabilities := &model.Capabilities{Os: os, Features: []*model.Capability{}}
+	for name, reason := range unsupported {
+		capabilities.Features = append(capabilities.Features, &model.Capability{Name: name, Supported: reason == "", Reason: reason})
+	}
+	sort.Slice(capabilities.Features, func(i, j int) bool { return capabilities.Features[i].Name < capabilities.Features[j].Name })
+	return capabilities
+}
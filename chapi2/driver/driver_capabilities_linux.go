@@ -0,0 +1,18 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+// platformCapabilities maps each feature to the reason it's not supported on Linux, or to an empty
+// string if the feature is supported (see CAPABILITIES in driver_capabilities.go)
+var platformCapabilities = map[string]string{
+	model.CapabilityNvme:       "NVMe volumes are not supported",
+	model.CapabilityFc:         "",
+	model.CapabilityBindMounts: "",
+	model.CapabilityExpand:     "devices are not partitioned and file systems are not resized on Linux",
+	model.CapabilityEncryption: "host based encryption is not supported",
+	model.CapabilityRawBlock:   "",
+}
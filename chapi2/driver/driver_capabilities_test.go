@@ -0,0 +1,47 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	capabilities, err := (&ChapiServer{}).GetCapabilities()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if capabilities.Os != runtime.GOOS {
+		t.Errorf("expected os %v, got %v", runtime.GOOS, capabilities.Os)
+	}
+
+	// Every feature is listed once, ordered by name, with a reason only if not supported
+	expected := []string{
+		model.CapabilityBindMounts,
+		model.CapabilityEncryption,
+		model.CapabilityExpand,
+		model.CapabilityFc,
+		model.CapabilityNvme,
+		model.CapabilityRawBlock,
+	}
+	if len(capabilities.Features) != len(expected) {
+		t.Fatalf("expected %v features, got %v", len(expected), len(capabilities.Features))
+	}
+	for i, feature := range capabilities.Features {
+		if feature.Name != expected[i] {
+			t.Errorf("expected feature %v at index %v, got %v", expected[i], i, feature.Name)
+		}
+		if feature.Supported == (feature.Reason != "") {
+			t.Errorf("feature %v supported=%v with reason %q", feature.Name, feature.Supported, feature.Reason)
+		}
+		if capabilities.IsSupported(feature.Name) != feature.Supported {
+			t.Errorf("IsSupported(%v) does not match the advertised feature", feature.Name)
+		}
+	}
+	if capabilities.IsSupported("unknown") {
+		t.Error("unknown feature reported as supported")
+	}
+}
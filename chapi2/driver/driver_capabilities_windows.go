@@ -0,0 +1,18 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+// platformCapabilities maps each feature to the reason it's not supported on Windows, or to an
+// empty string if the feature is supported (see CAPABILITIES in driver_capabilities.go)
+var platformCapabilities = map[string]string{
+	model.CapabilityNvme:       "NVMe volumes are not supported",
+	model.CapabilityFc:         "",
+	model.CapabilityBindMounts: "bind mounts not supported on this platform",
+	model.CapabilityExpand:     "",
+	model.CapabilityEncryption: "host based encryption is not supported",
+	model.CapabilityRawBlock:   "disks are only consumed through a partition and file system on Windows",
+}
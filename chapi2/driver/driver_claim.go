@@ -0,0 +1,180 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// DEVICE OWNERSHIP
+//
+//		When more than one orchestrator runs on a host (e.g. the docker plugin and the CSI node
+//		plugin), they can fight over the same device.  An orchestrator claims a device, with its
+//		owner name and a TTL, and renews the claim by claiming it again before it expires.  While
+//		the claim is active, requests that change the device or its mount points (e.g. attach,
+//		detach, mkfs, mount and unmount) fail with PermissionDenied unless they carry the owner
+//		name in the model.OwnerHeader request header.  Unclaimed devices, and devices whose claim
+//		has expired, are not restricted.
+//
+//		Administrators can override another owner's claim; the override is logged as a warning.
+//		Claims are recorded in the state store so that they survive a CHAPI restart.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// DefaultClaimTTL is the lifetime of a claim requested without a TTL
+	DefaultClaimTTL = 5 * time.Minute
+
+	// Device ownership error messages
+	errorMessageDeviceClaimed   = "device %v is claimed by %v until %v"
+	errorMessageDeviceUnclaimed = "device %v is not claimed"
+	errorMessageEmptyOwner      = "empty owner passed in the request"
+	errorMessageInvalidClaimTTL = "invalid ttl %v, must be 0 or more"
+)
+
+var (
+	// claimLock serializes the claim updates so that checking and recording a claim is atomic
+	claimLock sync.Mutex
+
+	// getClaim, recordClaim and removeClaim access the claims in the state store; variables so
+	// that tests can replace them
+	getClaim    = state.GetClaim
+	recordClaim = state.RecordClaim
+	removeClaim = state.RemoveClaim
+
+	// claimNow returns the current time; a variable so that tests can replace it
+	claimNow = time.Now
+)
+
+// GetDeviceClaim returns the active claim on the given device
+func (driver *ChapiServer) GetDeviceClaim(serialNumber string) (*model.DeviceClaim, error) {
+	log.Tracef(">>>>> GetDeviceClaim called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< GetDeviceClaim")
+
+	claim, err := getActiveClaim(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if claim == nil {
+		return nil, cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageDeviceUnclaimed, serialNumber)
+	}
+	return claim, nil
+}
+
+// ClaimDevice claims the given device for the request's owner, or renews the owner's claim.  A
+// device claimed by another owner fails with AlreadyExists unless the request is an override.
+func (driver *ChapiServer) ClaimDevice(serialNumber string, request model.ClaimRequest) (*model.DeviceClaim, error) {
+	log.Tracef(">>>>> ClaimDevice called, serialNumber=%v, owner=%v, ttl=%v, override=%v", serialNumber, request.Owner, request.TTLSeconds, request.Override)
+	defer log.Trace("<<<<< ClaimDevice")
+
+	if request.Owner == "" {
+		return nil, cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageEmptyOwner)
+	}
+	if request.TTLSeconds < 0 {
+		return nil, cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidClaimTTL, request.TTLSeconds)
+	}
+	ttl := DefaultClaimTTL
+	if request.TTLSeconds > 0 {
+		ttl = time.Duration(request.TTLSeconds) * time.Second
+	}
+
+	claimLock.Lock()
+	defer claimLock.Unlock()
+
+	now := claimNow().UTC()
+	claim, err := getActiveClaim(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if claim != nil && claim.Owner != request.Owner {
+		if !request.Override {
+			return nil, cerrors.NewChapiErrorf(cerrors.AlreadyExists, errorMessageDeviceClaimed, serialNumber, claim.Owner, claim.Expires)
+		}
+		log.Warnf("Claim on device %v by %v overridden by %v", serialNumber, claim.Owner, request.Owner)
+		claim = nil
+	}
+
+	// A renewed claim keeps the time the owner first claimed the device
+	if claim == nil {
+		claim = &model.DeviceClaim{SerialNumber: serialNumber, Owner: request.Owner, Claimed: now.Format(time.RFC3339)}
+	}
+	claim.Expires = now.Add(ttl).Format(time.RFC3339)
+	if err = recordClaim(claim); err != nil {
+		return nil, err
+	}
+	log.Infof("Device %v claimed by %v until %v", serialNumber, claim.Owner, claim.Expires)
+	return claim, nil
+}
+
+// ReleaseDevice releases the request owner's claim on the given device.  Releasing an unclaimed
+// device succeeds.  A device claimed by another owner fails with PermissionDenied unless the
+// request is an override.
+func (driver *ChapiServer) ReleaseDevice(serialNumber string, request model.ClaimRequest) error {
+	log.Tracef(">>>>> ReleaseDevice called, serialNumber=%v, owner=%v, override=%v", serialNumber, request.Owner, request.Override)
+	defer log.Trace("<<<<< ReleaseDevice")
+
+	claimLock.Lock()
+	defer claimLock.Unlock()
+
+	claim, err := getActiveClaim(serialNumber)
+	if err != nil || claim == nil {
+		return err
+	}
+	if err = checkClaimOwner(claim, request.Owner, request.Override); err != nil {
+		return err
+	}
+	log.Infof("Device %v released by %v", serialNumber, claim.Owner)
+	return removeClaim(serialNumber)
+}
+
+// CheckDeviceClaim returns PermissionDenied if the given device is claimed by an owner other than
+// the given one, unless override is true
+func CheckDeviceClaim(serialNumber string, owner string, override bool) error {
+	if serialNumber == "" {
+		return nil
+	}
+	claim, err := getActiveClaim(serialNumber)
+	if err != nil || claim == nil {
+		return err
+	}
+	return checkClaimOwner(claim, owner, override)
+}
+
+// checkClaimOwner returns PermissionDenied if the claim is held by an owner other than the given
+// one, unless override is true
+func checkClaimOwner(claim *model.DeviceClaim, owner string, override bool) error {
+	if claim.Owner == owner {
+		return nil
+	}
+	if !override {
+		return cerrors.NewChapiErrorf(cerrors.PermissionDenied, errorMessageDeviceClaimed, claim.SerialNumber, claim.Owner, claim.Expires)
+	}
+	log.Warnf("Claim on device %v by %v overridden by %q", claim.SerialNumber, claim.Owner, owner)
+	return nil
+}
+
+// getActiveClaim returns the claim on the given device, or nil if the device is not claimed or
+// the claim has expired.  Expired claims are removed from the state store.
+func getActiveClaim(serialNumber string) (*model.DeviceClaim, error) {
+	claim, err := getClaim(serialNumber)
+	if err != nil || claim == nil {
+		return nil, err
+	}
+	expires, err := time.Parse(time.RFC3339, claim.Expires)
+	if err == nil && claimNow().Before(expires) {
+		return claim, nil
+	}
+	log.Infof("Claim on device %v by %v expired at %v", serialNumber, claim.Owner, claim.Expires)
+	if err = removeClaim(serialNumber); err != nil {
+		log.Errorf("Unable to remove expired claim on device %v, err=%v", serialNumber, err)
+	}
+	return nil, nil
+}
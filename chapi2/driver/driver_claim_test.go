@@ -0,0 +1,139 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+// useTestClaims replaces the state store claims with an in-memory map and the clock with the
+// returned time
+func useTestClaims() (*time.Time, func()) {
+	claims := make(map[string]model.DeviceClaim)
+	now := time.Date(2020, 6, 1, 10, 20, 30, 0, time.UTC)
+	savedGet, savedRecord, savedRemove, savedNow := getClaim, recordClaim, removeClaim, claimNow
+	getClaim = func(serialNumber string) (*model.DeviceClaim, error) {
+		claim, ok := claims[serialNumber]
+		if !ok {
+			return nil, nil
+		}
+		return &claim, nil
+	}
+	recordClaim = func(claim *model.DeviceClaim) error {
+		claims[claim.SerialNumber] = *claim
+		return nil
+	}
+	removeClaim = func(serialNumber string) error {
+		delete(claims, serialNumber)
+		return nil
+	}
+	claimNow = func() time.Time { return now }
+	return &now, func() {
+		getClaim, recordClaim, removeClaim, claimNow = savedGet, savedRecord, savedRemove, savedNow
+	}
+}
+
+// claimErrorCode returns the CHAPI error code of the error, or OK if there is no error
+func claimErrorCode(err error) cerrors.ChapiErrorCode {
+	if err == nil {
+		return cerrors.OK
+	}
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok {
+		return chapiErr.Code
+	}
+	return cerrors.Unknown
+}
+
+func TestClaimDevice(t *testing.T) {
+	now, restore := useTestClaims()
+	defer restore()
+	driver := &ChapiServer{}
+
+	if _, err := driver.ClaimDevice("serial1", model.ClaimRequest{}); claimErrorCode(err) != cerrors.InvalidArgument {
+		t.Errorf("expected InvalidArgument claiming without owner, err=%v", err)
+	}
+	if _, err := driver.ClaimDevice("serial1", model.ClaimRequest{Owner: "csi-node", TTLSeconds: -1}); claimErrorCode(err) != cerrors.InvalidArgument {
+		t.Errorf("expected InvalidArgument claiming with negative ttl, err=%v", err)
+	}
+	if _, err := driver.GetDeviceClaim("serial1"); claimErrorCode(err) != cerrors.NotFound {
+		t.Errorf("expected NotFound for unclaimed device, err=%v", err)
+	}
+
+	// Claim with the default TTL, then renew it
+	claim, err := driver.ClaimDevice("serial1", model.ClaimRequest{Owner: "csi-node"})
+	if err != nil || claim.Claimed != "2020-06-01T10:20:30Z" || claim.Expires != "2020-06-01T10:25:30Z" {
+		t.Fatalf("unexpected claim %+v, err=%v", claim, err)
+	}
+	*now = now.Add(time.Minute)
+	claim, err = driver.ClaimDevice("serial1", model.ClaimRequest{Owner: "csi-node", TTLSeconds: 60})
+	if err != nil || claim.Claimed != "2020-06-01T10:20:30Z" || claim.Expires != "2020-06-01T10:22:30Z" {
+		t.Fatalf("unexpected renewed claim %+v, err=%v", claim, err)
+	}
+
+	// Another owner can only take the claim over with an override
+	if _, err = driver.ClaimDevice("serial1", model.ClaimRequest{Owner: "docker-plugin"}); claimErrorCode(err) != cerrors.AlreadyExists {
+		t.Errorf("expected AlreadyExists claiming a claimed device, err=%v", err)
+	}
+	claim, err = driver.ClaimDevice("serial1", model.ClaimRequest{Owner: "docker-plugin", Override: true})
+	if err != nil || claim.Owner != "docker-plugin" || claim.Claimed != "2020-06-01T10:21:30Z" {
+		t.Fatalf("unexpected overridden claim %+v, err=%v", claim, err)
+	}
+
+	// Once expired, any owner can claim the device
+	*now = now.Add(DefaultClaimTTL)
+	if _, err = driver.GetDeviceClaim("serial1"); claimErrorCode(err) != cerrors.NotFound {
+		t.Errorf("expected NotFound for expired claim, err=%v", err)
+	}
+	if claim, err = driver.ClaimDevice("serial1", model.ClaimRequest{Owner: "csi-node"}); err != nil || claim.Owner != "csi-node" {
+		t.Errorf("unexpected claim %+v after expiry, err=%v", claim, err)
+	}
+}
+
+func TestReleaseAndCheckDeviceClaim(t *testing.T) {
+	_, restore := useTestClaims()
+	defer restore()
+	driver := &ChapiServer{}
+
+	if err := driver.ReleaseDevice("serial1", model.ClaimRequest{Owner: "csi-node"}); err != nil {
+		t.Errorf("unexpected error releasing unclaimed device, err=%v", err)
+	}
+	if err := CheckDeviceClaim("serial1", "", false); err != nil {
+		t.Errorf("unexpected error checking unclaimed device, err=%v", err)
+	}
+
+	if _, err := driver.ClaimDevice("serial1", model.ClaimRequest{Owner: "csi-node"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		owner    string
+		override bool
+		expected cerrors.ChapiErrorCode
+	}{
+		{"csi-node", false, cerrors.OK},
+		{"docker-plugin", false, cerrors.PermissionDenied},
+		{"", false, cerrors.PermissionDenied},
+		{"", true, cerrors.OK},
+	}
+	for _, tc := range tests {
+		if err := CheckDeviceClaim("serial1", tc.owner, tc.override); claimErrorCode(err) != tc.expected {
+			t.Errorf("CheckDeviceClaim(%q, %v) err=%v, expected %v", tc.owner, tc.override, err, tc.expected)
+		}
+	}
+	if err := CheckDeviceClaim("serial2", "docker-plugin", false); err != nil {
+		t.Errorf("unexpected error checking another device, err=%v", err)
+	}
+
+	if err := driver.ReleaseDevice("serial1", model.ClaimRequest{Owner: "docker-plugin"}); claimErrorCode(err) != cerrors.PermissionDenied {
+		t.Errorf("expected PermissionDenied releasing another owner's claim, err=%v", err)
+	}
+	if err := driver.ReleaseDevice("serial1", model.ClaimRequest{Owner: "csi-node"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDeviceClaim("serial1", "docker-plugin", false); err != nil {
+		t.Errorf("unexpected error checking released device, err=%v", err)
+	}
+}
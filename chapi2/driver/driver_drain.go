@@ -0,0 +1,228 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/multipath"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// Number of volumes drained concurrently if the request does not specify it, and the limit
+	defaultDrainParallelism = 4
+	maxDrainParallelism     = 16
+
+	errorMessageNoDrainVolumes = "no serial numbers provided and all_managed not set"
+)
+
+// drainMount is a mount point removed while draining a volume
+type drainMount struct {
+	result *model.DrainResult // Drain result of the mount point's volume
+	mount  *model.Mount
+}
+
+// DrainNode unmounts, flushes and detaches the given volumes (e.g. from a kubelet drain hook).
+// Mount points are removed in dependency order; bind mounts before the mount point they were
+// fanned out from, and nested mount points before their parents.  A volume is only detached if
+// all its mount points were removed.  The outcome of each volume is returned.
+func (driver *ChapiServer) DrainNode(request *model.DrainRequest) (results []*model.DrainResult, err error) {
+	log.Trace(">>>>> DrainNode called")
+	defer log.Trace("<<<<< DrainNode")
+	defer bumpCacheGeneration(&err)
+
+	serialNumbers, err := getDrainSerialNumbers(request)
+	if err != nil {
+		return nil, err
+	}
+	parallelism := getDrainParallelism(request.Parallelism)
+
+	log.Infof("Drain Node, serialNumbers=%v, parallelism=%v, lazy=%v", serialNumbers, parallelism, request.Lazy)
+
+	// Enumerate the mount points of every volume
+	var mounts []*drainMount
+	results = make([]*model.DrainResult, len(serialNumbers))
+	for i, serialNumber := range serialNumbers {
+		results[i] = &model.DrainResult{SerialNumber: serialNumber}
+		volumeMounts, err := driver.GetAllMountDetails(serialNumber, "")
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		for _, volumeMount := range volumeMounts {
+			mounts = append(mounts, &drainMount{result: results[i], mount: volumeMount})
+		}
+	}
+
+	// Remove the mount points, one wave at a time; the mount points within a wave are independent
+	var resultLock sync.Mutex
+	for _, wave := range getDrainMountWaves(mounts) {
+		runBounded(len(wave), parallelism, func(i int) {
+			resultLock.Lock()
+			failed := wave[i].result.Error != ""
+			resultLock.Unlock()
+			if failed {
+				return
+			}
+
+			err := driver.DeleteMountWithOptions(wave[i].result.SerialNumber, wave[i].mount.ID, &model.DeleteMountOptions{Lazy: request.Lazy})
+
+			resultLock.Lock()
+			defer resultLock.Unlock()
+			if err != nil {
+				wave[i].result.Error = err.Error()
+				return
+			}
+			wave[i].result.Unmounted = append(wave[i].result.Unmounted, wave[i].mount.MountPoint)
+		})
+	}
+
+	// Flush and detach the volumes whose mount points were all removed
+	runBounded(len(results), parallelism, func(i int) {
+		if results[i].Error == "" {
+			driver.drainDevice(results[i])
+		}
+	})
+
+	for _, result := range results {
+		if result.Error != "" {
+			log.Errorf("Unable to drain volume %v, err=%v", result.SerialNumber, result.Error)
+		}
+	}
+	return results, nil
+}
+
+// drainDevice flushes and detaches the drained volume's device, updating its drain result
+func (driver *ChapiServer) drainDevice(result *model.DrainResult) {
+	devices, _ := multipath.NewMultipathPlugin().GetAllDeviceDetails(result.SerialNumber)
+	if len(devices) != 0 {
+		if err := multipath.NewMultipathPlugin().FlushDevice(*devices[0]); err != nil {
+			result.Error = err.Error()
+			return
+		}
+		result.Flushed = true
+	}
+	if err := driver.DeleteDevice(result.SerialNumber); err != nil {
+		result.Error = err.Error()
+		return
+	}
+	result.Detached = true
+}
+
+// getDrainSerialNumbers returns the unique serial numbers of the volumes to drain
+func getDrainSerialNumbers(request *model.DrainRequest) ([]string, error) {
+	if request == nil || (len(request.SerialNumbers) == 0 && !request.AllManaged) {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageNoDrainVolumes)
+		log.Error(err)
+		return nil, err
+	}
+
+	serialNumbers := request.SerialNumbers
+	if request.AllManaged {
+		managedState, err := state.GetState()
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range managedState.Devices {
+			serialNumbers = append(serialNumbers, device.SerialNumber)
+		}
+	}
+
+	var uniqueSerialNumbers []string
+	found := make(map[string]bool)
+	for _, serialNumber := range serialNumbers {
+		if serialNumber != "" && !found[serialNumber] {
+			found[serialNumber] = true
+			uniqueSerialNumbers = append(uniqueSerialNumbers, serialNumber)
+		}
+	}
+	return uniqueSerialNumbers, nil
+}
+
+// getDrainParallelism returns the requested parallelism, defaulted and capped
+func getDrainParallelism(parallelism int) int {
+	if parallelism <= 0 {
+		return defaultDrainParallelism
+	}
+	if parallelism > maxDrainParallelism {
+		return maxDrainParallelism
+	}
+	return parallelism
+}
+
+// getDrainMountWaves orders the mount points into waves that can be removed concurrently.  A mount
+// point is placed in a later wave than the mount points that depend on it; its bind mounts and the
+// mount points nested beneath it.
+func getDrainMountWaves(mounts []*drainMount) [][]*drainMount {
+	waveIndex := make([]int, len(mounts))
+	visiting := make([]bool, len(mounts))
+	computed := make([]bool, len(mounts))
+
+	var getWaveIndex func(i int) int
+	getWaveIndex = func(i int) int {
+		if computed[i] || visiting[i] {
+			return waveIndex[i]
+		}
+		visiting[i] = true
+		for j := range mounts {
+			if j != i && isDrainDependency(mounts[i].mount, mounts[j].mount) {
+				if index := getWaveIndex(j) + 1; index > waveIndex[i] {
+					waveIndex[i] = index
+				}
+			}
+		}
+		visiting[i] = false
+		computed[i] = true
+		return waveIndex[i]
+	}
+
+	var waves [][]*drainMount
+	for i := range mounts {
+		index := getWaveIndex(i)
+		for len(waves) <= index {
+			waves = append(waves, nil)
+		}
+		waves[index] = append(waves[index], mounts[i])
+	}
+	return waves
+}
+
+// isDrainDependency returns true if the dependent mount point must be removed before the given
+// mount point; i.e. it is a bind mount of, or is nested beneath, the mount point
+func isDrainDependency(mount *model.Mount, dependent *model.Mount) bool {
+	if mount.MountPoint == "" || dependent.MountPoint == "" {
+		return false
+	}
+	for _, bindMount := range mount.BindMounts {
+		if bindMount == dependent.MountPoint {
+			return true
+		}
+	}
+	parent := filepath.Clean(mount.MountPoint)
+	child := filepath.Clean(dependent.MountPoint)
+	return len(child) > len(parent) && strings.HasPrefix(child, parent) &&
+		(strings.HasSuffix(parent, string(filepath.Separator)) || child[len(parent)] == filepath.Separator)
+}
+
+// runBounded calls fn for each index in [0, count), running at most parallelism calls at once, and
+// returns once all the calls complete
+func runBounded(count int, parallelism int, fn func(i int)) {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallelism)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,87 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestDrainMountWaves(t *testing.T) {
+	newDrainMount := func(mountPoint string, bindMounts ...string) *drainMount {
+		return &drainMount{mount: &model.Mount{MountPoint: mountPoint, BindMounts: bindMounts}}
+	}
+	mounts := []*drainMount{
+		newDrainMount("/mnt/vol1", "/var/lib/pods/a"),
+		newDrainMount("/var/lib/pods/a"),
+		newDrainMount("/mnt/vol2"),
+		newDrainMount("/mnt/vol2/nested"),
+		newDrainMount("/mnt/vol2/nested/deeper"),
+		newDrainMount("/mnt/vol20"),
+	}
+
+	waves := getDrainMountWaves(mounts)
+	var mountPoints [][]string
+	for _, wave := range waves {
+		var points []string
+		for _, drain := range wave {
+			points = append(points, drain.mount.MountPoint)
+		}
+		sort.Strings(points)
+		mountPoints = append(mountPoints, points)
+	}
+	expected := [][]string{
+		{"/mnt/vol2/nested/deeper", "/mnt/vol20", "/var/lib/pods/a"},
+		{"/mnt/vol1", "/mnt/vol2/nested"},
+		{"/mnt/vol2"},
+	}
+	if len(mountPoints) != len(expected) {
+		t.Fatalf("unexpected waves %v", mountPoints)
+	}
+	for i := range expected {
+		if len(mountPoints[i]) != len(expected[i]) {
+			t.Fatalf("unexpected wave %v %v, expected %v", i, mountPoints[i], expected[i])
+		}
+		for j := range expected[i] {
+			if mountPoints[i][j] != expected[i][j] {
+				t.Errorf("unexpected wave %v %v, expected %v", i, mountPoints[i], expected[i])
+			}
+		}
+	}
+}
+
+func TestDrainRequest(t *testing.T) {
+	if _, err := getDrainSerialNumbers(&model.DrainRequest{}); err == nil {
+		t.Error("expected error without serial numbers")
+	}
+	serialNumbers, err := getDrainSerialNumbers(&model.DrainRequest{SerialNumbers: []string{"a", "b", "a", ""}})
+	if err != nil || len(serialNumbers) != 2 {
+		t.Errorf("unexpected serial numbers %v, err=%v", serialNumbers, err)
+	}
+	if getDrainParallelism(0) != defaultDrainParallelism || getDrainParallelism(100) != maxDrainParallelism || getDrainParallelism(2) != 2 {
+		t.Error("unexpected drain parallelism")
+	}
+}
+
+func TestRunBounded(t *testing.T) {
+	var running, maxRunning, calls int32
+	runBounded(10, 3, func(i int) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			previous := atomic.LoadInt32(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+	})
+	if calls != 10 || maxRunning > 3 {
+		t.Errorf("unexpected calls=%v, maxRunning=%v", calls, maxRunning)
+	}
+}
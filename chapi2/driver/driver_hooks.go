@@ -0,0 +1,262 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// EVENT HOOKS
+//
+//		Operators can have site specific automation (e.g. DNS updates, monitoring annotations) run
+//		whenever a device or mount point is created or deleted, without patching CHAPI.  Each
+//		EventHook either executes a command or posts to a webhook URL, and is invoked on success
+//		and failure of:
+//
+//		- CreateDevice
+//		- DeleteDevice
+//		- CreateMount
+//		- DeleteMount
+//
+//		The JSON HookEvent payload is written to the command's standard input, or posted as the
+//		webhook request body.  Commands are also passed the CHAPI_EVENT and CHAPI_EVENT_SUCCESS
+//		environment variables.  Hooks run asynchronously and their failures are only logged; they
+//		never fail or delay the CHAPI request.
+//
+//		Hooks are configured through SetEventHooks or a JSON file named by the
+//		CHAPI_EVENT_HOOKS_CONFIG environment variable.  A sample hook file:
+//
+//		{
+//		    "hooks": [
+//		        {"events": ["CreateMount", "DeleteMount"], "command": ["/usr/local/bin/update-dns"]},
+//		        {"url": "https://monitoring.example.com/chapi", "timeout_seconds": 5}
+//		    ]
+//		}
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// EventHooksConfigEnv names the JSON event hook file loaded on the first event
+	EventHooksConfigEnv = config.EventHooksConfigEnv
+
+	// Events that invoke the event hooks
+	EventCreateDevice = "CreateDevice"
+	EventDeleteDevice = "DeleteDevice"
+	EventCreateMount  = "CreateMount"
+	EventDeleteMount  = "DeleteMount"
+	EventMoveMount    = "MoveMount"
+
+	// defaultHookTimeout is used if an event hook does not provide a timeout
+	defaultHookTimeout = 30 * time.Second
+)
+
+// EventHook describes a command or webhook invoked on CHAPI events
+type EventHook struct {
+	Events         []string          `json:"events,omitempty"`          // Events that invoke the hook (all events if empty)
+	Command        []string          `json:"command,omitempty"`         // Command, and its arguments, to execute
+	URL            string            `json:"url,omitempty"`             // Webhook URL to post the event to
+	Headers        map[string]string `json:"headers,omitempty"`         // Webhook request headers (e.g. Authorization)
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"` // Hook timeout (default 30 seconds)
+}
+
+// EventHooksConfig is the event hook file format
+type EventHooksConfig struct {
+	Hooks []*EventHook `json:"hooks"`
+}
+
+// HookEvent is the JSON payload passed to the event hooks
+type HookEvent struct {
+	Event        string      `json:"event"`                    // Event name (e.g. "CreateDevice")
+	Success      bool        `json:"success"`                  // true if the request succeeded
+	Error        string      `json:"error,omitempty"`          // Request failure, if Success is false
+	Time         time.Time   `json:"time"`                     // Time the request completed
+	SerialNumber string      `json:"serial_number,omitempty"`  // Volume serial number
+	MountPointID string      `json:"mount_point_id,omitempty"` // DeleteMount and MoveMount only - ID of the deleted or moved mount point
+	Object       interface{} `json:"object,omitempty"`         // Created chapi2.Device or chapi2.Mount object, if any
+}
+
+var (
+	eventHooksLock    sync.RWMutex
+	eventHooks        []*EventHook
+	eventHooksEnvOnce sync.Once
+)
+
+// SetEventHooks validates and enables the given event hooks, replacing any hooks already enabled.
+// Passing no hooks disables the event hooks.
+func SetEventHooks(hooks []*EventHook) error {
+	for _, hook := range hooks {
+		if err := hook.validate(); err != nil {
+			return err
+		}
+	}
+
+	eventHooksLock.Lock()
+	defer eventHooksLock.Unlock()
+	eventHooks = hooks
+	return nil
+}
+
+// LoadEventHooksConfig enables the event hooks in the given JSON file
+func LoadEventHooksConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var config EventHooksConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("invalid event hooks config %v, err=%v", path, err)
+	}
+	return SetEventHooks(config.Hooks)
+}
+
+// validate verifies the hook either executes a command or posts to a webhook
+func (hook *EventHook) validate() error {
+	if (len(hook.Command) == 0) == (hook.URL == "") {
+		return fmt.Errorf("event hook must provide either a command or a url")
+	}
+	for _, event := range hook.Events {
+		switch event {
+		case EventCreateDevice, EventDeleteDevice, EventCreateMount, EventDeleteMount:
+		default:
+			return fmt.Errorf("event hook event %v is not valid", event)
+		}
+	}
+	return nil
+}
+
+// handles returns true if the hook is invoked for the given event
+func (hook *EventHook) handles(event string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, hookEvent := range hook.Events {
+		if hookEvent == event {
+			return true
+		}
+	}
+	return false
+}
+
+// timeout returns the hook's timeout
+func (hook *EventHook) timeout() time.Duration {
+	if hook.TimeoutSeconds > 0 {
+		return time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	return defaultHookTimeout
+}
+
+// notifyEvent asynchronously invokes the event hooks for the given request outcome
+func notifyEvent(event string, serialNumber string, mountPointID string, object interface{}, err error) {
+	// Load the hooks file, if any, on the first event
+	eventHooksEnvOnce.Do(func() {
+		if path := config.String(EventHooksConfigEnv); path != "" {
+			if err := LoadEventHooksConfig(path); err != nil {
+				log.Errorf("Unable to load event hooks config, err=%v", err)
+			}
+		}
+	})
+
+	eventHooksLock.RLock()
+	var hooks []*EventHook
+	for _, hook := range eventHooks {
+		if hook.handles(event) {
+			hooks = append(hooks, hook)
+		}
+	}
+	eventHooksLock.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	// Omit the object, rather than reporting null, if the request did not create one
+	if value := reflect.ValueOf(object); value.Kind() == reflect.Ptr && value.IsNil() {
+		object = nil
+	}
+	hookEvent := &HookEvent{
+		Event:        event,
+		Success:      err == nil,
+		Time:         time.Now(),
+		SerialNumber: serialNumber,
+		MountPointID: mountPointID,
+		Object:       object,
+	}
+	if err != nil {
+		hookEvent.Error = err.Error()
+	}
+	payload, err := json.Marshal(hookEvent)
+	if err != nil {
+		log.Errorf("Unable to marshal %v event, err=%v", event, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		go hook.invoke(event, hookEvent.Success, payload)
+	}
+}
+
+// invoke runs the command or posts to the webhook
+func (hook *EventHook) invoke(event string, success bool, payload []byte) {
+	log.Tracef(">>>>> invoke, event=%v, success=%v, command=%v, url=%v", event, success, hook.Command, hook.URL)
+	defer log.Trace("<<<<< invoke")
+
+	ctx, cancel := context.WithTimeout(context.Background(), hook.timeout())
+	defer cancel()
+
+	var err error
+	if len(hook.Command) != 0 {
+		err = hook.execCommand(ctx, event, success, payload)
+	} else {
+		err = hook.postWebhook(ctx, payload)
+	}
+	if err != nil {
+		log.Errorf("%v event hook failed, command=%v, url=%v, err=%v", event, hook.Command, hook.URL, err)
+	}
+}
+
+// execCommand executes the hook's command with the event payload as its standard input
+func (hook *EventHook) execCommand(ctx context.Context, event string, success bool, payload []byte) error {
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(), "CHAPI_EVENT="+event, fmt.Sprintf("CHAPI_EVENT_SUCCESS=%v", success))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v, output=%v", err, string(out))
+	}
+	return nil
+}
+
+// postWebhook posts the event payload to the hook's URL
+func (hook *EventHook) postWebhook(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range hook.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestEventHooksValidate(t *testing.T) {
+	invalidHooks := []*EventHook{
+		{},
+		{Command: []string{"/bin/true"}, URL: "http://localhost"},
+		{URL: "http://localhost", Events: []string{"OfflineDevice"}},
+	}
+	for _, hook := range invalidHooks {
+		if err := SetEventHooks([]*EventHook{hook}); err == nil {
+			t.Errorf("expected hook %+v to be rejected", hook)
+		}
+	}
+}
+
+func TestEventHooksWebhook(t *testing.T) {
+	events := make(chan *HookEvent, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event HookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("unable to decode event, err=%v", err)
+		}
+		if r.Header.Get("X-Token") != "secret" {
+			t.Errorf("webhook header not set")
+		}
+		events <- &event
+	}))
+	defer server.Close()
+
+	hooks := []*EventHook{{
+		Events:  []string{EventCreateMount, EventDeleteDevice},
+		URL:     server.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+	}}
+	if err := SetEventHooks(hooks); err != nil {
+		t.Fatalf("unable to set event hooks, err=%v", err)
+	}
+	defer SetEventHooks(nil)
+
+	// Events the hook is not registered for are not posted
+	notifyEvent(EventCreateDevice, "1234", "", nil, nil)
+	notifyEvent(EventCreateMount, "1234", "", &model.Mount{ID: "5678", MountPoint: "/mnt/vol1"}, nil)
+	event := waitForEvent(t, events)
+	if event == nil || event.Event != EventCreateMount || !event.Success || event.SerialNumber != "1234" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if object, ok := event.Object.(map[string]interface{}); !ok || object["mount_point"] != "/mnt/vol1" {
+		t.Errorf("unexpected event object %+v", event.Object)
+	}
+
+	// Failed requests are reported with their error
+	notifyEvent(EventDeleteDevice, "1234", "", nil, errors.New("volume mounted"))
+	event = waitForEvent(t, events)
+	if event == nil || event.Event != EventDeleteDevice || event.Success || event.Error != "volume mounted" {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func waitForEvent(t *testing.T, events chan *HookEvent) *HookEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Error("timed out waiting for event")
+	}
+	return nil
+}
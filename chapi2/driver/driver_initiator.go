@@ -0,0 +1,150 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/host"
+	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// RegenerateIqnEnv enables, when set to "true", replacing an iSCSI initiator name shared with a
+	// cloned host by the one derived from the host UUID when CHAPI starts (see
+	// CheckIscsiInitiatorName)
+	RegenerateIqnEnv = config.RegenerateIqnEnv
+)
+
+var (
+	// getIscsiInitiatorName returns the iSCSI initiator name of this host; a variable so that
+	// tests can replace it
+	getIscsiInitiatorName = func() (string, error) {
+		initiator, err := iscsi.NewIscsiPlugin().GetIscsiInitiators()
+		if err != nil {
+			return "", err
+		}
+		if initiator == nil || len(initiator.Init) == 0 {
+			return "", cerrors.NewChapiError(cerrors.NotFound, errorMessageEmptyIqnFound)
+		}
+		return initiator.Init[0], nil
+	}
+
+	// getHostUUID returns the UUID of this host; a variable so that tests can replace it
+	getHostUUID = func() (string, error) {
+		return host.NewHostPlugin().GetUuid()
+	}
+)
+
+// iscsiInitiatorIdentity is the iSCSI initiator name compared with the host UUID
+type iscsiInitiatorIdentity struct {
+	iqn      string                  // iSCSI initiator name
+	hostUUID string                  // UUID of this host
+	recorded *model.ManagedInitiator // Initiator name recorded in the state store, if any
+	shared   bool                    // True if the initiator name is shared with a cloned host
+}
+
+// CheckIscsiInitiatorName warns if the iSCSI initiator name is shared with a cloned host and, if
+// RegenerateIqnEnv is set, replaces it with the initiator name derived from the host UUID.  Called
+// when CHAPI starts, before any volume is attached.
+func (driver *ChapiServer) CheckIscsiInitiatorName() error {
+	log.Trace(">>>>> CheckIscsiInitiatorName called")
+	defer log.Trace("<<<<< CheckIscsiInitiatorName")
+
+	identity, err := getIscsiInitiatorIdentity()
+	if err != nil || !identity.shared {
+		return err
+	}
+	hostIqn := iscsi.HostIqn(identity.iqn, identity.hostUUID)
+	log.Warnf("iSCSI initiator name %v was recorded on host %v, this host (%v) is likely a clone sharing it",
+		identity.iqn, identity.recorded.HostUUID, identity.hostUUID)
+	if !config.Enabled(RegenerateIqnEnv) {
+		log.Warnf("Set %v=true, or change the initiator name, to use the unique initiator name %v", RegenerateIqnEnv, hostIqn)
+		return nil
+	}
+
+	if err = iscsi.NewIscsiPlugin().SetIscsiInitiatorName(hostIqn); err != nil {
+		return err
+	}
+	return state.RecordInitiator(&model.ManagedInitiator{Iqn: hostIqn, HostUUID: identity.hostUUID})
+}
+
+// getIscsiInitiatorIdentity compares the iSCSI initiator name with the host UUID.  The initiator
+// name is recorded, with the host UUID, when first seen or when changed on this host.  Hosts
+// without an iSCSI initiator are never reported as sharing one.
+func getIscsiInitiatorIdentity() (*iscsiInitiatorIdentity, error) {
+	iqn, err := getIscsiInitiatorName()
+	if err != nil {
+		if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.NotFound {
+			return &iscsiInitiatorIdentity{}, nil
+		}
+		return nil, err
+	}
+	hostUUID, err := getHostUUID()
+	if err != nil {
+		return nil, err
+	}
+	identity := &iscsiInitiatorIdentity{iqn: iqn, hostUUID: hostUUID}
+	if identity.recorded, err = state.GetInitiator(); err != nil {
+		// Without the recorded initiator name a clone cannot be detected, which is not fatal
+		log.Errorf("Unable to get the recorded iSCSI initiator name, err=%v", err)
+		return identity, nil
+	}
+
+	identity.shared = isSharedIqn(iqn, hostUUID, identity.recorded)
+	if !identity.shared && (identity.recorded == nil || identity.recorded.Iqn != iqn || identity.recorded.HostUUID != hostUUID) {
+		if err = state.RecordInitiator(&model.ManagedInitiator{Iqn: iqn, HostUUID: hostUUID}); err != nil {
+			log.Errorf("Unable to record the iSCSI initiator name, err=%v", err)
+		}
+	}
+	return identity, nil
+}
+
+// isSharedIqn returns true if the iSCSI initiator name was recorded on a host with a different UUID
+// (i.e. this host was cloned) and is not the initiator name derived from this host's UUID
+func isSharedIqn(iqn string, hostUUID string, recorded *model.ManagedInitiator) bool {
+	if recorded == nil || iqn == "" || iscsi.IsHostIqn(iqn, hostUUID) {
+		return false
+	}
+	return strings.EqualFold(recorded.Iqn, iqn) && !strings.EqualFold(recorded.HostUUID, hostUUID)
+}
+
+// getIscsiInitiatorReadiness checks the iSCSI initiator name is not shared with a cloned host
+func getIscsiInitiatorReadiness() *model.ReadinessCheck {
+	identity, err := getIscsiInitiatorIdentity()
+	if err != nil {
+		return newReadinessCheck("iscsi_initiator_name", model.ReadinessWarn, "unable to check the iSCSI initiator name, %v", err)
+	}
+	if identity.shared {
+		return newReadinessCheck("iscsi_initiator_name", model.ReadinessWarn, "iSCSI initiator name %v was recorded on host %v and is likely shared with a clone, use %v instead",
+			identity.iqn, identity.recorded.HostUUID, iscsi.HostIqn(identity.iqn, identity.hostUUID))
+	}
+	return newReadinessCheck("iscsi_initiator_name", model.ReadinessPass, "")
+}
+
+// RotateIscsiCredentials applies the new iSCSI initiator name and/or CHAP credentials and logs in
+// again the logged in targets, one connection at a time (see ROLLING RE-LOGIN in the iscsi
+// package).  The outcome of each target is returned, even on failure.
+func (driver *ChapiServer) RotateIscsiCredentials(rotation *model.CredentialRotation) ([]*model.ReloginResult, error) {
+	log.Tracef(">>>>> RotateIscsiCredentials called, initiatorName=%v, chapUser=%v, targets=%v", rotation.InitiatorName, rotation.ChapUser, rotation.Targets)
+	defer log.Trace("<<<<< RotateIscsiCredentials")
+
+	log.Infof("Rotate iSCSI credentials, initiatorName=%v, chapUser=%v, targets=%v", rotation.InitiatorName, rotation.ChapUser, rotation.Targets)
+
+	results, err := iscsi.NewIscsiPlugin().RotateCredentials(rotation)
+
+	// Record the new initiator name, once applied, so that it is not mistaken for a cloned host's
+	if rotation.InitiatorName != "" && results != nil {
+		if hostUUID, uuidErr := getHostUUID(); uuidErr == nil {
+			if recordErr := state.RecordInitiator(&model.ManagedInitiator{Iqn: rotation.InitiatorName, HostUUID: hostUUID}); recordErr != nil {
+				log.Errorf("Unable to record the iSCSI initiator name, err=%v", recordErr)
+			}
+		}
+	}
+	return results, err
+}
@@ -0,0 +1,34 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestIsSharedIqn(t *testing.T) {
+	const templateIqn = "iqn.1994-05.com.redhat:template"
+	recorded := &model.ManagedInitiator{Iqn: templateIqn, HostUUID: "uuid1"}
+
+	testCases := []struct {
+		iqn      string
+		hostUUID string
+		recorded *model.ManagedInitiator
+		shared   bool
+	}{
+		{templateIqn, "uuid1", nil, false},
+		{templateIqn, "uuid1", recorded, false},
+		{templateIqn, "UUID1", recorded, false},
+		{templateIqn, "uuid2", recorded, true},
+		{"iqn.1994-05.com.redhat:changed", "uuid2", recorded, false},
+		{iscsi.HostIqn(templateIqn, "uuid2"), "uuid2", &model.ManagedInitiator{Iqn: iscsi.HostIqn(templateIqn, "uuid2"), HostUUID: "uuid1"}, false},
+	}
+	for _, testCase := range testCases {
+		if shared := isSharedIqn(testCase.iqn, testCase.hostUUID, testCase.recorded); shared != testCase.shared {
+			t.Errorf("isSharedIqn(%v, %v, %+v) returned %v", testCase.iqn, testCase.hostUUID, testCase.recorded, shared)
+		}
+	}
+}
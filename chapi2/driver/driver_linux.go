@@ -2,7 +2,23 @@
 
 package driver
 
+import (
+	"syscall"
+)
+
 const (
 	configDir         = "/etc/hpe-storage/"
 	defaultFileSystem = "xfs"
 )
+
+const (
+	// The managed mount points are unmounted, and the targets logged out, before the host shuts
+	// down (see POWER EVENTS in driver_power.go)
+	shutdownUnmountSupported = true
+	shutdownLogoutSupported  = true
+)
+
+// syncFileSystems writes the cached file system data to the devices
+func syncFileSystems() {
+	syscall.Sync()
+}
@@ -0,0 +1,82 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// ATTACH PLAN
+//
+//		"POST /api/v1/devices/actions/plan" takes the same PublishInfo as CreateDevice and reports
+//		how the device would be attached, without discovering or logging into any target.  For
+//		iSCSI targets, the plan lists the connection type, the initiator/target port pairs that
+//		would be connected and the expected connection count.  Anything that would make
+//		CreateDevice fail before connecting (invalid publish info such as a CHAP user without a
+//		password, an undiscoverable target, no usable initiator port) is reported as a blocker
+//		rather than as a request failure, so that every problem is reported at once.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/fc"
+	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	errorMessageNoFcHostPorts = "no fibre channel host ports found on host"
+)
+
+var (
+	// planIscsiLogin plans the iSCSI target login; a variable so that tests can replace it
+	planIscsiLogin = func(blockDev model.BlockDeviceAccessInfo, plan *model.AttachPlan) {
+		iscsi.NewIscsiPlugin().PlanLogin(blockDev, plan)
+	}
+
+	// getFcHostPortWwns returns the FC host port WWNs; a variable so that tests can replace it
+	getFcHostPortWwns = func() ([]string, error) {
+		return fc.NewFcPlugin().GetAllFcHostPortWwn()
+	}
+)
+
+// PlanCreateDevice reports how CreateDevice would attach the device described by the publish info,
+// without attaching it
+func (driver *ChapiServer) PlanCreateDevice(publishInfo model.PublishInfo) (*model.AttachPlan, error) {
+	log.Tracef(">>>>> PlanCreateDevice called, publishInfo=%v", publishInfo.Redacted())
+	defer log.Trace("<<<<< PlanCreateDevice")
+
+	log.Info("Plan Create Device")
+
+	plan := &model.AttachPlan{SerialNumber: publishInfo.SerialNumber}
+	if publishInfo.BlockDev != nil {
+		plan.AccessProtocol = publishInfo.BlockDev.AccessProtocol
+	}
+
+	// Every invalid property of the publish info is a blocker
+	if err := publishInfo.Validate(); err != nil {
+		if errs, ok := err.(model.ValidationErrors); ok {
+			for _, fieldError := range errs {
+				plan.Blockers = append(plan.Blockers, fieldError.Field+": "+fieldError.Error)
+			}
+		} else {
+			plan.Blockers = append(plan.Blockers, err.Error())
+		}
+		return plan, nil
+	}
+
+	switch {
+	case publishInfo.VirtualDev != nil:
+		plan.Blockers = append(plan.Blockers, "virtual_device: "+errorMessageNotYetImplemented)
+	case publishInfo.BlockDev.AccessProtocol == model.AccessProtocolIscsi:
+		planIscsiLogin(*publishInfo.BlockDev, plan)
+	case publishInfo.BlockDev.AccessProtocol == model.AccessProtocolFC:
+		if wwns, err := getFcHostPortWwns(); err != nil {
+			plan.Blockers = append(plan.Blockers, err.Error())
+		} else if len(wwns) == 0 {
+			plan.Blockers = append(plan.Blockers, errorMessageNoFcHostPorts)
+		}
+	}
+
+	log.Infof("Attach plan, serialNumber=%v, connectType=%v, connections=%v, blockers=%v", plan.SerialNumber, plan.ConnectType, plan.ConnectionCount, plan.Blockers)
+	return plan, nil
+}
@@ -0,0 +1,59 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestPlanCreateDevice(t *testing.T) {
+	savedPlanIscsiLogin, savedGetFcHostPortWwns := planIscsiLogin, getFcHostPortWwns
+	defer func() { planIscsiLogin, getFcHostPortWwns = savedPlanIscsiLogin, savedGetFcHostPortWwns }()
+
+	planned := false
+	planIscsiLogin = func(blockDev model.BlockDeviceAccessInfo, plan *model.AttachPlan) {
+		planned = true
+		plan.TargetName = blockDev.TargetName
+	}
+	getFcHostPortWwns = func() ([]string, error) { return nil, nil }
+	driver := &ChapiServer{}
+
+	// Invalid publish info is reported as blockers, without planning the login
+	publishInfo := model.PublishInfo{
+		SerialNumber: "28174883c7719ac236c9ce900584f2795",
+		BlockDev: &model.BlockDeviceAccessInfo{
+			AccessProtocol:  model.AccessProtocolIscsi,
+			TargetName:      "iqn.2007-11.com.nimblestorage:group-c32-array3-g5a2cdea9cf0b91f1",
+			IscsiAccessInfo: &model.IscsiAccessInfo{DiscoveryIP: "10.1.1.10", ChapUser: "chapuser"},
+		},
+	}
+	plan, err := driver.PlanCreateDevice(publishInfo)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{"block_device.iscsi_access_info.chap_user: chap_user and chap_password must be provided together"}
+	if planned || !reflect.DeepEqual(plan.Blockers, expected) {
+		t.Errorf("expected blockers %v without a login plan, got %v (planned=%v)", expected, plan.Blockers, planned)
+	}
+
+	// Valid iSCSI publish info plans the login
+	publishInfo.BlockDev.IscsiAccessInfo.ChapPassword = "chappassword"
+	if plan, err = driver.PlanCreateDevice(publishInfo); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !planned || len(plan.Blockers) != 0 || plan.TargetName != publishInfo.BlockDev.TargetName || plan.AccessProtocol != model.AccessProtocolIscsi {
+		t.Errorf("unexpected plan %+v (planned=%v)", plan, planned)
+	}
+
+	// FC attach is blocked without FC host ports
+	publishInfo.BlockDev = &model.BlockDeviceAccessInfo{AccessProtocol: model.AccessProtocolFC}
+	if plan, err = driver.PlanCreateDevice(publishInfo); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if expected = []string{errorMessageNoFcHostPorts}; !reflect.DeepEqual(plan.Blockers, expected) {
+		t.Errorf("expected blockers %v, got %v", expected, plan.Blockers)
+	}
+}
@@ -0,0 +1,191 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// POWER EVENTS
+//
+//		When the host shuts down, the OS tears down the iSCSI sessions and multipath devices of the
+//		CHAPI managed volumes (see ManagedState) along with everything else, which can leave their
+//		file systems dirty.  Once notified of the shutdown (see the chapi2 power event monitor),
+//		QuiesceForShutdown prepares the managed volumes:
+//
+//		Linux		The managed mount points are unmounted in dependency order (as DrainNode
+//					does), the devices flushed and, once every volume of a target is quiesced,
+//					the target's sessions logged out.  The node records are kept so that the
+//					targets are logged in again on boot, and the managed state is kept so that
+//					the mount points are reported missing until they are mounted again.
+//		Windows		The devices are flushed.  Windows dismounts the volumes itself before the
+//					iSCSI initiator logs out, and the mount points (drive letters, directories)
+//					must survive the reboot, so they are not removed.
+//
+//		Before the host suspends, FlushForSuspend syncs the file systems and flushes the devices;
+//		the sessions are recovered once the host resumes.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/mount"
+	"github.com/hpe-storage/common-host-libs/chapi2/multipath"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+var (
+	// Power event routines; variables so that tests can replace them
+	getPowerManagedState = state.GetState
+	unmountForShutdown   = func(mountPoint string) error {
+		return mount.NewMounter().Unmount(mountPoint)
+	}
+	flushForPowerEvent = func(serialNumber string) error {
+		multipathPlugin := multipath.NewMultipathPlugin()
+		devices, err := multipathPlugin.GetAllDeviceDetails(serialNumber)
+		if err != nil || len(devices) == 0 {
+			return err
+		}
+		return multipathPlugin.FlushDevice(*devices[0])
+	}
+	logoutForShutdown = func(targetName string) error {
+		return iscsi.NewIscsiPlugin().LogoutTargetSessions(targetName)
+	}
+)
+
+// QuiesceForShutdown unmounts (Linux), flushes and logs out (Linux) the CHAPI managed volumes
+// before the host shuts down.  The outcome of each volume is returned; Detached reports whether
+// its target was logged out.
+func (driver *ChapiServer) QuiesceForShutdown() []*model.DrainResult {
+	log.Trace(">>>>> QuiesceForShutdown called")
+	defer log.Trace("<<<<< QuiesceForShutdown")
+
+	managedState, err := getPowerManagedState()
+	if err != nil {
+		log.Errorf("Unable to quiesce the managed volumes for shutdown, err=%v", err)
+		return nil
+	}
+	log.Infof("Quiescing %v managed volume(s) for shutdown", len(managedState.Devices))
+
+	results := make(map[string]*model.DrainResult)
+	var orderedResults []*model.DrainResult
+	for _, device := range managedState.Devices {
+		result := &model.DrainResult{SerialNumber: device.SerialNumber}
+		results[device.SerialNumber] = result
+		orderedResults = append(orderedResults, result)
+	}
+
+	// Unmount the managed mount points, one wave at a time
+	var resultLock sync.Mutex
+	if shutdownUnmountSupported {
+		var mounts []*drainMount
+		for _, managedMount := range managedState.Mounts {
+			result := results[managedMount.SerialNumber]
+			if result == nil {
+				result = &model.DrainResult{SerialNumber: managedMount.SerialNumber}
+				results[managedMount.SerialNumber] = result
+				orderedResults = append(orderedResults, result)
+			}
+			mounts = append(mounts, &drainMount{result: result, mount: &model.Mount{ID: managedMount.ID, SerialNumber: managedMount.SerialNumber, MountPoint: managedMount.MountPoint}})
+		}
+		for _, wave := range getDrainMountWaves(mounts) {
+			runBounded(len(wave), defaultDrainParallelism, func(i int) {
+				err := unmountForShutdown(wave[i].mount.MountPoint)
+
+				resultLock.Lock()
+				defer resultLock.Unlock()
+				if err != nil {
+					wave[i].result.Error = err.Error()
+					return
+				}
+				wave[i].result.Unmounted = append(wave[i].result.Unmounted, wave[i].mount.MountPoint)
+			})
+		}
+	}
+
+	// Flush the devices, including those whose mount points could not be removed
+	syncFileSystems()
+	runBounded(len(orderedResults), defaultDrainParallelism, func(i int) {
+		if err := flushForPowerEvent(orderedResults[i].SerialNumber); err != nil {
+			resultLock.Lock()
+			orderedResults[i].Error = err.Error()
+			resultLock.Unlock()
+			return
+		}
+		orderedResults[i].Flushed = true
+	})
+
+	// Log out the targets whose volumes were all quiesced
+	if shutdownLogoutSupported {
+		for _, targetName := range getQuiescedTargets(managedState.Devices, results) {
+			if err := logoutForShutdown(targetName); err != nil {
+				log.Errorf("Unable to logout target %v for shutdown, err=%v", targetName, err)
+				continue
+			}
+			for _, device := range managedState.Devices {
+				if strings.EqualFold(device.TargetName, targetName) {
+					results[device.SerialNumber].Detached = true
+				}
+			}
+		}
+	}
+
+	for _, result := range orderedResults {
+		if result.Error != "" {
+			log.Errorf("Unable to quiesce volume %v for shutdown, err=%v", result.SerialNumber, result.Error)
+		}
+	}
+	return orderedResults
+}
+
+// FlushForSuspend syncs the file systems and flushes the CHAPI managed volumes before the host
+// suspends
+func (driver *ChapiServer) FlushForSuspend() {
+	log.Trace(">>>>> FlushForSuspend called")
+	defer log.Trace("<<<<< FlushForSuspend")
+
+	managedState, err := getPowerManagedState()
+	if err != nil {
+		log.Errorf("Unable to flush the managed volumes for suspend, err=%v", err)
+		return
+	}
+	log.Infof("Flushing %v managed volume(s) for suspend", len(managedState.Devices))
+
+	syncFileSystems()
+	runBounded(len(managedState.Devices), defaultDrainParallelism, func(i int) {
+		if err := flushForPowerEvent(managedState.Devices[i].SerialNumber); err != nil {
+			log.Errorf("Unable to flush volume %v for suspend, err=%v", managedState.Devices[i].SerialNumber, err)
+		}
+	})
+}
+
+// getQuiescedTargets returns the iSCSI targets of the managed devices whose volumes were all
+// quiesced without error
+func getQuiescedTargets(devices []*model.ManagedDevice, results map[string]*model.DrainResult) []string {
+	var targetNames []string
+	quiesced := make(map[string]bool)
+	for _, device := range devices {
+		if device.TargetName == "" {
+			continue
+		}
+		targetName := strings.ToLower(device.TargetName)
+		ok := results[device.SerialNumber].Error == ""
+		if wasQuiesced, found := quiesced[targetName]; found {
+			quiesced[targetName] = wasQuiesced && ok
+			continue
+		}
+		quiesced[targetName] = ok
+		targetNames = append(targetNames, device.TargetName)
+	}
+
+	var quiescedTargets []string
+	for _, targetName := range targetNames {
+		if quiesced[strings.ToLower(targetName)] {
+			quiescedTargets = append(quiescedTargets, targetName)
+		}
+	}
+	return quiescedTargets
+}
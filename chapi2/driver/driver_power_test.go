@@ -0,0 +1,114 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestQuiesceForShutdown(t *testing.T) {
+	defer func(getState func() (*model.ManagedState, error), unmount, flush, logout func(string) error) {
+		getPowerManagedState, unmountForShutdown, flushForPowerEvent, logoutForShutdown = getState, unmount, flush, logout
+	}(getPowerManagedState, unmountForShutdown, flushForPowerEvent, logoutForShutdown)
+
+	getPowerManagedState = func() (*model.ManagedState, error) {
+		return &model.ManagedState{
+			Devices: []*model.ManagedDevice{
+				{SerialNumber: "serial1", TargetName: "iqn.target1"},
+				{SerialNumber: "serial2", TargetName: "IQN.TARGET1"},
+				{SerialNumber: "serial3", TargetName: "iqn.target2"},
+				{SerialNumber: "serial4"},
+			},
+			Mounts: []*model.ManagedMount{
+				{ID: "1", SerialNumber: "serial1", MountPoint: "/mnt/vol1"},
+				{ID: "2", SerialNumber: "serial3", MountPoint: "/mnt/vol3"},
+			},
+		}, nil
+	}
+	var lock sync.Mutex
+	var unmounted, flushed, loggedOut []string
+	unmountForShutdown = func(mountPoint string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		unmounted = append(unmounted, mountPoint)
+		if mountPoint == "/mnt/vol3" {
+			return errors.New("device busy")
+		}
+		return nil
+	}
+	flushForPowerEvent = func(serialNumber string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		flushed = append(flushed, serialNumber)
+		return nil
+	}
+	logoutForShutdown = func(targetName string) error {
+		loggedOut = append(loggedOut, targetName)
+		return nil
+	}
+
+	results := (&ChapiServer{}).QuiesceForShutdown()
+	if len(results) != 4 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	sort.Strings(flushed)
+	if !reflect.DeepEqual(flushed, []string{"serial1", "serial2", "serial3", "serial4"}) {
+		t.Errorf("unexpected flushed devices %v", flushed)
+	}
+	for _, result := range results {
+		if !result.Flushed {
+			t.Errorf("volume %v not flushed", result.SerialNumber)
+		}
+	}
+
+	if !shutdownUnmountSupported {
+		if len(unmounted) != 0 || len(loggedOut) != 0 {
+			t.Errorf("unexpected unmounts %v or logouts %v", unmounted, loggedOut)
+		}
+		return
+	}
+	sort.Strings(unmounted)
+	if !reflect.DeepEqual(unmounted, []string{"/mnt/vol1", "/mnt/vol3"}) {
+		t.Errorf("unexpected unmounts %v", unmounted)
+	}
+	if !reflect.DeepEqual(results[0].Unmounted, []string{"/mnt/vol1"}) || results[2].Error != "device busy" {
+		t.Errorf("unexpected unmount results %+v, %+v", results[0], results[2])
+	}
+
+	// target2 keeps its sessions as its volume could not be unmounted
+	if shutdownLogoutSupported {
+		if !reflect.DeepEqual(loggedOut, []string{"iqn.target1"}) {
+			t.Errorf("unexpected logouts %v", loggedOut)
+		}
+		if !results[0].Detached || !results[1].Detached || results[2].Detached || results[3].Detached {
+			t.Errorf("unexpected detached results %+v %+v %+v %+v", results[0], results[1], results[2], results[3])
+		}
+	}
+}
+
+func TestGetQuiescedTargets(t *testing.T) {
+	devices := []*model.ManagedDevice{
+		{SerialNumber: "serial1", TargetName: "iqn.target1"},
+		{SerialNumber: "serial2", TargetName: "IQN.TARGET1"},
+		{SerialNumber: "serial3", TargetName: "iqn.target2"},
+		{SerialNumber: "serial4", TargetName: "iqn.target2"},
+		{SerialNumber: "serial5"},
+	}
+	results := map[string]*model.DrainResult{
+		"serial1": {SerialNumber: "serial1"},
+		"serial2": {SerialNumber: "serial2"},
+		"serial3": {SerialNumber: "serial3"},
+		"serial4": {SerialNumber: "serial4", Error: "flush failed"},
+		"serial5": {SerialNumber: "serial5"},
+	}
+	targets := getQuiescedTargets(devices, results)
+	if !reflect.DeepEqual(targets, []string{"iqn.target1"}) {
+		t.Errorf("getQuiescedTargets = %v, expected [iqn.target1]", targets)
+	}
+}
@@ -0,0 +1,292 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// NODE READINESS
+//
+//		GetReadiness consolidates the host checks needed before HPE storage can be used into a
+//		single report (e.g. for CSI node labeling and fleet dashboards).  Checks are grouped into
+//		categories:
+//
+//		- recommendations	Host settings compared with the HPE recommended values
+//		- services			Services required to attach volumes (e.g. iscsid, MSiSCSI)
+//		- multipath			Multipath configuration and claims
+//		- modules			Kernel modules (Linux) or drivers (Windows) required to attach volumes,
+//							and module versions known to affect storage (e.g. ALUA regressions)
+//		- connectivity		Initiators (and duplicate iSCSI initiator names), network interfaces,
+//							iSCSI targets and, if iscsi.PathMtuCheckEnv is "true", the path MTU of
+//							the logged in target portals
+//		- mounts			Managed mount points whose mount options drifted (e.g. remounted
+//							read-only after I/O errors)
+//
+//		Each check passes (score 100), warns (score 50) or fails (score 0).  A category's score is
+//		the average of its check scores and its status is the worst of its check statuses; the
+//		overall score and status are derived from the categories the same way.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/host"
+	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/mount"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// Readiness categories
+	readinessRecommendations = "recommendations"
+	readinessServices        = "services"
+	readinessMultipath       = "multipath"
+	readinessModules         = "modules"
+	readinessConnectivity    = "connectivity"
+	readinessMounts          = "mounts"
+
+	// Readiness scores
+	readinessScorePass = 100
+	readinessScoreWarn = 50
+	readinessScoreFail = 0
+)
+
+// readinessSeverity orders the readiness statuses from best to worst
+var readinessSeverity = map[string]int{
+	model.ReadinessPass: 0,
+	model.ReadinessWarn: 1,
+	model.ReadinessFail: 2,
+}
+
+// knownBadModuleVersion is a kernel module (or driver) version known to affect storage
+type knownBadModuleVersion struct {
+	module  string         // Module (or driver) name, e.g. "scsi_dh_alua"
+	version *regexp.Regexp // Matches the affected versions (the kernel release for in-tree modules)
+	status  string         // Status reported if the loaded module version matches
+	reason  string
+}
+
+var (
+	// readinessKnownBadVersions are the module versions flagged by the readiness checks, added as
+	// compatibility issues are qualified
+	readinessKnownBadVersions []knownBadModuleVersion
+
+	// getStorageModules returns the storage modules of this host; a variable so that tests can
+	// replace it
+	getStorageModules = func() ([]*model.StorageModule, error) {
+		return host.NewHostPlugin().GetStorageModules()
+	}
+
+	// checkSessionPathMtu validates the path MTU of the logged in target portals; a variable so
+	// that tests can replace it
+	checkSessionPathMtu = func() ([]*model.PathMtu, error) {
+		return iscsi.NewIscsiPlugin().CheckSessionPathMtu()
+	}
+)
+
+// GetReadiness reports how ready this host is to use HPE storage
+func (driver *ChapiServer) GetReadiness() (*model.Readiness, error) {
+	log.Trace(">>>>> GetReadiness called")
+	defer log.Trace("<<<<< GetReadiness")
+
+	log.Info("Get Readiness")
+
+	categories := []*model.ReadinessCategory{
+		newReadinessCategory(readinessRecommendations, getRecommendationsReadiness()),
+		newReadinessCategory(readinessServices, getServicesReadiness()),
+		newReadinessCategory(readinessMultipath, getMultipathReadiness()),
+		newReadinessCategory(readinessModules, append(getModulesReadiness(), getModuleVersionsReadiness()...)),
+		newReadinessCategory(readinessConnectivity, driver.getConnectivityReadiness()),
+		newReadinessCategory(readinessMounts, []*model.ReadinessCheck{getMountOptionsReadiness(driver.GetAllMountDetails("", ""))}),
+	}
+	readiness := newReadiness(categories)
+	log.Infof("Readiness status=%v, score=%v", readiness.Status, readiness.Score)
+	return readiness, nil
+}
+
+// newReadiness scores the given readiness categories
+func newReadiness(categories []*model.ReadinessCategory) *model.Readiness {
+	readiness := &model.Readiness{Status: model.ReadinessPass, Score: readinessScorePass, Categories: categories}
+	if len(categories) == 0 {
+		return readiness
+	}
+	total := 0
+	for _, category := range categories {
+		total += category.Score
+		readiness.Status = worseReadinessStatus(readiness.Status, category.Status)
+	}
+	readiness.Score = total / len(categories)
+	return readiness
+}
+
+// newReadinessCategory scores the given readiness checks
+func newReadinessCategory(name string, checks []*model.ReadinessCheck) *model.ReadinessCategory {
+	category := &model.ReadinessCategory{Name: name, Status: model.ReadinessPass, Score: readinessScorePass, Checks: checks}
+	if len(checks) == 0 {
+		return category
+	}
+	total := 0
+	for _, check := range checks {
+		switch check.Status {
+		case model.ReadinessPass:
+			total += readinessScorePass
+		case model.ReadinessWarn:
+			total += readinessScoreWarn
+		default:
+			total += readinessScoreFail
+		}
+		category.Status = worseReadinessStatus(category.Status, check.Status)
+	}
+	category.Score = total / len(checks)
+	return category
+}
+
+// worseReadinessStatus returns the worse of the two readiness statuses
+func worseReadinessStatus(status1, status2 string) string {
+	if readinessSeverity[status2] > readinessSeverity[status1] {
+		return status2
+	}
+	return status1
+}
+
+// newReadinessCheck returns a readiness check with the given outcome
+func newReadinessCheck(name string, status string, format string, a ...interface{}) *model.ReadinessCheck {
+	check := &model.ReadinessCheck{Name: name, Status: status}
+	if format != "" {
+		check.Message = fmt.Sprintf(format, a...)
+	}
+	return check
+}
+
+// getModuleVersionsReadiness checks the loaded storage modules against the known-bad versions, with
+// one check per loaded module
+func getModuleVersionsReadiness() []*model.ReadinessCheck {
+	modules, err := getStorageModules()
+	if err != nil {
+		return []*model.ReadinessCheck{newReadinessCheck("module_versions", model.ReadinessWarn, "unable to determine module versions, %v", err)}
+	}
+	var checks []*model.ReadinessCheck
+	for _, module := range modules {
+		if !module.Loaded {
+			continue
+		}
+		check := newReadinessCheck(module.Name+"_version", model.ReadinessPass, "version %v", module.Version)
+		for _, knownBad := range readinessKnownBadVersions {
+			if knownBad.module == module.Name && knownBad.version.MatchString(module.Version) {
+				check = newReadinessCheck(module.Name+"_version", knownBad.status, "version %v %v", module.Version, knownBad.reason)
+				break
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// getConnectivityReadiness checks the host initiators, network interfaces and iSCSI targets
+func (driver *ChapiServer) getConnectivityReadiness() []*model.ReadinessCheck {
+	var checks []*model.ReadinessCheck
+
+	// At least one iSCSI or FC initiator is required to attach volumes
+	if initiators, err := driver.GetHostInitiators(); err != nil {
+		checks = append(checks, newReadinessCheck("initiators", model.ReadinessFail, "%v", err))
+	} else {
+		var protocols []string
+		for _, initiator := range initiators {
+			protocols = append(protocols, initiator.AccessProtocol)
+		}
+		checks = append(checks, newReadinessCheck("initiators", model.ReadinessPass, "%v initiators found", strings.Join(protocols, ", ")))
+	}
+
+	// Hosts cloned with the same iSCSI initiator name steal each other's sessions
+	checks = append(checks, getIscsiInitiatorReadiness())
+
+	// At least one network interface must be up, with an IPv4 address, to reach iSCSI targets
+	networks, err := driver.GetHostNetworks()
+	if err != nil {
+		checks = append(checks, newReadinessCheck("networks", model.ReadinessFail, "%v", err))
+	} else {
+		var upNetworks []string
+		for _, network := range networks {
+			if network.Up && network.AddressV4 != "" {
+				upNetworks = append(upNetworks, network.Name)
+			}
+		}
+		if len(upNetworks) == 0 {
+			checks = append(checks, newReadinessCheck("networks", model.ReadinessFail, "no network interfaces are up with an IPv4 address"))
+		} else {
+			checks = append(checks, newReadinessCheck("networks", model.ReadinessPass, ""))
+		}
+	}
+
+	// Configured iSCSI targets without a session will not have their volumes available
+	if targets, err := driver.GetUnconnectedTargets(); err != nil {
+		checks = append(checks, newReadinessCheck("iscsi_targets", model.ReadinessWarn, "unable to enumerate unconnected targets, %v", err))
+	} else if len(targets) != 0 {
+		var names []string
+		for _, target := range targets {
+			names = append(names, target.Name)
+		}
+		checks = append(checks, newReadinessCheck("iscsi_targets", model.ReadinessWarn, "%v configured iSCSI target(s) not connected: %v", len(targets), strings.Join(names, ", ")))
+	} else {
+		checks = append(checks, newReadinessCheck("iscsi_targets", model.ReadinessPass, ""))
+	}
+
+	// Jumbo frames dropped on the path to a target portal reset the iSCSI connections under load
+	if iscsi.IsPathMtuCheckEnabled() {
+		checks = append(checks, getPathMtuReadiness(checkSessionPathMtu()))
+	}
+
+	return checks
+}
+
+// getPathMtuReadiness checks the path MTU of the logged in target portals.  A path MTU smaller
+// than the NIC MTU warns.
+func getPathMtuReadiness(results []*model.PathMtu, err error) *model.ReadinessCheck {
+	if err != nil {
+		return newReadinessCheck("path_mtu", model.ReadinessWarn, "unable to validate the path MTU, %v", err)
+	}
+	var mismatched []string
+	for _, result := range results {
+		if result.Status == model.PathMtuMismatch {
+			mismatched = append(mismatched, fmt.Sprintf("%v (%v) to %v: path MTU %v, NIC MTU %v",
+				result.InitiatorAddress, result.InitiatorName, result.TargetAddress, result.PathMtu, result.InterfaceMtu))
+		}
+	}
+	if len(mismatched) == 0 {
+		return newReadinessCheck("path_mtu", model.ReadinessPass, "%v path(s) validated", len(results))
+	}
+	return newReadinessCheck("path_mtu", model.ReadinessWarn, "%v path(s) with a path MTU smaller than the NIC MTU: %v", len(mismatched), strings.Join(mismatched, ", "))
+}
+
+// getMountOptionsReadiness checks the mount options drift of the given mount points.  A mount
+// point that became read-only fails the check, any other drift warns.
+func getMountOptionsReadiness(mounts []*model.Mount, err error) *model.ReadinessCheck {
+	if err != nil {
+		if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.NotFound {
+			return newReadinessCheck("mount_options", model.ReadinessPass, "no mount points found")
+		}
+		return newReadinessCheck("mount_options", model.ReadinessWarn, "unable to enumerate mount points, %v", err)
+	}
+
+	status := model.ReadinessPass
+	var drifted []string
+	for _, enumeratedMount := range mounts {
+		if enumeratedMount.Status != model.MountStatusDrifted {
+			continue
+		}
+		drifted = append(drifted, fmt.Sprintf("%v (%v)", enumeratedMount.MountPoint, strings.Join(enumeratedMount.Drift, "; ")))
+		if mount.IsReadOnlyDrift(enumeratedMount) {
+			status = model.ReadinessFail
+		} else {
+			status = worseReadinessStatus(status, model.ReadinessWarn)
+		}
+	}
+	if len(drifted) == 0 {
+		return newReadinessCheck("mount_options", model.ReadinessPass, "")
+	}
+	return newReadinessCheck("mount_options", status, "%v mount point(s) with drifted mount options: %v", len(drifted), strings.Join(drifted, ", "))
+}
@@ -0,0 +1,156 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/tunelinux"
+	"github.com/hpe-storage/common-host-libs/util"
+)
+
+var (
+	// Paths checked for readiness; variables so that tests can redirect them
+	readinessModulePath     = "/sys/module"
+	readinessMultipathConf  = "/etc/multipath.conf"
+	readinessStorageVendors = []string{"Nimble", "3PARdata"}
+)
+
+// readinessService is a service required to attach volumes
+type readinessService struct {
+	name         string
+	failStatus   string // Status reported if the service is not active
+	failedReason string
+}
+
+// readinessModule is a kernel module required to attach volumes
+type readinessModule struct {
+	name       string
+	failStatus string // Status reported if the module is not loaded
+}
+
+var (
+	readinessServicesRequired = []readinessService{
+		{"multipathd", model.ReadinessFail, "multipath devices cannot be created"},
+		{"iscsid", model.ReadinessWarn, "iSCSI volumes cannot be attached"},
+	}
+	readinessModulesRequired = []readinessModule{
+		{"dm_multipath", model.ReadinessFail},
+		{"scsi_dh_alua", model.ReadinessWarn},
+		{"iscsi_tcp", model.ReadinessWarn},
+	}
+)
+
+// getRecommendationsReadiness compares the host settings with the recommended values, with one
+// check per recommendation category (e.g. "multipath", "iscsi")
+func getRecommendationsReadiness() []*model.ReadinessCheck {
+	recommendations, err := tunelinux.GetRecommendations()
+	if err != nil {
+		return []*model.ReadinessCheck{newReadinessCheck("settings", model.ReadinessWarn, "unable to determine recommendations, %v", err)}
+	}
+	return recommendationChecks(recommendations)
+}
+
+// recommendationChecks converts the recommendations into one readiness check per category.  A
+// category warns if any of its settings is not recommended, and fails if any such setting has a
+// critical or error severity.
+func recommendationChecks(recommendations []*tunelinux.Recommendation) []*model.ReadinessCheck {
+	checks := make(map[string]*model.ReadinessCheck)
+	total := make(map[string]int)
+	notRecommended := make(map[string][]string)
+	for _, recommendation := range recommendations {
+		check := checks[recommendation.Category]
+		if check == nil {
+			check = newReadinessCheck(recommendation.Category, model.ReadinessPass, "")
+			checks[recommendation.Category] = check
+		}
+		total[recommendation.Category]++
+		if recommendation.CompliantStatus == tunelinux.Recommended.String() {
+			continue
+		}
+		notRecommended[recommendation.Category] = append(notRecommended[recommendation.Category], recommendation.Parameter)
+		status := model.ReadinessWarn
+		if recommendation.Level == tunelinux.Critical.String() || recommendation.Level == tunelinux.Error.String() {
+			status = model.ReadinessFail
+		}
+		check.Status = worseReadinessStatus(check.Status, status)
+	}
+
+	var categories []string
+	for category := range checks {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var readinessChecks []*model.ReadinessCheck
+	for _, category := range categories {
+		check := checks[category]
+		if parameters := notRecommended[category]; len(parameters) != 0 {
+			check.Message = fmt.Sprintf("%v of %v settings not recommended: %v", len(parameters), total[category], strings.Join(parameters, ", "))
+		}
+		readinessChecks = append(readinessChecks, check)
+	}
+	return readinessChecks
+}
+
+// getServicesReadiness checks the required services are active
+func getServicesReadiness() []*model.ReadinessCheck {
+	var checks []*model.ReadinessCheck
+	for _, service := range readinessServicesRequired {
+		out, _, err := util.ExecCommandOutput("systemctl", []string{"is-active", service.name})
+		state := strings.TrimSpace(out)
+		switch {
+		case state == "active":
+			checks = append(checks, newReadinessCheck(service.name, model.ReadinessPass, ""))
+		case state == "" && err != nil:
+			checks = append(checks, newReadinessCheck(service.name, model.ReadinessWarn, "unable to determine service state, %v", err))
+		default:
+			checks = append(checks, newReadinessCheck(service.name, service.failStatus, "service is %v, %v", state, service.failedReason))
+		}
+	}
+	return checks
+}
+
+// getMultipathReadiness checks multipath is configured for HPE storage
+func getMultipathReadiness() []*model.ReadinessCheck {
+	data, err := ioutil.ReadFile(readinessMultipathConf)
+	if err != nil {
+		return []*model.ReadinessCheck{newReadinessCheck("multipath_conf", model.ReadinessFail, "unable to read %v, %v", readinessMultipathConf, err)}
+	}
+	checks := []*model.ReadinessCheck{newReadinessCheck("multipath_conf", model.ReadinessPass, "")}
+
+	// HPE storage should have a device section so that the recommended path settings are used
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "vendor" {
+			continue
+		}
+		vendor := strings.Trim(fields[1], `"`)
+		for _, storageVendor := range readinessStorageVendors {
+			if strings.EqualFold(vendor, storageVendor) {
+				return append(checks, newReadinessCheck("device_section", model.ReadinessPass, ""))
+			}
+		}
+	}
+	return append(checks, newReadinessCheck("device_section", model.ReadinessWarn, "no device section for %v in %v",
+		strings.Join(readinessStorageVendors, " or "), readinessMultipathConf))
+}
+
+// getModulesReadiness checks the required kernel modules are loaded (or built into the kernel)
+func getModulesReadiness() []*model.ReadinessCheck {
+	var checks []*model.ReadinessCheck
+	for _, module := range readinessModulesRequired {
+		if _, err := os.Stat(filepath.Join(readinessModulePath, module.name)); err != nil {
+			checks = append(checks, newReadinessCheck(module.name, module.failStatus, "kernel module not loaded"))
+		} else {
+			checks = append(checks, newReadinessCheck(module.name, model.ReadinessPass, ""))
+		}
+	}
+	return checks
+}
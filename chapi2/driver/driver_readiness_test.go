@@ -0,0 +1,124 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestReadinessScoring(t *testing.T) {
+	services := newReadinessCategory(readinessServices, []*model.ReadinessCheck{
+		newReadinessCheck("multipathd", model.ReadinessPass, ""),
+		newReadinessCheck("iscsid", model.ReadinessWarn, "service is inactive"),
+	})
+	if services.Status != model.ReadinessWarn || services.Score != 75 {
+		t.Errorf("unexpected services category %+v", services)
+	}
+
+	modules := newReadinessCategory(readinessModules, []*model.ReadinessCheck{
+		newReadinessCheck("dm_multipath", model.ReadinessFail, "kernel module not loaded"),
+		newReadinessCheck("scsi_dh_alua", model.ReadinessWarn, "kernel module not loaded"),
+	})
+	if modules.Status != model.ReadinessFail || modules.Score != 25 {
+		t.Errorf("unexpected modules category %+v", modules)
+	}
+
+	// A category without checks passes
+	empty := newReadinessCategory(readinessMultipath, nil)
+	if empty.Status != model.ReadinessPass || empty.Score != readinessScorePass {
+		t.Errorf("unexpected empty category %+v", empty)
+	}
+
+	readiness := newReadiness([]*model.ReadinessCategory{services, modules, empty})
+	if readiness.Status != model.ReadinessFail || readiness.Score != 66 {
+		t.Errorf("unexpected readiness status=%v, score=%v", readiness.Status, readiness.Score)
+	}
+}
+
+func TestModuleVersionsReadiness(t *testing.T) {
+	savedGetStorageModules, savedKnownBad := getStorageModules, readinessKnownBadVersions
+	defer func() { getStorageModules, readinessKnownBadVersions = savedGetStorageModules, savedKnownBad }()
+
+	getStorageModules = func() ([]*model.StorageModule, error) {
+		return []*model.StorageModule{
+			{Name: "dm_multipath", Version: "4.18.0-80.el8.x86_64", Loaded: true},
+			{Name: "scsi_dh_alua", Version: "4.18.0-80.el8.x86_64", Loaded: true},
+			{Name: "nvme_core", Loaded: false},
+		}, nil
+	}
+	readinessKnownBadVersions = []knownBadModuleVersion{
+		{"scsi_dh_alua", regexp.MustCompile(`^4\.18\.0-80\.`), model.ReadinessWarn, "is known to mishandle ALUA transitions"},
+		{"dm_multipath", regexp.MustCompile(`^3\.10\.`), model.ReadinessFail, "is not supported"},
+	}
+
+	checks := getModuleVersionsReadiness()
+	if len(checks) != 2 {
+		t.Fatalf("expected 2 checks, got %v", len(checks))
+	}
+	if checks[0].Name != "dm_multipath_version" || checks[0].Status != model.ReadinessPass {
+		t.Errorf("unexpected check %+v", checks[0])
+	}
+	if checks[1].Name != "scsi_dh_alua_version" || checks[1].Status != model.ReadinessWarn ||
+		checks[1].Message != "version 4.18.0-80.el8.x86_64 is known to mishandle ALUA transitions" {
+		t.Errorf("unexpected check %+v", checks[1])
+	}
+
+	// Modules that cannot be enumerated warn
+	getStorageModules = func() ([]*model.StorageModule, error) { return nil, errors.New("no sysfs") }
+	checks = getModuleVersionsReadiness()
+	if len(checks) != 1 || checks[0].Status != model.ReadinessWarn {
+		t.Errorf("unexpected checks %+v", checks)
+	}
+}
+
+func TestMountOptionsReadiness(t *testing.T) {
+	consistent := &model.Mount{MountPoint: "/mnt/vol1", Status: model.MountStatusConsistent, FsOpts: &model.FileSystemOptions{MountOpts: []string{"rw"}}}
+	atime := &model.Mount{MountPoint: "/mnt/vol2", Status: model.MountStatusDrifted, Drift: []string{"relatime in effect, noatime expected"}, FsOpts: &model.FileSystemOptions{MountOpts: []string{"rw", "relatime"}}}
+	readOnly := &model.Mount{MountPoint: "/mnt/vol3", Status: model.MountStatusDrifted, Drift: []string{"ro in effect, rw expected"}, FsOpts: &model.FileSystemOptions{MountOpts: []string{"ro"}}}
+
+	testCases := []struct {
+		name     string
+		mounts   []*model.Mount
+		err      error
+		expected string
+	}{
+		{"consistent", []*model.Mount{consistent, {MountPoint: "/mnt/unmanaged"}}, nil, model.ReadinessPass},
+		{"drifted", []*model.Mount{consistent, atime}, nil, model.ReadinessWarn},
+		{"read-only", []*model.Mount{readOnly, atime}, nil, model.ReadinessFail},
+		{"no mounts", nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageNoMountPointsFound), model.ReadinessPass},
+		{"failure", nil, errors.New("enumeration failure"), model.ReadinessWarn},
+	}
+	for _, tc := range testCases {
+		if check := getMountOptionsReadiness(tc.mounts, tc.err); check.Status != tc.expected {
+			t.Errorf("%v: expected %v, got %+v", tc.name, tc.expected, check)
+		}
+	}
+}
+
+func TestPathMtuReadiness(t *testing.T) {
+	match := &model.PathMtu{InitiatorAddress: "10.1.1.5", TargetAddress: "10.1.1.20", InterfaceMtu: 9000, PathMtu: 9000, Status: model.PathMtuMatch}
+	unreachable := &model.PathMtu{InitiatorAddress: "10.1.1.5", TargetAddress: "10.1.1.22", InterfaceMtu: 9000, Status: model.PathMtuUnreachable}
+	mismatch := &model.PathMtu{InitiatorAddress: "10.1.1.5", TargetAddress: "10.1.1.21", InterfaceMtu: 9000, PathMtu: 1500, Status: model.PathMtuMismatch}
+
+	testCases := []struct {
+		name     string
+		results  []*model.PathMtu
+		err      error
+		expected string
+	}{
+		{"match", []*model.PathMtu{match, unreachable}, nil, model.ReadinessPass},
+		{"no sessions", nil, nil, model.ReadinessPass},
+		{"mismatch", []*model.PathMtu{match, mismatch}, nil, model.ReadinessWarn},
+		{"failure", nil, errors.New("enumeration failure"), model.ReadinessWarn},
+	}
+	for _, tc := range testCases {
+		if check := getPathMtuReadiness(tc.results, tc.err); check.Status != tc.expected {
+			t.Errorf("%v: expected %v, got %+v", tc.name, tc.expected, check)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	// Registry keys and values checked for readiness
+	regKeyDiskService          = `SYSTEM\CurrentControlSet\Services\disk`
+	regValueDiskTimeout        = "TimeOutValue"
+	regKeyMsdsmParameters      = `SYSTEM\CurrentControlSet\Services\msdsm\Parameters`
+	regValueMsdsmSupportedList = "DsmSupportedDeviceList"
+
+	// Recommended minimum disk timeout, in seconds
+	recommendedDiskTimeout = 60
+
+	// Vendor prefix of the MSDSM supported device list entry for HPE Nimble storage
+	msdsmNimbleDevice = "Nimble"
+)
+
+// getRecommendationsReadiness compares the host settings with the recommended values
+func getRecommendationsReadiness() []*model.ReadinessCheck {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, regKeyDiskService, registry.QUERY_VALUE)
+	if err != nil {
+		return []*model.ReadinessCheck{newReadinessCheck("disk_timeout", model.ReadinessWarn, "unable to open disk service key, %v", err)}
+	}
+	defer k.Close()
+
+	timeout, _, err := k.GetIntegerValue(regValueDiskTimeout)
+	if err != nil {
+		return []*model.ReadinessCheck{newReadinessCheck("disk_timeout", model.ReadinessWarn, "%v not set, recommended value is %v", regValueDiskTimeout, recommendedDiskTimeout)}
+	}
+	if timeout < recommendedDiskTimeout {
+		return []*model.ReadinessCheck{newReadinessCheck("disk_timeout", model.ReadinessWarn, "%v is %v, recommended value is %v", regValueDiskTimeout, timeout, recommendedDiskTimeout)}
+	}
+	return []*model.ReadinessCheck{newReadinessCheck("disk_timeout", model.ReadinessPass, "")}
+}
+
+// getServicesReadiness checks the required services are running
+func getServicesReadiness() []*model.ReadinessCheck {
+	return []*model.ReadinessCheck{serviceReadinessCheck("MSiSCSI", model.ReadinessWarn)}
+}
+
+// getMultipathReadiness checks MPIO is installed and claims HPE storage
+func getMultipathReadiness() []*model.ReadinessCheck {
+	checks := []*model.ReadinessCheck{serviceReadinessCheck("mpio", model.ReadinessFail)}
+
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, regKeyMsdsmParameters, registry.QUERY_VALUE)
+	if err != nil {
+		return append(checks, newReadinessCheck("mpio_claim", model.ReadinessFail, "unable to open MSDSM parameters key, %v", err))
+	}
+	defer k.Close()
+
+	devices, _, err := k.GetStringsValue(regValueMsdsmSupportedList)
+	if err != nil {
+		return append(checks, newReadinessCheck("mpio_claim", model.ReadinessFail, "unable to read %v, %v", regValueMsdsmSupportedList, err))
+	}
+	for _, device := range devices {
+		if strings.HasPrefix(strings.ToLower(device), strings.ToLower(msdsmNimbleDevice)) {
+			return append(checks, newReadinessCheck("mpio_claim", model.ReadinessPass, ""))
+		}
+	}
+	return append(checks, newReadinessCheck("mpio_claim", model.ReadinessFail, "MPIO does not claim %v devices", msdsmNimbleDevice))
+}
+
+// getModulesReadiness checks the required drivers are running
+func getModulesReadiness() []*model.ReadinessCheck {
+	return []*model.ReadinessCheck{
+		serviceReadinessCheck("mpio", model.ReadinessFail),
+		serviceReadinessCheck("msdsm", model.ReadinessFail),
+	}
+}
+
+// serviceReadinessCheck checks the given service (or driver) is running, returning failStatus if not
+func serviceReadinessCheck(name string, failStatus string) *model.ReadinessCheck {
+	m, err := mgr.Connect()
+	if err != nil {
+		return newReadinessCheck(name, model.ReadinessWarn, "unable to connect to service manager, %v", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return newReadinessCheck(name, failStatus, "service not installed, %v", err)
+	}
+	defer s.Close()
+
+	status, err := s.Query()
+	if err != nil {
+		return newReadinessCheck(name, model.ReadinessWarn, "unable to query service state, %v", err)
+	}
+	if status.State != svc.Running {
+		return newReadinessCheck(name, failStatus, "service is not running")
+	}
+	return newReadinessCheck(name, model.ReadinessPass, "")
+}
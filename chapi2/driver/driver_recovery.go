@@ -0,0 +1,123 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// PATH RECOVERY
+//
+//		After an array controller failover (takeover or giveback), multipathd may leave some paths
+//		failed even though the controller behind them is back, until they are manually reinstated
+//		(multipathd reinstate path).  Path recovery probes each failed path of a device with TEST
+//		UNIT READY, then a direct read of its first block, and reinstates the paths that respond
+//		to both.  It is run on demand for a single device
+//		("PUT /api/v1/devices/{serialNumber}/actions/recover-paths") or, if PathRecoveryIntervalEnv
+//		is set, periodically for every device by the path recovery monitor.  A recovery report is
+//		logged for every device with failed paths.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/multipath"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// PathRecoveryIntervalEnv enables the path recovery monitor, recovering the failed paths of
+	// every device at the given interval (in seconds)
+	PathRecoveryIntervalEnv = config.PathRecoveryIntervalEnv
+)
+
+// RecoverDevicePaths reinstates the failed paths, that are reachable again, of the device with the
+// given serial number
+func (driver *ChapiServer) RecoverDevicePaths(serialNumber string) (*model.PathRecoveryReport, error) {
+	log.Tracef(">>>>> RecoverDevicePaths called, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< RecoverDevicePaths")
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	log.Infof("Recover Device Paths, serialNumber=%v", serialNumber)
+
+	// Enumerate full details for the serial number (the paths are found from the device)
+	device, err := driver.getSingleDeviceDetails(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := multipathPlugin.RecoverDevicePaths(*device)
+	if err != nil {
+		return nil, err
+	}
+	logPathRecoveryReport(report)
+	return report, nil
+}
+
+// RecoverAllDevicePaths reinstates the failed paths, that are reachable again, of every device and
+// returns the recovery reports of the devices with failed paths
+func (driver *ChapiServer) RecoverAllDevicePaths() ([]*model.PathRecoveryReport, error) {
+	log.Trace(">>>>> RecoverAllDevicePaths called")
+	defer log.Trace("<<<<< RecoverAllDevicePaths")
+	multipathPlugin := multipath.NewMultipathPlugin()
+
+	devices, err := multipathPlugin.GetAllDeviceDetails("")
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*model.PathRecoveryReport
+	for _, device := range devices {
+		report, err := multipathPlugin.RecoverDevicePaths(*device)
+		if err != nil {
+			log.Errorf("Unable to recover the paths of device %v, err=%v", device.SerialNumber, err)
+			continue
+		}
+		if len(report.Paths) != 0 {
+			logPathRecoveryReport(report)
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// StartPathRecoveryMonitor starts the path recovery monitor if PathRecoveryIntervalEnv is set
+func StartPathRecoveryMonitor() {
+	interval := getPathRecoveryInterval()
+	if interval == 0 {
+		return
+	}
+	log.Infof("Recovering failed device paths every %v", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := (&ChapiServer{}).RecoverAllDevicePaths(); err != nil {
+				log.Errorf("Unable to recover failed device paths, err=%v", err)
+			}
+		}
+	}()
+}
+
+// getPathRecoveryInterval returns the path recovery monitor interval, or 0 if PathRecoveryIntervalEnv
+// is not set or invalid
+func getPathRecoveryInterval() time.Duration {
+	return config.Seconds(PathRecoveryIntervalEnv, 0, 1)
+}
+
+// logPathRecoveryReport logs the outcome of every failed path of the device
+func logPathRecoveryReport(report *model.PathRecoveryReport) {
+	if len(report.Paths) == 0 {
+		log.Infof("Device %v has no failed paths", report.SerialNumber)
+		return
+	}
+	log.Infof("Device %v path recovery, reinstated=%v, failed=%v", report.SerialNumber, report.Recovered, report.Failed)
+	for _, path := range report.Paths {
+		if path.Error != "" {
+			log.Warnf("Device %v path %v %v, err=%v", report.SerialNumber, path.Name, path.Status, path.Error)
+		} else {
+			log.Infof("Device %v path %v %v", report.SerialNumber, path.Name, path.Status)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetPathRecoveryInterval(t *testing.T) {
+	savedValue, saved := os.LookupEnv(PathRecoveryIntervalEnv)
+	defer func() {
+		if saved {
+			os.Setenv(PathRecoveryIntervalEnv, savedValue)
+		} else {
+			os.Unsetenv(PathRecoveryIntervalEnv)
+		}
+	}()
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"60", time.Minute},
+		{"0", 0},
+		{"-5", 0},
+		{"1m", 0},
+	}
+	for _, tc := range tests {
+		os.Setenv(PathRecoveryIntervalEnv, tc.value)
+		if interval := getPathRecoveryInterval(); interval != tc.expected {
+			t.Errorf("getPathRecoveryInterval(%q) = %v, expected %v", tc.value, interval, tc.expected)
+		}
+	}
+}
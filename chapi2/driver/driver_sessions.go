@@ -0,0 +1,62 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/multipath"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// CollectStaleSessions logs out the iSCSI targets whose sessions have reported no LUN for longer
+// than the stale session age (see STALE SESSION COLLECTION in the iscsi package).  Every target
+// without a LUN is returned, along with whether it was logged out.
+func (driver *ChapiServer) CollectStaleSessions(collection *model.StaleSessionCollection) ([]*model.StaleTarget, error) {
+	log.Tracef(">>>>> CollectStaleSessions called, targets=%v, dryRun=%v", collection.Targets, collection.DryRun)
+	defer log.Trace("<<<<< CollectStaleSessions")
+
+	log.Infof("Collect Stale Sessions, targets=%v, dryRun=%v", collection.Targets, collection.DryRun)
+
+	return iscsi.NewIscsiPlugin().CollectStaleSessions(collection)
+}
+
+// InvalidateLunMaps drops the cached LUN maps of the given targets, or every cached LUN map (see
+// GROUP SCOPED TARGET LUN MAP in the multipath package).  The targets whose map was dropped are
+// returned.
+func (driver *ChapiServer) InvalidateLunMaps(invalidation *model.LunMapInvalidation) ([]string, error) {
+	log.Tracef(">>>>> InvalidateLunMaps called, targets=%v", invalidation.Targets)
+	defer log.Trace("<<<<< InvalidateLunMaps")
+
+	log.Infof("Invalidate LUN Maps, targets=%v", invalidation.Targets)
+
+	return multipath.NewMultipathPlugin().InvalidateLunMaps(invalidation.Targets), nil
+}
+
+// StartStaleSessionCollector starts the stale session collector if iscsi.StaleSessionIntervalEnv
+// is set
+func StartStaleSessionCollector() {
+	interval := getStaleSessionInterval()
+	if interval == 0 {
+		return
+	}
+	log.Infof("Collecting stale iSCSI sessions every %v", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := (&ChapiServer{}).CollectStaleSessions(&model.StaleSessionCollection{}); err != nil {
+				log.Errorf("Unable to collect stale iSCSI sessions, err=%v", err)
+			}
+		}
+	}()
+}
+
+// getStaleSessionInterval returns the stale session collector interval, or 0 if
+// iscsi.StaleSessionIntervalEnv is not set or invalid
+func getStaleSessionInterval() time.Duration {
+	return config.Seconds(iscsi.StaleSessionIntervalEnv, 0, 1)
+}
@@ -0,0 +1,127 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/mount"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// StageMount mounts the given volume at its staging path, once per volume (see STAGED AND
+// PUBLISHED MOUNTS in the mount package).  The staged mount point is returned with the target
+// paths it is published to as its bind mounts.
+func (driver *ChapiServer) StageMount(request *model.StagedMountRequest) (stagedMount *model.Mount, err error) {
+	log.Tracef(">>>>> StageMount called, serialNumber=%v, stagingPath=%v, fsOptions=%v", request.SerialNumber, request.StagingPath, request.FsOpts)
+	defer log.Trace("<<<<< StageMount")
+	defer bumpCacheGeneration(&err)
+	defer func() { notifyEvent(EventCreateMount, request.SerialNumber, "", stagedMount, err) }()
+
+	log.Infof("Stage Mount, serialNumber=%v, stagingPath=%v", request.SerialNumber, request.StagingPath)
+
+	// Verify the file system UUID recorded for the device unless the caller provided one
+	fsOptions := withManagedFileSystem(request.SerialNumber, request.FsOpts)
+
+	// Route request to the mount package to stage the volume
+	mountPlugin := mount.NewMounter()
+	stagedMount, err = mountPlugin.StageMount(request.SerialNumber, request.StagingPath, fsOptions)
+	if err != nil {
+		return nil, err
+	}
+	var mountOptions []string
+	if fsOptions != nil {
+		mountOptions = fsOptions.MountOpts
+	}
+	recordManagedMount(stagedMount, mountOptions)
+
+	driver.logMount(stagedMount)
+	return stagedMount, nil
+}
+
+// PublishMount bind mounts the given volume's staging path to the given target path
+func (driver *ChapiServer) PublishMount(request *model.StagedMountRequest) (publishedMount *model.Mount, err error) {
+	log.Tracef(">>>>> PublishMount called, serialNumber=%v, stagingPath=%v, targetPath=%v, readOnly=%v", request.SerialNumber, request.StagingPath, request.TargetPath, request.ReadOnly)
+	defer log.Trace("<<<<< PublishMount")
+	defer bumpCacheGeneration(&err)
+	defer func() { notifyEvent(EventCreateMount, request.SerialNumber, "", publishedMount, err) }()
+
+	log.Infof("Publish Mount, serialNumber=%v, stagingPath=%v, targetPath=%v, readOnly=%v", request.SerialNumber, request.StagingPath, request.TargetPath, request.ReadOnly)
+
+	// Route request to the mount package to publish the volume
+	mountPlugin := mount.NewMounter()
+	publishedMount, err = mountPlugin.PublishMount(request.SerialNumber, request.StagingPath, request.TargetPath, request.ReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	var mountOptions []string
+	if publishedMount.FsOpts != nil {
+		mountOptions = publishedMount.FsOpts.MountOpts
+	}
+	recordManagedMount(publishedMount, mountOptions)
+
+	driver.logMount(publishedMount)
+	return publishedMount, nil
+}
+
+// UnpublishMount removes the given volume's bind mount at the given target path.  A target path
+// the volume is not published at is not an error.
+func (driver *ChapiServer) UnpublishMount(request *model.StagedMountRequest) (err error) {
+	log.Tracef(">>>>> UnpublishMount called, serialNumber=%v, targetPath=%v", request.SerialNumber, request.TargetPath)
+	defer log.Trace("<<<<< UnpublishMount")
+	defer bumpCacheGeneration(&err)
+
+	// Hooks are only notified if a mount point was removed, or the request failed
+	var unpublishedMount *model.Mount
+	defer func() {
+		if unpublishedMount != nil {
+			notifyEvent(EventDeleteMount, request.SerialNumber, unpublishedMount.ID, nil, nil)
+		} else if err != nil {
+			notifyEvent(EventDeleteMount, request.SerialNumber, "", nil, err)
+		}
+	}()
+
+	log.Infof("Unpublish Mount, serialNumber=%v, targetPath=%v", request.SerialNumber, request.TargetPath)
+
+	// Route request to the mount package to unpublish the volume
+	mountPlugin := mount.NewMounter()
+	unpublishedMount, err = mountPlugin.UnpublishMount(request.SerialNumber, request.TargetPath)
+	if err != nil || unpublishedMount == nil {
+		return err
+	}
+	forgetManagedMount(unpublishedMount.ID)
+
+	log.Infof("Volume %v unpublished from %v", request.SerialNumber, unpublishedMount.MountPoint)
+	return nil
+}
+
+// UnstageMount unmounts the given volume from its staging path once it is no longer published.
+// If it is still published, a cerrors.Busy error is returned with the target paths as details.  A
+// staging path the volume is not staged at is not an error.
+func (driver *ChapiServer) UnstageMount(request *model.StagedMountRequest) (err error) {
+	log.Tracef(">>>>> UnstageMount called, serialNumber=%v, stagingPath=%v", request.SerialNumber, request.StagingPath)
+	defer log.Trace("<<<<< UnstageMount")
+	defer bumpCacheGeneration(&err)
+
+	// Hooks are only notified if a mount point was removed, or the request failed
+	var unstagedMount *model.Mount
+	defer func() {
+		if unstagedMount != nil {
+			notifyEvent(EventDeleteMount, request.SerialNumber, unstagedMount.ID, nil, nil)
+		} else if err != nil {
+			notifyEvent(EventDeleteMount, request.SerialNumber, "", nil, err)
+		}
+	}()
+
+	log.Infof("Unstage Mount, serialNumber=%v, stagingPath=%v", request.SerialNumber, request.StagingPath)
+
+	// Route request to the mount package to unstage the volume
+	mountPlugin := mount.NewMounter()
+	unstagedMount, err = mountPlugin.UnstageMount(request.SerialNumber, request.StagingPath)
+	if err != nil || unstagedMount == nil {
+		return err
+	}
+	forgetManagedMount(unstagedMount.ID)
+
+	log.Infof("Volume %v unstaged from %v", request.SerialNumber, unstagedMount.MountPoint)
+	return nil
+}
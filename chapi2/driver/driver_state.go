@@ -0,0 +1,201 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/mount"
+	"github.com/hpe-storage/common-host-libs/chapi2/multipath"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// GetManagedState reports the devices and mount points created through CHAPI, and whether each is
+// still present on this host
+func (driver *ChapiServer) GetManagedState() (*model.ManagedState, error) {
+	log.Trace(">>>>> GetManagedState called")
+	defer log.Trace("<<<<< GetManagedState")
+
+	log.Info("Get Managed State")
+
+	return driver.reconcileManagedState(false)
+}
+
+// ReconcileManagedState removes the state store records of the devices and mount points that are
+// no longer present on this host (e.g. removed outside of CHAPI).  The reconciled state is
+// returned with the removed records marked as pruned.
+func (driver *ChapiServer) ReconcileManagedState() (*model.ManagedState, error) {
+	log.Trace(">>>>> ReconcileManagedState called")
+	defer log.Trace("<<<<< ReconcileManagedState")
+
+	log.Info("Reconcile Managed State")
+
+	return driver.reconcileManagedState(true)
+}
+
+// reconcileManagedState compares the state store records with the devices and mount points
+// enumerated on this host.  If prune is true, the records of the missing objects are removed.
+func (driver *ChapiServer) reconcileManagedState(prune bool) (*model.ManagedState, error) {
+	managedState, err := state.GetState()
+	if err != nil {
+		return nil, err
+	}
+
+	// Enumerate the devices and mount points currently on the host
+	devices, err := multipath.NewMultipathPlugin().GetDevices("")
+	if err != nil {
+		return nil, err
+	}
+	presentDevices := make(map[string]bool)
+	for _, device := range devices {
+		presentDevices[device.SerialNumber] = true
+	}
+	mounts, err := mount.NewMounter().GetAllMountDetails("", "")
+	if err != nil {
+		return nil, err
+	}
+	presentMounts := make(map[string]bool)
+	for _, enumeratedMount := range mounts {
+		presentMounts[enumeratedMount.ID] = true
+	}
+
+	for _, device := range managedState.Devices {
+		device.Status = model.ManagedStatusPresent
+		if !presentDevices[device.SerialNumber] {
+			device.Status = driver.missingManagedObject(prune, func() error { return state.RemoveDevice(device.SerialNumber) })
+		}
+	}
+	for _, managedMount := range managedState.Mounts {
+		managedMount.Status = model.ManagedStatusPresent
+		if !presentMounts[managedMount.ID] {
+			managedMount.Status = driver.missingManagedObject(prune, func() error { return state.RemoveMount(managedMount.ID) })
+		}
+	}
+	return managedState, nil
+}
+
+// missingManagedObject returns the status of a missing object, removing its record if requested
+func (driver *ChapiServer) missingManagedObject(prune bool, remove func() error) string {
+	if !prune {
+		return model.ManagedStatusMissing
+	}
+	if err := remove(); err != nil {
+		log.Errorf("Unable to prune state store record, err=%v", err)
+		return model.ManagedStatusMissing
+	}
+	return model.ManagedStatusPruned
+}
+
+// recordManagedDevice records the device attached through CHAPI in the state store.  The state
+// store is advisory so failures are only logged.
+func recordManagedDevice(publishInfo model.PublishInfo) {
+	device := &model.ManagedDevice{SerialNumber: publishInfo.SerialNumber, FsUUID: publishInfo.FsUUID}
+	if publishInfo.BlockDev != nil {
+		device.AccessProtocol = publishInfo.BlockDev.AccessProtocol
+		device.TargetName = publishInfo.BlockDev.TargetName
+	}
+	if err := state.RecordDevice(device); err != nil {
+		log.Errorf("Unable to record device %v in state store, err=%v", publishInfo.SerialNumber, err)
+	}
+}
+
+// forgetManagedDevice removes the detached device from the state store
+func forgetManagedDevice(serialNumber string) {
+	if err := state.RemoveDevice(serialNumber); err != nil {
+		log.Errorf("Unable to remove device %v from state store, err=%v", serialNumber, err)
+	}
+}
+
+// recordManagedFileSystem records the UUID of the file system created on the device in the state
+// store
+func recordManagedFileSystem(multipathPlugin *multipath.MultipathPlugin, device model.Device) {
+	fsUUID, err := multipathPlugin.GetFileSystemUUID(device)
+	if err != nil || fsUUID == "" {
+		return
+	}
+	if err = state.RecordFileSystem(device.SerialNumber, fsUUID); err != nil {
+		log.Errorf("Unable to record file system of device %v in state store, err=%v", device.SerialNumber, err)
+	}
+}
+
+// withManagedFileSystem returns the file system options to mount the given device with.  If the
+// caller did not provide an expected file system UUID, the UUID recorded in the state store for
+// the device, if any, is verified instead.
+func withManagedFileSystem(serialNumber string, fsOptions *model.FileSystemOptions) *model.FileSystemOptions {
+	if fsOptions != nil && fsOptions.FsUUID != "" {
+		return fsOptions
+	}
+	device, err := state.GetDevice(serialNumber)
+	if err != nil || device == nil || device.FsUUID == "" {
+		return fsOptions
+	}
+	options := model.FileSystemOptions{}
+	if fsOptions != nil {
+		options = *fsOptions
+	}
+	options.FsUUID = device.FsUUID
+	return &options
+}
+
+// recordManagedMount records the mount point created through CHAPI, and the mount options it was
+// created with, in the state store
+func recordManagedMount(newMount *model.Mount, mountOptions []string) {
+	managedMount := &model.ManagedMount{ID: newMount.ID, MountPoint: newMount.MountPoint, SerialNumber: newMount.SerialNumber, MountOpts: mountOptions}
+	if err := state.RecordMount(managedMount); err != nil {
+		log.Errorf("Unable to record mount point %v in state store, err=%v", newMount.ID, err)
+	}
+}
+
+// getManagedMountOptions returns the mount options recorded for the given mount point ID, if any
+func getManagedMountOptions(mountPointID string) []string {
+	managedMount, err := state.GetMount(mountPointID)
+	if err != nil || managedMount == nil {
+		return nil
+	}
+	return managedMount.MountOpts
+}
+
+// checkManagedMountOptions compares the mount options in effect of the managed mount points with
+// the mount options they were created with.  The mount points not created through CHAPI have no
+// recorded options and are left unchecked.
+func checkManagedMountOptions(mounts []*model.Mount) {
+	managedState, err := state.GetState()
+	if err != nil {
+		return
+	}
+	managedMounts := make(map[string]*model.ManagedMount)
+	for _, managedMount := range managedState.Mounts {
+		managedMounts[managedMount.ID] = managedMount
+	}
+	for _, enumeratedMount := range mounts {
+		managedMount, ok := managedMounts[enumeratedMount.ID]
+		if !ok || enumeratedMount.FsOpts == nil || len(enumeratedMount.FsOpts.MountOpts) == 0 {
+			continue
+		}
+		mount.CheckMountOptionsDrift(enumeratedMount, managedMount.MountOpts)
+		if enumeratedMount.Status == model.MountStatusDrifted {
+			log.Warnf("Mount point %v options drifted, %v", enumeratedMount.MountPoint, strings.Join(enumeratedMount.Drift, "; "))
+		}
+	}
+}
+
+// forgetManagedMount removes the deleted mount point from the state store
+func forgetManagedMount(mountPointID string) {
+	if err := state.RemoveMount(mountPointID); err != nil {
+		log.Errorf("Unable to remove mount point %v from state store, err=%v", mountPointID, err)
+	}
+}
+
+// forgetRemovedOrphans removes the removed orphaned mount points from the state store
+func forgetRemovedOrphans(orphans []*model.OrphanedMount) {
+	for _, orphan := range orphans {
+		if !orphan.Removed {
+			continue
+		}
+		if _, err := state.RemoveMountPoint(orphan.Path, mount.IsSamePathName); err != nil {
+			log.Errorf("Unable to remove mount point %v from state store, err=%v", orphan.Path, err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package driver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBumpCacheGeneration(t *testing.T) {
+	generation := CacheGeneration()
+
+	// A failed request leaves the cache generation unchanged
+	err := errors.New("failed")
+	bumpCacheGeneration(&err)
+	if CacheGeneration() != generation {
+		t.Errorf("cache generation bumped by a failed request")
+	}
+
+	err = nil
+	bumpCacheGeneration(&err)
+	if CacheGeneration() != generation+1 {
+		t.Errorf("expected cache generation %v, got %v", generation+1, CacheGeneration())
+	}
+}
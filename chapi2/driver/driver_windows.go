@@ -1,3 +1,14 @@
 // (c) Copyright 2019 Hewlett Packard Enterprise Development LP
 
 package driver
+
+const (
+	// Windows dismounts the volumes itself before the iSCSI initiator logs out at shutdown, and the
+	// mount points must survive the reboot (see POWER EVENTS in driver_power.go)
+	shutdownUnmountSupported = false
+	shutdownLogoutSupported  = false
+)
+
+// syncFileSystems is a no-op under Windows; each device is flushed (FlushFileBuffers) instead
+func syncFileSystems() {
+}
@@ -7,6 +7,11 @@ import (
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
 
+const (
+	// Shared error messages
+	errorMessageTargetPortsNotLoggedIn = "none of the FC target ports %v is logged in"
+)
+
 type FcPlugin struct {
 }
 
@@ -67,9 +72,24 @@ func (plugin *FcPlugin) GetAllFcHostPortWwn() ([]string, error) {
 	return inits, nil
 }
 
+// GetFcTargetPorts get the FC target ports discovered by the host ports
+func (plugin *FcPlugin) GetFcTargetPorts() ([]*model.FcTargetPort, error) {
+	log.Trace(">>>>> GetFcTargetPorts")
+	defer log.Trace("<<<<< GetFcTargetPorts")
+	return getFcTargetPorts()
+}
+
 // RescanFcTarget rescans host ports for new Fibre Channel devices
 func (plugin *FcPlugin) RescanFcTarget(lunID string) error {
 	log.Tracef(">>>>> RescanFcTarget called with lun id %s", lunID)
 	defer log.Trace("<<<<< RescanFcTarget")
 	return rescanFcTarget(lunID)
 }
+
+// RescanFcTargetPorts rescans the given LUN only on the given FC target ports, for LUNs that are
+// pre-zoned and mapped to the host.  An error is returned if none of the target ports is logged in.
+func (plugin *FcPlugin) RescanFcTargetPorts(targetWwpns []string, lunID string) error {
+	log.Tracef(">>>>> RescanFcTargetPorts called with target ports %v, lun id %s", targetWwpns, lunID)
+	defer log.Trace("<<<<< RescanFcTargetPorts")
+	return rescanFcTargetPorts(targetWwpns, lunID)
+}
@@ -4,20 +4,66 @@ package fc
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/util"
 )
 
 const (
+	// EmulexLIPRescanEnv enables, when set to "true", issuing a LIP on a Broadcom/Emulex (lpfc)
+	// host whose LUN scan missed the LUN.  The LIP disrupts the I/O of every LUN on the host and
+	// is followed by lipSettleTime, so it is off by default.
+	EmulexLIPRescanEnv = config.EmulexLIPRescanEnv
+
+	fcRemotePortTargetIDName  = "scsi_target_id"
+	fcRemotePortChannelFormat = "rport-%s:%%d-%%d"
+	fcRemotePortHostFormat    = "rport-%d:%d-%d"
+	fcRemotePortRolesName     = "roles"
+	fcRemotePortNameName      = "port_name"
+	fcRemotePortNodeNameName  = "node_name"
+	fcRemotePortStateName     = "port_state"
+	fcRemotePortTargetRole    = "FCP Target"
+	fcRemotePortStateOnline   = "Online"
+
+	// HBA driver names, as reported by the scsi_host proc_name attribute
+	hbaDriverEmulex = "lpfc"
+	hbaDriverQLogic = "qla2xxx"
+)
+
+// sysfs paths; variables so that tests can replace them
+var (
 	fcHostBasePath       = "/sys/class/fc_host"
 	fcHostPortNameFormat = "/sys/class/fc_host/host%s/port_name"
 	fcHostNodeNameFormat = "/sys/class/fc_host/host%s/node_name"
 	fcHostScanPathFormat = "/sys/class/scsi_host/host%s/scan"
 	// FcHostLIPNameFormat :
 	FcHostLIPNameFormat = "/sys/class/fc_host/host%s/issue_lip"
+
+	scsiHostProcNameFormat  = "/sys/class/scsi_host/host%s/proc_name"
+	scsiDeviceLunPathFormat = "/sys/class/scsi_device/%s:*:*:%s"
+	fcRemotePortPathFormat  = "/sys/class/fc_remote_ports/rport-%s:*"
+	fcRemotePortsPath       = "/sys/class/fc_remote_ports/rport-*"
+)
+
+var (
+	// lipSettleTime is how long to wait, after issuing a LIP, for the fabric to be rediscovered
+	// before the targeted LUN scan is repeated
+	lipSettleTime = 5 * time.Second
+
+	// hbaRescanMethods are the HBA specific rescan methods, keyed by HBA driver name, used when
+	// the generic LUN scan doesn't discover the LUN
+	hbaRescanMethods = map[string]func(hostNumber string, lunID string) error{
+		hbaDriverEmulex: rescanEmulexHost,
+		hbaDriverQLogic: rescanQLogicHost,
+	}
 )
 
 // getHostPort get the host port details for given host number from H:C:T:L of device
@@ -43,35 +89,22 @@ func getHostPort(hostNumber string) (hostPort *model.FcHostPort, err error) {
 // getAllFcHostPorts get all the FC host port details on the host
 func getAllFcHostPorts() (hostPorts []*model.FcHostPort, err error) {
 	log.Infof("getAllFcHostPorts called")
-	var hostNumbers []string
-	args := []string{"-1", fcHostBasePath}
-	exists, _, err := util.FileExists(fcHostBasePath)
-	if !exists {
+	hosts, err := ioutil.ReadDir(fcHostBasePath)
+	if os.IsNotExist(err) || (err == nil && len(hosts) == 0) {
 		log.Errorf("no fc adapters found on the host")
 		return nil, nil
-	}
-
-	out, _, err := util.ExecCommandOutput("ls", args)
-	if err != nil {
+	} else if err != nil {
 		log.Errorf("unable to get list of host fc ports, error %s", err.Error())
 		return nil, err
 	}
 
-	hostNumbers = strings.Split(out, "\n")
-	if len(hostNumbers) == 0 {
-		log.Errorf("no fc adapters found on the host")
-		return nil, nil
-	}
-
-	for _, host := range hostNumbers {
-		if host != "" {
-			hostPort, err := getHostPort(strings.TrimPrefix(host, "host"))
-			if err != nil {
-				log.Errorf("unable to get details of fc host port %s, error %s", host, err.Error())
-				continue
-			}
-			hostPorts = append(hostPorts, hostPort)
+	for _, host := range hosts {
+		hostPort, err := getHostPort(strings.TrimPrefix(host.Name(), "host"))
+		if err != nil {
+			log.Errorf("unable to get details of fc host port %s, error %s", host.Name(), err.Error())
+			continue
 		}
+		hostPorts = append(hostPorts, hostPort)
 	}
 	return hostPorts, nil
 }
@@ -93,7 +126,56 @@ func getAllFcHostPortWWN() (portWWNs []string, err error) {
 	return inits, nil
 }
 
-// fescanFcTarget rescans host ports for new Fibre Channel devices
+// getFcTargetPorts get the FC target ports discovered by the host ports
+func getFcTargetPorts() (targetPorts []*model.FcTargetPort, err error) {
+	log.Infof("getFcTargetPorts called")
+	rports, err := filepath.Glob(fcRemotePortsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	hostPorts := make(map[string]*model.FcHostPort)
+	for _, rport := range rports {
+		// Skip the remote ports that are not targets (e.g. other initiators)
+		roles, err := util.FileReadFirstLine(filepath.Join(rport, fcRemotePortRolesName))
+		if err != nil || !strings.Contains(roles, fcRemotePortTargetRole) {
+			continue
+		}
+		var host, channel, index int
+		if _, err = fmt.Sscanf(filepath.Base(rport), fcRemotePortHostFormat, &host, &channel, &index); err != nil {
+			continue
+		}
+		portName, err := util.FileReadFirstLine(filepath.Join(rport, fcRemotePortNameName))
+		if err != nil {
+			log.Errorf("unable to get port WWN for remote port %s, error %s", rport, err.Error())
+			continue
+		}
+		targetPort := &model.FcTargetPort{
+			HostNumber: fmt.Sprint(host),
+			PortWwn:    strings.TrimPrefix(portName, "0x"),
+		}
+		if nodeName, err := util.FileReadFirstLine(filepath.Join(rport, fcRemotePortNodeNameName)); err == nil {
+			targetPort.NodeWwn = strings.TrimPrefix(nodeName, "0x")
+		}
+		if portState, err := util.FileReadFirstLine(filepath.Join(rport, fcRemotePortStateName)); err == nil {
+			targetPort.PortState = strings.TrimSpace(portState)
+		}
+
+		// Look up the host port once per host
+		hostPort, ok := hostPorts[targetPort.HostNumber]
+		if !ok {
+			hostPort, _ = getHostPort(targetPort.HostNumber)
+			hostPorts[targetPort.HostNumber] = hostPort
+		}
+		if hostPort != nil {
+			targetPort.HostPortWwn = hostPort.PortWwn
+		}
+		targetPorts = append(targetPorts, targetPort)
+	}
+	return targetPorts, nil
+}
+
+// rescanFcTarget rescans host ports for new Fibre Channel devices
 func rescanFcTarget(lunID string) (err error) {
 
 	// Get the list of FC hosts to rescan
@@ -115,6 +197,127 @@ func rescanFcTarget(lunID string) (err error) {
 			log.Errorf("unable to rescan for fc devices on host port :%s lun: %s err %s", fcHost.HostNumber, lunID, err.Error())
 			return err
 		}
+
+		// If the generic scan missed the LUN, fall back to the HBA specific rescan method
+		if lunID != "" && !isLunDiscovered(fcHost.HostNumber, lunID) {
+			rescanHbaHost(fcHost.HostNumber, lunID)
+		}
+	}
+	return nil
+}
+
+// rescanFcTargetPorts scans the LUN on the online remote ports with one of the given target port
+// WWNs, by channel and target ID, so that neither the other targets nor the other LUNs are scanned
+func rescanFcTargetPorts(targetWwpns []string, lunID string) error {
+	rports, err := filepath.Glob(fcRemotePortsPath)
+	if err != nil {
+		return err
+	}
+	scanned := 0
+	for _, rport := range rports {
+		portName, err := util.FileReadFirstLine(filepath.Join(rport, fcRemotePortNameName))
+		if err != nil || !isTargetPort(portName, targetWwpns) {
+			continue
+		}
+		portState, _ := util.FileReadFirstLine(filepath.Join(rport, fcRemotePortStateName))
+		targetID, err := util.FileReadFirstLine(filepath.Join(rport, fcRemotePortTargetIDName))
+		if strings.TrimSpace(portState) != fcRemotePortStateOnline || err != nil || strings.TrimSpace(targetID) == "-1" {
+			log.Infof("skipping remote port %s of target port %s, state %q", filepath.Base(rport), portName, portState)
+			continue
+		}
+		var host, channel, index int
+		if _, err = fmt.Sscanf(filepath.Base(rport), fcRemotePortHostFormat, &host, &channel, &index); err != nil {
+			continue
+		}
+		scan := fmt.Sprintf("%d %s %s", channel, strings.TrimSpace(targetID), lunID)
+		log.Tracef("scanning %s on host %d for target port %s", scan, host, portName)
+		if err = util.FileWriteString(fmt.Sprintf(fcHostScanPathFormat, fmt.Sprint(host)), scan); err != nil {
+			log.Errorf("unable to scan %s on host %d, err %s", scan, host, err.Error())
+			return err
+		}
+		scanned++
+	}
+	if scanned == 0 {
+		return cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageTargetPortsNotLoggedIn, targetWwpns)
+	}
+	return nil
+}
+
+// isLunDiscovered returns true if a SCSI device with the given LUN exists on the given host
+func isLunDiscovered(hostNumber string, lunID string) bool {
+	matches, _ := filepath.Glob(fmt.Sprintf(scsiDeviceLunPathFormat, hostNumber, lunID))
+	return len(matches) > 0
+}
+
+// getHbaDriverName returns the name of the driver backing the given FC host (e.g. "lpfc")
+func getHbaDriverName(hostNumber string) string {
+	driverName, err := util.FileReadFirstLine(fmt.Sprintf(scsiHostProcNameFormat, hostNumber))
+	if err != nil {
+		log.Errorf("unable to get driver name for host %s, error %s", hostNumber, err.Error())
+		return ""
+	}
+	return strings.TrimSpace(driverName)
+}
+
+// rescanHbaHost issues the HBA specific rescan, selected by driver name, for the given host.  Any
+// failure is only logged as the generic scan has already been issued.
+func rescanHbaHost(hostNumber string, lunID string) {
+	driverName := getHbaDriverName(hostNumber)
+	rescanMethod := hbaRescanMethods[driverName]
+	if rescanMethod == nil {
+		log.Infof("lun %s not discovered on host %s, no specific rescan method for driver %q", lunID, hostNumber, driverName)
+		return
+	}
+	log.Infof("lun %s not discovered on host %s, issuing %s specific rescan", lunID, hostNumber, driverName)
+	if err := rescanMethod(hostNumber, lunID); err != nil {
+		log.Errorf("unable to issue %s rescan on host %s lun: %s err %s", driverName, hostNumber, lunID, err.Error())
+	}
+}
+
+// isEmulexLIPRescanEnabled returns true if EmulexLIPRescanEnv is set to "true"
+func isEmulexLIPRescanEnabled() bool {
+	return config.Enabled(EmulexLIPRescanEnv)
+}
+
+// rescanEmulexHost issues a LIP on a Broadcom/Emulex (lpfc) host so that the fabric is logged into
+// again and newly mapped LUNs are reported, then repeats the LUN scan.  Only issued if enabled by
+// EmulexLIPRescanEnv.
+func rescanEmulexHost(hostNumber string, lunID string) error {
+	if !isEmulexLIPRescanEnabled() {
+		log.Infof("not issuing a LIP on host %s, not enabled by %s", hostNumber, EmulexLIPRescanEnv)
+		return nil
+	}
+	if err := util.FileWriteString(fmt.Sprintf(FcHostLIPNameFormat, hostNumber), "1"); err != nil {
+		return err
+	}
+	time.Sleep(lipSettleTime)
+	return util.FileWriteString(fmt.Sprintf(fcHostScanPathFormat, hostNumber), "- - "+lunID)
+}
+
+// rescanQLogicHost scans the LUN on each target remote port of a Marvell/QLogic (qla2xxx) host.
+// Scanning by channel and target makes the driver probe the LUN directly instead of relying on
+// the target's REPORT LUNS data, which may not yet include the newly mapped LUN.
+func rescanQLogicHost(hostNumber string, lunID string) error {
+	rports, err := filepath.Glob(fmt.Sprintf(fcRemotePortPathFormat, hostNumber))
+	if err != nil {
+		return err
+	}
+	fcHostScanPath := fmt.Sprintf(fcHostScanPathFormat, hostNumber)
+	for _, rport := range rports {
+		targetID, err := util.FileReadFirstLine(filepath.Join(rport, fcRemotePortTargetIDName))
+		if err != nil || strings.TrimSpace(targetID) == "-1" {
+			// Not a SCSI target (e.g. an initiator or fabric port)
+			continue
+		}
+		var channel, index int
+		if _, err = fmt.Sscanf(filepath.Base(rport), fmt.Sprintf(fcRemotePortChannelFormat, hostNumber), &channel, &index); err != nil {
+			continue
+		}
+		scan := fmt.Sprintf("%d %s %s", channel, strings.TrimSpace(targetID), lunID)
+		log.Tracef("scanning %s on host %s", scan, hostNumber)
+		if err = util.FileWriteString(fcHostScanPath, scan); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,151 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package fc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useFakeSysfs points the sysfs paths to a temporary directory, returned with a function
+// restoring them
+func useFakeSysfs(t *testing.T) (string, func()) {
+	root, err := ioutil.TempDir("", "fc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []*string{&fcHostBasePath, &fcHostPortNameFormat, &fcHostNodeNameFormat, &fcHostScanPathFormat, &FcHostLIPNameFormat,
+		&scsiHostProcNameFormat, &scsiDeviceLunPathFormat, &fcRemotePortPathFormat, &fcRemotePortsPath}
+	saved := make([]string, len(paths))
+	for i, path := range paths {
+		saved[i] = *path
+		*path = strings.Replace(*path, "/sys/class", root, 1)
+	}
+	return root, func() {
+		for i, path := range paths {
+			*path = saved[i]
+		}
+		os.RemoveAll(root)
+	}
+}
+
+// writeSysfsFiles creates the given sysfs attribute files, relative to the fake sysfs root
+func writeSysfsFiles(t *testing.T, root string, files map[string]string) {
+	for name, value := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readSysfsFile returns the contents of the given sysfs attribute file, relative to the fake sysfs
+// root, or an empty string if not written
+func readSysfsFile(root string, name string) string {
+	data, _ := ioutil.ReadFile(filepath.Join(root, name))
+	return string(data)
+}
+
+func TestGetFcPorts(t *testing.T) {
+	root, restore := useFakeSysfs(t)
+	defer restore()
+
+	if hostPorts, err := getAllFcHostPorts(); err != nil || len(hostPorts) != 0 {
+		t.Fatalf("expected no host ports without FC adapters, got %v, err=%v", hostPorts, err)
+	}
+
+	writeSysfsFiles(t, root, map[string]string{
+		"fc_host/host3/port_name":                "0x10000090fa1b2c3d\n",
+		"fc_host/host3/node_name":                "0x20000090fa1b2c3d\n",
+		"fc_remote_ports/rport-3:0-1/roles":      "FCP Target\n",
+		"fc_remote_ports/rport-3:0-1/port_name":  "0x56c9ce907ff1e201\n",
+		"fc_remote_ports/rport-3:0-1/node_name":  "0x56c9ce907ff1e200\n",
+		"fc_remote_ports/rport-3:0-1/port_state": "Online\n",
+		"fc_remote_ports/rport-3:0-2/roles":      "FCP Initiator\n",
+		"fc_remote_ports/rport-3:0-2/port_name":  "0x10000090fa000001\n",
+	})
+
+	hostPorts, err := getAllFcHostPorts()
+	if err != nil || len(hostPorts) != 1 {
+		t.Fatalf("expected 1 host port, got %v, err=%v", hostPorts, err)
+	}
+	if hostPorts[0].HostNumber != "3" || hostPorts[0].PortWwn != "10000090fa1b2c3d" || hostPorts[0].NodeWwn != "20000090fa1b2c3d" {
+		t.Errorf("unexpected host port %+v", hostPorts[0])
+	}
+
+	targetPorts, err := getFcTargetPorts()
+	if err != nil || len(targetPorts) != 1 {
+		t.Fatalf("expected 1 target port, got %v, err=%v", targetPorts, err)
+	}
+	if targetPorts[0].HostNumber != "3" || targetPorts[0].PortWwn != "56c9ce907ff1e201" || targetPorts[0].NodeWwn != "56c9ce907ff1e200" ||
+		targetPorts[0].PortState != "Online" || targetPorts[0].HostPortWwn != "10000090fa1b2c3d" {
+		t.Errorf("unexpected target port %+v", targetPorts[0])
+	}
+}
+
+func TestRescanFcTarget(t *testing.T) {
+	root, restore := useFakeSysfs(t)
+	defer restore()
+	defer func(settleTime time.Duration) { lipSettleTime = settleTime }(lipSettleTime)
+	defer os.Unsetenv(EmulexLIPRescanEnv)
+	lipSettleTime = 0
+
+	writeSysfsFiles(t, root, map[string]string{
+		"fc_host/host3/port_name":   "0x10000090fa1b2c3d\n",
+		"fc_host/host3/node_name":   "0x20000090fa1b2c3d\n",
+		"scsi_host/host3/scan":      "",
+		"scsi_host/host3/proc_name": "lpfc\n",
+		"fc_host/host3/issue_lip":   "",
+	})
+
+	// The LUN is not discovered by the scan, but the LIP is only issued if enabled
+	if err := rescanFcTarget("5"); err != nil {
+		t.Fatal(err)
+	}
+	if scan := readSysfsFile(root, "scsi_host/host3/scan"); scan != "- - 5" {
+		t.Errorf("unexpected scan %q", scan)
+	}
+	if lip := readSysfsFile(root, "fc_host/host3/issue_lip"); lip != "" {
+		t.Errorf("LIP issued although not enabled")
+	}
+
+	os.Setenv(EmulexLIPRescanEnv, "true")
+	if err := rescanFcTarget("5"); err != nil {
+		t.Fatal(err)
+	}
+	if lip := readSysfsFile(root, "fc_host/host3/issue_lip"); lip != "1" {
+		t.Errorf("LIP not issued although enabled")
+	}
+}
+
+func TestRescanFcTargetPorts(t *testing.T) {
+	root, restore := useFakeSysfs(t)
+	defer restore()
+
+	writeSysfsFiles(t, root, map[string]string{
+		"scsi_host/host3/scan":                       "",
+		"fc_remote_ports/rport-3:0-1/port_name":      "0x56c9ce907ff1e201\n",
+		"fc_remote_ports/rport-3:0-1/port_state":     "Online\n",
+		"fc_remote_ports/rport-3:0-1/scsi_target_id": "2\n",
+		"fc_remote_ports/rport-3:0-2/port_name":      "0x56c9ce907ff1e202\n",
+		"fc_remote_ports/rport-3:0-2/port_state":     "Blocked\n",
+		"fc_remote_ports/rport-3:0-2/scsi_target_id": "3\n",
+	})
+
+	if err := rescanFcTargetPorts([]string{"56:c9:ce:90:7f:f1:e2:02"}, "5"); err == nil {
+		t.Error("expected error scanning only offline target ports")
+	}
+	if err := rescanFcTargetPorts([]string{"56c9ce907ff1e201", "56c9ce907ff1e202"}, "5"); err != nil {
+		t.Fatal(err)
+	}
+	if scan := readSysfsFile(root, "scsi_host/host3/scan"); scan != "0 2 5" {
+		t.Errorf("unexpected scan %q", scan)
+	}
+}
@@ -3,6 +3,7 @@
 package fc
 
 import (
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
 
@@ -23,3 +24,13 @@ func getAllFcHostPortWwn() ([]string, error) {
 	}
 	return inits, nil
 }
+
+// isTargetPort returns true if the target port WWN is one of the given WWNs, whatever their format
+func isTargetPort(portWwn string, targetWwpns []string) bool {
+	for _, targetWwpn := range targetWwpns {
+		if model.NormalizeWwn(targetWwpn) == model.NormalizeWwn(portWwn) {
+			return true
+		}
+	}
+	return false
+}
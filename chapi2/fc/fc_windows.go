@@ -5,11 +5,20 @@ package fc
 import (
 	"fmt"
 
+	"github.com/hpe-storage/common-host-libs/blockdevice"
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/windows/wmi"
 )
 
+var (
+	// HBA API queries and the disk rescan; variables so that tests can replace them
+	getFCAdapterHBAAttributes   = wmi.GetMSFC_FCAdapterHBAAttributes
+	getDiscoveredPortAttributes = wmi.GetDiscoveredPortAttributes
+	rescanAllDisks              = blockdevice.RescanAll
+)
+
 // getAllFcHostPorts get all the FC host port details on the host
 func getAllFcHostPorts() (hostPorts []*model.FcHostPort, err error) {
 	log.Trace(">>>>> GetAllFcHostPorts called")
@@ -22,8 +31,13 @@ func getAllFcHostPorts() (hostPorts []*model.FcHostPort, err error) {
 		return nil, err
 	}
 
-	// Convert FC port array into array of FcHostPort
+	// Convert FC port array into array of FcHostPort, skipping the ports without attributes (e.g.
+	// a port of a disabled adapter)
 	for _, fcPort := range fcPorts {
+		if !fcPort.Active || fcPort.HBAStatus != wmi.HBA_STATUS_OK || fcPort.Attributes == nil {
+			log.Tracef("Skipping FC port %v, active=%v, status=%v", fcPort.InstanceName, fcPort.Active, fcPort.HBAStatus)
+			continue
+		}
 		hostPort := new(model.FcHostPort)
 		hostPort.PortWwn = wwnToString(fcPort.Attributes.PortWWN)
 		hostPort.NodeWwn = wwnToString(fcPort.Attributes.NodeWWN)
@@ -32,14 +46,97 @@ func getAllFcHostPorts() (hostPorts []*model.FcHostPort, err error) {
 	return hostPorts, nil
 }
 
+// getFcTargetPorts get the FC target ports discovered by the host ports
+func getFcTargetPorts() (targetPorts []*model.FcTargetPort, err error) {
+	log.Trace(">>>>> getFcTargetPorts called")
+	defer log.Trace("<<<<< getFcTargetPorts")
+
+	// Enumerate the FC adapters on this host
+	var fcAdapters []*wmi.MSFC_FCAdapterHBAAttributes
+	fcAdapters, err = getFCAdapterHBAAttributes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Enumerate the ports discovered by each adapter port until the HBA API reports the index is
+	// out of range
+	for _, fcAdapter := range fcAdapters {
+		if !fcAdapter.Active || fcAdapter.HBAStatus != wmi.HBA_STATUS_OK {
+			continue
+		}
+		for portIndex := uint32(0); portIndex < fcAdapter.NumberOfPorts; portIndex++ {
+			for discoveredPortIndex := uint32(0); ; discoveredPortIndex++ {
+				attributes, hbaStatus, err := getDiscoveredPortAttributes(fcAdapter.InstanceName, portIndex, discoveredPortIndex)
+				if err != nil {
+					return nil, err
+				}
+				if hbaStatus != wmi.HBA_STATUS_OK {
+					if hbaStatus != wmi.HBA_STATUS_ERROR_ILLEGAL_INDEX {
+						log.Errorf("Unable to get discovered port %v of FC adapter %v port %v, status=%v", discoveredPortIndex, fcAdapter.InstanceName, portIndex, hbaStatus)
+					}
+					break
+				}
+
+				// Only node ports can be targets; skip the fabric (switch) ports
+				if attributes.PortType != wmi.HBA_PORTTYPE_NPORT && attributes.PortType != wmi.HBA_PORTTYPE_NLPORT {
+					continue
+				}
+				targetPorts = append(targetPorts, &model.FcTargetPort{
+					PortWwn:   wwnToString(attributes.PortWWN),
+					NodeWwn:   wwnToString(attributes.NodeWWN),
+					PortState: portStateToString(attributes.PortState),
+				})
+			}
+		}
+	}
+	return targetPorts, nil
+}
+
 // rescanFcTarget rescans host ports for new Fibre Channel devices
 func rescanFcTarget(lunID string) (err error) {
 	// Unlike Linux, Windows does not have Target/LUN specific rescan capabilities so a synchronous
 	// disk rescan is initiated and the lunID is ignored.
-	return wmi.RescanDisks()
+	return blockdevice.RescanAll()
+}
+
+// rescanFcTargetPorts rescans the disks once one of the given target ports is logged in.  As for
+// rescanFcTarget, Windows cannot rescan specific targets or LUNs.
+func rescanFcTargetPorts(targetWwpns []string, lunID string) error {
+	targetPorts, err := getFcTargetPorts()
+	if err != nil {
+		return err
+	}
+	for _, targetPort := range targetPorts {
+		if isTargetPort(targetPort.PortWwn, targetWwpns) && targetPort.PortState == "Online" {
+			return rescanAllDisks()
+		}
+	}
+	return cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageTargetPortsNotLoggedIn, targetWwpns)
 }
 
 // wwnToString converts the given FC WWN into a string (e.g. "10:00:00:90:FA:73:6E:CA")
 func wwnToString(wwn [8]uint8) string {
 	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X:%02X:%02X", wwn[0], wwn[1], wwn[2], wwn[3], wwn[4], wwn[5], wwn[6], wwn[7])
 }
+
+// portStateToString converts the given HBA API port state into a string, named as reported by the
+// Linux fc_remote_ports port_state attribute where possible (e.g. "Online")
+func portStateToString(portState uint32) string {
+	switch portState {
+	case wmi.HBA_PORTSTATE_ONLINE:
+		return "Online"
+	case wmi.HBA_PORTSTATE_OFFLINE:
+		return "Offline"
+	case wmi.HBA_PORTSTATE_BYPASSED:
+		return "Bypassed"
+	case wmi.HBA_PORTSTATE_DIAGNOSTICS:
+		return "Diagnostics"
+	case wmi.HBA_PORTSTATE_LINKDOWN:
+		return "Linkdown"
+	case wmi.HBA_PORTSTATE_ERROR:
+		return "Error"
+	case wmi.HBA_PORTSTATE_LOOPBACK:
+		return "Loopback"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,79 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package fc
+
+import (
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/windows/wmi"
+)
+
+// fakeDiscoveredPorts replaces the HBA API queries with an active adapter with two ports, where
+// port 0 discovered a fabric port and two target ports, and an inactive adapter.  The returned
+// function restores the HBA API queries.
+func fakeDiscoveredPorts(t *testing.T) func() {
+	savedGetFCAdapterHBAAttributes, savedGetDiscoveredPortAttributes := getFCAdapterHBAAttributes, getDiscoveredPortAttributes
+
+	getFCAdapterHBAAttributes = func() ([]*wmi.MSFC_FCAdapterHBAAttributes, error) {
+		return []*wmi.MSFC_FCAdapterHBAAttributes{
+			{InstanceName: "adapter0", Active: true, HBAStatus: wmi.HBA_STATUS_OK, NumberOfPorts: 2},
+			{InstanceName: "adapter1", Active: false, HBAStatus: wmi.HBA_STATUS_OK, NumberOfPorts: 1},
+		}, nil
+	}
+	discoveredPorts := []*wmi.MSFC_HBAPortAttributesResults{
+		{PortType: wmi.HBA_PORTTYPE_FPORT, PortWWN: [8]uint8{0x20, 0x01, 0x00, 0x05, 0x1e, 0x00, 0x00, 0x01}},
+		{PortType: wmi.HBA_PORTTYPE_NPORT, PortState: wmi.HBA_PORTSTATE_ONLINE,
+			PortWWN: [8]uint8{0x56, 0xc9, 0xce, 0x90, 0x7f, 0xf1, 0xe2, 0x01}, NodeWWN: [8]uint8{0x56, 0xc9, 0xce, 0x90, 0x7f, 0xf1, 0xe2, 0x00}},
+		{PortType: wmi.HBA_PORTTYPE_NPORT, PortState: wmi.HBA_PORTSTATE_LINKDOWN,
+			PortWWN: [8]uint8{0x56, 0xc9, 0xce, 0x90, 0x7f, 0xf1, 0xe2, 0x02}, NodeWWN: [8]uint8{0x56, 0xc9, 0xce, 0x90, 0x7f, 0xf1, 0xe2, 0x00}},
+	}
+	getDiscoveredPortAttributes = func(instanceName string, portIndex uint32, discoveredPortIndex uint32) (*wmi.MSFC_HBAPortAttributesResults, uint32, error) {
+		if instanceName != "adapter0" {
+			t.Errorf("unexpected query of adapter %v", instanceName)
+		}
+		if portIndex != 0 || int(discoveredPortIndex) >= len(discoveredPorts) {
+			return nil, wmi.HBA_STATUS_ERROR_ILLEGAL_INDEX, nil
+		}
+		return discoveredPorts[discoveredPortIndex], wmi.HBA_STATUS_OK, nil
+	}
+	return func() {
+		getFCAdapterHBAAttributes, getDiscoveredPortAttributes = savedGetFCAdapterHBAAttributes, savedGetDiscoveredPortAttributes
+	}
+}
+
+func TestGetFcTargetPorts(t *testing.T) {
+	defer fakeDiscoveredPorts(t)()
+
+	targetPorts, err := getFcTargetPorts()
+	if err != nil {
+		t.Fatalf("getFcTargetPorts failed, err=%v", err)
+	}
+	if len(targetPorts) != 2 {
+		t.Fatalf("unexpected target ports %+v", targetPorts)
+	}
+	if targetPorts[0].PortWwn != "56:C9:CE:90:7F:F1:E2:01" || targetPorts[0].NodeWwn != "56:C9:CE:90:7F:F1:E2:00" || targetPorts[0].PortState != "Online" {
+		t.Errorf("unexpected target port %+v", targetPorts[0])
+	}
+	if targetPorts[1].PortWwn != "56:C9:CE:90:7F:F1:E2:02" || targetPorts[1].PortState != "Linkdown" {
+		t.Errorf("unexpected target port %+v", targetPorts[1])
+	}
+}
+
+func TestRescanFcTargetPorts(t *testing.T) {
+	defer fakeDiscoveredPorts(t)()
+	var rescans int
+	savedRescanAllDisks := rescanAllDisks
+	defer func() { rescanAllDisks = savedRescanAllDisks }()
+	rescanAllDisks = func() error {
+		rescans++
+		return nil
+	}
+
+	// Only an online target port triggers the rescan
+	if err := rescanFcTargetPorts([]string{"56c9ce907ff1e202"}, "5"); err == nil || rescans != 0 {
+		t.Errorf("rescan of a link down target port returned err=%v after %v rescans", err, rescans)
+	}
+	if err := rescanFcTargetPorts([]string{"56:c9:ce:90:7f:f1:e2:02", "56:c9:ce:90:7f:f1:e2:01"}, "5"); err != nil || rescans != 1 {
+		t.Errorf("rescan of an online target port returned err=%v after %v rescans", err, rescans)
+	}
+}
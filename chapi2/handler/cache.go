@@ -0,0 +1,188 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	chapiDriver "github.com/hpe-storage/common-host-libs/chapi2/driver"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// CACHED GET RESPONSES
+//
+//		Large GET endpoints (e.g. device and mount details) are polled frequently by sidecars.
+//		Their responses carry an ETag derived from the driver cache generation and the response
+//		body.  If the request's If-None-Match header matches, HTTP 304 is returned without a body.
+//
+//		While the cache generation is unchanged, a matching If-None-Match is answered from the
+//		ETag cache without enumerating the host again.  Changes made outside of CHAPI do not bump
+//		the cache generation, so cached ETags are only trusted for etagRevalidateInterval.
+//
+//		Responses are gzip compressed if the client accepts it and the body is large enough.  A
+//		gzip encoded response carries its own ETag (etagGzipSuffix), as required of strong ETags
+//		for different content codings.  YAML responses (see CONTENT NEGOTIATION in response.go)
+//		are never compressed, and carry their own ETag as they are a different representation of
+//		the same data.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentType     = "Content-Type"
+	headerETag            = "ETag"
+	headerIfNoneMatch     = "If-None-Match"
+	headerVary            = "Vary"
+
+	contentTypeJSON = "application/json"
+	gzipEncoding    = "gzip"
+	etagGzipSuffix  = "-gzip" // Appended to the ETag of gzip encoded responses
+
+	gzipMinBodySize     = 1024 // Smaller responses are not worth compressing
+	etagCacheMaxEntries = 256  // ETag cache is reset if it grows beyond this many entries
+)
+
+var (
+	// etagRevalidateInterval is how long a cached ETag is trusted before the host is enumerated
+	etagRevalidateInterval = 5 * time.Second
+
+	etagCache     = make(map[string]*etagCacheEntry)
+	etagCacheLock sync.Mutex
+)
+
+// etagCacheEntry is the last ETag returned for a request URI
+type etagCacheEntry struct {
+	etag       string
+	generation uint64
+	validated  time.Time
+}
+
+// handleCachedRequest enumerates the response data with the given function and writes it with an
+// ETag, gzip compressing it if supported by the client.  If the client already has the current
+// response (If-None-Match), HTTP 304 is returned instead.
+func handleCachedRequest(function func() (interface{}, error), w http.ResponseWriter, r *http.Request) {
+	var chapiResp Response
+	key := r.URL.RequestURI()
+	yamlResponse := acceptsYAML(r)
+	if yamlResponse {
+		key += etagYAMLSuffix
+	} else if acceptsGzip(r) {
+		key += etagGzipSuffix
+	}
+	generation := chapiDriver.CacheGeneration()
+	ifNoneMatch := r.Header.Get(headerIfNoneMatch)
+
+	// Answer from the ETag cache if nothing has changed through CHAPI since it was validated
+	if ifNoneMatch != "" {
+		if etag := getCachedETag(key, generation); etag != "" && etagMatches(ifNoneMatch, etag) {
+			log.Tracef("Cached response not modified, uri=%v, etag=%v", key, etag)
+			writeNotModified(w, etag)
+			return
+		}
+	}
+
+	data, err := function()
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+
+	setResponseData(&chapiResp, data)
+	body, err := json.Marshal(chapiResp)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	compress := len(body) >= gzipMinBodySize && acceptsGzip(r) && !yamlResponse
+	h := fnv.New64a()
+	h.Write(body)
+	etag := fmt.Sprintf(`"%x-%x"`, generation, h.Sum64())
+	if yamlResponse {
+		etag = fmt.Sprintf(`"%x-%x%v"`, generation, h.Sum64(), etagYAMLSuffix)
+	} else if compress {
+		etag = fmt.Sprintf(`"%x-%x%v"`, generation, h.Sum64(), etagGzipSuffix)
+	}
+	setCachedETag(key, &etagCacheEntry{etag: etag, generation: generation, validated: time.Now()})
+
+	if etagMatches(ifNoneMatch, etag) {
+		writeNotModified(w, etag)
+		return
+	}
+
+	w.Header().Set(headerETag, etag)
+	w.Header().Set(headerVary, headerAcceptEncoding)
+	w.Header().Set(headerContentType, contentTypeJSON)
+	if !compress {
+		w.Write(body)
+		return
+	}
+	w.Header().Set(headerContentEncoding, gzipEncoding)
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	gz.Write(body)
+}
+
+// getCachedETag returns the cached ETag for the given request URI if it is still current, else an
+// empty string
+func getCachedETag(key string, generation uint64) string {
+	etagCacheLock.Lock()
+	defer etagCacheLock.Unlock()
+
+	entry := etagCache[key]
+	if entry == nil || entry.generation != generation || time.Since(entry.validated) > etagRevalidateInterval {
+		return ""
+	}
+	return entry.etag
+}
+
+// setCachedETag records the ETag returned for the given request URI
+func setCachedETag(key string, entry *etagCacheEntry) {
+	etagCacheLock.Lock()
+	defer etagCacheLock.Unlock()
+
+	if len(etagCache) >= etagCacheMaxEntries {
+		etagCache = make(map[string]*etagCacheEntry)
+	}
+	etagCache[key] = entry
+}
+
+// writeNotModified writes an HTTP 304 response for the given ETag
+func writeNotModified(w http.ResponseWriter, etag string) {
+	w.Header().Set(headerETag, etag)
+	w.Header().Set(headerVary, headerAcceptEncoding)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagMatches returns true if the If-None-Match header value lists the given ETag
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip returns true if the client accepts a gzip encoded response
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get(headerAcceptEncoding), ",") {
+		encoding = strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0])
+		if strings.EqualFold(encoding, gzipEncoding) {
+			return true
+		}
+	}
+	return false
+}
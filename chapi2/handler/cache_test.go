@@ -0,0 +1,80 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleCachedRequest(t *testing.T) {
+	calls := 0
+	function := func() (interface{}, error) {
+		calls++
+		return []string{strings.Repeat("x", gzipMinBodySize)}, nil
+	}
+
+	// First request returns the (gzip encoded) body with an ETag
+	r := httptest.NewRequest("GET", "/api/v1/devices/details", nil)
+	r.Header.Set(headerAcceptEncoding, "deflate, gzip;q=1.0")
+	w := httptest.NewRecorder()
+	handleCachedRequest(function, w, r)
+	if w.Code != http.StatusOK || w.Header().Get(headerContentEncoding) != gzipEncoding {
+		t.Fatalf("unexpected response, code=%v, headers=%v", w.Code, w.Header())
+	}
+	etag := w.Header().Get(headerETag)
+	if !strings.HasSuffix(etag, etagGzipSuffix+`"`) {
+		t.Fatalf("expected gzip ETag, got %q", etag)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(gz)
+	if err != nil || !strings.HasPrefix(string(body), `{"data":["xxx`) {
+		t.Fatalf("unexpected body %q, err=%v", body, err)
+	}
+
+	// A matching If-None-Match is answered from the ETag cache without enumerating again
+	r = httptest.NewRequest("GET", "/api/v1/devices/details", nil)
+	r.Header.Set(headerAcceptEncoding, gzipEncoding)
+	r.Header.Set(headerIfNoneMatch, etag)
+	w = httptest.NewRecorder()
+	handleCachedRequest(function, w, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || calls != 1 {
+		t.Fatalf("expected cached 304, code=%v, calls=%v", w.Code, calls)
+	}
+
+	// Once the cached ETag expires the host is enumerated again, but the unchanged response is
+	// still reported as not modified
+	savedInterval := etagRevalidateInterval
+	etagRevalidateInterval = 0
+	defer func() { etagRevalidateInterval = savedInterval }()
+	w = httptest.NewRecorder()
+	handleCachedRequest(function, w, r)
+	if w.Code != http.StatusNotModified || calls != 2 {
+		t.Fatalf("expected revalidated 304, code=%v, calls=%v", w.Code, calls)
+	}
+
+	// The uncompressed response does not match the ETag of the gzip encoded response
+	r = httptest.NewRequest("GET", "/api/v1/devices/details", nil)
+	r.Header.Set(headerIfNoneMatch, etag)
+	w = httptest.NewRecorder()
+	handleCachedRequest(function, w, r)
+	identityETag := w.Header().Get(headerETag)
+	if w.Code != http.StatusOK || w.Header().Get(headerContentEncoding) != "" || identityETag == "" || identityETag == etag {
+		t.Fatalf("unexpected response, code=%v, headers=%v", w.Code, w.Header())
+	}
+
+	// Stale ETags get the full response
+	r.Header.Set(headerIfNoneMatch, `"stale"`)
+	w = httptest.NewRecorder()
+	handleCachedRequest(function, w, r)
+	if w.Code != http.StatusOK || w.Header().Get(headerETag) != identityETag {
+		t.Fatalf("unexpected response, code=%v, headers=%v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,136 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/timing"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+var (
+	// serverStartTime is used to report the CHAPI server uptime
+	serverStartTime = time.Now()
+)
+
+// diagnosticsContextKey marks requests received on the read-only diagnostics listener
+type diagnosticsContextKey struct{}
+
+// DiagnosticsHandler wraps a read-only endpoint handler served on the unauthenticated diagnostics
+// listener.  The request header is not validated for requests received through the wrapper.
+func DiagnosticsHandler(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handlerFunc(w, r.WithContext(context.WithValue(r.Context(), diagnosticsContextKey{}, true)))
+	}
+}
+
+// isDiagnosticsRequest returns true if the request was received on the diagnostics listener
+func isDiagnosticsRequest(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	diagnostics, _ := r.Context().Value(diagnosticsContextKey{}).(bool)
+	return diagnostics
+}
+
+//@APIVersion 1.0.0
+//@Title GetHealth
+//@Description reports whether the CHAPI server is able to respond, along with its uptime and log disk usage
+//@Accept json
+//@Resource /api/v1/health
+//@Success 200 Health
+//@Router /api/v1/health [get]
+func GetHealth(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	log.Trace(">>>>> GetHealth")
+	defer log.Trace("<<<<< GetHealth")
+
+	health := &model.Health{Status: model.HealthStatusOK, UptimeSeconds: uint64(time.Since(serverStartTime).Seconds())}
+	if usage := log.GetLogDiskUsage(); usage != nil {
+		logs := model.LogDiskUsage(*usage)
+		health.Logs = &logs
+	}
+
+	var chapiResp Response
+	chapiResp.Data = health
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetLatency
+//@Description reports the request latency histogram of each endpoint
+//@Accept json
+//@Resource /api/v1/latency
+//@Success 200 LatencyReport
+//@Router /api/v1/latency [get]
+func GetLatency(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	log.Trace(">>>>> GetLatency")
+	defer log.Trace("<<<<< GetLatency")
+
+	var chapiResp Response
+	chapiResp.Data = timing.GetLatencies()
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetLogging
+//@Description reports the active log level, format and component level overrides
+//@Accept json
+//@Resource /api/v1/logging
+//@Success 200 LogConfig
+//@Router /api/v1/logging [get]
+func GetLogging(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	log.Trace(">>>>> GetLogging")
+	defer log.Trace("<<<<< GetLogging")
+
+	var chapiResp Response
+	config := log.GetLogConfig()
+	chapiResp.Data = &config
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title SetLogging
+//@Description changes the active log level, format and component level overrides without a restart
+//@Accept json
+//@Resource /api/v1/logging
+//@Success 200 LogConfig
+//@Router /api/v1/logging [put]
+func SetLogging(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	log.Trace(">>>>> SetLogging")
+	defer log.Trace("<<<<< SetLogging")
+
+	var chapiResp Response
+	var config log.LogConfig
+	err := decodeRequest(r, &config)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	if err = log.SetLogConfig(config); err != nil {
+		handleError(w, chapiResp, cerrors.NewChapiError(cerrors.InvalidArgument, err.Error()), http.StatusBadRequest)
+		return
+	}
+	config = log.GetLogConfig()
+	chapiResp.Data = &config
+	json.NewEncoder(w).Encode(chapiResp)
+}
@@ -0,0 +1,129 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// SPARSE FIELDSETS
+//
+//		Detail endpoints (e.g. device and mount details) accept the optional "fields" query
+//		parameter, a comma separated list of the JSON property names to return for each item
+//		(e.g. ?fields=serial_number,size).  The other properties are left out of the response and,
+//		where the driver supports it, are not enumerated at all (e.g. the iSCSI target of a device
+//		under Windows).  Unknown property names fail the request.  Without the parameter every
+//		property is returned, as before.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+const (
+	queryFields = "fields"
+)
+
+// fieldsRequest is the set of item properties requested through the fields query parameter
+type fieldsRequest struct {
+	names []string        // Requested JSON property names, in request order
+	set   map[string]bool // Requested JSON property names
+}
+
+// getFieldsRequest parses the fields query parameter of the request, validating the property
+// names against the JSON properties of the given item type.  nil is returned if the parameter is
+// not given.
+func getFieldsRequest(r *http.Request, item interface{}) (*fieldsRequest, error) {
+	value := r.URL.Query().Get(queryFields)
+	if value == "" {
+		return nil, nil
+	}
+	properties := getJSONProperties(reflect.TypeOf(item))
+	fields := &fieldsRequest{set: make(map[string]bool)}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || fields.set[name] {
+			continue
+		}
+		if !properties[name] {
+			return nil, cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidField, name, strings.Join(sortedKeys(properties), " "))
+		}
+		fields.names = append(fields.names, name)
+		fields.set[name] = true
+	}
+	if len(fields.names) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// list returns the requested property names, or nil if every property is requested
+func (fields *fieldsRequest) list() []string {
+	if fields == nil {
+		return nil
+	}
+	return fields.names
+}
+
+// apply returns the given slice with only the requested properties of each item.  The slice is
+// returned as is if every property is requested.
+func (fields *fieldsRequest) apply(items interface{}) (interface{}, error) {
+	if fields == nil {
+		return items, nil
+	}
+	list := reflect.ValueOf(items)
+	sparseItems := make([]map[string]json.RawMessage, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		data, err := json.Marshal(list.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		var properties map[string]json.RawMessage
+		if err = json.Unmarshal(data, &properties); err != nil {
+			return nil, err
+		}
+		sparseItem := make(map[string]json.RawMessage)
+		for name, property := range properties {
+			if fields.set[name] {
+				sparseItem[name] = property
+			}
+		}
+		sparseItems = append(sparseItems, sparseItem)
+	}
+	return sparseItems, nil
+}
+
+// getJSONProperties returns the JSON property names of the given struct (or struct pointer) type
+func getJSONProperties(itemType reflect.Type) map[string]bool {
+	for itemType.Kind() == reflect.Ptr {
+		itemType = itemType.Elem()
+	}
+	properties := make(map[string]bool)
+	for i := 0; i < itemType.NumField(); i++ {
+		field := itemType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = true
+	}
+	return properties
+}
+
+// sortedKeys returns the keys of the given set in order
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
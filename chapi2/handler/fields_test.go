@@ -0,0 +1,63 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestGetFieldsRequest(t *testing.T) {
+	tests := []struct {
+		query   string
+		fields  []string
+		invalid bool
+	}{
+		{"", nil, false},
+		{"?fields=", nil, false},
+		{"?fields=serial_number", []string{"serial_number"}, false},
+		{"?fields=size,%20serial_number,size,", []string{"size", "serial_number"}, false},
+		{"?fields=serial_number,paths", nil, true},
+		{"?fields=Private", nil, true},
+	}
+	for _, test := range tests {
+		fields, err := getFieldsRequest(httptest.NewRequest("GET", "/api/v1/devices/details"+test.query, nil), model.Device{})
+		if test.invalid != (err != nil) || !reflect.DeepEqual(fields.list(), test.fields) {
+			t.Errorf("query %q, unexpected fields %v, err=%v", test.query, fields.list(), err)
+		}
+	}
+}
+
+func TestFieldsRequestApply(t *testing.T) {
+	devices := []*model.Device{
+		{SerialNumber: "c5a28c28a2487d3d6c9ce900584f2795", Pathname: "dm-3", Size: 1024},
+		{SerialNumber: "f4c97c5c1cd391756c9ce900584f2795", Pathname: "dm-4"},
+	}
+
+	var fields *fieldsRequest
+	if items, err := fields.apply(devices); err != nil || !reflect.DeepEqual(items, devices) {
+		t.Errorf("expected devices returned as is, got %v, err=%v", items, err)
+	}
+
+	fields, _ = getFieldsRequest(httptest.NewRequest("GET", "/api/v1/devices/details?fields=serial_number,size", nil), model.Device{})
+	items, err := fields.apply(devices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(items)
+	expected := `[{"serial_number":"c5a28c28a2487d3d6c9ce900584f2795","size":1024},{"serial_number":"f4c97c5c1cd391756c9ce900584f2795"}]`
+	if string(data) != expected {
+		t.Errorf("expected %v, got %v", expected, string(data))
+	}
+
+	// Sparse items can still be paged
+	page := &pageRequest{paged: true, limit: 1, offset: 1}
+	pageItems, pageResult := page.apply(items)
+	if data, _ = json.Marshal(pageItems); string(data) != `[{"serial_number":"f4c97c5c1cd391756c9ce900584f2795"}]` || pageResult.Total != 2 {
+		t.Errorf("unexpected page %v, %+v", string(data), pageResult)
+	}
+}
@@ -5,12 +5,16 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	chapiDriver "github.com/hpe-storage/common-host-libs/chapi2/driver"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/timing"
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
 
@@ -23,14 +27,20 @@ const (
 	errorMessageEmptyFileSystem       = "empty filesystem type passed in the request"
 	errorMessageEmptyMountID          = "empty mount id passed in the request"
 	errorMessageEmptySerialNumber     = "empty serial number passed in the request"
+	errorMessageEmptyWWID             = "empty wwid passed in the request"
 	errorMessageHTTPHeaderNotProvided = "http.Header not provided for authorization"
+	errorMessageInvalidField          = "invalid field %v, expected one of (%v)"
+	errorMessageInvalidPageLimit      = "invalid limit %v, must be between 1 and %v"
+	errorMessageInvalidPageOffset     = "invalid offset %v, must be 0 or more"
 	errorMessageInvalidToken          = "invalid token: "
+	errorMessageMissingPublishInfo    = "publish info not passed in the request"
 	errorMessageTokenNotSupplied      = "local access token not supplied"
 )
 
 //Response :
 type Response struct {
 	Data interface{} `json:"data,omitempty"`
+	Page *model.Page `json:"page,omitempty"` // Page of the list returned, if requested (see PAGINATION)
 	Err  interface{} `json:"errors,omitempty"`
 }
 
@@ -105,6 +115,196 @@ func GetHostInitiators(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(chapiResp)
 }
 
+//@APIVersion 1.0.0
+//@Title RotateIscsiCredentials
+//@Description rotate the iSCSI initiator name and/or CHAP credentials, logging in again the targets one connection at a time
+//@Accept json
+//@Resource /api/v1/initiators
+//@Success 200 {array} ReloginResult
+//@Router /api/v1/initiators/actions/rotate-credentials [put]
+func RotateIscsiCredentials(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var rotation model.CredentialRotation
+	err := decodeRequest(r, &rotation)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	results, err := driver.RotateIscsiCredentials(&rotation)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.InvalidArgument {
+			statusCode = http.StatusBadRequest
+		}
+		handleError(w, chapiResp, err, statusCode)
+		return
+	}
+	chapiResp.Data = results
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetUnconnectedTargets
+//@Description get configured iSCSI targets without an active session
+//@Accept json
+//@Resource /api/v1/targets/unconnected
+//@Success 200 UnconnectedTargets
+//@Router /api/v1/targets/unconnected [get]
+func GetUnconnectedTargets(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	page, err := getPageRequest(r)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusBadRequest)
+		return
+	}
+
+	targets, err := driver.GetUnconnectedTargets()
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	sort.SliceStable(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+	chapiResp.Data, chapiResp.Page = page.apply(targets)
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetFcTargetPorts
+//@Description get the remote FC target ports discovered by the host ports
+//@Accept json
+//@Resource /api/v1/targets/fc
+//@Success 200 FcTargetPorts
+//@Router /api/v1/targets/fc [get]
+func GetFcTargetPorts(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+
+	targetPorts, err := driver.GetFcTargetPorts()
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	sort.SliceStable(targetPorts, func(i, j int) bool { return targetPorts[i].PortWwn < targetPorts[j].PortWwn })
+	chapiResp.Data = targetPorts
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title CollectStaleSessions
+//@Description log out the iSCSI targets that have had no LUN for longer than the stale session age
+//@Accept json
+//@Resource /api/v1/targets
+//@Success 200 {array} StaleTarget
+//@Router /api/v1/targets/actions/collect-stale-sessions [put]
+func CollectStaleSessions(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var collection model.StaleSessionCollection
+	err := decodeRequest(r, &collection)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	staleTargets, err := driver.CollectStaleSessions(&collection)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.InvalidArgument {
+			statusCode = http.StatusBadRequest
+		}
+		handleError(w, chapiResp, err, statusCode)
+		return
+	}
+	chapiResp.Data = staleTargets
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title InvalidateLunMaps
+//@Description drop the cached LUN maps of the given iSCSI targets, or every cached LUN map
+//@Accept json
+//@Resource /api/v1/targets
+//@Success 200 {array} string
+//@Router /api/v1/targets/actions/invalidate-lun-maps [put]
+func InvalidateLunMaps(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var invalidation model.LunMapInvalidation
+	err := decodeRequest(r, &invalidation)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	targetNames, err := driver.InvalidateLunMaps(&invalidation)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = targetNames
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetReadiness
+//@Description get host readiness to use HPE storage
+//@Accept json
+//@Resource /api/v1/readiness
+//@Success 200 Readiness
+//@Router /api/v1/readiness [get]
+func GetReadiness(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+
+	readiness, err := driver.GetReadiness()
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = readiness
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetCapabilities
+//@Description get the features supported on this host's platform
+//@Accept json
+//@Resource /api/v1/capabilities
+//@Success 200 Capabilities
+//@Router /api/v1/capabilities [get]
+func GetCapabilities(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+
+	capabilities, err := driver.GetCapabilities()
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = capabilities
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
 //@APIVersion 1.0.0
 //@Title GetDevices
 //@Description retrieves all devices on host, optionally with serial filter
@@ -116,20 +316,25 @@ func GetDevices(w http.ResponseWriter, r *http.Request) {
 	if !validateRequestHeader(w, r) {
 		return
 	}
-	var chapiResp Response
 	serialNumber := ""
 	keys, ok := r.URL.Query()["serial"]
 
 	if ok && len(keys[0]) > 0 {
 		serialNumber = keys[0]
 	}
-	devices, err := driver.GetDevices(serialNumber)
+	page, err := getPageRequest(r)
 	if err != nil {
-		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		handleError(w, Response{}, err, http.StatusBadRequest)
 		return
 	}
-	chapiResp.Data = devices
-	json.NewEncoder(w).Encode(chapiResp)
+	handleCachedRequest(func() (interface{}, error) {
+		devices, err := driver.GetDevices(serialNumber)
+		if err != nil {
+			return nil, err
+		}
+		sortDevices(devices)
+		return page.data(devices), nil
+	}, w, r)
 }
 
 //@APIVersion 1.0.0
@@ -143,20 +348,34 @@ func GetAllDeviceDetails(w http.ResponseWriter, r *http.Request) {
 	if !validateRequestHeader(w, r) {
 		return
 	}
-	var chapiResp Response
 	serialNumber := ""
 	keys, ok := r.URL.Query()["serial"]
 
 	if ok && len(keys[0]) > 0 {
 		serialNumber = keys[0]
 	}
-	devices, err := driver.GetAllDeviceDetails(serialNumber)
+	page, err := getPageRequest(r)
 	if err != nil {
-		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		handleError(w, Response{}, err, http.StatusBadRequest)
 		return
 	}
-	chapiResp.Data = devices
-	json.NewEncoder(w).Encode(chapiResp)
+	fields, err := getFieldsRequest(r, model.Device{})
+	if err != nil {
+		handleError(w, Response{}, err, http.StatusBadRequest)
+		return
+	}
+	handleCachedRequest(func() (interface{}, error) {
+		devices, err := driver.GetDeviceDetailFields(serialNumber, fields.list())
+		if err != nil {
+			return nil, err
+		}
+		sortDevices(devices)
+		items, err := fields.apply(devices)
+		if err != nil {
+			return nil, err
+		}
+		return page.data(items), nil
+	}, w, r)
 }
 
 //@APIVersion 1.0.0
@@ -204,24 +423,71 @@ func CreateDevice(w http.ResponseWriter, r *http.Request) {
 	var chapiResp Response
 
 	var publishInfo *model.PublishInfo
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&publishInfo)
+	err := decodeRequest(r, &publishInfo)
 	defer r.Body.Close()
 
 	if err != nil {
-		handleError(w, chapiResp, err, http.StatusBadRequest)
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+	if publishInfo == nil {
+		handleError(w, chapiResp, errors.New(errorMessageMissingPublishInfo), http.StatusBadRequest)
+		return
+	}
+	if !checkDeviceClaim(w, r, publishInfo.SerialNumber) {
 		return
 	}
 
+	defer timing.TrackVolume(r.Context(), publishInfo.SerialNumber)()
 	devices, err := driver.CreateDevice(*publishInfo)
 	if err != nil {
-		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		handleError(w, chapiResp, err, createDeviceStatusCode(err))
 		return
 	}
+	if timings := timing.FromContext(r.Context()); timings != nil {
+		devices.Timings = timings.Report()
+	}
 	chapiResp.Data = devices
 	json.NewEncoder(w).Encode(chapiResp)
 }
 
+// PlanCreateDevice : report how the device would be attached for the PublishInfo passed
+//@APIVersion 1.0.0
+//@Title PlanCreateDevice
+//@Description report how nimble device would be attached for the PublishInfo passed, without attaching it
+//@Accept json
+//@Resource /api/v1/devices/actions/plan
+//@Success 200 AttachPlan
+//@Router /api/v1/devices/actions/plan [post]
+func PlanCreateDevice(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+
+	// The publish info is not validated here as its invalid properties are reported as blockers
+	var publishInfo *model.PublishInfo
+	err := decodeRequestBody(r, &publishInfo)
+	defer r.Body.Close()
+
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+	if publishInfo == nil {
+		handleError(w, chapiResp, errors.New(errorMessageMissingPublishInfo), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := driver.PlanCreateDevice(*publishInfo)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = plan
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
 // DeleteDevice : disconnect and delete the device from the host
 //@APIVersion 1.0.0
 //@Title DeleteDevice
@@ -243,6 +509,10 @@ func DeleteDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkDeviceClaim(w, r, serialNumber) {
+		return
+	}
+
 	err := driver.DeleteDevice(serialNumber)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
@@ -273,6 +543,10 @@ func OfflineDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkDeviceClaim(w, r, serialNumber) {
+		return
+	}
+
 	err := driver.OfflineDevice(serialNumber)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
@@ -284,9 +558,43 @@ func OfflineDevice(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+//@APIVersion 1.0.0
+//@Title ExtendPartition
+//@Description extend the last partition on the device with specific serialNumber to fill the device
+//@Accept json
+//@Resource /api/v1/devices/{serialNumber}
+//@Success 200 {array} DevicePartition
+//@Router /api/v1/devices/{serialNumber}/actions/extend-partition [put]
+func ExtendPartition(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
+
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	if !checkDeviceClaim(w, r, serialNumber) {
+		return
+	}
+
+	partitions, err := driver.ExtendPartition(serialNumber)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+
+	chapiResp.Data = partitions
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
 //@APIVersion 1.0.0
 //@Title CreateFileSystem on device
-//@Description create a filesysten on the device serialnumber=serialnumber
+//@Description create a filesysten on the device serialnumber=serialnumber, with optional FileSystemOptions
 //@Accept json
 //@Resource /api/v1/devices/{serialNumber}/filesystem/{fileSystem}
 //@Success 200 {array}
@@ -310,7 +618,21 @@ func CreateFileSystem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := driver.CreateFileSystem(serialNumber, fileSystem)
+	if !checkDeviceClaim(w, r, serialNumber) {
+		return
+	}
+
+	// File system options (e.g. NoDiscard) are optional
+	var fsOptions *model.FileSystemOptions
+	err := decodeRequest(r, &fsOptions)
+	defer r.Body.Close()
+	if err != nil && err != io.EOF {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	defer timing.TrackVolume(r.Context(), serialNumber)()
+	err = driver.CreateFileSystemWithOptions(serialNumber, fileSystem, fsOptions)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
 		return
@@ -319,115 +641,611 @@ func CreateFileSystem(w http.ResponseWriter, r *http.Request) {
 }
 
 //@APIVersion 1.0.0
-//@Title GetMounts
-//@Description retrieves all mounts on host, optionally with serial filter
+//@Title GetDeviceTuning
+//@Description get the queue settings of the device with serialnumber=serialnumber (Linux only)
 //@Accept json
-//@Resource /api/v1/mounts
-//@Success 200 {array} Mounts
-//@Router /api/v1/mounts [get]
-func GetMounts(w http.ResponseWriter, r *http.Request) {
+//@Resource /api/v1/devices/{serialNumber}/tuning
+//@Success 200 DeviceTuning
+//@Router /api/v1/devices/{serialNumber}/tuning [get]
+func GetDeviceTuning(w http.ResponseWriter, r *http.Request) {
 	if !validateRequestHeader(w, r) {
 		return
 	}
 	var chapiResp Response
-	serialNumber := ""
-	keys, ok := r.URL.Query()["serial"]
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
 
-	if ok && len(keys[0]) > 0 {
-		serialNumber = keys[0]
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
 	}
-	mounts, err := driver.GetMounts(serialNumber)
+
+	tuning, err := driver.GetDeviceTuning(serialNumber)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
 		return
 	}
-	chapiResp.Data = mounts
+	chapiResp.Data = tuning
 	json.NewEncoder(w).Encode(chapiResp)
 }
 
 //@APIVersion 1.0.0
-//@Title GetAllMountDetails
-//@Description retrieves all mount details on host, optionally with serial filter
+//@Title GetFormatStatus
+//@Description get the progress of the file system creation in progress on the device with serialnumber=serialnumber
 //@Accept json
-//@Resource /api/v1/mounts
-//@Success 200 {array} Mounts
-//@Router /api/v1/mounts/details [get]
-func GetAllMountDetails(w http.ResponseWriter, r *http.Request) {
+//@Resource /api/v1/devices/{serialNumber}/format
+//@Success 200 FormatStatus
+//@Router /api/v1/devices/{serialNumber}/format [get]
+func GetFormatStatus(w http.ResponseWriter, r *http.Request) {
 	if !validateRequestHeader(w, r) {
 		return
 	}
 	var chapiResp Response
-	serialNumber := ""
-	mountId := ""
-	keys, ok := r.URL.Query()["serial"]
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
 
-	if ok && len(keys[0]) > 0 {
-		serialNumber = keys[0]
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
 	}
 
-	keys, ok = r.URL.Query()["mountId"]
-	if ok && len(keys[0]) > 0 {
-		mountId = keys[0]
-	}
-	mounts, err := driver.GetAllMountDetails(serialNumber, mountId)
+	status, err := driver.GetFormatStatus(serialNumber)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
 		return
 	}
-	chapiResp.Data = mounts
+	chapiResp.Data = status
 	json.NewEncoder(w).Encode(chapiResp)
 }
 
 //@APIVersion 1.0.0
-//@Title  CreateMount
-//@Description Mount an attached device with a details passed in the request
+//@Title GetDevicePaths
+//@Description get the paths, grouped by ALUA access state, of the device with serialnumber=serialnumber
 //@Accept json
-//@Resource /api/v1/mounts
-//@Success 200 {array} Mount
-//@Router /api/v1/mounts [post]
-func CreateMount(w http.ResponseWriter, r *http.Request) {
+//@Resource /api/v1/devices/{serialNumber}/paths
+//@Success 200 {array} DevicePathGroup
+//@Router /api/v1/devices/{serialNumber}/paths [get]
+func GetDevicePaths(w http.ResponseWriter, r *http.Request) {
 	if !validateRequestHeader(w, r) {
 		return
 	}
 	var chapiResp Response
-	var mount *model.Mount
-
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&mount)
-	defer r.Body.Close()
-
-	if err != nil {
-		handleError(w, chapiResp, err, http.StatusBadRequest)
-		return
-	}
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
 
-	if mount.SerialNumber == "" {
+	if serialNumber == "" {
 		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
 		return
 	}
 
-	mnt, err := driver.CreateMount(mount.SerialNumber, mount.MountPoint, mount.FsOpts)
+	pathGroups, err := driver.GetDevicePaths(serialNumber)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
 		return
 	}
-	chapiResp.Data = mnt
+	chapiResp.Data = pathGroups
 	json.NewEncoder(w).Encode(chapiResp)
 }
 
 //@APIVersion 1.0.0
-//@Title  DeleteMount
-//@Description Unmount specified mount point on the host
+//@Title RecoverDevicePaths
+//@Description reinstate the failed paths, that are reachable again, of the device with serialnumber=serialnumber
 //@Accept json
-//@Resource /mounts
-//@Success 200 {array} Mount
-//@Router /api/v1/mounts/{mountId} [delete]
-func DeleteMount(w http.ResponseWriter, r *http.Request) {
+//@Resource /api/v1/devices/{serialNumber}/actions/recover-paths
+//@Success 200 PathRecoveryReport
+//@Router /api/v1/devices/{serialNumber}/actions/recover-paths [put]
+func RecoverDevicePaths(w http.ResponseWriter, r *http.Request) {
 	if !validateRequestHeader(w, r) {
 		return
 	}
 	var chapiResp Response
-	var serialNumber string
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
+
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	report, err := driver.RecoverDevicePaths(serialNumber)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = report
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title BenchmarkDevice
+//@Description run a short read-only latency probe against the device with serialnumber=serialnumber
+//@Accept json
+//@Resource /api/v1/devices/{serialNumber}/actions/benchmark
+//@Success 200 BenchmarkResult
+//@Router /api/v1/devices/{serialNumber}/actions/benchmark [post]
+func BenchmarkDevice(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
+
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	// Benchmark settings are optional (defaults are used if not provided)
+	var request model.BenchmarkRequest
+	err := decodeRequest(r, &request)
+	defer r.Body.Close()
+	if err != nil && err != io.EOF {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	result, err := driver.BenchmarkDevice(serialNumber, request)
+	if err != nil {
+		handleError(w, chapiResp, err, benchmarkDeviceStatusCode(err))
+		return
+	}
+	chapiResp.Data = result
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title SetDeviceTuning
+//@Description apply queue settings to the device with serialnumber=serialnumber (Linux only)
+//@Accept json
+//@Resource /api/v1/devices/{serialNumber}/tuning
+//@Success 200 DeviceTuning
+//@Router /api/v1/devices/{serialNumber}/tuning [put]
+func SetDeviceTuning(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
+
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	if !checkDeviceClaim(w, r, serialNumber) {
+		return
+	}
+
+	var tuning model.DeviceTuning
+	err := decodeRequest(r, &tuning)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	currentTuning, err := driver.SetDeviceTuning(serialNumber, tuning)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = currentTuning
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetIgnoredDevices
+//@Description retrieves the device WWIDs hidden from device enumeration
+//@Accept json
+//@Resource /api/v1/devices/ignored
+//@Success 200 {array} IgnoredDevices
+//@Router /api/v1/devices/ignored [get]
+func GetIgnoredDevices(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	page, err := getPageRequest(r)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusBadRequest)
+		return
+	}
+	ignoredDevices, err := driver.GetIgnoredDevices()
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	sort.SliceStable(ignoredDevices, func(i, j int) bool { return ignoredDevices[i].WWID < ignoredDevices[j].WWID })
+	chapiResp.Data, chapiResp.Page = page.apply(ignoredDevices)
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title AddIgnoredDevice
+//@Description hide the device WWID passed in the request from device enumeration
+//@Accept json
+//@Resource /api/v1/devices/ignored
+//@Success 200 IgnoredDevice
+//@Router /api/v1/devices/ignored [post]
+func AddIgnoredDevice(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var ignoredDevice model.IgnoredDevice
+
+	err := decodeRequest(r, &ignoredDevice)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	if ignoredDevice.WWID == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptyWWID), http.StatusBadRequest)
+		return
+	}
+
+	added, err := driver.AddIgnoredDevice(ignoredDevice.WWID)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = added
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title RemoveIgnoredDevice
+//@Description stop hiding the device with wwid=wwid from device enumeration
+//@Accept json
+//@Resource /api/v1/devices/ignored/{wwid}
+//@Success 200
+//@Router /api/v1/devices/ignored/{wwid} [delete]
+func RemoveIgnoredDevice(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	vars := mux.Vars(r)
+	wwid := vars["wwid"]
+
+	if wwid == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptyWWID), http.StatusBadRequest)
+		return
+	}
+
+	err := driver.RemoveIgnoredDevice(wwid)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.NotFound {
+			statusCode = http.StatusNotFound
+		}
+		handleError(w, chapiResp, err, statusCode)
+		return
+	}
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetDeviceClaim
+//@Description get the ownership claim on the device with serialnumber=serialnumber
+//@Accept json
+//@Resource /api/v1/devices/{serialNumber}/claim
+//@Success 200 DeviceClaim
+//@Router /api/v1/devices/{serialNumber}/claim [get]
+func GetDeviceClaim(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
+
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	claim, err := driver.GetDeviceClaim(serialNumber)
+	if err != nil {
+		handleError(w, chapiResp, err, deviceClaimStatusCode(err))
+		return
+	}
+	chapiResp.Data = claim
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title ClaimDevice
+//@Description claim the device with serialnumber=serialnumber for the owner passed in the request
+//@Accept json
+//@Resource /api/v1/devices/{serialNumber}/claim
+//@Success 200 DeviceClaim
+//@Router /api/v1/devices/{serialNumber}/claim [put]
+func ClaimDevice(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
+
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	var request model.ClaimRequest
+	err := decodeRequest(r, &request)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	claim, err := driver.ClaimDevice(serialNumber, request)
+	if err != nil {
+		handleError(w, chapiResp, err, deviceClaimStatusCode(err))
+		return
+	}
+	chapiResp.Data = claim
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title ReleaseDevice
+//@Description release the owner's claim on the device with serialnumber=serialnumber
+//@Accept json
+//@Resource /api/v1/devices/{serialNumber}/claim
+//@Success 200
+//@Router /api/v1/devices/{serialNumber}/claim [delete]
+func ReleaseDevice(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	vars := mux.Vars(r)
+	serialNumber := vars["serialNumber"]
+
+	if serialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	var request model.ClaimRequest
+	err := decodeRequest(r, &request)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	err = driver.ReleaseDevice(serialNumber, request)
+	if err != nil {
+		handleError(w, chapiResp, err, deviceClaimStatusCode(err))
+		return
+	}
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetMounts
+//@Description retrieves all mounts on host, optionally with serial filter
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 {array} Mounts
+//@Router /api/v1/mounts [get]
+func GetMounts(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	serialNumber := ""
+	keys, ok := r.URL.Query()["serial"]
+
+	if ok && len(keys[0]) > 0 {
+		serialNumber = keys[0]
+	}
+	mountPointPrefix := r.URL.Query().Get("mountPointPrefix")
+	page, err := getPageRequest(r)
+	if err != nil {
+		handleError(w, Response{}, err, http.StatusBadRequest)
+		return
+	}
+	handleCachedRequest(func() (interface{}, error) {
+		mounts, err := driver.GetMountsWithPrefix(serialNumber, mountPointPrefix)
+		if err != nil {
+			return nil, err
+		}
+		sortMounts(mounts)
+		return page.data(mounts), nil
+	}, w, r)
+}
+
+//@APIVersion 1.0.0
+//@Title GetAllMountDetails
+//@Description retrieves all mount details on host, optionally with serial filter
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 {array} Mounts
+//@Router /api/v1/mounts/details [get]
+func GetAllMountDetails(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	serialNumber := ""
+	mountId := ""
+	keys, ok := r.URL.Query()["serial"]
+
+	if ok && len(keys[0]) > 0 {
+		serialNumber = keys[0]
+	}
+
+	keys, ok = r.URL.Query()["mountId"]
+	if ok && len(keys[0]) > 0 {
+		mountId = keys[0]
+	}
+	mountPointPrefix := r.URL.Query().Get("mountPointPrefix")
+	page, err := getPageRequest(r)
+	if err != nil {
+		handleError(w, Response{}, err, http.StatusBadRequest)
+		return
+	}
+	fields, err := getFieldsRequest(r, model.Mount{})
+	if err != nil {
+		handleError(w, Response{}, err, http.StatusBadRequest)
+		return
+	}
+	handleCachedRequest(func() (interface{}, error) {
+		mounts, err := driver.GetAllMountDetailsWithPrefix(serialNumber, mountId, mountPointPrefix)
+		if err != nil {
+			return nil, err
+		}
+		sortMounts(mounts)
+		items, err := fields.apply(mounts)
+		if err != nil {
+			return nil, err
+		}
+		return page.data(items), nil
+	}, w, r)
+}
+
+//@APIVersion 1.0.0
+//@Title GetFreeDriveLetters
+//@Description retrieves the free drive letters that can be automatically assigned (Windows only)
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 {array} string
+//@Router /api/v1/mounts/driveletters [get]
+func GetFreeDriveLetters(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+
+	driveLetters, err := driver.GetFreeDriveLetters()
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = driveLetters
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title  CreateMount
+//@Description Mount an attached device with a details passed in the request
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 {array} Mount
+//@Router /api/v1/mounts [post]
+func CreateMount(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var mount *model.Mount
+
+	err := decodeRequest(r, &mount)
+	defer r.Body.Close()
+
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	if mount.SerialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	if !checkDeviceClaim(w, r, mount.SerialNumber) {
+		return
+	}
+
+	defer timing.TrackVolume(r.Context(), mount.SerialNumber)()
+	mnt, err := driver.CreateMount(mount.SerialNumber, mount.MountPoint, mount.FsOpts)
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	if timings := timing.FromContext(r.Context()); timings != nil {
+		mnt.Timings = timings.Report()
+	}
+	chapiResp.Data = mnt
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title  DeleteMount
+//@Description Unmount specified mount point on the host, optionally lazy=true to detach a busy mount
+//@Accept json
+//@Resource /mounts
+//@Success 200 {array} Mount
+//@Router /api/v1/mounts/{mountId} [delete]
+func DeleteMount(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var serialNumber string
+	vars := mux.Vars(r)
+	mountId := vars["mountId"]
+	if mountId == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptyMountID), http.StatusBadRequest)
+		return
+	}
+
+	err := decodeRequest(r, &serialNumber)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	lazy := false
+	keys, ok := r.URL.Query()["lazy"]
+	if ok && len(keys[0]) > 0 {
+		if lazy, err = strconv.ParseBool(keys[0]); err != nil {
+			handleError(w, chapiResp, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !checkDeviceClaim(w, r, serialNumber) {
+		return
+	}
+
+	err = driver.DeleteMountWithOptions(serialNumber, mountId, &model.DeleteMountOptions{Lazy: lazy})
+	if err != nil {
+		// A busy mount point is reported as a conflict along with the processes holding it
+		statusCode := http.StatusInternalServerError
+		if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.Busy {
+			statusCode = http.StatusConflict
+		}
+		handleError(w, chapiResp, err, statusCode)
+		return
+	}
+
+	chapiResp.Data = &model.Mount{}
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title MoveMount
+//@Description Move specified mount point to the mount point passed in the request without unmounting the volume
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 Mount
+//@Router /api/v1/mounts/{mountId}/move [put]
+func MoveMount(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var mount *model.Mount
 	vars := mux.Vars(r)
 	mountId := vars["mountId"]
 	if mountId == "" {
@@ -435,24 +1253,449 @@ func DeleteMount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&serialNumber)
+	err := decodeRequest(r, &mount)
 	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	if mount == nil || mount.SerialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return
+	}
+
+	if !checkDeviceClaim(w, r, mount.SerialNumber) {
+		return
+	}
+
+	mnt, err := driver.MoveMount(mount.SerialNumber, mountId, mount.MountPoint)
+	if err != nil {
+		handleError(w, chapiResp, err, moveMountStatusCode(err))
+		return
+	}
+	chapiResp.Data = mnt
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetOrphanedMounts
+//@Description retrieves the orphaned mount points beneath the given mount roots
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 {array} OrphanedMounts
+//@Router /api/v1/mounts/orphans [get]
+func GetOrphanedMounts(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	page, err := getPageRequest(r)
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusBadRequest)
 		return
 	}
+	roots := r.URL.Query()["root"]
+	orphans, err := driver.GetOrphanedMounts(roots)
+	if err != nil {
+		handleError(w, chapiResp, err, orphanedMountsStatusCode(err))
+		return
+	}
+	sort.SliceStable(orphans, func(i, j int) bool { return orphans[i].Path < orphans[j].Path })
+	chapiResp.Data, chapiResp.Page = page.apply(orphans)
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title DeleteOrphanedMounts
+//@Description removes the orphaned mount points beneath the given mount roots
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 {array} OrphanedMounts
+//@Router /api/v1/mounts/orphans [delete]
+func DeleteOrphanedMounts(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	roots := r.URL.Query()["root"]
+	orphans, err := driver.DeleteOrphanedMounts(roots)
+	if err != nil {
+		handleError(w, chapiResp, err, orphanedMountsStatusCode(err))
+		return
+	}
+	chapiResp.Data = orphans
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title FreezeMount
+//@Description quiesces the mount point identified by serial number and/or mount point ID
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 QuiescedMount
+//@Router /api/v1/mounts/actions/freeze [put]
+func FreezeMount(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var request model.QuiesceRequest
+	err := decodeRequest(r, &request)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	quiesced, err := driver.FreezeMount(&request)
+	if err != nil {
+		handleError(w, chapiResp, err, quiesceStatusCode(err))
+		return
+	}
+	chapiResp.Data = quiesced
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title ThawMount
+//@Description resumes I/O on a mount point frozen by FreezeMount
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200
+//@Router /api/v1/mounts/actions/thaw [put]
+func ThawMount(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var request model.QuiesceRequest
+	err := decodeRequest(r, &request)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	if err = driver.ThawMount(&request); err != nil {
+		handleError(w, chapiResp, err, quiesceStatusCode(err))
+		return
+	}
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title StageMount
+//@Description mount a volume once at its staging path
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 Mount
+//@Router /api/v1/mounts/actions/stage [put]
+func StageMount(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeStagedMountRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var chapiResp Response
+	defer timing.TrackVolume(r.Context(), request.SerialNumber)()
+	mnt, err := driver.StageMount(request)
+	if err != nil {
+		handleError(w, chapiResp, err, stagedMountStatusCode(err))
+		return
+	}
+	if timings := timing.FromContext(r.Context()); timings != nil {
+		mnt.Timings = timings.Report()
+	}
+	chapiResp.Data = mnt
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title PublishMount
+//@Description bind mount a volume's staging path to a target path
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200 Mount
+//@Router /api/v1/mounts/actions/publish [put]
+func PublishMount(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeStagedMountRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var chapiResp Response
+	mnt, err := driver.PublishMount(request)
+	if err != nil {
+		handleError(w, chapiResp, err, stagedMountStatusCode(err))
+		return
+	}
+	chapiResp.Data = mnt
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title UnpublishMount
+//@Description remove a volume's bind mount at a target path
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200
+//@Router /api/v1/mounts/actions/unpublish [put]
+func UnpublishMount(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeStagedMountRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var chapiResp Response
+	if err := driver.UnpublishMount(request); err != nil {
+		handleError(w, chapiResp, err, stagedMountStatusCode(err))
+		return
+	}
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title UnstageMount
+//@Description unmount a volume from its staging path once it is no longer published
+//@Accept json
+//@Resource /api/v1/mounts
+//@Success 200
+//@Router /api/v1/mounts/actions/unstage [put]
+func UnstageMount(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodeStagedMountRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var chapiResp Response
+	if err := driver.UnstageMount(request); err != nil {
+		handleError(w, chapiResp, err, stagedMountStatusCode(err))
+		return
+	}
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+// decodeStagedMountRequest decodes and validates a staged mount request.  false is returned, once
+// the error response is written, if the request is invalid or the device is claimed by another
+// agent.
+func decodeStagedMountRequest(w http.ResponseWriter, r *http.Request) (*model.StagedMountRequest, bool) {
+	if !validateRequestHeader(w, r) {
+		return nil, false
+	}
+	var chapiResp Response
+	var request model.StagedMountRequest
+	err := decodeRequest(r, &request)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return nil, false
+	}
+
+	if request.SerialNumber == "" {
+		handleError(w, chapiResp, errors.New(errorMessageEmptySerialNumber), http.StatusBadRequest)
+		return nil, false
+	}
+	if !checkDeviceClaim(w, r, request.SerialNumber) {
+		return nil, false
+	}
+	return &request, true
+}
+
+//@APIVersion 1.0.0
+//@Title DrainNode
+//@Description unmounts, flushes and detaches the given volumes, returning the outcome of each volume
+//@Accept json
+//@Resource /api/v1/node
+//@Success 200 {array} DrainResult
+//@Router /api/v1/node/drain [post]
+func DrainNode(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+	var request model.DrainRequest
+	err := decodeRequest(r, &request)
+	defer r.Body.Close()
+	if err != nil {
+		handleError(w, chapiResp, err, decodeStatusCode(err))
+		return
+	}
+
+	results, err := driver.DrainNode(&request)
+	if err != nil {
+		handleError(w, chapiResp, err, drainNodeStatusCode(err))
+		return
+	}
+	chapiResp.Data = results
+	json.NewEncoder(w).Encode(chapiResp)
+}
+
+//@APIVersion 1.0.0
+//@Title GetManagedState
+//@Description retrieves the devices and mount points created through CHAPI and whether each is still present
+//@Accept json
+//@Resource /api/v1/state
+//@Success 200 ManagedState
+//@Router /api/v1/state [get]
+func GetManagedState(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
 
-	err = driver.DeleteMount(serialNumber, mountId)
+	managedState, err := driver.GetManagedState()
 	if err != nil {
 		handleError(w, chapiResp, err, http.StatusInternalServerError)
 		return
 	}
+	chapiResp.Data = managedState
+	json.NewEncoder(w).Encode(chapiResp)
+}
 
-	chapiResp.Data = &model.Mount{}
+//@APIVersion 1.0.0
+//@Title ReconcileManagedState
+//@Description removes the records of the devices and mount points no longer present on the host
+//@Accept json
+//@Resource /api/v1/state
+//@Success 200 ManagedState
+//@Router /api/v1/state/actions/reconcile [put]
+func ReconcileManagedState(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	var chapiResp Response
+
+	managedState, err := driver.ReconcileManagedState()
+	if err != nil {
+		handleError(w, chapiResp, err, http.StatusInternalServerError)
+		return
+	}
+	chapiResp.Data = managedState
 	json.NewEncoder(w).Encode(chapiResp)
 }
 
+// quiesceStatusCode returns the HTTP status code for a freeze/thaw request failure
+func quiesceStatusCode(err error) int {
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok {
+		switch chapiErr.Code {
+		case cerrors.InvalidArgument:
+			return http.StatusBadRequest
+		case cerrors.NotFound:
+			return http.StatusNotFound
+		case cerrors.AlreadyExists:
+			return http.StatusConflict
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// stagedMountStatusCode returns the HTTP status code for a staged mount request failure.  A staging
+// path still published is reported as a conflict along with its target paths.
+func stagedMountStatusCode(err error) int {
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok {
+		switch chapiErr.Code {
+		case cerrors.InvalidArgument:
+			return http.StatusBadRequest
+		case cerrors.NotFound:
+			return http.StatusNotFound
+		case cerrors.AlreadyExists, cerrors.Busy, cerrors.ReadOnly:
+			return http.StatusConflict
+		case cerrors.Unimplemented:
+			return http.StatusNotImplemented
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// moveMountStatusCode returns the HTTP status code for a MoveMount request failure.  A busy mount
+// point is reported as a conflict along with the processes holding it.
+func moveMountStatusCode(err error) int {
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok {
+		switch chapiErr.Code {
+		case cerrors.InvalidArgument:
+			return http.StatusBadRequest
+		case cerrors.AlreadyExists, cerrors.Busy:
+			return http.StatusConflict
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// createDeviceStatusCode returns the HTTP status code for a CreateDevice request failure.  Invalid
+// publish info (see model.PublishInfo.Validate) is a client error, and a volume reserved by
+// another host conflicts with the request.
+func createDeviceStatusCode(err error) int {
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok {
+		switch chapiErr.Code {
+		case cerrors.InvalidArgument:
+			return http.StatusBadRequest
+		case cerrors.ReservationConflict:
+			return http.StatusConflict
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// orphanedMountsStatusCode returns the HTTP status code for an orphaned mounts request failure
+func orphanedMountsStatusCode(err error) int {
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.InvalidArgument {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// benchmarkDeviceStatusCode returns the HTTP status code for a device benchmark request failure
+func benchmarkDeviceStatusCode(err error) int {
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok {
+		switch chapiErr.Code {
+		case cerrors.InvalidArgument:
+			return http.StatusBadRequest
+		case cerrors.NotFound:
+			return http.StatusNotFound
+		case cerrors.Aborted:
+			return http.StatusConflict
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// drainNodeStatusCode returns the HTTP status code for a node drain request failure
+func drainNodeStatusCode(err error) int {
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok && chapiErr.Code == cerrors.InvalidArgument {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// deviceClaimStatusCode returns the HTTP status code for a device claim request failure
+func deviceClaimStatusCode(err error) int {
+	if chapiErr, ok := err.(*cerrors.ChapiError); ok {
+		switch chapiErr.Code {
+		case cerrors.InvalidArgument:
+			return http.StatusBadRequest
+		case cerrors.NotFound:
+			return http.StatusNotFound
+		case cerrors.PermissionDenied:
+			return http.StatusForbidden
+		case cerrors.AlreadyExists:
+			return http.StatusConflict
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// checkDeviceClaim fails the request, and returns false, if the device is claimed by an owner other
+// than the one in the request's model.OwnerHeader (see DEVICE OWNERSHIP in driver_claim.go)
+func checkDeviceClaim(w http.ResponseWriter, r *http.Request, serialNumber string) bool {
+	override, _ := strconv.ParseBool(r.Header.Get(model.ClaimOverrideHeader))
+	if err := chapiDriver.CheckDeviceClaim(serialNumber, r.Header.Get(model.OwnerHeader), override); err != nil {
+		handleError(w, Response{}, err, deviceClaimStatusCode(err))
+		return false
+	}
+	return true
+}
+
 // standard method for handling requests
 func handleRequest(function func() (interface{}, error), functionName string, w http.ResponseWriter, r *http.Request) {
 	var chapiResp Response
@@ -55,9 +55,9 @@ func GetDeletingDevices(w http.ResponseWriter, r *http.Request) {
 //@Title GetChapInfo
 //@Description get iSCSI CHAP info configured on host
 //@Accept json
-//@Resource /api/v1/chap
+//@Resource /api/v1/chapinfo
 //@Success 200 chapi2.ChapInfo
-//@Router /api/v1/chap [get]
+//@Router /api/v1/chapinfo [get]
 func GetChapInfo(w http.ResponseWriter, r *http.Request) {
 	function := func() (interface{}, error) {
 		return linux.GetChapInfo()
@@ -14,6 +14,7 @@ import (
 
 	"github.com/hectane/go-acl/api"
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/windows/advapi32"
@@ -75,6 +76,23 @@ func GetKeyfile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(chapiResp)
 }
 
+//@APIVersion 1.0.0
+//@Title GetTargetMappings
+//@Description reports how the LUNs of each active iSCSI session are mapped to OS SCSI addresses
+//@Accept json
+//@Resource /api/v1/targets/mappings
+//@Success 200 {array} IscsiTargetMapping
+//@Router /api/v1/targets/mappings [get]
+func GetTargetMappings(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	function := func() (interface{}, error) {
+		return iscsi.NewIscsiPlugin().GetTargetMappings(r.URL.Query().Get("target"))
+	}
+	handleRequest(function, "GetTargetMappings", w, r)
+}
+
 // CHAPI for Windows clients send an authorization key in the request header for every CHAPI endpoint
 // except for the "keyfile" endpoint.  The "keyfile" endpoint is used to retrieve the location of the
 // key file.  The key is then retrieved from that file.  Only processes with administrator access can
@@ -84,6 +102,11 @@ func GetKeyfile(w http.ResponseWriter, r *http.Request) {
 // True is returned if the header is valid.
 func validateRequestHeader(w http.ResponseWriter, r *http.Request) bool {
 
+	// Requests received on the read-only diagnostics listener are not authenticated
+	if isDiagnosticsRequest(r) {
+		return true
+	}
+
 	status := false
 	var err error
 	if (r == nil) || (r.Header == nil) {
@@ -0,0 +1,74 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/connectivity"
+)
+
+// validator is implemented by the model objects that validate their own properties (e.g.
+// model.PublishInfo)
+type validator interface {
+	Validate() error
+}
+
+var (
+	// requestLimits are the limits applied to the request bodies, set by the router
+	requestLimits     = connectivity.DefaultRequestLimits()
+	requestLimitsLock sync.RWMutex
+)
+
+// SetRequestLimits sets the limits applied to the request bodies (see connectivity.GetRequestLimits)
+func SetRequestLimits(limits *connectivity.Limits) {
+	requestLimitsLock.Lock()
+	defer requestLimitsLock.Unlock()
+	requestLimits = limits
+}
+
+// decodeRequest decodes the JSON request body into dest within the request limits.  If the decoded
+// object provides a Validate() method, an InvalidArgument error listing every invalid property is
+// returned when validation fails.
+func decodeRequest(r *http.Request, dest interface{}) error {
+	if err := decodeRequestBody(r, dest); err != nil {
+		return err
+	}
+	return validateRequest(dest)
+}
+
+// decodeRequestBody decodes the JSON request body into dest within the request limits, without
+// validating the decoded object
+func decodeRequestBody(r *http.Request, dest interface{}) error {
+	requestLimitsLock.RLock()
+	limits := requestLimits
+	requestLimitsLock.RUnlock()
+	return connectivity.DecodeJSON(r.Body, dest, limits)
+}
+
+// validateRequest validates the decoded request object.  dest may be a pointer to the object or,
+// for optional request bodies, a pointer to a nil or non-nil pointer to the object.
+func validateRequest(dest interface{}) error {
+	for value := reflect.ValueOf(dest); value.Kind() == reflect.Ptr && !value.IsNil(); value = value.Elem() {
+		if object, ok := value.Interface().(validator); ok {
+			if err := object.Validate(); err != nil {
+				return cerrors.NewChapiError(cerrors.InvalidArgument, model.ValidationErrorMessage(value.Type().Elem().Name(), err)).WithDetails(err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// decodeStatusCode returns the HTTP status code of a request body decoding error
+func decodeStatusCode(err error) int {
+	if errors.Is(err, connectivity.ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,62 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/connectivity"
+)
+
+func TestDecodeRequestLimits(t *testing.T) {
+	defer SetRequestLimits(connectivity.DefaultRequestLimits())
+
+	var dest map[string]string
+	body := `{"name":"` + strings.Repeat("x", 16) + `"}`
+	if err := decodeRequest(httptest.NewRequest("POST", "/api/v1/devices", strings.NewReader(body)), &dest); err != nil {
+		t.Fatalf("unable to decode request within the default limits, err=%v", err)
+	}
+
+	SetRequestLimits(&connectivity.Limits{MaxBytes: 8})
+	err := decodeRequest(httptest.NewRequest("POST", "/api/v1/devices", strings.NewReader(body)), &dest)
+	if err != connectivity.ErrBodyTooLarge {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", err)
+	}
+	if statusCode := decodeStatusCode(err); statusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status code %v, got %v", http.StatusRequestEntityTooLarge, statusCode)
+	}
+}
+
+func TestDecodeRequestValidation(t *testing.T) {
+	// Optional request bodies are decoded into a pointer to the object
+	var publishInfo *model.PublishInfo
+	body := `{"serial_number":"28174883c7719ac236c9ce900584f2795","block_device":{"access_protocol":"iscsi"}}`
+	err := decodeRequest(httptest.NewRequest("POST", "/api/v1/devices", strings.NewReader(body)), &publishInfo)
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.InvalidArgument {
+		t.Fatalf("expected an InvalidArgument error, got %v", err)
+	}
+	if statusCode := decodeStatusCode(err); statusCode != http.StatusBadRequest {
+		t.Errorf("expected status code %v, got %v", http.StatusBadRequest, statusCode)
+	}
+
+	// The plan request is decoded without validation and an empty body is left to the handler
+	publishInfo = nil
+	if err = decodeRequestBody(httptest.NewRequest("POST", "/api/v1/devices/actions/plan", strings.NewReader(body)), &publishInfo); err != nil || publishInfo == nil {
+		t.Errorf("unable to decode the publish info without validation, err=%v", err)
+	}
+	publishInfo = nil
+	if err = decodeRequest(httptest.NewRequest("POST", "/api/v1/devices", strings.NewReader("null")), &publishInfo); err != nil || publishInfo != nil {
+		t.Errorf("unexpected publish info %+v, err=%v", publishInfo, err)
+	}
+
+	// Request objects are also validated when decoded by value
+	var request model.BenchmarkRequest
+	if err = decodeRequest(httptest.NewRequest("PUT", "/api/v1/devices", strings.NewReader(`{"block_size":1000}`)), &request); err == nil {
+		t.Error("invalid benchmark request was accepted")
+	}
+}
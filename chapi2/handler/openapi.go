@@ -0,0 +1,59 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/openapi"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	errorMessageOpenAPIUnavailable = "OpenAPI document not available"
+)
+
+var (
+	// openAPIDocument is the OpenAPI document served by GetOpenAPI, set by the router
+	openAPIDocument     *openapi.Document
+	openAPIDocumentLock sync.RWMutex
+)
+
+// SetOpenAPIDocument sets the OpenAPI document describing the CHAPI endpoints
+func SetOpenAPIDocument(document *openapi.Document) {
+	openAPIDocumentLock.Lock()
+	defer openAPIDocumentLock.Unlock()
+	openAPIDocument = document
+}
+
+//@APIVersion 1.0.0
+//@Title GetOpenAPI
+//@Description retrieves the OpenAPI 3 document describing the CHAPI endpoints
+//@Accept json
+//@Resource /api/v1/openapi.json
+//@Success 200 Document
+//@Router /api/v1/openapi.json [get]
+func GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !validateRequestHeader(w, r) {
+		return
+	}
+	log.Trace(">>>>> GetOpenAPI")
+	defer log.Trace("<<<<< GetOpenAPI")
+
+	openAPIDocumentLock.RLock()
+	document := openAPIDocument
+	openAPIDocumentLock.RUnlock()
+
+	if document == nil {
+		var chapiResp Response
+		handleError(w, chapiResp, cerrors.NewChapiError(cerrors.Unimplemented, errorMessageOpenAPIUnavailable), http.StatusNotImplemented)
+		return
+	}
+
+	// The document is returned as is, not in the "data" envelope, so that OpenAPI tooling can
+	// consume it directly
+	json.NewEncoder(w).Encode(document)
+}
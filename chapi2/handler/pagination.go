@@ -0,0 +1,130 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// PAGINATION
+//
+//		List endpoints (e.g. devices and mounts) accept the optional "limit" and "offset" query
+//		parameters.  If either is given, only that page of the list is returned in the "data"
+//		property, and the response's "page" property reports the offset, limit, total number of
+//		items and the offset of the next page (omitted on the last page).  Lists are always
+//		returned in a deterministic order so that consecutive pages neither skip nor repeat items
+//		while the host is unchanged.  Without either parameter the whole list is returned, without
+//		a "page" property, as before.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+const (
+	queryLimit  = "limit"
+	queryOffset = "offset"
+
+	pageLimitMax = 1000 // Largest page that can be requested
+)
+
+// pageRequest is the page of a list requested through the limit and offset query parameters
+type pageRequest struct {
+	paged  bool // True if the limit or offset query parameter was given
+	offset int
+	limit  int // 0 if unlimited
+}
+
+// pagedData is a page of a list along with its page description
+type pagedData struct {
+	items interface{}
+	page  *model.Page
+}
+
+// getPageRequest parses the limit and offset query parameters of the request
+func getPageRequest(r *http.Request) (*pageRequest, error) {
+	page := &pageRequest{}
+	query := r.URL.Query()
+	if value := query.Get(queryLimit); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > pageLimitMax {
+			return nil, cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidPageLimit, value, pageLimitMax)
+		}
+		page.paged, page.limit = true, limit
+	}
+	if value := query.Get(queryOffset); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return nil, cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidPageOffset, value)
+		}
+		page.paged, page.offset = true, offset
+	}
+	return page, nil
+}
+
+// apply returns the requested page of the given (sorted) slice and its page description.  The
+// slice is returned as is, without a page description, if no page was requested.
+func (page *pageRequest) apply(items interface{}) (interface{}, *model.Page) {
+	if !page.paged {
+		return items, nil
+	}
+	list := reflect.ValueOf(items)
+	total := list.Len()
+	start, end := page.offset, total
+	if start > total {
+		start = total
+	}
+	if page.limit != 0 && start+page.limit < total {
+		end = start + page.limit
+	}
+
+	result := &model.Page{Offset: page.offset, Limit: page.limit, Total: total}
+	if end < total {
+		result.NextOffset = end
+	}
+	return list.Slice(start, end).Interface(), result
+}
+
+// data returns the requested page of the given (sorted) slice to be returned by a
+// handleCachedRequest function
+func (page *pageRequest) data(items interface{}) interface{} {
+	pageItems, pageResult := page.apply(items)
+	if pageResult == nil {
+		return pageItems
+	}
+	return &pagedData{items: pageItems, page: pageResult}
+}
+
+// setResponseData sets the response data, and page if the data is a page of a list
+func setResponseData(chapiResp *Response, data interface{}) {
+	if paged, ok := data.(*pagedData); ok {
+		chapiResp.Data, chapiResp.Page = paged.items, paged.page
+		return
+	}
+	chapiResp.Data = data
+}
+
+// sortDevices orders devices by serial number (and path name for devices with the same serial)
+func sortDevices(devices []*model.Device) {
+	sort.SliceStable(devices, func(i, j int) bool {
+		if devices[i].SerialNumber != devices[j].SerialNumber {
+			return devices[i].SerialNumber < devices[j].SerialNumber
+		}
+		return devices[i].Pathname < devices[j].Pathname
+	})
+}
+
+// sortMounts orders mounts by mount point (and ID for mounts with the same mount point)
+func sortMounts(mounts []*model.Mount) {
+	sort.SliceStable(mounts, func(i, j int) bool {
+		if mounts[i].MountPoint != mounts[j].MountPoint {
+			return mounts[i].MountPoint < mounts[j].MountPoint
+		}
+		return mounts[i].ID < mounts[j].ID
+	})
+}
@@ -0,0 +1,90 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestGetPageRequest(t *testing.T) {
+	tests := []struct {
+		query   string
+		page    *pageRequest
+		invalid bool
+	}{
+		{"", &pageRequest{}, false},
+		{"?serial=abc", &pageRequest{}, false},
+		{"?limit=10", &pageRequest{paged: true, limit: 10}, false},
+		{"?offset=20", &pageRequest{paged: true, offset: 20}, false},
+		{"?limit=10&offset=20", &pageRequest{paged: true, limit: 10, offset: 20}, false},
+		{"?limit=0", nil, true},
+		{"?limit=1001", nil, true},
+		{"?limit=ten", nil, true},
+		{"?offset=-1", nil, true},
+	}
+	for _, test := range tests {
+		page, err := getPageRequest(httptest.NewRequest("GET", "/api/v1/devices"+test.query, nil))
+		if test.invalid != (err != nil) || !reflect.DeepEqual(page, test.page) {
+			t.Errorf("query %q, unexpected page %+v, err=%v", test.query, page, err)
+		}
+	}
+}
+
+func TestPageRequestApply(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		page  *pageRequest
+		items []string
+		next  int
+	}{
+		{&pageRequest{paged: true, limit: 2}, []string{"a", "b"}, 2},
+		{&pageRequest{paged: true, limit: 2, offset: 2}, []string{"c", "d"}, 4},
+		{&pageRequest{paged: true, limit: 2, offset: 4}, []string{"e"}, 0},
+		{&pageRequest{paged: true, offset: 3}, []string{"d", "e"}, 0},
+		{&pageRequest{paged: true, limit: 2, offset: 9}, []string{}, 0},
+	}
+	for _, test := range tests {
+		pageItems, page := test.page.apply(items)
+		if !reflect.DeepEqual(pageItems, test.items) || page == nil || page.Total != len(items) ||
+			page.Offset != test.page.offset || page.Limit != test.page.limit || page.NextOffset != test.next {
+			t.Errorf("page %+v, unexpected items %v, page %+v", test.page, pageItems, page)
+		}
+	}
+
+	// The whole list is returned, without a page, if no page was requested
+	if pageItems, page := (&pageRequest{}).apply(items); !reflect.DeepEqual(pageItems, items) || page != nil {
+		t.Errorf("unexpected unpaged items %v, page %+v", pageItems, page)
+	}
+}
+
+func TestHandleCachedRequestPage(t *testing.T) {
+	devices := []*model.Device{{SerialNumber: "c"}, {SerialNumber: "a"}, {SerialNumber: "b"}}
+	sortDevices(devices)
+	page := &pageRequest{paged: true, limit: 2}
+
+	r := httptest.NewRequest("GET", "/api/v1/devices?limit=2", nil)
+	w := httptest.NewRecorder()
+	handleCachedRequest(func() (interface{}, error) { return page.data(devices), nil }, w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected response code %v", w.Code)
+	}
+	var response struct {
+		Data []*model.Device `json:"data"`
+		Page *model.Page     `json:"page"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data) != 2 || response.Data[0].SerialNumber != "a" || response.Data[1].SerialNumber != "b" {
+		t.Errorf("unexpected devices %+v", response.Data)
+	}
+	if response.Page == nil || *response.Page != (model.Page{Limit: 2, Total: 3, NextOffset: 2}) {
+		t.Errorf("unexpected page %+v", response.Page)
+	}
+}
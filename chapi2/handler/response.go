@@ -0,0 +1,149 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"gopkg.in/yaml.v3"
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// CONTENT NEGOTIATION
+//
+//		Handlers always encode their responses as JSON.  For human and CLI consumption, GET
+//		requests whose Accept header prefers YAML have the JSON response converted to YAML by
+//		ContentNegotiationHandler.  Object keys are kept in the order the JSON encoder wrote
+//		them.  If the response cannot be converted, the JSON response is returned unmodified.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+const (
+	headerAccept        = "Accept"
+	headerContentLength = "Content-Length"
+
+	contentTypeYAML = "application/yaml"
+
+	etagYAMLSuffix = "-yaml" // Appended to the ETag of YAML responses
+)
+
+// yamlContentTypes are the Accept media types that request a YAML response
+var yamlContentTypes = map[string]bool{
+	contentTypeYAML:      true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// ContentNegotiationHandler wraps a GET endpoint handler to convert its JSON response to YAML if
+// the request's Accept header prefers it
+func ContentNegotiationHandler(handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !acceptsYAML(r) {
+			handlerFunc(w, r)
+			return
+		}
+		yamlWriter := &yamlResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handlerFunc(yamlWriter, r)
+		yamlWriter.flush()
+	}
+}
+
+// acceptsYAML returns true if the request's Accept header prefers a YAML response.  The media
+// type with the highest quality wins, with ties going to the first listed.  Wildcards never
+// select YAML.
+func acceptsYAML(r *http.Request) bool {
+	accept := r.Header.Get(headerAccept)
+	if accept == "" {
+		return false
+	}
+
+	preferred := ""
+	preferredQuality := 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > preferredQuality {
+			preferred, preferredQuality = mediaType, quality
+		}
+	}
+	return yamlContentTypes[preferred]
+}
+
+// yamlResponseWriter buffers a JSON response so it can be written as YAML once the handler returns
+type yamlResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the response status code
+func (yamlWriter *yamlResponseWriter) WriteHeader(status int) {
+	yamlWriter.status = status
+}
+
+// Write buffers the JSON response body
+func (yamlWriter *yamlResponseWriter) Write(data []byte) (int, error) {
+	return yamlWriter.body.Write(data)
+}
+
+// flush converts the buffered JSON response to YAML and writes it
+func (yamlWriter *yamlResponseWriter) flush() {
+	header := yamlWriter.Header()
+	header.Add(headerVary, headerAccept)
+	body := yamlWriter.body.Bytes()
+	if len(body) != 0 {
+		if yamlBody, err := jsonToYAML(body); err != nil {
+			log.Errorf("Unable to convert response to YAML, returning JSON, err=%v", err)
+		} else {
+			body = yamlBody
+			header.Set(headerContentType, contentTypeYAML)
+			header.Del(headerContentLength)
+		}
+	}
+	yamlWriter.ResponseWriter.WriteHeader(yamlWriter.status)
+	yamlWriter.ResponseWriter.Write(body)
+}
+
+// jsonToYAML converts a JSON document to YAML, preserving the order of object keys
+func jsonToYAML(jsonBody []byte) ([]byte, error) {
+	// JSON is a subset of YAML, so it can be decoded straight into a YAML node tree
+	var node yaml.Node
+	if err := yaml.Unmarshal(jsonBody, &node); err != nil {
+		return nil, err
+	}
+	clearNodeStyle(&node)
+
+	var yamlBody bytes.Buffer
+	encoder := yaml.NewEncoder(&yamlBody)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return yamlBody.Bytes(), nil
+}
+
+// clearNodeStyle resets the JSON flow and quoting styles so the node tree is encoded in block style
+func clearNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearNodeStyle(child)
+	}
+}
@@ -0,0 +1,94 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsYAML(t *testing.T) {
+	testCases := []struct {
+		accept string
+		yaml   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/yaml", true},
+		{"text/x-yaml", true},
+		{"application/json, application/yaml", false},
+		{"application/json;q=0.5, application/yaml", true},
+		{"Application/YAML;q=0.9, */*;q=0.1", true},
+	}
+	for _, testCase := range testCases {
+		r := httptest.NewRequest("GET", "/api/v1/hosts", nil)
+		r.Header.Set(headerAccept, testCase.accept)
+		if acceptsYAML(r) != testCase.yaml {
+			t.Errorf("Accept %q, expected YAML=%v", testCase.accept, testCase.yaml)
+		}
+	}
+}
+
+func TestContentNegotiationHandler(t *testing.T) {
+	handlerFunc := ContentNegotiationHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Response{Data: map[string]interface{}{"name": "host1", "id": "123", "enabled": "true", "ips": []string{"10.1.2.3"}}})
+	})
+
+	// YAML requested, keys remain sorted as encoded and strings stay strings
+	r := httptest.NewRequest("GET", "/api/v1/hosts", nil)
+	r.Header.Set(headerAccept, contentTypeYAML)
+	w := httptest.NewRecorder()
+	handlerFunc(w, r)
+	expected := "data:\n  enabled: \"true\"\n  id: \"123\"\n  ips:\n    - 10.1.2.3\n  name: host1\n"
+	if w.Code != http.StatusNotFound || w.Header().Get(headerContentType) != contentTypeYAML || w.Body.String() != expected {
+		t.Errorf("unexpected YAML response, code=%v, headers=%v, body=%q", w.Code, w.Header(), w.Body.String())
+	}
+
+	// JSON by default
+	r.Header.Del(headerAccept)
+	w = httptest.NewRecorder()
+	handlerFunc(w, r)
+	if w.Header().Get(headerContentType) == contentTypeYAML || w.Body.Bytes()[0] != '{' {
+		t.Errorf("unexpected JSON response, headers=%v, body=%q", w.Header(), w.Body.String())
+	}
+}
+
+func TestContentNegotiationCachedRequest(t *testing.T) {
+	function := func() (interface{}, error) {
+		return []string{strings.Repeat("x", gzipMinBodySize)}, nil
+	}
+	handlerFunc := ContentNegotiationHandler(func(w http.ResponseWriter, r *http.Request) {
+		handleCachedRequest(function, w, r)
+	})
+
+	// YAML responses are not compressed and have their own ETag
+	r := httptest.NewRequest("GET", "/api/v1/devices/details", nil)
+	r.Header.Set(headerAccept, contentTypeYAML)
+	r.Header.Set(headerAcceptEncoding, gzipEncoding)
+	w := httptest.NewRecorder()
+	handlerFunc(w, r)
+	etag := w.Header().Get(headerETag)
+	if w.Code != http.StatusOK || w.Header().Get(headerContentEncoding) != "" || !strings.HasSuffix(etag, etagYAMLSuffix+`"`) ||
+		!strings.HasPrefix(w.Body.String(), "data:\n  - xxx") {
+		t.Fatalf("unexpected response, code=%v, headers=%v", w.Code, w.Header())
+	}
+
+	// The YAML ETag is not modified, but does not match the JSON response
+	r.Header.Set(headerIfNoneMatch, etag)
+	w = httptest.NewRecorder()
+	handlerFunc(w, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected not modified, code=%v", w.Code)
+	}
+	r.Header.Del(headerAccept)
+	w = httptest.NewRecorder()
+	handlerFunc(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("YAML ETag matched JSON response, code=%v", w.Code)
+	}
+}
@@ -10,7 +10,9 @@ import (
 
 const (
 	// Shared error messages
+	errorMessageEmptyFile                 = "%v is empty"
 	errorMessageInvalidIpv4Address        = "invalid ipv4 address or mask provided to get network address"
+	errorMessageOperatingSystemNotFound   = "unable to determine the operating system version"
 	errorMessageUnableToDetermineHostName = "unable to determine host domain name"
 	errorMessageUnableToParseIP           = "unable to parse ip address. Error:  %s"
 	errorMessageUnableToParseMask         = "unable to parse network mask %s"
@@ -54,3 +56,14 @@ func (plugin *HostPlugin) GetNetworks() ([]*model.Network, error) {
 	}
 	return networks, nil
 }
+
+// GetKernelVersion returns the kernel release (Linux) or the Windows version
+func (plugin *HostPlugin) GetKernelVersion() (string, error) {
+	return getKernelVersion()
+}
+
+// GetStorageModules returns the versions of the kernel modules (Linux) or drivers (Windows)
+// affecting storage
+func (plugin *HostPlugin) GetStorageModules() ([]*model.StorageModule, error) {
+	return getStorageModules()
+}
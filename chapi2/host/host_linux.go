@@ -4,8 +4,10 @@ package host
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -28,6 +30,11 @@ var (
 	maskFmt                = "%d.%d.%d.%d"
 	linkStatusPattern      = "\\s+Link detected:\\s+yes"
 	machineIdFile          = "/etc/machine-id"
+	kernelReleaseFile      = "/proc/sys/kernel/osrelease"
+	sysModulePath          = "/sys/module"
+
+	// Kernel modules affecting storage, reported with the host information
+	storageModuleNames = []string{"dm_multipath", "scsi_dh_alua", "iscsi_tcp", "nvme_core"}
 )
 
 func getHostId() (string, error) {
@@ -242,3 +249,37 @@ func getDomainName() (string, error) {
 	}
 	return "", cerrors.NewChapiError(cerrors.NotFound, errorMessageUnableToDetermineHostName)
 }
+
+func getKernelVersion() (string, error) {
+	lines, err := util.FileGetStrings(kernelReleaseFile)
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "", cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageEmptyFile, kernelReleaseFile)
+	}
+	return strings.TrimSpace(lines[0]), nil
+}
+
+// getStorageModules returns the storage kernel modules.  Modules built with the kernel have no
+// version of their own, the kernel release is reported instead.
+func getStorageModules() ([]*model.StorageModule, error) {
+	kernelVersion, err := getKernelVersion()
+	if err != nil {
+		return nil, err
+	}
+	var modules []*model.StorageModule
+	for _, name := range storageModuleNames {
+		module := &model.StorageModule{Name: name}
+		if _, err := os.Stat(filepath.Join(sysModulePath, name)); err == nil {
+			module.Loaded = true
+			module.Version = kernelVersion
+			if version, err := ioutil.ReadFile(filepath.Join(sysModulePath, name, "version")); err == nil && len(version) != 0 {
+				module.Version = strings.TrimSpace(string(version))
+			}
+		}
+		log.Tracef("Storage module %v, loaded=%v, version=%v", module.Name, module.Loaded, module.Version)
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
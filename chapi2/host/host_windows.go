@@ -4,12 +4,14 @@ package host
 
 import (
 	"encoding/binary"
+	"fmt"
 	"net"
 	"strings"
 	"sync"
 	"syscall"
 	"unsafe"
 
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/windows/shlwapi"
@@ -24,6 +26,11 @@ const (
 	registryHostIDDefault = `6f67b7d2-2bf2-4662-88c8-26e7274384e7`
 )
 
+var (
+	// Drivers affecting storage, reported with the host information
+	storageDriverNames = []string{"msiscsi", "msdsm", "mpio"}
+)
+
 var (
 	hostId     string     // Enumerated host ID
 	hostIdLock sync.Mutex // Host ID lock
@@ -57,8 +64,8 @@ func getNetworkInterfaces() ([]*model.Network, error) {
 		return nil, err
 	}
 
-	// Enumerate the iSCSI initiators on this host
-	iscsiInitiators, err := wmi.GetMSiSCSIPortalInfoClass()
+	// Enumerate the iSCSI initiators (software initiator and any iSCSI HBAs) on this host
+	iscsiInitiators, err := wmi.GetMSiSCSIPortalInfoClasses()
 	if err != nil {
 		// It's possible that the host has NICs but the iSCSI service has not been configured yet.
 		// In this case, we simply log the event but allow NIC enumeration to continue.
@@ -106,11 +113,15 @@ func getNetworkInterfaces() ([]*model.Network, error) {
 				continue
 			}
 
-			// Traverse the list of enumerated ISCSI_PortalInfo objects to find the one that mathces
-			// the current network adapter.
+			// Traverse the list of enumerated ISCSI_PortalInfo objects, of each initiator instance,
+			// to find the one that matches the current network adapter.
 			var matchingPortal *wmi.ISCSI_PortalInfo
-			if iscsiInitiators != nil {
-				for _, portal := range iscsiInitiators.PortalInformation {
+			var matchingInitiator *wmi.MSiSCSI_PortalInfoClass
+			for _, iscsiInitiator := range iscsiInitiators {
+				if matchingPortal != nil {
+					break
+				}
+				for _, portal := range iscsiInitiator.PortalInformation {
 					// Skip portal if not IPv4
 					if (portal.IPAddr.Type != wmi.ISCSI_IP_ADDRESS_IPV4) || (portal.IPAddr.IpV4Address == 0) {
 						continue
@@ -130,6 +141,7 @@ func getNetworkInterfaces() ([]*model.Network, error) {
 					// Found ISCSI_PortalInfo match for the current network adapter?
 					if ipv4.String() == ipAddress {
 						matchingPortal = portal
+						matchingInitiator = iscsiInitiator
 						break
 					}
 				}
@@ -149,7 +161,7 @@ func getNetworkInterfaces() ([]*model.Network, error) {
 			// adapter, populate the Windows specific NetworkPrivate object
 			if matchingPortal != nil {
 				nic.Private = &model.NetworkPrivate{
-					InitiatorInstance:   iscsiInitiators.InstanceName,
+					InitiatorInstance:   matchingInitiator.InstanceName,
 					InitiatorPortNumber: matchingPortal.Index,
 				}
 			}
@@ -289,3 +301,47 @@ func getDomainName() (string, error) {
 	// Convert domain name from UTF16 to a Go string and return to caller
 	return syscall.UTF16ToString(dataBuffer[:]), nil
 }
+
+// Retrieve the Windows version (e.g. 10.0.17763)
+func getKernelVersion() (string, error) {
+	operatingSystem, err := wmi.GetWin32OperatingSystem()
+	if err != nil {
+		return "", err
+	}
+	if operatingSystem == nil {
+		return "", cerrors.NewChapiError(cerrors.NotFound, errorMessageOperatingSystemNotFound)
+	}
+	return operatingSystem.Version, nil
+}
+
+// Retrieve the storage drivers; the version is the driver file version (e.g. 10.0.17763.1)
+func getStorageModules() ([]*model.StorageModule, error) {
+	var modules []*model.StorageModule
+	for _, name := range storageDriverNames {
+		module := &model.StorageModule{Name: name}
+		drivers, err := wmi.GetWin32SystemDriver(fmt.Sprintf("Name='%v'", name))
+		if err != nil {
+			return nil, err
+		}
+		if len(drivers) != 0 {
+			module.Loaded = drivers[0].Started
+			module.Version = getDriverFileVersion(drivers[0].PathName)
+		}
+		log.Tracef("Storage driver %v, started=%v, version=%v", module.Name, module.Loaded, module.Version)
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+// Retrieve the file version of the given driver file, an empty string if not available
+func getDriverFileVersion(path string) string {
+	if path == "" {
+		return ""
+	}
+	dataFiles, err := wmi.GetCIMDataFile(path)
+	if err != nil || len(dataFiles) == 0 {
+		log.Tracef("Unable to get the driver file version, path=%v, err=%v", path, err)
+		return ""
+	}
+	return dataFiles[0].Version
+}
@@ -3,6 +3,8 @@
 package iscsi
 
 import (
+	"net"
+
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
@@ -17,25 +19,106 @@ const (
 const (
 	// Shared error messages
 	errorMessageConnectionFailed       = "connection failed"
+	errorMessageDiscoveryFailed        = "discovery through %v failed, err=%v"
 	errorMessageEmptyIqnFound          = "empty iqn found"
 	errorMessageFailedInquiry          = "failed Inquiry with scsiStatus=%v, len(inquiryBuffer)=%v"
 	errorMessageInvalidConnectionType  = `invalid connection type "%v"`
+	errorMessageInvalidIqn             = "invalid iSCSI initiator name %q"
 	errorMessageInvalidTargetScope     = "invalid target scope %v"
+	errorMessageInitiatorNotFound      = "iscsi initiator instance %v not found"
+	errorMessageInitiatorNoPorts       = "no network ports found for iscsi initiator instance %v"
+	errorMessageInitiatorPortNotBound  = "initiator port %v has no iscsi initiator portal"
+	errorMessageInitiatorPortMismatch  = "connection made from %v rather than initiator port %v"
+	errorMessageIqnInUse               = "cannot change the iSCSI initiator name, %v"
+	errorMessageIqnNotMigrated         = "iSCSI initiator name changed but %v target(s) were not logged back in"
 	errorMessageIscsiPathNotFound      = "%s not found to determine iscsi initiator name"
+	errorMessageLoginFailed            = "unable to log in to target %v, err=%v"
 	errorMessageLoginTimeout           = "logins not completed in time"
 	errorMessageMissingIscsiAccessInfo = "missing IscsiAccessInfo object"
 	errorMessageMissingIscsiTargetName = "missing iscsi target name"
 	errorMessageNoAvailableConnections = "no available connections"
 	errorMessageNoActiveConnections    = "no active connections on sessionId %x-%x"
 	errorMessageNoTargetScope          = "no sessions could report the target scope"
+	errorMessageNodeStartup            = "unable to set node.startup of target %v to %v, err=%v"
 	errorMessageNonNimbleTarget        = "non-Nimble target %v"
+	errorMessageSessionNoDevices       = "no SCSI devices on session %v"
+	errorMessageSessionState           = "session state %v"
+	errorMessageTargetNotDiscovered    = "target %v not discovered through %v"
 	errorMessageTargetNotFound         = "target not found"
 )
 
+// parseTargetScope returns the target scope reported in the given standard Inquiry data, which
+// must hold more than nimbleTargetScopeOffset bytes
+func parseTargetScope(inquiryBuffer []byte) (string, error) {
+	// Convert the vendor/product ID into a string
+	vendorProduct := string(inquiryBuffer[8:32])
+
+	// If this isn't a Nimble target, fail request
+	if vendorProduct != nimbleVendorProduct {
+		return "", cerrors.NewChapiErrorf(cerrors.Internal, errorMessageNonNimbleTarget, vendorProduct)
+	}
+
+	// Get the target scope value from the Inquiry data
+	targetScopeBits := inquiryBuffer[nimbleTargetScopeOffset] & 0x03
+	switch targetScopeBits {
+	case 0:
+		return model.TargetScopeVolume, nil
+	case 1:
+		return model.TargetScopeGroup, nil
+	}
+
+	// If an unexpected target scope is returned, fail request
+	return "", cerrors.NewChapiErrorf(cerrors.Internal, errorMessageInvalidTargetScope, targetScopeBits)
+}
+
+// connectTypeToArray takes the connectType string and returns an array of connection types that
+// reflect the input type.
+func (plugin *IscsiPlugin) connectTypeToArray(connectType string) (connectTypes []string, err error) {
+
+	// Determine how we should try to connect to the iSCSI target using the provided iSCSI
+	// ConnectType.  If property not provided, use the default value.
+	switch connectType {
+	case "", model.ConnectTypeDefault:
+		// If the default option is selected, we try multiple connection techniques to try and log
+		// into the iSCSI target.  We start with ConnectTypePing, then ConnectTypeSubnet and end
+		// with ConnectTypeAutoInitiator.
+		connectTypes = []string{model.ConnectTypePing, model.ConnectTypeSubnet, model.ConnectTypeAutoInitiator}
+	case model.ConnectTypePing, model.ConnectTypeSubnet, model.ConnectTypeAutoInitiator:
+		// Simple/singular connection type requested
+		connectTypes = []string{connectType}
+	default:
+		// Invalid / Unsupported connection type
+		err = cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidConnectionType, connectType)
+		log.Error(err)
+		return nil, err
+	}
+
+	return connectTypes, nil
+}
+
 // ITNexus - Initiator Port and Target Port
 type ITNexus struct {
 	initiatorPort *model.Network
 	targetPort    *model.TargetPortal
+	sourceAddress string // Initiator IP address the connection was actually made from, if known
+}
+
+// isAnyInitiatorPort returns true if the initiator port was not selected (e.g. with
+// model.ConnectTypeAutoInitiator), leaving the initiator to pick the port
+func isAnyInitiatorPort(initiatorPort *model.Network) bool {
+	return initiatorPort == nil || initiatorPort.AddressV4 == "" || initiatorPort.AddressV4 == "0.0.0.0"
+}
+
+// checkSourceAddress returns an error if a connection to the selected initiator port was made from
+// another initiator IP address (e.g. through the NIC of the default route on a multi-NIC host)
+func checkSourceAddress(initiatorPort *model.Network, sourceAddress string) error {
+	if isAnyInitiatorPort(initiatorPort) || sourceAddress == "" {
+		return nil
+	}
+	if sourceIP, portIP := net.ParseIP(sourceAddress), net.ParseIP(initiatorPort.AddressV4); sourceIP != nil && sourceIP.Equal(portIP) {
+		return nil
+	}
+	return cerrors.NewChapiErrorf(cerrors.ConnectionFailed, errorMessageInitiatorPortMismatch, sourceAddress, initiatorPort.AddressV4)
 }
 
 type IscsiPlugin struct {
@@ -55,10 +138,34 @@ func (plugin *IscsiPlugin) GetAllLoggedInTargets() ([]*model.IscsiTarget, error)
 	return nil, nil
 }
 
+// GetUnconnectedTargets returns the iSCSI targets that are configured on this host (Windows
+// persistent logins, Linux node records) but have no active session
+func (plugin *IscsiPlugin) GetUnconnectedTargets() ([]*model.UnconnectedTarget, error) {
+	log.Trace(">>>>> GetUnconnectedTargets")
+	defer log.Traceln("<<<<< GetUnconnectedTargets")
+
+	// Call platform specific module
+	return plugin.getUnconnectedTargets()
+}
+
+// GetTargetPortals returns the target portals of the given iSCSI target, deduplicated and filtered
+// by the address family policy (see PortalAddressFamilyEnv).  If ipv4Only is true, only the IPv4
+// target portals are returned, regardless of the policy.
 func (plugin *IscsiPlugin) GetTargetPortals(targetName string, ipv4Only bool) ([]*model.TargetPortal, error) {
 	log.Tracef(">>>>> GetTargetPortals, targetName=%v, ipv4Only=%v", targetName, ipv4Only)
 	defer log.Traceln("<<<<< GetTargetPortals")
-	return plugin.getTargetPortals(targetName, ipv4Only)
+
+	family := PortalAddressFamilyIPv4
+	if !ipv4Only {
+		family = getPortalAddressFamily()
+	}
+
+	// Call platform specific module
+	portals, err := plugin.getTargetPortals(targetName, family == PortalAddressFamilyIPv4)
+	if err != nil {
+		return nil, err
+	}
+	return selectTargetPortals(portals, family), nil
 }
 
 func (plugin *IscsiPlugin) GetSessionProperties(targetName string, sessionId string) (map[string]string, error) {
@@ -96,6 +203,7 @@ func (plugin *IscsiPlugin) LoginTarget(blockDev model.BlockDeviceAccessInfo) (er
 	// If there was an error logging into the iSCSI target, but connections remain, clean up
 	// after ourselves by logging out the target.
 	if err != nil {
+		log.WithEvent(log.EventIDLoginFailure).Errorf("Unable to log in to iSCSI target %v, err=%v", blockDev.TargetName, err)
 		if loggedIn, _ := plugin.IsTargetLoggedIn(blockDev.TargetName); loggedIn == true {
 			plugin.LogoutTarget(blockDev.TargetName)
 		}
@@ -121,7 +229,23 @@ func (plugin *IscsiPlugin) LogoutTarget(targetName string) error {
 	defer log.Traceln("<<<<< LogoutTarget")
 
 	// Call platform specific module
-	return plugin.logoutTarget(targetName)
+	if err := plugin.logoutTarget(targetName); err != nil {
+		return err
+	}
+
+	// Forget the discovery IP used to discover the target
+	setDiscoveryPortal(targetName, "")
+	return nil
+}
+
+// LogoutTargetSessions logs out the sessions of the given iSCSI target, keeping its persistent
+// logins (Windows) or node records (Linux) so that it is logged in again when the host starts
+func (plugin *IscsiPlugin) LogoutTargetSessions(targetName string) error {
+	log.Tracef(">>>>> LogoutTargetSessions, TargetName=%v", targetName)
+	defer log.Traceln("<<<<< LogoutTargetSessions")
+
+	// Call platform specific module
+	return plugin.logoutTargetSessions(targetName)
 }
 
 // GetIscsiInitiators returns the host's iSCSI initiator object
@@ -0,0 +1,67 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// DISCOVERY PORTAL FAILOVER
+//
+//		A target can be discovered through more than one discovery IP (IscsiAccessInfo.DiscoveryIP
+//		followed by IscsiAccessInfo.DiscoveryIPs).  At login, each discovery IP is tried in turn
+//		until the target is discovered; an unreachable discovery portal no longer fails the login
+//		while other discovery IPs remain.  Under Windows, a discovery portal registered by the
+//		login that did not discover the target is removed again.  Under Linux, the send targets
+//		discovery is not persisted; only the node records of the discovered target are created.
+//
+//		The discovery IP that discovered each target is recorded in the CHAPI state store (see
+//		chapi2/state) so that it can be reported in the device details (IscsiTarget.DiscoveryIP)
+//		after a CHAPI restart.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// State store accessors for the discovery portal records; variables so that tests can replace them
+var (
+	getDiscoveryPortal    = state.GetDiscoveryPortal
+	recordDiscoveryPortal = state.RecordDiscoveryPortal
+)
+
+// GetDiscoveryPortal returns the discovery IP through which the given target was discovered, or
+// an empty string if not known
+func (plugin *IscsiPlugin) GetDiscoveryPortal(targetName string) string {
+	discoveryIP, err := getDiscoveryPortal(strings.ToLower(targetName))
+	if err != nil {
+		log.Errorf("Unable to load discovery portal of target %v, err=%v", targetName, err)
+	}
+	return discoveryIP
+}
+
+// setDiscoveryPortal records the discovery IP through which the given target was discovered.  An
+// empty discovery IP removes the record.  Failures are logged but not fatal.
+func setDiscoveryPortal(targetName string, discoveryIP string) {
+	if err := recordDiscoveryPortal(strings.ToLower(targetName), discoveryIP); err != nil {
+		log.Errorf("Unable to record discovery portal of target %v, err=%v", targetName, err)
+	}
+}
+
+// getDiscoveryIPs returns the discovery IPs to try, in order, without duplicates
+func getDiscoveryIPs(iscsiAccessInfo *model.IscsiAccessInfo) []string {
+	var discoveryIPs []string
+	seen := make(map[string]bool)
+	for _, discoveryIP := range append([]string{iscsiAccessInfo.DiscoveryIP}, iscsiAccessInfo.DiscoveryIPs...) {
+		discoveryIP = strings.TrimSpace(discoveryIP)
+		if discoveryIP == "" || seen[strings.ToLower(discoveryIP)] {
+			continue
+		}
+		seen[strings.ToLower(discoveryIP)] = true
+		discoveryIPs = append(discoveryIPs, discoveryIP)
+	}
+	return discoveryIPs
+}
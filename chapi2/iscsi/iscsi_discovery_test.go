@@ -0,0 +1,51 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestGetDiscoveryIPs(t *testing.T) {
+	iscsiAccessInfo := &model.IscsiAccessInfo{
+		DiscoveryIP:  "10.1.1.10",
+		DiscoveryIPs: []string{"10.1.2.10", " 10.1.1.10", "", "Array1.example.com", "array1.example.com"},
+	}
+	expected := []string{"10.1.1.10", "10.1.2.10", "Array1.example.com"}
+	if discoveryIPs := getDiscoveryIPs(iscsiAccessInfo); !reflect.DeepEqual(discoveryIPs, expected) {
+		t.Errorf("expected %v, got %v", expected, discoveryIPs)
+	}
+
+	if discoveryIPs := getDiscoveryIPs(&model.IscsiAccessInfo{}); len(discoveryIPs) != 0 {
+		t.Errorf("expected no discovery IPs, got %v", discoveryIPs)
+	}
+}
+
+func TestDiscoveryPortal(t *testing.T) {
+	defer func(get func(string) (string, error), record func(string, string) error) {
+		getDiscoveryPortal, recordDiscoveryPortal = get, record
+	}(getDiscoveryPortal, recordDiscoveryPortal)
+	discoveryPortals := make(map[string]string)
+	getDiscoveryPortal = func(targetName string) (string, error) { return discoveryPortals[targetName], nil }
+	recordDiscoveryPortal = func(targetName string, discoveryIP string) error {
+		if discoveryIP == "" {
+			delete(discoveryPortals, targetName)
+		} else {
+			discoveryPortals[targetName] = discoveryIP
+		}
+		return nil
+	}
+
+	plugin := NewIscsiPlugin()
+	setDiscoveryPortal("iqn.2007-11.com.nimblestorage:connected", "10.1.2.10")
+	if discoveryIP := plugin.GetDiscoveryPortal("IQN.2007-11.com.nimblestorage:CONNECTED"); discoveryIP != "10.1.2.10" {
+		t.Errorf("expected 10.1.2.10, got %v", discoveryIP)
+	}
+	setDiscoveryPortal("iqn.2007-11.com.nimblestorage:connected", "")
+	if discoveryIP := plugin.GetDiscoveryPortal("iqn.2007-11.com.nimblestorage:connected"); discoveryIP != "" {
+		t.Errorf("expected no discovery IP, got %v", discoveryIP)
+	}
+}
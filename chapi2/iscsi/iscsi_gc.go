@@ -0,0 +1,159 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// STALE SESSION COLLECTION
+//
+//		When a volume's access is removed on the array without its device being deleted from the
+//		host, the sessions to its target stay logged in without any LUN.  They use connection
+//		slots on the array and slow down discovery and logins.  CollectStaleSessions finds the
+//		targets whose sessions report no mapped LUN (Windows ReportActiveIScsiTargetMappings, Linux
+//		sysfs) and logs them out, removing their persistent logins (Windows) or node records
+//		(Linux), once they have been seen without any LUN for at least the stale session age
+//		(StaleSessionAgeEnv, default 10 minutes).  The age keeps a target just logged in by
+//		CreateDevice, and whose LUNs are not reported yet, from being logged out.
+//
+//		The sessions are not timestamped by the OS, so a target's age is the time since it was
+//		first seen without any LUN by this process; a restarted CHAPI server starts over.  The
+//		collection is run on demand ("PUT /api/v1/targets/actions/collect-stale-sessions"), where
+//		the age can be overridden, or, if StaleSessionIntervalEnv is set, periodically by the stale
+//		session collector.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// StaleSessionAgeEnv is the time, in seconds, a target must have been seen without any LUN
+	// before its sessions are logged out
+	StaleSessionAgeEnv = config.StaleSessionAgeEnv
+
+	// StaleSessionIntervalEnv enables the stale session collector, logging out the stale targets
+	// at the given interval (in seconds)
+	StaleSessionIntervalEnv = config.StaleSessionIntervalEnv
+
+	// DefaultStaleSessionAge is the stale session age if StaleSessionAgeEnv is not set
+	DefaultStaleSessionAge = 10 * time.Minute
+
+	errorMessageInvalidStaleSessionAge = "invalid minimum age %v"
+)
+
+var (
+	// Session enumeration and logout routines; variables so that tests can replace them
+	getStaleSessionCandidates = getTargetSessions
+	getTargetLuns             = getTargetLunCounts
+	logoutStaleTarget         = func(targetName string) error {
+		return NewIscsiPlugin().LogoutTarget(targetName)
+	}
+	staleSessionNow = time.Now
+
+	// emptyTargets records when each target was first seen without any LUN
+	emptyTargets     = make(map[string]time.Time)
+	emptyTargetsLock sync.Mutex
+)
+
+// CollectStaleSessions logs out the targets that have been without any mapped LUN for at least the
+// stale session age (or the requested minimum age).  Every target without a LUN is returned, along
+// with whether it was logged out.
+func (plugin *IscsiPlugin) CollectStaleSessions(request *model.StaleSessionCollection) ([]*model.StaleTarget, error) {
+	log.Tracef(">>>>> CollectStaleSessions, targets=%v, dryRun=%v", request.Targets, request.DryRun)
+	defer log.Trace("<<<<< CollectStaleSessions")
+
+	minAge := getStaleSessionAge()
+	if request.MinAge != nil {
+		if *request.MinAge < 0 {
+			return nil, cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidStaleSessionAge, *request.MinAge)
+		}
+		minAge = time.Duration(*request.MinAge) * time.Second
+	}
+
+	emptyTargetsLock.Lock()
+	defer emptyTargetsLock.Unlock()
+
+	sessions, err := getStaleSessionCandidates()
+	if err != nil {
+		return nil, err
+	}
+	lunCounts, err := getTargetLuns()
+	if err != nil {
+		return nil, err
+	}
+
+	// A target is empty only if none of its sessions, logged in or not, reports a LUN
+	targetNames, targetSessions := groupTargetSessions(sessions, nil)
+	now := staleSessionNow()
+	seen := make(map[string]bool)
+	var staleTargets []*model.StaleTarget
+	for _, targetName := range targetNames {
+		if lunCounts[strings.ToLower(targetName)] != 0 {
+			continue
+		}
+		seen[targetName] = true
+		if _, ok := emptyTargets[targetName]; !ok {
+			emptyTargets[targetName] = now
+		}
+		if !isRequestedTarget(targetName, request.Targets) {
+			continue
+		}
+
+		emptyFor := now.Sub(emptyTargets[targetName])
+		staleTarget := &model.StaleTarget{
+			TargetName:   targetName,
+			Sessions:     len(targetSessions[targetName]),
+			EmptySeconds: int64(emptyFor / time.Second),
+		}
+		staleTargets = append(staleTargets, staleTarget)
+		if emptyFor < minAge || request.DryRun {
+			log.Infof("Target %v has had no LUN for %v, %v session(s) left logged in", targetName, emptyFor.Round(time.Second), staleTarget.Sessions)
+			continue
+		}
+
+		log.Infof("Logging out target %v, no LUN for %v, %v stale session(s)", targetName, emptyFor.Round(time.Second), staleTarget.Sessions)
+		if err = logoutStaleTarget(targetName); err != nil {
+			log.Errorf("Unable to logout stale target %v, err=%v", targetName, err)
+			staleTarget.Error = err.Error()
+			continue
+		}
+		staleTarget.LoggedOut = true
+		delete(seen, targetName)
+	}
+
+	// Forget the targets that were logged out, are gone or report LUNs again
+	for targetName := range emptyTargets {
+		if !seen[targetName] {
+			delete(emptyTargets, targetName)
+		}
+	}
+	return staleTargets, nil
+}
+
+// isRequestedTarget returns true if the target is one of the requested targets, or if no target
+// is requested
+func isRequestedTarget(targetName string, requestedTargets []string) bool {
+	if len(requestedTargets) == 0 {
+		return true
+	}
+	for _, requestedTarget := range requestedTargets {
+		if strings.EqualFold(requestedTarget, targetName) {
+			return true
+		}
+	}
+	return false
+}
+
+// getStaleSessionAge returns the time a target must have been seen without any LUN before its
+// sessions are logged out
+func getStaleSessionAge() time.Duration {
+	return config.Seconds(StaleSessionAgeEnv, DefaultStaleSessionAge, 0)
+}
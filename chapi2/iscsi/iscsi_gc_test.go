@@ -0,0 +1,145 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestCollectStaleSessions(t *testing.T) {
+	defer func(getSessions func() ([]*targetSession, error), getLuns func() (map[string]int, error),
+		logout func(string) error, now func() time.Time) {
+		getStaleSessionCandidates, getTargetLuns, logoutStaleTarget, staleSessionNow = getSessions, getLuns, logout, now
+		emptyTargets = make(map[string]time.Time)
+	}(getStaleSessionCandidates, getTargetLuns, logoutStaleTarget, staleSessionNow)
+	os.Unsetenv(StaleSessionAgeEnv)
+
+	const target1, target2, target3 = "iqn.target1", "iqn.target2", "iqn.target3"
+	getStaleSessionCandidates = func() ([]*targetSession, error) {
+		return []*targetSession{
+			{id: "1", targetName: target1},
+			{id: "2", targetName: target1},
+			{id: "3", targetName: target2},
+			{id: "4", targetName: target3},
+		}, nil
+	}
+	lunCounts := map[string]int{target1: 0, target2: 1}
+	getTargetLuns = func() (map[string]int, error) {
+		return lunCounts, nil
+	}
+	var loggedOut []string
+	var logoutErr error
+	logoutStaleTarget = func(targetName string) error {
+		loggedOut = append(loggedOut, targetName)
+		return logoutErr
+	}
+	start := time.Unix(1600000000, 0)
+	now := start
+	staleSessionNow = func() time.Time { return now }
+	plugin := NewIscsiPlugin()
+
+	collect := func(collection *model.StaleSessionCollection) []*model.StaleTarget {
+		loggedOut = nil
+		staleTargets, err := plugin.CollectStaleSessions(collection)
+		if err != nil {
+			t.Fatalf("CollectStaleSessions failed, err=%v", err)
+		}
+		return staleTargets
+	}
+
+	// The targets without LUNs are first seen, not logged out
+	staleTargets := collect(&model.StaleSessionCollection{})
+	expected := []*model.StaleTarget{
+		{TargetName: target1, Sessions: 2},
+		{TargetName: target3, Sessions: 1},
+	}
+	if !reflect.DeepEqual(staleTargets, expected) || len(loggedOut) != 0 {
+		t.Errorf("first collection = %+v, loggedOut=%v", staleTargets, loggedOut)
+	}
+
+	// target3 reports a LUN again and is forgotten; target1 is now older than the stale session
+	// age but a dry run only reports it
+	lunCounts[target3] = 1
+	now = start.Add(DefaultStaleSessionAge)
+	staleTargets = collect(&model.StaleSessionCollection{DryRun: true})
+	expected = []*model.StaleTarget{{TargetName: target1, Sessions: 2, EmptySeconds: 600}}
+	if !reflect.DeepEqual(staleTargets, expected) || len(loggedOut) != 0 {
+		t.Errorf("dry run = %+v, loggedOut=%v", staleTargets, loggedOut)
+	}
+	if _, ok := emptyTargets[target3]; ok {
+		t.Errorf("target %v reporting a LUN was not forgotten", target3)
+	}
+
+	// A failed logout is reported and the target is kept
+	logoutErr = errors.New("logout failed")
+	staleTargets = collect(&model.StaleSessionCollection{})
+	if len(staleTargets) != 1 || staleTargets[0].LoggedOut || staleTargets[0].Error != "logout failed" {
+		t.Errorf("failed logout = %+v", staleTargets)
+	}
+	if _, ok := emptyTargets[target1]; !ok {
+		t.Errorf("target %v not kept after a failed logout", target1)
+	}
+
+	// target1 is logged out and forgotten
+	logoutErr = nil
+	staleTargets = collect(&model.StaleSessionCollection{})
+	if len(staleTargets) != 1 || !staleTargets[0].LoggedOut || !reflect.DeepEqual(loggedOut, []string{target1}) {
+		t.Errorf("logout = %+v, loggedOut=%v", staleTargets, loggedOut)
+	}
+	if _, ok := emptyTargets[target1]; ok {
+		t.Errorf("target %v not forgotten after its logout", target1)
+	}
+
+	// A minimum age of 0 logs out the requested target right away, leaving the others
+	lunCounts[target3] = 0
+	minAge := 0
+	staleTargets = collect(&model.StaleSessionCollection{Targets: []string{"IQN.TARGET3"}, MinAge: &minAge})
+	if len(staleTargets) != 1 || staleTargets[0].TargetName != target3 || !reflect.DeepEqual(loggedOut, []string{target3}) {
+		t.Errorf("minimum age 0 = %+v, loggedOut=%v", staleTargets, loggedOut)
+	}
+	if _, ok := emptyTargets[target1]; !ok {
+		t.Errorf("target %v not requested but not recorded", target1)
+	}
+
+	// Invalid minimum age
+	minAge = -1
+	_, err := plugin.CollectStaleSessions(&model.StaleSessionCollection{MinAge: &minAge})
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.InvalidArgument {
+		t.Errorf("minimum age -1: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestGetStaleSessionAge(t *testing.T) {
+	savedValue, saved := os.LookupEnv(StaleSessionAgeEnv)
+	defer func() {
+		if saved {
+			os.Setenv(StaleSessionAgeEnv, savedValue)
+		} else {
+			os.Unsetenv(StaleSessionAgeEnv)
+		}
+	}()
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", DefaultStaleSessionAge},
+		{"120", 2 * time.Minute},
+		{"0", 0},
+		{"-5", DefaultStaleSessionAge},
+		{"10m", DefaultStaleSessionAge},
+	}
+	for _, tc := range tests {
+		os.Setenv(StaleSessionAgeEnv, tc.value)
+		if age := getStaleSessionAge(); age != tc.expected {
+			t.Errorf("getStaleSessionAge(%q) = %v, expected %v", tc.value, age, tc.expected)
+		}
+	}
+}
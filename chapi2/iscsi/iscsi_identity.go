@@ -0,0 +1,65 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// INITIATOR IDENTITY
+//
+//		VMs cloned from a template often share its iSCSI initiator name.  The array cannot tell the
+//		hosts apart, so each host's logins steal the sessions of the others.  A host cannot see the
+//		initiator names of other hosts, but it can derive a unique one from its own UUID; HostIqn
+//		keeps the naming authority of the current initiator name and uses a hash of the host UUID
+//		as the unique string.  An initiator name derived this way cannot be shared by a clone.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// hostIqnHashLength is the number of host UUID hash digits in a host derived initiator name
+	hostIqnHashLength = 12
+)
+
+// HostIqn returns the iSCSI initiator name derived from the host UUID.  The naming authority of the
+// given initiator name (e.g. "iqn.1994-05.com.redhat") is kept; the platform default is used if the
+// given name is not an "iqn." name.
+func HostIqn(iqn string, hostUUID string) string {
+	authority := strings.SplitN(strings.TrimSpace(iqn), ":", 2)[0]
+	if !strings.HasPrefix(strings.ToLower(authority), "iqn.") {
+		authority = defaultIqnAuthority
+	}
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(hostUUID))))
+	return authority + ":" + hex.EncodeToString(hash[:])[:hostIqnHashLength]
+}
+
+// IsHostIqn returns true if the initiator name is the one derived from the host UUID
+func IsHostIqn(iqn string, hostUUID string) bool {
+	return hostUUID != "" && strings.EqualFold(strings.TrimSpace(iqn), HostIqn(iqn, hostUUID))
+}
+
+// SetIscsiInitiatorName changes the iSCSI initiator name of this host.  The initiator name cannot
+// be changed while iSCSI volumes are attached; persistent logins are migrated to the new name.
+func (plugin *IscsiPlugin) SetIscsiInitiatorName(iqn string) error {
+	log.Tracef(">>>>> SetIscsiInitiatorName, iqn=%v", iqn)
+	defer log.Trace("<<<<< SetIscsiInitiatorName")
+
+	if !isValidIqn(iqn) {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidIqn, iqn)
+	}
+
+	// Call platform specific module
+	return setIscsiInitiatorName(iqn)
+}
+
+// isValidIqn returns true if the initiator name is an "iqn." name without whitespace
+func isValidIqn(iqn string) bool {
+	return strings.HasPrefix(strings.ToLower(iqn), "iqn.") && !strings.ContainsAny(iqn, " \t\r\n")
+}
@@ -0,0 +1,28 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostIqn(t *testing.T) {
+	const hostUUID = "4c4c4544-0042-3610-8051-b4c04f4e3232"
+
+	iqn := HostIqn("iqn.1994-05.com.redhat:template", hostUUID)
+	if !strings.HasPrefix(iqn, "iqn.1994-05.com.redhat:") || len(iqn) != len("iqn.1994-05.com.redhat:")+hostIqnHashLength {
+		t.Fatalf("unexpected host iqn %v", iqn)
+	}
+
+	// The initiator name only depends on the naming authority and host UUID
+	if other := HostIqn("iqn.1994-05.com.redhat:other", strings.ToUpper(hostUUID)); other != iqn {
+		t.Errorf("host iqn %v differs from %v", other, iqn)
+	}
+	if !IsHostIqn(iqn, hostUUID) || IsHostIqn(iqn, "4c4c4544-0042-3610-8051-b4c04f4e3233") || IsHostIqn(iqn, "") {
+		t.Errorf("unexpected IsHostIqn result for %v", iqn)
+	}
+	if other := HostIqn("", hostUUID); !strings.HasPrefix(other, defaultIqnAuthority+":") {
+		t.Errorf("unexpected host iqn %v without a naming authority", other)
+	}
+}
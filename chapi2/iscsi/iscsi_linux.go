@@ -3,15 +3,76 @@
 package iscsi
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/host"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/timing"
+	"github.com/hpe-storage/common-host-libs/linux"
 	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/sgio"
 	"github.com/hpe-storage/common-host-libs/util"
 )
 
 const (
-	initiatorPath        = "/etc/iscsi/initiatorname.iscsi"
 	initiatorNamePattern = "^InitiatorName=(?P<iscsiinit>.*)$"
+
+	// defaultIqnAuthority is the naming authority of the initiator names generated by iscsi-iname
+	defaultIqnAuthority = "iqn.2005-03.org.open-iscsi"
+
+	nodeStartupKey       = "node.startup"
+	nodeAuthMethodKey    = "node.session.auth.authmethod"
+	nodeAuthUsernameKey  = "node.session.auth.username"
+	nodeAuthPasswordKey  = "node.session.auth.password"
+	ifaceInitiatorKey    = "iface.initiatorname"
+	nodeStartupAutomatic = "automatic"
+	nodeStartupManual    = "manual"
+	iscsiadmCommand      = "iscsiadm"
+	sessionStateLoggedIn = "LOGGED_IN"
+
+	// iscsiadmNoObjsFound is the iscsiadm exit code (ISCSI_ERR_NO_OBJS_FOUND) when no session or
+	// node record matches the request
+	iscsiadmNoObjsFound = 21
+)
+
+var (
+	// open-iscsi initiator name file, node record locations (distribution dependent), iface
+	// records and the iSCSI session sysfs path
+	initiatorPath    = "/etc/iscsi/initiatorname.iscsi"
+	iscsiNodesPaths  = []string{"/etc/iscsi/nodes", "/var/lib/iscsi/nodes"}
+	iscsiIfacesPath  = "/etc/iscsi/ifaces"
+	iscsiSessionPath = "/sys/class/iscsi_session"
+
+	// iSCSI connection sysfs path, holding the target portal of each session's connection
+	iscsiConnectionPath = "/sys/class/iscsi_connection"
+
+	// restartIscsid restarts iscsid so that it reads the initiator name again
+	restartIscsid = func() error {
+		return linux.SystemdUnitCommand(linux.SystemdUnitIscsid, "restart")
+	}
+
+	// execIscsiadm runs iscsiadm with the given arguments and returns its output and exit code
+	execIscsiadm = func(args ...string) (string, int, error) {
+		return util.ExecCommandOutput(iscsiadmCommand, args)
+	}
+
+	// devPath is the directory holding the SCSI device nodes
+	devPath = "/dev"
+
+	// scsiInquiry returns the standard Inquiry data (96 bytes) of the given SCSI device
+	scsiInquiry = func(devicePath string) ([]byte, error) {
+		inquiryBuffer := make([]byte, sgio.StandardInquiry[4])
+		if err := sgio.ExecIoctl(sgio.StandardInquiry, inquiryBuffer, devicePath); err != nil {
+			return nil, err
+		}
+		return inquiryBuffer, nil
+	}
 )
 
 func getIscsiInitiators() (init *model.Initiator, err error) {
@@ -34,14 +95,110 @@ func getIscsiInitiators() (init *model.Initiator, err error) {
 	}
 	log.Tracef("got iscsi initiator name as %s", initiators[0])
 	init = &model.Initiator{AccessProtocol: model.AccessProtocolIscsi, Init: initiators}
-	return init, err
+
+	// Offload iSCSI ports (e.g. be2iscsi, qedi, cxgb4i) can log in with their own iqn.  Their
+	// open-iscsi ifaces are reported as the initiator instances.
+	init.Init = append(init.Init, linux.GetOffloadInitiators(init.Init)...)
+	if offloadHosts, _ := linux.GetOffloadIscsiHosts(); len(offloadHosts) != 0 {
+		offloadIfaces, _ := linux.GetOffloadIfaces()
+		for _, offloadIface := range offloadIfaces {
+			init.Instances = append(init.Instances, offloadIface.Name)
+		}
+	}
+	return init, nil
 }
 
 // getTargetScope enumerates the target scope for the given iSCSI target.  An empty string is
-// returned if we were unable to determine the target scope.
-func getTargetScope(targetName string) (targetScope string, err error) {
-	// TODO
-	return "", nil
+// returned if we were unable to determine the target scope.  Unlike Windows, Linux cannot send an
+// Inquiry on a session directly, so the Inquiry is sent to a SCSI device (LUN) of one of the
+// target's logged in sessions.
+func getTargetScope(targetName string) (string, error) {
+	log.Tracef(">>>>> getTargetScope, targetName=%v", targetName)
+	defer log.Trace("<<<<< getTargetScope")
+
+	sessions, err := ioutil.ReadDir(iscsiSessionPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Error(err.Error())
+		return "", cerrors.NewChapiError(err)
+	}
+
+	// Keep track of the last enumeration error.  If all attempted queries fail, we'll return
+	// this error to the caller.
+	var lastErr error
+
+	// Loop through all the iSCSI sessions
+	for _, session := range sessions {
+		sessionDir := filepath.Join(iscsiSessionPath, session.Name())
+
+		// If the session isn't for our target, skip it
+		sessionTarget, err := ioutil.ReadFile(filepath.Join(sessionDir, "targetname"))
+		if err != nil || !strings.EqualFold(targetName, strings.TrimSpace(string(sessionTarget))) {
+			continue
+		}
+
+		// If the session isn't logged in (e.g. reconnecting), skip this session
+		state, _ := ioutil.ReadFile(filepath.Join(sessionDir, "state"))
+		if sessionState := strings.TrimSpace(string(state)); sessionState != sessionStateLoggedIn {
+			lastErr = cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageSessionState, sessionState)
+			log.Trace(lastErr.Error())
+			continue
+		}
+
+		// Issue an Inquiry request on the first of the session's SCSI devices that responds
+		devicePaths := getSessionDevicePaths(sessionDir)
+		if len(devicePaths) == 0 {
+			lastErr = cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageSessionNoDevices, session.Name())
+			log.Trace(lastErr.Error())
+			continue
+		}
+		for _, devicePath := range devicePaths {
+			inquiryBuffer, inquiryErr := scsiInquiry(devicePath)
+			if inquiryErr != nil {
+				// Inquiry request failed, update our lastErr
+				lastErr = cerrors.NewChapiError(cerrors.ScsiErrToCerrors(inquiryErr))
+				log.Tracef("Inquiry failed, devicePath=%v, err=%v", devicePath, inquiryErr)
+				continue
+			}
+
+			// Get the target scope value from the Inquiry data.  If this isn't a Nimble target, or
+			// an unexpected target scope is returned, log an error and fail request.
+			targetScope, err := parseTargetScope(inquiryBuffer)
+			if err != nil {
+				log.Error(err.Error())
+				return "", err
+			}
+
+			// Successfully enumerated target scope on this session.  Log target scope and return to the caller
+			log.Tracef("targetName=%v, targetScope=%v", targetName, targetScope)
+			return targetScope, nil
+		}
+	}
+
+	// We were unable to enumerate the target scope from any target session; return last error detected
+	if lastErr == nil {
+		// If we couldn't find any session for our target, we could end up here.  In that case,
+		// we'll log a generic error.
+		lastErr = cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageNoTargetScope)
+	}
+	log.Error(lastErr.Error())
+	return "", lastErr
+}
+
+// getSessionDevicePaths returns the device paths of the SCSI devices (LUNs) of the given iSCSI
+// session sysfs directory.  The SCSI generic device is preferred over the block device as it
+// is available even for LUNs without a block device driver.
+func getSessionDevicePaths(sessionDir string) []string {
+	var devicePaths []string
+	luns, _ := filepath.Glob(filepath.Join(sessionDir, "device", "target*", "*:*:*:*"))
+	for _, lun := range luns {
+		for _, class := range []string{"scsi_generic", "block"} {
+			if devices, _ := ioutil.ReadDir(filepath.Join(lun, class)); len(devices) != 0 {
+				devicePaths = append(devicePaths, filepath.Join(devPath, devices[0].Name()))
+				break
+			}
+		}
+	}
+	return devicePaths
 }
 
 // rescanIscsiTarget rescans host ports for iSCSI devices
@@ -59,18 +216,613 @@ func (plugin *IscsiPlugin) getTargetPortals(targetName string, ipv4Only bool) ([
 // loginTarget is called to connect to the given iSCSI target.  The parent LoginTarget() routine
 // has already validated that target iqn and blockDev.IscsiAccessInfo are provided.
 func (plugin *IscsiPlugin) loginTarget(blockDev model.BlockDeviceAccessInfo) (err error) {
-	// TODO
+	log.Infof("Login iSCSI target %v", blockDev.TargetName)
+
+	// As under Windows, a target that is already connected is left as is
+	if loggedIn, _ := plugin.isTargetLoggedIn(blockDev.TargetName); loggedIn {
+		log.Infof("Target %v already connected", blockDev.TargetName)
+		return nil
+	}
+
+	// Make sure the target can be discovered, failing over between the provided discovery IPs
+	endStage := timing.StartStage(blockDev.TargetName, timing.StageDiscovery)
+	portals, err := discoverTarget(blockDev.TargetName, getDiscoveryIPs(blockDev.IscsiAccessInfo))
+	endStage()
+	if err != nil {
+		return err
+	}
+	defer timing.StartStage(blockDev.TargetName, timing.StageLogin)()
+
+	// Create the discovered portals' node records and configure them before logging in.  open-iscsi
+	// node records default to node.startup=automatic; ephemeral sessions are switched to manual
+	// before they are logged in so that they are never logged in again at boot.
+	if err = createNodeRecords(blockDev.TargetName, portals); err != nil {
+		return err
+	}
+	startup := nodeStartupAutomatic
+	if !isPersistentLogin(blockDev.IscsiAccessInfo) {
+		startup = nodeStartupManual
+	}
+	if err = setNodeStartup(blockDev.TargetName, startup); err != nil {
+		return err
+	}
+	if blockDev.IscsiAccessInfo.ChapUser != "" {
+		if err = updateTargetCredentials(blockDev.TargetName, blockDev.IscsiAccessInfo.ChapUser, blockDev.IscsiAccessInfo.ChapPassword); err != nil {
+			return err
+		}
+	}
+
+	// Log in all of the target's node records
+	args := []string{"--mode", "node", "--targetname", blockDev.TargetName, "--login"}
+	if _, _, err = execIscsiadm(args...); err != nil {
+		err = cerrors.NewChapiErrorf(cerrors.ConnectionFailed, errorMessageLoginFailed, blockDev.TargetName, err)
+		log.Error(err)
+		return err
+	}
 	return nil
 }
 
-// logoutTarget is called to disconnect the given iSCSI target from this host.
+// createNodeRecords creates the node records of the given target portals ("address:port,tag")
+func createNodeRecords(targetName string, portals []string) error {
+	for _, portal := range portals {
+		args := []string{"--mode", "node", "--targetname", targetName, "--portal", portal, "--op", "new"}
+		if _, _, err := execIscsiadm(args...); err != nil {
+			log.Errorf("Unable to create the node record of target %v portal %v, err=%v", targetName, portal, err)
+			return cerrors.NewChapiError(err)
+		}
+	}
+	return nil
+}
+
+// discoverTarget queries the given discovery IPs, in order, until the target is discovered and
+// returns the target's portals ("address:port,tag").  The send targets discovery is not persisted
+// so that a discovery IP creates no node records for the other targets it reports.  The discovery
+// IP that discovered the target is recorded for the device details.  If no discovery IPs are
+// provided, the target's existing node records are used and no portals are returned.
+func discoverTarget(targetName string, discoveryIPs []string) (portals []string, err error) {
+	log.Tracef(">>>>> discoverTarget, targetName=%v, discoveryIPs=%v", targetName, discoveryIPs)
+	defer log.Traceln("<<<<< discoverTarget")
+
+	if len(discoveryIPs) == 0 {
+		if !hasNodeRecords(targetName) {
+			err = cerrors.NewChapiError(cerrors.NotFound, errorMessageTargetNotFound)
+			log.Error(err)
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	for _, discoveryIP := range discoveryIPs {
+		// If the discovery IP is unreachable, fail over to the next discovery IP
+		args := []string{"--mode", "discovery", "--type", "sendtargets", "--portal", discoveryIP, "--op", "nonpersistent"}
+		output, _, execErr := execIscsiadm(args...)
+		if execErr != nil {
+			err = cerrors.NewChapiErrorf(cerrors.ConnectionFailed, errorMessageDiscoveryFailed, discoveryIP, execErr)
+			log.Warn(err)
+			continue
+		}
+
+		if portals = parseSendTargets(output, targetName); len(portals) == 0 {
+			err = cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageTargetNotDiscovered, targetName, discoveryIP)
+			log.Warn(err)
+			continue
+		}
+
+		log.Infof("Target %v discovered through discovery IP %v, portals=%v", targetName, discoveryIP, portals)
+		setDiscoveryPortal(targetName, discoveryIP)
+		return portals, nil
+	}
+
+	// Return the last discovery failure
+	log.Error(err)
+	return nil, err
+}
+
+// parseSendTargets returns the portals ("address:port,tag") of the given target in the output of
+// a send targets discovery, which reports one "address:port,tag target" line per target portal
+func parseSendTargets(output string, targetName string) []string {
+	var portals []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.EqualFold(fields[1], targetName) {
+			portals = append(portals, fields[0])
+		}
+	}
+	return portals
+}
+
+// hasNodeRecords returns true if the given target has an open-iscsi node record
+func hasNodeRecords(targetName string) bool {
+	for _, nodesPath := range iscsiNodesPaths {
+		if info, err := os.Stat(filepath.Join(nodesPath, targetName)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// getPlanInitiatorPorts returns the initiator ports PlanLogin plans connections from
+func getPlanInitiatorPorts(blockDev model.BlockDeviceAccessInfo) ([]*model.Network, error) {
+	return host.NewHostPlugin().GetNetworks()
+}
+
+// getPlanConnectionLimits returns the minimum and maximum (0 if unlimited) connections PlanLogin
+// plans for the target; open-iscsi makes a single connection per IT nexus
+func getPlanConnectionLimits(targetScope string) (minConnections, maxConnections uint32) {
+	return 1, 0
+}
+
+// setNodeStartup sets the node.startup setting of all the given target's node records, which must
+// exist
+func setNodeStartup(targetName string, startup string) error {
+	args := []string{"--mode", "node", "--targetname", targetName, "--op", "update", "-n", nodeStartupKey, "-v", startup}
+	if _, _, err := execIscsiadm(args...); err != nil {
+		err = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageNodeStartup, targetName, startup, err)
+		log.Error(err)
+		return err
+	}
+	log.Infof("Set %v=%v for target %v", nodeStartupKey, startup, targetName)
+	return nil
+}
+
+// logoutTarget is called to disconnect the given iSCSI target from this host.  The target's node
+// records are removed as well so that the target isn't logged in again on boot.
 func (plugin *IscsiPlugin) logoutTarget(targetName string) (err error) {
-	// TODO
+	log.Infof("Logout iSCSI target %v", targetName)
+
+	// iscsiadm fails with ISCSI_ERR_NO_OBJS_FOUND if there is no session or node record left
+	args := []string{"--mode", "node", "--targetname", targetName, "--logout"}
+	if _, rc, err := util.ExecCommandOutput(iscsiadmCommand, args); err != nil && rc != iscsiadmNoObjsFound {
+		log.Errorf("Unable to logout iSCSI target %v, err=%v", targetName, err)
+		return cerrors.NewChapiError(err)
+	}
+	args = []string{"--mode", "node", "--targetname", targetName, "--op", "delete"}
+	if _, rc, err := util.ExecCommandOutput(iscsiadmCommand, args); err != nil && rc != iscsiadmNoObjsFound {
+		log.Errorf("Unable to delete the node records of iSCSI target %v, err=%v", targetName, err)
+		return cerrors.NewChapiError(err)
+	}
+	return nil
+}
+
+// logoutTargetSessions logs out the sessions of the given iSCSI target, keeping its node records
+func (plugin *IscsiPlugin) logoutTargetSessions(targetName string) error {
+	log.Infof("Logout iSCSI target %v sessions", targetName)
+
+	args := []string{"--mode", "node", "--targetname", targetName, "--logout"}
+	if _, rc, err := util.ExecCommandOutput(iscsiadmCommand, args); err != nil && rc != iscsiadmNoObjsFound {
+		log.Errorf("Unable to logout iSCSI target %v, err=%v", targetName, err)
+		return cerrors.NewChapiError(err)
+	}
 	return nil
 }
 
 // isTargetLoggedIn checks to see if the given iSCSI target is already logged in.
 func (plugin *IscsiPlugin) isTargetLoggedIn(targetName string) (bool, error) {
-	// TODO
+	for sessionTarget, state := range getSessionStates() {
+		if strings.EqualFold(sessionTarget, targetName) && state == sessionStateLoggedIn {
+			return true, nil
+		}
+	}
 	return false, nil
 }
+
+// getUnconnectedTargets returns the targets with an open-iscsi node record but no logged in
+// session.  open-iscsi does not persist login failures so the last error is only reported when a
+// session exists in a non LOGGED_IN state (e.g. FAILED).
+func (plugin *IscsiPlugin) getUnconnectedTargets() ([]*model.UnconnectedTarget, error) {
+	sessionStates := getSessionStates()
+
+	var targets []*model.UnconnectedTarget
+	seen := make(map[string]bool)
+	for _, nodesPath := range iscsiNodesPaths {
+		nodes, err := ioutil.ReadDir(nodesPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Errorf("unable to read %v, err=%v", nodesPath, err)
+			}
+			continue
+		}
+		for _, node := range nodes {
+			targetName := node.Name()
+			if !node.IsDir() || seen[targetName] {
+				continue
+			}
+			seen[targetName] = true
+
+			state, sessionFound := sessionStates[targetName]
+			if state == sessionStateLoggedIn {
+				continue
+			}
+
+			target := getNodeRecordTarget(filepath.Join(nodesPath, targetName))
+			target.Name = targetName
+			if sessionFound {
+				target.LastError = fmt.Sprintf(errorMessageSessionState, state)
+			}
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// getSessionStates returns the session state of each iSCSI target with a session on this host.
+// If a target has multiple sessions, LOGGED_IN takes precedence over any other state.
+func getSessionStates() map[string]string {
+	sessionStates := make(map[string]string)
+	sessions, err := ioutil.ReadDir(iscsiSessionPath)
+	if err != nil {
+		return sessionStates
+	}
+	for _, session := range sessions {
+		sessionDir := filepath.Join(iscsiSessionPath, session.Name())
+		targetName, err := ioutil.ReadFile(filepath.Join(sessionDir, "targetname"))
+		if err != nil {
+			continue
+		}
+		state, _ := ioutil.ReadFile(filepath.Join(sessionDir, "state"))
+		name := strings.TrimSpace(string(targetName))
+		if sessionStates[name] != sessionStateLoggedIn {
+			sessionStates[name] = strings.TrimSpace(string(state))
+		}
+	}
+	return sessionStates
+}
+
+// getNodeRecordTarget builds an UnconnectedTarget from the portal entries of the given node
+// record directory.  Depending on the open-iscsi version, each portal entry is either the record
+// file itself or a directory holding one record file per iface.
+func getNodeRecordTarget(targetDir string) *model.UnconnectedTarget {
+	target := &model.UnconnectedTarget{Source: model.UnconnectedTargetSourceNodeRecord}
+	portals, _ := ioutil.ReadDir(targetDir)
+	for _, portal := range portals {
+		targetPortal := parseNodePortal(portal.Name())
+		if targetPortal == nil {
+			continue
+		}
+		target.TargetPortals = append(target.TargetPortals, targetPortal)
+
+		recordPath := filepath.Join(targetDir, portal.Name())
+		if portal.IsDir() {
+			ifaces, _ := ioutil.ReadDir(recordPath)
+			if len(ifaces) == 0 {
+				continue
+			}
+			recordPath = filepath.Join(recordPath, ifaces[0].Name())
+		}
+		if target.Startup == "" {
+			if record, err := ioutil.ReadFile(recordPath); err == nil {
+				target.Startup = parseNodeRecordValue(string(record), nodeStartupKey)
+			}
+		}
+	}
+	return target
+}
+
+// parseNodePortal parses an open-iscsi portal entry name ("address,port,tag") into a TargetPortal
+func parseNodePortal(name string) *model.TargetPortal {
+	// IPv6 addresses contain colons but never commas, so split from the right
+	tagIndex := strings.LastIndex(name, ",")
+	if tagIndex < 0 {
+		return nil
+	}
+	portIndex := strings.LastIndex(name[:tagIndex], ",")
+	if portIndex < 0 {
+		return nil
+	}
+	return &model.TargetPortal{
+		Address: strings.Trim(name[:portIndex], "[]"),
+		Port:    name[portIndex+1 : tagIndex],
+		Tag:     name[tagIndex+1:],
+	}
+}
+
+// parseNodeRecordValue returns the value of the given "key = value" entry in a node record
+func parseNodeRecordValue(record string, key string) string {
+	for _, line := range strings.Split(record, "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// setIscsiInitiatorName changes the open-iscsi initiator name.  iscsid only reads the initiator name
+// when it starts so the sessions, which must not have any SCSI devices, are logged out and iscsid
+// is restarted.  The iface and node records bound to the previous initiator name are updated and
+// the targets that were logged in, along with the automatic node records, are logged back in.
+func setIscsiInitiatorName(iqn string) error {
+	previousIqn, err := readInitiatorName()
+	if err != nil {
+		return err
+	}
+	if previousIqn == iqn {
+		return nil
+	}
+
+	// Only sessions without SCSI devices can be logged out
+	var targets []string
+	seen := make(map[string]bool)
+	sessions, _ := ioutil.ReadDir(iscsiSessionPath)
+	for _, session := range sessions {
+		sessionDir := filepath.Join(iscsiSessionPath, session.Name())
+		if len(getSessionDevicePaths(sessionDir)) != 0 {
+			err = cerrors.NewChapiErrorf(cerrors.Aborted, errorMessageIqnInUse, "session "+session.Name()+" has SCSI devices")
+			log.Error(err)
+			return err
+		}
+		targetName, err := ioutil.ReadFile(filepath.Join(sessionDir, "targetname"))
+		if name := strings.TrimSpace(string(targetName)); err == nil && !seen[name] {
+			seen[name] = true
+			targets = append(targets, name)
+		}
+	}
+	for _, targetName := range targets {
+		if _, _, err = util.ExecCommandOutput(iscsiadmCommand, []string{"--mode", "node", "--targetname", targetName, "--logout"}); err != nil {
+			loginNodeTargets(targets)
+			err = cerrors.NewChapiErrorf(cerrors.Aborted, errorMessageIqnInUse, "unable to log out of "+targetName+", "+err.Error())
+			log.Error(err)
+			return err
+		}
+	}
+
+	if err = applyInitiatorName(previousIqn, iqn); err != nil {
+		loginNodeTargets(targets)
+		return err
+	}
+
+	if failed := loginNodeTargets(targets); failed != 0 {
+		err = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageIqnNotMigrated, failed)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// readInitiatorName returns the open-iscsi initiator name (empty if not set)
+func readInitiatorName() (string, error) {
+	initiators, err := util.FileGetStringsWithPattern(initiatorPath, initiatorNamePattern)
+	if err != nil {
+		log.Errorf("failed to get iqn from %s error %s", initiatorPath, err.Error())
+		return "", cerrors.NewChapiError(err)
+	}
+	if len(initiators) == 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(initiators[0]), nil
+}
+
+// applyInitiatorName writes the new initiator name, updates the iface and node records bound to
+// the previous initiator name and restarts iscsid.  The logged in sessions keep the previous
+// initiator name until they are logged in again.
+func applyInitiatorName(previousIqn string, iqn string) error {
+	err := writeInitiatorName(iqn)
+	if err == nil {
+		if previousIqn != "" {
+			migrateRecordInitiatorName(append([]string{iscsiIfacesPath}, iscsiNodesPaths...), previousIqn, iqn)
+		}
+		err = restartIscsid()
+	}
+	if err != nil {
+		log.Errorf("Unable to change the iSCSI initiator name to %v, err=%v", iqn, err)
+		return cerrors.NewChapiError(err)
+	}
+	log.Infof("Changed the iSCSI initiator name from %v to %v", previousIqn, iqn)
+	return nil
+}
+
+// writeInitiatorName replaces the initiator name in the open-iscsi initiator name file, keeping its
+// other lines (e.g. comments)
+func writeInitiatorName(iqn string) error {
+	data, err := ioutil.ReadFile(initiatorPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var lines []string
+	replaced := false
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if strings.HasPrefix(line, "InitiatorName=") {
+			if replaced {
+				continue
+			}
+			line, replaced = "InitiatorName="+iqn, true
+		}
+		if line != "" || len(lines) != 0 {
+			lines = append(lines, line)
+		}
+	}
+	if !replaced {
+		lines = append(lines, "InitiatorName="+iqn)
+	}
+
+	tempPath := initiatorPath + ".tmp"
+	if err = ioutil.WriteFile(tempPath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, initiatorPath)
+}
+
+// migrateRecordInitiatorName updates the iface and node records, beneath the given directories,
+// bound to the previous initiator name.  Failures are logged; such records keep the previous name.
+func migrateRecordInitiatorName(recordPaths []string, previousIqn string, iqn string) {
+	for _, recordPath := range recordPaths {
+		filepath.Walk(recordPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				log.Errorf("Unable to read iSCSI record %v, err=%v", path, err)
+				return nil
+			}
+			lines := strings.Split(string(data), "\n")
+			changed := false
+			for i, line := range lines {
+				parts := strings.SplitN(line, "=", 2)
+				if len(parts) == 2 && strings.TrimSpace(parts[0]) == ifaceInitiatorKey && strings.TrimSpace(parts[1]) == previousIqn {
+					lines[i] = ifaceInitiatorKey + " = " + iqn
+					changed = true
+				}
+			}
+			if !changed {
+				return nil
+			}
+			if err = ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), info.Mode().Perm()); err != nil {
+				log.Errorf("Unable to update iSCSI record %v, err=%v", path, err)
+			}
+			return nil
+		})
+	}
+}
+
+// loginNodeTargets logs in the node records of the given targets, along with the automatic node
+// records, and returns the number of targets that could not be logged in
+func loginNodeTargets(targets []string) int {
+	failed := 0
+	for _, targetName := range targets {
+		if _, _, err := util.ExecCommandOutput(iscsiadmCommand, []string{"--mode", "node", "--targetname", targetName, "--login"}); err != nil {
+			log.Errorf("Unable to log in to %v, err=%v", targetName, err)
+			failed++
+		}
+	}
+
+	// iscsiadm fails if there is no automatic node record left to log in
+	util.ExecCommandOutput(iscsiadmCommand, []string{"--mode", "node", "--loginall", "automatic"})
+	return failed
+}
+
+// targetSessionPrivate holds the Linux specific properties of a session logged in again by
+// RotateCredentials
+type targetSessionPrivate struct {
+	iface string // open-iscsi iface the session was logged in through
+}
+
+// getTargetSessions returns the iSCSI sessions of this host along with the target portal and iface
+// of their node record
+func getTargetSessions() ([]*targetSession, error) {
+	sessionDirs, err := ioutil.ReadDir(iscsiSessionPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, cerrors.NewChapiError(err)
+	}
+	var sessions []*targetSession
+	for _, sessionDir := range sessionDirs {
+		session := getTargetSession(sessionDir.Name())
+		if session != nil {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// getTargetSession returns the given iSCSI session (e.g. "session3"), or nil if it cannot be read
+// or is not logged in
+func getTargetSession(name string) *targetSession {
+	sessionDir := filepath.Join(iscsiSessionPath, name)
+	readValue := func(path string) string {
+		value, _ := ioutil.ReadFile(path)
+		return strings.TrimSpace(string(value))
+	}
+	if readValue(filepath.Join(sessionDir, "state")) != sessionStateLoggedIn {
+		return nil
+	}
+	session := &targetSession{
+		id:         strings.TrimPrefix(name, "session"),
+		targetName: readValue(filepath.Join(sessionDir, "targetname")),
+		devices:    len(getSessionDevicePaths(sessionDir)),
+		private:    &targetSessionPrivate{iface: readValue(filepath.Join(sessionDir, "ifacename"))},
+	}
+	connectionDir := filepath.Join(iscsiConnectionPath, "connection"+session.id+":0")
+	address := readValue(filepath.Join(connectionDir, "persistent_address"))
+	port := readValue(filepath.Join(connectionDir, "persistent_port"))
+	if session.targetName == "" || address == "" || port == "" {
+		log.Tracef("Skipping session %v, targetName=%v, address=%v, port=%v", name, session.targetName, address, port)
+		return nil
+	}
+	if strings.Contains(address, ":") {
+		address = "[" + address + "]"
+	}
+	session.portal = address + ":" + port
+	return session
+}
+
+// getTargetLunCounts returns the number of LUNs reported by the sessions of each target, keyed by
+// lowercase target name.  The sessions that are not logged in (e.g. recovering) are included.
+func getTargetLunCounts() (map[string]int, error) {
+	lunCounts := make(map[string]int)
+	sessionDirs, err := ioutil.ReadDir(iscsiSessionPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lunCounts, nil
+		}
+		return nil, cerrors.NewChapiError(err)
+	}
+	for _, sessionDir := range sessionDirs {
+		sessionPath := filepath.Join(iscsiSessionPath, sessionDir.Name())
+		targetName, err := ioutil.ReadFile(filepath.Join(sessionPath, "targetname"))
+		if os.IsNotExist(err) {
+			continue // logged out since enumerated
+		}
+		if err != nil {
+			return nil, cerrors.NewChapiError(err)
+		}
+		luns, _ := filepath.Glob(filepath.Join(sessionPath, "device", "target*", "*:*:*:*"))
+		lunCounts[strings.ToLower(strings.TrimSpace(string(targetName)))] += len(luns)
+	}
+	return lunCounts, nil
+}
+
+// reloginSession logs out the given session and logs its node record in again, waiting for the
+// new session to report all of the SCSI devices of the previous one
+func reloginSession(session *targetSession, rotation *model.CredentialRotation) error {
+	args := []string{"--mode", "session", "--sid", session.id, "--logout"}
+	if _, _, err := util.ExecCommandOutput(iscsiadmCommand, args); err != nil {
+		return cerrors.NewChapiError(err)
+	}
+	args = []string{"--mode", "node", "--targetname", session.targetName, "--portal", session.portal, "--login"}
+	if session.private.iface != "" {
+		args = append(args, "--interface", session.private.iface)
+	}
+	if _, _, err := util.ExecCommandOutput(iscsiadmCommand, args); err != nil {
+		return cerrors.NewChapiError(err)
+	}
+
+	for deadline := time.Now().Add(reloginTimeout); time.Now().Before(deadline); time.Sleep(time.Second) {
+		sessions, _ := getTargetSessions()
+		for _, newSession := range sessions {
+			if newSession.targetName == session.targetName && newSession.portal == session.portal && newSession.devices >= session.devices {
+				log.Infof("Session %v of target %v logged in again as session %v", session.id, session.targetName, newSession.id)
+				return nil
+			}
+		}
+	}
+	return cerrors.NewChapiErrorf(cerrors.Timeout, errorMessageReloginTimeout, session.portal, session.devices)
+}
+
+// updateTargetCredentials sets the CHAP credentials of all the given target's node records
+func updateTargetCredentials(targetName string, chapUser string, chapPassword string) error {
+	for _, setting := range [][]string{
+		{nodeAuthMethodKey, "CHAP"},
+		{nodeAuthUsernameKey, chapUser},
+		{nodeAuthPasswordKey, chapPassword},
+	} {
+		args := []string{"--mode", "node", "--targetname", targetName, "--op", "update", "-n", setting[0], "-v", setting[1]}
+		if _, _, err := execIscsiadm(args...); err != nil {
+			log.Errorf("Unable to set %v of target %v, err=%v", setting[0], targetName, err)
+			return cerrors.NewChapiError(err)
+		}
+	}
+	log.Infof("Updated the CHAP credentials of target %v", targetName)
+	return nil
+}
+
+// updateInitiatorName changes the open-iscsi initiator name without logging out the sessions
+func updateInitiatorName(iqn string) error {
+	previousIqn, err := readInitiatorName()
+	if err != nil || previousIqn == iqn {
+		return err
+	}
+	return applyInitiatorName(previousIqn, iqn)
+}
@@ -0,0 +1,338 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+const (
+	testConnectedTarget   = "iqn.2007-11.com.nimblestorage:connected"
+	testFailedTarget      = "iqn.2007-11.com.nimblestorage:failed"
+	testUnconnectedTarget = "iqn.2007-11.com.nimblestorage:unconnected"
+)
+
+func writeTestFile(t *testing.T, path string, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetUnconnectedTargets(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "iscsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	// Node records; the unconnected target uses the older per-iface directory layout
+	nodesPath := filepath.Join(testDir, "nodes")
+	writeTestFile(t, filepath.Join(nodesPath, testConnectedTarget, "10.0.0.1,3260,2460"), "node.startup = automatic\n")
+	writeTestFile(t, filepath.Join(nodesPath, testFailedTarget, "10.0.0.2,3260,2460"), "node.startup = automatic\n")
+	writeTestFile(t, filepath.Join(nodesPath, testUnconnectedTarget, "10.0.0.3,3260,2460", "default"), "node.name = x\nnode.startup = manual\n")
+
+	// iSCSI sessions
+	sessionPath := filepath.Join(testDir, "iscsi_session")
+	writeTestFile(t, filepath.Join(sessionPath, "session1", "targetname"), testConnectedTarget+"\n")
+	writeTestFile(t, filepath.Join(sessionPath, "session1", "state"), "LOGGED_IN\n")
+	writeTestFile(t, filepath.Join(sessionPath, "session2", "targetname"), testFailedTarget+"\n")
+	writeTestFile(t, filepath.Join(sessionPath, "session2", "state"), "FAILED\n")
+
+	savedNodesPaths, savedSessionPath := iscsiNodesPaths, iscsiSessionPath
+	defer func() { iscsiNodesPaths, iscsiSessionPath = savedNodesPaths, savedSessionPath }()
+	iscsiNodesPaths = []string{nodesPath, filepath.Join(testDir, "missing")}
+	iscsiSessionPath = sessionPath
+
+	targets, err := NewIscsiPlugin().GetUnconnectedTargets()
+	if err != nil {
+		t.Fatalf("GetUnconnectedTargets failed, err=%v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 unconnected targets, got %v", len(targets))
+	}
+	for _, target := range targets {
+		switch target.Name {
+		case testFailedTarget:
+			if target.LastError != "session state FAILED" || target.Startup != "automatic" {
+				t.Errorf("unexpected failed target %+v", target)
+			}
+		case testUnconnectedTarget:
+			if target.LastError != "" || target.Startup != "manual" {
+				t.Errorf("unexpected unconnected target %+v", target)
+			}
+			if len(target.TargetPortals) != 1 || target.TargetPortals[0].Address != "10.0.0.3" ||
+				target.TargetPortals[0].Port != "3260" || target.TargetPortals[0].Tag != "2460" {
+				t.Errorf("unexpected target portals %+v", target.TargetPortals)
+			}
+		default:
+			t.Errorf("unexpected target %v", target.Name)
+		}
+	}
+}
+
+func TestParseNodePortal(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		port    string
+		tag     string
+	}{
+		{"10.0.0.1,3260,1", "10.0.0.1", "3260", "1"},
+		{"[fe80::1],3260,2460", "fe80::1", "3260", "2460"},
+	}
+	for _, tc := range tests {
+		portal := parseNodePortal(tc.name)
+		if portal == nil || portal.Address != tc.address || portal.Port != tc.port || portal.Tag != tc.tag {
+			t.Errorf("parseNodePortal(%v) returned %+v", tc.name, portal)
+		}
+	}
+	if portal := parseNodePortal("default"); portal != nil {
+		t.Errorf("expected nil portal for invalid name, got %+v", portal)
+	}
+}
+
+func TestGetTargetScope(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "iscsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	// A failed session and a logged in session whose LUN has a SCSI generic device
+	sessionPath := filepath.Join(testDir, "iscsi_session")
+	writeTestFile(t, filepath.Join(sessionPath, "session1", "targetname"), testConnectedTarget+"\n")
+	writeTestFile(t, filepath.Join(sessionPath, "session1", "state"), "FAILED\n")
+	writeTestFile(t, filepath.Join(sessionPath, "session2", "targetname"), testConnectedTarget+"\n")
+	writeTestFile(t, filepath.Join(sessionPath, "session2", "state"), "LOGGED_IN\n")
+	lunDir := filepath.Join(sessionPath, "session2", "device", "target3:0:0", "3:0:0:0")
+	writeTestFile(t, filepath.Join(lunDir, "block", "sdc", "dev"), "8:32\n")
+	writeTestFile(t, filepath.Join(lunDir, "scsi_generic", "sg2", "dev"), "21:2\n")
+
+	// Standard Inquiry data of a Nimble group scoped target
+	inquiryBuffer := make([]byte, 96)
+	copy(inquiryBuffer[8:32], nimbleVendorProduct)
+	inquiryBuffer[nimbleTargetScopeOffset] = 1
+
+	var inquiredDevice string
+	savedSessionPath, savedInquiry := iscsiSessionPath, scsiInquiry
+	defer func() { iscsiSessionPath, scsiInquiry = savedSessionPath, savedInquiry }()
+	iscsiSessionPath = sessionPath
+	scsiInquiry = func(devicePath string) ([]byte, error) {
+		inquiredDevice = devicePath
+		return inquiryBuffer, nil
+	}
+
+	targetScope, err := NewIscsiPlugin().GetTargetScope(testConnectedTarget)
+	if err != nil || targetScope != model.TargetScopeGroup {
+		t.Errorf("unexpected target scope %v, err=%v", targetScope, err)
+	}
+	if inquiredDevice != filepath.Join(devPath, "sg2") {
+		t.Errorf("unexpected Inquiry device %v", inquiredDevice)
+	}
+
+	inquiryBuffer[nimbleTargetScopeOffset] = 0
+	if targetScope, err = getTargetScope(testConnectedTarget); err != nil || targetScope != model.TargetScopeVolume {
+		t.Errorf("unexpected target scope %v, err=%v", targetScope, err)
+	}
+
+	// Non-Nimble targets and targets without a session are rejected
+	copy(inquiryBuffer[8:32], "OTHER   Server          ")
+	if _, err = getTargetScope(testConnectedTarget); err == nil {
+		t.Error("non-Nimble target not rejected")
+	}
+	if _, err = getTargetScope(testUnconnectedTarget); err == nil {
+		t.Error("target without a session not rejected")
+	}
+}
+
+func TestSetIscsiInitiatorName(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "iscsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	const previousIqn = "iqn.1994-05.com.redhat:template"
+	const iqn = "iqn.1994-05.com.redhat:0123456789ab"
+	testInitiatorPath := filepath.Join(testDir, "initiatorname.iscsi")
+	writeTestFile(t, testInitiatorPath, "## Generated by the template\nInitiatorName="+previousIqn+"\n")
+	ifaceRecord := filepath.Join(testDir, "ifaces", "offload")
+	writeTestFile(t, ifaceRecord, "iface.iscsi_ifacename = offload\niface.initiatorname = "+previousIqn+"\n")
+	nodeRecord := filepath.Join(testDir, "nodes", testConnectedTarget, "10.0.0.1,3260,2460")
+	writeTestFile(t, nodeRecord, "node.startup = automatic\niface.initiatorname = "+previousIqn+"\n")
+
+	// A session with SCSI devices
+	sessionPath := filepath.Join(testDir, "iscsi_session")
+	writeTestFile(t, filepath.Join(sessionPath, "session1", "targetname"), testConnectedTarget+"\n")
+	writeTestFile(t, filepath.Join(sessionPath, "session1", "device", "target3:0:0", "3:0:0:0", "block", "sdc", "dev"), "8:32\n")
+
+	restarted := false
+	savedInitiatorPath, savedNodesPaths, savedIfacesPath, savedSessionPath, savedRestart := initiatorPath, iscsiNodesPaths, iscsiIfacesPath, iscsiSessionPath, restartIscsid
+	defer func() {
+		initiatorPath, iscsiNodesPaths, iscsiIfacesPath, iscsiSessionPath, restartIscsid = savedInitiatorPath, savedNodesPaths, savedIfacesPath, savedSessionPath, savedRestart
+	}()
+	initiatorPath, iscsiNodesPaths, iscsiIfacesPath, iscsiSessionPath = testInitiatorPath, []string{filepath.Join(testDir, "nodes")}, filepath.Join(testDir, "ifaces"), sessionPath
+	restartIscsid = func() error {
+		restarted = true
+		return nil
+	}
+
+	// The initiator name is not changed while a session has SCSI devices
+	if err = NewIscsiPlugin().SetIscsiInitiatorName(iqn); err == nil || restarted {
+		t.Fatalf("initiator name changed with SCSI devices attached, err=%v", err)
+	}
+	if err = NewIscsiPlugin().SetIscsiInitiatorName("template"); err == nil {
+		t.Error("invalid initiator name not rejected")
+	}
+
+	os.RemoveAll(sessionPath)
+	if err = NewIscsiPlugin().SetIscsiInitiatorName(iqn); err != nil || !restarted {
+		t.Fatalf("unexpected err=%v, restarted=%v", err, restarted)
+	}
+	expected := map[string]string{
+		testInitiatorPath: "## Generated by the template\nInitiatorName=" + iqn + "\n",
+		ifaceRecord:       "iface.iscsi_ifacename = offload\niface.initiatorname = " + iqn + "\n",
+		nodeRecord:        "node.startup = automatic\niface.initiatorname = " + iqn + "\n",
+	}
+	for path, data := range expected {
+		if actual, _ := ioutil.ReadFile(path); string(actual) != data {
+			t.Errorf("unexpected %v contents %q", path, actual)
+		}
+	}
+}
+
+func TestDiscoverTarget(t *testing.T) {
+	defer func(get func(string) (string, error), record func(string, string) error) {
+		getDiscoveryPortal, recordDiscoveryPortal = get, record
+	}(getDiscoveryPortal, recordDiscoveryPortal)
+	discoveryPortals := make(map[string]string)
+	recordDiscoveryPortal = func(targetName string, discoveryIP string) error {
+		discoveryPortals[targetName] = discoveryIP
+		return nil
+	}
+
+	// The first discovery IP is unreachable and the second doesn't report the target
+	var queried []string
+	savedExecIscsiadm := execIscsiadm
+	defer func() { execIscsiadm = savedExecIscsiadm }()
+	execIscsiadm = func(args ...string) (string, int, error) {
+		discoveryIP := args[5]
+		queried = append(queried, discoveryIP)
+		switch discoveryIP {
+		case "10.1.1.10":
+			return "", 4, errors.New("iscsiadm: connection to discovery portal failed")
+		case "10.1.2.10":
+			return "10.1.2.11:3260,2460 " + testUnconnectedTarget + "\n", 0, nil
+		}
+		return "10.1.3.11:3260,2460 " + testUnconnectedTarget + "\n" +
+			"10.1.3.11:3260,2460 " + testConnectedTarget + "\n" +
+			"[fe80::1]:3260,2460 " + testConnectedTarget + "\n", 0, nil
+	}
+
+	portals, err := discoverTarget(testConnectedTarget, []string{"10.1.1.10", "10.1.2.10", "10.1.3.10", "10.1.4.10"})
+	if err != nil || !reflect.DeepEqual(portals, []string{"10.1.3.11:3260,2460", "[fe80::1]:3260,2460"}) {
+		t.Fatalf("unexpected portals %v, err=%v", portals, err)
+	}
+	if !reflect.DeepEqual(queried, []string{"10.1.1.10", "10.1.2.10", "10.1.3.10"}) {
+		t.Errorf("unexpected discovery IPs queried %v", queried)
+	}
+	if discoveryPortals[testConnectedTarget] != "10.1.3.10" {
+		t.Errorf("unexpected discovery portal %v", discoveryPortals[testConnectedTarget])
+	}
+
+	// The last discovery failure is returned if no discovery IP reports the target
+	_, err = discoverTarget(testConnectedTarget, []string{"10.1.1.10", "10.1.2.10"})
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+
+	// Without discovery IPs, the target's existing node records are used
+	testDir, err := ioutil.TempDir("", "iscsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+	writeTestFile(t, filepath.Join(testDir, testConnectedTarget, "10.0.0.1,3260,2460"), "node.startup = automatic\n")
+	savedNodesPaths := iscsiNodesPaths
+	defer func() { iscsiNodesPaths = savedNodesPaths }()
+	iscsiNodesPaths = []string{testDir}
+	queried = nil
+	if portals, err = discoverTarget(testConnectedTarget, nil); err != nil || portals != nil || queried != nil {
+		t.Errorf("unexpected portals %v, queried=%v, err=%v", portals, queried, err)
+	}
+	if _, err = discoverTarget(testUnconnectedTarget, nil); err == nil {
+		t.Error("target without node records not rejected")
+	}
+}
+
+func TestLoginTarget(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "iscsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	defer func(record func(string, string) error) { recordDiscoveryPortal = record }(recordDiscoveryPortal)
+	recordDiscoveryPortal = func(targetName string, discoveryIP string) error { return nil }
+
+	var commands []string
+	savedSessionPath, savedExecIscsiadm := iscsiSessionPath, execIscsiadm
+	defer func() { iscsiSessionPath, execIscsiadm = savedSessionPath, savedExecIscsiadm }()
+	iscsiSessionPath = filepath.Join(testDir, "iscsi_session")
+	execIscsiadm = func(args ...string) (string, int, error) {
+		commands = append(commands, strings.Join(args, " "))
+		if args[1] == "discovery" {
+			return "10.1.1.11:3260,2460 " + testConnectedTarget + "\n10.1.1.12:3260,2460 " + testConnectedTarget + "\n", 0, nil
+		}
+		return "", 0, nil
+	}
+
+	// The node records are created, and set to manual startup, before the ephemeral login
+	persistent := false
+	blockDev := model.BlockDeviceAccessInfo{
+		TargetName: testConnectedTarget,
+		IscsiAccessInfo: &model.IscsiAccessInfo{
+			DiscoveryIP:  "10.1.1.10",
+			ChapUser:     "chapuser",
+			ChapPassword: "chappassword",
+			Persistent:   &persistent,
+		},
+	}
+	if err = NewIscsiPlugin().LoginTarget(blockDev); err != nil {
+		t.Fatalf("LoginTarget failed, err=%v", err)
+	}
+	target := "--mode node --targetname " + testConnectedTarget
+	expected := []string{
+		"--mode discovery --type sendtargets --portal 10.1.1.10 --op nonpersistent",
+		target + " --portal 10.1.1.11:3260,2460 --op new",
+		target + " --portal 10.1.1.12:3260,2460 --op new",
+		target + " --op update -n node.startup -v manual",
+		target + " --op update -n node.session.auth.authmethod -v CHAP",
+		target + " --op update -n node.session.auth.username -v chapuser",
+		target + " --op update -n node.session.auth.password -v chappassword",
+		target + " --login",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("unexpected commands\n%v\nexpected\n%v", strings.Join(commands, "\n"), strings.Join(expected, "\n"))
+	}
+
+	// A target that is already logged in is left as is
+	writeTestFile(t, filepath.Join(iscsiSessionPath, "session1", "targetname"), testConnectedTarget+"\n")
+	writeTestFile(t, filepath.Join(iscsiSessionPath, "session1", "state"), "LOGGED_IN\n")
+	commands = nil
+	if err = NewIscsiPlugin().LoginTarget(blockDev); err != nil || commands != nil {
+		t.Errorf("unexpected commands %v, err=%v", commands, err)
+	}
+}
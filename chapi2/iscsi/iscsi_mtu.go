@@ -0,0 +1,198 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// PATH MTU VALIDATION
+//
+//		Jumbo frames must be enabled end to end: on the host NIC, every switch port and the array
+//		data ports.  A switch port left at a smaller MTU silently drops the larger frames (no ICMP
+//		"fragmentation needed" is returned for a layer 2 MTU mismatch), so iSCSI logins and small
+//		I/Os succeed while large I/Os time out and show up as random iSCSI connection resets.
+//
+//		The path MTU between an initiator port and a target port is validated by ICMP echo
+//		requests, sent with the don't fragment flag, of increasing size (576, 1500 and 9000 bytes)
+//		up to the NIC MTU of the initiator port.  A target port only reached by packets smaller
+//		than the NIC MTU is a mismatch.  The validation is optional (PathMtuCheckEnv); when enabled
+//		PlanLogin validates each planned connection and the readiness report validates the target
+//		portals of the logged in sessions.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/host"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// PathMtuCheckEnv enables, when set to "true", the path MTU validation of the planned
+	// connections and of the readiness report
+	PathMtuCheckEnv = config.PathMtuCheckEnv
+
+	pathMtuEchoOverhead = 28 // IPv4 and ICMP header bytes of an echo request
+	pathMtuPingCount    = 2  // Echo requests sent for each probed MTU
+)
+
+var (
+	// pathMtuProbes are the MTUs probed, in increasing order, below the NIC MTU
+	pathMtuProbes = []int64{576, 1500, 9000}
+
+	// pingPathMtu returns true if an echo request of the given MTU, with the don't fragment flag,
+	// reaches the target port from the initiator port; a variable so that tests can replace it
+	pingPathMtu = func(initiatorPort *model.Network, targetAddress string, mtu int64) bool {
+		options := getPingOptions(&PingOptions{Count: pathMtuPingCount, Size: int(mtu - pathMtuEchoOverhead), DontFragment: true})
+		received, err := pingTargetPort(initiatorPort, targetAddress, options, rand.Intn(0x10000))
+		if err != nil {
+			log.Tracef("Unable to ping targetPort=%v from initiatorPort=%v with MTU %v, err=%v", targetAddress, initiatorPort.AddressV4, mtu, err)
+		}
+		return received != 0
+	}
+
+	// Session and initiator port enumeration routines; variables so that tests can replace them
+	getPathMtuSessions       = getTargetSessions
+	getPathMtuInitiatorPorts = func() ([]*model.Network, error) {
+		return host.NewHostPlugin().GetNetworks()
+	}
+)
+
+// IsPathMtuCheckEnabled returns true if PathMtuCheckEnv is set to "true"
+func IsPathMtuCheckEnabled() bool {
+	return config.Enabled(PathMtuCheckEnv)
+}
+
+// CheckPathMtu validates the path MTU of each IT nexus against the NIC MTU of its initiator port.
+// An IT nexus whose initiator port is picked by the initiator (e.g. model.ConnectTypeAutoInitiator)
+// is validated through the initiator ports sharing the target port's subnet.  The results are
+// ordered by initiator and target address.
+func CheckPathMtu(itNexus map[*model.Network][]*model.TargetPortal, initiatorPorts []*model.Network) []*model.PathMtu {
+	log.Trace(">>>>> CheckPathMtu")
+	defer log.Traceln("<<<<< CheckPathMtu")
+
+	// Resolve the initiator ports picked by the initiator
+	resolved := make(map[*model.Network][]*model.TargetPortal)
+	for initiatorPort, targetPorts := range itNexus {
+		if !isAnyInitiatorPort(initiatorPort) {
+			resolved[initiatorPort] = append(resolved[initiatorPort], targetPorts...)
+			continue
+		}
+		for subnetPort, subnetTargets := range getSubnetITNexus(initiatorPorts, targetPorts) {
+			resolved[subnetPort] = append(resolved[subnetPort], subnetTargets...)
+		}
+	}
+
+	// Validate each IT nexus in parallel; each validation waits for several echo replies
+	var results []*model.PathMtu
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+	checked := make(map[string]bool)
+	for initiatorPort, targetPorts := range resolved {
+		if initiatorPort.Mtu <= pathMtuEchoOverhead {
+			log.Tracef("Skipping initiatorPort=%v, unknown NIC MTU", initiatorPort.AddressV4)
+			continue
+		}
+		for _, targetPort := range targetPorts {
+			key := initiatorPort.AddressV4 + "-" + targetPort.Address
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+
+			wg.Add(1)
+			go func(initiatorPort *model.Network, targetAddress string) {
+				defer wg.Done()
+				result := checkPathMtu(initiatorPort, targetAddress)
+				mux.Lock()
+				results = append(results, result)
+				mux.Unlock()
+			}(initiatorPort, targetPort.Address)
+		}
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].InitiatorAddress != results[j].InitiatorAddress {
+			return results[i].InitiatorAddress < results[j].InitiatorAddress
+		}
+		return results[i].TargetAddress < results[j].TargetAddress
+	})
+	return results
+}
+
+// CheckSessionPathMtu validates the path MTU of the target portals of the logged in iSCSI sessions
+// through the initiator ports sharing their subnet
+func (plugin *IscsiPlugin) CheckSessionPathMtu() ([]*model.PathMtu, error) {
+	log.Trace(">>>>> CheckSessionPathMtu")
+	defer log.Traceln("<<<<< CheckSessionPathMtu")
+
+	sessions, err := getPathMtuSessions()
+	if err != nil {
+		return nil, err
+	}
+	var targetPorts []*model.TargetPortal
+	seen := make(map[string]bool)
+	for _, session := range sessions {
+		address, port, err := net.SplitHostPort(session.portal)
+		if err != nil || seen[address] {
+			continue
+		}
+		seen[address] = true
+		targetPorts = append(targetPorts, &model.TargetPortal{Address: address, Port: port})
+	}
+	if len(targetPorts) == 0 {
+		return nil, nil
+	}
+
+	initiatorPorts, err := getPathMtuInitiatorPorts()
+	if err != nil {
+		return nil, err
+	}
+	anyInitiatorPort := &model.Network{}
+	return CheckPathMtu(map[*model.Network][]*model.TargetPortal{anyInitiatorPort: targetPorts}, initiatorPorts), nil
+}
+
+// checkPathMtu validates the path MTU from the initiator port to the target address by sending
+// echo requests of increasing size, up to the NIC MTU, until one is not answered
+func checkPathMtu(initiatorPort *model.Network, targetAddress string) *model.PathMtu {
+	result := &model.PathMtu{
+		InitiatorAddress: initiatorPort.AddressV4,
+		InitiatorName:    initiatorPort.Name,
+		TargetAddress:    targetAddress,
+		InterfaceMtu:     initiatorPort.Mtu,
+	}
+	for _, mtu := range getPathMtuProbes(initiatorPort.Mtu) {
+		if !pingPathMtu(initiatorPort, targetAddress, mtu) {
+			break
+		}
+		result.PathMtu = mtu
+	}
+
+	switch {
+	case result.PathMtu == 0:
+		result.Status = model.PathMtuUnreachable
+	case result.PathMtu < result.InterfaceMtu:
+		result.Status = model.PathMtuMismatch
+		log.Warnf("Path MTU from %v (%v) to %v is %v, smaller than the NIC MTU %v", result.InitiatorAddress, result.InitiatorName, targetAddress, result.PathMtu, result.InterfaceMtu)
+	default:
+		result.Status = model.PathMtuMatch
+	}
+	return result
+}
+
+// getPathMtuProbes returns the MTUs probed, in increasing order, for the given NIC MTU
+func getPathMtuProbes(interfaceMtu int64) []int64 {
+	var probes []int64
+	for _, mtu := range pathMtuProbes {
+		if mtu < interfaceMtu {
+			probes = append(probes, mtu)
+		}
+	}
+	return append(probes, interfaceMtu)
+}
@@ -0,0 +1,92 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestCheckPathMtu(t *testing.T) {
+	savedPingPathMtu := pingPathMtu
+	defer func() { pingPathMtu = savedPingPathMtu }()
+
+	// Largest MTU reaching each target port, 0 if unreachable
+	pathMtu := map[string]int64{"10.1.1.20": 9000, "10.1.1.21": 1500, "10.1.1.22": 0, "10.1.2.20": 1500}
+	var mux sync.Mutex
+	probed := make(map[string][]int64)
+	pingPathMtu = func(initiatorPort *model.Network, targetAddress string, mtu int64) bool {
+		mux.Lock()
+		probed[targetAddress] = append(probed[targetAddress], mtu)
+		mux.Unlock()
+		return mtu <= pathMtu[targetAddress]
+	}
+
+	jumbo := &model.Network{Name: "eth1", AddressV4: "10.1.1.5", MaskV4: "255.255.255.0", Mtu: 9000}
+	standard := &model.Network{Name: "eth2", AddressV4: "10.1.2.5", MaskV4: "255.255.255.0", Mtu: 1500}
+	unknown := &model.Network{Name: "eth3", AddressV4: "10.1.3.5", MaskV4: "255.255.255.0"}
+	itNexus := map[*model.Network][]*model.TargetPortal{
+		jumbo:                  {{Address: "10.1.1.20"}, {Address: "10.1.1.21"}, {Address: "10.1.1.22"}},
+		{AddressV4: "0.0.0.0"}: {{Address: "10.1.2.20"}},
+		unknown:                {{Address: "10.1.3.20"}},
+	}
+	results := CheckPathMtu(itNexus, []*model.Network{jumbo, standard, unknown})
+
+	expected := []*model.PathMtu{
+		{InitiatorAddress: "10.1.1.5", InitiatorName: "eth1", TargetAddress: "10.1.1.20", InterfaceMtu: 9000, PathMtu: 9000, Status: model.PathMtuMatch},
+		{InitiatorAddress: "10.1.1.5", InitiatorName: "eth1", TargetAddress: "10.1.1.21", InterfaceMtu: 9000, PathMtu: 1500, Status: model.PathMtuMismatch},
+		{InitiatorAddress: "10.1.1.5", InitiatorName: "eth1", TargetAddress: "10.1.1.22", InterfaceMtu: 9000, Status: model.PathMtuUnreachable},
+		{InitiatorAddress: "10.1.2.5", InitiatorName: "eth2", TargetAddress: "10.1.2.20", InterfaceMtu: 1500, PathMtu: 1500, Status: model.PathMtuMatch},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		for _, result := range results {
+			t.Logf("result %+v", result)
+		}
+		t.Fatalf("unexpected path MTU results")
+	}
+
+	// Echo requests of increasing size, stopping at the first one not answered
+	if expected := []int64{576, 1500, 9000}; !reflect.DeepEqual(probed["10.1.1.21"], expected) {
+		t.Errorf("expected %v probed, got %v", expected, probed["10.1.1.21"])
+	}
+	if expected := []int64{576}; !reflect.DeepEqual(probed["10.1.1.22"], expected) {
+		t.Errorf("expected %v probed, got %v", expected, probed["10.1.1.22"])
+	}
+	if _, ok := probed["10.1.3.20"]; ok {
+		t.Error("expected the initiator port without a NIC MTU to be skipped")
+	}
+}
+
+func TestCheckSessionPathMtu(t *testing.T) {
+	savedPingPathMtu := pingPathMtu
+	savedGetPathMtuSessions := getPathMtuSessions
+	savedGetPathMtuInitiatorPorts := getPathMtuInitiatorPorts
+	defer func() {
+		pingPathMtu = savedPingPathMtu
+		getPathMtuSessions = savedGetPathMtuSessions
+		getPathMtuInitiatorPorts = savedGetPathMtuInitiatorPorts
+	}()
+
+	pingPathMtu = func(initiatorPort *model.Network, targetAddress string, mtu int64) bool { return mtu <= 1500 }
+	getPathMtuSessions = func() ([]*targetSession, error) {
+		return []*targetSession{
+			{id: "1", targetName: "iqn.target1", portal: "10.1.1.20:3260"},
+			{id: "2", targetName: "iqn.target2", portal: "10.1.1.20:3260"},
+			{id: "3", targetName: "iqn.target3", portal: "invalid"},
+		}, nil
+	}
+	getPathMtuInitiatorPorts = func() ([]*model.Network, error) {
+		return []*model.Network{{Name: "eth1", AddressV4: "10.1.1.5", MaskV4: "255.255.255.0", Mtu: 9000}}, nil
+	}
+
+	results, err := NewIscsiPlugin().CheckSessionPathMtu()
+	if err != nil {
+		t.Fatalf("CheckSessionPathMtu failed, err=%v", err)
+	}
+	if len(results) != 1 || results[0].TargetAddress != "10.1.1.20" || results[0].Status != model.PathMtuMismatch || results[0].PathMtu != 1500 {
+		t.Errorf("unexpected path MTU results %v", results)
+	}
+}
@@ -0,0 +1,167 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	iscsiPortalPort        = "3260"          // Default iSCSI discovery portal port
+	discoveryPortalTimeout = 3 * time.Second // Wait for a discovery portal to accept a connection
+)
+
+var (
+	// dialDiscoveryPortal checks the discovery portal accepts connections; a variable so that
+	// tests can replace it
+	dialDiscoveryPortal = func(address string) error {
+		conn, err := net.DialTimeout("tcp", address, discoveryPortalTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+)
+
+// PlanLogin reports, in the given plan, how LoginTarget would connect to the iSCSI target: the
+// connection type and initiator/target port pairs it would use, the number of connections it would
+// make and the preconditions preventing the login.  Nothing is discovered or logged in; IT nexuses
+// are only pinged with the "ping" connection type.
+func (plugin *IscsiPlugin) PlanLogin(blockDev model.BlockDeviceAccessInfo, plan *model.AttachPlan) {
+	log.Tracef(">>>>> PlanLogin, TargetName=%v", blockDev.TargetName)
+	defer log.Traceln("<<<<< PlanLogin")
+
+	plan.TargetName = blockDev.TargetName
+	iscsiAccessInfo := blockDev.IscsiAccessInfo
+	if iscsiAccessInfo == nil {
+		iscsiAccessInfo = &model.IscsiAccessInfo{}
+	}
+
+	// LoginTarget only rescans a target that is already logged in
+	loggedIn, err := plugin.IsTargetLoggedIn(blockDev.TargetName)
+	if err != nil {
+		plan.Blockers = append(plan.Blockers, fmt.Sprintf("unable to check if target %v is logged in, %v", blockDev.TargetName, err))
+		return
+	}
+	if loggedIn {
+		plan.AlreadyConnected = true
+		plan.DiscoveryIP = plugin.GetDiscoveryPortal(blockDev.TargetName)
+		return
+	}
+
+	connectTypes, err := plugin.connectTypeToArray(iscsiAccessInfo.ConnectType)
+	if err != nil {
+		plan.Blockers = append(plan.Blockers, err.Error())
+		return
+	}
+
+	// The target portals are only known once the target is discovered.  An undiscovered target
+	// must be discoverable through one of the discovery IPs.
+	targetPorts, _ := plugin.GetTargetPortals(blockDev.TargetName, false)
+	if len(targetPorts) == 0 {
+		discoveryIPs := getDiscoveryIPs(iscsiAccessInfo)
+		plan.DiscoveryIP = getReachableDiscoveryIP(discoveryIPs)
+		switch {
+		case len(discoveryIPs) == 0:
+			plan.Blockers = append(plan.Blockers, fmt.Sprintf("target %v is not discovered and no discovery IP was provided", blockDev.TargetName))
+		case plan.DiscoveryIP == "":
+			plan.Blockers = append(plan.Blockers, fmt.Sprintf("target %v is not discovered and none of the discovery IPs %v are reachable", blockDev.TargetName, discoveryIPs))
+		default:
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("target %v is not discovered, its ports are only known once discovered through %v", blockDev.TargetName, plan.DiscoveryIP))
+		}
+		return
+	}
+	plan.DiscoveryIP = plugin.GetDiscoveryPortal(blockDev.TargetName)
+
+	initiatorPorts, err := getPlanInitiatorPorts(blockDev)
+	if err != nil {
+		plan.Blockers = append(plan.Blockers, err.Error())
+		return
+	}
+
+	// Like loginTarget, the first connection type with an IT nexus is used
+	var plannedNexus map[*model.Network][]*model.TargetPortal
+	for _, connectType := range connectTypes {
+		itNexus, err := getConnectTypeITNexus(connectType, initiatorPorts, targetPorts, iscsiAccessInfo)
+		if err != nil || len(itNexus) == 0 {
+			continue
+		}
+		plan.ConnectType = connectType
+		plan.Connections = getPlannedConnections(itNexus)
+		plannedNexus = itNexus
+		break
+	}
+	if len(plan.Connections) == 0 {
+		plan.Blockers = append(plan.Blockers, fmt.Sprintf("no initiator port can connect to the target ports using connection type(s) %v", connectTypes))
+		return
+	}
+
+	// Jumbo frames dropped on the path to a target port reset the connections under load
+	if IsPathMtuCheckEnabled() {
+		plan.PathMtu = CheckPathMtu(plannedNexus, initiatorPorts)
+		for _, pathMtu := range plan.PathMtu {
+			if pathMtu.Status == model.PathMtuMismatch {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("path MTU from %v (%v) to target port %v is %v, smaller than the NIC MTU %v; enable the same MTU on every switch port of the path",
+					pathMtu.InitiatorAddress, pathMtu.InitiatorName, pathMtu.TargetAddress, pathMtu.PathMtu, pathMtu.InterfaceMtu))
+			}
+		}
+	}
+
+	minConnections, maxConnections := getPlanConnectionLimits(blockDev.TargetScope)
+	plan.ConnectionCount = getPlannedConnectionCount(len(plan.Connections), minConnections, maxConnections)
+}
+
+// getReachableDiscoveryIP returns the first discovery IP accepting connections, or an empty string
+// if none do
+func getReachableDiscoveryIP(discoveryIPs []string) string {
+	for _, discoveryIP := range discoveryIPs {
+		if err := dialDiscoveryPortal(net.JoinHostPort(discoveryIP, iscsiPortalPort)); err != nil {
+			log.Infof("Discovery IP %v is not reachable, err=%v", discoveryIP, err)
+			continue
+		}
+		return discoveryIP
+	}
+	return ""
+}
+
+// getPlannedConnections returns the IT nexuses as planned connections, ordered by initiator and
+// target address
+func getPlannedConnections(itNexus map[*model.Network][]*model.TargetPortal) []*model.PlannedConnection {
+	var connections []*model.PlannedConnection
+	for initiatorPort, targetPorts := range itNexus {
+		for _, targetPort := range targetPorts {
+			connections = append(connections, &model.PlannedConnection{
+				InitiatorAddress: initiatorPort.AddressV4,
+				TargetAddress:    targetPort.Address,
+				TargetPort:       targetPort.Port,
+			})
+		}
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		if connections[i].InitiatorAddress != connections[j].InitiatorAddress {
+			return connections[i].InitiatorAddress < connections[j].InitiatorAddress
+		}
+		return connections[i].TargetAddress < connections[j].TargetAddress
+	})
+	return connections
+}
+
+// getPlannedConnectionCount returns the number of connections made over the given number of IT
+// nexuses; one per IT nexus up to the maximum, doubled over the same IT nexuses until the minimum
+// is reached (as the Windows loginTarget does).  A maximum of 0 is unlimited.
+func getPlannedConnectionCount(nexusCount int, minConnections, maxConnections uint32) int {
+	count := uint32(nexusCount)
+	if maxConnections != 0 && count > maxConnections {
+		count = maxConnections
+	}
+	for count != 0 && count < minConnections {
+		count *= 2
+	}
+	return int(count)
+}
@@ -0,0 +1,76 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestGetReachableDiscoveryIP(t *testing.T) {
+	savedDial := dialDiscoveryPortal
+	defer func() { dialDiscoveryPortal = savedDial }()
+
+	var dialed []string
+	dialDiscoveryPortal = func(address string) error {
+		dialed = append(dialed, address)
+		if address == "10.1.2.10:3260" {
+			return nil
+		}
+		return errors.New("connection refused")
+	}
+
+	if discoveryIP := getReachableDiscoveryIP([]string{"10.1.1.10", "10.1.2.10", "10.1.3.10"}); discoveryIP != "10.1.2.10" {
+		t.Errorf("expected 10.1.2.10, got %v", discoveryIP)
+	}
+	if expected := []string{"10.1.1.10:3260", "10.1.2.10:3260"}; !reflect.DeepEqual(dialed, expected) {
+		t.Errorf("expected %v dialed, got %v", expected, dialed)
+	}
+	if discoveryIP := getReachableDiscoveryIP([]string{"10.1.1.10"}); discoveryIP != "" {
+		t.Errorf("expected no reachable discovery IP, got %v", discoveryIP)
+	}
+}
+
+func TestGetPlannedConnections(t *testing.T) {
+	initiator1 := &model.Network{AddressV4: "10.1.1.11"}
+	initiator2 := &model.Network{AddressV4: "10.1.1.12"}
+	target1 := &model.TargetPortal{Address: "10.1.1.21", Port: "3260"}
+	target2 := &model.TargetPortal{Address: "10.1.1.22", Port: "3260"}
+	itNexus := map[*model.Network][]*model.TargetPortal{
+		initiator2: {target2, target1},
+		initiator1: {target2},
+	}
+
+	expected := []*model.PlannedConnection{
+		{InitiatorAddress: "10.1.1.11", TargetAddress: "10.1.1.22", TargetPort: "3260"},
+		{InitiatorAddress: "10.1.1.12", TargetAddress: "10.1.1.21", TargetPort: "3260"},
+		{InitiatorAddress: "10.1.1.12", TargetAddress: "10.1.1.22", TargetPort: "3260"},
+	}
+	if connections := getPlannedConnections(itNexus); !reflect.DeepEqual(connections, expected) {
+		t.Errorf("unexpected planned connections %+v", connections)
+	}
+}
+
+func TestGetPlannedConnectionCount(t *testing.T) {
+	tests := []struct {
+		nexusCount     int
+		minConnections uint32
+		maxConnections uint32
+		expected       int
+	}{
+		{0, 1, 0, 0},
+		{4, 1, 0, 4},
+		{4, 1, 2, 2},
+		{1, 3, 8, 4},
+		{3, 2, 8, 3},
+		{2, 8, 8, 8},
+	}
+	for _, tc := range tests {
+		if count := getPlannedConnectionCount(tc.nexusCount, tc.minConnections, tc.maxConnections); count != tc.expected {
+			t.Errorf("getPlannedConnectionCount(%v, %v, %v) = %v, expected %v", tc.nexusCount, tc.minConnections, tc.maxConnections, count, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// DUAL-STACK PORTAL SELECTION
+//
+//		A dual-stack target can advertise the same target port over both IPv4 and IPv6, and can
+//		report the same portal more than once (e.g. "::ffff:10.1.0.10" and "10.1.0.10"), which
+//		would create duplicate sessions to the same target port.  GetTargetPortals therefore
+//		returns each target portal once, identified by its normalized address and port, and
+//		selects the portals of the address family policy (PortalAddressFamilyEnv):
+//
+//			ipv4			IPv4 portals only (default, as before)
+//			ipv6			IPv6 portals only
+//			prefer-ipv6		IPv6 portals if the target advertises any, otherwise IPv4 portals
+//			dual			IPv4 and IPv6 portals
+//
+//		Callers that request ipv4Only always get IPv4 portals only, regardless of the policy.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"net"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// PortalAddressFamilyEnv is the address family policy of the target portals
+	PortalAddressFamilyEnv = config.PortalAddressFamilyEnv
+
+	// Address family policies
+	PortalAddressFamilyIPv4       = "ipv4"
+	PortalAddressFamilyIPv6       = "ipv6"
+	PortalAddressFamilyPreferIPv6 = "prefer-ipv6"
+	PortalAddressFamilyDual       = "dual"
+
+	// DefaultPortalAddressFamily is the address family policy if PortalAddressFamilyEnv is not set
+	DefaultPortalAddressFamily = PortalAddressFamilyIPv4
+)
+
+// getPortalAddressFamily returns the address family policy of the target portals
+func getPortalAddressFamily() string {
+	return config.Choice(PortalAddressFamilyEnv, DefaultPortalAddressFamily,
+		PortalAddressFamilyIPv4, PortalAddressFamilyIPv6, PortalAddressFamilyPreferIPv6, PortalAddressFamilyDual)
+}
+
+// portalIdentity returns the identity of a target portal (its normalized address and port) and
+// whether its address is an IPv4 address.  An address that isn't an IP address is used as is.
+func portalIdentity(portal *model.TargetPortal) (identity string, ipv4 bool) {
+	address := strings.Trim(portal.Address, "[]")
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return address + "|" + portal.Port, true
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String() + "|" + portal.Port, true
+	}
+	return ip.String() + "|" + portal.Port, false
+}
+
+// selectTargetPortals returns the target portals deduplicated by target port identity, in their
+// reported order, and filtered by the given address family policy
+func selectTargetPortals(portals []*model.TargetPortal, family string) []*model.TargetPortal {
+	var unique, ipv4Portals, ipv6Portals []*model.TargetPortal
+	seen := make(map[string]bool)
+	for _, portal := range portals {
+		identity, ipv4 := portalIdentity(portal)
+		if seen[identity] {
+			log.Tracef("Ignoring duplicate target portal %v:%v", portal.Address, portal.Port)
+			continue
+		}
+		seen[identity] = true
+		unique = append(unique, portal)
+		if ipv4 {
+			ipv4Portals = append(ipv4Portals, portal)
+		} else {
+			ipv6Portals = append(ipv6Portals, portal)
+		}
+	}
+
+	switch family {
+	case PortalAddressFamilyIPv6:
+		return ipv6Portals
+	case PortalAddressFamilyPreferIPv6:
+		if len(ipv6Portals) != 0 {
+			return ipv6Portals
+		}
+		return ipv4Portals
+	case PortalAddressFamilyDual:
+		return unique
+	}
+	return ipv4Portals
+}
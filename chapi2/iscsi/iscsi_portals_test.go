@@ -0,0 +1,71 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestSelectTargetPortals(t *testing.T) {
+	portals := []*model.TargetPortal{
+		{Address: "10.1.0.10", Port: "3260"},
+		{Address: "fd00::10", Port: "3260"},
+		{Address: "::ffff:10.1.0.10", Port: "3260"},
+		{Address: "[FD00:0::10]", Port: "3260"},
+		{Address: "10.1.0.10", Port: "3261"},
+		{Address: "fd00::11", Port: "3260"},
+	}
+	addresses := func(portals []*model.TargetPortal) (result []string) {
+		for _, portal := range portals {
+			result = append(result, portal.Address+":"+portal.Port)
+		}
+		return result
+	}
+
+	tests := []struct {
+		family   string
+		expected []string
+	}{
+		{PortalAddressFamilyIPv4, []string{"10.1.0.10:3260", "10.1.0.10:3261"}},
+		{PortalAddressFamilyIPv6, []string{"fd00::10:3260", "fd00::11:3260"}},
+		{PortalAddressFamilyPreferIPv6, []string{"fd00::10:3260", "fd00::11:3260"}},
+		{PortalAddressFamilyDual, []string{"10.1.0.10:3260", "fd00::10:3260", "10.1.0.10:3261", "fd00::11:3260"}},
+	}
+	for _, test := range tests {
+		selected := addresses(selectTargetPortals(portals, test.family))
+		if len(selected) != len(test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.family, test.expected, selected)
+			continue
+		}
+		for index := range selected {
+			if selected[index] != test.expected[index] {
+				t.Errorf("%v: expected %v, got %v", test.family, test.expected, selected)
+				break
+			}
+		}
+	}
+
+	// A target without IPv6 portals falls back to IPv4 when IPv6 is preferred
+	if selected := selectTargetPortals(portals[:1], PortalAddressFamilyPreferIPv6); len(selected) != 1 || selected[0] != portals[0] {
+		t.Errorf("expected %v, got %v", addresses(portals[:1]), addresses(selected))
+	}
+}
+
+func TestGetPortalAddressFamily(t *testing.T) {
+	defer os.Unsetenv(PortalAddressFamilyEnv)
+	for value, expected := range map[string]string{
+		"":              DefaultPortalAddressFamily,
+		"IPv6":          PortalAddressFamilyIPv6,
+		" prefer-ipv6 ": PortalAddressFamilyPreferIPv6,
+		"dual":          PortalAddressFamilyDual,
+		"ipv5":          DefaultPortalAddressFamily,
+	} {
+		os.Setenv(PortalAddressFamilyEnv, value)
+		if family := getPortalAddressFamily(); family != expected {
+			t.Errorf("%q: expected %v, got %v", value, expected, family)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// ROLLING RE-LOGIN
+//
+//		Existing iSCSI sessions keep the initiator name and CHAP credentials they were logged in
+//		with.  Once the initiator name or the CHAP secrets are rotated (on the array first, so that
+//		it accepts the new credentials), RotateCredentials updates the host's initiator name and
+//		persistent logins (Linux node records, Windows persistent logins) and then logs each
+//		session in again, one connection at a time, so that the multipath devices never lose all
+//		of their paths at once.  The next connection is only logged in again once the previous one
+//		is back with all of its SCSI devices.
+//
+//		Under Linux, the session is logged out and its node record logged in again.  Under Windows,
+//		the new session is logged in before the old one is logged out.  Windows does not report
+//		the CHAP credentials of existing logins, so they must be provided again when only the
+//		initiator name is rotated on CHAP targets.
+//
+//		Targets with a single connection lose access while they are logged in again, so they are
+//		skipped unless AllowSinglePath is requested.  The first failed re-login stops the rotation;
+//		the credentials are likely not accepted by the array and logging in the remaining
+//		connections again would only take more paths down.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// reloginTimeout is how long a connection has to log in again with all of its SCSI devices
+	reloginTimeout = 60 * time.Second
+
+	// Rolling re-login error messages
+	errorMessageMissingCredentials  = "missing initiator name or CHAP credentials to rotate"
+	errorMessageMissingChapSecret   = "CHAP username and password must be provided together"
+	errorMessageReloginFailed       = "%v of %v target(s) were not logged in again with the new credentials"
+	errorMessageReloginNotAttempted = "not attempted, a previous re-login failed"
+	errorMessageReloginSinglePath   = "single connection, logging it in again would lose access"
+	errorMessageReloginTimeout      = "connection to %v not logged in again with %v SCSI device(s) in time"
+)
+
+var (
+	// Platform specific re-login routines; variables so that tests can replace them
+	getReloginSessions   = getTargetSessions
+	reloginTargetSession = reloginSession
+	setTargetCredentials = updateTargetCredentials
+	setInitiatorName     = updateInitiatorName
+)
+
+// targetSession is a connection (session) to an iSCSI target logged in again by RotateCredentials
+type targetSession struct {
+	id         string                // Session identifier
+	targetName string                // Target iSCSI name
+	portal     string                // Target portal ("address:port")
+	devices    int                   // SCSI devices (LUNs) of the session
+	private    *targetSessionPrivate // Platform specific session properties
+}
+
+// RotateCredentials applies the new initiator name and/or CHAP credentials to the host and logs in
+// again, one connection at a time, the logged in targets.  The outcome of each target is returned;
+// if a target could not be logged in again, a cerrors.Internal error is returned as well.
+func (plugin *IscsiPlugin) RotateCredentials(rotation *model.CredentialRotation) ([]*model.ReloginResult, error) {
+	log.Tracef(">>>>> RotateCredentials, initiatorName=%v, chapUser=%v, targets=%v", rotation.InitiatorName, rotation.ChapUser, rotation.Targets)
+	defer log.Trace("<<<<< RotateCredentials")
+
+	if err := validateCredentialRotation(rotation); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	// Update the host's initiator name; the existing sessions keep the previous name until they
+	// are logged in again
+	if rotation.InitiatorName != "" {
+		if err := setInitiatorName(rotation.InitiatorName); err != nil {
+			return nil, err
+		}
+	}
+
+	sessions, err := getReloginSessions()
+	if err != nil {
+		return nil, err
+	}
+	targetNames, targetSessions := groupTargetSessions(sessions, rotation.Targets)
+
+	var results []*model.ReloginResult
+	failed := 0
+	for _, targetName := range targetNames {
+		result := &model.ReloginResult{TargetName: targetName, Connections: len(targetSessions[targetName])}
+		results = append(results, result)
+		if failed != 0 {
+			result.Skipped, result.Error = true, errorMessageReloginNotAttempted
+			failed++
+			continue
+		}
+		if err = rotateTargetCredentials(targetName, targetSessions[targetName], rotation, result); err != nil {
+			result.Error = err.Error()
+			failed++
+		}
+	}
+
+	if failed != 0 {
+		err = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageReloginFailed, failed, len(results)).WithDetails(results)
+		log.Error(err)
+		return results, err
+	}
+	return results, nil
+}
+
+// validateCredentialRotation fails the request unless a valid initiator name and/or complete CHAP
+// credentials are provided
+func validateCredentialRotation(rotation *model.CredentialRotation) error {
+	if rotation.InitiatorName == "" && rotation.ChapUser == "" && rotation.ChapPassword == "" {
+		return cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMissingCredentials)
+	}
+	if rotation.InitiatorName != "" && !isValidIqn(rotation.InitiatorName) {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidIqn, rotation.InitiatorName)
+	}
+	if (rotation.ChapUser == "") != (rotation.ChapPassword == "") {
+		return cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMissingChapSecret)
+	}
+	return nil
+}
+
+// groupTargetSessions groups the sessions by target, keeping only the requested targets (all
+// targets if none are requested).  The requested targets without sessions are returned as well so
+// that their persistent logins are updated.  The target names are returned sorted.
+func groupTargetSessions(sessions []*targetSession, requestedTargets []string) ([]string, map[string][]*targetSession) {
+	targetSessions := make(map[string][]*targetSession)
+	for _, targetName := range requestedTargets {
+		targetSessions[targetName] = nil
+	}
+	for _, session := range sessions {
+		targetName := session.targetName
+		if len(requestedTargets) != 0 {
+			targetName = ""
+			for _, requestedTarget := range requestedTargets {
+				if strings.EqualFold(requestedTarget, session.targetName) {
+					targetName = requestedTarget
+				}
+			}
+			if targetName == "" {
+				continue
+			}
+		}
+		targetSessions[targetName] = append(targetSessions[targetName], session)
+	}
+
+	var targetNames []string
+	for targetName := range targetSessions {
+		targetNames = append(targetNames, targetName)
+	}
+	sort.Strings(targetNames)
+	return targetNames, targetSessions
+}
+
+// rotateTargetCredentials updates the persistent logins of the target with the new CHAP
+// credentials, if any, and logs in again each of its sessions, one at a time
+func rotateTargetCredentials(targetName string, sessions []*targetSession, rotation *model.CredentialRotation, result *model.ReloginResult) error {
+	if rotation.ChapUser != "" {
+		if err := setTargetCredentials(targetName, rotation.ChapUser, rotation.ChapPassword); err != nil {
+			result.Skipped = true
+			return err
+		}
+	}
+	if len(sessions) == 1 && !rotation.AllowSinglePath {
+		log.Warnf("Not logging in target %v again, %v", targetName, errorMessageReloginSinglePath)
+		result.Skipped, result.Error = true, errorMessageReloginSinglePath
+		return nil
+	}
+
+	for _, session := range sessions {
+		log.Infof("Logging in again session %v of target %v through %v (%v of %v)", session.id, targetName, session.portal, result.Relogged+1, len(sessions))
+		if err := reloginTargetSession(session, rotation); err != nil {
+			log.Errorf("Unable to log in again session %v of target %v, err=%v", session.id, targetName, err)
+			return err
+		}
+		result.Relogged++
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestRotateCredentials(t *testing.T) {
+	defer func(getSessions func() ([]*targetSession, error), relogin func(*targetSession, *model.CredentialRotation) error,
+		setCredentials func(string, string, string) error, setName func(string) error) {
+		getReloginSessions, reloginTargetSession, setTargetCredentials, setInitiatorName = getSessions, relogin, setCredentials, setName
+	}(getReloginSessions, reloginTargetSession, setTargetCredentials, setInitiatorName)
+
+	const target1, target2, target3 = "iqn.target1", "iqn.target2", "iqn.target3"
+	getReloginSessions = func() ([]*targetSession, error) {
+		return []*targetSession{
+			{id: "1", targetName: target1, portal: "10.1.1.21:3260"},
+			{id: "2", targetName: target2, portal: "10.1.1.21:3260"},
+			{id: "3", targetName: target1, portal: "10.1.1.22:3260"},
+			{id: "4", targetName: target3, portal: "10.1.1.21:3260"},
+			{id: "5", targetName: target3, portal: "10.1.1.22:3260"},
+		}, nil
+	}
+	var calls []string
+	failSession := ""
+	reloginTargetSession = func(session *targetSession, rotation *model.CredentialRotation) error {
+		calls = append(calls, "relogin "+session.id)
+		if session.id == failSession {
+			return errors.New("authentication failure")
+		}
+		return nil
+	}
+	setTargetCredentials = func(targetName string, chapUser string, chapPassword string) error {
+		calls = append(calls, "chap "+targetName+" "+chapUser)
+		return nil
+	}
+	setInitiatorName = func(iqn string) error {
+		calls = append(calls, "iqn "+iqn)
+		return nil
+	}
+	plugin := NewIscsiPlugin()
+
+	// Invalid requests
+	for _, rotation := range []*model.CredentialRotation{
+		{},
+		{InitiatorName: "host1"},
+		{ChapUser: "user1"},
+		{ChapPassword: "secret"},
+	} {
+		_, err := plugin.RotateCredentials(rotation)
+		if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.InvalidArgument {
+			t.Errorf("%+v: expected InvalidArgument, got %v", rotation, err)
+		}
+	}
+	if len(calls) != 0 {
+		t.Errorf("unexpected calls on invalid requests %v", calls)
+	}
+
+	// The initiator name is applied first, then each target's sessions are logged in again one at
+	// a time.  Single connection targets are skipped.
+	results, err := plugin.RotateCredentials(&model.CredentialRotation{InitiatorName: "iqn.2005-03.org.open-iscsi:host1", ChapUser: "user1", ChapPassword: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCalls := []string{"iqn iqn.2005-03.org.open-iscsi:host1", "chap iqn.target1 user1", "relogin 1", "relogin 3", "chap iqn.target2 user1", "chap iqn.target3 user1", "relogin 4", "relogin 5"}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, calls)
+	}
+	expectedResults := []*model.ReloginResult{
+		{TargetName: target1, Connections: 2, Relogged: 2},
+		{TargetName: target2, Connections: 1, Skipped: true, Error: errorMessageReloginSinglePath},
+		{TargetName: target3, Connections: 2, Relogged: 2},
+	}
+	if !reflect.DeepEqual(results, expectedResults) {
+		t.Errorf("expected results %+v, got %+v", expectedResults, results)
+	}
+
+	// The first failed re-login stops the rotation
+	calls, failSession = nil, "3"
+	results, err = plugin.RotateCredentials(&model.CredentialRotation{ChapUser: "user2", ChapPassword: "secret2", AllowSinglePath: true})
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.Internal {
+		t.Errorf("expected Internal error, got %v", err)
+	}
+	expectedCalls = []string{"chap iqn.target1 user2", "relogin 1", "relogin 3"}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, calls)
+	}
+	if len(results) != 3 || results[0].Relogged != 1 || results[0].Error == "" || !results[1].Skipped || !results[2].Skipped {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	// Only the requested targets are logged in again
+	calls, failSession = nil, ""
+	results, err = plugin.RotateCredentials(&model.CredentialRotation{ChapUser: "user3", ChapPassword: "secret3", Targets: []string{"IQN.TARGET3", "iqn.target4"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCalls = []string{"chap IQN.TARGET3 user3", "relogin 4", "relogin 5", "chap iqn.target4 user3"}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, calls)
+	}
+	if len(results) != 2 || results[0].Relogged != 2 || results[1].Connections != 0 || results[1].Skipped {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
@@ -0,0 +1,193 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// TOPOLOGY-AWARE PORTAL SELECTION
+//
+//		In stretched-cluster deployments, the target portals of both sites can share the subnet of
+//		the host's iSCSI NICs, so the subnet connect type would create high-latency cross-site
+//		sessions.  ITNexusSubnetCheckWithOptions weights each same subnet IT nexus by its target
+//		portal:  portals in a preferred CIDR (SubnetPreferredCIDRsEnv, e.g. the local site) rank
+//		first, portals in an avoided CIDR (SubnetAvoidCIDRsEnv, e.g. the remote site) rank last and
+//		are only used when no other portal matches, so access survives the loss of the local site.
+//		The IT nexuses of each physical NIC (identified by its MAC address, shared by the NIC's
+//		addresses) can also be capped (SubnetMaxConnectionsPerNICEnv), keeping the highest weights.
+//		Without any configuration, every same subnet IT nexus is returned, as before.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// SubnetPreferredCIDRsEnv lists the CIDRs of the preferred target portals (e.g.
+	// "10.1.0.0/24,10.2.0.0/24")
+	SubnetPreferredCIDRsEnv = config.SubnetPreferredCIDRsEnv
+
+	// SubnetAvoidCIDRsEnv lists the CIDRs of the target portals to avoid (e.g. the remote site)
+	SubnetAvoidCIDRsEnv = config.SubnetAvoidCIDRsEnv
+
+	// SubnetMaxConnectionsPerNICEnv is the maximum IT nexuses per physical NIC (0 for unlimited)
+	SubnetMaxConnectionsPerNICEnv = config.SubnetMaxConnectionsPerNICEnv
+
+	// Subnet affinity weights of an IT nexus
+	subnetWeightAvoided   = 0 // Target portal in an avoided CIDR
+	subnetWeightDefault   = 1 // Target portal in the initiator port's subnet
+	subnetWeightPreferred = 2 // Target portal in a preferred CIDR
+)
+
+// SubnetOptions are the topology preferences applied by ITNexusSubnetCheckWithOptions.  Zero
+// values apply no preference.
+type SubnetOptions struct {
+	PreferredCIDRs       []*net.IPNet // Target portals ranked first
+	AvoidCIDRs           []*net.IPNet // Target portals only used if no other target portal matches
+	MaxConnectionsPerNIC int          // Maximum IT nexuses per physical NIC (0 for unlimited)
+}
+
+// subnetNexus is a same subnet IT nexus along with its affinity weight
+type subnetNexus struct {
+	initiatorPort *model.Network
+	targetPort    *model.TargetPortal
+	weight        int
+}
+
+// ITNexusSubnetCheckWithOptions is ITNexusSubnetCheck with the given topology preferences.  The
+// target ports of each initiator port are returned by decreasing affinity weight.
+func ITNexusSubnetCheckWithOptions(initiatorPorts []*model.Network, targetPorts []*model.TargetPortal, options *SubnetOptions) (map[*model.Network][]*model.TargetPortal, error) {
+	log.Tracef(">>>>> ITNexusSubnetCheck, options=%+v", options)
+	defer log.Traceln("<<<<< ITNexusSubnetCheck")
+
+	itNexus := getSubnetITNexus(initiatorPorts, targetPorts)
+	if options != nil {
+		itNexus = applySubnetOptions(itNexus, initiatorPorts, options)
+	}
+
+	// Log and return IT nexus map
+	logITNexusMap(model.ConnectTypeSubnet, itNexus)
+	return itNexus, nil
+}
+
+// applySubnetOptions returns the IT nexus map weighted, filtered and capped by the topology
+// preferences
+func applySubnetOptions(itNexus map[*model.Network][]*model.TargetPortal, initiatorPorts []*model.Network, options *SubnetOptions) map[*model.Network][]*model.TargetPortal {
+	// Weight each IT nexus, walking the initiator ports in order so that the result is stable
+	var nexuses []*subnetNexus
+	avoidedOnly := true
+	for _, initiatorPort := range initiatorPorts {
+		for _, targetPort := range itNexus[initiatorPort] {
+			nexus := &subnetNexus{initiatorPort, targetPort, options.getWeight(targetPort.Address)}
+			if nexus.weight != subnetWeightAvoided {
+				avoidedOnly = false
+			}
+			nexuses = append(nexuses, nexus)
+		}
+		// Each initiator port is only walked once, even if listed twice
+		delete(itNexus, initiatorPort)
+	}
+
+	// Avoided target portals are dropped unless they are the only ones reachable
+	if !avoidedOnly {
+		var kept []*subnetNexus
+		for _, nexus := range nexuses {
+			if nexus.weight != subnetWeightAvoided {
+				kept = append(kept, nexus)
+			} else {
+				log.Tracef("Avoiding IT nexus, initiatorPort=%-15s, targetPort=%-15s", nexus.initiatorPort.AddressV4, nexus.targetPort.Address)
+			}
+		}
+		nexuses = kept
+	}
+	sort.SliceStable(nexuses, func(i, j int) bool { return nexuses[i].weight > nexuses[j].weight })
+
+	// Rebuild the IT nexus map, capping the IT nexuses of each physical NIC
+	itNexus = make(map[*model.Network][]*model.TargetPortal)
+	nicConnections := make(map[string]int)
+	for _, nexus := range nexuses {
+		nic := getPhysicalNIC(nexus.initiatorPort)
+		if options.MaxConnectionsPerNIC > 0 && nicConnections[nic] >= options.MaxConnectionsPerNIC {
+			log.Tracef("Skipping IT nexus, NIC %v at %v connections, initiatorPort=%-15s, targetPort=%-15s",
+				nic, options.MaxConnectionsPerNIC, nexus.initiatorPort.AddressV4, nexus.targetPort.Address)
+			continue
+		}
+		nicConnections[nic]++
+		itNexus[nexus.initiatorPort] = append(itNexus[nexus.initiatorPort], nexus.targetPort)
+	}
+	return itNexus
+}
+
+// getWeight returns the affinity weight of the target address
+func (options *SubnetOptions) getWeight(address string) int {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return subnetWeightDefault
+	}
+	if containsIP(options.AvoidCIDRs, ip) {
+		return subnetWeightAvoided
+	}
+	if containsIP(options.PreferredCIDRs, ip) {
+		return subnetWeightPreferred
+	}
+	return subnetWeightDefault
+}
+
+// containsIP returns true if one of the networks contains the IP address
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// getPhysicalNIC returns the identity of the initiator port's physical NIC; its MAC address if
+// known, otherwise its name or, lastly, its address
+func getPhysicalNIC(initiatorPort *model.Network) string {
+	if initiatorPort.Mac != "" {
+		return strings.ToLower(initiatorPort.Mac)
+	}
+	if initiatorPort.Name != "" {
+		return initiatorPort.Name
+	}
+	return initiatorPort.AddressV4
+}
+
+// getSubnetOptions returns the topology preferences configured in the environment, or nil if none
+// are configured
+func getSubnetOptions() *SubnetOptions {
+	options := &SubnetOptions{
+		PreferredCIDRs:       parseCIDRList(SubnetPreferredCIDRsEnv, config.String(SubnetPreferredCIDRsEnv)),
+		AvoidCIDRs:           parseCIDRList(SubnetAvoidCIDRsEnv, config.String(SubnetAvoidCIDRsEnv)),
+		MaxConnectionsPerNIC: config.Int(SubnetMaxConnectionsPerNICEnv, 0, 0),
+	}
+	if len(options.PreferredCIDRs) == 0 && len(options.AvoidCIDRs) == 0 && options.MaxConnectionsPerNIC == 0 {
+		return nil
+	}
+	return options
+}
+
+// parseCIDRList parses a comma separated list of CIDRs, logging and ignoring the invalid entries
+func parseCIDRList(name, value string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Warnf("Ignoring invalid %v CIDR %q, err=%v", name, entry, err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,98 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func getTargetAddresses(targetPorts []*model.TargetPortal) []string {
+	var addresses []string
+	for _, targetPort := range targetPorts {
+		addresses = append(addresses, targetPort.Address)
+	}
+	return addresses
+}
+
+func TestITNexusSubnetCheckWithOptions(t *testing.T) {
+	// Two addresses of the same NIC and one address of another NIC, all in 10.1.0.0/16
+	eth0a := &model.Network{Name: "eth0", AddressV4: "10.1.1.10", MaskV4: "255.255.0.0", Mac: "00:50:56:AA:00:01"}
+	eth0b := &model.Network{Name: "eth0", AddressV4: "10.1.1.11", MaskV4: "255.255.0.0", Mac: "00:50:56:aa:00:01"}
+	eth1 := &model.Network{Name: "eth1", AddressV4: "10.1.1.12", MaskV4: "255.255.0.0", Mac: "00:50:56:aa:00:02"}
+	initiatorPorts := []*model.Network{eth0a, eth0b, eth1}
+
+	// Local site 10.1.1.0/24, remote site 10.1.2.0/24, and a portal in another subnet
+	local := &model.TargetPortal{Address: "10.1.1.100"}
+	local2 := &model.TargetPortal{Address: "10.1.1.101"}
+	remote := &model.TargetPortal{Address: "10.1.2.100"}
+	other := &model.TargetPortal{Address: "192.168.1.100"}
+	preferred := parseCIDRList("preferred", "10.1.1.0/24")
+	avoided := parseCIDRList("avoided", "10.1.2.0/24")
+
+	// Without options, every same subnet IT nexus is returned
+	itNexus, _ := ITNexusSubnetCheckWithOptions(initiatorPorts, []*model.TargetPortal{remote, local, other}, nil)
+	if got := getTargetAddresses(itNexus[eth1]); !reflect.DeepEqual(got, []string{"10.1.2.100", "10.1.1.100"}) {
+		t.Errorf("expected both same subnet portals, got %v", got)
+	}
+
+	// Preferred portals rank first
+	itNexus, _ = ITNexusSubnetCheckWithOptions(initiatorPorts, []*model.TargetPortal{remote, local}, &SubnetOptions{PreferredCIDRs: preferred})
+	if got := getTargetAddresses(itNexus[eth1]); !reflect.DeepEqual(got, []string{"10.1.1.100", "10.1.2.100"}) {
+		t.Errorf("expected the preferred portal first, got %v", got)
+	}
+
+	// Avoided portals are dropped while another portal matches
+	itNexus, _ = ITNexusSubnetCheckWithOptions(initiatorPorts, []*model.TargetPortal{remote, local}, &SubnetOptions{AvoidCIDRs: avoided})
+	for _, initiatorPort := range initiatorPorts {
+		if got := getTargetAddresses(itNexus[initiatorPort]); !reflect.DeepEqual(got, []string{"10.1.1.100"}) {
+			t.Errorf("expected only the local portal from %v, got %v", initiatorPort.AddressV4, got)
+		}
+	}
+
+	// ...but are used if they are the only ones reachable
+	itNexus, _ = ITNexusSubnetCheckWithOptions(initiatorPorts, []*model.TargetPortal{remote, other}, &SubnetOptions{AvoidCIDRs: avoided})
+	if got := getTargetAddresses(itNexus[eth0a]); !reflect.DeepEqual(got, []string{"10.1.2.100"}) {
+		t.Errorf("expected the avoided portal as the last resort, got %v", got)
+	}
+
+	// The cap is shared by the addresses of a physical NIC
+	itNexus, _ = ITNexusSubnetCheckWithOptions(initiatorPorts, []*model.TargetPortal{remote, local, local2}, &SubnetOptions{PreferredCIDRs: preferred, MaxConnectionsPerNIC: 2})
+	if got := getTargetAddresses(itNexus[eth0a]); !reflect.DeepEqual(got, []string{"10.1.1.100", "10.1.1.101"}) {
+		t.Errorf("expected the preferred portals from the first eth0 address, got %v", got)
+	}
+	if got := itNexus[eth0b]; len(got) != 0 {
+		t.Errorf("expected no IT nexus from the second eth0 address, got %v", getTargetAddresses(got))
+	}
+	if got := getTargetAddresses(itNexus[eth1]); !reflect.DeepEqual(got, []string{"10.1.1.100", "10.1.1.101"}) {
+		t.Errorf("expected the preferred portals from eth1, got %v", got)
+	}
+}
+
+func TestGetSubnetOptions(t *testing.T) {
+	defer os.Unsetenv(SubnetPreferredCIDRsEnv)
+	defer os.Unsetenv(SubnetAvoidCIDRsEnv)
+	defer os.Unsetenv(SubnetMaxConnectionsPerNICEnv)
+
+	if options := getSubnetOptions(); options != nil {
+		t.Errorf("expected no options, got %+v", options)
+	}
+
+	os.Setenv(SubnetAvoidCIDRsEnv, "10.1.2.0/24, bogus, 10.1.3.0/24")
+	os.Setenv(SubnetMaxConnectionsPerNICEnv, "-1")
+	options := getSubnetOptions()
+	if options == nil || len(options.AvoidCIDRs) != 2 || options.MaxConnectionsPerNIC != 0 {
+		t.Fatalf("expected two avoided CIDRs and no cap, got %+v", options)
+	}
+	if options.getWeight("10.1.3.5") != subnetWeightAvoided || options.getWeight("10.1.1.5") != subnetWeightDefault {
+		t.Error("unexpected affinity weights")
+	}
+
+	os.Setenv(SubnetMaxConnectionsPerNICEnv, "4")
+	if options = getSubnetOptions(); options.MaxConnectionsPerNIC != 4 {
+		t.Errorf("expected a cap of 4, got %v", options.MaxConnectionsPerNIC)
+	}
+}
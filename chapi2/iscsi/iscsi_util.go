@@ -4,40 +4,58 @@ package iscsi
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"math/rand"
 	"net"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
-	ping "github.com/sparrc/go-ping"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
 )
 
+const (
+	pingDefaultCount    = 3     // Default echo requests sent to each IT nexus
+	pingDefaultInterval = 10    // Default wait between echo requests, in milliseconds
+	pingDefaultTimeout  = 250   // Default wait for each echo reply, in milliseconds
+	pingDefaultSize     = 56    // Default echo request payload size, in bytes
+	pingMaxSize         = 65507 // Largest IPv4 echo request payload, in bytes
+	pingProtocolICMP    = 1     // IANA ICMP protocol number
+	pingReplyOverhead   = 64    // Read buffer bytes beyond the echo payload (ICMP header and slack)
+)
+
+// PingOptions are the ICMP echo requests sent to each IT nexus by ITNexusPingCheckWithOptions.
+// Zero values are replaced by the defaults.
+type PingOptions struct {
+	Count        int  // Echo requests sent (default 3)
+	Interval     int  // Wait between echo requests, in milliseconds (default 10)
+	Timeout      int  // Wait for each echo reply, in milliseconds (default 250)
+	Size         int  // Echo request payload size, in bytes (default 56)
+	DontFragment bool // Set the IP don't fragment flag (e.g. with Size to validate the path MTU)
+}
+
 // ITNexusPingCheck takes an array of CHAPI2 initiator ports, and an array of target ports, and
 // returns a map of IT nexus connections that can reach each other (e.g. ICMP ping test). Each IT
 // nexus is pinged in parallel for maximum performance.  The returned map key is the initiator
 // port while the map value is an array of target ports.
 func ITNexusPingCheck(initiatorPorts []*model.Network, targetPorts []*model.TargetPortal, pingCount, pingInterval, pingTimeout int) (map[*model.Network][]*model.TargetPortal, error) {
-	log.Tracef(">>>>> ITNexusPingCheck, pingCount=%v, pingInterval=%v, pingTimeout=%v", pingCount, pingInterval, pingTimeout)
-	defer log.Traceln("<<<<< ITNexusPingCheck")
-
-	// If the echo packet count is zero or negative, set the default ping count to 3
-	if pingCount <= 0 {
-		pingCount = 3
-	}
-
-	// If the echo ping interval is zero or negative, set the default interval as 10 msec
-	if pingInterval <= 0 {
-		pingInterval = 10
-	}
+	return ITNexusPingCheckWithOptions(initiatorPorts, targetPorts, &PingOptions{Count: pingCount, Interval: pingInterval, Timeout: pingTimeout})
+}
 
-	// If the echo ping timeout is zero or negative, set the default ping timeout to 250 msec
-	if pingTimeout <= 0 {
-		pingTimeout = 250
-	}
+// ITNexusPingCheckWithOptions is ITNexusPingCheck with the given echo request options.  Echo
+// requests are sent from each initiator port's address and interface (see setPingSocketOptions),
+// so that an IT nexus is not matched because the target port is reachable through another
+// interface.
+func ITNexusPingCheckWithOptions(initiatorPorts []*model.Network, targetPorts []*model.TargetPortal, options *PingOptions) (map[*model.Network][]*model.TargetPortal, error) {
+	pingOptions := getPingOptions(options)
+	log.Tracef(">>>>> ITNexusPingCheck, options=%+v", pingOptions)
+	defer log.Traceln("<<<<< ITNexusPingCheck")
 
 	// Allocate an initial empty initiator/target nexus map
 	itNexus := make(map[*model.Network][]*model.TargetPortal)
@@ -51,9 +69,9 @@ func ITNexusPingCheck(initiatorPorts []*model.Network, targetPorts []*model.Targ
 	var mux sync.Mutex
 	var wg sync.WaitGroup
 
-	// Randomly pick a 64-bit tracker value to ensure that each ICMP request can be uniquely
+	// Randomly pick a 16-bit echo identifier to ensure that each ICMP reply can be uniquely
 	// attached to an IT nexus
-	icmpTracker := int64(rand.Uint64())
+	icmpTracker := rand.Intn(0x10000)
 
 	// Loop through each initiator and target
 	for _, initiatorPort := range initiatorPorts {
@@ -71,42 +89,19 @@ func ITNexusPingCheck(initiatorPorts []*model.Network, targetPorts []*model.Targ
 			wg.Add(1)
 
 			// Increment our ICMP tracker to ensure a unique value is used for each instance
-			icmpTracker++
+			icmpTracker = (icmpTracker + 1) & 0xffff
 
 			// In a separate go routine, ping the initiator and target
-			go func(initiatorPort *model.Network, targetPort *model.TargetPortal, tracker int64) {
+			go func(initiatorPort *model.Network, targetPort *model.TargetPortal, tracker int) {
 
 				// Decrement the WaitGroup counter when the goroutine completes.
 				defer wg.Done()
 
-				// Allocate a new ICMP ping object
-				pinger, err := ping.NewPinger(targetPort.Address)
+				// Perform the ping test; if we received any ICMP packet back, add the IT nexus to the return map
+				packetsRecv, err := pingTargetPort(initiatorPort, targetPort.Address, pingOptions, tracker)
 				if err != nil {
-					log.Errorf("NewPinger creation failure, err=%v", err)
-					return
+					log.Tracef("Unable to ping initiatorPort=%-15s, targetPort=%-15s, err=%v", initiatorPort.AddressV4, targetPort.Address, err)
 				}
-
-				// Give this thread's ICMP ping object a unique tracker value
-				pinger.Tracker = tracker
-
-				// Send a "privileged" raw ICMP ping (required by Windows)
-				pinger.SetPrivileged(true)
-
-				// Ping target from initiatorPort
-				pinger.Source = initiatorPort.AddressV4
-
-				// Count tells pinger to stop after sending (and receiving) Count echo packets
-				pinger.Count = pingCount
-
-				// Interval is the wait time between each packet sent
-				pinger.Interval = time.Duration(pingInterval) * time.Millisecond
-
-				// Timeout specifies a timeout before ping exits, regardless of how many packets have been received
-				pinger.Timeout = time.Duration((pingCount*pingTimeout)+((pingCount-1)*pingInterval)) * time.Millisecond
-
-				// Perform the ping test; if we received any ICMP packet back, add the IT nexus to the return map
-				pinger.Run()
-				packetsRecv := pinger.Statistics().PacketsRecv
 				if packetsRecv != 0 {
 					log.Tracef("Matched IT nexus initiatorPort=%-15s, targetPort=%-15s, packetsRecv=%v", initiatorPort.AddressV4, targetPort.Address, packetsRecv)
 					mux.Lock()
@@ -126,13 +121,123 @@ func ITNexusPingCheck(initiatorPorts []*model.Network, targetPorts []*model.Targ
 	return itNexus, nil
 }
 
+// getPingOptions returns a copy of the ping options with the defaults for any unset option
+func getPingOptions(options *PingOptions) *PingOptions {
+	pingOptions := &PingOptions{}
+	if options != nil {
+		*pingOptions = *options
+	}
+	if pingOptions.Count <= 0 {
+		pingOptions.Count = pingDefaultCount
+	}
+	if pingOptions.Interval <= 0 {
+		pingOptions.Interval = pingDefaultInterval
+	}
+	if pingOptions.Timeout <= 0 {
+		pingOptions.Timeout = pingDefaultTimeout
+	}
+	if pingOptions.Size <= 0 {
+		pingOptions.Size = pingDefaultSize
+	} else if pingOptions.Size > pingMaxSize {
+		pingOptions.Size = pingMaxSize
+	}
+	return pingOptions
+}
+
+// getIscsiPingOptions returns the ping options requested through the iSCSI access info
+func getIscsiPingOptions(iscsiAccessInfo *model.IscsiAccessInfo) *PingOptions {
+	if iscsiAccessInfo == nil {
+		return &PingOptions{}
+	}
+	return &PingOptions{Size: iscsiAccessInfo.PingSize, DontFragment: iscsiAccessInfo.PingDontFragment}
+}
+
+// pingTargetPort sends ICMP echo requests, with the given echo identifier, from the initiator port
+// to the target address and returns the number of echo replies received
+func pingTargetPort(initiatorPort *model.Network, targetAddress string, options *PingOptions, id int) (int, error) {
+	target := net.ParseIP(targetAddress).To4()
+	if target == nil {
+		return 0, fmt.Errorf("invalid IPv4 address %v", targetAddress)
+	}
+
+	// Open a raw ICMP socket bound to the initiator port
+	listenConfig := net.ListenConfig{Control: func(network, address string, c syscall.RawConn) error {
+		var controlErr error
+		if err := c.Control(func(fd uintptr) { controlErr = setPingSocketOptions(fd, initiatorPort, options.DontFragment) }); err != nil {
+			return err
+		}
+		return controlErr
+	}}
+	conn, err := listenConfig.ListenPacket(context.Background(), "ip4:icmp", initiatorPort.AddressV4)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	payload := bytes.Repeat([]byte{0xa5}, options.Size)
+	reply := make([]byte, options.Size+pingReplyOverhead)
+	received := 0
+	for seq := 1; seq <= options.Count; seq++ {
+		if seq > 1 {
+			time.Sleep(time.Duration(options.Interval) * time.Millisecond)
+		}
+		request, err := (&icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: id, Seq: seq, Data: payload}}).Marshal(nil)
+		if err != nil {
+			return received, err
+		}
+		// Sending fails if the don't fragment flag is set and the request exceeds the interface MTU
+		if _, err = conn.WriteTo(request, &net.IPAddr{IP: target}); err != nil {
+			return received, err
+		}
+		if waitEchoReply(conn, reply, target, id, seq, time.Duration(options.Timeout)*time.Millisecond) {
+			received++
+		}
+	}
+	return received, nil
+}
+
+// waitEchoReply returns true if the echo reply, from the target, to the echo request with the
+// given identifier and sequence number is received within the timeout.  All the ICMP packets
+// received by the host are read from a raw socket; the other packets are ignored.
+func waitEchoReply(conn net.PacketConn, reply []byte, target net.IP, id, seq int, timeout time.Duration) bool {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false
+	}
+	for {
+		n, peer, err := conn.ReadFrom(reply)
+		if err != nil {
+			// Timed out
+			return false
+		}
+		if isEchoReply(reply[:n], peer, target, id, seq) {
+			return true
+		}
+	}
+}
+
+// isEchoReply returns true if the ICMP packet is the echo reply, from the target, to the echo
+// request with the given identifier and sequence number
+func isEchoReply(packet []byte, peer net.Addr, target net.IP, id, seq int) bool {
+	if ipAddr, ok := peer.(*net.IPAddr); !ok || !ipAddr.IP.Equal(target) {
+		return false
+	}
+	message, err := icmp.ParseMessage(pingProtocolICMP, packet)
+	if err != nil || message.Type != ipv4.ICMPTypeEchoReply {
+		return false
+	}
+	echo, ok := message.Body.(*icmp.Echo)
+	return ok && echo.ID == id && echo.Seq == seq
+}
+
 // ITNexusSubnetCheck takes an array of CHAPI2 initiator ports, and an array of target ports, and
 // returns a map of IT nexus connections that could be made.  The returned map key is the initiator
 // port while the map value is an array of target ports.
 func ITNexusSubnetCheck(initiatorPorts []*model.Network, targetPorts []*model.TargetPortal) (map[*model.Network][]*model.TargetPortal, error) {
-	log.Traceln(">>>>> ITNexusSubnetCheck")
-	defer log.Traceln("<<<<< ITNexusSubnetCheck")
+	return ITNexusSubnetCheckWithOptions(initiatorPorts, targetPorts, nil)
+}
 
+// getSubnetITNexus returns the IT nexuses whose initiator port and target port share a subnet
+func getSubnetITNexus(initiatorPorts []*model.Network, targetPorts []*model.TargetPortal) map[*model.Network][]*model.TargetPortal {
 	// Allocate an initial empty initiator/target nexus map
 	itNexus := make(map[*model.Network][]*model.TargetPortal)
 
@@ -174,9 +279,30 @@ func ITNexusSubnetCheck(initiatorPorts []*model.Network, targetPorts []*model.Ta
 			itNexus[initiatorPort] = append(itNexus[initiatorPort], targetPort)
 		}
 	}
+	return itNexus
+}
 
-	// Log and return IT nexus map
-	logITNexusMap(model.ConnectTypeSubnet, itNexus)
+// getConnectTypeITNexus returns the IT nexuses to make connections with using the given connection
+// type.  With model.ConnectTypeAutoInitiator, every target port is paired with an unspecified
+// (0.0.0.0) initiator port, leaving the initiator to pick its port.
+func getConnectTypeITNexus(connectType string, initiatorPorts []*model.Network, targetPorts []*model.TargetPortal, iscsiAccessInfo *model.IscsiAccessInfo) (map[*model.Network][]*model.TargetPortal, error) {
+	var itNexus map[*model.Network][]*model.TargetPortal
+	switch connectType {
+	case model.ConnectTypePing:
+		itNexus, _ = ITNexusPingCheckWithOptions(initiatorPorts, targetPorts, getIscsiPingOptions(iscsiAccessInfo))
+	case model.ConnectTypeSubnet:
+		itNexus, _ = ITNexusSubnetCheckWithOptions(initiatorPorts, targetPorts, getSubnetOptions())
+	case model.ConnectTypeAutoInitiator:
+		itNexus = make(map[*model.Network][]*model.TargetPortal)
+		emptyInitiatorPort := &model.Network{AddressV4: "0.0.0.0"}
+		for _, ipTarget := range targetPorts {
+			itNexus[emptyInitiatorPort] = append(itNexus[emptyInitiatorPort], ipTarget)
+		}
+	default:
+		err := cerrors.NewChapiErrorf(cerrors.Internal, errorMessageInvalidConnectionType, connectType)
+		log.Error(err)
+		return nil, err
+	}
 	return itNexus, nil
 }
 
@@ -219,3 +345,10 @@ func ipToUint32(ipString string) (uint32, error) {
 	// Return the IP address as a uint32
 	return ipUint32, nil
 }
+
+// isPersistentLogin returns true if the iSCSI login is to be restored after a reboot (i.e. a
+// Windows persistent login or a Linux node.startup of "automatic").  Logins are persistent unless
+// the caller explicitly requests an ephemeral session.
+func isPersistentLogin(iscsiAccessInfo *model.IscsiAccessInfo) bool {
+	return iscsiAccessInfo == nil || iscsiAccessInfo.Persistent == nil || *iscsiAccessInfo.Persistent
+}
@@ -0,0 +1,23 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"golang.org/x/sys/unix"
+)
+
+// setPingSocketOptions binds the ping socket to the initiator port's interface, so that echo
+// requests are not routed through another interface sharing the subnet, and optionally sets the
+// don't fragment flag (path MTU discovery) on the echo requests
+func setPingSocketOptions(fd uintptr, initiatorPort *model.Network, dontFragment bool) error {
+	if initiatorPort.Name != "" {
+		if err := unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, initiatorPort.Name); err != nil {
+			return err
+		}
+	}
+	if dontFragment {
+		return unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_DO)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package iscsi
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestIsPersistentLogin(t *testing.T) {
+	persistent, ephemeral := true, false
+	testCases := []struct {
+		iscsiAccessInfo *model.IscsiAccessInfo
+		expected        bool
+	}{
+		{nil, true},
+		{&model.IscsiAccessInfo{}, true},
+		{&model.IscsiAccessInfo{Persistent: &persistent}, true},
+		{&model.IscsiAccessInfo{Persistent: &ephemeral}, false},
+	}
+	for i, testCase := range testCases {
+		if isPersistentLogin(testCase.iscsiAccessInfo) != testCase.expected {
+			t.Errorf("test case %v, expected persistent=%v", i, testCase.expected)
+		}
+	}
+}
+
+func TestGetPingOptions(t *testing.T) {
+	testCases := []struct {
+		options  *PingOptions
+		expected PingOptions
+	}{
+		{nil, PingOptions{Count: 3, Interval: 10, Timeout: 250, Size: 56}},
+		{&PingOptions{Count: 1, Interval: 5, Timeout: 100, Size: 8972, DontFragment: true}, PingOptions{Count: 1, Interval: 5, Timeout: 100, Size: 8972, DontFragment: true}},
+		{&PingOptions{Size: 100000}, PingOptions{Count: 3, Interval: 10, Timeout: 250, Size: 65507}},
+		{getIscsiPingOptions(&model.IscsiAccessInfo{PingSize: 1472, PingDontFragment: true}), PingOptions{Count: 3, Interval: 10, Timeout: 250, Size: 1472, DontFragment: true}},
+	}
+	for i, testCase := range testCases {
+		if options := getPingOptions(testCase.options); *options != testCase.expected {
+			t.Errorf("test case %v, expected %+v, got %+v", i, testCase.expected, *options)
+		}
+	}
+}
+
+func TestIsEchoReply(t *testing.T) {
+	target := net.ParseIP("10.1.1.10")
+	peer := &net.IPAddr{IP: target}
+	reply := []byte{0, 0, 0, 0, 0x12, 0x34, 0, 2, 0xa5} // Echo reply, ID 0x1234, sequence 2
+	request := []byte{8, 0, 0, 0, 0x12, 0x34, 0, 2, 0xa5}
+
+	if !isEchoReply(reply, peer, target, 0x1234, 2) {
+		t.Error("expected the echo reply to match")
+	}
+	if isEchoReply(reply, peer, target, 0x1235, 2) || isEchoReply(reply, peer, target, 0x1234, 3) {
+		t.Error("expected an echo reply with another ID or sequence not to match")
+	}
+	if isEchoReply(reply, &net.IPAddr{IP: net.ParseIP("10.1.1.11")}, target, 0x1234, 2) {
+		t.Error("expected an echo reply from another address not to match")
+	}
+	if isEchoReply(request, peer, target, 0x1234, 2) {
+		t.Error("expected an echo request not to match")
+	}
+}
+
+func TestCheckSourceAddress(t *testing.T) {
+	tests := []struct {
+		initiatorPort *model.Network
+		sourceAddress string
+		mismatch      bool
+	}{
+		{&model.Network{AddressV4: "10.1.1.10"}, "10.1.1.10", false},
+		{&model.Network{AddressV4: "10.1.1.10"}, "192.168.1.10", true},
+		{&model.Network{AddressV4: "10.1.1.10"}, "", false},
+		{&model.Network{AddressV4: "0.0.0.0"}, "192.168.1.10", false},
+		{nil, "192.168.1.10", false},
+	}
+	for _, tc := range tests {
+		if err := checkSourceAddress(tc.initiatorPort, tc.sourceAddress); (err != nil) != tc.mismatch {
+			t.Errorf("checkSourceAddress(%+v, %q) = %v, expected mismatch=%v", tc.initiatorPort, tc.sourceAddress, err, tc.mismatch)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+package iscsi
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	ipDontFragment = 14 // IP_DONTFRAGMENT socket option
+	ipUnicastIf    = 31 // IP_UNICAST_IF socket option
+)
+
+// setPingSocketOptions sends the echo requests of the ping socket through the initiator port's
+// interface, rather than the one picked by the routing table, and optionally sets the don't
+// fragment flag on the echo requests
+func setPingSocketOptions(fd uintptr, initiatorPort *model.Network, dontFragment bool) error {
+	if initiatorPort.Name != "" {
+		// The socket is also bound to the initiator port address, so a missing interface is not fatal
+		if netInterface, err := net.InterfaceByName(initiatorPort.Name); err != nil {
+			log.Tracef("Unable to find interface %v, err=%v", initiatorPort.Name, err)
+		} else {
+			// IP_UNICAST_IF takes the IPv4 interface index in network byte order
+			index := make([]byte, 4)
+			binary.BigEndian.PutUint32(index, uint32(netInterface.Index))
+			if err = windows.SetsockoptInt(windows.Handle(fd), windows.IPPROTO_IP, ipUnicastIf, int(binary.LittleEndian.Uint32(index))); err != nil {
+				return err
+			}
+		}
+	}
+	if dontFragment {
+		return windows.SetsockoptInt(windows.Handle(fd), windows.IPPROTO_IP, ipDontFragment, 1)
+	}
+	return nil
+}
@@ -9,16 +9,22 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hpe-storage/common-host-libs/blockdevice"
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/host"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/rescan"
+	"github.com/hpe-storage/common-host-libs/chapi2/timing"
 	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/sgio"
 	"github.com/hpe-storage/common-host-libs/windows/iscsidsc"
-	"github.com/hpe-storage/common-host-libs/windows/wmi"
 	"golang.org/x/sys/windows/registry"
 )
 
 const (
+	// defaultIqnAuthority is the naming authority of the Microsoft iSCSI initiator names
+	defaultIqnAuthority = "iqn.1991-05.com.microsoft"
+
 	// Registry locations for minimum/maximum connections per target.  These are the locations used
 	// by the Nimble Connection Service (NCS).  Future updates might move these to a more generic
 	// location.
@@ -54,6 +60,13 @@ func getIscsiInitiators() (init *model.Initiator, err error) {
 
 	initiators := []string{initiatorNodeName}
 	init = &model.Initiator{AccessProtocol: model.AccessProtocolIscsi, Init: initiators}
+
+	// Report the initiator instances (software initiator and any iSCSI HBAs) so that callers can
+	// select one through IscsiAccessInfo.InitiatorInstance.  Failing to enumerate them is not fatal.
+	if init.Instances, err = iscsidsc.ReportIScsiInitiatorList(); err != nil {
+		log.Tracef("Unable to enumerate iSCSI initiator instances, err=%v", err)
+		err = nil
+	}
 	return init, err
 }
 
@@ -91,33 +104,20 @@ func getTargetScope(targetName string) (string, error) {
 		}
 
 		// Issue an Inquiry request on the current session
-		scsiStatus, inquiryBuffer, _, inquiryErr := iscsidsc.SendScsiInquiry(iscsiSession.SessionID, 0, 0, 0)
+		scsiStatus, inquiryBuffer, senseBuffer, inquiryErr := iscsidsc.SendScsiInquiry(iscsiSession.SessionID, 0, 0, 0)
 		inquiryErr = cerrors.IscsiErrToCerrors(inquiryErr)
-		if len(inquiryBuffer) >= nimbleTargetScopeOffset {
-
-			// Convert the vendor/product ID into a string
-			vendorProduct := string(inquiryBuffer[8:32])
-
-			// If this isn't a Nimble target, log an error and fail request
-			if vendorProduct != nimbleVendorProduct {
-				lastErr = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageNonNimbleTarget, vendorProduct)
-				log.Error(lastErr.Error())
-				return "", lastErr
-			}
-
-			// Get the target scope value from the Inquiry data
-			var targetScope string
-			targetScopeBits := inquiryBuffer[nimbleTargetScopeOffset] & 0x03
-			switch targetScopeBits {
-			case 0:
-				targetScope = model.TargetScopeVolume
-			case 1:
-				targetScope = model.TargetScopeGroup
-			default:
-				// If an unexpected target scope is returned, log an error and fail request
-				lastErr = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageInvalidTargetScope, targetScopeBits)
-				log.Error(lastErr.Error())
-				return "", lastErr
+		if inquiryErr == nil && scsiStatus != iscsidsc.SCSISTAT_GOOD {
+			// Include the decoded sense data (e.g. LOGICAL UNIT NOT SUPPORTED) in the error
+			inquiryErr = cerrors.ScsiErrToCerrors(sgio.NewScsiError(scsiStatus, senseBuffer))
+		}
+		if len(inquiryBuffer) > nimbleTargetScopeOffset {
+
+			// Get the target scope value from the Inquiry data.  If this isn't a Nimble target, or
+			// an unexpected target scope is returned, log an error and fail request.
+			targetScope, err := parseTargetScope(inquiryBuffer)
+			if err != nil {
+				log.Error(err.Error())
+				return "", err
 			}
 
 			// Successfully enumerated target scope on this session.  Log target scope and return to the caller
@@ -146,7 +146,7 @@ func getTargetScope(targetName string) (string, error) {
 func rescanIscsiTarget(lunID string) error {
 	// Unlike Linux, Windows does not have Target/LUN specific rescan capabilities so a synchronous
 	// disk rescan is initiated and the lunID is ignored.
-	return wmi.RescanDisks()
+	return rescan.Run("", blockdevice.RescanAll)
 }
 
 // getTargetPortals enumerates the target portals for the given iSCSI target
@@ -189,13 +189,6 @@ func (plugin *IscsiPlugin) loginTarget(blockDev model.BlockDeviceAccessInfo) (er
 		return err
 	}
 
-	// Add discovery IP to host if one was provided
-	if blockDev.IscsiAccessInfo.DiscoveryIP != "" {
-		if err = plugin.addDiscoveryPortal(blockDev.IscsiAccessInfo.DiscoveryIP); err != nil {
-			return err
-		}
-	}
-
 	// See if the requested iSCSI target is already connected on this host.  This mirrors the CHAPI1
 	// behavior.  A future enhancement could be to compare the current connections, versus the
 	// optimal connections, and add/replace connections as needed.
@@ -209,7 +202,9 @@ func (plugin *IscsiPlugin) loginTarget(blockDev model.BlockDeviceAccessInfo) (er
 		// iSCSI target is already connected!  If it is *not* a volume scoped target (e.g. it's
 		// a group scoped target), perform a disk rescan before returning.
 		if !strings.EqualFold(blockDev.TargetScope, model.TargetScopeVolume) {
-			wmi.RescanDisks()
+			endStage := timing.StartStage(blockDev.TargetName, timing.StageRescan)
+			rescan.Run("", blockdevice.RescanAll)
+			endStage()
 		}
 
 		// Return no error.  Target is already connected.
@@ -217,11 +212,14 @@ func (plugin *IscsiPlugin) loginTarget(blockDev model.BlockDeviceAccessInfo) (er
 		return nil
 	}
 
-	// Make sure the target was found through the discovery IP.  If not found on the first query,
-	// perform a deep discovery and retry once more.
-	if err = plugin.isTargetPresent(blockDev.TargetName); err != nil {
+	// Make sure the target can be discovered, failing over between the provided discovery IPs
+	endStage := timing.StartStage(blockDev.TargetName, timing.StageDiscovery)
+	err = plugin.discoverTarget(blockDev.TargetName, getDiscoveryIPs(blockDev.IscsiAccessInfo))
+	endStage()
+	if err != nil {
 		return err
 	}
+	defer timing.StartStage(blockDev.TargetName, timing.StageLogin)()
 
 	// Enumerate the host initiator ports
 	initiatorPorts, err := host.NewHostPlugin().GetNetworks()
@@ -229,10 +227,17 @@ func (plugin *IscsiPlugin) loginTarget(blockDev model.BlockDeviceAccessInfo) (er
 		return err
 	}
 
+	// If a specific initiator instance was requested (e.g. an iSCSI HBA), only use its ports
+	if initiatorInstance := blockDev.IscsiAccessInfo.InitiatorInstance; initiatorInstance != "" {
+		if initiatorPorts, err = filterInitiatorPorts(initiatorPorts, initiatorInstance); err != nil {
+			return err
+		}
+	}
+
 	// Enumerate the target's data ports
 	log.Infof("Get iSCSI target portals for %v", blockDev.TargetName)
 	var targetPorts []*model.TargetPortal
-	if targetPorts, err = plugin.GetTargetPortals(blockDev.TargetName, true); err != nil {
+	if targetPorts, err = plugin.GetTargetPortals(blockDev.TargetName, false); err != nil {
 		return err
 	}
 
@@ -289,7 +294,7 @@ func (plugin *IscsiPlugin) loginTarget(blockDev model.BlockDeviceAccessInfo) (er
 		for uint32(len(connections)) < minConnectionCount {
 			var newConnections []ITNexus
 			for _, connection := range connections {
-				if err = plugin.loginTargetPort(blockDev, connection.initiatorPort, connection.targetPort, loginExpiration); err != nil {
+				if connection.sourceAddress, err = plugin.loginTargetPort(blockDev, connection.initiatorPort, connection.targetPort, loginExpiration); err != nil {
 					return err
 				}
 				newConnections = append(newConnections, connection)
@@ -302,7 +307,7 @@ func (plugin *IscsiPlugin) loginTarget(blockDev model.BlockDeviceAccessInfo) (er
 	// disk rescan before returning.  It's possible a LUN has been added to a GST and we
 	// need a rescan to ensure that the OS has detected all the target LUNs.
 	if !strings.EqualFold(blockDev.TargetScope, model.TargetScopeVolume) {
-		wmi.RescanDisks()
+		rescan.Run("", blockdevice.RescanAll)
 	}
 
 	// Success!  iSCSI connections established!
@@ -320,63 +325,177 @@ func (plugin *IscsiPlugin) logoutTarget(targetName string) (err error) {
 	return iscsidsc.LogoutIScsiTargetAll(targetName, true)
 }
 
-// connectTypeToArray takes the connectType string and returns an array of connection types that
-// reflect the input type.
-func (plugin *IscsiPlugin) connectTypeToArray(connectType string) (connectTypes []string, err error) {
-
-	// Determine how we should try to connect to the iSCSI target using the provided iSCSI
-	// ConnectType.  If property not provided, use the default value.
-	switch connectType {
-	case "", model.ConnectTypeDefault:
-		// If the default option is selected, we try multiple connection techniques to try and log
-		// into the iSCSI target.  We start with ConnectTypePing, then ConnectTypeSubnet and end
-		// with ConnectTypeAutoInitiator.
-		connectTypes = []string{model.ConnectTypePing, model.ConnectTypeSubnet, model.ConnectTypeAutoInitiator}
-	case model.ConnectTypePing, model.ConnectTypeSubnet, model.ConnectTypeAutoInitiator:
-		// Simple/singular connection type requested
-		connectTypes = []string{connectType}
-	default:
-		// Invalid / Unsupported connection type
-		err = cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidConnectionType, connectType)
-		log.Error(err)
-		return nil, err
+// logoutTargetSessions logs out the sessions of the given iSCSI target, keeping its persistent
+// logins
+func (plugin *IscsiPlugin) logoutTargetSessions(targetName string) error {
+	log.Infof("Logout iSCSI target %v sessions", targetName)
+
+	// Logout all iSCSI target sessions but keep the persistent settings
+	return iscsidsc.LogoutIScsiTargetAll(targetName, false)
+}
+
+// discoverTarget registers the given discovery IPs, in order, until the target is discovered.  The
+// discovery IP that discovered the target is recorded for the device details, while the discovery
+// IPs registered here that did not discover the target are removed again.  If no discovery IPs are
+// provided, the target must be discoverable through the already registered discovery portals.
+func (plugin *IscsiPlugin) discoverTarget(targetName string, discoveryIPs []string) (err error) {
+	log.Tracef(">>>>> discoverTarget, targetName=%v, discoveryIPs=%v", targetName, discoveryIPs)
+	defer log.Traceln("<<<<< discoverTarget")
+
+	if len(discoveryIPs) == 0 {
+		return plugin.isTargetPresent(targetName)
 	}
 
-	return connectTypes, nil
+	for _, discoveryIP := range discoveryIPs {
+		// Register the discovery IP; if unreachable, fail over to the next discovery IP
+		var added bool
+		if added, err = plugin.addDiscoveryPortal(discoveryIP); err != nil {
+			log.Warnf("Unable to add discovery IP %v, err=%v", discoveryIP, err)
+			plugin.removeDiscoveryPortal(discoveryIP)
+			continue
+		}
+
+		// Make sure the target was found through the discovery IP.  If not found on the first
+		// query, a deep discovery is performed.
+		if err = plugin.isTargetPresent(targetName); err != nil {
+			log.Warnf("Target %v not discovered through discovery IP %v", targetName, discoveryIP)
+			if added {
+				plugin.removeDiscoveryPortal(discoveryIP)
+			}
+			continue
+		}
+
+		log.Infof("Target %v discovered through discovery IP %v", targetName, discoveryIP)
+		setDiscoveryPortal(targetName, discoveryIP)
+		return nil
+	}
+
+	// Return the last discovery failure
+	return err
 }
 
-// addDiscoveryPortal adds the given discovery IP to the system's discovery portals.
-func (plugin *IscsiPlugin) addDiscoveryPortal(discoveryIP string) error {
+// addDiscoveryPortal adds the given discovery IP to the system's discovery portals.  Returns true
+// if the discovery IP was added, false if it was already registered.
+func (plugin *IscsiPlugin) addDiscoveryPortal(discoveryIP string) (bool, error) {
 	log.Tracef(">>>>> addDiscoveryPortal, discoveryIP=%v", discoveryIP)
 	defer log.Traceln("<<<<< addDiscoveryPortal")
 
+	// Does this host already have an entry for the discovery IP?
+	registered, err := isDiscoveryPortalRegistered(discoveryIP)
+	if err != nil {
+		return false, err
+	}
+	if registered {
+		// If discovery IP is already registed on this host, return nil
+		log.Infof("Use discovery IP %v", discoveryIP)
+		return false, nil
+	}
+
+	// Add discovery IP to host
+	log.Infof("Add discovery IP %v", discoveryIP)
+	if err = iscsidsc.AddIScsiSendTargetPortal("", iscsidsc.ISCSI_ANY_INITIATOR_PORT, discoveryIP); err != nil {
+		err = cerrors.IscsiErrToCerrors(err)
+		log.Error(err)
+		return false, err
+	}
+
+	// Discovery IP added to host successfully!
+	return true, nil
+}
+
+// removeDiscoveryPortal removes the given discovery IP from the system's discovery portals, if
+// registered.  Failures are logged but not fatal.
+func (plugin *IscsiPlugin) removeDiscoveryPortal(discoveryIP string) {
+	log.Tracef(">>>>> removeDiscoveryPortal, discoveryIP=%v", discoveryIP)
+	defer log.Traceln("<<<<< removeDiscoveryPortal")
+
+	if registered, err := isDiscoveryPortalRegistered(discoveryIP); err != nil || !registered {
+		return
+	}
+	log.Infof("Remove discovery IP %v", discoveryIP)
+	if err := iscsidsc.RemoveIScsiSendTargetPortal("", iscsidsc.ISCSI_ANY_INITIATOR_PORT, discoveryIP); err != nil {
+		log.Errorf("Unable to remove discovery IP %v, err=%v", discoveryIP, cerrors.IscsiErrToCerrors(err))
+	}
+}
+
+// isDiscoveryPortalRegistered returns true if the given discovery IP is among the system's
+// discovery portals
+func isDiscoveryPortalRegistered(discoveryIP string) (bool, error) {
 	// Enumerate the send target portals (e.g. discovery IPs)
 	sendTargetPortals, err := iscsidsc.ReportIScsiSendTargetPortalsEx()
 	if err != nil {
 		err = cerrors.IscsiErrToCerrors(err)
 		log.Error(err)
-		return err
+		return false, err
 	}
-
-	// Does this host already have an entry for the discovery IP?
 	for _, sendTargetPortal := range sendTargetPortals {
 		if sendTargetPortal.Address == discoveryIP {
-			// If discovery IP is already registed on this host, return nil
-			log.Infof("Use discovery IP %v", discoveryIP)
-			return nil
+			return true, nil
 		}
 	}
+	return false, nil
+}
 
-	// Add discovery IP to host
-	log.Infof("Add discovery IP %v", discoveryIP)
-	if err = iscsidsc.AddIScsiSendTargetPortal("", iscsidsc.ISCSI_ANY_INITIATOR_PORT, discoveryIP); err != nil {
+// getUnconnectedTargets returns the targets with a persistent login but no connected session.
+// The iSCSI initiator service does not report why a persistent login failed, so the last error
+// is inferred from the current session state and target discovery.
+func (plugin *IscsiPlugin) getUnconnectedTargets() ([]*model.UnconnectedTarget, error) {
+	log.Trace(">>>>> getUnconnectedTargets")
+	defer log.Trace("<<<<< getUnconnectedTargets")
+
+	persistentLogins, err := iscsidsc.ReportIScsiPersistentLogins()
+	if err != nil {
 		err = cerrors.IscsiErrToCerrors(err)
 		log.Error(err)
-		return err
+		return nil, err
 	}
 
-	// Discovery IP added to host successfully!
-	return nil
+	iscsiSessions, err := iscsidsc.GetIscsiSessionList()
+	if err != nil {
+		err = cerrors.IscsiErrToCerrors(err)
+		log.Error(err)
+		return nil, err
+	}
+
+	// Determine which targets have a session, and which of those have active connections
+	sessionFound := make(map[string]bool)
+	connected := make(map[string]bool)
+	for _, iscsiSession := range iscsiSessions {
+		targetName := strings.ToLower(iscsiSession.TargetName)
+		sessionFound[targetName] = true
+		if len(iscsiSession.Connections) != 0 {
+			connected[targetName] = true
+		}
+	}
+
+	// Group the persistent logins of each unconnected target
+	var targets []*model.UnconnectedTarget
+	targetMap := make(map[string]*model.UnconnectedTarget)
+	for _, persistentLogin := range persistentLogins {
+		targetName := strings.ToLower(persistentLogin.TargetName)
+		if connected[targetName] {
+			continue
+		}
+		target, ok := targetMap[targetName]
+		if !ok {
+			target = &model.UnconnectedTarget{
+				Name:   persistentLogin.TargetName,
+				Source: model.UnconnectedTargetSourcePersistentLogin,
+			}
+			if sessionFound[targetName] {
+				target.LastError = errorMessageNoAvailableConnections
+			} else if plugin.isTargetPresent(persistentLogin.TargetName) != nil {
+				target.LastError = errorMessageTargetNotFound
+			}
+			targetMap[targetName] = target
+			targets = append(targets, target)
+		}
+		target.TargetPortals = append(target.TargetPortals, &model.TargetPortal{
+			Address: persistentLogin.TargetPortal.Address,
+			Port:    strconv.Itoa(int(persistentLogin.TargetPortal.Socket)),
+		})
+	}
+	return targets, nil
 }
 
 // isTargetLoggedIn checks to see if the given iSCSI target is already logged in.
@@ -453,21 +572,8 @@ func (plugin *IscsiPlugin) loginTargetPorts(
 
 	// Enumerate the IT_nexuses we should attempt to make connections with using the
 	// specified connection type.
-	var itNexus map[*model.Network][]*model.TargetPortal
-	switch connectType {
-	case model.ConnectTypePing:
-		itNexus, _ = ITNexusPingCheck(initiatorPorts, targetPorts, 0, 0, 0)
-	case model.ConnectTypeSubnet:
-		itNexus, _ = ITNexusSubnetCheck(initiatorPorts, targetPorts)
-	case model.ConnectTypeAutoInitiator:
-		itNexus = make(map[*model.Network][]*model.TargetPortal)
-		emptyInitiatorPort := &model.Network{AddressV4: "0.0.0.0"}
-		for _, ipTarget := range targetPorts {
-			itNexus[emptyInitiatorPort] = append(itNexus[emptyInitiatorPort], ipTarget)
-		}
-	default:
-		err = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageInvalidConnectionType, connectType)
-		log.Error(err)
+	itNexus, err := getConnectTypeITNexus(connectType, initiatorPorts, targetPorts, blockDev.IscsiAccessInfo)
+	if err != nil {
 		return nil, err
 	}
 
@@ -488,13 +594,14 @@ func (plugin *IscsiPlugin) loginTargetPorts(
 
 			// Log into the given target port from the given initiator port.  If an error occurred,
 			// move to the next IT nexus.
-			if loginError := plugin.loginTargetPort(blockDev, initiatorPort, targetPort, loginExpiration); loginError != nil {
+			sourceAddress, loginError := plugin.loginTargetPort(blockDev, initiatorPort, targetPort, loginExpiration)
+			if loginError != nil {
 				lastLoginError = loginError
 				continue
 			}
 
 			// Connection successful; append connection to connections array
-			connections = append(connections, ITNexus{initiatorPort: initiatorPort, targetPort: targetPort})
+			connections = append(connections, ITNexus{initiatorPort: initiatorPort, targetPort: targetPort, sourceAddress: sourceAddress})
 		}
 	}
 
@@ -513,12 +620,15 @@ func (plugin *IscsiPlugin) loginTargetPorts(
 	return connections, nil
 }
 
-// loginTargetPort is called to log into a single target port from a single initiator port
+// loginTargetPort is called to log into a single target port from a single initiator port.  The
+// initiator IP address the connection was made from is returned, if it could be determined.  If an
+// initiator port was selected (e.g. from the ping or subnet checks), the connection must be made
+// from it; a connection made from another NIC (e.g. the NIC of the default route) is logged out.
 func (plugin *IscsiPlugin) loginTargetPort(
 	blockDev model.BlockDeviceAccessInfo,
 	initiatorPort *model.Network,
 	targetPort *model.TargetPortal,
-	loginExpiration time.Time) error {
+	loginExpiration time.Time) (string, error) {
 
 	log.Tracef(">>>>> loginTargetPort, targetName=%v", blockDev.TargetName)
 	defer log.Traceln("<<<<< loginTargetPort")
@@ -528,39 +638,153 @@ func (plugin *IscsiPlugin) loginTargetPort(
 	if time.Now().After(loginExpiration) {
 		err := cerrors.NewChapiError(cerrors.Timeout, errorMessageLoginTimeout)
 		log.Error(err)
-		return err
+		return "", err
 	}
 
-	// Determine the iSCSI initiator port number to use
+	// An initiator port without an iSCSI initiator portal cannot be bound to; logging in with
+	// any initiator port would let Windows pick the NIC.
+	if !isAnyInitiatorPort(initiatorPort) && initiatorPort.Private == nil {
+		err := cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageInitiatorPortNotBound, initiatorPort.AddressV4)
+		log.Error(err)
+		return "", err
+	}
+
+	// Determine the iSCSI initiator instance and port number to use.  The port number is relative
+	// to the initiator instance that owns the port (software initiator or iSCSI HBA).  If the
+	// initiator port is not known (i.e. model.ConnectTypeAutoInitiator), let the requested (or
+	// any) initiator instance decide which of its ports to use.
+	initiatorInstance := blockDev.IscsiAccessInfo.InitiatorInstance
 	initiatorPortNumber := iscsidsc.ISCSI_ANY_INITIATOR_PORT
 	if initiatorPort.Private != nil {
-		// If here, the model.ConnectTypeAutoInitiator option is being used (i.e. let host initiator
-		// decide which of its ports to use)
+		initiatorInstance = initiatorPort.Private.InitiatorInstance
 		initiatorPortNumber = initiatorPort.Private.InitiatorPortNumber
 	}
 
-	// Perform an iSCSI login
-	_, _, err := iscsidsc.LoginIScsiTargetEx(
+	// Perform an iSCSI login.  The persistent login is only made once the connection is known to
+	// be made from the selected initiator port; ephemeral sessions are not made persistent logins.
+	sessionID, connectionID, err := iscsidsc.LoginIScsiTargetEx(
 		blockDev.TargetName,                    // targetName string
-		"",                                     // initiatorInstance string
+		initiatorInstance,                      // initiatorInstance string
 		initiatorPortNumber,                    // initiatorPortNumber uint32
 		targetPort.Private.WindowsTargetPortal, // targetPortal *ISCSI_TARGET_PORTAL
 		iscsidsc.ISCSI_DIGEST_TYPE_NONE,        // headerDigest ISCSI_DIGEST_TYPES
 		iscsidsc.ISCSI_DIGEST_TYPE_NONE,        // headerDigest ISCSI_DIGEST_TYPES
 		blockDev.IscsiAccessInfo.ChapUser,      // chapUsername string
 		blockDev.IscsiAccessInfo.ChapPassword,  // chapPassword string
-		true) // isPersistent bool
+		false)                                  // isPersistent bool
 
 	// Log error if failure connection not successful
 	if err != nil {
 		err = cerrors.IscsiErrToCerrors(err)
 		log.Errorf("Connection failure, err=%v, iqn=%v, initiatorPort=%v, targetPort=%v", err, blockDev.TargetName, initiatorPort.AddressV4, targetPort.Address)
-		return err
+		return "", err
+	}
+
+	// Make sure the connection was made from the selected initiator port
+	sourceAddress := getConnectionSourceAddress(*sessionID, *connectionID)
+	if err = checkSourceAddress(initiatorPort, sourceAddress); err != nil {
+		log.Errorf("Connection failure, err=%v, iqn=%v, targetPort=%v", err, blockDev.TargetName, targetPort.Address)
+		if logoutErr := iscsidsc.LogoutIScsiTarget(*sessionID); logoutErr != nil {
+			log.Errorf("Unable to logout session %x-%x, err=%v", sessionID.AdapterUnique, sessionID.AdapterSpecific, logoutErr)
+		}
+		return "", err
+	}
+
+	if isPersistentLogin(blockDev.IscsiAccessInfo) {
+		iscsidsc.SetIScsiPersistentLogin(
+			blockDev.TargetName,
+			initiatorInstance,
+			initiatorPortNumber,
+			targetPort.Private.WindowsTargetPortal,
+			iscsidsc.ISCSI_DIGEST_TYPE_NONE,
+			iscsidsc.ISCSI_DIGEST_TYPE_NONE,
+			blockDev.IscsiAccessInfo.ChapUser,
+			blockDev.IscsiAccessInfo.ChapPassword)
 	}
 
 	// Success!!!  Connection established.
-	log.Infof("Connection established, iqn=%v, initiatorPort=%v, targetPort=%v", blockDev.TargetName, initiatorPort.AddressV4, targetPort.Address)
-	return nil
+	log.Infof("Connection established, iqn=%v, initiatorPort=%v, sourceAddress=%v, targetPort=%v", blockDev.TargetName, initiatorPort.AddressV4, sourceAddress, targetPort.Address)
+	return sourceAddress, nil
+}
+
+// getConnectionSourceAddress returns the initiator IP address the given connection was made from,
+// or an empty string if the connection could not be found
+func getConnectionSourceAddress(sessionID iscsidsc.ISCSI_UNIQUE_SESSION_ID, connectionID iscsidsc.ISCSI_UNIQUE_CONNECTION_ID) string {
+	iscsiSessions, err := iscsidsc.GetIscsiSessionList()
+	if err != nil {
+		log.Errorf("Unable to enumerate iSCSI sessions, err=%v", err)
+		return ""
+	}
+	for _, iscsiSession := range iscsiSessions {
+		if iscsiSession.SessionID != sessionID {
+			continue
+		}
+		for _, iscsiConnection := range iscsiSession.Connections {
+			if iscsiConnection.ConnectionID == connectionID {
+				return iscsiConnection.InitiatorAddress
+			}
+		}
+	}
+	log.Warnf("Connection %x-%x not found on session %x-%x", connectionID.AdapterUnique, connectionID.AdapterSpecific, sessionID.AdapterUnique, sessionID.AdapterSpecific)
+	return ""
+}
+
+// filterInitiatorPorts returns the initiator ports that belong to the given initiator instance.
+// An error is returned if the initiator instance does not exist or has no usable ports.
+func filterInitiatorPorts(initiatorPorts []*model.Network, initiatorInstance string) ([]*model.Network, error) {
+	log.Tracef(">>>>> filterInitiatorPorts, initiatorInstance=%v", initiatorInstance)
+	defer log.Traceln("<<<<< filterInitiatorPorts")
+
+	// Make sure the requested initiator instance exists on this host
+	initiatorInstances, err := iscsidsc.ReportIScsiInitiatorList()
+	if err != nil {
+		err = cerrors.IscsiErrToCerrors(err)
+		log.Error(err)
+		return nil, err
+	}
+	found := false
+	for _, instance := range initiatorInstances {
+		if strings.EqualFold(instance, initiatorInstance) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		err = cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageInitiatorNotFound, initiatorInstance)
+		log.Error(err)
+		return nil, err
+	}
+
+	var filteredPorts []*model.Network
+	for _, initiatorPort := range initiatorPorts {
+		if (initiatorPort.Private != nil) && strings.EqualFold(initiatorPort.Private.InitiatorInstance, initiatorInstance) {
+			filteredPorts = append(filteredPorts, initiatorPort)
+		}
+	}
+	if len(filteredPorts) == 0 {
+		err = cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageInitiatorNoPorts, initiatorInstance)
+		log.Error(err)
+		return nil, err
+	}
+	return filteredPorts, nil
+}
+
+// getPlanInitiatorPorts returns the initiator ports PlanLogin plans connections from, the ports of
+// the requested initiator instance if any, as loginTarget does
+func getPlanInitiatorPorts(blockDev model.BlockDeviceAccessInfo) ([]*model.Network, error) {
+	initiatorPorts, err := host.NewHostPlugin().GetNetworks()
+	if err != nil {
+		return nil, err
+	}
+	if (blockDev.IscsiAccessInfo != nil) && (blockDev.IscsiAccessInfo.InitiatorInstance != "") {
+		return filterInitiatorPorts(initiatorPorts, blockDev.IscsiAccessInfo.InitiatorInstance)
+	}
+	return initiatorPorts, nil
+}
+
+// getPlanConnectionLimits returns the minimum and maximum connections PlanLogin plans for the target
+func getPlanConnectionLimits(targetScope string) (minConnections, maxConnections uint32) {
+	return getMinMaxConnectionsPerTarget(targetScope)
 }
 
 // getMinMaxConnectionsPerTarget enumerates the minimum and maximum allowed iSCSI connections
@@ -629,3 +853,215 @@ func getRegistryUint32(key registry.Key, path string, name string) (uint32, erro
 	// Convert uint64 value to a uint32 and return to caller
 	return uint32(s), nil
 }
+
+// setIscsiInitiatorName changes the Microsoft iSCSI initiator name.  Persistent logins are bound to
+// the initiator instance, rather than its name, so they use the new name when next logged in.  The
+// name is not changed while any iSCSI session is logged in.
+func setIscsiInitiatorName(iqn string) error {
+	sessions, err := iscsidsc.GetIscsiSessionList()
+	if err != nil {
+		return cerrors.IscsiErrToCerrors(err)
+	}
+	if len(sessions) != 0 {
+		err = cerrors.NewChapiErrorf(cerrors.Aborted, errorMessageIqnInUse, fmt.Sprintf("%v iSCSI session(s) are logged in", len(sessions)))
+		log.Error(err)
+		return err
+	}
+	if err = iscsidsc.SetIScsiInitiatorNodeName(iqn); err != nil {
+		return cerrors.IscsiErrToCerrors(err)
+	}
+	log.Infof("Changed the iSCSI initiator name to %v", iqn)
+	return nil
+}
+
+// GetTargetMappings returns the active iSCSI target mappings (i.e. how each session's LUNs are
+// mapped to OS SCSI addresses).  If targetName is provided, only that target's mappings are
+// returned.
+func (plugin *IscsiPlugin) GetTargetMappings(targetName string) ([]*model.IscsiTargetMapping, error) {
+	log.Tracef(">>>>> GetTargetMappings, targetName=%v", targetName)
+	defer log.Trace("<<<<< GetTargetMappings")
+
+	targetMappings, err := iscsidsc.ReportActiveIScsiTargetMappings()
+	if err != nil {
+		return nil, cerrors.IscsiErrToCerrors(err)
+	}
+
+	// Enumerate the session connections to report the initiator IP address each one was made from
+	iscsiSessions, err := iscsidsc.GetIscsiSessionList()
+	if err != nil {
+		return nil, cerrors.IscsiErrToCerrors(err)
+	}
+	sessionConnections := make(map[iscsidsc.ISCSI_UNIQUE_SESSION_ID][]*model.IscsiConnection)
+	for _, iscsiSession := range iscsiSessions {
+		for _, iscsiConnection := range iscsiSession.Connections {
+			sessionConnections[iscsiSession.SessionID] = append(sessionConnections[iscsiSession.SessionID], &model.IscsiConnection{
+				InitiatorAddress: iscsiConnection.InitiatorAddress,
+				InitiatorSocket:  iscsiConnection.InitiatorSocket,
+				TargetAddress:    iscsiConnection.TargetAddress,
+				TargetSocket:     iscsiConnection.TargetSocket,
+			})
+		}
+	}
+
+	var mappings []*model.IscsiTargetMapping
+	for _, targetMapping := range targetMappings {
+		if targetName != "" && !strings.EqualFold(targetName, targetMapping.TargetName) {
+			continue
+		}
+		mapping := &model.IscsiTargetMapping{
+			TargetName:     targetMapping.TargetName,
+			InitiatorName:  targetMapping.InitiatorName,
+			OSDeviceName:   targetMapping.OSDeviceName,
+			SessionID:      fmt.Sprintf("%x-%x", targetMapping.SessionId.AdapterUnique, targetMapping.SessionId.AdapterSpecific),
+			OSBusNumber:    targetMapping.OSBusNumber,
+			OSTargetNumber: targetMapping.OSTargetNumber,
+			Connections:    sessionConnections[targetMapping.SessionId],
+		}
+		for _, lun := range targetMapping.LUNList {
+			mapping.Luns = append(mapping.Luns, &model.IscsiLunMapping{OSLun: lun.OSLUN, TargetLun: lun.TargetLUN})
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// targetSessionPrivate holds the Windows specific properties of a session logged in again by
+// RotateCredentials
+type targetSessionPrivate struct {
+	sessionID           iscsidsc.ISCSI_UNIQUE_SESSION_ID // iSCSI session ID
+	initiatorInstance   string                           // Initiator instance of the persistent login (empty for any)
+	initiatorPortNumber uint32                           // Initiator port of the persistent login
+	targetPortal        iscsidsc.ISCSI_TARGET_PORTAL     // Target portal of the session
+	persistent          bool                             // True if the session has a persistent login
+}
+
+// getTargetSessions returns the iSCSI sessions of this host along with their persistent login, if
+// any
+func getTargetSessions() ([]*targetSession, error) {
+	iscsiSessions, err := iscsidsc.GetIscsiSessionList()
+	if err != nil {
+		return nil, cerrors.IscsiErrToCerrors(err)
+	}
+	persistentLogins, err := iscsidsc.ReportIScsiPersistentLogins()
+	if err != nil {
+		return nil, cerrors.IscsiErrToCerrors(err)
+	}
+
+	var sessions []*targetSession
+	for _, iscsiSession := range iscsiSessions {
+		if len(iscsiSession.Connections) == 0 {
+			continue
+		}
+		connection := iscsiSession.Connections[0]
+		private := &targetSessionPrivate{
+			sessionID:           iscsiSession.SessionID,
+			initiatorPortNumber: iscsidsc.ISCSI_ANY_INITIATOR_PORT,
+			targetPortal:        iscsidsc.ISCSI_TARGET_PORTAL{Address: connection.TargetAddress, Socket: connection.TargetSocket},
+		}
+		for _, persistentLogin := range persistentLogins {
+			if strings.EqualFold(persistentLogin.TargetName, iscsiSession.TargetName) &&
+				strings.EqualFold(persistentLogin.TargetPortal.Address, connection.TargetAddress) &&
+				persistentLogin.TargetPortal.Socket == connection.TargetSocket {
+				private.initiatorInstance = persistentLogin.InitiatorInstance
+				private.initiatorPortNumber = persistentLogin.InitiatorPortNumber
+				private.targetPortal = persistentLogin.TargetPortal
+				private.persistent = true
+				break
+			}
+		}
+		devices, _ := iscsidsc.GetDevicesForIScsiSession(iscsiSession.SessionID)
+		sessions = append(sessions, &targetSession{
+			id:         fmt.Sprintf("%x-%x", iscsiSession.SessionID.AdapterUnique, iscsiSession.SessionID.AdapterSpecific),
+			targetName: iscsiSession.TargetName,
+			portal:     fmt.Sprintf("%v:%v", connection.TargetAddress, connection.TargetSocket),
+			devices:    len(devices),
+			private:    private,
+		})
+	}
+	return sessions, nil
+}
+
+// getTargetLunCounts returns the number of LUNs mapped by the sessions of each target, keyed by
+// lowercase target name
+func getTargetLunCounts() (map[string]int, error) {
+	targetMappings, err := iscsidsc.ReportActiveIScsiTargetMappings()
+	if err != nil {
+		return nil, cerrors.IscsiErrToCerrors(err)
+	}
+	lunCounts := make(map[string]int)
+	for _, targetMapping := range targetMappings {
+		lunCounts[strings.ToLower(targetMapping.TargetName)] += len(targetMapping.LUNList)
+	}
+	return lunCounts, nil
+}
+
+// reloginSession logs in a new session, with the new credentials, through the given session's
+// initiator and target portal, waits for it to report all of the SCSI devices of the given
+// session, replaces the session's persistent login and then logs out the given session
+func reloginSession(session *targetSession, rotation *model.CredentialRotation) error {
+	private := session.private
+	sessionID, _, err := iscsidsc.LoginIScsiTargetEx(
+		session.targetName,
+		private.initiatorInstance,
+		private.initiatorPortNumber,
+		&private.targetPortal,
+		iscsidsc.ISCSI_DIGEST_TYPE_NONE,
+		iscsidsc.ISCSI_DIGEST_TYPE_NONE,
+		rotation.ChapUser,
+		rotation.ChapPassword,
+		false)
+	if err != nil {
+		return cerrors.IscsiErrToCerrors(err)
+	}
+
+	// Keep the previous session until the new one reports all of its SCSI devices
+	loggedIn := false
+	for deadline := time.Now().Add(reloginTimeout); time.Now().Before(deadline); time.Sleep(time.Second) {
+		if devices, _ := iscsidsc.GetDevicesForIScsiSession(*sessionID); len(devices) >= session.devices {
+			loggedIn = true
+			break
+		}
+	}
+	if !loggedIn {
+		if logoutErr := iscsidsc.LogoutIScsiTarget(*sessionID); logoutErr != nil {
+			log.Errorf("Unable to logout session %x-%x, err=%v", sessionID.AdapterUnique, sessionID.AdapterSpecific, logoutErr)
+		}
+		return cerrors.NewChapiErrorf(cerrors.Timeout, errorMessageReloginTimeout, session.portal, session.devices)
+	}
+
+	if private.persistent {
+		if err = iscsidsc.RemoveIScsiPersistentTarget(private.initiatorInstance, private.initiatorPortNumber, session.targetName, private.targetPortal); err != nil {
+			log.Errorf("Unable to remove the persistent login of target %v through %v, err=%v", session.targetName, session.portal, err)
+		}
+		iscsidsc.SetIScsiPersistentLogin(
+			session.targetName,
+			private.initiatorInstance,
+			private.initiatorPortNumber,
+			&private.targetPortal,
+			iscsidsc.ISCSI_DIGEST_TYPE_NONE,
+			iscsidsc.ISCSI_DIGEST_TYPE_NONE,
+			rotation.ChapUser,
+			rotation.ChapPassword)
+	}
+
+	if err = iscsidsc.LogoutIScsiTarget(private.sessionID); err != nil {
+		return cerrors.IscsiErrToCerrors(err)
+	}
+	log.Infof("Session %v of target %v logged in again as session %x-%x", session.id, session.targetName, sessionID.AdapterUnique, sessionID.AdapterSpecific)
+	return nil
+}
+
+// updateTargetCredentials is a no-op under Windows; the persistent logins are replaced, with the
+// new CHAP credentials, as each session is logged in again
+func updateTargetCredentials(targetName string, chapUser string, chapPassword string) error {
+	return nil
+}
+
+// updateInitiatorName changes the Microsoft iSCSI initiator name without logging out the sessions
+func updateInitiatorName(iqn string) error {
+	if err := iscsidsc.SetIScsiInitiatorNodeName(iqn); err != nil {
+		return cerrors.IscsiErrToCerrors(err)
+	}
+	log.Infof("Changed the iSCSI initiator name to %v", iqn)
+	return nil
+}
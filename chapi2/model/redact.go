@@ -0,0 +1,81 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package model
+
+// The Redacted methods return deep copies of the objects carrying credentials, with the
+// credentials masked, so that the objects can be logged.  Log the redacted copy, never the object
+// itself (enforced by the logger package tests).
+
+const (
+	// redactedValue replaces a credential in a redacted copy
+	redactedValue = "**********"
+)
+
+// redactString returns the masked value of the given credential, empty if not set
+func redactString(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// copyStrings returns a copy of the given string slice
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// Redacted returns a deep copy of the publish info with its CHAP password masked
+func (publishInfo *PublishInfo) Redacted() *PublishInfo {
+	if publishInfo == nil {
+		return nil
+	}
+	redacted := *publishInfo
+	redacted.BlockDev = publishInfo.BlockDev.Redacted()
+	if publishInfo.VirtualDev != nil {
+		virtualDev := *publishInfo.VirtualDev
+		redacted.VirtualDev = &virtualDev
+	}
+	return &redacted
+}
+
+// Redacted returns a deep copy of the block device access info with its CHAP password masked
+func (blockDev *BlockDeviceAccessInfo) Redacted() *BlockDeviceAccessInfo {
+	if blockDev == nil {
+		return nil
+	}
+	redacted := *blockDev
+	redacted.IscsiAccessInfo = blockDev.IscsiAccessInfo.Redacted()
+	if blockDev.FcAccessInfo != nil {
+		redacted.FcAccessInfo = &FcAccessInfo{TargetWwpns: copyStrings(blockDev.FcAccessInfo.TargetWwpns)}
+	}
+	return &redacted
+}
+
+// Redacted returns a deep copy of the iSCSI access info with its CHAP password masked
+func (iscsiAccessInfo *IscsiAccessInfo) Redacted() *IscsiAccessInfo {
+	if iscsiAccessInfo == nil {
+		return nil
+	}
+	redacted := *iscsiAccessInfo
+	redacted.DiscoveryIPs = copyStrings(iscsiAccessInfo.DiscoveryIPs)
+	redacted.ChapPassword = redactString(iscsiAccessInfo.ChapPassword)
+	if iscsiAccessInfo.Persistent != nil {
+		persistent := *iscsiAccessInfo.Persistent
+		redacted.Persistent = &persistent
+	}
+	return &redacted
+}
+
+// Redacted returns a deep copy of the credential rotation with its CHAP password masked
+func (rotation *CredentialRotation) Redacted() *CredentialRotation {
+	if rotation == nil {
+		return nil
+	}
+	redacted := *rotation
+	redacted.ChapPassword = redactString(rotation.ChapPassword)
+	redacted.Targets = copyStrings(rotation.Targets)
+	return &redacted
+}
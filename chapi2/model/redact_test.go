@@ -0,0 +1,57 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPublishInfoRedacted(t *testing.T) {
+	persistent := true
+	publishInfo := &PublishInfo{
+		SerialNumber: "fc96d9c5dbd7e1a26c9ce900d5ed3a63",
+		BlockDev: &BlockDeviceAccessInfo{
+			AccessProtocol: AccessProtocolIscsi,
+			TargetName:     "iqn.2007-11.com.nimblestorage:group-c32-array3-g5a2cdea9cf0b91f1",
+			IscsiAccessInfo: &IscsiAccessInfo{
+				DiscoveryIP:  "172.16.6.70",
+				DiscoveryIPs: []string{"172.16.7.70"},
+				ChapUser:     "chapuser",
+				ChapPassword: "chappassword",
+				Persistent:   &persistent,
+			},
+		},
+	}
+
+	redacted := publishInfo.Redacted()
+	iscsiAccessInfo := redacted.BlockDev.IscsiAccessInfo
+	if iscsiAccessInfo.ChapPassword != redactedValue || iscsiAccessInfo.ChapUser != "chapuser" || iscsiAccessInfo.DiscoveryIP != "172.16.6.70" {
+		t.Errorf("unexpected redacted iSCSI access info %+v", iscsiAccessInfo)
+	}
+
+	// The redacted copy shares nothing with the original
+	iscsiAccessInfo.DiscoveryIPs[0] = "0.0.0.0"
+	*iscsiAccessInfo.Persistent = false
+	redacted.BlockDev.TargetName = ""
+	original := publishInfo.BlockDev
+	if original.IscsiAccessInfo.ChapPassword != "chappassword" || original.IscsiAccessInfo.DiscoveryIPs[0] != "172.16.7.70" || !*original.IscsiAccessInfo.Persistent || original.TargetName == "" {
+		t.Errorf("original publish info modified %+v", original.IscsiAccessInfo)
+	}
+
+	// No CHAP password stays empty
+	publishInfo.BlockDev.IscsiAccessInfo.ChapPassword = ""
+	if password := publishInfo.Redacted().BlockDev.IscsiAccessInfo.ChapPassword; password != "" {
+		t.Errorf("unexpected redacted empty password %v", password)
+	}
+
+	var nilPublishInfo *PublishInfo
+	if nilPublishInfo.Redacted() != nil {
+		t.Error("unexpected redacted nil publish info")
+	}
+
+	rotation := &CredentialRotation{ChapUser: "chapuser", ChapPassword: "chappassword", Targets: []string{"iqn.target"}}
+	if redactedRotation := rotation.Redacted(); strings.Contains(redactedRotation.ChapPassword, "chappassword") || redactedRotation.ChapUser != "chapuser" {
+		t.Errorf("unexpected redacted credential rotation %+v", redactedRotation)
+	}
+}
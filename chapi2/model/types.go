@@ -37,6 +37,14 @@ const (
 	TargetScopeVolume = "volume" // Volume Scoped Target (VST)
 )
 
+const (
+	// UnconnectedTargetSourcePersistentLogin - Target configured as a Windows persistent login
+	UnconnectedTargetSourcePersistentLogin = "persistent_login"
+
+	// UnconnectedTargetSourceNodeRecord - Target configured as a Linux open-iscsi node record
+	UnconnectedTargetSourceNodeRecord = "node_record"
+)
+
 const (
 	// ConnectTypeDefault - CHAPI2 will automatically detect and choose the optimal connection type.
 	// This setting is also used if the connect type is not provided (e.g. empty string)
@@ -62,11 +70,144 @@ type Host struct {
 	UUID   string `json:"id,omitempty"`     // Unique host identifier
 	Name   string `json:"name,omitempty"`   // Host name
 	Domain string `json:"domain,omitempty"` // Host domain name
+
+	// Versions of the kernel (or Windows) and of the modules (or drivers) affecting storage
+	KernelVersion string           `json:"kernel_version,omitempty"`
+	Modules       []*StorageModule `json:"modules,omitempty"`
+}
+
+// StorageModule : kernel module (Linux) or driver (Windows) affecting storage, e.g. dm_multipath
+// or msdsm
+type StorageModule struct {
+	Name    string `json:"name"`              // Module or driver name
+	Version string `json:"version,omitempty"` // Module version, the kernel release for in-tree Linux modules
+	Loaded  bool   `json:"loaded"`            // True if the module is loaded (or built into the kernel) or the driver is running
 }
 
 // Hosts returns an array of Host objects
 type Hosts []*Host
 
+// Page : the page of a list returned by a paginated endpoint, if the limit or offset query
+// parameter was given
+type Page struct {
+	Offset     int `json:"offset"`                // Index of the first item returned
+	Limit      int `json:"limit,omitempty"`       // Maximum number of items returned, 0 if unlimited
+	Total      int `json:"total"`                 // Number of items in the whole list
+	NextOffset int `json:"next_offset,omitempty"` // Offset of the next page, 0 if this is the last page
+}
+
+// Health : CHAPI server health, reported to monitoring agents
+type Health struct {
+	Status        string        `json:"status"`         // Always "ok" if CHAPI is able to respond
+	UptimeSeconds uint64        `json:"uptime_seconds"` // Seconds since the CHAPI server was started
+	Logs          *LogDiskUsage `json:"logs,omitempty"` // Log file disk usage, nil if not logging to a file
+}
+
+// LogDiskUsage : disk usage of the CHAPI log files
+type LogDiskUsage struct {
+	Directory     string `json:"directory"`                 // Log file directory
+	LogBytes      uint64 `json:"log_bytes"`                 // Total size of the log files, active and rotated
+	RotatedFiles  int    `json:"rotated_files"`             // Number of rotated log files
+	PrunedFiles   int    `json:"pruned_files"`              // Number of rotated log files removed to honor the total size cap
+	FreeBytes     uint64 `json:"free_bytes"`                // Free space of the log volume
+	MinFreeBytes  uint64 `json:"min_free_bytes"`            // Free space below which only warn and above entries are logged
+	MaxTotalBytes uint64 `json:"max_total_bytes,omitempty"` // Total size cap of the log files, 0 if unlimited
+	Degraded      bool   `json:"degraded"`                  // True if only warn and above entries are logged
+}
+
+// Health status values
+const (
+	HealthStatusOK = "ok"
+)
+
+// LatencyReport : request latency histograms of each CHAPI endpoint
+type LatencyReport struct {
+	SlowThresholdMs int64              `json:"slow_threshold_ms"` // Requests taking longer are logged and counted as slow
+	Endpoints       []*EndpointLatency `json:"endpoints"`
+}
+
+// EndpointLatency : request latency histogram of a CHAPI endpoint
+type EndpointLatency struct {
+	Endpoint  string           `json:"endpoint"`   // Route name (e.g. "CreateDevice")
+	Count     uint64           `json:"count"`      // Requests handled
+	SlowCount uint64           `json:"slow_count"` // Requests exceeding the slow request threshold
+	TotalMs   int64            `json:"total_ms"`   // Total time spent handling requests
+	MaxMs     int64            `json:"max_ms"`     // Slowest request
+	Buckets   []*LatencyBucket `json:"buckets"`
+}
+
+// LatencyBucket : requests completed within LeMs (and above the previous bucket's LeMs)
+type LatencyBucket struct {
+	LeMs  int64  `json:"le_ms"` // Bucket upper bound; -1 for the overflow bucket
+	Count uint64 `json:"count"`
+}
+
+// Readiness : how ready the host is to use HPE storage, scored per category
+type Readiness struct {
+	Status     string               `json:"status"`     // Worst category status ("pass", "warn" or "fail")
+	Score      int                  `json:"score"`      // Average category score (0-100)
+	Categories []*ReadinessCategory `json:"categories"` // Readiness categories (e.g. "services", "connectivity")
+}
+
+// ReadinessCategory : related readiness checks (e.g. the required services)
+type ReadinessCategory struct {
+	Name   string            `json:"name"`   // Category name
+	Status string            `json:"status"` // Worst check status ("pass", "warn" or "fail")
+	Score  int               `json:"score"`  // Average check score (pass=100, warn=50, fail=0)
+	Checks []*ReadinessCheck `json:"checks"` // Checks performed for the category
+}
+
+// ReadinessCheck : the result of a single readiness check
+type ReadinessCheck struct {
+	Name    string `json:"name"`              // Check name (e.g. "multipathd")
+	Status  string `json:"status"`            // "pass", "warn" or "fail"
+	Message string `json:"message,omitempty"` // Details when the check does not pass
+}
+
+// Readiness status values
+const (
+	ReadinessPass = "pass"
+	ReadinessWarn = "warn"
+	ReadinessFail = "fail"
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CHAPI Capabilities Object
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Capabilities : the features CHAPI supports on this host's platform
+type Capabilities struct {
+	Os       string        `json:"os"`       // Host platform ("linux" or "windows")
+	Features []*Capability `json:"features"` // Features ordered by name
+}
+
+// Capability : whether a single feature is supported
+type Capability struct {
+	Name      string `json:"name"`             // Feature name (e.g. "bind_mounts")
+	Supported bool   `json:"supported"`        // True if the feature is supported
+	Reason    string `json:"reason,omitempty"` // Why the feature is not supported
+}
+
+// Capability feature names
+const (
+	CapabilityNvme       = "nvme"        // NVMe volumes
+	CapabilityFc         = "fc"          // Fibre Channel volumes
+	CapabilityBindMounts = "bind_mounts" // Managed bind mounts of a mount point
+	CapabilityExpand     = "expand"      // Growing a device's partition/file system after a volume expansion
+	CapabilityEncryption = "encryption"  // Host based encryption of the volume
+	CapabilityRawBlock   = "raw_block"   // Consuming a device as a raw block device, without a file system
+)
+
+// IsSupported returns true if the given feature is advertised as supported
+func (capabilities *Capabilities) IsSupported(name string) bool {
+	for _, feature := range capabilities.Features {
+		if feature.Name == name {
+			return feature.Supported
+		}
+	}
+	return false
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // CHAPI Network Object
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -90,6 +231,49 @@ type Network struct {
 type Initiator struct {
 	AccessProtocol string   `json:"access_protocol,omitempty"` // Access protocol ("iscsi" or "fc")
 	Init           []string `json:"initiator,omitempty"`       // Initiator iqn if AccessProtocol=="iscsi" else WWPNs if "fc"
+	Instances      []string `json:"instances,omitempty"`       // iSCSI only - initiator instances (Windows software initiator and iSCSI HBAs, Linux offload ifaces)
+}
+
+// CredentialRotation is the new iSCSI initiator name and/or CHAP credentials rolled out to the
+// logged in iSCSI targets, one connection at a time
+type CredentialRotation struct {
+	InitiatorName   string   `json:"initiator_name,omitempty"`    // New iSCSI initiator name (empty to keep the current name)
+	ChapUser        string   `json:"chap_user,omitempty"`         // New CHAP username (empty to keep the current CHAP credentials)
+	ChapPassword    string   `json:"chap_password,omitempty"`     // New CHAP password (required with ChapUser)
+	Targets         []string `json:"targets,omitempty"`           // Targets to log in again (all logged in targets if empty)
+	AllowSinglePath bool     `json:"allow_single_path,omitempty"` // Also log in again the targets with a single connection, losing access until the new login completes
+}
+
+// ReloginResult is the outcome of the rolling re-login of a single iSCSI target
+type ReloginResult struct {
+	TargetName  string `json:"target_name"`
+	Connections int    `json:"connections"`       // Connections (sessions) to the target before the re-login
+	Relogged    int    `json:"relogged"`          // Connections logged in again with the new credentials
+	Skipped     bool   `json:"skipped,omitempty"` // True if the target was not logged in again
+	Error       string `json:"error,omitempty"`   // Reason the target was skipped or not fully logged in again
+}
+
+// StaleSessionCollection selects the iSCSI targets without any LUN that are logged out by
+// CollectStaleSessions
+type StaleSessionCollection struct {
+	Targets []string `json:"targets,omitempty"` // Targets to collect (all logged in targets if empty)
+	MinAge  *int     `json:"min_age,omitempty"` // Seconds a target must have been seen without any LUN (CHAPI_ISCSI_STALE_SESSION_AGE if not set)
+	DryRun  bool     `json:"dry_run,omitempty"` // Report the stale targets without logging them out
+}
+
+// StaleTarget is a logged in iSCSI target without any LUN
+type StaleTarget struct {
+	TargetName   string `json:"target_name"`
+	Sessions     int    `json:"sessions"`             // Sessions logged in to the target
+	EmptySeconds int64  `json:"empty_seconds"`        // Seconds since the target was first seen without any LUN
+	LoggedOut    bool   `json:"logged_out,omitempty"` // True if the target was logged out
+	Error        string `json:"error,omitempty"`      // Reason the logout failed
+}
+
+// LunMapInvalidation selects the iSCSI targets whose cached LUN map is dropped by
+// InvalidateLunMaps
+type LunMapInvalidation struct {
+	Targets []string `json:"targets,omitempty"` // Targets whose LUN map is dropped (every cached LUN map if empty)
 }
 
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -101,6 +285,7 @@ type IscsiTarget struct {
 	Name          string          `json:"name,omitempty"`           // Target iSCSI iqn
 	TargetPortals []*TargetPortal `json:"target_portals,omitempty"` // Target portals
 	TargetScope   string          `json:"target_scope,omitempty"`   // GST="group", VST="volume" or empty if unknown scope or FC
+	DiscoveryIP   string          `json:"discovery_ip,omitempty"`   // Discovery IP through which CHAPI discovered the target (empty if unknown)
 }
 
 // TargetPortal provides information for a single iSCSI target portal (i.e. Data IP)
@@ -111,6 +296,44 @@ type TargetPortal struct {
 	Private *TargetPortalPrivate `json:"-"`                 // Private TargetPortal properties used internally by CHAPI
 }
 
+// UnconnectedTarget describes an iSCSI target that has persistent configuration on the host
+// (Windows persistent login or Linux node record) but no active session
+type UnconnectedTarget struct {
+	Name          string          `json:"name,omitempty"`           // Target iSCSI iqn
+	TargetPortals []*TargetPortal `json:"target_portals,omitempty"` // Configured target portals
+	Source        string          `json:"source,omitempty"`         // "persistent_login" (Windows) or "node_record" (Linux)
+	Startup       string          `json:"startup,omitempty"`        // Linux only - node.startup setting (e.g. "automatic", "manual")
+	LastError     string          `json:"last_error,omitempty"`     // Last known login failure reason, if obtainable
+}
+
+// IscsiTargetMapping describes how an iSCSI session's LUNs are mapped to OS SCSI addresses (Windows
+// only)
+type IscsiTargetMapping struct {
+	TargetName     string             `json:"target_name,omitempty"`    // Target iSCSI iqn
+	InitiatorName  string             `json:"initiator_name,omitempty"` // Initiator instance (e.g. "ROOT\ISCSIPRT\0000_0")
+	OSDeviceName   string             `json:"os_device_name,omitempty"` // OS SCSI port device name (e.g. "\\.\Scsi2:")
+	SessionID      string             `json:"session_id,omitempty"`     // iSCSI session ID
+	OSBusNumber    uint32             `json:"os_bus_number"`            // OS SCSI bus (path) number
+	OSTargetNumber uint32             `json:"os_target_number"`         // OS SCSI target number
+	Luns           []*IscsiLunMapping `json:"luns,omitempty"`           // Mapped LUNs
+	Connections    []*IscsiConnection `json:"connections,omitempty"`    // Session connections
+}
+
+// IscsiConnection describes an iSCSI connection of a session, including the initiator IP address
+// it was actually made from
+type IscsiConnection struct {
+	InitiatorAddress string `json:"initiator_address,omitempty"` // Initiator (source) IP address
+	InitiatorSocket  uint16 `json:"initiator_socket,omitempty"`  // Initiator TCP port
+	TargetAddress    string `json:"target_address,omitempty"`    // Target portal IP address
+	TargetSocket     uint16 `json:"target_socket,omitempty"`     // Target portal TCP port
+}
+
+// IscsiLunMapping maps an OS LUN number to the LUN reported by the iSCSI target
+type IscsiLunMapping struct {
+	OSLun     uint32 `json:"os_lun"`     // OS SCSI LUN number
+	TargetLun uint64 `json:"target_lun"` // 8 byte SCSI LUN reported by the target
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // CHAPI Device Object
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -124,9 +347,25 @@ type Device struct {
 	Size            uint64         `json:"size,omitempty"`               // Volume capacity in total number of bytes //TODO ensure clients/servers change from MiB to byte count
 	State           string         `json:"state,omitempty"`              // TODO, Shiva to define states
 	IscsiTarget     *IscsiTarget   `json:"iscsi_target,omitempty"`       // Pointer to iSCSI target if device connected to an iSCSI target
+	Timings         *Timings       `json:"timings,omitempty"`            // CreateDevice only - time spent in each attach phase
 	Private         *DevicePrivate `json:"-"`                            // Private device properties used internally by CHAPI
 }
 
+// Timings : time spent, in milliseconds, in each phase of a CreateDevice or CreateMount request.
+// A phase retried within the request (e.g. a rescan) reports its total time; phases the request
+// did not go through are omitted.  Requests operating on the same volume concurrently share each
+// other's phase timings.
+type Timings struct {
+	DiscoveryMs         int64 `json:"discovery_ms,omitempty"`          // iSCSI target discovery
+	LoginMs             int64 `json:"login_ms,omitempty"`              // iSCSI target login
+	RescanMs            int64 `json:"rescan_ms,omitempty"`             // FC or disk rescan
+	MultipathAssembleMs int64 `json:"multipath_assemble_ms,omitempty"` // Enumeration of the attached multipath device
+	DeviceWaitMs        int64 `json:"device_wait_ms,omitempty"`        // Wait for the device to serve I/O
+	MkfsMs              int64 `json:"mkfs_ms,omitempty"`               // File system creation
+	MountMs             int64 `json:"mount_ms,omitempty"`              // Mount point creation
+	TotalMs             int64 `json:"total_ms"`                        // Total request time
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // CHAPI Partition Object
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -138,6 +377,168 @@ type DevicePartition struct {
 	Size          uint64 `json:"size,omitempty"`           // Partition size in total number of bytes
 }
 
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CHAPI DevicePath Object
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// DevicePath describes a single path to a device, along with its ALUA state
+type DevicePath struct {
+	Name            string  `json:"name,omitempty"`              // Path name (e.g. "sdb" for Linux, SCSI address "2:0:0:1" for Windows)
+	State           string  `json:"state,omitempty"`             // Path device state (e.g. "running", "offline" for Linux)
+	AccessState     string  `json:"access_state,omitempty"`      // ALUA access state (see AluaXxx constants)
+	TargetPortGroup *uint16 `json:"target_port_group,omitempty"` // ALUA target port group ID, if known
+	Preferred       bool    `json:"preferred,omitempty"`         // True if the target port group is the preferred path
+}
+
+// DevicePathGroup groups a device's paths by their ALUA access state
+type DevicePathGroup struct {
+	AccessState string        `json:"access_state"` // ALUA access state of the paths in the group
+	Paths       []*DevicePath `json:"paths"`        // Paths in the group
+}
+
+// ALUA access states, ordered from the most to the least preferred for I/O
+const (
+	AluaActiveOptimized    = "active/optimized"
+	AluaActiveNonOptimized = "active/non-optimized"
+	AluaLBADependent       = "lba-dependent"
+	AluaStandby            = "standby"
+	AluaTransitioning      = "transitioning"
+	AluaUnavailable        = "unavailable"
+	AluaOffline            = "offline"
+	AluaUnknown            = "unknown"
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CHAPI AttachPlan Object
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// AttachPlan reports how a device would be attached by CreateDevice, without attaching it (e.g. to
+// troubleshoot attach failures).  The device cannot be attached while blockers are reported.
+type AttachPlan struct {
+	SerialNumber     string               `json:"serial_number,omitempty"`     // Nimble volume serial number
+	AccessProtocol   string               `json:"access_protocol,omitempty"`   // Access protocol ("iscsi" or "fc")
+	TargetName       string               `json:"target_name,omitempty"`       // Target iSCSI iqn
+	AlreadyConnected bool                 `json:"already_connected,omitempty"` // Target already logged in, only a rescan would be performed
+	DiscoveryIP      string               `json:"discovery_ip,omitempty"`      // Discovery IP the target was (or would be) discovered through
+	ConnectType      string               `json:"connect_type,omitempty"`      // Connection type that would establish the connections
+	Connections      []*PlannedConnection `json:"connections,omitempty"`       // Initiator/target port pairs that would be used
+	ConnectionCount  int                  `json:"connection_count"`            // Number of connections that would be made
+	Blockers         []string             `json:"blockers,omitempty"`          // Preconditions preventing the attach
+	Warnings         []string             `json:"warnings,omitempty"`          // Limits of the plan (e.g. target ports unknown until discovery)
+	PathMtu          []*PathMtu           `json:"path_mtu,omitempty"`          // Path MTU of each planned connection (only if CHAPI_PATH_MTU_CHECK is "true")
+}
+
+// PlannedConnection is an initiator/target port pair an attach would connect through
+type PlannedConnection struct {
+	InitiatorAddress string `json:"initiator_address"`     // Initiator port IP address ("0.0.0.0" if picked by the initiator)
+	TargetAddress    string `json:"target_address"`        // Target port IP address
+	TargetPort       string `json:"target_port,omitempty"` // Target port socket
+}
+
+// PathMtu is the path MTU between an initiator port and a target port, validated against the NIC
+// MTU of the initiator port.  A smaller path MTU (e.g. jumbo frames enabled on the host but not on
+// a switch) silently drops the larger iSCSI PDUs.
+type PathMtu struct {
+	InitiatorAddress string `json:"initiator_address"`  // Initiator port IP address
+	InitiatorName    string `json:"initiator_name"`     // Initiator port NIC name
+	TargetAddress    string `json:"target_address"`     // Target port IP address
+	InterfaceMtu     int64  `json:"interface_mtu"`      // NIC MTU of the initiator port
+	PathMtu          int64  `json:"path_mtu,omitempty"` // Largest probed MTU reaching the target port unfragmented (0 if unreachable)
+	Status           string `json:"status"`             // Path MTU status (see PathMtuXxx constants)
+}
+
+// Path MTU statuses
+const (
+	PathMtuMatch       = "match"       // Packets of the NIC MTU reach the target port
+	PathMtuMismatch    = "mismatch"    // Only packets smaller than the NIC MTU reach the target port
+	PathMtuUnreachable = "unreachable" // The target port doesn't answer the smallest echo request
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CHAPI PathRecoveryReport Object
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// PathRecoveryReport reports the failed paths of a device found by path recovery (e.g. after an
+// array controller failover) and whether each one was reinstated
+type PathRecoveryReport struct {
+	SerialNumber string          `json:"serial_number"`   // Serial number of the device
+	Paths        []*PathRecovery `json:"paths,omitempty"` // Failed paths found
+	Recovered    int             `json:"recovered"`       // Number of failed paths reinstated
+	Failed       int             `json:"failed"`          // Number of failed paths left failed
+}
+
+// PathRecovery is the outcome of recovering a single failed path
+type PathRecovery struct {
+	Name   string `json:"name"`            // Path name (e.g. "sdb" for Linux, SCSI address "2:0:0:1" for Windows)
+	Status string `json:"status"`          // Recovery status (see PathRecoveryXxx constants)
+	Error  string `json:"error,omitempty"` // Reason the path was not reinstated
+}
+
+// Path recovery statuses
+const (
+	PathRecoveryReinstated  = "reinstated"  // Path was reachable and reinstated
+	PathRecoveryUnreachable = "unreachable" // Path did not respond to TEST UNIT READY
+	PathRecoveryFailed      = "failed"      // Path was reachable but could not be reinstated
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CHAPI DeviceTuning Object
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// IgnoredDevice is a device WWID that CHAPI device enumeration ignores.  Under Linux, the WWID is
+// also blacklisted from multipath so that the device is not reassembled if it is rediscovered.
+type IgnoredDevice struct {
+	WWID string `json:"wwid"` // Device WWID as reported by multipath (e.g. "2f4c97c5c1cd391756c9ce900584f2795")
+}
+
+// DeviceTuning represents the per-volume I/O queue settings.  When submitted with a PUT request,
+// only the non-nil (or non-empty) properties are applied; all other settings are left unchanged.
+type DeviceTuning struct {
+	ReadAheadKB *uint64 `json:"read_ahead_kb,omitempty"` // Linux only - read-ahead size in KiB
+	NrRequests  *uint64 `json:"nr_requests,omitempty"`   // Linux only - block layer request queue size
+	Scheduler   string  `json:"scheduler,omitempty"`     // Linux only - I/O scheduler (e.g. "none", "mq-deadline")
+	RqAffinity  *uint64 `json:"rq_affinity,omitempty"`   // Linux only - request completion CPU affinity (0, 1 or 2)
+	QueueDepth  *uint64 `json:"queue_depth,omitempty"`   // SCSI queue depth per path (Linux) or outstanding I/O limit per StorPort adapter (Windows)
+}
+
+// Device benchmark limits
+const (
+	BenchmarkDefaultDurationSeconds = 5       // Benchmark duration if not specified
+	BenchmarkMaxDurationSeconds     = 10      // Longest benchmark allowed
+	BenchmarkDefaultBlockSize       = 4096    // Read size in bytes if not specified
+	BenchmarkMaxBlockSize           = 1048576 // Largest read size allowed
+	BenchmarkBlockSizeAlignment     = 512     // Read sizes must be a multiple of the sector size
+)
+
+// BenchmarkRequest configures a device latency benchmark.  Zero values select the defaults.
+type BenchmarkRequest struct {
+	DurationSeconds int `json:"duration_seconds,omitempty"` // Benchmark duration (see BenchmarkMaxDurationSeconds)
+	BlockSize       int `json:"block_size,omitempty"`       // Size of each read in bytes
+}
+
+// BenchmarkResult reports the outcome of a device latency benchmark.  Reads are issued one at a
+// time (queue depth 1) at random offsets, bypassing the host's page cache.
+type BenchmarkResult struct {
+	SerialNumber       string            `json:"serial_number,omitempty"` // Nimble volume serial number
+	DurationMs         int64             `json:"duration_ms"`             // Time spent issuing reads
+	BlockSize          int               `json:"block_size"`              // Size of each read in bytes
+	Reads              uint64            `json:"reads"`                   // Number of reads completed
+	IOPS               float64           `json:"iops"`                    // Reads completed per second
+	ThroughputMBPerSec float64           `json:"throughput_mb_per_sec"`   // MiB read per second
+	Latency            *BenchmarkLatency `json:"latency_us,omitempty"`    // Read latency percentiles, in microseconds
+}
+
+// BenchmarkLatency are the read latency percentiles of a device benchmark, in microseconds
+type BenchmarkLatency struct {
+	Min  int64 `json:"min"`
+	Avg  int64 `json:"avg"`
+	P50  int64 `json:"p50"`
+	P90  int64 `json:"p90"`
+	P99  int64 `json:"p99"`
+	P999 int64 `json:"p99_9"`
+	Max  int64 `json:"max"`
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // CHAPI PublishInfo Object
 ///////////////////////////////////////////////////////////////////////////////////////////////////
@@ -147,6 +548,7 @@ type PublishInfo struct {
 	SerialNumber string                   `json:"serial_number,omitempty"`
 	BlockDev     *BlockDeviceAccessInfo   `json:"block_device,omitempty"`
 	VirtualDev   *VirtualDeviceAccessInfo `json:"virtual_device,omitempty"`
+	FsUUID       string                   `json:"fs_uuid,omitempty"` // Expected file system UUID (volume serial number under Windows), verified by CreateMount
 }
 
 // BlockDeviceAccessInfo contains the common fields for accessing a block device
@@ -156,14 +558,26 @@ type BlockDeviceAccessInfo struct {
 	TargetScope     string           `json:"target_scope,omitempty"`    // GST="group", VST="volume" or empty if unknown scope or FC
 	LunID           string           `json:"lun_id,omitempty"`          // LunID is only used by Linux for rescan optimization and not used/required for Windows
 	IscsiAccessInfo *IscsiAccessInfo `json:"iscsi_access_info,omitempty"`
+	FcAccessInfo    *FcAccessInfo    `json:"fc_access_info,omitempty"`
 }
 
 // IscsiAccessInfo contains the fields necessary for iSCSI access
 type IscsiAccessInfo struct {
-	ConnectType  string `json:"connect_type,omitempty"`  // How connections should be enumerated/established
-	DiscoveryIP  string `json:"discovery_ip,omitempty"`  // iSCSI Discovery IP (empty for FC volumes)
-	ChapUser     string `json:"chap_user,omitempty"`     // CHAP username (empty if CHAP not used)
-	ChapPassword string `json:"chap_password,omitempty"` // CHAP password (empty if CHAP not used)
+	ConnectType       string   `json:"connect_type,omitempty"`       // How connections should be enumerated/established
+	DiscoveryIP       string   `json:"discovery_ip,omitempty"`       // iSCSI Discovery IP (empty for FC volumes)
+	DiscoveryIPs      []string `json:"discovery_ips,omitempty"`      // Additional discovery IPs, tried in order, if DiscoveryIP cannot discover the target
+	ChapUser          string   `json:"chap_user,omitempty"`          // CHAP username (empty if CHAP not used)
+	ChapPassword      string   `json:"chap_password,omitempty"`      // CHAP password (empty if CHAP not used)
+	InitiatorInstance string   `json:"initiator_instance,omitempty"` // Windows only - initiator instance to login from (empty for any)
+	Persistent        *bool    `json:"persistent,omitempty"`         // Restore the login after a reboot (default true); false for ephemeral sessions
+	PingSize          int      `json:"ping_size,omitempty"`          // "ping" connect type only - echo request payload size in bytes (e.g. 8972 to validate a 9000 byte MTU)
+	PingDontFragment  bool     `json:"ping_dont_fragment,omitempty"` // "ping" connect type only - set the IP don't fragment flag on echo requests
+}
+
+// FcAccessInfo contains the FC target ports a pre-zoned LUN is mapped through.  When provided, with
+// the LunID, only the LUN is scanned and only on those target ports.
+type FcAccessInfo struct {
+	TargetWwpns []string `json:"target_wwpns,omitempty"` // Target port WWNs (e.g. "56c9ce9000cce702" or "56:C9:CE:90:00:CC:E7:02")
 }
 
 // VirtualDeviceAccessInfo contains the required data to access a virtual device
@@ -178,19 +592,237 @@ type VirtualDeviceAccessInfo struct {
 
 // Mount structure represents all information required to mount and setup filesystem
 type Mount struct {
-	ID           string             `json:"id,omitempty"`            // Unique mount point ID
-	MountPoint   string             `json:"mount_point,omitempty"`   // Mount point location e.g. "/mnt" for Linux, "C:\MountFolder" for Windows
-	SerialNumber string             `json:"serial_number,omitempty"` // Nimble volume serial number
-	FsOpts       *FileSystemOptions `json:"fs_options,omitempty"`    // Filesystem options like fsType, mode, owner and mount options
-	Private      *MountPrivate      `json:"-"`                       // Private mount properties used internally by CHAPI
+	ID            string             `json:"id,omitempty"`             // Unique mount point ID
+	MountPoint    string             `json:"mount_point,omitempty"`    // Mount point location e.g. "/mnt" for Linux, "C:\MountFolder" for Windows
+	SerialNumber  string             `json:"serial_number,omitempty"`  // Nimble volume serial number
+	FsOpts        *FileSystemOptions `json:"fs_options,omitempty"`     // Filesystem options like fsType, mode, owner and mount options
+	BindMounts    []string           `json:"bind_mounts,omitempty"`    // Linux only - managed bind mounts fanned out from this (primary) mount point
+	Status        string             `json:"status,omitempty"`         // Managed mount points only - mount options drift status (see MountStatus constants)
+	Drift         []string           `json:"drift,omitempty"`          // Mount options in effect that differ from the recorded mount options
+	Timings       *Timings           `json:"timings,omitempty"`        // CreateMount only - time spent in each mount phase
+	JournalReplay *JournalReplay     `json:"journal_replay,omitempty"` // CreateMount only - file system journal replayed while mounting
+	Private       *MountPrivate      `json:"-"`                        // Private mount properties used internally by CHAPI
 }
 
+// JournalReplay : file system journal replay performed while mounting (e.g. after an unclean
+// shutdown), reported by CreateMount
+type JournalReplay struct {
+	DurationMs int64    `json:"duration_ms"`        // Mount duration, including the journal replay
+	Messages   []string `json:"messages,omitempty"` // Kernel log (Linux) or event log (Windows) messages reporting the replay
+}
+
+// Mount options drift statuses
+const (
+	MountStatusConsistent = "consistent" // Mount options in effect match the recorded mount options
+	MountStatusDrifted    = "drifted"    // Mount options in effect differ (e.g. remounted read-only after I/O errors)
+)
+
+// MountPointAutoDriveLetter is passed as the Mount.MountPoint (Windows only) to mount to the next
+// free drive letter in the drive letter pool.  The assigned drive letter is returned in the Mount.
+// If no drive letter is free, the volume is mounted to its volume GUID path (e.g.
+// "\\?\Volume{GUID}\") instead.
+const MountPointAutoDriveLetter = "*"
+
 // FileSystemOptions represent file system options to be configured during mount
 type FileSystemOptions struct {
 	FsType    string   `json:"fs_type,omitempty"`       // Filesystem type
 	FsMode    string   `json:"fs_mode,omitempty"`       // Filesystem permissions
 	FsOwner   string   `json:"fs_owner,omitempty"`      // Filesystem owner
 	MountOpts []string `json:"mount_options,omitempty"` // Mount options rw,ro nodiscard etc
+
+	// SELinuxContext (Linux only) is the SELinux context for the file system.  By default it is
+	// applied to the whole mount with the "context=" mount option.  If SELinuxRelabel is set, the
+	// files are instead relabeled after the mount (chcon with SELinuxContext, else restorecon).
+	SELinuxContext string `json:"selinux_context,omitempty"`
+	SELinuxRelabel bool   `json:"selinux_relabel,omitempty"`
+
+	// File system creation options.  Discarding (or zeroing) every block of a multi-TB thin
+	// provisioned LUN can take hours, so NoDiscard (Linux only) skips the mkfs discard ("-K" for
+	// xfs/btrfs, "-E nodiscard" for ext2/3/4).  Windows quick formats unless FullFormat is set.
+	NoDiscard  bool `json:"no_discard,omitempty"`
+	FullFormat bool `json:"full_format,omitempty"`
+
+	// ReFS creation options (Windows only).  IntegrityStreams enables ReFS integrity streams
+	// (checksums of file data); they are not supported on clustered disks.  DevDrive formats the
+	// volume as a Dev Drive (ReFS, Windows 11 22H2 or Windows Server 2025 and later).
+	IntegrityStreams bool `json:"integrity_streams,omitempty"`
+	DevDrive         bool `json:"dev_drive,omitempty"`
+
+	// Expected file system identity.  If set, CreateMount verifies that the mounted file system
+	// has this UUID and/or label, and unmounts it and fails the request if it does not, so that a
+	// device mixup (e.g. a stale multipath map) never mounts the wrong volume.  Under Windows,
+	// FsUUID is the volume serial number as reported by the vol command (e.g. "1A2B-3C4D").  If
+	// FsUUID is not set, the UUID recorded when CHAPI created the file system (Linux only) or
+	// provided in the PublishInfo is verified instead.
+	FsUUID  string `json:"fs_uuid,omitempty"`
+	FsLabel string `json:"fs_label,omitempty"`
+
+	// VerifyWritable makes CreateMount verify that the mounted file system accepts writes, by
+	// FsOwner if set (Linux only), failing the request with a ReadOnly error if the volume is
+	// read-only on the array or the file system went read-only
+	VerifyWritable bool `json:"verify_writable,omitempty"`
+}
+
+// FormatStatus reports the progress of a file system creation in progress.  PercentComplete is
+// only reported for Windows full formats (0 otherwise); quick formats complete within seconds.
+type FormatStatus struct {
+	SerialNumber    string `json:"serial_number"`              // Serial number of the volume being formatted
+	FileSystem      string `json:"file_system"`                // File system being created
+	FullFormat      bool   `json:"full_format,omitempty"`      // Windows full format
+	PercentComplete int    `json:"percent_complete,omitempty"` // Percent complete, if reported
+	ElapsedMs       int64  `json:"elapsed_ms"`                 // Time since the format started
+}
+
+// DeleteMountOptions represent the options of a mount point deletion
+type DeleteMountOptions struct {
+	Lazy bool `json:"lazy,omitempty"` // Linux only - detach the mount point even if it is still in use
+}
+
+// MountBlocker identifies a process that is preventing a mount point from being unmounted
+type MountBlocker struct {
+	PID     int    `json:"pid"`               // Process ID
+	Command string `json:"command,omitempty"` // Process name
+	User    string `json:"user,omitempty"`    // Process owner
+	Path    string `json:"path,omitempty"`    // File (or directory) held open by the process
+}
+
+// QuiesceRequest identifies the mount point to freeze (or thaw).  The mount point is identified by
+// its mount point ID or, if the volume has a single mount point, just by the volume serial number.
+type QuiesceRequest struct {
+	SerialNumber   string `json:"serial_number,omitempty"`   // Nimble volume serial number
+	MountID        string `json:"mount_id,omitempty"`        // Mount point ID
+	TimeoutSeconds uint32 `json:"timeout_seconds,omitempty"` // Freeze only - seconds until the mount point is automatically thawed
+}
+
+// QuiescedMount describes a frozen mount point
+type QuiescedMount struct {
+	MountID      string `json:"mount_id,omitempty"`      // Mount point ID
+	MountPoint   string `json:"mount_point,omitempty"`   // Mount point location
+	SerialNumber string `json:"serial_number,omitempty"` // Nimble volume serial number
+	Method       string `json:"method,omitempty"`        // How the file system was quiesced (see QuiesceMethod constants)
+	ThawDeadline string `json:"thaw_deadline,omitempty"` // RFC 3339 time at which the mount point is automatically thawed
+}
+
+// StagedMountRequest is a StageMount, PublishMount, UnpublishMount or UnstageMount request.  A
+// volume is staged (mounted) once at its staging path and published (bind mounted) from there to
+// each target path.
+type StagedMountRequest struct {
+	SerialNumber string             `json:"serial_number,omitempty"` // Nimble volume serial number
+	StagingPath  string             `json:"staging_path,omitempty"`  // Global staging path (all but UnpublishMount)
+	TargetPath   string             `json:"target_path,omitempty"`   // Target path (PublishMount and UnpublishMount only)
+	ReadOnly     bool               `json:"read_only,omitempty"`     // PublishMount only - bind mount read-only
+	FsOpts       *FileSystemOptions `json:"fs_options,omitempty"`    // StageMount only - file system options, as for CreateMount
+}
+
+// QuiescedMount methods
+const (
+	QuiesceMethodFreeze = "fsfreeze" // Linux - writes are blocked until the file system is thawed
+	QuiesceMethodFlush  = "flush"    // Windows - file system buffers are flushed to the volume
+)
+
+// OrphanedMount describes a CHAPI style mount point directory that no longer has a device behind it
+type OrphanedMount struct {
+	Path    string `json:"path"`              // Mount point directory (or Windows access path)
+	Reason  string `json:"reason"`            // Why the mount point is considered orphaned (see OrphanReason constants)
+	Removed bool   `json:"removed"`           // True if the orphaned mount point was removed
+	Error   string `json:"error,omitempty"`   // Reason the orphaned mount point could not be removed
+	Managed bool   `json:"managed,omitempty"` // True if the mount point was created through CHAPI (see ManagedState)
+}
+
+// OrphanedMount reasons
+const (
+	OrphanReasonEmptyDirectory = "empty_directory" // Directory is not mounted and is empty
+	OrphanReasonMissingDevice  = "missing_device"  // Directory is still mounted, or is an access path, to a device that no longer exists
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CHAPI ManagedState Object
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ManagedState is the set of devices and mount points CHAPI created on this host, as recorded in
+// its local state store
+type ManagedState struct {
+	SchemaVersion int              `json:"schema_version"`    // State store schema version
+	Devices       []*ManagedDevice `json:"devices,omitempty"` // Devices attached through CHAPI
+	Mounts        []*ManagedMount  `json:"mounts,omitempty"`  // Mount points created through CHAPI
+}
+
+// ManagedDevice is a device attached through CHAPI
+type ManagedDevice struct {
+	SerialNumber   string `json:"serial_number"`             // Nimble volume serial number
+	AccessProtocol string `json:"access_protocol,omitempty"` // Access protocol ("iscsi" or "fc")
+	TargetName     string `json:"target_name,omitempty"`     // iSCSI target iqn (empty for FC)
+	FsUUID         string `json:"fs_uuid,omitempty"`         // File system UUID recorded at mkfs (or provided in the PublishInfo)
+	Created        string `json:"created,omitempty"`         // RFC 3339 time at which the device was attached
+	Status         string `json:"status,omitempty"`          // Reconciled status (see ManagedStatus constants)
+}
+
+// ManagedMount is a mount point created through CHAPI
+type ManagedMount struct {
+	ID           string   `json:"id"`                      // Mount point ID
+	MountPoint   string   `json:"mount_point,omitempty"`   // Mount point location
+	SerialNumber string   `json:"serial_number,omitempty"` // Nimble volume serial number
+	MountOpts    []string `json:"mount_options,omitempty"` // Mount options the mount point was created with
+	Created      string   `json:"created,omitempty"`       // RFC 3339 time at which the mount point was created
+	Status       string   `json:"status,omitempty"`        // Reconciled status (see ManagedStatus constants)
+}
+
+// ManagedInitiator is the iSCSI initiator name recorded with the UUID of the host it was first seen
+// on.  A host cloned after the initiator name was recorded finds its own UUID differs.
+type ManagedInitiator struct {
+	Iqn      string `json:"iqn"`                // iSCSI initiator name
+	HostUUID string `json:"host_uuid"`          // UUID of the host the initiator name was recorded on
+	Recorded string `json:"recorded,omitempty"` // RFC 3339 time at which the initiator name was recorded
+}
+
+// DeviceClaim is an orchestrator's ownership claim on a device.  While the claim is active, the
+// device and its mount points are only changed by requests from the owner (see OwnerHeader).
+type DeviceClaim struct {
+	SerialNumber string `json:"serial_number"` // Nimble volume serial number
+	Owner        string `json:"owner"`         // Orchestrator owning the device (e.g. "csi-node" or "docker-plugin")
+	Claimed      string `json:"claimed"`       // RFC 3339 time at which the owner first claimed the device
+	Expires      string `json:"expires"`       // RFC 3339 time at which the claim expires unless renewed
+}
+
+// ClaimRequest claims (or releases) a device on behalf of an orchestrator
+type ClaimRequest struct {
+	Owner      string `json:"owner"`                 // Orchestrator claiming (or releasing) the device
+	TTLSeconds int64  `json:"ttl_seconds,omitempty"` // Claim lifetime (default 300); claiming again renews the claim
+	Override   bool   `json:"override,omitempty"`    // Administrative override of another owner's claim
+}
+
+// Device claim request headers
+const (
+	OwnerHeader         = "X-Chapi-Owner"          // Orchestrator sending a request that changes a device or mount point
+	ClaimOverrideHeader = "X-Chapi-Claim-Override" // "true" for an administrative override of another owner's claim
+)
+
+// ManagedDevice and ManagedMount reconciled statuses
+const (
+	ManagedStatusPresent = "present" // Object is still present on the host
+	ManagedStatusMissing = "missing" // Object is no longer present on the host (e.g. removed outside of CHAPI)
+	ManagedStatusPruned  = "pruned"  // Missing object's record was removed from the state store
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CHAPI Drain Object
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// DrainRequest lists the volumes to unmount and detach when a node is drained
+type DrainRequest struct {
+	SerialNumbers []string `json:"serial_numbers,omitempty"` // Volumes to drain
+	AllManaged    bool     `json:"all_managed,omitempty"`    // Drain every device attached through CHAPI (see ManagedState)
+	Parallelism   int      `json:"parallelism,omitempty"`    // Maximum volumes processed concurrently (default 4)
+	Lazy          bool     `json:"lazy,omitempty"`           // Linux only - lazily unmount busy mount points
+}
+
+// DrainResult is the outcome of draining a single volume
+type DrainResult struct {
+	SerialNumber string   `json:"serial_number"`
+	Unmounted    []string `json:"unmounted,omitempty"` // Mount points removed from the volume
+	Flushed      bool     `json:"flushed"`             // True if the device buffers were flushed
+	Detached     bool     `json:"detached"`            // True if the device was detached (or was not present)
+	Error        string   `json:"error,omitempty"`     // Reason the volume could not be drained
 }
 
 // FcHostPort FC host port
@@ -199,3 +831,12 @@ type FcHostPort struct {
 	PortWwn    string `json:"-"`
 	NodeWwn    string `json:"-"`
 }
+
+// FcTargetPort is a remote FC target port discovered by a host port
+type FcTargetPort struct {
+	HostNumber  string `json:"-"`                       // Linux only - SCSI host number of the host port
+	HostPortWwn string `json:"host_port_wwn,omitempty"` // Linux only - host port the target port was discovered by
+	PortWwn     string `json:"port_wwn"`
+	NodeWwn     string `json:"node_wwn,omitempty"`
+	PortState   string `json:"port_state,omitempty"` // e.g. "Online"
+}
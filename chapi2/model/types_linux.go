@@ -35,4 +35,5 @@ type DevicePrivate struct {
 
 // MountPrivate provides model.Mount platform specific private data
 type MountPrivate struct {
+	DevicePath string `json:"-"` // Device (or partition) path to mount (e.g. /dev/mapper/mpathg)
 }
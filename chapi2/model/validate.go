@@ -0,0 +1,286 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package model
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// REQUEST VALIDATION
+//
+//		CHAPI objects passed in by clients (e.g. PublishInfo) provide a Validate() method.  Rather
+//		than failing on the first problem found, Validate() checks every property and returns a
+//		ValidationErrors list with one FieldError per invalid property.  Field names are the JSON
+//		property paths (e.g. "block_device.iscsi_access_info.discovery_ip") so that clients can
+//		map each error back to the request they sent.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	errorMessageFieldRequired        = "required"
+	errorMessageFieldRange           = "must be between %v and %v"
+	errorMessageFieldMultiple        = "must be a multiple of %v"
+	errorMessageFieldNotAllowed      = "not allowed with %v"
+	errorMessageInvalidAccessProto   = "invalid access protocol %q, expected one of (%v)"
+	errorMessageInvalidChapPair      = "chap_user and chap_password must be provided together"
+	errorMessageInvalidDiscoveryIP   = "%q is not a valid IP address or fully qualified domain name"
+	errorMessageInvalidIscsiName     = "%q is not a valid iSCSI qualified name (iqn.yyyy-mm.naming-authority[:unique] or eui.<16 hex digits>)"
+	errorMessageInvalidLunID         = "%q is not a valid LUN ID"
+	errorMessageInvalidPciSlot       = "%q is not a valid PCI slot number"
+	errorMessageInvalidTargetScope   = "invalid target scope %q, expected one of (%v)"
+	errorMessageInvalidWwn           = "%q is not a valid WWN (16 hex digits)"
+	errorMessageMultipleDeviceFields = "only one of block_device or virtual_device may be provided"
+	errorMessageNoDeviceFields       = "one of block_device or virtual_device is required"
+	errorMessageValidationFailed     = "invalid %v (%v)"
+)
+
+var (
+	// iqnRegexp matches iSCSI qualified names (RFC 3720 section 3.2.6.3.1)
+	iqnRegexp = regexp.MustCompile(`^iqn\.\d{4}-(0[1-9]|1[0-2])\.[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*(:.+)?$`)
+
+	// euiRegexp matches IEEE EUI-64 based iSCSI names (RFC 3720 section 3.2.6.3.2)
+	euiRegexp = regexp.MustCompile(`^eui\.[0-9a-f]{16}$`)
+
+	// wwnRegexp matches a normalized (see NormalizeWwn) FC world wide name
+	wwnRegexp = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+	// hostnameLabelRegexp matches a single DNS label of a fully qualified domain name
+	hostnameLabelRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+// FieldError describes why a single object property failed validation
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// ValidationErrors is the list of property errors returned by the model Validate() methods
+type ValidationErrors []*FieldError
+
+// Error returns all of the property errors as a single string
+func (errs ValidationErrors) Error() string {
+	var messages []string
+	for _, fieldError := range errs {
+		messages = append(messages, fmt.Sprintf("%v: %v", fieldError.Field, fieldError.Error))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// add appends a property error to the list
+func (errs *ValidationErrors) add(field string, format string, a ...interface{}) {
+	*errs = append(*errs, &FieldError{Field: field, Error: fmt.Sprintf(format, a...)})
+}
+
+// merge appends the property errors of a nested object, prefixing their field names
+func (errs *ValidationErrors) merge(prefix string, err error) {
+	if nested, ok := err.(ValidationErrors); ok {
+		for _, fieldError := range nested {
+			*errs = append(*errs, &FieldError{Field: prefix + "." + fieldError.Field, Error: fieldError.Error})
+		}
+	}
+}
+
+// toError returns nil if no property errors were found, else the ValidationErrors list.  This
+// avoids returning a non-nil error interface holding an empty list.
+func (errs ValidationErrors) toError() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidationErrorMessage returns the summary message used when an object of the given type fails
+// validation
+func ValidationErrorMessage(objectType string, err error) string {
+	return fmt.Sprintf(errorMessageValidationFailed, objectType, err.Error())
+}
+
+// Validate verifies that the PublishInfo object describes exactly one device and that the device
+// access properties are valid.  A ValidationErrors list is returned if validation fails.
+func (publishInfo *PublishInfo) Validate() error {
+	var errs ValidationErrors
+
+	if publishInfo.SerialNumber == "" {
+		errs.add("serial_number", errorMessageFieldRequired)
+	}
+
+	switch {
+	case (publishInfo.BlockDev == nil) && (publishInfo.VirtualDev == nil):
+		errs.add("block_device", errorMessageNoDeviceFields)
+	case (publishInfo.BlockDev != nil) && (publishInfo.VirtualDev != nil):
+		errs.add("virtual_device", errorMessageMultipleDeviceFields)
+	case publishInfo.BlockDev != nil:
+		errs.merge("block_device", publishInfo.BlockDev.Validate())
+	case publishInfo.VirtualDev != nil:
+		errs.merge("virtual_device", publishInfo.VirtualDev.Validate())
+	}
+
+	return errs.toError()
+}
+
+// Validate verifies that the benchmark duration and block size are within the supported limits.  A
+// ValidationErrors list is returned if validation fails.
+func (request *BenchmarkRequest) Validate() error {
+	var errs ValidationErrors
+
+	if (request.DurationSeconds < 0) || (request.DurationSeconds > BenchmarkMaxDurationSeconds) {
+		errs.add("duration_seconds", errorMessageFieldRange, 1, BenchmarkMaxDurationSeconds)
+	}
+	if (request.BlockSize < 0) || (request.BlockSize > BenchmarkMaxBlockSize) {
+		errs.add("block_size", errorMessageFieldRange, BenchmarkBlockSizeAlignment, BenchmarkMaxBlockSize)
+	} else if request.BlockSize%BenchmarkBlockSizeAlignment != 0 {
+		errs.add("block_size", errorMessageFieldMultiple, BenchmarkBlockSizeAlignment)
+	}
+
+	return errs.toError()
+}
+
+// Validate verifies the block device access properties required by the access protocol.  A
+// ValidationErrors list is returned if validation fails.
+func (blockDev *BlockDeviceAccessInfo) Validate() error {
+	var errs ValidationErrors
+
+	switch blockDev.AccessProtocol {
+	case AccessProtocolIscsi:
+		if blockDev.FcAccessInfo != nil {
+			errs.add("fc_access_info", errorMessageFieldNotAllowed, AccessProtocolIscsi)
+		}
+		if blockDev.TargetName == "" {
+			errs.add("target_name", errorMessageFieldRequired)
+		} else if !IsValidIscsiName(blockDev.TargetName) {
+			errs.add("target_name", errorMessageInvalidIscsiName, blockDev.TargetName)
+		}
+		if blockDev.IscsiAccessInfo == nil {
+			errs.add("iscsi_access_info", errorMessageFieldRequired)
+		} else {
+			errs.merge("iscsi_access_info", blockDev.IscsiAccessInfo.Validate())
+		}
+	case AccessProtocolFC:
+		if blockDev.IscsiAccessInfo != nil {
+			errs.add("iscsi_access_info", errorMessageFieldNotAllowed, AccessProtocolFC)
+		}
+		if blockDev.FcAccessInfo != nil {
+			// The LUN is scanned directly on the target ports so its ID is required
+			if blockDev.LunID == "" {
+				errs.add("lun_id", errorMessageFieldRequired)
+			}
+			errs.merge("fc_access_info", blockDev.FcAccessInfo.Validate())
+		}
+	case "":
+		errs.add("access_protocol", errorMessageFieldRequired)
+	default:
+		errs.add("access_protocol", errorMessageInvalidAccessProto, blockDev.AccessProtocol, AccessProtocolIscsi+" "+AccessProtocolFC)
+	}
+
+	switch strings.ToLower(blockDev.TargetScope) {
+	case "", TargetScopeGroup, TargetScopeVolume:
+	default:
+		errs.add("target_scope", errorMessageInvalidTargetScope, blockDev.TargetScope, TargetScopeGroup+" "+TargetScopeVolume)
+	}
+
+	if blockDev.LunID != "" {
+		if _, err := strconv.ParseUint(blockDev.LunID, 10, 64); err != nil {
+			errs.add("lun_id", errorMessageInvalidLunID, blockDev.LunID)
+		}
+	}
+
+	return errs.toError()
+}
+
+// Validate verifies that the virtual device location properties are provided.  A ValidationErrors
+// list is returned if validation fails.
+func (virtualDev *VirtualDeviceAccessInfo) Validate() error {
+	var errs ValidationErrors
+
+	if virtualDev.PciSlotNumber == "" {
+		errs.add("pci_slot_number", errorMessageFieldRequired)
+	} else if _, err := strconv.ParseUint(virtualDev.PciSlotNumber, 10, 32); err != nil {
+		errs.add("pci_slot_number", errorMessageInvalidPciSlot, virtualDev.PciSlotNumber)
+	}
+	if virtualDev.ScsiController == "" {
+		errs.add("scsi_controller", errorMessageFieldRequired)
+	}
+
+	return errs.toError()
+}
+
+// Validate verifies the iSCSI access properties.  A ValidationErrors list is returned if
+// validation fails.
+func (iscsiAccessInfo *IscsiAccessInfo) Validate() error {
+	var errs ValidationErrors
+
+	if iscsiAccessInfo.DiscoveryIP != "" && !IsValidIPOrFQDN(iscsiAccessInfo.DiscoveryIP) {
+		errs.add("discovery_ip", errorMessageInvalidDiscoveryIP, iscsiAccessInfo.DiscoveryIP)
+	}
+	for i, discoveryIP := range iscsiAccessInfo.DiscoveryIPs {
+		if !IsValidIPOrFQDN(discoveryIP) {
+			errs.add(fmt.Sprintf("discovery_ips[%v]", i), errorMessageInvalidDiscoveryIP, discoveryIP)
+		}
+	}
+
+	if (iscsiAccessInfo.ChapUser == "") != (iscsiAccessInfo.ChapPassword == "") {
+		errs.add("chap_user", errorMessageInvalidChapPair)
+	}
+
+	return errs.toError()
+}
+
+// Validate verifies the FC access properties.  A ValidationErrors list is returned if validation
+// fails.
+func (fcAccessInfo *FcAccessInfo) Validate() error {
+	var errs ValidationErrors
+
+	if len(fcAccessInfo.TargetWwpns) == 0 {
+		errs.add("target_wwpns", errorMessageFieldRequired)
+	}
+	for i, wwpn := range fcAccessInfo.TargetWwpns {
+		if !wwnRegexp.MatchString(NormalizeWwn(wwpn)) {
+			errs.add(fmt.Sprintf("target_wwpns[%v]", i), errorMessageInvalidWwn, wwpn)
+		}
+	}
+
+	return errs.toError()
+}
+
+// NormalizeWwn returns the given FC world wide name as 16 lower case hex digits, without the "0x"
+// prefix or ":" separators used by Linux and Windows respectively
+func NormalizeWwn(wwn string) string {
+	wwn = strings.ToLower(strings.TrimSpace(wwn))
+	return strings.Replace(strings.TrimPrefix(wwn, "0x"), ":", "", -1)
+}
+
+// IsValidIscsiName returns true if the given name is a valid iqn or eui formatted iSCSI name.
+// iSCSI names are case insensitive.
+func IsValidIscsiName(name string) bool {
+	name = strings.ToLower(name)
+	return iqnRegexp.MatchString(name) || euiRegexp.MatchString(name)
+}
+
+// IsValidIPOrFQDN returns true if the given address is an IPv4/IPv6 address or a syntactically
+// valid host name
+func IsValidIPOrFQDN(address string) bool {
+	if net.ParseIP(address) != nil {
+		return true
+	}
+
+	address = strings.TrimSuffix(address, ".")
+	if address == "" || len(address) > 253 {
+		return false
+	}
+	labels := strings.Split(address, ".")
+	for _, label := range labels {
+		if !hostnameLabelRegexp.MatchString(label) {
+			return false
+		}
+	}
+
+	// A host name whose last label is all digits would be an invalid IPv4 address
+	_, err := strconv.Atoi(labels[len(labels)-1])
+	return err != nil
+}
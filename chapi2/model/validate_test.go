@@ -0,0 +1,133 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package model
+
+import (
+	"testing"
+)
+
+func TestPublishInfoValidate(t *testing.T) {
+	iscsiBlockDev := func() *BlockDeviceAccessInfo {
+		return &BlockDeviceAccessInfo{
+			AccessProtocol:  AccessProtocolIscsi,
+			TargetName:      "iqn.2007-11.com.nimblestorage:group-c32-array3-g5a2cdea9cf0b91f1",
+			TargetScope:     TargetScopeGroup,
+			LunID:           "0",
+			IscsiAccessInfo: &IscsiAccessInfo{DiscoveryIP: "10.1.1.10"},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*PublishInfo)
+		fields []string
+	}{
+		{"valid iscsi", func(p *PublishInfo) {}, nil},
+		{"valid fc", func(p *PublishInfo) {
+			p.BlockDev = &BlockDeviceAccessInfo{AccessProtocol: AccessProtocolFC, LunID: "12"}
+		}, nil},
+		{"valid eui and fqdn", func(p *PublishInfo) {
+			p.BlockDev.TargetName = "eui.02004567A425678D"
+			p.BlockDev.IscsiAccessInfo.DiscoveryIP = "array1.example.com"
+		}, nil},
+		{"valid ipv6 and chap", func(p *PublishInfo) {
+			p.BlockDev.IscsiAccessInfo = &IscsiAccessInfo{DiscoveryIP: "fe80::1", ChapUser: "user", ChapPassword: "secret"}
+		}, nil},
+		{"no device", func(p *PublishInfo) { p.BlockDev = nil }, []string{"block_device"}},
+		{"multiple devices", func(p *PublishInfo) { p.VirtualDev = &VirtualDeviceAccessInfo{} }, []string{"virtual_device"}},
+		{"valid virtual device", func(p *PublishInfo) {
+			p.BlockDev = nil
+			p.VirtualDev = &VirtualDeviceAccessInfo{PciSlotNumber: "160", ScsiController: "0"}
+		}, nil},
+		{"invalid virtual device", func(p *PublishInfo) {
+			p.BlockDev = nil
+			p.VirtualDev = &VirtualDeviceAccessInfo{PciSlotNumber: "slot1"}
+		}, []string{"virtual_device.pci_slot_number", "virtual_device.scsi_controller"}},
+		{"no serial number", func(p *PublishInfo) { p.SerialNumber = "" }, []string{"serial_number"}},
+		{"no access protocol", func(p *PublishInfo) { p.BlockDev.AccessProtocol = "" }, []string{"block_device.access_protocol"}},
+		{"invalid access protocol", func(p *PublishInfo) { p.BlockDev.AccessProtocol = "nvme" }, []string{"block_device.access_protocol"}},
+		{"missing iscsi fields", func(p *PublishInfo) {
+			p.BlockDev.TargetName = ""
+			p.BlockDev.IscsiAccessInfo = nil
+		}, []string{"block_device.target_name", "block_device.iscsi_access_info"}},
+		{"invalid iqn", func(p *PublishInfo) { p.BlockDev.TargetName = "iqn.2007-13.com.nimblestorage" }, []string{"block_device.target_name"}},
+		{"iscsi info with fc", func(p *PublishInfo) { p.BlockDev.AccessProtocol = AccessProtocolFC }, []string{"block_device.iscsi_access_info"}},
+		{"valid pre-zoned fc", func(p *PublishInfo) {
+			p.BlockDev = &BlockDeviceAccessInfo{AccessProtocol: AccessProtocolFC, LunID: "12",
+				FcAccessInfo: &FcAccessInfo{TargetWwpns: []string{"0x56c9ce9000cce702", "56:C9:CE:90:00:CC:E7:03"}}}
+		}, nil},
+		{"invalid pre-zoned fc", func(p *PublishInfo) {
+			p.BlockDev = &BlockDeviceAccessInfo{AccessProtocol: AccessProtocolFC,
+				FcAccessInfo: &FcAccessInfo{TargetWwpns: []string{"56c9ce9000cce70"}}}
+		}, []string{"block_device.lun_id", "block_device.fc_access_info.target_wwpns[0]"}},
+		{"fc info with iscsi", func(p *PublishInfo) { p.BlockDev.FcAccessInfo = &FcAccessInfo{} }, []string{"block_device.fc_access_info"}},
+		{"invalid scope and lun", func(p *PublishInfo) {
+			p.BlockDev.TargetScope = "array"
+			p.BlockDev.LunID = "-1"
+		}, []string{"block_device.target_scope", "block_device.lun_id"}},
+		{"invalid discovery ip", func(p *PublishInfo) { p.BlockDev.IscsiAccessInfo.DiscoveryIP = "10.1.1.256" }, []string{"block_device.iscsi_access_info.discovery_ip"}},
+		{"invalid discovery ips", func(p *PublishInfo) {
+			p.BlockDev.IscsiAccessInfo.DiscoveryIPs = []string{"10.1.2.10", "bad_host"}
+		}, []string{"block_device.iscsi_access_info.discovery_ips[1]"}},
+		{"chap user without password", func(p *PublishInfo) { p.BlockDev.IscsiAccessInfo.ChapUser = "user" }, []string{"block_device.iscsi_access_info.chap_user"}},
+	}
+
+	for _, tc := range tests {
+		publishInfo := &PublishInfo{SerialNumber: "28174883c7719ac236c9ce900584f2795", BlockDev: iscsiBlockDev()}
+		tc.modify(publishInfo)
+
+		err := publishInfo.Validate()
+		if len(tc.fields) == 0 {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+
+		errs, ok := err.(ValidationErrors)
+		if !ok {
+			t.Errorf("%v: expected ValidationErrors, got %v", tc.name, err)
+			continue
+		}
+		if len(errs) != len(tc.fields) {
+			t.Errorf("%v: expected %v errors, got %v", tc.name, len(tc.fields), errs)
+			continue
+		}
+		for i, field := range tc.fields {
+			if errs[i].Field != field {
+				t.Errorf("%v: expected error on %v, got %v", tc.name, field, errs[i].Field)
+			}
+		}
+	}
+}
+
+func TestBenchmarkRequestValidate(t *testing.T) {
+	tests := []struct {
+		request BenchmarkRequest
+		fields  []string
+	}{
+		{BenchmarkRequest{}, nil},
+		{BenchmarkRequest{DurationSeconds: BenchmarkMaxDurationSeconds, BlockSize: BenchmarkMaxBlockSize}, nil},
+		{BenchmarkRequest{DurationSeconds: BenchmarkMaxDurationSeconds + 1}, []string{"duration_seconds"}},
+		{BenchmarkRequest{DurationSeconds: -1, BlockSize: 1000}, []string{"duration_seconds", "block_size"}},
+		{BenchmarkRequest{BlockSize: BenchmarkMaxBlockSize * 2}, []string{"block_size"}},
+	}
+
+	for _, tc := range tests {
+		err := tc.request.Validate()
+		errs, _ := err.(ValidationErrors)
+		if (err != nil) && (errs == nil) {
+			t.Errorf("%+v: expected ValidationErrors, got %v", tc.request, err)
+			continue
+		}
+		if len(errs) != len(tc.fields) {
+			t.Errorf("%+v: expected errors on %v, got %v", tc.request, tc.fields, err)
+			continue
+		}
+		for i, field := range tc.fields {
+			if errs[i].Field != field {
+				t.Errorf("%+v: expected error on %v, got %v", tc.request, field, errs[i].Field)
+			}
+		}
+	}
+}
@@ -3,26 +3,43 @@
 package mount
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	"github.com/hpe-storage/common-host-libs/chapi2/multipath"
+	"github.com/hpe-storage/common-host-libs/chapi2/timing"
 	"github.com/hpe-storage/common-host-libs/chapi2/virtualdevice"
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
 
 const (
 	// Shared error messages
+	errorMessageBindMountUnsupported        = "bind mounts not supported on this platform"
+	errorMessageBindMountsRemain            = `mount point "%v" is still referenced by %v bind mount(s)`
+	errorMessageDriveLettersUnsupported     = "drive letters not supported on this platform"
+	errorMessageFileSystemMismatch          = `file system %v mismatch on "%v", expected "%v" but found "%v"`
 	errorMessageInvalidInputParameter       = "invalid input parameter"
+	errorMessageInvalidMountRoot            = `mount root "%v" is not an absolute path`
+	errorMessageLazyUnmountUnsupported      = "lazy unmount not supported on this platform"
 	errorMessageMissingMountPoint           = "missing mount point"
 	errorMessageMissingMountPointID         = "missing mount point ID"
+	errorMessageMissingMountRoot            = "missing mount root"
 	errorMessageMissingSerialNumber         = "missing serial number"
+	errorMessageMountFailed                 = "mount failed, %v"
+	errorMessageMountPointBusy              = `mount point "%v" is busy, %v process(es) using it`
+	errorMessageMountPointFrozen            = `mount point "%v" is already frozen`
 	errorMessageMountPointInUse             = `mount point "%v" already in use`
 	errorMessageMountPointNotEmpty          = `mount point "%v" is not empty`
 	errorMessageMountPointNotFound          = "mount point not found"
+	errorMessageMountPointNotFrozen         = `mount point "%v" is not frozen`
 	errorMessageMultipathPluginNotSet       = "multipathPlugin not set"
 	errorMessageMultipleMountPointsDetected = "multiple mount points detected"
+	errorMessageQuiesceTimeoutTooLong       = "freeze timeout cannot exceed %v seconds"
+	errorMessageRelabelFailed               = `failed to relabel "%v", %v`
+	errorMessageUnmountUnsupported          = "unmount by path not supported on this platform"
 	errorMessageUnsupportedPartition        = "unsupported partition"
 	errorMessageVolumeAlreadyMounted        = `volume already mounted at "%v"`
 )
@@ -41,18 +58,112 @@ func NewMounter() *Mounter {
 
 // GetMounts reports all mounts on this host for the specified Nimble volume
 func (mounter *Mounter) GetMounts(serialNumber string) ([]*model.Mount, error) {
-	return mounter.getMounts(serialNumber, "", false, true)
+	return mounter.GetMountsWithPrefix(serialNumber, "")
 }
 
-// GetAllMountDetails enumerates the specified mount point ID
+// GetMountsWithPrefix reports all mounts on this host for the specified Nimble volume.  If
+// mountPointPrefix is provided, only the mount points at or beneath that path are reported.
+func (mounter *Mounter) GetMountsWithPrefix(serialNumber string, mountPointPrefix string) ([]*model.Mount, error) {
+	// The mount point paths are needed to filter by prefix
+	allDetails := mountPointPrefix != ""
+	mounts, err := mounter.getMounts(serialNumber, "", allDetails, true)
+	if err != nil {
+		return nil, err
+	}
+	mounts = addBindMounts(mounts, serialNumber, "", allDetails)
+	if !allDetails {
+		return mounts, nil
+	}
+
+	// Only report the mount point IDs, as if the details had not been enumerated
+	var filtered []*model.Mount
+	for _, mount := range filterMountPointPrefix(mounts, mountPointPrefix) {
+		filtered = append(filtered, &model.Mount{ID: mount.ID, Private: mount.Private})
+	}
+	return filtered, nil
+}
+
+// GetAllMountDetails enumerates the specified mount point ID, along with the mount options in
+// effect
 func (mounter *Mounter) GetAllMountDetails(serialNumber string, mountId string) ([]*model.Mount, error) {
-	return mounter.getMounts(serialNumber, mountId, true, true)
+	return mounter.GetAllMountDetailsWithPrefix(serialNumber, mountId, "")
+}
+
+// GetAllMountDetailsWithPrefix enumerates the specified mount point ID, along with the mount
+// options in effect.  If mountPointPrefix is provided, only the mount points at or beneath that
+// path are reported.
+func (mounter *Mounter) GetAllMountDetailsWithPrefix(serialNumber string, mountId string, mountPointPrefix string) ([]*model.Mount, error) {
+	mounts, err := mounter.getMounts(serialNumber, mountId, true, true)
+	if err != nil {
+		return nil, err
+	}
+	mounts = addBindMounts(mounts, serialNumber, mountId, true)
+	setActiveMountOptions(mounts)
+	if mountPointPrefix == "" {
+		return mounts, nil
+	}
+	return filterMountPointPrefix(mounts, mountPointPrefix), nil
+}
+
+// IsSamePathName returns true if the two mount point paths refer to the same location (e.g. mount
+// point paths are case insensitive under Windows)
+func IsSamePathName(path1, path2 string) bool {
+	return isSamePathName(path1, path2)
+}
+
+// filterMountPointPrefix returns the mounts whose mount point is at or beneath the given path
+// (e.g. "/var/lib/kubelet" matches "/var/lib/kubelet/pods/..." but not "/var/lib/kubelet2")
+func filterMountPointPrefix(mounts []*model.Mount, mountPointPrefix string) []*model.Mount {
+	var filtered []*model.Mount
+	for _, mount := range mounts {
+		if isPathPrefix(mountPointPrefix, mount.MountPoint) {
+			filtered = append(filtered, mount)
+		}
+	}
+	log.Tracef("%v of %v mount points beneath %v", len(filtered), len(mounts), mountPointPrefix)
+	return filtered
+}
+
+// isPathPrefix returns true if path is the same as, or beneath, the given prefix path
+func isPathPrefix(prefix string, path string) bool {
+	if path == "" {
+		return false
+	}
+	prefix = strings.TrimRight(prefix, `/\`)
+	if prefix == "" {
+		// Root directory
+		return true
+	}
+	if len(path) < len(prefix) || !isSamePathName(path[:len(prefix)], prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || os.IsPathSeparator(path[len(prefix)])
 }
 
 // CreateMount is called to mount the given device to the given mount point
 func (mounter *Mounter) CreateMount(serialNumber string, mountPoint string, fsOptions *model.FileSystemOptions) (*model.Mount, error) {
 	log.Tracef(">>>>> CreateMount, serialNumber=%v, mountPoint=%v, fsOptions=%v", serialNumber, mountPoint, fsOptions)
 	defer log.Trace("<<<<< CreateMount")
+	defer timing.StartStage(serialNumber, timing.StageMount)()
+
+	mount, err := mounter.createMountPoint(serialNumber, mountPoint, fsOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify that the file system accepts writes if requested (see mount_writable.go)
+	if err = verifyMountWritable(mount.MountPoint, fsOptions); err != nil {
+		return nil, err
+	}
+	return mount, nil
+}
+
+// createMountPoint mounts the given device to the given mount point, unless already mounted there
+func (mounter *Mounter) createMountPoint(serialNumber string, mountPoint string, fsOptions *model.FileSystemOptions) (*model.Mount, error) {
+	// Mount to the next free drive letter if requested (see mount_driveletter.go)
+	if mountPoint == model.MountPointAutoDriveLetter {
+		return mounter.createAutoDriveLetterMount(serialNumber, fsOptions)
+	}
 
 	// Validate and enumerate the mount object for the given serial number and mount point
 	mount, alreadyMounted, err := mounter.getMountForCreate(serialNumber, mountPoint)
@@ -67,21 +178,55 @@ func (mounter *Mounter) CreateMount(serialNumber string, mountPoint string, fsOp
 		return mount, nil
 	}
 
-	// Mount the volume at the specified mount point
+	// If the volume is already mounted elsewhere, fan out to the requested path with a managed
+	// bind mount of the primary mount point (see mount_bind.go)
+	if mount.MountPoint != "" {
+		return mounter.createManagedBindMount(mount, mountPoint, false)
+	}
+
+	// Mount the volume at the specified mount point, reporting any journal replay (see
+	// mount_journal.go)
+	replay := watchJournalReplay(mount)
 	err = mounter.createMount(mount, mountPoint, fsOptions)
+	journalReplay := replay.stop()
 	if err != nil {
+		log.WithEvent(log.EventIDMountFailure).Errorf("Unable to mount %v at %v, err=%v", serialNumber, mountPoint, err)
 		return nil, err
 	}
+	mount.JournalReplay = journalReplay
 
 	// Now that the device has been mounted, adjust the mount point and return the mount object
 	mount.MountPoint = mountPoint
 	return mount, nil
 }
 
+// Unmount unmounts the file system mounted at the given path, without enumerating its volume (e.g.
+// while the host shuts down).  A path that is not mounted is not an error.
+func (mounter *Mounter) Unmount(mountPoint string) error {
+	log.Tracef(">>>>> Unmount, mountPoint=%v", mountPoint)
+	defer log.Trace("<<<<< Unmount")
+
+	// Call the platform specific routine to unmount the path
+	return mounter.unmount(mountPoint)
+}
+
 // DeleteMount is called to unmount the given mount point ID
 func (mounter *Mounter) DeleteMount(serialNumber string, mountId string) error {
-	log.Tracef(">>>>> DeleteMount, serialNumber=%v, mountId=%v", serialNumber, mountId)
-	defer log.Trace("<<<<< DeleteMount")
+	return mounter.DeleteMountWithOptions(serialNumber, mountId, nil)
+}
+
+// DeleteMountWithOptions is called to unmount the given mount point ID with the given options (may
+// be nil).  If options.Lazy is true, and the platform supports it, the mount point is detached even
+// if it is still in use.
+func (mounter *Mounter) DeleteMountWithOptions(serialNumber string, mountId string, options *model.DeleteMountOptions) error {
+	lazy := options != nil && options.Lazy
+	log.Tracef(">>>>> DeleteMountWithOptions, serialNumber=%v, mountId=%v, lazy=%v", serialNumber, mountId, lazy)
+	defer log.Trace("<<<<< DeleteMountWithOptions")
+
+	// Managed bind mounts are tracked by CHAPI and only remove the bind mount path
+	if handled, err := mounter.deleteManagedBindMount(serialNumber, mountId, lazy); handled {
+		return err
+	}
 
 	// Validate and enumerate the mount object for the given serial number and mount point ID
 	mount, err := mounter.getMountForDelete(serialNumber, mountId)
@@ -91,8 +236,13 @@ func (mounter *Mounter) DeleteMount(serialNumber string, mountId string) error {
 		return err
 	}
 
+	// The primary mount point cannot be removed while bind mounts still reference it
+	if err = checkBindMountReferences(mount); err != nil {
+		return err
+	}
+
 	// Call the platform specific deleteMount routine to dismount the volume
-	return mounter.deleteMount(mount)
+	return mounter.deleteMount(mount, lazy)
 }
 
 // enumerateDevices enumerates the given serialNumber (or all devices if serialNumber is empty).
@@ -110,7 +260,9 @@ func (mounter *Mounter) enumerateDevices(serialNumber string, allDetails bool) (
 // data, and enumerates the Mount object.  The following properties are returned:
 //      mount           - Enumerated model.Mount object for the provided serialNumber/mountPoint
 //      alreadyMounted  - If volume is already mounted, at the requested mount point, true is
-//                        returned else false ("mount" object returned if alreadyMounted==true)
+//                        returned else false ("mount" object returned if alreadyMounted==true).
+//                        If false, and mount.MountPoint is set, the volume is mounted elsewhere
+//                        and a managed bind mount is required.
 //      err             - If volume cannot be mounted, an error object is returned ("mount" and
 //                        "alreadyMounted" are invalid)
 func (mounter *Mounter) getMountForCreate(serialNumber string, mountPoint string) (mount *model.Mount, alreadyMounted bool, err error) {
@@ -156,6 +308,20 @@ func (mounter *Mounter) getMountForCreate(serialNumber string, mountPoint string
 	// Get the mount point object we're going to try and mount
 	mount = mounts[0]
 
+	// A volume GUID path mount is replaced by a drive letter or directory mount point (see
+	// mount_volumepath.go)
+	if isVolumeGUIDPath(mount.MountPoint) {
+		if !isVolumeGUIDPath(mountPoint) {
+			log.Tracef(`Replacing volume GUID path mount %v with %v`, mount.MountPoint, mountPoint)
+			mount.MountPoint = ""
+			return mount, false, nil
+		}
+		if strings.EqualFold(normalizeVolumeGUIDPath(mountPoint), normalizeVolumeGUIDPath(mount.MountPoint)) {
+			log.Tracef(`Mount point ID=%v, SerialNumber=%v, volume GUID path %v, already mounted`, mount.ID, mount.SerialNumber, mount.MountPoint)
+			return mount, true, nil
+		}
+	}
+
 	// Handle case where Nimble volume is already mounted
 	if mount.MountPoint != "" {
 		// Convert the current mount point to its absolute path
@@ -181,8 +347,27 @@ func (mounter *Mounter) getMountForCreate(serialNumber string, mountPoint string
 			return mount, true, nil
 		}
 
-		// If here, the device is already mounted but to a different location.  Log the error and
-		// fail the request.
+		// If here, the device is already mounted but to a different location.  Where supported,
+		// the caller creates a managed bind mount to the requested location.  If the requested
+		// location is already one of those bind mounts, there is nothing to do.
+		if bindFanOutSupported {
+			var record *bindMountRecord
+			bindMountLock.Lock()
+			record, err = loadBindMountRecord(serialNumber)
+			bindMountLock.Unlock()
+			if err != nil {
+				log.Errorf("Unable to load the bind mounts of SerialNumber=%v, err=%v", serialNumber, err)
+				return nil, false, cerrors.NewChapiError(err)
+			}
+			if bind := record.findBindMount("", requestedMountPoint); bind != nil {
+				log.Tracef(`Bind mount ID=%v, SerialNumber=%v, MountPoint=%v, already mounted`, bind.ID, serialNumber, bind.MountPoint)
+				return record.toMount(bind, true), true, nil
+			}
+			log.Tracef(`Volume mounted at %v, bind mount required for %v`, currentMountPoint, requestedMountPoint)
+			return mount, false, nil
+		}
+
+		// Bind mounts are not supported on this platform.  Log the error and fail the request.
 		err = cerrors.NewChapiErrorf(cerrors.AlreadyExists, errorMessageVolumeAlreadyMounted, currentMountPoint)
 		log.Error(err)
 		return nil, false, err
@@ -0,0 +1,314 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// MANAGED BIND MOUNTS
+//
+//		CHAPI mounts each device/partition at a single primary mount point.  On platforms that
+//		support it (Linux), additional CreateMount requests for an already mounted volume do not
+//		fail; instead the primary mount point is bind mounted to the requested path.  These
+//		managed bind mounts are recorded, per volume serial number, in bindMountStatePath.
+//
+//		Each managed bind mount has its own mount point ID and is reported by GetMounts alongside
+//		the primary mount point.  The primary mount object lists its bind mounts in BindMounts.
+//
+//		Deletion is reference counted.  Deleting a bind mount only removes that path.  The primary
+//		mount point cannot be deleted while bind mounts still reference it; a Busy error, with
+//		the remaining bind mounts as details, is returned instead.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	bindMountRecordExt = ".json"
+)
+
+var (
+	// bindMountLock serializes read-modify-write access to the bind mount records
+	bindMountLock sync.Mutex
+)
+
+// bindMountRecord tracks the managed bind mounts fanned out from a volume's primary mount point
+type bindMountRecord struct {
+	SerialNumber string       `json:"serial_number"`
+	MountPoint   string       `json:"mount_point"`
+	BindMounts   []*bindMount `json:"bind_mounts"`
+}
+
+// bindMount is a single managed bind mount
+type bindMount struct {
+	ID         string `json:"id"`
+	MountPoint string `json:"mount_point"`
+	ReadOnly   bool   `json:"read_only,omitempty"`
+}
+
+// getBindMountID returns the mount point ID for the given volume's bind mount path
+func getBindMountID(serialNumber string, mountPoint string) string {
+	h := fnv.New64a()
+	h.Write([]byte(serialNumber + "." + mountPoint))
+	return fmt.Sprintf("%x-bind", h.Sum64())
+}
+
+// loadBindMountRecord returns the bind mount record for the given serial number, or nil if the
+// volume has no managed bind mounts
+func loadBindMountRecord(serialNumber string) (*bindMountRecord, error) {
+	data, err := ioutil.ReadFile(filepath.Join(bindMountStatePath, serialNumber+bindMountRecordExt))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	record := &bindMountRecord{}
+	if err = json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// loadBindMountRecords returns the bind mount records for the given serial number, or for all
+// volumes if serialNumber is empty
+func loadBindMountRecords(serialNumber string) ([]*bindMountRecord, error) {
+	if serialNumber != "" {
+		record, err := loadBindMountRecord(serialNumber)
+		if record == nil || err != nil {
+			return nil, err
+		}
+		return []*bindMountRecord{record}, nil
+	}
+
+	files, err := ioutil.ReadDir(bindMountStatePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records []*bindMountRecord
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), bindMountRecordExt) {
+			continue
+		}
+		record, err := loadBindMountRecord(strings.TrimSuffix(file.Name(), bindMountRecordExt))
+		if err != nil {
+			log.Errorf("Skipping invalid bind mount record %v, err=%v", file.Name(), err)
+			continue
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// saveBindMountRecord atomically persists the given record, removing it once no bind mounts remain
+func saveBindMountRecord(record *bindMountRecord) error {
+	recordPath := filepath.Join(bindMountStatePath, record.SerialNumber+bindMountRecordExt)
+	if len(record.BindMounts) == 0 {
+		if err := os.Remove(recordPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return state.WriteFileSync(recordPath, data)
+}
+
+// findBindMount returns the bind mount, within the record, with the given mount point ID or path
+func (record *bindMountRecord) findBindMount(mountId string, mountPoint string) *bindMount {
+	if record == nil {
+		return nil
+	}
+	for _, bind := range record.BindMounts {
+		if (mountId != "" && bind.ID == mountId) || (mountPoint != "" && isSamePathName(bind.MountPoint, mountPoint)) {
+			return bind
+		}
+	}
+	return nil
+}
+
+// toMount returns the model.Mount object for the given bind mount
+func (record *bindMountRecord) toMount(bind *bindMount, allDetails bool) *model.Mount {
+	if !allDetails {
+		return &model.Mount{ID: bind.ID}
+	}
+	mount := &model.Mount{ID: bind.ID, MountPoint: bind.MountPoint, SerialNumber: record.SerialNumber}
+	if bind.ReadOnly {
+		mount.FsOpts = &model.FileSystemOptions{MountOpts: []string{readOnlyMountOption}}
+	}
+	return mount
+}
+
+// addBindMounts appends the managed bind mounts to the enumerated mount points and, if all
+// details are requested, lists each primary mount point's bind mounts
+func addBindMounts(mounts []*model.Mount, serialNumber string, mountId string, allDetails bool) []*model.Mount {
+	if !bindFanOutSupported {
+		return mounts
+	}
+
+	bindMountLock.Lock()
+	defer bindMountLock.Unlock()
+
+	records, err := loadBindMountRecords(serialNumber)
+	if err != nil {
+		log.Errorf("Unable to load bind mount records, err=%v", err)
+		return mounts
+	}
+
+	for _, record := range records {
+		if allDetails {
+			for _, mount := range mounts {
+				if mount.SerialNumber == record.SerialNumber && isSamePathName(mount.MountPoint, record.MountPoint) {
+					for _, bind := range record.BindMounts {
+						mount.BindMounts = append(mount.BindMounts, bind.MountPoint)
+					}
+				}
+			}
+		}
+		for _, bind := range record.BindMounts {
+			if mountId == "" || mountId == bind.ID {
+				mounts = append(mounts, record.toMount(bind, allDetails))
+			}
+		}
+	}
+	return mounts
+}
+
+// createManagedBindMount bind mounts the volume's primary mount point to the given path, read-only
+// if requested, and records the new bind mount.  If the path is already a bind mount of the
+// primary mount point, the existing bind mount is returned instead.
+func (mounter *Mounter) createManagedBindMount(primary *model.Mount, mountPoint string, readOnly bool) (*model.Mount, error) {
+	log.Tracef(">>>>> createManagedBindMount, primary=%v, mountPoint=%v, readOnly=%v", primary.MountPoint, mountPoint, readOnly)
+	defer log.Trace("<<<<< createManagedBindMount")
+
+	bindMountLock.Lock()
+	defer bindMountLock.Unlock()
+
+	record, err := loadBindMountRecord(primary.SerialNumber)
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	// Start a new record if there is none, or if the existing one is for a stale primary
+	if record == nil || !isSamePathName(record.MountPoint, primary.MountPoint) {
+		record = &bindMountRecord{SerialNumber: primary.SerialNumber, MountPoint: primary.MountPoint}
+	}
+
+	// A concurrent request may have already bind mounted the path
+	if bind := record.findBindMount("", mountPoint); bind != nil {
+		if bind.ReadOnly != readOnly {
+			err = cerrors.NewChapiErrorf(cerrors.AlreadyExists, errorMessagePublishedReadOnlyDiffers, mountPoint, bind.ReadOnly)
+			log.Error(err)
+			return nil, err
+		}
+		log.Tracef("Volume %v already bind mounted at %v", primary.SerialNumber, mountPoint)
+		return record.toMount(bind, true), nil
+	}
+
+	if err = createBindMount(primary.MountPoint, mountPoint, readOnly); err != nil {
+		return nil, err
+	}
+
+	bind := &bindMount{ID: getBindMountID(primary.SerialNumber, mountPoint), MountPoint: mountPoint, ReadOnly: readOnly}
+	record.BindMounts = append(record.BindMounts, bind)
+	if err = saveBindMountRecord(record); err != nil {
+		// Don't leave an untracked bind mount behind
+		log.Errorf("Unable to record bind mount %v, err=%v", mountPoint, err)
+		mounter.deleteMount(&model.Mount{MountPoint: mountPoint}, false)
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	return record.toMount(bind, true), nil
+}
+
+// deleteManagedBindMount checks whether the given mount point ID is a managed bind mount and, if
+// so, removes it.  If serialNumber is empty, the bind mount records of all volumes are searched.
+// The handled return value is false if the ID is not a managed bind mount.
+func (mounter *Mounter) deleteManagedBindMount(serialNumber string, mountId string, lazy bool) (handled bool, err error) {
+	if !bindFanOutSupported {
+		return false, nil
+	}
+
+	bindMountLock.Lock()
+	defer bindMountLock.Unlock()
+
+	records, err := loadBindMountRecords(serialNumber)
+	if err != nil {
+		return true, cerrors.NewChapiError(err)
+	}
+	var record *bindMountRecord
+	var bind *bindMount
+	for _, record = range records {
+		if bind = record.findBindMount(mountId, ""); bind != nil {
+			break
+		}
+	}
+	if bind == nil {
+		return false, nil
+	}
+	serialNumber = record.SerialNumber
+
+	log.Tracef("Deleting managed bind mount, ID=%v, MountPoint=%v", bind.ID, bind.MountPoint)
+	if err = mounter.deleteMount(&model.Mount{ID: bind.ID, MountPoint: bind.MountPoint, SerialNumber: serialNumber}, lazy); err != nil {
+		return true, err
+	}
+
+	var remaining []*bindMount
+	for _, b := range record.BindMounts {
+		if b != bind {
+			remaining = append(remaining, b)
+		}
+	}
+	record.BindMounts = remaining
+	if err = saveBindMountRecord(record); err != nil {
+		return true, cerrors.NewChapiError(err)
+	}
+	return true, nil
+}
+
+// checkBindMountReferences fails the request if the given primary mount point is still referenced
+// by managed bind mounts
+func checkBindMountReferences(mount *model.Mount) error {
+	if !bindFanOutSupported {
+		return nil
+	}
+
+	bindMountLock.Lock()
+	defer bindMountLock.Unlock()
+
+	record, err := loadBindMountRecord(mount.SerialNumber)
+	if err != nil {
+		return cerrors.NewChapiError(err)
+	}
+	if record == nil || len(record.BindMounts) == 0 || !isSamePathName(record.MountPoint, mount.MountPoint) {
+		return nil
+	}
+
+	var bindMountPoints []string
+	for _, bind := range record.BindMounts {
+		bindMountPoints = append(bindMountPoints, bind.MountPoint)
+	}
+	err = cerrors.NewChapiErrorf(cerrors.Busy, errorMessageBindMountsRemain, mount.MountPoint, len(bindMountPoints)).WithDetails(bindMountPoints)
+	log.Error(err)
+	return err
+}
@@ -0,0 +1,134 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// MOUNT OPTIONS DRIFT
+//
+//		A mounted file system can end up with options other than the ones it was mounted with;
+//		most notably ext4 and xfs remount themselves read-only after I/O errors, which goes
+//		unnoticed until the application fails to write.  GetAllMountDetails reports the mount
+//		options in effect (Linux mount table, Windows partition/disk read-only attributes) in the
+//		Mount's FsOpts and CheckMountOptionsDrift compares them with the options recorded when
+//		the mount point was created.
+//
+//		Only the options that are known to drift, or to matter for health checks, are compared:
+//
+//		- ro/rw				Access mode; "rw" is expected unless "ro" was requested
+//		- discard			Online discard; only compared if discard or nodiscard was requested
+//		- noatime			Access time updates; only compared if an atime option was requested
+//		- nouuid			xfs duplicate UUID mounts; only compared if nouuid was requested
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+const (
+	mountOptionReadOnly  = "ro"
+	mountOptionReadWrite = "rw"
+)
+
+// mountOptionGroup is a set of mutually exclusive mount options; the first option is the default
+// when none of them is in effect
+type mountOptionGroup struct {
+	options    []string // Mutually exclusive options
+	alwaysRead bool     // True to compare the group even if none of its options were requested
+}
+
+// mountOptionGroups are the mount option groups compared by CheckMountOptionsDrift
+var mountOptionGroups = []mountOptionGroup{
+	{options: []string{mountOptionReadWrite, mountOptionReadOnly}, alwaysRead: true},
+	{options: []string{"nodiscard", "discard"}},
+	{options: []string{"relatime", "noatime", "strictatime", "atime"}},
+	{options: []string{"", "nouuid"}},
+}
+
+// CheckMountOptionsDrift compares the mount options in effect, enumerated by GetAllMountDetails,
+// with the given recorded mount options and sets the mount's Status and Drift accordingly
+func CheckMountOptionsDrift(mount *model.Mount, recordedOptions []string) {
+	var activeOptions []string
+	if mount.FsOpts != nil {
+		activeOptions = mount.FsOpts.MountOpts
+	}
+	mount.Drift = getMountOptionsDrift(activeOptions, recordedOptions)
+	mount.Status = model.MountStatusConsistent
+	if len(mount.Drift) != 0 {
+		mount.Status = model.MountStatusDrifted
+	}
+}
+
+// IsReadOnlyDrift returns true if the given mount was expected to be read-write but is read-only
+func IsReadOnlyDrift(mount *model.Mount) bool {
+	return mount.Status == model.MountStatusDrifted && mount.FsOpts != nil && hasMountOption(mount.FsOpts.MountOpts, mountOptionReadOnly)
+}
+
+// getMountOptionsDrift returns a description of each mount option group whose option in effect
+// differs from the recorded option
+func getMountOptionsDrift(activeOptions []string, recordedOptions []string) []string {
+	var drift []string
+	for _, group := range mountOptionGroups {
+		recorded, requested := findMountOption(recordedOptions, group.options)
+		if !requested && !group.alwaysRead {
+			continue
+		}
+		active, _ := findMountOption(activeOptions, group.options)
+		if active != recorded {
+			drift = append(drift, fmt.Sprintf("%v in effect, %v expected", describeMountOption(active, group), describeMountOption(recorded, group)))
+		}
+	}
+	return drift
+}
+
+// findMountOption returns the last of the given mutually exclusive options found in the mount
+// options, or the first (default) option if none is found.  The found return value is false if
+// none of the options is found.
+func findMountOption(mountOptions []string, options []string) (option string, found bool) {
+	option = options[0]
+	for _, mountOption := range mountOptions {
+		if hasMountOption(options, mountOption) {
+			option, found = mountOption, true
+		}
+	}
+	return option, found
+}
+
+// describeMountOption returns the given option of the group, or "no <option>" if the group's
+// default is the absence of an option
+func describeMountOption(option string, group mountOptionGroup) string {
+	if option == "" {
+		return "no " + group.options[1]
+	}
+	return option
+}
+
+// hasMountOption returns true if the given option is one of the mount options
+func hasMountOption(mountOptions []string, option string) bool {
+	for _, mountOption := range mountOptions {
+		if mountOption == option && option != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// setActiveMountOptions sets the mount options in effect in the FsOpts of each mounted mount point
+func setActiveMountOptions(mounts []*model.Mount) {
+	activeOptions := getActiveMountOptions(mounts)
+	for _, mount := range mounts {
+		options, ok := activeOptions[mount.ID]
+		if !ok {
+			continue
+		}
+		fsOptions := model.FileSystemOptions{}
+		if mount.FsOpts != nil {
+			fsOptions = *mount.FsOpts
+		}
+		fsOptions.MountOpts = options
+		mount.FsOpts = &fsOptions
+	}
+}
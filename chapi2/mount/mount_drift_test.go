@@ -0,0 +1,44 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestCheckMountOptionsDrift(t *testing.T) {
+	testCases := []struct {
+		name     string
+		active   []string
+		recorded []string
+		expected []string
+	}{
+		{"defaults", []string{"rw", "relatime", "attr2"}, nil, nil},
+		{"read-only", []string{"ro", "relatime"}, nil, []string{"ro in effect, rw expected"}},
+		{"requested read-only", []string{"ro"}, []string{"ro"}, nil},
+		{"discard", []string{"rw"}, []string{"discard", "noatime"}, []string{"nodiscard in effect, discard expected", "relatime in effect, noatime expected"}},
+		{"discard not requested", []string{"rw", "discard"}, nil, nil},
+		{"nouuid", []string{"rw"}, []string{"nouuid"}, []string{"no nouuid in effect, nouuid expected"}},
+		{"nouuid in effect", []string{"rw", "nouuid", "noatime"}, []string{"noatime", "nouuid"}, nil},
+	}
+	for _, tc := range testCases {
+		mount := &model.Mount{FsOpts: &model.FileSystemOptions{MountOpts: tc.active}}
+		CheckMountOptionsDrift(mount, tc.recorded)
+		if !reflect.DeepEqual(mount.Drift, tc.expected) {
+			t.Errorf("%v: expected drift %v, got %v", tc.name, tc.expected, mount.Drift)
+		}
+		expectedStatus := model.MountStatusConsistent
+		if tc.expected != nil {
+			expectedStatus = model.MountStatusDrifted
+		}
+		if mount.Status != expectedStatus {
+			t.Errorf("%v: expected status %v, got %v", tc.name, expectedStatus, mount.Status)
+		}
+		if readOnly := IsReadOnlyDrift(mount); readOnly != (tc.name == "read-only") {
+			t.Errorf("%v: unexpected read-only drift %v", tc.name, readOnly)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// DRIVE LETTER POOL
+//
+//		Windows workflows that just need "any drive letter" can mount to model.MountPointAutoDriveLetter
+//		instead of picking a drive letter themselves.  CreateMount then mounts the volume to the
+//		first free drive letter in the drive letter pool and returns the assigned drive letter in
+//		the Mount object.  If the volume is already mounted to a drive letter, that mount point is
+//		returned instead.  If no drive letter in the pool is free, the volume is mounted to its
+//		volume GUID path (see VOLUME GUID PATH MOUNTS in mount_volumepath.go).
+//
+//		The pool defaults to D through Z and is configured through SetDriveLetterPool or the
+//		CHAPI_DRIVE_LETTER_POOL environment variable.  A pool lists drive letters and drive letter
+//		ranges, in the order they are assigned (e.g. "M-Z", or "E,F,X-Z").
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// DriveLetterPoolEnv names the drive letter pool used if SetDriveLetterPool was not called
+	DriveLetterPoolEnv = config.DriveLetterPoolEnv
+
+	// defaultDriveLetterPool skips the floppy (A, B) and system (C) drive letters
+	defaultDriveLetterPool = "D-Z"
+
+	// Shared error messages
+	errorMessageInvalidDriveLetterPool = `invalid drive letter pool "%v"`
+	errorMessageNoFreeDriveLetter      = "no free drive letter in the drive letter pool"
+)
+
+var (
+	// driveLetterLock serializes drive letter assignment so that concurrent requests are not
+	// assigned the same drive letter
+	driveLetterLock sync.Mutex
+
+	// driveLetterPool is the ordered list of assignable drive letters (e.g. "D"), loaded on first
+	// use if not set through SetDriveLetterPool
+	driveLetterPool []string
+)
+
+// SetDriveLetterPool sets the drive letters, and drive letter ranges, that can be automatically
+// assigned (e.g. "E,F,X-Z").  An empty pool restores the default pool.
+func SetDriveLetterPool(pool string) error {
+	if pool == "" {
+		pool = defaultDriveLetterPool
+	}
+	letters, err := parseDriveLetterPool(pool)
+	if err != nil {
+		return err
+	}
+
+	driveLetterLock.Lock()
+	defer driveLetterLock.Unlock()
+	driveLetterPool = letters
+	return nil
+}
+
+// GetFreeDriveLetters returns the drive letter pool's drive letters (e.g. "E:\") that are not in
+// use, in the order they are assigned
+func (mounter *Mounter) GetFreeDriveLetters() ([]string, error) {
+	log.Trace(">>>>> GetFreeDriveLetters")
+	defer log.Trace("<<<<< GetFreeDriveLetters")
+
+	driveLetterLock.Lock()
+	defer driveLetterLock.Unlock()
+	return getFreeDriveLetters(getDriveLetterPool())
+}
+
+// createAutoDriveLetterMount mounts the given device to the first free drive letter in the drive
+// letter pool
+func (mounter *Mounter) createAutoDriveLetterMount(serialNumber string, fsOptions *model.FileSystemOptions) (*model.Mount, error) {
+	log.Tracef(">>>>> createAutoDriveLetterMount, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< createAutoDriveLetterMount")
+
+	driveLetterLock.Lock()
+	defer driveLetterLock.Unlock()
+
+	// If the volume is already mounted to a drive letter, return that mount point
+	if serialNumber != "" {
+		mounts, err := mounter.getMounts(serialNumber, "", true, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(mounts) == 1 && isWindowsDriveLetterPath(mounts[0].MountPoint) {
+			log.Tracef("Volume already mounted to drive letter %v", mounts[0].MountPoint)
+			return mounts[0], nil
+		}
+	}
+
+	freeDriveLetters, err := getFreeDriveLetters(getDriveLetterPool())
+	if err != nil {
+		return nil, err
+	}
+	if len(freeDriveLetters) == 0 {
+		// Dense hosts fall back to the volume GUID path (see mount_volumepath.go)
+		if volumePathMountSupported {
+			log.Tracef("No free drive letter, mounting serial number %v to its volume GUID path", serialNumber)
+			return mounter.createVolumePathMount(serialNumber, fsOptions)
+		}
+		err = cerrors.NewChapiError(cerrors.ResourceExhausted, errorMessageNoFreeDriveLetter)
+		log.Error(err)
+		return nil, err
+	}
+	log.Tracef("Assigning drive letter %v", freeDriveLetters[0])
+	return mounter.CreateMount(serialNumber, freeDriveLetters[0], fsOptions)
+}
+
+// getDriveLetterPool returns the drive letter pool, loading it from the environment (or the
+// default pool) on first use.  The caller must hold driveLetterLock.
+func getDriveLetterPool() []string {
+	if driveLetterPool == nil {
+		pool := config.String(DriveLetterPoolEnv)
+		letters, err := parseDriveLetterPool(pool)
+		if pool == "" || err != nil {
+			if err != nil {
+				log.Errorf("Ignoring %v, err=%v", DriveLetterPoolEnv, err)
+			}
+			letters, _ = parseDriveLetterPool(defaultDriveLetterPool)
+		}
+		driveLetterPool = letters
+	}
+	return driveLetterPool
+}
+
+// parseDriveLetterPool parses a comma separated list of drive letters and drive letter ranges
+// (e.g. "E,F,X-Z") into an ordered list of unique drive letters
+func parseDriveLetterPool(pool string) ([]string, error) {
+	var letters []string
+	found := make(map[byte]bool)
+	for _, entry := range strings.Split(strings.ToUpper(pool), ",") {
+		entry = strings.TrimSpace(entry)
+		first, last := entry, entry
+		if dash := strings.Index(entry, "-"); dash >= 0 {
+			first, last = strings.TrimSpace(entry[:dash]), strings.TrimSpace(entry[dash+1:])
+		}
+		if !isDriveLetter(first) || !isDriveLetter(last) || first[0] > last[0] {
+			return nil, cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidDriveLetterPool, pool)
+		}
+		for letter := first[0]; letter <= last[0]; letter++ {
+			if !found[letter] {
+				found[letter] = true
+				letters = append(letters, string(letter))
+			}
+		}
+	}
+	return letters, nil
+}
+
+// isDriveLetter returns true if the given string is a single drive letter (e.g. "E")
+func isDriveLetter(letter string) bool {
+	return len(letter) == 1 && letter[0] >= 'A' && letter[0] <= 'Z'
+}
+
+// driveLetterPath returns the access path of the given drive letter (e.g. "E:\")
+func driveLetterPath(letter string) string {
+	return fmt.Sprintf(`%v:\`, letter)
+}
+
+// isWindowsDriveLetterPath takes the given path and returns true if it's a path to a drive letter
+// (e.g. c:\ or c:) else false is returned.
+func isWindowsDriveLetterPath(accessPath string) bool {
+	if lenAccessPath := len(accessPath); (lenAccessPath == 2) || (lenAccessPath == 3) {
+		accessPath := strings.ToUpper(accessPath)
+		if (accessPath[0] >= 'A') && (accessPath[0] <= 'Z') && (accessPath[1] == ':') {
+			return (lenAccessPath == 2) || (accessPath[2] == '\\')
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDriveLetterPool(t *testing.T) {
+	tests := []struct {
+		pool    string
+		letters []string
+		valid   bool
+	}{
+		{"D-Z", []string{"D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N", "O", "P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", "Z"}, true},
+		{"e,f, x-z", []string{"E", "F", "X", "Y", "Z"}, true},
+		{"M", []string{"M"}, true},
+		{"Z,E-F,F", []string{"Z", "E", "F"}, true},
+		{"", nil, false},
+		{"Z-D", nil, false},
+		{"E,,F", nil, false},
+		{"EF", nil, false},
+		{"1-3", nil, false},
+	}
+	for _, test := range tests {
+		letters, err := parseDriveLetterPool(test.pool)
+		if (err == nil) != test.valid {
+			t.Errorf("pool %q: unexpected err=%v", test.pool, err)
+			continue
+		}
+		if test.valid && !reflect.DeepEqual(letters, test.letters) {
+			t.Errorf("pool %q: got %v, expected %v", test.pool, letters, test.letters)
+		}
+	}
+}
+
+func TestSetDriveLetterPool(t *testing.T) {
+	defer func() { driveLetterPool = nil }()
+
+	if err := SetDriveLetterPool("X-Z"); err != nil {
+		t.Fatal(err)
+	}
+	if pool := getDriveLetterPool(); !reflect.DeepEqual(pool, []string{"X", "Y", "Z"}) {
+		t.Errorf("unexpected pool %v", pool)
+	}
+	if err := SetDriveLetterPool("invalid"); err == nil {
+		t.Error("expected error setting invalid pool")
+	}
+	if err := SetDriveLetterPool(""); err != nil || len(getDriveLetterPool()) != 23 {
+		t.Errorf("expected default pool, pool=%v, err=%v", getDriveLetterPool(), err)
+	}
+	if path := driveLetterPath("E"); path != `E:\` || !isWindowsDriveLetterPath(path) {
+		t.Errorf("unexpected drive letter path %v", path)
+	}
+}
@@ -0,0 +1,105 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// JOURNAL REPLAY REPORTING
+//
+//		After an unclean shutdown, mounting a journaling file system (xfs, ext4, NTFS, ReFS) first
+//		replays its journal, which can take minutes on a large or busy file system; operators
+//		then assume the mount hung.  While CreateMount mounts a volume, the mount's progress is
+//		logged every journalReplayProgressInterval along with the journal replay messages logged
+//		so far, from the kernel log (Linux) or the System event log (Windows).  If the journal was
+//		replayed, the replay messages and the mount duration are returned in the Mount object
+//		(see model.JournalReplay).
+//
+//		Under Linux, the mount command is given MountMaxWaitEnv seconds (default 60) to complete,
+//		journal replay included, before CreateMount fails with a cerrors.Timeout error.  Windows
+//		replays the journal when the disk is brought online, so the maximum wait does not apply.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// MountMaxWaitEnv is the time, in seconds, a Linux mount may take, journal replay included
+	MountMaxWaitEnv = config.MountMaxWaitEnv
+
+	// DefaultMountMaxWait is the mount's maximum wait if MountMaxWaitEnv is not set
+	DefaultMountMaxWait = 60 * time.Second
+
+	errorMessageMountTimeout = "mount of %v did not complete within %v, the file system journal may still be replaying"
+)
+
+var (
+	// journalReplayProgressInterval is the interval at which the progress of a mount is logged; a
+	// variable so that tests can shorten it
+	journalReplayProgressInterval = 10 * time.Second
+)
+
+// journalReplayWatch tracks a mount in progress for journal replay
+type journalReplayWatch struct {
+	mount *model.Mount
+	start time.Time
+	done  chan struct{}
+}
+
+// watchJournalReplay starts tracking the given mount, logging its progress until stopped
+func watchJournalReplay(mount *model.Mount) *journalReplayWatch {
+	watch := &journalReplayWatch{mount: mount, start: time.Now(), done: make(chan struct{})}
+	go watch.logProgress()
+	return watch
+}
+
+// logProgress logs the progress of the mount, and any new journal replay message, until the watch
+// is stopped
+func (watch *journalReplayWatch) logProgress() {
+	ticker := time.NewTicker(journalReplayProgressInterval)
+	defer ticker.Stop()
+	logged := 0
+	for {
+		select {
+		case <-watch.done:
+			return
+		case <-ticker.C:
+			log.Infof("Mount of %v in progress for %v, the file system journal may be replaying", watch.mount.SerialNumber, time.Since(watch.start).Round(time.Second))
+			messages, err := getJournalReplayMessages(watch.mount, watch.start)
+			if err != nil {
+				log.Tracef("Unable to read journal replay messages, err=%v", err)
+				continue
+			}
+			for ; logged < len(messages); logged++ {
+				log.Infof("Mount of %v: %v", watch.mount.SerialNumber, messages[logged])
+			}
+		}
+	}
+}
+
+// stop stops tracking the mount and returns the journal replay it performed, or nil if the
+// journal was not replayed
+func (watch *journalReplayWatch) stop() *model.JournalReplay {
+	close(watch.done)
+	duration := time.Since(watch.start)
+	messages, err := getJournalReplayMessages(watch.mount, watch.start)
+	if err != nil {
+		log.Errorf("Unable to read journal replay messages of %v, err=%v", watch.mount.SerialNumber, err)
+		return nil
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	log.Infof("File system journal of %v replayed, mount took %v", watch.mount.SerialNumber, duration.Round(time.Millisecond))
+	return &model.JournalReplay{DurationMs: duration.Nanoseconds() / int64(time.Millisecond), Messages: messages}
+}
+
+// getMountMaxWait returns the time a mount may take, journal replay included
+func getMountMaxWait() time.Duration {
+	return config.Seconds(MountMaxWaitEnv, DefaultMountMaxWait, 1)
+}
@@ -3,7 +3,58 @@
 package mount
 
 import (
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/linux"
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/util"
+)
+
+const (
+	// Linux supports additional mount points per volume through managed bind mounts
+	bindFanOutSupported = true
+
+	// Volume GUID path mounts are only applicable to Windows
+	volumePathMountSupported = false
+
+	mountCommand         = "mount"
+	dmesgCommand         = "dmesg"
+	fsfreezeCommand      = "fsfreeze"
+	chconCommand         = "chcon"
+	restoreconCommand    = "restorecon"
+	selinuxContextOption = "context="
+)
+
+var (
+	procPath           = "/proc"
+	procMountsPath     = "/proc/self/mounts"
+	bindMountStatePath = "/var/lib/hpe-storage/chapi/bindmounts"
+
+	// getFileSystemIdentity probes the file system UUID and label of a device; a variable so that
+	// tests can replace it
+	getFileSystemIdentity = linux.GetFilesystemIdentity
+
+	// readKernelLog returns the kernel log; a variable so that tests can replace it
+	readKernelLog = func() (string, error) {
+		out, _, err := util.ExecCommandOutput(dmesgCommand, nil)
+		return out, err
+	}
+
+	// kernelLogLinePattern matches a timestamped kernel log line, e.g.
+	// "[  812.113401] XFS (dm-3): Starting recovery (logdev: internal)"
+	kernelLogLinePattern = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]\s*(.*)$`)
 )
 
 // getMounts enumerates the mountpoints for the given device / mount point.  The following input
@@ -26,23 +77,528 @@ import (
 // dismounted objects are returned.  Being able to enumerate Mount objects that are not mounted is
 // important because it provides details about the potential mount point.  For example, under
 // Windows, this includes disk and partition details that are needed in order to mount a volume.
+//
+// Under Linux, the file system is created on the whole multipath device, so each device has a
+// single potential mount point.  The managed bind mounts of a device (see mount_bind.go) share its
+// mount table source and are not reported here.
 func (mounter *Mounter) getMounts(serialNumber string, mountId string, allDetails bool, onlyMounted bool) ([]*model.Mount, error) {
-	// TODO
-	return nil, nil
+	log.Tracef(">>>>> getMounts, serialNumber=%v, mountId=%v, allDetails=%v, onlyMounted=%v", serialNumber, mountId, allDetails, onlyMounted)
+	defer log.Trace("<<<<< getMounts")
+
+	// Fail request if our Mounter object was not initialized properly
+	if mounter.multipathPlugin == nil {
+		err := cerrors.NewChapiError(cerrors.Internal, errorMessageMultipathPluginNotSet)
+		log.Error(err)
+		return nil, err
+	}
+
+	// If the caller passed in a mount point ID, with no serial number (Option #2), then log an error
+	// and recommend the caller use Option #4 instead.
+	if serialNumber == "" && mountId != "" {
+		log.Errorf("No serial number provided with mountId=%v.  A serial number is recommended to reduce the amount of enumeration this routine requires.", mountId)
+	}
+
+	// The device path is needed to find the device's mount point, so all device details are
+	// enumerated
+	devices, err := mounter.enumerateDevices(serialNumber, true)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := getMountEntries()
+	if err != nil {
+		log.Errorf("Unable to read mount table, err=%v", err)
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	var mountPoints []*model.Mount
+	for _, device := range devices {
+		if mountId != "" && mountId != getMountPointID(device.SerialNumber) {
+			continue
+		}
+		devicePath := device.AltFullPathName
+		if devicePath == "" {
+			devicePath = "/dev/" + device.Pathname
+		}
+
+		mountPointPath := getDeviceMountPoint(device, entries)
+		if onlyMounted && mountPointPath == "" {
+			continue
+		}
+		mountPoint := &model.Mount{
+			ID:      getMountPointID(device.SerialNumber),
+			Private: &model.MountPrivate{DevicePath: devicePath},
+		}
+		if allDetails {
+			mountPoint.MountPoint = mountPointPath
+			mountPoint.SerialNumber = device.SerialNumber
+		}
+		mountPoints = append(mountPoints, mountPoint)
+	}
+
+	// Log the enumerated mount points before exiting
+	logMountPoints(mountPoints, allDetails)
+	return mountPoints, nil
+}
+
+// getMountPointID returns the mount point ID of the given volume's primary mount point
+func getMountPointID(serialNumber string) string {
+	h := fnv.New64a()
+	h.Write([]byte(serialNumber))
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// getDeviceMountPoint returns the primary mount point of the given device, or an empty string if
+// the device is not mounted.  The primary mount point recorded with the device's managed bind
+// mounts is preferred; otherwise the device's first mount, that is not a managed bind mount, in
+// the mount table is returned.
+func getDeviceMountPoint(device *model.Device, entries []*util.MountEntry) string {
+	var mounted []string
+	for _, entry := range entries {
+		if isDeviceMountSource(device, entry.Source) {
+			mounted = append(mounted, entry.MountPoint)
+		}
+	}
+	if len(mounted) == 0 {
+		return ""
+	}
+
+	bindMountLock.Lock()
+	record, err := loadBindMountRecord(device.SerialNumber)
+	bindMountLock.Unlock()
+	if err != nil {
+		log.Errorf("Unable to load bind mount record of %v, err=%v", device.SerialNumber, err)
+	}
+	if record == nil {
+		return mounted[0]
+	}
+	for _, mountPoint := range mounted {
+		if mountPoint == record.MountPoint {
+			return mountPoint
+		}
+	}
+	for _, mountPoint := range mounted {
+		if record.findBindMount("", mountPoint) == nil {
+			return mountPoint
+		}
+	}
+	return ""
+}
+
+// isDeviceMountSource returns true if the given mount table source (e.g. "/dev/mapper/mpathg" or
+// "/dev/dm-3") is the given device
+func isDeviceMountSource(device *model.Device, source string) bool {
+	if !strings.HasPrefix(source, "/dev/") {
+		return false
+	}
+	if source == device.AltFullPathName {
+		return true
+	}
+	if resolved, err := filepath.EvalSymlinks(source); err == nil {
+		source = resolved
+	}
+	return filepath.Base(source) == device.Pathname
 }
 
 // createMount is called to mount the given device to the given mount point
 func (mounter *Mounter) createMount(mount *model.Mount, mountPoint string, fsOptions *model.FileSystemOptions) error {
-	// TODO
+	log.Tracef(">>>>> createMount, mountPoint=%v, fsOptions=%v", mountPoint, fsOptions)
+	defer log.Trace("<<<<< createMount")
+
+	// The device path is populated by getMounts()
+	if mount == nil || mount.Private == nil || mount.Private.DevicePath == "" {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageInvalidInputParameter)
+		log.Error(err)
+		return err
+	}
+
+	if err := os.MkdirAll(mountPoint, 0755); err != nil {
+		log.Errorf("Unable to create mount point %v, err=%v", mountPoint, err)
+		return cerrors.NewChapiError(err)
+	}
+
+	var args []string
+	if fsOptions != nil && fsOptions.FsType != "" {
+		args = append(args, "-t", fsOptions.FsType)
+	}
+	if mountOptions := getMountOptions(fsOptions); len(mountOptions) != 0 {
+		args = append(args, "-o", strings.Join(mountOptions, ","))
+	}
+	args = append(args, mount.Private.DevicePath, mountPoint)
+
+	// The mount includes the journal replay, if any (see mount_journal.go)
+	maxWait := getMountMaxWait()
+	start := time.Now()
+	out, rc, err := util.ExecCommandOutputWithTimeout(mountCommand, args, int(maxWait/time.Second))
+	if err != nil || rc != 0 {
+		log.Errorf("Failed to mount %v at %v, rc=%v, out=%v, err=%v", mount.Private.DevicePath, mountPoint, rc, out, err)
+		if time.Since(start) >= maxWait {
+			err = cerrors.NewChapiErrorf(cerrors.Timeout, errorMessageMountTimeout, mount.Private.DevicePath, maxWait)
+		} else if err == nil {
+			err = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageMountFailed, strings.TrimSpace(out))
+		}
+		return cerrors.NewChapiError(err)
+	}
+
+	// Make sure the intended file system got mounted before anything writes to it
+	if err = verifyFileSystemIdentity(mount.Private.DevicePath, mountPoint, fsOptions); err != nil {
+		return err
+	}
+
+	// Relabel the freshly mounted file system if requested
+	return relabelMount(mountPoint, fsOptions)
+}
+
+// verifyFileSystemIdentity verifies that the file system mounted from the given device has the
+// UUID and/or label expected by the file system options.  Parallel attaches can race a device
+// path to the wrong volume (e.g. a stale multipath map reusing a serial number), so a mismatch
+// unmounts the file system again and fails the request rather than leave another volume's data
+// exposed at the mount point.
+func verifyFileSystemIdentity(devicePath string, mountPoint string, fsOptions *model.FileSystemOptions) error {
+	if !hasFileSystemIdentity(fsOptions) {
+		return nil
+	}
+
+	fsUUID, fsLabel, err := getFileSystemIdentity(devicePath)
+	if err == nil {
+		err = checkFileSystemIdentity(devicePath, fsOptions, fsUUID, fsLabel)
+	}
+	if err == nil {
+		log.Tracef("Verified file system on %v, uuid=%v, label=%v", devicePath, fsUUID, fsLabel)
+		return nil
+	}
+
+	log.Errorf("Unable to verify file system mounted at %v, err=%v", mountPoint, err)
+	if unmountErr := syscall.Unmount(mountPoint, 0); unmountErr != nil {
+		log.Errorf("Failed to unmount %v, err=%v", mountPoint, unmountErr)
+	}
+	return cerrors.NewChapiError(err)
+}
+
+// getMountOptions returns the mount options for the given file system options.  If an SELinux
+// context is provided, and the file system is not going to be relabeled, the context is applied
+// to the whole mount with the "context=" mount option.
+func getMountOptions(fsOptions *model.FileSystemOptions) []string {
+	if fsOptions == nil {
+		return nil
+	}
+	mountOptions := append([]string{}, fsOptions.MountOpts...)
+	if fsOptions.SELinuxContext == "" || fsOptions.SELinuxRelabel {
+		return mountOptions
+	}
+	for _, option := range mountOptions {
+		if strings.HasPrefix(option, selinuxContextOption) {
+			// Caller already provided a context mount option; leave it alone
+			return mountOptions
+		}
+	}
+	// MCS categories (e.g. "s0:c1,c2") contain commas so the context must be quoted
+	return append(mountOptions, fmt.Sprintf(`%v"%v"`, selinuxContextOption, fsOptions.SELinuxContext))
+}
+
+// relabelMount relabels the files on the given mount point if SELinuxRelabel is set; with chcon
+// if an SELinux context is provided, else with restorecon using the host's policy defaults.
+func relabelMount(mountPoint string, fsOptions *model.FileSystemOptions) error {
+	if fsOptions == nil || !fsOptions.SELinuxRelabel {
+		return nil
+	}
+
+	command, args := restoreconCommand, []string{"-R", mountPoint}
+	if fsOptions.SELinuxContext != "" {
+		command, args = chconCommand, []string{"-R", fsOptions.SELinuxContext, mountPoint}
+	}
+
+	log.Infof("Relabeling %v with %v %v", mountPoint, command, strings.Join(args, " "))
+	out, rc, err := util.ExecCommandOutput(command, args)
+	if err != nil || rc != 0 {
+		log.Errorf("Failed to relabel %v, rc=%v, out=%v, err=%v", mountPoint, rc, out, err)
+		if err == nil {
+			err = cerrors.NewChapiErrorf(cerrors.Internal, errorMessageRelabelFailed, mountPoint, strings.TrimSpace(out))
+		}
+		return cerrors.NewChapiError(err)
+	}
 	return nil
 }
 
-// deleteMount is called to unmount the given mount point ID
-func (mounter *Mounter) deleteMount(mount *model.Mount) error {
-	// TODO
+// deleteMount is called to unmount the given mount point ID.  If lazy is true, the file system is
+// detached immediately and cleaned up by the kernel once it is no longer busy (umount -l).
+func (mounter *Mounter) deleteMount(mount *model.Mount, lazy bool) error {
+	log.Tracef(">>>>> deleteMount, mountPoint=%v, lazy=%v", mount.MountPoint, lazy)
+	defer log.Trace("<<<<< deleteMount")
+
+	flags := 0
+	if lazy {
+		flags = syscall.MNT_DETACH
+	}
+
+	err := syscall.Unmount(mount.MountPoint, flags)
+	if err == nil {
+		return nil
+	}
+
+	// If the mount point is busy, report which processes are holding it so the caller can decide
+	// whether to stop them or retry with a lazy unmount instead of blindly retrying.
+	if err == syscall.EBUSY {
+		blockers := getMountBlockers(mount.MountPoint)
+		busyErr := cerrors.NewChapiErrorf(cerrors.Busy, errorMessageMountPointBusy, mount.MountPoint, len(blockers))
+		log.Errorf("%v, blockers=%v", busyErr, blockers)
+		return busyErr.WithDetails(blockers)
+	}
+
+	log.Errorf("Failed to unmount %v, err=%v", mount.MountPoint, err)
+	return cerrors.NewChapiError(err)
+}
+
+// unmount unmounts the file system mounted at the given path; EINVAL is returned by the kernel if
+// the path is not mounted
+func (mounter *Mounter) unmount(mountPoint string) error {
+	if err := syscall.Unmount(mountPoint, 0); err != nil && err != syscall.EINVAL {
+		log.Errorf("Failed to unmount %v, err=%v", mountPoint, err)
+		return cerrors.NewChapiError(err)
+	}
 	return nil
 }
 
+// moveMount moves the given mount point to the new mount point path, creating the new directory
+// if needed.  If the kernel refuses to move the mount (e.g. its parent mount is shared), the mount
+// point is bind mounted to the new path and the old mount point is unmounted instead.
+func (mounter *Mounter) moveMount(mount *model.Mount, mountPoint string) error {
+	log.Tracef(">>>>> moveMount, mountPoint=%v, newMountPoint=%v", mount.MountPoint, mountPoint)
+	defer log.Trace("<<<<< moveMount")
+
+	createdMountDirectory := false
+	if _, err := os.Stat(mountPoint); os.IsNotExist(err) {
+		if err = os.MkdirAll(mountPoint, 0755); err != nil {
+			log.Errorf("Unable to create mount point directory %v, err=%v", mountPoint, err)
+			return cerrors.NewChapiError(err)
+		}
+		createdMountDirectory = true
+	}
+
+	err := syscall.Mount(mount.MountPoint, mountPoint, "", syscall.MS_MOVE, "")
+	if err == syscall.EINVAL {
+		log.Tracef("Unable to move %v, bind mounting to %v instead", mount.MountPoint, mountPoint)
+		if err = syscall.Mount(mount.MountPoint, mountPoint, "", syscall.MS_BIND, ""); err == nil {
+			if unmountErr := mounter.deleteMount(&model.Mount{MountPoint: mount.MountPoint}, false); unmountErr != nil {
+				// Leave the volume at its old mount point only
+				if err = syscall.Unmount(mountPoint, 0); err != nil {
+					log.Errorf("Unable to unmount %v, err=%v", mountPoint, err)
+				} else if createdMountDirectory {
+					os.Remove(mountPoint)
+				}
+				return unmountErr
+			}
+		}
+	}
+	if err != nil {
+		log.Errorf("Failed to move mount point %v to %v, err=%v", mount.MountPoint, mountPoint, err)
+		if createdMountDirectory {
+			os.Remove(mountPoint)
+		}
+		return cerrors.NewChapiError(err)
+	}
+
+	// The old mount point directory is no longer needed
+	if err = os.Remove(mount.MountPoint); err != nil {
+		log.Errorf("Unable to remove mount point directory %v, err=%v", mount.MountPoint, err)
+	}
+	return nil
+}
+
+// createBindMount bind mounts the source mount point to the target path, creating the target
+// directory if needed.  A read-only bind mount is remounted read-only once bound since the kernel
+// ignores MS_RDONLY when the bind mount is created.
+func createBindMount(source string, target string, readOnly bool) error {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		log.Errorf("Unable to create bind mount directory %v, err=%v", target, err)
+		return cerrors.NewChapiError(err)
+	}
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND, ""); err != nil {
+		log.Errorf("Failed to bind mount %v to %v, err=%v", source, target, err)
+		return cerrors.NewChapiError(err)
+	}
+	if readOnly {
+		if err := syscall.Mount("", target, "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY, ""); err != nil {
+			log.Errorf("Failed to remount bind mount %v read-only, err=%v", target, err)
+			syscall.Unmount(target, 0)
+			return cerrors.NewChapiError(err)
+		}
+	}
+	return nil
+}
+
+// findOrphanedMounts returns the orphaned managed mount points directly beneath the given mount
+// root.  A managed mount point is orphaned if it is not mounted and is empty, or if it is still
+// mounted but its block device no longer exists.
+func findOrphanedMounts(root string, managedMountPoints []string) ([]*model.OrphanedMount, error) {
+	log.Tracef(">>>>> findOrphanedMounts, root=%v", root)
+	defer log.Trace("<<<<< findOrphanedMounts")
+
+	// os.ReadDir does not stat each entry; stat of a mount point on a missing device can fail
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	mountTable, err := getMountTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []*model.OrphanedMount
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		if !isManagedMountPoint(path, managedMountPoints) {
+			continue
+		}
+		if source, mounted := mountTable[path]; mounted {
+			if strings.HasPrefix(source, "/dev/") {
+				if _, err := os.Stat(source); os.IsNotExist(err) {
+					orphans = append(orphans, &model.OrphanedMount{Path: path, Reason: model.OrphanReasonMissingDevice})
+				}
+			}
+			continue
+		}
+		if !entry.IsDir() {
+			continue
+		}
+		if empty, _ := isEmptyDirectory(path); empty {
+			orphans = append(orphans, &model.OrphanedMount{Path: path, Reason: model.OrphanReasonEmptyDirectory})
+		}
+	}
+	return orphans, nil
+}
+
+// removeOrphanedMount detaches the orphaned mount point, if still mounted, and removes the (empty)
+// mount point directory
+func removeOrphanedMount(orphan *model.OrphanedMount) error {
+	if orphan.Reason == model.OrphanReasonMissingDevice {
+		// The device is gone so the file system cannot be flushed; detach it lazily
+		if err := syscall.Unmount(orphan.Path, syscall.MNT_DETACH); err != nil && err != syscall.EINVAL {
+			return err
+		}
+	}
+	return os.Remove(orphan.Path)
+}
+
+// getMountTable returns the mounted file systems, as a map of mount point to mount source
+func getMountTable() (map[string]string, error) {
+	entries, err := getMountEntries()
+	if err != nil {
+		return nil, err
+	}
+	mountTable := make(map[string]string)
+	for _, entry := range entries {
+		mountTable[entry.MountPoint] = entry.Source
+	}
+	return mountTable, nil
+}
+
+// getMountEntries returns the mounted file systems in mount order
+func getMountEntries() ([]*util.MountEntry, error) {
+	data, err := ioutil.ReadFile(procMountsPath)
+	if err != nil {
+		return nil, err
+	}
+	return util.ParseMountOutput(string(data)), nil
+}
+
+// getActiveMountOptions returns the mount options in effect, from the mount table, of the given
+// mount points keyed by mount point ID.  If a mount point is mounted over, the options of the top
+// most mount are returned.
+func getActiveMountOptions(mounts []*model.Mount) map[string][]string {
+	activeOptions := make(map[string][]string)
+	data, err := ioutil.ReadFile(procMountsPath)
+	if err != nil {
+		log.Errorf("Unable to read mount table, err=%v", err)
+		return activeOptions
+	}
+	entries := util.ParseMountOutput(string(data))
+	for _, mount := range mounts {
+		for _, entry := range entries {
+			if mount.MountPoint != "" && isSamePathName(entry.MountPoint, mount.MountPoint) {
+				activeOptions[mount.ID] = entry.Options
+			}
+		}
+	}
+	return activeOptions
+}
+
+// getMountBlockers walks the process table and returns every process with an open file, current
+// working directory or root directory on the given mount point.
+func getMountBlockers(mountPoint string) []*model.MountBlocker {
+	log.Tracef(">>>>> getMountBlockers, mountPoint=%v", mountPoint)
+	defer log.Trace("<<<<< getMountBlockers")
+
+	var blockers []*model.MountBlocker
+	processes, err := ioutil.ReadDir(procPath)
+	if err != nil {
+		log.Errorf("Unable to enumerate processes, err=%v", err)
+		return blockers
+	}
+
+	for _, process := range processes {
+		pid, err := strconv.Atoi(process.Name())
+		if err != nil || !process.IsDir() {
+			continue
+		}
+		if path := getProcessPathOnMount(filepath.Join(procPath, process.Name()), mountPoint); path != "" {
+			blockers = append(blockers, &model.MountBlocker{
+				PID:     pid,
+				Command: getProcessCommand(filepath.Join(procPath, process.Name())),
+				User:    getProcessUser(filepath.Join(procPath, process.Name())),
+				Path:    path,
+			})
+		}
+	}
+	return blockers
+}
+
+// getProcessPathOnMount returns the first path, held by the process, that resides on the given
+// mount point (or an empty string if the process is not using the mount point)
+func getProcessPathOnMount(processDir string, mountPoint string) string {
+	links := []string{filepath.Join(processDir, "cwd"), filepath.Join(processDir, "root")}
+	if fds, err := ioutil.ReadDir(filepath.Join(processDir, "fd")); err == nil {
+		for _, fd := range fds {
+			links = append(links, filepath.Join(processDir, "fd", fd.Name()))
+		}
+	}
+	for _, link := range links {
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		if target == mountPoint || strings.HasPrefix(target, strings.TrimSuffix(mountPoint, "/")+"/") {
+			return target
+		}
+	}
+	return ""
+}
+
+// getProcessCommand returns the process name
+func getProcessCommand(processDir string) string {
+	comm, err := ioutil.ReadFile(filepath.Join(processDir, "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+// getProcessUser returns the user name of the process owner (or the uid if it cannot be resolved)
+func getProcessUser(processDir string) string {
+	info, err := os.Stat(processDir)
+	if err != nil {
+		return ""
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	uid := strconv.FormatUint(uint64(stat.Uid), 10)
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
+
 // isSamePathName returns true if the two provided directory paths are equal else false.  Under
 // Linux we perform a case sensitive comparison.  Under Windows, it's case insensitive.  This
 // routine assumes that the caller (likely platform independent caller) has already retrieved the
@@ -50,3 +606,177 @@ func (mounter *Mounter) deleteMount(mount *model.Mount) error {
 func isSamePathName(path1, path2 string) bool {
 	return path1 == path2
 }
+
+// freezeFileSystem freezes the file system mounted at the given mount point.  New writes block
+// until the file system is thawed.
+func freezeFileSystem(mountPoint string) (string, error) {
+	if _, _, err := util.ExecCommandOutput(fsfreezeCommand, []string{"--freeze", mountPoint}); err != nil {
+		log.Errorf("Unable to freeze %v, err=%v", mountPoint, err)
+		return "", cerrors.NewChapiError(err)
+	}
+	return model.QuiesceMethodFreeze, nil
+}
+
+// thawFileSystem thaws the file system frozen by freezeFileSystem
+func thawFileSystem(mountPoint string, method string) error {
+	if _, _, err := util.ExecCommandOutput(fsfreezeCommand, []string{"--unfreeze", mountPoint}); err != nil {
+		log.Errorf("Unable to thaw %v, err=%v", mountPoint, err)
+		return cerrors.NewChapiError(err)
+	}
+	return nil
+}
+
+// createVolumePathMount is only applicable to Windows; Linux has no volume GUID paths
+func (mounter *Mounter) createVolumePathMount(serialNumber string, fsOptions *model.FileSystemOptions) (*model.Mount, error) {
+	err := cerrors.NewChapiError(cerrors.Unimplemented, errorMessageVolumePathUnsupported)
+	log.Error(err)
+	return nil, err
+}
+
+// getFreeDriveLetters is only applicable to Windows; Linux has no drive letters
+func getFreeDriveLetters(pool []string) ([]string, error) {
+	err := cerrors.NewChapiError(cerrors.Unimplemented, errorMessageDriveLettersUnsupported)
+	log.Error(err)
+	return nil, err
+}
+
+// getJournalReplayMessages returns the xfs and ext4 journal replay (log recovery) messages the
+// kernel logged for the given mount's device since the given time
+func getJournalReplayMessages(mount *model.Mount, since time.Time) ([]string, error) {
+	if mount.Private == nil || mount.Private.DevicePath == "" {
+		return nil, nil
+	}
+
+	// The kernel identifies the device by its kernel name (e.g. "dm-3" for /dev/mapper/mpathg)
+	devicePath := mount.Private.DevicePath
+	if resolvedPath, err := filepath.EvalSymlinks(devicePath); err == nil {
+		devicePath = resolvedPath
+	}
+
+	// Kernel log timestamps are seconds since boot
+	uptime, err := ioutil.ReadFile(filepath.Join(procPath, "uptime"))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(uptime))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid uptime %q", string(uptime))
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	kernelLog, err := readKernelLog()
+	if err != nil {
+		return nil, err
+	}
+	return parseJournalReplayMessages(kernelLog, filepath.Base(devicePath), seconds-time.Since(since).Seconds()), nil
+}
+
+// parseJournalReplayMessages returns the journal replay messages, of the given device, in the
+// given kernel log that were logged at or after the given time (in seconds since boot), e.g.
+//
+//	XFS (dm-3): Starting recovery (logdev: internal)
+//	XFS (dm-3): Ending recovery (logdev: internal)
+//	EXT4-fs (dm-4): recovery complete
+func parseJournalReplayMessages(kernelLog string, deviceName string, since float64) []string {
+	prefixes := []string{"XFS (" + deviceName + "): ", "EXT4-fs (" + deviceName + "): "}
+	var messages []string
+	for _, line := range strings.Split(kernelLog, "\n") {
+		match := kernelLogLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		if timestamp, err := strconv.ParseFloat(match[1], 64); err != nil || timestamp < since {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(match[2], prefix) && strings.Contains(strings.ToLower(match[2][len(prefix):]), "recover") {
+				messages = append(messages, match[2])
+			}
+		}
+	}
+	return messages
+}
+
+// isWriteProtectError returns true if the error reports a read-only file system
+func isWriteProtectError(err error) bool {
+	return err == syscall.EROFS
+}
+
+// hideProbeFile hides the probe file written to verify a mount; under Linux, its leading dot
+// already hides it
+func hideProbeFile(probePath string) {
+}
+
+// checkOwnerWritable checks that the given file system owner ("user:group", by name or ID) can
+// write to the mount point according to its permission bits.  Only the owner's primary group (or
+// the group provided) is considered; supplementary groups and ACLs are not.
+func checkOwnerWritable(mountPoint string, fsOwner string) error {
+	uid, gid, err := lookupFsOwner(fsOwner)
+	if err != nil {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidFsOwner, fsOwner, err)
+	}
+	info, err := os.Stat(mountPoint)
+	if err != nil {
+		return cerrors.NewChapiError(err)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || uid == 0 {
+		return nil
+	}
+
+	mode := info.Mode().Perm()
+	writeBit := os.FileMode(0002)
+	if uid == stat.Uid {
+		writeBit = 0200
+	} else if gid == stat.Gid {
+		writeBit = 0020
+	}
+	if mode&writeBit == 0 {
+		return cerrors.NewChapiErrorf(cerrors.PermissionDenied, errorMessageOwnerNotWritable, mountPoint, fsOwner, mode, stat.Uid, stat.Gid)
+	}
+	return nil
+}
+
+// lookupFsOwner returns the user and group IDs of the given file system owner ("user:group" or
+// "user", by name or ID).  The user's primary group is used if no group is provided.
+func lookupFsOwner(fsOwner string) (uid uint32, gid uint32, err error) {
+	owner := strings.SplitN(fsOwner, ":", 2)
+	userID := owner[0]
+	if _, err = strconv.ParseUint(userID, 10, 32); err != nil {
+		u, err := user.Lookup(userID)
+		if err != nil {
+			return 0, 0, err
+		}
+		userID = u.Uid
+	}
+	groupID := ""
+	if len(owner) == 2 && owner[1] != "" {
+		groupID = owner[1]
+		if _, err = strconv.ParseUint(groupID, 10, 32); err != nil {
+			g, err := user.LookupGroup(groupID)
+			if err != nil {
+				return 0, 0, err
+			}
+			groupID = g.Gid
+		}
+	} else if u, err := user.LookupId(userID); err == nil {
+		groupID = u.Gid
+	}
+
+	id, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	uid = uint32(id)
+	gid = ^uint32(0) // no group
+	if groupID != "" {
+		if id, err = strconv.ParseUint(groupID, 10, 32); err != nil {
+			return 0, 0, err
+		}
+		gid = uint32(id)
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,663 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/util"
+)
+
+func TestGetMountBlockers(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "mountblockers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Build a fake process table; pid 100 has a file open on the mount point, pid 200 has its
+	// working directory on a sibling mount point with the same prefix and pid 300 is idle.
+	mountPoint := filepath.Join(tempDir, "mnt", "vol1")
+	fakeProcess(t, tempDir, "100", "bash", filepath.Join(mountPoint, "data.db"))
+	fakeProcess(t, tempDir, "200", "sleep", mountPoint+"0")
+	fakeProcess(t, tempDir, "300", "idle", "")
+	if err = os.MkdirAll(filepath.Join(tempDir, "proc", "self"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	savedProcPath := procPath
+	procPath = filepath.Join(tempDir, "proc")
+	defer func() { procPath = savedProcPath }()
+
+	blockers := getMountBlockers(mountPoint)
+	if len(blockers) != 1 {
+		t.Fatalf("expected 1 blocker, got %v", len(blockers))
+	}
+	if blockers[0].PID != 100 || blockers[0].Command != "bash" || blockers[0].Path != filepath.Join(mountPoint, "data.db") {
+		t.Errorf("unexpected blocker %+v", blockers[0])
+	}
+}
+
+// fakeProcess creates a /proc/<pid> entry whose fd/3 symlink points to openFile
+func fakeProcess(t *testing.T, root, pid, command, openFile string) {
+	processDir := filepath.Join(root, "proc", pid)
+	if err := os.MkdirAll(filepath.Join(processDir, "fd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(processDir, "comm"), []byte(command+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if openFile != "" {
+		if err := os.Symlink(openFile, filepath.Join(processDir, "fd", "3")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBindMountReferences(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "bindmounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	savedStatePath := bindMountStatePath
+	bindMountStatePath = filepath.Join(tempDir, "state")
+	defer func() { bindMountStatePath = savedStatePath }()
+
+	const serialNumber = "abc123"
+	primary := &model.Mount{ID: "primary", MountPoint: "/mnt/primary", SerialNumber: serialNumber}
+	bind := &bindMount{ID: getBindMountID(serialNumber, "/mnt/pod1"), MountPoint: "/mnt/pod1"}
+	record := &bindMountRecord{SerialNumber: serialNumber, MountPoint: primary.MountPoint, BindMounts: []*bindMount{bind}}
+	if err = saveBindMountRecord(record); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bind mount is reported alongside the primary mount point
+	mounts := addBindMounts([]*model.Mount{primary}, serialNumber, "", true)
+	if len(mounts) != 2 || mounts[1].ID != bind.ID || mounts[1].MountPoint != bind.MountPoint {
+		t.Fatalf("unexpected mounts %+v", mounts)
+	}
+	if len(primary.BindMounts) != 1 || primary.BindMounts[0] != bind.MountPoint {
+		t.Errorf("unexpected primary bind mounts %v", primary.BindMounts)
+	}
+
+	// The primary mount point cannot be deleted while referenced
+	err = checkBindMountReferences(primary)
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.Busy {
+		t.Errorf("expected Busy error, got %v", err)
+	}
+
+	// Bind mounts are found by mount point ID without the serial number; the bind mount path
+	// doesn't exist so its unmount fails
+	mounter := &Mounter{}
+	if handled, err := mounter.deleteManagedBindMount("", bind.ID, false); !handled || err == nil {
+		t.Errorf("expected the bind mount to be handled and its unmount to fail, handled=%v, err=%v", handled, err)
+	}
+	if handled, err := mounter.deleteManagedBindMount("", primary.ID, false); handled || err != nil {
+		t.Errorf("expected the primary mount point not to be handled, handled=%v, err=%v", handled, err)
+	}
+
+	// Once the last bind mount is removed, the record is deleted and the primary is unreferenced
+	record.BindMounts = nil
+	if err = saveBindMountRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkBindMountReferences(primary); err != nil {
+		t.Errorf("expected no references, got %v", err)
+	}
+	if record, err = loadBindMountRecord(serialNumber); record != nil || err != nil {
+		t.Errorf("expected record to be removed, got %+v, err=%v", record, err)
+	}
+}
+
+func TestGetMountOptions(t *testing.T) {
+	const context = "system_u:object_r:container_file_t:s0:c1,c2"
+	tests := []struct {
+		fsOptions *model.FileSystemOptions
+		expected  string
+	}{
+		{nil, ""},
+		{&model.FileSystemOptions{MountOpts: []string{"rw", "nodiscard"}}, "rw,nodiscard"},
+		{&model.FileSystemOptions{MountOpts: []string{"rw"}, SELinuxContext: context}, `rw,context="` + context + `"`},
+		{&model.FileSystemOptions{MountOpts: []string{"rw"}, SELinuxContext: context, SELinuxRelabel: true}, "rw"},
+		{&model.FileSystemOptions{MountOpts: []string{`context="a:b:c:s0"`}, SELinuxContext: context}, `context="a:b:c:s0"`},
+	}
+	for _, tc := range tests {
+		if options := strings.Join(getMountOptions(tc.fsOptions), ","); options != tc.expected {
+			t.Errorf("getMountOptions(%+v) returned %v, expected %v", tc.fsOptions, options, tc.expected)
+		}
+	}
+}
+
+func TestVerifyFileSystemIdentity(t *testing.T) {
+	defer func(probe func(string) (string, string, error)) { getFileSystemIdentity = probe }(getFileSystemIdentity)
+	var probed string
+	getFileSystemIdentity = func(devicePath string) (string, string, error) {
+		probed = devicePath
+		return "63a91d01-b388-45fd-8ae3-ebe3b687200d", "data", nil
+	}
+
+	// Nothing is probed unless an identity is expected
+	if err := verifyFileSystemIdentity("/dev/mapper/mpatha", "/mnt/vol1", &model.FileSystemOptions{FsType: "xfs"}); err != nil || probed != "" {
+		t.Errorf("unexpected verification of %v, err=%v", probed, err)
+	}
+
+	tests := []struct {
+		fsOptions *model.FileSystemOptions
+		match     bool
+	}{
+		{&model.FileSystemOptions{FsUUID: "63A91D01-B388-45FD-8AE3-EBE3B687200D"}, true},
+		{&model.FileSystemOptions{FsUUID: "63a91d01-b388-45fd-8ae3-ebe3b687200d", FsLabel: "data"}, true},
+		{&model.FileSystemOptions{FsUUID: "0c5d0cd2-6f3b-4f1a-9d1c-2d3e4f5a6b7c"}, false},
+		{&model.FileSystemOptions{FsLabel: "logs"}, false},
+	}
+	mountPoint, err := ioutil.TempDir("", "mountverify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mountPoint)
+	for _, tc := range tests {
+		err := verifyFileSystemIdentity("/dev/mapper/mpatha", mountPoint, tc.fsOptions)
+		if tc.match && err != nil {
+			t.Errorf("verification of %+v failed, err=%v", tc.fsOptions, err)
+		}
+		if !tc.match && (err == nil || err.(*cerrors.ChapiError).Code != cerrors.Aborted) {
+			t.Errorf("mismatch of %+v not detected, err=%v", tc.fsOptions, err)
+		}
+	}
+	if probed != "/dev/mapper/mpatha" {
+		t.Errorf("unexpected device probed %v", probed)
+	}
+}
+
+func TestGetOrphanedMounts(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "orphans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// "empty" is an orphaned directory, "data" holds a file, "mounted" is mounted from a device
+	// that still exists and "stale" is mounted from a device that is gone.  "unrecorded" is empty
+	// but was not created by CHAPI.
+	root := filepath.Join(tempDir, "mounts")
+	for _, dir := range []string{"empty", "data", "mounted", "stale", "unrecorded"} {
+		if err = os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ioutil.WriteFile(filepath.Join(root, "data", "file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	device := filepath.Join(tempDir, "dev", "sda")
+	if err = os.MkdirAll(filepath.Dir(device), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(device, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mounts := device + " " + filepath.Join(root, "mounted") + " ext4 rw 0 0\n" +
+		"/dev/hpe-missing-device " + filepath.Join(root, "stale") + " ext4 rw 0 0\n"
+	mountsPath := filepath.Join(tempDir, "mounts.txt")
+	if err = ioutil.WriteFile(mountsPath, []byte(mounts), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	savedProcMountsPath := procMountsPath
+	savedGetManagedMountPoints := getManagedMountPoints
+	procMountsPath = mountsPath
+	getManagedMountPoints = func() ([]string, error) {
+		var mountPoints []string
+		for _, dir := range []string{"empty", "data", "mounted", "stale"} {
+			mountPoints = append(mountPoints, filepath.Join(root, dir))
+		}
+		return mountPoints, nil
+	}
+	defer func() {
+		procMountsPath = savedProcMountsPath
+		getManagedMountPoints = savedGetManagedMountPoints
+	}()
+
+	mounter := NewMounter()
+	if _, err = mounter.GetOrphanedMounts(nil, false); err == nil {
+		t.Error("expected error for missing mount root")
+	}
+	if _, err = mounter.GetOrphanedMounts([]string{"mounts"}, false); err == nil {
+		t.Error("expected error for relative mount root")
+	}
+
+	orphans, err := mounter.GetOrphanedMounts([]string{root, filepath.Join(tempDir, "missing")}, false)
+	if err != nil {
+		t.Fatalf("GetOrphanedMounts failed, err=%v", err)
+	}
+	expected := map[string]string{
+		filepath.Join(root, "empty"): model.OrphanReasonEmptyDirectory,
+		filepath.Join(root, "stale"): model.OrphanReasonMissingDevice,
+	}
+	if len(orphans) != len(expected) {
+		t.Fatalf("expected %v orphans, got %+v", len(expected), orphans)
+	}
+	for _, orphan := range orphans {
+		if expected[orphan.Path] != orphan.Reason || orphan.Removed || !orphan.Managed {
+			t.Errorf("unexpected orphan %+v", orphan)
+		}
+	}
+
+	// "stale" is not really mounted so whether its lazy unmount succeeds depends on privileges;
+	// only check that the empty directory is removed and the data directory is left alone
+	orphans, err = mounter.GetOrphanedMounts([]string{root}, true)
+	if err != nil {
+		t.Fatalf("GetOrphanedMounts failed, err=%v", err)
+	}
+	for _, orphan := range orphans {
+		if orphan.Reason == model.OrphanReasonEmptyDirectory && !orphan.Removed {
+			t.Errorf("expected %v to be removed, err=%v", orphan.Path, orphan.Error)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(root, "empty")); !os.IsNotExist(err) {
+		t.Errorf("expected empty directory to be removed")
+	}
+	if _, err = os.Stat(filepath.Join(root, "data", "file")); err != nil {
+		t.Errorf("expected data directory to be left alone, err=%v", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "unrecorded")); err != nil {
+		t.Errorf("expected unrecorded directory to be left alone, err=%v", err)
+	}
+}
+
+func TestGetMountTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mounttable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedPath := procMountsPath
+	defer func() { procMountsPath = savedPath }()
+	procMountsPath = filepath.Join(dir, "mounts")
+	mounts := "/dev/mapper/mpatha /mnt/my\\040vol xfs rw,relatime 0 0\n/dev/mapper/mpathb /mnt/vol1 ext4 rw 0 0\n"
+	if err = ioutil.WriteFile(procMountsPath, []byte(mounts), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mountTable, err := getMountTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := mountTable["/mnt/my vol"]; source != "/dev/mapper/mpatha" {
+		t.Errorf("unexpected source %q for unescaped mount point", source)
+	}
+	if source := mountTable["/mnt/vol1"]; source != "/dev/mapper/mpathb" {
+		t.Errorf("unexpected source %q", source)
+	}
+}
+
+func TestGetDeviceMountPoint(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "devicemount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	savedStatePath := bindMountStatePath
+	bindMountStatePath = filepath.Join(tempDir, "state")
+	defer func() { bindMountStatePath = savedStatePath }()
+
+	device := &model.Device{SerialNumber: "abc123", Pathname: "dm-3", AltFullPathName: "/dev/mapper/mpathg"}
+	entries := []*util.MountEntry{
+		{Source: "/dev/mapper/mpathh", MountPoint: "/mnt/other"},
+		{Source: "/dev/dm-3", MountPoint: "/mnt/pod1"},
+		{Source: "/dev/mapper/mpathg", MountPoint: "/mnt/primary"},
+	}
+
+	// Without a bind mount record, the first mount is the primary mount point
+	if mountPoint := getDeviceMountPoint(device, entries); mountPoint != "/mnt/pod1" {
+		t.Errorf("unexpected mount point %v", mountPoint)
+	}
+	if mountPoint := getDeviceMountPoint(device, entries[:1]); mountPoint != "" {
+		t.Errorf("expected the device not to be mounted, got %v", mountPoint)
+	}
+
+	// Managed bind mounts are not primary mount points
+	record := &bindMountRecord{SerialNumber: device.SerialNumber, MountPoint: "/mnt/gone", BindMounts: []*bindMount{{ID: "1-bind", MountPoint: "/mnt/pod1"}}}
+	if err = saveBindMountRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if mountPoint := getDeviceMountPoint(device, entries); mountPoint != "/mnt/primary" {
+		t.Errorf("unexpected mount point %v", mountPoint)
+	}
+
+	// The recorded primary mount point is preferred
+	record.MountPoint = "/mnt/pod1"
+	record.BindMounts[0].MountPoint = "/mnt/primary"
+	if err = saveBindMountRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if mountPoint := getDeviceMountPoint(device, entries); mountPoint != "/mnt/pod1" {
+		t.Errorf("unexpected mount point %v", mountPoint)
+	}
+}
+
+func TestQuiesceValidation(t *testing.T) {
+	mounter := NewMounter()
+	tests := []struct {
+		name     string
+		freeze   bool
+		request  *model.QuiesceRequest
+		expected cerrors.ChapiErrorCode
+	}{
+		{"freeze timeout too long", true, &model.QuiesceRequest{SerialNumber: "1234", TimeoutSeconds: 301}, cerrors.InvalidArgument},
+		{"freeze without mount", true, &model.QuiesceRequest{}, cerrors.InvalidArgument},
+		{"freeze unknown mount", true, &model.QuiesceRequest{SerialNumber: "1234"}, cerrors.NotFound},
+		{"thaw without mount", false, &model.QuiesceRequest{}, cerrors.InvalidArgument},
+	}
+	for _, tc := range tests {
+		var err error
+		if tc.freeze {
+			_, err = mounter.FreezeMount(tc.request)
+		} else {
+			err = mounter.ThawMount(tc.request)
+		}
+		chapiErr, ok := err.(*cerrors.ChapiError)
+		if !ok || chapiErr.Code != tc.expected {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		}
+	}
+
+	// Thawing a mount point that isn't frozen fails
+	if chapiErr, ok := thawMountPoint("/mnt/notfrozen").(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.NotFound {
+		t.Errorf("unexpected thaw error %v", chapiErr)
+	}
+}
+
+func TestFilterMountPointPrefix(t *testing.T) {
+	mounts := []*model.Mount{
+		{ID: "1", MountPoint: "/var/lib/kubelet/pods/1234/volumes/vol1"},
+		{ID: "2", MountPoint: "/var/lib/kubelet"},
+		{ID: "3", MountPoint: "/var/lib/kubelet2/vol3"},
+		{ID: "4", MountPoint: "/mnt/vol4"},
+		{ID: "5"},
+	}
+	testCases := []struct {
+		prefix string
+		ids    string
+	}{
+		{"/var/lib/kubelet", "1,2"},
+		{"/var/lib/kubelet/", "1,2"},
+		{"/var/lib/kubelet/pods", "1"},
+		{"/mnt", "4"},
+		{"/", "1,2,3,4"},
+		{"/opt", ""},
+	}
+	for _, testCase := range testCases {
+		var ids []string
+		for _, mount := range filterMountPointPrefix(mounts, testCase.prefix) {
+			ids = append(ids, mount.ID)
+		}
+		if strings.Join(ids, ",") != testCase.ids {
+			t.Errorf("prefix %v, expected %v, got %v", testCase.prefix, testCase.ids, ids)
+		}
+	}
+}
+
+func TestMoveMountValidation(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "movemount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	savedStatePath := bindMountStatePath
+	bindMountStatePath = filepath.Join(tempDir, "state")
+	defer func() { bindMountStatePath = savedStatePath }()
+
+	const serialNumber = "abc123"
+	primaryPath := filepath.Join(tempDir, "primary")
+	bindPath := filepath.Join(tempDir, "pod1")
+	notEmptyPath := filepath.Join(tempDir, "notempty")
+	if err = os.MkdirAll(filepath.Join(notEmptyPath, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	bind := &bindMount{ID: getBindMountID(serialNumber, bindPath), MountPoint: bindPath}
+	record := &bindMountRecord{SerialNumber: serialNumber, MountPoint: primaryPath, BindMounts: []*bindMount{bind}}
+	if err = saveBindMountRecord(record); err != nil {
+		t.Fatal(err)
+	}
+
+	mounter := NewMounter()
+	tests := []struct {
+		name       string
+		mountId    string
+		mountPoint string
+		expected   cerrors.ChapiErrorCode
+	}{
+		{"missing mount point", bind.ID, "", cerrors.InvalidArgument},
+		{"volume GUID path", bind.ID, `\\?\Volume{5a0a7a5e-2a1f-4a3b-9c1d-0e2f3a4b5c6d}\`, cerrors.InvalidArgument},
+		{"primary mount point", bind.ID, primaryPath, cerrors.AlreadyExists},
+		{"not empty", bind.ID, notEmptyPath, cerrors.AlreadyExists},
+		{"beneath itself", bind.ID, filepath.Join(bindPath, "sub"), cerrors.InvalidArgument},
+		{"unknown mount", "unknown", filepath.Join(tempDir, "pod2"), cerrors.InvalidArgument},
+	}
+	for _, tc := range tests {
+		_, err := mounter.MoveMount(serialNumber, tc.mountId, tc.mountPoint)
+		chapiErr, ok := err.(*cerrors.ChapiError)
+		if !ok || chapiErr.Code != tc.expected {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		}
+	}
+
+	// Moving a bind mount to its current path is a no-op
+	moved, err := mounter.MoveMount(serialNumber, bind.ID, bindPath)
+	if err != nil || moved.ID != bind.ID || moved.MountPoint != bindPath {
+		t.Errorf("unexpected move to current path %+v, err=%v", moved, err)
+	}
+
+	// Moving the primary mount point retargets its bind mount record
+	newPrimaryPath := filepath.Join(tempDir, "newprimary")
+	if err = retargetBindMounts(serialNumber, primaryPath, newPrimaryPath); err != nil {
+		t.Fatal(err)
+	}
+	if record, err = loadBindMountRecord(serialNumber); err != nil || record.MountPoint != newPrimaryPath || len(record.BindMounts) != 1 {
+		t.Errorf("unexpected record %+v, err=%v", record, err)
+	}
+}
+
+func TestParseJournalReplayMessages(t *testing.T) {
+	kernelLog := strings.Join([]string{
+		"[  700.000001] XFS (dm-3): Starting recovery (logdev: internal)",
+		"[  700.500000] XFS (dm-3): Ending recovery (logdev: internal)",
+		"[  812.113401] XFS (dm-3): Mounting V5 Filesystem",
+		"[  812.200000] XFS (dm-3): Starting recovery (logdev: internal)",
+		"[  812.300000] XFS (dm-30): Starting recovery (logdev: internal)",
+		"[  815.722108] XFS (dm-3): Ending recovery (logdev: internal)",
+		"[  901.004311] EXT4-fs (dm-4): recovery complete",
+		"XFS (dm-3): Starting recovery (logdev: internal)",
+	}, "\n")
+
+	// Only the recovery messages of the device, logged since the mount started, are reported
+	expected := []string{
+		"XFS (dm-3): Starting recovery (logdev: internal)",
+		"XFS (dm-3): Ending recovery (logdev: internal)",
+	}
+	if messages := parseJournalReplayMessages(kernelLog, "dm-3", 800); !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected messages %v, got %v", expected, messages)
+	}
+	if messages := parseJournalReplayMessages(kernelLog, "dm-4", 800); len(messages) != 1 {
+		t.Errorf("expected 1 ext4 message, got %v", messages)
+	}
+	if messages := parseJournalReplayMessages(kernelLog, "dm-5", 0); len(messages) != 0 {
+		t.Errorf("expected no messages, got %v", messages)
+	}
+}
+
+func TestVerifyMountWritable(t *testing.T) {
+	mountPoint, err := ioutil.TempDir("", "mountwritable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mountPoint)
+
+	// Not requested, writable, and read-only mounts that are not verified
+	for _, fsOptions := range []*model.FileSystemOptions{
+		nil,
+		{},
+		{VerifyWritable: true},
+		{VerifyWritable: true, FsOwner: "0:0"},
+		{VerifyWritable: true, MountOpts: []string{"ro"}, FsOwner: "12345:12345"},
+	} {
+		if err = verifyMountWritable(mountPoint, fsOptions); err != nil {
+			t.Errorf("verifyMountWritable(%+v) failed, err=%v", fsOptions, err)
+		}
+	}
+	if files, _ := ioutil.ReadDir(mountPoint); len(files) != 0 {
+		t.Errorf("probe file left at the mount point, files=%v", files)
+	}
+
+	// The mount point permissions are checked for the file system owner
+	tests := []struct {
+		mode     os.FileMode
+		fsOwner  string
+		expected cerrors.ChapiErrorCode
+	}{
+		{0755, "12345:12345", cerrors.PermissionDenied},
+		{0777, "12345:12345", cerrors.OK},
+		{0755, "", cerrors.OK},
+		{0755, "no-such-user-chapi:0", cerrors.InvalidArgument},
+	}
+	for _, tc := range tests {
+		if err = os.Chmod(mountPoint, tc.mode); err != nil {
+			t.Fatal(err)
+		}
+		if os.Getuid() != 0 && tc.fsOwner != "" {
+			continue // the owner check depends on the mount point being owned by root
+		}
+		err = verifyMountWritable(mountPoint, &model.FileSystemOptions{VerifyWritable: true, FsOwner: tc.fsOwner})
+		code := cerrors.OK
+		if chapiErr, ok := err.(*cerrors.ChapiError); ok {
+			code = chapiErr.Code
+		} else if err != nil {
+			code = cerrors.Unknown
+		}
+		if code != tc.expected {
+			t.Errorf("mode=%v, fsOwner=%q: expected %v, got %v", tc.mode, tc.fsOwner, tc.expected, err)
+		}
+	}
+
+	// A missing mount point cannot be written to
+	err = verifyMountWritable(filepath.Join(mountPoint, "missing"), &model.FileSystemOptions{VerifyWritable: true})
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.Internal {
+		t.Errorf("missing mount point: expected Internal, got %v", err)
+	}
+}
+
+func TestLookupFsOwner(t *testing.T) {
+	tests := []struct {
+		fsOwner string
+		uid     uint32
+		gid     uint32
+		valid   bool
+	}{
+		{"1000:1001", 1000, 1001, true},
+		{"root:root", 0, 0, true},
+		{"root", 0, 0, true},
+		{"1000:no-such-group-chapi", 0, 0, false},
+	}
+	for _, tc := range tests {
+		uid, gid, err := lookupFsOwner(tc.fsOwner)
+		if (err == nil) != tc.valid || (tc.valid && (uid != tc.uid || gid != tc.gid)) {
+			t.Errorf("lookupFsOwner(%q) = %v, %v, err=%v", tc.fsOwner, uid, gid, err)
+		}
+	}
+}
+
+func TestStagedMountValidation(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "stagedmount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	savedStatePath := bindMountStatePath
+	bindMountStatePath = filepath.Join(tempDir, "state")
+	defer func() { bindMountStatePath = savedStatePath }()
+
+	const serialNumber = "abc123"
+	stagingPath := filepath.Join(tempDir, "globalmount")
+	targetPath := filepath.Join(tempDir, "pod1")
+	staged := &model.Mount{ID: "staged", SerialNumber: serialNumber, MountPoint: stagingPath}
+	savedGetStagedMount := getStagedMount
+	getStagedMount = func(mounter *Mounter, serialNumber string) (*model.Mount, error) { return staged, nil }
+	defer func() { getStagedMount = savedGetStagedMount }()
+
+	bind := &bindMount{ID: getBindMountID(serialNumber, targetPath), MountPoint: targetPath, ReadOnly: true}
+	record := &bindMountRecord{SerialNumber: serialNumber, MountPoint: stagingPath, BindMounts: []*bindMount{bind}}
+	if err = saveBindMountRecord(record); err != nil {
+		t.Fatal(err)
+	}
+
+	mounter := NewMounter()
+	otherPath := filepath.Join(tempDir, "other")
+	tests := []struct {
+		name         string
+		operation    string
+		serialNumber string
+		stagingPath  string
+		targetPath   string
+		readOnly     bool
+		expected     cerrors.ChapiErrorCode
+	}{
+		{"stage missing serial number", "stage", "", stagingPath, "", false, cerrors.InvalidArgument},
+		{"stage missing staging path", "stage", serialNumber, "", "", false, cerrors.InvalidArgument},
+		{"staged elsewhere", "stage", serialNumber, otherPath, "", false, cerrors.AlreadyExists},
+		{"publish missing target path", "publish", serialNumber, stagingPath, "", false, cerrors.InvalidArgument},
+		{"publish to staging path", "publish", serialNumber, stagingPath, stagingPath, false, cerrors.InvalidArgument},
+		{"publish not staged", "publish", serialNumber, otherPath, targetPath, false, cerrors.NotFound},
+		{"publish read only differs", "publish", serialNumber, stagingPath, targetPath, false, cerrors.AlreadyExists},
+		{"unpublish missing target path", "unpublish", serialNumber, "", "", false, cerrors.InvalidArgument},
+		{"unstage published", "unstage", serialNumber, stagingPath, "", false, cerrors.Busy},
+	}
+	for _, tc := range tests {
+		var err error
+		switch tc.operation {
+		case "stage":
+			_, err = mounter.StageMount(tc.serialNumber, tc.stagingPath, nil)
+		case "publish":
+			_, err = mounter.PublishMount(tc.serialNumber, tc.stagingPath, tc.targetPath, tc.readOnly)
+		case "unpublish":
+			_, err = mounter.UnpublishMount(tc.serialNumber, tc.targetPath)
+		case "unstage":
+			_, err = mounter.UnstageMount(tc.serialNumber, tc.stagingPath)
+		}
+		chapiErr, ok := err.(*cerrors.ChapiError)
+		if !ok || chapiErr.Code != tc.expected {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		}
+	}
+
+	// Publishing again at the same target path returns the existing bind mount
+	published, err := mounter.PublishMount(serialNumber, stagingPath, targetPath, true)
+	if err != nil || published.ID != bind.ID || published.MountPoint != targetPath {
+		t.Errorf("unexpected publish to current target path %+v, err=%v", published, err)
+	}
+
+	// Unpublishing or unstaging a path that is not mounted succeeds
+	if unpublished, err := mounter.UnpublishMount(serialNumber, otherPath); unpublished != nil || err != nil {
+		t.Errorf("unexpected unpublish of unknown target path %+v, err=%v", unpublished, err)
+	}
+	if unstaged, err := mounter.UnstageMount(serialNumber, otherPath); unstaged != nil || err != nil {
+		t.Errorf("unexpected unstage of unknown staging path %+v, err=%v", unstaged, err)
+	}
+
+	// The staged mount point reports the target paths it is published to
+	if targetPaths := getPublishedPaths(serialNumber, stagingPath); !reflect.DeepEqual(targetPaths, []string{targetPath}) {
+		t.Errorf("unexpected published paths %v", targetPaths)
+	}
+}
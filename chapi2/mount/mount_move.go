@@ -0,0 +1,212 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// MOVE MOUNT POINTS
+//
+//		MoveMount reassigns a mounted volume to a new mount point without unmounting it from the
+//		host, so that applications holding files open on the volume are not interrupted.  The new
+//		path is made available before the old one is removed; if the old path cannot be removed,
+//		the new path is removed again and the mount point is left where it was.
+//
+//		Under Linux the mount is moved with MS_MOVE (mount --move).  The kernel refuses to move a
+//		mount whose parent mount is shared, in which case the old mount point is bind mounted to
+//		the new path and then unmounted; a busy old mount point fails the request with the
+//		processes holding it as details.  Under Windows the new access path is added to the
+//		partition (Add-PartitionAccessPath) before the old one is removed
+//		(Remove-PartitionAccessPath).
+//
+//		Managed bind mounts (see mount_bind.go) can be moved as well; their mount point ID is
+//		derived from their path so the moved mount is returned with its new ID.  Moving a
+//		primary mount point updates the bind mount record that references it.  Volume GUID path
+//		mounts cannot be moved; CreateMount replaces them with a drive letter or directory.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	errorMessageMoveBeneathMountPoint = `mount point "%v" cannot be moved beneath itself to "%v"`
+	errorMessageMoveVolumePath        = `volume GUID path "%v" cannot be moved, create a mount point instead`
+)
+
+// MoveMount moves the given mount point ID to the given mount point path without unmounting the
+// volume.  The moved mount point is returned.
+func (mounter *Mounter) MoveMount(serialNumber string, mountId string, mountPoint string) (*model.Mount, error) {
+	log.Tracef(">>>>> MoveMount, serialNumber=%v, mountId=%v, mountPoint=%v", serialNumber, mountId, mountPoint)
+	defer log.Trace("<<<<< MoveMount")
+
+	// Validate and adjust the new mount point path
+	mountPoint, err := getMoveMountPoint(mountPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Managed bind mounts are tracked by CHAPI and moved along with their record
+	if moved, handled, err := mounter.moveManagedBindMount(serialNumber, mountId, mountPoint); handled {
+		return moved, err
+	}
+
+	// Validate and enumerate the mount object for the given serial number and mount point ID
+	mount, err := mounter.getMountForDelete(serialNumber, mountId)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the volume is already mounted at the requested mount point, there is nothing to do
+	if isSamePathName(mount.MountPoint, mountPoint) {
+		return mount, nil
+	}
+	if isVolumeGUIDPath(mount.MountPoint) {
+		err = cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMoveVolumePath, mount.MountPoint)
+		log.Error(err)
+		return nil, err
+	}
+	if err = validateMoveTarget(mount.MountPoint, mountPoint); err != nil {
+		return nil, err
+	}
+
+	// Call the platform specific moveMount routine to move the mount point
+	if err = mounter.moveMount(mount, mountPoint); err != nil {
+		return nil, err
+	}
+
+	// Bind mounts of the volume now reference the new primary mount point
+	if err = retargetBindMounts(mount.SerialNumber, mount.MountPoint, mountPoint); err != nil {
+		log.Errorf("Unable to update bind mount record of %v, err=%v", mount.SerialNumber, err)
+	}
+
+	log.Infof("Mount point ID %v moved from %v to %v", mount.ID, mount.MountPoint, mountPoint)
+	moved := *mount
+	moved.MountPoint = mountPoint
+	return &moved, nil
+}
+
+// getMoveMountPoint validates the requested mount point path and returns its absolute path
+func getMoveMountPoint(mountPoint string) (string, error) {
+	if mountPoint == "" {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMissingMountPoint)
+		log.Error(err)
+		return "", err
+	}
+	if isVolumeGUIDPath(mountPoint) {
+		err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMoveVolumePath, mountPoint)
+		log.Error(err)
+		return "", err
+	}
+	if isWindowsDriveLetterPath(mountPoint) {
+		return mountPoint, nil
+	}
+	absPath, err := filepath.Abs(mountPoint)
+	if err != nil {
+		log.Errorf("Invalid requested mount point, MountPoint=%v, err=%v", mountPoint, err)
+		return "", cerrors.NewChapiError(cerrors.InvalidArgument, err.Error())
+	}
+	return absPath, nil
+}
+
+// validateMoveTarget fails the request if the current mount point cannot be moved to the given
+// mount point path; the new path must not be beneath the current mount point, a drive letter
+// must be free and a directory must be empty
+func validateMoveTarget(currentMountPoint string, mountPoint string) error {
+	if isPathPrefix(currentMountPoint, mountPoint) {
+		err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMoveBeneathMountPoint, currentMountPoint, mountPoint)
+		log.Error(err)
+		return err
+	}
+	if _, err := os.Stat(mountPoint); os.IsNotExist(err) {
+		return nil
+	}
+	if isWindowsDriveLetterPath(mountPoint) {
+		err := cerrors.NewChapiErrorf(cerrors.AlreadyExists, errorMessageMountPointInUse, mountPoint)
+		log.Error(err)
+		return err
+	}
+	if isEmpty, _ := isEmptyDirectory(mountPoint); !isEmpty {
+		err := cerrors.NewChapiErrorf(cerrors.AlreadyExists, errorMessageMountPointNotEmpty, mountPoint)
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// moveManagedBindMount checks whether the given mount point ID is a managed bind mount and, if so,
+// moves it and updates its record.  The handled return value is false if the ID is not a managed
+// bind mount.
+func (mounter *Mounter) moveManagedBindMount(serialNumber string, mountId string, mountPoint string) (moved *model.Mount, handled bool, err error) {
+	if !bindFanOutSupported || serialNumber == "" {
+		return nil, false, nil
+	}
+
+	bindMountLock.Lock()
+	defer bindMountLock.Unlock()
+
+	record, err := loadBindMountRecord(serialNumber)
+	if err != nil {
+		return nil, true, cerrors.NewChapiError(err)
+	}
+	bind := record.findBindMount(mountId, "")
+	if bind == nil {
+		return nil, false, nil
+	}
+
+	// If the bind mount is already at the requested mount point, there is nothing to do
+	if isSamePathName(bind.MountPoint, mountPoint) {
+		return record.toMount(bind, true), true, nil
+	}
+	if isSamePathName(record.MountPoint, mountPoint) || record.findBindMount("", mountPoint) != nil {
+		err = cerrors.NewChapiErrorf(cerrors.AlreadyExists, errorMessageMountPointInUse, mountPoint)
+		log.Error(err)
+		return nil, true, err
+	}
+	if err = validateMoveTarget(bind.MountPoint, mountPoint); err != nil {
+		return nil, true, err
+	}
+
+	log.Tracef("Moving managed bind mount, ID=%v, MountPoint=%v, newMountPoint=%v", bind.ID, bind.MountPoint, mountPoint)
+	previous := &model.Mount{ID: bind.ID, MountPoint: bind.MountPoint, SerialNumber: serialNumber}
+	if err = mounter.moveMount(previous, mountPoint); err != nil {
+		return nil, true, err
+	}
+
+	bind.ID = getBindMountID(serialNumber, mountPoint)
+	bind.MountPoint = mountPoint
+	if err = saveBindMountRecord(record); err != nil {
+		// Don't leave an untracked bind mount behind; move it back to its recorded path
+		log.Errorf("Unable to record bind mount %v, err=%v", mountPoint, err)
+		if moveErr := mounter.moveMount(&model.Mount{MountPoint: mountPoint, SerialNumber: serialNumber}, previous.MountPoint); moveErr != nil {
+			log.Errorf("Unable to move bind mount %v back to %v, err=%v", mountPoint, previous.MountPoint, moveErr)
+		}
+		return nil, true, cerrors.NewChapiError(err)
+	}
+
+	log.Infof("Bind mount ID %v moved from %v to %v, new ID %v", previous.ID, previous.MountPoint, mountPoint, bind.ID)
+	return record.toMount(bind, true), true, nil
+}
+
+// retargetBindMounts updates the bind mount record of the given volume once its primary mount
+// point has moved
+func retargetBindMounts(serialNumber string, currentMountPoint string, mountPoint string) error {
+	if !bindFanOutSupported {
+		return nil
+	}
+
+	bindMountLock.Lock()
+	defer bindMountLock.Unlock()
+
+	record, err := loadBindMountRecord(serialNumber)
+	if err != nil || record == nil || !isSamePathName(record.MountPoint, currentMountPoint) {
+		return err
+	}
+	record.MountPoint = mountPoint
+	return saveBindMountRecord(record)
+}
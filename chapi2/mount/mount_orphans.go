@@ -0,0 +1,114 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// ORPHANED MOUNT POINTS
+//
+//		CHAPI mount points are not recorded in /etc/fstab (or any other persistent mount table).
+//		After an ungraceful reboot, or if a device disappears while mounted, the mount point
+//		directories are left behind.  Under Windows, the access path can remain while the volume
+//		it points to is gone.
+//
+//		Only the immediate subdirectories of the caller provided mount roots (e.g. the docker
+//		plugin mount directory) that CHAPI recorded as mount points in its state store (see
+//		chapi2/state) are inspected; directories created by anything else, and the roots
+//		themselves, are never removed.  Removal is non-recursive so that a directory holding
+//		data is never deleted.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+var (
+	// getManagedMountPoints returns the mount points CHAPI recorded in its state store; a variable
+	// so that tests can replace it
+	getManagedMountPoints = func() ([]string, error) {
+		managedState, err := state.GetState()
+		if err != nil {
+			return nil, err
+		}
+		var mountPoints []string
+		for _, managedMount := range managedState.Mounts {
+			mountPoints = append(mountPoints, managedMount.MountPoint)
+		}
+		return mountPoints, nil
+	}
+)
+
+// GetOrphanedMounts reports the orphaned mount points under the given mount roots.  If remove is
+// true, each orphaned mount point is also removed and its Removed/Error properties updated.
+func (mounter *Mounter) GetOrphanedMounts(roots []string, remove bool) ([]*model.OrphanedMount, error) {
+	log.Tracef(">>>>> GetOrphanedMounts, roots=%v, remove=%v", roots, remove)
+	defer log.Trace("<<<<< GetOrphanedMounts")
+
+	// If no mount roots are provided, fail the request
+	if len(roots) == 0 {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMissingMountRoot)
+		log.Error(err)
+		return nil, err
+	}
+
+	// Only the mount points recorded by CHAPI are candidates
+	managedMountPoints, err := getManagedMountPoints()
+	if err != nil {
+		log.Errorf("Unable to enumerate the managed mount points, err=%v", err)
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	orphans := []*model.OrphanedMount{}
+	for _, root := range roots {
+		// Only absolute mount roots are accepted; we don't want to guess what a relative path
+		// is relative to before removing anything beneath it.
+		if !filepath.IsAbs(root) {
+			err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidMountRoot, root)
+			log.Error(err)
+			return nil, err
+		}
+
+		rootOrphans, err := findOrphanedMounts(filepath.Clean(root), managedMountPoints)
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Tracef("Mount root %v does not exist, skipping", root)
+				continue
+			}
+			log.Errorf("Unable to enumerate mount root %v, err=%v", root, err)
+			return nil, cerrors.NewChapiError(err)
+		}
+		orphans = append(orphans, rootOrphans...)
+	}
+
+	for _, orphan := range orphans {
+		orphan.Managed = true
+		log.Infof("Orphaned mount point, path=%v, reason=%v", orphan.Path, orphan.Reason)
+		if !remove {
+			continue
+		}
+		if err := removeOrphanedMount(orphan); err != nil {
+			log.Errorf("Unable to remove orphaned mount point %v, err=%v", orphan.Path, err)
+			orphan.Error = err.Error()
+			continue
+		}
+		orphan.Removed = true
+	}
+	return orphans, nil
+}
+
+// isManagedMountPoint returns true if the given path is one of the managed mount points
+func isManagedMountPoint(path string, managedMountPoints []string) bool {
+	for _, mountPoint := range managedMountPoints {
+		if isSamePathName(mountPoint, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// MOUNT POINT QUIESCE
+//
+//		Array side snapshot orchestration freezes a mount point, takes the snapshot, and thaws
+//		the mount point so that the snapshot is consistent.  Under Linux the file system is
+//		frozen with fsfreeze; writes block until it is thawed.  Under Windows, the volume's file
+//		system buffers are only flushed (FlushFileBuffers); writes are not blocked, so the
+//		snapshot is crash consistent and applications must coordinate their own quiesce.
+//
+//		A frozen mount point blocks every writer on the host, so each freeze has a timeout after
+//		which the mount point is automatically thawed, even if the orchestrator never calls back.
+//		A failed automatic thaw is retried until the mount point is thawed.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	defaultQuiesceTimeout = 60 * time.Second  // Used if the request does not specify a timeout
+	maxQuiesceTimeout     = 300 * time.Second // Longest a mount point may remain frozen
+	quiesceThawRetry      = 10 * time.Second  // Delay before a failed automatic thaw is retried
+)
+
+var (
+	// quiescedMounts are the frozen mount points, keyed by mount point path
+	quiescedMounts     = make(map[string]*quiescedMount)
+	quiescedMountsLock sync.Mutex
+
+	// thawQuiescedFileSystem thaws a frozen file system; a variable so that tests can replace it
+	thawQuiescedFileSystem = thawFileSystem
+)
+
+// quiescedMount tracks a frozen mount point and its automatic thaw timer
+type quiescedMount struct {
+	mount *model.QuiescedMount
+	timer *time.Timer
+}
+
+// FreezeMount quiesces the file system at the given mount point until ThawMount is called or the
+// timeout expires
+func (mounter *Mounter) FreezeMount(request *model.QuiesceRequest) (*model.QuiescedMount, error) {
+	log.Tracef(">>>>> FreezeMount, request=%+v", request)
+	defer log.Trace("<<<<< FreezeMount")
+
+	timeout := time.Duration(request.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = defaultQuiesceTimeout
+	}
+	if timeout > maxQuiesceTimeout {
+		err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageQuiesceTimeoutTooLong, uint32(maxQuiesceTimeout.Seconds()))
+		log.Error(err)
+		return nil, err
+	}
+
+	mount, err := mounter.getMountForQuiesce(request)
+	if err != nil {
+		return nil, err
+	}
+
+	quiescedMountsLock.Lock()
+	defer quiescedMountsLock.Unlock()
+
+	if _, frozen := quiescedMounts[mount.MountPoint]; frozen {
+		err = cerrors.NewChapiErrorf(cerrors.AlreadyExists, errorMessageMountPointFrozen, mount.MountPoint)
+		log.Error(err)
+		return nil, err
+	}
+
+	method, err := freezeFileSystem(mount.MountPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	mountPoint := mount.MountPoint
+	quiesced := &quiescedMount{
+		mount: &model.QuiescedMount{
+			MountID:      mount.ID,
+			MountPoint:   mountPoint,
+			SerialNumber: mount.SerialNumber,
+			Method:       method,
+			ThawDeadline: time.Now().Add(timeout).UTC().Format(time.RFC3339),
+		},
+		timer: time.AfterFunc(timeout, func() {
+			log.Warnf("Freeze timeout expired, thawing mount point %v", mountPoint)
+			autoThawMountPoint(mountPoint)
+		}),
+	}
+	quiescedMounts[mountPoint] = quiesced
+	log.Infof("Mount point frozen, mountPoint=%v, method=%v, timeout=%v", mountPoint, method, timeout)
+	return quiesced.mount, nil
+}
+
+// ThawMount resumes I/O on a mount point frozen by FreezeMount
+func (mounter *Mounter) ThawMount(request *model.QuiesceRequest) error {
+	log.Tracef(">>>>> ThawMount, request=%+v", request)
+	defer log.Trace("<<<<< ThawMount")
+
+	mount, err := mounter.getMountForQuiesce(request)
+	if err != nil {
+		return err
+	}
+	return thawMountPoint(mount.MountPoint)
+}
+
+// thawMountPoint thaws the given frozen mount point and cancels its automatic thaw.  If the thaw
+// fails, the automatic thaw is left pending.
+func thawMountPoint(mountPoint string) error {
+	quiescedMountsLock.Lock()
+	defer quiescedMountsLock.Unlock()
+
+	quiesced, frozen := quiescedMounts[mountPoint]
+	if !frozen {
+		err := cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageMountPointNotFrozen, mountPoint)
+		log.Error(err)
+		return err
+	}
+	if err := thawQuiescedFileSystem(mountPoint, quiesced.mount.Method); err != nil {
+		// Leave the mount point tracked so that the thaw can be retried
+		return err
+	}
+	quiesced.timer.Stop()
+	delete(quiescedMounts, mountPoint)
+	log.Infof("Mount point thawed, mountPoint=%v", mountPoint)
+	return nil
+}
+
+// autoThawMountPoint thaws a mount point whose freeze timeout expired.  A failed thaw is retried
+// after quiesceThawRetry.
+func autoThawMountPoint(mountPoint string) {
+	if err := thawMountPoint(mountPoint); err == nil {
+		return
+	}
+
+	quiescedMountsLock.Lock()
+	defer quiescedMountsLock.Unlock()
+	if quiesced, frozen := quiescedMounts[mountPoint]; frozen {
+		log.Warnf("Unable to thaw mount point %v, retrying in %v", mountPoint, quiesceThawRetry)
+		quiesced.timer.Reset(quiesceThawRetry)
+	}
+}
+
+// getMountForQuiesce enumerates the mount point identified by the quiesce request
+func (mounter *Mounter) getMountForQuiesce(request *model.QuiesceRequest) (*model.Mount, error) {
+	// Either the serial number or mount point ID must be provided
+	if request.SerialNumber == "" && request.MountID == "" {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMissingMountPointID)
+		log.Error(err)
+		return nil, err
+	}
+
+	mounts, err := mounter.getMounts(request.SerialNumber, request.MountID, true, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(mounts) == 0 {
+		err = cerrors.NewChapiError(cerrors.NotFound, errorMessageMountPointNotFound)
+		log.Error(err)
+		return nil, err
+	}
+	if len(mounts) > 1 {
+		err = cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMultipleMountPointsDetected)
+		log.Error(err)
+		return nil, err
+	}
+	return mounts[0], nil
+}
@@ -0,0 +1,49 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestThawMountPointRetry(t *testing.T) {
+	savedThawQuiescedFileSystem := thawQuiescedFileSystem
+	defer func() { thawQuiescedFileSystem = savedThawQuiescedFileSystem }()
+
+	// The first thaw fails; the mount point stays frozen with its automatic thaw pending
+	thawErr := errors.New("thaw failure")
+	thawQuiescedFileSystem = func(mountPoint string, method string) error { return thawErr }
+	mountPoint := "/mnt/quiesced"
+	timer := time.NewTimer(time.Hour)
+	quiescedMountsLock.Lock()
+	quiescedMounts[mountPoint] = &quiescedMount{mount: &model.QuiescedMount{MountPoint: mountPoint}, timer: timer}
+	quiescedMountsLock.Unlock()
+
+	if err := thawMountPoint(mountPoint); err != thawErr {
+		t.Fatalf("expected %v, got %v", thawErr, err)
+	}
+	if !timer.Stop() {
+		t.Error("expected the automatic thaw to remain pending after a failed thaw")
+	}
+
+	// A failed automatic thaw is rescheduled
+	autoThawMountPoint(mountPoint)
+	if !timer.Stop() {
+		t.Error("expected a failed automatic thaw to be retried")
+	}
+
+	// Once thawed, the mount point is no longer tracked
+	thawQuiescedFileSystem = func(mountPoint string, method string) error { return nil }
+	timer.Reset(time.Hour)
+	autoThawMountPoint(mountPoint)
+	quiescedMountsLock.Lock()
+	_, frozen := quiescedMounts[mountPoint]
+	quiescedMountsLock.Unlock()
+	if frozen || timer.Stop() {
+		t.Error("expected the mount point to be thawed")
+	}
+}
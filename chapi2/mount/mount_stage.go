@@ -0,0 +1,243 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// STAGED AND PUBLISHED MOUNTS
+//
+//		A CSI node driver mounts a volume once per node at a global staging path (NodeStageVolume)
+//		and then bind mounts the staging path to the target path of each pod using the volume
+//		(NodePublishVolume).  StageMount, PublishMount, UnpublishMount and UnstageMount map those
+//		calls directly onto CHAPI:
+//
+//		StageMount		Mounts the volume at the staging path, its primary mount point.  A
+//						volume can only be staged at one path.
+//		PublishMount	Bind mounts the staging path to a target path, read-only if requested.
+//						Each publication is a managed bind mount (see mount_bind.go).
+//		UnpublishMount	Removes the bind mount at a target path.
+//		UnstageMount	Unmounts the staging path once the volume is no longer published; a Busy
+//						error, with the remaining target paths as details, is returned otherwise.
+//
+//		The publications are reference counted through the volume's bind mount record, which
+//		survives a CHAPI restart.  Every operation is idempotent:  staging or publishing again at
+//		the same path returns the existing mount point, and unpublishing or unstaging a path that
+//		is not mounted succeeds.  Publishing again with a different read-only setting fails with
+//		an AlreadyExists error.  Staging requires bind mounts, which are only supported under
+//		Linux.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"path/filepath"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	errorMessageMissingStagingPath       = "missing staging path"
+	errorMessageMissingTargetPath        = "missing target path"
+	errorMessageStagedElsewhere          = `volume already staged at "%v"`
+	errorMessageNotStaged                = `volume not staged at "%v"`
+	errorMessageTargetIsStagingPath      = `target path "%v" is the staging path`
+	errorMessagePublishedReadOnlyDiffers = `volume already published at "%v" with read_only=%v`
+)
+
+var (
+	// getStagedMount returns the volume's primary mount point, nil if the volume is not mounted; a
+	// variable so that tests can replace it
+	getStagedMount = func(mounter *Mounter, serialNumber string) (*model.Mount, error) {
+		mounts, err := mounter.getMounts(serialNumber, "", true, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, mount := range mounts {
+			if mount.MountPoint != "" {
+				return mount, nil
+			}
+		}
+		return nil, nil
+	}
+)
+
+// StageMount mounts the given volume at the given staging path, unless already staged there.  The
+// staged mount point is returned along with the target paths it is published to.
+func (mounter *Mounter) StageMount(serialNumber string, stagingPath string, fsOptions *model.FileSystemOptions) (*model.Mount, error) {
+	log.Tracef(">>>>> StageMount, serialNumber=%v, stagingPath=%v", serialNumber, stagingPath)
+	defer log.Trace("<<<<< StageMount")
+
+	stagingPath, err := validateStagingRequest(serialNumber, stagingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// A volume is staged at a single path
+	staged, err := getStagedMount(mounter, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if staged != nil && !isSamePathName(staged.MountPoint, stagingPath) {
+		err = cerrors.NewChapiErrorf(cerrors.AlreadyExists, errorMessageStagedElsewhere, staged.MountPoint)
+		log.Error(err)
+		return nil, err
+	}
+
+	mount, err := mounter.CreateMount(serialNumber, stagingPath, fsOptions)
+	if err != nil {
+		return nil, err
+	}
+	mount.BindMounts = getPublishedPaths(serialNumber, stagingPath)
+	return mount, nil
+}
+
+// PublishMount bind mounts the given volume's staging path to the given target path, read-only if
+// requested, unless already published there.  The published (bind) mount point is returned.
+func (mounter *Mounter) PublishMount(serialNumber string, stagingPath string, targetPath string, readOnly bool) (*model.Mount, error) {
+	log.Tracef(">>>>> PublishMount, serialNumber=%v, stagingPath=%v, targetPath=%v, readOnly=%v", serialNumber, stagingPath, targetPath, readOnly)
+	defer log.Trace("<<<<< PublishMount")
+
+	stagingPath, err := validateStagingRequest(serialNumber, stagingPath)
+	if err != nil {
+		return nil, err
+	}
+	if targetPath, err = validatePublishRequest(serialNumber, targetPath); err != nil {
+		return nil, err
+	}
+	if isSamePathName(targetPath, stagingPath) {
+		err = cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageTargetIsStagingPath, targetPath)
+		log.Error(err)
+		return nil, err
+	}
+
+	// The volume must be staged at the given staging path
+	staged, err := getStagedMount(mounter, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if staged == nil || !isSamePathName(staged.MountPoint, stagingPath) {
+		err = cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageNotStaged, stagingPath)
+		log.Error(err)
+		return nil, err
+	}
+
+	// An existing publication at the target path is returned, checked under the bind mount lock
+	// so that concurrent requests for the same target path bind mount it only once
+	return mounter.createManagedBindMount(staged, targetPath, readOnly)
+}
+
+// UnpublishMount removes the bind mount of the given volume at the given target path.  The
+// removed mount point is returned, nil if the volume was not published at the target path.
+func (mounter *Mounter) UnpublishMount(serialNumber string, targetPath string) (*model.Mount, error) {
+	log.Tracef(">>>>> UnpublishMount, serialNumber=%v, targetPath=%v", serialNumber, targetPath)
+	defer log.Trace("<<<<< UnpublishMount")
+
+	targetPath, err := validatePublishRequest(serialNumber, targetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	bindMountLock.Lock()
+	record, err := loadBindMountRecord(serialNumber)
+	bindMountLock.Unlock()
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+	bind := record.findBindMount("", targetPath)
+	if bind == nil {
+		log.Tracef("Volume %v not published at %v", serialNumber, targetPath)
+		return nil, nil
+	}
+	if _, err = mounter.deleteManagedBindMount(serialNumber, bind.ID, false); err != nil {
+		return nil, err
+	}
+	return record.toMount(bind, true), nil
+}
+
+// UnstageMount unmounts the given volume from the given staging path once it is no longer
+// published.  The removed mount point is returned, nil if the volume was not staged at the path.
+func (mounter *Mounter) UnstageMount(serialNumber string, stagingPath string) (*model.Mount, error) {
+	log.Tracef(">>>>> UnstageMount, serialNumber=%v, stagingPath=%v", serialNumber, stagingPath)
+	defer log.Trace("<<<<< UnstageMount")
+
+	stagingPath, err := validateStagingRequest(serialNumber, stagingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	staged, err := getStagedMount(mounter, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if staged == nil || !isSamePathName(staged.MountPoint, stagingPath) {
+		log.Tracef("Volume %v not staged at %v", serialNumber, stagingPath)
+		return nil, nil
+	}
+
+	// The staging path cannot be unmounted while it is still published
+	if err = checkBindMountReferences(staged); err != nil {
+		return nil, err
+	}
+	if err = mounter.deleteMount(staged, false); err != nil {
+		return nil, err
+	}
+	return staged, nil
+}
+
+// validateStagingRequest validates the serial number and staging path of a staging request and
+// returns the absolute staging path
+func validateStagingRequest(serialNumber string, stagingPath string) (string, error) {
+	return validateStagedMountPath(serialNumber, stagingPath, errorMessageMissingStagingPath)
+}
+
+// validatePublishRequest validates the serial number and target path of a publish request and
+// returns the absolute target path
+func validatePublishRequest(serialNumber string, targetPath string) (string, error) {
+	return validateStagedMountPath(serialNumber, targetPath, errorMessageMissingTargetPath)
+}
+
+// validateStagedMountPath validates the serial number and the given staging or target path and
+// returns the absolute path.  missingPathMessage is the error message if the path is empty.
+func validateStagedMountPath(serialNumber string, path string, missingPathMessage string) (string, error) {
+	if !bindFanOutSupported {
+		err := cerrors.NewChapiError(cerrors.Unimplemented, errorMessageBindMountUnsupported)
+		log.Error(err)
+		return "", err
+	}
+	if serialNumber == "" {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMissingSerialNumber)
+		log.Error(err)
+		return "", err
+	}
+	if path == "" {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, missingPathMessage)
+		log.Error(err)
+		return "", err
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", cerrors.NewChapiError(cerrors.InvalidArgument, err)
+	}
+	return path, nil
+}
+
+// getPublishedPaths returns the target paths the given volume's staging path is published to
+func getPublishedPaths(serialNumber string, stagingPath string) []string {
+	bindMountLock.Lock()
+	defer bindMountLock.Unlock()
+
+	record, err := loadBindMountRecord(serialNumber)
+	if err != nil {
+		log.Errorf("Unable to load the bind mount record of %v, err=%v", serialNumber, err)
+		return nil
+	}
+	if record == nil || !isSamePathName(record.MountPoint, stagingPath) {
+		return nil
+	}
+	var targetPaths []string
+	for _, bind := range record.BindMounts {
+		targetPaths = append(targetPaths, bind.MountPoint)
+	}
+	return targetPaths
+}
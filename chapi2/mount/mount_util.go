@@ -1,2 +1,56 @@
 // (c) Copyright 2019 Hewlett Packard Enterprise Development LP
-package mount
\ No newline at end of file
+package mount
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+// isEmptyDirectory takes the given directory path and returns true if the directory is empty else
+// false is returned.  If the path is invalid / inaccessible, an error is returned.
+func isEmptyDirectory(accessPath string) (bool, error) {
+
+	// Start by getting a handle to the directory path
+	f, err := os.Open(accessPath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	// Query the directory to see if there is at least one child file/subdirectory present
+	_, err = f.Readdirnames(1)
+
+	// If Readdirnames(1) fails with io.EOF, we know that the directory is empty
+	if err == io.EOF {
+		return true, nil
+	}
+
+	// Directory isn't empty
+	return false, err
+}
+
+// hasFileSystemIdentity returns true if the file system options carry an expected file system
+// UUID and/or label to verify after mounting
+func hasFileSystemIdentity(fsOptions *model.FileSystemOptions) bool {
+	return fsOptions != nil && (fsOptions.FsUUID != "" || fsOptions.FsLabel != "")
+}
+
+// checkFileSystemIdentity compares the UUID and label of the file system found on the given device
+// with the identity expected by the file system options.  UUIDs are compared case insensitively
+// as tools differ in how they format them.
+func checkFileSystemIdentity(devicePath string, fsOptions *model.FileSystemOptions, fsUUID string, fsLabel string) error {
+	if fsOptions == nil {
+		return nil
+	}
+	if fsOptions.FsUUID != "" && !strings.EqualFold(fsOptions.FsUUID, fsUUID) {
+		return cerrors.NewChapiErrorf(cerrors.Aborted, errorMessageFileSystemMismatch, "UUID", devicePath, fsOptions.FsUUID, fsUUID)
+	}
+	if fsOptions.FsLabel != "" && fsOptions.FsLabel != fsLabel {
+		return cerrors.NewChapiErrorf(cerrors.Aborted, errorMessageFileSystemMismatch, "label", devicePath, fsOptions.FsLabel, fsLabel)
+	}
+	return nil
+}
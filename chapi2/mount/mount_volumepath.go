@@ -0,0 +1,121 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// VOLUME GUID PATH MOUNTS
+//
+//		Windows hosts can have more volumes than there are drive letters.  On such hosts a volume
+//		can instead be mounted to its volume GUID path (e.g. "\\?\Volume{GUID}\"), either
+//		explicitly or automatically when a model.MountPointAutoDriveLetter request finds no free
+//		drive letter.  The volume is then only reachable through its volume GUID path and any
+//		directory mount points; no drive letter is consumed.
+//
+//		Windows exposes every volume at its volume GUID path, so these mounts are recorded in the
+//		CHAPI state store (see chapi2/state) to tell them apart from volumes CHAPI has not mounted.
+//		A recorded volume GUID path is reported as the volume's mount point while the volume has no
+//		drive letter or directory access path.  Mounting the volume to a directory replaces it.
+//
+//		Mount point IDs are unchanged.  A Windows mount can additionally be addressed by its volume
+//		GUID (e.g. "Volume{GUID}" or "{GUID}") wherever a mount point ID is accepted, as the
+//		volume GUID is stable across disk renumbering.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/state"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	volumeGUIDPathPrefix = `\\?\Volume{`
+
+	errorMessageVolumePathMismatch     = `"%v" is not the volume GUID path of this volume (%v)`
+	errorMessageVolumePathUnsupported  = "volume GUID path mounts not supported on this platform"
+	errorMessageVolumePathNotAvailable = "volume GUID path not available"
+)
+
+// State store accessors for the volume GUID path mount records; variables so that tests can replace them
+var (
+	getVolumePathMounts   = state.GetVolumePathMounts
+	recordVolumePathMount = state.RecordVolumePathMount
+	forgetVolumePathMount = state.RemoveVolumePathMount
+)
+
+// isVolumeGUIDPath returns true if the given path is a volume GUID path (e.g. "\\?\Volume{GUID}\"),
+// with or without the trailing backslash
+func isVolumeGUIDPath(path string) bool {
+	return getVolumeGUID(path) != ""
+}
+
+// getVolumeGUID returns the volume GUID, including its braces, of the given volume GUID path, or an
+// empty string if the path is not a volume GUID path
+func getVolumeGUID(path string) string {
+	if len(path) < len(volumeGUIDPathPrefix) || !strings.EqualFold(path[:len(volumeGUIDPathPrefix)], volumeGUIDPathPrefix) {
+		return ""
+	}
+	guid := strings.TrimSuffix(path[len(volumeGUIDPathPrefix)-1:], `\`)
+	if !strings.HasSuffix(guid, "}") || strings.ContainsAny(guid[1:len(guid)-1], `{}\`) {
+		return ""
+	}
+	return strings.ToLower(guid)
+}
+
+// getVolumeGUIDPath returns the volume GUID path among the given partition access paths, or an
+// empty string if there is none
+func getVolumeGUIDPath(accessPaths []string) string {
+	for _, accessPath := range accessPaths {
+		if isVolumeGUIDPath(accessPath) {
+			return accessPath
+		}
+	}
+	return ""
+}
+
+// normalizeVolumeGUIDPath returns the given volume GUID path with a lower case GUID and trailing
+// backslash, as reported in the partition access paths
+func normalizeVolumeGUIDPath(path string) string {
+	return `\\?\Volume` + getVolumeGUID(path) + `\`
+}
+
+// matchesVolumeGUID returns true if the given mount point ID names the volume GUID of the given
+// volume GUID path (e.g. "Volume{GUID}", "{GUID}" or the volume GUID path itself)
+func matchesVolumeGUID(volumePath string, mountId string) bool {
+	guid := getVolumeGUID(volumePath)
+	if guid == "" || mountId == "" {
+		return false
+	}
+	mountId = strings.TrimSuffix(strings.TrimPrefix(mountId, `\\?\`), `\`)
+	if len(mountId) > len("Volume") && strings.EqualFold(mountId[:len("Volume")], "Volume") {
+		mountId = mountId[len("Volume"):]
+	}
+	return strings.EqualFold(mountId, guid)
+}
+
+// isVolumePathMounted returns true if the given volume GUID path is recorded as mounted
+func isVolumePathMounted(volumePath string) bool {
+	volumePaths, err := getVolumePathMounts()
+	if err != nil {
+		log.Errorf("Unable to load volume GUID path mounts, err=%v", err)
+		return false
+	}
+	_, ok := volumePaths[normalizeVolumeGUIDPath(volumePath)]
+	return ok
+}
+
+// addVolumePathMount records the given volume as mounted to its volume GUID path
+func addVolumePathMount(serialNumber string, volumePath string) error {
+	if !volumePathMountSupported {
+		return cerrors.NewChapiError(cerrors.Unimplemented, errorMessageVolumePathUnsupported)
+	}
+	return recordVolumePathMount(normalizeVolumeGUIDPath(volumePath), serialNumber)
+}
+
+// removeVolumePathMount forgets the given volume GUID path mount, if recorded
+func removeVolumePathMount(volumePath string) error {
+	return forgetVolumePathMount(normalizeVolumeGUIDPath(volumePath))
+}
@@ -0,0 +1,82 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+import (
+	"testing"
+)
+
+const testVolumePath = `\\?\Volume{4c1b2f5e-1d2a-11e9-a4c6-806e6f6e6963}\`
+
+func TestVolumeGUIDPath(t *testing.T) {
+	tests := []struct {
+		path string
+		guid string
+	}{
+		{testVolumePath, "{4c1b2f5e-1d2a-11e9-a4c6-806e6f6e6963}"},
+		{`\\?\VOLUME{4C1B2F5E-1D2A-11E9-A4C6-806E6F6E6963}`, "{4c1b2f5e-1d2a-11e9-a4c6-806e6f6e6963}"},
+		{`\\?\Volume{abc}\folder\`, ""},
+		{`\\?\Volume`, ""},
+		{`E:\`, ""},
+		{`C:\mnt\vol1`, ""},
+	}
+	for _, test := range tests {
+		if guid := getVolumeGUID(test.path); guid != test.guid {
+			t.Errorf("path %q: expected GUID %q, got %q", test.path, test.guid, guid)
+		}
+	}
+
+	if path := getVolumeGUIDPath([]string{`E:\`, testVolumePath}); path != testVolumePath {
+		t.Errorf("unexpected volume GUID path %q", path)
+	}
+	if normalizeVolumeGUIDPath(`\\?\Volume{4C1B2F5E-1D2A-11E9-A4C6-806E6F6E6963}`) != testVolumePath {
+		t.Errorf("unexpected normalized path %q", normalizeVolumeGUIDPath(`\\?\Volume{4C1B2F5E-1D2A-11E9-A4C6-806E6F6E6963}`))
+	}
+	for _, mountId := range []string{"{4c1b2f5e-1d2a-11e9-a4c6-806e6f6e6963}", "Volume{4C1B2F5E-1D2A-11E9-A4C6-806E6F6E6963}", testVolumePath} {
+		if !matchesVolumeGUID(testVolumePath, mountId) {
+			t.Errorf("mount ID %q does not match %v", mountId, testVolumePath)
+		}
+	}
+	if matchesVolumeGUID(testVolumePath, "d1c4e9a7b2a0c3f1-3-2") || matchesVolumeGUID(`E:\`, "{4c1b2f5e-1d2a-11e9-a4c6-806e6f6e6963}") {
+		t.Error("unexpected volume GUID match")
+	}
+}
+
+func TestVolumePathMountRecords(t *testing.T) {
+	if !volumePathMountSupported {
+		if err := addVolumePathMount("serial1", testVolumePath); err == nil {
+			t.Error("expected error recording volume GUID path mount on unsupported platform")
+		}
+		return
+	}
+
+	defer func(get func() (map[string]string, error), record func(string, string) error, forget func(string) error) {
+		getVolumePathMounts, recordVolumePathMount, forgetVolumePathMount = get, record, forget
+	}(getVolumePathMounts, recordVolumePathMount, forgetVolumePathMount)
+	volumePaths := make(map[string]string)
+	getVolumePathMounts = func() (map[string]string, error) { return volumePaths, nil }
+	recordVolumePathMount = func(volumePath string, serialNumber string) error {
+		volumePaths[volumePath] = serialNumber
+		return nil
+	}
+	forgetVolumePathMount = func(volumePath string) error {
+		delete(volumePaths, volumePath)
+		return nil
+	}
+
+	if isVolumePathMounted(testVolumePath) {
+		t.Fatal("volume GUID path mounted before recorded")
+	}
+	if err := addVolumePathMount("serial1", `\\?\Volume{4C1B2F5E-1D2A-11E9-A4C6-806E6F6E6963}`); err != nil {
+		t.Fatal(err)
+	}
+	if !isVolumePathMounted(testVolumePath) || volumePaths[testVolumePath] != "serial1" {
+		t.Errorf("recorded volume GUID path not mounted, records=%v", volumePaths)
+	}
+	if err := removeVolumePathMount(`\\?\Volume{4C1B2F5E-1D2A-11E9-A4C6-806E6F6E6963}\`); err != nil {
+		t.Fatal(err)
+	}
+	if isVolumePathMounted(testVolumePath) {
+		t.Error("removed volume GUID path still mounted")
+	}
+}
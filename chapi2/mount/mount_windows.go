@@ -3,22 +3,46 @@
 package mount
 
 import (
+	"errors"
 	"fmt"
 	"hash/fnv"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+	"unsafe"
 
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/windows/powershell"
+	"github.com/hpe-storage/common-host-libs/windows/rstrtmgr"
 	"github.com/hpe-storage/common-host-libs/windows/wmi"
+	"golang.org/x/sys/windows"
 )
 
 const (
 	PARTITION_BASIC_DATA_GUID = "{ebd0a0a2-b9e5-4433-87c0-68b6b72699c7}"
+
+	// Windows supports multiple access paths per partition natively; CHAPI does not fan out
+	// additional mount points with managed bind mounts.
+	bindFanOutSupported = false
+
+	// Volumes can be mounted to their volume GUID path (see mount_volumepath.go)
+	volumePathMountSupported = true
+
+	// Event log of the NTFS and ReFS journal replay events
+	journalReplayEventLog = "System"
+
+	// Most files on a mount point registered with the Restart Manager to find its blockers
+	mountBlockerMaxFiles = 10000
+)
+
+var (
+	bindMountStatePath = ""
+
+	// Providers of the NTFS and ReFS journal replay events
+	journalReplayEventProviders = []string{"Microsoft-Windows-Ntfs", "Microsoft-Windows-ReFS"}
 )
 
 // getMounts enumerates the mountpoints for the given device / mount point.  The following input
@@ -89,9 +113,9 @@ func (mounter *Mounter) getMounts(serialNumber string, mountId string, allDetail
 				continue
 			}
 
-			// If we were passed in a mount point ID as input, and the ID does not match, skip
-			// this mount point ID.
-			if (mountId != "") && (mountId != mountPoint.ID) {
+			// If we were passed in a mount point ID as input, and neither the ID nor the volume
+			// GUID matches, skip this mount point ID.
+			if (mountId != "") && (mountId != mountPoint.ID) && !matchesVolumeGUID(getVolumeGUIDPath(partition.AccessPaths), mountId) {
 				log.Tracef("Skipping mount point ID %v, does not match requested ID %v", mountPoint.ID, mountId)
 				continue
 			}
@@ -177,7 +201,8 @@ func getMountPointFromPartition(device *model.Device, partition *wmi.MSFT_Partit
 
 // getMountPointPath takes the given MSFT_Partition AccessPaths array (i.e. An array of strings
 // containing the various mount points for the partition) and returns back an array of mount
-// point paths *if* the partition is currently mounted.
+// point paths *if* the partition is currently mounted.  The volume GUID path is only returned if
+// it is the volume's only mount point and was mounted by CHAPI (see mount_volumepath.go).
 func getMountPointPaths(accessPaths []string) []string {
 	log.Tracef(">>>>> getMountPointPath, accessPaths=%v", strings.Join(accessPaths, ","))
 	defer log.Trace("<<<<< getMountPointPath")
@@ -195,6 +220,11 @@ func getMountPointPaths(accessPaths []string) []string {
 		}
 	}
 
+	// Report the volume GUID path if the volume was only mounted there
+	if volumePath := getVolumeGUIDPath(accessPaths); (len(mountPointPaths) == 0) && (volumePath != "") && isVolumePathMounted(volumePath) {
+		mountPointPaths = append(mountPointPaths, volumePath)
+	}
+
 	// Log enumerated mount point paths before returning
 	log.Tracef("mountPointPaths=%v", strings.Join(mountPointPaths, ","))
 	return mountPointPaths
@@ -233,7 +263,8 @@ func (mounter *Mounter) createMount(mount *model.Mount, mountPoint string, fsOpt
 	log.Tracef(`>>>>> createMount, mountPoint="%v", fsOptions=%v`, mountPoint, fsOptions)
 	defer log.Trace("<<<<< createMount")
 
-	// TODO - How is fsOptions going to be used under Windows?
+	// TODO - How is fsOptions going to be used under Windows?  Only the expected file system
+	// identity (FsUUID/FsLabel) is verified.
 
 	// Validate the Mount object
 	if err := validateMount(mount); err != nil {
@@ -266,6 +297,21 @@ func (mounter *Mounter) createMount(mount *model.Mount, mountPoint string, fsOpt
 		mount = newMount
 	}
 
+	// Mounting to the volume GUID path only records the mount; the path is always present
+	volumePath := getVolumeGUIDPath(mount.Private.WindowsPartition.AccessPaths)
+	if isVolumeGUIDPath(mountPoint) {
+		if (volumePath == "") || !strings.EqualFold(normalizeVolumeGUIDPath(mountPoint), normalizeVolumeGUIDPath(volumePath)) {
+			err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageVolumePathMismatch, mountPoint, volumePath)
+			log.Error(err)
+			return err
+		}
+		if err := addVolumePathMount(mount.SerialNumber, volumePath); err != nil {
+			log.Errorf("Unable to record volume GUID path mount %v, err=%v", volumePath, err)
+			return cerrors.NewChapiError(err)
+		}
+		return nil
+	}
+
 	// Determine details about the drive letter, or directory, we're going to mount to
 	var isDriveLetterMount, isDirectoryExists, isDirectoryEmpty bool
 	isDriveLetterMount = isWindowsDriveLetterPath(mountPoint)
@@ -323,13 +369,38 @@ func (mounter *Mounter) createMount(mount *model.Mount, mountPoint string, fsOpt
 		return err
 	}
 
+	// Make sure the intended file system got mounted before anything writes to it
+	if err := verifyVolumeIdentity(mount, mountPoint, fsOptions); err != nil {
+		if _, _, errRemove := powershell.RemovePartitionAccessPath(mountPoint, mount.Private.WindowsPartition.DiskNumber, mount.Private.WindowsPartition.PartitionNumber); errRemove != nil {
+			log.Errorf(`Unable to remove access path "%v", err=%v`, mountPoint, errRemove)
+		} else if createdMountDirectory {
+			if errRemove = os.Remove(mountPoint); errRemove != nil {
+				log.Errorf(`Unable to remove created directory, directory="%v", err=%v`, mountPoint, errRemove)
+			}
+		}
+		return err
+	}
+
+	// A drive letter or directory mount point replaces a volume GUID path mount
+	if volumePath != "" {
+		if err := removeVolumePathMount(volumePath); err != nil {
+			log.Errorf("Unable to remove volume GUID path mount %v, err=%v", volumePath, err)
+		}
+	}
+
 	// Success!
 	return nil
 }
 
+// unmount is not supported under Windows; a volume's access paths are only removed through
+// deleteMount
+func (mounter *Mounter) unmount(mountPoint string) error {
+	return cerrors.NewChapiError(cerrors.Unimplemented, errorMessageUnmountUnsupported)
+}
+
 // deleteMount is called to unmount the given mount point ID
-func (mounter *Mounter) deleteMount(mount *model.Mount) error {
-	log.Trace(">>>>> deleteMount")
+func (mounter *Mounter) deleteMount(mount *model.Mount, lazy bool) error {
+	log.Tracef(">>>>> deleteMount, lazy=%v", lazy)
 	defer log.Trace("<<<<< deleteMount")
 
 	// Validate the Mount object
@@ -337,6 +408,13 @@ func (mounter *Mounter) deleteMount(mount *model.Mount) error {
 		return err
 	}
 
+	// Windows has no equivalent of a lazy unmount; an access path is either removed or it isn't
+	if lazy {
+		err := cerrors.NewChapiError(cerrors.Unimplemented, errorMessageLazyUnmountUnsupported)
+		log.Error(err)
+		return err
+	}
+
 	// If the volume has more than one mount point, one or more mount points were not set by CHAPI
 	// since CHAPI only supports a single mount point per device/partition.  Windows supports
 	// multiple mount points per partition.  Since we cannot be certain which of the mount points
@@ -353,8 +431,25 @@ func (mounter *Mounter) deleteMount(mount *model.Mount) error {
 	log.Tracef("SerialNumber=%v, PathName=%v, IsOffline=%v, IsReadOnly=%v",
 		mount.SerialNumber, mount.Private.WindowsDisk.Path, mount.Private.WindowsDisk.IsOffline, mount.Private.WindowsDisk.IsReadOnly)
 
-	// Unmount the device/partition from the specified mount point
+	// Volume GUID path mounts are only recorded by CHAPI; the path itself cannot be removed
+	if isVolumeGUIDPath(mount.MountPoint) {
+		if err := removeVolumePathMount(mount.MountPoint); err != nil {
+			log.Errorf("Unable to remove volume GUID path mount %v, err=%v", mount.MountPoint, err)
+			return cerrors.NewChapiError(err)
+		}
+		return nil
+	}
+
+	// Unmount the device/partition from the specified mount point.  If that fails, report which
+	// processes are running from the mount point so the caller can decide whether to stop them.
 	_, _, err := powershell.RemovePartitionAccessPath(mount.MountPoint, mount.Private.WindowsPartition.DiskNumber, mount.Private.WindowsPartition.PartitionNumber)
+	if err != nil {
+		if blockers := getMountBlockers(mount.MountPoint); len(blockers) > 0 {
+			busyErr := cerrors.NewChapiErrorf(cerrors.Busy, errorMessageMountPointBusy, mount.MountPoint, len(blockers))
+			log.Errorf("%v, blockers=%v", busyErr, blockers)
+			return busyErr.WithDetails(blockers)
+		}
+	}
 
 	// If the mount point was removed, and we were mounted to an empty directory, we clean up after
 	// ourselves by removing the empty directory.
@@ -372,6 +467,226 @@ func (mounter *Mounter) deleteMount(mount *model.Mount) error {
 	return err
 }
 
+// verifyVolumeIdentity verifies that the file system mounted at the given mount point has the
+// volume serial number (FsUUID) and/or label expected by the file system options.  As under Linux,
+// the caller removes the mount point again on a mismatch so that another volume's data is never
+// left exposed at the mount point.
+func verifyVolumeIdentity(mount *model.Mount, mountPoint string, fsOptions *model.FileSystemOptions) error {
+	if !hasFileSystemIdentity(fsOptions) {
+		return nil
+	}
+
+	fsUUID, fsLabel, err := getVolumeIdentity(mountPoint)
+	if err == nil {
+		err = checkFileSystemIdentity(mount.Private.WindowsDisk.Path, fsOptions, fsUUID, fsLabel)
+	}
+	if err != nil {
+		log.Errorf("Unable to verify file system mounted at %v, err=%v", mountPoint, err)
+		return cerrors.NewChapiError(err)
+	}
+	log.Tracef("Verified file system on %v, serialNumber=%v, label=%v", mount.Private.WindowsDisk.Path, fsUUID, fsLabel)
+	return nil
+}
+
+// getVolumeIdentity returns the volume serial number, formatted as reported by the vol command
+// (e.g. "1A2B-3C4D"), and the label of the file system mounted at the given mount point
+func getVolumeIdentity(mountPoint string) (serialNumber string, label string, err error) {
+	rootPath, err := windows.UTF16PtrFromString(strings.TrimSuffix(mountPoint, `\`) + `\`)
+	if err != nil {
+		return "", "", err
+	}
+	var volumeSerialNumber uint32
+	volumeName := make([]uint16, windows.MAX_PATH+1)
+	if err = windows.GetVolumeInformation(rootPath, &volumeName[0], uint32(len(volumeName)), &volumeSerialNumber, nil, nil, nil, 0); err != nil {
+		return "", "", err
+	}
+	serialNumber = fmt.Sprintf("%04X-%04X", volumeSerialNumber>>16, volumeSerialNumber&0xFFFF)
+	return serialNumber, windows.UTF16ToString(volumeName), nil
+}
+
+// getMountBlockers returns the processes using the files on the given mount point.  The files are
+// registered with the Restart Manager, which reports the processes holding them open or running
+// them.  If the Restart Manager cannot be used, the processes running an executable, or with a
+// module loaded, from the mount point are reported instead.
+func getMountBlockers(mountPoint string) []*model.MountBlocker {
+	log.Tracef(">>>>> getMountBlockers, mountPoint=%v", mountPoint)
+	defer log.Trace("<<<<< getMountBlockers")
+
+	processes, err := rstrtmgr.GetProcessesUsingFiles(getMountFiles(mountPoint))
+	if err != nil {
+		log.Errorf("Unable to query the Restart Manager, reporting the modules loaded from %v, err=%v", mountPoint, err)
+		return getModuleMountBlockers(mountPoint)
+	}
+
+	var blockers []*model.MountBlocker
+	for _, process := range processes {
+		blockers = append(blockers, &model.MountBlocker{
+			PID:     int(process.PID),
+			Command: process.AppName,
+			Path:    getProcessModuleOnMount(process.PID, mountPoint),
+		})
+	}
+	return blockers
+}
+
+// getMountFiles returns the files on the given mount point, up to mountBlockerMaxFiles files
+func getMountFiles(mountPoint string) []string {
+	var files []string
+	errMaxFiles := errors.New("too many files")
+	err := filepath.Walk(mountPoint, func(path string, info os.FileInfo, err error) error {
+		// Skip the directories that cannot be read (e.g. System Volume Information)
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			if len(files) == mountBlockerMaxFiles {
+				return errMaxFiles
+			}
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err == errMaxFiles {
+		log.Warnf("Only the first %v files on %v are checked for blocking processes", mountBlockerMaxFiles, mountPoint)
+	}
+	return files
+}
+
+// getModuleMountBlockers returns the processes running an executable, or with a module loaded,
+// from the given mount point
+func getModuleMountBlockers(mountPoint string) []*model.MountBlocker {
+	var blockers []*model.MountBlocker
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		log.Errorf("Unable to enumerate processes, err=%v", err)
+		return blockers
+	}
+	defer windows.CloseHandle(snapshot)
+
+	process := windows.ProcessEntry32{}
+	process.Size = uint32(unsafe.Sizeof(process))
+	for err = windows.Process32First(snapshot, &process); err == nil; err = windows.Process32Next(snapshot, &process) {
+		if path := getProcessModuleOnMount(process.ProcessID, mountPoint); path != "" {
+			blockers = append(blockers, &model.MountBlocker{
+				PID:     int(process.ProcessID),
+				Command: windows.UTF16ToString(process.ExeFile[:]),
+				Path:    path,
+			})
+		}
+	}
+	return blockers
+}
+
+// getProcessModuleOnMount returns the first module (executable or DLL), loaded by the process, that
+// resides on the given mount point (or an empty string if there is none)
+func getProcessModuleOnMount(pid uint32, mountPoint string) string {
+	// Protected and system processes cannot be enumerated; they don't run from CHAPI volumes
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPMODULE|windows.TH32CS_SNAPMODULE32, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(snapshot)
+
+	mountPrefix := strings.ToLower(strings.TrimSuffix(mountPoint, `\`) + `\`)
+	module := windows.ModuleEntry32{}
+	module.Size = uint32(unsafe.Sizeof(module))
+	for err = windows.Module32First(snapshot, &module); err == nil; err = windows.Module32Next(snapshot, &module) {
+		if path := windows.UTF16ToString(module.ExePath[:]); strings.HasPrefix(strings.ToLower(path), mountPrefix) {
+			return path
+		}
+	}
+	return ""
+}
+
+// moveMount moves the given mount point to the new drive letter or directory.  The new access
+// path is added to the partition before the old access path is removed so that the volume remains
+// accessible throughout; if the old access path cannot be removed, the new one is removed again.
+func (mounter *Mounter) moveMount(mount *model.Mount, mountPoint string) error {
+	log.Tracef(`>>>>> moveMount, mountPoint="%v", newMountPoint="%v"`, mount.MountPoint, mountPoint)
+	defer log.Trace("<<<<< moveMount")
+
+	// Validate the Mount object
+	if err := validateMount(mount); err != nil {
+		return err
+	}
+
+	// As with deleteMount, we cannot be certain which mount point CHAPI created if the partition
+	// has more than one
+	mountPointPaths := getMountPointPaths(mount.Private.WindowsPartition.AccessPaths)
+	if len(mountPointPaths) > 1 {
+		err := cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMultipleMountPointsDetected)
+		log.Errorf("Multiple paths detected, paths=%v, err=%v", strings.Join(mountPointPaths, ","), err)
+		return err
+	}
+
+	// The Add-PartitionAccessPath PowerShell cmdlet requires the directory to be present
+	createdMountDirectory := false
+	if !isWindowsDriveLetterPath(mountPoint) {
+		if _, err := os.Stat(mountPoint); os.IsNotExist(err) {
+			if err = os.MkdirAll(mountPoint, os.ModePerm); err != nil {
+				log.Error(err)
+				return err
+			}
+			createdMountDirectory = true
+			log.Tracef(`Created mount point directory "%v"`, mountPoint)
+		}
+	}
+	removeCreatedDirectory := func() {
+		if createdMountDirectory {
+			if errRemove := os.Remove(mountPoint); errRemove != nil {
+				log.Errorf(`Unable to remove created directory, directory="%v", err=%v`, mountPoint, errRemove)
+			}
+		}
+	}
+
+	// Add the new access path first
+	diskNumber, partitionNumber := mount.Private.WindowsPartition.DiskNumber, mount.Private.WindowsPartition.PartitionNumber
+	if _, _, err := powershell.AddPartitionAccessPath(mountPoint, diskNumber, partitionNumber); err != nil {
+		removeCreatedDirectory()
+		return err
+	}
+
+	// Then remove the old access path, rolling back the new access path on failure
+	if _, _, err := powershell.RemovePartitionAccessPath(mount.MountPoint, diskNumber, partitionNumber); err != nil {
+		log.Errorf(`Unable to remove access path "%v", err=%v`, mount.MountPoint, err)
+		if _, _, errRemove := powershell.RemovePartitionAccessPath(mountPoint, diskNumber, partitionNumber); errRemove != nil {
+			log.Errorf(`Unable to remove access path "%v", err=%v`, mountPoint, errRemove)
+		} else {
+			removeCreatedDirectory()
+		}
+		return err
+	}
+
+	// We clean up after ourselves by removing the old mount point's empty directory
+	if !isWindowsDriveLetterPath(mount.MountPoint) {
+		log.Tracef(`Removing "%v" directory`, mount.MountPoint)
+		if removeErr := os.Remove(mount.MountPoint); removeErr != nil {
+			log.Errorf("Failed to remove mount point directory, err=%v", removeErr)
+		}
+	}
+	return nil
+}
+
+// getActiveMountOptions returns the access mode ("ro" or "rw") in effect of the given mount
+// points, from their partition and disk read-only attributes, keyed by mount point ID
+func getActiveMountOptions(mounts []*model.Mount) map[string][]string {
+	activeOptions := make(map[string][]string)
+	for _, mount := range mounts {
+		if mount.Private == nil || mount.Private.WindowsPartition == nil {
+			continue
+		}
+		readOnly := mount.Private.WindowsPartition.IsReadOnly
+		if mount.Private.WindowsDisk != nil {
+			readOnly = readOnly || mount.Private.WindowsDisk.IsReadOnly
+		}
+		activeOptions[mount.ID] = []string{mountOptionReadWrite}
+		if readOnly {
+			activeOptions[mount.ID] = []string{mountOptionReadOnly}
+		}
+	}
+	return activeOptions
+}
+
 // validateMount validates that the Mount object was initialized properly.  The Mount object has
 // some private Windows properties that were populated during the getMounts() routine.  The Windows
 // properties should *always* be available.  Adding a routine to validate that the properties were
@@ -385,39 +700,84 @@ func validateMount(mount *model.Mount) error {
 	return nil
 }
 
-// isWindowsDriveLetterPath takes the given path and returns true if it's a path to a drive letter
-// (e.g. c:\ or c:) else false is returned.
-func isWindowsDriveLetterPath(accessPath string) bool {
-	if lenAccessPath := len(accessPath); (lenAccessPath == 2) || (lenAccessPath == 3) {
-		accessPath := strings.ToUpper(accessPath)
-		if (accessPath[0] >= 'A') && (accessPath[0] <= 'Z') && (accessPath[1] == ':') {
-			return (lenAccessPath == 2) || (accessPath[2] == '\\')
+// findOrphanedMounts returns the orphaned managed mount points directly beneath the given mount
+// root.  A managed mount point is orphaned if it is an empty directory, or if it is an access path
+// (volume mount point) whose volume no longer exists.
+func findOrphanedMounts(root string, managedMountPoints []string) ([]*model.OrphanedMount, error) {
+	log.Tracef(">>>>> findOrphanedMounts, root=%v", root)
+	defer log.Trace("<<<<< findOrphanedMounts")
+
+	// os.ReadDir does not follow the access paths; opening one to a missing volume fails
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []*model.OrphanedMount
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		if !isManagedMountPoint(path, managedMountPoints) {
+			continue
+		}
+		pathUTF16, err := windows.UTF16PtrFromString(path)
+		if err != nil {
+			continue
+		}
+		attributes, err := windows.GetFileAttributes(pathUTF16)
+		if err != nil || (attributes&windows.FILE_ATTRIBUTE_DIRECTORY) == 0 {
+			continue
+		}
+		if (attributes & windows.FILE_ATTRIBUTE_REPARSE_POINT) != 0 {
+			if isMissingVolumeAccessPath(path) {
+				orphans = append(orphans, &model.OrphanedMount{Path: path, Reason: model.OrphanReasonMissingDevice})
+			}
+			continue
+		}
+		if empty, _ := isEmptyDirectory(path); empty {
+			orphans = append(orphans, &model.OrphanedMount{Path: path, Reason: model.OrphanReasonEmptyDirectory})
 		}
 	}
-	return false
+	return orphans, nil
 }
 
-// isEmptyDirectory takes the given directory path and returns true if the directory is empty else
-// false is returned.  If the path is invalid / inaccessible, an error is returned.
-func isEmptyDirectory(accessPath string) (bool, error) {
-
-	// Start by getting a handle to the directory path
-	f, err := os.Open(accessPath)
+// isMissingVolumeAccessPath returns true if the given reparse point directory is an access path to
+// a volume that no longer exists.  Access paths to present volumes, and other reparse points (e.g.
+// junctions to directories that still exist), return false.
+func isMissingVolumeAccessPath(path string) bool {
+	// Volume mount point APIs require the trailing backslash
+	mountPointUTF16, err := windows.UTF16PtrFromString(path + `\`)
 	if err != nil {
-		return false, err
+		return false
+	}
+	volumeName := make([]uint16, windows.MAX_PATH)
+	if err = windows.GetVolumeNameForVolumeMountPoint(mountPointUTF16, &volumeName[0], uint32(len(volumeName))); err == nil {
+		return false
 	}
-	defer f.Close()
+	log.Tracef("Unable to resolve volume for access path %v, err=%v", path, err)
 
-	// Query the directory to see if there is at least one child file/subdirectory present
-	_, err = f.Readdirnames(1)
+	// Be conservative and only report the path if its target cannot be opened either
+	_, err = os.Stat(path)
+	return err != nil
+}
 
-	// If Readdirnames(1) fails with io.EOF, we know that the directory is empty
-	if err == io.EOF {
-		return true, nil
+// removeOrphanedMount removes the orphaned access path, if present, and the (empty) mount point
+// directory
+func removeOrphanedMount(orphan *model.OrphanedMount) error {
+	if orphan.Reason == model.OrphanReasonMissingDevice {
+		// The mount manager may no longer know about the access path; removing the directory
+		// below also removes its reparse point so a failure here is only logged.
+		if mountPointUTF16, err := windows.UTF16PtrFromString(orphan.Path + `\`); err == nil {
+			if err = windows.DeleteVolumeMountPoint(mountPointUTF16); err != nil {
+				log.Tracef("Unable to delete volume mount point %v, err=%v", orphan.Path, err)
+			}
+		}
 	}
+	return os.Remove(orphan.Path)
+}
 
-	// Directory isn't empty
-	return false, err
+// createBindMount is not supported under Windows
+func createBindMount(source string, target string, readOnly bool) error {
+	return cerrors.NewChapiError(cerrors.Unimplemented, errorMessageBindMountUnsupported)
 }
 
 // isSamePathName returns true if the two provided directory paths are equal else false.  Under
@@ -427,3 +787,140 @@ func isEmptyDirectory(accessPath string) (bool, error) {
 func isSamePathName(path1, path2 string) bool {
 	return strings.EqualFold(path1, path2)
 }
+
+// freezeFileSystem flushes the file system buffers of the volume mounted at the given mount point.
+// Writes are not blocked.
+func freezeFileSystem(mountPoint string) (string, error) {
+	// Volume mount point APIs require the trailing backslash
+	mountPointUTF16, err := windows.UTF16PtrFromString(strings.TrimSuffix(mountPoint, `\`) + `\`)
+	if err != nil {
+		return "", cerrors.NewChapiError(err)
+	}
+	volumeName := make([]uint16, windows.MAX_PATH)
+	if err = windows.GetVolumeNameForVolumeMountPoint(mountPointUTF16, &volumeName[0], uint32(len(volumeName))); err != nil {
+		log.Errorf("Unable to resolve volume for %v, err=%v", mountPoint, err)
+		return "", cerrors.NewChapiError(err)
+	}
+
+	// The volume is opened without the trailing backslash (i.e. the volume rather than its root
+	// directory) so that flushing covers the entire file system
+	volumePath, err := windows.UTF16PtrFromString(strings.TrimSuffix(windows.UTF16ToString(volumeName), `\`))
+	if err != nil {
+		return "", cerrors.NewChapiError(err)
+	}
+	handle, err := windows.CreateFile(volumePath, windows.GENERIC_READ|windows.GENERIC_WRITE, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		log.Errorf("Unable to open volume for %v, err=%v", mountPoint, err)
+		return "", cerrors.NewChapiError(err)
+	}
+	defer windows.CloseHandle(handle)
+	if err = windows.FlushFileBuffers(handle); err != nil {
+		log.Errorf("Unable to flush volume for %v, err=%v", mountPoint, err)
+		return "", cerrors.NewChapiError(err)
+	}
+	return model.QuiesceMethodFlush, nil
+}
+
+// thawFileSystem has nothing to undo under Windows; the flushed volume was never blocked
+func thawFileSystem(mountPoint string, method string) error {
+	return nil
+}
+
+// createVolumePathMount mounts the given device to its volume GUID path
+func (mounter *Mounter) createVolumePathMount(serialNumber string, fsOptions *model.FileSystemOptions) (*model.Mount, error) {
+	log.Tracef(">>>>> createVolumePathMount, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< createVolumePathMount")
+
+	mounts, err := mounter.getMounts(serialNumber, "", true, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(mounts) != 1 {
+		err = cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMountPointNotFound)
+		if len(mounts) > 1 {
+			err = cerrors.NewChapiError(cerrors.InvalidArgument, errorMessageMultipleMountPointsDetected)
+		}
+		log.Error(err)
+		return nil, err
+	}
+	mount := mounts[0]
+	if isVolumeGUIDPath(mount.MountPoint) {
+		log.Tracef("Volume already mounted to volume GUID path %v", mount.MountPoint)
+		return mount, nil
+	}
+
+	// The volume GUID path is only enumerable once the disk is online
+	if mount.Private.WindowsDisk.IsOffline {
+		if err = mounter.multipathPlugin.MakeDiskOnlineAndWritable(mount.Private.WindowsDisk.Path, true, mount.Private.WindowsDisk.IsReadOnly); err != nil {
+			return nil, err
+		}
+		if mounts, err = mounter.getMounts(serialNumber, "", true, false); err != nil {
+			return nil, err
+		}
+		if len(mounts) == 1 {
+			mount = mounts[0]
+		}
+	}
+
+	volumePath := getVolumeGUIDPath(mount.Private.WindowsPartition.AccessPaths)
+	if volumePath == "" {
+		err = cerrors.NewChapiError(cerrors.NotFound, errorMessageVolumePathNotAvailable)
+		log.Error(err)
+		return nil, err
+	}
+	log.Tracef("Mounting to volume GUID path %v", volumePath)
+	return mounter.CreateMount(serialNumber, volumePath, fsOptions)
+}
+
+// getFreeDriveLetters returns the drive letter paths, from the given pool, that are not in use
+func getFreeDriveLetters(pool []string) ([]string, error) {
+	drives, err := windows.GetLogicalDrives()
+	if err != nil {
+		log.Errorf("Unable to enumerate logical drives, err=%v", err)
+		return nil, cerrors.NewChapiError(err)
+	}
+	var freeDriveLetters []string
+	for _, letter := range pool {
+		if drives&(1<<uint(letter[0]-'A')) == 0 {
+			freeDriveLetters = append(freeDriveLetters, driveLetterPath(letter))
+		}
+	}
+	return freeDriveLetters, nil
+}
+
+// getJournalReplayMessages returns the NTFS and ReFS journal replay (log file recovery) messages
+// logged to the System event log since the given time.  The volumes are not identified by disk,
+// so the messages of volumes mounted concurrently are reported as well.
+func getJournalReplayMessages(mount *model.Mount, since time.Time) ([]string, error) {
+	out, _, err := powershell.GetWinEventMessages(journalReplayEventLog, journalReplayEventProviders, since)
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, message := range strings.Split(out, "\n") {
+		message = strings.TrimSpace(message)
+		lower := strings.ToLower(message)
+		if strings.Contains(lower, "recover") || strings.Contains(lower, "replay") {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// isWriteProtectError returns true if the error reports a write protected volume
+func isWriteProtectError(err error) bool {
+	return err == windows.ERROR_WRITE_PROTECT
+}
+
+// hideProbeFile sets the hidden attribute of the probe file written to verify a mount
+func hideProbeFile(probePath string) {
+	if probePathUTF16, err := windows.UTF16PtrFromString(probePath); err == nil {
+		windows.SetFileAttributes(probePathUTF16, windows.FILE_ATTRIBUTE_HIDDEN)
+	}
+}
+
+// checkOwnerWritable is not supported under Windows, where the file system owner is not applied
+func checkOwnerWritable(mountPoint string, fsOwner string) error {
+	log.Tracef("File system owner %v not verified under Windows", fsOwner)
+	return nil
+}
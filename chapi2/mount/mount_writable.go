@@ -0,0 +1,101 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package mount
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// WRITABLE MOUNT VERIFICATION
+//
+//		A volume can mount successfully and still not accept writes: the volume may be read-only
+//		on the array (e.g. a snapshot or a replica), or the file system may have been remounted
+//		read-only after an error.  The application only finds out on its first write.  If
+//		FileSystemOptions.VerifyWritable is set, CreateMount creates, syncs and deletes a hidden
+//		probe file at the mount point and then checks that the mount point is writable by the
+//		file system owner (FsOwner, Linux only).  A write protected file system fails the request
+//		with a cerrors.ReadOnly error, a mount point the owner cannot write to with a
+//		cerrors.PermissionDenied error.
+//
+//		The mount is left in place when the verification fails so that it can be examined; as the
+//		verification is repeated for a volume already mounted at the requested mount point, a
+//		retried CreateMount keeps failing until the volume is writable or the request no longer
+//		asks for the verification.  Mounts requested read-only ("ro" mount option) are not
+//		verified.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// writeProbePrefix is the name prefix of the probe file written to verify a mount
+	writeProbePrefix = ".chapi-write-probe-"
+
+	// readOnlyMountOption is the mount option of a read-only mount
+	readOnlyMountOption = "ro"
+
+	errorMessageMountReadOnly    = `file system mounted at "%v" is read-only, the volume may be read-only on the array or the file system was remounted read-only after an error`
+	errorMessageMountNotWritable = `unable to write to "%v", %v`
+	errorMessageOwnerNotWritable = `mount point "%v" is not writable by owner %v, mode=%v, uid=%v, gid=%v`
+	errorMessageInvalidFsOwner   = `invalid file system owner "%v", %v`
+)
+
+// verifyMountWritable verifies, if requested by the file system options, that the file system
+// mounted at the given mount point accepts writes, by its owner if any
+func verifyMountWritable(mountPoint string, fsOptions *model.FileSystemOptions) error {
+	if fsOptions == nil || !fsOptions.VerifyWritable {
+		return nil
+	}
+	for _, option := range fsOptions.MountOpts {
+		if option == readOnlyMountOption {
+			log.Tracef("Mount point %v requested read-only, not verifying that it is writable", mountPoint)
+			return nil
+		}
+	}
+
+	if err := writeProbeFile(mountPoint); err != nil {
+		log.WithEvent(log.EventIDMountFailure).Errorf("Mount point %v is not writable, err=%v", mountPoint, err)
+		return err
+	}
+	if fsOptions.FsOwner != "" {
+		if err := checkOwnerWritable(mountPoint, fsOptions.FsOwner); err != nil {
+			log.WithEvent(log.EventIDMountFailure).Errorf("Mount point %v is not writable by %v, err=%v", mountPoint, fsOptions.FsOwner, err)
+			return err
+		}
+	}
+	log.Tracef("Verified that mount point %v is writable", mountPoint)
+	return nil
+}
+
+// writeProbeFile creates, syncs and deletes a hidden probe file at the given mount point.  The
+// sync makes a device that rejects writes fail the probe even if the file system still accepts
+// them in its cache.
+func writeProbeFile(mountPoint string) error {
+	probePath := filepath.Join(mountPoint, fmt.Sprintf("%v%v", writeProbePrefix, os.Getpid()))
+	file, err := os.OpenFile(probePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err == nil {
+		hideProbeFile(probePath)
+		if _, err = file.Write([]byte("chapi")); err == nil {
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if removeErr := os.Remove(probePath); err == nil {
+			err = removeErr
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	if pathErr, ok := err.(*os.PathError); ok && isWriteProtectError(pathErr.Err) {
+		return cerrors.NewChapiErrorf(cerrors.ReadOnly, errorMessageMountReadOnly, mountPoint)
+	}
+	return cerrors.NewChapiErrorf(cerrors.Internal, errorMessageMountNotWritable, mountPoint, err)
+}
@@ -10,18 +10,31 @@ import (
 	"github.com/hpe-storage/common-host-libs/chapi2/fc"
 	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/rescan"
+	"github.com/hpe-storage/common-host-libs/chapi2/timing"
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
 
 const (
 	// Shared error messages
 	errorMessageDeviceNotFound           = "device not found"
+	errorMessageIgnoredDeviceNotFound    = `device WWID "%v" is not ignored`
+	errorMessageInvalidWWID              = `invalid device WWID "%v"`
 	errorMessageInvalidAccessProtocol    = `invalid AccessProtocol "%v"`
 	errorMessageMisconfiguredMultipathIO = `misconfigured multipath I/O - multiple instances of serial number "%v" detected`
+	errorMessageMultipathdFailed         = `multipathd "%v" failed: %v`
+	errorMessageNoPartitions             = "device has no partitions"
 	errorMessageSerialNumberNotProvided  = "serial number not provided"
+	errorMessageTuningNotApplied         = `unable to apply "%v" to %v, %v`
 	errorMessageUnableLocateIscsiTarget  = "unable to locate iSCSI target"
 )
 
+const (
+	// deviceFieldIscsiTarget is the Device property holding the iSCSI target, the most expensive
+	// property to enumerate
+	deviceFieldIscsiTarget = "iscsi_target"
+)
+
 var (
 	lock            = &sync.Mutex{}
 	targetTypeCache *TargetTypeCache // Global target type cache
@@ -42,26 +55,54 @@ func NewMultipathPlugin() *MultipathPlugin {
 // GetDevices enumerates all the Nimble volumes while only providing basic details (e.g. serial number).
 // If a "serialNumber" is passed in, only that specific serial number is enumerated.
 func (plugin *MultipathPlugin) GetDevices(serialNumber string) ([]*model.Device, error) {
-	devices, err := plugin.getDevices(serialNumber)
+	devices, err := plugin.getDevices(lookupSerialNumber(serialNumber))
 	if err != nil {
 		return nil, err
 	}
-	return devices, nil
+	return filterIgnoredDevices(devices), nil
 }
 
 // GetAllDeviceDetails enumerates all the Nimble volumes while providing full details about the
 // device.  If a "serialNumber" is passed in, only that specific serial number is enumerated.
 func (plugin *MultipathPlugin) GetAllDeviceDetails(serialNumber string) ([]*model.Device, error) {
-	devices, err := plugin.getAllDeviceDetails(serialNumber)
+	return plugin.GetDeviceDetailFields(serialNumber, nil)
+}
+
+// GetDeviceDetailFields enumerates the Nimble volumes like GetAllDeviceDetails, but only the given
+// Device properties (JSON property names, e.g. "serial_number") need to be populated.  Properties
+// that are expensive to enumerate (e.g. "iscsi_target") are skipped unless requested.  Every
+// property is populated if no fields are given.
+func (plugin *MultipathPlugin) GetDeviceDetailFields(serialNumber string, fields []string) ([]*model.Device, error) {
+	devices, err := plugin.getAllDeviceDetails(lookupSerialNumber(serialNumber), newDeviceFields(fields))
 	if err != nil {
 		return nil, err
 	}
-	return devices, nil
+	return filterIgnoredDevices(devices), nil
+}
+
+// deviceFields is the set of Device properties to populate; nil selects every property
+type deviceFields map[string]bool
+
+// newDeviceFields returns the set of the given Device properties, or nil if none are given
+func newDeviceFields(fields []string) deviceFields {
+	if len(fields) == 0 {
+		return nil
+	}
+	set := make(deviceFields)
+	for _, field := range fields {
+		set[field] = true
+	}
+	return set
+}
+
+// has returns true if the given Device property is to be populated
+func (fields deviceFields) has(field string) bool {
+	return fields == nil || fields[field]
 }
 
 // GetPartitionInfo enumerates the partitions on the given volume
 func (plugin *MultipathPlugin) GetPartitionInfo(serialNumber string) ([]*model.DevicePartition, error) {
-	partitions, err := plugin.getPartitionInfo(serialNumber)
+	partitions, err := plugin.getPartitionInfo(lookupSerialNumber(serialNumber))
 	if err != nil {
 		return nil, err
 	}
@@ -73,9 +114,88 @@ func (plugin *MultipathPlugin) OfflineDevice(device model.Device) error {
 	return plugin.offlineDevice(device)
 }
 
+// FlushDevice writes the given device's cached data to the volume, e.g. before it is detached
+func (plugin *MultipathPlugin) FlushDevice(device model.Device) error {
+	return plugin.flushDevice(device)
+}
+
 // CreateFileSystem is called to create a file system on the given device
 func (plugin *MultipathPlugin) CreateFileSystem(device model.Device, filesystem string) error {
-	return plugin.createFileSystem(device, filesystem)
+	return plugin.CreateFileSystemWithOptions(device, filesystem, nil)
+}
+
+// CreateFileSystemWithOptions is called to create a file system on the given device with the given
+// file system options (may be nil)
+func (plugin *MultipathPlugin) CreateFileSystemWithOptions(device model.Device, filesystem string, fsOptions *model.FileSystemOptions) error {
+	if fsOptions == nil {
+		fsOptions = &model.FileSystemOptions{}
+	}
+	defer timing.StartStage(device.SerialNumber, timing.StageMkfs)()
+	defer trackFormat(device.SerialNumber, filesystem, fsOptions.FullFormat && isFullFormatSupported)()
+	return plugin.createFileSystem(device, filesystem, fsOptions)
+}
+
+// GetFileSystemUUID returns the UUID of the file system on the given device, or an empty string if
+// the platform does not report one
+func (plugin *MultipathPlugin) GetFileSystemUUID(device model.Device) (string, error) {
+	return plugin.getFileSystemUUID(device)
+}
+
+// BenchmarkDevice measures the read latency of the given device with a bounded read-only probe.
+// The request's duration and block size must already be set.  Reads bypass the host's cache, so
+// the caller must ensure the device is not mounted read-write.
+func (plugin *MultipathPlugin) BenchmarkDevice(device model.Device, request model.BenchmarkRequest) (*model.BenchmarkResult, error) {
+	result, err := plugin.benchmarkDevice(device, request)
+	if err != nil {
+		return nil, err
+	}
+	result.SerialNumber = device.SerialNumber
+	return result, nil
+}
+
+// GetDeviceTuning reports the current queue settings of the given device
+func (plugin *MultipathPlugin) GetDeviceTuning(device model.Device) (*model.DeviceTuning, error) {
+	return plugin.getDeviceTuning(device)
+}
+
+// SetDeviceTuning applies the given queue settings to the device and its paths
+func (plugin *MultipathPlugin) SetDeviceTuning(device model.Device, tuning model.DeviceTuning) error {
+	return plugin.setDeviceTuning(device, tuning)
+}
+
+// ExtendPartition extends the last partition on the given device to fill the device (e.g. after
+// the volume was expanded on the array) and returns the device's updated partitions
+func (plugin *MultipathPlugin) ExtendPartition(device model.Device) ([]*model.DevicePartition, error) {
+	return plugin.extendPartition(device)
+}
+
+// GetDevicePaths reports the paths to the given device grouped by their ALUA access state, with
+// the active/optimized paths first
+func (plugin *MultipathPlugin) GetDevicePaths(device model.Device) ([]*model.DevicePathGroup, error) {
+	paths, err := plugin.getDevicePaths(device)
+	if err != nil {
+		return nil, err
+	}
+	return groupDevicePaths(paths), nil
+}
+
+// RecoverDevicePaths reinstates the failed paths of the given device that are reachable again (e.g.
+// after an array controller failover) and reports the outcome of each failed path
+func (plugin *MultipathPlugin) RecoverDevicePaths(device model.Device) (*model.PathRecoveryReport, error) {
+	report, err := plugin.recoverDevicePaths(device)
+	if err != nil {
+		return nil, err
+	}
+	report.SerialNumber = device.SerialNumber
+	for _, path := range report.Paths {
+		if path.Status == model.PathRecoveryReinstated {
+			report.Recovered++
+		} else {
+			report.Failed++
+			log.WithEvent(log.EventIDPathLoss).Errorf("Path %v to %v lost and not recovered, status=%v, err=%v", path.Name, device.SerialNumber, path.Status, path.Error)
+		}
+	}
+	return report, nil
 }
 
 // AttachDevice attaches the given block device to this host.  If the device is successfully
@@ -93,12 +213,25 @@ func (plugin *MultipathPlugin) AttachDevice(serialNumber string, blockDev model.
 		return nil, err
 	}
 
-	// If it's an FC volume, all we need to do is an FC rescan.  If it's iSCSI, we need to
-	// ensure the target is logged in.  Any other AccessProtocol is invalid and unsupported.
+	// The volume is attached again so its devices must no longer be suppressed (see rescan.go)
+	rescan.Resume(serialNumber)
+
+	// If it's an FC volume, all we need to do is an FC rescan; only of the LUN on its target ports
+	// if the LUN is pre-zoned.  If it's iSCSI, we need to ensure the target is logged in.  Any
+	// other AccessProtocol is invalid and unsupported.
 	switch blockDev.AccessProtocol {
 	case model.AccessProtocolFC:
-		err = fc.NewFcPlugin().RescanFcTarget(blockDev.LunID)
+		endStage := timing.StartStage(serialNumber, timing.StageRescan)
+		err = rescan.Run(serialNumber, func() error {
+			if blockDev.FcAccessInfo != nil && blockDev.LunID != "" {
+				return fc.NewFcPlugin().RescanFcTargetPorts(blockDev.FcAccessInfo.TargetWwpns, blockDev.LunID)
+			}
+			return fc.NewFcPlugin().RescanFcTarget(blockDev.LunID)
+		})
+		endStage()
 	case model.AccessProtocolIscsi:
+		// The iSCSI plugin times its stages by target name
+		defer timing.AliasVolume(blockDev.TargetName, serialNumber)()
 		err = iscsi.NewIscsiPlugin().LoginTarget(blockDev)
 	default:
 		err = cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidAccessProtocol, blockDev.AccessProtocol)
@@ -112,10 +245,13 @@ func (plugin *MultipathPlugin) AttachDevice(serialNumber string, blockDev model.
 
 	// Enumerate the device with the provided serial number
 	var devices []*model.Device
-	devices, err = plugin.GetAllDeviceDetails(serialNumber)
+	endStage := timing.StartStage(serialNumber, timing.StageMultipathAssemble)
+	devices, err = plugin.getAttachedDevices(serialNumber, blockDev)
+	endStage()
 	if err != nil {
 		return nil, err
 	}
+	devices = filterIgnoredDevices(devices)
 
 	// If device was not found, fail the request
 	if len(devices) == 0 {
@@ -136,23 +272,49 @@ func (plugin *MultipathPlugin) DetachDevice(device model.Device) error {
 
 	log.Infof("Detach device, serialNumber=%v", device.SerialNumber)
 
+	// Rescans for other volumes, during and shortly after the detach, must not re-surface the
+	// device (see rescan.go)
+	defer rescan.BeginDetach(device.SerialNumber, func() error {
+		return plugin.suppressDevices(device.SerialNumber)
+	})()
+
 	// Start by offlining the device on the host
 	if err := plugin.OfflineDevice(device); err != nil {
 		return err
 	}
+	forgetLun(device.SerialNumber)
 
 	// If this is an iSCSI Volume Scoped Target (VST), logout iSCSI connections.  For all other
-	// target types (e.g. GST, FC), leave connections intact.
+	// target types (e.g. GST, FC), leave connections intact unless the GST is left without any
+	// device and LogoutEmptyTargetEnv is set.
 	if (device.IscsiTarget != nil) && strings.EqualFold(device.IscsiTarget.TargetScope, model.TargetScopeVolume) {
 		if err := iscsi.NewIscsiPlugin().LogoutTarget(device.IscsiTarget.Name); err != nil {
 			return err
 		}
+		plugin.InvalidateLunMaps([]string{device.IscsiTarget.Name})
+	} else if isLogoutEmptyTargetEnabled() && plugin.logoutEmptyGroupTarget(device) {
+		plugin.InvalidateLunMaps([]string{device.IscsiTarget.Name})
 	}
 
 	// Success!
 	return nil
 }
 
+// suppressDevices offlines the devices of the given detached volume that a rescan re-surfaced
+func (plugin *MultipathPlugin) suppressDevices(serialNumber string) error {
+	devices, err := plugin.GetDevices(serialNumber)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		log.Infof("Offlining re-surfaced device, serialNumber=%v", device.SerialNumber)
+		if err = plugin.OfflineDevice(*device); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // getTargetTypeCache returns the global TargetTypeCache object
 func getTargetTypeCache() *TargetTypeCache {
 	lock.Lock()
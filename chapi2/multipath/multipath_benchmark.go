@@ -0,0 +1,106 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"io"
+	"math/rand"
+	"sort"
+	"time"
+	"unsafe"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// benchmarkBufferAlignment is the memory alignment required for direct (unbuffered) I/O
+	benchmarkBufferAlignment = 4096
+
+	errorMessageDeviceTooSmall = "device size (%v bytes) is smaller than the benchmark block size (%v bytes)"
+)
+
+// runBenchmark reads blockSize bytes at random block aligned offsets within the first size bytes
+// of the device, one read at a time, until the duration has elapsed.  The device must have been
+// opened for direct (unbuffered) I/O so that the reads measure the device and not the page cache.
+func runBenchmark(device io.ReaderAt, size uint64, blockSize int, duration time.Duration) (*model.BenchmarkResult, error) {
+	log.Tracef(">>>>> runBenchmark, size=%v, blockSize=%v, duration=%v", size, blockSize, duration)
+	defer log.Trace("<<<<< runBenchmark")
+
+	blocks := int64(size / uint64(blockSize))
+	if blocks == 0 {
+		return nil, cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageDeviceTooSmall, size, blockSize)
+	}
+
+	buffer := alignedBuffer(blockSize, benchmarkBufferAlignment)
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var latencies []time.Duration
+
+	start := time.Now()
+	for time.Since(start) < duration {
+		offset := random.Int63n(blocks) * int64(blockSize)
+		readStart := time.Now()
+		if _, err := device.ReadAt(buffer, offset); err != nil {
+			log.Errorf("Benchmark read failed, offset=%v, err=%v", offset, err)
+			return nil, cerrors.NewChapiError(err)
+		}
+		latencies = append(latencies, time.Since(readStart))
+	}
+	elapsed := time.Since(start)
+
+	result := &model.BenchmarkResult{
+		DurationMs: int64(elapsed / time.Millisecond),
+		BlockSize:  blockSize,
+		Reads:      uint64(len(latencies)),
+		Latency:    latencyPercentiles(latencies),
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		result.IOPS = float64(result.Reads) / seconds
+		result.ThroughputMBPerSec = result.IOPS * float64(blockSize) / (1024 * 1024)
+	}
+	return result, nil
+}
+
+// latencyPercentiles returns the latency percentiles, in microseconds, of the given read latencies
+// (nil if there are none).  The latencies are sorted in place.
+func latencyPercentiles(latencies []time.Duration) *model.BenchmarkLatency {
+	if len(latencies) == 0 {
+		return nil
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+
+	// Nearest-rank percentile
+	percentile := func(p float64) int64 {
+		rank := int(p*float64(len(latencies))+0.999999) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return int64(latencies[rank] / time.Microsecond)
+	}
+
+	return &model.BenchmarkLatency{
+		Min:  int64(latencies[0] / time.Microsecond),
+		Avg:  int64(total / time.Duration(len(latencies)) / time.Microsecond),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P99:  percentile(0.99),
+		P999: percentile(0.999),
+		Max:  int64(latencies[len(latencies)-1] / time.Microsecond),
+	}
+}
+
+// alignedBuffer returns a buffer of the given size whose address is a multiple of alignment
+func alignedBuffer(size int, alignment int) []byte {
+	buffer := make([]byte, size+alignment)
+	offset := 0
+	if remainder := int(uintptr(unsafe.Pointer(&buffer[0])) % uintptr(alignment)); remainder != 0 {
+		offset = alignment - remainder
+	}
+	return buffer[offset : offset+size]
+}
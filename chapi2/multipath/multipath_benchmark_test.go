@@ -0,0 +1,69 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// benchmarkTestDevice is a fake device that records the offsets read
+type benchmarkTestDevice struct {
+	offsets []int64
+	err     error
+}
+
+func (device *benchmarkTestDevice) ReadAt(p []byte, off int64) (int, error) {
+	device.offsets = append(device.offsets, off)
+	return len(p), device.err
+}
+
+func TestRunBenchmark(t *testing.T) {
+	device := &benchmarkTestDevice{}
+	result, err := runBenchmark(device, 10*4096+100, 4096, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reads == 0 || result.Reads != uint64(len(device.offsets)) || result.BlockSize != 4096 || result.Latency == nil || result.IOPS <= 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	for _, offset := range device.offsets {
+		if (offset%4096 != 0) || (offset > 9*4096) {
+			t.Fatalf("unexpected offset %v", offset)
+		}
+	}
+
+	// Read errors and devices smaller than a block fail the benchmark
+	if _, err = runBenchmark(&benchmarkTestDevice{err: errors.New("I/O error")}, 8192, 4096, time.Second); err == nil {
+		t.Error("read error not reported")
+	}
+	if _, err = runBenchmark(&benchmarkTestDevice{}, 512, 4096, time.Second); err == nil {
+		t.Error("small device not rejected")
+	}
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	if latencyPercentiles(nil) != nil {
+		t.Error("expected no percentiles without latencies")
+	}
+
+	// 1000us..1us in reverse order
+	var latencies []time.Duration
+	for i := 1000; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Microsecond)
+	}
+	latency := latencyPercentiles(latencies)
+	if latency.Min != 1 || latency.Max != 1000 || latency.Avg != 500 || latency.P50 != 500 || latency.P90 != 900 ||
+		latency.P99 != 990 || latency.P999 != 999 {
+		t.Errorf("unexpected percentiles %+v", latency)
+	}
+}
+
+func TestAlignedBuffer(t *testing.T) {
+	buffer := alignedBuffer(4096, benchmarkBufferAlignment)
+	if len(buffer) != 4096 || uintptr(unsafe.Pointer(&buffer[0]))%benchmarkBufferAlignment != 0 {
+		t.Errorf("buffer not aligned, len=%v, address=%p", len(buffer), &buffer[0])
+	}
+}
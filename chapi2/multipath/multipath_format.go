@@ -0,0 +1,126 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// FORMAT PROGRESS
+//
+//		Windows quick formats unless FileSystemOptions.FullFormat is set; a full format zeroes the
+//		whole volume, which takes hours on a multi-TB volume.  While CreateFileSystem runs, the
+//		format is tracked by volume serial number and reported by GetFormatStatus (GET
+//		/api/v1/devices/{serialNumber}/format).  The percent complete of a Windows full format is
+//		polled from its WMI storage job (MSFT_StorageJob) every formatProgressInterval and logged.
+//		Storage jobs don't identify their volume, so the percent complete is only reported while
+//		a single full format is in progress.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	errorMessageNoFormatInProgress = "no file system creation in progress for %v"
+)
+
+var (
+	// formatProgressInterval is the interval at which the progress of a full format is polled; a
+	// variable so that tests can shorten it
+	formatProgressInterval = 30 * time.Second
+
+	// getFormatPercentComplete returns the percent complete of the full format in progress; a
+	// variable so that tests can replace it
+	getFormatPercentComplete = getPlatformFormatPercentComplete
+
+	formatsLock sync.Mutex
+	formats     = make(map[string]*formatProgress) // Formats in progress, keyed by serial number
+)
+
+// formatProgress is a file system creation in progress, protected by formatsLock
+type formatProgress struct {
+	status model.FormatStatus
+	start  time.Time
+	done   chan struct{}
+}
+
+// trackFormat tracks the creation of the given file system on the given volume until the returned
+// function is called
+func trackFormat(serialNumber string, filesystem string, fullFormat bool) (stop func()) {
+	format := &formatProgress{
+		status: model.FormatStatus{SerialNumber: serialNumber, FileSystem: filesystem, FullFormat: fullFormat},
+		start:  time.Now(),
+		done:   make(chan struct{}),
+	}
+	formatsLock.Lock()
+	formats[serialNumber] = format
+	formatsLock.Unlock()
+
+	if fullFormat {
+		go format.pollProgress()
+	}
+	return func() {
+		close(format.done)
+		formatsLock.Lock()
+		defer formatsLock.Unlock()
+		if formats[serialNumber] == format {
+			delete(formats, serialNumber)
+		}
+	}
+}
+
+// pollProgress polls, and logs, the percent complete of the full format until it completes
+func (format *formatProgress) pollProgress() {
+	ticker := time.NewTicker(formatProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-format.done:
+			return
+		case <-ticker.C:
+			if countFullFormats() != 1 {
+				log.Infof("Full format of %v in progress for %v", format.status.SerialNumber, time.Since(format.start).Round(time.Second))
+				continue
+			}
+			percentComplete, ok := getFormatPercentComplete()
+			if !ok {
+				continue
+			}
+			formatsLock.Lock()
+			format.status.PercentComplete = percentComplete
+			formatsLock.Unlock()
+			log.Infof("Full format of %v %v%% complete after %v", format.status.SerialNumber, percentComplete, time.Since(format.start).Round(time.Second))
+		}
+	}
+}
+
+// countFullFormats returns the number of full formats in progress
+func countFullFormats() int {
+	formatsLock.Lock()
+	defer formatsLock.Unlock()
+	count := 0
+	for _, format := range formats {
+		if format.status.FullFormat {
+			count++
+		}
+	}
+	return count
+}
+
+// GetFormatStatus reports the progress of the file system creation in progress on the given volume
+func (plugin *MultipathPlugin) GetFormatStatus(serialNumber string) (*model.FormatStatus, error) {
+	formatsLock.Lock()
+	defer formatsLock.Unlock()
+	format := formats[lookupSerialNumber(serialNumber)]
+	if format == nil {
+		return nil, cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageNoFormatInProgress, serialNumber)
+	}
+	status := format.status
+	status.ElapsedMs = time.Since(format.start).Nanoseconds() / int64(time.Millisecond)
+	return &status, nil
+}
@@ -0,0 +1,51 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+// isNotFound returns true if the given error is a cerrors.NotFound error
+func isNotFound(err error) bool {
+	chapiErr, ok := err.(*cerrors.ChapiError)
+	return ok && chapiErr.ErrorCode() == cerrors.NotFound
+}
+
+func TestFormatStatus(t *testing.T) {
+	plugin := NewMultipathPlugin()
+	serialNumber := "2ab8d1c0d4b4a6d46c9ce9003bc8aa27"
+
+	if _, err := plugin.GetFormatStatus(serialNumber); !isNotFound(err) {
+		t.Fatalf("expected a NotFound error, got %v", err)
+	}
+
+	// The percent complete of a full format is polled
+	defer func(interval time.Duration) { formatProgressInterval = interval }(formatProgressInterval)
+	defer func(get func() (int, bool)) { getFormatPercentComplete = get }(getFormatPercentComplete)
+	formatProgressInterval = time.Millisecond
+	getFormatPercentComplete = func() (int, bool) { return 42, true }
+
+	stop := trackFormat(serialNumber, "NTFS", true)
+	var status *model.FormatStatus
+	var err error
+	for i := 0; i < 1000; i++ {
+		if status, err = plugin.GetFormatStatus(serialNumber); err != nil || status.PercentComplete != 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err != nil || status.FileSystem != "NTFS" || !status.FullFormat || status.PercentComplete != 42 {
+		t.Errorf("expected a 42%% complete NTFS full format, got %+v, err=%v", status, err)
+	}
+
+	// Once complete, the format is no longer reported
+	stop()
+	if _, err := plugin.GetFormatStatus(serialNumber); !isNotFound(err) {
+		t.Errorf("expected a NotFound error once complete, got %v", err)
+	}
+}
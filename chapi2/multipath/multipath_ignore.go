@@ -0,0 +1,195 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// IGNORED DEVICES
+//
+//		After a volume is deleted, lingering sessions can cause the host to rediscover the device
+//		and automatically reassemble it.  Device WWIDs added to the ignore list are hidden from
+//		GetDevices and GetAllDeviceDetails.  Under Linux, the ignored WWIDs are also written to a
+//		CHAPI managed multipath blacklist so that multipathd no longer creates maps for them.
+//
+//		The ignore list is persisted, as JSON, in ignoredDevicesPath.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+var (
+	// ignoredDevicesLock serializes read-modify-write access to the ignore list
+	ignoredDevicesLock sync.Mutex
+)
+
+// GetIgnoredDevices returns the device WWIDs that are ignored by device enumeration
+func (plugin *MultipathPlugin) GetIgnoredDevices() ([]*model.IgnoredDevice, error) {
+	ignoredDevicesLock.Lock()
+	defer ignoredDevicesLock.Unlock()
+
+	ignoredDevices, err := loadIgnoredDevices()
+	if err != nil {
+		log.Errorf("Unable to load ignored devices, err=%v", err)
+		return nil, cerrors.NewChapiError(err)
+	}
+	return ignoredDevices, nil
+}
+
+// AddIgnoredDevice adds the given device WWID to the ignore list (and, under Linux, the multipath
+// blacklist)
+func (plugin *MultipathPlugin) AddIgnoredDevice(wwid string) (*model.IgnoredDevice, error) {
+	log.Tracef(">>>>> AddIgnoredDevice, wwid=%v", wwid)
+	defer log.Trace("<<<<< AddIgnoredDevice")
+
+	wwid, err := normalizeWWID(wwid)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoredDevicesLock.Lock()
+	defer ignoredDevicesLock.Unlock()
+
+	ignoredDevices, err := loadIgnoredDevices()
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+	for _, ignoredDevice := range ignoredDevices {
+		if ignoredDevice.WWID == wwid {
+			// Already ignored; nothing to do
+			return ignoredDevice, nil
+		}
+	}
+
+	ignoredDevice := &model.IgnoredDevice{WWID: wwid}
+	if err = saveIgnoredDevices(append(ignoredDevices, ignoredDevice)); err != nil {
+		return nil, err
+	}
+	return ignoredDevice, nil
+}
+
+// RemoveIgnoredDevice removes the given device WWID from the ignore list (and, under Linux, the
+// multipath blacklist)
+func (plugin *MultipathPlugin) RemoveIgnoredDevice(wwid string) error {
+	log.Tracef(">>>>> RemoveIgnoredDevice, wwid=%v", wwid)
+	defer log.Trace("<<<<< RemoveIgnoredDevice")
+
+	wwid, err := normalizeWWID(wwid)
+	if err != nil {
+		return err
+	}
+
+	ignoredDevicesLock.Lock()
+	defer ignoredDevicesLock.Unlock()
+
+	ignoredDevices, err := loadIgnoredDevices()
+	if err != nil {
+		return cerrors.NewChapiError(err)
+	}
+	var remaining []*model.IgnoredDevice
+	for _, ignoredDevice := range ignoredDevices {
+		if ignoredDevice.WWID != wwid {
+			remaining = append(remaining, ignoredDevice)
+		}
+	}
+	if len(remaining) == len(ignoredDevices) {
+		err = cerrors.NewChapiErrorf(cerrors.NotFound, errorMessageIgnoredDeviceNotFound, wwid)
+		log.Error(err)
+		return err
+	}
+	return saveIgnoredDevices(remaining)
+}
+
+// filterIgnoredDevices removes the ignored and skipped (see multipath_skip.go) devices from the
+// given device list
+func filterIgnoredDevices(devices []*model.Device) []*model.Device {
+	ignoredDevicesLock.Lock()
+	ignoredDevices, err := loadIgnoredDevices()
+	ignoredDevicesLock.Unlock()
+	if err != nil {
+		log.Errorf("Unable to load ignored devices, err=%v", err)
+	}
+	skipList := getDeviceSkipList()
+	if len(ignoredDevices) == 0 && skipList.isEmpty() {
+		return devices
+	}
+
+	var filtered []*model.Device
+	for _, device := range devices {
+		if device != nil && isIgnoredDevice(ignoredDevices, device.SerialNumber) {
+			log.Tracef("Ignoring device, SerialNumber=%v", device.SerialNumber)
+			continue
+		}
+		if device != nil && skipList.isSkipped(device) {
+			log.Tracef("Skipping device, SerialNumber=%v", device.SerialNumber)
+			continue
+		}
+		filtered = append(filtered, device)
+	}
+	return filtered
+}
+
+// isIgnoredDevice returns true if the given serial number matches one of the ignored WWIDs.  The
+// multipath WWID is the serial number prefixed with the SCSI designator type (see SERIAL NUMBER
+// FORMATS in multipath_serial.go).
+func isIgnoredDevice(ignoredDevices []*model.IgnoredDevice, serialNumber string) bool {
+	for _, ignoredDevice := range ignoredDevices {
+		if SerialNumbersMatch(ignoredDevice.WWID, serialNumber) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeWWID validates the given WWID and returns it in lower case
+func normalizeWWID(wwid string) (string, error) {
+	wwid = strings.ToLower(strings.TrimSpace(wwid))
+	if wwid == "" || strings.ContainsAny(wwid, " \t\"\\/{}") {
+		err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidWWID, wwid)
+		log.Error(err)
+		return "", err
+	}
+	return wwid, nil
+}
+
+// loadIgnoredDevices returns the persisted ignore list
+func loadIgnoredDevices() ([]*model.IgnoredDevice, error) {
+	data, err := ioutil.ReadFile(ignoredDevicesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ignoredDevices []*model.IgnoredDevice
+	if err = json.Unmarshal(data, &ignoredDevices); err != nil {
+		return nil, err
+	}
+	return ignoredDevices, nil
+}
+
+// saveIgnoredDevices persists the given ignore list and updates the platform blacklist
+func saveIgnoredDevices(ignoredDevices []*model.IgnoredDevice) error {
+	if err := os.MkdirAll(filepath.Dir(ignoredDevicesPath), 0700); err != nil {
+		return cerrors.NewChapiError(err)
+	}
+	data, err := json.Marshal(ignoredDevices)
+	if err != nil {
+		return cerrors.NewChapiError(err)
+	}
+	if err = ioutil.WriteFile(ignoredDevicesPath, data, 0600); err != nil {
+		log.Errorf("Unable to save ignored devices, err=%v", err)
+		return cerrors.NewChapiError(err)
+	}
+	return updateDeviceBlacklist(ignoredDevices)
+}
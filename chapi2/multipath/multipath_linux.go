@@ -3,28 +3,183 @@
 package multipath
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/linux"
 	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/sgio"
+	"github.com/hpe-storage/common-host-libs/util"
+)
+
+const (
+	sysBlockQueueAttribute   = "%v/%v/queue/%v"           // e.g. /sys/block/sdb/queue/read_ahead_kb
+	sysBlockSlaves           = "%v/%v/slaves"             // e.g. /sys/block/dm-3/slaves
+	sysBlockDeviceQueueDepth = "%v/%v/device/queue_depth" // e.g. /sys/block/sdb/device/queue_depth
+	sysBlockDeviceAttribute  = "%v/%v/device/%v"          // e.g. /sys/block/sdb/device/access_state
+	sysBlockDevice           = "%v/%v/device"             // e.g. /sys/block/sdb/device -> ../../../3:0:0:1
+	sysBlockDmAttribute      = "%v/%v/dm/%v"              // e.g. /sys/block/dm-3/dm/uuid
+	sysBlockSize             = "%v/%v/size"               // e.g. /sys/block/dm-3/size (512 byte sectors)
+
+	// dm device attributes
+	dmName = "name"
+	dmUUID = "uuid"
+
+	// dmDevicePrefix prefixes the kernel name of device-mapper devices (e.g. "dm-3")
+	dmDevicePrefix = "dm-"
+
+	// nimbleVendor is the SCSI inquiry vendor of Nimble volumes
+	nimbleVendor = "Nimble"
+	sectorSize   = 512
+
+	queueReadAheadKB = "read_ahead_kb"
+	queueNrRequests  = "nr_requests"
+	queueScheduler   = "scheduler"
+	queueRqAffinity  = "rq_affinity"
+
+	// SCSI device attributes (access_state and preferred_path are provided by scsi_dh_alua)
+	deviceState         = "state"
+	deviceAccessState   = "access_state"
+	devicePreferredPath = "preferred_path"
+
+	// multipathd path device-mapper states
+	pathDmStateFailed = "failed"
+
+	multipathdCommand = "multipathd"
+	blockdevCommand   = "blockdev"
+)
+
+var (
+	sysBlockPath  = "/sys/block"
+	devMapperPath = "/dev/mapper"
+
+	ignoredDevicesPath     = "/var/lib/hpe-storage/chapi/ignored_devices.json"
+	multipathBlacklistPath = "/etc/multipath/conf.d/hpe-chapi-blacklist.conf"
+
+	// ALUA queries sent to the array; variables so that tests can replace them
+	getTargetPortGroup  = sgio.GetTargetPortGroup
+	getTargetPortGroups = sgio.GetTargetPortGroups
+
+	// Path recovery commands; variables so that tests can replace them
+	getPathDmStates = multipathdPathDmStates
+	testUnitReady   = sgio.TestUnitReady
+	reinstatePath   = multipathdReinstatePath
 )
 
-// getDevices enumerates all the Nimble volumes while only providing basic details (e.g. serial number).
-// If a "serialNumber" is passed in, only that specific serial number is enumerated.
 // getDevices enumerates all the Nimble volumes while only providing basic details (e.g. serial number).
 // If a "serialNumber" is passed in, only that specific serial number is enumerated.
 func (plugin *MultipathPlugin) getDevices(serialNumber string) ([]*model.Device, error) {
 	log.Tracef(">>>>> getDevices, serialNumber=%v", serialNumber)
 	defer log.Trace("<<<<< getDevices")
-	// TODO
-	return nil, nil
+
+	return getNimbleDmDevices(serialNumber, false)
 }
 
-// getDevices enumerates all the Nimble volumes while providing full details about the device.
-// If a "serialNumber" is passed in, only that specific serial number is enumerated.
-func (plugin *MultipathPlugin) getAllDeviceDetails(serialNumber string) ([]*model.Device, error) {
+// getAllDeviceDetails enumerates all the Nimble volumes while providing full details about the
+// device.  If a "serialNumber" is passed in, only that specific serial number is enumerated.  Only
+// the given device fields need to be populated.  The iSCSI target is not enumerated under Linux.
+func (plugin *MultipathPlugin) getAllDeviceDetails(serialNumber string, fields deviceFields) ([]*model.Device, error) {
 	log.Trace(">>>>> getAllDeviceDetails")
 	defer log.Trace("<<<<< getAllDeviceDetails")
-	// TODO
-	return nil, nil
+
+	return getNimbleDmDevices(serialNumber, true)
+}
+
+// getNimbleDmDevices enumerates the multipath devices of Nimble volumes from sysfs, only the device
+// with the given serial number if one is passed in.  A multipath device has a dm uuid of "mpath-"
+// followed by its WWID; it is a Nimble volume if its paths report the Nimble SCSI vendor.  If
+// allDetails is true, the device name, size and paths are populated as well.
+func getNimbleDmDevices(serialNumber string, allDetails bool) ([]*model.Device, error) {
+	entries, err := ioutil.ReadDir(sysBlockPath)
+	if err != nil {
+		log.Errorf("Unable to enumerate block devices, err=%v", err)
+		return nil, cerrors.NewChapiError(err)
+	}
+
+	var devices []*model.Device
+	for _, entry := range entries {
+		pathname := entry.Name()
+		if !strings.HasPrefix(pathname, dmDevicePrefix) {
+			continue
+		}
+		uuid := readDmAttribute(pathname, dmUUID)
+		if !strings.HasPrefix(uuid, dmUUIDPrefix) {
+			// Not a multipath device (e.g. LVM logical volume)
+			continue
+		}
+		deviceSerialNumber := CanonicalSerialNumber(uuid)
+		if serialNumber != "" && deviceSerialNumber != serialNumber {
+			continue
+		}
+		slaves, err := getDeviceSlaves(pathname)
+		if err != nil || !isNimbleDevice(slaves) {
+			continue
+		}
+
+		device := &model.Device{SerialNumber: deviceSerialNumber, Pathname: pathname}
+		if allDetails {
+			if name := readDmAttribute(pathname, dmName); name != "" {
+				device.AltFullPathName = filepath.Join(devMapperPath, name)
+			}
+			if sectors, err := readUintAttribute(fmt.Sprintf(sysBlockSize, sysBlockPath, pathname)); err == nil {
+				device.Size = *sectors * sectorSize
+			}
+			device.Private = &model.DevicePrivate{Paths: getDevicePathDetails(slaves)}
+		}
+		log.Tracef("SerialNumber=%v, Pathname=%v, AltFullPathName=%v", device.SerialNumber, device.Pathname, device.AltFullPathName)
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// isNimbleDevice returns true if the first path, of the given paths, reporting a SCSI vendor is a
+// Nimble volume
+func isNimbleDevice(slaves []string) bool {
+	for _, slave := range slaves {
+		if vendor := readDeviceAttribute(slave, "vendor"); vendor != "" {
+			return vendor == nimbleVendor
+		}
+	}
+	return false
+}
+
+// getDevicePathDetails returns the path details (name, H:C:T:L and state) of the given paths
+func getDevicePathDetails(slaves []string) []model.Path {
+	var paths []model.Path
+	for _, slave := range slaves {
+		path := model.Path{Name: slave, State: readDeviceAttribute(slave, deviceState)}
+		if link, err := os.Readlink(fmt.Sprintf(sysBlockDevice, sysBlockPath, slave)); err == nil {
+			path.Hcils = filepath.Base(link)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// readDmAttribute returns the trimmed value of the given dm device attribute, or an empty string
+// if the attribute is not present
+func readDmAttribute(pathname string, attribute string) string {
+	data, err := ioutil.ReadFile(fmt.Sprintf(sysBlockDmAttribute, sysBlockPath, pathname, attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// getAttachedDevices enumerates, with full details, the device with the given serial number once
+// it has been attached
+func (plugin *MultipathPlugin) getAttachedDevices(serialNumber string, blockDev model.BlockDeviceAccessInfo) ([]*model.Device, error) {
+	return plugin.getAllDeviceDetails(serialNumber, nil)
 }
 
 // getPartitionInfo enumerates the partitions on the given volume
@@ -44,11 +199,496 @@ func (plugin *MultipathPlugin) offlineDevice(device model.Device) error {
 	return nil
 }
 
+// flushDevice flushes the given device's buffer cache (blockdev --flushbufs)
+func (plugin *MultipathPlugin) flushDevice(device model.Device) error {
+	log.Tracef(">>>>> flushDevice, Pathname=%v", device.Pathname)
+	defer log.Trace("<<<<< flushDevice")
+
+	devPath := device.AltFullPathName
+	if devPath == "" {
+		if device.Pathname == "" {
+			return cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+		}
+		devPath = "/dev/" + device.Pathname
+	}
+
+	if _, _, err := util.ExecCommandOutput(blockdevCommand, []string{"--flushbufs", devPath}); err != nil {
+		log.Errorf("Unable to flush %v, err=%v", devPath, err)
+		return cerrors.NewChapiError(err)
+	}
+	return nil
+}
+
 // createFileSystem is called to create a file system on the given device
-func (plugin *MultipathPlugin) createFileSystem(device model.Device, filesystem string) error {
-	log.Tracef(">>>>> createFileSystem")
+func (plugin *MultipathPlugin) createFileSystem(device model.Device, filesystem string, fsOptions *model.FileSystemOptions) error {
+	log.Tracef(">>>>> createFileSystem, Pathname=%v, filesystem=%v, noDiscard=%v", device.Pathname, filesystem, fsOptions.NoDiscard)
 	defer log.Trace("<<<<< createFileSystem")
 
-	// TODO
+	devPath := device.AltFullPathName
+	if devPath == "" {
+		if device.Pathname == "" {
+			return cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+		}
+		devPath = "/dev/" + device.Pathname
+	}
+
+	if err := linux.RetryCreateFileSystemWithOptions(devPath, filesystem, getMkfsOptions(filesystem, fsOptions)); err != nil {
+		log.Errorf("Unable to create %v file system on %v, err=%v", filesystem, devPath, err)
+		return cerrors.NewChapiError(err)
+	}
 	return nil
 }
+
+// getFileSystemUUID returns the UUID of the file system on the given device
+func (plugin *MultipathPlugin) getFileSystemUUID(device model.Device) (string, error) {
+	devPath := device.AltFullPathName
+	if devPath == "" {
+		if device.Pathname == "" {
+			return "", cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+		}
+		devPath = "/dev/" + device.Pathname
+	}
+
+	fsUUID, _, err := linux.GetFilesystemIdentity(devPath)
+	if err != nil {
+		log.Errorf("Unable to read file system UUID of %v, err=%v", devPath, err)
+		return "", cerrors.NewChapiError(err)
+	}
+	return fsUUID, nil
+}
+
+// benchmarkDevice opens the given device for direct I/O (O_DIRECT) and measures its read latency
+func (plugin *MultipathPlugin) benchmarkDevice(device model.Device, request model.BenchmarkRequest) (*model.BenchmarkResult, error) {
+	log.Tracef(">>>>> benchmarkDevice, Pathname=%v", device.Pathname)
+	defer log.Trace("<<<<< benchmarkDevice")
+
+	devPath, err := getProbePath(device)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := openDirect(devPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Read the block device size if it wasn't enumerated
+	size := device.Size
+	if size == 0 {
+		end, err := file.Seek(0, io.SeekEnd)
+		if err != nil {
+			log.Errorf("Unable to determine the size of %v, err=%v", devPath, err)
+			return nil, cerrors.NewChapiError(err)
+		}
+		size = uint64(end)
+	}
+
+	return runBenchmark(file, size, request.BlockSize, time.Duration(request.DurationSeconds)*time.Second)
+}
+
+// getProbePath returns the block device path of the given device read by the direct I/O probes
+func getProbePath(device model.Device) (string, error) {
+	if device.AltFullPathName != "" {
+		return device.AltFullPathName, nil
+	}
+	if device.Pathname == "" {
+		return "", cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+	return "/dev/" + device.Pathname, nil
+}
+
+// openDirect opens the given block device read-only for direct I/O (O_DIRECT)
+func openDirect(devPath string) (*os.File, error) {
+	file, err := os.OpenFile(devPath, os.O_RDONLY|syscall.O_DIRECT, 0)
+	if err != nil {
+		log.Errorf("Unable to open %v for direct I/O, err=%v", devPath, err)
+		return nil, cerrors.NewChapiError(err)
+	}
+	return file, nil
+}
+
+// isReservationConflictError returns true if the I/O error was caused by a RESERVATION CONFLICT
+// status, which the kernel reports as EBADE (BLK_STS_NEXUS)
+func isReservationConflictError(err error) bool {
+	return errors.Is(err, syscall.EBADE)
+}
+
+// getMkfsOptions returns the mkfs options for the given file system and file system options
+func getMkfsOptions(filesystem string, fsOptions *model.FileSystemOptions) []string {
+	var options []string
+	if fsOptions.NoDiscard {
+		switch filesystem {
+		case "xfs", "btrfs":
+			options = append(options, "-K")
+		case "ext2", "ext3", "ext4":
+			options = append(options, "-E", "nodiscard")
+		}
+	}
+	return options
+}
+
+// getDeviceTuning reports the current queue settings of the given device.  Block queue settings
+// are read from the multipath (dm) device while the SCSI queue depth is read from the first path.
+func (plugin *MultipathPlugin) getDeviceTuning(device model.Device) (*model.DeviceTuning, error) {
+	log.Tracef(">>>>> getDeviceTuning, Pathname=%v", device.Pathname)
+	defer log.Trace("<<<<< getDeviceTuning")
+
+	if device.Pathname == "" {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+
+	tuning := &model.DeviceTuning{}
+	var err error
+	if tuning.ReadAheadKB, err = readQueueAttribute(device.Pathname, queueReadAheadKB); err != nil {
+		return nil, err
+	}
+
+	slaves, err := getDeviceSlaves(device.Pathname)
+	if err != nil {
+		return nil, err
+	}
+	if len(slaves) == 0 {
+		return tuning, nil
+	}
+
+	// Request based dm-multipath hands I/O straight to the paths, so the remaining settings only
+	// have meaning on the underlying SCSI devices.
+	if tuning.NrRequests, err = readQueueAttribute(slaves[0], queueNrRequests); err != nil {
+		return nil, err
+	}
+	if tuning.RqAffinity, err = readQueueAttribute(slaves[0], queueRqAffinity); err != nil {
+		return nil, err
+	}
+	if tuning.QueueDepth, err = readUintAttribute(fmt.Sprintf(sysBlockDeviceQueueDepth, sysBlockPath, slaves[0])); err != nil {
+		return nil, err
+	}
+	scheduler, err := util.FileReadFirstLine(fmt.Sprintf(sysBlockQueueAttribute, sysBlockPath, slaves[0], queueScheduler))
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+	tuning.Scheduler = parseActiveScheduler(scheduler)
+
+	return tuning, nil
+}
+
+// setDeviceTuning applies the provided queue settings to the multipath (dm) device and all of its
+// underlying paths.  Properties that are not set in "tuning" are left unchanged.
+func (plugin *MultipathPlugin) setDeviceTuning(device model.Device, tuning model.DeviceTuning) error {
+	log.Tracef(">>>>> setDeviceTuning, Pathname=%v", device.Pathname)
+	defer log.Trace("<<<<< setDeviceTuning")
+
+	if device.Pathname == "" {
+		return cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+
+	slaves, err := getDeviceSlaves(device.Pathname)
+	if err != nil {
+		return err
+	}
+
+	// read_ahead_kb is honored by the dm device itself, so it needs to be set on the map as well
+	// as on each path (the paths are used directly by some tools like blkid)
+	if tuning.ReadAheadKB != nil {
+		for _, dev := range append([]string{device.Pathname}, slaves...) {
+			if err = writeQueueAttribute(dev, queueReadAheadKB, strconv.FormatUint(*tuning.ReadAheadKB, 10)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, slave := range slaves {
+		if tuning.Scheduler != "" {
+			if err = writeQueueAttribute(slave, queueScheduler, tuning.Scheduler); err != nil {
+				return err
+			}
+		}
+		if tuning.NrRequests != nil {
+			if err = writeQueueAttribute(slave, queueNrRequests, strconv.FormatUint(*tuning.NrRequests, 10)); err != nil {
+				return err
+			}
+		}
+		if tuning.RqAffinity != nil {
+			if err = writeQueueAttribute(slave, queueRqAffinity, strconv.FormatUint(*tuning.RqAffinity, 10)); err != nil {
+				return err
+			}
+		}
+		if tuning.QueueDepth != nil {
+			if err = writeSysfsAttribute(fmt.Sprintf(sysBlockDeviceQueueDepth, sysBlockPath, slave), strconv.FormatUint(*tuning.QueueDepth, 10)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// extendPartition extends the last partition on the given device to fill the device
+func (plugin *MultipathPlugin) extendPartition(device model.Device) ([]*model.DevicePartition, error) {
+	log.Tracef(">>>>> extendPartition, Pathname=%v", device.Pathname)
+	defer log.Trace("<<<<< extendPartition")
+
+	// TODO - CHAPI does not partition Linux devices; file systems are created on the whole device
+	return nil, cerrors.NewChapiError(cerrors.Unimplemented)
+}
+
+// getDevicePaths reports the paths (slaves) of the given multipath device along with their ALUA
+// state.  The access state is read from sysfs when scsi_dh_alua is attached to the path, else it
+// is queried from the array with REPORT TARGET PORT GROUPS.
+func (plugin *MultipathPlugin) getDevicePaths(device model.Device) ([]*model.DevicePath, error) {
+	log.Tracef(">>>>> getDevicePaths, Pathname=%v", device.Pathname)
+	defer log.Trace("<<<<< getDevicePaths")
+
+	if device.Pathname == "" {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+
+	slaves, err := getDeviceSlaves(device.Pathname)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []*model.DevicePath
+	var targetPortGroups []*sgio.TargetPortGroup
+	targetPortGroupsReported := false
+	for _, slave := range slaves {
+		path := &model.DevicePath{Name: slave, AccessState: model.AluaUnknown}
+		path.State = readDeviceAttribute(slave, deviceState)
+
+		devicePath := "/dev/" + slave
+		if id, err := getTargetPortGroup(devicePath); err == nil {
+			path.TargetPortGroup = &id
+		}
+
+		if accessState := readDeviceAttribute(slave, deviceAccessState); accessState != "" {
+			path.AccessState = accessState
+			path.Preferred = readDeviceAttribute(slave, devicePreferredPath) == "1"
+		} else if path.TargetPortGroup != nil {
+			// Every path reports the same target port groups, so only ask the array once
+			if !targetPortGroupsReported {
+				targetPortGroupsReported = true
+				if targetPortGroups, err = getTargetPortGroups(devicePath); err != nil {
+					log.Errorf("Unable to report target port groups for %v, err=%v", devicePath, err)
+				}
+			}
+			for _, group := range targetPortGroups {
+				if group.ID == *path.TargetPortGroup {
+					path.AccessState = group.AccessState
+					path.Preferred = group.Preferred
+				}
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// recoverDevicePaths reinstates the failed paths (slaves) of the given multipath device that
+// respond to TEST UNIT READY.  Paths that multipathd failed during an array controller failover may
+// stay failed once the controller is back, until they are reinstated.
+func (plugin *MultipathPlugin) recoverDevicePaths(device model.Device) (*model.PathRecoveryReport, error) {
+	log.Tracef(">>>>> recoverDevicePaths, Pathname=%v", device.Pathname)
+	defer log.Trace("<<<<< recoverDevicePaths")
+
+	if device.Pathname == "" {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+
+	slaves, err := getDeviceSlaves(device.Pathname)
+	if err != nil {
+		return nil, err
+	}
+	dmStates, err := getPathDmStates()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.PathRecoveryReport{}
+	for _, slave := range slaves {
+		if dmStates[slave] != pathDmStateFailed {
+			continue
+		}
+		path := &model.PathRecovery{Name: slave, Status: model.PathRecoveryReinstated}
+		if err = probePath("/dev/" + slave); err != nil {
+			path.Status, path.Error = model.PathRecoveryUnreachable, err.Error()
+		} else if err = reinstatePath(slave); err != nil {
+			path.Status, path.Error = model.PathRecoveryFailed, err.Error()
+		}
+		report.Paths = append(report.Paths, path)
+	}
+	return report, nil
+}
+
+// probePath sends TEST UNIT READY to the given path.  The first command after a failover usually
+// fails with a UNIT ATTENTION (e.g. asymmetric access state changed), so it is sent once more.  A
+// path that is ready must also serve a direct read (unless the probes are disabled).
+func probePath(devicePath string) error {
+	err := testUnitReady(devicePath)
+	if scsiErr, ok := err.(*sgio.ScsiError); ok && scsiErr.Sense != nil && scsiErr.Sense.SenseKey == sgio.SenseKeyUnitAttention {
+		err = testUnitReady(devicePath)
+	}
+	if err != nil {
+		return err
+	}
+	if timeout := getDeviceProbeTimeout(); timeout != 0 {
+		return directReadProbe(devicePath, timeout)
+	}
+	return nil
+}
+
+// multipathdPathDmStates returns the device-mapper state (e.g. "active", "failed") of every path
+// known to multipathd, by path name
+func multipathdPathDmStates() (map[string]string, error) {
+	out, rc, err := util.ExecCommandOutput(multipathdCommand, []string{"show", "paths", "format", "%d %t"})
+	if err != nil || rc != 0 {
+		log.Errorf("Unable to show multipathd paths, rc=%v, out=%v, err=%v", rc, out, err)
+		return nil, cerrors.NewChapiErrorf(cerrors.Internal, errorMessageMultipathdFailed, "show paths", out)
+	}
+	return parsePathDmStates(out), nil
+}
+
+// parsePathDmStates parses the output of "multipathd show paths format '%d %t'".  Older
+// multipathd versions report the states in brackets (e.g. "[failed]").
+func parsePathDmStates(out string) map[string]string {
+	dmStates := make(map[string]string)
+	for _, path := range util.ParseColumnOutput(out) {
+		if path["dev"] != "" {
+			dmStates[path["dev"]] = path["dm_st"]
+		}
+	}
+	return dmStates
+}
+
+// multipathdReinstatePath asks multipathd to reinstate the given failed path
+func multipathdReinstatePath(path string) error {
+	log.Infof("Reinstating path %v", path)
+	out, rc, err := util.ExecCommandOutput(multipathdCommand, []string{"reinstate", "path", path})
+	if err != nil || rc != 0 || strings.TrimSpace(out) != "ok" {
+		log.Errorf("Unable to reinstate path %v, rc=%v, out=%v, err=%v", path, rc, out, err)
+		return cerrors.NewChapiErrorf(cerrors.Internal, errorMessageMultipathdFailed, "reinstate path "+path, strings.TrimSpace(out))
+	}
+	return nil
+}
+
+// readDeviceAttribute returns the trimmed value of the given SCSI device attribute, or an empty
+// string if the attribute is not present (e.g. access_state without scsi_dh_alua)
+func readDeviceAttribute(dev string, attribute string) string {
+	data, err := ioutil.ReadFile(fmt.Sprintf(sysBlockDeviceAttribute, sysBlockPath, dev, attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// getInquiryProduct returns the SCSI inquiry product of the given device's first path, or an empty
+// string if it is unknown
+func getInquiryProduct(device *model.Device) string {
+	var paths []string
+	if device.Private != nil {
+		for _, path := range device.Private.Paths {
+			paths = append(paths, path.Name)
+		}
+	}
+	if len(paths) == 0 && device.Pathname != "" {
+		paths, _ = getDeviceSlaves(device.Pathname)
+	}
+	for _, path := range paths {
+		if product := readDeviceAttribute(path, "model"); product != "" {
+			return product
+		}
+	}
+	return ""
+}
+
+// getDeviceSlaves returns the block devices (e.g. "sdb", "sdc") that make up the given dm device
+func getDeviceSlaves(pathname string) ([]string, error) {
+	entries, err := ioutil.ReadDir(fmt.Sprintf(sysBlockSlaves, sysBlockPath, pathname))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, cerrors.NewChapiError(err)
+	}
+	var slaves []string
+	for _, entry := range entries {
+		slaves = append(slaves, entry.Name())
+	}
+	return slaves, nil
+}
+
+// readQueueAttribute reads an unsigned integer block queue attribute for the given device
+func readQueueAttribute(dev string, attribute string) (*uint64, error) {
+	return readUintAttribute(fmt.Sprintf(sysBlockQueueAttribute, sysBlockPath, dev, attribute))
+}
+
+// readUintAttribute reads an unsigned integer from the given sysfs attribute
+func readUintAttribute(path string) (*uint64, error) {
+	line, err := util.FileReadFirstLine(path)
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(line), 10, 64)
+	if err != nil {
+		return nil, cerrors.NewChapiError(cerrors.Internal, err)
+	}
+	return &value, nil
+}
+
+// writeQueueAttribute writes a block queue attribute for the given device
+func writeQueueAttribute(dev string, attribute string, value string) error {
+	return writeSysfsAttribute(fmt.Sprintf(sysBlockQueueAttribute, sysBlockPath, dev, attribute), value)
+}
+
+// writeSysfsAttribute writes the value to the given sysfs attribute
+func writeSysfsAttribute(path string, value string) error {
+	log.Infof("Setting %v to %v", path, value)
+	if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageTuningNotApplied, value, path, err)
+	}
+	return nil
+}
+
+// updateDeviceBlacklist rewrites the CHAPI managed multipath blacklist with the ignored device
+// WWIDs and asks multipathd to reload its configuration
+func updateDeviceBlacklist(ignoredDevices []*model.IgnoredDevice) error {
+	log.Tracef(">>>>> updateDeviceBlacklist, count=%v", len(ignoredDevices))
+	defer log.Trace("<<<<< updateDeviceBlacklist")
+
+	if len(ignoredDevices) == 0 {
+		if err := os.Remove(multipathBlacklistPath); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Unable to remove %v, err=%v", multipathBlacklistPath, err)
+			return cerrors.NewChapiError(err)
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(multipathBlacklistPath), 0755); err != nil {
+			return cerrors.NewChapiError(err)
+		}
+		if err := ioutil.WriteFile(multipathBlacklistPath, []byte(getMultipathBlacklist(ignoredDevices)), 0644); err != nil {
+			log.Errorf("Unable to write %v, err=%v", multipathBlacklistPath, err)
+			return cerrors.NewChapiError(err)
+		}
+	}
+
+	// The blacklist is persisted; if multipathd is not running it is applied when it starts
+	if out, rc, err := util.ExecCommandOutput(multipathdCommand, []string{"reconfigure"}); err != nil || rc != 0 {
+		log.Errorf("Unable to reconfigure multipathd, rc=%v, out=%v, err=%v", rc, out, err)
+	}
+	return nil
+}
+
+// getMultipathBlacklist returns the multipath.conf blacklist section for the ignored devices
+func getMultipathBlacklist(ignoredDevices []*model.IgnoredDevice) string {
+	blacklist := "# Managed by CHAPI, do not edit\nblacklist {\n"
+	for _, ignoredDevice := range ignoredDevices {
+		blacklist += fmt.Sprintf("\twwid \"^%v$\"\n", ignoredDevice.WWID)
+	}
+	return blacklist + "}\n"
+}
+
+// isFullFormatSupported is false under Linux; mkfs has no full format option
+const isFullFormatSupported = false
+
+// getPlatformFormatPercentComplete is not supported under Linux
+func getPlatformFormatPercentComplete() (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,398 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/sgio"
+)
+
+func TestIgnoredDevices(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "ignored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	savedIgnoredDevicesPath, savedBlacklistPath := ignoredDevicesPath, multipathBlacklistPath
+	defer func() { ignoredDevicesPath, multipathBlacklistPath = savedIgnoredDevicesPath, savedBlacklistPath }()
+	ignoredDevicesPath = filepath.Join(tempDir, "chapi", "ignored_devices.json")
+	multipathBlacklistPath = filepath.Join(tempDir, "conf.d", "hpe-chapi-blacklist.conf")
+
+	plugin := NewMultipathPlugin()
+	if _, err = plugin.AddIgnoredDevice("bad wwid"); err == nil {
+		t.Error("expected error for invalid WWID")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err = plugin.AddIgnoredDevice("2F4C97C5C1CD391756C9CE900584F2795"); err != nil {
+			t.Fatalf("AddIgnoredDevice failed, err=%v", err)
+		}
+	}
+	ignoredDevices, err := plugin.GetIgnoredDevices()
+	if err != nil || len(ignoredDevices) != 1 || ignoredDevices[0].WWID != "2f4c97c5c1cd391756c9ce900584f2795" {
+		t.Fatalf("unexpected ignored devices %v, err=%v", ignoredDevices, err)
+	}
+
+	blacklist, err := ioutil.ReadFile(multipathBlacklistPath)
+	if err != nil {
+		t.Fatalf("blacklist not written, err=%v", err)
+	}
+	if !strings.Contains(string(blacklist), `wwid "^2f4c97c5c1cd391756c9ce900584f2795$"`) {
+		t.Errorf("unexpected blacklist %v", string(blacklist))
+	}
+
+	// The ignored device is matched by serial number (WWID without the designator type)
+	devices := []*model.Device{{SerialNumber: "f4c97c5c1cd391756c9ce900584f2795"}, {SerialNumber: "c5a28c28a2487d3d6c9ce900584f2795"}}
+	if filtered := filterIgnoredDevices(devices); len(filtered) != 1 || filtered[0].SerialNumber != devices[1].SerialNumber {
+		t.Errorf("unexpected filtered devices %v", filtered)
+	}
+
+	if err = plugin.RemoveIgnoredDevice("2f4c97c5c1cd391756c9ce900584f2795"); err != nil {
+		t.Fatalf("RemoveIgnoredDevice failed, err=%v", err)
+	}
+	if err = plugin.RemoveIgnoredDevice("2f4c97c5c1cd391756c9ce900584f2795"); err == nil {
+		t.Error("expected error removing a WWID that is not ignored")
+	}
+	if _, err = os.Stat(multipathBlacklistPath); !os.IsNotExist(err) {
+		t.Errorf("expected blacklist to be removed, err=%v", err)
+	}
+	if filtered := filterIgnoredDevices(devices); len(filtered) != 2 {
+		t.Errorf("unexpected filtered devices %v", filtered)
+	}
+}
+
+func TestGetMkfsOptions(t *testing.T) {
+	tests := []struct {
+		filesystem string
+		noDiscard  bool
+		expected   []string
+	}{
+		{"xfs", false, nil},
+		{"xfs", true, []string{"-K"}},
+		{"btrfs", true, []string{"-K"}},
+		{"ext4", true, []string{"-E", "nodiscard"}},
+		{"ext3", true, []string{"-E", "nodiscard"}},
+		{"vfat", true, nil},
+	}
+	for _, tc := range tests {
+		options := getMkfsOptions(tc.filesystem, &model.FileSystemOptions{NoDiscard: tc.noDiscard})
+		if !reflect.DeepEqual(options, tc.expected) {
+			t.Errorf("getMkfsOptions(%v, noDiscard=%v) = %v, expected %v", tc.filesystem, tc.noDiscard, options, tc.expected)
+		}
+	}
+}
+
+func TestGetDevicePaths(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "devicepaths")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// dm-3 has three paths; sdb and sdc are attached to scsi_dh_alua while sdd is not
+	writeAttribute := func(path string, value string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, slave := range []string{"sdb", "sdc", "sdd"} {
+		writeAttribute(filepath.Join(tempDir, "dm-3", "slaves", slave), "")
+		writeAttribute(filepath.Join(tempDir, slave, "device", deviceState), "running")
+	}
+	writeAttribute(filepath.Join(tempDir, "sdb", "device", deviceAccessState), model.AluaActiveOptimized)
+	writeAttribute(filepath.Join(tempDir, "sdb", "device", devicePreferredPath), "1")
+	writeAttribute(filepath.Join(tempDir, "sdc", "device", deviceAccessState), model.AluaStandby)
+	writeAttribute(filepath.Join(tempDir, "sdc", "device", devicePreferredPath), "0")
+
+	savedSysBlockPath, savedGetTargetPortGroup, savedGetTargetPortGroups := sysBlockPath, getTargetPortGroup, getTargetPortGroups
+	defer func() {
+		sysBlockPath, getTargetPortGroup, getTargetPortGroups = savedSysBlockPath, savedGetTargetPortGroup, savedGetTargetPortGroups
+	}()
+	sysBlockPath = tempDir
+	getTargetPortGroup = func(device string) (uint16, error) {
+		return map[string]uint16{"/dev/sdb": 1, "/dev/sdc": 2, "/dev/sdd": 1}[device], nil
+	}
+	getTargetPortGroups = func(device string) ([]*sgio.TargetPortGroup, error) {
+		return []*sgio.TargetPortGroup{
+			{ID: 1, AccessState: sgio.AluaActiveOptimized, Preferred: true},
+			{ID: 2, AccessState: sgio.AluaStandby},
+		}, nil
+	}
+
+	groups, err := NewMultipathPlugin().GetDevicePaths(model.Device{Pathname: "dm-3"})
+	if err != nil {
+		t.Fatalf("GetDevicePaths failed, err=%v", err)
+	}
+	if len(groups) != 2 || groups[0].AccessState != model.AluaActiveOptimized || groups[1].AccessState != model.AluaStandby {
+		t.Fatalf("unexpected path groups %+v", groups)
+	}
+
+	// sdd's access state is reported by the array
+	var optimized []string
+	for _, path := range groups[0].Paths {
+		if !path.Preferred || path.State != "running" || path.TargetPortGroup == nil || *path.TargetPortGroup != 1 {
+			t.Errorf("unexpected optimized path %+v", path)
+		}
+		optimized = append(optimized, path.Name)
+	}
+	if strings.Join(optimized, ",") != "sdb,sdd" {
+		t.Errorf("unexpected optimized paths %v", optimized)
+	}
+	if len(groups[1].Paths) != 1 || groups[1].Paths[0].Name != "sdc" || groups[1].Paths[0].Preferred {
+		t.Errorf("unexpected standby paths %+v", groups[1].Paths)
+	}
+}
+
+func TestGetNimbleDmDevices(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "dmdevices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	writeAttribute := func(path string, value string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// dm-3 is a Nimble volume, dm-4 an LVM logical volume and dm-5 another vendor's volume
+	const serialNumber = "f4c97c5c1cd391756c9ce900584f2795"
+	writeAttribute(filepath.Join(tempDir, "dm-3", "dm", "uuid"), SerialNumberToDmUUID(serialNumber))
+	writeAttribute(filepath.Join(tempDir, "dm-3", "dm", "name"), "mpathg")
+	writeAttribute(filepath.Join(tempDir, "dm-3", "size"), "2097152")
+	writeAttribute(filepath.Join(tempDir, "dm-3", "slaves", "sdb"), "")
+	writeAttribute(filepath.Join(tempDir, "sdb", "device", "vendor"), "Nimble  ")
+	writeAttribute(filepath.Join(tempDir, "sdb", "device", deviceState), "running")
+	writeAttribute(filepath.Join(tempDir, "dm-4", "dm", "uuid"), "LVM-Nc3bVGjF8UC0P7dm")
+	writeAttribute(filepath.Join(tempDir, "dm-5", "dm", "uuid"), "mpath-360002ac0000000000000000a00021f6b")
+	writeAttribute(filepath.Join(tempDir, "dm-5", "slaves", "sdc"), "")
+	writeAttribute(filepath.Join(tempDir, "sdc", "device", "vendor"), "3PARdata")
+
+	savedSysBlockPath := sysBlockPath
+	defer func() { sysBlockPath = savedSysBlockPath }()
+	sysBlockPath = tempDir
+
+	plugin := NewMultipathPlugin()
+	devices, err := plugin.GetDevices("")
+	if err != nil {
+		t.Fatalf("GetDevices failed, err=%v", err)
+	}
+	if len(devices) != 1 || devices[0].SerialNumber != serialNumber || devices[0].Pathname != "dm-3" || devices[0].AltFullPathName != "" {
+		t.Fatalf("unexpected devices %+v", devices)
+	}
+
+	devices, err = plugin.GetAllDeviceDetails(strings.ToUpper(serialNumber))
+	if err != nil {
+		t.Fatalf("GetAllDeviceDetails failed, err=%v", err)
+	}
+	if len(devices) != 1 || devices[0].AltFullPathName != "/dev/mapper/mpathg" || devices[0].Size != 1<<30 {
+		t.Fatalf("unexpected devices %+v", devices)
+	}
+	if paths := devices[0].Private.Paths; len(paths) != 1 || paths[0].Name != "sdb" || paths[0].State != "running" {
+		t.Errorf("unexpected paths %+v", paths)
+	}
+
+	if devices, err = plugin.GetDevices("c5a28c28a2487d3d6c9ce900584f2795"); err != nil || len(devices) != 0 {
+		t.Errorf("unexpected devices %+v, err=%v", devices, err)
+	}
+}
+
+func TestParsePathDmStates(t *testing.T) {
+	out := "dev dm_st\nsdb active\nsdc failed\nsdd [failed]\n\n"
+	expected := map[string]string{"sdb": "active", "sdc": "failed", "sdd": "failed"}
+	if dmStates := parsePathDmStates(out); !reflect.DeepEqual(dmStates, expected) {
+		t.Errorf("parsePathDmStates = %v, expected %v", dmStates, expected)
+	}
+}
+
+func TestRecoverDevicePaths(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "recoverpaths")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// dm-3 has four paths; sdc, sdd and sde are failed, sde no longer responds and sdd responds
+	// once the UNIT ATTENTION reported after the failover is cleared
+	if err = os.MkdirAll(filepath.Join(tempDir, "dm-3", "slaves"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, slave := range []string{"sdb", "sdc", "sdd", "sde"} {
+		if err = ioutil.WriteFile(filepath.Join(tempDir, "dm-3", "slaves", slave), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	savedSysBlockPath, savedGetPathDmStates, savedTestUnitReady, savedReinstatePath := sysBlockPath, getPathDmStates, testUnitReady, reinstatePath
+	defer func() {
+		sysBlockPath, getPathDmStates, testUnitReady, reinstatePath = savedSysBlockPath, savedGetPathDmStates, savedTestUnitReady, savedReinstatePath
+	}()
+	sysBlockPath = tempDir
+	getPathDmStates = func() (map[string]string, error) {
+		return map[string]string{"sdb": "active", "sdc": "failed", "sdd": "failed", "sde": "failed"}, nil
+	}
+	unitAttention := map[string]bool{"/dev/sdd": true}
+	testUnitReady = func(device string) error {
+		if device == "/dev/sde" {
+			return &sgio.ScsiError{Status: sgio.ScsiStatusCheckCondition, Sense: &sgio.Sense{SenseKey: sgio.SenseKeyNotReady}}
+		}
+		if unitAttention[device] {
+			unitAttention[device] = false
+			return &sgio.ScsiError{Status: sgio.ScsiStatusCheckCondition, Sense: &sgio.Sense{SenseKey: sgio.SenseKeyUnitAttention}}
+		}
+		return nil
+	}
+	savedOpenProbeDevice := openProbeDevice
+	defer func() { openProbeDevice = savedOpenProbeDevice }()
+	openProbeDevice = func(devicePath string, timeout time.Duration) (probeDevice, error) { return &fakeProbeDevice{}, nil }
+	var reinstated []string
+	reinstatePath = func(path string) error {
+		reinstated = append(reinstated, path)
+		return nil
+	}
+
+	report, err := NewMultipathPlugin().RecoverDevicePaths(model.Device{SerialNumber: "c5a28c28a2487d3d6c9ce900584f2795", Pathname: "dm-3"})
+	if err != nil {
+		t.Fatalf("RecoverDevicePaths failed, err=%v", err)
+	}
+	if strings.Join(reinstated, ",") != "sdc,sdd" {
+		t.Errorf("unexpected reinstated paths %v", reinstated)
+	}
+	if report.SerialNumber != "c5a28c28a2487d3d6c9ce900584f2795" || report.Recovered != 2 || report.Failed != 1 || len(report.Paths) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if path := report.Paths[2]; path.Name != "sde" || path.Status != model.PathRecoveryUnreachable || path.Error == "" {
+		t.Errorf("unexpected unreachable path %+v", path)
+	}
+
+	if _, err = NewMultipathPlugin().RecoverDevicePaths(model.Device{}); err == nil {
+		t.Error("expected error for a device without a path name")
+	}
+}
+
+// createTuningSysfs creates a fake /sys/block with dm-3 on top of the sdb and sdc paths
+func createTuningSysfs(t *testing.T, tempDir string) {
+	writeAttribute := func(path string, value string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeAttribute(filepath.Join(tempDir, "dm-3", "queue", queueReadAheadKB), "128")
+	for _, slave := range []string{"sdb", "sdc"} {
+		writeAttribute(filepath.Join(tempDir, "dm-3", "slaves", slave), "")
+		writeAttribute(filepath.Join(tempDir, slave, "queue", queueReadAheadKB), "128")
+		writeAttribute(filepath.Join(tempDir, slave, "queue", queueNrRequests), "256")
+		writeAttribute(filepath.Join(tempDir, slave, "queue", queueRqAffinity), "1")
+		writeAttribute(filepath.Join(tempDir, slave, "queue", queueScheduler), "noop [deadline] cfq")
+		writeAttribute(filepath.Join(tempDir, slave, "device", "queue_depth"), "32")
+	}
+}
+
+func TestGetDeviceTuning(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "devicetuning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	createTuningSysfs(t, tempDir)
+
+	savedSysBlockPath := sysBlockPath
+	defer func() { sysBlockPath = savedSysBlockPath }()
+	sysBlockPath = tempDir
+
+	plugin := NewMultipathPlugin()
+	tuning, err := plugin.getDeviceTuning(model.Device{Pathname: "dm-3"})
+	if err != nil {
+		t.Fatalf("getDeviceTuning failed, err=%v", err)
+	}
+	if tuning.ReadAheadKB == nil || *tuning.ReadAheadKB != 128 || tuning.NrRequests == nil || *tuning.NrRequests != 256 ||
+		tuning.RqAffinity == nil || *tuning.RqAffinity != 1 || tuning.QueueDepth == nil || *tuning.QueueDepth != 32 ||
+		tuning.Scheduler != "deadline" {
+		t.Errorf("unexpected tuning %+v", tuning)
+	}
+
+	// A dm device without paths only reports its own read-ahead
+	if err = os.RemoveAll(filepath.Join(tempDir, "dm-3", "slaves")); err != nil {
+		t.Fatal(err)
+	}
+	if tuning, err = plugin.getDeviceTuning(model.Device{Pathname: "dm-3"}); err != nil {
+		t.Fatalf("getDeviceTuning failed, err=%v", err)
+	}
+	if tuning.ReadAheadKB == nil || *tuning.ReadAheadKB != 128 || tuning.NrRequests != nil || tuning.QueueDepth != nil || tuning.Scheduler != "" {
+		t.Errorf("unexpected tuning %+v", tuning)
+	}
+
+	if _, err = plugin.getDeviceTuning(model.Device{}); err == nil {
+		t.Error("getDeviceTuning succeeded without a device path name")
+	}
+}
+
+func TestSetDeviceTuning(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "devicetuning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	createTuningSysfs(t, tempDir)
+
+	savedSysBlockPath := sysBlockPath
+	defer func() { sysBlockPath = savedSysBlockPath }()
+	sysBlockPath = tempDir
+
+	readAheadKB, queueDepth := uint64(4096), uint64(64)
+	plugin := NewMultipathPlugin()
+	if err = plugin.setDeviceTuning(model.Device{Pathname: "dm-3"}, model.DeviceTuning{ReadAheadKB: &readAheadKB, QueueDepth: &queueDepth, Scheduler: "none"}); err != nil {
+		t.Fatalf("setDeviceTuning failed, err=%v", err)
+	}
+
+	// read_ahead_kb is applied to the map and its paths, everything else only to the paths, and
+	// the settings that were not provided are left unchanged
+	expected := map[string]string{
+		filepath.Join("dm-3", "queue", queueReadAheadKB): "4096",
+		filepath.Join("sdb", "queue", queueReadAheadKB):  "4096",
+		filepath.Join("sdc", "queue", queueReadAheadKB):  "4096",
+		filepath.Join("sdb", "queue", queueScheduler):    "none",
+		filepath.Join("sdc", "queue", queueScheduler):    "none",
+		filepath.Join("sdb", "device", "queue_depth"):    "64",
+		filepath.Join("sdc", "device", "queue_depth"):    "64",
+		filepath.Join("sdb", "queue", queueNrRequests):   "256",
+		filepath.Join("sdc", "queue", queueRqAffinity):   "1",
+	}
+	for path, value := range expected {
+		data, err := ioutil.ReadFile(filepath.Join(tempDir, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(data)) != value {
+			t.Errorf("%v = %q, expected %q", path, strings.TrimSpace(string(data)), value)
+		}
+	}
+
+	// Attributes that cannot be written are reported as an invalid argument
+	if err = os.RemoveAll(filepath.Join(tempDir, "sdc", "device")); err != nil {
+		t.Fatal(err)
+	}
+	err = plugin.setDeviceTuning(model.Device{Pathname: "dm-3"}, model.DeviceTuning{QueueDepth: &queueDepth})
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.InvalidArgument {
+		t.Errorf("setDeviceTuning with a missing queue_depth attribute returned %v, expected InvalidArgument", err)
+	}
+}
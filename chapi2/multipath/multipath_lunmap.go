@@ -0,0 +1,143 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// GROUP SCOPED TARGET LUN MAP
+//
+//		A Group Scoped Target (GST) can provide many LUNs, and resolving a serial number to its
+//		device walks every LUN of the target (Windows queries the disk at each of the target's
+//		SCSI addresses).  Each walk records the target's LUN map, the host device of each LUN
+//		(the disk number on Windows) keyed by serial number, so that the next CreateDevice or
+//		GetDevices of a serial number goes straight to its device:
+//
+//		-	A map is valid until the next rescan (see the rescan package); the first lookup after
+//			a rescan walks the target again and refreshes its map.
+//		-	A cached device is verified when used (its serial number must still match); a stale
+//			entry is dropped and the LUNs are walked as before.
+//		-	Deleting a device drops its entry, logging out a target drops its map, and
+//			InvalidateLunMaps drops the maps of the given targets (or every map), e.g. after LUNs
+//			were remapped on the array without a rescan.
+//
+//		The Linux device enumeration doesn't walk the target LUNs yet, so its maps stay empty.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/rescan"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// lunMap is the cached LUN map of a target
+type lunMap struct {
+	targetName string            // Target iSCSI iqn
+	generation uint64            // Rescan generation when the target's LUNs were walked
+	luns       map[string]string // Host device of each LUN, keyed by canonical serial number
+}
+
+var (
+	// lunMapGeneration returns the current rescan generation; a variable so that tests can
+	// replace it
+	lunMapGeneration = rescan.Generation
+
+	lunMapLock    sync.Mutex
+	lunMaps       = make(map[string]*lunMap) // Cached LUN maps, keyed by lower case target name
+	lunMapTargets = make(map[string]string)  // Lower case target name, keyed by canonical serial number
+)
+
+// setLunMap records the LUN map of the given target, walked at the given rescan generation.  The
+// map replaces any map previously cached for the target.
+func setLunMap(targetName string, generation uint64, luns map[string]string) {
+	lunMapLock.Lock()
+	defer lunMapLock.Unlock()
+
+	key := strings.ToLower(targetName)
+	dropLunMap(key)
+	cached := &lunMap{targetName: targetName, generation: generation, luns: make(map[string]string)}
+	for serialNumber, hostDevice := range luns {
+		serialNumber = CanonicalSerialNumber(serialNumber)
+		cached.luns[serialNumber] = hostDevice
+		lunMapTargets[serialNumber] = key
+	}
+	lunMaps[key] = cached
+	log.Tracef("Cached the LUN map of target %v, luns=%v, generation=%v", targetName, len(luns), generation)
+}
+
+// lookupLun returns the cached host device of the given serial number, and the target providing
+// it.  false is returned if the serial number isn't in a LUN map, or its map predates the last
+// rescan.
+func lookupLun(serialNumber string) (targetName string, hostDevice string, ok bool) {
+	lunMapLock.Lock()
+	defer lunMapLock.Unlock()
+
+	serialNumber = CanonicalSerialNumber(serialNumber)
+	cached := lunMaps[lunMapTargets[serialNumber]]
+	if cached == nil {
+		return "", "", false
+	}
+	if cached.generation != lunMapGeneration() {
+		log.Tracef("LUN map of target %v predates the last rescan, dropping it", cached.targetName)
+		dropLunMap(strings.ToLower(cached.targetName))
+		return "", "", false
+	}
+	hostDevice, ok = cached.luns[serialNumber]
+	return cached.targetName, hostDevice, ok
+}
+
+// forgetLun drops the cached LUN of the given serial number, e.g. once its device is deleted or
+// found stale
+func forgetLun(serialNumber string) {
+	lunMapLock.Lock()
+	defer lunMapLock.Unlock()
+
+	serialNumber = CanonicalSerialNumber(serialNumber)
+	if cached := lunMaps[lunMapTargets[serialNumber]]; cached != nil {
+		delete(cached.luns, serialNumber)
+	}
+	delete(lunMapTargets, serialNumber)
+}
+
+// InvalidateLunMaps drops the cached LUN maps of the given targets, or every cached LUN map if no
+// target is given.  The names of the targets whose map was dropped are returned.
+func (plugin *MultipathPlugin) InvalidateLunMaps(targetNames []string) []string {
+	log.Tracef(">>>>> InvalidateLunMaps, targetNames=%v", targetNames)
+	defer log.Trace("<<<<< InvalidateLunMaps")
+
+	lunMapLock.Lock()
+	defer lunMapLock.Unlock()
+
+	if len(targetNames) == 0 {
+		for key := range lunMaps {
+			targetNames = append(targetNames, key)
+		}
+	}
+	var invalidated []string
+	for _, targetName := range targetNames {
+		if cached := dropLunMap(strings.ToLower(targetName)); cached != nil {
+			invalidated = append(invalidated, cached.targetName)
+		}
+	}
+	sort.Strings(invalidated)
+	return invalidated
+}
+
+// dropLunMap drops the LUN map of the given (lower case) target and returns it, nil if the target
+// has no map; lunMapLock must be held
+func dropLunMap(key string) *lunMap {
+	cached := lunMaps[key]
+	if cached == nil {
+		return nil
+	}
+	for serialNumber := range cached.luns {
+		if lunMapTargets[serialNumber] == key {
+			delete(lunMapTargets, serialNumber)
+		}
+	}
+	delete(lunMaps, key)
+	return cached
+}
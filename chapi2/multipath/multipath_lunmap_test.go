@@ -0,0 +1,73 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLunMap(t *testing.T) {
+	defer func(generation func() uint64) {
+		lunMapGeneration = generation
+		lunMaps = make(map[string]*lunMap)
+		lunMapTargets = make(map[string]string)
+	}(lunMapGeneration)
+	var generation uint64 = 7
+	lunMapGeneration = func() uint64 { return generation }
+
+	const target1, target2 = "iqn.group-target1", "iqn.group-target2"
+	const serial1, serial2, serial3 = "6d70f5a2c0d7a8bd6c9ce900e3ba4f6c", "0e1a9ba5d5e18f2c6c9ce900e3ba4f6c", "1f2b0cb6e6f2903d6c9ce900e3ba4f6c"
+	plugin := NewMultipathPlugin()
+
+	// LUNs are resolved by their canonical serial number
+	setLunMap(target1, generation, map[string]string{" " + serial1 + " ": "3", serial2: "4"})
+	setLunMap(target2, generation, map[string]string{serial3: "5"})
+	if targetName, hostDevice, ok := lookupLun(SerialNumberToWWID(serial1)); !ok || targetName != target1 || hostDevice != "3" {
+		t.Errorf("lookup %v = %v, %v, %v", serial1, targetName, hostDevice, ok)
+	}
+	if _, _, ok := lookupLun("00000000000000006c9ce900e3ba4f6c"); ok {
+		t.Error("unmapped serial number resolved")
+	}
+
+	// A deleted LUN is dropped from its map
+	forgetLun(serial2)
+	if _, _, ok := lookupLun(serial2); ok {
+		t.Errorf("forgotten serial number %v resolved", serial2)
+	}
+
+	// A map replacing a target's map drops the LUNs no longer mapped
+	setLunMap(target1, generation, map[string]string{serial2: "6"})
+	if _, _, ok := lookupLun(serial1); ok {
+		t.Errorf("serial number %v no longer mapped resolved", serial1)
+	}
+	if _, hostDevice, ok := lookupLun(serial2); !ok || hostDevice != "6" {
+		t.Errorf("remapped serial number %v = %v, %v", serial2, hostDevice, ok)
+	}
+
+	// A rescan makes the maps stale
+	generation++
+	if _, _, ok := lookupLun(serial2); ok {
+		t.Errorf("serial number %v resolved after a rescan", serial2)
+	}
+	if _, ok := lunMaps[target1]; ok {
+		t.Errorf("stale map of %v not dropped", target1)
+	}
+
+	// Invalidation of given targets, then of every target
+	setLunMap(target1, generation, map[string]string{serial1: "3"})
+	setLunMap(target2, generation, map[string]string{serial3: "5"})
+	if invalidated := plugin.InvalidateLunMaps([]string{"IQN.GROUP-TARGET2", "iqn.unknown"}); !reflect.DeepEqual(invalidated, []string{target2}) {
+		t.Errorf("InvalidateLunMaps(%v) = %v", target2, invalidated)
+	}
+	if _, _, ok := lookupLun(serial3); ok {
+		t.Errorf("serial number %v of an invalidated map resolved", serial3)
+	}
+	setLunMap(target2, generation, map[string]string{serial3: "5"})
+	if invalidated := plugin.InvalidateLunMaps(nil); !reflect.DeepEqual(invalidated, []string{target1, target2}) {
+		t.Errorf("InvalidateLunMaps() = %v", invalidated)
+	}
+	if len(lunMaps) != 0 || len(lunMapTargets) != 0 {
+		t.Errorf("maps left after invalidating every map, lunMaps=%v, lunMapTargets=%v", lunMaps, lunMapTargets)
+	}
+}
@@ -0,0 +1,123 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// DIRECT READ PROBES
+//
+//		A device (or path) can answer SCSI commands, such as TEST UNIT READY, and still fail or
+//		hang reads (e.g. a LUN whose backing volume is offline on the array).  A direct read probe
+//		reads the first 4 KiB block (LBA 0) of the device, bypassing the host's cache, without
+//		spawning any tool:
+//
+//		- Linux		The device is opened with O_DIRECT and read through io_uring.  If the
+//					device's blk-mq queue supports polling (queue/io_poll), the read is
+//					issued on a polled (IORING_SETUP_IOPOLL) ring and its completion is polled
+//					instead of waiting for an interrupt.  Otherwise the read is linked to a
+//					timeout so that the kernel cancels it once the probe times out.  If
+//					io_uring is unavailable (e.g. kernel.io_uring_disabled), the read falls
+//					back to preadv2, with RWF_HIPRI on a polled queue.
+//		- Windows	The disk is opened with FILE_FLAG_NO_BUFFERING and read synchronously.
+//
+//		The probes are opt-in: they are disabled unless DeviceProbeTimeoutEnv is set to the time,
+//		in seconds, the read must complete within.  Once enabled, CreateDevice probes every
+//		attached device before returning it, and path recovery probes each failed path before
+//		reinstating it.  A read that the kernel cannot cancel is abandoned; its goroutine exits
+//		once the kernel completes or fails the I/O.  A read failed by a persistent reservation is
+//		reported as a ReservationConflict (see multipath_reservation.go).
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"io"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/timing"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// DeviceProbeTimeoutEnv enables the direct read probes with the given timeout, in seconds
+	DeviceProbeTimeoutEnv = config.DeviceProbeTimeoutEnv
+
+	// probeBlockSize is the size of the block read at LBA 0
+	probeBlockSize = 4096
+
+	errorMessageProbeTimeout = "direct read of %v did not complete within %v"
+)
+
+// probeDevice is a device opened for direct (unbuffered) I/O
+type probeDevice interface {
+	io.ReaderAt
+	io.Closer
+}
+
+var (
+	// openProbeDevice opens the device for direct I/O; a variable so that tests can replace it
+	openProbeDevice = openProbe
+)
+
+// ProbeDevice reads the first block of the given device, bypassing the host's cache, to validate
+// that the device is serving I/O.  Nothing is read unless the probes are enabled.
+func (plugin *MultipathPlugin) ProbeDevice(device model.Device) error {
+	timeout := getDeviceProbeTimeout()
+	if timeout == 0 {
+		return nil
+	}
+	devicePath, err := getProbePath(device)
+	if err != nil {
+		return err
+	}
+	defer timing.StartStage(device.SerialNumber, timing.StageDeviceWait)()
+	return directReadProbe(devicePath, timeout)
+}
+
+// directReadProbe reads the first block of the device, bypassing the host's cache, and fails if
+// the read fails or does not complete within the timeout
+func directReadProbe(devicePath string, timeout time.Duration) error {
+	log.Tracef(">>>>> directReadProbe, devicePath=%v, timeout=%v", devicePath, timeout)
+	defer log.Trace("<<<<< directReadProbe")
+
+	// The read runs in its own goroutine since a read from a device without any usable path can
+	// block for as long as the device queues I/O.  The abandoned goroutine of a hung read can
+	// outlive the call, so it only uses its own copy of openProbeDevice.
+	open := openProbeDevice
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		device, err := open(devicePath, timeout)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer device.Close()
+		_, err = device.ReadAt(alignedBuffer(probeBlockSize, benchmarkBufferAlignment), 0)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Errorf("Direct read probe of %v failed, err=%v", devicePath, err)
+			if conflictErr := checkReservationConflict(devicePath, err); conflictErr != nil {
+				return conflictErr
+			}
+			return cerrors.NewChapiError(err)
+		}
+		log.Tracef("Direct read probe of %v completed in %v", devicePath, time.Since(start))
+		return nil
+	case <-time.After(timeout):
+		err := cerrors.NewChapiErrorf(cerrors.Timeout, errorMessageProbeTimeout, devicePath, timeout)
+		log.Error(err)
+		return err
+	}
+}
+
+// getDeviceProbeTimeout returns the direct read probe timeout, 0 if the probes are disabled
+func getDeviceProbeTimeout() time.Duration {
+	return config.Seconds(DeviceProbeTimeoutEnv, 0, 0)
+}
@@ -0,0 +1,355 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/util"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	queueIoPoll = "io_poll" // e.g. /sys/block/nvme0n1/queue/io_poll
+
+	// io_uring setup flags, mmap offsets, opcodes and submission flags (see linux/io_uring.h)
+	ioringSetupIopoll     = 1 << 0
+	ioringOffSqRing       = 0
+	ioringOffCqRing       = 0x8000000
+	ioringOffSqes         = 0x10000000
+	ioringEnterGetevents  = 1 << 0
+	ioringOpRead          = 22
+	ioringOpLinkTimeout   = 15
+	iosqeIoLink           = 1 << 2
+	ioringProbeEntries    = 2
+	ioringReadUserData    = 1
+	ioringTimeoutUserData = 2
+
+	// probePollInterval is the interval between the completion polls of a polled read that
+	// outlived its timeout; the read buffer must stay alive until the read completes
+	probePollInterval = 100 * time.Millisecond
+)
+
+// Probe read methods, from the most to the least preferred
+const (
+	probeMethodUringPolled = "io_uring polled"
+	probeMethodUring       = "io_uring"
+	probeMethodHipri       = "preadv2 RWF_HIPRI"
+	probeMethodPread       = "pread"
+)
+
+var (
+	// ioUringSetup creates an io_uring instance; a variable so that tests can replace it
+	ioUringSetup = func(entries uint32, params *ioUringParams) (int, error) {
+		fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(params)), 0)
+		if errno != 0 {
+			return -1, errno
+		}
+		return int(fd), nil
+	}
+)
+
+// linuxProbeDevice reads a block device opened for direct I/O (O_DIRECT) through io_uring, polling
+// the completion (blk-mq polling) if the device's queue supports it.  If io_uring is unavailable
+// (e.g. an older kernel or kernel.io_uring_disabled), the read is issued through preadv2, with
+// RWF_HIPRI if the queue supports polling.
+type linuxProbeDevice struct {
+	file    *os.File
+	polled  bool          // The device's queue supports polled I/O (io_poll)
+	timeout time.Duration // Kernel side timeout of the io_uring reads
+	method  string        // Probe read method of the last read
+}
+
+// openProbe opens the device for direct I/O
+func openProbe(devicePath string, timeout time.Duration) (probeDevice, error) {
+	file, err := openDirect(devicePath)
+	if err != nil {
+		return nil, err
+	}
+	return &linuxProbeDevice{file: file, polled: isPolledQueue(devicePath), timeout: timeout}, nil
+}
+
+// isPolledQueue returns true if the block queue of the device supports polled I/O
+func isPolledQueue(devicePath string) bool {
+	if resolved, err := filepath.EvalSymlinks(devicePath); err == nil {
+		devicePath = resolved // e.g. /dev/mapper/mpatha -> /dev/dm-3
+	}
+	value, err := util.FileReadFirstLine(fmt.Sprintf(sysBlockQueueAttribute, sysBlockPath, filepath.Base(devicePath), queueIoPoll))
+	return err == nil && strings.TrimSpace(value) == "1"
+}
+
+// ReadAt reads len(p) bytes at the given offset with the most preferred available method
+func (device *linuxProbeDevice) ReadAt(p []byte, off int64) (int, error) {
+	methods := []string{probeMethodUring, probeMethodPread}
+	if device.polled {
+		methods = []string{probeMethodUringPolled, probeMethodUring, probeMethodHipri, probeMethodPread}
+	}
+	for i, method := range methods {
+		n, err := device.readAt(method, p, off)
+		if i != len(methods)-1 && isUnsupportedProbeError(err) {
+			log.Tracef("Probe read method %v not supported for %v, err=%v", method, device.file.Name(), err)
+			continue
+		}
+		device.method = method
+		return n, err
+	}
+	return 0, nil
+}
+
+// Close closes the device
+func (device *linuxProbeDevice) Close() error {
+	return device.file.Close()
+}
+
+// readAt reads len(p) bytes at the given offset with the given method
+func (device *linuxProbeDevice) readAt(method string, p []byte, off int64) (int, error) {
+	fd := int(device.file.Fd())
+	switch method {
+	case probeMethodUringPolled:
+		return uringRead(fd, p, off, true, device.timeout)
+	case probeMethodUring:
+		return uringRead(fd, p, off, false, device.timeout)
+	case probeMethodHipri:
+		return unix.Preadv2(fd, [][]byte{p}, off, unix.RWF_HIPRI)
+	}
+	return device.file.ReadAt(p, off)
+}
+
+// isUnsupportedProbeError returns true if the read method isn't supported by the kernel or device,
+// as opposed to the read failing
+func isUnsupportedProbeError(err error) bool {
+	return errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.EINVAL) ||
+		errors.Is(err, syscall.EPERM)
+}
+
+// ioUringParams, ioUringSqe and ioUringCqe mirror struct io_uring_params, io_uring_sqe and
+// io_uring_cqe (see linux/io_uring.h)
+type ioUringParams struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFd         uint32
+	resv         [3]uint32
+	sqOff        ioSqringOffsets
+	cqOff        ioCqringOffsets
+}
+
+type ioSqringOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	flags       uint32
+	dropped     uint32
+	array       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+type ioCqringOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	overflow    uint32
+	cqes        uint32
+	flags       uint32
+	resv1       uint32
+	userAddr    uint64
+}
+
+type ioUringSqe struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	rwFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFdIn  int32
+	addr3       uint64
+	pad         uint64
+}
+
+type ioUringCqe struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// kernelTimespec mirrors struct __kernel_timespec
+type kernelTimespec struct {
+	sec  int64
+	nsec int64
+}
+
+// uringRing is an io_uring instance with its submission queue, completion queue and submission
+// queue entries mapped
+type uringRing struct {
+	fd     int
+	params ioUringParams
+	sqRing []byte
+	cqRing []byte
+	sqes   []byte
+}
+
+// uint32At returns a pointer to the mapped uint32 at the given offset
+func uint32At(mapping []byte, offset uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&mapping[offset]))
+}
+
+// newUringRing creates an io_uring instance with the given setup flags
+func newUringRing(flags uint32) (*uringRing, error) {
+	ring := &uringRing{params: ioUringParams{flags: flags}}
+	fd, err := ioUringSetup(ioringProbeEntries, &ring.params)
+	if err != nil {
+		return nil, err
+	}
+	ring.fd = fd
+
+	params := &ring.params
+	sqRingSize := int(params.sqOff.array + params.sqEntries*4)
+	cqRingSize := int(params.cqOff.cqes + params.cqEntries*uint32(unsafe.Sizeof(ioUringCqe{})))
+	sqesSize := int(params.sqEntries * uint32(unsafe.Sizeof(ioUringSqe{})))
+	if ring.sqRing, err = mmapRing(fd, ioringOffSqRing, sqRingSize); err == nil {
+		if ring.cqRing, err = mmapRing(fd, ioringOffCqRing, cqRingSize); err == nil {
+			ring.sqes, err = mmapRing(fd, ioringOffSqes, sqesSize)
+		}
+	}
+	if err != nil {
+		ring.close()
+		return nil, err
+	}
+	return ring, nil
+}
+
+// mmapRing maps an io_uring ring
+func mmapRing(fd int, offset int64, size int) ([]byte, error) {
+	return unix.Mmap(fd, offset, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+}
+
+// close unmaps the rings and closes the io_uring instance.  The kernel completes (or cancels) any
+// I/O still in flight.
+func (ring *uringRing) close() {
+	for _, mapping := range [][]byte{ring.sqes, ring.cqRing, ring.sqRing} {
+		if mapping != nil {
+			unix.Munmap(mapping)
+		}
+	}
+	unix.Close(ring.fd)
+}
+
+// submit queues a submission queue entry
+func (ring *uringRing) submit(entry ioUringSqe) {
+	sqOff := &ring.params.sqOff
+	sqTail := uint32At(ring.sqRing, sqOff.tail)
+	tail := atomic.LoadUint32(sqTail)
+	index := tail & *uint32At(ring.sqRing, sqOff.ringMask)
+	*(*ioUringSqe)(unsafe.Pointer(&ring.sqes[uintptr(index)*unsafe.Sizeof(entry)])) = entry
+	*uint32At(ring.sqRing, sqOff.array+index*4) = index
+	atomic.StoreUint32(sqTail, tail+1)
+}
+
+// enter submits the queued entries and waits for (or, on a polled ring, polls for) minComplete
+// completions
+func (ring *uringRing) enter(toSubmit uint32, minComplete uint32) error {
+	for {
+		_, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(ring.fd), uintptr(toSubmit), uintptr(minComplete), ioringEnterGetevents, 0, 0)
+		if errno != syscall.EINTR {
+			if errno != 0 {
+				return errno
+			}
+			return nil
+		}
+	}
+}
+
+// reap adds the results of the completed entries, by user data
+func (ring *uringRing) reap(results map[uint64]int32) {
+	cqOff := &ring.params.cqOff
+	cqHead := uint32At(ring.cqRing, cqOff.head)
+	mask := *uint32At(ring.cqRing, cqOff.ringMask)
+	head := atomic.LoadUint32(cqHead)
+	for tail := atomic.LoadUint32(uint32At(ring.cqRing, cqOff.tail)); head != tail; head++ {
+		cqe := (*ioUringCqe)(unsafe.Pointer(&ring.cqRing[uintptr(cqOff.cqes)+uintptr(head&mask)*unsafe.Sizeof(ioUringCqe{})]))
+		results[cqe.userData] = cqe.res
+	}
+	atomic.StoreUint32(cqHead, head)
+}
+
+// uringRead reads len(p) bytes at the given offset through io_uring.  A polled ring doesn't
+// support timeouts, so a polled read polls its completion until the timeout, then less frequently
+// until the read completes.  Otherwise, the read is linked to a timeout so that the kernel cancels
+// it once the timeout expires.
+func uringRead(fd int, p []byte, off int64, polled bool, timeout time.Duration) (int, error) {
+	flags := uint32(0)
+	if polled {
+		flags = ioringSetupIopoll
+	}
+	ring, err := newUringRing(flags)
+	if err != nil {
+		return 0, err
+	}
+	defer ring.close()
+
+	// The buffer and the timespec must stay alive until their entries complete
+	timespec := &kernelTimespec{sec: int64(timeout / time.Second), nsec: int64(timeout % time.Second)}
+	defer runtime.KeepAlive(timespec)
+	defer runtime.KeepAlive(p)
+	read := ioUringSqe{opcode: ioringOpRead, fd: int32(fd), off: uint64(off), addr: uint64(uintptr(unsafe.Pointer(&p[0]))), len: uint32(len(p)), userData: ioringReadUserData}
+
+	results := make(map[uint64]int32)
+	if polled {
+		ring.submit(read)
+		deadline := time.Now().Add(timeout)
+		for toSubmit := uint32(1); ; toSubmit = 0 {
+			if err = ring.enter(toSubmit, 1); err != nil {
+				return 0, err
+			}
+			if ring.reap(results); len(results) != 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				time.Sleep(probePollInterval)
+			}
+		}
+	} else {
+		read.flags = iosqeIoLink
+		ring.submit(read)
+		ring.submit(ioUringSqe{opcode: ioringOpLinkTimeout, addr: uint64(uintptr(unsafe.Pointer(timespec))), len: 1, userData: ioringTimeoutUserData})
+		if err = ring.enter(2, 2); err != nil {
+			return 0, err
+		}
+		ring.reap(results)
+	}
+
+	res, ok := results[ioringReadUserData]
+	switch {
+	case !ok:
+		return 0, syscall.EIO
+	case res == -int32(syscall.ECANCELED):
+		return 0, syscall.ETIMEDOUT
+	case res < 0:
+		return 0, syscall.Errno(-res)
+	case int(res) < len(p):
+		return int(res), syscall.EIO
+	}
+	return int(res), nil
+}
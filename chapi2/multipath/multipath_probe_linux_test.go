@@ -0,0 +1,82 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsPolledQueue(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	savedSysBlockPath := sysBlockPath
+	defer func() { sysBlockPath = savedSysBlockPath }()
+	sysBlockPath = tempDir
+
+	for device, ioPoll := range map[string]string{"nvme0n1": "1", "dm-3": "0"} {
+		os.MkdirAll(filepath.Join(tempDir, device, "queue"), 0755)
+		ioutil.WriteFile(filepath.Join(tempDir, device, "queue", queueIoPoll), []byte(ioPoll+"\n"), 0644)
+	}
+	if !isPolledQueue("/dev/nvme0n1") || isPolledQueue("/dev/dm-3") || isPolledQueue("/dev/sdz") {
+		t.Error("unexpected polled queues")
+	}
+}
+
+func TestLinuxProbeDeviceReadAt(t *testing.T) {
+	savedIoUringSetup := ioUringSetup
+	defer func() { ioUringSetup = savedIoUringSetup }()
+
+	// Read a regular file, as the probes read LBA 0 of a block device
+	data := bytes.Repeat([]byte("0123456789abcdef"), probeBlockSize/16)
+	file, err := ioutil.TempFile("", "probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.Write(data)
+	file.Close()
+
+	read := func(polled bool) (string, error) {
+		file, err := os.Open(file.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		device := &linuxProbeDevice{file: file, polled: polled, timeout: time.Second}
+		defer device.Close()
+		buffer := alignedBuffer(probeBlockSize, benchmarkBufferAlignment)
+		if _, err = device.ReadAt(buffer, 0); err == nil && !bytes.Equal(buffer, data) {
+			t.Errorf("%v read unexpected data", device.method)
+		}
+		return device.method, err
+	}
+
+	// io_uring, if available; a regular file doesn't support polled I/O so a polled read falls
+	// back to a read on an interrupt driven ring
+	for _, polled := range []bool{false, true} {
+		method, err := read(polled)
+		if err != nil {
+			t.Errorf("polled=%v: read failed, err=%v", polled, err)
+		}
+		if method != probeMethodUring && method != probeMethodPread {
+			t.Errorf("polled=%v: unexpected method %v", polled, method)
+		}
+	}
+
+	// Without io_uring (e.g. kernel.io_uring_disabled), the read falls back to preadv2
+	ioUringSetup = func(entries uint32, params *ioUringParams) (int, error) { return -1, syscall.EPERM }
+	if method, err := read(false); err != nil || method != probeMethodPread {
+		t.Errorf("expected a pread fallback, got %v, err=%v", method, err)
+	}
+	if method, err := read(true); err != nil || (method != probeMethodHipri && method != probeMethodPread) {
+		t.Errorf("expected a preadv2 fallback, got %v, err=%v", method, err)
+	}
+}
@@ -0,0 +1,96 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/sgio"
+)
+
+// fakeProbeDevice is a probeDevice whose reads fail with err, or block until release is closed
+type fakeProbeDevice struct {
+	err     error
+	release chan struct{}
+	offset  int64
+	size    int
+}
+
+func (device *fakeProbeDevice) ReadAt(p []byte, off int64) (int, error) {
+	device.offset, device.size = off, len(p)
+	if device.release != nil {
+		<-device.release
+	}
+	return len(p), device.err
+}
+
+func (device *fakeProbeDevice) Close() error {
+	return nil
+}
+
+func TestDirectReadProbe(t *testing.T) {
+	savedOpenProbeDevice := openProbeDevice
+	savedGetPersistentReservation := getPersistentReservation
+	defer func() {
+		openProbeDevice = savedOpenProbeDevice
+		getPersistentReservation = savedGetPersistentReservation
+	}()
+	getPersistentReservation = func(devicePath string) (*sgio.PersistentReservation, error) {
+		return &sgio.PersistentReservation{}, nil
+	}
+
+	// Successful read of the first block
+	device := &fakeProbeDevice{offset: -1}
+	openProbeDevice = func(devicePath string, timeout time.Duration) (probeDevice, error) { return device, nil }
+	if err := directReadProbe("/dev/dm-3", time.Second); err != nil {
+		t.Fatalf("expected probe to succeed, err=%v", err)
+	}
+	if device.offset != 0 || device.size != probeBlockSize {
+		t.Errorf("expected a %v byte read at LBA 0, got %v bytes at %v", probeBlockSize, device.size, device.offset)
+	}
+
+	// Failed read
+	device = &fakeProbeDevice{err: errors.New("input/output error")}
+	if err := directReadProbe("/dev/dm-3", time.Second); err == nil {
+		t.Error("expected a failed read to fail the probe")
+	}
+
+	// Failed open
+	openProbeDevice = func(devicePath string, timeout time.Duration) (probeDevice, error) { return nil, os.ErrNotExist }
+	if err := directReadProbe("/dev/dm-3", time.Second); err == nil {
+		t.Error("expected a failed open to fail the probe")
+	}
+
+	// Hung read
+	device = &fakeProbeDevice{release: make(chan struct{})}
+	defer close(device.release)
+	openProbeDevice = func(devicePath string, timeout time.Duration) (probeDevice, error) { return device, nil }
+	err := directReadProbe("/dev/dm-3", 50*time.Millisecond)
+	if chapiErr, ok := err.(*cerrors.ChapiError); !ok || chapiErr.Code != cerrors.Timeout {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
+
+func TestGetDeviceProbeTimeout(t *testing.T) {
+	defer os.Unsetenv(DeviceProbeTimeoutEnv)
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"12", 12 * time.Second},
+		{"-1", 0},
+		{"bogus", 0},
+	}
+	for _, testCase := range testCases {
+		os.Setenv(DeviceProbeTimeoutEnv, testCase.value)
+		if timeout := getDeviceProbeTimeout(); timeout != testCase.expected {
+			t.Errorf("%v=%q, expected %v, got %v", DeviceProbeTimeoutEnv, testCase.value, testCase.expected, timeout)
+		}
+	}
+}
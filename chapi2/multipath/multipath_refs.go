@@ -0,0 +1,131 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// REFS FORMAT VALIDATION
+//
+//		Format-Volume fails deep inside the Storage Management Provider, with an HRESULT that
+//		doesn't name the cause, when ReFS (or one of its features) is not supported by the host or
+//		by the disk.  CreateFileSystem (Windows) therefore validates the requested ReFS format
+//		against the host's OS build before formatting, and reports every missing OS feature:
+//
+//		- refs				ReFS volume creation (Windows Server 2012, build 9200)
+//		- block_cloning		ReFS block cloning, copy-on-write of file ranges (Windows Server
+//							2016, build 14393); reported for information, never required
+//		- integrity_streams	ReFS integrity streams (build 9200); not supported on clustered disks
+//		- dev_drive			Dev Drive volumes (Windows 11 22H2, build 22621); always ReFS and at
+//							least 50 GB
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	fileSystemReFS = "ReFS"
+
+	// ReFS features (see getRefsFeatures)
+	refsFeatureFormat           = "refs"
+	refsFeatureBlockCloning     = "block_cloning"
+	refsFeatureIntegrityStreams = "integrity_streams"
+	refsFeatureDevDrive         = "dev_drive"
+
+	// Minimum OS builds of the ReFS features
+	refsMinimumBuild         = 9200  // Windows Server 2012
+	refsBlockCloningBuild    = 14393 // Windows Server 2016
+	refsDevDriveMinimumBuild = 22621 // Windows 11 22H2
+
+	// devDriveMinimumSize is the smallest volume that can be formatted as a Dev Drive
+	devDriveMinimumSize = 50 * 1000 * 1000 * 1000
+
+	errorMessageRefsOptionFileSystem   = "integrity_streams and dev_drive require the ReFS file system, not %v"
+	errorMessageRefsUnsupported        = "ReFS format options not supported by this host (build %v), missing: %v"
+	errorMessageRefsClusteredIntegrity = "integrity streams are not supported on clustered disk %v"
+	errorMessageDevDriveTooSmall       = "device size %v bytes is smaller than the %v bytes Dev Drive minimum"
+)
+
+// formatHost describes the host and disk properties the ReFS format options depend on
+type formatHost struct {
+	build     int  // OS build number (e.g. 17763)
+	clustered bool // True if the disk is a cluster disk
+}
+
+// getRefsFeatures returns the ReFS features supported by the given OS build
+func getRefsFeatures(build int) map[string]bool {
+	return map[string]bool{
+		refsFeatureFormat:           build >= refsMinimumBuild,
+		refsFeatureBlockCloning:     build >= refsBlockCloningBuild,
+		refsFeatureIntegrityStreams: build >= refsMinimumBuild,
+		refsFeatureDevDrive:         build >= refsDevDriveMinimumBuild,
+	}
+}
+
+// isRefsFileSystem returns true if the given file system is ReFS
+func isRefsFileSystem(filesystem string) bool {
+	return strings.EqualFold(filesystem, fileSystemReFS)
+}
+
+// validateRefsFormat validates the ReFS format options of the given device against the host and
+// returns the file system to format the device with; a Dev Drive defaults to ReFS.  Options that
+// don't apply to the file system fail with cerrors.InvalidArgument and options not supported by
+// the host fail with cerrors.Unimplemented, with the missing features as details.
+func validateRefsFormat(device model.Device, filesystem string, fsOptions *model.FileSystemOptions, host formatHost) (string, error) {
+	if fsOptions == nil || (!isRefsFileSystem(filesystem) && !fsOptions.IntegrityStreams && !fsOptions.DevDrive) {
+		return filesystem, nil
+	}
+	if filesystem == "" && fsOptions.DevDrive {
+		filesystem = fileSystemReFS
+	}
+	if !isRefsFileSystem(filesystem) {
+		err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageRefsOptionFileSystem, filesystem)
+		log.Error(err)
+		return "", err
+	}
+
+	// Report every OS feature the requested format is missing
+	features := getRefsFeatures(host.build)
+	log.Tracef("ReFS features of build %v: %v", host.build, features)
+	required := []string{refsFeatureFormat}
+	if fsOptions.IntegrityStreams {
+		required = append(required, refsFeatureIntegrityStreams)
+	}
+	if fsOptions.DevDrive {
+		required = append(required, refsFeatureDevDrive)
+	}
+	var missing []string
+	for _, feature := range required {
+		if !features[feature] {
+			missing = append(missing, feature)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		err := cerrors.NewChapiErrorf(cerrors.Unimplemented, errorMessageRefsUnsupported, host.build, strings.Join(missing, ", ")).WithDetails(missing)
+		log.Error(err)
+		return "", err
+	}
+
+	// Reject the feature combinations the disk can't support
+	if fsOptions.IntegrityStreams && host.clustered {
+		err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageRefsClusteredIntegrity, device.SerialNumber)
+		log.Error(err)
+		return "", err
+	}
+	if fsOptions.DevDrive && device.Size != 0 && device.Size < devDriveMinimumSize {
+		err := cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageDevDriveTooSmall, device.Size, uint64(devDriveMinimumSize))
+		log.Error(err)
+		return "", err
+	}
+	if !features[refsFeatureBlockCloning] {
+		log.Warnf("ReFS block cloning is not supported by build %v, file copies will not be copy-on-write", host.build)
+	}
+	return filesystem, nil
+}
@@ -0,0 +1,61 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestValidateRefsFormat(t *testing.T) {
+	const server2019, windows11 = 17763, 22631
+	device := model.Device{SerialNumber: "sn1", Size: 100 * 1000 * 1000 * 1000}
+	smallDevice := model.Device{SerialNumber: "sn2", Size: 10 * 1000 * 1000 * 1000}
+
+	testCases := []struct {
+		name       string
+		device     model.Device
+		filesystem string
+		fsOptions  *model.FileSystemOptions
+		host       formatHost
+		expected   string
+		code       cerrors.ChapiErrorCode
+		missing    []string
+	}{
+		{"no options", device, "NTFS", nil, formatHost{}, "NTFS", cerrors.OK, nil},
+		{"ntfs", device, "NTFS", &model.FileSystemOptions{FullFormat: true}, formatHost{}, "NTFS", cerrors.OK, nil},
+		{"refs", device, "refs", &model.FileSystemOptions{}, formatHost{build: server2019}, "refs", cerrors.OK, nil},
+		{"refs integrity streams", device, "ReFS", &model.FileSystemOptions{IntegrityStreams: true}, formatHost{build: server2019}, "ReFS", cerrors.OK, nil},
+		{"dev drive default", device, "", &model.FileSystemOptions{DevDrive: true}, formatHost{build: windows11}, fileSystemReFS, cerrors.OK, nil},
+		{"ntfs integrity streams", device, "NTFS", &model.FileSystemOptions{IntegrityStreams: true}, formatHost{build: windows11}, "", cerrors.InvalidArgument, nil},
+		{"refs old build", device, "ReFS", &model.FileSystemOptions{IntegrityStreams: true}, formatHost{build: 7601}, "", cerrors.Unimplemented, []string{"integrity_streams", "refs"}},
+		{"dev drive old build", device, "ReFS", &model.FileSystemOptions{DevDrive: true}, formatHost{build: server2019}, "", cerrors.Unimplemented, []string{"dev_drive"}},
+		{"clustered integrity streams", device, "ReFS", &model.FileSystemOptions{IntegrityStreams: true}, formatHost{build: server2019, clustered: true}, "", cerrors.InvalidArgument, nil},
+		{"clustered refs", device, "ReFS", &model.FileSystemOptions{}, formatHost{build: server2019, clustered: true}, "ReFS", cerrors.OK, nil},
+		{"dev drive too small", smallDevice, "ReFS", &model.FileSystemOptions{DevDrive: true}, formatHost{build: windows11}, "", cerrors.InvalidArgument, nil},
+	}
+
+	for _, tc := range testCases {
+		filesystem, err := validateRefsFormat(tc.device, tc.filesystem, tc.fsOptions, tc.host)
+		if filesystem != tc.expected {
+			t.Errorf("%v: expected file system %q, got %q", tc.name, tc.expected, filesystem)
+		}
+		if tc.code == cerrors.OK {
+			if err != nil {
+				t.Errorf("%v: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+		chapiErr, ok := err.(*cerrors.ChapiError)
+		if !ok || chapiErr.Code != tc.code {
+			t.Errorf("%v: expected error code %v, got %v", tc.name, tc.code, err)
+			continue
+		}
+		if tc.missing != nil && !reflect.DeepEqual(chapiErr.Details, tc.missing) {
+			t.Errorf("%v: expected missing features %v, got %v", tc.name, tc.missing, chapiErr.Details)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// PERSISTENT RESERVATION CONFLICTS
+//
+//		A LUN persistently reserved by another host (e.g. a cluster node still holding the volume)
+//		fails this host's I/O with a RESERVATION CONFLICT status, which surfaces as an opaque I/O
+//		error (EBADE, or EIO on older kernels, under Linux).  When the direct read probe of an
+//		attached device fails, the LUN's persistent reservation is read (PERSISTENT RESERVE IN) and,
+//		if the failure is caused by a reservation held by another host, CreateDevice fails with a
+//		ReservationConflict error whose details hold the reservation holder and registered keys.
+//		The PERSISTENT RESERVE IN command is not available under Windows; the read error is then
+//		returned as is.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/sgio"
+)
+
+const (
+	errorMessageReservationConflict = "device %v is reserved by another host (%v reservation, holder key %v, registered keys %v); " +
+		"release the reservation from the host holding it, or clear it (e.g. sg_persist --out --clear), and retry"
+	allRegistrantsHolder = "all registrants"
+)
+
+var (
+	// getPersistentReservation reads the device's persistent reservation; a variable so that tests
+	// can replace it
+	getPersistentReservation = sgio.GetPersistentReservation
+)
+
+// checkReservationConflict returns a ReservationConflict error if the failed direct read of the
+// device was caused by a persistent reservation held by another host, nil otherwise
+func checkReservationConflict(devicePath string, readErr error) error {
+	reservation, err := getPersistentReservation(devicePath)
+	if err != nil {
+		log.Tracef("Unable to read the persistent reservation of %v, err=%v", devicePath, err)
+		return nil
+	}
+	if !reservation.Reserved {
+		return nil
+	}
+	if !isReservationConflictError(readErr) && !reservation.BlocksReads() {
+		// The reservation allows reads; the read failed for another reason
+		return nil
+	}
+
+	holder := reservation.HolderKey
+	if holder == "" {
+		holder = allRegistrantsHolder
+	}
+	err = cerrors.NewChapiErrorf(cerrors.ReservationConflict, errorMessageReservationConflict,
+		devicePath, reservation.TypeName, holder, reservation.RegisteredKeys).WithDetails(reservation)
+	log.Error(err)
+	return err
+}
@@ -0,0 +1,60 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/sgio"
+)
+
+func TestReservationConflict(t *testing.T) {
+	savedOpenProbeDevice := openProbeDevice
+	savedGetPersistentReservation := getPersistentReservation
+	defer func() {
+		openProbeDevice = savedOpenProbeDevice
+		getPersistentReservation = savedGetPersistentReservation
+	}()
+	device := &fakeProbeDevice{err: errors.New("input/output error")}
+	openProbeDevice = func(devicePath string, timeout time.Duration) (probeDevice, error) { return device, nil }
+
+	exclusiveAccess := &sgio.PersistentReservation{
+		RegisteredKeys: []string{"0x8a3b000000000002"},
+		Reserved:       true,
+		HolderKey:      "0x8a3b000000000002",
+		Type:           sgio.PrTypeExclusiveAccess,
+		TypeName:       "Exclusive Access",
+	}
+	testCases := []struct {
+		name        string
+		reservation *sgio.PersistentReservation
+		reserveErr  error
+		conflict    bool
+	}{
+		{"exclusive access", exclusiveAccess, nil, true},
+		{"write exclusive", &sgio.PersistentReservation{Reserved: true, HolderKey: "0x1", Type: sgio.PrTypeWriteExclusive}, nil, false},
+		{"not reserved", &sgio.PersistentReservation{RegisteredKeys: []string{"0x1"}}, nil, false},
+		{"reservation unavailable", nil, errors.New("not implemented"), false},
+	}
+	for _, testCase := range testCases {
+		getPersistentReservation = func(devicePath string) (*sgio.PersistentReservation, error) {
+			return testCase.reservation, testCase.reserveErr
+		}
+		err := directReadProbe("/dev/dm-3", time.Second)
+		chapiErr, ok := err.(*cerrors.ChapiError)
+		if !ok {
+			t.Errorf("%v: expected a ChapiError, got %v", testCase.name, err)
+			continue
+		}
+		if conflict := chapiErr.Code == cerrors.ReservationConflict; conflict != testCase.conflict {
+			t.Errorf("%v: expected conflict=%v, got %v", testCase.name, testCase.conflict, err)
+			continue
+		}
+		if testCase.conflict && chapiErr.Details != testCase.reservation {
+			t.Errorf("%v: expected the reservation as details, got %v", testCase.name, chapiErr.Details)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// SERIAL NUMBER FORMATS
+//
+//		The array reports a volume's serial number as lower case hex digits (e.g.
+//		"6d70f5a2c0d7a8bd6c9ce900e3ba4f6c") but the host reports the same volume in other formats:
+//
+//			Linux multipath WWID		"2" + serial number (the SCSI designator type, "2" for
+//										EUI-64 and "3" for NAA), "mpath-" prefixed in the dm uuid
+//			Windows MSFT_Disk			SerialNumber, possibly upper case or padded with spaces
+//
+//		Comparing the raw strings fails silently (e.g. a lookup returns no device).  Serial
+//		numbers are therefore compared in their canonical form (see CanonicalSerialNumber), and
+//		GetSerialNumberPitfalls reports the format differences found in a given serial number.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+	"unicode"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// wwidDesignatorEUI is the SCSI designator type multipath prefixes to the serial number of a
+	// Nimble volume to form its WWID
+	wwidDesignatorEUI = "2"
+
+	// wwidDesignators are the SCSI designator types multipath prefixes to a serial number
+	wwidDesignators = "123"
+
+	// dmUUIDPrefix prefixes the WWID in the dm uuid of a multipath device
+	dmUUIDPrefix = "mpath-"
+
+	// Serial number pitfalls
+	pitfallWhitespace = "contains white space"
+	pitfallUpperCase  = "contains upper case letters"
+	pitfallDmUUID     = "has the dm uuid prefix " + dmUUIDPrefix
+	pitfallDesignator = "has a multipath WWID designator prefix"
+	pitfallSeparators = "contains separators"
+	pitfallNotHex     = "contains characters that are not hex digits"
+)
+
+// CanonicalSerialNumber returns the given serial number, multipath WWID or dm uuid as the serial
+// number reported by the array: lower case hex digits without white space, separators, dm uuid
+// prefix or WWID designator type
+func CanonicalSerialNumber(serialNumber string) string {
+	serialNumber = stripSerialNumber(serialNumber)
+	if hasWWIDDesignator(serialNumber) {
+		serialNumber = serialNumber[1:]
+	}
+	return serialNumber
+}
+
+// stripSerialNumber returns the given serial number in lower case without white space, separators
+// or dm uuid prefix
+func stripSerialNumber(serialNumber string) string {
+	serialNumber = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(serialNumber)), dmUUIDPrefix)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || isSerialSeparator(r) {
+			return -1
+		}
+		return r
+	}, serialNumber)
+}
+
+// SerialNumberToWWID returns the Linux multipath WWID of the Nimble volume with the given serial
+// number (e.g. "2" + serial number)
+func SerialNumberToWWID(serialNumber string) string {
+	serialNumber = CanonicalSerialNumber(serialNumber)
+	if serialNumber == "" {
+		return ""
+	}
+	return wwidDesignatorEUI + serialNumber
+}
+
+// SerialNumberToDmUUID returns the Linux dm uuid of the multipath device of the Nimble volume
+// with the given serial number (e.g. "mpath-2" + serial number)
+func SerialNumberToDmUUID(serialNumber string) string {
+	wwid := SerialNumberToWWID(serialNumber)
+	if wwid == "" {
+		return ""
+	}
+	return dmUUIDPrefix + wwid
+}
+
+// SerialNumberToWindowsSerial returns the serial number to match against the Windows MSFT_Disk
+// SerialNumber property.  WQL string comparisons ignore case, but not surrounding white space, so
+// the canonical serial number is returned.
+func SerialNumberToWindowsSerial(serialNumber string) string {
+	return CanonicalSerialNumber(serialNumber)
+}
+
+// SerialNumbersMatch returns true if the two serial numbers, multipath WWIDs or dm uuids identify
+// the same volume
+func SerialNumbersMatch(serialNumber1, serialNumber2 string) bool {
+	canonical := CanonicalSerialNumber(serialNumber1)
+	return canonical != "" && canonical == CanonicalSerialNumber(serialNumber2)
+}
+
+// GetSerialNumberPitfalls returns the differences between the given serial number and the format
+// reported by the array, or nil if the serial number is already canonical
+func GetSerialNumberPitfalls(serialNumber string) []string {
+	var pitfalls []string
+	if strings.IndexFunc(serialNumber, unicode.IsSpace) >= 0 {
+		pitfalls = append(pitfalls, pitfallWhitespace)
+	}
+	if strings.ToLower(serialNumber) != serialNumber {
+		pitfalls = append(pitfalls, pitfallUpperCase)
+	}
+	trimmed := strings.ToLower(strings.TrimSpace(serialNumber))
+	if strings.HasPrefix(trimmed, dmUUIDPrefix) {
+		pitfalls = append(pitfalls, pitfallDmUUID)
+		trimmed = strings.TrimPrefix(trimmed, dmUUIDPrefix)
+	}
+	if strings.IndexFunc(trimmed, isSerialSeparator) >= 0 {
+		pitfalls = append(pitfalls, pitfallSeparators)
+	}
+	stripped := stripSerialNumber(serialNumber)
+	if hasWWIDDesignator(stripped) {
+		pitfalls = append(pitfalls, pitfallDesignator)
+		stripped = stripped[1:]
+	}
+	if strings.IndexFunc(stripped, func(r rune) bool { return !isHexDigit(r) }) >= 0 {
+		pitfalls = append(pitfalls, pitfallNotHex)
+	}
+	return pitfalls
+}
+
+// lookupSerialNumber returns the canonical form of the serial number passed to a device lookup,
+// logging the format differences it had
+func lookupSerialNumber(serialNumber string) string {
+	canonical := CanonicalSerialNumber(serialNumber)
+	if canonical != serialNumber {
+		log.Warnf("Serial number %q %v, looking up %v instead", serialNumber, strings.Join(GetSerialNumberPitfalls(serialNumber), ", "), canonical)
+	}
+	return canonical
+}
+
+// hasWWIDDesignator returns true if the given lower case identifier is a serial number prefixed
+// with a multipath WWID designator type (i.e. one designator digit followed by an even number of
+// hex digits)
+func hasWWIDDesignator(identifier string) bool {
+	if len(identifier) < 2 || len(identifier)%2 == 0 || !strings.ContainsRune(wwidDesignators, rune(identifier[0])) {
+		return false
+	}
+	return strings.IndexFunc(identifier, func(r rune) bool { return !isHexDigit(r) }) < 0
+}
+
+// isSerialSeparator returns true if the rune separates the digits of a formatted serial number
+// (e.g. "6d70f5a2-c0d7-a8bd")
+func isSerialSeparator(r rune) bool {
+	return r == '-' || r == ':'
+}
+
+// isHexDigit returns true if the rune is a lower case hex digit
+func isHexDigit(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f')
+}
@@ -0,0 +1,78 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+const testSerialNumber = "6d70f5a2c0d7a8bd6c9ce900e3ba4f6c"
+
+func TestCanonicalSerialNumber(t *testing.T) {
+	tests := []struct {
+		serialNumber string
+		expected     string
+	}{
+		{testSerialNumber, testSerialNumber},
+		{"6D70F5A2C0D7A8BD6C9CE900E3BA4F6C", testSerialNumber},
+		{"  6d70f5a2c0d7a8bd6c9ce900e3ba4f6c ", testSerialNumber},
+		{"2" + testSerialNumber, testSerialNumber},
+		{"3" + testSerialNumber, testSerialNumber},
+		{"mpath-2" + testSerialNumber, testSerialNumber},
+		{"6d70f5a2-c0d7-a8bd-6c9c-e900e3ba4f6c", testSerialNumber},
+		{"6d70 f5a2 c0d7 a8bd 6c9c e900 e3ba 4f6c", testSerialNumber},
+		{"serial1", "serial1"},
+		{"", ""},
+	}
+	for _, tc := range tests {
+		if canonical := CanonicalSerialNumber(tc.serialNumber); canonical != tc.expected {
+			t.Errorf("CanonicalSerialNumber(%q) = %q, expected %q", tc.serialNumber, canonical, tc.expected)
+		}
+	}
+}
+
+func TestSerialNumberConversions(t *testing.T) {
+	if wwid := SerialNumberToWWID(" 6D70F5A2C0D7A8BD6C9CE900E3BA4F6C"); wwid != "2"+testSerialNumber {
+		t.Errorf("unexpected WWID %q", wwid)
+	}
+	if uuid := SerialNumberToDmUUID(testSerialNumber); uuid != "mpath-2"+testSerialNumber {
+		t.Errorf("unexpected dm uuid %q", uuid)
+	}
+	if serial := SerialNumberToWindowsSerial(testSerialNumber + " "); serial != testSerialNumber {
+		t.Errorf("unexpected Windows serial %q", serial)
+	}
+	if SerialNumberToWWID("") != "" || SerialNumberToDmUUID(" ") != "" {
+		t.Error("expected empty WWID and dm uuid for an empty serial number")
+	}
+
+	if !SerialNumbersMatch("mpath-2"+testSerialNumber, "6D70F5A2C0D7A8BD6C9CE900E3BA4F6C ") {
+		t.Error("expected dm uuid to match the Windows serial number")
+	}
+	if SerialNumbersMatch(testSerialNumber, "0"+testSerialNumber[1:]) || SerialNumbersMatch("", "") {
+		t.Error("unexpected serial number match")
+	}
+	if !isIgnoredDevice([]*model.IgnoredDevice{{WWID: "2" + testSerialNumber}}, "6D70F5A2C0D7A8BD6C9CE900E3BA4F6C") {
+		t.Error("expected ignored WWID to match the serial number")
+	}
+}
+
+func TestGetSerialNumberPitfalls(t *testing.T) {
+	tests := []struct {
+		serialNumber string
+		expected     []string
+	}{
+		{testSerialNumber, nil},
+		{"6D70F5A2C0D7A8BD6C9CE900E3BA4F6C ", []string{pitfallWhitespace, pitfallUpperCase}},
+		{"mpath-2" + testSerialNumber, []string{pitfallDmUUID, pitfallDesignator}},
+		{"6d70f5a2-c0d7-a8bd-6c9c-e900e3ba4f6c", []string{pitfallSeparators}},
+		{"serial1", []string{pitfallNotHex}},
+	}
+	for _, tc := range tests {
+		if pitfalls := GetSerialNumberPitfalls(tc.serialNumber); !reflect.DeepEqual(pitfalls, tc.expected) {
+			t.Errorf("GetSerialNumberPitfalls(%q) = %v, expected %v", tc.serialNumber, pitfalls, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// SKIPPED DEVICES
+//
+//		Arrays can present LUNs that are not volumes to attach, such as the witness or quorum LUNs
+//		of the array file services (e.g. SMB/NFS File Persona).  Unlike the ignore list, which
+//		hides individual WWIDs, the skip list hides every device whose serial number starts with
+//		one of SkipSerialPrefixesEnv, or whose SCSI inquiry product matches one of SkipProductsEnv.
+//		Both are comma separated and case insensitive.  Skipped devices are filtered along with
+//		the ignored devices, so they are hidden from GetDevices, GetAllDeviceDetails and from the
+//		routines built on them (e.g. AttachDevice, path recovery, drain and state reconciliation).
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+const (
+	// SkipSerialPrefixesEnv lists the serial number prefixes of the devices skipped by device
+	// enumeration (e.g. "5000,6000c29")
+	SkipSerialPrefixesEnv = config.SkipSerialPrefixesEnv
+
+	// SkipProductsEnv lists the SCSI inquiry products of the devices skipped by device enumeration
+	SkipProductsEnv = config.SkipProductsEnv
+)
+
+var (
+	// getDeviceProduct returns the device's SCSI inquiry product; a variable so that tests can
+	// replace it
+	getDeviceProduct = getInquiryProduct
+)
+
+// deviceSkipList is the skip list loaded from SkipSerialPrefixesEnv and SkipProductsEnv
+type deviceSkipList struct {
+	serialPrefixes []string // Lower case serial number prefixes
+	products       []string // Lower case inquiry products
+}
+
+// getDeviceSkipList returns the skip list configured in the environment
+func getDeviceSkipList() *deviceSkipList {
+	return &deviceSkipList{
+		serialPrefixes: parseSkipList(config.String(SkipSerialPrefixesEnv)),
+		products:       parseSkipList(config.String(SkipProductsEnv)),
+	}
+}
+
+// parseSkipList splits a comma separated skip list into its lower case, non empty, entries
+func parseSkipList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// isEmpty returns true if no device is skipped
+func (skipList *deviceSkipList) isEmpty() bool {
+	return len(skipList.serialPrefixes) == 0 && len(skipList.products) == 0
+}
+
+// isSkipped returns true if the device's serial number or inquiry product is in the skip list.  The
+// inquiry product is only looked up if products are skipped.
+func (skipList *deviceSkipList) isSkipped(device *model.Device) bool {
+	serialNumber := strings.ToLower(device.SerialNumber)
+	for _, prefix := range skipList.serialPrefixes {
+		if strings.HasPrefix(serialNumber, prefix) {
+			return true
+		}
+	}
+	if len(skipList.products) == 0 {
+		return false
+	}
+	product := strings.ToLower(strings.TrimSpace(getDeviceProduct(device)))
+	if product == "" {
+		return false
+	}
+	for _, skippedProduct := range skipList.products {
+		if product == skippedProduct {
+			return true
+		}
+	}
+	return false
+}
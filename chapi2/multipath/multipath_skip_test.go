@@ -0,0 +1,63 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestParseSkipList(t *testing.T) {
+	if entries := parseSkipList(" 5000, ,6000C29 ,"); !reflect.DeepEqual(entries, []string{"5000", "6000c29"}) {
+		t.Errorf("unexpected skip list %v", entries)
+	}
+	if entries := parseSkipList(""); len(entries) != 0 {
+		t.Errorf("expected empty skip list, got %v", entries)
+	}
+}
+
+func TestSkippedDevices(t *testing.T) {
+	savedIgnoredDevicesPath, savedGetDeviceProduct := ignoredDevicesPath, getDeviceProduct
+	defer func() { ignoredDevicesPath, getDeviceProduct = savedIgnoredDevicesPath, savedGetDeviceProduct }()
+	ignoredDevicesPath = filepath.Join(os.TempDir(), "chapi-skip-test", "ignored_devices.json")
+
+	for _, env := range []string{SkipSerialPrefixesEnv, SkipProductsEnv} {
+		savedValue, saved := os.LookupEnv(env)
+		defer func(env string) {
+			if saved {
+				os.Setenv(env, savedValue)
+			} else {
+				os.Unsetenv(env)
+			}
+		}(env)
+	}
+
+	products := map[string]string{
+		"c5a28c28a2487d3d6c9ce900584f2795": "Server",
+		"f4c97c5c1cd391756c9ce900584f2795": "File Witness",
+		"5000c5008e7a3bdf6c9ce900584f2795": "Server",
+	}
+	getDeviceProduct = func(device *model.Device) string { return products[device.SerialNumber] }
+	devices := []*model.Device{
+		{SerialNumber: "c5a28c28a2487d3d6c9ce900584f2795"},
+		{SerialNumber: "F4C97C5C1CD391756C9CE900584F2795"},
+		{SerialNumber: "5000c5008e7a3bdf6c9ce900584f2795"},
+	}
+
+	os.Unsetenv(SkipSerialPrefixesEnv)
+	os.Unsetenv(SkipProductsEnv)
+	if filtered := filterIgnoredDevices(devices); len(filtered) != 3 {
+		t.Errorf("expected no skipped device, got %v", filtered)
+	}
+
+	os.Setenv(SkipSerialPrefixesEnv, "5000C5")
+	os.Setenv(SkipProductsEnv, "file witness")
+	devices[1].SerialNumber = "f4c97c5c1cd391756c9ce900584f2795"
+	if filtered := filterIgnoredDevices(devices); len(filtered) != 1 || filtered[0] != devices[0] {
+		t.Errorf("unexpected filtered devices %v", filtered)
+	}
+}
@@ -0,0 +1,80 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// EMPTY GROUP SCOPED TARGET LOGOUT
+//
+//		A Volume Scoped Target (VST) provides a single LUN so its sessions are logged out when its
+//		device is deleted.  A Group Scoped Target (GST) provides every LUN of the group exported to
+//		the host and its sessions are left logged in, as other LUNs are likely to be attached
+//		through it.  Once the last LUN of a GST is deleted, those sessions only consume connection
+//		slots on the array.  If LogoutEmptyTargetEnv is set to "true", DeleteDevice logs out the
+//		GST, and removes its persistent logins (Windows) or node records (Linux), once no other
+//		device is provided through it.  The next CreateDevice logs the target in again.
+//
+//		The remaining devices are enumerated without filtering the ignored devices; an ignored LUN
+//		still uses the target's sessions.  A failed logout is logged but does not fail the delete
+//		since the device itself was detached.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// LogoutEmptyTargetEnv enables, when set to "true", logging out a Group Scoped Target once the
+	// last device provided through it is deleted
+	LogoutEmptyTargetEnv = config.LogoutEmptyTargetEnv
+)
+
+var (
+	// Target device enumeration and logout routines; variables so that tests can replace them
+	getTargetDevices = func(plugin *MultipathPlugin) ([]*model.Device, error) {
+		return plugin.getAllDeviceDetails("", newDeviceFields([]string{"serial_number", "iscsi_target"}))
+	}
+	logoutIscsiTarget = func(targetName string) error {
+		return iscsi.NewIscsiPlugin().LogoutTarget(targetName)
+	}
+)
+
+// isLogoutEmptyTargetEnabled returns true if LogoutEmptyTargetEnv is set to "true"
+func isLogoutEmptyTargetEnabled() bool {
+	return config.Enabled(LogoutEmptyTargetEnv)
+}
+
+// logoutEmptyGroupTarget logs out the Group Scoped Target of the given (detached) device if no
+// other device is provided through it.  Returns true if the target was logged out.
+func (plugin *MultipathPlugin) logoutEmptyGroupTarget(device model.Device) bool {
+	if (device.IscsiTarget == nil) || !strings.EqualFold(device.IscsiTarget.TargetScope, model.TargetScopeGroup) {
+		return false
+	}
+	targetName := device.IscsiTarget.Name
+
+	devices, err := getTargetDevices(plugin)
+	if err != nil {
+		log.Errorf("Unable to enumerate the devices of target %v, leaving it logged in, err=%v", targetName, err)
+		return false
+	}
+	for _, targetDevice := range devices {
+		if (targetDevice.IscsiTarget != nil) && strings.EqualFold(targetDevice.IscsiTarget.Name, targetName) &&
+			!strings.EqualFold(targetDevice.SerialNumber, device.SerialNumber) {
+			log.Tracef("Target %v still provides device %v, leaving it logged in", targetName, targetDevice.SerialNumber)
+			return false
+		}
+	}
+
+	log.Infof("Logging out group scoped target %v, no device left after deleting %v", targetName, device.SerialNumber)
+	if err = logoutIscsiTarget(targetName); err != nil {
+		log.Errorf("Unable to logout group scoped target %v, err=%v", targetName, err)
+		return false
+	}
+	return true
+}
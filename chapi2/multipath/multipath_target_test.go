@@ -0,0 +1,61 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+func TestLogoutEmptyGroupTarget(t *testing.T) {
+	defer func(getDevices func(*MultipathPlugin) ([]*model.Device, error), logout func(string) error) {
+		getTargetDevices, logoutIscsiTarget = getDevices, logout
+	}(getTargetDevices, logoutIscsiTarget)
+
+	const gst, otherGst = "iqn.group1", "iqn.group2"
+	var devices []*model.Device
+	var enumerateErr error
+	getTargetDevices = func(*MultipathPlugin) ([]*model.Device, error) { return devices, enumerateErr }
+	var loggedOut []string
+	logoutIscsiTarget = func(targetName string) error {
+		loggedOut = append(loggedOut, targetName)
+		return nil
+	}
+
+	deleted := model.Device{SerialNumber: "sn1", IscsiTarget: &model.IscsiTarget{Name: gst, TargetScope: model.TargetScopeGroup}}
+	testCases := []struct {
+		name      string
+		device    model.Device
+		devices   []*model.Device
+		err       error
+		loggedOut bool
+	}{
+		{"other LUN", deleted, []*model.Device{
+			{SerialNumber: "sn1", IscsiTarget: &model.IscsiTarget{Name: gst}},
+			{SerialNumber: "sn2", IscsiTarget: &model.IscsiTarget{Name: "IQN.GROUP1"}},
+		}, nil, false},
+		{"last LUN", deleted, []*model.Device{
+			{SerialNumber: "SN1", IscsiTarget: &model.IscsiTarget{Name: gst}},
+			{SerialNumber: "sn3", IscsiTarget: &model.IscsiTarget{Name: otherGst}},
+			{SerialNumber: "sn4"},
+		}, nil, true},
+		{"enumeration failure", deleted, nil, errors.New("enumeration failure"), false},
+		{"volume scoped", model.Device{SerialNumber: "sn1", IscsiTarget: &model.IscsiTarget{Name: gst, TargetScope: model.TargetScopeVolume}}, nil, nil, false},
+		{"fibre channel", model.Device{SerialNumber: "sn1"}, nil, nil, false},
+	}
+
+	plugin := NewMultipathPlugin()
+	for _, tc := range testCases {
+		loggedOut, devices, enumerateErr = nil, tc.devices, tc.err
+		if logged := plugin.logoutEmptyGroupTarget(tc.device); logged != tc.loggedOut {
+			t.Errorf("%v: expected logged out %v, got %v", tc.name, tc.loggedOut, logged)
+		}
+		if tc.loggedOut && (len(loggedOut) != 1 || loggedOut[0] != gst) {
+			t.Errorf("%v: unexpected logouts %v", tc.name, loggedOut)
+		} else if !tc.loggedOut && len(loggedOut) != 0 {
+			t.Errorf("%v: unexpected logouts %v", tc.name, loggedOut)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
 
@@ -225,3 +226,68 @@ func (c *TargetTypeCache) maintainCache(lockNeeded bool) {
 		}
 	}
 }
+
+// parseActiveScheduler returns the active I/O scheduler from the contents of a block device's
+// queue/scheduler attribute (e.g. "mq-deadline kyber [bfq] none" returns "bfq").  If no scheduler
+// is bracketed, the trimmed input is returned.
+func parseActiveScheduler(schedulers string) string {
+	start := strings.Index(schedulers, "[")
+	end := strings.Index(schedulers, "]")
+	if start < 0 || end < start {
+		return strings.TrimSpace(schedulers)
+	}
+	return schedulers[start+1 : end]
+}
+
+// aluaStateOrder orders the ALUA access states from the most to the least preferred for I/O
+var aluaStateOrder = []string{
+	model.AluaActiveOptimized,
+	model.AluaActiveNonOptimized,
+	model.AluaLBADependent,
+	model.AluaStandby,
+	model.AluaTransitioning,
+	model.AluaUnavailable,
+	model.AluaOffline,
+	model.AluaUnknown,
+}
+
+// groupDevicePaths groups the given paths by their ALUA access state.  The groups are ordered from
+// the most to the least preferred access state, and any unrecognized states follow in name order.
+func groupDevicePaths(paths []*model.DevicePath) []*model.DevicePathGroup {
+	groups := make(map[string]*model.DevicePathGroup)
+	var unrecognized []string
+	for _, path := range paths {
+		accessState := path.AccessState
+		if accessState == "" {
+			accessState = model.AluaUnknown
+		}
+		group := groups[accessState]
+		if group == nil {
+			group = &model.DevicePathGroup{AccessState: accessState}
+			groups[accessState] = group
+			if !isKnownAluaState(accessState) {
+				unrecognized = append(unrecognized, accessState)
+			}
+		}
+		group.Paths = append(group.Paths, path)
+	}
+	sort.Strings(unrecognized)
+
+	var pathGroups []*model.DevicePathGroup
+	for _, accessState := range append(aluaStateOrder, unrecognized...) {
+		if group := groups[accessState]; group != nil {
+			pathGroups = append(pathGroups, group)
+		}
+	}
+	return pathGroups
+}
+
+// isKnownAluaState returns true if the given access state is one of the ALUA access states
+func isKnownAluaState(accessState string) bool {
+	for _, state := range aluaStateOrder {
+		if state == accessState {
+			return true
+		}
+	}
+	return false
+}
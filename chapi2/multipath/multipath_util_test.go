@@ -71,3 +71,21 @@ func TestTargetTypeCache(t *testing.T) {
 func getTargetName(index int) string {
 	return fmt.Sprintf("target%v", index)
 }
+
+func TestParseActiveScheduler(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"noop [deadline] cfq", "deadline"},
+		{"[none] mq-deadline kyber bfq", "none"},
+		{"mq-deadline kyber [bfq] none\n", "bfq"},
+		{"none", "none"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if scheduler := parseActiveScheduler(test.input); scheduler != test.expected {
+			t.Errorf("parseActiveScheduler(%q) = %q, expected %q", test.input, scheduler, test.expected)
+		}
+	}
+}
@@ -4,17 +4,76 @@ package multipath
 
 import (
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
 	"github.com/hpe-storage/common-host-libs/chapi2/iscsi"
 	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/chapi2/rescan"
 	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/sgio"
 	"github.com/hpe-storage/common-host-libs/windows/ioctl"
 	"github.com/hpe-storage/common-host-libs/windows/iscsidsc"
 	"github.com/hpe-storage/common-host-libs/windows/powershell"
 	"github.com/hpe-storage/common-host-libs/windows/wmi"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
+const (
+	ignoredDevicesFile   = `hpe-storage\chapi\ignored_devices.json` // Path appended to %ProgramData%
+	scsiPortDevicePrefix = `\\.\Scsi`                               // Prefix of the SCSI port device names (e.g. "\\.\Scsi2:")
+
+	// MPIO path states, named as the Linux path device states
+	devicePathRunning = "running"
+	devicePathOffline = "offline"
+
+	// DSM_Path_V2 ALUASupport value of the paths without ALUA
+	dsmALUANotSupported = 0
+
+	// StorPort adapter registry keys and values
+	regKeyScsiPort         = `HARDWARE\DEVICEMAP\Scsi\Scsi Port %v`                   // SCSI device map entry of a port
+	regKeyMiniportDevice   = `SYSTEM\CurrentControlSet\Services\%v\Parameters\Device` // Adapter parameters of a miniport driver
+	regValueScsiPortDriver = "Driver"                                                 // Miniport driver service name of a port
+	regValueMaxNumberOfIO  = "MaxNumberOfIO"                                          // Outstanding I/O limit of an adapter
+
+	errorMessageInvalidQueueDepth = "invalid queue depth %v"
+	errorMessageTuningUnsupported = "only the queue depth can be tuned on Windows, the other queue settings are Linux only"
+)
+
+var (
+	ignoredDevicesPath = filepath.Join(getProgramDataPath(), ignoredDevicesFile)
+)
+
+// getMPIODescriptors and getDSMPolicies query the MPIO disk paths, and getScsiPortMiniport,
+// readMiniportQueueDepth and writeMiniportQueueDepth access the StorPort adapter settings; variables
+// so that tests can replace them
+var (
+	getMPIODescriptors      = wmi.GetMPIO_GET_DESCRIPTOR
+	getDSMPolicies          = wmi.GetDSM_QueryLBPolicy_V2
+	getScsiPortMiniport     = getScsiPortMiniportFromRegistry
+	readMiniportQueueDepth  = readMiniportQueueDepthFromRegistry
+	writeMiniportQueueDepth = writeMiniportQueueDepthToRegistry
+)
+
+// getProgramDataPath returns the system's %ProgramData% folder
+func getProgramDataPath() string {
+	if programDataPath := os.Getenv("ProgramData"); programDataPath != "" {
+		return programDataPath
+	}
+	return `C:\ProgramData`
+}
+
+// updateDeviceBlacklist is a no-op under Windows; ignored devices are only hidden from enumeration
+func updateDeviceBlacklist(ignoredDevices []*model.IgnoredDevice) error {
+	return nil
+}
+
 // getDevices enumerates all the Nimble volumes while only providing basic details (e.g. serial number).
 // If a "serialNumber" is passed in, only that specific serial number is enumerated.
 func (plugin *MultipathPlugin) getDevices(serialNumber string) ([]*model.Device, error) {
@@ -22,7 +81,7 @@ func (plugin *MultipathPlugin) getDevices(serialNumber string) ([]*model.Device,
 	defer log.Trace("<<<<< getDevices")
 
 	// Enumerate all Nimble volumes
-	nimbleDisks, err := wmi.GetNimbleMSFTDisk(serialNumber)
+	nimbleDisks, err := getNimbleDisks(serialNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -46,14 +105,43 @@ func (plugin *MultipathPlugin) getDevices(serialNumber string) ([]*model.Device,
 	return devices, nil
 }
 
+// getNimbleDisks enumerates the Nimble disks, only the disk with the given serial number if one is
+// passed in.  A serial number cached in a LUN map is queried by its disk number.  If the WMI query
+// does not find the serial number, the disks are compared with their canonical serial numbers
+// (e.g. a SerialNumber property padded with spaces).
+func getNimbleDisks(serialNumber string) ([]*wmi.MSFT_Disk, error) {
+	if serialNumber != "" {
+		if nimbleDisks := getCachedNimbleDisks(serialNumber, ""); len(nimbleDisks) != 0 {
+			return nimbleDisks, nil
+		}
+	}
+	nimbleDisks, err := wmi.GetNimbleMSFTDisk(SerialNumberToWindowsSerial(serialNumber))
+	if err != nil || len(nimbleDisks) != 0 || serialNumber == "" {
+		return nimbleDisks, err
+	}
+	allDisks, err := wmi.GetNimbleMSFTDisk("")
+	if err != nil {
+		return nil, err
+	}
+	for _, nimbleDisk := range allDisks {
+		if SerialNumbersMatch(nimbleDisk.SerialNumber, serialNumber) {
+			log.Warnf("Serial number %v matched disk %v with SerialNumber %q %v", serialNumber, nimbleDisk.Number, nimbleDisk.SerialNumber, GetSerialNumberPitfalls(nimbleDisk.SerialNumber))
+			nimbleDisks = append(nimbleDisks, nimbleDisk)
+		}
+	}
+	return nimbleDisks, nil
+}
+
 // getAllDeviceDetails enumerates all the Nimble volumes while providing full details about the
-// device.  If a "serialNumber" is passed in, only that specific serial number is enumerated.
-func (plugin *MultipathPlugin) getAllDeviceDetails(serialNumber string) ([]*model.Device, error) {
+// device.  If a "serialNumber" is passed in, only that specific serial number is enumerated.  Only
+// the given device fields need to be populated; the iSCSI target mappings are not enumerated
+// unless the iSCSI target is requested.
+func (plugin *MultipathPlugin) getAllDeviceDetails(serialNumber string, fields deviceFields) ([]*model.Device, error) {
 	log.Trace(">>>>> getAllDeviceDetails")
 	defer log.Trace("<<<<< getAllDeviceDetails")
 
 	// Enumerate all Nimble volumes
-	nimbleDisks, err := wmi.GetNimbleMSFTDisk(serialNumber)
+	nimbleDisks, err := getNimbleDisks(serialNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -61,12 +149,118 @@ func (plugin *MultipathPlugin) getAllDeviceDetails(serialNumber string) ([]*mode
 	// If an iSCSI device was detected, enumerate the iSCSI target mappings
 	var targetMappings []*iscsidsc.ISCSI_TARGET_MAPPING
 	for _, nimbleDisk := range nimbleDisks {
-		if wmi.STORAGE_BUS_TYPE(nimbleDisk.BusType) == wmi.BusTypeiScsi {
+		if fields.has(deviceFieldIscsiTarget) && (wmi.STORAGE_BUS_TYPE(nimbleDisk.BusType) == wmi.BusTypeiScsi) {
 			targetMappings, _ = iscsidsc.ReportActiveIScsiTargetMappings()
 			break
 		}
 	}
 
+	return plugin.getDeviceDetails(nimbleDisks, targetMappings, fields)
+}
+
+// getAttachedDevices enumerates, with full details, the device with the given serial number once
+// it has been attached.  An iSCSI device is resolved through the active mappings of its target,
+// so that only the disks at the target's OS SCSI addresses are queried rather than every Nimble
+// disk.  If the device cannot be resolved that way, all the Nimble disks are enumerated.
+func (plugin *MultipathPlugin) getAttachedDevices(serialNumber string, blockDev model.BlockDeviceAccessInfo) ([]*model.Device, error) {
+	log.Tracef(">>>>> getAttachedDevices, serialNumber=%v", serialNumber)
+	defer log.Trace("<<<<< getAttachedDevices")
+
+	if blockDev.AccessProtocol == model.AccessProtocolIscsi {
+		nimbleDisks, targetMappings, err := getMappedIscsiDisks(serialNumber, blockDev.TargetName)
+		if err == nil && len(nimbleDisks) != 0 {
+			return plugin.getDeviceDetails(nimbleDisks, targetMappings, nil)
+		}
+		log.Tracef("Serial number %v not resolved through the mappings of target %v, err=%v", serialNumber, blockDev.TargetName, err)
+	}
+	return plugin.getAllDeviceDetails(serialNumber, nil)
+}
+
+// getMappedIscsiDisks returns the Nimble disks with the given serial number at the OS SCSI
+// addresses (port, bus, target and LUN) of the given target's active mappings, along with the
+// active mappings of all targets.  The disk number of every Nimble disk found at the target's
+// addresses is cached in the target's LUN map (see GROUP SCOPED TARGET LUN MAP), which resolves
+// the serial number directly until the next rescan.
+func getMappedIscsiDisks(serialNumber string, targetName string) ([]*wmi.MSFT_Disk, []*iscsidsc.ISCSI_TARGET_MAPPING, error) {
+	generation := lunMapGeneration()
+	targetMappings, err := iscsidsc.ReportActiveIScsiTargetMappings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Resolve the serial number through the target's cached LUN map, if any
+	if nimbleDisks := getCachedNimbleDisks(serialNumber, targetName); len(nimbleDisks) != 0 {
+		return nimbleDisks, targetMappings, nil
+	}
+
+	// Query the disk numbers of the target's SCSI addresses, which are much quicker to enumerate
+	// through Win32_DiskDrive than Nimble disks are through MSFT_Disk
+	var addresses []string
+	for _, targetMapping := range targetMappings {
+		if !strings.EqualFold(targetMapping.TargetName, targetName) {
+			continue
+		}
+		port := ""
+		if portNumber, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(targetMapping.OSDeviceName, scsiPortDevicePrefix), ":"), 10, 16); err == nil {
+			port = fmt.Sprintf("SCSIPort=%v AND ", portNumber)
+		}
+		for _, lun := range targetMapping.LUNList {
+			addresses = append(addresses, fmt.Sprintf("(%vSCSIBus=%v AND SCSITargetId=%v AND SCSILogicalUnit=%v)",
+				port, targetMapping.OSBusNumber, targetMapping.OSTargetNumber, lun.OSLUN))
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, targetMappings, nil
+	}
+	diskDrives, err := wmi.GetWin32DiskDrive(strings.Join(addresses, " OR "))
+	if err != nil || len(diskDrives) == 0 {
+		return nil, targetMappings, err
+	}
+
+	var diskNumbers []string
+	for _, diskDrive := range diskDrives {
+		diskNumbers = append(diskNumbers, fmt.Sprintf("Number=%v", diskDrive.Index))
+	}
+	targetDisks, err := wmi.GetMSFTDisk(strings.Join(diskNumbers, " OR "))
+	if err != nil {
+		return nil, targetMappings, err
+	}
+
+	// Cache the target's LUN map and return the disks with the requested serial number
+	luns := make(map[string]string)
+	var nimbleDisks []*wmi.MSFT_Disk
+	for _, targetDisk := range targetDisks {
+		luns[targetDisk.SerialNumber] = strconv.FormatUint(uint64(targetDisk.Number), 10)
+		if SerialNumbersMatch(targetDisk.SerialNumber, serialNumber) {
+			nimbleDisks = append(nimbleDisks, targetDisk)
+		}
+	}
+	setLunMap(targetName, generation, luns)
+	return nimbleDisks, targetMappings, nil
+}
+
+// getCachedNimbleDisks returns the Nimble disk with the given serial number at its disk number
+// cached in the LUN map of the given target (any target if empty).  The cached LUN is dropped, and
+// nil returned, if the disk at that number no longer has the serial number.
+func getCachedNimbleDisks(serialNumber string, targetName string) []*wmi.MSFT_Disk {
+	cachedTarget, diskNumber, ok := lookupLun(serialNumber)
+	if !ok || (targetName != "" && !strings.EqualFold(cachedTarget, targetName)) {
+		return nil
+	}
+	nimbleDisks, err := wmi.GetMSFTDisk("Number=" + diskNumber)
+	if err == nil && len(nimbleDisks) == 1 && SerialNumbersMatch(nimbleDisks[0].SerialNumber, serialNumber) {
+		log.Tracef("Serial number %v resolved to disk %v through the LUN map of target %v", serialNumber, diskNumber, cachedTarget)
+		return nimbleDisks
+	}
+	log.Tracef("Disk %v cached for serial number %v is stale, err=%v", diskNumber, serialNumber, err)
+	forgetLun(serialNumber)
+	return nil
+}
+
+// getDeviceDetails creates the fully populated devices of the given Nimble disks.  The iSCSI
+// target mappings are used to enumerate the iSCSI details of the iSCSI disks, if requested in the
+// given device fields.
+func (plugin *MultipathPlugin) getDeviceDetails(nimbleDisks []*wmi.MSFT_Disk, targetMappings []*iscsidsc.ISCSI_TARGET_MAPPING, fields deviceFields) ([]*model.Device, error) {
 	// On a Group Scoped Target (GST), a single target could have multiple LUNs.  To speed the
 	// enumerate of a device's target ports, we'll cache the iqn target ports so that they can
 	// be used on other GST LUNs (if present).
@@ -85,8 +279,9 @@ func (plugin *MultipathPlugin) getAllDeviceDetails(serialNumber string) ([]*mode
 			Private:         &model.DevicePrivate{WindowsDisk: nimbleDisk},
 		}
 
-		// Is this an iSCSI volume?  If so, we want to populate the device iSCSI details.
-		if wmi.STORAGE_BUS_TYPE(nimbleDisk.BusType) == wmi.BusTypeiScsi {
+		// Is this an iSCSI volume?  If so, we want to populate the device iSCSI details (unless
+		// they were not requested).
+		if fields.has(deviceFieldIscsiTarget) && (wmi.STORAGE_BUS_TYPE(nimbleDisk.BusType) == wmi.BusTypeiScsi) {
 
 			// If we were not provided an iSCSI plugin object, log an error and skip volume
 			if plugin.iscsiPlugin == nil {
@@ -116,7 +311,7 @@ func (plugin *MultipathPlugin) getAllDeviceDetails(serialNumber string) ([]*mode
 	}
 
 	// Make sure duplicate serial numbers are not detected (e.g. misconfigured MPIO)
-	if err = plugin.checkDuplicateSerialNumbers(devices); err != nil {
+	if err := plugin.checkDuplicateSerialNumbers(devices); err != nil {
 		return nil, err
 	}
 
@@ -187,11 +382,89 @@ func (plugin *MultipathPlugin) offlineDevice(device model.Device) error {
 	return err
 }
 
+// flushDevice flushes the given disk's buffers to the volume
+func (plugin *MultipathPlugin) flushDevice(device model.Device) error {
+	log.Tracef(">>>>> flushDevice, Path=%v", device.Private.WindowsDisk.Path)
+	defer log.Trace("<<<<< flushDevice")
+
+	diskPath, err := windows.UTF16PtrFromString(device.Private.WindowsDisk.Path)
+	if err != nil {
+		return cerrors.NewChapiError(err)
+	}
+	handle, err := windows.CreateFile(diskPath, windows.GENERIC_READ|windows.GENERIC_WRITE, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		log.Errorf("Unable to open disk %v, err=%v", device.Private.WindowsDisk.Path, err)
+		return cerrors.NewChapiError(err)
+	}
+	defer windows.CloseHandle(handle)
+	if err = windows.FlushFileBuffers(handle); err != nil {
+		log.Errorf("Unable to flush disk %v, err=%v", device.Private.WindowsDisk.Path, err)
+		return cerrors.NewChapiError(err)
+	}
+	return nil
+}
+
+// benchmarkDevice opens the given disk without buffering (FILE_FLAG_NO_BUFFERING) and measures its
+// read latency
+func (plugin *MultipathPlugin) benchmarkDevice(device model.Device, request model.BenchmarkRequest) (*model.BenchmarkResult, error) {
+	log.Tracef(">>>>> benchmarkDevice, Path=%v", device.Private.WindowsDisk.Path)
+	defer log.Trace("<<<<< benchmarkDevice")
+
+	file, err := openDirect(device.Private.WindowsDisk.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return runBenchmark(file, device.Size, request.BlockSize, time.Duration(request.DurationSeconds)*time.Second)
+}
+
+// getProbePath returns the disk path of the given device read by the direct I/O probes
+func getProbePath(device model.Device) (string, error) {
+	if device.Private == nil || device.Private.WindowsDisk == nil || device.Private.WindowsDisk.Path == "" {
+		return "", cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+	return device.Private.WindowsDisk.Path, nil
+}
+
+// openProbe opens the disk for direct I/O; Windows reads the disk synchronously, bounded by the
+// probe's timeout
+func openProbe(devicePath string, timeout time.Duration) (probeDevice, error) {
+	return openDirect(devicePath)
+}
+
+// openDirect opens the given disk read-only without buffering (FILE_FLAG_NO_BUFFERING)
+func openDirect(path string) (*os.File, error) {
+	diskPath, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, cerrors.NewChapiError(err)
+	}
+	handle, err := windows.CreateFile(diskPath, windows.GENERIC_READ, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_NO_BUFFERING, 0)
+	if err != nil {
+		log.Errorf("Unable to open disk %v, err=%v", path, err)
+		return nil, cerrors.NewChapiError(err)
+	}
+	return os.NewFile(uintptr(handle), path), nil
+}
+
+// isReservationConflictError returns false; Windows doesn't report a RESERVATION CONFLICT status
+// as a distinct I/O error
+func isReservationConflictError(err error) bool {
+	return false
+}
+
 // createFileSystem is called to create a file system on the given device
-func (plugin *MultipathPlugin) createFileSystem(device model.Device, filesystem string) error {
-	log.Tracef(">>>>> createFileSystem, Path=%v, filesystem=%v", device.Private.WindowsDisk.Path, filesystem)
+func (plugin *MultipathPlugin) createFileSystem(device model.Device, filesystem string, fsOptions *model.FileSystemOptions) error {
+	log.Tracef(">>>>> createFileSystem, Path=%v, filesystem=%v, fullFormat=%v", device.Private.WindowsDisk.Path, filesystem, fsOptions.FullFormat)
 	defer log.Trace("<<<<< createFileSystem")
 
+	// Validate the ReFS format options before touching the disk; Format-Volume only reports an
+	// unsupported option once the disk has been initialized and partitioned
+	filesystem, err := validateRefsFormat(device, filesystem, fsOptions, getFormatHost(device))
+	if err != nil {
+		return err
+	}
+
 	// Make sure disk is online and writable before attempting the format
 	if err := plugin.MakeDiskOnlineAndWritable(device.Private.WindowsDisk.Path, true, true); err != nil {
 		return err
@@ -210,10 +483,97 @@ func (plugin *MultipathPlugin) createFileSystem(device model.Device, filesystem
 	}
 
 	// Use PowerShell to format the disk
-	_, _, err := powershell.PartitionAndFormatVolume(device.Private.WindowsDisk.Path, filesystem)
+	formatOptions := powershell.FormatOptions{FullFormat: fsOptions.FullFormat}
+	if isRefsFileSystem(filesystem) {
+		formatOptions.IntegrityStreams, formatOptions.DevDrive = fsOptions.IntegrityStreams, fsOptions.DevDrive
+	}
+	_, _, err = powershell.PartitionAndFormatVolumeWithFormatOptions(device.Private.WindowsDisk.Path, filesystem, formatOptions)
 	return err
 }
 
+// isFullFormatSupported is true under Windows; Format-Volume -Full zeroes the whole volume
+const isFullFormatSupported = true
+
+// getPlatformFormatPercentComplete returns the percent complete of the running format storage job,
+// if a single format storage job is running
+func getPlatformFormatPercentComplete() (int, bool) {
+	storageJobs, err := wmi.GetMSFTStorageJob(fmt.Sprintf("JobState=%v AND Name LIKE '%%Format%%'", wmi.StorageJobStateRunning))
+	if err != nil {
+		log.Tracef("Unable to enumerate the format storage jobs, err=%v", err)
+		return 0, false
+	}
+	if len(storageJobs) != 1 {
+		log.Tracef("%v format storage jobs running, percent complete not reported", len(storageJobs))
+		return 0, false
+	}
+	return int(storageJobs[0].PercentComplete), true
+}
+
+// getFormatHost returns the OS build and disk properties the ReFS format options depend on.  If
+// the OS build cannot be enumerated, it's reported as build 0 so that ReFS formats fail validation
+// with the missing features rather than deep inside Format-Volume.
+func getFormatHost(device model.Device) formatHost {
+	host := formatHost{clustered: device.Private.WindowsDisk.IsClustered}
+	operatingSystem, err := wmi.GetWin32OperatingSystem()
+	if err != nil || operatingSystem == nil {
+		log.Errorf("Unable to enumerate the OS build, err=%v", err)
+		return host
+	}
+	host.build, _ = strconv.Atoi(operatingSystem.BuildNumber)
+	return host
+}
+
+// getFileSystemUUID is not reported under Windows; mounted file systems are not verified
+func (plugin *MultipathPlugin) getFileSystemUUID(device model.Device) (string, error) {
+	return "", nil
+}
+
+// extendPartition rescans the host's disks, so that Windows sees the expanded volume size, and then
+// extends the last partition on the given device to fill the device.  It's equivalent to running
+// Update-HostStorageCache followed by Resize-Partition with the partition's maximum supported size.
+func (plugin *MultipathPlugin) extendPartition(device model.Device) ([]*model.DevicePartition, error) {
+	diskNumber := device.Private.WindowsDisk.Number
+	log.Tracef(">>>>> extendPartition, diskNumber=%v", diskNumber)
+	defer log.Trace("<<<<< extendPartition")
+
+	// Update the host's storage cache
+	if err := rescan.Run(device.SerialNumber, wmi.RescanDisks); err != nil {
+		return nil, err
+	}
+
+	// Only the last partition can be extended into the space added to the end of the disk
+	partitions, err := wmi.GetMSFTPartitionForDiskNumber(diskNumber)
+	if err != nil {
+		return nil, err
+	}
+	var lastPartition *wmi.MSFT_Partition
+	for _, partition := range partitions {
+		if (lastPartition == nil) || (partition.Offset > lastPartition.Offset) {
+			lastPartition = partition
+		}
+	}
+	if lastPartition == nil {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageNoPartitions)
+	}
+
+	// Resize the partition to its maximum supported size, if not already that size
+	_, sizeMax, err := wmi.GetMSFTPartitionSupportedSize(diskNumber, lastPartition.PartitionNumber)
+	if err != nil {
+		return nil, err
+	}
+	if sizeMax > lastPartition.Size {
+		log.Infof("Extending disk %v partition %v from %v to %v bytes", diskNumber, lastPartition.PartitionNumber, lastPartition.Size, sizeMax)
+		if err = wmi.ResizeMSFTPartition(diskNumber, lastPartition.PartitionNumber, sizeMax); err != nil {
+			return nil, err
+		}
+	} else {
+		log.Infof("Disk %v partition %v already at its maximum size of %v bytes", diskNumber, lastPartition.PartitionNumber, lastPartition.Size)
+	}
+
+	// Return the updated partitions
+	return plugin.getPartitionInfo(device.SerialNumber)
+}
+
 // getIscsiTarget enumerates the IscsiTarget object for the "devicePathID" device.  The caller needs
 // to pass in the current target mappings (targetMappings object) and pass in cache objects where
 // this routine can cache the last enumerated target ports.  This routine first checks the cache to
@@ -247,6 +607,7 @@ func (plugin *MultipathPlugin) getIscsiTarget(devicePathID string, targetMapping
 		// We found the iSCSI target for the device.  Populate the IscsiTarget object
 		// with the target iqn.
 		iscsiTarget = &model.IscsiTarget{Name: targetMapping.TargetName}
+		iscsiTarget.DiscoveryIP = plugin.iscsiPlugin.GetDiscoveryPortal(targetMapping.TargetName)
 
 		// See if we have a cached target scope for the iqn.  If we do not, enumerate
 		// the scope from the device.
@@ -318,3 +679,264 @@ func (plugin *MultipathPlugin) MakeDiskOnlineAndWritable(path string, makeOnline
 
 	return nil
 }
+
+// getDeviceTuning reports the queue depth of the StorPort adapters the given device is reached
+// through.  StorPort limits the outstanding I/O per adapter (MaxNumberOfIO), rather than per LUN,
+// so the lowest limit configured on the device's adapters is reported; the queue depth is omitted
+// if no limit is configured (StorPort default).  The other queue settings are Linux only.
+func (plugin *MultipathPlugin) getDeviceTuning(device model.Device) (*model.DeviceTuning, error) {
+	log.Trace(">>>>> getDeviceTuning")
+	defer log.Trace("<<<<< getDeviceTuning")
+
+	miniports, err := getDeviceMiniports(device)
+	if err != nil {
+		return nil, err
+	}
+
+	tuning := new(model.DeviceTuning)
+	for _, miniport := range miniports {
+		queueDepth, err := readMiniportQueueDepth(miniport)
+		if err != nil {
+			log.Errorf("Unable to read the %v queue depth, err=%v", miniport, err)
+			return nil, cerrors.NewChapiError(err)
+		}
+		if (queueDepth != nil) && ((tuning.QueueDepth == nil) || (*queueDepth < *tuning.QueueDepth)) {
+			tuning.QueueDepth = queueDepth
+		}
+	}
+	return tuning, nil
+}
+
+// getDeviceMiniports returns the service names of the StorPort miniport drivers (e.g. "iScsiPrt")
+// of the adapters the given device is reached through
+func getDeviceMiniports(device model.Device) ([]string, error) {
+	if (device.Private == nil) || (device.Private.WindowsDisk == nil) {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+
+	// Each MPIO path is reached through a SCSI port (adapter)
+	descriptors, err := getMPIODescriptors()
+	if err != nil {
+		log.Errorf("Unable to enumerate the MPIO disks, err=%v", err)
+		return nil, cerrors.NewChapiError(err)
+	}
+	descriptor := findMPIODescriptor(descriptors, device.Private.WindowsDisk.Path)
+	if descriptor == nil {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+
+	var miniports []string
+	for _, pdo := range descriptor.PdoInformation {
+		miniport, err := getScsiPortMiniport(pdo.PortNumber)
+		if err != nil {
+			log.Errorf("Unable to find the miniport driver of SCSI port %v, err=%v", pdo.PortNumber, err)
+			return nil, cerrors.NewChapiError(err)
+		}
+		if !stringInSlice(miniports, miniport) {
+			miniports = append(miniports, miniport)
+		}
+	}
+	return miniports, nil
+}
+
+// stringInSlice returns true if the given slice contains the given string (case insensitive)
+func stringInSlice(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// getScsiPortMiniportFromRegistry returns the service name of the miniport driver of the given
+// SCSI port, as recorded in the registry's SCSI device map
+func getScsiPortMiniportFromRegistry(portNumber uint8) (string, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, fmt.Sprintf(regKeyScsiPort, portNumber), registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer k.Close()
+
+	miniport, _, err := k.GetStringValue(regValueScsiPortDriver)
+	return miniport, err
+}
+
+// readMiniportQueueDepthFromRegistry returns the outstanding I/O limit (MaxNumberOfIO) of the
+// given miniport driver's adapters, or nil if not configured
+func readMiniportQueueDepthFromRegistry(miniport string) (*uint64, error) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, fmt.Sprintf(regKeyMiniportDevice, miniport), registry.QUERY_VALUE)
+	if err == registry.ErrNotExist {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	queueDepth, _, err := k.GetIntegerValue(regValueMaxNumberOfIO)
+	if err == registry.ErrNotExist {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &queueDepth, nil
+}
+
+// writeMiniportQueueDepthToRegistry sets the outstanding I/O limit (MaxNumberOfIO) of the given
+// miniport driver's adapters
+func writeMiniportQueueDepthToRegistry(miniport string, queueDepth uint64) error {
+	k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, fmt.Sprintf(regKeyMiniportDevice, miniport), registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	return k.SetDWordValue(regValueMaxNumberOfIO, uint32(queueDepth))
+}
+
+// getDevicePaths reports the paths to the given device along with their ALUA state
+func (plugin *MultipathPlugin) getDevicePaths(device model.Device) ([]*model.DevicePath, error) {
+	log.Trace(">>>>> getDevicePaths")
+	defer log.Trace("<<<<< getDevicePaths")
+
+	if (device.Private == nil) || (device.Private.WindowsDisk == nil) {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+
+	// MPIO reports the device's paths (PDOs) and the Microsoft DSM reports their states
+	descriptors, err := getMPIODescriptors()
+	if err != nil {
+		log.Errorf("Unable to enumerate the MPIO disks, err=%v", err)
+		return nil, cerrors.NewChapiError(err)
+	}
+	descriptor := findMPIODescriptor(descriptors, device.Private.WindowsDisk.Path)
+	if descriptor == nil {
+		return nil, cerrors.NewChapiError(cerrors.NotFound, errorMessageDeviceNotFound)
+	}
+
+	var dsmPaths []*wmi.DSM_Path_V2
+	if policies, err := getDSMPolicies(); err != nil {
+		log.Errorf("Unable to query the DSM load balance policies, err=%v", err)
+	} else if policy := findDSMPolicy(policies, descriptor.InstanceName); policy != nil {
+		dsmPaths = policy.DSM_Paths
+	}
+	return toDevicePaths(descriptor, dsmPaths), nil
+}
+
+// findMPIODescriptor returns the MPIO_GET_DESCRIPTOR object of the disk with the given path (e.g.
+// "\\?\mpio#disk&ven_nimble&prod_server&rev_1.0#1&7f6ac24&0&...#{53f56307-...}"), or nil if the disk
+// is not an MPIO disk.  The descriptor's instance name is the disk's PnP device instance ID,
+// followed by a WMI instance index (e.g. "MPIO\Disk&Ven_Nimble&Prod_Server&Rev_1.0\1&7f6ac24&0&..._0").
+func findMPIODescriptor(descriptors []*wmi.MPIO_GET_DESCRIPTOR, diskPath string) *wmi.MPIO_GET_DESCRIPTOR {
+	instanceID := strings.TrimPrefix(diskPath, `\\?\`)
+	if i := strings.LastIndex(instanceID, "#{"); i >= 0 {
+		instanceID = instanceID[:i]
+	}
+	instanceID = strings.Replace(instanceID, "#", `\`, -1)
+	for _, descriptor := range descriptors {
+		if strings.EqualFold(trimInstanceIndex(descriptor.InstanceName), instanceID) {
+			return descriptor
+		}
+	}
+	return nil
+}
+
+// findDSMPolicy returns the DSM_QueryLBPolicy_V2 object with the given instance name, or nil if the
+// disk is not claimed by the Microsoft DSM
+func findDSMPolicy(policies []*wmi.DSM_QueryLBPolicy_V2, instanceName string) *wmi.DSM_QueryLBPolicy_V2 {
+	for _, policy := range policies {
+		if strings.EqualFold(trimInstanceIndex(policy.InstanceName), trimInstanceIndex(instanceName)) {
+			return policy
+		}
+	}
+	return nil
+}
+
+// trimInstanceIndex removes the WMI instance index (e.g. "_0") from the given instance name
+func trimInstanceIndex(instanceName string) string {
+	if i := strings.LastIndex(instanceName, "_"); i >= 0 {
+		if _, err := strconv.Atoi(instanceName[i+1:]); err == nil {
+			return instanceName[:i]
+		}
+	}
+	return instanceName
+}
+
+// toDevicePaths converts the given MPIO disk's paths into device paths.  The paths are named after
+// their SCSI address (port:path:target:lun) and their ALUA states are taken from the DSM paths, if
+// known.
+func toDevicePaths(descriptor *wmi.MPIO_GET_DESCRIPTOR, dsmPaths []*wmi.DSM_Path_V2) []*model.DevicePath {
+	var paths []*model.DevicePath
+	for _, pdo := range descriptor.PdoInformation {
+		path := &model.DevicePath{
+			Name:        fmt.Sprintf("%v:%v:%v:%v", pdo.PortNumber, pdo.ScsiPathId, pdo.TargetId, pdo.Lun),
+			State:       devicePathRunning,
+			AccessState: model.AluaUnknown,
+		}
+		for _, dsmPath := range dsmPaths {
+			if dsmPath.DsmPathId != pdo.PathIdentifier {
+				continue
+			}
+			if dsmPath.FailedPath {
+				path.State = devicePathOffline
+			}
+			if dsmPath.ALUASupport != dsmALUANotSupported {
+				targetPortGroup := dsmPath.TargetPortGroup_Identifier
+				path.AccessState = sgio.AluaAccessState(byte(dsmPath.TargetPortGroup_State))
+				path.TargetPortGroup = &targetPortGroup
+				path.Preferred = dsmPath.TargetPortGroup_Preferred
+			}
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// recoverDevicePaths reinstates the failed paths to the given device that are reachable again
+func (plugin *MultipathPlugin) recoverDevicePaths(device model.Device) (*model.PathRecoveryReport, error) {
+	log.Trace(">>>>> recoverDevicePaths")
+	defer log.Trace("<<<<< recoverDevicePaths")
+
+	// TODO - MPIO restores failed paths itself once the path verification period expires
+	return nil, cerrors.NewChapiError(cerrors.Unimplemented)
+}
+
+// setDeviceTuning sets the queue depth of the StorPort adapters the given device is reached
+// through.  The limit applies to every adapter of the same miniport driver, and to all their LUNs,
+// once the adapters are restarted (e.g. on reboot).  The other queue settings are Linux only.
+func (plugin *MultipathPlugin) setDeviceTuning(device model.Device, tuning model.DeviceTuning) error {
+	log.Trace(">>>>> setDeviceTuning")
+	defer log.Trace("<<<<< setDeviceTuning")
+
+	if (tuning.ReadAheadKB != nil) || (tuning.NrRequests != nil) || (tuning.Scheduler != "") || (tuning.RqAffinity != nil) {
+		return cerrors.NewChapiError(cerrors.Unimplemented, errorMessageTuningUnsupported)
+	}
+	if tuning.QueueDepth == nil {
+		return nil
+	}
+	if (*tuning.QueueDepth == 0) || (*tuning.QueueDepth > math.MaxUint32) {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageInvalidQueueDepth, *tuning.QueueDepth)
+	}
+
+	miniports, err := getDeviceMiniports(device)
+	if err != nil {
+		return err
+	}
+	for _, miniport := range miniports {
+		if err = writeMiniportQueueDepth(miniport, *tuning.QueueDepth); err != nil {
+			log.Errorf("Unable to set the %v queue depth, err=%v", miniport, err)
+			return cerrors.NewChapiErrorf(cerrors.Internal, errorMessageTuningNotApplied, regValueMaxNumberOfIO, miniport, err)
+		}
+		log.Infof("%v %v set to %v, applied when its adapters are restarted", miniport, regValueMaxNumberOfIO, *tuning.QueueDepth)
+	}
+	return nil
+}
+
+// getInquiryProduct returns the SCSI inquiry product of the given device, or an empty string if it
+// is unknown
+func getInquiryProduct(device *model.Device) string {
+	if (device.Private == nil) || (device.Private.WindowsDisk == nil) {
+		return ""
+	}
+	return device.Private.WindowsDisk.Model
+}
@@ -0,0 +1,123 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package multipath
+
+import (
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	"github.com/hpe-storage/common-host-libs/windows/wmi"
+)
+
+func TestGetDevicePaths(t *testing.T) {
+	savedGetMPIODescriptors, savedGetDSMPolicies := getMPIODescriptors, getDSMPolicies
+	defer func() { getMPIODescriptors, getDSMPolicies = savedGetMPIODescriptors, savedGetDSMPolicies }()
+
+	instanceName := `MPIO\Disk&Ven_Nimble&Prod_Server&Rev_1.0\1&7f6ac24&0&3630`
+	getMPIODescriptors = func() ([]*wmi.MPIO_GET_DESCRIPTOR, error) {
+		return []*wmi.MPIO_GET_DESCRIPTOR{
+			{InstanceName: instanceName + "1_0", NumberPaths: 1, PdoInformation: []*wmi.MPIO_PDOINFORMATION{{PortNumber: 3, PathIdentifier: 1}}},
+			{InstanceName: instanceName + "_0", NumberPaths: 2, PdoInformation: []*wmi.MPIO_PDOINFORMATION{
+				{PortNumber: 2, TargetId: 0, Lun: 1, PathIdentifier: 0x77030000},
+				{PortNumber: 2, TargetId: 1, Lun: 1, PathIdentifier: 0x77030001},
+			}},
+		}, nil
+	}
+	getDSMPolicies = func() ([]*wmi.DSM_QueryLBPolicy_V2, error) {
+		return []*wmi.DSM_QueryLBPolicy_V2{{InstanceName: instanceName + "_0", DSM_Paths: []*wmi.DSM_Path_V2{
+			{DsmPathId: 0x77030001, FailedPath: true, ALUASupport: 1, TargetPortGroup_State: 2, TargetPortGroup_Identifier: 2},
+			{DsmPathId: 0x77030000, ALUASupport: 1, TargetPortGroup_State: 0, TargetPortGroup_Identifier: 1, TargetPortGroup_Preferred: true},
+		}}}, nil
+	}
+
+	device := model.Device{Private: &model.DevicePrivate{WindowsDisk: &wmi.MSFT_Disk{
+		Path: `\\?\mpio#disk&ven_nimble&prod_server&rev_1.0#1&7f6ac24&0&3630#{53f56307-b6bf-11d0-94f2-00a0c91efb8b}`,
+	}}}
+	paths, err := (&MultipathPlugin{}).getDevicePaths(device)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 paths, got %v", len(paths))
+	}
+	expected := []model.DevicePath{
+		{Name: "2:0:0:1", State: "running", AccessState: model.AluaActiveOptimized, Preferred: true},
+		{Name: "2:0:1:1", State: "offline", AccessState: model.AluaStandby},
+	}
+	for i, path := range paths {
+		if path.TargetPortGroup == nil || *path.TargetPortGroup != uint16(i+1) {
+			t.Errorf("unexpected target port group, path=%+v", path)
+		}
+		path.TargetPortGroup = nil
+		if *path != expected[i] {
+			t.Errorf("unexpected path %+v", path)
+		}
+	}
+
+	// The paths of a disk not claimed by the Microsoft DSM are reported without their ALUA state
+	getDSMPolicies = func() ([]*wmi.DSM_QueryLBPolicy_V2, error) { return nil, nil }
+	if paths, err = (&MultipathPlugin{}).getDevicePaths(device); err != nil || len(paths) != 2 || paths[0].AccessState != model.AluaUnknown {
+		t.Errorf("unexpected paths %+v, err=%v", paths, err)
+	}
+
+	device.Private.WindowsDisk.Path = `\\?\scsi#disk&ven_nimble&prod_server#4&1a2b3c4d&0&000100#{53f56307-b6bf-11d0-94f2-00a0c91efb8b}`
+	if _, err = (&MultipathPlugin{}).getDevicePaths(device); err == nil {
+		t.Error("non MPIO disk paths reported")
+	}
+}
+
+func TestDeviceTuning(t *testing.T) {
+	savedGetMPIODescriptors, savedGetScsiPortMiniport := getMPIODescriptors, getScsiPortMiniport
+	savedReadMiniportQueueDepth, savedWriteMiniportQueueDepth := readMiniportQueueDepth, writeMiniportQueueDepth
+	defer func() {
+		getMPIODescriptors, getScsiPortMiniport = savedGetMPIODescriptors, savedGetScsiPortMiniport
+		readMiniportQueueDepth, writeMiniportQueueDepth = savedReadMiniportQueueDepth, savedWriteMiniportQueueDepth
+	}()
+
+	// The device is reached through two iScsiPrt ports and one QLogic port
+	getMPIODescriptors = func() ([]*wmi.MPIO_GET_DESCRIPTOR, error) {
+		return []*wmi.MPIO_GET_DESCRIPTOR{{InstanceName: `MPIO\Disk&Ven_Nimble\1&7f6ac24_0`, PdoInformation: []*wmi.MPIO_PDOINFORMATION{
+			{PortNumber: 2}, {PortNumber: 3}, {PortNumber: 4},
+		}}}, nil
+	}
+	getScsiPortMiniport = func(portNumber uint8) (string, error) {
+		if portNumber == 4 {
+			return "ql2300", nil
+		}
+		return "iScsiPrt", nil
+	}
+	queueDepths := map[string]uint64{"ql2300": 256}
+	readMiniportQueueDepth = func(miniport string) (*uint64, error) {
+		if queueDepth, ok := queueDepths[miniport]; ok {
+			return &queueDepth, nil
+		}
+		return nil, nil
+	}
+	writeMiniportQueueDepth = func(miniport string, queueDepth uint64) error {
+		queueDepths[miniport] = queueDepth
+		return nil
+	}
+
+	plugin := &MultipathPlugin{}
+	device := model.Device{Private: &model.DevicePrivate{WindowsDisk: &wmi.MSFT_Disk{Path: `\\?\mpio#disk&ven_nimble#1&7f6ac24#{53f56307-b6bf-11d0-94f2-00a0c91efb8b}`}}}
+	tuning, err := plugin.getDeviceTuning(device)
+	if err != nil || tuning.QueueDepth == nil || *tuning.QueueDepth != 256 {
+		t.Fatalf("unexpected tuning %+v, err=%v", tuning, err)
+	}
+
+	queueDepth := uint64(64)
+	if err = plugin.setDeviceTuning(device, model.DeviceTuning{QueueDepth: &queueDepth}); err != nil {
+		t.Fatal(err)
+	}
+	if len(queueDepths) != 2 || queueDepths["iScsiPrt"] != 64 || queueDepths["ql2300"] != 64 {
+		t.Errorf("unexpected queue depths %v", queueDepths)
+	}
+
+	// The Linux only settings and invalid queue depths are rejected
+	readAheadKB, invalidQueueDepth := uint64(4096), uint64(0)
+	for _, tuning := range []model.DeviceTuning{{ReadAheadKB: &readAheadKB}, {Scheduler: "none", QueueDepth: &queueDepth}, {QueueDepth: &invalidQueueDepth}} {
+		if err = plugin.setDeviceTuning(device, tuning); err == nil {
+			t.Errorf("tuning %+v not rejected", tuning)
+		}
+	}
+}
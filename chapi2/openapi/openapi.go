@@ -0,0 +1,322 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package openapi
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// OPENAPI SPECIFICATION
+//
+//		Integrators generating CHAPI clients in other languages need a machine readable
+//		description of the API.  NewDocument builds an OpenAPI 3 document from a list of
+//		endpoints, each naming the Go objects it accepts and returns.  Object schemas are derived
+//		from the Go types by reflection, using their json struct tags for property names.
+//		Properties without omitempty are reported as required.  Named struct types are added to
+//		the document's component schemas and referenced by name.
+//
+//		CHAPI responses wrap the returned object in a "data" property and failures return a
+//		cerrors.ChapiError in an "errors" property; each operation's responses describe that
+//		envelope unless the endpoint is Unwrapped.  Paged endpoints also accept the "limit" and
+//		"offset" query parameters and may return a model.Page in a "page" property.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+const (
+	// Version is the OpenAPI specification version of the generated documents
+	Version = "3.0.3"
+
+	contentTypeJSON = "application/json"
+	componentsRef   = "#/components/schemas/"
+)
+
+var (
+	// pathParameterRegexp matches the path variables (e.g. "{serialNumber}" or "{id:[0-9]+}") of
+	// a route pattern
+	pathParameterRegexp = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	ipType         = reflect.TypeOf(net.IP{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	chapiErrorType = reflect.TypeOf(cerrors.ChapiError{})
+	pageType       = reflect.TypeOf(model.Page{})
+)
+
+// Endpoint describes a single route and the objects it accepts and returns
+type Endpoint struct {
+	Name      string      // Route name, used as the operation ID
+	Method    string      // HTTP method (e.g. "GET")
+	Pattern   string      // Route pattern (e.g. "/api/v1/devices/{serialNumber}")
+	Summary   string      // Short description of the operation
+	Query     []string    // Optional query parameters
+	Request   interface{} // Request body object (e.g. model.PublishInfo{}), nil if none
+	Response  interface{} // Returned object (e.g. []*model.Device{}), nil if none
+	Unwrapped bool        // Response is returned as is rather than in the "data" envelope (any object if nil)
+	Paged     bool        // Response list is paginated through the limit and offset query parameters
+}
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       *Info                            `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components *Components                      `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Operation is a single API operation (i.e. method on a path)
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's request body
+type RequestBody struct {
+	Content map[string]*MediaType `json:"content"`
+}
+
+// Response describes an operation's response
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType describes the body content of a request or response
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the named object schemas referenced by the operations
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is the subset of the OpenAPI schema object needed to describe Go types
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// NewDocument returns the OpenAPI document describing the given endpoints
+func NewDocument(title string, version string, endpoints []*Endpoint) *Document {
+	generator := &schemaGenerator{schemas: make(map[string]*Schema), types: make(map[reflect.Type]string)}
+	document := &Document{
+		OpenAPI:    Version,
+		Info:       &Info{Title: title, Version: version},
+		Paths:      make(map[string]map[string]*Operation),
+		Components: &Components{Schemas: generator.schemas},
+	}
+	errorEnvelope := envelopeSchema("errors", generator.schemaFor(chapiErrorType))
+
+	for _, endpoint := range endpoints {
+		path := pathParameterRegexp.ReplaceAllString(endpoint.Pattern, "{$1}")
+		operation := &Operation{
+			OperationID: endpoint.Name,
+			Summary:     endpoint.Summary,
+			Responses: map[string]*Response{
+				"200":     {Description: "Success"},
+				"default": {Description: "Failure", Content: jsonContent(errorEnvelope)},
+			},
+		}
+
+		for _, match := range pathParameterRegexp.FindAllStringSubmatch(endpoint.Pattern, -1) {
+			operation.Parameters = append(operation.Parameters, &Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		for _, query := range endpoint.Query {
+			operation.Parameters = append(operation.Parameters, &Parameter{Name: query, In: "query", Schema: &Schema{Type: "string"}})
+		}
+		if endpoint.Paged {
+			operation.Parameters = append(operation.Parameters,
+				&Parameter{Name: "limit", In: "query", Schema: &Schema{Type: "integer", Format: "int64"}},
+				&Parameter{Name: "offset", In: "query", Schema: &Schema{Type: "integer", Format: "int64"}})
+		}
+		if endpoint.Request != nil {
+			operation.RequestBody = &RequestBody{Content: jsonContent(generator.schemaFor(reflect.TypeOf(endpoint.Request)))}
+		}
+
+		switch {
+		case endpoint.Response != nil && endpoint.Unwrapped:
+			operation.Responses["200"].Content = jsonContent(generator.schemaFor(reflect.TypeOf(endpoint.Response)))
+		case endpoint.Response != nil:
+			envelope := envelopeSchema("data", generator.schemaFor(reflect.TypeOf(endpoint.Response)))
+			if endpoint.Paged {
+				envelope.Properties["page"] = generator.schemaFor(pageType)
+			}
+			operation.Responses["200"].Content = jsonContent(envelope)
+		case endpoint.Unwrapped:
+			operation.Responses["200"].Content = jsonContent(&Schema{Type: "object"})
+		}
+
+		if document.Paths[path] == nil {
+			document.Paths[path] = make(map[string]*Operation)
+		}
+		document.Paths[path][strings.ToLower(endpoint.Method)] = operation
+	}
+	return document
+}
+
+// envelopeSchema returns the schema of a CHAPI response carrying the given schema in the named
+// property
+func envelopeSchema(property string, schema *Schema) *Schema {
+	return &Schema{Type: "object", Properties: map[string]*Schema{property: schema}}
+}
+
+// jsonContent returns the JSON body content with the given schema
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{contentTypeJSON: {Schema: schema}}
+}
+
+// schemaGenerator derives schemas from Go types, collecting the named struct schemas
+type schemaGenerator struct {
+	schemas map[string]*Schema      // Component schemas by name
+	types   map[reflect.Type]string // Component name of each named struct type
+}
+
+// schemaFor returns the schema of the given Go type.  Named struct types are returned as a
+// reference to their component schema.
+func (generator *schemaGenerator) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64"}
+	case ipType:
+		return &Schema{Type: "string"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: generator.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: generator.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return generator.structSchema(t)
+		}
+		return &Schema{Ref: componentsRef + generator.componentName(t)}
+	}
+
+	// Interfaces (and anything else) can hold any value
+	return &Schema{}
+}
+
+// componentName returns the component schema name of the given named struct type, adding its
+// schema to the components on first use
+func (generator *schemaGenerator) componentName(t reflect.Type) string {
+	if name, ok := generator.types[t]; ok {
+		return name
+	}
+
+	// Qualify the name with the package name if another type already uses it
+	name := t.Name()
+	if _, taken := generator.schemas[name]; taken {
+		packagePath := strings.Split(t.PkgPath(), "/")
+		name = packagePath[len(packagePath)-1] + "." + name
+	}
+
+	// Register the name before generating the schema so that recursive types resolve
+	generator.types[t] = name
+	generator.schemas[name] = &Schema{}
+	*generator.schemas[name] = *generator.structSchema(t)
+	return name
+}
+
+// structSchema returns the object schema of the given struct type's JSON properties
+func (generator *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	generator.addProperties(schema, t)
+	return schema
+}
+
+// addProperties adds the JSON properties of the given struct type to the object schema.  The
+// properties of embedded structs without a json tag are promoted, as encoding/json does.
+func (generator *schemaGenerator) addProperties(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, options = tag[:comma], tag[comma:]
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			generator.addProperties(schema, fieldType)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue // Unexported
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(options, ",string") {
+			schema.Properties[name] = &Schema{Type: "string"}
+		} else {
+			schema.Properties[name] = generator.schemaFor(field.Type)
+		}
+		if !strings.Contains(options, ",omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
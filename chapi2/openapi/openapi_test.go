@@ -0,0 +1,102 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testObject struct {
+	testBase
+	Name     string            `json:"name,omitempty"`
+	Size     uint64            `json:"size,string"`
+	Created  time.Time         `json:"created"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Children []*testObject     `json:"children,omitempty"`
+	Ignored  string            `json:"-"`
+	hidden   string
+}
+
+func TestNewDocument(t *testing.T) {
+	document := NewDocument("Test API", "1.0.0", []*Endpoint{
+		{Name: "GetObjects", Method: "GET", Pattern: "/api/v1/objects/{id:[0-9]+}", Query: []string{"name"}, Response: []*testObject{}},
+		{Name: "CreateObject", Method: "POST", Pattern: "/api/v1/objects", Request: testObject{}, Response: &testObject{}},
+		{Name: "GetDocument", Method: "GET", Pattern: "/api/v1/document", Unwrapped: true},
+		{Name: "ListObjects", Method: "GET", Pattern: "/api/v1/objects", Response: []*testObject{}, Paged: true},
+	})
+
+	// The document must be JSON encodable (recursive types must not recurse forever)
+	if _, err := json.Marshal(document); err != nil {
+		t.Fatal(err)
+	}
+	if document.OpenAPI != Version || document.Info.Title != "Test API" || len(document.Paths) != 3 {
+		t.Fatalf("unexpected document %+v", document)
+	}
+
+	getObjects := document.Paths["/api/v1/objects/{id}"]["get"]
+	if getObjects == nil || getObjects.OperationID != "GetObjects" || len(getObjects.Parameters) != 2 {
+		t.Fatalf("unexpected operation %+v", getObjects)
+	}
+	if parameter := getObjects.Parameters[0]; parameter.Name != "id" || parameter.In != "path" || !parameter.Required {
+		t.Errorf("unexpected path parameter %+v", parameter)
+	}
+	if parameter := getObjects.Parameters[1]; parameter.Name != "name" || parameter.In != "query" || parameter.Required {
+		t.Errorf("unexpected query parameter %+v", parameter)
+	}
+	data := getObjects.Responses["200"].Content[contentTypeJSON].Schema.Properties["data"]
+	if data.Type != "array" || data.Items.Ref != componentsRef+"testObject" {
+		t.Errorf("unexpected response schema %+v", data)
+	}
+	failure := getObjects.Responses["default"].Content[contentTypeJSON].Schema.Properties["errors"]
+	if failure.Ref != componentsRef+"ChapiError" {
+		t.Errorf("unexpected failure schema %+v", failure)
+	}
+
+	createObject := document.Paths["/api/v1/objects"]["post"]
+	if createObject == nil || createObject.RequestBody.Content[contentTypeJSON].Schema.Ref != componentsRef+"testObject" {
+		t.Errorf("unexpected operation %+v", createObject)
+	}
+
+	listObjects := document.Paths["/api/v1/objects"]["get"]
+	if listObjects == nil || len(listObjects.Parameters) != 2 || listObjects.Parameters[0].Name != "limit" || listObjects.Parameters[1].Name != "offset" {
+		t.Errorf("unexpected paged operation %+v", listObjects)
+	} else if page := listObjects.Responses["200"].Content[contentTypeJSON].Schema.Properties["page"]; page == nil || page.Ref != componentsRef+"Page" {
+		t.Errorf("unexpected page schema %+v", page)
+	}
+
+	getDocument := document.Paths["/api/v1/document"]["get"]
+	if schema := getDocument.Responses["200"].Content[contentTypeJSON].Schema; schema.Type != "object" || schema.Properties != nil {
+		t.Errorf("unexpected unwrapped response schema %+v", schema)
+	}
+}
+
+func TestStructSchema(t *testing.T) {
+	document := NewDocument("Test API", "1.0.0", []*Endpoint{{Name: "GetObject", Method: "GET", Pattern: "/", Response: testObject{}}})
+	schema := document.Components.Schemas["testObject"]
+	if schema == nil {
+		t.Fatal("testObject schema not generated")
+	}
+
+	expected := map[string]*Schema{
+		"id":       {Type: "string"},
+		"name":     {Type: "string"},
+		"size":     {Type: "string"},
+		"created":  {Type: "string", Format: "date-time"},
+		"labels":   {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		"children": {Type: "array", Items: &Schema{Ref: componentsRef + "testObject"}},
+	}
+	if !reflect.DeepEqual(schema.Properties, expected) {
+		properties, _ := json.Marshal(schema.Properties)
+		t.Errorf("unexpected properties %s", properties)
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"id", "size", "created"}) {
+		t.Errorf("unexpected required properties %v", schema.Required)
+	}
+}
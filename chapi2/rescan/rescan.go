@@ -0,0 +1,162 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package rescan
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// RESCAN COORDINATION
+//
+//		Rescans are host wide (a Windows disk rescan, a Linux FC host scan), so a rescan issued
+//		while attaching one volume can re-surface the devices of another volume being detached,
+//		or just detached but not yet unmapped by the array, undoing the detach.  The attach and
+//		detach flows therefore coordinate through this package, by volume serial number:
+//
+//		-	Every rescan runs through Run, which numbers it (its generation).
+//		-	A detach runs between BeginDetach and the returned end function.  If any rescan ran
+//			during the detach, the volume's devices are cleaned up again (the cleanup function
+//			given to BeginDetach) before the detach completes.
+//		-	Once detached, the volume's devices are suppressed for SuppressWindowEnv seconds
+//			(default 30):  rescans run during that window clean them up again once complete.
+//		-	Attaching the volume again (Resume) ends its suppression window, so that its own
+//			rescans are never undone.
+//
+//		Cleanups are strictly scoped to the serial number of the suppressed volume and never run
+//		concurrently for the same volume.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// SuppressWindowEnv is the time, in seconds, a detached volume's devices are suppressed
+	// after the detach (0 to disable)
+	SuppressWindowEnv = config.SuppressWindowEnv
+
+	// DefaultSuppressWindow is the suppression window if SuppressWindowEnv is not set
+	DefaultSuppressWindow = 30 * time.Second
+)
+
+// Cleanup removes the devices of a detached volume re-surfaced by a rescan
+type Cleanup func() error
+
+// suppression is a volume being detached, or within its suppression window
+type suppression struct {
+	lock       sync.Mutex // Serializes the cleanups of the volume
+	cleanup    Cleanup    // Removes the volume's re-surfaced devices
+	generation uint64     // Rescan generation when the detach started
+	detaching  bool       // True while the volume is being detached
+	expires    time.Time  // End of the suppression window, once detached
+}
+
+var (
+	// now returns the current time; a variable so that tests can replace it
+	now = time.Now
+
+	lock         sync.Mutex
+	generation   uint64                          // Number of rescans started
+	suppressions = make(map[string]*suppression) // Suppressed volumes, keyed by serial number
+)
+
+// Generation returns the number of rescans started
+func Generation() uint64 {
+	lock.Lock()
+	defer lock.Unlock()
+	return generation
+}
+
+// Run runs the given host wide rescan on behalf of the given volume ("" if the volume isn't
+// known) and then cleans up the re-surfaced devices of the other volumes within their
+// suppression window.  The rescan's error is returned.
+func Run(serialNumber string, rescan func() error) error {
+	lock.Lock()
+	generation++
+	rescanGeneration := generation
+	lock.Unlock()
+
+	log.Tracef("Rescan %v started, serialNumber=%v", rescanGeneration, serialNumber)
+	err := rescan()
+
+	// Clean up the suppressed volumes once detached; a volume still being detached is cleaned up
+	// when its detach completes
+	for suppressedSerialNumber, suppressed := range getSuppressions(serialNumber) {
+		log.Infof("Rescan %v ran within the suppression window of %v, cleaning up its devices", rescanGeneration, suppressedSerialNumber)
+		suppressed.run(suppressedSerialNumber)
+	}
+	return err
+}
+
+// BeginDetach records that the given volume is being detached.  The returned function must be
+// called once the detach completes; if a rescan ran during the detach, the cleanup function is
+// called again, and the volume's suppression window starts.
+func BeginDetach(serialNumber string, cleanup Cleanup) (end func()) {
+	lock.Lock()
+	suppressed := &suppression{cleanup: cleanup, generation: generation, detaching: true}
+	suppressions[serialNumber] = suppressed
+	lock.Unlock()
+
+	return func() {
+		lock.Lock()
+		rescanned := generation != suppressed.generation
+		lock.Unlock()
+
+		if rescanned {
+			log.Infof("Volume %v rescanned while detaching, cleaning up its devices", serialNumber)
+			suppressed.run(serialNumber)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		suppressed.detaching = false
+		suppressed.expires = now().Add(getSuppressWindow())
+	}
+}
+
+// Resume ends the suppression of the given volume, e.g. because it's being attached again
+func Resume(serialNumber string) {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := suppressions[serialNumber]; ok {
+		log.Tracef("Suppression of %v ended", serialNumber)
+		delete(suppressions, serialNumber)
+	}
+}
+
+// getSuppressions returns the detached volumes, other than the given volume, within their
+// suppression window; expired suppressions are removed
+func getSuppressions(serialNumber string) map[string]*suppression {
+	lock.Lock()
+	defer lock.Unlock()
+
+	active := make(map[string]*suppression)
+	for suppressedSerialNumber, suppressed := range suppressions {
+		switch {
+		case suppressed.detaching:
+			continue
+		case !now().Before(suppressed.expires):
+			delete(suppressions, suppressedSerialNumber)
+		case suppressedSerialNumber != serialNumber:
+			active[suppressedSerialNumber] = suppressed
+		}
+	}
+	return active
+}
+
+// run calls the cleanup function of the suppressed volume
+func (suppressed *suppression) run(serialNumber string) {
+	suppressed.lock.Lock()
+	defer suppressed.lock.Unlock()
+	if err := suppressed.cleanup(); err != nil {
+		log.Errorf("Unable to clean up the re-surfaced devices of %v, err=%v", serialNumber, err)
+	}
+}
+
+// getSuppressWindow returns the time a detached volume's devices are suppressed
+func getSuppressWindow() time.Duration {
+	return config.Seconds(SuppressWindowEnv, DefaultSuppressWindow, 0)
+}
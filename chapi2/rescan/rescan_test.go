@@ -0,0 +1,87 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package rescan
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// setNow replaces the current time of the package and returns a function restoring it
+func setNow(current *time.Time) func() {
+	now = func() time.Time { return *current }
+	return func() { now = time.Now }
+}
+
+func TestDetachRescanned(t *testing.T) {
+	cleanups := 0
+	end := BeginDetach("serial1", func() error { cleanups++; return nil })
+
+	// A rescan during the detach doesn't clean up the volume being detached
+	Run("serial2", func() error { return nil })
+	if cleanups != 0 {
+		t.Fatalf("expected no cleanup during the detach, got %v", cleanups)
+	}
+
+	// The volume is cleaned up again once detached since a rescan ran during the detach
+	end()
+	if cleanups != 1 {
+		t.Errorf("expected 1 cleanup once detached, got %v", cleanups)
+	}
+	Resume("serial1")
+
+	// Without a rescan during the detach, the volume isn't cleaned up again
+	cleanups = 0
+	BeginDetach("serial1", func() error { cleanups++; return nil })()
+	if cleanups != 0 {
+		t.Errorf("expected no cleanup, got %v", cleanups)
+	}
+	Resume("serial1")
+}
+
+func TestSuppressWindow(t *testing.T) {
+	current := time.Now()
+	defer setNow(&current)()
+
+	cleanups := 0
+	BeginDetach("serial1", func() error { cleanups++; return nil })()
+
+	// Rescans within the window clean up the detached volume, except the volume's own rescans
+	Run("serial2", func() error { return nil })
+	Run("serial1", func() error { return nil })
+	if cleanups != 1 {
+		t.Errorf("expected 1 cleanup within the suppression window, got %v", cleanups)
+	}
+
+	// Rescans after the window don't
+	current = current.Add(DefaultSuppressWindow)
+	Run("serial2", func() error { return nil })
+	if cleanups != 1 {
+		t.Errorf("expected no cleanup after the suppression window, got %v", cleanups)
+	}
+
+	// Attaching the volume again ends its suppression window
+	BeginDetach("serial1", func() error { cleanups++; return nil })()
+	Resume("serial1")
+	Run("serial2", func() error { return nil })
+	if cleanups != 1 {
+		t.Errorf("expected no cleanup once the volume is attached again, got %v", cleanups)
+	}
+}
+
+func TestGetSuppressWindow(t *testing.T) {
+	defer os.Unsetenv(SuppressWindowEnv)
+	for value, expected := range map[string]time.Duration{
+		"":    DefaultSuppressWindow,
+		"0":   0,
+		"120": 120 * time.Second,
+		"-1":  DefaultSuppressWindow,
+		"1m":  DefaultSuppressWindow,
+	} {
+		os.Setenv(SuppressWindowEnv, value)
+		if window := getSuppressWindow(); window != expected {
+			t.Errorf("%q: expected %v, got %v", value, expected, window)
+		}
+	}
+}
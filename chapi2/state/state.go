@@ -0,0 +1,475 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package state
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// STATE STORE
+//
+//		CHAPI otherwise infers everything by re-enumerating the host, which cannot tell a device or
+//		mount point CHAPI created from one created by the administrator.  The state store records
+//		the devices and mount points created through CHAPI so that cleanup and reconciliation can
+//		tell which objects CHAPI owns, and which have since disappeared from the host.  It also
+//		records the iSCSI initiator name with the host UUID so that a cloned host sharing the
+//		initiator name can be detected, the orchestrators' device claims so that they survive a
+//		CHAPI restart, and (Windows only) the volumes mounted to their volume GUID path, which
+//		Windows otherwise exposes for every volume, and the discovery IP each iSCSI target was
+//		discovered through.
+//
+//		The store is a single JSON file (see statePath) that is rewritten atomically; the new
+//		contents are written and synced to a temporary file which then replaces the store.  The
+//		file carries a schema version.  Older schemas are migrated in memory when loaded (and
+//		written back in the current schema on the next update).  A store written by a newer CHAPI
+//		is never overwritten; updates fail until CHAPI is upgraded again.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/cerrors"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// SchemaVersion is the state store schema written by this version of CHAPI
+	SchemaVersion = 1
+
+	// Shared error messages
+	errorMessageNewerSchema   = "state store %v has schema version %v, newer than the supported version %v"
+	errorMessageCorruptStore  = "state store %v is corrupt, moved to %v, err=%v"
+	errorMessageMissingObject = "missing %v"
+)
+
+var (
+	// stateLock serializes access to the state store file
+	stateLock sync.Mutex
+
+	// migrations upgrade a state file from the indexed schema version to the next version
+	migrations = map[int]func(*stateFile){
+		// Version 0 (unversioned) files have the same layout as version 1
+		0: func(file *stateFile) {},
+	}
+)
+
+// stateFile is the state store file format
+type stateFile struct {
+	SchemaVersion int                             `json:"schema_version"`
+	Devices       map[string]*model.ManagedDevice `json:"devices,omitempty"` // Keyed by serial number
+	Mounts        map[string]*model.ManagedMount  `json:"mounts,omitempty"`  // Keyed by mount point ID
+	Initiator     *model.ManagedInitiator         `json:"initiator,omitempty"`
+	Claims        map[string]*model.DeviceClaim   `json:"claims,omitempty"`            // Keyed by serial number
+	VolumePaths   map[string]string               `json:"volume_paths,omitempty"`      // Serial numbers keyed by volume GUID path
+	Discoveries   map[string]string               `json:"discovery_portals,omitempty"` // Discovery IPs keyed by target iqn
+}
+
+// GetState returns the devices and mount points recorded in the state store
+func GetState() (*model.ManagedState, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	file, err := loadStateFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// Report copies of the records, in a stable order
+	state := &model.ManagedState{SchemaVersion: SchemaVersion}
+	for _, device := range file.Devices {
+		record := *device
+		state.Devices = append(state.Devices, &record)
+	}
+	for _, mount := range file.Mounts {
+		record := *mount
+		state.Mounts = append(state.Mounts, &record)
+	}
+	sort.Slice(state.Devices, func(i, j int) bool { return state.Devices[i].SerialNumber < state.Devices[j].SerialNumber })
+	sort.Slice(state.Mounts, func(i, j int) bool { return state.Mounts[i].ID < state.Mounts[j].ID })
+	return state, nil
+}
+
+// GetDevice returns the record of the given device, or nil if the device is not recorded
+func GetDevice(serialNumber string) (*model.ManagedDevice, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	file, err := loadStateFile()
+	if err != nil {
+		return nil, err
+	}
+	device, ok := file.Devices[serialNumber]
+	if !ok {
+		return nil, nil
+	}
+	record := *device
+	return &record, nil
+}
+
+// RecordDevice records a device attached through CHAPI, replacing any previous record for the
+// device's serial number.  A file system UUID already recorded for the device is kept unless a
+// new one is provided.
+func RecordDevice(device *model.ManagedDevice) error {
+	if device == nil || device.SerialNumber == "" {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMissingObject, "serial number")
+	}
+	return updateStateFile(func(file *stateFile) bool {
+		record := *device
+		record.Status = ""
+		if record.Created == "" {
+			record.Created = time.Now().UTC().Format(time.RFC3339)
+		}
+		if previous, ok := file.Devices[record.SerialNumber]; ok && record.FsUUID == "" {
+			record.FsUUID = previous.FsUUID
+		}
+		file.Devices[record.SerialNumber] = &record
+		return true
+	})
+}
+
+// RecordFileSystem records the UUID of the file system created on the given device.  Only devices
+// attached through CHAPI are recorded; the call is ignored for any other device.
+func RecordFileSystem(serialNumber string, fsUUID string) error {
+	return updateStateFile(func(file *stateFile) bool {
+		device, ok := file.Devices[serialNumber]
+		if !ok || device.FsUUID == fsUUID {
+			return false
+		}
+		device.FsUUID = fsUUID
+		return true
+	})
+}
+
+// RemoveDevice removes the record of the given device, if any
+func RemoveDevice(serialNumber string) error {
+	return updateStateFile(func(file *stateFile) bool {
+		if _, ok := file.Devices[serialNumber]; !ok {
+			return false
+		}
+		delete(file.Devices, serialNumber)
+		return true
+	})
+}
+
+// GetMount returns the record of the given mount point ID, or nil if the mount point is not
+// recorded
+func GetMount(mountPointID string) (*model.ManagedMount, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	file, err := loadStateFile()
+	if err != nil {
+		return nil, err
+	}
+	mount, ok := file.Mounts[mountPointID]
+	if !ok {
+		return nil, nil
+	}
+	record := *mount
+	return &record, nil
+}
+
+// RecordMount records a mount point created through CHAPI, replacing any previous record for the
+// mount point ID
+func RecordMount(mount *model.ManagedMount) error {
+	if mount == nil || mount.ID == "" {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMissingObject, "mount point ID")
+	}
+	return updateStateFile(func(file *stateFile) bool {
+		record := *mount
+		record.Status = ""
+		if record.Created == "" {
+			record.Created = time.Now().UTC().Format(time.RFC3339)
+		}
+		file.Mounts[record.ID] = &record
+		return true
+	})
+}
+
+// RemoveMount removes the record of the given mount point ID, if any
+func RemoveMount(mountPointID string) error {
+	return updateStateFile(func(file *stateFile) bool {
+		if _, ok := file.Mounts[mountPointID]; !ok {
+			return false
+		}
+		delete(file.Mounts, mountPointID)
+		return true
+	})
+}
+
+// RemoveMountPoint removes the records of the mount points at the given location, if any, and
+// returns the number of records removed
+func RemoveMountPoint(mountPoint string, isSamePath func(path1, path2 string) bool) (int, error) {
+	removed := 0
+	err := updateStateFile(func(file *stateFile) bool {
+		for id, mount := range file.Mounts {
+			if isSamePath(mount.MountPoint, mountPoint) {
+				delete(file.Mounts, id)
+				removed++
+			}
+		}
+		return removed != 0
+	})
+	return removed, err
+}
+
+// GetInitiator returns the recorded iSCSI initiator name, or nil if none is recorded
+func GetInitiator() (*model.ManagedInitiator, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	file, err := loadStateFile()
+	if err != nil || file.Initiator == nil {
+		return nil, err
+	}
+	record := *file.Initiator
+	return &record, nil
+}
+
+// RecordInitiator records the iSCSI initiator name along with the UUID of this host, replacing any
+// previous record
+func RecordInitiator(initiator *model.ManagedInitiator) error {
+	if initiator == nil || initiator.Iqn == "" || initiator.HostUUID == "" {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMissingObject, "initiator name or host UUID")
+	}
+	return updateStateFile(func(file *stateFile) bool {
+		record := *initiator
+		if record.Recorded == "" {
+			record.Recorded = time.Now().UTC().Format(time.RFC3339)
+		}
+		file.Initiator = &record
+		return true
+	})
+}
+
+// GetClaim returns the recorded claim on the given device, or nil if the device is not claimed.
+// Expired claims are returned as is; the caller checks the expiry.
+func GetClaim(serialNumber string) (*model.DeviceClaim, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	file, err := loadStateFile()
+	if err != nil {
+		return nil, err
+	}
+	claim, ok := file.Claims[serialNumber]
+	if !ok {
+		return nil, nil
+	}
+	record := *claim
+	return &record, nil
+}
+
+// RecordClaim records a claim on a device, replacing any previous claim on the device
+func RecordClaim(claim *model.DeviceClaim) error {
+	if claim == nil || claim.SerialNumber == "" || claim.Owner == "" {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMissingObject, "serial number or owner")
+	}
+	return updateStateFile(func(file *stateFile) bool {
+		record := *claim
+		file.Claims[record.SerialNumber] = &record
+		return true
+	})
+}
+
+// RemoveClaim removes the claim on the given device, if any
+func RemoveClaim(serialNumber string) error {
+	return updateStateFile(func(file *stateFile) bool {
+		if _, ok := file.Claims[serialNumber]; !ok {
+			return false
+		}
+		delete(file.Claims, serialNumber)
+		return true
+	})
+}
+
+// GetVolumePathMounts returns the serial numbers of the volumes mounted to their volume GUID path,
+// keyed by volume GUID path
+func GetVolumePathMounts() (map[string]string, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	file, err := loadStateFile()
+	if err != nil {
+		return nil, err
+	}
+	volumePaths := make(map[string]string)
+	for volumePath, serialNumber := range file.VolumePaths {
+		volumePaths[volumePath] = serialNumber
+	}
+	return volumePaths, nil
+}
+
+// RecordVolumePathMount records the given volume as mounted to the given volume GUID path,
+// replacing any previous record for the volume GUID path
+func RecordVolumePathMount(volumePath string, serialNumber string) error {
+	if volumePath == "" {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMissingObject, "volume GUID path")
+	}
+	return updateStateFile(func(file *stateFile) bool {
+		if file.VolumePaths[volumePath] == serialNumber {
+			return false
+		}
+		file.VolumePaths[volumePath] = serialNumber
+		return true
+	})
+}
+
+// RemoveVolumePathMount removes the record of the given volume GUID path mount, if any
+func RemoveVolumePathMount(volumePath string) error {
+	return updateStateFile(func(file *stateFile) bool {
+		if _, ok := file.VolumePaths[volumePath]; !ok {
+			return false
+		}
+		delete(file.VolumePaths, volumePath)
+		return true
+	})
+}
+
+// GetDiscoveryPortal returns the discovery IP through which the given target was discovered, or
+// an empty string if not recorded
+func GetDiscoveryPortal(targetName string) (string, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	file, err := loadStateFile()
+	if err != nil {
+		return "", err
+	}
+	return file.Discoveries[targetName], nil
+}
+
+// RecordDiscoveryPortal records the discovery IP through which the given target was discovered.
+// An empty discovery IP removes the record.
+func RecordDiscoveryPortal(targetName string, discoveryIP string) error {
+	if targetName == "" {
+		return cerrors.NewChapiErrorf(cerrors.InvalidArgument, errorMessageMissingObject, "target name")
+	}
+	return updateStateFile(func(file *stateFile) bool {
+		if file.Discoveries[targetName] == discoveryIP {
+			return false
+		}
+		if discoveryIP == "" {
+			delete(file.Discoveries, targetName)
+		} else {
+			file.Discoveries[targetName] = discoveryIP
+		}
+		return true
+	})
+}
+
+// updateStateFile loads the state store, applies the update and, if the update reports a change,
+// saves the state store
+func updateStateFile(update func(file *stateFile) bool) error {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	file, err := loadStateFile()
+	if err != nil {
+		return err
+	}
+	if !update(file) {
+		return nil
+	}
+	return saveStateFile(file)
+}
+
+// loadStateFile reads the state store, migrating it to the current schema version.  An empty state
+// is returned if the store does not exist yet.
+func loadStateFile() (*stateFile, error) {
+	file := &stateFile{SchemaVersion: SchemaVersion}
+	data, err := ioutil.ReadFile(statePath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Errorf("Unable to read state store %v, err=%v", statePath, err)
+		return nil, cerrors.NewChapiError(err)
+	}
+	if err == nil {
+		file.SchemaVersion = 0
+		if err = json.Unmarshal(data, file); err != nil {
+			// Set the corrupt store aside, for support, rather than fail every update
+			corruptPath := fmt.Sprintf("%v.corrupt.%v", statePath, time.Now().Unix())
+			if renameErr := os.Rename(statePath, corruptPath); renameErr != nil {
+				log.Errorf("Unable to move corrupt state store %v, err=%v", statePath, renameErr)
+				return nil, cerrors.NewChapiError(err)
+			}
+			log.Errorf(errorMessageCorruptStore, statePath, corruptPath, err)
+			file = &stateFile{SchemaVersion: SchemaVersion}
+		}
+	}
+
+	if file.SchemaVersion > SchemaVersion {
+		err = cerrors.NewChapiErrorf(cerrors.Aborted, errorMessageNewerSchema, statePath, file.SchemaVersion, SchemaVersion)
+		log.Error(err)
+		return nil, err
+	}
+	for file.SchemaVersion < SchemaVersion {
+		log.Infof("Migrating state store %v from schema version %v", statePath, file.SchemaVersion)
+		migrations[file.SchemaVersion](file)
+		file.SchemaVersion++
+	}
+
+	if file.Devices == nil {
+		file.Devices = make(map[string]*model.ManagedDevice)
+	}
+	if file.Mounts == nil {
+		file.Mounts = make(map[string]*model.ManagedMount)
+	}
+	if file.Claims == nil {
+		file.Claims = make(map[string]*model.DeviceClaim)
+	}
+	if file.VolumePaths == nil {
+		file.VolumePaths = make(map[string]string)
+	}
+	if file.Discoveries == nil {
+		file.Discoveries = make(map[string]string)
+	}
+	return file, nil
+}
+
+// saveStateFile atomically replaces the state store with the given state
+func saveStateFile(file *stateFile) error {
+	data, err := json.MarshalIndent(file, "", "    ")
+	if err != nil {
+		return cerrors.NewChapiError(err)
+	}
+	if err = WriteFileSync(statePath, data); err != nil {
+		log.Errorf("Unable to save state store %v, err=%v", statePath, err)
+		return cerrors.NewChapiError(err)
+	}
+	return nil
+}
+
+// WriteFileSync writes the data to a temporary file, syncs it to disk and then renames it over the
+// given path so that the file is never left partially written
+func WriteFileSync(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+
+	if _, err = tempFile.Write(data); err == nil {
+		err = tempFile.Sync()
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tempPath, path); err != nil {
+		return err
+	}
+	return syncDirectory(filepath.Dir(path))
+}
@@ -0,0 +1,21 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package state
+
+import (
+	"os"
+)
+
+var (
+	statePath = "/var/lib/hpe-storage/chapi/state.json"
+)
+
+// syncDirectory syncs the given directory so that a file renamed into it survives a crash
+func syncDirectory(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,225 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+// useTempStatePath redirects the state store to a temporary directory for the test
+func useTempStatePath(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "chapistate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedPath := statePath
+	statePath = filepath.Join(dir, "state.json")
+	return func() {
+		statePath = savedPath
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStateRecordRemove(t *testing.T) {
+	defer useTempStatePath(t)()
+
+	if err := RecordDevice(&model.ManagedDevice{SerialNumber: "serial1", AccessProtocol: "iscsi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordMount(&model.ManagedMount{ID: "mount1", MountPoint: "/mnt/vol1", SerialNumber: "serial1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordMount(&model.ManagedMount{ID: "mount2", MountPoint: "/mnt/vol2", SerialNumber: "serial1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordDevice(&model.ManagedDevice{}); err == nil {
+		t.Error("expected error recording device without serial number")
+	}
+
+	managedState, err := GetState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if managedState.SchemaVersion != SchemaVersion || len(managedState.Devices) != 1 || len(managedState.Mounts) != 2 {
+		t.Fatalf("unexpected state %+v", managedState)
+	}
+	if managedState.Devices[0].Created == "" || managedState.Mounts[0].ID != "mount1" {
+		t.Errorf("unexpected records %+v %+v", managedState.Devices[0], managedState.Mounts[0])
+	}
+
+	isSamePath := func(path1, path2 string) bool { return path1 == path2 }
+	if removed, err := RemoveMountPoint("/mnt/vol2", isSamePath); err != nil || removed != 1 {
+		t.Errorf("unexpected RemoveMountPoint removed=%v, err=%v", removed, err)
+	}
+	if err = RemoveMount("mount1"); err != nil {
+		t.Error(err)
+	}
+	if err = RemoveDevice("serial1"); err != nil {
+		t.Error(err)
+	}
+	if managedState, err = GetState(); err != nil || len(managedState.Devices) != 0 || len(managedState.Mounts) != 0 {
+		t.Errorf("unexpected state %+v, err=%v", managedState, err)
+	}
+}
+
+func TestStateSchemaVersion(t *testing.T) {
+	defer useTempStatePath(t)()
+
+	// An unversioned store is migrated
+	unversioned := `{"devices":{"serial1":{"serial_number":"serial1"}}}`
+	if err := ioutil.WriteFile(statePath, []byte(unversioned), 0600); err != nil {
+		t.Fatal(err)
+	}
+	managedState, err := GetState()
+	if err != nil || len(managedState.Devices) != 1 {
+		t.Fatalf("unexpected migrated state %+v, err=%v", managedState, err)
+	}
+
+	// A store from a newer CHAPI is neither read nor overwritten
+	newer := `{"schema_version":99}`
+	if err = ioutil.WriteFile(statePath, []byte(newer), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GetState(); err == nil {
+		t.Error("expected error reading newer schema")
+	}
+	if err = RecordDevice(&model.ManagedDevice{SerialNumber: "serial2"}); err == nil {
+		t.Error("expected error updating newer schema")
+	}
+	if data, _ := ioutil.ReadFile(statePath); string(data) != newer {
+		t.Errorf("newer state store was overwritten, %v", string(data))
+	}
+}
+
+func TestStateCorrupt(t *testing.T) {
+	defer useTempStatePath(t)()
+
+	if err := ioutil.WriteFile(statePath, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	managedState, err := GetState()
+	if err != nil || len(managedState.Devices) != 0 {
+		t.Fatalf("unexpected state %+v, err=%v", managedState, err)
+	}
+	corrupt, _ := filepath.Glob(statePath + ".corrupt.*")
+	if len(corrupt) != 1 {
+		t.Errorf("expected corrupt state store to be moved aside, found %v", corrupt)
+	}
+}
+
+func TestStateRecordFileSystem(t *testing.T) {
+	defer useTempStatePath(t)()
+
+	// File systems on devices that were not attached through CHAPI are not recorded
+	if err := RecordFileSystem("serial1", "uuid1"); err != nil {
+		t.Fatal(err)
+	}
+	if device, err := GetDevice("serial1"); err != nil || device != nil {
+		t.Fatalf("unexpected device %+v, err=%v", device, err)
+	}
+
+	if err := RecordDevice(&model.ManagedDevice{SerialNumber: "serial1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordFileSystem("serial1", "uuid1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Attaching the device again keeps the recorded file system UUID
+	if err := RecordDevice(&model.ManagedDevice{SerialNumber: "serial1", AccessProtocol: "fc"}); err != nil {
+		t.Fatal(err)
+	}
+	if device, err := GetDevice("serial1"); err != nil || device == nil || device.FsUUID != "uuid1" || device.AccessProtocol != "fc" {
+		t.Errorf("unexpected device %+v, err=%v", device, err)
+	}
+}
+
+func TestStateRecordInitiator(t *testing.T) {
+	defer useTempStatePath(t)()
+
+	if initiator, err := GetInitiator(); err != nil || initiator != nil {
+		t.Fatalf("unexpected initiator %+v, err=%v", initiator, err)
+	}
+	if err := RecordInitiator(&model.ManagedInitiator{Iqn: "iqn.1994-05.com.redhat:host1"}); err == nil {
+		t.Error("expected error recording initiator without host UUID")
+	}
+	if err := RecordInitiator(&model.ManagedInitiator{Iqn: "iqn.1994-05.com.redhat:host1", HostUUID: "uuid1"}); err != nil {
+		t.Fatal(err)
+	}
+	initiator, err := GetInitiator()
+	if err != nil || initiator == nil || initiator.Iqn != "iqn.1994-05.com.redhat:host1" || initiator.HostUUID != "uuid1" || initiator.Recorded == "" {
+		t.Errorf("unexpected initiator %+v, err=%v", initiator, err)
+	}
+}
+
+func TestStateRecordClaim(t *testing.T) {
+	defer useTempStatePath(t)()
+
+	if claim, err := GetClaim("serial1"); err != nil || claim != nil {
+		t.Fatalf("unexpected claim %+v, err=%v", claim, err)
+	}
+	if err := RecordClaim(&model.DeviceClaim{SerialNumber: "serial1"}); err == nil {
+		t.Error("expected error recording claim without owner")
+	}
+	if err := RecordClaim(&model.DeviceClaim{SerialNumber: "serial1", Owner: "csi-node", Expires: "2020-06-01T10:25:30Z"}); err != nil {
+		t.Fatal(err)
+	}
+	claim, err := GetClaim("serial1")
+	if err != nil || claim == nil || claim.Owner != "csi-node" || claim.Expires != "2020-06-01T10:25:30Z" {
+		t.Errorf("unexpected claim %+v, err=%v", claim, err)
+	}
+	if err = RemoveClaim("serial1"); err != nil {
+		t.Fatal(err)
+	}
+	if claim, err = GetClaim("serial1"); err != nil || claim != nil {
+		t.Errorf("unexpected claim %+v after removal, err=%v", claim, err)
+	}
+}
+
+func TestStateRecordVolumePathMount(t *testing.T) {
+	defer useTempStatePath(t)()
+
+	const volumePath = `\\?\Volume{4c1b2f5e-1d2a-11e9-a4c6-806e6f6e6963}\`
+	if err := RecordVolumePathMount("", "serial1"); err == nil {
+		t.Error("expected error recording volume GUID path mount without volume GUID path")
+	}
+	if err := RecordVolumePathMount(volumePath, "serial1"); err != nil {
+		t.Fatal(err)
+	}
+	volumePaths, err := GetVolumePathMounts()
+	if err != nil || len(volumePaths) != 1 || volumePaths[volumePath] != "serial1" {
+		t.Errorf("unexpected volume GUID path mounts %v, err=%v", volumePaths, err)
+	}
+	if err = RemoveVolumePathMount(volumePath); err != nil {
+		t.Fatal(err)
+	}
+	if volumePaths, err = GetVolumePathMounts(); err != nil || len(volumePaths) != 0 {
+		t.Errorf("unexpected volume GUID path mounts %v after removal, err=%v", volumePaths, err)
+	}
+}
+
+func TestStateRecordDiscoveryPortal(t *testing.T) {
+	defer useTempStatePath(t)()
+
+	const targetName = "iqn.2007-11.com.nimblestorage:connected"
+	if err := RecordDiscoveryPortal("", "10.1.2.10"); err == nil {
+		t.Error("expected error recording discovery portal without target name")
+	}
+	if err := RecordDiscoveryPortal(targetName, "10.1.2.10"); err != nil {
+		t.Fatal(err)
+	}
+	if discoveryIP, err := GetDiscoveryPortal(targetName); err != nil || discoveryIP != "10.1.2.10" {
+		t.Errorf("expected 10.1.2.10, got %v, err=%v", discoveryIP, err)
+	}
+	if err := RecordDiscoveryPortal(targetName, ""); err != nil {
+		t.Fatal(err)
+	}
+	if discoveryIP, err := GetDiscoveryPortal(targetName); err != nil || discoveryIP != "" {
+		t.Errorf("expected no discovery IP, got %v, err=%v", discoveryIP, err)
+	}
+}
@@ -0,0 +1,30 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package state
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	stateFileName = `hpe-storage\chapi\state.json` // Path appended to %ProgramData%
+)
+
+var (
+	statePath = filepath.Join(getProgramDataPath(), stateFileName)
+)
+
+// getProgramDataPath returns the system's %ProgramData% folder
+func getProgramDataPath() string {
+	if programDataPath := os.Getenv("ProgramData"); programDataPath != "" {
+		return programDataPath
+	}
+	return `C:\ProgramData`
+}
+
+// syncDirectory is a no-op under Windows; NTFS journals the rename and directories cannot be
+// opened for syncing
+func syncDirectory(path string) error {
+	return nil
+}
@@ -0,0 +1,133 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package timing
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/config"
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+const (
+	// SlowRequestThresholdEnv overrides the slow request threshold, in milliseconds
+	SlowRequestThresholdEnv = config.SlowRequestThresholdEnv
+
+	// DefaultSlowRequestThreshold is the slow request threshold unless overridden
+	DefaultSlowRequestThreshold = 30 * time.Second
+
+	// overflowBucket is the upper bound reported for the overflow bucket
+	overflowBucket = -1
+)
+
+var (
+	// latencyBucketsMs are the latency histogram bucket upper bounds, in milliseconds
+	latencyBucketsMs = []int64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 120000}
+
+	latencyLock          sync.Mutex
+	latencies            = make(map[string]*endpointLatency)
+	slowRequestThreshold time.Duration
+	slowRequestEnvOnce   sync.Once
+)
+
+// endpointLatency is the latency histogram of a single endpoint
+type endpointLatency struct {
+	count     uint64
+	slowCount uint64
+	total     time.Duration
+	max       time.Duration
+	buckets   []uint64 // One per latencyBucketsMs entry, plus the overflow bucket
+}
+
+// SetSlowRequestThreshold sets the duration above which requests are logged and counted as slow.
+// A zero duration restores DefaultSlowRequestThreshold.
+func SetSlowRequestThreshold(threshold time.Duration) {
+	// The explicit threshold takes precedence over SlowRequestThresholdEnv
+	slowRequestEnvOnce.Do(func() {})
+	if threshold <= 0 {
+		threshold = DefaultSlowRequestThreshold
+	}
+	latencyLock.Lock()
+	defer latencyLock.Unlock()
+	slowRequestThreshold = threshold
+}
+
+// SlowRequestThreshold returns the duration above which requests are logged and counted as slow
+func SlowRequestThreshold() time.Duration {
+	slowRequestEnvOnce.Do(loadSlowRequestThresholdFromEnv)
+	latencyLock.Lock()
+	defer latencyLock.Unlock()
+	return slowRequestThreshold
+}
+
+// loadSlowRequestThresholdFromEnv sets the slow request threshold from SlowRequestThresholdEnv
+func loadSlowRequestThresholdFromEnv() {
+	threshold := config.Milliseconds(SlowRequestThresholdEnv, DefaultSlowRequestThreshold, 1)
+	latencyLock.Lock()
+	defer latencyLock.Unlock()
+	slowRequestThreshold = threshold
+}
+
+// ObserveRequest adds the request duration to the endpoint's latency histogram and returns true if
+// the request exceeded the slow request threshold
+func ObserveRequest(endpoint string, duration time.Duration) bool {
+	slow := duration > SlowRequestThreshold()
+
+	latencyLock.Lock()
+	defer latencyLock.Unlock()
+
+	latency := latencies[endpoint]
+	if latency == nil {
+		latency = &endpointLatency{buckets: make([]uint64, len(latencyBucketsMs)+1)}
+		latencies[endpoint] = latency
+	}
+	latency.count++
+	latency.total += duration
+	if duration > latency.max {
+		latency.max = duration
+	}
+	if slow {
+		latency.slowCount++
+	}
+	ms := int64(duration / time.Millisecond)
+	bucket := sort.Search(len(latencyBucketsMs), func(i int) bool { return ms <= latencyBucketsMs[i] })
+	latency.buckets[bucket]++
+	return slow
+}
+
+// GetLatencies returns the latency histogram of each endpoint that has handled a request
+func GetLatencies() *model.LatencyReport {
+	report := &model.LatencyReport{SlowThresholdMs: int64(SlowRequestThreshold() / time.Millisecond)}
+
+	latencyLock.Lock()
+	defer latencyLock.Unlock()
+
+	for endpoint, latency := range latencies {
+		endpointLatency := &model.EndpointLatency{
+			Endpoint:  endpoint,
+			Count:     latency.count,
+			SlowCount: latency.slowCount,
+			TotalMs:   int64(latency.total / time.Millisecond),
+			MaxMs:     int64(latency.max / time.Millisecond),
+		}
+		for i, count := range latency.buckets {
+			leMs := int64(overflowBucket)
+			if i < len(latencyBucketsMs) {
+				leMs = latencyBucketsMs[i]
+			}
+			endpointLatency.Buckets = append(endpointLatency.Buckets, &model.LatencyBucket{LeMs: leMs, Count: count})
+		}
+		report.Endpoints = append(report.Endpoints, endpointLatency)
+	}
+	sort.Slice(report.Endpoints, func(i, j int) bool { return report.Endpoints[i].Endpoint < report.Endpoints[j].Endpoint })
+	return report
+}
+
+// ResetLatencies clears the latency histograms
+func ResetLatencies() {
+	latencyLock.Lock()
+	defer latencyLock.Unlock()
+	latencies = make(map[string]*endpointLatency)
+}
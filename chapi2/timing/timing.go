@@ -0,0 +1,220 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package timing
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// REQUEST TIMING
+//
+//		Attaching a volume spends its time in a few distinct stages (iSCSI discovery and login,
+//		rescan, multipath device assembly, device wait, mkfs and mount).  To tell operators which
+//		stage makes a request slow, the plugins time each stage and the durations are added to
+//		the timings of the in-flight requests operating on the same volume.  Requests are
+//		correlated by volume serial number, rather than by request context, as the request
+//		context is not passed down to the plugins.  Concurrent requests for the same volume
+//		therefore share each other's stage timings.
+//
+//		Plugins that don't know the volume (e.g. the iSCSI plugin, which only knows the target)
+//		time their stages under another key that the caller aliases to the volume (see
+//		AliasVolume).  The stage timings are logged for slow requests and returned in the
+//		CreateDevice and CreateMount responses (see Timings.Report).
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/chapi2/model"
+)
+
+// Timed stages
+const (
+	StageDiscovery         = "discovery"
+	StageLogin             = "login"
+	StageRescan            = "rescan"
+	StageMultipathAssemble = "multipath_assemble"
+	StageDeviceWait        = "device_wait"
+	StageMkfs              = "mkfs"
+	StageMount             = "mount"
+)
+
+// Timings are the stage durations of a single request
+type Timings struct {
+	lock   sync.Mutex
+	start  time.Time                // Time the request started
+	stages []string                 // Stage names, in the order first timed
+	times  map[string]time.Duration // Total duration of each stage
+}
+
+// timingsContextKey stores the request's Timings in the request context
+type timingsContextKey struct{}
+
+var (
+	// volumeTimings are the timings of the in-flight requests, keyed by volume serial number
+	volumeTimingsLock sync.Mutex
+	volumeTimings     = make(map[string][]*Timings)
+
+	// volumeAliases are the volume serial numbers each alias (e.g. iSCSI target name) times the
+	// stages of, protected by volumeTimingsLock
+	volumeAliases = make(map[string][]string)
+)
+
+// NewTimings returns an empty set of stage timings
+func NewTimings() *Timings {
+	return &Timings{start: time.Now(), times: make(map[string]time.Duration)}
+}
+
+// NewContext returns a copy of the context carrying the given timings
+func NewContext(ctx context.Context, timings *Timings) context.Context {
+	return context.WithValue(ctx, timingsContextKey{}, timings)
+}
+
+// FromContext returns the timings carried by the context, if any
+func FromContext(ctx context.Context) *Timings {
+	timings, _ := ctx.Value(timingsContextKey{}).(*Timings)
+	return timings
+}
+
+// Add adds the duration to the given stage.  A stage timed more than once (e.g. a rescan retried)
+// reports its total duration.
+func (timings *Timings) Add(stage string, duration time.Duration) {
+	timings.lock.Lock()
+	defer timings.lock.Unlock()
+	if _, ok := timings.times[stage]; !ok {
+		timings.stages = append(timings.stages, stage)
+	}
+	timings.times[stage] += duration
+}
+
+// String returns the stage durations in the order the stages were timed (e.g. "login=1.2s,
+// rescan=3.4s"), or an empty string if no stage was timed
+func (timings *Timings) String() string {
+	timings.lock.Lock()
+	defer timings.lock.Unlock()
+	var stages []string
+	for _, stage := range timings.stages {
+		stages = append(stages, fmt.Sprintf("%v=%v", stage, timings.times[stage]))
+	}
+	return strings.Join(stages, ", ")
+}
+
+// Report returns the stage durations, along with the time elapsed since the timings were created,
+// as a model.Timings object
+func (timings *Timings) Report() *model.Timings {
+	timings.lock.Lock()
+	defer timings.lock.Unlock()
+	ms := func(stage string) int64 { return int64(timings.times[stage] / time.Millisecond) }
+	return &model.Timings{
+		DiscoveryMs:         ms(StageDiscovery),
+		LoginMs:             ms(StageLogin),
+		RescanMs:            ms(StageRescan),
+		MultipathAssembleMs: ms(StageMultipathAssemble),
+		DeviceWaitMs:        ms(StageDeviceWait),
+		MkfsMs:              ms(StageMkfs),
+		MountMs:             ms(StageMount),
+		TotalMs:             int64(time.Since(timings.start) / time.Millisecond),
+	}
+}
+
+// TrackVolume adds the stages timed for the given volume to the timings carried by the context,
+// until the returned function is called.  Nothing is tracked if the context has no timings.
+func TrackVolume(ctx context.Context, serialNumber string) func() {
+	timings := FromContext(ctx)
+	if timings == nil || serialNumber == "" {
+		return func() {}
+	}
+
+	volumeTimingsLock.Lock()
+	volumeTimings[serialNumber] = append(volumeTimings[serialNumber], timings)
+	volumeTimingsLock.Unlock()
+
+	return func() {
+		volumeTimingsLock.Lock()
+		defer volumeTimingsLock.Unlock()
+		tracked := volumeTimings[serialNumber]
+		for i := range tracked {
+			if tracked[i] == timings {
+				tracked = append(tracked[:i], tracked[i+1:]...)
+				break
+			}
+		}
+		if len(tracked) == 0 {
+			delete(volumeTimings, serialNumber)
+		} else {
+			volumeTimings[serialNumber] = tracked
+		}
+	}
+}
+
+// StartStage starts timing a stage of the given volume.  The returned function ends the stage and
+// adds its duration to the requests tracking the volume, e.g.:
+//
+//	defer timing.StartStage(serialNumber, timing.StageMount)()
+func StartStage(serialNumber string, stage string) func() {
+	start := time.Now()
+	return func() {
+		duration := time.Since(start)
+		volumeTimingsLock.Lock()
+		defer volumeTimingsLock.Unlock()
+		for _, timings := range getTrackedTimings(serialNumber) {
+			timings.Add(stage, duration)
+		}
+	}
+}
+
+// AliasVolume adds the stages timed under the given alias (e.g. the iSCSI target name) to the
+// requests tracking the given volume, until the returned function is called
+func AliasVolume(alias string, serialNumber string) func() {
+	if alias == "" || alias == serialNumber {
+		return func() {}
+	}
+
+	volumeTimingsLock.Lock()
+	volumeAliases[alias] = append(volumeAliases[alias], serialNumber)
+	volumeTimingsLock.Unlock()
+
+	return func() {
+		volumeTimingsLock.Lock()
+		defer volumeTimingsLock.Unlock()
+		aliased := volumeAliases[alias]
+		for i := range aliased {
+			if aliased[i] == serialNumber {
+				aliased = append(aliased[:i], aliased[i+1:]...)
+				break
+			}
+		}
+		if len(aliased) == 0 {
+			delete(volumeAliases, alias)
+		} else {
+			volumeAliases[alias] = aliased
+		}
+	}
+}
+
+// getTrackedTimings returns the timings of the requests tracking the given volume, or the volumes
+// aliased by the given key, each only once.  The caller must hold volumeTimingsLock.
+func getTrackedTimings(key string) []*Timings {
+	tracked := volumeTimings[key]
+	for _, serialNumber := range volumeAliases[key] {
+		for _, timings := range volumeTimings[serialNumber] {
+			if !containsTimings(tracked, timings) {
+				tracked = append(tracked[:len(tracked):len(tracked)], timings)
+			}
+		}
+	}
+	return tracked
+}
+
+// containsTimings returns true if the given timings are in the list
+func containsTimings(list []*Timings, timings *Timings) bool {
+	for _, entry := range list {
+		if entry == timings {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package timing
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackVolume(t *testing.T) {
+	timings := NewTimings()
+	untrack := TrackVolume(NewContext(context.Background(), timings), "serial1")
+
+	StartStage("serial1", StageLogin)()
+	StartStage("serial2", StageMount)() // Not tracked
+	StartStage("serial1", StageRescan)()
+	StartStage("serial1", StageLogin)()
+	untrack()
+	StartStage("serial1", StageMkfs)() // No longer tracked
+
+	stages := timings.String()
+	if !strings.HasPrefix(stages, "login=") || !strings.Contains(stages, ", rescan=") || strings.Count(stages, "=") != 2 {
+		t.Errorf("unexpected stages %q", stages)
+	}
+	if len(volumeTimings) != 0 {
+		t.Errorf("volume timings not removed, %v", volumeTimings)
+	}
+
+	// A request without timings is not tracked
+	TrackVolume(context.Background(), "serial1")()
+	if len(volumeTimings) != 0 {
+		t.Errorf("untimed request tracked, %v", volumeTimings)
+	}
+}
+
+func TestAliasVolume(t *testing.T) {
+	const target = "iqn.target1"
+	timings1, timings2 := NewTimings(), NewTimings()
+	untrack1 := TrackVolume(NewContext(context.Background(), timings1), "serial1")
+	untrack2 := TrackVolume(NewContext(context.Background(), timings2), "serial2")
+	unalias1 := AliasVolume(target, "serial1")
+	unalias2 := AliasVolume(target, "serial2")
+	AliasVolume("", "serial1")()
+
+	timings1.Add(StageDiscovery, time.Second) // Already timed stage
+	StartStage(target, StageDiscovery)()
+	unalias2()
+	StartStage(target, StageLogin)()
+	unalias1()
+	StartStage(target, StageMount)() // No longer aliased
+	untrack1()
+	untrack2()
+
+	if stages := timings1.String(); !strings.HasPrefix(stages, "discovery=1") || !strings.Contains(stages, ", login=") || strings.Count(stages, "=") != 2 {
+		t.Errorf("unexpected aliased stages %q", stages)
+	}
+	if stages := timings2.String(); !strings.HasPrefix(stages, "discovery=") || strings.Count(stages, "=") != 1 {
+		t.Errorf("unexpected aliased stages %q", stages)
+	}
+	if len(volumeAliases) != 0 || len(volumeTimings) != 0 {
+		t.Errorf("volume aliases not removed, %v", volumeAliases)
+	}
+
+	report := timings1.Report()
+	if report.DiscoveryMs < 1000 || report.MountMs != 0 || report.TotalMs < 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestObserveRequest(t *testing.T) {
+	defer ResetLatencies()
+	defer SetSlowRequestThreshold(0)
+	SetSlowRequestThreshold(time.Second)
+
+	if ObserveRequest("CreateDevice", 5*time.Millisecond) {
+		t.Error("fast request reported as slow")
+	}
+	if !ObserveRequest("CreateDevice", 2*time.Second) {
+		t.Error("slow request not reported as slow")
+	}
+	ObserveRequest("CreateDevice", time.Hour)
+	ObserveRequest("CreateMount", 100*time.Millisecond)
+
+	report := GetLatencies()
+	if report.SlowThresholdMs != 1000 || len(report.Endpoints) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	latency := report.Endpoints[0]
+	if latency.Endpoint != "CreateDevice" || latency.Count != 3 || latency.SlowCount != 2 || latency.MaxMs != int64(time.Hour/time.Millisecond) {
+		t.Errorf("unexpected latency %+v", latency)
+	}
+	counts := make(map[int64]uint64)
+	for _, bucket := range latency.Buckets {
+		counts[bucket.LeMs] = bucket.Count
+	}
+	if counts[10] != 1 || counts[2500] != 1 || counts[overflowBucket] != 1 || len(latency.Buckets) != len(latencyBucketsMs)+1 {
+		t.Errorf("unexpected buckets %v", counts)
+	}
+	if report.Endpoints[1].Buckets[2].LeMs != 100 || report.Endpoints[1].Buckets[2].Count != 1 {
+		t.Errorf("unexpected CreateMount bucket %+v", report.Endpoints[1].Buckets[2])
+	}
+}
@@ -0,0 +1,169 @@
+/*
+(c) Copyright 2020 Hewlett Packard Enterprise Development LP
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrent
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// ErrWorkerPoolBusy is returned by WorkerPool.Run when no worker was available within the queue
+// timeout
+var ErrWorkerPoolBusy = errors.New("all workers are busy")
+
+// WorkerPool runs requests on a fixed number of workers.  Requests with the same key (e.g. a
+// volume name) are serialized through a MapMutex, which may be shared with other requests for the
+// same keys.  A request waits at most the queue timeout for a worker; the time spent waiting for
+// its key is not counted as it depends only on the other requests for the same key.
+type WorkerPool struct {
+	name         string
+	workers      int
+	queueTimeout time.Duration
+	keyLocks     *MapMutex
+	jobs         chan *workerJob
+
+	statsLock sync.Mutex
+	active    int           // Requests being run
+	queued    int           // Requests waiting for a worker
+	completed uint64        // Requests run
+	rejected  uint64        // Requests that timed out waiting for a worker
+	waitTotal time.Duration // Total time waited for a worker by the requests run
+	waitMax   time.Duration // Longest time waited for a worker by a request run
+}
+
+// WorkerPoolStats are the request counts and worker wait times of a WorkerPool
+type WorkerPoolStats struct {
+	Name          string  `json:"name"`
+	Workers       int     `json:"workers"`
+	Active        int     `json:"active"`
+	Queued        int     `json:"queued"`
+	Completed     uint64  `json:"completed"`
+	Rejected      uint64  `json:"rejected"`
+	WaitTimeAvgMs float64 `json:"wait_time_avg_ms"`
+	WaitTimeMaxMs int64   `json:"wait_time_max_ms"`
+}
+
+// workerJob is a request handed to a worker
+type workerJob struct {
+	request func()
+	queued  time.Time
+	done    chan struct{}
+	panic   interface{} // Value the request panicked with, if any
+}
+
+// NewWorkerPool creates a WorkerPool, and starts its workers, serializing requests with the same
+// key through keyLocks
+func NewWorkerPool(name string, workers int, queueTimeout time.Duration, keyLocks *MapMutex) *WorkerPool {
+	pool := &WorkerPool{
+		name:         name,
+		workers:      workers,
+		queueTimeout: queueTimeout,
+		keyLocks:     keyLocks,
+		jobs:         make(chan *workerJob),
+	}
+	for i := 0; i < workers; i++ {
+		go pool.work()
+	}
+	return pool
+}
+
+// Run runs the request on a worker once the other requests with the same key are done, and waits
+// for it to complete.  ErrWorkerPoolBusy is returned, without running the request, if no worker
+// was available within the queue timeout.  A request panic is raised again in the caller.
+func (pool *WorkerPool) Run(key string, request func()) error {
+	pool.keyLocks.Lock(key)
+	defer pool.keyLocks.Unlock(key)
+
+	job := &workerJob{request: request, queued: time.Now(), done: make(chan struct{})}
+	pool.updateStats(func() { pool.queued++ })
+	timer := time.NewTimer(pool.queueTimeout)
+	defer timer.Stop()
+	select {
+	case pool.jobs <- job:
+	case <-timer.C:
+		pool.updateStats(func() {
+			pool.queued--
+			pool.rejected++
+		})
+		log.Errorf("%s request for %s timed out after %v waiting for one of %d workers", pool.name, key, pool.queueTimeout, pool.workers)
+		return ErrWorkerPoolBusy
+	}
+
+	<-job.done
+	if job.panic != nil {
+		panic(job.panic)
+	}
+	return nil
+}
+
+// GetStats returns the request counts and worker wait times of the pool
+func (pool *WorkerPool) GetStats() *WorkerPoolStats {
+	pool.statsLock.Lock()
+	defer pool.statsLock.Unlock()
+
+	stats := &WorkerPoolStats{
+		Name:          pool.name,
+		Workers:       pool.workers,
+		Active:        pool.active,
+		Queued:        pool.queued,
+		Completed:     pool.completed,
+		Rejected:      pool.rejected,
+		WaitTimeMaxMs: int64(pool.waitMax / time.Millisecond),
+	}
+	if pool.completed != 0 {
+		stats.WaitTimeAvgMs = float64(pool.waitTotal/time.Millisecond) / float64(pool.completed)
+	}
+	return stats
+}
+
+// work runs the requests handed to the worker
+func (pool *WorkerPool) work() {
+	for job := range pool.jobs {
+		wait := time.Since(job.queued)
+		pool.updateStats(func() {
+			pool.queued--
+			pool.active++
+			pool.waitTotal += wait
+			if wait > pool.waitMax {
+				pool.waitMax = wait
+			}
+		})
+		log.Tracef("%s request started after waiting %v for a worker", pool.name, wait)
+		pool.runJob(job)
+		pool.updateStats(func() {
+			pool.active--
+			pool.completed++
+		})
+		close(job.done)
+	}
+}
+
+// runJob runs the request, recovering from a panic so that the worker keeps running
+func (pool *WorkerPool) runJob(job *workerJob) {
+	defer func() {
+		job.panic = recover()
+	}()
+	job.request()
+}
+
+// updateStats updates the pool statistics under the statistics lock
+func (pool *WorkerPool) updateStats(update func()) {
+	pool.statsLock.Lock()
+	defer pool.statsLock.Unlock()
+	update()
+}
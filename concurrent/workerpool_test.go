@@ -0,0 +1,89 @@
+/*
+(c) Copyright 2020 Hewlett Packard Enterprise Development LP
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package concurrent
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := NewWorkerPool("test", 2, 50*time.Millisecond, NewMapMutex())
+
+	// Requests with the same key are serialized
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Run("volume", func() {
+				if n := atomic.AddInt32(&running, 1); n > atomic.LoadInt32(&maxRunning) {
+					atomic.StoreInt32(&maxRunning, n)
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+			})
+			if err != nil {
+				t.Error("Run failed ", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxRunning != 1 {
+		t.Errorf("Expected requests for the same key to be serialized, %d ran concurrently", maxRunning)
+	}
+
+	// A request waiting longer than the queue timeout for a worker is rejected
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	for _, key := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			pool.Run(key, func() {
+				started <- struct{}{}
+				<-release
+			})
+		}(key)
+	}
+	<-started
+	<-started
+	if err := pool.Run("c", func() { t.Error("Busy request was run") }); err != ErrWorkerPoolBusy {
+		t.Errorf("Expected ErrWorkerPoolBusy, got %v", err)
+	}
+	close(release)
+	wg.Wait()
+
+	// A request panic is raised in the caller, the worker keeps running
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Request panic was not raised")
+			}
+		}()
+		pool.Run("d", func() { panic("test") })
+	}()
+	if err := pool.Run("e", func() {}); err != nil {
+		t.Error("Run failed after a panic ", err)
+	}
+
+	stats := pool.GetStats()
+	if stats.Completed != 9 || stats.Rejected != 1 || stats.Queued != 0 || stats.Active != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
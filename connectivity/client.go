@@ -18,6 +18,7 @@ package connectivity
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -48,12 +49,15 @@ type Request struct {
 	Response interface{}
 	//ResponseError to marshal error into (may be nil)
 	ResponseError interface{}
+	//Context of the request being served (may be nil); its request correlation ID is forwarded
+	Context context.Context
 }
 
 // Client is a simple wrapper for http.Client
 type Client struct {
 	*http.Client
-	pathPrefix string
+	pathPrefix     string
+	responseLimits *Limits // Limits applied to the response bodies (nil for DefaultResponseLimits)
 }
 
 // NewHTTPClient returns a client that communicates over ip using a 30 second timeout
@@ -66,7 +70,7 @@ func NewHTTPClientWithTimeout(url string, timeout time.Duration) *Client {
 	if timeout < 1 {
 		timeout = defaultTimeout
 	}
-	return &Client{&http.Client{Timeout: timeout}, url}
+	return &Client{&http.Client{Timeout: timeout}, url, nil}
 }
 
 // NewHTTPClientWithTimeoutAndRedirectPolicy returns a client that communicates over ip.
@@ -75,7 +79,7 @@ func NewHTTPClientWithTimeoutAndRedirectPolicy(url string, timeout time.Duration
 	if timeout < 1 {
 		timeout = defaultTimeout
 	}
-	return &Client{&http.Client{Timeout: timeout, CheckRedirect: redirectPolicyFunc}, url}
+	return &Client{&http.Client{Timeout: timeout, CheckRedirect: redirectPolicyFunc}, url, nil}
 }
 
 // NewHTTPSClientWithTimeout returns a client that communicates over ip with tls :
@@ -83,7 +87,7 @@ func NewHTTPSClientWithTimeout(url string, transport http.RoundTripper, timeout
 	if timeout < 1 {
 		timeout = defaultTimeout
 	}
-	return &Client{&http.Client{Timeout: timeout, Transport: transport}, url}
+	return &Client{&http.Client{Timeout: timeout, Transport: transport}, url, nil}
 }
 
 // NewHTTPSClientWithTimeoutAndRedirectPolicy returns a client that communicates over ip
@@ -91,7 +95,7 @@ func NewHTTPSClientWithTimeoutAndRedirectPolicy(url string, transport http.Round
 	if timeout < 1 {
 		timeout = defaultTimeout
 	}
-	return &Client{&http.Client{Timeout: timeout, Transport: transport, CheckRedirect: redirectPolicyFunc}, url}
+	return &Client{&http.Client{Timeout: timeout, Transport: transport, CheckRedirect: redirectPolicyFunc}, url, nil}
 }
 
 // NewHTTPSClient returns a new https client
@@ -115,7 +119,20 @@ func NewSocketClientWithTimeout(filename string, timeout time.Duration) *Client
 	tr.Dial = func(_, _ string) (net.Conn, error) {
 		return net.DialTimeout("unix", filename, timeout)
 	}
-	return &Client{&http.Client{Transport: tr, Timeout: timeout}, "http://unix"}
+	return &Client{&http.Client{Transport: tr, Timeout: timeout}, "http://unix", nil}
+}
+
+// SetResponseLimits sets the limits applied to the response bodies (nil for DefaultResponseLimits)
+func (client *Client) SetResponseLimits(limits *Limits) {
+	client.responseLimits = limits
+}
+
+// getResponseLimits returns the limits applied to the response bodies
+func (client *Client) getResponseLimits() *Limits {
+	if client.responseLimits == nil {
+		return DefaultResponseLimits()
+	}
+	return client.responseLimits
 }
 
 // Helper function to check if the error response is parsable for the given status code
@@ -158,6 +175,10 @@ func (client *Client) DoJSON(r *Request) (int, error) {
 	// Add headers
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
+	// Forward the request correlation ID of the request being served, if any
+	if requestID := log.RequestIDFromContext(r.Context); requestID != "" {
+		req.Header.Set(log.RequestIDHeader, requestID)
+	}
 	// Include other headers specified in the input request
 	for key, val := range r.Header {
 		req.Header.Add(key, val)
@@ -185,7 +206,7 @@ func (client *Client) DoJSON(r *Request) (int, error) {
 		// Check if this error is parsable
 		if isParsableError(res.StatusCode) {
 			// Decode the body into the error response
-			err = decode(res.Body, r.ResponseError, r)
+			err = decode(res.Body, r.ResponseError, r, client.getResponseLimits())
 			if err != nil {
 				log.Error("Failed to decode error response.")
 				r.ResponseError = "Failed to decode error response, Error:" + fmt.Sprintf("%d", res.StatusCode)
@@ -197,7 +218,7 @@ func (client *Client) DoJSON(r *Request) (int, error) {
 
 	// Docker /info always has contentLength =-1 so that is not the sufficient condition to not decode the body.
 	// Rather check for io.EOF and do not throw error if empty body exist
-	err = decode(res.Body, r.Response, r)
+	err = decode(res.Body, r.Response, r, client.getResponseLimits())
 	if err != nil {
 		return res.StatusCode, err
 	}
@@ -226,10 +247,10 @@ func doWithRetry(client *Client, request *http.Request) (*http.Response, error)
 	}
 }
 
-func decode(rc io.ReadCloser, dest interface{}, r *Request) error {
+func decode(rc io.ReadCloser, dest interface{}, r *Request, limits *Limits) error {
 	if rc != nil && dest != nil {
 		log.Debugf("About to decode the error response %v into destination interface", rc)
-		if err := json.NewDecoder(rc).Decode(&dest); err != nil {
+		if err := DecodeJSON(rc, &dest, limits); err != nil {
 			switch {
 			case err == io.EOF:
 				log.Tracef("empty body %s", io.EOF.Error())
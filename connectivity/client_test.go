@@ -16,15 +16,19 @@ limitations under the License.
 package connectivity
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 	"time"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
 )
 
 const (
@@ -261,3 +265,31 @@ func verifyEmpty(err error, bad badnews, t *testing.T) {
 			"got", bad.Info)
 	}
 }
+
+func TestRequestIDForwarded(t *testing.T) {
+	var forwarded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(log.RequestIDHeader)
+		fmt.Fprint(w, "{\"pong\":\"test\"}")
+	}))
+	defer server.Close()
+	client := NewHTTPClient(server.URL)
+
+	// The request ID of the request context is forwarded
+	var foo answer
+	ctx := log.NewRequestIDContext(context.Background(), "abc-123")
+	if _, err := client.DoJSON(&Request{Action: "GET", Path: "/", Context: ctx, Response: &foo}); err != nil {
+		t.Fatalf("DoJSON failed, err=%v", err)
+	}
+	if forwarded != "abc-123" {
+		t.Errorf("expected request ID abc-123 to be forwarded, got %q", forwarded)
+	}
+
+	// No request ID without a request context
+	if _, err := client.DoJSON(&Request{Action: "GET", Path: "/", Response: &foo}); err != nil {
+		t.Fatalf("DoJSON failed, err=%v", err)
+	}
+	if forwarded != "" {
+		t.Errorf("expected no request ID to be forwarded, got %q", forwarded)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+(c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package connectivity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// JSON payloads are read in full before they are decoded, so a malformed (or malicious) payload
+// can exhaust the memory of the node agent, or of a client, before decoding fails.  Limits caps
+// the size of a payload and the nesting depth of its JSON values, and can reject the properties
+// unknown to the destination type.  Clients apply DefaultResponseLimits to every response (see
+// Client.SetResponseLimits) while servers read GetRequestLimits once, when their router is built,
+// and apply it to every request through LimitRequestBody and DecodeJSON.
+
+const (
+	// DefaultMaxRequestBytes is the largest request body accepted by default
+	DefaultMaxRequestBytes = 1024 * 1024
+	// DefaultMaxResponseBytes is the largest response body accepted by default
+	DefaultMaxResponseBytes = 64 * 1024 * 1024
+	// DefaultMaxJSONDepth is the deepest JSON nesting accepted by default
+	DefaultMaxJSONDepth = 64
+
+	// MaxRequestBytesEnv overrides the largest request body accepted by servers (0 for unlimited)
+	MaxRequestBytesEnv = "CHAPI_MAX_REQUEST_BYTES"
+	// MaxJSONDepthEnv overrides the deepest JSON nesting of the requests accepted by servers (0 for
+	// unlimited)
+	MaxJSONDepthEnv = "CHAPI_MAX_JSON_DEPTH"
+	// DisallowUnknownFieldsEnv, if "true", rejects requests with properties unknown to the
+	// destination type
+	DisallowUnknownFieldsEnv = "CHAPI_DISALLOW_UNKNOWN_FIELDS"
+)
+
+var (
+	// ErrBodyTooLarge is returned when a payload exceeds Limits.MaxBytes
+	ErrBodyTooLarge = errors.New("payload too large")
+	// ErrJSONTooDeep is returned when a payload nests JSON values deeper than Limits.MaxDepth
+	ErrJSONTooDeep = errors.New("JSON nesting too deep")
+)
+
+// Limits are the protections applied when decoding a JSON payload.  Zero values are unlimited.
+type Limits struct {
+	MaxBytes              int64 // Largest payload, in bytes
+	MaxDepth              int   // Deepest nesting of JSON objects and arrays
+	DisallowUnknownFields bool  // Reject properties unknown to the destination type
+}
+
+// DefaultResponseLimits returns the limits applied by clients to the response bodies
+func DefaultResponseLimits() *Limits {
+	return &Limits{MaxBytes: DefaultMaxResponseBytes, MaxDepth: DefaultMaxJSONDepth}
+}
+
+// DefaultRequestLimits returns the limits applied by servers to the request bodies by default
+func DefaultRequestLimits() *Limits {
+	return &Limits{MaxBytes: DefaultMaxRequestBytes, MaxDepth: DefaultMaxJSONDepth}
+}
+
+// GetRequestLimits returns the limits applied by servers to the request bodies; the defaults
+// overridden by MaxRequestBytesEnv, MaxJSONDepthEnv and DisallowUnknownFieldsEnv.  The environment
+// is read on every call, so servers call it once when their router is built.
+func GetRequestLimits() *Limits {
+	limits := DefaultRequestLimits()
+	limits.DisallowUnknownFields = strings.EqualFold(os.Getenv(DisallowUnknownFieldsEnv), "true")
+	if value := os.Getenv(MaxRequestBytesEnv); value != "" {
+		if maxBytes, err := strconv.ParseInt(value, 10, 64); err == nil && maxBytes >= 0 {
+			limits.MaxBytes = maxBytes
+		} else {
+			log.Errorf("Invalid %v %q, using %v", MaxRequestBytesEnv, value, limits.MaxBytes)
+		}
+	}
+	if value := os.Getenv(MaxJSONDepthEnv); value != "" {
+		if maxDepth, err := strconv.Atoi(value); err == nil && maxDepth >= 0 {
+			limits.MaxDepth = maxDepth
+		} else {
+			log.Errorf("Invalid %v %q, using %v", MaxJSONDepthEnv, value, limits.MaxDepth)
+		}
+	}
+	return limits
+}
+
+// DecodeJSON decodes the JSON payload read from the reader into dest, within the given limits (nil
+// for unlimited).  io.EOF is returned if the payload is empty.
+func DecodeJSON(reader io.Reader, dest interface{}, limits *Limits) error {
+	if limits == nil {
+		limits = &Limits{}
+	}
+	if limits.MaxBytes > 0 {
+		reader = io.LimitReader(reader, limits.MaxBytes+1)
+	}
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		// Bodies wrapped by LimitRequestBody fail to read once the limit is exceeded
+		if errors.As(err, new(*http.MaxBytesError)) {
+			return ErrBodyTooLarge
+		}
+		return err
+	}
+	if limits.MaxBytes > 0 && int64(len(data)) > limits.MaxBytes {
+		return ErrBodyTooLarge
+	}
+	if limits.MaxDepth > 0 {
+		if err = checkJSONDepth(data, limits.MaxDepth); err != nil {
+			return err
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if limits.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(dest)
+}
+
+// LimitRequestBody returns a handler that caps the request body of the given handler to maxBytes
+// (0 for unlimited), so that reading a larger body fails
+func LimitRequestBody(handlerFunc http.HandlerFunc, maxBytes int64) http.HandlerFunc {
+	if maxBytes <= 0 {
+		return handlerFunc
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			log.Errorf("Request body of %v bytes exceeds %v bytes, action=%v path=%v", r.ContentLength, maxBytes, r.Method, r.URL.Path)
+		}
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		}
+		handlerFunc(w, r)
+	}
+}
+
+// IsLimitError returns true if the error is a payload size or depth limit error
+func IsLimitError(err error) bool {
+	return errors.Is(err, ErrBodyTooLarge) || errors.Is(err, ErrJSONTooDeep)
+}
+
+// checkJSONDepth returns ErrJSONTooDeep if the JSON data nests objects and arrays deeper than
+// maxDepth.  Malformed JSON is left for the decoder to report.
+func checkJSONDepth(data []byte, maxDepth int) error {
+	depth := 0
+	inString, escaped := false, false
+	for i, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			if depth++; depth > maxDepth {
+				log.Errorf("JSON nesting exceeds a depth of %v at offset %v", maxDepth, i)
+				return fmt.Errorf("%w (limit %v)", ErrJSONTooDeep, maxDepth)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
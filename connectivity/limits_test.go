@@ -0,0 +1,95 @@
+/*
+(c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package connectivity
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	limits := &Limits{MaxBytes: 32, MaxDepth: 2}
+
+	var q question
+	if err := DecodeJSON(strings.NewReader(requestJSON), &q, limits); err != nil || q.Ping != "junk" {
+		t.Errorf("expected ping=junk, got %+v, err=%v", q, err)
+	}
+	if err := DecodeJSON(strings.NewReader(""), &q, limits); err != io.EOF {
+		t.Errorf("expected io.EOF for an empty payload, got %v", err)
+	}
+	if err := DecodeJSON(strings.NewReader(`{"ping":"`+strings.Repeat("x", 32)+`"}`), &q, limits); err != ErrBodyTooLarge {
+		t.Errorf("expected ErrBodyTooLarge, got %v", err)
+	}
+
+	var value interface{}
+	if err := DecodeJSON(strings.NewReader(`{"a":[1,"[[[{"]}`), &value, limits); err != nil {
+		t.Errorf("expected brackets within strings to be ignored, err=%v", err)
+	}
+	if err := DecodeJSON(strings.NewReader(`{"a":[{"b":1}]}`), &value, limits); !IsLimitError(err) {
+		t.Errorf("expected ErrJSONTooDeep, got %v", err)
+	}
+
+	// Unknown properties are only rejected if disallowed
+	if err := DecodeJSON(strings.NewReader(`{"ping":"junk","pong":1}`), &q, limits); err != nil {
+		t.Errorf("expected unknown properties to be ignored, err=%v", err)
+	}
+	limits.DisallowUnknownFields = true
+	if err := DecodeJSON(strings.NewReader(`{"ping":"junk","pong":1}`), &q, limits); err == nil {
+		t.Error("expected unknown properties to be rejected")
+	}
+
+	// No limits
+	if err := DecodeJSON(strings.NewReader(`[[[[{"ping":"`+strings.Repeat("x", 64)+`"}]]]]`), &value, nil); err != nil {
+		t.Errorf("expected no limits, err=%v", err)
+	}
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	var decodeErr error
+	handler := LimitRequestBody(func(w http.ResponseWriter, r *http.Request) {
+		var q question
+		decodeErr = DecodeJSON(r.Body, &q, nil)
+	}, 16)
+
+	handler(httptest.NewRecorder(), httptest.NewRequest("POST", pathString, strings.NewReader(requestJSON)))
+	if decodeErr != nil {
+		t.Errorf("expected request within the limit to decode, err=%v", decodeErr)
+	}
+	handler(httptest.NewRecorder(), httptest.NewRequest("POST", pathString, strings.NewReader(`{"ping":"`+strings.Repeat("x", 16)+`"}`)))
+	if decodeErr != ErrBodyTooLarge {
+		t.Errorf("expected ErrBodyTooLarge, got %v", decodeErr)
+	}
+}
+
+func TestGetRequestLimits(t *testing.T) {
+	defer os.Unsetenv(MaxRequestBytesEnv)
+	defer os.Unsetenv(MaxJSONDepthEnv)
+	defer os.Unsetenv(DisallowUnknownFieldsEnv)
+
+	if limits := GetRequestLimits(); *limits != (Limits{MaxBytes: DefaultMaxRequestBytes, MaxDepth: DefaultMaxJSONDepth}) {
+		t.Errorf("unexpected default limits %+v", limits)
+	}
+	os.Setenv(MaxRequestBytesEnv, "4096")
+	os.Setenv(MaxJSONDepthEnv, "bogus")
+	os.Setenv(DisallowUnknownFieldsEnv, "TRUE")
+	if limits := GetRequestLimits(); *limits != (Limits{MaxBytes: 4096, MaxDepth: DefaultMaxJSONDepth, DisallowUnknownFields: true}) {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+}
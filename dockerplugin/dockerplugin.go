@@ -5,15 +5,20 @@ package dockerplugin
 import (
 	"github.com/gorilla/mux"
 	"github.com/hpe-storage/common-host-libs/dockerplugin/handler"
+	"github.com/hpe-storage/common-host-libs/dockerplugin/plugin"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/util"
 	"net"
 	"net/http"
+	"time"
 )
 
 var (
 	// LogLevel represents plugin logging level, set info as default
 	LogLevel = "info"
+	// reconcileRetryInterval is the wait between attempts to reconcile the volume mounts on start
+	reconcileRetryInterval = 30 * time.Second
+	reconcileTries         = 3
 )
 
 // NewRouter creates a new mux.Router
@@ -79,6 +84,30 @@ func NewRouter() *mux.Router {
 			Pattern:     "/VolumeDriver.Update",
 			HandlerFunc: handler.VolumeDriverUpdate,
 		},
+		util.Route{
+			Name:        "Volume Driver Options",
+			Method:      "GET",
+			Pattern:     "/VolumeDriver.Options",
+			HandlerFunc: handler.VolumeDriverOptions,
+		},
+		util.Route{
+			Name:        "Plugin Get Logging",
+			Method:      "GET",
+			Pattern:     "/Plugin.Logging",
+			HandlerFunc: handler.PluginGetLogging,
+		},
+		util.Route{
+			Name:        "Plugin Set Logging",
+			Method:      "PUT",
+			Pattern:     "/Plugin.Logging",
+			HandlerFunc: handler.PluginSetLogging,
+		},
+		util.Route{
+			Name:        "Plugin Requests",
+			Method:      "GET",
+			Pattern:     "/Plugin.Requests",
+			HandlerFunc: handler.PluginRequests,
+		},
 	}
 	router := mux.NewRouter().StrictSlash(true)
 	util.InitializeRouter(router, routes)
@@ -92,3 +121,23 @@ func runNimbledockerd(l net.Listener, m *mux.Router, c chan error) {
 	log.Tracef("closing the socket %v", l.Addr().String())
 	l.Close()
 }
+
+// reconcileMounts reconciles, in the background, the volume mounts with the container provider
+// unless disabled in the config file.  The container provider may not be ready when the plugin
+// starts, so listing the volumes is retried.
+func reconcileMounts() {
+	plugin.InitializeReconcileOnStart()
+	if !plugin.ReconcileOnStart {
+		return
+	}
+	go func() {
+		for try := 1; try <= reconcileTries; try++ {
+			time.Sleep(reconcileRetryInterval)
+			err := handler.ReconcileMounts()
+			if err == nil {
+				return
+			}
+			log.Errorf("unable to reconcile volume mounts (attempt %d of %d), err %s", try, reconcileTries, err.Error())
+		}
+	}()
+}
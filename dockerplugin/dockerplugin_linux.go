@@ -33,6 +33,12 @@ func RunNimbledockerd(c chan error, version string) (err error) {
 		log.Errorf("unable to load hpe volume config %s", err.Error())
 		return err
 	}
+	// initialize the mount root and mount path template
+	err = plugin.InitializeMountPath()
+	if err != nil {
+		log.Errorf("invalid mount path configuration %s", err.Error())
+		return err
+	}
 	// initialize the DeleteConflictDelay timeout
 	plugin.InitializeDeleteConflictDelay()
 
@@ -40,5 +46,7 @@ func RunNimbledockerd(c chan error, version string) (err error) {
 	router := NewRouter()
 	//use channel to listen to multiple sockets simultaneously
 	go runNimbledockerd(listener, router, c)
+	// repair volume mounts out of sync with the container provider while the plugin was down
+	reconcileMounts()
 	return nil
 }
@@ -32,6 +32,12 @@ func RunNimbledockerd(c chan error, version string) (err error) {
 		log.Errorf("unable to load hpe volume config %s", err.Error())
 		return err
 	}
+	// initialize the mount root and mount path template
+	err = plugin.InitializeMountPath()
+	if err != nil {
+		log.Errorf("invalid mount path configuration %s", err.Error())
+		return err
+	}
 	// initialize the DeleteConflictDelay timeout
 	//Fix : this is causing crash and not really required for Windows
 	// since windows doesnt support K8s yet.
@@ -44,5 +50,7 @@ func RunNimbledockerd(c chan error, version string) (err error) {
 
 	//use channel to listen to multiple ports simultaneously
 	go runNimbledockerd(listener, router, c)
+	// repair volume mounts out of sync with the container provider while the plugin was down
+	reconcileMounts()
 	return nil
 }
@@ -50,13 +50,13 @@ func ActivatePlugin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// container-provider /Plugin.Activate called
-	log.Trace(pluginReq)
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.ActivateURI, Payload: &pluginReq, Response: &actPlugResp, ResponseError: nil})
+	log.Trace(pluginReq.Redacted())
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.ActivateURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &actPlugResp, ResponseError: nil})
 	if err != nil {
 		resp := &DriverResponse{Err: err.Error()}
 		json.NewEncoder(w).Encode(resp)
 		return
 	}
-	log.Infof("%s: request=(%+v) response=(%+v)", provider.ActivateURI, pluginReq, actPlugResp.Activate)
+	log.Infof("%s: request=(%+v) response=(%+v)", provider.ActivateURI, pluginReq.Redacted(), actPlugResp.Activate)
 	json.NewEncoder(w).Encode(actPlugResp)
 }
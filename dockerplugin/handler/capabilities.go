@@ -52,7 +52,7 @@ func VolumeDriverCapabilities(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// container-provider /VolumeDriver.Capabilities called
-	_, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: provider.CapabilitiesURI, Payload: &pluginReq, Response: &capability, ResponseError: nil})
+	_, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: provider.CapabilitiesURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &capability, ResponseError: nil})
 	if err != nil {
 		resp := &DriverResponse{
 			Err: err.Error(),
@@ -60,7 +60,7 @@ func VolumeDriverCapabilities(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(resp)
 		return
 	}
-	log.Debugf("%s: request=(%+v) response=(%+v)", provider.CapabilitiesURI, pluginReq, capability)
+	log.Debugf("%s: request=(%+v) response=(%+v)", provider.CapabilitiesURI, pluginReq.Redacted(), capability)
 	json.NewEncoder(w).Encode(capability)
 	return
 }
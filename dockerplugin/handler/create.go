@@ -61,14 +61,14 @@ func VolumeDriverCreate(w http.ResponseWriter, r *http.Request) {
 		log.Errorf("%s failed to add mount options from config file using defaults", err.Error())
 	}
 
-	// validate fsMode and fsOwner if specified in the request
-	fsMode, fsOwner, err := getFileSystemModeAndOwnerFromRequest(pluginReq)
+	// parse and validate the create options handled by the plugin
+	options, err := parseOptions(createOptionSchema, pluginReq.Opts)
 	if err != nil {
 		dr := DriverResponse{Err: err.Error()}
 		json.NewEncoder(w).Encode(dr)
 		return
 	}
-	fsOpts := &model.FilesystemOpts{Mode: fsMode, Owner: fsOwner}
+	fsOpts := &model.FilesystemOpts{Type: options.getString(model.FsCreateOpt), Mode: options.getString(model.FsModeOpt), Owner: options.getString(model.FsOwnerOpt)}
 
 	// populate delayed create option to pluginReq except for import and clone workflows
 	if !isValidDelayedCreateOpt(pluginReq) {
@@ -79,9 +79,16 @@ func VolumeDriverCreate(w http.ResponseWriter, r *http.Request) {
 	// remove global options from create request
 	removeGlobalOptionsFromCreateRequest(pluginReq)
 
-	// check if valid fileystem was present in the request
-	if !isValidFilesystem(pluginReq) {
-		dr := DriverResponse{Err: fmt.Sprintf("invalid filesystem type(%s), please enter one of the following options (%s)", pluginReq.Opts["filesystem"], strings.Join(plugin.SupportedFileSystems, " "))}
+	// validate hpecv create options against the provider capabilities
+	if err = validateHPECloudVolumesCreateOptions(providerClient, pluginReq); err != nil {
+		dr := DriverResponse{Err: err.Error()}
+		json.NewEncoder(w).Encode(dr)
+		return
+	}
+
+	// validate QoS options against the provider QoS capabilities
+	if err = validateQosOptions(providerClient, pluginReq); err != nil {
+		dr := DriverResponse{Err: err.Error()}
 		json.NewEncoder(w).Encode(dr)
 		return
 	}
@@ -92,7 +99,7 @@ func VolumeDriverCreate(w http.ResponseWriter, r *http.Request) {
 
 	//1. container-provider /VolumeDriver.Create called
 	var dr DriverResponse
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.CreateURI, Payload: &pluginReq, Response: &cr, ResponseError: &dr})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.CreateURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &cr, ResponseError: &dr})
 	if err != nil {
 		if cr.Err != "" {
 			dr := DriverResponse{Err: fmt.Errorf("unable to create the volume %s %s", pluginReq.Name, cr.Err).Error()}
@@ -123,7 +130,7 @@ func VolumeDriverCreate(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(cr)
 	} else if len(cr.Volumes) > 0 {
 		// check if it is delayedCreate Response else continue with older create
-		log.Tracef("response from volume create (%+v)", cr.Volumes[0])
+		log.Tracef("response from volume create (%+v)", cr.Volumes[0].Redacted())
 		if val, ok := cr.Volumes[0].Status[delayedCreateOpt]; ok {
 			log.Tracef("delayedCreate response %s", val)
 			json.NewEncoder(w).Encode(cr)
@@ -137,6 +144,7 @@ func VolumeDriverCreate(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(cr)
 			return
 		}
+		chapiClient.SetContext(r.Context())
 		// Creation of new volume
 		log.Debug("Volume creation initiated for ", cr.Volumes[0].Name)
 		discoveryIP := cr.Volumes[0].DiscoveryIP
@@ -155,7 +163,7 @@ func VolumeDriverCreate(w http.ResponseWriter, r *http.Request) {
 				// detach device and cleanup host side
 				log.Debugf("initiating device detach for %+v", device)
 				// set the target scope
-				device.TargetScope = cr.Volumes[0].TargetScope
+				cr.Volumes[0].UpdateDevice(device)
 
 				// offline the device
 				err = chapiClient.OfflineDevice(device)
@@ -178,16 +186,16 @@ func VolumeDriverCreate(w http.ResponseWriter, r *http.Request) {
 			var dr DriverResponse
 			//force delete the volume on create failures else it will lie around in offline state
 			pluginReq.Opts["destroyOnRm"] = true
-			providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.RemoveURI, Payload: &pluginReq, Response: &dr, ResponseError: nil})
+			providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.RemoveURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &dr, ResponseError: nil})
 			dr = DriverResponse{Err: err.Error()}
 			json.NewEncoder(w).Encode(dr)
 			// final return after all the cleanup
 			return
 		}
 		//3. Now offline the device
-		log.Debugf("device %+v is unmounted for volume %+v, offline the device", device, cr.Volumes[0])
+		log.Debugf("device %+v is unmounted for volume %+v, offline the device", device, cr.Volumes[0].Redacted())
 		// set the target scope
-		device.TargetScope = cr.Volumes[0].TargetScope
+		cr.Volumes[0].UpdateDevice(device)
 
 		err = chapiClient.OfflineDevice(device)
 		if err != nil {
@@ -212,7 +220,7 @@ func VolumeDriverCreate(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	log.Infof("%s: request=(%+v) response=(%+v)", provider.CreateURI, pluginReq, cr.Volumes)
+	log.Infof("%s: request=(%+v) response=(%+v)", provider.CreateURI, pluginReq.Redacted(), cr.Volumes)
 	json.NewEncoder(w).Encode(cr)
 	return
 }
@@ -228,6 +236,7 @@ func createFileSystemOnVolume(vols []*model.Volume, pluginReq *PluginRequest, fs
 	if err != nil {
 		return nil, err
 	}
+	chapiClient.SetContext(pluginReq.ctx)
 
 	//1. Create and attach the device
 	log.Tracef("calling attach device with vols %+v", vols)
@@ -248,17 +257,20 @@ func createFileSystemOnVolume(vols []*model.Volume, pluginReq *PluginRequest, fs
 	vol := vols[0]
 
 	//2. Make a put request to put a partition / filesystem on the device
-	fileSystemType := getFileSystemTypeFromRequest(pluginReq)
 	//make sure volume.Mountpoint is populated
-	vol.MountPoint = plugin.MountDir + vol.Name
-	err = chapiClient.SetupFilesystemAndPermissions(device, vol, fileSystemType)
+	vol.MountPoint, err = plugin.GetMountPath(vol)
+	if err != nil {
+		return nil, err
+	}
+	err = chapiClient.SetupFilesystemAndPermissions(device, vol, fsOpts.Type)
 	if err != nil {
 		return nil, fmt.Errorf("unable to setup filesystem for device %s, err(%s)", device.AltFullPathName, err.Error())
 	}
 	err = chapiClient.UnmountDevice(vol)
 	if err == nil {
-		// delete the mountPoint
+		// delete the mountPoint, and its parent directories created for the mount path template
 		os.RemoveAll(vol.MountPoint)
+		plugin.RemoveMountPath(vol.MountPoint)
 		if err != nil {
 			return nil, err
 		}
@@ -274,10 +286,8 @@ func populateVolCreateOptions(req *PluginRequest) (err error) {
 
 	// populate options based on correct priority order
 
-	// check for short form or size and add complete key
-	if _, present := req.Opts["size"]; present {
-		req.Opts["sizeInGiB"] = req.Opts["size"]
-	}
+	// rename deprecated options (e.g. size) first so that the config defaults don't override them
+	renameDeprecatedOptions(createOptionSchema, req.Opts)
 
 	// check if config file exist and load config
 	volumeDriverConfFile := plugin.PluginConfigDir + plugin.DriverConfigFile
@@ -317,6 +327,42 @@ func populateVolCreateOptions(req *PluginRequest) (err error) {
 	return nil
 }
 
+// validateHPECloudVolumesCreateOptions rejects create options that the hpecv provider does not
+// support.  If the provider capabilities cannot be fetched (e.g. older provider), the options are
+// left for the provider to validate.
+func validateHPECloudVolumesCreateOptions(providerClient *connectivity.Client, pluginReq *PluginRequest) error {
+	if !provider.IsHPECloudVolumesPlugin() || pluginReq.Opts == nil {
+		return nil
+	}
+	capabilities, err := provider.GetHPECloudVolumesCapabilities(providerClient, pluginReq.User)
+	if err != nil {
+		log.Debugf("unable to validate create options, provider capabilities unavailable, err %s", err.Error())
+		return nil
+	}
+	return capabilities.ValidateCreateOptions(pluginReq.Opts)
+}
+
+// validateQosOptions rejects QoS options (limitIOPS, limitMBPS and perfPolicy) that the provider
+// does not support.  If the provider QoS capabilities cannot be fetched (e.g. older provider), the
+// options are left for the provider to validate.
+func validateQosOptions(providerClient *connectivity.Client, pluginReq *PluginRequest) error {
+	found := false
+	for _, spec := range qosOptionSchema {
+		if _, ok := pluginReq.Opts[spec.Name]; ok {
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	capabilities, err := provider.GetQosCapabilities(providerClient, pluginReq.User)
+	if err != nil {
+		log.Debugf("unable to validate QoS options, provider QoS capabilities unavailable, err %s", err.Error())
+		return nil
+	}
+	return capabilities.ValidateQosOptions(pluginReq.Opts)
+}
+
 func isValidDelayedCreateOpt(pluginReq *PluginRequest) bool {
 	log.Trace(">>>> isValidDelayedCreateOpt called")
 	defer log.Tracef("<<<< isValidDelayedCreateOpt")
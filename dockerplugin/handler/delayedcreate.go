@@ -0,0 +1,210 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/connectivity"
+	"github.com/hpe-storage/common-host-libs/dockerplugin/plugin"
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/model"
+)
+
+// A delayed create volume (e.g. a clone) has its file system created by the first mount, when the
+// mount fails, after which the delayedCreate option is removed from the volume metadata on the
+// container provider.  If that metadata update fails, a later failed mount would create the file
+// system again and lose the volume's data.  The delayed creates performed on this host are
+// therefore persisted locally, as intents, until the container provider metadata is updated:
+//
+//	pending		the file system is being created; if the plugin stops before it's created, the
+//				next failed mount creates it again
+//	created		the file system was created; it's never created again and the metadata update
+//				is retried on the next mount of the volume and when the plugin starts
+
+const (
+	delayedCreatePending = "pending"
+	delayedCreateCreated = "created"
+
+	delayedCreateStateFile = "delayed_create.json"
+)
+
+var (
+	// delayedCreateStatePath is the file the delayed create intents are persisted to; a variable so
+	// that tests can redirect it
+	delayedCreateStatePath = filepath.Join(plugin.PluginBaseDir, delayedCreateStateFile)
+	delayedCreateLock      sync.Mutex
+)
+
+// delayedCreateIntent is a delayed create performed on this host, keyed by volume serial number
+type delayedCreateIntent struct {
+	Name   string `json:"name"`
+	FsType string `json:"fs_type"`
+	State  string `json:"state"`
+}
+
+// getDelayedCreateIntent returns the delayed create intent of the volume, or nil if none
+func getDelayedCreateIntent(serialNumber string) (*delayedCreateIntent, error) {
+	delayedCreateLock.Lock()
+	defer delayedCreateLock.Unlock()
+	intents, err := loadDelayedCreateIntents()
+	if err != nil {
+		return nil, err
+	}
+	return intents[serialNumber], nil
+}
+
+// setDelayedCreateIntent persists the delayed create intent of the volume
+func setDelayedCreateIntent(serialNumber string, intent *delayedCreateIntent) error {
+	delayedCreateLock.Lock()
+	defer delayedCreateLock.Unlock()
+	intents, err := loadDelayedCreateIntents()
+	if err != nil {
+		return err
+	}
+	intents[serialNumber] = intent
+	return saveDelayedCreateIntents(intents)
+}
+
+// removeDelayedCreateIntent removes the delayed create intent of the volume, if any
+func removeDelayedCreateIntent(serialNumber string) error {
+	delayedCreateLock.Lock()
+	defer delayedCreateLock.Unlock()
+	intents, err := loadDelayedCreateIntents()
+	if err != nil {
+		return err
+	}
+	if _, ok := intents[serialNumber]; !ok {
+		return nil
+	}
+	delete(intents, serialNumber)
+	return saveDelayedCreateIntents(intents)
+}
+
+// loadDelayedCreateIntents reads the persisted delayed create intents
+func loadDelayedCreateIntents() (map[string]*delayedCreateIntent, error) {
+	intents := make(map[string]*delayedCreateIntent)
+	data, err := ioutil.ReadFile(delayedCreateStatePath)
+	if os.IsNotExist(err) {
+		return intents, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read delayed create state %s, %s", delayedCreateStatePath, err.Error())
+	}
+	if err = json.Unmarshal(data, &intents); err != nil {
+		return nil, fmt.Errorf("invalid delayed create state %s, %s", delayedCreateStatePath, err.Error())
+	}
+	return intents, nil
+}
+
+// saveDelayedCreateIntents persists the delayed create intents, replacing the state file atomically
+// so that an interrupted write doesn't lose the intents already persisted.  The temporary file is
+// synced before, and the state directory after, the rename so that the intents survive a crash.
+func saveDelayedCreateIntents(intents map[string]*delayedCreateIntent) error {
+	data, err := json.MarshalIndent(intents, "", "  ")
+	if err != nil {
+		return err
+	}
+	stateDir := filepath.Dir(delayedCreateStatePath)
+	if err = os.MkdirAll(stateDir, 0700); err != nil {
+		return err
+	}
+	tempPath := delayedCreateStatePath + ".tmp"
+	if err = writeFileSync(tempPath, data); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("unable to write delayed create state %s, %s", tempPath, err.Error())
+	}
+	if err = os.Rename(tempPath, delayedCreateStatePath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("unable to write delayed create state %s, %s", delayedCreateStatePath, err.Error())
+	}
+	if err = syncDir(stateDir); err != nil {
+		return fmt.Errorf("unable to sync delayed create state directory %s, %s", stateDir, err.Error())
+	}
+	return nil
+}
+
+// writeFileSync writes the data to the given file and syncs it to disk
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// syncDir syncs the given directory so that a file renamed into it survives a crash
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// completeDelayedCreate removes the delayedCreate option from the metadata of the mounted volume,
+// if present, and then its delayed create intent.  If the metadata update fails, the intent is kept
+// so that the update is retried.
+func completeDelayedCreate(pluginReq *PluginRequest, volume *model.Volume) {
+	if _, ok := volume.Status[delayedCreateOpt]; ok {
+		if err := removeDelayedCreateMetadata(pluginReq, volume); err != nil {
+			log.Errorf("%s, it will be removed again on the next mount", err.Error())
+			return
+		}
+	}
+	if err := removeDelayedCreateIntent(volume.SerialNumber); err != nil {
+		log.Errorf("unable to remove delayed create intent of volume %s, err %s", volume.Name, err.Error())
+	}
+}
+
+// reconcileDelayedCreates completes the delayed creates whose file system was created on this host
+// but whose volume metadata was not updated
+func reconcileDelayedCreates(providerClient *connectivity.Client, pluginReq *PluginRequest) {
+	delayedCreateLock.Lock()
+	intents, err := loadDelayedCreateIntents()
+	delayedCreateLock.Unlock()
+	if err != nil {
+		log.Errorf("unable to reconcile delayed creates, err %s", err.Error())
+		return
+	}
+	for serialNumber, intent := range intents {
+		if intent.State != delayedCreateCreated {
+			log.Infof("file system creation of volume %s was interrupted, it will be created on the next mount", intent.Name)
+			continue
+		}
+		volumeReq := *pluginReq
+		volumeReq.Name = intent.Name
+		reconcileDelayedCreate(providerClient, &volumeReq, serialNumber)
+	}
+}
+
+// reconcileDelayedCreate completes the delayed create of the requested volume under its volume lock
+func reconcileDelayedCreate(providerClient *connectivity.Client, pluginReq *PluginRequest, serialNumber string) {
+	mapMutex.Lock(pluginReq.Name)
+	defer mapMutex.Unlock(pluginReq.Name)
+
+	volume, err := getVolumeInfo(providerClient, pluginReq)
+	if err != nil {
+		log.Errorf("unable to reconcile delayed create of volume %s, err %s", pluginReq.Name, err.Error())
+		return
+	}
+	if volume.SerialNumber != serialNumber {
+		// the volume was deleted, and its name reused, since its file system was created
+		log.Infof("volume %s no longer has serial number %s, removing its delayed create intent", pluginReq.Name, serialNumber)
+		volume = &model.Volume{Name: pluginReq.Name, SerialNumber: serialNumber}
+	}
+	log.Infof("completing delayed create of volume %s", pluginReq.Name)
+	completeDelayedCreate(pluginReq, volume)
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDelayedCreateIntents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delayedcreate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statePath := delayedCreateStatePath
+	defer func() { delayedCreateStatePath = statePath }()
+	delayedCreateStatePath = filepath.Join(dir, "state", delayedCreateStateFile)
+
+	// No intent is recorded before the state file exists
+	if intent, err := getDelayedCreateIntent("sn1"); err != nil || intent != nil {
+		t.Fatalf("unexpected intent %+v, err=%v", intent, err)
+	}
+
+	// Intents are persisted and updated per serial number
+	if err = setDelayedCreateIntent("sn1", &delayedCreateIntent{Name: "vol1", FsType: "xfs", State: delayedCreatePending}); err != nil {
+		t.Fatal(err)
+	}
+	if err = setDelayedCreateIntent("sn2", &delayedCreateIntent{Name: "vol2", FsType: "ext4", State: delayedCreatePending}); err != nil {
+		t.Fatal(err)
+	}
+	if err = setDelayedCreateIntent("sn1", &delayedCreateIntent{Name: "vol1", FsType: "xfs", State: delayedCreateCreated}); err != nil {
+		t.Fatal(err)
+	}
+	intent, err := getDelayedCreateIntent("sn1")
+	if err != nil || intent == nil || intent.State != delayedCreateCreated || intent.Name != "vol1" {
+		t.Fatalf("unexpected intent %+v, err=%v", intent, err)
+	}
+
+	// Removing an intent leaves the other intents
+	if err = removeDelayedCreateIntent("sn1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = removeDelayedCreateIntent("sn3"); err != nil {
+		t.Fatal(err)
+	}
+	intents, err := loadDelayedCreateIntents()
+	if err != nil || len(intents) != 1 || intents["sn2"] == nil {
+		t.Errorf("unexpected intents %v, err=%v", intents, err)
+	}
+
+	// A corrupt state file is reported rather than treated as empty
+	if err = ioutil.WriteFile(delayedCreateStatePath, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = getDelayedCreateIntent("sn2"); err == nil {
+		t.Error("expected error reading corrupt state")
+	}
+}
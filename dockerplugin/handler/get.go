@@ -49,7 +49,7 @@ func VolumeDriverGet(w http.ResponseWriter, r *http.Request) {
 	defer mapMutex.Unlock(pluginReq.Name)
 
 	// container provider /VolumeDriver.Get called
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.VolumeDriverGetURI, Payload: &pluginReq, Response: &volumeResp, ResponseError: &volumeResp})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.VolumeDriverGetURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &volumeResp, ResponseError: &volumeResp})
 
 	if err != nil {
 		if volumeResp.Err != "" {
@@ -71,6 +71,7 @@ func VolumeDriverGet(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(vr)
 			return
 		}
+		chapiClient.SetContext(r.Context())
 		var respMount []*model.Mount
 
 		err = chapiClient.GetMounts(&respMount, volumeResp.Volume.SerialNumber)
@@ -81,7 +82,7 @@ func VolumeDriverGet(w http.ResponseWriter, r *http.Request) {
 		}
 		setVolumeStatus(respMount, volumeResp)
 	}
-	log.Debugf("%s: request=(%+v) response=(%+v)", provider.VolumeDriverGetURI, pluginReq, volumeResp.Volume)
+	log.Debugf("%s: request=(%+v) response=(%+v)", provider.VolumeDriverGetURI, pluginReq.Redacted(), volumeResp.Volume.Redacted())
 	json.NewEncoder(w).Encode(volumeResp)
 	return
 }
@@ -105,7 +106,7 @@ func getVolumeInfo(providerClient *connectivity.Client, pluginReq *PluginRequest
 	log.Tracef(">>>>> getVolumeInfo called with %s", pluginReq.Name)
 	defer log.Tracef("<<<<< getVolumeInfo")
 	volResp := &VolumeResponse{}
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.VolumeDriverGetURI, Payload: &pluginReq, Response: &volResp, ResponseError: &volResp})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.VolumeDriverGetURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &volResp, ResponseError: &volResp})
 	if err != nil {
 		if volResp.Err != "" {
 			log.Errorf("getVolumeInfo err %s", volResp.Err)
@@ -126,7 +127,7 @@ func nimbleGetVolumeInfo(providerClient *connectivity.Client, pluginReq *PluginR
 	log.Tracef(">>>>> nimbleGetVolumeInfo called with %s", pluginReq.Name)
 	defer log.Trace("<<<<< nimbleGetVolumeInfo")
 	volResp := &VolumeResponse{}
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.NimbleGetURI, Payload: &pluginReq, Response: &volResp, ResponseError: nil})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.NimbleGetURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &volResp, ResponseError: nil})
 	if err != nil {
 		if volResp.Err != "" {
 			log.Trace(volResp.Err)
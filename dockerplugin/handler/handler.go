@@ -3,6 +3,9 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/hpe-storage/common-host-libs/concurrent"
 	"github.com/hpe-storage/common-host-libs/dockerplugin/plugin"
 	"github.com/hpe-storage/common-host-libs/dockerplugin/provider"
@@ -10,6 +13,7 @@ import (
 	"github.com/hpe-storage/common-host-libs/model"
 	"net/http"
 	"regexp"
+	"time"
 )
 
 const (
@@ -17,7 +21,10 @@ const (
 	destroyOnDetach        = "destroyondetach"
 	manualMode             = "manual"
 	busyMount              = "busymount"
-	defaultChannelCapacity = 30
+	// requestWorkers is the number of mount, and of unmount, requests processed concurrently
+	requestWorkers = 30
+	// requestQueueTimeout is how long a mount or unmount request waits for a request worker
+	requestQueueTimeout = 2 * time.Minute
 	// options
 	delayedCreateOpt = "delayedCreate"
 	volumeDirKey     = "volumeDir"
@@ -28,6 +35,11 @@ var (
 	mapMutex      = concurrent.NewMapMutex()
 	fsModeRegexp  = regexp.MustCompile(fsModePattern)
 	fsOwnerRegexp = regexp.MustCompile(fsOwnerPattern)
+
+	// mountRequestPool and unmountRequestPool process the mount and unmount requests, serialized per
+	// volume with the other volume requests
+	mountRequestPool   = concurrent.NewWorkerPool("mount", requestWorkers, requestQueueTimeout, mapMutex)
+	unmountRequestPool = concurrent.NewWorkerPool("unmount", requestWorkers, requestQueueTimeout, mapMutex)
 )
 
 //PluginRequest : Request routed for the plugin
@@ -40,6 +52,37 @@ type PluginRequest struct {
 	Scope       bool                   `json:"scope,omitempty"`
 	User        *provider.User         `json:"user,omitempty"`
 	ReqID       string                 `json:"req_id,omitempty"`
+	// ctx is the context of the plugin request, whose request correlation ID is forwarded to the
+	// container provider and chapid
+	ctx context.Context
+}
+
+// Redacted returns a copy of the plugin request, safe to log, with the user's access keys and the
+// sensitive options and preferences (see logger.IsSensitive) masked
+func (pluginReq *PluginRequest) Redacted() *PluginRequest {
+	if pluginReq == nil {
+		return nil
+	}
+	redacted := *pluginReq
+	redacted.Opts = redactOptions(pluginReq.Opts)
+	redacted.Preferences = redactOptions(pluginReq.Preferences)
+	redacted.User = pluginReq.User.Redacted()
+	return &redacted
+}
+
+// redactOptions returns a copy of the given options with the sensitive values masked
+func redactOptions(options map[string]interface{}) map[string]interface{} {
+	if options == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(options))
+	for key, value := range options {
+		if log.IsSensitive(key) {
+			value = log.RedactedValue
+		}
+		redacted[key] = value
+	}
+	return redacted
 }
 
 //NimbleDetachRequest : Request to call detach on container provider
@@ -166,14 +209,12 @@ func preparePluginRequest(request *http.Request) (pluginReq *PluginRequest, err
 }
 
 // read off the channel to consume the work when the specific handler is done with work allocated to it
-func unblockChannelHandler(handler, volume string, channel chan string) {
-	log.Tracef("unblockChannelHandler called for %s : %s", handler, volume)
-	select {
-	case <-channel:
-		log.Debugf("returning from %s for %s channel length :%d, channel capacity :%d", handler, volume, len(channel), cap(channel))
-	default:
-		log.Debugf("default handler for %s for %s channel length :%d channel capacity :%d", handler, volume, len(channel), cap(channel))
-	}
+// writeBusyResponse fails a request, with the 503 (service unavailable) status, that was not
+// processed because no request worker was available
+func writeBusyResponse(w http.ResponseWriter, uri, volume string, err error) {
+	log.Errorf("%s for volume %s failed, %s", uri, volume, err.Error())
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(&DriverResponse{Err: fmt.Sprintf("%s for volume %s not processed, %s, retry later", uri, volume, err.Error())})
 }
 
 func populateHostContextAndScope(r *http.Request) (*PluginRequest, error) {
@@ -185,6 +226,9 @@ func populateHostContextAndScope(r *http.Request) (*PluginRequest, error) {
 		return nil, err
 	}
 	pluginReq.Scope = scope
+	// correlate the container provider request with this plugin request
+	pluginReq.ReqID = log.RequestIDFromContext(r.Context())
+	pluginReq.ctx = r.Context()
 	// add host nlt version version
 	pluginReq.Host.Version = plugin.Version
 	log.Trace("host context in Plugin Req: ", pluginReq.Host, " Scope :", pluginReq.Scope)
@@ -52,7 +52,7 @@ func VolumeDriverList(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(resp)
 		return
 	}
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.ListURI, Payload: &pluginReq, Response: &listResp, ResponseError: &errResp})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.ListURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &listResp, ResponseError: &errResp})
 	if err != nil {
 		if errResp != nil {
 			log.Error(errResp.Info)
@@ -0,0 +1,57 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// LoggingResponse : logging configuration response
+type LoggingResponse struct {
+	*log.LogConfig
+	Err string `json:"Err"`
+}
+
+//@APIVersion 1.0.0
+//@Title  report the plugin logging configuration
+//@Description implement the /Plugin.Logging get end point
+//@Accept json
+//@Resource /Plugin.Logging
+//@Success 200 LoggingResponse
+//@Router /Plugin.Logging [get]
+//@BasePath http:/Plugin.Logging
+// PluginGetLogging reports the active log level, format and component level overrides
+func PluginGetLogging(w http.ResponseWriter, r *http.Request) {
+	log.Trace("Plugin.Logging")
+	config := log.GetLogConfig()
+	json.NewEncoder(w).Encode(&LoggingResponse{LogConfig: &config})
+}
+
+//@APIVersion 1.0.0
+//@Title  change the plugin logging configuration
+//@Description implement the /Plugin.Logging put end point
+//@Accept json
+//@Resource /Plugin.Logging
+//@Success 200 LoggingResponse
+//@Router /Plugin.Logging [put]
+//@BasePath http:/Plugin.Logging
+// PluginSetLogging changes the active log level, format and component level overrides without
+// restarting the plugin.  Settings omitted from the request are left unchanged.
+func PluginSetLogging(w http.ResponseWriter, r *http.Request) {
+	log.Trace("Plugin.Logging")
+	var config log.LogConfig
+	err := json.NewDecoder(r.Body).Decode(&config)
+	defer r.Body.Close()
+	if err == nil {
+		err = log.SetLogConfig(config)
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(&LoggingResponse{Err: err.Error()})
+		return
+	}
+	config = log.GetLogConfig()
+	json.NewEncoder(w).Encode(&LoggingResponse{LogConfig: &config})
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -26,10 +25,6 @@ const (
 	fsModePattern  = "^[0-7]{1,4}$"
 )
 
-var (
-	mountRequestsChan = make(chan string, defaultChannelCapacity)
-)
-
 //@APIVersion 1.0.0
 //@Title  implement the Nimble Volume Driver Mount for docker
 //@Description implement the /VolumeDriver.Mount Docker end point
@@ -57,8 +52,7 @@ func VolumeDriverMount(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(mr)
 		return
 	}
-	var respMount []*model.Mount
-	volResp := &VolumeResponse{}
+	chapiClient.SetContext(r.Context())
 
 	// Add user credentials for request
 	user, err := provider.GetProviderAccessKeys()
@@ -103,17 +97,26 @@ func VolumeDriverMount(w http.ResponseWriter, r *http.Request) {
 	//2. this method does poll to container provider to check if other hosts are attached until mountConflictDelay
 	processMountConflictDelay(pluginReq.Name, providerClient, pluginReq, plugin.MountConflictDelay)
 
-	mapMutex.Lock(pluginReq.Name)
-	log.Debugf("taken lock for volume %s in Mount", pluginReq.Name)
-	defer mapMutex.Unlock(pluginReq.Name)
+	// mount the volume on a request worker, once the other requests for the volume are done
+	err = mountRequestPool.Run(pluginReq.Name, func() {
+		log.Debugf("taken lock for volume %s in Mount", pluginReq.Name)
+		mountVolume(w, pluginReq, providerClient, chapiClient)
+	})
+	if err != nil {
+		writeBusyResponse(w, provider.MountURI, pluginReq.Name, err)
+	}
+}
 
-	mountRequestsChan <- pluginReq.Name
-	log.Tracef("taken channel for volume %s in mount with channel length :%d channel capacity :%d", pluginReq.Name, len(mountRequestsChan), cap(mountRequestsChan))
-	defer unblockChannelHandler("mount", pluginReq.Name, mountRequestsChan)
+// mountVolume attaches and mounts the volume on the host, steps 3 to 5 of VolumeDriverMount
+// nolint : gocyclo exceeded
+func mountVolume(w http.ResponseWriter, pluginReq *PluginRequest, providerClient *connectivity.Client, chapiClient *chapi.Client) {
+	var mr MountResponse
+	var respMount []*model.Mount
+	volResp := &VolumeResponse{}
 
 	//3. container-provider /VolumeDriver.Mount called
-	log.Debugf("/VolumeDriver.Mount for volume %s request=%+v", pluginReq.Name, pluginReq)
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.MountURI, Payload: &pluginReq, Response: &volResp, ResponseError: &volResp})
+	log.Debugf("/VolumeDriver.Mount for volume %s request=%+v", pluginReq.Name, pluginReq.Redacted())
+	_, err := providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.MountURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &volResp, ResponseError: &volResp})
 	log.Debugf("/VolumeDriver.Mount for volume %s response=%+v", pluginReq.Name, volResp)
 	if volResp.Err != "" {
 		if strings.Contains(volResp.Err, busyMount) {
@@ -131,7 +134,7 @@ func VolumeDriverMount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	volume := volResp.Volume
-	log.Tracef("retrieved volume response from container provider for volume: %+v", volume)
+	log.Tracef("retrieved volume response from container provider for volume: %+v", volume.Redacted())
 
 	//4.  Get mounts from host
 	err = chapiClient.GetMounts(&respMount, volume.SerialNumber)
@@ -140,7 +143,17 @@ func VolumeDriverMount(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(mr)
 		return
 	}
-	mountPoint := plugin.MountDir + volume.Name
+	mountPoint, err := plugin.GetMountPath(volume)
+	if err != nil {
+		mr = MountResponse{Err: err.Error()}
+		json.NewEncoder(w).Encode(mr)
+		return
+	}
+	if err = plugin.ClaimMountPath(mountPoint, volume.Name); err != nil {
+		mr = MountResponse{Err: err.Error()}
+		json.NewEncoder(w).Encode(mr)
+		return
+	}
 	// change the connection mode to manual for docker
 	volume.ConnectionMode = manualMode
 	//5. Attach and Mount the volume
@@ -157,45 +170,21 @@ func VolumeDriverMount(w http.ResponseWriter, r *http.Request) {
 			log.Errorf("mount response error %s", mr.Err)
 			err = cleanupMountFailure(chapiClient, volume, mountPoint, pluginReq)
 			if err != nil {
-				log.Errorf("unable to cleanup device for volume %v and mounpoint %s. err :(%s)", volume, mountPoint, err.Error())
+				log.Errorf("unable to cleanup device for volume %v and mounpoint %s. err :(%s)", volume.Redacted(), mountPoint, err.Error())
 			}
+			plugin.ReleaseMountPath(mountPoint)
 		}
 	}
 	//always try to cleanup the filesystem metadata on the volume when there is no error on mount
 	if mr.Err == "" {
-		if _, ok := volume.Status[delayedCreateOpt]; ok {
-			err := removeDelayedCreateMetadata(pluginReq, volume)
-			// if the metadata update failed don't treat this as an error as next node will take care of it
-			if err != nil {
-				log.Tracef(err.Error())
-			}
-		}
+		completeDelayedCreate(pluginReq, volume)
 	}
 
-	log.Infof("%s: request=(%+v) response=(%+v)", provider.MountURI, pluginReq, mr)
+	log.Infof("%s: request=(%+v) response=(%+v)", provider.MountURI, pluginReq.Redacted(), mr)
 	json.NewEncoder(w).Encode(mr)
 	return
 }
 
-func getStringSliceParam(paramName string, opts map[string]interface{}) (strSlice []string, err error) {
-	prefix := "getStringParam"
-	if _, ok := opts[paramName]; !ok {
-		return nil, fmt.Errorf("%s: param name %s key not found in request", prefix, paramName)
-	}
-
-	switch value := opts[paramName].(type) {
-	case []interface{}:
-		for _, d := range value {
-			strSlice = append(strSlice, strings.TrimSpace(fmt.Sprintf("%v", d)))
-		}
-		return strSlice, nil
-	case string:
-		return []string{strings.TrimSpace(value)}, nil
-	default:
-		return nil, fmt.Errorf("param name:%v is not a slice.  value:%v kind:%s type:%s", paramName, opts[paramName], reflect.TypeOf(opts[paramName]).Kind(), reflect.TypeOf(opts[paramName]))
-	}
-}
-
 func isIPAddress(host string) bool {
 	parts := strings.Split(host, ".")
 
@@ -226,16 +215,17 @@ func filterHostNetworks(pluginReq *PluginRequest) error {
 		log.Errorf("%s failed to add create options from config file using defaults", err.Error())
 	}
 
-	// verify if interfaces are specified by user
-	if _, ok := pluginReq.Opts["initiators"]; !ok {
-		return errors.New("initiators are not specified in the volume-driver.json file for mount request")
-	}
-
-	initiators, err := getStringSliceParam("initiators", pluginReq.Opts)
+	options, err := parseOptions(mountOptionSchema, pluginReq.Opts)
 	if err != nil {
 		return err
 	}
 
+	// verify if interfaces are specified by user
+	initiators := options.getStringSlice("initiators")
+	if initiators == nil {
+		return errors.New("initiators are not specified in the volume-driver.json file for mount request")
+	}
+
 	index := 0
 	for _, initiator := range initiators {
 		// ignore unwanted networks based on user input
@@ -256,14 +246,27 @@ func filterHostNetworks(pluginReq *PluginRequest) error {
 
 // handleDelayedCreateAndMountFilesystem the exception workflow on a failed mount to create a filesystem and mount it if the create fs metadata is present
 func handleDelayedCreateAndMountFilesystem(chapiClient *chapi.Client, volume *model.Volume, mountPoint string) (mr MountResponse) {
-	log.Tracef(">>>>> handleDelayedCreateAndMountFilesystem called with mountPoint %s and volume (%+v) ", mountPoint, volume)
-	defer log.Tracef("<<<<< handleDelayedCreateAndMountFilesystem for volume %+v", volume)
+	log.Tracef(">>>>> handleDelayedCreateAndMountFilesystem called with mountPoint %s and volume (%+v) ", mountPoint, volume.Redacted())
+	defer log.Tracef("<<<<< handleDelayedCreateAndMountFilesystem for volume %+v", volume.Redacted())
 	fsType, ok := volume.Status[model.FsCreateOpt]
 	if !ok {
 		// fail safe. the filesystem should always be present
 		return MountResponse{Err: "no filesystem or filesystem options present"}
 	}
 
+	// never create the filesystem again once created on this host (see delayedcreate.go)
+	intent, err := getDelayedCreateIntent(volume.SerialNumber)
+	if err != nil {
+		return MountResponse{Err: err.Error()}
+	}
+	if intent != nil && intent.State == delayedCreateCreated {
+		return MountResponse{Err: fmt.Sprintf("filesystem %s was already created on volume %s, not creating it again", intent.FsType, volume.Name)}
+	}
+	err = setDelayedCreateIntent(volume.SerialNumber, &delayedCreateIntent{Name: volume.Name, FsType: fmt.Sprintf("%v", fsType), State: delayedCreatePending})
+	if err != nil {
+		return MountResponse{Err: err.Error()}
+	}
+
 	// if there is an error create filesystem and mount it from fsType retrieved
 	log.Tracef("initiating create filesystem %s for volume (%s)", fsType, volume.Name)
 	volume.MountPoint = mountPoint
@@ -277,13 +280,15 @@ func handleDelayedCreateAndMountFilesystem(chapiClient *chapi.Client, volume *mo
 		return MountResponse{Err: fmt.Errorf("no device found for volume %s to create filesystem %s", volume.Name, fsType).Error()}
 	}
 	//2. create filesystem
-	//make sure volume.Mountpoint is populated
-	volume.MountPoint = plugin.MountDir + volume.Name
 	err = chapiClient.SetupFilesystemAndPermissions(devices[0], volume, fsType.(string))
 	if err != nil {
 		log.Tracef(err.Error())
 		return MountResponse{Err: err.Error()}
 	}
+	err = setDelayedCreateIntent(volume.SerialNumber, &delayedCreateIntent{Name: volume.Name, FsType: fmt.Sprintf("%v", fsType), State: delayedCreateCreated})
+	if err != nil {
+		log.Errorf("unable to record filesystem creation on volume %s, err %s", volume.Name, err.Error())
+	}
 	// the filesystem is already mounted so just return from here
 	return MountResponse{MountPoint: mountPoint, Err: ""}
 }
@@ -295,7 +300,7 @@ func removeMountConflictMetadata(containerProviderClient *connectivity.Client, p
 	//reset mountConflict to 0
 	pluginReq.Opts["mountConflictDelay"] = "0"
 	var cr *CreateResponse
-	_, err := containerProviderClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UpdateURI, Payload: &pluginReq, Response: &cr, ResponseError: &cr})
+	_, err := containerProviderClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UpdateURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &cr, ResponseError: &cr})
 	if err != nil {
 		err = fmt.Errorf("unable to remove mountConflictDelay from volume metadata (%s)", err.Error())
 		return err
@@ -317,7 +322,7 @@ func removeDelayedCreateMetadata(pluginReq *PluginRequest, volume *model.Volume)
 	if err != nil {
 		return err
 	}
-	_, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UpdateURI, Payload: &pluginReq, Response: &cr, ResponseError: nil})
+	_, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UpdateURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &cr, ResponseError: nil})
 	if err != nil {
 		err = fmt.Errorf("unable to remove delayedCreate from volume metadata (%s)", err.Error())
 		return err
@@ -336,7 +341,7 @@ func mountVolumeOnHost(chapiClient *chapi.Client, respMount []*model.Mount, host
 	}
 	log.Tracef("No mounts found for volume %s on host side, perform mount on the host", volume.Name)
 
-	err := chapiClient.AttachAndMountDevice(volume, plugin.MountDir+volume.Name)
+	err := chapiClient.AttachAndMountDevice(volume, mountPoint)
 	if err != nil {
 		return MountResponse{Err: err.Error()}
 	}
@@ -354,12 +359,13 @@ func cleanupMountFailure(chapiClient *chapi.Client, volume *model.Volume, mountP
 		// continue as we still need to remove mount-id's added to volume metadata etc
 	}
 
-	//2. delete the mount point
+	//2. delete the mount point, and its parent directories created for the mount path template
 	log.Tracef("removing the mount point %s", mountPoint)
 	err = os.RemoveAll(mountPoint)
 	if err != nil {
 		return err
 	}
+	plugin.RemoveMountPath(mountPoint)
 
 	//3. peform cleanup on the container provider
 	//get containerProviderClient
@@ -369,7 +375,7 @@ func cleanupMountFailure(chapiClient *chapi.Client, volume *model.Volume, mountP
 	}
 	// container-provider /VolumeDriver.Unmount called
 	volResp := &VolumeUnmountResponse{}
-	_, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UnmountURI, Payload: &pluginReq, Response: &volResp, ResponseError: nil})
+	_, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UnmountURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &volResp, ResponseError: nil})
 	if err != nil {
 		return err
 	}
@@ -402,52 +408,6 @@ func cleanupMountFailure(chapiClient *chapi.Client, volume *model.Volume, mountP
 	return nil
 }
 
-func getFileSystemTypeFromRequest(pluginReq *PluginRequest) string {
-	log.Trace("retrieving filesystemType from request", pluginReq.Opts)
-	fsType, found := pluginReq.Opts[model.FsCreateOpt].(string)
-	if !found || strings.TrimSpace(fsType) == "" {
-		fsType = "xfs"
-	}
-	return fsType
-}
-
-func getFileSystemModeAndOwnerFromRequest(pluginReq *PluginRequest) (string, string, error) {
-	log.Trace("retrieving filesystemType from request", pluginReq.Opts)
-	fsMode, found := pluginReq.Opts[model.FsModeOpt].(string)
-	if !found || strings.TrimSpace(fsMode) == "" {
-		fsMode = ""
-	}
-	if fsMode != "" && !fsModeRegexp.MatchString(fsMode) {
-		// invalid fsMode
-		return "", "", fmt.Errorf("invalid fsMode (%s) specified for filesystem", fsMode)
-	}
-	fsOwner, found := pluginReq.Opts[model.FsOwnerOpt].(string)
-	if !found || strings.TrimSpace(fsOwner) == "" {
-		fsOwner = ""
-	}
-	if fsOwner != "" && !fsOwnerRegexp.MatchString(fsOwner) {
-		// invalid fsOwner
-		return "", "", fmt.Errorf("invalid fsOwner (%s) specified for filesystem", fsOwner)
-	}
-	log.Tracef("fsMode (%s) fsOwner (%s)", fsMode, fsOwner)
-	return fsMode, fsOwner, nil
-}
-
-func isValidFilesystem(pluginReq *PluginRequest) bool {
-	log.Tracef("isValidFilesystem called")
-	val, ok := pluginReq.Opts["filesystem"]
-	if !ok {
-		// no filesystem passed in the cli return true as it will use the defaults
-		return true
-	}
-	for _, v := range plugin.SupportedFileSystems {
-		if v == strings.ToLower(val.(string)) {
-			return true
-		}
-	}
-	return false
-}
-
 // Unmount stale mounts if all the below conditions are met
 // 1. mount point is found for the volume
 // 2. device is still attached.
@@ -463,7 +423,7 @@ func cleanupStaleMounts(containerProviderClient *connectivity.Client, chapiClien
 	if volumeInfo == nil {
 		return fmt.Errorf("unable to find volume %s, failing request", pluginReq.Name)
 	}
-	log.Tracef("volumeInfo is %+v", volumeInfo)
+	log.Tracef("volumeInfo is %+v", volumeInfo.Redacted())
 	// get the mounts of the volumes's serial number
 	_ = chapiClient.GetMounts(&respMount, volumeInfo.SerialNumber)
 	if respMount == nil || len(respMount) == 0 {
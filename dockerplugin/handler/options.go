@@ -0,0 +1,264 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// OPTIONS SCHEMA
+//
+//		The create, update (QoS) and mount options handled by the plugin itself are described by
+//		an options schema (name, type, default, validator and deprecated aliases).  parseOptions
+//		parses pluginReq.Opts against a schema:
+//
+//		- Deprecated aliases are renamed, in pluginReq.Opts, to the current option name
+//		- Values are converted to the option type (e.g. "true" for a bool option)
+//		- Missing options take their default value
+//		- All type and validation errors are returned together as a single error
+//
+//		Options not in the schema are left for the container provider to validate.  The parsed
+//		values are returned separately so that pluginReq.Opts is forwarded to the provider as is.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/dockerplugin/plugin"
+	"github.com/hpe-storage/common-host-libs/dockerplugin/provider"
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/model"
+)
+
+// Option types
+const (
+	optionTypeString      = "string"
+	optionTypeBool        = "bool"
+	optionTypeInt         = "int"
+	optionTypeStringSlice = "[]string"
+)
+
+// OptionSpec describes a single plugin option
+type OptionSpec struct {
+	Name        string                  `json:"name"`
+	Type        string                  `json:"type"`
+	Description string                  `json:"description,omitempty"`
+	Default     interface{}             `json:"default,omitempty"`
+	Deprecated  []string                `json:"deprecated_aliases,omitempty"`
+	Validator   func(interface{}) error `json:"-"`
+}
+
+// OptionsResponse : options listing response
+type OptionsResponse struct {
+	Create []*OptionSpec `json:"create"`
+	Mount  []*OptionSpec `json:"mount"`
+	Err    string        `json:"Err"`
+}
+
+// parsedOptions are the typed option values, keyed by option name
+type parsedOptions map[string]interface{}
+
+var (
+	// createOptionSchema describes the create options validated by the plugin
+	createOptionSchema = append([]*OptionSpec{
+		{Name: model.FsCreateOpt, Type: optionTypeString, Default: "xfs", Description: "Filesystem to create on the volume", Validator: validateFilesystem},
+		{Name: model.FsModeOpt, Type: optionTypeString, Description: "Octal permissions of the filesystem root", Validator: validatePattern(fsModeRegexp.MatchString, fsModePattern)},
+		{Name: model.FsOwnerOpt, Type: optionTypeString, Description: "User and group ID (uid:gid) owning the filesystem root", Validator: validatePattern(fsOwnerRegexp.MatchString, fsOwnerPattern)},
+		{Name: "sizeInGiB", Type: optionTypeInt, Deprecated: []string{"size"}, Description: "Volume size in GiB", Validator: validatePositiveInt},
+		{Name: "destroyOnRm", Type: optionTypeBool, Description: "Destroy the volume on the array when it is removed"},
+		{Name: "cloneOf", Type: optionTypeString, Description: "Name of the volume to clone"},
+		{Name: "importVol", Type: optionTypeString, Description: "Name of the array volume to import"},
+		{Name: "importVolAsClone", Type: optionTypeString, Description: "Name of the array volume to import as a clone"},
+	}, qosOptionSchema...)
+
+	// qosOptionSchema describes the QoS options, accepted on create and update, validated by the
+	// plugin and against the provider QoS capabilities
+	qosOptionSchema = []*OptionSpec{
+		{Name: provider.QosLimitIopsOpt, Type: optionTypeInt, Description: "IOPS limit of the volume, -1 for unlimited", Validator: validateQosLimit},
+		{Name: provider.QosLimitMbpsOpt, Type: optionTypeInt, Description: "Throughput limit of the volume in MB/s, -1 for unlimited", Validator: validateQosLimit},
+		{Name: provider.QosPerfPolicyOpt, Type: optionTypeString, Description: "Name of the performance policy of the volume"},
+	}
+
+	// mountOptionSchema describes the mount options, from the driver configuration file, used by
+	// the plugin
+	mountOptionSchema = []*OptionSpec{
+		{Name: "initiators", Type: optionTypeStringSlice, Description: "Network interface names or IP addresses used to access the volume"},
+		{Name: plugin.MountConflictDelayKey, Type: optionTypeInt, Description: "Seconds to wait for a volume mounted on another host to be unmounted", Validator: validateNonNegativeInt},
+	}
+)
+
+//@APIVersion 1.0.0
+//@Title  list the options supported by the plugin
+//@Description implement the /VolumeDriver.Options end point
+//@Accept json
+//@Resource /VolumeDriver.Options
+//@Success 200 OptionsResponse
+//@Router /VolumeDriver.Options [get]
+//@BasePath http:/VolumeDriver.Options
+// VolumeDriverOptions lists the create and mount options validated by the plugin
+func VolumeDriverOptions(w http.ResponseWriter, r *http.Request) {
+	log.Trace("VolumeDriver.Options")
+	json.NewEncoder(w).Encode(&OptionsResponse{Create: createOptionSchema, Mount: mountOptionSchema})
+}
+
+// parseOptions parses the given options against the schema.  Deprecated aliases are renamed in
+// opts; all other values are returned, converted to their option type, in parsedOptions.
+func parseOptions(schema []*OptionSpec, opts map[string]interface{}) (parsedOptions, error) {
+	renameDeprecatedOptions(schema, opts)
+
+	parsed := make(parsedOptions)
+	var errs []string
+	for _, spec := range schema {
+		value, found := opts[spec.Name]
+		if !found {
+			if spec.Default != nil {
+				parsed[spec.Name] = spec.Default
+			}
+			continue
+		}
+
+		typedValue, err := convertOption(spec.Type, value)
+		if err == nil && spec.Validator != nil {
+			err = spec.Validator(typedValue)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", spec.Name, err.Error()))
+			continue
+		}
+		parsed[spec.Name] = typedValue
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("invalid options (%s)", strings.Join(errs, "; "))
+	}
+	return parsed, nil
+}
+
+// renameDeprecatedOptions renames, in opts, the deprecated aliases to the current option name.  If
+// both are present, the current option name wins.  Aliases must be renamed before the driver
+// configuration defaults are merged into opts, so that a default never overrides an alias.
+func renameDeprecatedOptions(schema []*OptionSpec, opts map[string]interface{}) {
+	for _, spec := range schema {
+		_, found := opts[spec.Name]
+		for _, alias := range spec.Deprecated {
+			aliasValue, ok := opts[alias]
+			if !ok {
+				continue
+			}
+			log.Warnf("option %s is deprecated, use %s instead", alias, spec.Name)
+			delete(opts, alias)
+			if !found {
+				found = true
+				opts[spec.Name] = aliasValue
+			}
+		}
+	}
+}
+
+// convertOption converts the given value to the option type
+func convertOption(optionType string, value interface{}) (interface{}, error) {
+	switch optionType {
+	case optionTypeString:
+		if s, ok := value.(string); ok {
+			return strings.TrimSpace(s), nil
+		}
+	case optionTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			// A flag option without a value (e.g. "-o destroyOnRm") is enabled
+			if strings.TrimSpace(v) == "" {
+				return true, nil
+			}
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+	case optionTypeInt:
+		switch v := value.(type) {
+		case float64:
+			// JSON numbers (e.g. from the driver configuration file)
+			if v == float64(int64(v)) {
+				return int64(v), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case optionTypeStringSlice:
+		switch v := value.(type) {
+		case []interface{}:
+			var strSlice []string
+			for _, d := range v {
+				strSlice = append(strSlice, strings.TrimSpace(fmt.Sprintf("%v", d)))
+			}
+			return strSlice, nil
+		case string:
+			return []string{strings.TrimSpace(v)}, nil
+		}
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", value, optionType)
+}
+
+// getString returns the named string option, or an empty string if not present
+func (parsed parsedOptions) getString(name string) string {
+	s, _ := parsed[name].(string)
+	return s
+}
+
+// getStringSlice returns the named string slice option, or nil if not present
+func (parsed parsedOptions) getStringSlice(name string) []string {
+	strSlice, _ := parsed[name].([]string)
+	return strSlice
+}
+
+// validateFilesystem verifies that the filesystem type is supported by the plugin
+func validateFilesystem(value interface{}) error {
+	for _, fsType := range plugin.SupportedFileSystems {
+		if fsType == strings.ToLower(value.(string)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid filesystem type(%s), please enter one of the following options (%s)", value, strings.Join(plugin.SupportedFileSystems, " "))
+}
+
+// validatePattern returns a validator that verifies a non-empty string matches the given pattern
+func validatePattern(match func(string) bool, pattern string) func(interface{}) error {
+	return func(value interface{}) error {
+		if s := value.(string); s != "" && !match(s) {
+			return fmt.Errorf("%s does not match %s", s, pattern)
+		}
+		return nil
+	}
+}
+
+// validatePositiveInt verifies that the integer is greater than zero
+func validatePositiveInt(value interface{}) error {
+	if value.(int64) <= 0 {
+		return fmt.Errorf("%v must be greater than 0", value)
+	}
+	return nil
+}
+
+// validateNonNegativeInt verifies that the integer is not negative
+func validateNonNegativeInt(value interface{}) error {
+	if value.(int64) < 0 {
+		return fmt.Errorf("%v must not be negative", value)
+	}
+	return nil
+}
+
+// validateQosLimit verifies that the QoS limit is positive or -1 (unlimited)
+func validateQosLimit(value interface{}) error {
+	if limit := value.(int64); limit <= 0 && limit != provider.QosUnlimited {
+		return fmt.Errorf("%v must be greater than 0, or %d for unlimited", value, provider.QosUnlimited)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenameDeprecatedOptions(t *testing.T) {
+	// The deprecated alias is renamed
+	opts := map[string]interface{}{"size": "10"}
+	renameDeprecatedOptions(createOptionSchema, opts)
+	if expected := map[string]interface{}{"sizeInGiB": "10"}; !reflect.DeepEqual(opts, expected) {
+		t.Errorf("expected %v, got %v", expected, opts)
+	}
+
+	// The current option name wins over the alias
+	opts = map[string]interface{}{"size": "10", "sizeInGiB": "20"}
+	renameDeprecatedOptions(createOptionSchema, opts)
+	if expected := map[string]interface{}{"sizeInGiB": "20"}; !reflect.DeepEqual(opts, expected) {
+		t.Errorf("expected %v, got %v", expected, opts)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts := map[string]interface{}{
+		"size":        "10",
+		"destroyOnRm": "",
+		"fsMode":      "0755",
+		"limitIOPS":   float64(-1),
+		"perfPolicy":  " default ",
+		"unknown":     "value",
+	}
+	parsed, err := parseOptions(createOptionSchema, opts)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := parsedOptions{
+		"filesystem":  "xfs",
+		"fsMode":      "0755",
+		"sizeInGiB":   int64(10),
+		"destroyOnRm": true,
+		"limitIOPS":   int64(-1),
+		"perfPolicy":  "default",
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("expected %v, got %v", expected, parsed)
+	}
+	// Options are forwarded as is, except for the renamed aliases
+	if _, ok := opts["size"]; ok || opts["sizeInGiB"] != "10" || opts["unknown"] != "value" {
+		t.Errorf("unexpected forwarded options %v", opts)
+	}
+
+	// All errors are returned together
+	opts = map[string]interface{}{
+		"filesystem":  "fat32",
+		"sizeInGiB":   "0",
+		"destroyOnRm": "maybe",
+		"limitMBPS":   "-2",
+		"fsOwner":     "root",
+	}
+	_, err = parseOptions(createOptionSchema, opts)
+	if err == nil {
+		t.Fatal("expected invalid options to be rejected")
+	}
+	for name := range opts {
+		if !strings.Contains(err.Error(), name+":") {
+			t.Errorf("expected an error for %v, got %v", name, err)
+		}
+	}
+
+	// Mount options from the driver configuration file
+	parsed, err = parseOptions(mountOptionSchema, map[string]interface{}{"initiators": []interface{}{"eth1", " eth2"}, "mountConflictDelay": float64(30)})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if initiators := parsed.getStringSlice("initiators"); !reflect.DeepEqual(initiators, []string{"eth1", "eth2"}) {
+		t.Errorf("unexpected initiators %v", initiators)
+	}
+}
@@ -43,6 +43,7 @@ func VolumeDriverPath(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(mr)
 		return
 	}
+	chapiClient.SetContext(r.Context())
 	var respMount []*model.Mount
 
 	// Add user credentials for request
@@ -65,7 +66,7 @@ func VolumeDriverPath(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	//1. container-provider /Nimble.Get called
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.NimbleGetURI, Payload: &pluginReq, Response: &volResp, ResponseError: nil})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.NimbleGetURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &volResp, ResponseError: nil})
 	if volResp.Err != "" {
 		mr = MountResponse{Err: volResp.Err}
 		json.NewEncoder(w).Encode(mr)
@@ -79,7 +80,12 @@ func VolumeDriverPath(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(mr)
 		return
 	}
-	mountPoint := plugin.MountDir + volResp.Volume.Name
+	mountPoint, err := plugin.GetMountPath(volResp.Volume)
+	if err != nil {
+		mr = MountResponse{Err: err.Error()}
+		json.NewEncoder(w).Encode(mr)
+		return
+	}
 	if respMount != nil {
 		for _, mts := range respMount {
 			if mts.Mountpoint == mountPoint {
@@ -90,7 +96,7 @@ func VolumeDriverPath(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
-	log.Infof("%s: request=(%+v) response=(%+v)", "VolumeDriver.Path", pluginReq, mr)
+	log.Infof("%s: request=(%+v) response=(%+v)", "VolumeDriver.Path", pluginReq.Redacted(), mr)
 	json.NewEncoder(w).Encode(mr)
 	return
 }
@@ -0,0 +1,180 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hpe-storage/common-host-libs/chapi"
+	"github.com/hpe-storage/common-host-libs/connectivity"
+	"github.com/hpe-storage/common-host-libs/dockerplugin/plugin"
+	"github.com/hpe-storage/common-host-libs/dockerplugin/provider"
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/model"
+)
+
+// reconcileAction is what is done to bring a volume mount in line with the container provider
+type reconcileAction int
+
+const (
+	reconcileNone    reconcileAction = iota // Volume is neither in use on this host nor mounted
+	reconcileClaim                          // Volume is in use and mounted, claim its mount path again
+	reconcileMount                          // Volume is in use but not mounted, mount it again
+	reconcileUnmount                        // Volume is mounted but no longer in use, unmount it
+	reconcileSkip                           // Volume in use cannot be matched with this host
+)
+
+// ReconcileMounts compares the volumes the container provider reports as in use on this host with
+// the volumes mounted on it, when the plugin starts, rather than waiting for the next mount or
+// unmount request to find them out of sync.  Volumes in use by containers on this host but not
+// mounted are mounted again, volumes mounted by the plugin but no longer in use on this host are
+// unmounted and their devices removed, and the mount paths of the other mounted volumes are claimed
+// again.  Delayed creates whose volume metadata was not updated are then completed (see
+// delayedcreate.go).  Each volume is reconciled under its volume lock.  An error is only returned
+// if the volumes could not be listed; volumes that fail to reconcile are logged and left as is.
+func ReconcileMounts() error {
+	log.Trace(">>>>> ReconcileMounts")
+	defer log.Trace("<<<<< ReconcileMounts")
+
+	chapiClient, err := chapi.NewChapiClient()
+	if err != nil {
+		return fmt.Errorf("unable to setup the chapi client %s", err.Error())
+	}
+	providerClient, err := provider.GetProviderClient()
+	if err != nil {
+		return fmt.Errorf("unable to setup the container-provider client %s", err.Error())
+	}
+	hostContext, err := buildHostContext(nil)
+	if err != nil {
+		return err
+	}
+	pluginReq := &PluginRequest{Host: hostContext, Scope: plugin.IsLocalScopeDriver(), Preferences: make(map[string]interface{})}
+	hostContext.Version = plugin.Version
+	if pluginReq.User, err = provider.GetProviderAccessKeys(); err != nil {
+		return err
+	}
+
+	listResp := &ListResponse{}
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.ListURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &listResp, ResponseError: &listResp})
+	if listResp.Err != "" {
+		return fmt.Errorf("unable to list volumes %s", listResp.Err)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to list volumes %s", err.Error())
+	}
+
+	var failed []string
+	for _, listed := range listResp.Volumes {
+		volumeReq := *pluginReq
+		volumeReq.Name = listed.Name
+		if err = reconcileVolume(providerClient, chapiClient, &volumeReq); err != nil {
+			log.Errorf("unable to reconcile volume %s, err %s", listed.Name, err.Error())
+			failed = append(failed, listed.Name)
+		}
+	}
+	log.Infof("reconciled %d volumes, %d failed %v", len(listResp.Volumes), len(failed), failed)
+
+	// complete the delayed creates interrupted before their volume metadata was updated
+	reconcileDelayedCreates(providerClient, pluginReq)
+	return nil
+}
+
+// reconcileVolume brings the mount of the requested volume in line with the container provider
+func reconcileVolume(providerClient *connectivity.Client, chapiClient *chapi.Client, pluginReq *PluginRequest) error {
+	mapMutex.Lock(pluginReq.Name)
+	defer mapMutex.Unlock(pluginReq.Name)
+
+	volume, err := getVolumeInfo(providerClient, pluginReq)
+	if err != nil {
+		return err
+	}
+	var respMount []*model.Mount
+	err = chapiClient.GetMounts(&respMount, volume.SerialNumber)
+	if err != nil && !(strings.Contains(err.Error(), "object was not found")) {
+		return err
+	}
+	mountPoint, err := plugin.GetMountPath(volume)
+	if err != nil {
+		return err
+	}
+
+	inUse := volume.InUse && (isCurrentHostAttachedIscsi(volume, pluginReq) || isCurrentHostAttachedFC(volume, pluginReq))
+	knownHost := pluginReq.Host != nil && len(pluginReq.Host.Initiators) != 0
+	switch getReconcileAction(respMount, mountPoint, inUse, volume.InUse && !knownHost) {
+	case reconcileClaim:
+		log.Debugf("volume %s is mounted on %s", volume.Name, mountPoint)
+		return plugin.ClaimMountPath(mountPoint, volume.Name)
+	case reconcileMount:
+		log.Infof("volume %s is in use on this host but not mounted, mounting it on %s", volume.Name, mountPoint)
+		if err = plugin.ClaimMountPath(mountPoint, volume.Name); err != nil {
+			return err
+		}
+		volume.ConnectionMode = manualMode
+		if err = chapiClient.AttachAndMountDevice(volume, mountPoint); err != nil {
+			plugin.ReleaseMountPath(mountPoint)
+			return err
+		}
+	case reconcileUnmount:
+		log.Infof("volume %s is mounted but no longer in use on this host, unmounting it", volume.Name)
+		return unmountUnusedVolume(chapiClient, volume, mountPoint, pluginReq)
+	case reconcileSkip:
+		log.Infof("volume %s is in use but the host initiators are unknown, skipping it", volume.Name)
+	}
+	return nil
+}
+
+// getReconcileAction returns what is done to bring the volume mounts (on this host) in line with
+// the container provider.  Only mounts under the plugin mount directory are unmounted.
+func getReconcileAction(respMount []*model.Mount, mountPoint string, inUse bool, unknownHost bool) reconcileAction {
+	mounted, pluginMounted := false, false
+	for _, mount := range respMount {
+		if mount.Mountpoint == mountPoint && mount.Device != nil && mount.Device.State != model.FailedState.String() && mount.Device.State != model.LunIDConflict.String() {
+			mounted = true
+		}
+		if plugin.MountDir != "" && strings.HasPrefix(mount.Mountpoint, plugin.MountDir) {
+			pluginMounted = true
+		}
+	}
+	switch {
+	case inUse && mounted:
+		return reconcileClaim
+	case inUse:
+		return reconcileMount
+	case unknownHost:
+		// without the host initiators, a volume in use on this host looks in use on another host
+		return reconcileSkip
+	case pluginMounted:
+		return reconcileUnmount
+	}
+	return reconcileNone
+}
+
+// unmountUnusedVolume unmounts the volume no longer in use on this host, detaches it and removes
+// its device, the way VolumeDriverUnmount does
+func unmountUnusedVolume(chapiClient *chapi.Client, volume *model.Volume, mountPoint string, pluginReq *PluginRequest) error {
+	err := chapiClient.UnmountDevice(volume)
+	if err != nil {
+		return err
+	}
+	plugin.ReleaseMountPath(mountPoint)
+	if err = plugin.RemoveMountPath(mountPoint); err != nil {
+		log.Errorf("unable to remove mount point %s, err %s", mountPoint, err.Error())
+	}
+
+	device, _ := chapiClient.GetDeviceFromVolume(volume)
+	if device != nil {
+		if err = chapiClient.OfflineDevice(device); err != nil {
+			log.Errorf("unable to offline device %s, err %s", device.MpathName, err.Error())
+		}
+	}
+	if err = nimbleDetach(volume, pluginReq); err != nil {
+		log.Errorf("unable to detach volume %s, err %s", volume.Name, err.Error())
+	}
+	if device != nil {
+		if err = chapiClient.DeleteDevice(device); err != nil {
+			log.Errorf("unable to delete device %s, err %s", device.MpathName, err.Error())
+		}
+	}
+	return nil
+}
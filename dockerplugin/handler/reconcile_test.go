@@ -0,0 +1,41 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/dockerplugin/plugin"
+	"github.com/hpe-storage/common-host-libs/model"
+)
+
+func TestGetReconcileAction(t *testing.T) {
+	mountDir := plugin.MountDir
+	defer func() { plugin.MountDir = mountDir }()
+	plugin.MountDir = "/var/lib/kubelet/plugins/hpe.com/mounts/"
+	mountPoint := plugin.MountDir + "vol1"
+	mounted := []*model.Mount{{Mountpoint: mountPoint, Device: &model.Device{}}}
+	failed := []*model.Mount{{Mountpoint: mountPoint, Device: &model.Device{State: model.FailedState.String()}}}
+	userMounted := []*model.Mount{{Mountpoint: "/mnt/vol1", Device: &model.Device{}}}
+
+	tests := []struct {
+		name        string
+		mounts      []*model.Mount
+		inUse       bool
+		unknownHost bool
+		action      reconcileAction
+	}{
+		{"in use and mounted", mounted, true, false, reconcileClaim},
+		{"in use and not mounted", nil, true, false, reconcileMount},
+		{"in use and failed device", failed, true, false, reconcileMount},
+		{"not in use and mounted", mounted, false, false, reconcileUnmount},
+		{"not in use and mounted by the user", userMounted, false, false, reconcileNone},
+		{"not in use and not mounted", nil, false, false, reconcileNone},
+		{"unknown host", mounted, false, true, reconcileSkip},
+	}
+	for _, tc := range tests {
+		if action := getReconcileAction(tc.mounts, mountPoint, tc.inUse, tc.unknownHost); action != tc.action {
+			t.Errorf("%s: expected action %v, got %v", tc.name, tc.action, action)
+		}
+	}
+}
@@ -57,7 +57,7 @@ func VolumeDriverRemove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	//1. container-provider /Nimble.Get called
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.VolumeDriverGetURI, Payload: &pluginReq, Response: &volResp, ResponseError: &volResp})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.VolumeDriverGetURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &volResp, ResponseError: &volResp})
 	if volResp.Err != "" {
 		dr = &DriverResponse{Err: volResp.Err}
 		json.NewEncoder(w).Encode(dr)
@@ -71,7 +71,7 @@ func VolumeDriverRemove(w http.ResponseWriter, r *http.Request) {
 	}
 
 	volume := volResp.Volume
-	log.Tracef("Volume found :%+v", volume)
+	log.Tracef("Volume found :%+v", volume.Redacted())
 
 	// Check if the volume has ACR before we attempt to delete the volume
 	log.Tracef("volume status is %+v ", volResp.Volume.Status)
@@ -97,6 +97,7 @@ func VolumeDriverRemove(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(dr)
 		return
 	}
+	chapiClient.SetContext(r.Context())
 
 	//2.Perform host side remove workflow
 	err = chapiClient.UnmountDevice(volume)
@@ -109,29 +110,29 @@ func VolumeDriverRemove(w http.ResponseWriter, r *http.Request) {
 	// 3 . Offline the device (if present)
 	device, _ := chapiClient.GetDeviceFromVolume(volume)
 	if device != nil {
-		log.Tracef("best effort to ofline device for %+v", volume)
+		log.Tracef("best effort to ofline device for %+v", volume.Redacted())
 		chapiClient.OfflineDevice(device)
 	}
 
 	// 4. Finally call Nimble.Detach (remove acl's). It should not have acl's so don't fail the request but do our best attempt
-	log.Tracef("best effort to remove acl for %+v", volume)
+	log.Tracef("best effort to remove acl for %+v", volume.Redacted())
 	nimbleDetach(volume, pluginReq)
 
 	// 5 . Delete the device (if present)
 	if device != nil {
-		log.Tracef("best effort to remove device for %+v", volume)
+		log.Tracef("best effort to remove device for %+v", volume.Redacted())
 		chapiClient.DeleteDevice(device)
 	}
 
 	// 6. container-provider /VolumeDriver.Remove called
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.RemoveURI, Payload: &pluginReq, Response: &dr, ResponseError: nil})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.RemoveURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &dr, ResponseError: nil})
 
 	if err != nil {
 		dr = &DriverResponse{Err: err.Error()}
 		json.NewEncoder(w).Encode(dr)
 		return
 	}
-	log.Infof("%s: request=(%+v) response=(%+v)", provider.RemoveURI, pluginReq, dr)
+	log.Infof("%s: request=(%+v) response=(%+v)", provider.RemoveURI, pluginReq.Redacted(), dr)
 	json.NewEncoder(w).Encode(dr)
 	return
 }
@@ -0,0 +1,32 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hpe-storage/common-host-libs/concurrent"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// RequestsResponse : request worker pools response
+type RequestsResponse struct {
+	Pools []*concurrent.WorkerPoolStats `json:"pools"`
+	Err   string                        `json:"Err"`
+}
+
+//@APIVersion 1.0.0
+//@Title  report the plugin request worker pools
+//@Description implement the /Plugin.Requests end point
+//@Accept json
+//@Resource /Plugin.Requests
+//@Success 200 RequestsResponse
+//@Router /Plugin.Requests [get]
+//@BasePath http:/Plugin.Requests
+// PluginRequests reports the active, queued, completed and rejected mount and unmount requests,
+// and the time they waited for a request worker
+func PluginRequests(w http.ResponseWriter, r *http.Request) {
+	log.Trace("Plugin.Requests")
+	json.NewEncoder(w).Encode(&RequestsResponse{Pools: []*concurrent.WorkerPoolStats{mountRequestPool.GetStats(), unmountRequestPool.GetStats()}})
+}
@@ -10,15 +10,12 @@ import (
 
 	"github.com/hpe-storage/common-host-libs/chapi"
 	"github.com/hpe-storage/common-host-libs/connectivity"
+	"github.com/hpe-storage/common-host-libs/dockerplugin/plugin"
 	"github.com/hpe-storage/common-host-libs/dockerplugin/provider"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/model"
 )
 
-var (
-	unmountRequestsChan = make(chan string, defaultChannelCapacity)
-)
-
 //@APIVersion 1.0.0
 //@Title  implement the Nimble Volume Driver Unmount for docker
 //@Description implement the /VolumeDriver.Unmount Docker end point
@@ -31,8 +28,6 @@ var (
 // VolumeDriverUnmount implement the /VolumeDriver.Unmount Docker end point
 func VolumeDriverUnmount(w http.ResponseWriter, r *http.Request) {
 	log.Debug("/VolumeDriver.Unmount called ")
-	volResp := &VolumeUnmountResponse{}
-	log.Trace("volResp ", volResp)
 	var dr DriverResponse
 	// Populate Host Context to the Plugin Request
 	pluginReq, err := preparePluginRequest(r)
@@ -50,6 +45,7 @@ func VolumeDriverUnmount(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(resp)
 		return
 	}
+	chapiClient.SetContext(r.Context())
 
 	//get containerProviderClient
 	providerClient, err := provider.GetProviderClient()
@@ -60,16 +56,25 @@ func VolumeDriverUnmount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mapMutex.Lock(pluginReq.Name)
-	log.Debugf("taken lock for volume %s in Unmount", pluginReq.Name)
-	defer mapMutex.Unlock(pluginReq.Name)
+	// unmount the volume on a request worker, once the other requests for the volume are done
+	err = unmountRequestPool.Run(pluginReq.Name, func() {
+		log.Debugf("taken lock for volume %s in Unmount", pluginReq.Name)
+		unmountVolume(w, pluginReq, providerClient, chapiClient)
+	})
+	if err != nil {
+		writeBusyResponse(w, provider.UnmountURI, pluginReq.Name, err)
+	}
+}
 
-	unmountRequestsChan <- pluginReq.Name
-	log.Tracef("taken channel for volume %s in unmount with channel length :%d channel capacity :%d", pluginReq.Name, len(unmountRequestsChan), cap(unmountRequestsChan))
-	defer unblockChannelHandler("unmount", pluginReq.Name, unmountRequestsChan)
+// unmountVolume unmounts and detaches the volume from the host, steps 1 to 7 of
+// VolumeDriverUnmount
+// nolint: gocyclo
+func unmountVolume(w http.ResponseWriter, pluginReq *PluginRequest, providerClient *connectivity.Client, chapiClient *chapi.Client) {
+	volResp := &VolumeUnmountResponse{}
+	var dr DriverResponse
 
 	//1. container-provider /VolumeDriver.Unmount called
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UnmountURI, Payload: &pluginReq, Response: &volResp, ResponseError: &volResp})
+	_, err := providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UnmountURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &volResp, ResponseError: &volResp})
 	log.Tracef("/VolumeDriver.Unmount for volume %s response=%+v", pluginReq.Name, volResp)
 	if volResp.Err != "" {
 		log.Errorf("unmount error (%s) on volume(%s) ", volResp.Err, pluginReq.Name)
@@ -93,13 +98,19 @@ func VolumeDriverUnmount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//3. unmount the volume
+	//3. unmount the volume, removing the parent directories created for the mount path template
 	err = chapiClient.UnmountDevice(volume)
 	if err != nil {
 		dr = DriverResponse{Err: err.Error()}
 		json.NewEncoder(w).Encode(dr)
 		return
 	}
+	if mountPoint, err := plugin.GetMountPath(volume); err == nil {
+		plugin.ReleaseMountPath(mountPoint)
+		if err = plugin.RemoveMountPath(mountPoint); err != nil {
+			log.Errorf("unable to remove mount point %s, err %s", mountPoint, err.Error())
+		}
+	}
 
 	//4. Offline the device
 	device, _ := chapiClient.GetDeviceFromVolume(volume)
@@ -142,12 +153,12 @@ func VolumeDriverUnmount(w http.ResponseWriter, r *http.Request) {
 		prefs := make(map[string]interface{})
 		prefs["destroyOnDetach"] = "true"
 		pluginReq.Preferences = prefs
-		_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.RemoveURI, Payload: &pluginReq, Response: &dr, ResponseError: nil})
+		_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.RemoveURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &dr, ResponseError: nil})
 	}
 	if err != nil {
 		log.Debugf(err.Error())
 	}
-	log.Infof("%s: request=(%+v) response=(%+v)", provider.UnmountURI, pluginReq, volResp.VolumeResponse)
+	log.Infof("%s: request=(%+v) response=(%+v)", provider.UnmountURI, pluginReq.Redacted(), volResp.VolumeResponse)
 	json.NewEncoder(w).Encode(dr)
 	return
 }
@@ -159,12 +170,13 @@ func nimbleDetach(vol *model.Volume, req *PluginRequest) error {
 		Volume: vol,
 		Host:   req.Host,
 		User:   req.User,
+		ReqID:  req.ReqID,
 	}
 	var dr DriverResponse
 	providerClient, err := provider.GetProviderClient()
 	if err != nil {
 		return err
 	}
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.NimbleDetachURI, Payload: &nimbleDetach, Response: &dr, ResponseError: nil})
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.NimbleDetachURI, Context: req.ctx, Payload: &nimbleDetach, Response: &dr, ResponseError: nil})
 	return err
 }
@@ -38,8 +38,17 @@ func VolumeDriverUpdate(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(resp)
 		return
 	}
-	//container-provider /VolumeDriver.Update called
-	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UpdateURI, Payload: &pluginReq, Response: &cr, ResponseError: &cr})
+	// parse and validate the QoS options, locally and against the provider QoS capabilities
+	if _, err = parseOptions(qosOptionSchema, pluginReq.Opts); err == nil {
+		err = validateQosOptions(providerClient, pluginReq)
+	}
+	if err != nil {
+		cr = &CreateResponse{Err: err.Error()}
+		json.NewEncoder(w).Encode(cr)
+		return
+	}
+		//container-provider /VolumeDriver.Update called
+	_, err = providerClient.DoJSON(&connectivity.Request{Action: "POST", Path: provider.UpdateURI, Context: pluginReq.ctx, Payload: &pluginReq, Response: &cr, ResponseError: &cr})
 	if cr.Err != "" {
 		cr = &CreateResponse{Err: cr.Err}
 		json.NewEncoder(w).Encode(cr)
@@ -51,7 +60,7 @@ func VolumeDriverUpdate(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(cr)
 		return
 	}
-	log.Debugf("%s: request=(%+v) response=(%+v)", provider.UpdateURI, pluginReq, cr)
+	log.Debugf("%s: request=(%+v) response=(%+v)", provider.UpdateURI, pluginReq.Redacted(), cr)
 	json.NewEncoder(w).Encode(cr)
 	return
 }
@@ -0,0 +1,188 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/model"
+)
+
+// Volume mount paths are MountDir followed by the path template of the global section of
+// volume-driver.json, e.g. "mountPathTemplate": "{tenant}/{volume}".  The template variables are
+// {volume} (volume name), {id} (volume ID) or the name of any volume config option (e.g. {tenant}).
+// Every template must contain {volume} or {id} so that volumes are not mounted on the same path.
+// The mount root itself defaults to MountBaseDir and can be changed with "mountRoot".
+
+const (
+	// MountRootKey represents the key name for the mount root directory
+	MountRootKey = "mountRoot"
+	// MountPathTemplateKey represents the key name for the mount path template
+	MountPathTemplateKey = "mountPathTemplate"
+	// DefaultMountPathTemplate represents the default mount path template, the volume name
+	DefaultMountPathTemplate = "{volume}"
+
+	mountPathVolume = "volume"
+	mountPathID     = "id"
+)
+
+var (
+	// MountPathTemplate represents the mount path template, relative to MountDir
+	MountPathTemplate = DefaultMountPathTemplate
+
+	mountPathVariableRegexp     = regexp.MustCompile(`\{([^{}]*)\}`)
+	mountPathVariableNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+	// mountPathOwners are the volumes mounted by the plugin, by mount path
+	mountPathOwners = make(map[string]string)
+	mountPathLock   sync.Mutex
+)
+
+// InitializeMountPath initializes MountDir and MountPathTemplate from the global section of the
+// config file, creating the mount root if needed.  An invalid root or template is returned as an
+// error and leaves both unchanged.
+func InitializeMountPath() error {
+	if VolumeDriverConfig == nil {
+		log.Debugf("unable to load hpe volume config")
+		return nil
+	}
+	optsMap, err := VolumeDriverConfig.cache.GetMap(Section.String(Global))
+	if err != nil {
+		log.Debugf("failed to read from config file with err %s", err.Error())
+		return nil
+	}
+
+	template := DefaultMountPathTemplate
+	if val, ok := optsMap[MountPathTemplateKey].(string); ok && val != "" {
+		template = val
+	}
+	if err = ValidateMountPathTemplate(template); err != nil {
+		return err
+	}
+	root := MountDir
+	if val, ok := optsMap[MountRootKey].(string); ok && val != "" {
+		if !filepath.IsAbs(val) {
+			return fmt.Errorf("%s %s must be an absolute path", MountRootKey, val)
+		}
+		root = filepath.Clean(val) + string(filepath.Separator)
+		if err = os.MkdirAll(root, 0644); err != nil {
+			return fmt.Errorf("unable to create plugin mount directory %s, err %s", root, err.Error())
+		}
+	}
+
+	MountDir = root
+	MountPathTemplate = template
+	log.Infof("volumes are mounted on %s%s", MountDir, MountPathTemplate)
+	return nil
+}
+
+// ValidateMountPathTemplate checks the mount path template is relative, only uses well formed
+// variables and contains {volume} or {id}
+func ValidateMountPathTemplate(template string) error {
+	if filepath.IsAbs(template) || strings.HasPrefix(template, "/") || strings.HasPrefix(template, "\\") {
+		return fmt.Errorf("%s %s must be relative to the mount root", MountPathTemplateKey, template)
+	}
+	unique := false
+	for _, match := range mountPathVariableRegexp.FindAllStringSubmatch(template, -1) {
+		if !mountPathVariableNameRegexp.MatchString(match[1]) {
+			return fmt.Errorf("%s %s has an invalid variable %s", MountPathTemplateKey, template, match[0])
+		}
+		if match[1] == mountPathVolume || match[1] == mountPathID {
+			unique = true
+		}
+	}
+	if strings.ContainsAny(mountPathVariableRegexp.ReplaceAllString(template, ""), "{}") {
+		return fmt.Errorf("%s %s has an unbalanced brace", MountPathTemplateKey, template)
+	}
+	for _, element := range strings.FieldsFunc(template, isPathSeparator) {
+		if element == ".." || element == "." {
+			return fmt.Errorf("%s %s must not contain %s", MountPathTemplateKey, template, element)
+		}
+	}
+	if !unique {
+		return fmt.Errorf("%s %s must contain {%s} or {%s}", MountPathTemplateKey, template, mountPathVolume, mountPathID)
+	}
+	return nil
+}
+
+// GetMountPath returns the mount path of the volume, MountDir followed by the expanded mount path
+// template.  Path separators in the variable values are replaced so that each variable is a single
+// path element.
+func GetMountPath(volume *model.Volume) (string, error) {
+	var err error
+	relative := mountPathVariableRegexp.ReplaceAllStringFunc(MountPathTemplate, func(variable string) string {
+		name := variable[1 : len(variable)-1]
+		value := ""
+		switch name {
+		case mountPathVolume:
+			value = volume.Name
+		case mountPathID:
+			value = volume.ID
+		default:
+			if configValue, ok := volume.Config[name]; ok && configValue != nil {
+				value = fmt.Sprintf("%v", configValue)
+			}
+		}
+		value = strings.Map(func(r rune) rune {
+			if isPathSeparator(r) {
+				return '_'
+			}
+			return r
+		}, value)
+		if (value == "" || value == "." || value == "..") && err == nil {
+			err = fmt.Errorf("volume %s has no valid %s for mount path template %s", volume.Name, name, MountPathTemplate)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return MountDir + filepath.FromSlash(relative), nil
+}
+
+// ClaimMountPath records the volume as mounted on the mount path, failing if another volume is
+// already mounted on it
+func ClaimMountPath(mountPath string, volumeName string) error {
+	mountPathLock.Lock()
+	defer mountPathLock.Unlock()
+	if owner, ok := mountPathOwners[mountPath]; ok && owner != volumeName {
+		return fmt.Errorf("mount path %s of volume %s is already used by volume %s", mountPath, volumeName, owner)
+	}
+	mountPathOwners[mountPath] = volumeName
+	return nil
+}
+
+// ReleaseMountPath removes the volume mounted on the mount path from the claimed mount paths
+func ReleaseMountPath(mountPath string) {
+	mountPathLock.Lock()
+	defer mountPathLock.Unlock()
+	delete(mountPathOwners, mountPath)
+}
+
+// RemoveMountPath removes the empty mount path directory along with its parent directories created
+// for the mount path template, stopping at the first parent that is not empty or at MountDir.  A
+// mount path that is not empty (e.g. still mounted) is left in place, and the error returned.
+func RemoveMountPath(mountPath string) error {
+	if err := os.Remove(mountPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	root := filepath.Clean(MountDir)
+	for dir := filepath.Dir(mountPath); strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		// os.Remove fails on directories that are not empty
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// isPathSeparator returns true for both the slash and the platform path separator
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == filepath.Separator
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hpe-storage/common-host-libs/model"
+)
+
+func TestValidateMountPathTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		valid    bool
+	}{
+		{"{volume}", true},
+		{"{tenant}/{volume}", true},
+		{"projects/{project}/{id}", true},
+		{"{tenant}", false},
+		{"/mnt/{volume}", false},
+		{"../{volume}", false},
+		{"{tenant}/./{volume}", false},
+		{"{volume", false},
+		{"{volume}}", false},
+		{"{}/{volume}", false},
+		{"{ten-ant}/{volume}", false},
+	}
+	for _, tc := range tests {
+		if err := ValidateMountPathTemplate(tc.template); (err == nil) != tc.valid {
+			t.Errorf("ValidateMountPathTemplate(%v) returned err=%v, expected valid=%v", tc.template, err, tc.valid)
+		}
+	}
+}
+
+func TestGetMountPath(t *testing.T) {
+	savedMountDir, savedTemplate := MountDir, MountPathTemplate
+	defer func() { MountDir, MountPathTemplate = savedMountDir, savedTemplate }()
+	MountDir = "/mnt/"
+
+	volume := &model.Volume{Name: "vol1", ID: "0675", Config: map[string]interface{}{"tenant": "team/a"}}
+	MountPathTemplate = DefaultMountPathTemplate
+	if path, err := GetMountPath(volume); err != nil || path != filepath.FromSlash("/mnt/vol1") {
+		t.Errorf("unexpected path %v, err=%v", path, err)
+	}
+
+	// Path separators in the variable values are replaced
+	MountPathTemplate = "{tenant}/{volume}-{id}"
+	if path, err := GetMountPath(volume); err != nil || path != filepath.FromSlash("/mnt/team_a/vol1-0675") {
+		t.Errorf("unexpected path %v, err=%v", path, err)
+	}
+
+	// A volume without a template variable has no mount path
+	MountPathTemplate = "{project}/{volume}"
+	if path, err := GetMountPath(volume); err == nil {
+		t.Errorf("expected an error, got path %v", path)
+	}
+}
+
+func TestClaimAndRemoveMountPath(t *testing.T) {
+	savedMountDir := MountDir
+	defer func() { MountDir = savedMountDir }()
+	root, err := ioutil.TempDir("", "mountpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	MountDir = root + string(filepath.Separator)
+
+	mountPath := filepath.Join(root, "tenant", "vol1")
+	if err = ClaimMountPath(mountPath, "vol1"); err != nil {
+		t.Errorf("unexpected err=%v", err)
+	}
+	if err = ClaimMountPath(mountPath, "vol2"); err == nil {
+		t.Error("expected a collision with vol1")
+	}
+	ReleaseMountPath(mountPath)
+	if err = ClaimMountPath(mountPath, "vol2"); err != nil {
+		t.Errorf("unexpected err=%v", err)
+	}
+	ReleaseMountPath(mountPath)
+
+	// Empty template directories are removed up to the mount root, the mount root is kept
+	if err = os.MkdirAll(mountPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = RemoveMountPath(mountPath); err != nil {
+		t.Errorf("unexpected err=%v", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "tenant")); !os.IsNotExist(err) {
+		t.Errorf("expected the tenant directory to be removed, err=%v", err)
+	}
+	if _, err = os.Stat(root); err != nil {
+		t.Errorf("expected the mount root to be kept, err=%v", err)
+	}
+
+	// A mount path that is not empty is kept
+	if err = os.MkdirAll(mountPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(mountPath, "data"), []byte("data"), 0644)
+	if err = RemoveMountPath(mountPath); err == nil {
+		t.Error("expected an error removing a mount path that is not empty")
+	}
+}
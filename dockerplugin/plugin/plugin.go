@@ -32,6 +32,8 @@ const (
 	MountConflictDelayKey = "mountConflictDelay"
 	// DefaultMountConflictDelay represents the default delay to wait on conflicts during mount
 	DefaultMountConflictDelay = 120
+	// ReconcileOnStartKey represents the key name to enable reconciling volume mounts on start
+	ReconcileOnStartKey = "reconcileOnStart"
 )
 
 var (
@@ -44,6 +46,8 @@ var (
 	DeleteConflictDelay = DefaultDeleteConflictDelay
 	// MountConflictDelay represent conflict delay to wait during mount
 	MountConflictDelay = DefaultMountConflictDelay
+	// ReconcileOnStart represents if volume mounts are reconciled with the container provider on start
+	ReconcileOnStart = true
 )
 
 // ConfigCache to store config options
@@ -337,3 +341,31 @@ func InitializeDeleteConflictDelay() {
 	}
 	log.Debugf("%s is set to %d", DeleteConflictDelayKey, DeleteConflictDelay)
 }
+
+// InitializeReconcileOnStart initializes reconcileOnStart, enabled unless set to false
+func InitializeReconcileOnStart() {
+	ReconcileOnStart = true
+	if VolumeDriverConfig == nil {
+		log.Debugf("unable to load hpe volume config")
+		return
+	}
+	optsMap, err := VolumeDriverConfig.cache.GetMap(Section.String(Global))
+	if err != nil {
+		log.Debugf("failed to read from config file with err %s", err.Error())
+		return
+	}
+	if val, ok := optsMap[ReconcileOnStartKey]; ok {
+		switch v := val.(type) {
+		case string:
+			boolVal, err := strconv.ParseBool(v)
+			if err != nil {
+				log.Warnf("unable to parse %s from config file, setting reconcileOnStart=true", ReconcileOnStartKey)
+				return
+			}
+			ReconcileOnStart = boolVal
+		case bool:
+			ReconcileOnStart = v
+		}
+	}
+	log.Debugf("%s is set to %v", ReconcileOnStartKey, ReconcileOnStart)
+}
@@ -5,6 +5,8 @@ package plugin
 var (
 	// PluginConfigDir represents config directory for plugin
 	PluginConfigDir = ""
+	// MountDir represents volume mount directory for the plugin
+	MountDir = ""
 )
 
 // GetOrCreatePluginConfigDirectory get or create plugin config directory
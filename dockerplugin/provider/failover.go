@@ -0,0 +1,228 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/connectivity"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// The container provider can be configured with multiple endpoints (e.g. PROVIDER_IP=ip1,ip2) so
+// that the plugin rides through a provider restart or maintenance.  Requests are sent to the first
+// healthy endpoint in the configured order.  An endpoint that cannot be connected to (or answers
+// 503 Service Unavailable) is marked unhealthy and the request is retried on the next endpoint.
+// An unhealthy endpoint is health checked (TCP connect) again after a jittered, exponentially
+// increasing delay, so that the plugins on many hosts do not reconnect in lockstep, and is used
+// again, in its configured order, once the health check succeeds.
+
+const (
+	providerReconnectMinDelay  = time.Duration(2) * time.Second
+	providerReconnectMaxDelay  = time.Duration(60) * time.Second
+	providerHealthCheckTimeout = time.Duration(5) * time.Second
+)
+
+// providerEndpoint is a container provider endpoint and its health
+type providerEndpoint struct {
+	scheme   string    // URI scheme (http or https)
+	host     string    // host:port
+	healthy  bool      // false once a request to the endpoint fails
+	failures uint      // consecutive failures, used to back off reconnects
+	retryAt  time.Time // time the unhealthy endpoint is next health checked
+}
+
+// failoverTransport is an http.RoundTripper that sends each request to the first healthy container
+// provider endpoint, failing over to the next endpoint if the request cannot be delivered
+type failoverTransport struct {
+	transport   http.RoundTripper
+	endpoints   []*providerEndpoint
+	lock        sync.Mutex
+	healthCheck func(endpoint *providerEndpoint) error
+}
+
+// newProviderClient returns a container provider client for the given provider URIs.  The URIs
+// must only differ in their host and port.  If more than one URI is given, requests fail over
+// between them.  If request signing is enabled, requests are signed (see signing.go); each
+// failover attempt is signed again so that every attempt carries its own nonce.
+func newProviderClient(providerURIs []string, transport http.RoundTripper, timeout time.Duration) (*connectivity.Client, error) {
+	if len(providerURIs) == 0 {
+		return nil, errors.New("no container provider endpoints configured")
+	}
+	if IsRequestSigningEnabled() {
+		log.Infof("signing container provider requests")
+		transport = newSigningTransport(transport)
+	}
+	if len(providerURIs) > 1 {
+		failover, err := newFailoverTransport(providerURIs, transport)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("using container provider endpoints %v with failover", providerURIs)
+		transport = failover
+	}
+	if transport == nil {
+		return connectivity.NewHTTPClientWithTimeout(providerURIs[0], timeout), nil
+	}
+	return connectivity.NewHTTPSClientWithTimeout(providerURIs[0], transport, timeout), nil
+}
+
+// newFailoverTransport returns a failoverTransport sending requests to the given provider URIs
+// over the given transport (http.DefaultTransport if nil)
+func newFailoverTransport(providerURIs []string, transport http.RoundTripper) (*failoverTransport, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	failover := &failoverTransport{transport: transport}
+	failover.healthCheck = failover.dialEndpoint
+	for _, providerURI := range providerURIs {
+		parsed, err := url.Parse(providerURI)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Host == "" {
+			return nil, fmt.Errorf("invalid container provider uri %s", providerURI)
+		}
+		failover.endpoints = append(failover.endpoints, &providerEndpoint{scheme: parsed.Scheme, host: parsed.Host, healthy: true})
+	}
+	return failover, nil
+}
+
+// RoundTrip sends the request to the first healthy endpoint, retrying it on the next endpoints
+// while it cannot be delivered
+func (t *failoverTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	endpoints := t.candidateEndpoints()
+	for i, endpoint := range endpoints {
+		last := i == len(endpoints)-1
+
+		// The request body has been consumed by the previous attempt, rewind it
+		attempt := request.Clone(request.Context())
+		if i > 0 && request.Body != nil && request.Body != http.NoBody {
+			if request.GetBody == nil {
+				return nil, fmt.Errorf("unable to retry request to %s, request body cannot be rewound", request.URL.Path)
+			}
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt.Body = body
+		}
+		attempt.URL.Scheme = endpoint.scheme
+		attempt.URL.Host = endpoint.host
+		attempt.Host = ""
+
+		response, err := t.transport.RoundTrip(attempt)
+		if err != nil {
+			t.markFailed(endpoint, err)
+			// Only retry requests the provider never received, they are not necessarily idempotent
+			if !isDialError(err) || last {
+				return nil, err
+			}
+			continue
+		}
+		if response.StatusCode == http.StatusServiceUnavailable && !last {
+			response.Body.Close()
+			t.markFailed(endpoint, errors.New(response.Status))
+			continue
+		}
+		t.markHealthy(endpoint)
+		return response, nil
+	}
+	return nil, errors.New("no container provider endpoints configured")
+}
+
+// candidateEndpoints returns the endpoints to try, in order.  Healthy endpoints are returned along
+// with the unhealthy endpoints due a health check that pass it.  If there are none, all endpoints
+// are returned so that the request is still attempted.
+func (t *failoverTransport) candidateEndpoints() []*providerEndpoint {
+	t.lock.Lock()
+	var due []*providerEndpoint
+	now := time.Now()
+	for _, endpoint := range t.endpoints {
+		if !endpoint.healthy && !now.Before(endpoint.retryAt) {
+			// Push the next health check out so that concurrent requests do not all check it
+			endpoint.retryAt = now.Add(providerHealthCheckTimeout)
+			due = append(due, endpoint)
+		}
+	}
+	t.lock.Unlock()
+
+	for _, endpoint := range due {
+		if err := t.healthCheck(endpoint); err != nil {
+			t.markFailed(endpoint, err)
+		} else {
+			log.Infof("container provider endpoint %s is reachable again", endpoint.host)
+			t.markHealthy(endpoint)
+		}
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var candidates []*providerEndpoint
+	for _, endpoint := range t.endpoints {
+		if endpoint.healthy {
+			candidates = append(candidates, endpoint)
+		}
+	}
+	if len(candidates) == 0 {
+		log.Errorf("no healthy container provider endpoint, attempting all endpoints")
+		return append(candidates, t.endpoints...)
+	}
+	return candidates
+}
+
+// markFailed marks the endpoint unhealthy until its next (jittered) health check
+func (t *failoverTransport) markFailed(endpoint *providerEndpoint, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delay := reconnectDelay(endpoint.failures)
+	endpoint.failures++
+	endpoint.retryAt = time.Now().Add(delay)
+	if endpoint.healthy {
+		log.Errorf("container provider endpoint %s failed, failing over, err=%v", endpoint.host, err)
+	}
+	endpoint.healthy = false
+	log.Debugf("container provider endpoint %s will be health checked in %v", endpoint.host, delay)
+}
+
+// markHealthy marks the endpoint healthy
+func (t *failoverTransport) markHealthy(endpoint *providerEndpoint) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	endpoint.healthy = true
+	endpoint.failures = 0
+}
+
+// dialEndpoint health checks the endpoint by connecting to it
+func (t *failoverTransport) dialEndpoint(endpoint *providerEndpoint) error {
+	conn, err := net.DialTimeout("tcp", endpoint.host, providerHealthCheckTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// reconnectDelay returns the delay before an endpoint that failed the given number of consecutive
+// times is health checked again.  The delay doubles with each failure, up to
+// providerReconnectMaxDelay, and is randomized by +/-50% to spread out reconnects.
+func reconnectDelay(failures uint) time.Duration {
+	delay := providerReconnectMaxDelay
+	if failures < 8 && providerReconnectMinDelay<<failures < providerReconnectMaxDelay {
+		delay = providerReconnectMinDelay << failures
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay)))
+}
+
+// isDialError returns true if the error occurred connecting to the endpoint, i.e. the request was
+// not sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
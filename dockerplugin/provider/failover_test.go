@@ -0,0 +1,98 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/connectivity"
+)
+
+func TestGetProviderURIs(t *testing.T) {
+	defer os.Unsetenv(EnvIP)
+	defer os.Unsetenv(EnvInsecure)
+	os.Setenv(EnvIP, "10.1.1.1, 10.1.1.2:8444,fd00::1")
+	os.Setenv(EnvInsecure, "false")
+
+	uris, err := GetProviderURIs("", "8443", "/container-provider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"https://10.1.1.1:8443/container-provider",
+		"https://10.1.1.2:8444/container-provider",
+		"https://[fd00::1]:8443/container-provider",
+	}
+	if !reflect.DeepEqual(uris, expected) {
+		t.Errorf("unexpected uris %v", uris)
+	}
+
+	uri, err := GetProviderURI("", "8443", "/container-provider")
+	if err != nil || uri != expected[0] {
+		t.Errorf("unexpected uri %v, err=%v", uri, err)
+	}
+}
+
+func TestFailoverTransport(t *testing.T) {
+	var served int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&served, 1)
+		w.Write([]byte(`{"Name":"` + r.URL.Path + `"}`))
+	}))
+	defer healthy.Close()
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	client, err := newProviderClient([]string{down.URL + "/base", unavailable.URL + "/base", healthy.URL + "/base"}, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failover := client.Transport.(*failoverTransport)
+
+	// The request, including its payload, fails over to the healthy endpoint
+	response := &struct{ Name string }{}
+	payload := &struct{ Name string }{Name: "vol1"}
+	if _, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: CreateURI, Payload: payload, Response: response}); err != nil {
+		t.Fatal(err)
+	}
+	if response.Name != "/base"+CreateURI {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if failover.endpoints[0].healthy || failover.endpoints[1].healthy || !failover.endpoints[2].healthy {
+		t.Errorf("unexpected endpoint health %+v %+v %+v", failover.endpoints[0], failover.endpoints[1], failover.endpoints[2])
+	}
+
+	// Unhealthy endpoints are skipped until their health check is due
+	if candidates := failover.candidateEndpoints(); len(candidates) != 1 || candidates[0] != failover.endpoints[2] {
+		t.Errorf("unexpected candidates %v", candidates)
+	}
+
+	// A due endpoint that passes its health check is used again
+	failover.endpoints[0].retryAt = time.Now()
+	failover.endpoints[1].retryAt = time.Now()
+	if candidates := failover.candidateEndpoints(); len(candidates) != 2 || candidates[0] != failover.endpoints[1] {
+		t.Errorf("unexpected candidates %v", candidates)
+	}
+	if failover.endpoints[0].healthy {
+		t.Error("closed endpoint passed its health check")
+	}
+}
+
+func TestReconnectDelay(t *testing.T) {
+	for failures := uint(0); failures < 12; failures++ {
+		delay := reconnectDelay(failures)
+		if delay < providerReconnectMinDelay/2 || delay > providerReconnectMaxDelay*3/2 {
+			t.Errorf("reconnect delay %v after %v failures out of range", delay, failures)
+		}
+	}
+}
@@ -3,16 +3,62 @@
 package provider
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/hpe-storage/common-host-libs/connectivity"
 	log "github.com/hpe-storage/common-host-libs/logger"
-	"os"
 )
 
 const (
 	defaultHpecvProviderPort   = "8090"
 	defaultHpecvProviderPortal = "cloudvolumes.hpe.com"
+
+	// HPECloudVolumesCapabilitiesURI represents the hpecv provider capabilities endpoint
+	HPECloudVolumesCapabilitiesURI = "/HPECloudVolumes.Capabilities"
+
+	// hpecvCapabilitiesTTL is how long the provider capabilities are cached before they are fetched
+	// again (e.g. to pick up newly enabled regions)
+	hpecvCapabilitiesTTL = 15 * time.Minute
+
+	// hpecv create options validated against the provider capabilities
+	hpecvRegionOpt           = "region"
+	hpecvReplicationStoreOpt = "replicationStore"
+	hpecvVolumeTypeOpt       = "volumeType"
+	hpecvIopsOpt             = "iops"
 )
 
+var (
+	hpecvCapabilities       *HPECloudVolumesCapabilities
+	hpecvCapabilitiesExpiry time.Time
+	hpecvCapabilitiesLock   sync.Mutex
+)
+
+// HPECloudVolumesCapabilities describes the create options supported by the hpecv provider.  An
+// empty list (or zero limit) means the provider does not restrict that option.
+type HPECloudVolumesCapabilities struct {
+	Regions           []string `json:"regions,omitempty"`
+	ReplicationStores []string `json:"replication_stores,omitempty"`
+	VolumeTypes       []string `json:"volume_types,omitempty"`
+	MinIops           int64    `json:"min_iops,omitempty"`
+	MaxIops           int64    `json:"max_iops,omitempty"`
+}
+
+// hpecvCapabilitiesRequest is the request submitted to the hpecv capabilities endpoint
+type hpecvCapabilitiesRequest struct {
+	User *User `json:"user,omitempty"`
+}
+
+// hpecvCapabilitiesResponse is the response returned by the hpecv capabilities endpoint
+type hpecvCapabilitiesResponse struct {
+	Capabilities *HPECloudVolumesCapabilities `json:"capabilities,omitempty"`
+	Err          string                       `json:"Err,omitempty"`
+}
+
 //IsHPECloudVolumesPlugin returns true if plugin type is hpecv
 func IsHPECloudVolumesPlugin() bool {
 	if os.Getenv("PLUGIN_TYPE") == "cv" {
@@ -25,9 +71,105 @@ func getCloudContainerProviderClient() (*connectivity.Client, error) {
 	log.Trace(">>> getCloudContainerProviderClient")
 	defer log.Trace("<<< getCloudContainerProviderClient")
 
-	uri, err := GetProviderURI(defaultHpecvProviderPortal, defaultHpecvProviderPort, "")
+	uris, err := GetProviderURIs(defaultHpecvProviderPortal, defaultHpecvProviderPort, "")
 	if err != nil {
 		return nil, err
 	}
-	return connectivity.NewHTTPClientWithTimeout(uri, providerClientTimeout), nil
+	return newProviderClient(uris, nil, providerClientTimeout)
+}
+
+// GetHPECloudVolumesCapabilities returns the hpecv provider capabilities.  They are cached for
+// hpecvCapabilitiesTTL; if they cannot be fetched again once expired, the previously fetched
+// capabilities continue to be used.
+func GetHPECloudVolumesCapabilities(client *connectivity.Client, user *User) (*HPECloudVolumesCapabilities, error) {
+	log.Trace(">>> GetHPECloudVolumesCapabilities")
+	defer log.Trace("<<< GetHPECloudVolumesCapabilities")
+
+	hpecvCapabilitiesLock.Lock()
+	defer hpecvCapabilitiesLock.Unlock()
+
+	// see if we have already fetched them
+	if hpecvCapabilities != nil && time.Now().Before(hpecvCapabilitiesExpiry) {
+		return hpecvCapabilities, nil
+	}
+
+	capabilities, err := fetchHPECloudVolumesCapabilities(client, user)
+	if err != nil {
+		if hpecvCapabilities != nil {
+			log.Debugf("unable to refresh hpecv provider capabilities, using cached capabilities, err %s", err.Error())
+			return hpecvCapabilities, nil
+		}
+		return nil, err
+	}
+	log.Debugf("hpecv provider capabilities %+v", capabilities)
+	hpecvCapabilities = capabilities
+	hpecvCapabilitiesExpiry = time.Now().Add(hpecvCapabilitiesTTL)
+	return hpecvCapabilities, nil
+}
+
+// fetchHPECloudVolumesCapabilities requests the capabilities from the hpecv provider
+func fetchHPECloudVolumesCapabilities(client *connectivity.Client, user *User) (*HPECloudVolumesCapabilities, error) {
+	response := &hpecvCapabilitiesResponse{}
+	_, err := client.DoJSON(&connectivity.Request{Action: "POST", Path: HPECloudVolumesCapabilitiesURI, Payload: &hpecvCapabilitiesRequest{User: user}, Response: response, ResponseError: response})
+	if err != nil {
+		return nil, err
+	}
+	if response.Err != "" {
+		return nil, fmt.Errorf("unable to get hpecv provider capabilities, %s", response.Err)
+	}
+	if response.Capabilities == nil {
+		return nil, fmt.Errorf("hpecv provider returned no capabilities")
+	}
+	return response.Capabilities, nil
+}
+
+// ValidateCreateOptions checks the region, replication store, volume type and iops create options
+// against the provider capabilities so that unsupported options are rejected before the (long
+// running) create request is submitted to the provider
+func (c *HPECloudVolumesCapabilities) ValidateCreateOptions(opts map[string]interface{}) error {
+	log.Tracef(">>> ValidateCreateOptions called with %v", opts)
+	defer log.Trace("<<< ValidateCreateOptions")
+
+	choices := []struct {
+		option    string
+		supported []string
+	}{
+		{hpecvRegionOpt, c.Regions},
+		{hpecvReplicationStoreOpt, c.ReplicationStores},
+		{hpecvVolumeTypeOpt, c.VolumeTypes},
+	}
+	for _, choice := range choices {
+		value, ok := opts[choice.option]
+		if !ok || len(choice.supported) == 0 {
+			continue
+		}
+		if !containsFold(choice.supported, fmt.Sprintf("%v", value)) {
+			return fmt.Errorf("unsupported %s %v, please enter one of the following options (%s)", choice.option, value, strings.Join(choice.supported, " "))
+		}
+	}
+
+	if value, ok := opts[hpecvIopsOpt]; ok {
+		// options from the config file are decoded as float64, options from docker as strings
+		iops, err := strconv.ParseFloat(fmt.Sprintf("%v", value), 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %v, %s", hpecvIopsOpt, value, err.Error())
+		}
+		if c.MinIops > 0 && iops < float64(c.MinIops) {
+			return fmt.Errorf("unsupported %s %v, minimum is %d", hpecvIopsOpt, value, c.MinIops)
+		}
+		if c.MaxIops > 0 && iops > float64(c.MaxIops) {
+			return fmt.Errorf("unsupported %s %v, maximum is %d", hpecvIopsOpt, value, c.MaxIops)
+		}
+	}
+	return nil
+}
+
+// containsFold returns true if values contains value (case insensitive)
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,109 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateCreateOptions(t *testing.T) {
+	capabilities := &HPECloudVolumesCapabilities{
+		Regions:           []string{"us-east-1", "us-west-2"},
+		ReplicationStores: []string{"repl-store-1"},
+		VolumeTypes:       []string{"GPF", "PF"},
+		MinIops:           300,
+		MaxIops:           50000,
+	}
+
+	tests := []struct {
+		opts  map[string]interface{}
+		valid bool
+	}{
+		{map[string]interface{}{}, true},
+		{map[string]interface{}{hpecvRegionOpt: "US-EAST-1", hpecvVolumeTypeOpt: "gpf", hpecvIopsOpt: "1000"}, true},
+		{map[string]interface{}{hpecvReplicationStoreOpt: "repl-store-1", hpecvIopsOpt: float64(300)}, true},
+		{map[string]interface{}{hpecvRegionOpt: "eu-central-1"}, false},
+		{map[string]interface{}{hpecvReplicationStoreOpt: "repl-store-2"}, false},
+		{map[string]interface{}{hpecvVolumeTypeOpt: "SSD"}, false},
+		{map[string]interface{}{hpecvIopsOpt: "100"}, false},
+		{map[string]interface{}{hpecvIopsOpt: float64(60000)}, false},
+		{map[string]interface{}{hpecvIopsOpt: "fast"}, false},
+	}
+	for _, tc := range tests {
+		if err := capabilities.ValidateCreateOptions(tc.opts); (err == nil) != tc.valid {
+			t.Errorf("ValidateCreateOptions(%v) returned err=%v, expected valid=%v", tc.opts, err, tc.valid)
+		}
+	}
+
+	// Without capabilities, any create option is left for the provider to validate
+	if err := (&HPECloudVolumesCapabilities{}).ValidateCreateOptions(map[string]interface{}{hpecvRegionOpt: "eu-central-1", hpecvIopsOpt: "100"}); err != nil {
+		t.Errorf("unexpected err=%v", err)
+	}
+}
+
+func TestGetHPECloudVolumesCapabilities(t *testing.T) {
+	var requests int32
+	var response atomic.Value
+	response.Store(`{"capabilities":{"regions":["us-east-1"]}}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != HPECloudVolumesCapabilitiesURI {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(response.Load().(string)))
+	}))
+	defer server.Close()
+
+	client, err := newProviderClient([]string{server.URL}, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		hpecvCapabilities, hpecvCapabilitiesExpiry = nil, time.Time{}
+	}()
+	hpecvCapabilities, hpecvCapabilitiesExpiry = nil, time.Time{}
+
+	// The capabilities are fetched once and then served from the cache
+	for i := 0; i < 2; i++ {
+		capabilities, err := GetHPECloudVolumesCapabilities(client, nil)
+		if err != nil {
+			t.Fatalf("GetHPECloudVolumesCapabilities failed, err=%v", err)
+		}
+		if len(capabilities.Regions) != 1 || capabilities.Regions[0] != "us-east-1" {
+			t.Errorf("unexpected capabilities %+v", capabilities)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("capabilities were requested %v times, expected once", requests)
+	}
+
+	// Expired capabilities are fetched again
+	response.Store(`{"capabilities":{"regions":["us-east-1","us-west-2"]}}`)
+	hpecvCapabilitiesExpiry = time.Now()
+	capabilities, err := GetHPECloudVolumesCapabilities(client, nil)
+	if err != nil {
+		t.Fatalf("GetHPECloudVolumesCapabilities failed, err=%v", err)
+	}
+	if len(capabilities.Regions) != 2 || requests != 2 {
+		t.Errorf("unexpected capabilities %+v after %v requests", capabilities, requests)
+	}
+
+	// The cached capabilities are used if they cannot be refreshed
+	response.Store(`{"Err":"capabilities unavailable"}`)
+	hpecvCapabilitiesExpiry = time.Now()
+	if capabilities, err = GetHPECloudVolumesCapabilities(client, nil); err != nil || len(capabilities.Regions) != 2 {
+		t.Errorf("unexpected capabilities %+v, err=%v", capabilities, err)
+	}
+
+	// Without cached capabilities, the provider error is returned
+	hpecvCapabilities = nil
+	if capabilities, err = GetHPECloudVolumesCapabilities(client, nil); err == nil {
+		t.Errorf("unexpected capabilities %+v", capabilities)
+	}
+}
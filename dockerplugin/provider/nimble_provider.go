@@ -88,7 +88,7 @@ func getNimbleContainerProviderClient() (*connectivity.Client, error) {
 	log.Trace(">>>>> getNimbleContainerProviderClient")
 	defer log.Trace("<<<<< getNimbleContainerProviderClient")
 
-	providerURI, err := GetProviderURI("", nimbleProviderPort, "/container-provider")
+	providerURIs, err := GetProviderURIs("", nimbleProviderPort, "/container-provider")
 	if err != nil {
 		return nil, err
 	}
@@ -134,10 +134,7 @@ func getNimbleContainerProviderClient() (*connectivity.Client, error) {
 
 	// Setup HTTPS client
 	transport := &http.Transport{TLSClientConfig: tlsConfig}
-	containerProviderClient := connectivity.NewHTTPSClientWithTimeout(providerURI, transport,
-		providerClientTimeout)
-
-	return containerProviderClient, nil
+	return newProviderClient(providerURIs, transport, providerClientTimeout)
 }
 
 // LoginAndCreateCerts :
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"github.com/hpe-storage/common-host-libs/connectivity"
 	log "github.com/hpe-storage/common-host-libs/logger"
+	"net"
 	"os"
+	"strings"
 	"sync"
 	"time"
 )
@@ -60,7 +62,7 @@ const (
 	providerClientTimeout = time.Duration(300) * time.Second
 
 	// env params
-	// EnvIP represents provider IP env
+	// EnvIP represents provider IP env, optionally a comma separated list of endpoints to fail over between
 	EnvIP = "PROVIDER_IP"
 	// EnvService represents service name when provider running as k8s service
 	EnvService = "PROVIDER_SERVICE"
@@ -80,6 +82,21 @@ type User struct {
 	AccessSecret string `json:"access_secret,omitempty"`
 }
 
+// Redacted returns a copy of the user, safe to log, with the access keys masked
+func (user *User) Redacted() *User {
+	if user == nil {
+		return nil
+	}
+	redacted := &User{}
+	if user.AccessKey != "" {
+		redacted.AccessKey = log.RedactedValue
+	}
+	if user.AccessSecret != "" {
+		redacted.AccessSecret = log.RedactedValue
+	}
+	return redacted
+}
+
 // GetProviderClient returns container-storage-provider client based on the plugin type
 func GetProviderClient() (*connectivity.Client, error) {
 	log.Trace(">>> getProviderClient")
@@ -119,29 +136,46 @@ func GetProviderIP() (ip string, err error) {
 	return "", fmt.Errorf("%s env is not set", EnvIP)
 }
 
-// GetProviderAccessKeys returns api access keys for the provider configured in env
+// GetProviderAccessKeys returns api access keys for the provider configured in env (or in the
+// PROVIDER_CREDENTIALS_FILE).  If request signing is enabled the access secret is only used to
+// sign the requests and is not returned, so that it's not sent in the request payloads.
 func GetProviderAccessKeys() (*User, error) {
-	// read from environment variables
-	accessKey := os.Getenv(EnvUsername)
-	if accessKey == "" {
-		return nil, fmt.Errorf("env variable %s is not provided", EnvUsername)
+	user, err := getProviderCredentials()
+	if err != nil {
+		return nil, err
 	}
-	accessSecret := os.Getenv(EnvPassword)
-	if accessKey == "" {
-		return nil, fmt.Errorf("env variable %s is not provided", EnvPassword)
+	if IsRequestSigningEnabled() {
+		return &User{AccessKey: user.AccessKey}, nil
 	}
-	return &User{AccessKey: accessKey, AccessSecret: accessSecret}, nil
+	return user, nil
 }
 
-// GetProviderURI returns container storage provider URI based on env set or using passed in defaults
+// GetProviderURI returns container storage provider URI based on env set or using passed in defaults.
+// If multiple provider endpoints are configured, the first one is returned.
 func GetProviderURI(defaultProviderPortal, defaultProviderPort, basePath string) (providerURI string, err error) {
+	providerURIs, err := GetProviderURIs(defaultProviderPortal, defaultProviderPort, basePath)
+	if err != nil {
+		return "", err
+	}
+	return providerURIs[0], nil
+}
+
+// GetProviderURIs returns the container storage provider URIs based on env set or using passed in
+// defaults.  PROVIDER_IP can list multiple provider endpoints, separated by commas, to fail over
+// between (e.g. "10.1.1.1,10.1.1.2:8444"); endpoints without a port use PROVIDER_PORT.
+func GetProviderURIs(defaultProviderPortal, defaultProviderPort, basePath string) (providerURIs []string, err error) {
 	// Assume defaults
-	portal := defaultProviderPortal
+	portals := []string{defaultProviderPortal}
 	port := defaultProviderPort
 
 	// Override ip:port if specified from env
 	if envportal := os.Getenv(EnvIP); envportal != "" {
-		portal = envportal
+		portals = nil
+		for _, portal := range strings.Split(envportal, ",") {
+			if portal = strings.TrimSpace(portal); portal != "" {
+				portals = append(portals, portal)
+			}
+		}
 	}
 
 	if envport := os.Getenv(EnvPort); envport != "" {
@@ -151,23 +185,28 @@ func GetProviderURI(defaultProviderPortal, defaultProviderPort, basePath string)
 	// if service name is provided, then handle container-provider running as k8s service
 	if envService := os.Getenv(EnvService); envService != "" {
 		// override with service name
-		portal = envService
+		portals = []string{envService}
 		// allow http connection to service
 		os.Setenv(EnvInsecure, "true")
 	}
 
-	if port == "" || portal == "" {
-		return "", fmt.Errorf("unable to get provider uri as environment param %s/%s are not set", EnvIP, EnvPort)
-	}
-
+	scheme := "https"
 	if os.Getenv(EnvInsecure) == "true" {
-		providerURI = fmt.Sprintf("http://%s:%s", portal, port)
-	} else {
-		providerURI = fmt.Sprintf("https://%s:%s", portal, port)
+		scheme = "http"
+	}
+	for _, portal := range portals {
+		host, portalPort := portal, port
+		if h, p, splitErr := net.SplitHostPort(portal); splitErr == nil {
+			host, portalPort = h, p
+		}
+		if portalPort == "" || host == "" {
+			return nil, fmt.Errorf("unable to get provider uri as environment param %s/%s are not set", EnvIP, EnvPort)
+		}
+		providerURIs = append(providerURIs, fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, portalPort), basePath))
 	}
-	if basePath != "" {
-		providerURI = providerURI + basePath
+	if len(providerURIs) == 0 {
+		return nil, fmt.Errorf("unable to get provider uri as environment param %s/%s are not set", EnvIP, EnvPort)
 	}
-	log.Debugf("using container provider URI %s", providerURI)
-	return providerURI, nil
+	log.Debugf("using container provider URIs %v", providerURIs)
+	return providerURIs, nil
 }
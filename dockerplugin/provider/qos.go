@@ -0,0 +1,127 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hpe-storage/common-host-libs/connectivity"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+const (
+	// QosCapabilitiesURI represents the provider QoS capabilities endpoint
+	QosCapabilitiesURI = "/HPEVolume.QosCapabilities"
+
+	// QosLimitIopsOpt is the create/update option limiting the volume IOPS
+	QosLimitIopsOpt = "limitIOPS"
+	// QosLimitMbpsOpt is the create/update option limiting the volume throughput in MB/s
+	QosLimitMbpsOpt = "limitMBPS"
+	// QosPerfPolicyOpt is the create/update option selecting the volume performance policy
+	QosPerfPolicyOpt = "perfPolicy"
+
+	// QosUnlimited is the limitIOPS/limitMBPS value removing the limit
+	QosUnlimited = -1
+)
+
+var (
+	qosCapabilities     *QosCapabilities
+	qosCapabilitiesLock sync.Mutex
+)
+
+// QosCapabilities describes the QoS options supported by the provider.  An empty list (or zero
+// limit) means the provider does not restrict that option.
+type QosCapabilities struct {
+	PerfPolicies []string `json:"perf_policies,omitempty"`
+	MinIops      int64    `json:"min_iops,omitempty"`
+	MaxIops      int64    `json:"max_iops,omitempty"`
+	MinMbps      int64    `json:"min_mbps,omitempty"`
+	MaxMbps      int64    `json:"max_mbps,omitempty"`
+}
+
+// qosCapabilitiesRequest is the request submitted to the QoS capabilities endpoint
+type qosCapabilitiesRequest struct {
+	User *User `json:"user,omitempty"`
+}
+
+// qosCapabilitiesResponse is the response returned by the QoS capabilities endpoint
+type qosCapabilitiesResponse struct {
+	Capabilities *QosCapabilities `json:"capabilities,omitempty"`
+	Err          string           `json:"Err,omitempty"`
+}
+
+// GetQosCapabilities returns the provider QoS capabilities.  They are fetched from the provider
+// once and cached for the lifetime of the plugin.
+func GetQosCapabilities(client *connectivity.Client, user *User) (*QosCapabilities, error) {
+	log.Trace(">>> GetQosCapabilities")
+	defer log.Trace("<<< GetQosCapabilities")
+
+	qosCapabilitiesLock.Lock()
+	defer qosCapabilitiesLock.Unlock()
+
+	// see if we have already fetched them
+	if qosCapabilities != nil {
+		return qosCapabilities, nil
+	}
+
+	response := &qosCapabilitiesResponse{}
+	_, err := client.DoJSON(&connectivity.Request{Action: "POST", Path: QosCapabilitiesURI, Payload: &qosCapabilitiesRequest{User: user}, Response: response, ResponseError: response})
+	if err != nil {
+		return nil, err
+	}
+	if response.Err != "" {
+		return nil, fmt.Errorf("unable to get provider QoS capabilities, %s", response.Err)
+	}
+	if response.Capabilities == nil {
+		return nil, fmt.Errorf("provider returned no QoS capabilities")
+	}
+	log.Debugf("provider QoS capabilities %+v", response.Capabilities)
+	qosCapabilities = response.Capabilities
+	return qosCapabilities, nil
+}
+
+// ValidateQosOptions checks the limitIOPS, limitMBPS and perfPolicy options against the provider
+// capabilities so that unsupported QoS settings are rejected before the request is submitted to
+// the provider.  A limit of -1 (unlimited) is always accepted.
+func (c *QosCapabilities) ValidateQosOptions(opts map[string]interface{}) error {
+	log.Tracef(">>> ValidateQosOptions called with %v", opts)
+	defer log.Trace("<<< ValidateQosOptions")
+
+	if value, ok := opts[QosPerfPolicyOpt]; ok && len(c.PerfPolicies) != 0 {
+		if !containsFold(c.PerfPolicies, fmt.Sprintf("%v", value)) {
+			return fmt.Errorf("unsupported %s %v, please enter one of the following options (%s)", QosPerfPolicyOpt, value, strings.Join(c.PerfPolicies, ", "))
+		}
+	}
+
+	limits := []struct {
+		option   string
+		min, max int64
+	}{
+		{QosLimitIopsOpt, c.MinIops, c.MaxIops},
+		{QosLimitMbpsOpt, c.MinMbps, c.MaxMbps},
+	}
+	for _, limit := range limits {
+		value, ok := opts[limit.option]
+		if !ok {
+			continue
+		}
+		// options from the config file are decoded as float64, options from docker as strings
+		number, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprintf("%v", value)), 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %v, %s", limit.option, value, err.Error())
+		}
+		if number == QosUnlimited {
+			continue
+		}
+		if limit.min > 0 && number < float64(limit.min) {
+			return fmt.Errorf("unsupported %s %v, minimum is %d", limit.option, value, limit.min)
+		}
+		if limit.max > 0 && number > float64(limit.max) {
+			return fmt.Errorf("unsupported %s %v, maximum is %d", limit.option, value, limit.max)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package provider
+
+import (
+	"testing"
+)
+
+func TestValidateQosOptions(t *testing.T) {
+	capabilities := &QosCapabilities{PerfPolicies: []string{"default", "SQL Server"}, MinIops: 256, MaxIops: 4294967294, MinMbps: 1}
+
+	tests := []struct {
+		opts  map[string]interface{}
+		valid bool
+	}{
+		{map[string]interface{}{}, true},
+		{map[string]interface{}{QosPerfPolicyOpt: "sql server", QosLimitIopsOpt: "1000", QosLimitMbpsOpt: float64(100)}, true},
+		{map[string]interface{}{QosLimitIopsOpt: "-1", QosLimitMbpsOpt: float64(-1)}, true},
+		{map[string]interface{}{QosPerfPolicyOpt: "Exchange"}, false},
+		{map[string]interface{}{QosLimitIopsOpt: "100"}, false},
+		{map[string]interface{}{QosLimitIopsOpt: "5000000000"}, false},
+		{map[string]interface{}{QosLimitMbpsOpt: "fast"}, false},
+	}
+	for _, tc := range tests {
+		if err := capabilities.ValidateQosOptions(tc.opts); (err == nil) != tc.valid {
+			t.Errorf("ValidateQosOptions(%v) returned err=%v, expected valid=%v", tc.opts, err, tc.valid)
+		}
+	}
+
+	// Without capabilities, any QoS setting is left for the provider to validate
+	if err := (&QosCapabilities{}).ValidateQosOptions(map[string]interface{}{QosPerfPolicyOpt: "Exchange", QosLimitIopsOpt: "100"}); err != nil {
+		t.Errorf("unexpected err=%v", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package provider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// Provider requests carry the provider access keys in their payload (see User).  With
+// PROVIDER_REQUEST_SIGNING=true, the access secret is no longer sent; instead every request is
+// signed with an HMAC-SHA256 of the request, keyed by the access secret, along with a timestamp and
+// a random nonce so that the provider can reject stale and replayed requests:
+//
+//	X-Hpe-Access-Key	access key whose secret signed the request
+//	X-Hpe-Timestamp		request time, in seconds since the epoch
+//	X-Hpe-Nonce			random value, unique to the request
+//	X-Hpe-Signature		hex HMAC-SHA256 of "method\nrequest uri\ntimestamp\nnonce\nhex SHA-256 of body"
+//
+// The access keys are read from PROVIDER_USERNAME/PROVIDER_PASSWORD or, to support key rotation
+// without restarting the plugin, from the JSON file named by PROVIDER_CREDENTIALS_FILE (e.g. a
+// mounted secret, {"access_key": "...", "access_secret": "..."}), which is read again whenever it
+// is modified.
+
+const (
+	// EnvRequestSigning enables provider request signing
+	EnvRequestSigning = "PROVIDER_REQUEST_SIGNING"
+	// EnvCredentialsFile represents the provider access keys file, read again when rotated
+	EnvCredentialsFile = "PROVIDER_CREDENTIALS_FILE"
+
+	// Request signing headers
+	signingAccessKeyHeader = "X-Hpe-Access-Key"
+	signingTimestampHeader = "X-Hpe-Timestamp"
+	signingNonceHeader     = "X-Hpe-Nonce"
+	signingSignatureHeader = "X-Hpe-Signature"
+
+	signingNonceBytes = 16
+)
+
+var (
+	// access keys read from the credentials file, and the file modification time they were read at
+	credentialsFileUser    *User
+	credentialsFileModTime time.Time
+	credentialsFileLock    sync.Mutex
+)
+
+// signingTransport is an http.RoundTripper that signs each request with the provider access keys
+type signingTransport struct {
+	transport   http.RoundTripper
+	credentials func() (*User, error)
+	now         func() time.Time
+}
+
+// IsRequestSigningEnabled returns true if provider requests are signed
+func IsRequestSigningEnabled() bool {
+	return strings.EqualFold(os.Getenv(EnvRequestSigning), "true")
+}
+
+// newSigningTransport returns a signingTransport sending the signed requests over the given
+// transport (http.DefaultTransport if nil)
+func newSigningTransport(transport http.RoundTripper) *signingTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &signingTransport{transport: transport, credentials: getProviderCredentials, now: time.Now}
+}
+
+// RoundTrip signs the request, with a new timestamp and nonce, and sends it
+func (t *signingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	user, err := t.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	// Hash the request body and rewind it so that it can still be sent (and failed over)
+	var body []byte
+	if request.Body != nil && request.Body != http.NoBody {
+		body, err = ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	signed := request.Clone(request.Context())
+	if body != nil {
+		signed.Body = ioutil.NopCloser(bytes.NewReader(body))
+		signed.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	nonce, err := newSigningNonce()
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(t.now().Unix(), 10)
+	signed.Header.Set(signingAccessKeyHeader, user.AccessKey)
+	signed.Header.Set(signingTimestampHeader, timestamp)
+	signed.Header.Set(signingNonceHeader, nonce)
+	signed.Header.Set(signingSignatureHeader, signRequest(user.AccessSecret, signed.Method, signed.URL.RequestURI(), timestamp, nonce, body))
+	return t.transport.RoundTrip(signed)
+}
+
+// signRequest returns the hex HMAC-SHA256 signature of the given request fields
+func signRequest(secret, method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{method, requestURI, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSigningNonce returns a random request nonce
+func newSigningNonce() (string, error) {
+	nonce := make([]byte, signingNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce), nil
+}
+
+// getProviderCredentials returns the provider access keys, including the access secret, from the
+// credentials file if configured or else from env
+func getProviderCredentials() (*User, error) {
+	if credentialsFile := os.Getenv(EnvCredentialsFile); credentialsFile != "" {
+		return readCredentialsFile(credentialsFile)
+	}
+	accessKey := os.Getenv(EnvUsername)
+	if accessKey == "" {
+		return nil, fmt.Errorf("env variable %s is not provided", EnvUsername)
+	}
+	accessSecret := os.Getenv(EnvPassword)
+	if accessSecret == "" {
+		return nil, fmt.Errorf("env variable %s is not provided", EnvPassword)
+	}
+	return &User{AccessKey: accessKey, AccessSecret: accessSecret}, nil
+}
+
+// readCredentialsFile returns the access keys in the given credentials file, reading the file
+// again only if it was modified since last read
+func readCredentialsFile(credentialsFile string) (*User, error) {
+	credentialsFileLock.Lock()
+	defer credentialsFileLock.Unlock()
+
+	info, err := os.Stat(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read provider credentials file %s, %s", credentialsFile, err.Error())
+	}
+	if credentialsFileUser != nil && info.ModTime().Equal(credentialsFileModTime) {
+		return credentialsFileUser, nil
+	}
+
+	data, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read provider credentials file %s, %s", credentialsFile, err.Error())
+	}
+	user := &User{}
+	if err = json.Unmarshal(data, user); err != nil {
+		return nil, fmt.Errorf("invalid provider credentials file %s, %s", credentialsFile, err.Error())
+	}
+	if user.AccessKey == "" || user.AccessSecret == "" {
+		return nil, fmt.Errorf("provider credentials file %s must set access_key and access_secret", credentialsFile)
+	}
+	if credentialsFileUser != nil && credentialsFileUser.AccessKey != user.AccessKey {
+		log.Infof("provider access key rotated from %s to %s", credentialsFileUser.AccessKey, user.AccessKey)
+	}
+	credentialsFileUser, credentialsFileModTime = user, info.ModTime()
+	return user, nil
+}
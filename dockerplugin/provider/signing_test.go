@@ -0,0 +1,139 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package provider
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hpe-storage/common-host-libs/connectivity"
+)
+
+func TestSigningTransport(t *testing.T) {
+	defer os.Unsetenv(EnvRequestSigning)
+	defer os.Unsetenv(EnvUsername)
+	defer os.Unsetenv(EnvPassword)
+	os.Setenv(EnvRequestSigning, "true")
+	os.Setenv(EnvUsername, "key1")
+	os.Setenv(EnvPassword, "secret1")
+
+	nonces := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		nonce := r.Header.Get(signingNonceHeader)
+		expected := signRequest("secret1", r.Method, r.URL.RequestURI(), r.Header.Get(signingTimestampHeader), nonce, body)
+		if r.Header.Get(signingAccessKeyHeader) != "key1" || r.Header.Get(signingSignatureHeader) != expected || nonces[nonce] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		nonces[nonce] = true
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client, err := newProviderClient([]string{server.URL + "/base"}, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := GetProviderAccessKeys()
+	if err != nil || user.AccessKey != "key1" || user.AccessSecret != "" {
+		t.Fatalf("unexpected access keys %+v, err=%v", user, err)
+	}
+
+	// Each request is signed, with its own nonce, and its payload is delivered unchanged
+	for i := 0; i < 2; i++ {
+		response := &struct{ User *User }{}
+		if _, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: CreateURI, Payload: &struct{ User *User }{User: user}, Response: response}); err != nil {
+			t.Fatal(err)
+		}
+		if response.User == nil || *response.User != *user {
+			t.Errorf("unexpected response %+v", response)
+		}
+	}
+	if len(nonces) != 2 {
+		t.Errorf("expected 2 nonces, got %v", len(nonces))
+	}
+}
+
+func TestSigningFailover(t *testing.T) {
+	defer os.Unsetenv(EnvRequestSigning)
+	defer os.Unsetenv(EnvUsername)
+	defer os.Unsetenv(EnvPassword)
+	os.Setenv(EnvRequestSigning, "true")
+	os.Setenv(EnvUsername, "key1")
+
+	// The access secret is required to sign requests
+	if _, err := getProviderCredentials(); err == nil {
+		t.Error("expected error without an access secret")
+	}
+	os.Setenv(EnvPassword, "secret1")
+
+	// Each failover attempt is signed with its own nonce
+	var nonces []string
+	handler := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			nonces = append(nonces, r.Header.Get(signingNonceHeader))
+			w.WriteHeader(status)
+			w.Write([]byte("{}"))
+		}
+	}
+	unavailable := httptest.NewServer(handler(http.StatusServiceUnavailable))
+	defer unavailable.Close()
+	healthy := httptest.NewServer(handler(http.StatusOK))
+	defer healthy.Close()
+
+	client, err := newProviderClient([]string{unavailable.URL + "/base", healthy.URL + "/base"}, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.DoJSON(&connectivity.Request{Action: "POST", Path: CreateURI, Payload: &struct{ Name string }{Name: "vol1"}, Response: &struct{}{}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(nonces) != 2 || nonces[0] == "" || nonces[0] == nonces[1] {
+		t.Errorf("expected a new nonce for each attempt, got %v", nonces)
+	}
+}
+
+func TestReadCredentialsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "provider")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(EnvCredentialsFile)
+	credentialsFile := filepath.Join(dir, "credentials.json")
+	os.Setenv(EnvCredentialsFile, credentialsFile)
+
+	if _, err = getProviderCredentials(); err == nil {
+		t.Error("expected error reading missing credentials file")
+	}
+
+	// The file is read again once rotated
+	for i, keys := range []string{`{"access_key":"key1","access_secret":"secret1"}`, `{"access_key":"key2","access_secret":"secret2"}`} {
+		if err = ioutil.WriteFile(credentialsFile, []byte(keys), 0600); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(time.Duration(i) * time.Minute)
+		os.Chtimes(credentialsFile, modTime, modTime)
+		user, err := getProviderCredentials()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := []string{"key1", "key2"}[i]; user.AccessKey != expected {
+			t.Errorf("expected access key %v, got %v", expected, user.AccessKey)
+		}
+	}
+
+	if err = ioutil.WriteFile(credentialsFile, []byte(`{"access_key":"key3"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(credentialsFile, time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+	if _, err = getProviderCredentials(); err == nil {
+		t.Error("expected error reading credentials file without a secret")
+	}
+}
@@ -26,9 +26,9 @@ func getSimplivityContainerProviderClient() (*connectivity.Client, error) {
 	log.Trace(">>> getSimplivityContainerProviderClient")
 	defer log.Trace("<<< getSimplivityContainerProviderClient")
 
-	providerURI, err := GetProviderURI(defaultSimplivityProviderPortal, defaultSimplivityProviderPort, defaultSimplivityBasePath)
+	providerURIs, err := GetProviderURIs(defaultSimplivityProviderPortal, defaultSimplivityProviderPort, defaultSimplivityBasePath)
 	if err != nil {
 		return nil, err
 	}
-	return connectivity.NewHTTPClientWithTimeout(providerURI, providerClientTimeout), nil
+	return newProviderClient(providerURIs, nil, providerClientTimeout)
 }
@@ -14,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hpe-storage/common-host-libs/blockdevice"
 	log "github.com/hpe-storage/common-host-libs/logger"
 	"github.com/hpe-storage/common-host-libs/model"
 	"github.com/hpe-storage/common-host-libs/mpathconfig"
@@ -29,7 +30,6 @@ const (
 	dmPrefix           = "dm-"
 	dmUUIDdFormat      = "/sys/block/dm-%s/dm/uuid"
 	dmNameFormat       = "/sys/block/dm-%s/dm/name"
-	dmSizeFormat       = "/sys/block/dm-%s/size"
 	devMapperPath      = "/dev/mapper/"
 	failedDevPath      = "failed to get device path"
 	notBlockDevice     = "not a block device"
@@ -40,7 +40,7 @@ const (
 	sysBlockHolders    = "/sys/block/%s/holders/"
 	holderPattern      = "^.*dm-"
 	countdownTicker    = 5
-	sectorstoMiBFactor = 2 * 1024
+	bytesPerMiB        = 1024 * 1024
 	procScsiPath       = "/proc/scsi/scsi"
 	procScsiPathLocal  = "/proc_local/scsi/scsi"
 	// HCTL format in /proc/scsi/scsi
@@ -345,18 +345,12 @@ func isLunIdPresentIn(searchLun string, luns []int32) bool {
 }
 
 func getSizeOfDeviceInMiB(minorDev string, device *model.Device) (int64, error) {
-	sizeFileName := fmt.Sprintf(dmSizeFormat, minorDev)
-	size, err := util.FileReadFirstLine(sizeFileName)
+	blockDevice, err := blockdevice.GetDevice(dmPrefix + minorDev)
 	if err != nil {
 		err = fmt.Errorf("unable to get size for device: %s Err: %s", device.Pathname, err.Error())
 		return -1, err
 	}
-	sizeInSector, err := strconv.ParseInt(size, 10, 0)
-	if err != nil {
-		err = fmt.Errorf("unable to parse size for device: %s Err: %s", device.Pathname, err.Error())
-		return -1, err
-	}
-	return sizeInSector / sectorstoMiBFactor, nil
+	return int64(blockDevice.Size / bytesPerMiB), nil
 }
 
 //GetMpathName for device
@@ -392,7 +386,7 @@ func setAltFullPathName(dev *model.Device) (err error) {
 
 // Helper function to perform rescan and detect the newly attached volume (FC/iSCSI volume)
 func rescanLoginVolume(volume *model.Volume) error {
-	log.Traceln(">>>>> rescanLoginVolume", volume, "and accessProtocol", volume.AccessProtocol)
+	log.Traceln(">>>>> rescanLoginVolume", volume.Redacted(), "and accessProtocol", volume.AccessProtocol)
 	defer log.Traceln("<<<< rescanLoginVolume")
 	var err error
 	var primaryVolObj *model.Volume
@@ -441,7 +435,7 @@ func rescanLoginVolume(volume *model.Volume) error {
 
 func rescanLoginVolumeForBackend(volObj *model.Volume) error {
 
-	log.Traceln("Called rescanLoginVolumeForBackend", volObj, "and accessProtocol", volObj.AccessProtocol)
+	log.Traceln("Called rescanLoginVolumeForBackend", volObj.Redacted(), "and accessProtocol", volObj.AccessProtocol)
 	var err error
 	if strings.EqualFold(volObj.AccessProtocol, "fc") {
 		// FC volume
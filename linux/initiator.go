@@ -66,6 +66,8 @@ func getIscsiInitiators() (init *model.Initiator, err error) {
 		return nil, errors.New("empty iqn found")
 	}
 	log.Debugf("got iscsi initiator name as %s", initiators[0])
+	// offload iSCSI ports may log in with their own iqn
+	initiators = append(initiators, GetOffloadInitiators(initiators)...)
 	// fetch CHAP credentials
 	chapInfo, err := GetChapInfo()
 	if err != nil {
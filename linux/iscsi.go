@@ -187,6 +187,11 @@ func loginToVolume(volume *model.Volume) (err error) {
 	if len(reachablePortals) == 0 {
 		return fmt.Errorf("none of the discovery portals provided [%+v] are reachable", volume.DiscoveryIPs)
 	}
+	// login through the requested offload iSCSI port, if any, instead of the software initiator
+	if volume.IscsiIface != "" {
+		return loginToVolumeWithOffloadIface(volume, reachablePortals)
+	}
+
 	// perform discovery and login to targets
 	discoveredTargets, err := PerformDiscovery(reachablePortals)
 	if err != nil {
@@ -201,8 +206,32 @@ func loginToVolume(volume *model.Volume) (err error) {
 		return fmt.Errorf("unable to retrieve iSCSI bound ifaces. Error: %s", err.Error())
 	}
 
-	// login to all targets for given volume
+	return loginToVolumeTargets(volume, discoveredTargets, ifaces)
+}
+
+// loginToVolumeWithOffloadIface performs discovery and login through the offload iSCSI iface
+// requested by the volume
+func loginToVolumeWithOffloadIface(volume *model.Volume, reachablePortals []string) (err error) {
+	log.Tracef(">>>>> loginToVolumeWithOffloadIface for volume %s, iface %s", volume.SerialNumber, volume.IscsiIface)
+	defer log.Trace("<<<<< loginToVolumeWithOffloadIface")
+
+	iface, err := getOffloadIface(volume.IscsiIface)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
 
+	// discovery must be performed through the offload iface to create its node records
+	discoveredTargets, err := performDiscovery(reachablePortals, iface.Name)
+	if err != nil {
+		return err
+	}
+	return loginToVolumeTargets(volume, discoveredTargets, []*model.Iface{iface})
+}
+
+// loginToVolumeTargets logs in to all the targets of the given volume using the given ifaces
+func loginToVolumeTargets(volume *model.Volume, discoveredTargets model.IscsiTargets, ifaces []*model.Iface) (err error) {
+	// login to all targets for given volume
 	for _, target := range volume.TargetNames() {
 		if volume.Chap == nil {
 			err = loginToTarget(discoveredTargets, target, ifaces, "", "", volume.ConnectionMode)
@@ -233,6 +262,7 @@ func HandleIscsiDiscovery(volume *model.Volume) (err error) {
 	primaryVolObj.DiscoveryIPs = volume.DiscoveryIPs
 	primaryVolObj.Chap = volume.Chap
 	primaryVolObj.ConnectionMode = volume.ConnectionMode
+	primaryVolObj.IscsiIface = volume.IscsiIface
 	primaryVolObj.SerialNumber = volume.SerialNumber
 
 	err = handleIscsiDiscoveryForBackend(primaryVolObj, true)
@@ -252,6 +282,7 @@ func HandleIscsiDiscovery(volume *model.Volume) (err error) {
 		secondaryVolObj.DiscoveryIPs = secondaryLunInfo.DiscoveryIPs
 		secondaryVolObj.Chap = volume.Chap
 		secondaryVolObj.ConnectionMode = volume.ConnectionMode
+		secondaryVolObj.IscsiIface = volume.IscsiIface
 		secondaryVolObj.SerialNumber = volume.SerialNumber
 
 		err = handleIscsiDiscoveryForBackend(secondaryVolObj, true)
@@ -259,7 +290,7 @@ func HandleIscsiDiscovery(volume *model.Volume) (err error) {
 	return nil
 }
 func handleIscsiDiscoveryForBackend(volume *model.Volume, isPrimaryBackend bool) (err error) {
-	log.Tracef(">>>>> handleIscsiDiscoveryForBackend for volume obj : %v, isPrimary %v", volume, isPrimaryBackend)
+	log.Tracef(">>>>> handleIscsiDiscoveryForBackend for volume obj : %v, isPrimary %v", volume.Redacted(), isPrimaryBackend)
 	defer log.Tracef("<<<<< handleIscsiDiscoveryForBackend")
 	// determine if all required targets are already logged-in
 	loggedIn, err := areTargetsLoggedIn(volume.TargetNames())
@@ -434,6 +465,17 @@ func addTarget(target *model.IscsiTarget, ifaces []*model.Iface, chapUser, chapP
 
 	if len(ifaces) > 0 {
 		for _, iface := range ifaces {
+			// offload ifaces use their own network stack, so a ping test from the host is not
+			// meaningful; login directly through the offload iface
+			if iface.NetworkInterface == nil {
+				ifaceArgs := append(args, "-I", iface.Name)
+				out, _, err = util.ExecCommandOutput(iscsicmd, ifaceArgs)
+				if err != nil {
+					log.Debugf("iscsi login failed using offload iface %s, transport %s, Error: %s", iface.Name, iface.Transport, err.Error())
+				}
+				log.Trace("addTarget Response :", out)
+				continue
+			}
 			// verify if the target is reachable from this interface
 			reachable, err := isReachable(iface.NetworkInterface.AddressV4, target.Address)
 			if err != nil {
@@ -742,8 +784,13 @@ func GetIscsiNodesFromIsciadm() (a model.IscsiTargets, err error) {
 // PerformDiscovery : adds iscsi targets to iscsi database after performing
 // send targets
 func PerformDiscovery(discoveryIPs []string) (a model.IscsiTargets, err error) {
-	log.Tracef(">>>>> PerformDiscovery with discovery IPs %s", discoveryIPs)
-	defer log.Trace("<<<<< PerformDiscovery")
+	return performDiscovery(discoveryIPs, "")
+}
+
+// performDiscovery performs send targets discovery, through the given iface if not empty
+func performDiscovery(discoveryIPs []string, ifaceName string) (a model.IscsiTargets, err error) {
+	log.Tracef(">>>>> performDiscovery with discovery IPs %s, iface %s", discoveryIPs, ifaceName)
+	defer log.Trace("<<<<< performDiscovery")
 
 	iscsiMutex.Lock()
 	defer iscsiMutex.Unlock()
@@ -759,6 +806,9 @@ func PerformDiscovery(discoveryIPs []string) (a model.IscsiTargets, err error) {
 		}
 		if isDiscoveryIpReachable {
 			args := []string{"-m", "discovery", "-t", "st", "-p", discoveryIP, "-o", "new"}
+			if ifaceName != "" {
+				args = append(args, "-I", ifaceName)
+			}
 			out, _, err = util.ExecCommandOutput(iscsicmd, args)
 			if err != nil {
 				log.Error(err.Error())
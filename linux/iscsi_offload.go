@@ -0,0 +1,225 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package linux
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/model"
+	"github.com/hpe-storage/common-host-libs/util"
+)
+
+// iSCSI offload initiators (e.g. Emulex be2iscsi, QLogic qedi, Chelsio cxgb4i) register their own
+// iSCSI host, with their own iqn and IP address, under /sys/class/iscsi_host.  open-iscsi creates
+// an iface for each offload port, named <transport>.<hwaddress>, and logins through that iface
+// (iscsiadm -I) are handled by the offload port rather than the software initiator (tcp).
+
+const (
+	ifaceTransportTCP     = "tcp"
+	iscsiTCPDriver        = "iscsi_tcp"
+	ifaceEmptyValue       = "<empty>"
+	iscsiHostNullValue    = "<NULL>"
+	scsiHostProcName      = "proc_name"
+	ifaceRecordFieldCount = 5 // transport_name,hwaddress,ipaddress,net_ifacename,initiatorname
+)
+
+var (
+	// offloadTransports are the iSCSI transports handled by an offload initiator
+	offloadTransports = map[string]bool{
+		"be2iscsi": true,
+		"bnx2i":    true,
+		"cxgb3i":   true,
+		"cxgb4i":   true,
+		"qedi":     true,
+		"qla4xxx":  true,
+	}
+
+	// sysfs class directories; variables so that tests can redirect them
+	iscsiHostClassPath = iscsiHostPathFormat
+	scsiHostClassPath  = "/sys/class/scsi_host"
+)
+
+// IsOffloadTransport returns true if the given iSCSI transport is handled by an offload initiator
+func IsOffloadTransport(transport string) bool {
+	return offloadTransports[strings.ToLower(transport)]
+}
+
+// GetIscsiHosts returns the iSCSI hosts (software and offload) on this host
+func GetIscsiHosts() ([]*model.IscsiHost, error) {
+	log.Trace(">>>>> GetIscsiHosts")
+	defer log.Trace("<<<<< GetIscsiHosts")
+
+	files, err := ioutil.ReadDir(iscsiHostClassPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Trace("no iscsi hosts found")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get list of iscsi hosts, error %s", err.Error())
+	}
+
+	var iscsiHosts []*model.IscsiHost
+	for _, file := range files {
+		iscsiHosts = append(iscsiHosts, getIscsiHost(file.Name()))
+	}
+	return iscsiHosts, nil
+}
+
+// GetOffloadIscsiHosts returns the offload iSCSI hosts (e.g. be2iscsi, qedi, cxgb4i) on this host
+func GetOffloadIscsiHosts() ([]*model.IscsiHost, error) {
+	iscsiHosts, err := GetIscsiHosts()
+	if err != nil {
+		return nil, err
+	}
+	var offloadHosts []*model.IscsiHost
+	for _, iscsiHost := range iscsiHosts {
+		if IsOffloadTransport(iscsiHost.Transport) {
+			log.Tracef("found offload iscsi host %s, transport %s", iscsiHost.Name, iscsiHost.Transport)
+			offloadHosts = append(offloadHosts, iscsiHost)
+		}
+	}
+	return offloadHosts, nil
+}
+
+// getIscsiHost reads the sysfs attributes of the given iSCSI host
+func getIscsiHost(hostName string) *model.IscsiHost {
+	iscsiHost := &model.IscsiHost{Name: hostName}
+
+	// The SCSI host driver identifies the transport; the software initiator is iscsi_tcp
+	driver := readIscsiHostAttribute(filepath.Join(scsiHostClassPath, hostName, scsiHostProcName))
+	if driver == iscsiTCPDriver {
+		driver = ifaceTransportTCP
+	}
+	iscsiHost.Transport = strings.ToLower(driver)
+
+	hostPath := filepath.Join(iscsiHostClassPath, hostName)
+	iscsiHost.InitiatorName = readIscsiHostAttribute(filepath.Join(hostPath, "initiatorname"))
+	iscsiHost.IPAddress = readIscsiHostAttribute(filepath.Join(hostPath, "ipaddress"))
+	iscsiHost.HwAddress = strings.ToLower(readIscsiHostAttribute(filepath.Join(hostPath, "hwaddress")))
+	iscsiHost.NetDev = readIscsiHostAttribute(filepath.Join(hostPath, "netdev"))
+	return iscsiHost
+}
+
+// readIscsiHostAttribute returns the given sysfs attribute, or an empty string if the attribute
+// is not supported by the driver
+func readIscsiHostAttribute(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	value := strings.TrimSpace(string(data))
+	if value == iscsiHostNullValue {
+		return ""
+	}
+	return value
+}
+
+// GetOffloadIfaces returns the open-iscsi ifaces of the offload iSCSI ports
+func GetOffloadIfaces() ([]*model.Iface, error) {
+	log.Trace(">>>>> GetOffloadIfaces")
+	defer log.Trace("<<<<< GetOffloadIfaces")
+
+	// iscsiadm -m iface
+	out, _, err := util.ExecCommandOutput(iscsicmd, []string{"-m", "iface"})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list iscsi ifaces, error %s", err.Error())
+	}
+
+	var offloadIfaces []*model.Iface
+	for _, iface := range parseIfaceRecords(out) {
+		if IsOffloadTransport(iface.Transport) {
+			offloadIfaces = append(offloadIfaces, iface)
+		}
+	}
+	return offloadIfaces, nil
+}
+
+// parseIfaceRecords parses the "iscsiadm -m iface" output.  Each line is formatted as "<iface name>
+// <transport_name>,<hwaddress>,<ipaddress>,<net_ifacename>,<initiatorname>".
+func parseIfaceRecords(out string) []*model.Iface {
+	var ifaces []*model.Iface
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		values := strings.Split(fields[1], ",")
+		if len(values) != ifaceRecordFieldCount {
+			continue
+		}
+		for i := range values {
+			if values[i] == ifaceEmptyValue {
+				values[i] = ""
+			}
+		}
+		ifaces = append(ifaces, &model.Iface{
+			Name:          fields[0],
+			Transport:     values[0],
+			HwAddress:     strings.ToLower(values[1]),
+			IPAddress:     values[2],
+			InitiatorName: values[4],
+		})
+	}
+	return ifaces
+}
+
+// getOffloadIface returns the offload iface with the given name.  The offload port may also be
+// identified by its iSCSI host name (e.g. "host3"), in which case the iface with the same hardware
+// address is returned.
+func getOffloadIface(name string) (*model.Iface, error) {
+	log.Tracef(">>>>> getOffloadIface, name=%s", name)
+	defer log.Trace("<<<<< getOffloadIface")
+
+	offloadIfaces, err := GetOffloadIfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range offloadIfaces {
+		if iface.Name == name {
+			return iface, nil
+		}
+	}
+
+	offloadHosts, err := GetOffloadIscsiHosts()
+	if err != nil {
+		return nil, err
+	}
+	for _, iscsiHost := range offloadHosts {
+		if iscsiHost.Name != name || iscsiHost.HwAddress == "" {
+			continue
+		}
+		for _, iface := range offloadIfaces {
+			if iface.Transport == iscsiHost.Transport && iface.HwAddress == iscsiHost.HwAddress {
+				return iface, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("offload iscsi iface %s not found", name)
+}
+
+// GetOffloadInitiators returns the initiator iqns of the offload iSCSI hosts that are not already
+// in the given initiator list
+func GetOffloadInitiators(initiators []string) []string {
+	offloadHosts, err := GetOffloadIscsiHosts()
+	if err != nil {
+		log.Debugf("unable to get offload iscsi hosts, error %s", err.Error())
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, initiator := range initiators {
+		seen[initiator] = true
+	}
+	var offloadInitiators []string
+	for _, iscsiHost := range offloadHosts {
+		if iscsiHost.InitiatorName != "" && !seen[iscsiHost.InitiatorName] {
+			seen[iscsiHost.InitiatorName] = true
+			offloadInitiators = append(offloadInitiators, iscsiHost.InitiatorName)
+		}
+	}
+	return offloadInitiators
+}
@@ -1,8 +1,12 @@
 package linux
 
 import (
-	"github.com/hpe-storage/common-host-libs/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/hpe-storage/common-host-libs/model"
 )
 
 func TestDeleteEmptyTarget(t *testing.T) {
@@ -16,3 +20,75 @@ func TestDeleteEmptyTarget(t *testing.T) {
 		t.Error("empty target should not be allowed to be deleted")
 	}
 }
+
+func TestParseIfaceRecords(t *testing.T) {
+	out := `default tcp,<empty>,<empty>,<empty>,<empty>
+iser iser,<empty>,<empty>,<empty>,<empty>
+iface_eth1 tcp,<empty>,<empty>,eth1,<empty>
+be2iscsi.00:90:FA:12:34:56 be2iscsi,00:90:FA:12:34:56,10.1.1.50,<empty>,iqn.1990-07.com.emulex:host1
+`
+	ifaces := parseIfaceRecords(out)
+	if len(ifaces) != 4 {
+		t.Fatalf("expected 4 ifaces, got %v", len(ifaces))
+	}
+	offload := ifaces[3]
+	if offload.Name != "be2iscsi.00:90:FA:12:34:56" || offload.Transport != "be2iscsi" || offload.HwAddress != "00:90:fa:12:34:56" ||
+		offload.IPAddress != "10.1.1.50" || offload.InitiatorName != "iqn.1990-07.com.emulex:host1" {
+		t.Errorf("unexpected offload iface %+v", offload)
+	}
+	if IsOffloadTransport(ifaces[0].Transport) || IsOffloadTransport(ifaces[1].Transport) || !IsOffloadTransport(offload.Transport) {
+		t.Error("unexpected offload transport classification")
+	}
+}
+
+func TestGetOffloadIscsiHosts(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "iscsi_host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testDir)
+
+	savedIscsiHostClassPath, savedScsiHostClassPath := iscsiHostClassPath, scsiHostClassPath
+	defer func() { iscsiHostClassPath, scsiHostClassPath = savedIscsiHostClassPath, savedScsiHostClassPath }()
+	iscsiHostClassPath = filepath.Join(testDir, "iscsi_host")
+	scsiHostClassPath = filepath.Join(testDir, "scsi_host")
+
+	testFiles := map[string]string{
+		"scsi_host/host2/proc_name":      "iscsi_tcp\n",
+		"iscsi_host/host2/initiatorname": "<NULL>\n",
+		"scsi_host/host3/proc_name":      "qedi\n",
+		"iscsi_host/host3/initiatorname": "iqn.1986-03.com.hp:qedi.host3\n",
+		"iscsi_host/host3/ipaddress":     "10.1.1.51\n",
+		"iscsi_host/host3/hwaddress":     "00:0E:1E:AA:BB:CC\n",
+	}
+	for path, data := range testFiles {
+		if err = os.MkdirAll(filepath.Dir(filepath.Join(testDir, path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(testDir, path), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	iscsiHosts, err := GetIscsiHosts()
+	if err != nil || len(iscsiHosts) != 2 {
+		t.Fatalf("expected 2 iscsi hosts, got %v, err=%v", iscsiHosts, err)
+	}
+	if iscsiHosts[0].Transport != ifaceTransportTCP || iscsiHosts[0].InitiatorName != "" {
+		t.Errorf("unexpected software iscsi host %+v", iscsiHosts[0])
+	}
+
+	offloadHosts, err := GetOffloadIscsiHosts()
+	if err != nil || len(offloadHosts) != 1 {
+		t.Fatalf("expected 1 offload iscsi host, got %v, err=%v", offloadHosts, err)
+	}
+	expected := model.IscsiHost{Name: "host3", Transport: "qedi", InitiatorName: "iqn.1986-03.com.hp:qedi.host3", IPAddress: "10.1.1.51", HwAddress: "00:0e:1e:aa:bb:cc"}
+	if *offloadHosts[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, *offloadHosts[0])
+	}
+
+	initiators := GetOffloadInitiators([]string{"iqn.1994-05.com.redhat:host", "iqn.1986-03.com.hp:qedi.host3"})
+	if len(initiators) != 0 {
+		t.Errorf("expected no additional initiators, got %v", initiators)
+	}
+}
@@ -98,12 +98,8 @@ func GetMountPointsForDevices(devices []*model.Device) ([]*model.Mount, error) {
 	if err != nil {
 		return nil, err
 	}
-	mountLines := strings.Split(out, "\n")
-	for _, line := range mountLines {
-		entry := strings.Fields(line)
-		if len(entry) > 3 {
-			devToMounts[entry[0]] = append(devToMounts[entry[0]], entry[2])
-		}
+	for _, entry := range util.ParseMountOutput(out) {
+		devToMounts[entry.Source] = append(devToMounts[entry.Source], entry.MountPoint)
 	}
 
 	for _, dev := range devices {
@@ -688,29 +684,32 @@ func GetFilesystemType(devPath string) (string, error) {
 	if err != nil && len(out) != 0 {
 		return "", fmt.Errorf("Failed to verify if FS exists on device %s, %s", devPath, err.Error())
 	}
-	// TODO: Add RegEx validation
-	if len(out) != 0 {
-		// split using space as delimiter
-		list := strings.Split(out, " ")
-		for _, item := range list {
-			if strings.HasPrefix(item, "TYPE=") {
-				strs := strings.Split(item, "=")
-				if len(strs) >= 2 {
-					value := strs[1]
-					// Remove the newline chars if present
-					value = strings.Trim(value, "\n")
-					// Remove double quotes
-					fsType := strings.Trim(value, "\"")
-					log.Trace("Found filesystem type: ", fsType)
-					return fsType, nil
-				}
-			}
-		}
+	if fsType := util.ParseKeyValuePairs(out)["TYPE"]; fsType != "" {
+		log.Trace("Found filesystem type: ", fsType)
+		return fsType, nil
 	}
 	log.Trace("No filesystem found on the device ", devPath)
 	return "", nil
 }
 
+// GetFilesystemIdentity returns the UUID and label of the filesystem on the given device.  The
+// device is probed directly (blkid -p) rather than through the blkid cache, which can be stale
+// after the device behind a path changed.
+func GetFilesystemIdentity(devPath string) (uuid string, label string, err error) {
+	log.Trace(">>>>> GetFilesystemIdentity, devPath: ", devPath)
+	defer log.Trace("<<<<< GetFilesystemIdentity")
+
+	// Sample output format:
+	// # blkid -p /dev/mapper/21bab810d4d816c6a6c9ce900b13eb9ef
+	// /dev/mapper/21bab810d4d816c6a6c9ce900b13eb9ef: LABEL="data" UUID="63a91d01-b388-45fd-8ae3-ebe3b687200d" TYPE="xfs" USAGE="filesystem"
+	out, _, err := util.ExecCommandOutput(blkid, []string{"-p", devPath})
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to probe filesystem on device %s, %s", devPath, err.Error())
+	}
+	pairs := util.ParseKeyValuePairs(out)
+	return pairs["UUID"], pairs["LABEL"], nil
+}
+
 func mountForPartition(devPath, mountPoint string, options []string) (mount *model.Mount, err error) {
 	log.Tracef("mountForPartition called for %s on %s", devPath, mountPoint)
 	// check if there are partitions
@@ -828,13 +827,15 @@ func updateDbConfiguration(lines []string, mountDir string) (updatedLines []stri
 
 // CheckFsCreationInProgress checks if mkfs process is using the device using lsof
 func CheckFsCreationInProgress(device model.Device) (inProgress bool, err error) {
-	args := []string{device.AltFullPathName}
+	args := []string{"-F", "c", device.AltFullPathName}
 	out, _, err := util.ExecCommandOutput(lsof, args)
 	if err != nil {
 		return false, fmt.Errorf("failed to verify if FS creation is in progress on device %s", err.Error())
 	}
-	if strings.Contains(out, "mkfs") {
-		return true, nil
+	for _, command := range util.ParseLsofCommands(out) {
+		if strings.HasPrefix(command, "mkfs") {
+			return true, nil
+		}
 	}
 	return false, nil
 }
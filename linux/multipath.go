@@ -17,7 +17,6 @@ import (
 var (
 	showPathsFormat      = []string{"show", "paths", "format", "%w %d %t %i %o %T %z %s %m"}
 	showMapsFormat       = []string{"show", "maps", "format", "%w %d %n %s"}
-	orphanPathRegexp     = regexp.MustCompile(getOrphanPathsPattern())
 	multipathMutex       sync.Mutex
 	DeviceVendorPatterns = []string{"Nimble", "3PARdata", "TrueNAS", "FreeNAS"}
 )
@@ -26,16 +25,24 @@ const (
 	// MultipathConf configuration file for multipathd
 	MultipathConf = "/etc/multipath.conf"
 	// MultipathBindings bindings file for multipathd
-	MultipathBindings  = "/etc/multipath/bindings"
-	orphanPathsPattern = ".*\\s+(?P<host>\\d+):(?P<channel>\\d+):(?P<target>\\d+):(?P<lun>\\d+).*(REPLACE_VENDOR).*orphan"
-	maxTries           = 3
+	MultipathBindings = "/etc/multipath/bindings"
+	maxTries          = 3
+
+	// multipathd column names of the showPathsFormat and showMapsFormat wildcards
+	multipathdUUIDColumn      = "uuid"
+	multipathdDevColumn       = "dev"
+	multipathdSysfsColumn     = "sysfs"
+	multipathdNameColumn      = "name"
+	multipathdDmStateColumn   = "dm_st"
+	multipathdHcilColumn      = "hcil"
+	multipathdChkStateColumn  = "chk_st"
+	multipathdVendorColumn    = "vend/prod/rev"
+	multipathdMultipathColumn = "multipath"
+
+	// multipathdOrphanMap is reported in the multipath column of the paths without a map
+	multipathdOrphanMap = "orphan"
 )
 
-func getOrphanPathsPattern() string {
-	vendorPattern := strings.Join(DeviceVendorPatterns, "|")
-	return strings.Replace(orphanPathsPattern, "REPLACE_VENDOR", vendorPattern, -1)
-}
-
 // MultipathdShowMaps output
 func MultipathdShowMaps(serialNumber string) (a []string, err error) {
 	log.Tracef(">>>>> MultipathdShowMaps for %s", serialNumber)
@@ -74,27 +81,35 @@ func isMultipathTimeoutError(msg string) bool {
 	return strings.Contains(msg, "timeout") || strings.Contains(msg, "receiving packet")
 }
 
-func multipathShowCmdOrphanPaths() (output []string, err error) {
-	log.Trace(">>>>> multipathShowCmdOrphanPaths")
-	defer log.Trace("<<<<<< multipathShowCmdOrphanPaths")
+// isSupportedVendor returns true if the given multipathd vend/prod/rev matches one of the
+// DeviceVendorPatterns
+func isSupportedVendor(vendProdRev string) bool {
+	for _, vendor := range DeviceVendorPatterns {
+		if strings.Contains(vendProdRev, vendor) {
+			return true
+		}
+	}
+	return false
+}
 
+// multipathdShowTable runs the given multipathd show command and returns its rows keyed by
+// column name (see util.ParseColumnOutput)
+func multipathdShowTable(args []string) (rows []map[string]string, err error) {
 	multipathMutex.Lock()
 	defer multipathMutex.Unlock()
 
-	out, _, err := util.ExecCommandOutput(multipathd, showPathsFormat)
+	out, _, err := util.ExecCommandOutput(multipathd, args)
 	if err != nil {
-		log.Warnf("multipathdShowCmd: error %v with args %v", err, showPathsFormat)
+		log.Warnf("multipathdShowTable: error %v with args %v", err, args)
 		return nil, err
 	}
 	// rc can be 0 on the below error conditions as well
 	if isMultipathTimeoutError(out) {
-		err = fmt.Errorf("failed to get multipathd %v, out %s", showPathsFormat, out)
+		err = fmt.Errorf("failed to get multipathd %v, out %s", args, out)
 		log.Warn(err.Error())
 		return nil, err
 	}
-	// look for orphan paths
-	listMultipathShowCmdOut := orphanPathRegexp.FindAllString(out, -1)
-	return listMultipathShowCmdOut, nil
+	return util.ParseColumnOutput(out), nil
 }
 
 func multipathdShowCmd(args []string, serialNumber string) (output []string, err error) {
@@ -127,23 +142,17 @@ func tearDownMultipathDevice(dev *model.Device) (err error) {
 	log.Tracef(">>>>> tearDownMultipathDevice called for %s", dev.SerialNumber)
 	defer log.Trace("<<<<< tearDownMultipathDevice")
 
-	lines, err := MultipathdShowMaps(dev.SerialNumber)
+	maps, err := multipathdShowTable(showMapsFormat)
 	if err != nil {
 		log.Trace(err)
 		return err
 	}
-	for _, line := range lines {
-		if strings.Contains(line, dev.SerialNumber) {
-			entry := strings.Fields(line)
-			if strings.Contains(entry[0], dev.SerialNumber) {
-				// entry[0] --> uuid
-				// entry[1] --> dm device name
-				// entry[2] --> dm map name
-				log.Debugf("uuid %s dm %s map name %s", entry[0], entry[1], entry[2])
-				err := retryCleanupDeviceAndSlaves(dev)
-				if err != nil {
-					return err
-				}
+	for _, mpath := range maps {
+		if strings.Contains(mpath[multipathdUUIDColumn], dev.SerialNumber) {
+			log.Debugf("uuid %s dm %s map name %s", mpath[multipathdUUIDColumn], mpath[multipathdSysfsColumn], mpath[multipathdNameColumn])
+			err := retryCleanupDeviceAndSlaves(dev)
+			if err != nil {
+				return err
 			}
 		}
 	}
@@ -414,73 +423,58 @@ func multipathGetOrphanPathsByLunID(lunID string) []string {
 	log.Tracef(">>>> multipathGetOrphanPathsByLunID called with %s", lunID)
 	defer log.Trace("<<<<< multipathGetOrphanPathsByLunID")
 	var hctls []string
-	lines, _ := multipathShowCmdOrphanPaths()
-	if len(lines) == 0 {
-		log.Tracef("no orphan paths found for lunID %s", lunID)
-		return hctls
-	}
-
-	for _, line := range lines {
-		result := util.FindStringSubmatchMap(line, orphanPathRegexp)
-		lun := result["lun"]
-		if lun == lunID {
-			hctl := result["host"] + ":" + result["channel"] + ":" + result["target"] + ":" + lun
+	paths, _ := multipathdShowTable(showPathsFormat)
+	for _, path := range paths {
+		if path[multipathdMultipathColumn] != multipathdOrphanMap || !isSupportedVendor(path[multipathdVendorColumn]) {
+			continue
+		}
+		hctl := path[multipathdHcilColumn]
+		if parts := strings.Split(hctl, ":"); len(parts) == 4 && parts[3] == lunID {
 			log.Debugf("orphan h:c:t:l found is %s", hctl)
 			hctls = append(hctls, hctl)
 		}
 	}
+	if len(hctls) == 0 {
+		log.Tracef("no orphan paths found for lunID %s", lunID)
+	}
 	return hctls
 }
 
 // multipathGetPathsOfDevice : get all scsi paths and host, channel information of multipath device
-// nolint : gocyclo
 func multipathGetPathsOfDevice(dev *model.Device, needActivePath bool) (paths []*model.PathInfo, err error) {
 	log.Tracef(">>>> multipathGetPathsOfDevice with %s", dev.SerialNumber)
 	defer log.Trace("<<<<< multipathGetPathsOfDevice")
-	lines, err := MultipathdShowPaths(dev.SerialNumber)
+	rows, err := multipathdShowTable(showPathsFormat)
 	if err != nil {
 		return nil, err
 	}
-	if len(lines) == 0 && dev != nil && dev.SerialNumber != "" {
+	found := false
+	for _, row := range rows {
+		uuid := row[multipathdUUIDColumn]
+		if uuid == "" || !strings.Contains(uuid, dev.SerialNumber) {
+			continue
+		}
+		found = true
+		log.Tracef("path %+v device serial number is %s", row, dev.SerialNumber)
+		path := &model.PathInfo{
+			UUID:     uuid[1:],
+			Device:   row[multipathdDevColumn],
+			DmState:  row[multipathdDmStateColumn],
+			Hcil:     row[multipathdHcilColumn],
+			ChkState: row[multipathdChkStateColumn],
+		}
+		// return all paths if we don't need active paths, else only the paths with chk_st as ready
+		// and dm_st as active
+		if needActivePath && (path.DmState != model.ActiveState.String() || path.ChkState != "ready") {
+			continue
+		}
+		log.Tracef("needActive %v dev(%s) chk_st(%s) dm_st(%s) for uuid(%s)", needActivePath, path.Device, path.ChkState, path.DmState, uuid)
+		paths = append(paths, path)
+	}
+	if !found && dev.SerialNumber != "" {
 		log.Errorf("no paths found for multipath device %+v", dev)
 		return nil, nil
 	}
-	for _, line := range lines {
-		log.Tracef("line is %s device serial number is %s", line, dev.SerialNumber)
-		if strings.Contains(line, dev.SerialNumber) {
-			entry := strings.Fields(line)
-			// entry[0] uuid
-			// entry[1] dev
-			// entry[2] dm_st
-			// entry[3] hcil
-			// entry[4] dev_st
-			// entry[5] chk_st
-			// entry[6] checker
-			// return all paths if we don't need active paths
-			if !needActivePath {
-				log.Tracef("needActive %v dev(%s) dm_st(%s) hcil(%s) for uuid(%s)", needActivePath, entry[1], entry[2], entry[3], entry[0])
-				path := &model.PathInfo{
-					UUID:     entry[0][1:],
-					Device:   entry[1],
-					DmState:  entry[2],
-					Hcil:     entry[3],
-					ChkState: entry[5],
-				}
-				paths = append(paths, path)
-				// return only active paths with chk_st as ready and dm_st as active
-			} else if len(entry) >= 5 && entry[2] == model.ActiveState.String() && entry[5] == "ready" {
-				log.Tracef("needActive %v dev(%s) chk_st(%s) dm_st(%s) for uuid(%s)", needActivePath, entry[1], entry[5], entry[2], entry[0])
-				path := &model.PathInfo{
-					UUID:     entry[0][1:],
-					Device:   entry[1],
-					DmState:  entry[2],
-					Hcil:     entry[3],
-					ChkState: entry[5],
-				}
-				paths = append(paths, path)
-			}
-		}
-	}
 	if len(paths) > 0 {
 		log.Debugf("paths for the device %+v are)", dev)
 		for _, path := range paths {
@@ -379,34 +379,21 @@ func InstallPackage(packageType string) error {
 	return nil
 }
 
-func applySuseWorkaround() (err error) {
+func applySuseWorkaround(osInfo *OsInfo) (err error) {
 	// apply workaround for SuSE for multipathd.service, NLT-1226
 	if _, err = os.Stat("/usr/lib/systemd/system/multipathd.service"); err == nil {
-		args := []string{"-i", "s/^ConditionKernelCommandLine=!multipath=off/#ConditionKernelCommandLine=!multipath=off/", "/usr/lib/systemd/system/multipathd.service"}
-		_, _, err = util.ExecCommandOutput("sed", args)
+		// override the vendor unit file conditions with a drop-in, reloading systemd if it changed
+		err = applySystemdDropIns(osInfo, SystemdUnitMultipathd)
 		if err != nil {
 			log.Errorf("unable to apply suse workaround for multipathd.service package %v", err.Error())
 			return err
 		}
-		args = []string{"-i", "s/^ConditionKernelCommandLine=!nompath/#ConditionKernelCommandLine=!nompath/", "/usr/lib/systemd/system/multipathd.service"}
+		args := []string{"-i", "s/multipath=off/multipath=on/", "/boot/grub2/grub.cfg"}
 		_, _, err = util.ExecCommandOutput("sed", args)
 		if err != nil {
 			log.Errorf("unable to apply suse workaround for multipathd.service package %v", err.Error())
 			return err
 		}
-		args = []string{"-i", "s/multipath=off/multipath=on/", "/boot/grub2/grub.cfg"}
-		_, _, err = util.ExecCommandOutput("sed", args)
-		if err != nil {
-			log.Errorf("unable to apply suse workaround for multipathd.service package %v", err.Error())
-			return err
-		}
-		// reload service with changes
-		args = []string{"daemon-reload"}
-		_, _, err = util.ExecCommandOutput("systemctl", args)
-		if err != nil {
-			log.Errorf("unable to reload systemd after multipathd.service changes for sles %v\n", err.Error())
-			return err
-		}
 	}
 	return nil
 }
@@ -497,7 +484,7 @@ func getSuseBasedPackageArgs(packageType string, operation string) (cmd string,
 	return cmd, args
 }
 
-// EnableService enables given service type on the system, only iscsi and multipath service types are supported
+// EnableService enables given service type on the system, only iscsi, multipath and rpcbind service types are supported
 func EnableService(serviceType string) (err error) {
 	log.Tracef(">>>>> EnableService called with %s", serviceType)
 	defer log.Traceln("<<<<< EnableService")
@@ -523,26 +510,9 @@ func EnableService(serviceType string) (err error) {
 }
 
 func enableSystemdService(osInfo *OsInfo, serviceType string) (err error) {
-	var serviceName string
-	if serviceType == iscsi {
-		serviceName = "iscsid.service"
-	} else if serviceType == multipath {
-		serviceName = "multipathd.service"
-	} else {
-		return errors.New("unknown service type " + serviceType + " provided to enable")
-	}
-
-	args := []string{"daemon-reload"}
-	_, _, err = util.ExecCommandOutput("systemctl", args)
-	if err != nil {
-		log.Errorf("unable to enable %s service %v\n", serviceName, err.Error())
-		return err
-	}
-
-	args = []string{"enable", serviceName}
-	_, _, err = util.ExecCommandOutput("systemctl", args)
+	err = enableSystemdUnit(osInfo, serviceType)
 	if err != nil {
-		log.Errorf("unable to enable %s service %v\n", serviceName, err.Error())
+		log.Errorf("unable to enable %s service %v\n", serviceType, err.Error())
 		return err
 	}
 	return nil
@@ -559,6 +529,8 @@ func enableInitVService(osInfo *OsInfo, serviceType string) (err error) {
 		}
 	} else if serviceType == multipath {
 		serviceName = "multipathd"
+	} else if serviceType == rpcbind {
+		serviceName = rpcbind
 	} else {
 		return errors.New("unknown service type provided to enable")
 	}
@@ -587,6 +559,8 @@ func enableUbuntuService(osInfo *OsInfo, serviceType string) (err error) {
 	} else if serviceType == multipath {
 		// generic for all distros
 		serviceName = multipathd
+	} else if serviceType == rpcbind {
+		serviceName = rpcbind
 	} else {
 		return errors.New("unknown service type provided to enable")
 	}
@@ -600,7 +574,8 @@ func enableUbuntuService(osInfo *OsInfo, serviceType string) (err error) {
 	return nil
 }
 
-// ServiceCommand runs service command on given service, valid service types are iscsi and multipath
+// ServiceCommand runs service command on given service, valid service types are iscsi, multipath and rpcbind.
+// On systemd hosts the service's systemd unit is managed directly (see systemd.go).
 // nolint : gocyclo
 func ServiceCommand(serviceType string, operationType string) (err error) {
 	log.Tracef(">>>>> ServiceCommand called with type %s op %s", serviceType, operationType)
@@ -614,7 +589,7 @@ func ServiceCommand(serviceType string, operationType string) (err error) {
 	}
 
 	switch serviceType {
-	case iscsi, multipath:
+	case iscsi, multipath, rpcbind:
 	default:
 		return errors.New("invalid service type " + serviceType + " provided for service command")
 	}
@@ -627,12 +602,16 @@ func ServiceCommand(serviceType string, operationType string) (err error) {
 
 	if osInfo.GetOsDistro() == OsTypeSuse && serviceType == multipath {
 		// apply workaround for SuSE for multipathd.service, NLT-1226
-		err = applySuseWorkaround()
+		err = applySuseWorkaround(osInfo)
 		if err != nil {
 			return err
 		}
 	}
 
+	if osInfo.IsSystemdSupported() {
+		return systemdServiceCommand(serviceType, operationType)
+	}
+
 	// get suitable command args based on os type, package type and install operation
 	cmd, args := getServiceCommandArgs(osInfo, serviceType, operationType)
 	log.Traceln("running command ", cmd, " args: ", args)
@@ -645,8 +624,29 @@ func ServiceCommand(serviceType string, operationType string) (err error) {
 	return nil
 }
 
+// systemdServiceCommand runs the service command on the systemd unit of the given service type
+func systemdServiceCommand(serviceType string, operationType string) error {
+	unit, err := GetSystemdUnit(serviceType)
+	if err != nil {
+		return err
+	}
+	if operationType != "status" {
+		return SystemdUnitCommand(unit, operationType)
+	}
+	status, err := GetSystemdUnitStatus(unit)
+	if err != nil {
+		return err
+	}
+	if !status.IsActive() {
+		return fmt.Errorf("%s is %s (%s)", unit, status.ActiveState, status.SubState)
+	}
+	return nil
+}
+
 func getServiceCommandArgs(osInfo *OsInfo, packageType string, operation string) (cmd string, args []string) {
-	if packageType == multipath {
+	if packageType == rpcbind {
+		args = append(args, rpcbind)
+	} else if packageType == multipath {
 		// get distro specific iscsi service names
 		if osInfo.GetOsDistro() == OsTypeUbuntu && osInfo.GetOsMajorVersion() == "14" {
 			// ubuntu 14.* has multipath-tools service and 16.* has multipathd.service
@@ -0,0 +1,300 @@
+/*
+(c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package linux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/util"
+)
+
+const (
+	// SystemdUnitIscsid is the iscsid systemd unit
+	SystemdUnitIscsid = "iscsid.service"
+	// SystemdUnitMultipathd is the multipathd systemd unit
+	SystemdUnitMultipathd = "multipathd.service"
+	// SystemdUnitMultipathdSocket is the socket activating multipathd on some distributions
+	SystemdUnitMultipathdSocket = "multipathd.socket"
+	// SystemdUnitRpcbind is the rpcbind systemd unit, required by NFS
+	SystemdUnitRpcbind = "rpcbind.service"
+
+	// SystemdActiveStateActive is the active state of a running unit
+	SystemdActiveStateActive = "active"
+	// SystemdLoadStateNotFound is the load state of a unit that is not installed
+	SystemdLoadStateNotFound = "not-found"
+
+	// systemdDropIn is the name of the drop-in holding the overrides required by HPE storage
+	systemdDropIn = "hpe-storage"
+
+	// systemdShowProperties are the unit properties reported by GetSystemdUnitStatus
+	systemdShowProperties = "Id,LoadState,ActiveState,SubState,UnitFileState,MainPID"
+)
+
+var (
+	// systemdUnitPath is the directory holding the administrator's unit files and drop-ins
+	systemdUnitPath = "/etc/systemd/system/"
+
+	// systemdNameRegexp matches valid unit and drop-in names, which must not contain a path
+	systemdNameRegexp = regexp.MustCompile(`^[A-Za-z0-9:_.\\@-]+$`)
+
+	// systemdUnits maps the supported service types to their systemd unit
+	systemdUnits = map[string]string{
+		iscsi:     SystemdUnitIscsid,
+		multipath: SystemdUnitMultipathd,
+		rpcbind:   SystemdUnitRpcbind,
+	}
+)
+
+// SystemdUnitStatus is the state of a systemd unit as reported by "systemctl show"
+type SystemdUnitStatus struct {
+	Unit          string `json:"unit,omitempty"`
+	LoadState     string `json:"load_state,omitempty"`      // e.g. "loaded" or "not-found"
+	ActiveState   string `json:"active_state,omitempty"`    // e.g. "active", "inactive" or "failed"
+	SubState      string `json:"sub_state,omitempty"`       // e.g. "running", "dead" or "listening"
+	UnitFileState string `json:"unit_file_state,omitempty"` // e.g. "enabled", "disabled" or "static"
+	MainPID       int    `json:"main_pid,omitempty"`
+}
+
+// IsInstalled returns true if the unit file is installed
+func (s *SystemdUnitStatus) IsInstalled() bool {
+	return s.LoadState != "" && s.LoadState != SystemdLoadStateNotFound
+}
+
+// IsActive returns true if the unit is active (e.g. the service is running)
+func (s *SystemdUnitStatus) IsActive() bool {
+	return s.ActiveState == SystemdActiveStateActive
+}
+
+// IsEnabled returns true if the unit is started at boot
+func (s *SystemdUnitStatus) IsEnabled() bool {
+	return strings.HasPrefix(s.UnitFileState, "enabled")
+}
+
+// GetSystemdUnit returns the systemd unit of the given service type (iscsi, multipath or rpcbind)
+func GetSystemdUnit(serviceType string) (string, error) {
+	unit, ok := systemdUnits[serviceType]
+	if !ok {
+		return "", errors.New("invalid service type " + serviceType + " provided for systemd unit")
+	}
+	return unit, nil
+}
+
+// GetSystemdUnitStatus returns the state of the given systemd unit.  A unit that is not installed
+// is reported with the "not-found" load state rather than an error.
+func GetSystemdUnitStatus(unit string) (*SystemdUnitStatus, error) {
+	log.Tracef(">>>>> GetSystemdUnitStatus called with %s", unit)
+	defer log.Trace("<<<<< GetSystemdUnitStatus")
+
+	if !systemdNameRegexp.MatchString(unit) {
+		return nil, errors.New("invalid systemd unit " + unit)
+	}
+	args := []string{"show", "--no-pager", "--property=" + systemdShowProperties, unit}
+	out, _, err := util.ExecCommandOutput(systemCtl, args)
+	if err != nil {
+		log.Errorf("unable to get status of %s, %v", unit, err.Error())
+		return nil, err
+	}
+	status := parseSystemdShowOutput(out)
+	if status.Unit == "" {
+		status.Unit = unit
+	}
+	return status, nil
+}
+
+// parseSystemdShowOutput parses the "Key=Value" lines written by "systemctl show"
+func parseSystemdShowOutput(out string) *SystemdUnitStatus {
+	status := &SystemdUnitStatus{}
+	for _, line := range strings.Split(out, "\n") {
+		keyValue := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(keyValue) != 2 {
+			continue
+		}
+		switch keyValue[0] {
+		case "Id":
+			status.Unit = keyValue[1]
+		case "LoadState":
+			status.LoadState = keyValue[1]
+		case "ActiveState":
+			status.ActiveState = keyValue[1]
+		case "SubState":
+			status.SubState = keyValue[1]
+		case "UnitFileState":
+			status.UnitFileState = keyValue[1]
+		case "MainPID":
+			status.MainPID, _ = strconv.Atoi(keyValue[1])
+		}
+	}
+	return status
+}
+
+// SystemdUnitCommand runs the given operation (start, stop, restart, reload, enable or disable) on
+// the given systemd unit
+func SystemdUnitCommand(unit string, operation string) error {
+	log.Tracef(">>>>> SystemdUnitCommand called with unit %s op %s", unit, operation)
+	defer log.Trace("<<<<< SystemdUnitCommand")
+
+	switch operation {
+	case "start", "stop", "restart", "reload", "enable", "disable":
+	default:
+		return errors.New("invalid operation type " + operation + " provided for systemd unit")
+	}
+	if !systemdNameRegexp.MatchString(unit) {
+		return errors.New("invalid systemd unit " + unit)
+	}
+
+	out, _, err := util.ExecCommandOutput(systemCtl, []string{operation, unit})
+	if err != nil {
+		log.Errorf("unable to %s %s, %v %s", operation, unit, err.Error(), out)
+		return fmt.Errorf("unable to %s %s, %s", operation, unit, err.Error())
+	}
+	return nil
+}
+
+// SystemdDaemonReload reloads the systemd manager configuration to pick up unit file and drop-in
+// changes
+func SystemdDaemonReload() error {
+	_, _, err := util.ExecCommandOutput(systemCtl, []string{"daemon-reload"})
+	if err != nil {
+		log.Errorf("unable to reload systemd, %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+// SetSystemdDropIn writes the given drop-in (e.g. /etc/systemd/system/<unit>.d/<name>.conf) to
+// override settings of a unit without editing its vendor unit file, which package updates
+// overwrite.  It returns true if the drop-in changed, in which case the caller must reload systemd
+// (SystemdDaemonReload) for the change to take effect.
+func SetSystemdDropIn(unit string, name string, content string) (changed bool, err error) {
+	log.Tracef(">>>>> SetSystemdDropIn called with unit %s name %s", unit, name)
+	defer log.Trace("<<<<< SetSystemdDropIn")
+
+	dropInFile, err := getSystemdDropInFile(unit, name)
+	if err != nil {
+		return false, err
+	}
+	if current, err := ioutil.ReadFile(dropInFile); err == nil && bytes.Equal(current, []byte(content)) {
+		return false, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(dropInFile), 0755); err != nil {
+		return false, err
+	}
+	if err = ioutil.WriteFile(dropInFile, []byte(content), 0644); err != nil {
+		log.Errorf("unable to write systemd drop-in %s, %v", dropInFile, err.Error())
+		return false, err
+	}
+	log.Infof("systemd drop-in %s written", dropInFile)
+	return true, nil
+}
+
+// RemoveSystemdDropIn removes the given drop-in of a unit.  It returns true if the drop-in existed,
+// in which case the caller must reload systemd (SystemdDaemonReload).
+func RemoveSystemdDropIn(unit string, name string) (changed bool, err error) {
+	log.Tracef(">>>>> RemoveSystemdDropIn called with unit %s name %s", unit, name)
+	defer log.Trace("<<<<< RemoveSystemdDropIn")
+
+	dropInFile, err := getSystemdDropInFile(unit, name)
+	if err != nil {
+		return false, err
+	}
+	if err = os.Remove(dropInFile); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	// remove the drop-in directory if it is now empty
+	os.Remove(filepath.Dir(dropInFile))
+	return true, nil
+}
+
+// getSystemdDropInFile returns the path of the given drop-in of a unit
+func getSystemdDropInFile(unit string, name string) (string, error) {
+	if !systemdNameRegexp.MatchString(unit) {
+		return "", errors.New("invalid systemd unit " + unit)
+	}
+	if !systemdNameRegexp.MatchString(name) {
+		return "", errors.New("invalid systemd drop-in " + name)
+	}
+	return filepath.Join(systemdUnitPath, unit+".d", strings.TrimSuffix(name, ".conf")+".conf"), nil
+}
+
+// getSystemdDropIns returns the drop-ins (overrides) required by HPE storage for the given unit
+func getSystemdDropIns(osInfo *OsInfo, unit string) map[string]string {
+	if unit != SystemdUnitMultipathd || osInfo == nil || osInfo.GetOsDistro() != OsTypeSuse {
+		return nil
+	}
+	// SuSE does not start multipathd when multipath is disabled on the kernel command line,
+	// NLT-1226.  An empty condition resets the conditions of the vendor unit file.
+	return map[string]string{
+		systemdDropIn: "[Unit]\nConditionKernelCommandLine=\n",
+	}
+}
+
+// applySystemdDropIns writes the drop-ins required by HPE storage for the given unit and reloads
+// systemd if any changed
+func applySystemdDropIns(osInfo *OsInfo, unit string) error {
+	reload := false
+	for name, content := range getSystemdDropIns(osInfo, unit) {
+		changed, err := SetSystemdDropIn(unit, name, content)
+		if err != nil {
+			return err
+		}
+		reload = reload || changed
+	}
+	if reload {
+		return SystemdDaemonReload()
+	}
+	return nil
+}
+
+// enableSystemdUnit enables the systemd unit of the given service type along with its required
+// overrides.  Where multipathd is socket activated, multipathd.socket is
+// enabled too so that multipath commands issued while multipathd restarts activate it again
+// instead of failing.
+func enableSystemdUnit(osInfo *OsInfo, serviceType string) error {
+	unit, err := GetSystemdUnit(serviceType)
+	if err != nil {
+		return err
+	}
+	if err = applySystemdDropIns(osInfo, unit); err != nil {
+		return err
+	}
+	if err = SystemdDaemonReload(); err != nil {
+		return err
+	}
+	if err = SystemdUnitCommand(unit, "enable"); err != nil {
+		return err
+	}
+	if unit == SystemdUnitMultipathd {
+		if status, err := GetSystemdUnitStatus(SystemdUnitMultipathdSocket); err == nil && status.IsInstalled() && !status.IsEnabled() {
+			if err = SystemdUnitCommand(SystemdUnitMultipathdSocket, "enable"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
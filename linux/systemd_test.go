@@ -0,0 +1,89 @@
+/*
+(c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package linux
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSystemdShowOutput(t *testing.T) {
+	out := "Id=multipathd.service\nLoadState=loaded\nActiveState=active\nSubState=running\nUnitFileState=enabled\nMainPID=1234\n"
+	status := parseSystemdShowOutput(out)
+	expected := SystemdUnitStatus{Unit: SystemdUnitMultipathd, LoadState: "loaded", ActiveState: "active", SubState: "running", UnitFileState: "enabled", MainPID: 1234}
+	if *status != expected {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if !status.IsInstalled() || !status.IsActive() || !status.IsEnabled() {
+		t.Errorf("status %+v not reported installed, active and enabled", status)
+	}
+
+	status = parseSystemdShowOutput("Id=rpcbind.service\nLoadState=not-found\nActiveState=inactive\nSubState=dead\nUnitFileState=\nMainPID=0\n")
+	if status.IsInstalled() || status.IsActive() || status.IsEnabled() {
+		t.Errorf("status %+v reported installed, active or enabled", status)
+	}
+}
+
+func TestSystemdDropIn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { systemdUnitPath = path }(systemdUnitPath)
+	systemdUnitPath = dir
+
+	content := "[Unit]\nConditionKernelCommandLine=\n"
+	if changed, err := SetSystemdDropIn(SystemdUnitMultipathd, systemdDropIn, content); err != nil || !changed {
+		t.Fatalf("drop-in not written, changed=%v, err=%v", changed, err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "multipathd.service.d", "hpe-storage.conf"))
+	if err != nil || string(data) != content {
+		t.Errorf("unexpected drop-in %q, err=%v", data, err)
+	}
+	if changed, err := SetSystemdDropIn(SystemdUnitMultipathd, systemdDropIn+".conf", content); err != nil || changed {
+		t.Errorf("unchanged drop-in reported changed=%v, err=%v", changed, err)
+	}
+
+	if changed, err := RemoveSystemdDropIn(SystemdUnitMultipathd, systemdDropIn); err != nil || !changed {
+		t.Errorf("drop-in not removed, changed=%v, err=%v", changed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "multipathd.service.d")); !os.IsNotExist(err) {
+		t.Errorf("empty drop-in directory not removed, err=%v", err)
+	}
+	if changed, err := RemoveSystemdDropIn(SystemdUnitMultipathd, systemdDropIn); err != nil || changed {
+		t.Errorf("missing drop-in reported changed=%v, err=%v", changed, err)
+	}
+
+	if _, err := SetSystemdDropIn("../multipathd.service", systemdDropIn, content); err == nil {
+		t.Error("unit name with a path not rejected")
+	}
+}
+
+func TestGetSystemdDropIns(t *testing.T) {
+	if dropIns := getSystemdDropIns(&OsInfo{osDistro: OsTypeSuse}, SystemdUnitMultipathd); len(dropIns) != 1 {
+		t.Errorf("unexpected SuSE multipathd drop-ins %v", dropIns)
+	}
+	if dropIns := getSystemdDropIns(&OsInfo{osDistro: OsTypeRedhat}, SystemdUnitMultipathd); len(dropIns) != 0 {
+		t.Errorf("unexpected RedHat multipathd drop-ins %v", dropIns)
+	}
+	if _, err := GetSystemdUnit("nfs"); err == nil {
+		t.Error("unsupported service type not rejected")
+	}
+}
@@ -40,6 +40,7 @@ const (
 	openIscsi               = "open-iscsi"
 	iscsid                  = "iscsid"
 	multipathd              = "multipathd"
+	rpcbind                 = "rpcbind"
 	systemCtl               = "systemctl"
 	service                 = "service"
 	linuxExtraImage         = "linux-image-extra" // ubuntu package linux-image-extra needed for scsi_dh_alua
@@ -0,0 +1,191 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The log level and format can be changed at runtime (e.g. to enable trace logging during a live
+// incident and revert it afterwards) through SetLogConfig.  The level can also be overridden per
+// component, a source directory such as "chapi2/iscsi", so that one area can be traced without
+// flooding the log.  Each overridden component logs through its own logrus logger, with the
+// standard logger's hooks, so that the level check stays where logrus makes it.
+
+// LogConfig is the runtime logging configuration
+type LogConfig struct {
+	Level      string            `json:"level,omitempty"`      // Log level (trace, debug, info, warn or error)
+	Format     string            `json:"format,omitempty"`     // Log format (text or json)
+	Components map[string]string `json:"components,omitempty"` // Log level overrides keyed by component (e.g. "chapi2/iscsi")
+}
+
+var (
+	// configMutex protects the log format, the hook formatters and the component loggers
+	configMutex sync.RWMutex
+
+	// formatHooks are the hooks whose formatter follows the log format
+	formatHooks []formatHook
+
+	// componentLoggers are the loggers of the components with a level override
+	componentLoggers = make(map[string]*log.Logger)
+)
+
+// formatHook is a hook whose formatter can be changed at runtime
+type formatHook interface {
+	setFormat(format string)
+}
+
+// GetLogConfig returns the current logging configuration
+func GetLogConfig() LogConfig {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	config := LogConfig{Level: levelName(log.GetLevel()), Format: logParams.GetLogFormat()}
+	if len(componentLoggers) != 0 {
+		config.Components = make(map[string]string)
+		for component, logger := range componentLoggers {
+			config.Components[component] = levelName(logger.GetLevel())
+		}
+	}
+	return config
+}
+
+// SetLogConfig changes the logging configuration.  An empty level or format is left unchanged.  If
+// Components is not nil, it replaces the component level overrides (an empty map removes them).
+// The configuration is validated as a whole; nothing is changed if any setting is invalid.
+func SetLogConfig(config LogConfig) error {
+	var level log.Level
+	var err error
+	if config.Level != "" {
+		if level, err = parseLevel(config.Level); err != nil {
+			return err
+		}
+	}
+	if config.Format != "" && !(LogParams{Format: config.Format}).isValidLogFormat() {
+		return fmt.Errorf("invalid log format %q, must be %s or %s", config.Format, TextFormat, JsonFormat)
+	}
+	components := make(map[string]log.Level)
+	for component, componentLevel := range config.Components {
+		name := strings.Trim(path.Clean("/"+component), "/")
+		if name == "" {
+			return fmt.Errorf("invalid log component %q", component)
+		}
+		if components[name], err = parseLevel(componentLevel); err != nil {
+			return fmt.Errorf("component %s: %s", name, err.Error())
+		}
+	}
+
+	configMutex.Lock()
+	if config.Level != "" {
+		log.SetLevel(level)
+		logParams.Level = levelName(level)
+	}
+	if config.Format != "" && config.Format != logParams.Format {
+		logParams.Format = config.Format
+		for _, hook := range formatHooks {
+			hook.setFormat(config.Format)
+		}
+	}
+	if config.Components != nil {
+		componentLoggers = make(map[string]*log.Logger)
+		for component, componentLevel := range components {
+			componentLoggers[component] = newComponentLogger(componentLevel)
+		}
+	}
+
+	fields := log.Fields{
+		"logLevel":   levelName(log.GetLevel()),
+		"logFormat":  logParams.GetLogFormat(),
+		"components": sortedComponents(componentLoggers),
+	}
+	configMutex.Unlock()
+
+	// Log once the hooks can read the new configuration
+	log.WithFields(fields).Info("Logging configuration changed.")
+	return nil
+}
+
+// parseLevel parses one of the supported log levels
+func parseLevel(level string) (log.Level, error) {
+	if !(LogParams{Level: level}).isValidLevel() {
+		return log.InfoLevel, fmt.Errorf("invalid log level %q, must be trace, debug, info, warn or error", level)
+	}
+	return log.ParseLevel(level)
+}
+
+// levelName returns the name of the level as accepted by SetLogConfig (logrus names the warn
+// level "warning")
+func levelName(level log.Level) string {
+	if level == log.WarnLevel {
+		return "warn"
+	}
+	return level.String()
+}
+
+// newComponentLogger returns a logger, firing the standard logger's hooks, logging at the given level
+func newComponentLogger(level log.Level) *log.Logger {
+	logger := log.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.SetLevel(level)
+	logger.ReplaceHooks(log.LevelHooks{})
+	logger.AddHook(standardLoggerHooks{})
+	return logger
+}
+
+// standardLoggerHooks fires the standard logger's hooks for the entries of a component logger.
+// The hooks are looked up when the entry is logged, so that hooks added after the component was
+// overridden (e.g. by AddFileHook) also receive the component's entries.
+type standardLoggerHooks struct{}
+
+// Levels returns all the levels, the standard logger's hooks select their own levels
+func (standardLoggerHooks) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire fires the standard logger's hooks for the entry's level
+func (standardLoggerHooks) Fire(entry *log.Entry) error {
+	return log.StandardLogger().Hooks.Fire(entry.Level, entry)
+}
+
+// getComponentLogger returns the logger for the given source file; the logger of the most specific
+// component containing the file, or the standard logger if no component is overridden
+func getComponentLogger(file string) *log.Logger {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+
+	if len(componentLoggers) == 0 {
+		return log.StandardLogger()
+	}
+	logger, matched := log.StandardLogger(), ""
+	dir := "/" + path.Dir(file) + "/"
+	for component, componentLogger := range componentLoggers {
+		if len(component) > len(matched) && strings.Contains(dir, "/"+component+"/") {
+			logger, matched = componentLogger, component
+		}
+	}
+	return logger
+}
+
+// registerFormatHook registers a hook whose formatter follows the log format
+func registerFormatHook(hook formatHook) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	formatHooks = append(formatHooks, hook)
+}
+
+// sortedComponents returns the overridden components in order, for logging
+func sortedComponents(loggers map[string]*log.Logger) []string {
+	var components []string
+	for component, logger := range loggers {
+		components = append(components, component+"="+levelName(logger.GetLevel()))
+	}
+	sort.Strings(components)
+	return components
+}
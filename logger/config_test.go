@@ -0,0 +1,83 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+package logger
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// testFormatHook records the format it was switched to
+type testFormatHook struct {
+	format string
+}
+
+func (hook *testFormatHook) setFormat(format string) {
+	hook.format = format
+}
+
+func TestSetLogConfig(t *testing.T) {
+	saved := GetLogConfig()
+	defer func() {
+		saved.Components = map[string]string{}
+		SetLogConfig(saved)
+	}()
+	hook := &testFormatHook{}
+	registerFormatHook(hook)
+
+	// Invalid settings are rejected without changing anything
+	assert.NotNil(t, SetLogConfig(LogConfig{Level: "verbose"}))
+	assert.NotNil(t, SetLogConfig(LogConfig{Level: "trace", Format: "xml"}))
+	assert.NotNil(t, SetLogConfig(LogConfig{Components: map[string]string{"chapi2/iscsi": "loud"}}))
+	assert.NotNil(t, SetLogConfig(LogConfig{Components: map[string]string{"/": "trace"}}))
+	assert.Equal(t, saved.Level, log.GetLevel().String())
+
+	assert.Nil(t, SetLogConfig(LogConfig{Level: "warn", Format: JsonFormat, Components: map[string]string{"chapi2/iscsi/": "trace", "chapi2": "debug"}}))
+	config := GetLogConfig()
+	assert.Equal(t, "warn", config.Level)
+	assert.Equal(t, JsonFormat, config.Format)
+	assert.Equal(t, JsonFormat, hook.format)
+	assert.Equal(t, map[string]string{"chapi2/iscsi": "trace", "chapi2": "debug"}, config.Components)
+
+	// The most specific component applies, other sources use the standard logger
+	assert.Equal(t, log.TraceLevel, getComponentLogger("/src/common-host-libs/chapi2/iscsi/iscsi_linux.go").GetLevel())
+	assert.Equal(t, log.DebugLevel, getComponentLogger("/src/common-host-libs/chapi2/driver/driver.go").GetLevel())
+	assert.Equal(t, log.StandardLogger(), getComponentLogger("/src/common-host-libs/linux/iscsi.go"))
+	assert.Equal(t, log.StandardLogger(), getComponentLogger("/src/common-host-libs/chapi2x/driver.go"))
+
+	// Empty settings are left unchanged, an empty component map removes the overrides
+	assert.Nil(t, SetLogConfig(LogConfig{Components: map[string]string{}}))
+	config = GetLogConfig()
+	assert.Equal(t, "warn", config.Level)
+	assert.Nil(t, config.Components)
+	assert.Equal(t, log.StandardLogger(), getComponentLogger("/src/common-host-libs/chapi2/iscsi/iscsi_linux.go"))
+}
+
+// testEntryHook records the messages of the entries it is fired for
+type testEntryHook struct {
+	messages []string
+}
+
+func (hook *testEntryHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (hook *testEntryHook) Fire(entry *log.Entry) error {
+	hook.messages = append(hook.messages, entry.Message)
+	return nil
+}
+
+func TestComponentLoggerHooks(t *testing.T) {
+	savedHooks := log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
+	defer log.StandardLogger().ReplaceHooks(savedHooks)
+
+	// A hook added to the standard logger after the component logger was created is also fired
+	// for the component's entries
+	logger := newComponentLogger(log.DebugLevel)
+	hook := &testEntryHook{}
+	log.AddHook(hook)
+	logger.Debug("component entry")
+	logger.Trace("filtered entry")
+	assert.Equal(t, []string{"component entry"}, hook.messages)
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The file hook can fill small root volumes (e.g. with trace logging enabled).  Before writing an
+// entry, the file hook checks, at most every diskCheckInterval, the free space of the log file's
+// volume.  Below the minimum free space (LOG_MIN_FREE_SPACE), only warn and above entries are
+// written until space is available again.  Each check also removes the oldest rotated log files
+// once all the log files in the log directory (e.g. the CHAPI and plugin logs along with their
+// rotated files) exceed the total size cap (LOG_MAX_TOTAL_SIZE).  The active log files are never
+// removed.
+
+const (
+	DefaultMinFreeSpace = 64 // in MB
+
+	// diskCheckInterval is how often the file hook checks the log volume free space
+	diskCheckInterval = 30 * time.Second
+)
+
+var (
+	// getFreeSpace returns the free space, in bytes, of the volume holding the given directory; a
+	// variable so that tests can replace it
+	getFreeSpace = getDirectoryFreeSpace
+
+	// rotatedLogRegexp matches the lumberjack rotated log file names (e.g.
+	// "chapid-2020-06-01T10-20-30.000.log" or "chapid-2020-06-01T10-20-30.000.log.gz")
+	rotatedLogRegexp = regexp.MustCompile(`-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3}(\.[^.]*)?(\.gz)?$`)
+
+	// activeDiskGuard is the disk guard of the file hook, reported by GetLogDiskUsage
+	activeDiskGuard     *diskGuard
+	activeDiskGuardLock sync.Mutex
+)
+
+// LogDiskUsage reports the disk usage of the log files
+type LogDiskUsage struct {
+	Directory     string `json:"directory"`                 // Log file directory
+	LogBytes      uint64 `json:"log_bytes"`                 // Total size of the log files, active and rotated
+	RotatedFiles  int    `json:"rotated_files"`             // Number of rotated log files
+	PrunedFiles   int    `json:"pruned_files"`              // Number of rotated log files removed to honor the total size cap
+	FreeBytes     uint64 `json:"free_bytes"`                // Free space of the log volume
+	MinFreeBytes  uint64 `json:"min_free_bytes"`            // Free space below which only warn and above entries are written
+	MaxTotalBytes uint64 `json:"max_total_bytes,omitempty"` // Total size cap of the log files, 0 if unlimited
+	Degraded      bool   `json:"degraded"`                  // True if only warn and above entries are written
+}
+
+// diskGuard checks the log volume free space and the total size of the log files
+type diskGuard struct {
+	mutex     sync.Mutex
+	file      string    // Active log file
+	lastCheck time.Time // Time of the last check, zero if never checked
+	usage     LogDiskUsage
+}
+
+// newDiskGuard returns the disk guard of the given log file
+func newDiskGuard(file string, minFreeSpaceMiB int, maxTotalSizeMiB int) *diskGuard {
+	guard := &diskGuard{file: file}
+	guard.usage.Directory = filepath.Dir(file)
+	guard.usage.MinFreeBytes = uint64(minFreeSpaceMiB) * 1024 * 1024
+	if maxTotalSizeMiB > 0 {
+		guard.usage.MaxTotalBytes = uint64(maxTotalSizeMiB) * 1024 * 1024
+	}
+	return guard
+}
+
+// allows returns true if an entry of the given level can be written, checking the disk space if
+// diskCheckInterval has elapsed.  changed is true if the guard entered or left degraded mode.
+func (guard *diskGuard) allows(level log.Level, now time.Time) (allowed bool, changed bool) {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	if now.Sub(guard.lastCheck) >= diskCheckInterval {
+		degraded := guard.usage.Degraded
+		guard.check(now)
+		changed = degraded != guard.usage.Degraded
+	}
+	return !guard.usage.Degraded || level <= log.WarnLevel, changed
+}
+
+// isDegraded returns true if only warn and above entries are written
+func (guard *diskGuard) isDegraded() bool {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	return guard.usage.Degraded
+}
+
+// getUsage checks the disk space and returns the log disk usage
+func (guard *diskGuard) getUsage() LogDiskUsage {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+	guard.check(time.Now())
+	return guard.usage
+}
+
+// check prunes the rotated log files beyond the total size cap, updates the log disk usage and
+// enters (or leaves) degraded mode.  The caller must hold the guard mutex.
+func (guard *diskGuard) check(now time.Time) {
+	guard.lastCheck = now
+	files, totalBytes := getLogFiles(guard.usage.Directory)
+	if guard.usage.MaxTotalBytes != 0 {
+		var pruned int
+		files, totalBytes, pruned = pruneRotatedLogs(guard.usage.Directory, files, totalBytes, guard.usage.MaxTotalBytes)
+		guard.usage.PrunedFiles += pruned
+	}
+	guard.usage.LogBytes, guard.usage.RotatedFiles = totalBytes, 0
+	for _, file := range files {
+		if isRotatedLog(file.Name()) {
+			guard.usage.RotatedFiles++
+		}
+	}
+
+	freeBytes, err := getFreeSpace(guard.usage.Directory)
+	if err != nil {
+		// Keep logging if the free space is unknown
+		guard.usage.FreeBytes, guard.usage.Degraded = 0, false
+		return
+	}
+	guard.usage.FreeBytes = freeBytes
+	guard.usage.Degraded = freeBytes < guard.usage.MinFreeBytes
+}
+
+// getLogFiles returns the log files, active and rotated, in the given directory (oldest first)
+// along with their total size
+func getLogFiles(dir string) ([]os.FileInfo, uint64) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, 0
+	}
+	var files []os.FileInfo
+	var totalBytes uint64
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && strings.Contains(entry.Name(), ".log") {
+			files = append(files, entry)
+			totalBytes += uint64(entry.Size())
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	return files, totalBytes
+}
+
+// pruneRotatedLogs removes the oldest rotated log files, of the given directory, until the total
+// size of the log files is within the cap, returning the remaining files, their total size and the
+// number of files removed
+func pruneRotatedLogs(dir string, files []os.FileInfo, totalBytes uint64, maxTotalBytes uint64) ([]os.FileInfo, uint64, int) {
+	var remaining []os.FileInfo
+	pruned := 0
+	for _, file := range files {
+		if totalBytes > maxTotalBytes && isRotatedLog(file.Name()) {
+			if err := os.Remove(filepath.Join(dir, file.Name())); err == nil {
+				totalBytes -= uint64(file.Size())
+				pruned++
+				continue
+			}
+		}
+		remaining = append(remaining, file)
+	}
+	return remaining, totalBytes, pruned
+}
+
+// isRotatedLog returns true if the file name is the name of a rotated log file
+func isRotatedLog(name string) bool {
+	return rotatedLogRegexp.MatchString(name)
+}
+
+// GetLogDiskUsage returns the disk usage of the log files, or nil if not logging to a file
+func GetLogDiskUsage() *LogDiskUsage {
+	activeDiskGuardLock.Lock()
+	guard := activeDiskGuard
+	activeDiskGuardLock.Unlock()
+	if guard == nil {
+		return nil
+	}
+	usage := guard.getUsage()
+	return &usage
+}
+
+// setActiveDiskGuard sets the disk guard reported by GetLogDiskUsage
+func setActiveDiskGuard(guard *diskGuard) {
+	activeDiskGuardLock.Lock()
+	defer activeDiskGuardLock.Unlock()
+	activeDiskGuard = guard
+}
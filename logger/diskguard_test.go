@@ -0,0 +1,99 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+package logger
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestLog(t *testing.T, dir string, name string, size int, modTime time.Time) {
+	file := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(file, make([]byte, size), 0644))
+	assert.Nil(t, os.Chtimes(file, modTime, modTime))
+}
+
+func TestPruneRotatedLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskguard")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	writeTestLog(t, dir, "chapid-2020-06-01T10-20-30.000.log.gz", 1024*1024, now.Add(-3*time.Hour))
+	writeTestLog(t, dir, "chapid-2020-06-02T10-20-30.000.log.gz", 1024*1024, now.Add(-2*time.Hour))
+	writeTestLog(t, dir, "chapid-2020-06-03T10-20-30.000.log", 1024*1024, now.Add(-time.Hour))
+	writeTestLog(t, dir, "chapid.log", 1024*1024, now)
+	writeTestLog(t, dir, "notes.txt", 1024*1024, now.Add(-4*time.Hour))
+
+	saved := getFreeSpace
+	defer func() { getFreeSpace = saved }()
+	getFreeSpace = func(string) (uint64, error) { return 1024 * 1024 * 1024, nil }
+
+	// The oldest rotated logs are removed until the log files fit in 2MiB, the active log is kept
+	guard := newDiskGuard(filepath.Join(dir, "chapid.log"), DefaultMinFreeSpace, 2)
+	usage := guard.getUsage()
+	assert.Equal(t, 2, usage.PrunedFiles)
+	assert.Equal(t, 1, usage.RotatedFiles)
+	assert.Equal(t, uint64(2*1024*1024), usage.LogBytes)
+	assert.False(t, usage.Degraded)
+
+	for name, exists := range map[string]bool{
+		"chapid-2020-06-01T10-20-30.000.log.gz": false,
+		"chapid-2020-06-02T10-20-30.000.log.gz": false,
+		"chapid-2020-06-03T10-20-30.000.log":    true,
+		"chapid.log":                            true,
+		"notes.txt":                             true,
+	} {
+		_, err = os.Stat(filepath.Join(dir, name))
+		assert.Equal(t, exists, err == nil, name)
+	}
+
+	// Without a cap, nothing is removed
+	guard = newDiskGuard(filepath.Join(dir, "chapid.log"), DefaultMinFreeSpace, 0)
+	usage = guard.getUsage()
+	assert.Equal(t, 0, usage.PrunedFiles)
+	assert.Equal(t, uint64(0), usage.MaxTotalBytes)
+}
+
+func TestDiskGuardAllows(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskguard")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	saved := getFreeSpace
+	defer func() { getFreeSpace = saved }()
+	freeBytes := uint64(32 * 1024 * 1024)
+	getFreeSpace = func(string) (uint64, error) { return freeBytes, nil }
+
+	// Below the minimum free space, only warn and above entries are allowed
+	now := time.Now()
+	guard := newDiskGuard(filepath.Join(dir, "chapid.log"), 64, 0)
+	allowed, changed := guard.allows(log.TraceLevel, now)
+	assert.False(t, allowed)
+	assert.True(t, changed)
+	allowed, changed = guard.allows(log.WarnLevel, now)
+	assert.True(t, allowed)
+	assert.False(t, changed)
+	allowed, _ = guard.allows(log.ErrorLevel, now)
+	assert.True(t, allowed)
+
+	// Free space is only checked again after diskCheckInterval
+	freeBytes = 128 * 1024 * 1024
+	allowed, changed = guard.allows(log.InfoLevel, now.Add(time.Second))
+	assert.False(t, allowed)
+	assert.False(t, changed)
+	allowed, changed = guard.allows(log.InfoLevel, now.Add(diskCheckInterval))
+	assert.True(t, allowed)
+	assert.True(t, changed)
+
+	// Unknown free space does not degrade logging
+	getFreeSpace = func(string) (uint64, error) { return 0, errors.New("statfs failed") }
+	allowed, _ = guard.allows(log.DebugLevel, now.Add(2*diskCheckInterval))
+	assert.True(t, allowed)
+}
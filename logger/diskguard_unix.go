@@ -0,0 +1,19 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+// +build !windows
+
+package logger
+
+import (
+	"syscall"
+)
+
+// getDirectoryFreeSpace returns the space, in bytes, available to unprivileged users on the volume
+// holding the given directory
+func getDirectoryFreeSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
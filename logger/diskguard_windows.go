@@ -0,0 +1,23 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+package logger
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// getDirectoryFreeSpace returns the space, in bytes, available to the caller on the volume holding
+// the given directory
+func getDirectoryFreeSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var freeBytes, totalBytes, totalFreeBytes uint64
+	if err = windows.GetDiskFreeSpaceEx(path, &freeBytes, &totalBytes, &totalFreeBytes); err != nil {
+		return 0, err
+	}
+	return freeBytes, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Windows event log
+//
+// Windows administrators monitor the Event Viewer rather than log files.  Once AddEventLogHook is
+// called, the error, fatal and panic entries are also written to the Windows Application event
+// log, under the given event source.  Each event is written with an event ID identifying the
+// failure (e.g. WithEvent(EventIDLoginFailure).Errorf(...)), or EventIDError if the entry has
+// none.  The event source is registered with the EventCreate.exe message file, which only
+// supports event IDs 1 through 1000.
+
+const (
+	// EventIDField is the entry field holding the event ID of the entry
+	EventIDField = "eventId"
+
+	// Event IDs of the entries written to the Windows event log
+	EventIDError        uint32 = 100 // Any other error
+	EventIDLoginFailure uint32 = 101 // Unable to log in to a target
+	EventIDMountFailure uint32 = 102 // Unable to mount a volume
+	EventIDPathLoss     uint32 = 103 // Path to a device lost and not recovered
+
+	// maxEventMessageLength is the longest message written to the Windows event log
+	maxEventMessageLength = 31839
+)
+
+// eventLogLevels are the entry levels written to the Windows event log
+var eventLogLevels = []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel}
+
+// WithEvent creates an entry from the standard logger with the given event ID
+func WithEvent(eventID uint32) *log.Entry {
+	return sourced().WithField(EventIDField, eventID)
+}
+
+// getEventID returns the event ID of the given entry
+func getEventID(entry *log.Entry) uint32 {
+	if eventID, ok := entry.Data[EventIDField].(uint32); ok {
+		return eventID
+	}
+	return EventIDError
+}
+
+// getEventMessage returns the Windows event log message of the given entry:  the entry's message
+// followed by its fields, sorted by name
+func getEventMessage(entry *log.Entry) string {
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		if key != EventIDField {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var message strings.Builder
+	message.WriteString(entry.Message)
+	for _, key := range keys {
+		fmt.Fprintf(&message, "\n%s=%v", key, entry.Data[key])
+	}
+	if message.Len() > maxEventMessageLength {
+		return message.String()[:maxEventMessageLength]
+	}
+	return message.String()
+}
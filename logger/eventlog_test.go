@@ -0,0 +1,35 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestGetEventID(t *testing.T) {
+	entry := log.WithField(EventIDField, EventIDMountFailure)
+	if eventID := getEventID(entry); eventID != EventIDMountFailure {
+		t.Errorf("expected event ID %v, got %v", EventIDMountFailure, eventID)
+	}
+	if eventID := getEventID(log.WithField("file", "mount.go:10")); eventID != EventIDError {
+		t.Errorf("expected event ID %v, got %v", EventIDError, eventID)
+	}
+}
+
+func TestGetEventMessage(t *testing.T) {
+	entry := log.WithFields(log.Fields{EventIDField: EventIDLoginFailure, "target": "iqn.2007-11.com.nimblestorage:vol1", "file": "iscsi.go:10"})
+	entry.Message = "Unable to log in to the target"
+	expected := "Unable to log in to the target\nfile=iscsi.go:10\ntarget=iqn.2007-11.com.nimblestorage:vol1"
+	if message := getEventMessage(entry); message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+
+	entry = log.WithFields(log.Fields{})
+	entry.Message = strings.Repeat("x", maxEventMessageLength+1)
+	if message := getEventMessage(entry); len(message) != maxEventMessageLength {
+		t.Errorf("expected a %v character message, got %v characters", maxEventMessageLength, len(message))
+	}
+}
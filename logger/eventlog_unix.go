@@ -0,0 +1,14 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+// +build !windows
+
+package logger
+
+import (
+	"errors"
+)
+
+// AddEventLogHook is only supported on Windows
+func AddEventLogHook(source string) error {
+	return errors.New("the Windows event log is not supported on this platform")
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+package logger
+
+import (
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+var (
+	eventLogHook     *EventLogHook
+	eventLogHookLock sync.Mutex
+)
+
+// EventLogHook sends the error, fatal and panic log entries to the Windows Application event log
+type EventLogHook struct {
+	source   string
+	eventLog *eventlog.Log
+}
+
+// AddEventLogHook registers the given event source, unless already registered, and writes the
+// error, fatal and panic log entries to the Windows Application event log under that source.
+// Registering the event source requires administrative privileges.  Calling AddEventLogHook
+// once the hook is added has no effect.
+func AddEventLogHook(source string) error {
+	eventLogHookLock.Lock()
+	defer eventLogHookLock.Unlock()
+
+	if eventLogHook != nil {
+		return nil
+	}
+	err := eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil && !strings.HasSuffix(err.Error(), "registry key already exists") {
+		return err
+	}
+	eventLog, err := eventlog.Open(source)
+	if err != nil {
+		return err
+	}
+	eventLogHook = &EventLogHook{source: source, eventLog: eventLog}
+	log.AddHook(eventLogHook)
+	return nil
+}
+
+func (hook *EventLogHook) Levels() []log.Level {
+	return eventLogLevels
+}
+
+func (hook *EventLogHook) Fire(entry *log.Entry) error {
+	return hook.eventLog.Error(getEventID(entry), getEventMessage(entry))
+}
@@ -39,6 +39,16 @@ type LogParams struct {
 	MaxFiles   int
 	MaxSizeMiB int
 	Format     string
+
+	// MinFreeSpaceMiB is the log volume free space below which only warn and above entries are
+	// written to the log file; MaxTotalSizeMiB caps the total size of the log files, active and
+	// rotated, in the log directory (0 if unlimited)
+	MinFreeSpaceMiB int
+	MaxTotalSizeMiB int
+
+	// Syslog also sends the log entries to syslog (SyslogOutput) or to the systemd journal
+	// (JournaldOutput); not supported on Windows
+	Syslog string
 }
 
 var (
@@ -113,6 +123,31 @@ func (l LogParams) GetMaxSize() int {
 	return l.MaxSizeMiB
 }
 
+func (l LogParams) GetMinFreeSpace() int {
+	if l.MinFreeSpaceMiB <= 0 {
+		return DefaultMinFreeSpace
+	}
+	return l.MinFreeSpaceMiB
+}
+
+func (l LogParams) GetMaxTotalSize() int {
+	if l.MaxTotalSizeMiB < 0 {
+		return 0
+	}
+	return l.MaxTotalSizeMiB
+}
+
+func (l LogParams) isValidSyslog() bool {
+	return l.Syslog == SyslogOutput || l.Syslog == JournaldOutput
+}
+
+func (l LogParams) GetSyslog() string {
+	if !l.isValidSyslog() {
+		return ""
+	}
+	return l.Syslog
+}
+
 func (l LogParams) GetLogFormat() string {
 	if !l.isValidLogFormat() {
 		return DefaultLogFormat
@@ -157,6 +192,27 @@ func updateLogParamsFromEnv() {
 		}
 	}
 
+	minFreeSpace := os.Getenv("LOG_MIN_FREE_SPACE")
+	if minFreeSpace != "" {
+		size, err := strconv.ParseInt(minFreeSpace, 0, 0)
+		if err == nil {
+			logParams.MinFreeSpaceMiB = int(size)
+		}
+	}
+
+	maxTotalSize := os.Getenv("LOG_MAX_TOTAL_SIZE")
+	if maxTotalSize != "" {
+		size, err := strconv.ParseInt(maxTotalSize, 0, 0)
+		if err == nil {
+			logParams.MaxTotalSizeMiB = int(size)
+		}
+	}
+
+	syslogOutput := os.Getenv("LOG_SYSLOG")
+	if syslogOutput != "" {
+		logParams.Syslog = syslogOutput
+	}
+
 	logFormat := os.Getenv("LOG_FORMAT")
 	if logFormat != "" {
 		logParams.Format = logFormat
@@ -174,6 +230,7 @@ func InitLogging(logName string, params *LogParams, alsoLogToStderr bool) (err e
 		logParams.Level = DefaultLogLevel
 		logParams.MaxSizeMiB = DefaultMaxLogSize
 		logParams.MaxFiles = DefaultMaxLogFiles
+		logParams.MinFreeSpaceMiB = DefaultMinFreeSpace
 		logParams.Format = DefaultLogFormat
 	} else {
 		logParams = *params
@@ -196,6 +253,12 @@ func InitLogging(logName string, params *LogParams, alsoLogToStderr bool) (err e
 			return err
 		}
 	}
+	if logParams.GetSyslog() != "" {
+		err = AddSyslogHook(logParams.GetSyslog())
+		if err != nil {
+			return err
+		}
+	}
 	if alsoLogToStderr {
 		err = AddConsoleHook()
 		if err != nil {
@@ -215,6 +278,7 @@ func InitLogging(logName string, params *LogParams, alsoLogToStderr bool) (err e
 		"logLevel":        log.GetLevel().String(),
 		"logFileLocation": logParams.GetFile(),
 		"alsoLogToStderr": alsoLogToStderr,
+		"syslog":          logParams.GetSyslog(),
 	}).Info("Initialized logging.")
 
 	return nil
@@ -222,7 +286,9 @@ func InitLogging(logName string, params *LogParams, alsoLogToStderr bool) (err e
 
 func AddConsoleHook() error {
 	// Write to stdout/stderr
-	log.AddHook(NewConsoleHook())
+	hook := NewConsoleHook()
+	registerFormatHook(hook)
+	log.AddHook(hook)
 	return nil
 }
 
@@ -232,6 +298,8 @@ func AddFileHook() error {
 	if err != nil {
 		return fmt.Errorf("could not initialize logging to file %s: %v", logFileHook.GetLocation(), err)
 	}
+	registerFormatHook(logFileHook)
+	setActiveDiskGuard(logFileHook.guard)
 	log.AddHook(logFileHook)
 	return nil
 }
@@ -243,10 +311,20 @@ type ConsoleHook struct {
 
 // NewConsoleHook creates a new log hook for writing to stdout/stderr.
 func NewConsoleHook() *ConsoleHook {
-	if logParams.UseJsonFormatter() {
-		return &ConsoleHook{&log.JSONFormatter{CallerPrettyfier: CustomCallerPrettyfier}}
+	return &ConsoleHook{newConsoleFormatter(logParams.Format)}
+}
+
+// newConsoleFormatter returns the console formatter for the given log format
+func newConsoleFormatter(format string) log.Formatter {
+	if format == JsonFormat {
+		return &log.JSONFormatter{CallerPrettyfier: CustomCallerPrettyfier}
 	}
-	return &ConsoleHook{&log.TextFormatter{FullTimestamp: true, CallerPrettyfier: CustomCallerPrettyfier}}
+	return &log.TextFormatter{FullTimestamp: true, CallerPrettyfier: CustomCallerPrettyfier}
+}
+
+// setFormat switches the hook to the given log format
+func (hook *ConsoleHook) setFormat(format string) {
+	hook.formatter = newConsoleFormatter(format)
 }
 
 func (hook *ConsoleHook) Levels() []log.Level {
@@ -273,14 +351,17 @@ func (hook *ConsoleHook) Fire(entry *log.Entry) error {
 	}
 
 	// Write log entry to output stream
-	if logParams.UseTextFormatter() {
+	configMutex.RLock()
+	formatter := hook.formatter
+	configMutex.RUnlock()
+	if textFormatter, ok := formatter.(*log.TextFormatter); ok {
 		//https://github.com/sirupsen/logrus/issues/172
 		if runtime.GOOS != "windows" {
-			hook.formatter.(*log.TextFormatter).ForceColors = hook.checkIfTerminal(logWriter)
+			textFormatter.ForceColors = hook.checkIfTerminal(logWriter)
 		}
 	}
 
-	lineBytes, err := hook.formatter.Format(entry)
+	lineBytes, err := formatter.Format(entry)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to read entry, %v", err)
 		return err
@@ -294,6 +375,7 @@ type FileHook struct {
 	formatter log.Formatter
 	mutex     *sync.Mutex
 	logWriter io.Writer
+	guard     *diskGuard
 }
 
 func CustomCallerPrettyfier(f *runtime.Frame) (string, string) {
@@ -306,11 +388,8 @@ func CustomCallerPrettyfier(f *runtime.Frame) (string, string) {
 // NewFileHook creates a new log hook for writing to a file.
 func NewFileHook() (hook *FileHook, err error) {
 
-	if logParams.UseJsonFormatter() {
-		hook = &FileHook{&log.JSONFormatter{}, &sync.Mutex{}, nil}
-	} else {
-		hook = &FileHook{&log.TextFormatter{FullTimestamp: true}, &sync.Mutex{}, nil}
-	}
+	hook = &FileHook{newFileFormatter(logParams.Format), &sync.Mutex{}, nil, nil}
+	hook.guard = newDiskGuard(logParams.GetFile(), logParams.GetMinFreeSpace(), logParams.GetMaxTotalSize())
 
 	// use lumberjack for log rotation
 	hook.logWriter = &lumberjack.Logger{
@@ -323,13 +402,51 @@ func NewFileHook() (hook *FileHook, err error) {
 	return hook, nil
 }
 
+// newFileFormatter returns the log file formatter for the given log format
+func newFileFormatter(format string) log.Formatter {
+	if format == JsonFormat {
+		return &log.JSONFormatter{}
+	}
+	return &log.TextFormatter{FullTimestamp: true}
+}
+
+// setFormat switches the hook to the given log format
+func (hook *FileHook) setFormat(format string) {
+	hook.formatter = newFileFormatter(format)
+}
+
 func (hook *FileHook) Levels() []log.Level {
 	return log.AllLevels
 }
 
 func (hook *FileHook) Fire(entry *log.Entry) error {
+	// Drop the entry if the log volume is low on free space, noting when the guard enters or
+	// leaves degraded mode
+	if hook.guard != nil {
+		allowed, changed := hook.guard.allows(entry.Level, time.Now())
+		if changed {
+			note := log.NewEntry(entry.Logger).WithTime(time.Now())
+			note.Level = log.WarnLevel
+			note.Message = "log volume free space restored, logging all levels"
+			if hook.guard.isDegraded() {
+				note.Message = "log volume free space low, only warn and above entries are logged"
+			}
+			hook.write(note)
+		}
+		if !allowed {
+			return nil
+		}
+	}
+	return hook.write(entry)
+}
+
+// write formats the entry and writes it to the log file
+func (hook *FileHook) write(entry *log.Entry) error {
 	// Get formatted entry
-	lineBytes, err := hook.formatter.Format(entry)
+	configMutex.RLock()
+	formatter := hook.formatter
+	configMutex.RUnlock()
+	lineBytes, err := formatter.Format(entry)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Could not read log entry. %v", err)
 		return err
@@ -374,9 +491,14 @@ func WithError(err error) *log.Entry {
 	return log.WithField(log.ErrorKey, err)
 }
 
-// WithContext creates an entry from the standard logger and adds a context to it.
+// WithContext creates an entry from the standard logger and adds a context to it.  The request
+// correlation ID carried by the context, if any, is added as the "request_id" field.
 func WithContext(ctx context.Context) *log.Entry {
-	return log.WithContext(ctx)
+	entry := sourced().WithContext(ctx)
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		entry = entry.WithField(requestIDField, requestID)
+	}
+	return entry
 }
 
 // WithField creates an entry from the standard logger and adds a field to
@@ -407,20 +529,29 @@ func WithTime(t time.Time) *log.Entry {
 	return log.WithTime(t)
 }
 
-// HTTPLogger : wrapper for http logging
+// HTTPLogger : wrapper for http logging.  The request's X-Request-ID (or a new ID) is returned in
+// the response header and carried by the request context (see RequestIDFromContext).
 func HTTPLogger(inner http.Handler, name string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		r = r.WithContext(NewRequestIDContext(r.Context(), requestID))
+		entry := sourced().WithField(requestIDField, requestID)
+
 		panicked := true
 		defer func() {
 			if panicked {
 				const size = 64 << 10
 				buf := make([]byte, size)
 				buf = buf[:runtime.Stack(buf, false)]
-				sourced().Errorf("HTTPLogger: panic serving %v:\n%s", name, buf)
+				entry.Errorf("HTTPLogger: panic serving %v:\n%s", name, buf)
 			}
 		}()
 
-		sourced().Infof(
+		entry.Infof(
 			">>>>> %s %s - %s",
 			r.Method,
 			r.RequestURI,
@@ -430,7 +561,7 @@ func HTTPLogger(inner http.Handler, name string) http.Handler {
 		start := time.Now()
 		inner.ServeHTTP(w, r)
 
-		sourced().Infof(
+		entry.Infof(
 			"<<<<< %s %s - %s %s",
 			r.Method,
 			r.RequestURI,
@@ -442,6 +573,9 @@ func HTTPLogger(inner http.Handler, name string) http.Handler {
 	})
 }
 
+// RedactedValue replaces sensitive information in the logs
+const RedactedValue = "**********"
+
 // IsSensitive checks if the given key exists in the list of bad words (sensitive info)
 func IsSensitive(key string) bool {
 	// TODO: Add more sensitive words (lower-case) to this list
@@ -472,7 +606,7 @@ func IsSensitive(key string) bool {
 func Scrubber(args []string) []string {
 	for _, arg := range args {
 		if IsSensitive(arg) {
-			return []string{"**********"}
+			return []string{RedactedValue}
 		}
 	}
 	return args
@@ -484,7 +618,7 @@ func MapScrubber(m map[string]string) map[string]string {
 	retMap := make(map[string]string)
 	for k, v := range m {
 		if IsSensitive(k) {
-			retMap[k] = "**********"
+			retMap[k] = RedactedValue
 		} else {
 			retMap[k] = v
 		}
@@ -496,14 +630,16 @@ func MapScrubber(m map[string]string) map[string]string {
 // the file name and line where the logging happened.
 func sourced() *log.Entry {
 	_, file, line, ok := runtime.Caller(2)
+	logger := log.StandardLogger()
 	if !ok {
 		file = "<???>"
 		line = 1
 	} else {
+		logger = getComponentLogger(file)
 		slash := strings.LastIndex(file, "/")
 		file = file[slash+1:]
 	}
-	return log.WithField("file", fmt.Sprintf("%s:%d", file, line))
+	return logger.WithField("file", fmt.Sprintf("%s:%d", file, line))
 }
 
 // Trace logs a message at level Trace on the standard logger.
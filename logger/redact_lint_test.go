@@ -0,0 +1,121 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package logger
+
+import (
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"testing"
+)
+
+const loggerPath = "github.com/hpe-storage/common-host-libs/logger"
+
+// redactedLoggingDirs are the packages, relative to the logger package, that log the objects
+// carrying credentials
+var redactedLoggingDirs = []string{
+	"../chapi",
+	"../chapi2/chapiclient",
+	"../chapi2/compat",
+	"../chapi2/driver",
+	"../chapi2/handler",
+	"../chapi2/iscsi",
+	"../chapi2/multipath",
+	"../dockerplugin/handler",
+	"../dockerplugin/provider",
+	"../linux",
+}
+
+// TestRedactedLogging type checks the packages logging the objects carrying credentials, and fails
+// if such an object (any type with a Redacted method) is passed to a logger function instead of its
+// redacted copy.  Type checking the consumer packages from source is slow, so the check is skipped
+// in short mode (go test -short).
+func TestRedactedLogging(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the redacted logging check in short mode")
+	}
+	fset := token.NewFileSet()
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	for _, dir := range redactedLoggingDirs {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buildPkg, err := build.ImportDir(dir, 0)
+		if err != nil {
+			t.Fatalf("%v: %v", dir, err)
+		}
+		var files []*ast.File
+		for _, name := range buildPkg.GoFiles {
+			file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, file)
+		}
+		info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue), Uses: make(map[*ast.Ident]types.Object)}
+		if _, err = conf.Check(buildPkg.ImportPath, fset, files, info); err != nil {
+			t.Fatalf("%v: %v", dir, err)
+		}
+		for _, file := range files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok || !isLoggerCall(info, call) {
+					return true
+				}
+				for _, arg := range call.Args {
+					if isRedactedCall(arg) {
+						continue
+					}
+					if typ := info.TypeOf(arg); typ != nil && hasRedacted(typ) {
+						t.Errorf("%v: %v logged without redaction, log its Redacted() copy", fset.Position(arg.Pos()), typ)
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// isLoggerCall returns true if the given call is a call to a logger package function
+func isLoggerCall(info *types.Info, call *ast.CallExpr) bool {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	function, ok := info.Uses[selector.Sel].(*types.Func)
+	return ok && function.Pkg() != nil && function.Pkg().Path() == loggerPath
+}
+
+// isRedactedCall returns true if the given expression is a Redacted method call
+func isRedactedCall(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && selector.Sel.Name == "Redacted"
+}
+
+// hasRedacted returns true if the given type, or the type it points to, has a Redacted method
+// returning a redacted copy of itself
+func hasRedacted(typ types.Type) bool {
+	if pointer, ok := typ.(*types.Pointer); ok {
+		typ = pointer.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	object, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, named.Obj().Pkg(), "Redacted")
+	method, ok := object.(*types.Func)
+	if !ok {
+		return false
+	}
+	signature := method.Type().(*types.Signature)
+	return signature.Results().Len() == 1 && types.Identical(signature.Results().At(0).Type(), types.NewPointer(named))
+}
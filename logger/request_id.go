@@ -0,0 +1,74 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// REQUEST CORRELATION IDS
+//
+//		HTTPLogger accepts the X-Request-ID header of each incoming request (or generates a new
+//		ID), returns it in the response header and stores it in the request context.  Lines
+//		logged through WithContext(r.Context()) include the ID as the "request_id" field, and
+//		connectivity clients forward the ID of the connectivity.Request context on outgoing
+//		requests.  A single request can then be traced across the docker plugin, chapid and
+//		container provider logs.
+//
+//		The ID follows the context; work handed off to other goroutines stays correlated as long
+//		as the request context is passed along.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+const (
+	// RequestIDHeader is the HTTP header carrying the request correlation ID
+	RequestIDHeader = "X-Request-ID"
+
+	// requestIDField is the log field holding the request correlation ID
+	requestIDField = "request_id"
+
+	// maxRequestIDLength is the longest request ID accepted from a client
+	maxRequestIDLength = 64
+)
+
+// requestIDContextKey stores the request correlation ID in the request context
+type requestIDContextKey struct{}
+
+// NewRequestID returns a new random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewRequestIDContext returns a copy of the context carrying the given request ID
+func NewRequestIDContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by the context, or an empty string if the
+// context (which may be nil) has none
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// isValidRequestID returns true if a client supplied request ID is safe to log and forward
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDContext(t *testing.T) {
+	assert.Equal(t, "", RequestIDFromContext(nil))
+	assert.Equal(t, "", RequestIDFromContext(context.Background()))
+
+	ctx := NewRequestIDContext(context.Background(), "outer")
+	assert.Equal(t, "outer", RequestIDFromContext(ctx))
+	assert.Equal(t, "inner", RequestIDFromContext(NewRequestIDContext(ctx, "inner")))
+
+	// The request ID follows the context to other goroutines
+	other := make(chan string)
+	go func() { other <- RequestIDFromContext(ctx) }()
+	assert.Equal(t, "outer", <-other)
+
+	// Lines logged with the context include the request ID
+	assert.Equal(t, "outer", WithContext(ctx).Data[requestIDField])
+	_, found := WithContext(context.Background()).Data[requestIDField]
+	assert.False(t, found)
+}
+
+func TestHTTPLoggerRequestID(t *testing.T) {
+	var served string
+	handler := HTTPLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = RequestIDFromContext(r.Context())
+	}), "test")
+
+	// A valid client request ID is used as is
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/test", nil)
+	r.Header.Set(RequestIDHeader, "abc-123")
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "abc-123", served)
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+
+	// An invalid request ID is replaced with a new one
+	w = httptest.NewRecorder()
+	r.Header.Set(RequestIDHeader, "bad\nid")
+	handler.ServeHTTP(w, r)
+	assert.Len(t, served, 32)
+	assert.Equal(t, served, w.Header().Get(RequestIDHeader))
+	assert.Equal(t, "", RequestIDFromContext(r.Context()))
+}
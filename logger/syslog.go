@@ -0,0 +1,129 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// System log
+//
+// On Linux, the log entries can also be sent to syslog or to the systemd journal (LogParams.Syslog
+// or LOG_SYSLOG), so that the node logs flow into the host's log pipeline without scraping the log
+// file.  The entry levels are mapped to syslog priorities.  Syslog messages carry the entry fields
+// as key=value pairs; journal entries carry them as journal fields, named after the entry field in
+// upper case (e.g. FILE for "file" and REQUESTID for "requestId").  Syslog and the journal are
+// not available on Windows.
+
+const (
+	// SyslogOutput sends the log entries to the local syslog daemon
+	SyslogOutput = "syslog"
+
+	// JournaldOutput sends the log entries to the systemd journal
+	JournaldOutput = "journald"
+
+	// Syslog priorities of the log entries
+	syslogPriorityCrit    = 2
+	syslogPriorityErr     = 3
+	syslogPriorityWarning = 4
+	syslogPriorityInfo    = 6
+	syslogPriorityDebug   = 7
+)
+
+// syslogPriority returns the syslog priority of the given entry level
+func syslogPriority(level log.Level) int {
+	switch level {
+	case log.PanicLevel, log.FatalLevel:
+		return syslogPriorityCrit
+	case log.ErrorLevel:
+		return syslogPriorityErr
+	case log.WarnLevel:
+		return syslogPriorityWarning
+	case log.InfoLevel:
+		return syslogPriorityInfo
+	}
+	return syslogPriorityDebug
+}
+
+// sortedFieldNames returns the names of the fields of the given entry, sorted
+func sortedFieldNames(entry *log.Entry) []string {
+	names := make([]string, 0, len(entry.Data))
+	for name := range entry.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getSyslogMessage returns the syslog message of the given entry:  the entry's message followed by
+// its fields, sorted by name, as key=value pairs
+func getSyslogMessage(entry *log.Entry) string {
+	var message strings.Builder
+	message.WriteString(entry.Message)
+	for _, name := range sortedFieldNames(entry) {
+		value := fmt.Sprint(entry.Data[name])
+		if strings.ContainsAny(value, " \t\n\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&message, " %s=%s", name, value)
+	}
+	return message.String()
+}
+
+// journalFieldName returns the journal field name of the given entry field name; journal field
+// names only contain upper case letters, digits and underscores, and can't start with a digit or
+// an underscore.  An empty name is returned if the entry field name has no valid character.
+func journalFieldName(name string) string {
+	field := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		}
+		return '_'
+	}, name)
+	field = strings.TrimLeft(field, "_0123456789")
+	switch field {
+	case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+		// Don't override the fields set from the entry itself
+		return "ENTRY_" + field
+	}
+	return field
+}
+
+// getJournalMessage returns the journal native protocol datagram of the given entry
+func getJournalMessage(entry *log.Entry, identifier string) []byte {
+	var message bytes.Buffer
+	writeJournalField(&message, "MESSAGE", entry.Message)
+	writeJournalField(&message, "PRIORITY", fmt.Sprint(syslogPriority(entry.Level)))
+	writeJournalField(&message, "SYSLOG_IDENTIFIER", identifier)
+	for _, name := range sortedFieldNames(entry) {
+		if field := journalFieldName(name); field != "" {
+			writeJournalField(&message, field, fmt.Sprint(entry.Data[name]))
+		}
+	}
+	return message.Bytes()
+}
+
+// writeJournalField appends the given journal field to the message; values spanning multiple lines
+// are written with their length, as required by the journal native protocol
+func writeJournalField(message *bytes.Buffer, field, value string) {
+	message.WriteString(field)
+	if !strings.Contains(value, "\n") {
+		message.WriteByte('=')
+		message.WriteString(value)
+		message.WriteByte('\n')
+		return
+	}
+	message.WriteByte('\n')
+	binary.Write(message, binary.LittleEndian, uint64(len(value)))
+	message.WriteString(value)
+	message.WriteByte('\n')
+}
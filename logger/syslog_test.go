@@ -0,0 +1,66 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+package logger
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSyslogPriority(t *testing.T) {
+	for level, expected := range map[log.Level]int{
+		log.PanicLevel: syslogPriorityCrit,
+		log.FatalLevel: syslogPriorityCrit,
+		log.ErrorLevel: syslogPriorityErr,
+		log.WarnLevel:  syslogPriorityWarning,
+		log.InfoLevel:  syslogPriorityInfo,
+		log.DebugLevel: syslogPriorityDebug,
+		log.TraceLevel: syslogPriorityDebug,
+	} {
+		if priority := syslogPriority(level); priority != expected {
+			t.Errorf("%v: expected priority %v, got %v", level, expected, priority)
+		}
+	}
+}
+
+func TestGetSyslogMessage(t *testing.T) {
+	entry := log.WithFields(log.Fields{"file": "mount.go:10", "mountPoint": "/mnt/my vol"})
+	entry.Message = "Unable to mount"
+	expected := `Unable to mount file=mount.go:10 mountPoint="/mnt/my vol"`
+	if message := getSyslogMessage(entry); message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"file":      "FILE",
+		"requestId": "REQUESTID",
+		"log-level": "LOG_LEVEL",
+		"_1source":  "SOURCE",
+		"message":   "ENTRY_MESSAGE",
+		"__":        "",
+	} {
+		if field := journalFieldName(name); field != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, field)
+		}
+	}
+}
+
+func TestGetJournalMessage(t *testing.T) {
+	entry := log.WithFields(log.Fields{"file": "mount.go:10"})
+	entry.Level = log.ErrorLevel
+	entry.Message = "Unable to mount"
+	expected := "MESSAGE=Unable to mount\nPRIORITY=3\nSYSLOG_IDENTIFIER=chapid\nFILE=mount.go:10\n"
+	if message := string(getJournalMessage(entry, "chapid")); message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+
+	// Multi-line values are written with their length
+	entry.Message = "line1\nline2"
+	expected = "MESSAGE\n\x0b\x00\x00\x00\x00\x00\x00\x00line1\nline2\nPRIORITY=3\nSYSLOG_IDENTIFIER=chapid\nFILE=mount.go:10\n"
+	if message := string(getJournalMessage(entry, "chapid")); message != expected {
+		t.Errorf("expected %q, got %q", expected, message)
+	}
+}
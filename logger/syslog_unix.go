@@ -0,0 +1,102 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+// +build !windows
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// journalSocketPath is the systemd journal native protocol socket; a variable so that tests can
+	// redirect it
+	journalSocketPath = "/run/systemd/journal/socket"
+)
+
+// SyslogHook sends log entries to the local syslog daemon.
+type SyslogHook struct {
+	writer *syslog.Writer
+}
+
+// JournaldHook sends log entries to the systemd journal.
+type JournaldHook struct {
+	identifier string
+	conn       *net.UnixConn
+}
+
+// AddSyslogHook sends the log entries to syslog (SyslogOutput) or to the systemd journal
+// (JournaldOutput)
+func AddSyslogHook(output string) error {
+	identifier := filepath.Base(os.Args[0])
+	switch output {
+	case SyslogOutput:
+		hook, err := NewSyslogHook(identifier)
+		if err != nil {
+			return fmt.Errorf("could not initialize logging to syslog: %v", err)
+		}
+		log.AddHook(hook)
+	case JournaldOutput:
+		hook, err := NewJournaldHook(identifier)
+		if err != nil {
+			return fmt.Errorf("could not initialize logging to the systemd journal: %v", err)
+		}
+		log.AddHook(hook)
+	default:
+		return fmt.Errorf("invalid syslog output %q, must be %s or %s", output, SyslogOutput, JournaldOutput)
+	}
+	return nil
+}
+
+// NewSyslogHook creates a new log hook for writing to syslog under the given identifier.
+func NewSyslogHook(identifier string) (*SyslogHook, error) {
+	writer, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, identifier)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogHook{writer}, nil
+}
+
+func (hook *SyslogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (hook *SyslogHook) Fire(entry *log.Entry) error {
+	message := getSyslogMessage(entry)
+	switch syslogPriority(entry.Level) {
+	case syslogPriorityCrit:
+		return hook.writer.Crit(message)
+	case syslogPriorityErr:
+		return hook.writer.Err(message)
+	case syslogPriorityWarning:
+		return hook.writer.Warning(message)
+	case syslogPriorityInfo:
+		return hook.writer.Info(message)
+	}
+	return hook.writer.Debug(message)
+}
+
+// NewJournaldHook creates a new log hook for writing to the systemd journal under the given
+// identifier.
+func NewJournaldHook(identifier string) (*JournaldHook, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &JournaldHook{identifier, conn}, nil
+}
+
+func (hook *JournaldHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (hook *JournaldHook) Fire(entry *log.Entry) error {
+	_, err := hook.conn.Write(getJournalMessage(entry, hook.identifier))
+	return err
+}
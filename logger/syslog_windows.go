@@ -0,0 +1,14 @@
+// Copyright 2020 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+package logger
+
+import (
+	"errors"
+)
+
+// AddSyslogHook is not supported on Windows
+func AddSyslogHook(output string) error {
+	return errors.New("syslog and the systemd journal are not supported on Windows")
+}
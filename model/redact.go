@@ -0,0 +1,165 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package model
+
+// The Redacted methods return deep copies of the objects carrying credentials, with the
+// credentials masked, so that the objects can be logged.  Log the redacted copy, never the object
+// itself (enforced by the logger package tests).
+
+const (
+	// redactedValue replaces a credential in a redacted copy
+	redactedValue = "**********"
+)
+
+// redactString returns the masked value of the given credential, empty if not set
+func redactString(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// copyStrings returns a copy of the given string slice
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// copyMap returns a shallow copy of the given map
+func copyMap(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
+
+// Redacted returns a copy of the CHAP credentials with the password masked
+func (chap *ChapInfo) Redacted() *ChapInfo {
+	if chap == nil {
+		return nil
+	}
+	return &ChapInfo{Name: chap.Name, Password: redactString(chap.Password)}
+}
+
+// Redacted returns a deep copy of the initiator with its CHAP password masked
+func (initiator *Initiator) Redacted() *Initiator {
+	if initiator == nil {
+		return nil
+	}
+	return &Initiator{Type: initiator.Type, Init: copyStrings(initiator.Init), Chap: initiator.Chap.Redacted()}
+}
+
+// Redacted returns a deep copy of the volume with its CHAP password and encryption key masked
+func (v *Volume) Redacted() *Volume {
+	if v == nil {
+		return nil
+	}
+	redacted := *v
+	redacted.Config = copyMap(v.Config)
+	redacted.Status = copyMap(v.Status)
+	redacted.Iqns = copyStrings(v.Iqns)
+	redacted.DiscoveryIPs = copyStrings(v.DiscoveryIPs)
+	redacted.Chap = v.Chap.Redacted()
+	redacted.EncryptionKey = redactString(v.EncryptionKey)
+	if v.Metadata != nil {
+		redacted.Metadata = make([]*KeyValue, len(v.Metadata))
+		for i, keyValue := range v.Metadata {
+			if keyValue != nil {
+				copied := *keyValue
+				redacted.Metadata[i] = &copied
+			}
+		}
+	}
+	if v.Networks != nil {
+		redacted.Networks = make([]*NetworkInterface, len(v.Networks))
+		for i, network := range v.Networks {
+			if network != nil {
+				copied := *network
+				redacted.Networks[i] = &copied
+			}
+		}
+	}
+	if v.IscsiSessions != nil {
+		redacted.IscsiSessions = make([]*IscsiSession, len(v.IscsiSessions))
+		for i, session := range v.IscsiSessions {
+			if session != nil {
+				copied := *session
+				redacted.IscsiSessions[i] = &copied
+			}
+		}
+	}
+	if v.FcSessions != nil {
+		redacted.FcSessions = make([]*FcSession, len(v.FcSessions))
+		for i, session := range v.FcSessions {
+			if session != nil {
+				copied := *session
+				redacted.FcSessions[i] = &copied
+			}
+		}
+	}
+	return &redacted
+}
+
+// Redacted returns a deep copy of the iSCSI access info with its CHAP password masked
+func (iscsiAccessInfo *IscsiAccessInfo) Redacted() *IscsiAccessInfo {
+	if iscsiAccessInfo == nil {
+		return nil
+	}
+	return &IscsiAccessInfo{
+		DiscoveryIPs: copyStrings(iscsiAccessInfo.DiscoveryIPs),
+		ChapUser:     iscsiAccessInfo.ChapUser,
+		ChapPassword: redactString(iscsiAccessInfo.ChapPassword),
+	}
+}
+
+// Redacted returns a deep copy of the secondary LUN info with its CHAP password masked
+func (info *SecondaryLunInfo) Redacted() *SecondaryLunInfo {
+	if info == nil {
+		return nil
+	}
+	return &SecondaryLunInfo{
+		LunID:           info.LunID,
+		TargetNames:     copyStrings(info.TargetNames),
+		IscsiAccessInfo: *info.IscsiAccessInfo.Redacted(),
+	}
+}
+
+// Redacted returns a copy of the token with its password and session token masked
+func (token *Token) Redacted() *Token {
+	if token == nil {
+		return nil
+	}
+	redacted := *token
+	redacted.Password = redactString(token.Password)
+	redacted.SessionToken = redactString(token.SessionToken)
+	return &redacted
+}
+
+// Redacted returns a deep copy of the node with its CHAP password masked
+func (node *Node) Redacted() *Node {
+	if node == nil {
+		return nil
+	}
+	redacted := *node
+	redacted.ChapPassword = redactString(node.ChapPassword)
+	for _, values := range []*[]*string{&redacted.Iqns, &redacted.Networks, &redacted.Wwpns} {
+		if *values == nil {
+			continue
+		}
+		copied := make([]*string, len(*values))
+		for i, value := range *values {
+			if value != nil {
+				copiedValue := *value
+				copied[i] = &copiedValue
+			}
+		}
+		*values = copied
+	}
+	return &redacted
+}
@@ -200,6 +200,7 @@ type Volume struct {
 	UsedBytes             int64                  `json:"used_bytes,omitempty"`
 	FreeBytes             int64                  `json:"free_bytes,omitempty"`
 	EncryptionKey         string                 `json:"encryption_key,omitempty"`
+	IscsiIface            string                 `json:"iscsi_iface,omitempty"` // Linux only - offload iSCSI iface (or iSCSI host, e.g. "host3") to login through
 }
 
 func (v Volume) TargetNames() []string {
@@ -209,6 +210,26 @@ func (v Volume) TargetNames() []string {
 	return v.Iqns
 }
 
+// UpdateDevice copies the volume details that CHAPI does not report for a device (e.g. the target
+// scope) onto the given device.  Callers should use it, rather than copying fields by hand, before
+// passing a device for the volume to the CHAPI device endpoints (e.g. OfflineDevice, DeleteDevice).
+func (v Volume) UpdateDevice(device *Device) {
+	if device == nil {
+		return
+	}
+	if device.VolumeID == "" {
+		device.VolumeID = v.ID
+	}
+	if v.TargetScope != "" {
+		device.TargetScope = v.TargetScope
+	}
+	for _, iscsiTarget := range device.IscsiTargets {
+		if iscsiTarget != nil && iscsiTarget.Scope == "" {
+			iscsiTarget.Scope = device.TargetScope
+		}
+	}
+}
+
 // Workaround NOS 5.0.x vs 5.1.x responses with different case
 // FcSession info
 type FcSession struct {
@@ -329,7 +350,22 @@ type FcHostPort struct {
 // Iface represents iface configuring with port binding
 type Iface struct {
 	Name             string
-	NetworkInterface *NetworkInterface
+	NetworkInterface *NetworkInterface // Network interface bound to a software (tcp) iface, nil for offload ifaces
+	Transport        string            // iface.transport_name (e.g. "tcp", "be2iscsi", "qedi", "cxgb4i")
+	HwAddress        string            // Offload iface only - MAC address of the offload port
+	IPAddress        string            // Offload iface only - IP address configured on the offload port
+	InitiatorName    string            // Offload iface only - initiator iqn, if different from the host iqn
+}
+
+// IscsiHost represents an iSCSI host from /sys/class/iscsi_host, either the software initiator or
+// an offload initiator port
+type IscsiHost struct {
+	Name          string `json:"name,omitempty"`           // SCSI host name (e.g. "host3")
+	Transport     string `json:"transport,omitempty"`      // iSCSI transport (e.g. "tcp", "be2iscsi", "qedi", "cxgb4i")
+	InitiatorName string `json:"initiator_name,omitempty"` // Initiator iqn of an offload host
+	IPAddress     string `json:"ip_address,omitempty"`     // IP address of an offload host
+	HwAddress     string `json:"hw_address,omitempty"`     // MAC address of an offload host
+	NetDev        string `json:"netdev,omitempty"`         // Network device of an offload host, if any
 }
 
 // IscsiTargets : array of pointers to IscsiTarget
@@ -52,3 +52,30 @@ func TestGetFilesystemOpts(t *testing.T) {
 	}
 
 }
+
+func TestVolumeRedacted(t *testing.T) {
+	volume := &Volume{
+		Name:          "vol1",
+		Config:        map[string]interface{}{"limit": 100},
+		Chap:          &ChapInfo{Name: "chapuser", Password: "chappassword"},
+		EncryptionKey: "encryptionkey",
+		Metadata:      []*KeyValue{{Key: "key", Value: "value"}},
+	}
+
+	redacted := volume.Redacted()
+	if redacted.Chap.Password != redactedValue || redacted.Chap.Name != "chapuser" || redacted.EncryptionKey != redactedValue || redacted.Name != "vol1" {
+		t.Errorf("unexpected redacted volume %+v, chap=%+v", redacted, redacted.Chap)
+	}
+
+	// The redacted copy shares nothing with the original
+	redacted.Config["limit"] = 0
+	redacted.Metadata[0].Value = ""
+	if volume.Chap.Password != "chappassword" || volume.EncryptionKey != "encryptionkey" || volume.Config["limit"] != 100 || volume.Metadata[0].Value != "value" {
+		t.Errorf("original volume modified %+v", volume)
+	}
+
+	var nilVolume *Volume
+	if nilVolume.Redacted() != nil {
+		t.Error("unexpected redacted nil volume")
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"container/list"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,13 +34,23 @@ const (
 
 var (
 	propertyPattern = "^[\\s\\t]*(?P<param>[^\\s\\t]+)[\\s\\t]*(?P<value>\".+\"|[^\\s\\t]+)\\s*"
+	includePattern  = regexp.MustCompile("^include\\s+(?P<pattern>.+)$")
 )
 
+// Hand-edited multipath.conf files are preserved when saved: comments (starting with # or !),
+// blank lines and the order of properties and sections are kept as read, and only properties
+// and sections added since are appended to their section.  A top level "include <file|glob>"
+// directive, relative to the including file, parses the included files as configurations of
+// their own; sections are looked up in the including file first and then in the included files,
+// and SaveConfig writes each modified file back to where it was read from.
+
 // Configuration represents entire multipath.conf along with all sections
 type Configuration struct {
-	file  string
-	root  *Section
-	mutex sync.RWMutex
+	file     string
+	root     *Section
+	mutex    sync.RWMutex
+	includes []*Configuration // Configurations of the included files
+	problems []string         // Syntax errors found while parsing, reported by Validate
 }
 
 // Section represents a multipath.conf section embedded between { and }
@@ -48,6 +61,17 @@ type Section struct {
 	children   *list.List
 	mutex      sync.RWMutex
 	duplicates *list.List
+	comment    string         // Comment following the section name
+	lines      []*sectionLine // Comments, properties, include directives and sections in file order
+}
+
+// sectionLine is a line of a section as read from the file
+type sectionLine struct {
+	comment   string     // Comment or blank line, or the comment following a property
+	property  string     // Name of the property
+	duplicate *Duplicate // Duplicate property
+	include   string     // Include directive file or glob
+	section   *Section   // Child section
 }
 
 // Duplicate manages duplicate params with same key in defaults section
@@ -106,46 +130,96 @@ func (section *Section) PrintSection(indent int) (conf []string) {
 	section.mutex.RLock()
 	defer section.mutex.RUnlock()
 
-	indenter := ""
-	if indent > 0 {
-		indenter = fmt.Sprintf("%"+strconv.Itoa(indent)+"s", " ")
-	}
+	indenter := strings.Repeat(" ", indent)
 
 	// Begin section
-	conf = append(conf, fmt.Sprintf("%s%s%s%s", indenter, section.name, " {", NEWLINE))
-	var formattedProperty string
+	header := section.name + " {"
+	if section.comment != "" {
+		header += " " + section.comment
+	}
+	conf = append(conf, indenter+header+NEWLINE)
+	conf = append(conf, section.printLines(indent+4)...)
+
+	// End section
+	conf = append(conf, fmt.Sprintf("%s%s%s", indenter, "}", NEWLINE))
+	return conf
+}
+
+// printLines returns the lines of the section, in the order they were read, followed by the
+// properties (sorted by name), duplicates and child sections added since.  The caller must hold
+// the section lock.
+func (section *Section) printLines(indent int) (conf []string) {
+	indenter := strings.Repeat(" ", indent)
 	spacerSize := 1
 	for property := range section.properties {
 		if len(property) > spacerSize {
 			spacerSize = len(property)
 		}
 	}
-	spacerSize++
-	for property, value := range section.properties {
-		formattedProperty = fmt.Sprintf("%s    %-"+strconv.Itoa(spacerSize)+"s%s%s", indenter, property, value, NEWLINE)
-		conf = append(conf, formattedProperty)
+	for e := section.duplicates.Front(); e != nil; e = e.Next() {
+		if dup := e.Value.(*Duplicate); len(dup.key) > spacerSize {
+			spacerSize = len(dup.key)
+		}
 	}
-	// handle duplicate param case in defaults/alias/multipaths section
-	if section.duplicates.Len() != 0 {
-		for e := section.duplicates.Front(); e != nil; e = e.Next() {
-			dup := e.Value.(*Duplicate)
-			formattedProperty = fmt.Sprintf("%s    %-"+strconv.Itoa(spacerSize)+"s%s%s", indenter, dup.key, dup.value, NEWLINE)
-			conf = append(conf, formattedProperty)
+	spacerSize++
+	formatProperty := func(property, value, comment string) string {
+		formattedProperty := fmt.Sprintf("%s%-"+strconv.Itoa(spacerSize)+"s%s", indenter, property, value)
+		if comment != "" {
+			formattedProperty += " " + comment
 		}
+		return formattedProperty + NEWLINE
 	}
-	if section.children.Len() != 0 {
-		indent = indent + 4
-		for e := section.children.Front(); e != nil; e = e.Next() {
-			s := e.Value.(*Section)
-			sectionParams := s.PrintSection(indent)
-			for _, line := range sectionParams {
-				conf = append(conf, line)
+
+	printedProperties := make(map[string]bool)
+	printed := make(map[interface{}]bool)
+	for _, line := range section.lines {
+		switch {
+		case line.section != nil:
+			if containsSection(section.children, line.section) {
+				conf = append(conf, line.section.PrintSection(indent)...)
+				printed[line.section] = true
+			}
+		case line.duplicate != nil:
+			if containsDuplicate(section.duplicates, line.duplicate) {
+				conf = append(conf, formatProperty(line.duplicate.key, line.duplicate.value, line.comment))
+				printed[line.duplicate] = true
+			}
+		case line.property != "":
+			// Properties removed since are dropped along with their comment
+			if value, ok := section.properties[line.property]; ok && !printedProperties[line.property] {
+				conf = append(conf, formatProperty(line.property, value, line.comment))
+				printedProperties[line.property] = true
 			}
+		case line.include != "":
+			conf = append(conf, fmt.Sprintf("%sinclude %s%s", indenter, line.include, NEWLINE))
+		case line.comment != "":
+			conf = append(conf, indenter+line.comment+NEWLINE)
+		default:
+			conf = append(conf, NEWLINE)
 		}
 	}
 
-	// End section
-	conf = append(conf, fmt.Sprintf("%s%s%s", indenter, "}", NEWLINE))
+	// handle properties, duplicates and sections added since the section was read
+	var properties []string
+	for property := range section.properties {
+		if !printedProperties[property] {
+			properties = append(properties, property)
+		}
+	}
+	sort.Strings(properties)
+	for _, property := range properties {
+		conf = append(conf, formatProperty(property, section.properties[property], ""))
+	}
+	for e := section.duplicates.Front(); e != nil; e = e.Next() {
+		if dup := e.Value.(*Duplicate); !printed[dup] {
+			conf = append(conf, formatProperty(dup.key, dup.value, ""))
+		}
+	}
+	for e := section.children.Front(); e != nil; e = e.Next() {
+		if s := e.Value.(*Section); !printed[s] {
+			conf = append(conf, s.PrintSection(indent)...)
+		}
+	}
 	return conf
 }
 
@@ -156,23 +230,69 @@ func (config *Configuration) PrintConf() (conf []string) {
 	defer config.mutex.RUnlock()
 
 	root := config.GetRoot()
-	if root != nil && root.GetChildren().Len() != 0 {
-		for e := root.GetChildren().Front(); e != nil; e = e.Next() {
-			s := e.Value.(*Section)
-			sectionConf := s.PrintSection(0)
-			for _, line := range sectionConf {
-				conf = append(conf, line)
-			}
-		}
+	if root != nil {
+		root.mutex.RLock()
+		defer root.mutex.RUnlock()
+		conf = root.printLines(0)
 	}
 	return conf
 }
 
+// GetIncludes returns the configurations of the files included by this configuration
+func (config *Configuration) GetIncludes() []*Configuration {
+	config.mutex.RLock()
+	defer config.mutex.RUnlock()
+
+	return config.includes
+}
+
+// getConfigurations returns this configuration followed by all the configurations it includes
+func (config *Configuration) getConfigurations() []*Configuration {
+	configs := []*Configuration{config}
+	for _, include := range config.GetIncludes() {
+		configs = append(configs, include.getConfigurations()...)
+	}
+	return configs
+}
+
+// containsSection returns true if the list contains the given section
+func containsSection(sections *list.List, section *Section) bool {
+	for e := sections.Front(); e != nil; e = e.Next() {
+		if e.Value.(*Section) == section {
+			return true
+		}
+	}
+	return false
+}
+
+// containsDuplicate returns true if the list contains the given duplicate
+func containsDuplicate(duplicates *list.List, duplicate *Duplicate) bool {
+	for e := duplicates.Front(); e != nil; e = e.Next() {
+		if e.Value.(*Duplicate) == duplicate {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSection returns true if the section is, or is nested in, the given root section
+func hasSection(root *Section, section *Section) bool {
+	if root == section {
+		return true
+	}
+	for e := root.GetChildren().Front(); e != nil; e = e.Next() {
+		if hasSection(e.Value.(*Section), section) {
+			return true
+		}
+	}
+	return false
+}
+
 // newConfiguration creates a new Configuration instance.
 func newConfiguration(filePath string) *Configuration {
 	return &Configuration{
 		file: filePath,
-		root: &Section{name: "root", properties: nil, parent: nil, children: list.New(), duplicates: list.New()},
+		root: &Section{name: "root", properties: make(map[string]string), parent: nil, children: list.New(), duplicates: list.New()},
 	}
 }
 
@@ -181,24 +301,17 @@ func (config *Configuration) AddSection(sectionName string, parent *Section) (se
 	log.Trace("addSection called with ", sectionName)
 	section = &Section{name: sectionName, properties: make(map[string]string), parent: parent, children: list.New(), duplicates: list.New()}
 	foundParent := false
+	configs := config.getConfigurations()
 
 	config.mutex.Lock()
 	defer config.mutex.Unlock()
 
-	root := config.GetRoot()
-	if root != nil {
-		if parent.GetName() == root.GetName() {
-			// add new section under root
-			root.GetChildren().PushBack(section)
+	// the parent may be nested at any level, in this file or in an included file
+	for _, c := range configs {
+		if c.root != nil && parent != nil && hasSection(c.root, parent) {
+			parent.GetChildren().PushBack(section)
 			foundParent = true
-		} else {
-			for e := root.GetChildren().Front(); e != nil; e = e.Next() {
-				// find parent to insert the section
-				if e.Value.(*Section).GetName() == parent.GetName() {
-					e.Value.(*Section).GetChildren().PushBack(section)
-					foundParent = true
-				}
-			}
+			break
 		}
 	}
 	if !foundParent {
@@ -220,28 +333,55 @@ func parseOption(option string) (opt, value string) {
 	return
 }
 
-func addOption(section *Section, option string) {
+// addOption adds the property, followed by the given comment, to the section
+func addOption(section *Section, option string, comment string) {
+	if section == nil {
+		return
+	}
 	section.mutex.Lock()
 	defer section.mutex.Unlock()
 
 	var key, value string
-	if section != nil {
-		if key, value = parseOption(option); value != "" {
-			if _, ok := section.properties[key]; ok {
-				// already another parameter present in section with same name, add to duplicates
-				duplicate := &Duplicate{key: key, value: value}
-				section.duplicates.PushBack(duplicate)
-			} else {
-				section.properties[key] = value
-			}
+	if key, value = parseOption(option); value != "" {
+		if _, ok := section.properties[key]; ok {
+			// already another parameter present in section with same name, add to duplicates
+			duplicate := &Duplicate{key: key, value: value}
+			section.duplicates.PushBack(duplicate)
+			section.lines = append(section.lines, &sectionLine{duplicate: duplicate, comment: comment})
+		} else {
+			section.properties[key] = value
+			section.lines = append(section.lines, &sectionLine{property: key, comment: comment})
+		}
+	}
+}
+
+// addLine adds a comment, blank line, include directive or child section line to the section
+func addLine(section *Section, line *sectionLine) {
+	section.mutex.Lock()
+	defer section.mutex.Unlock()
+
+	section.lines = append(section.lines, line)
+}
+
+// splitComment splits the line into its content and the comment, starting with # or ! outside of
+// a quoted value, that follows it
+func splitComment(line string) (content, comment string) {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case (c == '#' || c == '!') && !quoted && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i:])
 		}
 	}
+	return strings.TrimSpace(line), ""
 }
 
 //checks for the section
 func isSection(line string) (bool, error) {
 	line = strings.TrimSpace(line)
-	prefixes := []string{"defaults", "blacklist", "blacklist_exceptions", "devices", "device", "multipaths", "multipath"}
+	prefixes := []string{"defaults", "blacklist", "blacklist_exceptions", "devices", "device", "multipaths", "multipath", "overrides", "protocol"}
 	for _, prefix := range prefixes {
 		r, err := regexp.Compile("^" + prefix + "\\s*[{]*$")
 		if err != nil {
@@ -259,13 +399,19 @@ func isSection(line string) (bool, error) {
 // ParseConfig reads and parses give config file into sections
 func ParseConfig(filePath string) (config *Configuration, err error) {
 	log.Trace("ParseConfig called")
-	filePath = path.Clean(filePath)
+	return parseConfig(path.Clean(filePath), map[string]bool{})
+}
 
+// parseConfig parses the config file, and the files it includes, into sections.  Files already
+// being parsed (i.e. include loops) are not parsed again.
+func parseConfig(filePath string, parsing map[string]bool) (config *Configuration, err error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
+	parsing[filePath] = true
+	defer delete(parsing, filePath)
 
 	// initialize new configuration
 	config = newConfiguration(filePath)
@@ -273,77 +419,172 @@ func ParseConfig(filePath string) (config *Configuration, err error) {
 	currentSection := config.root
 
 	scanner := bufio.NewScanner(bufio.NewReader(file))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !(strings.HasPrefix(line, "#")) && len(line) > 0 {
-			section_present, err := isSection(line)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, comment := splitComment(scanner.Text())
+		if line == "" {
+			// comment or blank line
+			addLine(currentSection, &sectionLine{comment: comment})
+			continue
+		}
+		sectionPresent, err := isSection(line)
+		if err != nil {
+			return nil, err
+		}
+		if sectionPresent {
+			name := strings.TrimSpace(strings.TrimRight(line, "{"))
+			// add new section with parent updated
+			log.Trace("adding section ", name)
+			section, err := config.AddSection(name, currentSection)
 			if err != nil {
 				return nil, err
 			}
-			if section_present {
-				name := strings.Trim(line, " {")
-				// add new section with parent updated
-				log.Trace("adding section ", name)
-				currentSection, err = config.AddSection(name, currentSection)
-				if err != nil {
-					return nil, err
-				}
-				// indicate new section begun
+			section.comment = comment
+			addLine(currentSection, &sectionLine{section: section})
+			// indicate new section begun
+			currentSection = section
+			continue
+		}
+		if line == "{" {
+			// ignore beginning of section if { is in different line than section name
+			continue
+		} else if line == "}" {
+			// end section
+			if currentSection == config.root {
+				config.problems = append(config.problems, fmt.Sprintf("line %d: unexpected }", lineNumber))
 				continue
-			} else {
-				if strings.TrimSpace(line) == "{" {
-					// ignore beginning of section if { is in different line than section name
-					continue
-				} else if strings.TrimSpace(line) == "}" {
-					// end section
-					currentSection = currentSection.parent
-					continue
-				}
-				addOption(currentSection, line)
 			}
+			currentSection = currentSection.parent
+			continue
 		}
+		if currentSection == config.root && includePattern.MatchString(line) {
+			include := util.FindStringSubmatchMap(line, includePattern)["pattern"]
+			addLine(currentSection, &sectionLine{include: include, comment: comment})
+			if err = config.parseInclude(strings.Trim(include, "\""), lineNumber, parsing); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		addOption(currentSection, line, comment)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	for section := currentSection; section != config.root; section = section.parent {
+		config.problems = append(config.problems, fmt.Sprintf("section %s is not closed", section.GetName()))
+	}
 	return config, nil
 }
 
-// SaveConfig writes corrected config to file after taking backup
+// parseInclude parses the files matching an include directive, relative to the including file
+func (config *Configuration) parseInclude(pattern string, lineNumber int, parsing map[string]bool) error {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(config.file), pattern)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		config.problems = append(config.problems, fmt.Sprintf("line %d: invalid include %s, %s", lineNumber, pattern, err.Error()))
+		return nil
+	}
+	if len(files) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		config.problems = append(config.problems, fmt.Sprintf("line %d: included file %s not found", lineNumber, pattern))
+	}
+	for _, file := range files {
+		file = path.Clean(file)
+		if parsing[file] {
+			config.problems = append(config.problems, fmt.Sprintf("line %d: %s is already being included", lineNumber, file))
+			continue
+		}
+		include, err := parseConfig(file, parsing)
+		if err != nil {
+			return err
+		}
+		config.includes = append(config.includes, include)
+	}
+	return nil
+}
+
+// SaveConfig writes corrected config to file after taking backup.  The configuration, including
+// the files it includes, is validated first and nothing is written if it is invalid.  Each modified
+// file is backed up, written to a temporary file that must read back to the same configuration and
+// then renamed over the file; if any file cannot be written, the files already written are restored
+// from their backup.
 func SaveConfig(config *Configuration, filePath string) (err error) {
 	log.Trace("SaveConfig called")
 
-	config.mutex.Lock()
-	err = TakeBackupOfConfFile(MPATHCONF, NimbleBackupSuffix)
-	if err != nil {
+	if err = config.Validate(); err != nil {
 		return err
 	}
 
-	f, err := os.Create(filePath)
-	if err != nil {
-		return err
+	// included files are saved where they were read from, the main file last
+	type savedFile struct {
+		file   string
+		backup string
 	}
-
-	defer func() {
-		err = f.Close()
-	}()
-
-	w := bufio.NewWriter(f)
+	var saved []savedFile
 	defer func() {
-		err = w.Flush()
+		if err != nil {
+			for _, s := range saved {
+				restoreConfFile(s.file, s.backup)
+			}
+		}
 	}()
-	config.mutex.Unlock()
+	configs := config.getConfigurations()
+	for i := len(configs) - 1; i >= 0; i-- {
+		file := configs[i].file
+		if i == 0 {
+			file = filePath
+		}
+		conf := strings.Join(configs[i].PrintConf(), "")
+		if current, readErr := ioutil.ReadFile(file); readErr == nil && string(current) == conf {
+			continue
+		}
+		backup, err := backupConfFile(file, NimbleBackupSuffix)
+		if err != nil {
+			return err
+		}
+		if err = writeConfFile(file, conf); err != nil {
+			return err
+		}
+		saved = append(saved, savedFile{file: file, backup: backup})
+	}
+	return nil
+}
 
-	s := config.PrintConf()
+// writeConfFile replaces the file with the given configuration through a temporary file, in the same
+// directory, that must parse back to the same configuration
+func writeConfFile(filePath string, conf string) (err error) {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(filePath); err == nil {
+		mode = fi.Mode()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(filePath), filepath.Base(filePath)+".")
+	if err != nil {
+		return err
+	}
+	tempFile := f.Name()
+	defer os.Remove(tempFile)
 
-	config.mutex.Lock()
-	defer config.mutex.Unlock()
-	for _, line := range s {
-		w.WriteString(line)
+	_, err = f.WriteString(conf)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempFile, mode)
+	}
+	if err != nil {
+		return err
 	}
 
-	return err
+	// verify the written file reads back to the same configuration
+	written, err := ParseConfig(tempFile)
+	if err != nil {
+		return err
+	}
+	if strings.Join(written.PrintConf(), "") != conf {
+		return fmt.Errorf("%s does not read back to the saved configuration", filePath)
+	}
+	return os.Rename(tempFile, filePath)
 }
 
 // GetDeviceSection gets device section in /etc/multipath.conf
@@ -368,6 +609,12 @@ func (config *Configuration) GetDeviceSection(deviceType string) (section *Secti
 			}
 		}
 	}
+	// then look in the included files
+	for _, include := range config.includes {
+		if section, err = include.GetDeviceSection(deviceType); err == nil {
+			return section, nil
+		}
+	}
 	return nil, errors.New("nimble section is not found")
 }
 
@@ -397,19 +644,48 @@ func (config *Configuration) GetSection(sectionName, vendor string) (section *Se
 			}
 		}
 	}
+	// then look in the included files
+	for _, include := range config.includes {
+		if section, err = include.GetSection(sectionName, vendor); err == nil {
+			return section, nil
+		}
+	}
 	return nil, errors.New(SectionNotFoundError + " with name " + sectionName)
 }
 
 // TakeBackupOfConfFile take a backup of srcFile after taking backup in same directory with suffix and timestamp appended
 func TakeBackupOfConfFile(srcFile string, backupSuffix string) (err error) {
-	backupName := fmt.Sprintf("%s-%s-%s", srcFile, backupSuffix, time.Now().Format("2006-01-02 15:04:05"))
+	_, err = backupConfFile(srcFile, backupSuffix)
+	return err
+}
+
+// backupConfFile takes a backup of srcFile and returns the backup file name, or an empty name if
+// srcFile does not exist
+func backupConfFile(srcFile string, backupSuffix string) (backupName string, err error) {
+	backupName = fmt.Sprintf("%s-%s-%s", srcFile, backupSuffix, time.Now().Format("2006-01-02 15:04:05"))
 	err = util.CopyFile(srcFile, backupName)
 	if err != nil {
 		if !os.IsNotExist(err) { // fine if the file does not exists
-			return err
+			return "", err
 		}
+		return "", nil
 	}
-	return nil
+	return backupName, nil
+}
+
+// restoreConfFile restores the file from its backup, or removes it if it did not exist
+func restoreConfFile(filePath string, backupName string) {
+	var err error
+	if backupName == "" {
+		err = os.Remove(filePath)
+	} else {
+		err = util.CopyFile(backupName, filePath)
+	}
+	if err != nil {
+		log.Errorf("unable to restore %s from backup %s, err %s", filePath, backupName, err.Error())
+		return
+	}
+	log.Infof("restored %s from backup %s", filePath, backupName)
 }
 
 // IsUserFriendlyNamesEnabled returns true if user_friendly_names is enabled
@@ -3,6 +3,10 @@ package mpathconfig
 // Copyright 2019 Hewlett Packard Enterprise Development LP
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -120,3 +124,119 @@ func TestParseBlacklistExceptionsSection(t *testing.T) {
 		}
 	}
 }
+
+func TestPrintConfPreservesFile(t *testing.T) {
+	content, err := ioutil.ReadFile("./multipath_comments_test.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseConfig("./multipath_comments_test.conf")
+	if err != nil {
+		t.Fatal("Parsing multipath.conf failed ", err)
+	}
+	// comments, blank lines and ordering are kept as read
+	if conf := strings.Join(config.PrintConf(), ""); conf != string(content) {
+		t.Errorf("Printed configuration does not match the file:\n%s", conf)
+	}
+	if err = config.Validate(); err != nil {
+		t.Error("Validating multipath.conf failed ", err)
+	}
+
+	// added properties follow the properties read, removed properties are dropped with their comment
+	section, _ := config.GetSection("defaults", "")
+	section.GetProperties()["polling_interval"] = "10"
+	delete(section.GetProperties(), "find_multipaths")
+	expected := "defaults {\n    user_friendly_names yes\n    polling_interval    10\n}\n"
+	if conf := strings.Join(section.PrintSection(0), ""); conf != expected {
+		t.Errorf("Printed defaults section %q, expected %q", conf, expected)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mpathconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		conf    string
+		invalid bool
+	}{
+		{"valid", "defaults {\n    no_path_retry \"queue\"\n}\n", false},
+		{"unexpected close", "defaults {\n}\n}\n", true},
+		{"not closed", "devices {\n    device {\n", true},
+		{"unknown section", "defaults {\n    unknown {\n    }\n}\n", true},
+		{"misplaced section", "defaults {\n    device {\n        vendor \"Nimble\"\n    }\n}\n", true},
+		{"outside of a section", "find_multipaths no\n", true},
+		{"device without product", "devices {\n    device {\n        vendor \"Nimble\"\n    }\n}\n", true},
+		{"invalid value", "defaults {\n    user_friendly_names maybe\n}\n", true},
+		{"unbalanced quote", "defaults {\n    getuid_callout \"/lib/udev/scsi_id\n}\n", true},
+		{"missing include", "include missing.conf\n", true},
+	}
+	for _, tc := range tests {
+		file := filepath.Join(dir, "multipath.conf")
+		if err = ioutil.WriteFile(file, []byte(tc.conf), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := ParseConfig(file)
+		if err != nil {
+			t.Fatalf("%s: parsing failed, %v", tc.name, err)
+		}
+		if err = config.Validate(); (err != nil) != tc.invalid {
+			t.Errorf("%s: expected invalid=%v, got %v", tc.name, tc.invalid, err)
+		}
+	}
+}
+
+func TestSaveConfigWithInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mpathconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "multipath.conf")
+	included := filepath.Join(dir, "nimble.conf")
+	mainConf := "# main file\ninclude nimble.conf\ndefaults {\n    find_multipaths yes\n}\n"
+	includedConf := "devices {\n    device {\n        vendor  \"Nimble\" # array\n        product \"Server\"\n    }\n}\n"
+	ioutil.WriteFile(file, []byte(mainConf), 0644)
+	ioutil.WriteFile(included, []byte(includedConf), 0644)
+
+	config, err := ParseConfig(file)
+	if err != nil {
+		t.Fatal("Parsing multipath.conf failed ", err)
+	}
+	if len(config.GetIncludes()) != 1 {
+		t.Fatalf("Expected 1 included file, got %d", len(config.GetIncludes()))
+	}
+
+	// sections of the included file are updated in the included file
+	device, err := config.GetDeviceSection("Nimble")
+	if err != nil {
+		t.Fatal("Getting the included device section failed ", err)
+	}
+	device.GetProperties()["no_path_retry"] = "30"
+	if err = SaveConfig(config, file); err != nil {
+		t.Fatal("Saving multipath.conf failed ", err)
+	}
+	content, _ := ioutil.ReadFile(file)
+	if string(content) != mainConf {
+		t.Errorf("Unmodified main file was changed to %q", content)
+	}
+	content, _ = ioutil.ReadFile(included)
+	expected := "devices {\n    device {\n        vendor        \"Nimble\" # array\n        product       \"Server\"\n        no_path_retry 30\n    }\n}\n"
+	if string(content) != expected {
+		t.Errorf("Included file saved as %q, expected %q", content, expected)
+	}
+
+	// an invalid configuration is not saved
+	device.GetProperties()["no_path_retry"] = "sometimes"
+	if err = SaveConfig(config, file); err == nil {
+		t.Error("Invalid configuration was saved")
+	}
+	if content, _ = ioutil.ReadFile(included); string(content) != expected {
+		t.Errorf("Included file changed to %q by an invalid configuration", content)
+	}
+}
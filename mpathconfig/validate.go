@@ -0,0 +1,135 @@
+package mpathconfig
+
+// Copyright 2019 Hewlett Packard Enterprise Development LP.
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// sectionParents lists the sections each section may be nested in ("root" for top level)
+	sectionParents = map[string][]string{
+		"defaults":             {"root"},
+		"blacklist":            {"root"},
+		"blacklist_exceptions": {"root"},
+		"devices":              {"root"},
+		"multipaths":           {"root"},
+		"overrides":            {"root"},
+		"device":               {"devices", "blacklist", "blacklist_exceptions"},
+		"multipath":            {"multipaths"},
+		"protocol":             {"overrides", "device"},
+	}
+
+	// propertyValues are the values accepted by multipath for well known properties
+	propertyValues = map[string]*regexp.Regexp{
+		"user_friendly_names":  regexp.MustCompile("^(yes|no)$"),
+		"find_multipaths":      regexp.MustCompile("^(yes|no|on|off|greedy|smart|strict)$"),
+		"path_grouping_policy": regexp.MustCompile("^(failover|multibus|group_by_serial|group_by_prio|group_by_node_name)$"),
+		"rr_weight":            regexp.MustCompile("^(uniform|priorities)$"),
+		"failback":             regexp.MustCompile("^([0-9]+|immediate|manual|followover)$"),
+		"no_path_retry":        regexp.MustCompile("^([0-9]+|fail|queue)$"),
+		"dev_loss_tmo":         regexp.MustCompile("^([0-9]+|infinity)$"),
+		"fast_io_fail_tmo":     regexp.MustCompile("^([0-9]+|off)$"),
+		"rr_min_io":            regexp.MustCompile("^[0-9]+$"),
+		"rr_min_io_rq":         regexp.MustCompile("^[0-9]+$"),
+		"polling_interval":     regexp.MustCompile("^[0-9]+$"),
+		"max_polling_interval": regexp.MustCompile("^[0-9]+$"),
+		"checker_timeout":      regexp.MustCompile("^[0-9]+$"),
+	}
+)
+
+// Validate checks the configuration, and the files it includes, the way multipath does when it
+// reads multipath.conf (multipath -t): syntax errors, unknown or misplaced sections, properties
+// outside of a section, device and multipath sections without the properties identifying them and
+// invalid values of well known properties.  All problems are returned together as a single error.
+func (config *Configuration) Validate() error {
+	var problems []string
+	for _, c := range config.getConfigurations() {
+		c.mutex.RLock()
+		for _, problem := range c.problems {
+			problems = append(problems, fmt.Sprintf("%s: %s", c.file, problem))
+		}
+		sectionProblems := validateSection(c.root)
+		sort.Strings(sectionProblems)
+		for _, problem := range sectionProblems {
+			problems = append(problems, fmt.Sprintf("%s: %s", c.file, problem))
+		}
+		c.mutex.RUnlock()
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid multipath configuration (%s)", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateSection returns the problems found in the section and its child sections
+func validateSection(section *Section) (problems []string) {
+	section.mutex.RLock()
+	defer section.mutex.RUnlock()
+
+	name := section.name
+	switch {
+	case section.parent == nil:
+		for property := range section.properties {
+			problems = append(problems, fmt.Sprintf("property %s is outside of a section", property))
+		}
+	case !isValidParent(name, section.parent.GetName()):
+		problems = append(problems, fmt.Sprintf("section %s is not valid in %s", name, section.parent.GetName()))
+	}
+
+	if name == "devices" || name == "multipaths" {
+		for property := range section.properties {
+			problems = append(problems, fmt.Sprintf("property %s is not valid in section %s", property, name))
+		}
+	}
+	if name == "device" && section.parent != nil {
+		_, vendor := section.properties["vendor"]
+		_, product := section.properties["product"]
+		if section.parent.GetName() == "devices" && (!vendor || !product) {
+			problems = append(problems, "device section in devices must have both vendor and product")
+		} else if section.parent.GetName() != "devices" && !vendor && !product {
+			problems = append(problems, fmt.Sprintf("device section in %s must have vendor or product", section.parent.GetName()))
+		}
+	}
+	if _, wwid := section.properties["wwid"]; name == "multipath" && !wwid {
+		problems = append(problems, "multipath section must have a wwid")
+	}
+
+	for property, value := range section.properties {
+		problems = append(problems, validateProperty(name, property, value)...)
+	}
+	for e := section.duplicates.Front(); e != nil; e = e.Next() {
+		dup := e.Value.(*Duplicate)
+		problems = append(problems, validateProperty(name, dup.key, dup.value)...)
+	}
+	for e := section.children.Front(); e != nil; e = e.Next() {
+		problems = append(problems, validateSection(e.Value.(*Section))...)
+	}
+	return problems
+}
+
+// validateProperty returns the problems found in the property value
+func validateProperty(sectionName, property, value string) (problems []string) {
+	if value == "{" {
+		return []string{fmt.Sprintf("unknown section %s in section %s", property, sectionName)}
+	}
+	if strings.Count(value, "\"")%2 != 0 {
+		return []string{fmt.Sprintf("%s %s has an unbalanced quote in section %s", property, value, sectionName)}
+	}
+	if pattern, ok := propertyValues[property]; ok && !pattern.MatchString(strings.Trim(value, "\"")) {
+		problems = append(problems, fmt.Sprintf("%s %s is not valid in section %s", property, value, sectionName))
+	}
+	return problems
+}
+
+// isValidParent returns true if the section may be nested in the parent section
+func isValidParent(name, parentName string) bool {
+	for _, parent := range sectionParents[name] {
+		if parent == parentName {
+			return true
+		}
+	}
+	return false
+}
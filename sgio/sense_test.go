@@ -0,0 +1,67 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package sgio
+
+import (
+	"testing"
+)
+
+func TestDecodeSense(t *testing.T) {
+	tests := []struct {
+		name     string
+		senseBuf []byte
+		sense    *Sense
+	}{
+		{"fixed", []byte{0xf0, 0, 0x05, 0, 0, 0, 0, 10, 0, 0, 0, 0, 0x25, 0x00, 0, 0, 0, 0},
+			&Sense{SenseKey: SenseKeyIllegalRequest, Asc: 0x25, Key: "ILLEGAL REQUEST", Reason: "LOGICAL UNIT NOT SUPPORTED"}},
+		{"fixed deferred", []byte{0x71, 0, 0x03, 0, 0, 0, 0, 10, 0, 0, 0, 0, 0x11, 0x00},
+			&Sense{SenseKey: SenseKeyMediumError, Asc: 0x11, Key: "MEDIUM ERROR", Reason: "UNRECOVERED READ ERROR", Deferred: true}},
+		{"fixed without asc", []byte{0x70, 0, 0x06, 0, 0, 0, 0, 0},
+			&Sense{SenseKey: SenseKeyUnitAttention, Key: "UNIT ATTENTION", Reason: "NO ADDITIONAL SENSE INFORMATION"}},
+		{"descriptor", []byte{0x72, 0x02, 0x04, 0x03, 0, 0, 0, 0},
+			&Sense{SenseKey: SenseKeyNotReady, Asc: 0x04, Ascq: 0x03, Key: "NOT READY", Reason: "LOGICAL UNIT NOT READY, MANUAL INTERVENTION REQUIRED"}},
+		{"unknown asc", []byte{0x72, 0x0c, 0x99, 0x01},
+			&Sense{SenseKey: 0x0c, Asc: 0x99, Ascq: 0x01, Key: "SENSE KEY 0xC"}},
+		{"empty", nil, nil},
+		{"short", []byte{0x70, 0}, nil},
+		{"unsupported", []byte{0x7f, 0x05, 0x25, 0x00}, nil},
+	}
+	for _, tc := range tests {
+		sense, err := DecodeSense(tc.senseBuf)
+		if tc.sense == nil {
+			if err == nil {
+				t.Errorf("%v: expected error, got %+v", tc.name, sense)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: DecodeSense failed, err=%v", tc.name, err)
+			continue
+		}
+		if *sense != *tc.sense {
+			t.Errorf("%v: expected %+v, got %+v", tc.name, tc.sense, sense)
+		}
+	}
+}
+
+func TestScsiError(t *testing.T) {
+	scsiErr := NewScsiError(ScsiStatusCheckCondition, []byte{0x72, 0x05, 0x25, 0x00})
+	expected := "SCSI status 0x02 (CHECK CONDITION), sense ILLEGAL REQUEST: LOGICAL UNIT NOT SUPPORTED (ASC=0x25 ASCQ=0x00)"
+	if scsiErr.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, scsiErr.Error())
+	}
+	if scsiErr.IsRecovered() {
+		t.Error("expected error not to be recovered")
+	}
+
+	// Sense data that cannot be decoded is ignored
+	scsiErr = NewScsiError(ScsiStatusBusy, []byte{0})
+	expected = "SCSI status 0x08 (BUSY)"
+	if scsiErr.Sense != nil || scsiErr.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, scsiErr.Error())
+	}
+
+	if !NewScsiError(ScsiStatusCheckCondition, []byte{0x72, 0x01, 0x17, 0x01}).IsRecovered() {
+		t.Error("expected recovered error")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package sgio
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ALUA asymmetric access states, named as reported by the Linux scsi_dh_alua access_state
+// sysfs attribute
+const (
+	AluaActiveOptimized    = "active/optimized"
+	AluaActiveNonOptimized = "active/non-optimized"
+	AluaStandby            = "standby"
+	AluaUnavailable        = "unavailable"
+	AluaLBADependent       = "lba-dependent"
+	AluaOffline            = "offline"
+	AluaTransitioning      = "transitioning"
+	AluaUnknown            = "unknown"
+)
+
+const (
+	rtpgHeaderLen           = 4 // RTPG return data length
+	rtpgDescriptorLen       = 8 // Target port group descriptor, excluding its target ports
+	rtpgTargetPortLen       = 4 // Relative target port descriptor
+	vpdHeaderLen            = 4 // VPD page code and page length
+	vpdDesignatorHeaderLen  = 4 // Designator code set, type and length
+	vpdDesignatorTypeTPG    = 0x5
+	vpdDesignatorTypeMask   = 0x0f
+	rtpgPreferredMask       = 0x80
+	rtpgAccessStateMask     = 0x0f
+	vpdDeviceIdentification = 0x83
+)
+
+// TargetPortGroup is a target port group descriptor from a REPORT TARGET PORT GROUPS response
+type TargetPortGroup struct {
+	ID                  uint16   // Target port group identifier
+	AccessState         string   // Asymmetric access state (e.g. AluaActiveOptimized)
+	Preferred           bool     // Target port group is the preferred path (PREF bit)
+	RelativeTargetPorts []uint16 // Relative target port identifiers in the group
+}
+
+// AluaAccessState returns the name of the given asymmetric access state
+func AluaAccessState(state byte) string {
+	switch state & rtpgAccessStateMask {
+	case 0x0:
+		return AluaActiveOptimized
+	case 0x1:
+		return AluaActiveNonOptimized
+	case 0x2:
+		return AluaStandby
+	case 0x3:
+		return AluaUnavailable
+	case 0x4:
+		return AluaLBADependent
+	case 0xe:
+		return AluaOffline
+	case 0xf:
+		return AluaTransitioning
+	}
+	return AluaUnknown
+}
+
+// parseTargetPortGroups parses a (length only format) REPORT TARGET PORT GROUPS response
+func parseTargetPortGroups(respBuf []byte) ([]*TargetPortGroup, error) {
+	if len(respBuf) < rtpgHeaderLen {
+		return nil, fmt.Errorf("target port groups response too short, length=%v", len(respBuf))
+	}
+	end := rtpgHeaderLen + int(binary.BigEndian.Uint32(respBuf[0:4]))
+	if end > len(respBuf) {
+		return nil, fmt.Errorf("target port groups response truncated, length=%v, expected=%v", len(respBuf), end)
+	}
+
+	var groups []*TargetPortGroup
+	for offset := rtpgHeaderLen; offset+rtpgDescriptorLen <= end; {
+		descriptor := respBuf[offset:]
+		group := &TargetPortGroup{
+			ID:          binary.BigEndian.Uint16(descriptor[2:4]),
+			AccessState: AluaAccessState(descriptor[0]),
+			Preferred:   descriptor[0]&rtpgPreferredMask != 0,
+		}
+		portCount := int(descriptor[7])
+		offset += rtpgDescriptorLen
+		if offset+portCount*rtpgTargetPortLen > end {
+			return nil, fmt.Errorf("target port group %v descriptor truncated", group.ID)
+		}
+		for i := 0; i < portCount; i++ {
+			group.RelativeTargetPorts = append(group.RelativeTargetPorts, binary.BigEndian.Uint16(respBuf[offset+2:offset+4]))
+			offset += rtpgTargetPortLen
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// parseVpd83TargetPortGroup returns the target port group designator from a device
+// identification (VPD page 0x83) response
+func parseVpd83TargetPortGroup(respBuf []byte) (uint16, error) {
+	if len(respBuf) < vpdHeaderLen || respBuf[1] != vpdDeviceIdentification {
+		return 0, fmt.Errorf("invalid device identification page")
+	}
+	end := vpdHeaderLen + int(binary.BigEndian.Uint16(respBuf[2:4]))
+	if end > len(respBuf) {
+		end = len(respBuf)
+	}
+	for offset := vpdHeaderLen; offset+vpdDesignatorHeaderLen <= end; {
+		designatorType := respBuf[offset+1] & vpdDesignatorTypeMask
+		designatorLen := int(respBuf[offset+3])
+		designator := offset + vpdDesignatorHeaderLen
+		if designatorType == vpdDesignatorTypeTPG && designatorLen >= 4 && designator+4 <= end {
+			return binary.BigEndian.Uint16(respBuf[designator+2 : designator+4]), nil
+		}
+		offset = designator + designatorLen
+	}
+	return 0, fmt.Errorf("device identification page has no target port group designator")
+}
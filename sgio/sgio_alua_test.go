@@ -0,0 +1,50 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package sgio
+
+import (
+	"testing"
+)
+
+func TestParseTargetPortGroups(t *testing.T) {
+	respBuf := []byte{
+		0, 0, 0, 24, // Return data length
+		0x80, 0x0f, 0, 1, 0, 0, 0, 2, // Preferred, active/optimized, TPG 1, 2 ports
+		0, 0, 0, 1,
+		0, 0, 0, 2,
+		0x02, 0x0f, 0, 2, 0, 0, 0, 0, // Standby, TPG 2, no ports
+		0, 0, 0, 0, // Unused allocation
+	}
+	groups, err := parseTargetPortGroups(respBuf)
+	if err != nil {
+		t.Fatalf("parseTargetPortGroups failed, err=%v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 target port groups, got %v", len(groups))
+	}
+	if groups[0].ID != 1 || groups[0].AccessState != AluaActiveOptimized || !groups[0].Preferred || len(groups[0].RelativeTargetPorts) != 2 || groups[0].RelativeTargetPorts[1] != 2 {
+		t.Errorf("unexpected target port group %+v", groups[0])
+	}
+	if groups[1].ID != 2 || groups[1].AccessState != AluaStandby || groups[1].Preferred {
+		t.Errorf("unexpected target port group %+v", groups[1])
+	}
+
+	// Descriptor claiming more target ports than returned
+	if _, err = parseTargetPortGroups([]byte{0, 0, 0, 8, 0, 0, 0, 1, 0, 0, 0, 4}); err == nil {
+		t.Error("expected error for truncated descriptor")
+	}
+}
+
+func TestParseVpd83TargetPortGroup(t *testing.T) {
+	respBuf := []byte{
+		0, 0x83, 0, 16, // Device identification page, page length
+		0x01, 0x03, 0, 4, 0x11, 0x22, 0x33, 0x44, // NAA designator
+		0x01, 0x15, 0, 4, 0, 0, 0, 3, // Target port group designator, TPG 3
+	}
+	if id, err := parseVpd83TargetPortGroup(respBuf); err != nil || id != 3 {
+		t.Errorf("expected target port group 3, got %v, err=%v", id, err)
+	}
+	if _, err := parseVpd83TargetPortGroup(respBuf[:12]); err == nil {
+		t.Error("expected error without target port group designator")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package sgio
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Persistent reservation types, as reported by PERSISTENT RESERVE IN (READ RESERVATION)
+const (
+	PrTypeWriteExclusive                 = 0x1
+	PrTypeExclusiveAccess                = 0x3
+	PrTypeWriteExclusiveRegistrantsOnly  = 0x5
+	PrTypeExclusiveAccessRegistrantsOnly = 0x6
+	PrTypeWriteExclusiveAllRegistrants   = 0x7
+	PrTypeExclusiveAccessAllRegistrants  = 0x8
+)
+
+const (
+	prHeaderLen          = 8  // PRGENERATION and ADDITIONAL LENGTH
+	prKeyLen             = 8  // Reservation key
+	prReservationDescLen = 16 // READ RESERVATION reservation descriptor
+	prTypeOffset         = 13 // Scope and type, within the reservation descriptor
+	prTypeMask           = 0x0f
+)
+
+var (
+	prTypeNames = map[uint8]string{
+		PrTypeWriteExclusive:                 "Write Exclusive",
+		PrTypeExclusiveAccess:                "Exclusive Access",
+		PrTypeWriteExclusiveRegistrantsOnly:  "Write Exclusive - Registrants Only",
+		PrTypeExclusiveAccessRegistrantsOnly: "Exclusive Access - Registrants Only",
+		PrTypeWriteExclusiveAllRegistrants:   "Write Exclusive - All Registrants",
+		PrTypeExclusiveAccessAllRegistrants:  "Exclusive Access - All Registrants",
+	}
+)
+
+// PersistentReservation is the persistent reservation state of a LUN, as reported by PERSISTENT
+// RESERVE IN
+type PersistentReservation struct {
+	Generation     uint32   `json:"generation"`                // PRGENERATION, incremented by every registration change
+	RegisteredKeys []string `json:"registered_keys,omitempty"` // Keys registered with the LUN, one per I_T nexus (e.g. "0x8a3b000000000001")
+	Reserved       bool     `json:"reserved"`                  // True if the LUN is reserved
+	HolderKey      string   `json:"holder_key,omitempty"`      // Key of the reservation holder (empty for the all registrants types, held by every registered key)
+	Type           uint8    `json:"type,omitempty"`            // Reservation type (e.g. PrTypeExclusiveAccess)
+	TypeName       string   `json:"type_name,omitempty"`       // Reservation type name (e.g. "Exclusive Access")
+}
+
+// BlocksReads returns true if the reservation blocks the reads of the hosts not holding it (the
+// Exclusive Access types)
+func (reservation *PersistentReservation) BlocksReads() bool {
+	if !reservation.Reserved {
+		return false
+	}
+	switch reservation.Type {
+	case PrTypeExclusiveAccess, PrTypeExclusiveAccessRegistrantsOnly, PrTypeExclusiveAccessAllRegistrants:
+		return true
+	}
+	return false
+}
+
+// IsReservationConflict returns true if the command failed since the LUN is reserved by another
+// initiator
+func (e *ScsiError) IsReservationConflict() bool {
+	return e.Status == ScsiStatusReservationConflict
+}
+
+// PersistentReservationType returns the name of the given reservation type
+func PersistentReservationType(prType uint8) string {
+	if name, ok := prTypeNames[prType]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type 0x%X", prType)
+}
+
+// formatReservationKey returns the given reservation key as hex digits (e.g. "0x8a3b000000000001")
+func formatReservationKey(key []byte) string {
+	return fmt.Sprintf("0x%016x", binary.BigEndian.Uint64(key))
+}
+
+// parseReadKeys parses a PERSISTENT RESERVE IN (READ KEYS) response into the given reservation
+func parseReadKeys(respBuf []byte, reservation *PersistentReservation) error {
+	if len(respBuf) < prHeaderLen {
+		return fmt.Errorf("READ KEYS response too short (%v bytes)", len(respBuf))
+	}
+	reservation.Generation = binary.BigEndian.Uint32(respBuf[0:4])
+	end := prHeaderLen + int(binary.BigEndian.Uint32(respBuf[4:8]))
+	if end > len(respBuf) {
+		// The allocation length truncated the key list
+		end = len(respBuf)
+	}
+	reservation.RegisteredKeys = nil
+	for offset := prHeaderLen; offset+prKeyLen <= end; offset += prKeyLen {
+		reservation.RegisteredKeys = append(reservation.RegisteredKeys, formatReservationKey(respBuf[offset:offset+prKeyLen]))
+	}
+	return nil
+}
+
+// parseReadReservation parses a PERSISTENT RESERVE IN (READ RESERVATION) response into the given
+// reservation
+func parseReadReservation(respBuf []byte, reservation *PersistentReservation) error {
+	if len(respBuf) < prHeaderLen {
+		return fmt.Errorf("READ RESERVATION response too short (%v bytes)", len(respBuf))
+	}
+	reservation.Generation = binary.BigEndian.Uint32(respBuf[0:4])
+	length := int(binary.BigEndian.Uint32(respBuf[4:8]))
+	if length == 0 {
+		// Not reserved
+		reservation.Reserved = false
+		return nil
+	}
+	if length < prReservationDescLen || len(respBuf) < prHeaderLen+prReservationDescLen {
+		return fmt.Errorf("READ RESERVATION descriptor too short (%v bytes)", length)
+	}
+	descriptor := respBuf[prHeaderLen : prHeaderLen+prReservationDescLen]
+	reservation.Reserved = true
+	reservation.Type = descriptor[prTypeOffset] & prTypeMask
+	reservation.TypeName = PersistentReservationType(reservation.Type)
+	reservation.HolderKey = ""
+	if reservation.Type != PrTypeWriteExclusiveAllRegistrants && reservation.Type != PrTypeExclusiveAccessAllRegistrants {
+		reservation.HolderKey = formatReservationKey(descriptor[0:prKeyLen])
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package sgio
+
+import (
+	"testing"
+)
+
+func TestParseReadKeys(t *testing.T) {
+	respBuf := []byte{
+		0, 0, 0, 7, // PRGENERATION
+		0, 0, 0, 16, // Additional length, 2 keys
+		0x8a, 0x3b, 0, 0, 0, 0, 0, 0x01,
+		0x8a, 0x3b, 0, 0, 0, 0, 0, 0x02,
+		0, 0, 0, 0, 0, 0, 0, 0, // Unused allocation
+	}
+	reservation := &PersistentReservation{}
+	if err := parseReadKeys(respBuf, reservation); err != nil {
+		t.Fatalf("parseReadKeys failed, err=%v", err)
+	}
+	if reservation.Generation != 7 || len(reservation.RegisteredKeys) != 2 || reservation.RegisteredKeys[1] != "0x8a3b000000000002" {
+		t.Errorf("unexpected registered keys %+v", reservation)
+	}
+
+	// Key list truncated by the allocation length
+	if err := parseReadKeys(respBuf[:20], reservation); err != nil || len(reservation.RegisteredKeys) != 1 {
+		t.Errorf("expected 1 registered key, got %+v, err=%v", reservation, err)
+	}
+	if err := parseReadKeys(respBuf[:4], reservation); err == nil {
+		t.Error("expected error for truncated header")
+	}
+}
+
+func TestParseReadReservation(t *testing.T) {
+	respBuf := []byte{
+		0, 0, 0, 7, // PRGENERATION
+		0, 0, 0, 16, // Additional length
+		0x8a, 0x3b, 0, 0, 0, 0, 0, 0x02, // Reservation key
+		0, 0, 0, 0, // Obsolete
+		0,    // Reserved
+		0x03, // LU scope, Exclusive Access
+		0, 0, // Obsolete
+	}
+	reservation := &PersistentReservation{}
+	if err := parseReadReservation(respBuf, reservation); err != nil {
+		t.Fatalf("parseReadReservation failed, err=%v", err)
+	}
+	if !reservation.Reserved || reservation.HolderKey != "0x8a3b000000000002" || reservation.TypeName != "Exclusive Access" || !reservation.BlocksReads() {
+		t.Errorf("unexpected reservation %+v", reservation)
+	}
+
+	// All registrants hold the reservation
+	respBuf[21] = PrTypeWriteExclusiveAllRegistrants
+	if err := parseReadReservation(respBuf, reservation); err != nil || reservation.HolderKey != "" || reservation.BlocksReads() {
+		t.Errorf("unexpected all registrants reservation %+v, err=%v", reservation, err)
+	}
+
+	// Not reserved
+	if err := parseReadReservation([]byte{0, 0, 0, 7, 0, 0, 0, 0}, reservation); err != nil || reservation.Reserved || reservation.BlocksReads() {
+		t.Errorf("unexpected reservation %+v, err=%v", reservation, err)
+	}
+	if err := parseReadReservation(respBuf[:12], reservation); err == nil {
+		t.Error("expected error for truncated descriptor")
+	}
+}
@@ -26,3 +26,18 @@ func GetDeviceSerial(device string) (string, error) {
 func TestUnitReady(device string) error {
 	return fmt.Errorf("not implemented")
 }
+
+// GetTargetPortGroup returns the target port group, of the path to the device, using vpd page 0x83
+func GetTargetPortGroup(device string) (uint16, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
+// GetTargetPortGroups returns the ALUA state of the device's target port groups
+func GetTargetPortGroups(device string) ([]*TargetPortGroup, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// GetPersistentReservation returns the registered keys and the reservation of the device
+func GetPersistentReservation(device string) (*PersistentReservation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
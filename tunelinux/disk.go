@@ -14,12 +14,12 @@ import (
 )
 
 const (
-	// UdevFilePathName path name of the udev file deployed to tune settings
-	UdevFilePathName = "/etc/udev/rules.d/99-nimble-tune.rules"
-	udevAttrFormat   = "ATTR{queue/%s}=\"%s\""
+	udevAttrFormat = "ATTR{queue/%s}=\"%s\""
 )
 
 var (
+	// UdevFilePathName path name of the udev file deployed to tune settings
+	UdevFilePathName = "/etc/udev/rules.d/99-nimble-tune.rules"
 	// UdevTemplatePath is the path to template 99-nimble-tune.rules file
 	UdevTemplatePath = GetUdevTemplateFile()
 )
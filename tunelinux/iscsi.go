@@ -3,13 +3,16 @@ package tunelinux
 // Copyright 2019 Hewlett Packard Enterprise Development LP.
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/hpe-storage/common-host-libs/linux"
 	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/mpathconfig"
 	"github.com/hpe-storage/common-host-libs/util"
 )
 
@@ -23,6 +26,7 @@ const (
 	noopOutTimeoutPattern         = "^node.conn\\[0\\].timeo.noop_out_timeout\\s*=\\s*(?P<noop_out_timeout>.*)"
 	noopOutTimeoutIntervalPattern = "^node.conn\\[0\\].timeo.noop_out_interval\\s*=\\s*(?P<noop_out_interval>.*)"
 	startupPattern                = "^node.startup\\s*=\\s*(?P<startup>.*)"
+	loginRetriesPattern           = "^node.session.initial_login_retry_max\\s*=\\s*(?P<login_retries>.*)"
 	iscsi                         = "iscsi"
 )
 
@@ -36,7 +40,20 @@ var (
 		"noop_out_timeout":    "node.conn[0].timeo.noop_out_timeout",
 		"noop_out_interval":   "node.conn[0].timeo.noop_out_interval",
 		"replacement_timeout": "node.session.timeo.replacement_timeout",
-		"nr_sessions":         "node.session.nr_sessions"}
+		"nr_sessions":         "node.session.nr_sessions",
+		"login_retries":       "node.session.initial_login_retry_max"}
+
+	// open-iscsi defaults, used when a parameter is missing (or commented out) in iscsid.conf
+	iscsiParamDefaultMap = map[string]string{
+		"startup":             "manual",
+		"queue_depth":         "32",
+		"cmds_max":            "128",
+		"login_timeout":       "30",
+		"noop_out_timeout":    "5",
+		"noop_out_interval":   "5",
+		"replacement_timeout": "120",
+		"nr_sessions":         "1",
+		"login_retries":       "4"}
 
 	// used to verify parameter settings in iscisd.conf
 	iscsiParamPatternMap = map[string]string{
@@ -47,7 +64,8 @@ var (
 		"noop_out_timeout":    noopOutTimeoutPattern,
 		"noop_out_interval":   noopOutTimeoutIntervalPattern,
 		"replacement_timeout": replacementTimeoutPattern,
-		"nr_sessions":         nrSessionsPattern}
+		"nr_sessions":         nrSessionsPattern,
+		"login_retries":       loginRetriesPattern}
 )
 
 // getIscsiParamRecommendation get the recommendation for given parameter and value
@@ -208,22 +226,19 @@ func GetIscsiRecommendations(deviceParam ...string) (settings []*Recommendation,
 	for index, dev := range iscsiParamMap {
 		if dev.DeviceType == deviceType {
 			for param, recommendedValue := range iscsiParamMap[index].deviceMap {
-				formattedParam, _ := getIscsiFormattedParam(param)
-				for _, line := range lines {
-					if false == strings.HasPrefix(line, formattedParam) {
-						// skip other parameters in iscsid.conf
-						continue
-					}
-					var description = iscsiParamDescriptionMap[index].deviceMap[param]
-					var severity = iscsiParamSeverityMap[index].deviceMap[param]
-					recommendation, err = getIscsiParamRecommendation(param, recommendedValue, line, description, severity)
-					if err != nil {
-						log.Error("Unable to get recommendation for iscsi param ", param, "error: ", err.Error())
-					}
-					if recommendation != nil {
-						recommendations = append(recommendations, recommendation)
-					}
-					break
+				line, err := getIscsiParamLine(lines, param)
+				if err != nil {
+					log.Error("Unable to get recommendation for iscsi param ", param, "error: ", err.Error())
+					continue
+				}
+				var description = iscsiParamDescriptionMap[index].deviceMap[param]
+				var severity = iscsiParamSeverityMap[index].deviceMap[param]
+				recommendation, err = getIscsiParamRecommendation(param, recommendedValue, line, description, severity)
+				if err != nil {
+					log.Error("Unable to get recommendation for iscsi param ", param, "error: ", err.Error())
+				}
+				if recommendation != nil {
+					recommendations = append(recommendations, recommendation)
 				}
 			}
 		}
@@ -236,6 +251,23 @@ func GetIscsiRecommendations(deviceParam ...string) (settings []*Recommendation,
 	return recommendations, nil
 }
 
+// getIscsiParamLine returns the iscsid.conf line setting the given parameter.  If the parameter is
+// missing, or commented out, a line with the open-iscsi default value is returned instead.
+func getIscsiParamLine(lines []string, param string) (line string, err error) {
+	formattedParam, err := getIscsiFormattedParam(param)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, formattedParam) {
+			return line, nil
+		}
+	}
+	log.Trace("iscsid.conf param ", formattedParam, " not set, assuming default value ", iscsiParamDefaultMap[param])
+	return formattedParam + " = " + iscsiParamDefaultMap[param], nil
+}
+
 func readIscsiConfigFile() (content []byte, err error) {
 	configLock.Lock()
 	defer configLock.Unlock()
@@ -263,43 +295,85 @@ func writeIscsiConfigFile(content []byte) (err error) {
 // SetIscsiParamRecommendation set parameter value in iscsid.conf
 func SetIscsiParamRecommendation(parameter string, recommendation string) (err error) {
 	log.Trace("SetIscsiParamRecommendation called with ", parameter, " ", recommendation)
-	var paramFound = false
+	_, err = setIscsiParamRecommendations(map[string]string{parameter: recommendation})
+	return err
+}
 
-	// get formatted parameter name for iscsid.conf
-	formattedParam, err := getIscsiFormattedParam(parameter)
-	if err != nil {
-		return err
+// setIscsiParamRecommendations sets the given parameter values in iscsid.conf, adding the parameters
+// that are missing (or commented out) at the end of the file.  A backup of iscsid.conf is taken
+// before it is modified.  Returns true if iscsid.conf was modified.
+func setIscsiParamRecommendations(recommendations map[string]string) (modified bool, err error) {
+	// get formatted parameter names for iscsid.conf
+	formattedParams := make(map[string]string)
+	for parameter := range recommendations {
+		formattedParam, err := getIscsiFormattedParam(parameter)
+		if err != nil {
+			return false, err
+		}
+		formattedParams[parameter] = formattedParam
 	}
 	// check if conf file is present
 	if _, err = os.Stat(linux.IscsiConf); os.IsNotExist(err) {
 		log.Error(linux.IscsiConf, " file missing")
-		return err
+		return false, err
 	}
 	content, err := readIscsiConfigFile()
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	lines := strings.Split(string(content), "\n")
-	for index, line := range lines {
-		if strings.HasPrefix(line, formattedParam) {
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	for parameter, recommendation := range recommendations {
+		formattedParam := formattedParams[parameter]
+		paramLine := formattedParam + " = " + recommendation
+		paramFound := false
+		for index, line := range lines {
+			if !strings.HasPrefix(strings.TrimSpace(line), formattedParam) {
+				continue
+			}
 			// update recommended value inline
-			lines[index] = formattedParam + " = " + recommendation + "\n"
 			paramFound = true
+			if strings.TrimSpace(line) != paramLine {
+				lines[index] = paramLine
+				modified = true
+			}
 			break
 		}
-	}
-	// check if parameter is found and update with recommendation after backup is taken
-	if paramFound == true {
-		output := strings.Join(lines, "\n")
-		err = writeIscsiConfigFile([]byte(output))
-		if err != nil {
-			log.Error("Unable to modify iscsi parameter ", parameter, " error: ", err.Error())
-			return err
+		if !paramFound {
+			lines = append(lines, paramLine)
+			modified = true
 		}
 	}
-	log.Trace("Successfully updated iscsid.conf param ", parameter, " value: ", recommendation)
-	return nil
+	if !modified {
+		return false, nil
+	}
+
+	// update with recommendations after backup is taken
+	backupName, err := backupIscsiConfigFile()
+	if err != nil {
+		log.Error("Unable to backup ", linux.IscsiConf, " error: ", err.Error())
+		return false, err
+	}
+	output := strings.Join(lines, "\n") + "\n"
+	err = writeIscsiConfigFile([]byte(output))
+	if err != nil {
+		log.Error("Unable to modify iscsi parameters ", recommendations, " error: ", err.Error())
+		return false, err
+	}
+	log.Info("Successfully updated iscsid.conf params ", recommendations, ", backup: ", backupName)
+	return true, nil
+}
+
+// backupIscsiConfigFile copies iscsid.conf to a timestamped backup file, named like the
+// multipath.conf backups, and returns the backup file path
+func backupIscsiConfigFile() (backupName string, err error) {
+	configLock.Lock()
+	defer configLock.Unlock()
+	backupName = fmt.Sprintf("%s-%s-%s", linux.IscsiConf, mpathconfig.NimbleBackupSuffix, time.Now().Format("2006-01-02 15:04:05"))
+	if err = util.CopyFile(linux.IscsiConf, backupName); err != nil {
+		return "", err
+	}
+	return backupName, nil
 }
 
 // getIscsiFormattedParam get properly formatted string for given parameter name
@@ -359,6 +433,7 @@ func SetIscsiSessionParam(target string, parameter string, value string) (err er
 func SetIscsiRecommendations(global bool) (err error) {
 	log.Trace("SetIscsiRecommendations called")
 	var remediations []*Recommendation
+	var configRemediations = make(map[string]string)
 	// Get iSCSI recommendations
 	recommendations, err := GetIscsiRecommendations()
 	if err != nil {
@@ -377,17 +452,27 @@ func SetIscsiRecommendations(global bool) (err error) {
 			}
 
 			if global {
-				// Modify iscsid.conf settings accordingly only when global param is not set
-				err = SetIscsiParamRecommendation(recommendation.Parameter, recommendation.Recommendation)
-				if err != nil {
-					// continue with other recommendations
-					continue
-				}
+				// Modify iscsid.conf settings accordingly only when global param is set
+				configRemediations[recommendation.Parameter] = recommendation.Recommendation
 			}
 			// append to remediations list for corrected settings
 			remediations = append(remediations, recommendation)
 		}
 	}
+	if len(configRemediations) > 0 {
+		// update iscsid.conf and restart iscsid for new settings to take effect
+		modified, err := setIscsiParamRecommendations(configRemediations)
+		if err != nil {
+			log.Error("Unable to set iscsid.conf recommendations ", err.Error())
+			return err
+		}
+		if modified {
+			err = linux.ServiceCommand(iscsi, "restart")
+			if err != nil {
+				return err
+			}
+		}
+	}
 	if len(remediations) > 0 {
 		// update remediations for logged-in sessions
 		err = updateIscsiSessionParameters(remediations)
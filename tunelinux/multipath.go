@@ -485,28 +485,11 @@ func removeBlockDevices(blockDevices []string, multipathDevice string) error {
 	return nil
 }
 
+// UnmountMultipathDevice unmounts every mount point of the multipath device without signaling any
+// process (see UnmountMultipathDeviceWithForce to stop the processes holding a busy mount point)
 func UnmountMultipathDevice(multipathDevice string) error {
-	log.Tracef(">>>> UnmountMultipathDevice: %s", multipathDevice)
-	defer log.Trace("<<<<< UnmountMultipathDevice")
-
-	mountPoints, err := findMountPointsOfMultipathDevice("/dev/mapper/" + multipathDevice)
-	if err != nil {
-		return fmt.Errorf("Error occurred while fetching the mount points of the multipath device %s:%s", multipathDevice, err.Error())
-	}
-	if len(mountPoints) == 0 {
-		log.Infof("No mount points found for the multipath device %s", multipathDevice)
-		return nil
-	}
-
-	for _, mountPoint := range mountPoints {
-		err = unmount(mountPoint)
-		if err != nil {
-			log.Errorf("Error occurred while unmounting the mount point %s: %s", mountPoint, err.Error())
-			return err
-		}
-		log.Debugf("Mount point %s unmounted successfully.", mountPoint)
-	}
-	return nil
+	_, err := UnmountMultipathDeviceWithForce(multipathDevice, &UnmountForceOptions{Level: UnmountGraceful})
+	return err
 }
 
 func unmount(mountPoint string) error {
@@ -0,0 +1,336 @@
+package tunelinux
+
+// Copyright 2019 Hewlett Packard Enterprise Development LP.
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/hpe-storage/common-host-libs/linux"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// Udev rule states reported by GetUdevRulesStatus
+const (
+	// UdevRulesApplied the installed rules match the rendered rules
+	UdevRulesApplied = "applied"
+	// UdevRulesPending the rules are not installed, or the installed rules differ from the rendered rules
+	UdevRulesPending = "pending"
+	// UdevRulesModified the installed rules were edited, or not generated by InstallUdevRules, and
+	// are only replaced if forced
+	UdevRulesModified = "modified"
+)
+
+const (
+	// udevChecksumPrefix prefixes the checksum, of the rules that follow, in the rendered rules
+	udevChecksumPrefix = "# checksum: sha256:"
+	// udevDeviceTimeout is the SCSI command timeout parameter of the disk category
+	udevDeviceTimeout = "timeout"
+	// udevScheduler is the I/O scheduler parameter of the disk category
+	udevScheduler = "scheduler"
+
+	// udevRulesTemplate renders the rules tuning the devices of each vendor; sd devices are
+	// matched by SCSI vendor and multipath (dm) devices by the vendor's OUI in their WWID
+	udevRulesTemplate = `##
+# Copyright 2019 Hewlett Packard Enterprise Development LP.
+#
+# Generated by nimbletune, do not edit.  The rules are replaced when installed again.
+##
+ACTION!="add|change", GOTO="nimble_tuning_end"
+SUBSYSTEM!="block", GOTO="nimble_tuning_end"
+ENV{DEVTYPE}=="partition", GOTO="nimble_tuning_end"
+{{range .Vendors}}
+# {{.Name}} devices
+KERNEL=="sd*", ATTRS{vendor}=="{{.Name}}*"{{$.SdAssignments}}
+KERNEL=="dm-*", ENV{DM_UUID}=="mpath-*{{.OUI}}*"{{$.DmAssignments}}
+{{end}}
+LABEL="nimble_tuning_end"
+`
+)
+
+var (
+	// udevVendorOUIs are the OUIs found in the WWIDs of each supported vendor's volumes
+	udevVendorOUIs = map[string]string{
+		"Nimble":   "6c9ce9",
+		"3PARdata": "0002ac",
+	}
+
+	// defaultUdevQueueAttributes are the block queue attributes rendered if the template settings
+	// have no disk recommendations; they match the 99-nimble-tune.rules template
+	defaultUdevQueueAttributes = map[string]string{
+		"max_sectors_kb": "4096",
+		"read_ahead_kb":  "128",
+		"nr_requests":    "512",
+		"scheduler":      "noop",
+		"add_random":     "0",
+		"rotational":     "0",
+		"rq_affinity":    "2",
+	}
+
+	udevAttributeNamePattern  = regexp.MustCompile(`^[a-z0-9_]+$`)
+	udevAttributeValuePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+)
+
+// UdevRuleOptions settings rendered into the udev rules
+type UdevRuleOptions struct {
+	// Vendors array vendors whose devices are tuned (e.g. "Nimble", "3PARdata")
+	Vendors []string `json:"vendors,omitempty"`
+	// DeviceTimeout SCSI command timeout, in seconds, of the sd devices (0 leaves the kernel default)
+	DeviceTimeout int `json:"device_timeout,omitempty"`
+	// Scheduler I/O scheduler of the sd and dm devices
+	Scheduler string `json:"scheduler,omitempty"`
+	// QueueAttributes other block queue attributes of the sd and dm devices (e.g. nr_requests)
+	QueueAttributes map[string]string `json:"queue_attributes,omitempty"`
+}
+
+// UdevRuleStatus state of the installed udev rules
+type UdevRuleStatus struct {
+	// Path path name of the udev rules file
+	Path string `json:"path"`
+	// State one among (applied, pending and modified)
+	State string `json:"state"`
+	// Installed true if the udev rules file exists
+	Installed bool `json:"installed"`
+	// InstalledChecksum checksum recorded in the installed rules, if any
+	InstalledChecksum string `json:"installed_checksum,omitempty"`
+	// RenderedChecksum checksum of the rendered rules
+	RenderedChecksum string `json:"rendered_checksum"`
+}
+
+// udevVendor vendor rendered into the udev rules
+type udevVendor struct {
+	Name string
+	OUI  string
+}
+
+// GetDefaultUdevRuleOptions returns the udev rule settings recommended by the template settings
+func GetDefaultUdevRuleOptions() (options *UdevRuleOptions, err error) {
+	log.Trace(">>>>> GetDefaultUdevRuleOptions")
+	defer log.Trace("<<<<< GetDefaultUdevRuleOptions")
+
+	if err = loadTemplateSettings(); err != nil {
+		return nil, err
+	}
+	blockParamMap, _ := getParamToTemplateFieldMap(Disk, "recommendation", "")
+
+	options = &UdevRuleOptions{QueueAttributes: make(map[string]string)}
+	for _, dev := range blockParamMap {
+		// Device types without a known OUI cannot be matched by the multipath rules
+		if _, ok := udevVendorOUIs[dev.DeviceType]; !ok {
+			log.Tracef("Skipping udev rules for device type %v without OUI", dev.DeviceType)
+			continue
+		}
+		options.Vendors = append(options.Vendors, dev.DeviceType)
+		if dev.DeviceType != defaultDeviceType {
+			continue
+		}
+		for param, recommendation := range dev.deviceMap {
+			options.QueueAttributes[param] = recommendation
+		}
+	}
+	if len(options.QueueAttributes) == 0 {
+		for param, recommendation := range defaultUdevQueueAttributes {
+			options.QueueAttributes[param] = recommendation
+		}
+	}
+
+	// The timeout and scheduler are rendered as their own settings
+	if timeout, ok := options.QueueAttributes[udevDeviceTimeout]; ok {
+		if options.DeviceTimeout, err = strconv.Atoi(timeout); err != nil {
+			return nil, errors.New("error: invalid disk timeout recommendation " + timeout)
+		}
+		delete(options.QueueAttributes, udevDeviceTimeout)
+	}
+	options.Scheduler = options.QueueAttributes[udevScheduler]
+	delete(options.QueueAttributes, udevScheduler)
+	return options, nil
+}
+
+// RenderUdevRules renders the udev rules for the given settings
+func RenderUdevRules(options *UdevRuleOptions) (rules string, err error) {
+	log.Trace(">>>>> RenderUdevRules")
+	defer log.Trace("<<<<< RenderUdevRules")
+
+	body, err := renderUdevRulesBody(options)
+	if err != nil {
+		return "", err
+	}
+	return addUdevChecksum(body), nil
+}
+
+// GetUdevRulesStatus reports whether the installed udev rules match the rules rendered for the given settings
+func GetUdevRulesStatus(options *UdevRuleOptions) (status *UdevRuleStatus, err error) {
+	log.Trace(">>>>> GetUdevRulesStatus")
+	defer log.Trace("<<<<< GetUdevRulesStatus")
+
+	body, err := renderUdevRulesBody(options)
+	if err != nil {
+		return nil, err
+	}
+	status = &UdevRuleStatus{Path: UdevFilePathName, State: UdevRulesPending, RenderedChecksum: getUdevChecksum(body)}
+
+	installed, err := ioutil.ReadFile(UdevFilePathName)
+	if os.IsNotExist(err) {
+		return status, nil
+	} else if err != nil {
+		log.Error("Unable to read ", UdevFilePathName, ", ", err.Error())
+		return nil, err
+	}
+	status.Installed = true
+
+	// Rules without a checksum, or that no longer match their checksum, were edited (or installed
+	// by SetBlockDeviceRecommendations)
+	recordedChecksum, installedBody := splitUdevChecksum(string(installed))
+	status.InstalledChecksum = recordedChecksum
+	switch {
+	case recordedChecksum == "" || recordedChecksum != getUdevChecksum(installedBody):
+		status.State = UdevRulesModified
+	case recordedChecksum == status.RenderedChecksum:
+		status.State = UdevRulesApplied
+	}
+	return status, nil
+}
+
+// InstallUdevRules renders and installs the udev rules for the given settings, unless already
+// applied, then reloads and triggers the udev rules.  Modified rules are only replaced if force is set.
+func InstallUdevRules(options *UdevRuleOptions, force bool) (status *UdevRuleStatus, err error) {
+	log.Trace(">>>>> InstallUdevRules, force=", force)
+	defer log.Trace("<<<<< InstallUdevRules")
+
+	status, err = GetUdevRulesStatus(options)
+	if err != nil {
+		return nil, err
+	}
+	switch status.State {
+	case UdevRulesApplied:
+		log.Info("udev rules ", UdevFilePathName, " already applied")
+		return status, nil
+	case UdevRulesModified:
+		if !force {
+			return status, errors.New("error: " + UdevFilePathName + " was modified, installation must be forced to replace it")
+		}
+		log.Warn("Replacing modified udev rules ", UdevFilePathName)
+	}
+
+	rules, err := RenderUdevRules(options)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeUdevRules(rules); err != nil {
+		return nil, err
+	}
+	if err = linux.UdevadmReloadRules(); err != nil {
+		return nil, err
+	}
+	if err = linux.UdevadmTrigger(); err != nil {
+		return nil, err
+	}
+	log.Info("Successfully installed udev rules ", UdevFilePathName)
+	return GetUdevRulesStatus(options)
+}
+
+// renderUdevRulesBody renders the udev rules, without their checksum, for the given settings
+func renderUdevRulesBody(options *UdevRuleOptions) (string, error) {
+	if options == nil || len(options.Vendors) == 0 {
+		return "", errors.New("error: no vendor to render udev rules for")
+	}
+
+	var vendors []udevVendor
+	for _, name := range options.Vendors {
+		oui, ok := udevVendorOUIs[name]
+		if !ok {
+			return "", errors.New("error: unsupported udev rule vendor " + name)
+		}
+		vendors = append(vendors, udevVendor{Name: name, OUI: oui})
+	}
+
+	// Queue attributes are rendered in name order so that the checksum is stable
+	queueAttributes := make(map[string]string)
+	for name, value := range options.QueueAttributes {
+		queueAttributes[name] = value
+	}
+	if options.Scheduler != "" {
+		queueAttributes[udevScheduler] = options.Scheduler
+	}
+	var names []string
+	for name := range queueAttributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dmAssignments string
+	for _, name := range names {
+		value := queueAttributes[name]
+		if !udevAttributeNamePattern.MatchString(name) || !udevAttributeValuePattern.MatchString(value) {
+			return "", fmt.Errorf("error: invalid udev queue attribute %q=%q", name, value)
+		}
+		dmAssignments += ", " + fmt.Sprintf(udevAttrFormat, name, value)
+	}
+	sdAssignments := dmAssignments
+	if options.DeviceTimeout < 0 {
+		return "", fmt.Errorf("error: invalid udev device timeout %v", options.DeviceTimeout)
+	} else if options.DeviceTimeout > 0 {
+		sdAssignments = fmt.Sprintf(`, ATTR{device/timeout}="%v"`, options.DeviceTimeout) + sdAssignments
+	}
+
+	rulesTemplate := template.Must(template.New("udev").Parse(udevRulesTemplate))
+	var rules bytes.Buffer
+	err := rulesTemplate.Execute(&rules, struct {
+		Vendors       []udevVendor
+		SdAssignments string
+		DmAssignments string
+	}{vendors, sdAssignments, dmAssignments})
+	if err != nil {
+		return "", err
+	}
+	return rules.String(), nil
+}
+
+// getUdevChecksum returns the checksum of the given udev rules
+func getUdevChecksum(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// addUdevChecksum prefixes the given udev rules with their checksum
+func addUdevChecksum(body string) string {
+	return udevChecksumPrefix + getUdevChecksum(body) + "\n" + body
+}
+
+// splitUdevChecksum returns the checksum recorded in the given udev rules, if any, and the rules
+// that follow it
+func splitUdevChecksum(rules string) (checksum string, body string) {
+	if !strings.HasPrefix(rules, udevChecksumPrefix) {
+		return "", rules
+	}
+	index := strings.Index(rules, "\n")
+	if index < 0 {
+		return "", rules
+	}
+	return strings.TrimPrefix(rules[:index], udevChecksumPrefix), rules[index+1:]
+}
+
+// writeUdevRules atomically replaces the udev rules file with the given rules
+func writeUdevRules(rules string) error {
+	tempFile := filepath.Join(filepath.Dir(UdevFilePathName), "."+filepath.Base(UdevFilePathName)+".tmp")
+	if err := ioutil.WriteFile(tempFile, []byte(rules), 0644); err != nil {
+		log.Error("Unable to write ", tempFile, ", ", err.Error())
+		return errors.New("error: unable to create " + UdevFilePathName + ", reason: " + err.Error())
+	}
+	if err := os.Rename(tempFile, UdevFilePathName); err != nil {
+		os.Remove(tempFile)
+		log.Error("Unable to replace ", UdevFilePathName, ", ", err.Error())
+		return errors.New("error: unable to create " + UdevFilePathName + ", reason: " + err.Error())
+	}
+	return nil
+}
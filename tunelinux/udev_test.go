@@ -0,0 +1,135 @@
+package tunelinux
+
+// Copyright 2019 Hewlett Packard Enterprise Development LP.
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderUdevRulesBody(t *testing.T) {
+	options := &UdevRuleOptions{
+		Vendors:         []string{"Nimble", "3PARdata"},
+		DeviceTimeout:   60,
+		Scheduler:       "noop",
+		QueueAttributes: map[string]string{"nr_requests": "512", "add_random": "0"},
+	}
+	body, err := renderUdevRulesBody(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`KERNEL=="sd*", ATTRS{vendor}=="Nimble*", ATTR{device/timeout}="60", ATTR{queue/add_random}="0", ATTR{queue/nr_requests}="512", ATTR{queue/scheduler}="noop"`,
+		`KERNEL=="dm-*", ENV{DM_UUID}=="mpath-*6c9ce9*", ATTR{queue/add_random}="0", ATTR{queue/nr_requests}="512", ATTR{queue/scheduler}="noop"`,
+		`KERNEL=="dm-*", ENV{DM_UUID}=="mpath-*0002ac*"`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("rendered rules missing %q:\n%v", line, body)
+		}
+	}
+
+	// The rendered rules must be stable for their checksum
+	if again, _ := renderUdevRulesBody(options); again != body {
+		t.Error("rendered rules not stable")
+	}
+
+	invalidOptions := []*UdevRuleOptions{
+		nil,
+		{},
+		{Vendors: []string{"Unknown"}},
+		{Vendors: []string{"Nimble"}, DeviceTimeout: -1},
+		{Vendors: []string{"Nimble"}, Scheduler: `noop", RUN+="/bin/sh`},
+		{Vendors: []string{"Nimble"}, QueueAttributes: map[string]string{"nr_requests": "512\"\nRUN+=\"/bin/sh"}},
+		{Vendors: []string{"Nimble"}, QueueAttributes: map[string]string{"../device/timeout": "1"}},
+	}
+	for _, invalid := range invalidOptions {
+		if _, err = renderUdevRulesBody(invalid); err == nil {
+			t.Errorf("expected error rendering udev rules for %+v", invalid)
+		}
+	}
+}
+
+func TestSplitUdevChecksum(t *testing.T) {
+	body := "KERNEL==\"sd*\"\n"
+	checksum, rest := splitUdevChecksum(addUdevChecksum(body))
+	if checksum != getUdevChecksum(body) || rest != body {
+		t.Errorf("unexpected checksum %q and body %q", checksum, rest)
+	}
+	for _, rules := range []string{body, udevChecksumPrefix + "abc"} {
+		if checksum, rest = splitUdevChecksum(rules); checksum != "" || rest != rules {
+			t.Errorf("unexpected checksum %q and body %q for %q", checksum, rest, rules)
+		}
+	}
+}
+
+func TestGetUdevRulesStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "udev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { UdevFilePathName = path }(UdevFilePathName)
+	UdevFilePathName = filepath.Join(dir, "99-nimble-tune.rules")
+
+	options := &UdevRuleOptions{Vendors: []string{"Nimble"}, Scheduler: "noop"}
+	status, err := GetUdevRulesStatus(options)
+	if err != nil || status.State != UdevRulesPending || status.Installed {
+		t.Fatalf("unexpected status %+v before installation, err=%v", status, err)
+	}
+
+	rules, err := RenderUdevRules(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writeUdevRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	if status, err = GetUdevRulesStatus(options); err != nil || status.State != UdevRulesApplied || status.InstalledChecksum != status.RenderedChecksum {
+		t.Errorf("unexpected status %+v after installation, err=%v", status, err)
+	}
+
+	// Other settings leave the installed rules pending
+	if status, err = GetUdevRulesStatus(&UdevRuleOptions{Vendors: []string{"Nimble"}, Scheduler: "deadline"}); err != nil || status.State != UdevRulesPending {
+		t.Errorf("unexpected status %+v for other settings, err=%v", status, err)
+	}
+
+	// Edited rules, and rules without a checksum, are modified
+	for _, edited := range []string{rules + "# edited\n", "KERNEL==\"sd*\"\n"} {
+		if err = ioutil.WriteFile(UdevFilePathName, []byte(edited), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if status, err = GetUdevRulesStatus(options); err != nil || status.State != UdevRulesModified {
+			t.Errorf("unexpected status %+v for edited rules, err=%v", status, err)
+		}
+	}
+}
+
+func TestGetDefaultUdevRuleOptions(t *testing.T) {
+	defer func(template []DeviceTemplate) { deviceTemplate = template }(deviceTemplate)
+	deviceTemplate = []DeviceTemplate{
+		{DeviceType: "Nimble", TemplateArray: []TemplateSetting{
+			{Category: "disk", Parameter: "nr_requests", Recommendation: "512"},
+			{Category: "disk", Parameter: "scheduler", Recommendation: "noop"},
+			{Category: "disk", Parameter: "timeout", Recommendation: "60"},
+		}},
+		{DeviceType: "3PARdata"},
+		{DeviceType: "Unknown"},
+	}
+
+	options, err := GetDefaultUdevRuleOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(options.Vendors, ",") != "Nimble,3PARdata" {
+		t.Errorf("unexpected vendors %v", options.Vendors)
+	}
+	if options.DeviceTimeout != 60 || options.Scheduler != "noop" || len(options.QueueAttributes) != 1 || options.QueueAttributes["nr_requests"] != "512" {
+		t.Errorf("unexpected options %+v", options)
+	}
+	if _, err = renderUdevRulesBody(options); err != nil {
+		t.Errorf("unable to render default options, err=%v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package tunelinux
+
+// Copyright 2019 Hewlett Packard Enterprise Development LP.
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// UnmountForceLevel is how far UnmountMultipathDeviceWithForce goes to unmount a busy mount point.
+// Each level includes the stages of the previous levels.
+type UnmountForceLevel int
+
+const (
+	// UnmountGraceful only unmounts; busy mount points fail with the processes holding them
+	UnmountGraceful UnmountForceLevel = iota + 1
+	// UnmountTerminate sends SIGTERM to the processes holding a busy mount point and waits for them
+	// to exit before unmounting again
+	UnmountTerminate
+	// UnmountKill sends SIGKILL to the processes still holding a busy mount point after SIGTERM
+	UnmountKill
+)
+
+const (
+	// Unmount cascade stages
+	UnmountStageGraceful  = "graceful"
+	UnmountStageTerminate = "terminate"
+	UnmountStageKill      = "kill"
+
+	// DefaultUnmountTermTimeout is the default wait for the processes to exit after SIGTERM
+	DefaultUnmountTermTimeout = 10 * time.Second
+
+	unmountKillTimeout  = 2 * time.Second        // Wait for the processes to exit after SIGKILL
+	unmountPollInterval = 250 * time.Millisecond // Poll interval while waiting for processes to exit
+)
+
+var (
+	// procPath is the process table; a variable so that tests can replace it
+	procPath = "/proc"
+)
+
+// UnmountForceOptions are the options of UnmountMultipathDeviceWithForce
+type UnmountForceOptions struct {
+	Level       UnmountForceLevel // Required - how far to go to unmount a busy mount point
+	TermTimeout time.Duration     // Wait for the processes to exit after SIGTERM (default DefaultUnmountTermTimeout)
+	Confirm     bool              // Required with UnmountTerminate and UnmountKill - the caller accepts that processes are signaled
+}
+
+// UnmountProcess is a process holding a mount point
+type UnmountProcess struct {
+	PID     int    `json:"pid"`               // Process ID
+	Command string `json:"command,omitempty"` // Process name
+	Path    string `json:"path,omitempty"`    // File (or directory) held open by the process
+}
+
+// UnmountStage reports a stage of the unmount cascade of a mount point
+type UnmountStage struct {
+	MountPoint string            `json:"mount_point"`         // Mount point
+	Stage      string            `json:"stage"`               // UnmountStageGraceful, UnmountStageTerminate or UnmountStageKill
+	Processes  []*UnmountProcess `json:"processes,omitempty"` // Processes holding the mount point (graceful) or signaled (terminate/kill)
+	Unmounted  bool              `json:"unmounted"`           // True if the mount point was unmounted by this stage
+}
+
+// UnmountResult reports the stages run by UnmountMultipathDeviceWithForce
+type UnmountResult struct {
+	Device string          `json:"device"`           // Multipath device
+	Stages []*UnmountStage `json:"stages,omitempty"` // Stages run, in order, for each mount point
+}
+
+// UnmountMultipathDeviceWithForce unmounts every mount point of the multipath device, going up to
+// the given force level for busy mount points:  graceful (umount only), then SIGTERM to the
+// processes holding the mount point with a timeout, then SIGKILL.  The force level must be given
+// and signaling processes must be confirmed.  The processes impacted at each stage are returned,
+// even on failure.  The host's init process and the calling process are never signaled.
+func UnmountMultipathDeviceWithForce(multipathDevice string, options *UnmountForceOptions) (*UnmountResult, error) {
+	log.Tracef(">>>> UnmountMultipathDeviceWithForce: %s, options=%+v", multipathDevice, options)
+	defer log.Trace("<<<<< UnmountMultipathDeviceWithForce")
+
+	if options == nil || options.Level < UnmountGraceful || options.Level > UnmountKill {
+		return nil, fmt.Errorf("An unmount force level must be specified to unmount the multipath device %s", multipathDevice)
+	}
+	if options.Level > UnmountGraceful && !options.Confirm {
+		return nil, fmt.Errorf("Unmounting the multipath device %s with force level %d signals processes and must be confirmed", multipathDevice, options.Level)
+	}
+	termTimeout := options.TermTimeout
+	if termTimeout <= 0 {
+		termTimeout = DefaultUnmountTermTimeout
+	}
+
+	result := &UnmountResult{Device: multipathDevice}
+	mountPoints, err := findMountPointsOfMultipathDevice("/dev/mapper/" + multipathDevice)
+	if err != nil {
+		return result, fmt.Errorf("Error occurred while fetching the mount points of the multipath device %s:%s", multipathDevice, err.Error())
+	}
+	if len(mountPoints) == 0 {
+		log.Infof("No mount points found for the multipath device %s", multipathDevice)
+		return result, nil
+	}
+
+	for _, mountPoint := range mountPoints {
+		// Graceful
+		stage := &UnmountStage{MountPoint: mountPoint, Stage: UnmountStageGraceful}
+		result.Stages = append(result.Stages, stage)
+		if err = unmount(mountPoint); err == nil {
+			stage.Unmounted = true
+			log.Debugf("Mount point %s unmounted successfully.", mountPoint)
+			continue
+		}
+		stage.Processes = getUnmountProcesses(mountPoint)
+		if options.Level == UnmountGraceful {
+			return result, fmt.Errorf("Mount point %s is held by %d processes: %s", mountPoint, len(stage.Processes), err.Error())
+		}
+
+		// SIGTERM, then SIGKILL
+		stages := []struct {
+			name    string
+			signal  syscall.Signal
+			timeout time.Duration
+		}{
+			{UnmountStageTerminate, syscall.SIGTERM, termTimeout},
+			{UnmountStageKill, syscall.SIGKILL, unmountKillTimeout},
+		}
+		for i, signalStage := range stages {
+			if options.Level < UnmountTerminate+UnmountForceLevel(i) {
+				break
+			}
+			stage = &UnmountStage{MountPoint: mountPoint, Stage: signalStage.name}
+			result.Stages = append(result.Stages, stage)
+			stage.Processes = signalUnmountProcesses(mountPoint, signalStage.signal)
+			waitUnmountProcesses(mountPoint, signalStage.timeout)
+			if err = unmount(mountPoint); err == nil {
+				stage.Unmounted = true
+				log.Infof("Mount point %s unmounted after the %s stage, %d processes signaled", mountPoint, signalStage.name, len(stage.Processes))
+				break
+			}
+		}
+		if err != nil {
+			log.Errorf("Error occurred while unmounting the mount point %s: %s", mountPoint, err.Error())
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// signalUnmountProcesses sends the signal to every process holding the mount point, returning the
+// processes signaled
+func signalUnmountProcesses(mountPoint string, signal syscall.Signal) []*UnmountProcess {
+	var signaled []*UnmountProcess
+	for _, process := range getUnmountProcesses(mountPoint) {
+		if process.PID <= 1 || process.PID == os.Getpid() {
+			log.Warnf("Not signaling process %d (%s) holding the mount point %s", process.PID, process.Command, mountPoint)
+			continue
+		}
+		osProcess, err := os.FindProcess(process.PID)
+		if err == nil {
+			err = osProcess.Signal(signal)
+		}
+		if err != nil {
+			log.Errorf("Error occurred while sending %v to process %d (%s): %s", signal, process.PID, process.Command, err.Error())
+			continue
+		}
+		log.Infof("Sent %v to process %d (%s) holding the mount point %s", signal, process.PID, process.Command, mountPoint)
+		signaled = append(signaled, process)
+	}
+	return signaled
+}
+
+// waitUnmountProcesses waits, up to the timeout, for the processes holding the mount point to exit
+func waitUnmountProcesses(mountPoint string, timeout time.Duration) {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(unmountPollInterval) {
+		if len(getUnmountProcesses(mountPoint)) == 0 {
+			return
+		}
+	}
+}
+
+// getUnmountProcesses walks the process table and returns every process with an open file,
+// current working directory or root directory on the mount point
+func getUnmountProcesses(mountPoint string) []*UnmountProcess {
+	var processes []*UnmountProcess
+	entries, err := ioutil.ReadDir(procPath)
+	if err != nil {
+		log.Errorf("Error occurred while enumerating the processes: %s", err.Error())
+		return processes
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		processDir := filepath.Join(procPath, entry.Name())
+		if path := getProcessPathOnMount(processDir, mountPoint); path != "" {
+			comm, _ := ioutil.ReadFile(filepath.Join(processDir, "comm"))
+			processes = append(processes, &UnmountProcess{PID: pid, Command: strings.TrimSpace(string(comm)), Path: path})
+		}
+	}
+	return processes
+}
+
+// getProcessPathOnMount returns the first path, held by the process, that resides on the mount
+// point (or an empty string if the process is not using the mount point)
+func getProcessPathOnMount(processDir string, mountPoint string) string {
+	links := []string{filepath.Join(processDir, "cwd"), filepath.Join(processDir, "root")}
+	if fds, err := ioutil.ReadDir(filepath.Join(processDir, "fd")); err == nil {
+		for _, fd := range fds {
+			links = append(links, filepath.Join(processDir, "fd", fd.Name()))
+		}
+	}
+	mountPoint = strings.TrimSuffix(mountPoint, "/")
+	for _, link := range links {
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		if target == mountPoint || strings.HasPrefix(target, mountPoint+"/") {
+			return target
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package tunelinux
+
+// Copyright 2019 Hewlett Packard Enterprise Development LP.
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// writeFakeProcess adds a process, holding the given paths open, to the fake process table
+func writeFakeProcess(t *testing.T, testProcPath string, pid int, comm string, cwd string, fds ...string) {
+	processDir := filepath.Join(testProcPath, strconv.Itoa(pid))
+	if err := os.MkdirAll(filepath.Join(processDir, "fd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(processDir, "comm"), []byte(comm+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{filepath.Join(processDir, "cwd"): cwd, filepath.Join(processDir, "root"): "/"}
+	for i, fd := range fds {
+		links[filepath.Join(processDir, "fd", strconv.Itoa(i))] = fd
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetUnmountProcesses(t *testing.T) {
+	testProcPath, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testProcPath)
+
+	savedProcPath := procPath
+	defer func() { procPath = savedProcPath }()
+	procPath = testProcPath
+
+	// /mnt/a must not match the processes using /mnt/ab
+	writeFakeProcess(t, testProcPath, 100, "editor", "/home/user", "/dev/null", "/mnt/a/file")
+	writeFakeProcess(t, testProcPath, 101, "shell", "/mnt/a")
+	writeFakeProcess(t, testProcPath, 102, "other", "/mnt/ab", "/mnt/ab/file")
+	if err = ioutil.WriteFile(filepath.Join(testProcPath, "uptime"), []byte("1.00 1.00\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if path := getProcessPathOnMount(filepath.Join(testProcPath, "102"), "/mnt/a"); path != "" {
+		t.Errorf("/mnt/a matched %v", path)
+	}
+	if path := getProcessPathOnMount(filepath.Join(testProcPath, "102"), "/mnt/ab/"); path != "/mnt/ab" {
+		t.Errorf("unexpected path %v", path)
+	}
+
+	processes := getUnmountProcesses("/mnt/a")
+	if len(processes) != 2 {
+		t.Fatalf("expected 2 processes, got %v", len(processes))
+	}
+	expected := map[int]UnmountProcess{
+		100: {PID: 100, Command: "editor", Path: "/mnt/a/file"},
+		101: {PID: 101, Command: "shell", Path: "/mnt/a"},
+	}
+	for _, process := range processes {
+		if *process != expected[process.PID] {
+			t.Errorf("unexpected process %+v", process)
+		}
+	}
+}
+
+func TestUnmountForceOptions(t *testing.T) {
+	invalidOptions := []*UnmountForceOptions{
+		nil,
+		{},
+		{Level: UnmountKill + 1, Confirm: true},
+		{Level: UnmountTerminate},
+		{Level: UnmountKill},
+	}
+	for _, options := range invalidOptions {
+		if _, err := UnmountMultipathDeviceWithForce("mpatha", options); err == nil {
+			t.Errorf("options %+v not rejected", options)
+		}
+	}
+}
+
+func TestSignalUnmountProcesses(t *testing.T) {
+	testProcPath, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testProcPath)
+
+	savedProcPath := procPath
+	defer func() { procPath = savedProcPath }()
+	procPath = testProcPath
+
+	// Only the child process may be signaled; init and the calling process are left alone
+	child := exec.Command("sleep", "60")
+	if err = child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer child.Process.Kill()
+	writeFakeProcess(t, testProcPath, 1, "init", "/", "/mnt/a/file")
+	writeFakeProcess(t, testProcPath, os.Getpid(), "tunelinux.test", "/mnt/a")
+	writeFakeProcess(t, testProcPath, child.Process.Pid, "sleep", "/mnt/a")
+
+	signaled := signalUnmountProcesses("/mnt/a", syscall.SIGTERM)
+	if len(signaled) != 1 || signaled[0].PID != child.Process.Pid {
+		t.Fatalf("unexpected processes signaled %+v", signaled)
+	}
+	err = child.Wait()
+	if status, ok := child.ProcessState.Sys().(syscall.WaitStatus); !ok || !status.Signaled() || status.Signal() != syscall.SIGTERM {
+		t.Errorf("child not terminated by SIGTERM, err=%v", err)
+	}
+}
@@ -19,6 +19,7 @@ package util
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
 	"regexp"
 	"strings"
@@ -30,12 +31,17 @@ import (
 
 const (
 	defaultTimeout = 60
+
+	// commandLocale is the locale commands are executed with so that their output (messages,
+	// column headings, number and date formats) is the same on every host
+	commandLocale = "C"
 )
 
 func execCommandOutputWithTimeout(cmd string, args []string, stdinArgs []string, timeout int) (string, int, error) {
 	log.Trace("execCommandOutputWithTimeout called with ", cmd, log.Scrubber(args), timeout)
 	var err error
 	c := exec.Command(cmd, args...)
+	c.Env = commandEnvironment(os.Environ())
 	var b bytes.Buffer
 	c.Stdout = &b
 	c.Stderr = &b
@@ -81,6 +87,20 @@ func execCommandOutputWithTimeout(cmd string, args []string, stdinArgs []string,
 	return out, 0, nil
 }
 
+// commandEnvironment returns the given environment with the locale variables replaced by the C
+// locale.  Output parsers otherwise break on hosts with a non-English locale.
+func commandEnvironment(environ []string) []string {
+	env := make([]string, 0, len(environ)+2)
+	for _, variable := range environ {
+		name := strings.SplitN(variable, "=", 2)[0]
+		if name == "LANG" || name == "LANGUAGE" || strings.HasPrefix(name, "LC_") {
+			continue
+		}
+		env = append(env, variable)
+	}
+	return append(env, "LANG="+commandLocale, "LC_ALL="+commandLocale)
+}
+
 // ExecCommandOutputWithTimeout  executes ExecCommandOutput with the specified timeout
 func ExecCommandOutputWithTimeout(cmd string, args []string, timeout int) (string, int, error) {
 	return execCommandOutputWithTimeout(cmd, args, []string{}, timeout)
@@ -0,0 +1,174 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package util
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// mountTableFields is the number of fields in a mount table line
+	mountTableFields = 6
+)
+
+var (
+	// mountCommandLine matches a mount command output line, e.g.
+	// "/dev/mapper/mpatha on /mnt/vol1 type xfs (rw,relatime,attr2,inode64,noquota)"
+	mountCommandLine = regexp.MustCompile(`^(\S+) on (.+) type (\S+)(?: \((.*)\))?$`)
+
+	// keyValuePair matches a KEY=value or KEY="quoted value" pair (e.g. blkid output)
+	keyValuePair = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)=(?:"((?:[^"\\]|\\.)*)"|(\S*))`)
+
+	// columnName matches a column name in the header line of a command's tabular output
+	columnName = regexp.MustCompile(`\S+`)
+)
+
+// MountEntry is a mounted file system parsed by ParseMountOutput
+type MountEntry struct {
+	Source     string   // Mounted device (or pseudo file system name)
+	MountPoint string   // Mount point path
+	FsType     string   // File system type
+	Options    []string // Mount options
+}
+
+// ParseMountOutput parses the output of the mount command ("<source> on <mount point> type <fs>
+// (<options>)") or the contents of a mount table such as /proc/mounts ("<source> <mount point>
+// <fs> <options> <dump> <pass>").  The mount table's octal escapes (e.g. "\040") are decoded.
+// Lines in neither format are skipped.
+func ParseMountOutput(output string) []*MountEntry {
+	var entries []*MountEntry
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if match := mountCommandLine.FindStringSubmatch(line); match != nil {
+			entries = append(entries, &MountEntry{
+				Source:     match[1],
+				MountPoint: match[2],
+				FsType:     match[3],
+				Options:    splitMountOptions(match[4]),
+			})
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != mountTableFields {
+			continue
+		}
+		entries = append(entries, &MountEntry{
+			Source:     UnescapeOctal(fields[0]),
+			MountPoint: UnescapeOctal(fields[1]),
+			FsType:     fields[2],
+			Options:    splitMountOptions(fields[3]),
+		})
+	}
+	return entries
+}
+
+// splitMountOptions splits a comma separated mount option list
+func splitMountOptions(options string) []string {
+	if options == "" {
+		return nil
+	}
+	return strings.Split(options, ",")
+}
+
+// ParseKeyValuePairs parses KEY=value and KEY="quoted value" pairs, in any order, such as the
+// output of blkid or of lsblk -P.  If a key is repeated the last value is returned.
+func ParseKeyValuePairs(output string) map[string]string {
+	pairs := make(map[string]string)
+	for _, match := range keyValuePair.FindAllStringSubmatch(output, -1) {
+		value := match[3]
+		if strings.HasPrefix(match[0], match[1]+`="`) {
+			value = strings.Replace(match[2], `\"`, `"`, -1)
+		}
+		pairs[match[1]] = value
+	}
+	return pairs
+}
+
+// ParseColumnOutput parses tabular command output whose first non-empty line is a header naming
+// the columns (e.g. "multipathd show paths format ..."), returning one map per row keyed by column
+// name so that callers do not depend on the column order.  A row is split on white space when it
+// has one field per column, else on the header's column offsets so that values containing spaces
+// (e.g. a vend/prod/rev of "HP,OPEN-V 2") are kept whole.  The brackets older multipathd versions
+// print around states (e.g. "[failed]") are removed.
+func ParseColumnOutput(output string) []map[string]string {
+	var columns []string
+	var offsets []int
+	var rows []map[string]string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if columns == nil {
+			for _, match := range columnName.FindAllStringIndex(line, -1) {
+				columns = append(columns, line[match[0]:match[1]])
+				offsets = append(offsets, match[0])
+			}
+			continue
+		}
+		values := strings.Fields(line)
+		if len(values) != len(columns) {
+			values = splitColumns(line, offsets)
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[column] = strings.Trim(values[i], "[]")
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// splitColumns splits a row of tabular output at the given column offsets
+func splitColumns(line string, offsets []int) []string {
+	values := make([]string, len(offsets))
+	for i, start := range offsets {
+		end := len(line)
+		if i+1 < len(offsets) && offsets[i+1] < end {
+			end = offsets[i+1]
+		}
+		if start < end {
+			values[i] = strings.TrimSpace(line[start:end])
+		}
+	}
+	return values
+}
+
+// ParseLsofCommands returns the command names of the processes reported by "lsof -F c".  The
+// field output has one field per line, prefixed by its field identifier (e.g. "p1234" and
+// "cmkfs.xfs"), and unlike the default output does not depend on column widths.
+func ParseLsofCommands(output string) []string {
+	var commands []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "c") && len(line) > 1 {
+			commands = append(commands, line[1:])
+		}
+	}
+	return commands
+}
+
+// UnescapeOctal decodes the octal escapes (e.g. "\040" for a space) used by the kernel's mount
+// tables for paths containing white space
+func UnescapeOctal(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+	var unescaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if value, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				unescaped.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		unescaped.WriteByte(path[i])
+	}
+	return unescaped.String()
+}
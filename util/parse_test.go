@@ -0,0 +1,183 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+package util
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	// mount command output (util-linux on RHEL/CentOS and Ubuntu, busybox)
+	mountOutputRHEL = `sysfs on /sys type sysfs (rw,nosuid,nodev,noexec,relatime,seclabel)
+/dev/mapper/mpatha on /mnt/vol1 type xfs (rw,relatime,seclabel,attr2,inode64,noquota)
+/dev/mapper/mpathb1 on /var/lib/kubelet/pods/my vol type ext4 (rw,relatime,data=ordered)
+`
+	mountOutputUbuntu = `/dev/mapper/mpatha on /mnt/vol1 type xfs (rw,relatime,attr2,inode64,logbufs=8,logbsize=32k,noquota)
+tmpfs on /run/user/1000 type tmpfs (rw,nosuid,nodev,relatime,size=400000k,mode=700,uid=1000,gid=1000)
+`
+	mountOutputBusybox = `/dev/mapper/mpatha on /mnt/vol1 type xfs (rw,relatime)
+`
+
+	// mount table contents (/proc/mounts, SLES /etc/mtab)
+	procMountsOutput = `sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+/dev/mapper/mpatha /mnt/vol1 xfs rw,relatime,attr2,inode64,noquota 0 0
+/dev/mapper/mpathb1 /var/lib/kubelet/pods/my\040vol ext4 rw,relatime,data=ordered 0 0
+`
+
+	// blkid output, the key order differs between versions
+	blkidOutputRHEL7   = `/dev/mapper/mpatha: UUID="63a91d01-b388-45fd-8ae3-ebe3b687200d" TYPE="xfs"`
+	blkidOutputRHEL8   = `/dev/mapper/mpatha: UUID="63a91d01-b388-45fd-8ae3-ebe3b687200d" BLOCK_SIZE="512" TYPE="xfs"`
+	blkidOutputUbuntu  = `/dev/mapper/mpatha: LABEL="my data" UUID="3e6be9de-8139-11d1-9106-a43f08d823a6" TYPE="ext4" PTTYPE="dos"`
+	blkidOutputPartUID = `/dev/mapper/mpatha1: TYPE="ext4" PARTUUID="5e6a4c8d-01"`
+)
+
+func TestParseMountOutput(t *testing.T) {
+	vol1 := &MountEntry{Source: "/dev/mapper/mpatha", MountPoint: "/mnt/vol1", FsType: "xfs"}
+	tests := []struct {
+		name    string
+		output  string
+		count   int
+		options int
+	}{
+		{"rhel", mountOutputRHEL, 3, 6},
+		{"ubuntu", mountOutputUbuntu, 2, 7},
+		{"busybox", mountOutputBusybox, 1, 2},
+		{"proc", procMountsOutput, 3, 5},
+	}
+	for _, test := range tests {
+		entries := ParseMountOutput(test.output)
+		if len(entries) != test.count {
+			t.Errorf("%v: expected %v entries, got %v", test.name, test.count, len(entries))
+			continue
+		}
+		var found *MountEntry
+		for _, entry := range entries {
+			if entry.MountPoint == vol1.MountPoint {
+				found = entry
+			}
+		}
+		if found == nil || found.Source != vol1.Source || found.FsType != vol1.FsType || len(found.Options) != test.options {
+			t.Errorf("%v: unexpected entry %+v", test.name, found)
+		}
+	}
+
+	// Mount points with white space are reported verbatim by mount, escaped in the mount table
+	for _, output := range []string{mountOutputRHEL, procMountsOutput} {
+		entries := ParseMountOutput(output)
+		if entries[2].MountPoint != "/var/lib/kubelet/pods/my vol" || entries[2].FsType != "ext4" {
+			t.Errorf("unexpected entry %+v", entries[2])
+		}
+	}
+	if entries := ParseMountOutput("\nnot a mount line\n"); len(entries) != 0 {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestParseKeyValuePairs(t *testing.T) {
+	for _, output := range []string{blkidOutputRHEL7, blkidOutputRHEL8, blkidOutputUbuntu, blkidOutputPartUID} {
+		pairs := ParseKeyValuePairs(output + "\n")
+		if fsType := pairs["TYPE"]; fsType != "xfs" && fsType != "ext4" {
+			t.Errorf("unexpected TYPE %q from %v", fsType, output)
+		}
+	}
+	pairs := ParseKeyValuePairs(blkidOutputUbuntu)
+	expected := map[string]string{
+		"LABEL":  "my data",
+		"UUID":   "3e6be9de-8139-11d1-9106-a43f08d823a6",
+		"TYPE":   "ext4",
+		"PTTYPE": "dos",
+	}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("unexpected pairs %v", pairs)
+	}
+	if pairs := ParseKeyValuePairs(`NAME=mpatha1 TYPE=part LABEL="a \"b\""`); pairs["NAME"] != "mpatha1" || pairs["LABEL"] != `a "b"` {
+		t.Errorf("unexpected pairs %v", pairs)
+	}
+}
+
+func TestUnescapeOctal(t *testing.T) {
+	if path := UnescapeOctal(`/mnt/my\040vol\011tab`); path != "/mnt/my vol\ttab" {
+		t.Errorf("unexpected unescaped path %q", path)
+	}
+	if path := UnescapeOctal(`/mnt/vol1\`); path != `/mnt/vol1\` {
+		t.Errorf("unexpected unescaped path %q", path)
+	}
+}
+
+func TestCommandEnvironment(t *testing.T) {
+	env := commandEnvironment([]string{"PATH=/usr/bin", "LANG=de_DE.UTF-8", "LC_MESSAGES=fr_FR.UTF-8", "LANGUAGE=de", "LC_ALL="})
+	expected := []string{"PATH=/usr/bin", "LANG=C", "LC_ALL=C"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("unexpected environment %v", env)
+	}
+
+	// Commands are executed with the C locale
+	if _, err := exec.LookPath("sh"); err != nil {
+		return
+	}
+	out, _, err := ExecCommandOutput("sh", []string{"-c", "echo $LANG $LC_ALL"})
+	if err != nil || strings.TrimSpace(out) != "C C" {
+		t.Errorf("unexpected locale %q, err=%v", out, err)
+	}
+}
+
+var (
+	// multipathd show paths format "%w %d %t %i %o %T %z %s %m" (multipath-tools 0.7+), including
+	// a product name with a space and an orphan path without a uuid
+	multipathdPathsOutput = `uuid                              dev dm_st  hcil    dev_st  chk_st serial                           vend/prod/rev                multipath
+2f4c97c5c1cd391756c9ce900584f2795 sdb active 3:0:0:1 running ready  f4c97c5c1cd391756c9ce900584f2795 Nimble,Server,1.0            mpatha
+36000d31000fdd6000000000000000041 sdc active 4:0:0:3 running ready  6000d31000fdd6000000000000000041 COMPELNT,Compellent Vol,0606 mpathb
+                                  sdd undef  5:0:0:2 running ready  e1c1f7c1cd391756c9ce900584f2795  Nimble,Server,1.0            [orphan]
+`
+
+	// multipathd show paths format "%d %t %i" (multipath-tools 0.4.9, RHEL 6), states in brackets
+	multipathdPathsOutputRHEL6 = `dev dm_st    hcil
+sdb [active] 3:0:0:1
+sdc [failed] 4:0:0:1
+`
+
+	// lsof -F c output for a device opened by two processes
+	lsofFieldOutput = `p2301
+cmkfs.xfs
+f3
+p2302
+cudevd
+`
+)
+
+func TestParseColumnOutput(t *testing.T) {
+	rows := ParseColumnOutput(multipathdPathsOutput)
+	if len(rows) != 3 {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+	expected := []map[string]string{
+		{"uuid": "2f4c97c5c1cd391756c9ce900584f2795", "dev": "sdb", "dm_st": "active", "hcil": "3:0:0:1", "vend/prod/rev": "Nimble,Server,1.0", "multipath": "mpatha"},
+		{"uuid": "36000d31000fdd6000000000000000041", "dev": "sdc", "chk_st": "ready", "vend/prod/rev": "COMPELNT,Compellent Vol,0606", "multipath": "mpathb"},
+		{"uuid": "", "dev": "sdd", "dm_st": "undef", "hcil": "5:0:0:2", "vend/prod/rev": "Nimble,Server,1.0", "multipath": "orphan"},
+	}
+	for i, columns := range expected {
+		for column, value := range columns {
+			if rows[i][column] != value {
+				t.Errorf("row %v %v = %q, expected %q", i, column, rows[i][column], value)
+			}
+		}
+	}
+
+	rows = ParseColumnOutput(multipathdPathsOutputRHEL6)
+	if len(rows) != 2 || rows[0]["dm_st"] != "active" || rows[1]["dev"] != "sdc" || rows[1]["dm_st"] != "failed" || rows[1]["hcil"] != "4:0:0:1" {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	if rows = ParseColumnOutput("\n"); rows != nil {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
+
+func TestParseLsofCommands(t *testing.T) {
+	if commands := ParseLsofCommands(lsofFieldOutput); !reflect.DeepEqual(commands, []string{"mkfs.xfs", "udevd"}) {
+		t.Errorf("unexpected commands %v", commands)
+	}
+}
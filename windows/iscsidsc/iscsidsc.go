@@ -226,13 +226,16 @@ var (
 	procLoginIScsiTargetW                = iscsidsc.NewProc("LoginIScsiTargetW")
 	procLogoutIScsiTarget                = iscsidsc.NewProc("LogoutIScsiTarget")
 	procRemoveIScsiPersistentTargetW     = iscsidsc.NewProc("RemoveIScsiPersistentTargetW")
+	procRemoveIScsiSendTargetPortalW     = iscsidsc.NewProc("RemoveIScsiSendTargetPortalW")
 	procReportActiveIScsiTargetMappingsW = iscsidsc.NewProc("ReportActiveIScsiTargetMappingsW")
+	procReportIScsiInitiatorListW        = iscsidsc.NewProc("ReportIScsiInitiatorListW")
 	procReportIScsiPersistentLoginsW     = iscsidsc.NewProc("ReportIScsiPersistentLoginsW")
 	procReportIScsiSendTargetPortalsExW  = iscsidsc.NewProc("ReportIScsiSendTargetPortalsExW")
 	procReportIScsiSendTargetPortalsW    = iscsidsc.NewProc("ReportIScsiSendTargetPortalsW")
 	procReportIScsiTargetPortalsW        = iscsidsc.NewProc("ReportIScsiTargetPortalsW")
 	procReportIScsiTargetsW              = iscsidsc.NewProc("ReportIScsiTargetsW")
 	procSendScsiInquiry                  = iscsidsc.NewProc("SendScsiInquiry")
+	procSetIScsiInitiatorNodeNameW       = iscsidsc.NewProc("SetIScsiInitiatorNodeNameW")
 )
 
 // ISCSI_CONNECTION_INFO (Wrapped version)
@@ -28,19 +28,28 @@ func LoginIScsiTargetEx(targetName string, initiatorInstance string, initiatorPo
 	// If the connection was successful, and "isPersistent" was set to true, now try to make it a
 	// persistent connection.
 	if (err == nil) && isPersistent {
-
-		// NWT-3305: One second delay before setting persistent connection to work around Windows API issue
-		time.Sleep(1000 * time.Millisecond)
-
-		// Now make the persistent connection.  We don't do anything with the error should it occur.  The
-		// routine we're calling will log the error in the unlikely event it does occur.  The more critical
-		// task, that of logging into the target, was successful.
-		loginIScsiTarget(targetName, initiatorInstance, initiatorPortNumber, targetPortal, headerDigest, dataDigest, chapUsername, chapPassword, true)
+		SetIScsiPersistentLogin(targetName, initiatorInstance, initiatorPortNumber, targetPortal, headerDigest, dataDigest, chapUsername, chapPassword)
 	}
 
 	return uniqueSessionID, uniqueConnectionID, err
 }
 
+// SetIScsiPersistentLogin makes the persistent connection for a target already logged in with
+// LoginIScsiTargetEx (with "isPersistent" set to false), e.g. once the caller has checked the
+// connection that was made.
+func SetIScsiPersistentLogin(targetName string, initiatorInstance string, initiatorPortNumber uint32, targetPortal *ISCSI_TARGET_PORTAL, headerDigest ISCSI_DIGEST_TYPES, dataDigest ISCSI_DIGEST_TYPES, chapUsername string, chapPassword string) {
+	log.Trace(">>>>> SetIScsiPersistentLogin")
+	defer log.Trace("<<<<< SetIScsiPersistentLogin")
+
+	// NWT-3305: One second delay before setting persistent connection to work around Windows API issue
+	time.Sleep(1000 * time.Millisecond)
+
+	// Now make the persistent connection.  We don't do anything with the error should it occur.  The
+	// routine we're calling will log the error in the unlikely event it does occur.  The more critical
+	// task, that of logging into the target, was successful.
+	loginIScsiTarget(targetName, initiatorInstance, initiatorPortNumber, targetPortal, headerDigest, dataDigest, chapUsername, chapPassword, true)
+}
+
 // loginIScsiTarget wraps the iSCSI discovery LoginIScsiTarget() API.  It's only for internal
 // package use as we recommend the public LoginIScsiTarget() function be used instead.
 func loginIScsiTarget(targetName string, initiatorInstance string, initiatorPortNumber uint32, targetPortal *ISCSI_TARGET_PORTAL, headerDigest ISCSI_DIGEST_TYPES, dataDigest ISCSI_DIGEST_TYPES, chapUsername string, chapPassword string, isPersistent bool) (uniqueSessionID *ISCSI_UNIQUE_SESSION_ID, uniqueConnectionID *ISCSI_UNIQUE_CONNECTION_ID, err error) {
@@ -0,0 +1,57 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+// Package iscsidsc wraps the Windows iSCSI Discovery Library API
+package iscsidsc
+
+import (
+	"syscall"
+	"unsafe"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// ReportIScsiInitiatorList - Go wrapped Win32 API - ReportIScsiInitiatorListW()
+// https://docs.microsoft.com/en-us/windows/desktop/api/iscsidsc/nf-iscsidsc-reportiscsiinitiatorlistw
+// Returns the initiator instance name of the Microsoft software initiator and of each iSCSI HBA.
+func ReportIScsiInitiatorList() (initiatorInstances []string, err error) {
+	log.Trace(">>>>> ReportIScsiInitiatorList")
+	defer log.Trace("<<<<< ReportIScsiInitiatorList")
+
+	// Do an initial call to the function to find the buffer size (in characters)
+	var bufferSizeNeeded uint32
+	iscsiErr, _, _ := procReportIScsiInitiatorListW.Call(uintptr(unsafe.Pointer(&bufferSizeNeeded)), uintptr(0))
+
+	if (iscsiErr == ERROR_INSUFFICIENT_BUFFER) && (bufferSizeNeeded > 0) {
+		// Allocate a buffer large enough to hold the list of initiator instances and retrieve them
+		buffer := make([]uint16, bufferSizeNeeded)
+		iscsiErr, _, _ = procReportIScsiInitiatorListW.Call(uintptr(unsafe.Pointer(&bufferSizeNeeded)), uintptr(unsafe.Pointer(&buffer[0])))
+		if iscsiErr == ERROR_SUCCESS {
+			// Scan through the list of null terminated strings, ending with a double null
+			startIndex := 0
+			for index, element := range buffer {
+				if element != 0 {
+					continue
+				} else if startIndex == index {
+					break
+				}
+				initiatorInstances = append(initiatorInstances, syscall.UTF16ToString(buffer[startIndex:index]))
+				startIndex = index + 1
+			}
+		}
+	}
+
+	if iscsiErr != ERROR_SUCCESS {
+		// If an unexpected error occurs, initialize error object and log failure
+		err = syscall.Errno(iscsiErr)
+		log.Error(logIscsiFailure, err.Error())
+	} else {
+		// Log the enumerated initiator instances
+		for index, initiatorInstance := range initiatorInstances {
+			log.Tracef("initiatorInstances[%v]=%v", index, initiatorInstance)
+		}
+	}
+
+	return initiatorInstances, err
+}
@@ -10,6 +10,7 @@ import (
 	"unsafe"
 
 	log "github.com/hpe-storage/common-host-libs/logger"
+	"github.com/hpe-storage/common-host-libs/sgio"
 )
 
 // SendScsiInquiry - Go wrapped Win32 API - SendScsiInquiry()
@@ -42,6 +43,9 @@ func SendScsiInquiry(sessionID ISCSI_UNIQUE_SESSION_ID, lun uint64, evpdCmddt ui
 		// Only return the data that the iSCSI initiator claims was returned by the target
 		senseBuffer = senseBuffer[:senseBufferSize]
 		logTraceHexDump(senseBuffer, "Sense Data")
+		if sense, senseErr := sgio.DecodeSense(senseBuffer); senseErr == nil {
+			log.Tracef("Sense - %v", sense)
+		}
 	} else {
 		// Empty sense buffer if no check condition
 		senseBuffer = nil
@@ -0,0 +1,32 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+// Package iscsidsc wraps the Windows iSCSI Discovery Library API
+package iscsidsc
+
+import (
+	"syscall"
+	"unsafe"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// SetIScsiInitiatorNodeName - Go wrapped Win32 API - SetIScsiInitiatorNodeNameW()
+// https://docs.microsoft.com/en-us/windows/desktop/api/iscsidsc/nf-iscsidsc-setiscsiinitiatornodenamew
+func SetIScsiInitiatorNodeName(initiatorNodeName string) (err error) {
+	log.Tracef(">>>>> SetIScsiInitiatorNodeName, initiatorNodeName=%v", initiatorNodeName)
+	defer log.Trace("<<<<< SetIScsiInitiatorNodeName")
+
+	// Get UTF16 version of the initiator name
+	initiatorNodeNameUTF16 := syscall.StringToUTF16(initiatorNodeName)
+
+	// Call the Win32 API
+	if iscsiErr, _, _ := procSetIScsiInitiatorNodeNameW.Call(uintptr(unsafe.Pointer(&initiatorNodeNameUTF16[0]))); iscsiErr != ERROR_SUCCESS {
+		// If an unexpected error occurs, initialize error object and log failure
+		err = syscall.Errno(iscsiErr)
+		log.Error(logIscsiFailure, err.Error())
+	}
+
+	return err
+}
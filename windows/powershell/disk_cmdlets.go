@@ -23,6 +23,10 @@ const (
 
 	// TimeoutPartitionAndFormatVolume specifies the partition and format cmdlet timeout (in seconds)
 	TimeoutPartitionAndFormatVolume = 5 * 60
+
+	// TimeoutPartitionAndFullFormatVolume specifies the partition and full format cmdlet timeout
+	// (in seconds).  A full format zeroes the entire volume.
+	TimeoutPartitionAndFullFormatVolume = 24 * 60 * 60
 )
 
 // AddPartitionAccessPath wraps the Add-PartitionAccessPath cmdlet
@@ -62,8 +66,28 @@ func InitializeDisk(path string, partitionStyle string) (string, int, error) {
 // create and format a volume with the specified file system.  If no file system is passed in, we
 // default to NTFS.
 func PartitionAndFormatVolume(diskPath string, fileSystem string) (string, int, error) {
-	log.Tracef(">>>>> PartitionAndFormatVolume, diskPath=%v, fileSystem=%v", diskPath, fileSystem)
-	defer log.Trace("<<<<< PartitionAndFormatVolume")
+	return PartitionAndFormatVolumeWithOptions(diskPath, fileSystem, false)
+}
+
+// PartitionAndFormatVolumeWithOptions is PartitionAndFormatVolume with the option to perform a
+// full format, which zeroes the entire volume, instead of a quick format.  A full format of a
+// large thin provisioned volume can take hours.
+func PartitionAndFormatVolumeWithOptions(diskPath string, fileSystem string, fullFormat bool) (string, int, error) {
+	return PartitionAndFormatVolumeWithFormatOptions(diskPath, fileSystem, FormatOptions{FullFormat: fullFormat})
+}
+
+// FormatOptions are the Format-Volume options of PartitionAndFormatVolumeWithFormatOptions
+type FormatOptions struct {
+	FullFormat       bool // Full format (-Full), zeroing the entire volume, instead of a quick format
+	IntegrityStreams bool // ReFS only - enable integrity streams (-SetIntegrityStreams $True)
+	DevDrive         bool // ReFS only - format the volume as a Dev Drive (-DevDrive)
+}
+
+// PartitionAndFormatVolumeWithFormatOptions is PartitionAndFormatVolume with the given
+// Format-Volume options.  The caller must validate that the file system supports the options.
+func PartitionAndFormatVolumeWithFormatOptions(diskPath string, fileSystem string, options FormatOptions) (string, int, error) {
+	log.Tracef(">>>>> PartitionAndFormatVolumeWithFormatOptions, diskPath=%v, fileSystem=%v, options=%+v", diskPath, fileSystem, options)
+	defer log.Trace("<<<<< PartitionAndFormatVolumeWithFormatOptions")
 
 	// Default to NTFS if file system not provided
 	if len(fileSystem) == 0 {
@@ -71,6 +95,15 @@ func PartitionAndFormatVolume(diskPath string, fileSystem string) (string, int,
 	}
 
 	arg := fmt.Sprintf(`New-Partition -DiskPath "%v" -UseMaximumSize:$True | Format-Volume -FileSystem %v`, diskPath, fileSystem)
+	if options.IntegrityStreams {
+		arg += " -SetIntegrityStreams $True"
+	}
+	if options.DevDrive {
+		arg += " -DevDrive"
+	}
+	if options.FullFormat {
+		return execCommandOutputWithTimeout(arg+" -Full", TimeoutPartitionAndFullFormatVolume)
+	}
 	return execCommandOutputWithTimeout(arg, TimeoutPartitionAndFormatVolume)
 }
 
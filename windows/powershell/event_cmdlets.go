@@ -0,0 +1,26 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+package powershell
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// GetWinEventMessages wraps the Get-WinEvent cmdlet with the -FilterHashtable option and returns
+// the messages of the events the given providers logged to the given log since the given time,
+// one message per line
+func GetWinEventMessages(logName string, providerNames []string, since time.Time) (string, int, error) {
+	log.Tracef(">>>>> GetWinEventMessages, logName=%v, providerNames=%v, since=%v", logName, providerNames, since)
+	defer log.Trace("<<<<< GetWinEventMessages")
+
+	// Get-WinEvent fails if no event matches the filter, hence SilentlyContinue
+	arg := fmt.Sprintf(`Get-WinEvent -FilterHashtable @{LogName='%v'; ProviderName='%v'; StartTime=[DateTime]'%v'} -ErrorAction SilentlyContinue | Sort-Object TimeCreated | ForEach-Object { $_.Message -replace '\s*\r?\n\s*', ' ' }`,
+		logName, strings.Join(providerNames, "','"), since.UTC().Format(time.RFC3339))
+	return execCommandOutput(arg)
+}
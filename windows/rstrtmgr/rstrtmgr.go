@@ -0,0 +1,137 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// Package rstrtmgr wraps the Restart Manager (rstrtmgr.dll) APIs used to find the processes that
+// are using a set of files.  Windows has no other documented API to enumerate the files held open
+// by other processes.
+
+//go:build windows
+// +build windows
+
+package rstrtmgr
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// Restart Manager string lengths, excluding the terminating null character
+	cchRmSessionKey = 32  // CCH_RM_SESSION_KEY
+	cchRmMaxAppName = 255 // CCH_RM_MAX_APP_NAME
+	cchRmMaxSvcName = 63  // CCH_RM_MAX_SVC_NAME
+
+	// errorMoreData (ERROR_MORE_DATA) is returned by RmGetList if the process array is too small
+	errorMoreData = 234
+)
+
+// Lazy load our rstrtmgr.dll APIs
+var (
+	rstrtmgr                = windows.NewLazySystemDLL("rstrtmgr.dll")
+	procRmStartSession      = rstrtmgr.NewProc("RmStartSession")
+	procRmEndSession        = rstrtmgr.NewProc("RmEndSession")
+	procRmRegisterResources = rstrtmgr.NewProc("RmRegisterResources")
+	procRmGetList           = rstrtmgr.NewProc("RmGetList")
+)
+
+// rmUniqueProcess is the RM_UNIQUE_PROCESS structure
+type rmUniqueProcess struct {
+	ProcessID        uint32
+	ProcessStartTime windows.Filetime
+}
+
+// rmProcessInfo is the RM_PROCESS_INFO structure
+type rmProcessInfo struct {
+	Process          rmUniqueProcess
+	AppName          [cchRmMaxAppName + 1]uint16
+	ServiceShortName [cchRmMaxSvcName + 1]uint16
+	ApplicationType  uint32
+	AppStatus        uint32
+	TSSessionID      uint32
+	Restartable      int32
+}
+
+// ProcessInfo identifies a process using one of the registered files
+type ProcessInfo struct {
+	PID         uint32 // Process ID
+	AppName     string // Application (or service display) name
+	ServiceName string // Service short name, if the process is a service
+}
+
+// GetProcessesUsingFiles returns the processes that are using any of the given files
+func GetProcessesUsingFiles(paths []string) ([]ProcessInfo, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	// Start a Restart Manager session
+	var session uint32
+	sessionKey := make([]uint16, cchRmSessionKey+1)
+	ret, _, _ := procRmStartSession.Call(
+		uintptr(unsafe.Pointer(&session)),
+		0,
+		uintptr(unsafe.Pointer(&sessionKey[0])),
+	)
+	if ret != 0 {
+		return nil, syscall.Errno(ret)
+	}
+	defer procRmEndSession.Call(uintptr(session))
+
+	// Register the files
+	fileNames := make([]*uint16, 0, len(paths))
+	for _, path := range paths {
+		fileName, err := windows.UTF16PtrFromString(path)
+		if err != nil {
+			return nil, err
+		}
+		fileNames = append(fileNames, fileName)
+	}
+	ret, _, _ = procRmRegisterResources.Call(
+		uintptr(session),
+		uintptr(len(fileNames)),
+		uintptr(unsafe.Pointer(&fileNames[0])),
+		0, 0, 0, 0,
+	)
+	if ret != 0 {
+		return nil, syscall.Errno(ret)
+	}
+
+	// Get the processes using the files, growing the process array until it's large enough
+	var infos []rmProcessInfo
+	for {
+		var needed, count uint32
+		var rebootReasons uint32
+		var infosPtr uintptr
+		count = uint32(len(infos))
+		if count != 0 {
+			infosPtr = uintptr(unsafe.Pointer(&infos[0]))
+		}
+		ret, _, _ = procRmGetList.Call(
+			uintptr(session),
+			uintptr(unsafe.Pointer(&needed)),
+			uintptr(unsafe.Pointer(&count)),
+			infosPtr,
+			uintptr(unsafe.Pointer(&rebootReasons)),
+		)
+		if ret == errorMoreData {
+			infos = make([]rmProcessInfo, needed)
+			continue
+		}
+		if ret != 0 {
+			return nil, syscall.Errno(ret)
+		}
+		infos = infos[:count]
+		break
+	}
+
+	processes := make([]ProcessInfo, 0, len(infos))
+	for _, info := range infos {
+		processes = append(processes, ProcessInfo{
+			PID:         info.Process.ProcessID,
+			AppName:     windows.UTF16ToString(info.AppName[:]),
+			ServiceName: windows.UTF16ToString(info.ServiceShortName[:]),
+		})
+	}
+	return processes, nil
+}
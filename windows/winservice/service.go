@@ -21,9 +21,23 @@ import (
 
 var elog debug.Log
 
+const (
+	// pbtAPMSuspend is the power event (PBT_APMSUSPEND) sent before the system suspends
+	pbtAPMSuspend = 0x0004
+
+	// preShutdownWaitHint is the time, in milliseconds, the pre-shutdown handler is expected to take
+	preShutdownWaitHint = 60000
+)
+
 // Execute is the thread executing the service and receiving control events
 func (winService *WinService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
-	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue
+	cmdsAccepted := svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue
+	if winService.PreShutdown != nil {
+		cmdsAccepted |= svc.AcceptPreShutdown
+	}
+	if winService.Suspend != nil {
+		cmdsAccepted |= svc.AcceptPowerEvent
+	}
 	changes <- svc.Status{State: svc.StartPending}
 	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
 
@@ -49,6 +63,19 @@ loop:
 				log.Infof("Stop/shutdown signal received, testOutput=%v, c.Cmd=%v", testOutput, c.Cmd)
 				winService.Stop()
 				break loop
+			case svc.PreShutdown:
+				// The system is about to shut down; the service still runs, and its storage is
+				// still reachable, until the pre-shutdown handler returns
+				log.Info("Pre-shutdown signal received")
+				changes <- svc.Status{State: svc.StopPending, WaitHint: preShutdownWaitHint}
+				winService.PreShutdown()
+				winService.Stop()
+				break loop
+			case svc.PowerEvent:
+				if c.EventType == pbtAPMSuspend {
+					log.Info("Suspend signal received")
+					winService.Suspend()
+				}
 			default:
 				msg := fmt.Sprintf("unexpected control request #%d", c)
 				if winService.UseEventLog {
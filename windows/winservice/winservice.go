@@ -35,6 +35,9 @@
 //			// This routine will be called when the winservice framework stops the service
 // 		}
 //
+// The optional PreShutdown and Suspend routines are called before the system shuts down or
+// suspends, e.g. to quiesce storage (see chapi2.QuiesceForShutdown and chapi2.FlushForSuspend).
+//
 //-------------------------------------------------------------------------------------------------
 //
 // When comparing the windows/svc/example sample code, with this package, you will see a number of
@@ -76,4 +79,6 @@ type WinService struct {
 	UseEventLog bool   // Does the Windows service want events recorded to the application event log?
 	Start       func() // Pointer to function that framework will call to start the service
 	Stop        func() // Pointer to function that framework will call to stop the service
+	PreShutdown func() // Optional function called before the system shuts down (SERVICE_CONTROL_PRESHUTDOWN), before Stop
+	Suspend     func() // Optional function called before the system suspends (PBT_APMSUSPEND)
 }
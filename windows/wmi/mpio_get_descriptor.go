@@ -0,0 +1,191 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+// Package wmi handles WMI queries
+package wmi
+
+import (
+	"fmt"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// MPIO_GET_DESCRIPTOR WMI class; the paths of an MPIO disk
+type MPIO_GET_DESCRIPTOR struct {
+	InstanceName   string
+	DeviceName     string
+	NumberPaths    uint32
+	PdoInformation []*MPIO_PDOINFORMATION
+}
+
+// MPIO_PDOINFORMATION WMI class; a path of an MPIO disk (embedded in MPIO_GET_DESCRIPTOR)
+type MPIO_PDOINFORMATION struct {
+	PortNumber     uint8 // ScsiAddress.PortNumber
+	ScsiPathId     uint8 // ScsiAddress.ScsiPathId
+	TargetId       uint8 // ScsiAddress.TargetId
+	Lun            uint8 // ScsiAddress.Lun
+	DeviceName     string
+	PathIdentifier uint64
+}
+
+// DSM_QueryLBPolicy_V2 WMI class; the load balance policy, and path states, of a Microsoft DSM disk
+type DSM_QueryLBPolicy_V2 struct {
+	InstanceName string
+	DSM_Paths    []*DSM_Path_V2 // LoadBalancePolicy.DSM_Paths
+}
+
+// DSM_Path_V2 WMI class; a path of a Microsoft DSM disk (embedded in DSM_QueryLBPolicy_V2)
+type DSM_Path_V2 struct {
+	DsmPathId                  uint64
+	PrimaryPath                bool
+	OptimizedPath              bool
+	PreferredPath              bool
+	FailedPath                 bool
+	TargetPortGroup_State      uint32
+	ALUASupport                uint32
+	TargetPortGroup_Preferred  bool
+	TargetPortGroup_Identifier uint16
+}
+
+// GetMPIO_GET_DESCRIPTOR enumerates this host's MPIO_GET_DESCRIPTOR objects
+func GetMPIO_GET_DESCRIPTOR() (descriptors []*MPIO_GET_DESCRIPTOR, err error) {
+	log.Trace(">>>>> GetMPIO_GET_DESCRIPTOR")
+	defer log.Trace("<<<<< GetMPIO_GET_DESCRIPTOR")
+
+	// PdoInformation is an array of embedded objects, which ExecQuery can't return
+	objects, err := ExecQueryProperties("SELECT * FROM MPIO_GET_DESCRIPTOR", rootWMI)
+	if err != nil {
+		return nil, err
+	}
+	for _, properties := range objects {
+		descriptor := &MPIO_GET_DESCRIPTOR{
+			InstanceName: propertyString(properties, "InstanceName"),
+			DeviceName:   propertyString(properties, "DeviceName"),
+		}
+		if descriptor.NumberPaths, err = propertyUint32(properties, "NumberPaths"); err != nil {
+			return nil, err
+		}
+		pdos, _ := properties["PdoInformation"].([]interface{})
+		for _, pdo := range pdos {
+			pdoProperties, ok := pdo.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unexpected PdoInformation %v (%T)", pdo, pdo)
+			}
+			pdoInformation, err := toMPIO_PDOINFORMATION(pdoProperties)
+			if err != nil {
+				return nil, err
+			}
+			descriptor.PdoInformation = append(descriptor.PdoInformation, pdoInformation)
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors, nil
+}
+
+// GetDSM_QueryLBPolicy_V2 enumerates this host's DSM_QueryLBPolicy_V2 objects
+func GetDSM_QueryLBPolicy_V2() (policies []*DSM_QueryLBPolicy_V2, err error) {
+	log.Trace(">>>>> GetDSM_QueryLBPolicy_V2")
+	defer log.Trace("<<<<< GetDSM_QueryLBPolicy_V2")
+
+	// LoadBalancePolicy is an embedded object, which ExecQuery can't return
+	objects, err := ExecQueryProperties("SELECT * FROM DSM_QueryLBPolicy_V2", rootWMI)
+	if err != nil {
+		return nil, err
+	}
+	for _, properties := range objects {
+		policy := &DSM_QueryLBPolicy_V2{InstanceName: propertyString(properties, "InstanceName")}
+		loadBalancePolicy, _ := properties["LoadBalancePolicy"].(map[string]interface{})
+		paths, _ := loadBalancePolicy["DSM_Paths"].([]interface{})
+		for _, path := range paths {
+			pathProperties, ok := path.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unexpected DSM_Paths %v (%T)", path, path)
+			}
+			dsmPath, err := toDSM_Path_V2(pathProperties)
+			if err != nil {
+				return nil, err
+			}
+			policy.DSM_Paths = append(policy.DSM_Paths, dsmPath)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// toMPIO_PDOINFORMATION converts the properties of an embedded MPIO_PDOINFORMATION object into an
+// MPIO_PDOINFORMATION
+func toMPIO_PDOINFORMATION(properties map[string]interface{}) (pdoInformation *MPIO_PDOINFORMATION, err error) {
+	pdoInformation = &MPIO_PDOINFORMATION{DeviceName: propertyString(properties, "DeviceName")}
+	if properties["PathIdentifier"] != nil {
+		if pdoInformation.PathIdentifier, err = variantToUint64(properties["PathIdentifier"]); err != nil {
+			return nil, err
+		}
+	}
+	scsiAddress, _ := properties["ScsiAddress"].(map[string]interface{})
+	for name, value := range map[string]*uint8{
+		"PortNumber": &pdoInformation.PortNumber,
+		"ScsiPathId": &pdoInformation.ScsiPathId,
+		"TargetId":   &pdoInformation.TargetId,
+		"Lun":        &pdoInformation.Lun,
+	} {
+		u, err := propertyUint32(scsiAddress, name)
+		if err != nil {
+			return nil, err
+		}
+		*value = uint8(u)
+	}
+	return pdoInformation, nil
+}
+
+// toDSM_Path_V2 converts the properties of an embedded DSM_Path_V2 object into a DSM_Path_V2
+func toDSM_Path_V2(properties map[string]interface{}) (dsmPath *DSM_Path_V2, err error) {
+	dsmPath = new(DSM_Path_V2)
+	if properties["DsmPathId"] != nil {
+		if dsmPath.DsmPathId, err = variantToUint64(properties["DsmPathId"]); err != nil {
+			return nil, err
+		}
+	}
+	for name, value := range map[string]*bool{
+		"PrimaryPath":   &dsmPath.PrimaryPath,
+		"OptimizedPath": &dsmPath.OptimizedPath,
+		"PreferredPath": &dsmPath.PreferredPath,
+		"FailedPath":    &dsmPath.FailedPath,
+	} {
+		*value, _ = properties[name].(bool)
+	}
+	for name, value := range map[string]*uint32{
+		"TargetPortGroup_State": &dsmPath.TargetPortGroup_State,
+		"ALUASupport":           &dsmPath.ALUASupport,
+	} {
+		if *value, err = propertyUint32(properties, name); err != nil {
+			return nil, err
+		}
+	}
+	preferred, err := propertyUint32(properties, "TargetPortGroup_Preferred")
+	if err != nil {
+		return nil, err
+	}
+	dsmPath.TargetPortGroup_Preferred = preferred != 0
+	identifier, err := propertyUint32(properties, "TargetPortGroup_Identifier")
+	if err != nil {
+		return nil, err
+	}
+	dsmPath.TargetPortGroup_Identifier = uint16(identifier)
+	return dsmPath, nil
+}
+
+// propertyString returns the named string property, or "" if not set
+func propertyString(properties map[string]interface{}, name string) string {
+	value, _ := properties[name].(string)
+	return value
+}
+
+// propertyUint32 returns the named unsigned integer property, or 0 if not set
+func propertyUint32(properties map[string]interface{}, name string) (uint32, error) {
+	if properties[name] == nil {
+		return 0, nil
+	}
+	value, err := variantToUint64(properties[name])
+	return uint32(value), err
+}
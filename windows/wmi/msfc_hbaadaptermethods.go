@@ -0,0 +1,127 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+// Package wmi handles WMI queries
+package wmi
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// HBA API status values, as returned in the HBAStatus property and output parameter
+const (
+	HBA_STATUS_OK                  = 0
+	HBA_STATUS_ERROR_ILLEGAL_INDEX = 6
+)
+
+// HBA API port types and states (MSFC_HBAPortAttributesResults PortType and PortState)
+const (
+	HBA_PORTTYPE_NPORT  = 5
+	HBA_PORTTYPE_NLPORT = 6
+	HBA_PORTTYPE_FPORT  = 8
+
+	HBA_PORTSTATE_UNKNOWN     = 1
+	HBA_PORTSTATE_ONLINE      = 2
+	HBA_PORTSTATE_OFFLINE     = 3
+	HBA_PORTSTATE_BYPASSED    = 4
+	HBA_PORTSTATE_DIAGNOSTICS = 5
+	HBA_PORTSTATE_LINKDOWN    = 6
+	HBA_PORTSTATE_ERROR       = 7
+	HBA_PORTSTATE_LOOPBACK    = 8
+)
+
+// MSFC_FCAdapterHBAAttributes WMI class
+type MSFC_FCAdapterHBAAttributes struct {
+	InstanceName     string
+	Active           bool
+	UniqueAdapterId  uint64
+	HBAStatus        uint32
+	NodeWWN          [8]uint8
+	NumberOfPorts    uint32
+	Manufacturer     string
+	Model            string
+	DriverName       string
+	DriverVersion    string
+	FirmwareVersion  string
+	ModelDescription string
+}
+
+// GetMSFC_FCAdapterHBAAttributes enumerates this host's MSFC_FCAdapterHBAAttributes objects
+func GetMSFC_FCAdapterHBAAttributes() (fcAdapters []*MSFC_FCAdapterHBAAttributes, err error) {
+	log.Trace(">>>>> GetMSFC_FCAdapterHBAAttributes")
+	defer log.Trace("<<<<< GetMSFC_FCAdapterHBAAttributes")
+
+	// Execute the WMI query
+	err = ExecQuery("SELECT * FROM MSFC_FCAdapterHBAAttributes", rootWMI, &fcAdapters)
+	return fcAdapters, err
+}
+
+// GetDiscoveredPortAttributes calls the GetDiscoveredPortAttributes method of the given adapter's
+// MSFC_HBAAdapterMethods object.  The attributes of the remote port, discovered by the adapter port,
+// are returned along with the HBA API status (e.g. HBA_STATUS_ERROR_ILLEGAL_INDEX once all the
+// discovered ports have been enumerated).
+func GetDiscoveredPortAttributes(instanceName string, portIndex uint32, discoveredPortIndex uint32) (attributes *MSFC_HBAPortAttributesResults, hbaStatus uint32, err error) {
+	log.Tracef(">>>>> GetDiscoveredPortAttributes, instanceName=%v, portIndex=%v, discoveredPortIndex=%v", instanceName, portIndex, discoveredPortIndex)
+	defer log.Trace("<<<<< GetDiscoveredPortAttributes")
+
+	wmiQuery := fmt.Sprintf(`SELECT * FROM MSFC_HBAAdapterMethods WHERE InstanceName="%v"`, strings.Replace(instanceName, `\`, `\\`, -1))
+	inParams := map[string]interface{}{"PortIndex": portIndex, "DiscoveredPortIndex": discoveredPortIndex}
+	outParams, err := ExecWmiInstanceMethod(wmiQuery, "GetDiscoveredPortAttributes", rootWMI, inParams, "HBAStatus", "PortAttributes")
+	if err != nil {
+		return nil, 0, err
+	}
+	status, err := variantToUint64(outParams["HBAStatus"])
+	if err != nil {
+		return nil, 0, err
+	}
+	if status != HBA_STATUS_OK {
+		return nil, uint32(status), nil
+	}
+	properties, ok := outParams["PortAttributes"].(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("unexpected PortAttributes %v (%T)", outParams["PortAttributes"], outParams["PortAttributes"])
+	}
+	attributes, err = toHBAPortAttributesResults(properties)
+	return attributes, HBA_STATUS_OK, err
+}
+
+// toHBAPortAttributesResults converts the properties of an embedded MSFC_HBAPortAttributesResults
+// object into an MSFC_HBAPortAttributesResults
+func toHBAPortAttributesResults(properties map[string]interface{}) (attributes *MSFC_HBAPortAttributesResults, err error) {
+	attributes = new(MSFC_HBAPortAttributesResults)
+	for name, value := range map[string]*[8]uint8{
+		"NodeWWN":    &attributes.NodeWWN,
+		"PortWWN":    &attributes.PortWWN,
+		"FabricName": &attributes.FabricName,
+	} {
+		values, _ := properties[name].([]interface{})
+		for i := 0; i < len(values) && i < len(value); i++ {
+			b, err := variantToUint64(values[i])
+			if err != nil {
+				return nil, err
+			}
+			value[i] = uint8(b)
+		}
+	}
+	for name, value := range map[string]*uint32{
+		"PortFcId":                &attributes.PortFcId,
+		"PortType":                &attributes.PortType,
+		"PortState":               &attributes.PortState,
+		"PortSpeed":               &attributes.PortSpeed,
+		"NumberofDiscoveredPorts": &attributes.NumberofDiscoveredPorts,
+	} {
+		if properties[name] == nil {
+			continue
+		}
+		u, err := variantToUint64(properties[name])
+		if err != nil {
+			return nil, err
+		}
+		*value = uint32(u)
+	}
+	return attributes, nil
+}
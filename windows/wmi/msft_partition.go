@@ -7,6 +7,8 @@ package wmi
 
 import (
 	"fmt"
+	"reflect"
+	"strconv"
 
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
@@ -66,3 +68,77 @@ func GetMSFTPartitionForDiskNumber(diskNumber uint32) (diskPartitions []*MSFT_Pa
 	whereOperator := fmt.Sprintf("DiskNumber=%v", diskNumber)
 	return GetMSFTPartition(whereOperator)
 }
+
+// MSFTPartitionSuccess is the MSFT_Partition method return value on success
+const MSFTPartitionSuccess = 0
+
+// GetMSFTPartitionSupportedSize calls the GetSupportedSize method of the given MSFT_Partition and
+// returns the minimum and maximum sizes, in bytes, that the partition can be resized to
+func GetMSFTPartitionSupportedSize(diskNumber uint32, partitionNumber uint32) (sizeMin uint64, sizeMax uint64, err error) {
+	log.Tracef(">>>>> GetMSFTPartitionSupportedSize, diskNumber=%v, partitionNumber=%v", diskNumber, partitionNumber)
+	defer log.Trace("<<<<< GetMSFTPartitionSupportedSize")
+
+	outParams, err := ExecWmiInstanceMethod(msftPartitionQuery(diskNumber, partitionNumber), "GetSupportedSize",
+		rootMicrosoftWindowsStorage, nil, "ReturnValue", "SizeMin", "SizeMax", "ExtendedStatus")
+	if err != nil {
+		return 0, 0, err
+	}
+	if err = msftPartitionMethodError("GetSupportedSize", outParams); err != nil {
+		return 0, 0, err
+	}
+	if sizeMin, err = variantToUint64(outParams["SizeMin"]); err != nil {
+		return 0, 0, err
+	}
+	if sizeMax, err = variantToUint64(outParams["SizeMax"]); err != nil {
+		return 0, 0, err
+	}
+	log.Tracef("Disk %v partition %v supports sizes %v - %v", diskNumber, partitionNumber, sizeMin, sizeMax)
+	return sizeMin, sizeMax, nil
+}
+
+// ResizeMSFTPartition calls the Resize method of the given MSFT_Partition.  It's equivalent to the
+// Resize-Partition PowerShell cmdlet.
+func ResizeMSFTPartition(diskNumber uint32, partitionNumber uint32, size uint64) error {
+	log.Tracef(">>>>> ResizeMSFTPartition, diskNumber=%v, partitionNumber=%v, size=%v", diskNumber, partitionNumber, size)
+	defer log.Trace("<<<<< ResizeMSFTPartition")
+
+	// WMI scripting represents uint64 values as strings
+	inParams := map[string]interface{}{"Size": strconv.FormatUint(size, 10)}
+	outParams, err := ExecWmiInstanceMethod(msftPartitionQuery(diskNumber, partitionNumber), "Resize",
+		rootMicrosoftWindowsStorage, inParams, "ReturnValue", "ExtendedStatus")
+	if err != nil {
+		return err
+	}
+	return msftPartitionMethodError("Resize", outParams)
+}
+
+// msftPartitionQuery returns the WMI query for the given disk partition
+func msftPartitionQuery(diskNumber uint32, partitionNumber uint32) string {
+	return fmt.Sprintf("SELECT * FROM MSFT_Partition WHERE DiskNumber=%v AND PartitionNumber=%v", diskNumber, partitionNumber)
+}
+
+// msftPartitionMethodError returns an error if the MSFT_Partition method did not succeed
+func msftPartitionMethodError(methodName string, outParams map[string]interface{}) error {
+	returnValue, err := variantToUint64(outParams["ReturnValue"])
+	if err != nil {
+		return err
+	}
+	if returnValue != MSFTPartitionSuccess {
+		return fmt.Errorf("MSFT_Partition.%v failed, ReturnValue=%v, ExtendedStatus=%v", methodName, returnValue, outParams["ExtendedStatus"])
+	}
+	return nil
+}
+
+// variantToUint64 converts a WMI method output parameter into a uint64.  WMI scripting returns
+// uint64 values as strings.
+func variantToUint64(value interface{}) (uint64, error) {
+	switch v := value.(type) {
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	case int8, int16, int32, int64, int:
+		return uint64(reflect.ValueOf(v).Int()), nil
+	case uint8, uint16, uint32, uint64, uint:
+		return reflect.ValueOf(v).Uint(), nil
+	}
+	return 0, fmt.Errorf("unexpected WMI value %v (%T)", value, value)
+}
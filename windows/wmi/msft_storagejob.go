@@ -0,0 +1,42 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+package wmi
+
+import (
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// MSFT_StorageJob WMI class, the long running storage operations (e.g. a full format)
+type MSFT_StorageJob struct {
+	InstanceId       string
+	Name             string
+	Description      string
+	JobState         uint16
+	PercentComplete  uint16
+	IsBackgroundTask bool
+}
+
+// MSFT_StorageJob JobState values
+const (
+	StorageJobStateNew      = 2
+	StorageJobStateStarting = 3
+	StorageJobStateRunning  = 4
+)
+
+// GetMSFTStorageJob enumerates this host's MSFT_StorageJob objects
+func GetMSFTStorageJob(whereOperator string) (storageJobs []*MSFT_StorageJob, err error) {
+	log.Tracef(">>>>> GetMSFTStorageJob, whereOperator=%v", whereOperator)
+	defer log.Trace("<<<<< GetMSFTStorageJob")
+
+	// Form the WMI query
+	wmiQuery := "SELECT * FROM MSFT_StorageJob"
+	if whereOperator != "" {
+		wmiQuery += " WHERE " + whereOperator
+	}
+
+	// Execute the WMI query
+	err = ExecQuery(wmiQuery, rootMicrosoftWindowsStorage, &storageJobs)
+	return storageJobs, err
+}
@@ -6,24 +6,185 @@
 package wmi
 
 import (
+	"sync"
+	"time"
+
 	log "github.com/hpe-storage/common-host-libs/logger"
 )
 
+///////////////////////////////////////////////////////////////////////////////////////////////////
+//
+// DISK RESCANS
+//
+//		A disk rescan (MSFT_StorageSetting.UpdateHostStorageCache) can take over a minute on hosts
+//		with many disks.  Rescans run on their own COM connection and are serialized by their own
+//		rescanLock, instead of the package lock, so that other WMI queries are not stalled while a
+//		rescan is in progress.  rescanLock also protects the rescan state, which is only held for
+//		short periods so that the rescan status can be polled during a rescan.
+//
+//		StartRescanDisks starts an asynchronous rescan and returns a RescanStatus that can be
+//		polled (GetRescanStatus) or waited on (RescanStatus.Done).  Rescan requests are coalesced;
+//		if a rescan is already running, a single follow-up rescan is queued so that every caller
+//		is guaranteed a rescan that started after its request.
+//
+///////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Rescan states
+const (
+	RescanStateQueued    = "queued"
+	RescanStateRunning   = "running"
+	RescanStateCompleted = "completed"
+	RescanStateFailed    = "failed"
+)
+
+// RescanStatus reports the progress of an asynchronous disk rescan
+type RescanStatus struct {
+	ID        uint64    // Unique rescan ID
+	State     string    // See the RescanState constants
+	StartTime time.Time // Time the rescan started running (zero while queued)
+	EndTime   time.Time // Time the rescan completed or failed (zero until then)
+	Err       error     // Rescan failure, if State is RescanStateFailed
+	done      chan struct{}
+}
+
+var (
+	rescanLock    sync.Mutex    // Serializes disk rescans and protects the rescan state below
+	rescanID      uint64        // Last assigned rescan ID
+	rescanCurrent *RescanStatus // Running rescan, if any
+	rescanPending *RescanStatus // Rescan queued behind the running rescan, if any
+	rescanLast    *RescanStatus // Most recent rescan to run
+
+	// updateHostStorageCache calls the UpdateHostStorageCache method of the MSFT_StorageSetting
+	// WMI class without the package lock; a variable so that tests can replace it
+	updateHostStorageCache = func() error {
+		results, err := execWmiMethod("MSFT_StorageSetting", "UpdateHostStorageCache", rootMicrosoftWindowsStorage)
+		if results != nil {
+			// Log the RescanDisks result
+			log.Tracef("RescanDisks status = %v", results.Value())
+
+			// Release the VARIANT
+			results.Clear()
+		}
+		return err
+	}
+)
+
+// Done returns a channel that's closed when the rescan completes or fails
+func (status *RescanStatus) Done() <-chan struct{} {
+	return status.done
+}
+
+// Elapsed returns how long the rescan has been running (or ran for)
+func (status *RescanStatus) Elapsed() time.Duration {
+	switch {
+	case status.StartTime.IsZero():
+		return 0
+	case status.EndTime.IsZero():
+		return time.Since(status.StartTime)
+	}
+	return status.EndTime.Sub(status.StartTime)
+}
+
 // RescanDisks calls the UpdateHostStorageCache method of the MSFT_StorageSetting WMI class.
-// It's equivalent to performing a rescan within diskpart.exe.
+// It's equivalent to performing a rescan within diskpart.exe.  RescanDisks waits for the rescan
+// to complete; use StartRescanDisks for an asynchronous rescan.
 func RescanDisks() error {
 	log.Trace(">>>>> RescanDisks")
 	defer log.Trace("<<<<< RescanDisks")
 
-	results, err := ExecWmiMethod("MSFT_StorageSetting", "UpdateHostStorageCache", rootMicrosoftWindowsStorage)
+	rescanLock.Lock()
+	status := queueRescan()
+	rescanLock.Unlock()
+
+	// The queued rescan is promoted when the running rescan completes, so it's no longer known by
+	// its ID; its error is read from the rescan itself
+	<-status.Done()
+	rescanLock.Lock()
+	defer rescanLock.Unlock()
+	return status.Err
+}
+
+// StartRescanDisks starts an asynchronous disk rescan and returns its status.  If a rescan is
+// already running, a follow-up rescan is queued (or the already queued rescan is returned).
+func StartRescanDisks() *RescanStatus {
+	rescanLock.Lock()
+	defer rescanLock.Unlock()
+	return queueRescan().copy()
+}
+
+// queueRescan starts a disk rescan, or queues it behind the running rescan, and returns it.  The
+// caller must hold rescanLock.
+func queueRescan() *RescanStatus {
+	// Join the queued rescan, if any; it has not started yet so it will see any new disks
+	if rescanPending != nil {
+		log.Tracef("Disk rescan %v already queued", rescanPending.ID)
+		return rescanPending
+	}
+
+	rescanID++
+	status := &RescanStatus{ID: rescanID, State: RescanStateQueued, done: make(chan struct{})}
+	if rescanCurrent != nil {
+		log.Tracef("Disk rescan %v queued behind rescan %v", status.ID, rescanCurrent.ID)
+		rescanPending = status
+	} else {
+		startRescan(status)
+	}
+	return status
+}
+
+// GetRescanStatus returns the status of the given rescan ID.  If the ID is zero, the status of
+// the most recent rescan is returned.  nil is returned if the rescan is not known.
+func GetRescanStatus(id uint64) *RescanStatus {
+	rescanLock.Lock()
+	defer rescanLock.Unlock()
+
+	for _, status := range []*RescanStatus{rescanPending, rescanCurrent, rescanLast} {
+		if status != nil && (id == 0 || status.ID == id) {
+			return status.copy()
+		}
+	}
+	return nil
+}
+
+// startRescan runs the given rescan in a new goroutine.  The caller must hold rescanLock.
+func startRescan(status *RescanStatus) {
+	status.State = RescanStateRunning
+	status.StartTime = time.Now()
+	rescanCurrent = status
+	rescanLast = status
+	go runRescan(status)
+}
+
+// runRescan performs the disk rescan and then starts the queued rescan, if any
+func runRescan(status *RescanStatus) {
+	log.Tracef(">>>>> runRescan, id=%v", status.ID)
+	defer log.Trace("<<<<< runRescan")
 
-	if results != nil {
-		// Log the RescanDisks result
-		log.Tracef("RescanDisks status = %v", results.Value())
+	err := updateHostStorageCache()
 
-		// Release the VARIANT
-		results.Clear()
+	rescanLock.Lock()
+	defer rescanLock.Unlock()
+
+	status.EndTime = time.Now()
+	status.State = RescanStateCompleted
+	if err != nil {
+		log.Errorf("Disk rescan %v failed, err=%v", status.ID, err)
+		status.State = RescanStateFailed
+		status.Err = err
+	}
+	log.Tracef("Disk rescan %v %v in %v", status.ID, status.State, status.EndTime.Sub(status.StartTime))
+	close(status.done)
+
+	rescanCurrent = nil
+	if rescanPending != nil {
+		pending := rescanPending
+		rescanPending = nil
+		startRescan(pending)
 	}
+}
 
-	return err
+// copy returns a snapshot of the rescan status.  The caller must hold rescanLock.
+func (status *RescanStatus) copy() *RescanStatus {
+	snapshot := *status
+	return &snapshot
 }
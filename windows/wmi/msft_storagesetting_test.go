@@ -0,0 +1,53 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+package wmi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRescanDisksQueued(t *testing.T) {
+	savedUpdateHostStorageCache := updateHostStorageCache
+	defer func() { updateHostStorageCache = savedUpdateHostStorageCache }()
+
+	// The first rescan blocks until released; the queued rescan fails
+	started := make(chan struct{})
+	release := make(chan struct{})
+	queuedErr := errors.New("rescan failure")
+	calls := 0
+	updateHostStorageCache = func() error {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-release
+			return nil
+		}
+		return queuedErr
+	}
+
+	firstErr := make(chan error, 1)
+	go func() { firstErr <- RescanDisks() }()
+	<-started
+
+	// Queue a second rescan behind the running one
+	secondErr := make(chan error, 1)
+	go func() { secondErr <- RescanDisks() }()
+	for GetRescanStatus(0).State != RescanStateQueued {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	if err := <-firstErr; err != nil {
+		t.Errorf("expected the first rescan to succeed, err=%v", err)
+	}
+	if err := <-secondErr; err != queuedErr {
+		t.Errorf("expected the queued rescan to fail with %v, got %v", queuedErr, err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 rescans, got %v", calls)
+	}
+}
@@ -54,3 +54,14 @@ func GetMSiSCSIPortalInfoClass() (portals *MSiSCSI_PortalInfoClass, err error) {
 	err = ExecQuery("SELECT * FROM MSiSCSI_PortalInfoClass", rootWMI, &portals)
 	return portals, err
 }
+
+// GetMSiSCSIPortalInfoClasses enumerates all of this host's MSiSCSI_PortalInfoClass objects; one
+// for the software initiator and one for each iSCSI HBA
+func GetMSiSCSIPortalInfoClasses() (portals []*MSiSCSI_PortalInfoClass, err error) {
+	log.Trace(">>>>> GetMSiSCSIPortalInfoClasses")
+	defer log.Trace("<<<<< GetMSiSCSIPortalInfoClasses")
+
+	// Execute the WMI query
+	err = ExecQuery("SELECT * FROM MSiSCSI_PortalInfoClass", rootWMI, &portals)
+	return portals, err
+}
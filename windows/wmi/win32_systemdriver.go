@@ -0,0 +1,83 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+// Package wmi handles WMI queries
+package wmi
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// Win32_SystemDriver WMI class
+type Win32_SystemDriver struct {
+	AcceptPause             bool
+	AcceptStop              bool
+	Caption                 string
+	CreationClassName       string
+	Description             string
+	DesktopInteract         bool
+	DisplayName             string
+	ErrorControl            string
+	ExitCode                uint32
+	InstallDate             string
+	Name                    string
+	PathName                string
+	ServiceSpecificExitCode uint32
+	ServiceType             string
+	Started                 bool
+	StartMode               string
+	StartName               string
+	State                   string
+	Status                  string
+	SystemCreationClassName string
+	SystemName              string
+	TagId                   uint32
+}
+
+// CIM_DataFile WMI class
+type CIM_DataFile struct {
+	Caption      string
+	CreationDate string
+	Description  string
+	Drive        string
+	Extension    string
+	FileName     string
+	FileSize     uint64
+	FileType     string
+	LastModified string
+	Manufacturer string
+	Name         string
+	Path         string
+	Version      string
+}
+
+// GetWin32SystemDriver enumerates this host's Win32_SystemDriver objects
+func GetWin32SystemDriver(whereOperator string) (drivers []*Win32_SystemDriver, err error) {
+	log.Tracef(">>>>> GetWin32SystemDriver, whereOperator=%v", whereOperator)
+	defer log.Trace("<<<<< GetWin32SystemDriver")
+
+	// Form the WMI query
+	wmiQuery := "SELECT * FROM Win32_SystemDriver"
+	if whereOperator != "" {
+		wmiQuery += " WHERE " + whereOperator
+	}
+
+	// Execute the WMI query
+	err = ExecQuery(wmiQuery, rootCIMV2, &drivers)
+	return drivers, err
+}
+
+// GetCIMDataFile enumerates the CIM_DataFile object of the given file (e.g. a driver's PathName)
+func GetCIMDataFile(path string) (dataFiles []*CIM_DataFile, err error) {
+	log.Tracef(">>>>> GetCIMDataFile, path=%v", path)
+	defer log.Trace("<<<<< GetCIMDataFile")
+
+	// WQL string literals escape backslashes and quotes
+	name := strings.Replace(strings.Replace(path, `\`, `\\`, -1), `'`, `\'`, -1)
+	err = ExecQuery(fmt.Sprintf("SELECT * FROM CIM_DataFile WHERE Name='%v'", name), rootCIMV2, &dataFiles)
+	return dataFiles, err
+}
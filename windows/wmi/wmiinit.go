@@ -0,0 +1,150 @@
+// (c) Copyright 2019 Hewlett Packard Enterprise Development LP
+
+// +build windows
+
+package wmi
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	ole "github.com/go-ole/go-ole"
+	log "github.com/hpe-storage/common-host-libs/logger"
+)
+
+// COM initialization
+//
+// COM is initialized per OS thread, while goroutines migrate between threads.  Every WMI call
+// therefore locks its goroutine to its thread and joins the thread to the multithreaded apartment
+// (MTA) for the duration of the call (see initializeThreadCOM).  The process wide initialization
+// (joining the MTA for the lifetime of the process, setting the COM security levels and obtaining
+// the WMI locator) used to be performed by the package init() routine, on the startup thread.
+// In some hosting processes (e.g. DLL style embedding, or a Windows service whose startup thread
+// is a single threaded apartment) that initialization fails and every WMI query then failed for
+// the lifetime of the process.  The process wide initialization is now performed lazily, on the
+// first WMI call, and retried on later calls until successful.  Callers that want to initialize
+// WMI once their process setup is complete, and see the failure, may call InitWMI.
+
+const (
+	// COM HRESULT values handled during initialization
+	RPC_E_TOO_LATE     = 0x80010119 // CoInitializeSecurity already called by the process
+	RPC_E_CHANGED_MODE = 0x80010106 // Thread already initialized for a different apartment
+
+	// wmiInitAttempts is the number of times InitWMI attempts to initialize WMI
+	wmiInitAttempts = 3
+
+	// wmiInitRetryDelay is the delay between the InitWMI initialization attempts
+	wmiInitRetryDelay = 250 * time.Millisecond
+)
+
+var (
+	// initLock serializes the process wide COM initialization; it's never held while acquiring
+	// the package lock
+	initLock sync.Mutex
+)
+
+// InitWMI initializes COM, and obtains the WMI locator, for use by this package.  WMI is otherwise
+// initialized on the first WMI call; InitWMI allows callers to initialize WMI once their process
+// setup is complete and to handle an initialization failure.  The initialization is retried a few
+// times before failing and is retried again on the next WMI call.  Calling InitWMI once WMI is
+// initialized has no effect.
+func InitWMI() (err error) {
+	log.Trace(">>>>> InitWMI")
+	defer log.Trace("<<<<< InitWMI")
+
+	for attempt := 1; attempt <= wmiInitAttempts; attempt++ {
+		if err = initWMI(); err == nil {
+			return nil
+		}
+		log.Warnf("WMI initialization attempt %v of %v failed, err=%v", attempt, wmiInitAttempts, err)
+		if attempt < wmiInitAttempts {
+			time.Sleep(wmiInitRetryDelay)
+		}
+	}
+	return err
+}
+
+// initWMI performs the process wide COM initialization, unless already performed, and obtains the
+// WMI locator.  A failed initialization is attempted again on the next call.
+func initWMI() (err error) {
+	initLock.Lock()
+	defer initLock.Unlock()
+
+	if wmiWbemLocator != nil {
+		return nil
+	}
+
+	// The initialization must be performed on a single thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Join the MTA for the lifetime of the process, on the first successful attempt, so that the
+	// MTA (and the WMI locator it holds) outlives the individual WMI calls
+	if !comInitialized {
+		if err = ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED); err != nil && !isOleCode(err, S_OK, S_FALSE) {
+			log.Errorf("Unable to initialize COM, err=%v", err)
+			return err
+		}
+		comInitialized = true
+	}
+
+	// Set general COM security levels.  If the hosting process has already set them (i.e.
+	// RPC_E_TOO_LATE), its security levels are used instead.
+	hres, _, _ := procCoInitializeSecurity.Call(
+		uintptr(0),
+		uintptr(0xFFFFFFFF),                  // COM authentication
+		uintptr(0),                           // Authentication services
+		uintptr(0),                           // Reserved
+		uintptr(RPC_C_AUTHN_LEVEL_DEFAULT),   // Default authentication
+		uintptr(RPC_C_IMP_LEVEL_IMPERSONATE), // Default Impersonation
+		uintptr(0),                           // Authentication info
+		uintptr(EOAC_NONE),                   // Additional capabilities
+		uintptr(0))                           // Reserved
+	if FAILED(hres) && (hres != RPC_E_TOO_LATE) {
+		err = ole.NewError(hres)
+		log.Errorf("Unable to initialize COM security, err=%v", err)
+		return err
+	}
+
+	// Obtain the locator to WMI
+	locator, err := ole.CreateInstance(CLSID_WbemLocator, IID_IWbemLocator)
+	if err != nil {
+		log.Errorf("Unable to obtain the locator to WMI, err=%v", err)
+		return err
+	}
+	wmiWbemLocator = locator
+	log.Trace("WMI initialized")
+	return nil
+}
+
+// initializeThreadCOM joins the calling thread to the MTA for the duration of a WMI call.  The
+// calling goroutine must be locked to its thread and must call the returned function, on the
+// same thread, once the WMI call completes.  A thread that already belongs to a single threaded
+// apartment is used as is.
+func initializeThreadCOM() (uninitialize func(), err error) {
+	err = ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED)
+	switch {
+	case err == nil || isOleCode(err, S_OK, S_FALSE):
+		return ole.CoUninitialize, nil
+	case isOleCode(err, RPC_E_CHANGED_MODE):
+		log.Trace("Thread already initialized for a single threaded apartment")
+		return func() {}, nil
+	}
+	log.Errorf("Unable to initialize COM on the calling thread, err=%v", err)
+	return nil, err
+}
+
+// isOleCode returns true if the given error is an ole.OleError with one of the given HRESULTs
+func isOleCode(err error, codes ...uintptr) bool {
+	oleErr, ok := err.(*ole.OleError)
+	if !ok {
+		return false
+	}
+	for _, code := range codes {
+		if oleErr.Code() == code {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@
 package wmi
 
 import (
+	"fmt"
 	"runtime"
 
 	ole "github.com/go-ole/go-ole"
@@ -22,6 +23,19 @@ func ExecWmiMethod(className, methodName, namespace string, params ...interface{
 	lock.Lock()
 	defer lock.Unlock()
 
+	return execWmiMethod(className, methodName, namespace, params...)
+}
+
+// execWmiMethod executes a WMI method on its own COM connection; the locator, WMI service and class
+// objects are created, and released, by the call on its own thread, and the process wide COM
+// initialization is serialized by initLock.  The package lock is therefore not required; it's
+// held by ExecWmiMethod to serialize the WMI methods, while long running methods (e.g. a disk
+// rescan) are serialized by their own lock so that they do not stall all other WMI queries.
+func execWmiMethod(className, methodName, namespace string, params ...interface{}) (result *ole.VARIANT, err error) {
+	if err = initWMI(); err != nil {
+		return nil, err
+	}
+
 	// LockOSThread wires the calling goroutine to its current operating system thread. The calling
 	// goroutine will always execute in that thread, and no other goroutine will execute in it,
 	// until the calling goroutine has made as many calls to UnlockOSThread as to LockOSThread. If
@@ -32,24 +46,31 @@ func ExecWmiMethod(className, methodName, namespace string, params ...interface{
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
+	// Join the thread to the MTA for the duration of the method
+	uninitialize, err := initializeThreadCOM()
+	if err != nil {
+		return nil, err
+	}
+	defer uninitialize()
+
 	// Get WMI interface
 	unknown, err := oleutil.CreateObject("WbemScripting.SWbemLocator")
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 	defer unknown.Release()
 
 	// Get WMI IDispatch interface
 	wmi, err := unknown.QueryInterface(ole.IID_IDispatch)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 	defer wmi.Release()
 
 	// Connect to WMI
 	connectServerRaw, err := oleutil.CallMethod(wmi, "ConnectServer", nil, namespace)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 	connectServer := connectServerRaw.ToIDispatch()
 	defer connectServerRaw.Clear()
@@ -57,7 +78,7 @@ func ExecWmiMethod(className, methodName, namespace string, params ...interface{
 	// Get the WMI class
 	wmiClassRaw, err := oleutil.CallMethod(connectServer, "Get", className)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 	wmiClass := wmiClassRaw.ToIDispatch()
 	defer wmiClassRaw.Clear()
@@ -66,3 +87,266 @@ func ExecWmiMethod(className, methodName, namespace string, params ...interface{
 	results, err := oleutil.CallMethod(wmiClass, methodName, params...)
 	return results, err
 }
+
+// ExecWmiInstanceMethod is used to execute a WMI method on the single WMI object returned by the
+// given WMI query (e.g. MSFT_Partition.Resize).  The method's input parameters are set by name and
+// the requested output parameters (e.g. "ReturnValue") are returned by name.
+func ExecWmiInstanceMethod(wmiQuery, methodName, namespace string, inParams map[string]interface{}, outParamNames ...string) (outParams map[string]interface{}, err error) {
+	log.Tracef(">>>>> ExecWmiInstanceMethod, wmiQuery=%v, methodName=%v, namespace=%v", wmiQuery, methodName, namespace)
+	defer log.Trace("<<<<< ExecWmiInstanceMethod")
+
+	if err = initWMI(); err != nil {
+		return nil, err
+	}
+
+	// Only support one WMI query at a time
+	lock.Lock()
+	defer lock.Unlock()
+
+	// See execWmiMethod for why the goroutine is locked to its thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Join the thread to the MTA for the duration of the method
+	uninitialize, err := initializeThreadCOM()
+	if err != nil {
+		return nil, err
+	}
+	defer uninitialize()
+
+	// Get WMI interface
+	unknown, err := oleutil.CreateObject("WbemScripting.SWbemLocator")
+	if err != nil {
+		return nil, err
+	}
+	defer unknown.Release()
+
+	// Get WMI IDispatch interface
+	wmi, err := unknown.QueryInterface(ole.IID_IDispatch)
+	if err != nil {
+		return nil, err
+	}
+	defer wmi.Release()
+
+	// Connect to WMI
+	connectServerRaw, err := oleutil.CallMethod(wmi, "ConnectServer", nil, namespace)
+	if err != nil {
+		return nil, err
+	}
+	connectServer := connectServerRaw.ToIDispatch()
+	defer connectServerRaw.Clear()
+
+	// Query the WMI object; the method must only be executed on a single object
+	objectSetRaw, err := oleutil.CallMethod(connectServer, "ExecQuery", wmiQuery)
+	if err != nil {
+		return nil, err
+	}
+	objectSet := objectSetRaw.ToIDispatch()
+	defer objectSetRaw.Clear()
+
+	countRaw, err := oleutil.GetProperty(objectSet, "Count")
+	if err != nil {
+		return nil, err
+	}
+	count := countRaw.Val
+	countRaw.Clear()
+	if count != 1 {
+		return nil, fmt.Errorf("WMI query %v returned %v objects, expected 1", wmiQuery, count)
+	}
+
+	objectRaw, err := oleutil.CallMethod(objectSet, "ItemIndex", 0)
+	if err != nil {
+		return nil, err
+	}
+	object := objectRaw.ToIDispatch()
+	defer objectRaw.Clear()
+
+	// Populate the method's input parameters, if any
+	var inParamsObject *ole.IDispatch
+	if len(inParams) != 0 {
+		methodsRaw, err := oleutil.GetProperty(object, "Methods_")
+		if err != nil {
+			return nil, err
+		}
+		defer methodsRaw.Clear()
+
+		methodRaw, err := oleutil.CallMethod(methodsRaw.ToIDispatch(), "Item", methodName)
+		if err != nil {
+			return nil, err
+		}
+		defer methodRaw.Clear()
+
+		inParamsClassRaw, err := oleutil.GetProperty(methodRaw.ToIDispatch(), "InParameters")
+		if err != nil {
+			return nil, err
+		}
+		defer inParamsClassRaw.Clear()
+
+		inParamsRaw, err := oleutil.CallMethod(inParamsClassRaw.ToIDispatch(), "SpawnInstance_")
+		if err != nil {
+			return nil, err
+		}
+		defer inParamsRaw.Clear()
+
+		inParamsObject = inParamsRaw.ToIDispatch()
+		for name, value := range inParams {
+			if _, err = oleutil.PutProperty(inParamsObject, name, value); err != nil {
+				return nil, fmt.Errorf("unable to set %v.%v parameter %v, err=%v", wmiQuery, methodName, name, err)
+			}
+		}
+	}
+
+	// Execute the WMI method
+	var resultsRaw *ole.VARIANT
+	if inParamsObject != nil {
+		resultsRaw, err = oleutil.CallMethod(object, "ExecMethod_", methodName, inParamsObject)
+	} else {
+		resultsRaw, err = oleutil.CallMethod(object, "ExecMethod_", methodName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resultsRaw.Clear()
+
+	// Return the requested output parameters
+	outParams = make(map[string]interface{})
+	results := resultsRaw.ToIDispatch()
+	for _, name := range outParamNames {
+		valueRaw, err := oleutil.GetProperty(results, name)
+		if err != nil {
+			return nil, err
+		}
+		outParams[name], err = variantValue(valueRaw)
+		valueRaw.Clear()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return outParams, nil
+}
+
+// ExecQueryProperties executes the given WMI query and returns the properties, by name, of each
+// object returned.  Unlike ExecQuery, embedded objects (e.g. MPIO_PDOINFORMATION), and arrays of
+// embedded objects, are returned as a map[string]interface{} of their properties.
+func ExecQueryProperties(wmiQuery, namespace string) (objects []map[string]interface{}, err error) {
+	log.Tracef(">>>>> ExecQueryProperties, wmiQuery=%v, namespace=%v", wmiQuery, namespace)
+	defer log.Trace("<<<<< ExecQueryProperties")
+
+	if err = initWMI(); err != nil {
+		return nil, err
+	}
+
+	// Only support one WMI query at a time
+	lock.Lock()
+	defer lock.Unlock()
+
+	// See execWmiMethod for why the goroutine is locked to its thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Join the thread to the MTA for the duration of the query
+	uninitialize, err := initializeThreadCOM()
+	if err != nil {
+		return nil, err
+	}
+	defer uninitialize()
+
+	// Get WMI interface
+	unknown, err := oleutil.CreateObject("WbemScripting.SWbemLocator")
+	if err != nil {
+		return nil, err
+	}
+	defer unknown.Release()
+
+	// Get WMI IDispatch interface
+	wmi, err := unknown.QueryInterface(ole.IID_IDispatch)
+	if err != nil {
+		return nil, err
+	}
+	defer wmi.Release()
+
+	// Connect to WMI
+	connectServerRaw, err := oleutil.CallMethod(wmi, "ConnectServer", nil, namespace)
+	if err != nil {
+		return nil, err
+	}
+	connectServer := connectServerRaw.ToIDispatch()
+	defer connectServerRaw.Clear()
+
+	// Query the WMI objects and return their properties
+	objectSetRaw, err := oleutil.CallMethod(connectServer, "ExecQuery", wmiQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer objectSetRaw.Clear()
+
+	err = oleutil.ForEach(objectSetRaw.ToIDispatch(), func(objectRaw *ole.VARIANT) error {
+		defer objectRaw.Clear()
+		properties, err := objectProperties(objectRaw.ToIDispatch())
+		if err != nil {
+			return err
+		}
+		objects = append(objects, properties)
+		return nil
+	})
+	return objects, err
+}
+
+// variantValue returns the value of the given VARIANT.  Unlike VARIANT.Value, arrays are returned
+// as []interface{} and embedded objects (e.g. MSFC_HBAPortAttributesResults), including those in
+// arrays, are returned as a map[string]interface{} of their properties.
+func variantValue(valueRaw *ole.VARIANT) (interface{}, error) {
+	if valueRaw.VT&ole.VT_ARRAY != 0 {
+		values := valueRaw.ToArray().ToValueArray()
+		for i, value := range values {
+			if object, ok := value.(*ole.IDispatch); ok {
+				properties, err := objectProperties(object)
+				object.Release()
+				if err != nil {
+					return nil, err
+				}
+				values[i] = properties
+			}
+		}
+		return values, nil
+	}
+	if valueRaw.VT != ole.VT_DISPATCH {
+		return valueRaw.Value(), nil
+	}
+
+	object := valueRaw.ToIDispatch()
+	if object == nil {
+		return nil, nil
+	}
+	return objectProperties(object)
+}
+
+// objectProperties returns the properties, by name, of the given WMI object
+func objectProperties(object *ole.IDispatch) (map[string]interface{}, error) {
+	propertiesRaw, err := oleutil.GetProperty(object, "Properties_")
+	if err != nil {
+		return nil, err
+	}
+	defer propertiesRaw.Clear()
+
+	properties := make(map[string]interface{})
+	err = oleutil.ForEach(propertiesRaw.ToIDispatch(), func(propertyRaw *ole.VARIANT) error {
+		defer propertyRaw.Clear()
+		property := propertyRaw.ToIDispatch()
+		nameRaw, err := oleutil.GetProperty(property, "Name")
+		if err != nil {
+			return err
+		}
+		name := nameRaw.ToString()
+		nameRaw.Clear()
+
+		propertyValueRaw, err := oleutil.GetProperty(property, "Value")
+		if err != nil {
+			return err
+		}
+		defer propertyValueRaw.Clear()
+		properties[name], err = variantValue(propertyValueRaw)
+		return err
+	})
+	return properties, err
+}
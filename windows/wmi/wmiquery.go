@@ -142,7 +142,7 @@ var (
 	// Map to convert CIM type to reflect.Kind
 	cimTypeToGoType map[CIMTYPE_ENUMERATION]reflect.Type
 
-	comInitialized bool          // Did COM successfully initialize?
+	comInitialized bool          // Did the process join the MTA? (see initWMI)
 	wmiWbemLocator *ole.IUnknown // Enumerated WMI locator object
 )
 
@@ -413,49 +413,8 @@ func init() {
 		CIM_OBJECT:    nil,
 	}
 
-	// Initialize the COM library for use by our calling thread (all init functions are run on the
-	// startup thread).  Handle case where COM library is already initialized on this thread.
-	comInitialized = true
-	err := ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED)
-	if err != nil {
-		// If an ole.OleError error is returned, and S_OK or S_FALSE is returned, then we
-		// ignore the error and continue COM initialization.
-		comInitialized = false
-		if oleCode, ok := err.(*ole.OleError); ok == true {
-			switch oleCode.Code() {
-			case S_OK, S_FALSE:
-				comInitialized = true
-			}
-		}
-		// Log error if unexpected failure occurs
-		if !comInitialized {
-			log.Errorf("Unable to initialize COM, err=%v", err)
-		}
-	}
-
-	// Set general COM security levels
-	if comInitialized {
-		hres, _, _ := procCoInitializeSecurity.Call(
-			uintptr(0),
-			uintptr(0xFFFFFFFF),                  // COM authentication
-			uintptr(0),                           // Authentication services
-			uintptr(0),                           // Reserved
-			uintptr(RPC_C_AUTHN_LEVEL_DEFAULT),   // Default authentication
-			uintptr(RPC_C_IMP_LEVEL_IMPERSONATE), // Default Impersonation
-			uintptr(0),                           // Authentication info
-			uintptr(EOAC_NONE),                   // Additional capabilities
-			uintptr(0))                           // Reserved
-		if FAILED(hres) {
-			log.Errorf("Unable to initialize COM security, err=%v", ole.NewError(hres))
-		} else {
-			// Obtain the initial locator to WMI
-			wmiWbemLocator, err = ole.CreateInstance(CLSID_WbemLocator, IID_IWbemLocator)
-			if err != nil {
-				log.Errorf("Unable to obtain the initial locator to WMI, err=%v", err)
-				wmiWbemLocator = nil
-			}
-		}
-	}
+	// COM and the WMI locator are initialized on the first WMI call (see wmiinit.go), rather than
+	// on the startup thread, so that an initialization failure is not permanent
 }
 
 // Cleanup is an optional routine that should only be called when the process using the WMI package
@@ -463,6 +422,8 @@ func init() {
 func Cleanup() {
 	lock.Lock()
 	defer lock.Unlock()
+	initLock.Lock()
+	defer initLock.Unlock()
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 	if wmiWbemLocator != nil {
@@ -471,6 +432,7 @@ func Cleanup() {
 	}
 	if comInitialized {
 		ole.CoUninitialize()
+		comInitialized = false
 	}
 }
 
@@ -480,9 +442,10 @@ func ExecQuery(wqlQuery string, namespace string, dst interface{}) (err error) {
 	log.Tracef(">>>>> ExecQuery, wqlQuery=%v, namespace=%v", wqlQuery, namespace)
 	defer log.Trace("<<<<< ExecQuery")
 
-	// If our package init routine was unable to initialize COM, immediately fail the request
-	if wmiWbemLocator == nil {
-		log.Error("COM initialization was not successful during init(), failing WMI query")
+	// Initialize COM, and obtain the WMI locator, unless already initialized.  If unsuccessful,
+	// fail the request; the initialization is attempted again on the next request.
+	if err = initWMI(); err != nil {
+		log.Error("COM initialization was not successful, failing WMI query")
 		return ole.NewError(WBEM_E_CRITICAL_ERROR)
 	}
 
@@ -537,6 +500,13 @@ func ExecQuery(wqlQuery string, namespace string, dst interface{}) (err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
+	// Join the thread to the MTA for the duration of the query
+	uninitialize, err := initializeThreadCOM()
+	if err != nil {
+		return err
+	}
+	defer uninitialize()
+
 	// Connect to WMI through the IWbemLocator::ConnectServer method
 	var pSvc *ole.IUnknown
 	namespaceUTF16 := syscall.StringToUTF16(namespace)